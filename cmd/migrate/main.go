@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"flag"
 	"fmt"
@@ -14,7 +15,7 @@ import (
 	"github.com/kenneth/s3-encryption-gateway/internal/config"
 	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
 	"github.com/kenneth/s3-encryption-gateway/internal/migrate"
-	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/kenneth/s3-encryption-gateway/internal/storage"
 )
 
 func main() {
@@ -29,6 +30,21 @@ func main() {
 			runBackfill()
 			return
 		}
+		if a == "decrypt-file" {
+			os.Args = append(os.Args[:i+1], os.Args[i+2:]...)
+			runDecryptFile()
+			return
+		}
+		if a == "repair" {
+			os.Args = append(os.Args[:i+1], os.Args[i+2:]...)
+			runRepair()
+			return
+		}
+		if a == "hybrid-keygen" {
+			os.Args = append(os.Args[:i+1], os.Args[i+2:]...)
+			runHybridKeygen()
+			return
+		}
 	}
 	runMigrate()
 }
@@ -173,6 +189,191 @@ func runBackfill() {
 	logger.Info("backfill finished successfully", "elapsed", time.Since(start))
 }
 
+// runRepair re-attaches rebuilt manifests to chunked objects whose manifest
+// metadata was lost or corrupted, making their data readable again.
+func runRepair() {
+	fs := flag.NewFlagSet("repair", flag.ExitOnError)
+	var (
+		configPath    = fs.String("config", "gateway.yaml", "gateway config file")
+		bucket        = fs.String("bucket", "", "target S3 bucket")
+		prefix        = fs.String("prefix", "", "optional: object prefix")
+		chunkSize     = fs.Int("chunk-size", 0, "chunk size to assume when an object's chunk-size metadata is also lost (default: encryption.chunk_size)")
+		dryRun        = fs.Bool("dry-run", false, "scan only; report what would be repaired without writing")
+		noVerify      = fs.Bool("no-verify", false, "skip decrypting each object with its rebuilt manifest before writing")
+		confirmRepair = fs.Bool("confirm-repair", false, "REQUIRED: acknowledge that this operation rewrites object metadata")
+		logLevel      = fs.String("log-level", "info", "log level: debug, info, warn, error")
+		outputFormat  = fs.String("output", "text", "output format: text or json")
+	)
+	_ = fs.Parse(os.Args[1:])
+
+	logger := newLogger(*logLevel, *outputFormat)
+
+	if !*confirmRepair && !*dryRun {
+		logger.Error("repair requires --confirm-repair (or --dry-run to preview)")
+		os.Exit(1)
+	}
+	if *bucket == "" {
+		logger.Error("--bucket is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	cfg, s3Client, sourceEngine, _ := mustBuildDeps(*configPath, 0, 0, logger)
+	if *chunkSize == 0 {
+		*chunkSize = cfg.Encryption.ChunkSize
+	}
+	if *chunkSize == 0 {
+		*chunkSize = crypto.DefaultChunkSize
+	}
+
+	m := &migrate.Migrator{
+		S3Client:     s3Client,
+		SourceEngine: sourceEngine,
+		DryRun:       *dryRun,
+		Verify:       !*noVerify,
+		Logger:       logger,
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	start := time.Now()
+	if err := m.RepairManifests(ctx, *bucket, *prefix, *chunkSize); err != nil {
+		logger.Error("repair finished with errors", "error", err, "elapsed", time.Since(start))
+		os.Exit(2)
+	}
+	logger.Info("repair finished successfully", "elapsed", time.Since(start))
+}
+
+// runDecryptFile decrypts a local copy of an object written with
+// encryption.body_header, using only the body and the gateway's password.
+// It needs no backend access, so it works on copies whose metadata a
+// transfer or another store has stripped.
+func runDecryptFile() {
+	fs := flag.NewFlagSet("decrypt-file", flag.ExitOnError)
+	var (
+		configPath   = fs.String("config", "gateway.yaml", "gateway config file")
+		inPath       = fs.String("in", "", "REQUIRED: encrypted object body")
+		outPath      = fs.String("out", "-", "plaintext output file (- for stdout)")
+		hybridKey    = fs.String("hybrid-private-key", "", "optional: unwrap the data key with this hybrid private key instead of the KMS")
+		logLevel     = fs.String("log-level", "info", "log level: debug, info, warn, error")
+		outputFormat = fs.String("output", "text", "output format: text or json")
+	)
+	_ = fs.Parse(os.Args[1:])
+
+	logger := newLogger(*logLevel, *outputFormat)
+
+	if *inPath == "" {
+		logger.Error("--in is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		logger.Error("failed to load config", "error", err)
+		os.Exit(1)
+	}
+	var opts []crypto.Option
+	if *hybridKey != "" {
+		pemData, err := os.ReadFile(*hybridKey)
+		if err != nil {
+			logger.Error("failed to read hybrid private key", "error", err)
+			os.Exit(1)
+		}
+		priv, err := crypto.ParseHybridPrivateKeyPEM(pemData)
+		if err != nil {
+			logger.Error("failed to parse hybrid private key", "error", err)
+			os.Exit(1)
+		}
+		opts = append(opts, crypto.WithHybridUnwrapKey(priv))
+	}
+	engine, _, err := buildEngines(cfg, 0, 0, opts...)
+	if err != nil {
+		logger.Error("failed to build engines", "error", err)
+		os.Exit(1)
+	}
+
+	in, err := os.Open(*inPath)
+	if err != nil {
+		logger.Error("failed to open input", "error", err)
+		os.Exit(1)
+	}
+	defer in.Close()
+
+	out := os.Stdout
+	if *outPath != "-" {
+		out, err = os.OpenFile(*outPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			logger.Error("failed to create output", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	n, err := migrate.DecryptBody(context.Background(), engine, bufio.NewReader(in), out)
+	if err == nil && out != os.Stdout {
+		err = out.Close()
+	}
+	if err != nil {
+		logger.Error("decrypt-file failed", "error", err, "in", *inPath)
+		if out != os.Stdout {
+			_ = os.Remove(*outPath)
+		}
+		os.Exit(2)
+	}
+	logger.Info("decrypt-file finished", "in", *inPath, "out", *outPath, "bytes", n)
+}
+
+// runHybridKeygen generates an X25519 + ML-KEM-768 key pair for
+// encryption.hybrid_wrap. The public key goes to the gateway; the private
+// key is for decrypt-file and should be stored offline.
+func runHybridKeygen() {
+	fs := flag.NewFlagSet("hybrid-keygen", flag.ExitOnError)
+	var (
+		privatePath  = fs.String("private-out", "", "REQUIRED: file to write the private key to")
+		publicPath   = fs.String("public-out", "", "REQUIRED: file to write the public key to")
+		logLevel     = fs.String("log-level", "info", "log level: debug, info, warn, error")
+		outputFormat = fs.String("output", "text", "output format: text or json")
+	)
+	_ = fs.Parse(os.Args[1:])
+
+	logger := newLogger(*logLevel, *outputFormat)
+
+	if *privatePath == "" || *publicPath == "" {
+		logger.Error("--private-out and --public-out are required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	priv, err := crypto.GenerateHybridKey()
+	if err != nil {
+		logger.Error("failed to generate hybrid key", "error", err)
+		os.Exit(1)
+	}
+	if err := writeNewFile(*privatePath, priv.MarshalPEM(), 0o600); err != nil {
+		logger.Error("failed to write private key", "error", err)
+		os.Exit(1)
+	}
+	if err := writeNewFile(*publicPath, priv.Public().MarshalPEM(), 0o644); err != nil {
+		logger.Error("failed to write public key", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("hybrid key pair written", "key_id", priv.Public().KeyID(), "private", *privatePath, "public", *publicPath)
+}
+
+// writeNewFile writes data to a file that must not exist yet.
+func writeNewFile(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 // mustBuildDeps loads config and constructs the S3 client and crypto engines.
 // It calls os.Exit(1) on any fatal error.
 func mustBuildDeps(configPath string, sourceKeyVer, targetKeyVer int, logger *slog.Logger) (*config.Config, migrate.S3Client, crypto.EncryptionEngine, crypto.EncryptionEngine) {
@@ -182,9 +383,9 @@ func mustBuildDeps(configPath string, sourceKeyVer, targetKeyVer int, logger *sl
 		os.Exit(1)
 	}
 
-	s3Client, err := s3.NewClient(&cfg.Backend)
+	s3Client, err := storage.New(&cfg.Backend, nil)
 	if err != nil {
-		logger.Error("failed to create S3 client", "error", err)
+		logger.Error("failed to create storage backend client", "error", err)
 		os.Exit(1)
 	}
 
@@ -218,7 +419,7 @@ func newLogger(level, format string) *slog.Logger {
 	return slog.New(handler)
 }
 
-func buildEngines(cfg *config.Config, sourceKeyVer, targetKeyVer int) (crypto.EncryptionEngine, crypto.EncryptionEngine, error) {
+func buildEngines(cfg *config.Config, sourceKeyVer, targetKeyVer int, extra ...crypto.Option) (crypto.EncryptionEngine, crypto.EncryptionEngine, error) {
 	password := cfg.Encryption.Password
 	if password == "" && cfg.Encryption.KeyFile != "" {
 		data, err := os.ReadFile(cfg.Encryption.KeyFile)
@@ -235,13 +436,17 @@ func buildEngines(cfg *config.Config, sourceKeyVer, targetKeyVer int) (crypto.En
 	// If key versions differ, a key resolver is required — for now both engines
 	// are identical and the migration tool assumes the same password/key.
 	// TODO: support per-version key resolvers when KMS rotation is used.
-	engine, err := crypto.NewEngineWithChunking(
+	opts := []crypto.Option{
+		crypto.WithPreferredAlgorithm(cfg.Encryption.PreferredAlgorithm),
+		crypto.WithSupportedAlgorithms(cfg.Encryption.SupportedAlgorithms),
+		crypto.WithChunking(cfg.Encryption.ChunkedMode),
+		crypto.WithChunkSize(cfg.Encryption.ChunkSize),
+		crypto.WithBodyHeader(cfg.Encryption.BodyHeader),
+	}
+	engine, err := crypto.NewEngineWithOpts(
 		[]byte(password),
 		nil, // compression engine — can be added later
-		cfg.Encryption.PreferredAlgorithm,
-		cfg.Encryption.SupportedAlgorithms,
-		cfg.Encryption.ChunkedMode,
-		cfg.Encryption.ChunkSize,
+		append(opts, extra...)...,
 	)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create engine: %w", err)
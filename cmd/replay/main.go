@@ -0,0 +1,123 @@
+// Command s3eg-replay replays a gateway access log against a gateway, to
+// reproduce production traffic patterns in staging load tests.
+//
+//	s3eg-replay -log access.log -target http://staging-gateway:8080 -speed 2 \
+//	    -bucket-map prod-photos=staging-photos
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/replay"
+)
+
+var (
+	version = "dev"
+	commit  = "unknown"
+)
+
+// bucketMap collects repeated -bucket-map src=dst flags.
+type bucketMap map[string]string
+
+func (m bucketMap) String() string { return fmt.Sprint(map[string]string(m)) }
+
+func (m bucketMap) Set(v string) error {
+	src, dst, ok := strings.Cut(v, "=")
+	if !ok || src == "" || dst == "" {
+		return fmt.Errorf("want src=dst, got %q", v)
+	}
+	m[src] = dst
+	return nil
+}
+
+func main() {
+	buckets := bucketMap{}
+	var (
+		logPath     = flag.String("log", "-", "access log file, or - for stdin")
+		target      = flag.String("target", "", "REQUIRED: gateway base URL")
+		speed       = flag.Float64("speed", 1, "pace relative to the recording; 0 sends as fast as -concurrency allows")
+		concurrency = flag.Int("concurrency", 64, "maximum requests in flight")
+		seed        = flag.Uint64("seed", 1, "seed for synthetic request bodies")
+		accessKey   = flag.String("access-key", os.Getenv("AWS_ACCESS_KEY_ID"), "access key for SigV4 signing (default $AWS_ACCESS_KEY_ID)")
+		secretKey   = flag.String("secret-key", os.Getenv("AWS_SECRET_ACCESS_KEY"), "secret key for SigV4 signing (default $AWS_SECRET_ACCESS_KEY)")
+		region      = flag.String("region", "us-east-1", "region for SigV4 signing")
+		output      = flag.String("output", "text", "report format: text or json")
+		showVersion = flag.Bool("version", false, "print version and exit")
+	)
+	flag.Var(buckets, "bucket-map", "replace bucket src with dst, as src=dst (repeatable)")
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Printf("s3eg-replay %s (%s)\n", version, commit)
+		return
+	}
+	if *target == "" {
+		fmt.Fprintln(os.Stderr, "-target is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	targetURL, err := url.Parse(*target)
+	if err != nil || targetURL.Scheme == "" || targetURL.Host == "" {
+		fmt.Fprintf(os.Stderr, "invalid -target %q\n", *target)
+		os.Exit(2)
+	}
+	if *output != "text" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "invalid -output %q: want text or json\n", *output)
+		os.Exit(2)
+	}
+
+	in := os.Stdin
+	if *logPath != "-" {
+		f, err := os.Open(*logPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
+	}
+	parsed, err := replay.Parse(in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "replaying %d requests (%d multipart steps skipped, %d lines ignored)\n",
+		len(parsed.Requests), parsed.Skipped, parsed.Ignored)
+
+	player := &replay.Player{
+		Target:      targetURL,
+		Speed:       *speed,
+		Concurrency: *concurrency,
+		Seed:        *seed,
+		BucketMap:   buckets,
+		Region:      *region,
+	}
+	if *accessKey != "" {
+		player.Credentials = &aws.Credentials{AccessKeyID: *accessKey, SecretAccessKey: *secretKey}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	report := player.Run(ctx, parsed.Requests)
+
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	} else {
+		fmt.Print(report.String())
+	}
+	if report.Errors > 0 || ctx.Err() != nil {
+		os.Exit(1)
+	}
+}
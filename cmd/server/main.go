@@ -3,26 +3,19 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 	"time"
 
-	"github.com/gorilla/mux"
 	"github.com/kenneth/s3-encryption-gateway/internal/admin"
-	"github.com/kenneth/s3-encryption-gateway/internal/api"
-	"github.com/kenneth/s3-encryption-gateway/internal/audit"
-	"github.com/kenneth/s3-encryption-gateway/internal/cache"
 	"github.com/kenneth/s3-encryption-gateway/internal/config"
 	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
 	"github.com/kenneth/s3-encryption-gateway/internal/debug"
-	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
-	"github.com/kenneth/s3-encryption-gateway/internal/middleware"
-	mpupkg "github.com/kenneth/s3-encryption-gateway/internal/mpu"
-	"github.com/kenneth/s3-encryption-gateway/internal/s3"
-	"github.com/kenneth/s3-encryption-gateway/internal/util"
+	"github.com/kenneth/s3-encryption-gateway/internal/service"
+	"github.com/kenneth/s3-encryption-gateway/pkg/gateway"
 	"github.com/sirupsen/logrus"
 
 	"go.opentelemetry.io/otel"
@@ -37,224 +30,9 @@ import (
 var (
 	version = "dev"
 	commit  = "unknown"
+	date    = "unknown"
 )
 
-// ConfigChangeApplier holds references to components that can be updated during hot reload
-type ConfigChangeApplier struct {
-	logger         *logrus.Logger
-	tracerProvider *sdktrace.TracerProvider
-	RateLimiter    *middleware.RateLimiter
-	cache          cache.Cache
-	auditLogger    audit.Logger
-	config         *config.Config
-	policyManager  *config.PolicyManager
-}
-
-// NewConfigChangeApplier creates a new applier for configuration changes
-func NewConfigChangeApplier(logger *logrus.Logger, tracerProvider *sdktrace.TracerProvider, rateLimiter *middleware.RateLimiter, cache cache.Cache, auditLogger audit.Logger, initialConfig *config.Config, policyManager *config.PolicyManager) *ConfigChangeApplier {
-	return &ConfigChangeApplier{
-		logger:         logger,
-		tracerProvider: tracerProvider,
-		RateLimiter:    rateLimiter,
-		cache:          cache,
-		auditLogger:    auditLogger,
-		config:         initialConfig,
-		policyManager:  policyManager,
-	}
-}
-
-// stringSlicesEqual compares two string slices element-by-element.
-func stringSlicesEqual(a, b []string) bool {
-	if len(a) != len(b) {
-		return false
-	}
-	for i := range a {
-		if a[i] != b[i] {
-			return false
-		}
-	}
-	return true
-}
-
-// ApplyConfigChanges applies non-crypto configuration changes to running components
-func (a *ConfigChangeApplier) ApplyConfigChanges(oldConfig, newConfig *config.Config) error {
-	changes := []string{}
-
-	// Update log level
-	if oldConfig.LogLevel != newConfig.LogLevel {
-		level, err := logrus.ParseLevel(newConfig.LogLevel)
-		if err != nil {
-			a.logger.WithError(err).Warn("Invalid log level in reloaded config, keeping current level")
-		} else {
-			a.logger.SetLevel(level)
-			debug.InitFromLogLevel(newConfig.LogLevel)
-			changes = append(changes, fmt.Sprintf("log_level: %s -> %s", oldConfig.LogLevel, newConfig.LogLevel))
-		}
-	}
-
-	// Update rate limiting
-	if oldConfig.RateLimit.Enabled != newConfig.RateLimit.Enabled ||
-		oldConfig.RateLimit.Limit != newConfig.RateLimit.Limit ||
-		oldConfig.RateLimit.Window != newConfig.RateLimit.Window {
-
-		if a.RateLimiter != nil {
-			a.RateLimiter.Stop()
-		}
-
-		if newConfig.RateLimit.Enabled {
-			a.RateLimiter = middleware.NewRateLimiter(
-				newConfig.RateLimit.Limit,
-				newConfig.RateLimit.Window,
-				a.logger,
-			)
-			changes = append(changes, fmt.Sprintf("rate_limit: enabled=%v, limit=%d, window=%v",
-				newConfig.RateLimit.Enabled, newConfig.RateLimit.Limit, newConfig.RateLimit.Window))
-		} else {
-			a.RateLimiter = nil
-			changes = append(changes, "rate_limit: disabled")
-		}
-	}
-
-	// Update cache settings
-	if oldConfig.Cache.Enabled != newConfig.Cache.Enabled ||
-		oldConfig.Cache.MaxSize != newConfig.Cache.MaxSize ||
-		oldConfig.Cache.MaxItems != newConfig.Cache.MaxItems ||
-		oldConfig.Cache.DefaultTTL != newConfig.Cache.DefaultTTL {
-
-		// Note: Cache reconfiguration is complex and may not be safe for existing entries
-		// For now, we'll log the change but not apply it
-		a.logger.WithFields(logrus.Fields{
-			"old_enabled":   oldConfig.Cache.Enabled,
-			"new_enabled":   newConfig.Cache.Enabled,
-			"old_max_size":  oldConfig.Cache.MaxSize,
-			"new_max_size":  newConfig.Cache.MaxSize,
-			"old_max_items": oldConfig.Cache.MaxItems,
-			"new_max_items": newConfig.Cache.MaxItems,
-			"old_ttl":       oldConfig.Cache.DefaultTTL,
-			"new_ttl":       newConfig.Cache.DefaultTTL,
-		}).Warn("Cache configuration changed - restart required for changes to take effect")
-
-		changes = append(changes, "cache: configuration changed (restart required)")
-	}
-
-	// Update audit settings
-	if oldConfig.Audit.Enabled != newConfig.Audit.Enabled ||
-		oldConfig.Audit.MaxEvents != newConfig.Audit.MaxEvents {
-
-		// Note: Changing audit settings during runtime is complex
-		// For now, we'll log the change but not apply it
-		a.logger.WithFields(logrus.Fields{
-			"old_enabled":    oldConfig.Audit.Enabled,
-			"new_enabled":    newConfig.Audit.Enabled,
-			"old_max_events": oldConfig.Audit.MaxEvents,
-			"new_max_events": newConfig.Audit.MaxEvents,
-		}).Warn("Audit configuration changed - restart required for changes to take effect")
-
-		changes = append(changes, "audit: configuration changed (restart required)")
-	}
-
-	// Update tracing settings
-	if oldConfig.Tracing.Enabled != newConfig.Tracing.Enabled ||
-		oldConfig.Tracing.ServiceName != newConfig.Tracing.ServiceName ||
-		oldConfig.Tracing.ServiceVersion != newConfig.Tracing.ServiceVersion ||
-		oldConfig.Tracing.Exporter != newConfig.Tracing.Exporter ||
-		oldConfig.Tracing.JaegerEndpoint != newConfig.Tracing.JaegerEndpoint ||
-		oldConfig.Tracing.OtlpEndpoint != newConfig.Tracing.OtlpEndpoint ||
-		oldConfig.Tracing.SamplingRatio != newConfig.Tracing.SamplingRatio ||
-		oldConfig.Tracing.RedactSensitive != newConfig.Tracing.RedactSensitive {
-
-		// Tracing reconfiguration is complex and may require restarting the tracer provider
-		a.logger.WithFields(logrus.Fields{
-			"old_enabled": oldConfig.Tracing.Enabled,
-			"new_enabled": newConfig.Tracing.Enabled,
-		}).Warn("Tracing configuration changed - restart required for changes to take effect")
-
-		changes = append(changes, "tracing: configuration changed (restart required)")
-	}
-
-	// Update proxied bucket
-	if oldConfig.ProxiedBucket != newConfig.ProxiedBucket {
-		a.logger.WithFields(logrus.Fields{
-			"old_bucket": oldConfig.ProxiedBucket,
-			"new_bucket": newConfig.ProxiedBucket,
-		}).Warn("Proxied bucket changed - restart required for changes to take effect")
-
-		changes = append(changes, fmt.Sprintf("proxied_bucket: %s -> %s (restart required)", oldConfig.ProxiedBucket, newConfig.ProxiedBucket))
-	}
-
-	// Update server timeouts (these require server restart, but we can log the change)
-	if oldConfig.Server.ReadTimeout != newConfig.Server.ReadTimeout ||
-		oldConfig.Server.WriteTimeout != newConfig.Server.WriteTimeout ||
-		oldConfig.Server.IdleTimeout != newConfig.Server.IdleTimeout ||
-		oldConfig.Server.ReadHeaderTimeout != newConfig.Server.ReadHeaderTimeout ||
-		oldConfig.Server.MaxHeaderBytes != newConfig.Server.MaxHeaderBytes ||
-		oldConfig.Server.DisableMultipartUploads != newConfig.Server.DisableMultipartUploads {
-
-		a.logger.WithFields(logrus.Fields{
-			"read_timeout":  newConfig.Server.ReadTimeout,
-			"write_timeout": newConfig.Server.WriteTimeout,
-			"idle_timeout":  newConfig.Server.IdleTimeout,
-		}).Warn("Server configuration changed - restart required for changes to take effect")
-
-		changes = append(changes, "server: timeouts/configuration changed (restart required)")
-	}
-
-	// Update logging configuration
-	if oldConfig.Logging.AccessLogFormat != newConfig.Logging.AccessLogFormat ||
-		len(oldConfig.Logging.RedactHeaders) != len(newConfig.Logging.RedactHeaders) {
-
-		// Check if redact headers changed
-		headersChanged := len(oldConfig.Logging.RedactHeaders) != len(newConfig.Logging.RedactHeaders)
-		if !headersChanged {
-			for i, header := range oldConfig.Logging.RedactHeaders {
-				if i >= len(newConfig.Logging.RedactHeaders) || header != newConfig.Logging.RedactHeaders[i] {
-					headersChanged = true
-					break
-				}
-			}
-		}
-
-		if oldConfig.Logging.AccessLogFormat != newConfig.Logging.AccessLogFormat || headersChanged {
-			a.logger.WithFields(logrus.Fields{
-				"old_format": oldConfig.Logging.AccessLogFormat,
-				"new_format": newConfig.Logging.AccessLogFormat,
-			}).Warn("Logging configuration changed - restart required for changes to take effect")
-
-			changes = append(changes, "logging: configuration changed (restart required)")
-		}
-	}
-
-	// Update policy files
-	if !stringSlicesEqual(oldConfig.PolicyFiles, newConfig.PolicyFiles) {
-		if len(newConfig.PolicyFiles) > 0 {
-			if a.policyManager == nil {
-				a.policyManager = config.NewPolicyManager()
-			}
-			if err := a.policyManager.LoadPolicies(newConfig.PolicyFiles); err != nil {
-				a.logger.WithError(err).Warn("Failed to reload policy files during config change")
-				changes = append(changes, "policy_files: reload failed")
-			} else {
-				changes = append(changes, fmt.Sprintf("policy_files: reloaded (%d files)", len(newConfig.PolicyFiles)))
-			}
-		} else {
-			a.policyManager = nil
-			changes = append(changes, "policy_files: cleared")
-		}
-	}
-
-	// Update the config reference
-	a.config = newConfig
-
-	// Log all changes
-	if len(changes) > 0 {
-		a.logger.WithField("changes", changes).Info("Configuration reloaded with changes")
-	} else {
-		a.logger.Info("Configuration reloaded (no changes detected)")
-	}
-
-	return nil
-}
-
 // InitTracing initializes OpenTelemetry tracing based on configuration
 func InitTracing(cfg config.TracingConfig, logger *logrus.Logger) (*sdktrace.TracerProvider, error) {
 	// Create resource with service information
@@ -320,19 +98,28 @@ func InitTracing(cfg config.TracingConfig, logger *logrus.Logger) (*sdktrace.Tra
 	return tp, nil
 }
 
-// zeroBytes overwrites a byte slice with zeros for secure memory cleanup.
-func zeroBytes(b []byte) {
-	for i := range b {
-		b[i] = 0
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "probe" {
+		os.Exit(runProbe(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replicate" {
+		os.Exit(runReplicate(os.Args[2:], os.Stderr))
 	}
-}
 
-func main() {
 	// Initialize logger
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
 	logger.SetLevel(logrus.InfoLevel)
 
+	// Report readiness and shutdown to systemd (Type=notify) or the Windows
+	// service control manager when started by one. This comes first because
+	// the control manager expects a started service to connect promptly.
+	svcManager, err := service.New("s3-encryption-gateway", logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to connect to the service manager")
+	}
+	defer svcManager.Stopped()
+
 	// Load configuration
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
@@ -363,8 +150,9 @@ func main() {
 	debug.InitFromLogLevel(cfg.LogLevel)
 
 	logger.WithFields(logrus.Fields{
-		"version": version,
-		"commit":  commit,
+		"version":    version,
+		"commit":     commit,
+		"build_date": date,
 	}).Info("Starting S3 Encryption Gateway")
 
 	// Assert FIPS profile if binary was built with -tags=fips
@@ -392,368 +180,21 @@ func main() {
 		}).Info("Tracing initialized")
 	}
 
-	// Initialize metrics
-	metricsConfig := metrics.Config{
-		EnableBucketLabel: cfg.Metrics.EnableBucketLabel,
-	}
-	m := metrics.NewMetricsWithConfig(metricsConfig)
-	metrics.SetVersion(version)
-	m.SetFIPSMode(crypto.FIPSEnabled())
-	logger.WithFields(logrus.Fields{
-		"fips": crypto.FIPSEnabled(),
-	}).Info("crypto profile")
-
-	// Start system metrics collector
-	m.StartSystemMetricsCollector()
-
-	// Log any advisory warnings from the retry configuration.
-	for _, w := range s3.ValidationWarnings(cfg.Backend.Retry) {
-		logger.Warn("backend retry config: " + w)
-	}
-
-	// Initialize S3 client.
-	// V0.6-PERF-2: always use ClientFactory so the retry policy is applied.
-	factory := s3.NewClientFactory(&cfg.Backend, s3.WithMetrics(m))
-	s3Client, err := factory.GetClient()
-	if err != nil {
-		logger.WithError(err).Fatal("Failed to create S3 client")
-	}
-	logger.WithFields(logrus.Fields{
-		"retry_mode":      cfg.Backend.Retry.Mode,
-		"max_attempts":    cfg.Backend.Retry.MaxAttempts,
-		"initial_backoff": cfg.Backend.Retry.InitialBackoff,
-	}).Info("S3 backend client initialized with configured credentials and retry policy")
-
-	// Load encryption password (required for both single password and KMS modes)
-	var encryptionPassword []byte
-	var keyManager crypto.KeyManager
-
-	if cfg.Encryption.Password != "" {
-		encryptionPassword = []byte(cfg.Encryption.Password)
-	} else if cfg.Encryption.KeyFile != "" {
-		keyData, err := os.ReadFile(cfg.Encryption.KeyFile)
-		if err != nil {
-			logger.WithError(err).Fatal("Failed to read encryption key file")
-		}
-		encryptionPassword = make([]byte, len(keyData))
-		copy(encryptionPassword, keyData)
-		zeroBytes(keyData)
-	}
-
-	if len(encryptionPassword) == 0 {
-		logger.Fatal("Encryption password is required (set ENCRYPTION_PASSWORD or encryption.password)")
-	}
-
-	activePassword := make([]byte, len(encryptionPassword))
-	copy(activePassword, encryptionPassword)
-	zeroBytes(encryptionPassword)
-	if cfg.Encryption.KeyManager.Enabled {
-		keyManager, err = api.BuildKeyManager(&cfg.Encryption.KeyManager, logger)
-		if err != nil {
-			logger.WithError(err).Fatal("Failed to initialize key manager")
-		}
-		defer func() {
-			if err := keyManager.Close(context.Background()); err != nil {
-				logger.WithError(err).Warn("Failed to close key manager cleanly")
-			}
-		}()
-		logger.WithFields(logrus.Fields{
-			"provider": strings.ToLower(cfg.Encryption.KeyManager.Provider),
-		}).Info("External key manager initialized")
-	} else {
-		// Password-only mode: construct a PasswordKeyManager so that encrypted
-		// multipart uploads (EncryptMultipartUploads=true) can wrap per-upload
-		// DEKs with PBKDF2+AES-256-GCM instead of storing them in plaintext.
-		// This provides equivalent confidentiality to single-PUT chunked encryption.
-		pkm, pkmErr := crypto.NewPasswordKeyManager(activePassword, cfg.Encryption.KDF.PBKDF2.Iterations)
-		if pkmErr != nil {
-			logger.WithError(pkmErr).Fatal("Failed to initialize password key manager")
-		}
-		keyManager = pkm
-		logger.Info("Using single password mode (no key rotation); PasswordKeyManager active for MPU DEK wrapping")
-	}
-
-	// Initialize compression engine if enabled
-	var compressionEngine crypto.CompressionEngine
-	if cfg.Compression.Enabled {
-		compressionEngine = crypto.NewCompressionEngine(
-			cfg.Compression.Enabled,
-			cfg.Compression.MinSize,
-			cfg.Compression.ContentTypes,
-			cfg.Compression.Algorithm,
-			cfg.Compression.Level,
-		)
-		logger.WithFields(logrus.Fields{
-			"enabled":   cfg.Compression.Enabled,
-			"algorithm": cfg.Compression.Algorithm,
-			"min_size":  cfg.Compression.MinSize,
-		}).Info("Compression enabled")
-	}
-
-	// Log hardware acceleration info
-	hwInfo := crypto.GetHardwareAccelerationInfo(&cfg.Encryption.Hardware)
-	logger.WithFields(logrus.Fields{
-		"aes_hardware_support": hwInfo["aes_hardware_support"],
-		"architecture":         hwInfo["architecture"],
-		"active":               hwInfo["hardware_acceleration_active"],
-	}).Info("Hardware acceleration status")
-
-	// Set hardware acceleration metric
-	if active, ok := hwInfo["hardware_acceleration_active"].(bool); ok {
-		accelType := "unknown"
-		if strings.Contains(hwInfo["architecture"].(string), "amd64") || strings.Contains(hwInfo["architecture"].(string), "386") {
-			accelType = "aes-ni"
-		} else if strings.Contains(hwInfo["architecture"].(string), "arm") {
-			accelType = "armv8-aes"
-		}
-		m.SetHardwareAccelerationStatus(accelType, active)
-	}
-
-	// Initialize encryption engine with compression, algorithm support, chunked mode, and key resolver (if KMS mode)
-	var encryptionEngine crypto.EncryptionEngine
-
-	// Default to chunked mode enabled unless explicitly disabled
-	chunkedMode := cfg.Encryption.ChunkedMode
-	if !cfg.Encryption.ChunkedMode && cfg.Encryption.ChunkSize == 0 {
-		// If neither is set, default to enabled for new installations
-		chunkedMode = true
-	}
-
-	// NEW-01: Emit a prominent startup warning when legacy (non-chunked) mode is
-	// active. In this mode Encrypt and Decrypt each call io.ReadAll on the
-	// entire object body, so a single large upload allocates roughly the object
-	// size as heap. This is a remote memory-exhaustion risk for large objects.
-	// Chunked mode streams data in fixed-size chunks and is the recommended
-	// default for all production deployments.
-	if !chunkedMode {
-		logger.Warn("SECURITY WARNING: chunked_mode is disabled. " +
-			"Encrypt and Decrypt buffer entire objects in heap memory (io.ReadAll). " +
-			"A large upload or download will allocate the full object size on the heap, " +
-			"enabling a trivial remote memory-exhaustion DoS. " +
-			"Enable encryption.chunked_mode: true in your configuration for production workloads.")
-	}
-
-	chunkSize := cfg.Encryption.ChunkSize
-	if chunkSize == 0 {
-		chunkSize = crypto.DefaultChunkSize
-	}
-
-	encryptionEngine, err = crypto.NewEngineWithOpts(
-		activePassword,
-		compressionEngine,
-		crypto.WithPreferredAlgorithm(cfg.Encryption.PreferredAlgorithm),
-		crypto.WithSupportedAlgorithms(cfg.Encryption.SupportedAlgorithms),
-		crypto.WithChunking(chunkedMode),
-		crypto.WithChunkSize(chunkSize),
-		crypto.WithProvider("default"),
-		crypto.WithPBKDF2Iterations(cfg.Encryption.KDF.PBKDF2.Iterations),
+	// Build the request pipeline and start the background subsystems. The
+	// binary is a thin wrapper around the embeddable gateway package.
+	gw, err := gateway.New(cfg,
+		gateway.WithLogger(logger),
+		gateway.WithVersion(version, commit, date),
+		gateway.WithConfigFile(configPath),
 	)
-	// Zero the upstream password copy now that the engine owns its own defensive copy.
-	zeroBytes(activePassword)
 	if err != nil {
-		logger.WithError(err).Fatal("Failed to create encryption engine")
-	}
-	if keyManager != nil {
-		crypto.SetKeyManager(encryptionEngine, keyManager)
-	}
-
-	logger.WithFields(logrus.Fields{
-		"preferred_algorithm":   cfg.Encryption.PreferredAlgorithm,
-		"supported_algorithms":  cfg.Encryption.SupportedAlgorithms,
-		"chunked_mode":          chunkedMode,
-		"chunk_size":            chunkSize,
-		"kdf_pbkdf2_iterations": cfg.Encryption.KDF.PBKDF2.Iterations,
-	}).Info("Encryption configuration")
-
-	// Initialize cache if enabled (Phase 5 feature)
-	var objectCache cache.Cache
-	if cfg.Cache.Enabled {
-		objectCache = cache.NewMemoryCache(
-			cfg.Cache.MaxSize,
-			cfg.Cache.MaxItems,
-			cfg.Cache.DefaultTTL,
-		)
-		logger.WithFields(logrus.Fields{
-			"max_size":    cfg.Cache.MaxSize,
-			"max_items":   cfg.Cache.MaxItems,
-			"default_ttl": cfg.Cache.DefaultTTL,
-		}).Info("Cache enabled")
+		logger.WithError(err).Fatal("Failed to start gateway")
 	}
 
-	// Initialize audit logger if enabled (Phase 5 feature)
-	var auditLogger audit.Logger
-	if cfg.Audit.Enabled {
-		var err error
-		auditLogger, err = audit.NewLoggerFromConfig(cfg.Audit)
-		if err != nil {
-			logger.WithError(err).Fatal("Failed to initialize audit logger")
-		}
-		logger.WithFields(logrus.Fields{
-			"max_events": cfg.Audit.MaxEvents,
-			"sink_type":  cfg.Audit.Sink.Type,
-		}).Info("Audit logging enabled")
-	}
-
-	// Initialize policy manager if policy files are configured
-	var policyManager *config.PolicyManager
-	if len(cfg.PolicyFiles) > 0 {
-		policyManager = config.NewPolicyManager()
-		if err := policyManager.LoadPolicies(cfg.PolicyFiles); err != nil {
-			logger.WithError(err).Fatal("Failed to load policy files")
-		}
-		logger.WithField("count", len(cfg.PolicyFiles)).Info("Policy files loaded")
-	}
-
-	// Fail-closed startup check: if any bucket policy enables EncryptMultipartUploads
-	// but the Valkey addr is not configured, refuse to start rather than silently
-	// falling back to plaintext multipart uploads (Phase C DoD requirement).
-	if policyManager.AnyPolicyRequiresMPUEncryption() && cfg.MultipartState.Valkey.Addr == "" {
-		logger.Fatal("One or more bucket policies require EncryptMultipartUploads=true " +
-			"but multipart_state.valkey.addr is not configured. " +
-			"Configure Valkey or remove EncryptMultipartUploads from all policies.")
-	}
-
-	// Create gateway credential store from resolved credentials.
-	// V1.0-AUTH-1: every request must present valid gateway-managed credentials.
-	resolvedCreds := cfg.ResolvedCredentials()
-	credStore, err := api.NewStaticCredentialStore(resolvedCreds)
-	if err != nil {
-		logger.WithError(err).Fatal("Failed to create credential store")
-	}
-	// Zero plaintext secrets from the transient slice; the store holds its own copy.
-	for i := range resolvedCreds {
-		resolvedCreds[i].SecretKey = strings.Repeat("\x00", len(resolvedCreds[i].SecretKey))
-	}
-	resolvedCreds = nil
-	logger.WithField("count", len(cfg.Auth.Credentials)).Info("Gateway credential store initialized")
-
-	// Initialize API handler with Phase 5 features
-	handler := api.NewHandlerWithFeatures(s3Client, encryptionEngine, logger, m, keyManager, objectCache, auditLogger, cfg, policyManager)
-
-	// Initialise Valkey state store for encrypted multipart uploads when any
-	// bucket policy enables EncryptMultipartUploads. Fail-closed: if Valkey is
-	// unreachable at startup and encrypted MPU is required, refuse to start.
-	var mpuStore mpupkg.StateStore
-	if cfg.MultipartState.Valkey.Addr != "" {
-		var err error
-		mpuStore, err = mpupkg.NewValkeyStateStore(context.Background(), cfg.MultipartState.Valkey)
-		if err != nil {
-			logger.WithError(err).Fatal("Failed to initialise MPU Valkey state store")
-		}
-		handler.WithMPUStateStore(mpuStore)
-		m.SetMPUValkeyInsecure(!cfg.MultipartState.Valkey.TLS.Enabled)
-		logger.WithField("addr", cfg.MultipartState.Valkey.Addr).Info("MPU Valkey state store initialised")
-	}
-
-	// Initialize configuration hot-reload (only if config file is specified)
-	var configReloader *config.ConfigReloader
-	var configApplier *ConfigChangeApplier
-
-	if configPath != "" { // Enable hot-reload for any non-empty config path
-		// Create config change applier
-		var rateLimiterPtr *middleware.RateLimiter
-		if cfg.RateLimit.Enabled {
-			rateLimiterPtr = middleware.NewRateLimiter(
-				cfg.RateLimit.Limit,
-				cfg.RateLimit.Window,
-				logger,
-			)
-			defer rateLimiterPtr.Stop()
-		}
-
-		configApplier = NewConfigChangeApplier(logger, tracerProvider, rateLimiterPtr, objectCache, auditLogger, cfg, policyManager)
-
-		// Create and start config reloader
-		var err error
-		configReloader, err = config.NewConfigReloader(configPath, cfg, logger)
-		if err != nil {
-			logger.WithError(err).Fatal("Failed to initialize config reloader")
-		}
-
-		// Set the reload callback
-		configReloader.SetOnReloadCallback(configApplier.ApplyConfigChanges)
-
-		// Start config reloader in background
-		go configReloader.Start()
-
-		logger.WithField("config_file", configPath).Info("Configuration hot-reload enabled")
-	}
-
-	// Setup router
-	router := mux.NewRouter()
-
-	// Register health check endpoint
-	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-	}).Methods("GET", "HEAD")
-
-	// Register API routes
-	handler.RegisterRoutes(router)
-
-	// Initialize IP extractor with trusted proxies (V1.0-SEC-6, V1.0-SEC-16).
-	// This ensures client IP extraction honors trusted proxy configuration
-	// and prevents X-Forwarded-For header spoofing in tracing, rate limiting,
-	// and audit logging.
-	ipExtractor, err := util.NewIPExtractor(cfg.Server.TrustedProxies)
-	if err != nil {
-		logger.WithError(err).Fatal("Invalid trusted proxies configuration")
-	}
-	api.SetIPExtractor(ipExtractor)
-	middleware.SetIPExtractor(ipExtractor)
-
-	// Apply middleware
-	// RecoveryMiddleware is intentionally applied LAST so it wraps all other
-	// layers. If it were innermost, panics in outer middleware (logging,
-	// security headers, tracing, bucket validation, rate limiting) would
-	// bypass recovery and crash the server goroutine.
-	httpHandler := middleware.LoggingMiddleware(logger, &cfg.Logging)(router)
-	httpHandler = middleware.SecurityHeadersMiddleware(cfg.Server.ForceHTTPS)(httpHandler)
-
-	// Apply tracing middleware if tracing is enabled
-	if cfg.Tracing.Enabled {
-		httpHandler = middleware.TracingMiddleware(cfg.Tracing.RedactSensitive, ipExtractor)(httpHandler)
-	}
-
-	// Apply bucket validation middleware if proxied bucket is configured
-	if cfg.ProxiedBucket != "" {
-		httpHandler = middleware.BucketValidationMiddleware(cfg.ProxiedBucket, logger)(httpHandler)
-		logger.WithField("proxied_bucket", cfg.ProxiedBucket).Info("Single bucket proxy mode enabled")
-	}
-
-	// Add rate limiting if enabled
-	if cfg.RateLimit.Enabled {
-		// Use the rate limiter from config applier if hot-reload is enabled
-		var rateLimiter *middleware.RateLimiter
-		if configApplier != nil && configApplier.RateLimiter != nil {
-			rateLimiter = configApplier.RateLimiter
-		} else {
-			rateLimiter = middleware.NewRateLimiter(
-				cfg.RateLimit.Limit,
-				cfg.RateLimit.Window,
-				logger,
-			)
-			defer rateLimiter.Stop()
-		}
-		httpHandler = middleware.RateLimitMiddleware(rateLimiter)(httpHandler)
-		logger.WithFields(logrus.Fields{
-			"limit":  cfg.RateLimit.Limit,
-			"window": cfg.RateLimit.Window,
-		}).Info("Rate limiting enabled")
-	}
-
-	// V1.0-AUTH-1: AuthMiddleware gatekeeps every request before it reaches
-	// business logic. It runs inside RecoveryMiddleware so panics during auth
-	// validation are caught, but it must be outermost among functional
-	// middleware so unauthenticated requests are rejected early.
-	httpHandler = api.AuthMiddleware(credStore, cfg.Auth.ClockSkewTolerance, logger)(httpHandler)
-
-	// RecoveryMiddleware wraps the ENTIRE chain so panics in any layer are caught.
-	httpHandler = middleware.RecoveryMiddleware(logger)(httpHandler)
-
 	// Create HTTP server
 	server := &http.Server{
 		Addr:              cfg.ListenAddr,
-		Handler:           httpHandler,
+		Handler:           gw,
 		ReadTimeout:       cfg.Server.ReadTimeout,
 		WriteTimeout:      cfg.Server.WriteTimeout,
 		IdleTimeout:       cfg.Server.IdleTimeout,
@@ -761,6 +202,13 @@ func main() {
 		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
 	}
 
+	// Bind before serving so readiness is only reported once the port
+	// accepts connections.
+	listener, err := net.Listen("tcp", cfg.ListenAddr)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to start server")
+	}
+
 	// Start server in goroutine
 	go func() {
 		var err error
@@ -770,10 +218,10 @@ func main() {
 				"cert_file": cfg.TLS.CertFile,
 				"key_file":  cfg.TLS.KeyFile,
 			}).Info("Starting HTTPS server")
-			err = server.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+			err = server.ServeTLS(listener, cfg.TLS.CertFile, cfg.TLS.KeyFile)
 		} else {
 			logger.WithField("addr", cfg.ListenAddr).Info("Starting HTTP server")
-			err = server.ListenAndServe()
+			err = server.Serve(listener)
 		}
 		if err != nil && err != http.ErrServerClosed {
 			logger.WithError(err).Fatal("Failed to start server")
@@ -784,46 +232,10 @@ func main() {
 	var adminServer *admin.Server
 	if cfg.Admin.Enabled {
 		adminServer = admin.NewServer(cfg.Admin, logger)
-
-		// Register rotation handler on admin mux
-		rotationHandler := api.NewAdminRotationHandler(encryptionEngine, logger, m, auditLogger)
-		rotationHandler.RegisterRoutes(adminServer.Mux())
-
-		// Register MPU admin endpoints
-		var abortFn admin.MPUAbortFunc
-		if s3Client != nil {
-			abortFn = s3Client.AbortMultipartUpload
-		}
-		admin.RegisterMPUAdminRoutes(adminServer.Mux(), mpuStore, abortFn, logger)
-
-		// V0.6-OBS-1 — register pprof routes when profiling is enabled.
-		if cfg.Admin.Profiling.Enabled {
-			admin.ApplyRuntimeProfilingRates(cfg.Admin.Profiling, logger)
-			admin.RegisterPprofRoutes(
-				adminServer.Mux(),
-				cfg.Admin.Profiling,
-				m,
-				auditLogger,
-				logger,
-			)
-			m.SetAdminProfilingEnabled(true)
-			logger.WithFields(logrus.Fields{
-				"block_rate":     cfg.Admin.Profiling.BlockRate,
-				"mutex_fraction": cfg.Admin.Profiling.MutexFraction,
-				"max_seconds":    cfg.Admin.Profiling.MaxProfileSeconds,
-				"max_concurrent": cfg.Admin.Profiling.MaxConcurrentProfiles,
-			}).Warn("admin_profiling_enabled") // WARN — this surface widens blast radius
-		}
-
-		// Register metrics on admin mux only when no dedicated metrics addr is
-		// configured (fallback: authenticated admin port).
-		if cfg.Metrics.Addr == "" {
-			adminServer.Mux().Handle("/metrics", m.Handler())
+		if err := gw.RegisterAdminRoutes(adminServer.Mux()); err != nil {
+			logger.WithError(err).Fatal("Failed to register admin routes")
 		}
 
-		// Set admin API enabled metric
-		m.SetAdminAPIEnabled(true)
-
 		go func() {
 			if err := adminServer.Start(context.Background()); err != nil {
 				logger.WithError(err).Error("Admin server failed")
@@ -834,11 +246,11 @@ func main() {
 	// Start dedicated metrics listener when metrics.addr is configured.
 	// This is the recommended approach for Kubernetes: serve /metrics on a
 	// separate unauthenticated port and restrict access via NetworkPolicy.
-	// When metrics.addr is empty and admin is disabled, fall back to the S3
-	// data-plane router (legacy behaviour, no TLS / auth on metrics).
+	// When metrics.addr is empty and admin is disabled, the gateway serves
+	// them on the S3 port itself (legacy behaviour, no TLS / auth on metrics).
 	if cfg.Metrics.Addr != "" {
 		metricsMux := http.NewServeMux()
-		metricsMux.Handle("/metrics", m.Handler())
+		metricsMux.Handle("/metrics", gw.MetricsHandler())
 		metricsServer := &http.Server{
 			Addr:              cfg.Metrics.Addr,
 			Handler:           metricsMux,
@@ -850,24 +262,24 @@ func main() {
 				logger.WithError(err).Error("metrics listener failed")
 			}
 		}()
-	} else if !cfg.Admin.Enabled {
-		// No dedicated metrics addr, no admin server — serve on S3 port (legacy).
-		router.Handle("/metrics", m.Handler()).Methods("GET")
 	}
 
-	// Wait for interrupt signal
+	// Tell the service manager once the readiness checks behind /ready pass.
+	readyCtx, stopReady := context.WithCancel(context.Background())
+	go svcManager.WaitReady(readyCtx, gw.Ready, time.Second)
+
+	// Wait for interrupt signal or a stop request from the service manager
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-	<-quit
+	select {
+	case <-quit:
+	case <-svcManager.StopRequested():
+	}
+	stopReady()
+	svcManager.Stopping()
 
 	logger.Info("Shutting down server...")
 
-	// Close the API handler, zeroising any cached per-policy engine passwords.
-	if handler != nil {
-		handler.Close()
-		logger.Info("API handler closed")
-	}
-
 	// Stop admin server if running
 	if adminServer != nil {
 		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -878,20 +290,6 @@ func main() {
 		logger.Info("Admin server stopped")
 	}
 
-	// Stop config reloader if enabled
-	if configReloader != nil {
-		configReloader.Stop()
-		logger.Info("Configuration reloader stopped")
-	}
-
-	// Stop audit logger
-	if auditLogger != nil {
-		if err := auditLogger.Close(); err != nil {
-			logger.WithError(err).Error("Failed to close audit logger")
-		}
-		logger.Info("Audit logger stopped")
-	}
-
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -901,4 +299,12 @@ func main() {
 	} else {
 		logger.Info("Server stopped gracefully")
 	}
+
+	// Background work is stopped and flushed once the requests that feed
+	// it have drained.
+	if err := gw.Close(ctx); err != nil {
+		logger.WithError(err).Warn("Gateway did not shut down cleanly")
+	} else {
+		logger.Info("Gateway stopped")
+	}
 }
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/probe"
+	"github.com/kenneth/s3-encryption-gateway/internal/storage"
+)
+
+// runProbe implements "probe": it runs the provider conformance probe
+// against the configured backend and prints a capability report. It
+// returns the process exit code.
+func runProbe(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("probe", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", os.Getenv("CONFIG_PATH"), "gateway config file (default config.yaml, or $CONFIG_PATH)")
+	bucket := fs.String("bucket", "", "bucket to probe (default proxied_bucket)")
+	prefix := fs.String("prefix", probe.DefaultKeyPrefix, "key prefix for probe objects; all are deleted afterwards")
+	name := fs.String("name", "", "provider name for the suggested profile (default backend.provider)")
+	output := fs.String("output", "text", "output format: text or json")
+	timeout := fs.Duration("timeout", 2*time.Minute, "overall time limit")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *configPath == "" {
+		*configPath = "config.yaml"
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "probe: load configuration: %v\n", err)
+		return 1
+	}
+	if *bucket == "" {
+		*bucket = cfg.ProxiedBucket
+	}
+	if *bucket == "" {
+		fmt.Fprintln(stderr, "probe: -bucket is required when proxied_bucket is not set")
+		return 2
+	}
+	if *name == "" {
+		*name = cfg.Backend.Provider
+	}
+	if *name == "" {
+		*name = "custom"
+	}
+	client, err := storage.New(&cfg.Backend, nil)
+	if err != nil {
+		fmt.Fprintf(stderr, "probe: create backend client: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	report, err := probe.Run(ctx, client, *bucket, *prefix)
+	if err != nil {
+		fmt.Fprintf(stderr, "probe: %v\n", err)
+		return 1
+	}
+
+	switch *output {
+	case "json":
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			*probe.Report
+			SuggestedProfile           *crypto.ProviderProfile `json:"suggested_profile"`
+			SuggestedConditionalWrites string                  `json:"suggested_conditional_writes"`
+		}{report, report.Profile(*name), report.ConditionalWritesMode()}); err != nil {
+			fmt.Fprintf(stderr, "probe: %v\n", err)
+			return 1
+		}
+	default:
+		writeProbeReport(stdout, report, *name)
+	}
+	return 0
+}
+
+func writeProbeReport(w io.Writer, r *probe.Report, name string) {
+	yes := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+	limit := fmt.Sprintf("%d bytes", r.UserMetadataLimit)
+	if r.MetadataLimitIsLowerBound {
+		limit = "at least " + limit
+	}
+	minPart := "none accepted"
+	if r.MultipartMinPartSize > 0 {
+		minPart = fmt.Sprintf("%d bytes or less", r.MultipartMinPartSize)
+	}
+
+	fmt.Fprintf(w, "Provider capability report for bucket %q\n\n", r.Bucket)
+	fmt.Fprintf(w, "  user metadata limit        %s\n", limit)
+	fmt.Fprintf(w, "  long metadata keys         %s\n", yes(r.LongMetadataKeys))
+	fmt.Fprintf(w, "  range: bounded             %s\n", yes(r.Range.Bounded))
+	fmt.Fprintf(w, "  range: suffix              %s\n", yes(r.Range.Suffix))
+	fmt.Fprintf(w, "  range: clamps past end     %s\n", yes(r.Range.ClampsPastEnd))
+	fmt.Fprintf(w, "  range: rejects past end    %s\n", yes(r.Range.RejectsUnsatisfiable))
+	fmt.Fprintf(w, "  multipart min part size    %s\n", minPart)
+	fmt.Fprintf(w, "  If-None-Match on writes    %s\n", yes(r.ConditionalWrites.IfNoneMatch))
+	fmt.Fprintf(w, "  If-Match on writes         %s\n", yes(r.ConditionalWrites.IfMatch))
+	fmt.Fprintf(w, "  list after write           %s\n", yes(r.List.ReadAfterWrite))
+	fmt.Fprintf(w, "  list after delete          %s\n", yes(r.List.ReadAfterDelete))
+	for _, e := range r.Errors {
+		fmt.Fprintf(w, "  error                      %s\n", e)
+	}
+
+	p := r.Profile(name)
+	fmt.Fprintf(w, "\nSuggested provider profile:\n\n")
+	fmt.Fprintf(w, "  Name:                %q\n", p.Name)
+	fmt.Fprintf(w, "  UserMetadataLimit:   %d\n", p.UserMetadataLimit)
+	fmt.Fprintf(w, "  SystemMetadataLimit: %d\n", p.SystemMetadataLimit)
+	fmt.Fprintf(w, "  TotalHeaderLimit:    %d\n", p.TotalHeaderLimit)
+	fmt.Fprintf(w, "  SupportsLongKeys:    %v\n", p.SupportsLongKeys)
+	fmt.Fprintf(w, "  CompactionStrategy:  %q\n", p.CompactionStrategy)
+	fmt.Fprintf(w, "\nSuggested backend.conditional_writes: %q\n", r.ConditionalWritesMode())
+}
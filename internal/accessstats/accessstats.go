@@ -0,0 +1,400 @@
+// Package accessstats counts object reads and records when each object was
+// last read.
+//
+// The backend only ever serves ciphertext to the gateway, and its own
+// access logs and tiering policies cannot tell a client read from a
+// listing enrichment HEAD or a hook reading an object back. The gateway
+// does know, so it keeps the counters itself: one JSON object per key
+// prefix ("directory"), stored under a reserved backend prefix, mapping each
+// key to its read count and last access time.
+//
+// Reads are counted in memory and flushed asynchronously. A flush re-reads
+// the stats object, adds the pending counts and writes it back
+// conditionally, so concurrent gateway instances do not lose each other's
+// reads on backends that honour If-Match. Objects that have never been read
+// since tracking was enabled have no entry.
+package accessstats
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/sirupsen/logrus"
+)
+
+// statsObjectName is the object name of each shard under its prefix.
+const statsObjectName = "stats.json"
+
+// Counter is the recorded access state of one object.
+type Counter struct {
+	Reads      int64     `json:"reads"`
+	LastAccess time.Time `json:"last_access"`
+}
+
+// add merges o into c: counts are summed and the later access time kept.
+func (c *Counter) add(o Counter) {
+	c.Reads += o.Reads
+	if o.LastAccess.After(c.LastAccess) {
+		c.LastAccess = o.LastAccess
+	}
+}
+
+// Entry is a Counter together with the key it belongs to.
+type Entry struct {
+	Key string `json:"key"`
+	Counter
+}
+
+// Query selects entries for Tracker.Query.
+type Query struct {
+	Bucket string
+	// Prefix restricts the result to keys starting with it.
+	Prefix string
+	// ColdBefore, when set, restricts the result to objects last read
+	// before it.
+	ColdBefore time.Time
+	// Limit caps the number of entries returned; 0 means no limit.
+	Limit int
+}
+
+// Backend is the subset of s3.Client used to read, write and find stats
+// objects.
+type Backend interface {
+	GetObject(ctx context.Context, bucket, key string, versionID *string, rangeHeader *string) (io.ReadCloser, map[string]string, error)
+	PutObject(ctx context.Context, bucket, key string, reader io.Reader, metadata map[string]string, contentLength *int64, tags string, lock *s3.ObjectLockInput) error
+	ListObjects(ctx context.Context, bucket, prefix string, opts s3.ListOptions) (s3.ListResult, error)
+}
+
+type shardID struct {
+	bucket string
+	prefix string
+}
+
+// pendingShard holds changes to one shard not yet flushed.
+type pendingShard struct {
+	reads map[string]Counter
+	// removed lists keys whose stored counters are dropped on flush;
+	// reads recorded after the removal are kept.
+	removed map[string]bool
+}
+
+// Tracker counts reads and persists them. All methods are safe on a nil
+// *Tracker, which behaves as disabled tracking.
+type Tracker struct {
+	backend  Backend
+	prefix   string
+	interval time.Duration
+	logger   *logrus.Logger
+	now      func() time.Time
+
+	mu      sync.Mutex
+	pending map[shardID]*pendingShard
+
+	flushMu  sync.Mutex
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// New returns a tracker stored through backend, or nil when cfg disables
+// it.
+func New(backend Backend, cfg config.AccessStatsConfig, logger *logrus.Logger) *Tracker {
+	if !cfg.Enabled || backend == nil {
+		return nil
+	}
+	return &Tracker{
+		backend:  backend,
+		prefix:   cfg.Prefix,
+		interval: cfg.FlushInterval,
+		logger:   logger,
+		now:      time.Now,
+		pending:  make(map[shardID]*pendingShard),
+		stop:     make(chan struct{}),
+	}
+}
+
+// IsStatsKey reports whether key belongs to the stats objects and must be
+// hidden from clients.
+func (t *Tracker) IsStatsKey(key string) bool {
+	return t != nil && strings.HasPrefix(key, t.prefix)
+}
+
+func splitKey(bucket, key string) shardID {
+	return shardID{bucket: bucket, prefix: key[:strings.LastIndex(key, "/")+1]}
+}
+
+func (t *Tracker) objectKey(id shardID) string {
+	return t.prefix + id.prefix + statsObjectName
+}
+
+func (t *Tracker) shardLocked(id shardID) *pendingShard {
+	ps, ok := t.pending[id]
+	if !ok {
+		ps = &pendingShard{reads: make(map[string]Counter)}
+		t.pending[id] = ps
+	}
+	return ps
+}
+
+// Record counts one read of bucket/key.
+func (t *Tracker) Record(bucket, key string) {
+	if t == nil || t.IsStatsKey(key) {
+		return
+	}
+	now := t.now().UTC()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ps := t.shardLocked(splitKey(bucket, key))
+	c := ps.reads[key]
+	c.add(Counter{Reads: 1, LastAccess: now})
+	ps.reads[key] = c
+}
+
+// Remove drops the counters of bucket/key, for use when the object is
+// deleted.
+func (t *Tracker) Remove(bucket, key string) {
+	if t == nil || t.IsStatsKey(key) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ps := t.shardLocked(splitKey(bucket, key))
+	delete(ps.reads, key)
+	if ps.removed == nil {
+		ps.removed = make(map[string]bool)
+	}
+	ps.removed[key] = true
+}
+
+// load reads a shard from the backend. A missing stats object is an empty
+// shard; etag is "" in that case.
+func (t *Tracker) load(ctx context.Context, id shardID) (counters map[string]Counter, etag string, err error) {
+	body, meta, err := t.backend.GetObject(ctx, id.bucket, t.objectKey(id), nil, nil)
+	if err != nil {
+		if errors.Is(err, s3.ErrNotFound) {
+			return map[string]Counter{}, "", nil
+		}
+		return nil, "", err
+	}
+	defer body.Close()
+
+	counters = map[string]Counter{}
+	if err := json.NewDecoder(body).Decode(&counters); err != nil {
+		return nil, "", fmt.Errorf("accessstats: decode %s: %w", t.objectKey(id), err)
+	}
+	return counters, meta["ETag"], nil
+}
+
+// Flush writes every shard with pending reads. Shards that fail to flush
+// keep their reads for the next attempt; the first error is returned.
+func (t *Tracker) Flush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.flushMu.Lock()
+	defer t.flushMu.Unlock()
+
+	t.mu.Lock()
+	work := t.pending
+	t.pending = make(map[shardID]*pendingShard)
+	t.mu.Unlock()
+
+	ids := make([]shardID, 0, len(work))
+	for id := range work {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ids[i].bucket != ids[j].bucket {
+			return ids[i].bucket < ids[j].bucket
+		}
+		return ids[i].prefix < ids[j].prefix
+	})
+
+	var firstErr error
+	for _, id := range ids {
+		if err := t.flushShard(ctx, id, work[id]); err != nil {
+			t.requeue(id, work[id])
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// requeue puts back changes whose flush failed, merging them with reads
+// recorded since the flush started.
+func (t *Tracker) requeue(id shardID, failed *pendingShard) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ps := t.shardLocked(id)
+	for k, c := range failed.reads {
+		if ps.removed[k] {
+			// Removed since the flush started.
+			continue
+		}
+		merged := ps.reads[k]
+		merged.add(c)
+		ps.reads[k] = merged
+	}
+	for k := range failed.removed {
+		if ps.removed == nil {
+			ps.removed = make(map[string]bool)
+		}
+		ps.removed[k] = true
+	}
+}
+
+func (t *Tracker) flushShard(ctx context.Context, id shardID, changes *pendingShard) error {
+	counters, etag, err := t.load(ctx, id)
+	if err != nil {
+		return err
+	}
+	for k := range changes.removed {
+		delete(counters, k)
+	}
+	for k, c := range changes.reads {
+		merged := counters[k]
+		merged.add(c)
+		counters[k] = merged
+	}
+
+	data, err := json.Marshal(counters)
+	if err != nil {
+		return fmt.Errorf("accessstats: encode: %w", err)
+	}
+	conds := s3.WriteConditions{IfMatch: etag}
+	if etag == "" {
+		conds.IfNoneMatch = "*"
+	}
+	size := int64(len(data))
+	meta := map[string]string{"Content-Type": "application/json"}
+	if err := t.backend.PutObject(s3.WithWriteConditions(ctx, conds), id.bucket, t.objectKey(id), bytes.NewReader(data), meta, &size, "", nil); err != nil {
+		return fmt.Errorf("accessstats: write %s/%s: %w", id.bucket, t.objectKey(id), err)
+	}
+	return nil
+}
+
+// Query returns the counters of the objects matching q, least recently read
+// first. Reads not yet flushed are included.
+func (t *Tracker) Query(ctx context.Context, q Query) ([]Entry, error) {
+	if t == nil {
+		return nil, nil
+	}
+	// Keys under q.Prefix live in the shard of its directory or below it.
+	dir := q.Prefix[:strings.LastIndex(q.Prefix, "/")+1]
+	var entries []Entry
+	seen := make(map[shardID]bool)
+	merge := func(id shardID, counters map[string]Counter) {
+		t.mu.Lock()
+		if ps := t.pending[id]; ps != nil {
+			for k := range ps.removed {
+				delete(counters, k)
+			}
+			for k, c := range ps.reads {
+				merged := counters[k]
+				merged.add(c)
+				counters[k] = merged
+			}
+		}
+		t.mu.Unlock()
+		for k, c := range counters {
+			if !strings.HasPrefix(k, q.Prefix) {
+				continue
+			}
+			if !q.ColdBefore.IsZero() && !c.LastAccess.Before(q.ColdBefore) {
+				continue
+			}
+			entries = append(entries, Entry{Key: k, Counter: c})
+		}
+	}
+
+	opts := s3.ListOptions{MaxKeys: 1000}
+	for {
+		page, err := t.backend.ListObjects(ctx, q.Bucket, t.prefix+dir, opts)
+		if err != nil {
+			return nil, fmt.Errorf("accessstats: list %s/%s: %w", q.Bucket, t.prefix+dir, err)
+		}
+		for _, obj := range page.Objects {
+			if !strings.HasSuffix(obj.Key, "/"+statsObjectName) {
+				continue
+			}
+			id := shardID{bucket: q.Bucket, prefix: strings.TrimSuffix(strings.TrimPrefix(obj.Key, t.prefix), statsObjectName)}
+			counters, _, err := t.load(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			seen[id] = true
+			merge(id, counters)
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		opts.ContinuationToken = page.NextContinuationToken
+	}
+
+	// Shards that have never been flushed exist only in memory.
+	t.mu.Lock()
+	var unflushed []shardID
+	for id := range t.pending {
+		if id.bucket == q.Bucket && strings.HasPrefix(id.prefix, dir) && !seen[id] {
+			unflushed = append(unflushed, id)
+		}
+	}
+	t.mu.Unlock()
+	for _, id := range unflushed {
+		merge(id, map[string]Counter{})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].LastAccess.Equal(entries[j].LastAccess) {
+			return entries[i].LastAccess.Before(entries[j].LastAccess)
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[:q.Limit]
+	}
+	return entries, nil
+}
+
+// Start flushes pending reads every flush interval until Stop is called.
+func (t *Tracker) Start() {
+	if t == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := t.Flush(context.Background()); err != nil && t.logger != nil {
+					t.logger.WithError(err).Warn("Access stats flush failed; will retry")
+				}
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the flush loop and performs a final flush. A flush already in
+// progress completes first.
+func (t *Tracker) Stop(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.stopOnce.Do(func() { close(t.stop) })
+	return t.Flush(ctx)
+}
+
@@ -0,0 +1,160 @@
+package accessstats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func testConfig() config.AccessStatsConfig {
+	return config.AccessStatsConfig{Enabled: true, Prefix: ".s3eg-access/", FlushInterval: time.Second}
+}
+
+// clock returns a tracker clock that advances one minute per call.
+func clock(start time.Time) func() time.Time {
+	next := start
+	return func() time.Time {
+		now := next
+		next = next.Add(time.Minute)
+		return now
+	}
+}
+
+func query(t *testing.T, tr *Tracker, q Query) map[string]Counter {
+	t.Helper()
+	entries, err := tr.Query(context.Background(), q)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	got := make(map[string]Counter, len(entries))
+	for _, e := range entries {
+		got[e.Key] = e.Counter
+	}
+	return got
+}
+
+func TestTracker_RecordFlushQuery(t *testing.T) {
+	ctx := context.Background()
+	backend := testsupport.NewMemoryClient()
+	tr := New(backend, testConfig(), nil)
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tr.now = clock(start)
+
+	tr.Record("b", "dir/a")
+	tr.Record("b", "dir/a")
+	tr.Record("b", "top")
+	if got := query(t, tr, Query{Bucket: "b"}); got["dir/a"].Reads != 2 || got["top"].Reads != 1 {
+		t.Fatalf("pending reads not visible: %+v", got)
+	}
+	if err := tr.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	for _, k := range []string{".s3eg-access/dir/stats.json", ".s3eg-access/stats.json"} {
+		if _, _, ok := backend.Object("b", k); !ok {
+			t.Errorf("expected stats object %s", k)
+		}
+	}
+
+	fresh := New(backend, testConfig(), nil)
+	got := query(t, fresh, Query{Bucket: "b", Prefix: "dir/"})
+	if len(got) != 1 || got["dir/a"].Reads != 2 || !got["dir/a"].LastAccess.Equal(start.Add(time.Minute)) {
+		t.Errorf("persisted counters = %+v", got)
+	}
+
+	cold := query(t, fresh, Query{Bucket: "b", ColdBefore: start.Add(90 * time.Second)})
+	if _, ok := cold["dir/a"]; len(cold) != 1 || !ok {
+		t.Errorf("expected only dir/a cold, got %+v", cold)
+	}
+
+	tr.Remove("b", "dir/a")
+	if err := tr.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got := query(t, New(backend, testConfig(), nil), Query{Bucket: "b"}); len(got) != 1 {
+		t.Errorf("removed counters still reported: %+v", got)
+	}
+}
+
+func TestTracker_QueryOrderAndLimit(t *testing.T) {
+	tr := New(testsupport.NewMemoryClient(), testConfig(), nil)
+	tr.now = clock(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	for _, k := range []string{"c", "a", "b"} {
+		tr.Record("b", k)
+	}
+	entries, err := tr.Query(context.Background(), Query{Bucket: "b", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Key != "c" || entries[1].Key != "a" {
+		t.Errorf("expected least recently read first, got %+v", entries)
+	}
+}
+
+func TestTracker_FlushMergesConcurrentInstances(t *testing.T) {
+	ctx := context.Background()
+	backend := testsupport.NewMemoryClient()
+	one := New(backend, testConfig(), nil)
+	two := New(backend, testConfig(), nil)
+
+	one.Record("b", "k")
+	two.Record("b", "k")
+	two.Record("b", "other")
+	if err := one.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := two.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got := query(t, New(backend, testConfig(), nil), Query{Bucket: "b"})
+	if got["k"].Reads != 2 || got["other"].Reads != 1 {
+		t.Errorf("reads lost in merge: %+v", got)
+	}
+}
+
+func TestTracker_FailedFlushIsRetried(t *testing.T) {
+	ctx := context.Background()
+	backend := testsupport.NewMemoryClient()
+	fail := errors.New("backend unavailable")
+	backend.OnCall = func(op, bucket, key string) error {
+		if op == "PutObject" {
+			return fail
+		}
+		return nil
+	}
+	tr := New(backend, testConfig(), nil)
+
+	tr.Record("b", "k")
+	if err := tr.Flush(ctx); !errors.Is(err, fail) {
+		t.Fatalf("expected flush error, got %v", err)
+	}
+	tr.Record("b", "k")
+	backend.OnCall = nil
+	if err := tr.Flush(ctx); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if got := query(t, New(backend, testConfig(), nil), Query{Bucket: "b"}); got["k"].Reads != 2 {
+		t.Errorf("reads lost after failed flush: %+v", got)
+	}
+}
+
+func TestTracker_NilIsDisabled(t *testing.T) {
+	var tr *Tracker
+	tr.Record("b", "k")
+	tr.Remove("b", "k")
+	if tr.IsStatsKey(".s3eg-access/stats.json") {
+		t.Error("nil tracker claims keys")
+	}
+	if entries, err := tr.Query(context.Background(), Query{Bucket: "b"}); err != nil || entries != nil {
+		t.Errorf("nil tracker returned %v, %v", entries, err)
+	}
+	if err := tr.Stop(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if New(testsupport.NewMemoryClient(), config.AccessStatsConfig{}, nil) != nil {
+		t.Error("disabled config must return a nil tracker")
+	}
+}
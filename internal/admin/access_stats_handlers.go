@@ -0,0 +1,76 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/accessstats"
+)
+
+// AccessStatsQuerier is the subset of accessstats.Tracker used by the admin
+// handler.
+type AccessStatsQuerier interface {
+	Query(ctx context.Context, q accessstats.Query) ([]accessstats.Entry, error)
+}
+
+// RegisterAccessStatsAdminRoutes mounts the per-object read counters on the
+// provided mux.
+//
+//	GET /admin/access-stats?bucket=B[&prefix=P][&cold_after=720h][&limit=N]
+//
+// Entries are listed least recently read first. cold_after keeps only
+// objects not read within that duration. Objects never read since tracking
+// was enabled have no entry; compare against a listing to find them.
+func RegisterAccessStatsAdminRoutes(muxSrv *http.ServeMux, stats AccessStatsQuerier) {
+	muxSrv.HandleFunc("/admin/access-stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "GET required")
+			return
+		}
+		q := accessstats.Query{
+			Bucket: r.URL.Query().Get("bucket"),
+			Prefix: r.URL.Query().Get("prefix"),
+		}
+		if q.Bucket == "" {
+			writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "bucket is required")
+			return
+		}
+		if v := r.URL.Query().Get("cold_after"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "cold_after must be a positive duration")
+				return
+			}
+			q.ColdBefore = time.Now().Add(-d)
+		}
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "limit must be a non-negative integer")
+				return
+			}
+			q.Limit = n
+		}
+
+		entries, err := stats.Query(r.Context(), q)
+		if err != nil {
+			writeAdminError(w, http.StatusInternalServerError, "InternalError", "failed to read access stats")
+			return
+		}
+		if entries == nil {
+			entries = []accessstats.Entry{}
+		}
+		resp := map[string]interface{}{
+			"bucket":    q.Bucket,
+			"prefix":    q.Prefix,
+			"objects":   entries,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
@@ -0,0 +1,71 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/accessstats"
+)
+
+type fakeAccessStats struct {
+	got     accessstats.Query
+	entries []accessstats.Entry
+}
+
+func (f *fakeAccessStats) Query(ctx context.Context, q accessstats.Query) ([]accessstats.Entry, error) {
+	f.got = q
+	return f.entries, nil
+}
+
+func TestRegisterAccessStatsAdminRoutes(t *testing.T) {
+	stats := &fakeAccessStats{entries: []accessstats.Entry{{Key: "logs/a", Counter: accessstats.Counter{Reads: 3}}}}
+	mux := http.NewServeMux()
+	RegisterAccessStatsAdminRoutes(mux, stats)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/access-stats?bucket=b&prefix=logs/&cold_after=24h&limit=10", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if stats.got.Bucket != "b" || stats.got.Prefix != "logs/" || stats.got.Limit != 10 {
+		t.Errorf("unexpected query: %+v", stats.got)
+	}
+	if d := time.Since(stats.got.ColdBefore); d < 24*time.Hour || d > 25*time.Hour {
+		t.Errorf("cold_after not applied: %v", stats.got.ColdBefore)
+	}
+
+	var body struct {
+		Objects []accessstats.Entry `json:"objects"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(body.Objects) != 1 || body.Objects[0].Key != "logs/a" || body.Objects[0].Reads != 3 {
+		t.Errorf("unexpected body: %+v", body)
+	}
+}
+
+func TestRegisterAccessStatsAdminRoutes_BadRequests(t *testing.T) {
+	mux := http.NewServeMux()
+	RegisterAccessStatsAdminRoutes(mux, &fakeAccessStats{})
+
+	for _, tt := range []struct {
+		method, target string
+		want           int
+	}{
+		{http.MethodPost, "/admin/access-stats?bucket=b", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/admin/access-stats", http.StatusBadRequest},
+		{http.MethodGet, "/admin/access-stats?bucket=b&cold_after=soon", http.StatusBadRequest},
+		{http.MethodGet, "/admin/access-stats?bucket=b&limit=-1", http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.target, tt.want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/archive"
+	"github.com/sirupsen/logrus"
+)
+
+// ArchiveService is the subset of api.Handler used by the export/import
+// endpoints.
+type ArchiveService interface {
+	ExportArchive(ctx context.Context, w io.Writer, bucket, prefix string, format archive.Format) (archive.Stats, error)
+	ImportArchive(ctx context.Context, r io.Reader, bucket, prefix string, format archive.Format) (archive.Stats, error)
+}
+
+// RegisterArchiveAdminRoutes mounts the export/import endpoints on the
+// provided mux.
+//
+//	GET  /admin/export?bucket=&prefix=&format=tar|tar.zst — stream decrypted objects
+//	POST /admin/import?bucket=&prefix=&format=tar|tar.zst — encrypt and store an archive
+//
+// Both lift the admin server's read/write deadlines: an archive of a large
+// prefix takes far longer than any single admin call.
+func RegisterArchiveAdminRoutes(muxSrv *http.ServeMux, svc ArchiveService, logger *logrus.Logger) {
+	muxSrv.HandleFunc("/admin/export", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "GET required")
+			return
+		}
+		bucket, prefix, format, ok := archiveParams(w, r)
+		if !ok {
+			return
+		}
+
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		filename := strings.Trim(path.Base("/"+bucket+"/"+prefix), "/") + "." + string(format)
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+		start := time.Now()
+		stats, err := svc.ExportArchive(r.Context(), w, bucket, prefix, format)
+		fields := logrus.Fields{
+			"bucket":   bucket,
+			"prefix":   prefix,
+			"objects":  stats.Objects,
+			"bytes":    stats.Bytes,
+			"duration": time.Since(start),
+		}
+		if err != nil {
+			// The status line is already sent; abort the connection so the
+			// client sees a truncated archive instead of a valid-looking one.
+			logger.WithError(err).WithFields(fields).Error("admin/export: export failed")
+			panic(http.ErrAbortHandler)
+		}
+		logger.WithFields(fields).Info("admin/export: archive exported")
+	})
+
+	muxSrv.HandleFunc("/admin/import", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "POST required")
+			return
+		}
+		bucket, prefix, format, ok := archiveParams(w, r)
+		if !ok {
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		_ = rc.SetReadDeadline(time.Time{})
+		_ = rc.SetWriteDeadline(time.Time{})
+
+		stats, err := svc.ImportArchive(r.Context(), r.Body, bucket, prefix, format)
+		fields := logrus.Fields{
+			"bucket":  bucket,
+			"prefix":  prefix,
+			"objects": stats.Objects,
+			"bytes":   stats.Bytes,
+		}
+		if err != nil {
+			logger.WithError(err).WithFields(fields).Error("admin/import: import failed")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": map[string]string{
+					"code":    "ImportFailed",
+					"message": err.Error(),
+				},
+				"imported": stats,
+			})
+			return
+		}
+		logger.WithFields(fields).Info("admin/import: archive imported")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"bucket":    bucket,
+			"prefix":    prefix,
+			"imported":  stats,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		})
+	})
+}
+
+// archiveParams validates the query parameters shared by export and import.
+func archiveParams(w http.ResponseWriter, r *http.Request) (bucket, prefix string, format archive.Format, ok bool) {
+	q := r.URL.Query()
+	bucket = q.Get("bucket")
+	if bucket == "" {
+		writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "bucket is required")
+		return "", "", "", false
+	}
+	format, err := archive.ParseFormat(q.Get("format"))
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, "InvalidArgument", err.Error())
+		return "", "", "", false
+	}
+	return bucket, q.Get("prefix"), format, true
+}
@@ -11,49 +11,36 @@ import (
 // BearerAuthMiddleware returns HTTP middleware that validates an
 // Authorization: Bearer <token> header using constant-time comparison.
 // tokenSource is called on every request to support runtime token rotation
-// (e.g. via file-watch).
+// (e.g. via file-watch). The token grants every role.
 func BearerAuthMiddleware(tokenSource func() []byte, logger *logrus.Logger) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				writeAdminError(w, http.StatusUnauthorized, "Unauthorized", "missing Authorization header")
-				return
-			}
-
-			// Expect "Bearer <token>"
-			// Use constant-time comparison for the scheme prefix to
-			// eliminate any timing distinguisher between "wrong scheme" and
-			// "correct scheme, wrong token". The prefix "Bearer " is a public
-			// constant so the practical risk is negligible, but defense-in-depth
-			// requires the entire auth header to be handled in constant time.
-			const prefix = "Bearer "
-			if len(authHeader) <= len(prefix) {
-				writeAdminError(w, http.StatusUnauthorized, "Unauthorized", "malformed Authorization header")
-				return
-			}
-			if subtle.ConstantTimeCompare([]byte(authHeader[:len(prefix)]), []byte(prefix)) != 1 {
-				writeAdminError(w, http.StatusUnauthorized, "Unauthorized", "only Bearer authentication is supported")
-				return
-			}
-
-			got := []byte(authHeader[len(prefix):])
-			want := tokenSource()
-			if want == nil || len(want) == 0 {
-				logger.Error("admin: bearer token source returned empty token")
-				writeAdminError(w, http.StatusInternalServerError, "InternalError", "admin authentication misconfigured")
-				return
-			}
+	return RoleAuthMiddleware([]Credential{{Token: tokenSource, Roles: AllRoles}}, logger)
+}
 
-			// Constant-time comparison to prevent timing side-channels.
-			if subtle.ConstantTimeCompare(got, want) != 1 {
-				writeAdminError(w, http.StatusUnauthorized, "Unauthorized", "invalid bearer token")
-				return
-			}
+// bearerToken extracts the token from an Authorization: Bearer header. On
+// failure it writes a 401 and reports false.
+func bearerToken(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		writeAdminError(w, http.StatusUnauthorized, "Unauthorized", "missing Authorization header")
+		return nil, false
+	}
 
-			next.ServeHTTP(w, r)
-		})
+	// Expect "Bearer <token>"
+	// Use constant-time comparison for the scheme prefix to
+	// eliminate any timing distinguisher between "wrong scheme" and
+	// "correct scheme, wrong token". The prefix "Bearer " is a public
+	// constant so the practical risk is negligible, but defense-in-depth
+	// requires the entire auth header to be handled in constant time.
+	const prefix = "Bearer "
+	if len(authHeader) <= len(prefix) {
+		writeAdminError(w, http.StatusUnauthorized, "Unauthorized", "malformed Authorization header")
+		return nil, false
+	}
+	if subtle.ConstantTimeCompare([]byte(authHeader[:len(prefix)]), []byte(prefix)) != 1 {
+		writeAdminError(w, http.StatusUnauthorized, "Unauthorized", "only Bearer authentication is supported")
+		return nil, false
 	}
+	return []byte(authHeader[len(prefix):]), true
 }
 
 // adminErrorResponse is the JSON error shape for admin endpoints.
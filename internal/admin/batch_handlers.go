@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/batch"
+	"github.com/sirupsen/logrus"
+)
+
+// BatchService is the subset of batch.Manager used by the admin handlers.
+type BatchService interface {
+	Submit(ctx context.Context, spec batch.Spec) (batch.Job, error)
+	Get(id string) (batch.Job, bool)
+	List() []batch.Job
+	Cancel(id string) (batch.Job, error)
+}
+
+// maxBatchSpecBytes bounds a job submission with inline keys.
+const maxBatchSpecBytes = 16 << 20
+
+// RegisterBatchAdminRoutes mounts the batch job endpoints.
+//
+//	POST   /admin/batch/jobs       — submit a job; body {"operation":"verify","bucket":"b","keys":[...]}
+//	                                 or {"operation":"copy","bucket":"b","manifest":{"bucket":"b","key":"list.txt"},"dest_prefix":"copy/"}
+//	GET    /admin/batch/jobs       — every retained job, newest first
+//	GET    /admin/batch/jobs/{id}  — progress of one job
+//	DELETE /admin/batch/jobs/{id}  — cancel a queued or running job
+func RegisterBatchAdminRoutes(muxSrv *http.ServeMux, svc BatchService, logger *logrus.Logger) {
+	muxSrv.HandleFunc("/admin/batch/jobs", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeBatchJSON(w, http.StatusOK, map[string]interface{}{"jobs": svc.List()})
+		case http.MethodPost:
+			var spec batch.Spec
+			dec := json.NewDecoder(io.LimitReader(r.Body, maxBatchSpecBytes))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&spec); err != nil {
+				writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "invalid job: "+err.Error())
+				return
+			}
+			job, err := svc.Submit(r.Context(), spec)
+			switch {
+			case errors.Is(err, batch.ErrInvalidSpec):
+				writeAdminError(w, http.StatusBadRequest, "InvalidArgument", err.Error())
+				return
+			case errors.Is(err, batch.ErrQueueFull):
+				writeAdminError(w, http.StatusTooManyRequests, "TooManyJobs", err.Error())
+				return
+			case errors.Is(err, batch.ErrStopped):
+				writeAdminError(w, http.StatusServiceUnavailable, "ServiceUnavailable", err.Error())
+				return
+			case err != nil:
+				logger.WithError(err).WithField("bucket", spec.Bucket).Error("admin/batch: job submission failed")
+				writeAdminError(w, http.StatusInternalServerError, "BatchSubmitFailed", err.Error())
+				return
+			}
+			logger.WithFields(logrus.Fields{
+				"job":       job.ID,
+				"operation": job.Operation,
+				"bucket":    job.Bucket,
+				"total":     job.Total,
+			}).Info("admin/batch: job submitted")
+			writeBatchJSON(w, http.StatusAccepted, map[string]interface{}{"job": job})
+		default:
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "GET or POST required")
+		}
+	})
+
+	muxSrv.HandleFunc("/admin/batch/jobs/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/admin/batch/jobs/")
+		switch r.Method {
+		case http.MethodGet:
+			job, ok := svc.Get(id)
+			if !ok {
+				writeAdminError(w, http.StatusNotFound, "NoSuchJob", "no batch job "+id)
+				return
+			}
+			writeBatchJSON(w, http.StatusOK, map[string]interface{}{"job": job})
+		case http.MethodDelete:
+			job, err := svc.Cancel(id)
+			switch {
+			case errors.Is(err, batch.ErrNotFound):
+				writeAdminError(w, http.StatusNotFound, "NoSuchJob", "no batch job "+id)
+				return
+			case errors.Is(err, batch.ErrFinished):
+				writeAdminError(w, http.StatusConflict, "JobFinished", "batch job "+id+" is already "+job.State)
+				return
+			case err != nil:
+				writeAdminError(w, http.StatusInternalServerError, "InternalError", err.Error())
+				return
+			}
+			logger.WithField("job", id).Warn("admin/batch: job cancelled")
+			writeBatchJSON(w, http.StatusOK, map[string]interface{}{"job": job, "cancelled": true})
+		default:
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "GET or DELETE required")
+		}
+	})
+}
+
+func writeBatchJSON(w http.ResponseWriter, status int, body map[string]interface{}) {
+	body["timestamp"] = time.Now().UTC().Format(time.RFC3339)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/batch"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeBatchService struct {
+	spec batch.Spec
+	jobs map[string]batch.Job
+}
+
+func (f *fakeBatchService) Submit(ctx context.Context, spec batch.Spec) (batch.Job, error) {
+	if spec.Bucket == "" {
+		return batch.Job{}, fmt.Errorf("%w: bucket is required", batch.ErrInvalidSpec)
+	}
+	f.spec = spec
+	j := batch.Job{ID: "batch-1-1", Operation: spec.Operation, Bucket: spec.Bucket, State: batch.StateQueued, Total: len(spec.Keys)}
+	f.jobs[j.ID] = j
+	return j, nil
+}
+
+func (f *fakeBatchService) Get(id string) (batch.Job, bool) {
+	j, ok := f.jobs[id]
+	return j, ok
+}
+
+func (f *fakeBatchService) List() []batch.Job {
+	var out []batch.Job
+	for _, j := range f.jobs {
+		out = append(out, j)
+	}
+	return out
+}
+
+func (f *fakeBatchService) Cancel(id string) (batch.Job, error) {
+	j, ok := f.jobs[id]
+	switch {
+	case !ok:
+		return batch.Job{}, batch.ErrNotFound
+	case j.State != batch.StateQueued:
+		return j, batch.ErrFinished
+	}
+	j.State = batch.StateCancelled
+	f.jobs[id] = j
+	return j, nil
+}
+
+func TestBatchHandlers(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	svc := &fakeBatchService{jobs: map[string]batch.Job{}}
+	mux := http.NewServeMux()
+	RegisterBatchAdminRoutes(mux, svc, logger)
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/admin/batch/jobs", `{"operation":"retag","bucket":"b","keys":["k1","k2"],"tags":{"class":"cold"}}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("submit = %d: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Job batch.Job `json:"job"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Job.ID != "batch-1-1" || body.Job.Total != 2 || svc.spec.Tags["class"] != "cold" {
+		t.Errorf("submit response %s, spec %+v", rec.Body, svc.spec)
+	}
+
+	if rec := do(http.MethodGet, "/admin/batch/jobs/batch-1-1", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"state":"queued"`) {
+		t.Errorf("get = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodGet, "/admin/batch/jobs", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "batch-1-1") {
+		t.Errorf("list = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodDelete, "/admin/batch/jobs/batch-1-1", ""); rec.Code != http.StatusOK {
+		t.Errorf("cancel = %d: %s", rec.Code, rec.Body)
+	}
+
+	for _, tc := range []struct {
+		method, target, body string
+		status               int
+	}{
+		{http.MethodPost, "/admin/batch/jobs", `{"operation":"verify","keys":["k"]}`, http.StatusBadRequest},
+		{http.MethodPost, "/admin/batch/jobs", `{"operation":"verify","bucket":"b","unknown":1}`, http.StatusBadRequest},
+		{http.MethodPut, "/admin/batch/jobs", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/admin/batch/jobs/batch-9-9", "", http.StatusNotFound},
+		{http.MethodDelete, "/admin/batch/jobs/batch-9-9", "", http.StatusNotFound},
+		{http.MethodDelete, "/admin/batch/jobs/batch-1-1", "", http.StatusConflict},
+		{http.MethodPost, "/admin/batch/jobs/batch-1-1", "", http.StatusMethodNotAllowed},
+	} {
+		if rec := do(tc.method, tc.target, tc.body); rec.Code != tc.status {
+			t.Errorf("%s %s = %d, want %d", tc.method, tc.target, rec.Code, tc.status)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/featureflag"
+)
+
+// FeatureFlagService is the subset of featureflag.Set used by the feature
+// flag endpoints.
+type FeatureFlagService interface {
+	List() []featureflag.State
+	Override(flag string, f config.FeatureFlag) error
+	ClearOverride(flag string) bool
+}
+
+// RegisterFeatureFlagAdminRoutes mounts the feature flag endpoints.
+//
+//	GET    /admin/flags         — configured rule, override and effective rule of every flag
+//	PUT    /admin/flags/{name}  — override a flag; body {"enabled":true,"buckets":[...],"percentage":N}
+//	DELETE /admin/flags/{name}  — drop the override and return to the configured rule
+//
+// Overrides apply to this instance only and are lost on restart.
+func RegisterFeatureFlagAdminRoutes(muxSrv *http.ServeMux, svc FeatureFlagService, logger *logrus.Logger) {
+	muxSrv.HandleFunc("/admin/flags", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "GET required")
+			return
+		}
+		writeFeatureFlagJSON(w, map[string]interface{}{"flags": svc.List()})
+	})
+
+	muxSrv.HandleFunc("/admin/flags/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/admin/flags/")
+		if !featureflag.Known(name) {
+			writeAdminError(w, http.StatusNotFound, "NoSuchFeatureFlag", "unknown feature flag "+name)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			var f config.FeatureFlag
+			dec := json.NewDecoder(io.LimitReader(r.Body, 64<<10))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&f); err != nil {
+				writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "invalid feature flag rule: "+err.Error())
+				return
+			}
+			if err := svc.Override(name, f); err != nil {
+				writeAdminError(w, http.StatusBadRequest, "InvalidArgument", err.Error())
+				return
+			}
+			logger.WithFields(logrus.Fields{
+				"flag":       name,
+				"enabled":    f.Enabled,
+				"buckets":    f.Buckets,
+				"percentage": f.Percentage,
+			}).Warn("admin/flags: feature flag overridden")
+			writeFeatureFlagJSON(w, map[string]interface{}{"flag": name, "override": f})
+		case http.MethodDelete:
+			if !svc.ClearOverride(name) {
+				writeAdminError(w, http.StatusNotFound, "NoSuchOverride", "feature flag "+name+" is not overridden")
+				return
+			}
+			logger.WithField("flag", name).Warn("admin/flags: feature flag override cleared")
+			writeFeatureFlagJSON(w, map[string]interface{}{"flag": name, "cleared": true})
+		default:
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "PUT or DELETE required")
+		}
+	})
+}
+
+func writeFeatureFlagJSON(w http.ResponseWriter, body map[string]interface{}) {
+	body["timestamp"] = time.Now().UTC().Format(time.RFC3339)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,78 @@
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/featureflag"
+)
+
+func TestFeatureFlagHandler_OverrideAndClear(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	flags, err := featureflag.New(map[string]config.FeatureFlag{featureflag.ParallelRangeFetch: {Enabled: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	RegisterFeatureFlagAdminRoutes(mux, flags, logger)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := do(http.MethodPut, "/admin/flags/parallel_range_fetch", `{"enabled":true,"buckets":["canary"],"percentage":10}`); rec.Code != http.StatusOK {
+		t.Fatalf("override = %d: %s", rec.Code, rec.Body)
+	}
+	if flags.Enabled(featureflag.ParallelRangeFetch, "prod", "k") {
+		t.Error("override not applied")
+	}
+
+	rec := do(http.MethodGet, "/admin/flags", "")
+	var body struct {
+		Flags     []featureflag.State `json:"flags"`
+		Timestamp string              `json:"timestamp"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || len(body.Flags) != 1 || body.Flags[0].Override == nil || body.Timestamp == "" {
+		t.Fatalf("list = %s (%v)", rec.Body, err)
+	}
+
+	if rec := do(http.MethodDelete, "/admin/flags/parallel_range_fetch", ""); rec.Code != http.StatusOK {
+		t.Errorf("clear = %d: %s", rec.Code, rec.Body)
+	}
+	if !flags.Enabled(featureflag.ParallelRangeFetch, "prod", "k") {
+		t.Error("configured rule not restored")
+	}
+}
+
+func TestFeatureFlagHandler_Errors(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	flags, _ := featureflag.New(nil)
+	mux := http.NewServeMux()
+	RegisterFeatureFlagAdminRoutes(mux, flags, logger)
+	for _, tc := range []struct {
+		method, target, body string
+		want                 int
+	}{
+		{http.MethodPut, "/admin/flags/no_such_flag", `{"enabled":true}`, http.StatusNotFound},
+		{http.MethodPut, "/admin/flags/parallel_range_fetch", `{"enabled":true,"percentage":150}`, http.StatusBadRequest},
+		{http.MethodPut, "/admin/flags/parallel_range_fetch", `{"enable":true}`, http.StatusBadRequest},
+		{http.MethodDelete, "/admin/flags/parallel_range_fetch", "", http.StatusNotFound},
+		{http.MethodPost, "/admin/flags", "", http.StatusMethodNotAllowed},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body)))
+		if rec.Code != tc.want {
+			t.Errorf("%s %s = %d, want %d", tc.method, tc.target, rec.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/journal"
+)
+
+// JournalStatusSource is the subset of journal.Journal used by the admin handler.
+type JournalStatusSource interface {
+	Status() journal.Status
+}
+
+// RegisterJournalAdminRoutes mounts the write-ahead journal status endpoint.
+//
+//	GET /admin/journal — in-flight count, unresolved writes and the last
+//	                     startup recovery report
+func RegisterJournalAdminRoutes(muxSrv *http.ServeMux, src JournalStatusSource) {
+	muxSrv.HandleFunc("/admin/journal", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "GET required")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(src.Status())
+	})
+}
@@ -0,0 +1,45 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/journal"
+)
+
+type fakeJournalStatus struct{ st journal.Status }
+
+func (f fakeJournalStatus) Status() journal.Status { return f.st }
+
+func TestRegisterJournalAdminRoutes(t *testing.T) {
+	mux := http.NewServeMux()
+	RegisterJournalAdminRoutes(mux, fakeJournalStatus{st: journal.Status{
+		Path:     "/tmp/j.log",
+		InFlight: 2,
+		LastRecovery: []journal.Resolution{{
+			Record: journal.Record{ID: "x", Operation: "PutObject", Bucket: "b", Key: "k"},
+			State:  journal.StateInconsistent,
+		}},
+	}})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/journal", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var got journal.Status
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.InFlight != 2 || len(got.LastRecovery) != 1 || got.LastRecovery[0].State != journal.StateInconsistent {
+		t.Errorf("unexpected status: %+v", got)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/journal", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
@@ -0,0 +1,120 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/keyhold"
+)
+
+// KeyHoldService is the subset of api.Handler used by the key hold
+// endpoints.
+type KeyHoldService interface {
+	ListKeyHolds(ctx context.Context) (keyhold.Holds, error)
+	PlaceKeyHold(ctx context.Context, bucket, key, prefix, reason string) (keyhold.Hold, error)
+	ReleaseKeyHold(ctx context.Context, bucket, key, prefix string) (bool, error)
+	KeyHoldInventory(ctx context.Context) (keyhold.Inventory, error)
+}
+
+// RegisterKeyHoldAdminRoutes mounts the key hold endpoints.
+//
+//	GET    /admin/keyhold                                   — list holds
+//	POST   /admin/keyhold?bucket=&key=|prefix=[&reason=]    — place a hold
+//	DELETE /admin/keyhold?bucket=&key=|prefix=              — release a hold
+//	GET    /admin/keyhold/inventory                         — held objects and
+//	       the wrapping key versions they depend on
+//
+// A held object is never shredded, and a key version a held object depends
+// on cannot be retired. A change that races another instance's returns 409
+// and can be retried.
+func RegisterKeyHoldAdminRoutes(muxSrv *http.ServeMux, svc KeyHoldService, logger *logrus.Logger) {
+	muxSrv.HandleFunc("/admin/keyhold", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			holds, err := svc.ListKeyHolds(r.Context())
+			if err != nil {
+				logger.WithError(err).Error("admin/keyhold: list failed")
+				writeAdminError(w, http.StatusInternalServerError, "InternalError", "failed to read key holds")
+				return
+			}
+			if holds == nil {
+				holds = keyhold.Holds{}
+			}
+			writeKeyHoldJSON(w, http.StatusOK, map[string]interface{}{"holds": holds})
+			return
+		}
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "GET, POST or DELETE required")
+			return
+		}
+		q := r.URL.Query()
+		bucket, key, prefix := q.Get("bucket"), q.Get("key"), q.Get("prefix")
+		if bucket == "" {
+			writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "bucket is required")
+			return
+		}
+		if (key == "") == (prefix == "") {
+			writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "exactly one of key and prefix is required")
+			return
+		}
+		fields := logrus.Fields{"bucket": bucket, "key": key, "prefix": prefix}
+
+		if r.Method == http.MethodPost {
+			hold, err := svc.PlaceKeyHold(r.Context(), bucket, key, prefix, q.Get("reason"))
+			if err != nil {
+				writeKeyHoldError(w, logger.WithFields(fields), "place", err)
+				return
+			}
+			logger.WithFields(fields).WithField("reason", hold.Reason).Warn("admin/keyhold: hold placed")
+			writeKeyHoldJSON(w, http.StatusOK, map[string]interface{}{"hold": hold})
+			return
+		}
+
+		released, err := svc.ReleaseKeyHold(r.Context(), bucket, key, prefix)
+		if err != nil {
+			writeKeyHoldError(w, logger.WithFields(fields), "release", err)
+			return
+		}
+		if !released {
+			writeAdminError(w, http.StatusNotFound, "NoSuchKeyHold", "no hold on that key or prefix")
+			return
+		}
+		logger.WithFields(fields).Warn("admin/keyhold: hold released")
+		writeKeyHoldJSON(w, http.StatusOK, map[string]interface{}{"released": true})
+	})
+
+	muxSrv.HandleFunc("/admin/keyhold/inventory", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "GET required")
+			return
+		}
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		inv, err := svc.KeyHoldInventory(r.Context())
+		if err != nil {
+			logger.WithError(err).Error("admin/keyhold: inventory failed")
+			writeAdminError(w, http.StatusInternalServerError, "InternalError", err.Error())
+			return
+		}
+		writeKeyHoldJSON(w, http.StatusOK, map[string]interface{}{"inventory": inv})
+	})
+}
+
+func writeKeyHoldError(w http.ResponseWriter, log *logrus.Entry, op string, err error) {
+	if errors.Is(err, keyhold.ErrConflict) {
+		writeAdminError(w, http.StatusConflict, "KeyHoldConflict", err.Error())
+		return
+	}
+	log.WithError(err).Errorf("admin/keyhold: %s failed", op)
+	writeAdminError(w, http.StatusInternalServerError, "InternalError", "failed to "+op+" key hold")
+}
+
+func writeKeyHoldJSON(w http.ResponseWriter, status int, body map[string]interface{}) {
+	body["timestamp"] = time.Now().UTC().Format(time.RFC3339)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,119 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/keyhold"
+)
+
+type fakeKeyHoldService struct {
+	holds keyhold.Holds
+	err   error
+}
+
+func (f *fakeKeyHoldService) ListKeyHolds(context.Context) (keyhold.Holds, error) {
+	return f.holds, f.err
+}
+
+func (f *fakeKeyHoldService) PlaceKeyHold(_ context.Context, bucket, key, prefix, reason string) (keyhold.Hold, error) {
+	h := keyhold.Hold{Bucket: bucket, Key: key, Prefix: prefix, Reason: reason}
+	f.holds = append(f.holds, h)
+	return h, f.err
+}
+
+func (f *fakeKeyHoldService) ReleaseKeyHold(_ context.Context, bucket, key, prefix string) (bool, error) {
+	for i, h := range f.holds {
+		if h.Bucket == bucket && h.Key == key && h.Prefix == prefix {
+			f.holds = append(f.holds[:i], f.holds[i+1:]...)
+			return true, f.err
+		}
+	}
+	return false, f.err
+}
+
+func (f *fakeKeyHoldService) KeyHoldInventory(context.Context) (keyhold.Inventory, error) {
+	return keyhold.Inventory{KeyVersions: map[int]int{3: 2}}, f.err
+}
+
+func newKeyHoldTestMux() (*http.ServeMux, *fakeKeyHoldService) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	svc := &fakeKeyHoldService{}
+	mux := http.NewServeMux()
+	RegisterKeyHoldAdminRoutes(mux, svc, logger)
+	return mux, svc
+}
+
+func TestKeyHoldHandler_PlaceListRelease(t *testing.T) {
+	mux, svc := newKeyHoldTestMux()
+	do := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/admin/keyhold?bucket=b&prefix=case/&reason=litigation"); rec.Code != http.StatusOK {
+		t.Fatalf("place = %d: %s", rec.Code, rec.Body)
+	}
+	if len(svc.holds) != 1 || svc.holds[0].Reason != "litigation" {
+		t.Fatalf("holds = %+v", svc.holds)
+	}
+
+	rec := do(http.MethodGet, "/admin/keyhold")
+	var body struct {
+		Holds     keyhold.Holds `json:"holds"`
+		Timestamp string        `json:"timestamp"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || len(body.Holds) != 1 || body.Timestamp == "" {
+		t.Fatalf("list = %s (%v)", rec.Body, err)
+	}
+
+	if rec := do(http.MethodDelete, "/admin/keyhold?bucket=b&prefix=case/"); rec.Code != http.StatusOK {
+		t.Errorf("release = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodDelete, "/admin/keyhold?bucket=b&prefix=case/"); rec.Code != http.StatusNotFound {
+		t.Errorf("release twice = %d", rec.Code)
+	}
+
+	rec = do(http.MethodGet, "/admin/keyhold/inventory")
+	var inv struct {
+		Inventory keyhold.Inventory `json:"inventory"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &inv); err != nil || inv.Inventory.KeyVersions[3] != 2 {
+		t.Errorf("inventory = %s (%v)", rec.Body, err)
+	}
+}
+
+func TestKeyHoldHandler_Errors(t *testing.T) {
+	mux, svc := newKeyHoldTestMux()
+	for _, tc := range []struct {
+		method, target string
+		want           int
+	}{
+		{http.MethodPost, "/admin/keyhold?key=k", http.StatusBadRequest},
+		{http.MethodPost, "/admin/keyhold?bucket=b", http.StatusBadRequest},
+		{http.MethodPost, "/admin/keyhold?bucket=b&key=k&prefix=p/", http.StatusBadRequest},
+		{http.MethodPut, "/admin/keyhold?bucket=b&key=k", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/admin/keyhold/inventory", http.StatusMethodNotAllowed},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, nil))
+		if rec.Code != tc.want {
+			t.Errorf("%s %s = %d, want %d", tc.method, tc.target, rec.Code, tc.want)
+		}
+	}
+
+	svc.err = keyhold.ErrConflict
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/keyhold?bucket=b&key=k", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("conflicting place = %d, want 409", rec.Code)
+	}
+}
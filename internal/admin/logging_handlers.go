@@ -0,0 +1,121 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/logsample"
+)
+
+// LogLevelService is the subset of logsample.LevelControl used by the
+// logging endpoints.
+type LogLevelService interface {
+	State() logsample.LevelState
+	Override(level logrus.Level, d time.Duration) logsample.LevelState
+	Reset() logsample.LevelState
+}
+
+// LogSamplingService is the subset of logsample.Sampler used by the
+// logging endpoints.
+type LogSamplingService interface {
+	Rules() map[string]config.LogSamplingRule
+	SetRules(rules map[string]config.LogSamplingRule)
+}
+
+// RegisterLoggingAdminRoutes mounts the log level and sampling endpoints.
+//
+//	GET    /admin/logging           — current and configured level, sampling rules
+//	PUT    /admin/logging/level     — override the level; body {"level":"debug","duration":"15m"}
+//	DELETE /admin/logging/level     — return to the configured level
+//	PUT    /admin/logging/sampling  — replace the sampling rules; body {"access":{"every":100}}
+//
+// Without a duration an override lasts until it is reset or the process
+// restarts. Changes apply to this instance only.
+func RegisterLoggingAdminRoutes(muxSrv *http.ServeMux, levels LogLevelService, sampling LogSamplingService, logger *logrus.Logger) {
+	muxSrv.HandleFunc("/admin/logging", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "GET required")
+			return
+		}
+		writeLoggingJSON(w, map[string]interface{}{
+			"level":    levels.State(),
+			"sampling": sampling.Rules(),
+		})
+	})
+
+	muxSrv.HandleFunc("/admin/logging/level", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			var body struct {
+				Level    string `json:"level"`
+				Duration string `json:"duration"`
+			}
+			dec := json.NewDecoder(io.LimitReader(r.Body, 64<<10))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&body); err != nil {
+				writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "invalid request body: "+err.Error())
+				return
+			}
+			level, err := logrus.ParseLevel(body.Level)
+			if err != nil {
+				writeAdminError(w, http.StatusBadRequest, "InvalidArgument", err.Error())
+				return
+			}
+			var d time.Duration
+			if body.Duration != "" {
+				if d, err = time.ParseDuration(body.Duration); err != nil || d <= 0 {
+					writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "duration must be a positive duration such as 15m")
+					return
+				}
+			}
+			st := levels.Override(level, d)
+			logger.WithFields(logrus.Fields{
+				"level":    st.Level,
+				"duration": body.Duration,
+			}).Warn("admin/logging: log level overridden")
+			writeLoggingJSON(w, map[string]interface{}{"level": st})
+		case http.MethodDelete:
+			st := levels.Reset()
+			logger.WithField("level", st.Level).Warn("admin/logging: log level reset to configured level")
+			writeLoggingJSON(w, map[string]interface{}{"level": st})
+		default:
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "PUT or DELETE required")
+		}
+	})
+
+	muxSrv.HandleFunc("/admin/logging/sampling", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "PUT required")
+			return
+		}
+		var rules map[string]config.LogSamplingRule
+		dec := json.NewDecoder(io.LimitReader(r.Body, 64<<10))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&rules); err != nil {
+			writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "invalid sampling rules: "+err.Error())
+			return
+		}
+		for name, rule := range rules {
+			if err := rule.Validate(); err != nil {
+				writeAdminError(w, http.StatusBadRequest, "InvalidArgument", fmt.Sprintf("sampling rule %s: %v", name, err))
+				return
+			}
+		}
+		sampling.SetRules(rules)
+		logger.WithField("rules", rules).Warn("admin/logging: log sampling rules replaced")
+		writeLoggingJSON(w, map[string]interface{}{"sampling": sampling.Rules()})
+	})
+}
+
+func writeLoggingJSON(w http.ResponseWriter, body map[string]interface{}) {
+	body["timestamp"] = time.Now().UTC().Format(time.RFC3339)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,91 @@
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/logsample"
+)
+
+func TestLoggingHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(logrus.InfoLevel)
+	levels := logsample.NewLevelControl(logger, nil)
+	sampler := logsample.New(nil)
+	mux := http.NewServeMux()
+	RegisterLoggingAdminRoutes(mux, levels, sampler, logger)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := do(http.MethodPut, "/admin/logging/level", `{"level":"debug","duration":"15m"}`); rec.Code != http.StatusOK {
+		t.Fatalf("override = %d: %s", rec.Code, rec.Body)
+	}
+	if logger.GetLevel() != logrus.DebugLevel {
+		t.Errorf("level = %s", logger.GetLevel())
+	}
+	if rec := do(http.MethodPut, "/admin/logging/sampling", `{"access":{"every":100}}`); rec.Code != http.StatusOK {
+		t.Fatalf("sampling = %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := do(http.MethodGet, "/admin/logging", "")
+	var body struct {
+		Level    logsample.LevelState `json:"level"`
+		Sampling map[string]struct {
+			Every int `json:"every"`
+		} `json:"sampling"`
+		Timestamp string `json:"timestamp"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Timestamp == "" {
+		t.Fatalf("get = %s (%v)", rec.Body, err)
+	}
+	if body.Level.Level != "debug" || body.Level.Configured != "info" || body.Level.RevertAt == nil || body.Sampling["access"].Every != 100 {
+		t.Errorf("get = %s", rec.Body)
+	}
+
+	if rec := do(http.MethodDelete, "/admin/logging/level", ""); rec.Code != http.StatusOK {
+		t.Errorf("reset = %d: %s", rec.Code, rec.Body)
+	}
+	if logger.GetLevel() != logrus.InfoLevel {
+		t.Errorf("level after reset = %s", logger.GetLevel())
+	}
+}
+
+func TestLoggingHandler_Errors(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	mux := http.NewServeMux()
+	RegisterLoggingAdminRoutes(mux, logsample.NewLevelControl(logger, nil), logsample.New(nil), logger)
+
+	cases := []struct {
+		method, target, body string
+		want                 int
+	}{
+		{http.MethodPut, "/admin/logging/level", `{"level":"loud"}`, http.StatusBadRequest},
+		{http.MethodPut, "/admin/logging/level", `{"level":"debug","duration":"soon"}`, http.StatusBadRequest},
+		{http.MethodPut, "/admin/logging/level", `{"level":"debug","duration":"-1m"}`, http.StatusBadRequest},
+		{http.MethodPut, "/admin/logging/sampling", `{"access":{"every":0}}`, http.StatusBadRequest},
+		{http.MethodPut, "/admin/logging/sampling", `{"access":{"every":2,"level":"loud"}}`, http.StatusBadRequest},
+		{http.MethodPost, "/admin/logging", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/admin/logging/level", "", http.StatusMethodNotAllowed},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body)))
+		if rec.Code != tc.want {
+			t.Errorf("%s %s %s = %d, want %d", tc.method, tc.target, tc.body, rec.Code, tc.want)
+		}
+	}
+	if logger.GetLevel() != logrus.InfoLevel {
+		t.Errorf("rejected request changed the level to %s", logger.GetLevel())
+	}
+}
@@ -0,0 +1,133 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MoveResult lists the objects one move request touched, by source key.
+type MoveResult struct {
+	Moved []string `json:"moved"`
+	// Skipped objects no longer existed.
+	Skipped []string `json:"skipped,omitempty"`
+	// Conflicts already existed at the destination and were left alone.
+	Conflicts []string `json:"conflicts,omitempty"`
+	// Failed objects are unchanged at their source.
+	Failed []string `json:"failed,omitempty"`
+}
+
+// MoveRequest names what to move and where. Exactly one of Key and Prefix
+// is set, with DestKey or DestPrefix to match.
+type MoveRequest struct {
+	Bucket     string
+	Key        string
+	Prefix     string
+	DestBucket string
+	DestKey    string
+	DestPrefix string
+	Overwrite  bool
+}
+
+// MoveService is the subset of api.Handler used by the move endpoint.
+type MoveService interface {
+	MoveObjects(ctx context.Context, req MoveRequest) (MoveResult, error)
+}
+
+// RegisterMoveAdminRoutes mounts the object move endpoint.
+//
+//	POST /admin/move?bucket=&key=&dest_key=|prefix=&dest_prefix=
+//	     [&dest_bucket=][&overwrite=true] — rename one object or every
+//	     object under a prefix without decrypting it
+func RegisterMoveAdminRoutes(muxSrv *http.ServeMux, svc MoveService, logger *logrus.Logger) {
+	muxSrv.HandleFunc("/admin/move", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "POST required")
+			return
+		}
+		q := r.URL.Query()
+		req := MoveRequest{
+			Bucket:     q.Get("bucket"),
+			Key:        q.Get("key"),
+			Prefix:     q.Get("prefix"),
+			DestBucket: q.Get("dest_bucket"),
+			DestKey:    q.Get("dest_key"),
+			DestPrefix: q.Get("dest_prefix"),
+		}
+		if req.DestBucket == "" {
+			req.DestBucket = req.Bucket
+		}
+		if req.Bucket == "" {
+			writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "bucket is required")
+			return
+		}
+		switch {
+		case req.Key != "" && req.Prefix == "" && req.DestKey != "" && req.DestPrefix == "":
+			if req.Bucket == req.DestBucket && req.Key == req.DestKey {
+				writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "source and destination are the same object")
+				return
+			}
+		case req.Prefix != "" && req.Key == "" && req.DestPrefix != "" && req.DestKey == "":
+			// A destination inside the source would be listed and moved again.
+			if req.Bucket == req.DestBucket && (strings.HasPrefix(req.DestPrefix, req.Prefix) || strings.HasPrefix(req.Prefix, req.DestPrefix)) {
+				writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "prefix and dest_prefix must not overlap")
+				return
+			}
+		default:
+			writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "either key with dest_key or prefix with dest_prefix is required")
+			return
+		}
+		if v := q.Get("overwrite"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "overwrite must be a boolean")
+				return
+			}
+			req.Overwrite = b
+		}
+
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		res, err := svc.MoveObjects(r.Context(), req)
+		fields := logrus.Fields{
+			"bucket":      req.Bucket,
+			"dest_bucket": req.DestBucket,
+			"moved":       len(res.Moved),
+			"skipped":     len(res.Skipped),
+			"conflicts":   len(res.Conflicts),
+			"failed":      len(res.Failed),
+		}
+		body := map[string]interface{}{
+			"bucket":      req.Bucket,
+			"dest_bucket": req.DestBucket,
+			"result":      res,
+			"timestamp":   time.Now().UTC().Format(time.RFC3339),
+		}
+		if req.Key != "" {
+			body["key"], body["dest_key"] = req.Key, req.DestKey
+			fields["key"], fields["dest_key"] = req.Key, req.DestKey
+		} else {
+			body["prefix"], body["dest_prefix"] = req.Prefix, req.DestPrefix
+			fields["prefix"], fields["dest_prefix"] = req.Prefix, req.DestPrefix
+		}
+		status := http.StatusOK
+		if err != nil {
+			logger.WithError(err).WithFields(fields).Error("admin/move: move failed")
+			status = http.StatusInternalServerError
+			body["error"] = map[string]string{
+				"code":    "MoveFailed",
+				"message": err.Error(),
+			}
+		} else {
+			logger.WithFields(fields).Info("admin/move: objects moved")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	})
+}
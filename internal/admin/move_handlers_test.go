@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+type fakeMoveService struct {
+	req   MoveRequest
+	calls int
+}
+
+func (f *fakeMoveService) MoveObjects(ctx context.Context, req MoveRequest) (MoveResult, error) {
+	f.req = req
+	f.calls++
+	return MoveResult{Moved: []string{"2025/a"}, Conflicts: []string{"2025/b"}}, nil
+}
+
+func TestMoveHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	svc := &fakeMoveService{}
+	mux := http.NewServeMux()
+	RegisterMoveAdminRoutes(mux, svc, logger)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/move?bucket=b&prefix=2025/&dest_prefix=archive/2025/&overwrite=true", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	want := MoveRequest{Bucket: "b", Prefix: "2025/", DestBucket: "b", DestPrefix: "archive/2025/", Overwrite: true}
+	if svc.req != want {
+		t.Errorf("service called with %+v", svc.req)
+	}
+	var body struct {
+		DestPrefix string     `json:"dest_prefix"`
+		Result     MoveResult `json:"result"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.DestPrefix != "archive/2025/" || len(body.Result.Moved) != 1 || len(body.Result.Conflicts) != 1 {
+		t.Errorf("response = %s", rec.Body)
+	}
+
+	for _, tc := range []struct {
+		method, target string
+		status         int
+	}{
+		{http.MethodGet, "/admin/move?bucket=b&key=k&dest_key=l", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/admin/move?key=k&dest_key=l", http.StatusBadRequest},
+		{http.MethodPost, "/admin/move?bucket=b&key=k", http.StatusBadRequest},
+		{http.MethodPost, "/admin/move?bucket=b&key=k&dest_prefix=p/", http.StatusBadRequest},
+		{http.MethodPost, "/admin/move?bucket=b&key=k&dest_key=k", http.StatusBadRequest},
+		{http.MethodPost, "/admin/move?bucket=b&prefix=a/&dest_prefix=a/b/", http.StatusBadRequest},
+		{http.MethodPost, "/admin/move?bucket=b&key=k&dest_key=l&overwrite=maybe", http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, nil))
+		if rec.Code != tc.status {
+			t.Errorf("%s %s = %d, want %d", tc.method, tc.target, rec.Code, tc.status)
+		}
+	}
+	if svc.calls != 1 {
+		t.Errorf("service called %d times for invalid requests", svc.calls-1)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/move?bucket=b&key=k&dest_bucket=c&dest_key=k", nil))
+	if rec.Code != http.StatusOK || svc.req.DestBucket != "c" || svc.req.DestKey != "k" {
+		t.Errorf("move across buckets = %d, %+v", rec.Code, svc.req)
+	}
+}
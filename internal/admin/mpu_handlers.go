@@ -3,6 +3,7 @@ package admin
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -41,7 +42,7 @@ func RegisterMPUAdminRoutes(muxSrv *http.ServeMux, store MPUStateStore, abortFn
 		ctx := r.Context()
 		state, err := store.Get(ctx, uploadID)
 		if err != nil {
-			if isNotFound(err) {
+			if errors.Is(err, mpu.ErrUploadNotFound) {
 				writeAdminError(w, http.StatusNotFound, "NoSuchUpload", "upload not found in state store")
 			} else {
 				logger.WithError(err).WithField("uploadID", uploadID).Error("admin/mpu/abort: failed to get state")
@@ -104,11 +105,3 @@ func RegisterMPUAdminRoutes(muxSrv *http.ServeMux, store MPUStateStore, abortFn
 		_ = json.NewEncoder(w).Encode(resp)
 	})
 }
-
-func isNotFound(err error) bool {
-	if err == nil {
-		return false
-	}
-	return err == mpu.ErrUploadNotFound
-}
-
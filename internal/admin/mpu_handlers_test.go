@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -104,6 +105,17 @@ func TestMPUAdmin_Abort_NotFound(t *testing.T) {
 	}
 }
 
+func TestMPUAdmin_Abort_WrappedNotFound(t *testing.T) {
+	mux, store, _ := newTestMPUMux()
+	store.getErr = fmt.Errorf("valkey get: %w", mpu.ErrUploadNotFound)
+	req := httptest.NewRequest("POST", "/admin/mpu/abort/upload-1", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d body=%s", w.Code, w.Body.String())
+	}
+}
+
 func TestMPUAdmin_Abort_MissingUploadID(t *testing.T) {
 	mux, _, _ := newTestMPUMux()
 	req := httptest.NewRequest("POST", "/admin/mpu/abort/", nil)
@@ -293,24 +305,3 @@ func (n *nilListStore) Delete(_ context.Context, _ string) error { return nil }
 func (n *nilListStore) List(_ context.Context) ([]mpu.UploadState, error) {
 	return nil, nil // deliberately return nil
 }
-
-// TestIsNotFound_Nil verifies isNotFound returns false for nil error.
-func TestIsNotFound_Nil(t *testing.T) {
-	if isNotFound(nil) {
-		t.Error("isNotFound(nil) should return false")
-	}
-}
-
-// TestIsNotFound_Other verifies isNotFound returns false for other errors.
-func TestIsNotFound_Other(t *testing.T) {
-	if isNotFound(errors.New("some other error")) {
-		t.Error("isNotFound(other) should return false")
-	}
-}
-
-// TestIsNotFound_ErrUploadNotFound verifies isNotFound returns true for ErrUploadNotFound.
-func TestIsNotFound_ErrUploadNotFound(t *testing.T) {
-	if !isNotFound(mpu.ErrUploadNotFound) {
-		t.Error("isNotFound(ErrUploadNotFound) should return true")
-	}
-}
@@ -0,0 +1,57 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/quota"
+)
+
+// QuotaReporter is the subset of quota.Manager used by the admin handler.
+type QuotaReporter interface {
+	Statuses() []quota.Status
+	Scan(ctx context.Context) error
+}
+
+// RegisterQuotaAdminRoutes mounts the quota report on the provided mux.
+//
+//	GET  /admin/quota       — limits, usage and remaining quota
+//	POST /admin/quota/scan  — re-measure every limited bucket now
+//
+// Usage counts stored (encrypted) bytes. A quota is "known" once each of
+// its buckets has been scanned.
+func RegisterQuotaAdminRoutes(muxSrv *http.ServeMux, quotas QuotaReporter) {
+	writeStatuses := func(w http.ResponseWriter) {
+		statuses := quotas.Statuses()
+		if statuses == nil {
+			statuses = []quota.Status{}
+		}
+		resp := map[string]interface{}{
+			"quotas":    statuses,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+	muxSrv.HandleFunc("/admin/quota", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "GET required")
+			return
+		}
+		writeStatuses(w)
+	})
+	muxSrv.HandleFunc("/admin/quota/scan", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "POST required")
+			return
+		}
+		if err := quotas.Scan(r.Context()); err != nil {
+			writeAdminError(w, http.StatusInternalServerError, "QuotaScanFailed", err.Error())
+			return
+		}
+		writeStatuses(w)
+	})
+}
@@ -0,0 +1,80 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/quota"
+)
+
+type fakeQuotas struct {
+	statuses []quota.Status
+	scans    int
+	scanErr  error
+}
+
+func (f *fakeQuotas) Statuses() []quota.Status { return f.statuses }
+
+func (f *fakeQuotas) Scan(ctx context.Context) error {
+	f.scans++
+	return f.scanErr
+}
+
+func TestRegisterQuotaAdminRoutes(t *testing.T) {
+	left := int64(24)
+	quotas := &fakeQuotas{statuses: []quota.Status{{
+		Scope:     quota.ScopeBucket,
+		Name:      "logs",
+		Buckets:   []string{"logs"},
+		Limit:     config.QuotaLimit{MaxObjects: 100},
+		Used:      quota.Usage{Bytes: 4096, Objects: 76},
+		Known:     true,
+		Remaining: quota.Remaining{Objects: &left},
+	}}}
+	mux := http.NewServeMux()
+	RegisterQuotaAdminRoutes(mux, quotas)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/quota", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Quotas []quota.Status `json:"quotas"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(body.Quotas) != 1 || body.Quotas[0].Name != "logs" || *body.Quotas[0].Remaining.Objects != 24 || body.Quotas[0].Remaining.Bytes != nil {
+		t.Errorf("unexpected body: %+v", body)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/quota/scan", nil))
+	if w.Code != http.StatusOK || quotas.scans != 1 {
+		t.Errorf("scan = %d, scans %d", w.Code, quotas.scans)
+	}
+
+	quotas.scanErr = errors.New("backend down")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/quota/scan", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("failed scan = %d", w.Code)
+	}
+
+	for _, tt := range []struct{ method, target string }{
+		{http.MethodPost, "/admin/quota"},
+		{http.MethodGet, "/admin/quota/scan"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s = %d, want 405", tt.method, tt.target, w.Code)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Role is a set of admin operations a bearer token may perform.
+type Role string
+
+const (
+	// RoleObserver may read status, metrics and reports but change nothing.
+	RoleObserver Role = "observer"
+	// RoleKeyOperator may also rotate and retire keys, shred objects and
+	// manage key holds.
+	RoleKeyOperator Role = "key_operator"
+	// RoleConfigAdmin may also change gateway state that is not key
+	// material: abort uploads, export and import objects, and profile the
+	// process.
+	RoleConfigAdmin Role = "config_admin"
+)
+
+// AllRoles is granted to the primary admin token.
+var AllRoles = []Role{RoleObserver, RoleKeyOperator, RoleConfigAdmin}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	return r == RoleObserver || r == RoleKeyOperator || r == RoleConfigAdmin
+}
+
+// RequiredRole returns the role a request to the admin mux needs. Key
+// operations need RoleKeyOperator; reads of status and reports need only
+// RoleObserver; everything else, including reads that return object
+// plaintext or process internals, needs RoleConfigAdmin.
+func RequiredRole(r *http.Request) Role {
+	path := r.URL.Path
+	read := r.Method == http.MethodGet || r.Method == http.MethodHead
+	switch {
+	case strings.HasPrefix(path, "/admin/export"), strings.HasPrefix(path, "/debug/pprof"), strings.HasPrefix(path, "/admin/debug/pprof"):
+		return RoleConfigAdmin
+	case read:
+		return RoleObserver
+	case strings.HasPrefix(path, "/admin/kms/"), path == "/admin/shred", strings.HasPrefix(path, "/admin/keyhold"):
+		return RoleKeyOperator
+	default:
+		return RoleConfigAdmin
+	}
+}
+
+// Credential is an admin bearer token and the roles it grants. Token is
+// called on every request so the token can be rotated at runtime.
+type Credential struct {
+	Token func() []byte
+	Roles []Role
+}
+
+// grants reports whether the credential covers role. Every role includes
+// the observer's reads, but key operators and config admins do not hold
+// each other's roles unless granted both, so a monitoring token can never
+// trigger a rotation and a config admin cannot shred.
+func (c Credential) grants(role Role) bool {
+	if role == RoleObserver && len(c.Roles) > 0 {
+		return true
+	}
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// RolesFromContext returns the roles of the token that authenticated the
+// admin request, or nil outside the admin API.
+func RolesFromContext(ctx context.Context) []Role {
+	roles, _ := ctx.Value(ctxKeyRoles).([]Role)
+	return roles
+}
+
+// RoleAuthMiddleware authenticates Authorization: Bearer <token> against
+// every credential and rejects with 403 a request that the matching token's
+// roles do not cover. Every credential is compared in constant time, so the
+// response time does not reveal which token, if any, came close.
+func RoleAuthMiddleware(creds []Credential, logger *logrus.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, ok := bearerToken(w, r)
+			if !ok {
+				return
+			}
+
+			var match *Credential
+			configured := false
+			for i := range creds {
+				want := creds[i].Token()
+				if len(want) == 0 {
+					continue
+				}
+				configured = true
+				if subtle.ConstantTimeCompare(got, want) == 1 && match == nil {
+					match = &creds[i]
+				}
+			}
+			if !configured {
+				logger.Error("admin: bearer token source returned empty token")
+				writeAdminError(w, http.StatusInternalServerError, "InternalError", "admin authentication misconfigured")
+				return
+			}
+			if match == nil {
+				writeAdminError(w, http.StatusUnauthorized, "Unauthorized", "invalid bearer token")
+				return
+			}
+
+			need := RequiredRole(r)
+			if !match.grants(need) {
+				logger.WithFields(logrus.Fields{
+					"method": r.Method,
+					"path":   r.URL.Path,
+					"needs":  need,
+					"roles":  match.Roles,
+				}).Warn("admin: request denied by role")
+				writeAdminError(w, http.StatusForbidden, "Forbidden", "this token lacks the "+string(need)+" role")
+				return
+			}
+			ctx := context.WithValue(r.Context(), ctxKeyRoles, match.Roles)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// roleTokenFile caches a role token read from a file, like the primary
+// token's cache in Server.
+type roleTokenFile struct {
+	path   string
+	role   Role
+	mu     sync.RWMutex
+	cached []byte
+}
+
+// load reads the token, refusing a file readable by group or others.
+func (f *roleTokenFile) load(logger *logrus.Logger) {
+	entry := logger.WithField("path", f.path)
+	info, err := os.Stat(f.path)
+	if err != nil {
+		entry.WithError(err).Warn("admin: failed to stat role token file")
+		return
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		entry.WithField("mode", info.Mode().Perm().String()).Error("admin: role token file has overly permissive permissions; token disabled")
+		f.set(nil)
+		return
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		entry.WithError(err).Warn("admin: failed to read role token file")
+		return
+	}
+	f.set([]byte(strings.TrimSpace(string(data))))
+}
+
+func (f *roleTokenFile) set(token []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cached = token
+}
+
+// clear zeroes the cached token on shutdown.
+func (f *roleTokenFile) clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	zeroBytes(f.cached)
+	f.cached = nil
+}
+
+func (f *roleTokenFile) token() []byte {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.cached
+}
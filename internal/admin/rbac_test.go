@@ -0,0 +1,124 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+func TestRequiredRole(t *testing.T) {
+	for _, tc := range []struct {
+		method, path string
+		want         Role
+	}{
+		{http.MethodGet, "/admin/kms/rotate/status", RoleObserver},
+		{http.MethodGet, "/admin/keyhold/inventory", RoleObserver},
+		{http.MethodGet, "/metrics", RoleObserver},
+		{http.MethodPost, "/admin/kms/rotate/start", RoleKeyOperator},
+		{http.MethodPost, "/admin/kms/retire", RoleKeyOperator},
+		{http.MethodPost, "/admin/shred", RoleKeyOperator},
+		{http.MethodDelete, "/admin/keyhold", RoleKeyOperator},
+		{http.MethodGet, "/admin/export", RoleConfigAdmin},
+		{http.MethodGet, "/debug/pprof/heap", RoleConfigAdmin},
+		{http.MethodPost, "/admin/import", RoleConfigAdmin},
+		{http.MethodPost, "/admin/mpu/abort/u1", RoleConfigAdmin},
+	} {
+		if got := RequiredRole(httptest.NewRequest(tc.method, tc.path, nil)); got != tc.want {
+			t.Errorf("%s %s = %s, want %s", tc.method, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestRoleAuthMiddleware(t *testing.T) {
+	tokens := map[string]string{}
+	var creds []Credential
+	for _, c := range []struct {
+		name  string
+		roles []Role
+	}{
+		{"primary", AllRoles},
+		{"observer", []Role{RoleObserver}},
+		{"operator", []Role{RoleKeyOperator}},
+		{"config", []Role{RoleConfigAdmin}},
+	} {
+		tok := randomToken(t)
+		tokens[c.name] = tok
+		creds = append(creds, Credential{Token: func() []byte { return []byte(tok) }, Roles: c.roles})
+	}
+	var seenRoles []Role
+	handler := RoleAuthMiddleware(creds, testLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenRoles = RolesFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tc := range []struct {
+		token, method, path string
+		want                int
+	}{
+		{"observer", http.MethodGet, "/admin/kms/rotate/status", http.StatusOK},
+		{"observer", http.MethodPost, "/admin/kms/rotate/start", http.StatusForbidden},
+		{"observer", http.MethodGet, "/admin/export", http.StatusForbidden},
+		{"operator", http.MethodPost, "/admin/kms/rotate/start", http.StatusOK},
+		{"operator", http.MethodGet, "/admin/slo", http.StatusOK},
+		{"operator", http.MethodPost, "/admin/import", http.StatusForbidden},
+		{"config", http.MethodPost, "/admin/import", http.StatusOK},
+		{"config", http.MethodPost, "/admin/shred", http.StatusForbidden},
+		{"primary", http.MethodPost, "/admin/shred", http.StatusOK},
+		{"primary", http.MethodGet, "/debug/pprof/heap", http.StatusOK},
+		{"unknown", http.MethodGet, "/admin/slo", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		tok := tokens[tc.token]
+		if tok == "" {
+			tok = randomToken(t)
+		}
+		req.Header.Set("Authorization", "Bearer "+tok)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: %s %s = %d, want %d", tc.token, tc.method, tc.path, rec.Code, tc.want)
+		}
+	}
+	if len(seenRoles) != len(AllRoles) {
+		t.Errorf("roles in context = %v", seenRoles)
+	}
+}
+
+func TestServer_BuildCredentials_RoleTokens(t *testing.T) {
+	dir := t.TempDir()
+	observer := filepath.Join(dir, "observer")
+	loose := filepath.Join(dir, "loose")
+	if err := os.WriteFile(observer, []byte("observer-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(loose, []byte("loose-token"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(config.AdminConfig{Auth: config.AdminAuthConfig{Tokens: []config.AdminRoleToken{
+		{Role: "observer", TokenFile: observer},
+		{Role: "config_admin", TokenFile: loose},
+	}}}, testLogger())
+
+	creds := s.buildCredentials()
+	if len(creds) != 3 {
+		t.Fatalf("credentials = %d, want primary plus two role tokens", len(creds))
+	}
+	if got := string(creds[1].Token()); got != "observer-token" || creds[1].Roles[0] != RoleObserver {
+		t.Errorf("observer credential = %q %v", got, creds[1].Roles)
+	}
+	if got := creds[2].Token(); got != nil {
+		t.Errorf("token from a permissive file was loaded: %q", got)
+	}
+
+	if err := os.WriteFile(observer, []byte("rotated-token"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s.roleTokens[0].load(testLogger())
+	if got := string(creds[1].Token()); got != "rotated-token" {
+		t.Errorf("after refresh = %q", got)
+	}
+}
@@ -0,0 +1,100 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/scheduler"
+	"github.com/sirupsen/logrus"
+)
+
+// SchedulerService is the subset of scheduler.Scheduler used by the admin
+// handlers.
+type SchedulerService interface {
+	List() []scheduler.Status
+	Get(name string) (scheduler.Status, bool)
+	Trigger(name string) (scheduler.Status, error)
+	Pause(name string) (scheduler.Status, error)
+	Resume(name string) (scheduler.Status, error)
+}
+
+// RegisterSchedulerAdminRoutes mounts the background job scheduler
+// endpoints.
+//
+//	GET  /admin/scheduler                — schedule, state and last pass of every job
+//	GET  /admin/scheduler/{job}          — one job
+//	POST /admin/scheduler/{job}/trigger  — start a pass now
+//	POST /admin/scheduler/{job}/pause    — stop scheduled passes
+//	POST /admin/scheduler/{job}/resume   — schedule passes again
+//
+// Pausing applies to this instance only and is lost on restart.
+func RegisterSchedulerAdminRoutes(muxSrv *http.ServeMux, svc SchedulerService, logger *logrus.Logger) {
+	muxSrv.HandleFunc("/admin/scheduler", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "GET required")
+			return
+		}
+		writeSchedulerJSON(w, map[string]interface{}{"jobs": svc.List()})
+	})
+
+	muxSrv.HandleFunc("/admin/scheduler/", func(w http.ResponseWriter, r *http.Request) {
+		name, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/admin/scheduler/"), "/")
+		if _, ok := svc.Get(name); !ok {
+			writeAdminError(w, http.StatusNotFound, "NoSuchJob", "no scheduled job "+name)
+			return
+		}
+		if action == "" {
+			if r.Method != http.MethodGet {
+				writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "GET required")
+				return
+			}
+			st, _ := svc.Get(name)
+			writeSchedulerJSON(w, map[string]interface{}{"job": st})
+			return
+		}
+
+		var act func(string) (scheduler.Status, error)
+		switch action {
+		case "trigger":
+			act = svc.Trigger
+		case "pause":
+			act = svc.Pause
+		case "resume":
+			act = svc.Resume
+		default:
+			writeAdminError(w, http.StatusNotFound, "NotFound", "unknown action "+action)
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "POST required")
+			return
+		}
+		st, err := act(name)
+		switch {
+		case errors.Is(err, scheduler.ErrRunning):
+			writeAdminError(w, http.StatusConflict, "JobRunning", "job "+name+" is already running")
+			return
+		case errors.Is(err, scheduler.ErrStopped):
+			writeAdminError(w, http.StatusServiceUnavailable, "ServiceUnavailable", err.Error())
+			return
+		case err != nil:
+			writeAdminError(w, http.StatusInternalServerError, "InternalError", err.Error())
+			return
+		}
+		logger.WithFields(logrus.Fields{
+			"job":    name,
+			"action": action,
+		}).Warn("admin/scheduler: job " + action + " requested")
+		writeSchedulerJSON(w, map[string]interface{}{"job": st, "action": action})
+	})
+}
+
+func writeSchedulerJSON(w http.ResponseWriter, body map[string]interface{}) {
+	body["timestamp"] = time.Now().UTC().Format(time.RFC3339)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(body)
+}
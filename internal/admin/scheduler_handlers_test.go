@@ -0,0 +1,104 @@
+package admin
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/scheduler"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeSchedulerService struct {
+	jobs    map[string]scheduler.Status
+	actions []string
+}
+
+func (f *fakeSchedulerService) List() []scheduler.Status {
+	return []scheduler.Status{f.jobs["trash"]}
+}
+
+func (f *fakeSchedulerService) Get(name string) (scheduler.Status, bool) {
+	st, ok := f.jobs[name]
+	return st, ok
+}
+
+func (f *fakeSchedulerService) Trigger(name string) (scheduler.Status, error) {
+	st := f.jobs[name]
+	if st.Running {
+		return st, scheduler.ErrRunning
+	}
+	f.actions = append(f.actions, "trigger "+name)
+	st.Running = true
+	f.jobs[name] = st
+	return st, nil
+}
+
+func (f *fakeSchedulerService) Pause(name string) (scheduler.Status, error) {
+	f.actions = append(f.actions, "pause "+name)
+	st := f.jobs[name]
+	st.Paused = true
+	f.jobs[name] = st
+	return st, nil
+}
+
+func (f *fakeSchedulerService) Resume(name string) (scheduler.Status, error) {
+	f.actions = append(f.actions, "resume "+name)
+	st := f.jobs[name]
+	st.Paused = false
+	f.jobs[name] = st
+	return st, nil
+}
+
+func TestSchedulerHandlers(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	svc := &fakeSchedulerService{jobs: map[string]scheduler.Status{
+		"trash": {Name: "trash", Schedule: "@hourly"},
+	}}
+	mux := http.NewServeMux()
+	RegisterSchedulerAdminRoutes(mux, svc, logger)
+
+	do := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	if rec := do(http.MethodGet, "/admin/scheduler"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"schedule":"@hourly"`) {
+		t.Errorf("list = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodGet, "/admin/scheduler/trash"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"name":"trash"`) {
+		t.Errorf("get = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPost, "/admin/scheduler/trash/pause"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"paused":true`) {
+		t.Errorf("pause = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPost, "/admin/scheduler/trash/resume"); rec.Code != http.StatusOK {
+		t.Errorf("resume = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPost, "/admin/scheduler/trash/trigger"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"running":true`) {
+		t.Errorf("trigger = %d: %s", rec.Code, rec.Body)
+	}
+
+	for _, tc := range []struct {
+		method, target string
+		status         int
+	}{
+		{http.MethodPost, "/admin/scheduler/trash/trigger", http.StatusConflict},
+		{http.MethodPost, "/admin/scheduler", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/admin/scheduler/trash/pause", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/admin/scheduler/trash", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/admin/scheduler/trash/restart", http.StatusNotFound},
+		{http.MethodPost, "/admin/scheduler/inventory/trigger", http.StatusNotFound},
+	} {
+		if rec := do(tc.method, tc.target); rec.Code != tc.status {
+			t.Errorf("%s %s = %d, want %d", tc.method, tc.target, rec.Code, tc.status)
+		}
+	}
+	if got := strings.Join(svc.actions, ","); got != "pause trash,resume trash,trigger trash" {
+		t.Errorf("actions = %s", got)
+	}
+}
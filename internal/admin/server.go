@@ -27,6 +27,8 @@ type contextKey int
 const (
 	// ctxKeyAdmin is set on requests arriving on the admin listener.
 	ctxKeyAdmin contextKey = iota
+	// ctxKeyRoles holds the roles of the token that authenticated the request.
+	ctxKeyRoles
 )
 
 // IsAdminRequest returns true if the request arrived on the admin listener.
@@ -54,6 +56,10 @@ type Server struct {
 	tokenCache []byte
 	tokenMu    sync.RWMutex
 
+	// roleTokens are the role-limited tokens from cfg.Auth.Tokens, cached
+	// and refreshed like tokenCache.
+	roleTokens []*roleTokenFile
+
 	// stopRefresh signals the periodic token refresh goroutine to exit.
 	stopRefresh     chan struct{}
 	stopRefreshOnce sync.Once
@@ -70,6 +76,9 @@ func NewServer(cfg config.AdminConfig, logger *logrus.Logger) *Server {
 		mux:         mux,
 		stopRefresh: make(chan struct{}),
 	}
+	for _, t := range cfg.Auth.Tokens {
+		s.roleTokens = append(s.roleTokens, &roleTokenFile{path: t.TokenFile, role: Role(t.Role)})
+	}
 	return s
 }
 
@@ -83,11 +92,11 @@ func (s *Server) Mux() *http.ServeMux {
 // cancelled or Shutdown is called.
 func (s *Server) Start(ctx context.Context) error {
 	// Build the handler chain: admin-context → bearer auth → rate limit → mux
-	tokenSource := s.buildTokenSource()
+	creds := s.buildCredentials()
 
 	// Launch periodic token refresh so that runtime rotation is supported
 	// without re-reading the file on every request (V1.0-SEC-22).
-	if s.cfg.Auth.TokenFile != "" {
+	if s.cfg.Auth.TokenFile != "" || len(s.roleTokens) > 0 {
 		go s.tokenRefreshLoop(s.cfg.Auth.TokenFile)
 	}
 
@@ -99,8 +108,8 @@ func (s *Server) Start(ctx context.Context) error {
 		handler = rl.Middleware(handler)
 	}
 
-	// Apply bearer authentication
-	handler = BearerAuthMiddleware(tokenSource, s.logger)(handler)
+	// Apply bearer authentication and role checks
+	handler = RoleAuthMiddleware(creds, s.logger)(handler)
 
 	// Set admin context flag on every request
 	handler = adminContextMiddleware(handler)
@@ -129,6 +138,7 @@ func (s *Server) Start(ctx context.Context) error {
 		"address":        boundAddr,
 		"tls":            s.cfg.TLS.Enabled,
 		"auth":           "bearer",
+		"role_tokens":    len(s.roleTokens),
 		"rate_limit_rpm": s.cfg.RateLimit.RequestsPerMinute,
 	}).Info("admin_api_enabled")
 
@@ -168,6 +178,9 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	zeroBytes(s.tokenCache)
 	s.tokenCache = nil
 	s.tokenMu.Unlock()
+	for _, rt := range s.roleTokens {
+		rt.clear()
+	}
 
 	s.mu.Lock()
 	hs := s.httpServer
@@ -254,6 +267,17 @@ func (s *Server) buildTokenSource() func() []byte {
 	}
 }
 
+// buildCredentials returns the primary token, granting every role, followed
+// by the role-limited tokens.
+func (s *Server) buildCredentials() []Credential {
+	creds := []Credential{{Token: s.buildTokenSource(), Roles: AllRoles}}
+	for _, rt := range s.roleTokens {
+		rt.load(s.logger)
+		creds = append(creds, Credential{Token: rt.token, Roles: []Role{rt.role}})
+	}
+	return creds
+}
+
 // refreshToken re-reads the token file, validates its permissions, and
 // updates the in-memory cache. It is called by the periodic refresh loop.
 func (s *Server) refreshToken(path string) {
@@ -287,7 +311,12 @@ func (s *Server) tokenRefreshLoop(path string) {
 	for {
 		select {
 		case <-ticker.C:
-			s.refreshToken(path)
+			if path != "" {
+				s.refreshToken(path)
+			}
+			for _, rt := range s.roleTokens {
+				rt.load(s.logger)
+			}
 		case <-s.stopRefresh:
 			return
 		}
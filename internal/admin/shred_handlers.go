@@ -0,0 +1,106 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ShredResult lists the objects one shred request touched.
+type ShredResult struct {
+	// Shredded objects had their data key destroyed, or would have in a
+	// dry run.
+	Shredded []string `json:"shredded"`
+	// Skipped objects were missing, not encrypted or already shredded.
+	Skipped []string `json:"skipped,omitempty"`
+	// Failed objects still hold their key material.
+	Failed []string `json:"failed,omitempty"`
+	// Held objects are covered by a key hold and were left alone.
+	Held []string `json:"held,omitempty"`
+}
+
+// ShredService is the subset of api.Handler used by the shred endpoint.
+type ShredService interface {
+	ShredObjects(ctx context.Context, bucket, key, prefix string, dryRun bool) (ShredResult, error)
+}
+
+// RegisterShredAdminRoutes mounts the crypto-shredding endpoint.
+//
+//	POST /admin/shred?bucket=&key=|prefix=[&dry_run=true] — destroy the data
+//	     keys of one object or of every object under a prefix
+//
+// Exactly one of key and prefix is required, so a whole bucket cannot be
+// shredded by leaving the prefix out. The ciphertext stays on the backend
+// until it is deleted or expires.
+func RegisterShredAdminRoutes(muxSrv *http.ServeMux, svc ShredService, logger *logrus.Logger) {
+	muxSrv.HandleFunc("/admin/shred", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "POST required")
+			return
+		}
+		q := r.URL.Query()
+		bucket, key, prefix := q.Get("bucket"), q.Get("key"), q.Get("prefix")
+		if bucket == "" {
+			writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "bucket is required")
+			return
+		}
+		if (key == "") == (prefix == "") {
+			writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "exactly one of key and prefix is required")
+			return
+		}
+		dryRun := false
+		if v := q.Get("dry_run"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				writeAdminError(w, http.StatusBadRequest, "InvalidArgument", "dry_run must be a boolean")
+				return
+			}
+			dryRun = b
+		}
+
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		res, err := svc.ShredObjects(r.Context(), bucket, key, prefix, dryRun)
+		fields := logrus.Fields{
+			"bucket":   bucket,
+			"key":      key,
+			"prefix":   prefix,
+			"dry_run":  dryRun,
+			"shredded": len(res.Shredded),
+			"skipped":  len(res.Skipped),
+			"failed":   len(res.Failed),
+			"held":     len(res.Held),
+		}
+		body := map[string]interface{}{
+			"bucket":    bucket,
+			"dry_run":   dryRun,
+			"result":    res,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		}
+		if key != "" {
+			body["key"] = key
+		} else {
+			body["prefix"] = prefix
+		}
+		status := http.StatusOK
+		if err != nil {
+			logger.WithError(err).WithFields(fields).Error("admin/shred: shred failed")
+			status = http.StatusInternalServerError
+			body["error"] = map[string]string{
+				"code":    "ShredFailed",
+				"message": err.Error(),
+			}
+		} else if dryRun {
+			logger.WithFields(fields).Info("admin/shred: dry run")
+		} else {
+			logger.WithFields(fields).Warn("admin/shred: key material destroyed")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	})
+}
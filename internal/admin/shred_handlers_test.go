@@ -0,0 +1,91 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+type fakeShredService struct {
+	bucket, key, prefix string
+	dryRun              bool
+	err                 error
+}
+
+func (f *fakeShredService) ShredObjects(ctx context.Context, bucket, key, prefix string, dryRun bool) (ShredResult, error) {
+	f.bucket, f.key, f.prefix, f.dryRun = bucket, key, prefix, dryRun
+	return ShredResult{Shredded: []string{"users/42/a"}, Failed: []string{"users/42/b"}}, f.err
+}
+
+func newShredTestMux() (*http.ServeMux, *fakeShredService) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	svc := &fakeShredService{}
+	mux := http.NewServeMux()
+	RegisterShredAdminRoutes(mux, svc, logger)
+	return mux, svc
+}
+
+func TestShredHandler_Prefix(t *testing.T) {
+	mux, svc := newShredTestMux()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/shred?bucket=b&prefix=users/42/&dry_run=true", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	if svc.bucket != "b" || svc.prefix != "users/42/" || svc.key != "" || !svc.dryRun {
+		t.Errorf("service called with %+v", svc)
+	}
+	var body struct {
+		Prefix string      `json:"prefix"`
+		DryRun bool        `json:"dry_run"`
+		Result ShredResult `json:"result"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Prefix != "users/42/" || !body.DryRun || len(body.Result.Shredded) != 1 || len(body.Result.Failed) != 1 {
+		t.Errorf("response = %s", rec.Body)
+	}
+}
+
+func TestShredHandler_Errors(t *testing.T) {
+	mux, svc := newShredTestMux()
+	for _, tc := range []struct {
+		method, target string
+		status         int
+	}{
+		{http.MethodGet, "/admin/shred?bucket=b&key=k", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/admin/shred?key=k", http.StatusBadRequest},
+		{http.MethodPost, "/admin/shred?bucket=b", http.StatusBadRequest},
+		{http.MethodPost, "/admin/shred?bucket=b&key=k&prefix=p", http.StatusBadRequest},
+		{http.MethodPost, "/admin/shred?bucket=b&key=k&dry_run=maybe", http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, nil))
+		if rec.Code != tc.status {
+			t.Errorf("%s %s = %d, want %d", tc.method, tc.target, rec.Code, tc.status)
+		}
+	}
+
+	svc.err = errors.New("list failed")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/shred?bucket=b&prefix=p", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+	var body struct {
+		Error  map[string]string `json:"error"`
+		Result ShredResult       `json:"result"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if body.Error["code"] != "ShredFailed" || len(body.Result.Shredded) != 1 {
+		t.Errorf("response = %s", rec.Body)
+	}
+}
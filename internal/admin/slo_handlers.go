@@ -0,0 +1,34 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/slo"
+)
+
+// SLOSummarizer is the subset of slo.Tracker used by the admin handler.
+type SLOSummarizer interface {
+	Summary() []slo.OperationSummary
+}
+
+// RegisterSLOAdminRoutes mounts the error-budget summary on the provided mux.
+//
+//	GET /admin/slo — per-operation targets, window counts and burn rates
+func RegisterSLOAdminRoutes(muxSrv *http.ServeMux, tracker SLOSummarizer) {
+	muxSrv.HandleFunc("/admin/slo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeAdminError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "GET required")
+			return
+		}
+
+		resp := map[string]interface{}{
+			"operations": tracker.Summary(),
+			"timestamp":  time.Now().UTC().Format(time.RFC3339),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
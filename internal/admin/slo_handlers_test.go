@@ -0,0 +1,52 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/slo"
+)
+
+type fakeSLOSummarizer struct {
+	summary []slo.OperationSummary
+}
+
+func (f *fakeSLOSummarizer) Summary() []slo.OperationSummary { return f.summary }
+
+func TestRegisterSLOAdminRoutes(t *testing.T) {
+	mux := http.NewServeMux()
+	RegisterSLOAdminRoutes(mux, &fakeSLOSummarizer{summary: []slo.OperationSummary{{
+		Operation: "GetObject",
+		Target:    0.999,
+		Windows:   []slo.WindowSummary{{Window: "5m", Total: 10, Bad: 1, ErrorRate: 0.1, BurnRate: 100}},
+	}}})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/slo", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var body struct {
+		Operations []slo.OperationSummary `json:"operations"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(body.Operations) != 1 || body.Operations[0].Windows[0].BurnRate != 100 {
+		t.Errorf("unexpected body: %+v", body)
+	}
+}
+
+func TestRegisterSLOAdminRoutes_MethodNotAllowed(t *testing.T) {
+	mux := http.NewServeMux()
+	RegisterSLOAdminRoutes(mux, &fakeSLOSummarizer{})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/slo", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
@@ -0,0 +1,285 @@
+// Package admission bounds the object requests the gateway serves at once.
+//
+// Encryption and decryption are CPU-bound, so past a point more concurrent
+// requests only make every request slower. A Controller hands out a fixed
+// number of slots; when they are all taken the gateway is saturated and new
+// requests wait in a bounded queue. Slots freed under saturation go to the
+// highest-priority waiter: metadata operations first, ordinary reads and
+// small writes next, large uploads last. A full queue makes room for a
+// higher-priority request by shedding the newest lower-priority waiter, and
+// requests that cannot wait are refused so the client backs off, instead
+// of latency climbing without bound.
+//
+// Traffic of buckets and credentials marked as priority bypasses all of
+// this: it is admitted at once, even past the slot limit, and only counts
+// towards the saturation other requests see.
+package admission
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+// ErrOverloaded is returned by Acquire when a request is shed.
+var ErrOverloaded = errors.New("admission: gateway overloaded")
+
+// Class is a request's priority; lower values are admitted first.
+type Class int
+
+// Request classes, highest priority first.
+const (
+	// ClassPriority covers priority buckets and credentials. It is never
+	// queued or shed.
+	ClassPriority Class = iota
+	// ClassMetadata covers HEAD, DELETE, bucket-level and sub-resource
+	// requests, which are cheap and often on a client's critical path.
+	ClassMetadata
+	// ClassNormal covers object reads and small writes.
+	ClassNormal
+	// ClassBulk covers large or unsized uploads.
+	ClassBulk
+
+	numClasses
+)
+
+// String returns the class's metric label.
+func (c Class) String() string {
+	switch c {
+	case ClassPriority:
+		return "priority"
+	case ClassMetadata:
+		return "metadata"
+	case ClassNormal:
+		return "normal"
+	default:
+		return "bulk"
+	}
+}
+
+// Recorder receives the controller's gauges and counters.
+type Recorder interface {
+	SetAdmissionInFlight(class string, n int)
+	SetAdmissionQueued(class string, n int)
+	RecordAdmissionAdmitted(class string)
+	RecordAdmissionRejection(class string)
+}
+
+// Stats is a snapshot of the controller.
+type Stats struct {
+	MaxInFlight int `json:"max_in_flight"`
+	// InFlight counts every admitted request, priority traffic included.
+	InFlight        int            `json:"in_flight"`
+	InFlightByClass map[string]int `json:"in_flight_by_class"`
+	Queued          map[string]int `json:"queued"`
+}
+
+// Waiter states.
+const (
+	waiting = iota
+	granted
+	shed
+)
+
+type waiter struct {
+	class Class
+	ready chan struct{}
+	state int
+}
+
+// Controller hands out request slots. A nil *Controller admits everything.
+type Controller struct {
+	maxInFlight int
+	queueSize   int
+	maxWait     time.Duration
+	recorder    Recorder
+
+	mu       sync.Mutex
+	inFlight int
+	byClass  [numClasses]int
+	queues   [numClasses][]*waiter
+	queued   int
+}
+
+// New returns the controller described by cfg, or nil when admission
+// control is disabled. recorder may be nil.
+func New(cfg config.AdmissionConfig, recorder Recorder) *Controller {
+	if !cfg.Enabled {
+		return nil
+	}
+	max := cfg.MaxInFlight
+	if max <= 0 {
+		max = 4 * runtime.GOMAXPROCS(0)
+	}
+	return &Controller{
+		maxInFlight: max,
+		queueSize:   cfg.QueueSize,
+		maxWait:     cfg.MaxWait,
+		recorder:    recorder,
+	}
+}
+
+// Acquire takes a slot for a request of class, waiting in the queue while
+// the gateway is saturated. It returns ErrOverloaded when the request is
+// shed, or ctx's error. Every successful Acquire must be paired with a
+// Release of the same class.
+func (c *Controller) Acquire(ctx context.Context, class Class) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	if class == ClassPriority || (c.inFlight < c.maxInFlight && c.queued == 0) {
+		c.admitLocked(class)
+		c.mu.Unlock()
+		return nil
+	}
+	if c.maxWait <= 0 || (c.queued >= c.queueSize && !c.shedLocked(class)) {
+		c.mu.Unlock()
+		c.reject(class)
+		return ErrOverloaded
+	}
+	w := &waiter{class: class, ready: make(chan struct{})}
+	c.queues[class] = append(c.queues[class], w)
+	c.queued++
+	c.recordQueuedLocked(class)
+	c.mu.Unlock()
+
+	timer := time.NewTimer(c.maxWait)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-w.ready:
+	case <-timer.C:
+		err = ErrOverloaded
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch w.state {
+	case granted:
+		// Granted while giving up; the caller owns the slot now.
+		return nil
+	case shed:
+		c.reject(class)
+		return ErrOverloaded
+	}
+	c.removeLocked(w)
+	if errors.Is(err, ErrOverloaded) {
+		c.reject(class)
+	}
+	return err
+}
+
+// Release frees a slot taken by Acquire for class and hands it to the
+// highest-priority waiter.
+func (c *Controller) Release(class Class) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+	c.byClass[class]--
+	c.recordInFlightLocked(class)
+	for c.inFlight < c.maxInFlight && c.queued > 0 {
+		for next := Class(0); next < numClasses; next++ {
+			q := c.queues[next]
+			if len(q) == 0 {
+				continue
+			}
+			w := q[0]
+			c.queues[next] = q[1:]
+			c.queued--
+			c.recordQueuedLocked(next)
+			c.admitLocked(next)
+			w.state = granted
+			close(w.ready)
+			break
+		}
+	}
+}
+
+// Stats returns a snapshot of the controller.
+func (c *Controller) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := Stats{
+		MaxInFlight:     c.maxInFlight,
+		InFlight:        c.inFlight,
+		InFlightByClass: make(map[string]int, numClasses),
+		Queued:          make(map[string]int, numClasses),
+	}
+	for class := Class(0); class < numClasses; class++ {
+		st.InFlightByClass[class.String()] = c.byClass[class]
+		st.Queued[class.String()] = len(c.queues[class])
+	}
+	return st
+}
+
+// shedLocked frees a queue place for a request of class by shedding the
+// newest waiter of the lowest class below it. It reports whether a place
+// was freed.
+func (c *Controller) shedLocked(class Class) bool {
+	for victim := numClasses - 1; victim > class; victim-- {
+		q := c.queues[victim]
+		if len(q) == 0 {
+			continue
+		}
+		w := q[len(q)-1]
+		c.queues[victim] = q[:len(q)-1]
+		c.queued--
+		c.recordQueuedLocked(victim)
+		w.state = shed
+		close(w.ready)
+		return true
+	}
+	return false
+}
+
+func (c *Controller) removeLocked(w *waiter) {
+	q := c.queues[w.class]
+	for i, qw := range q {
+		if qw == w {
+			c.queues[w.class] = append(q[:i], q[i+1:]...)
+			c.queued--
+			c.recordQueuedLocked(w.class)
+			return
+		}
+	}
+}
+
+func (c *Controller) admitLocked(class Class) {
+	c.inFlight++
+	c.byClass[class]++
+	c.recordInFlightLocked(class)
+	if c.recorder != nil {
+		c.recorder.RecordAdmissionAdmitted(class.String())
+	}
+}
+
+func (c *Controller) recordInFlightLocked(class Class) {
+	if c.recorder != nil {
+		c.recorder.SetAdmissionInFlight(class.String(), c.byClass[class])
+	}
+}
+
+func (c *Controller) recordQueuedLocked(class Class) {
+	if c.recorder != nil {
+		c.recorder.SetAdmissionQueued(class.String(), len(c.queues[class]))
+	}
+}
+
+func (c *Controller) reject(class Class) {
+	if c.recorder != nil {
+		c.recorder.RecordAdmissionRejection(class.String())
+	}
+}
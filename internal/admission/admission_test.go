@@ -0,0 +1,189 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+type fakeRecorder struct {
+	admitted   map[string]int
+	rejections map[string]int
+}
+
+func (f *fakeRecorder) SetAdmissionInFlight(string, int)  {}
+func (f *fakeRecorder) SetAdmissionQueued(string, int)    {}
+func (f *fakeRecorder) RecordAdmissionAdmitted(c string)  { f.admitted[c]++ }
+func (f *fakeRecorder) RecordAdmissionRejection(c string) { f.rejections[c]++ }
+
+func newController(maxInFlight, queueSize int, maxWait time.Duration) (*Controller, *fakeRecorder) {
+	rec := &fakeRecorder{admitted: map[string]int{}, rejections: map[string]int{}}
+	return New(config.AdmissionConfig{Enabled: true, MaxInFlight: maxInFlight, QueueSize: queueSize, MaxWait: maxWait}, rec), rec
+}
+
+// waitQueued blocks until n requests are queued.
+func waitQueued(t *testing.T, c *Controller, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c.mu.Lock()
+		queued := c.queued
+		c.mu.Unlock()
+		if queued == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d requests queued, want %d", queued, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNew_Disabled(t *testing.T) {
+	var c *Controller = New(config.AdmissionConfig{}, nil)
+	if c != nil {
+		t.Fatal("disabled config returned a controller")
+	}
+	if err := c.Acquire(context.Background(), ClassBulk); err != nil {
+		t.Fatalf("nil controller refused a request: %v", err)
+	}
+	c.Release(ClassBulk)
+}
+
+func TestAcquire_PriorityOrder(t *testing.T) {
+	c, _ := newController(1, 10, time.Minute)
+	ctx := context.Background()
+	if err := c.Acquire(ctx, ClassNormal); err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan Class, 3)
+	for i, class := range []Class{ClassBulk, ClassNormal, ClassMetadata} {
+		class := class
+		go func() {
+			if err := c.Acquire(ctx, class); err != nil {
+				t.Errorf("%s: %v", class, err)
+				return
+			}
+			order <- class
+			c.Release(class)
+		}()
+		waitQueued(t, c, i+1)
+	}
+
+	c.Release(ClassNormal)
+	for _, want := range []Class{ClassMetadata, ClassNormal, ClassBulk} {
+		if got := <-order; got != want {
+			t.Fatalf("admitted %s, want %s", got, want)
+		}
+	}
+	if st := c.Stats(); st.InFlight != 0 {
+		t.Errorf("in flight after all releases = %d", st.InFlight)
+	}
+}
+
+func TestAcquire_FullQueueShedsLowerClass(t *testing.T) {
+	c, rec := newController(1, 1, time.Minute)
+	ctx := context.Background()
+	if err := c.Acquire(ctx, ClassNormal); err != nil {
+		t.Fatal(err)
+	}
+
+	bulk := make(chan error, 1)
+	go func() { bulk <- c.Acquire(ctx, ClassBulk) }()
+	waitQueued(t, c, 1)
+
+	// Another bulk request has nobody to displace.
+	if err := c.Acquire(ctx, ClassBulk); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("bulk request on a full queue: %v, want ErrOverloaded", err)
+	}
+
+	meta := make(chan error, 1)
+	go func() { meta <- c.Acquire(ctx, ClassMetadata) }()
+	if err := <-bulk; !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("queued bulk request: %v, want ErrOverloaded after being shed", err)
+	}
+	waitQueued(t, c, 1)
+
+	c.Release(ClassNormal)
+	if err := <-meta; err != nil {
+		t.Fatalf("metadata request: %v", err)
+	}
+	c.Release(ClassMetadata)
+	if rec.rejections["bulk"] != 2 || rec.rejections["metadata"] != 0 {
+		t.Errorf("rejections = %v", rec.rejections)
+	}
+}
+
+func TestAcquire_Timeouts(t *testing.T) {
+	c, rec := newController(1, 10, 20*time.Millisecond)
+	ctx := context.Background()
+	if err := c.Acquire(ctx, ClassNormal); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Acquire(ctx, ClassNormal); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("wait past max_wait: %v, want ErrOverloaded", err)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := c.Acquire(cctx, ClassNormal); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled wait: %v, want context.Canceled", err)
+	}
+	if st := c.Stats(); st.Queued["normal"] != 0 || st.InFlight != 1 {
+		t.Errorf("stats after abandoned waits = %+v", st)
+	}
+	if rec.rejections["normal"] != 1 {
+		t.Errorf("rejections = %v, want one for the timeout only", rec.rejections)
+	}
+
+	noWait, _ := newController(1, 10, 0)
+	if err := noWait.Acquire(ctx, ClassMetadata); err != nil {
+		t.Fatal(err)
+	}
+	if err := noWait.Acquire(ctx, ClassMetadata); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("max_wait 0: %v, want ErrOverloaded at once", err)
+	}
+}
+
+func TestAcquire_PriorityBypassesQueue(t *testing.T) {
+	c, rec := newController(1, 1, time.Minute)
+	ctx := context.Background()
+	if err := c.Acquire(ctx, ClassBulk); err != nil {
+		t.Fatal(err)
+	}
+	normal := make(chan error, 1)
+	go func() { normal <- c.Acquire(ctx, ClassNormal) }()
+	waitQueued(t, c, 1)
+
+	// Saturated with a full queue, priority requests still go straight in.
+	for i := 0; i < 2; i++ {
+		if err := c.Acquire(ctx, ClassPriority); err != nil {
+			t.Fatalf("priority request %d: %v", i, err)
+		}
+	}
+	st := c.Stats()
+	if st.InFlight != 3 || st.InFlightByClass["priority"] != 2 || st.Queued["normal"] != 1 {
+		t.Errorf("stats with priority traffic = %+v", st)
+	}
+
+	// The queued request waits until in-flight drops below the limit.
+	c.Release(ClassBulk)
+	c.Release(ClassPriority)
+	select {
+	case err := <-normal:
+		t.Fatalf("normal request admitted past the limit: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	c.Release(ClassPriority)
+	if err := <-normal; err != nil {
+		t.Fatalf("normal request: %v", err)
+	}
+	c.Release(ClassNormal)
+	if rec.admitted["priority"] != 2 || rec.admitted["normal"] != 1 || len(rec.rejections) != 0 {
+		t.Errorf("admitted = %v, rejections = %v", rec.admitted, rec.rejections)
+	}
+}
@@ -0,0 +1,195 @@
+// Package airgap confines the gateway's outbound connections to the hosts an
+// air-gapped deployment allows: the backend, the KMS and whatever the
+// operator lists in air_gapped.allow.
+//
+// A Guard is installed process-wide. Every transport the gateway builds
+// dials through Wrap, and Install wraps http.DefaultTransport, so a
+// connection to any other host (a telemetry collector, a cloud provider's
+// instance metadata endpoint) fails with ErrBlocked before it is attempted.
+// Unix sockets and loopback addresses never leave the machine and are always
+// allowed. Name resolution is left to the system resolver.
+package airgap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrBlocked is returned for connections to hosts the installed Guard does
+// not allow.
+var ErrBlocked = errors.New("airgap: outbound connection not allowed")
+
+// DialFunc dials a network address, like net.Dialer.DialContext.
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Guard allows connections to a fixed set of hosts.
+type Guard struct {
+	hosts   map[string]bool
+	domains []string // ".example.com" for an "*.example.com" entry
+
+	// OnBlock, when set, is called with the address of every refused
+	// connection. Set it before Install.
+	OnBlock func(network, addr string)
+}
+
+// NewGuard returns a Guard allowing hosts. An entry is a host name or IP, a
+// host:port or a URL (the port is not checked), or "*.example.com" for every
+// subdomain of example.com.
+func NewGuard(hosts []string) *Guard {
+	g := &Guard{hosts: make(map[string]bool)}
+	for _, h := range hosts {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(h), "*."); ok {
+			if d := Host(rest); d != "" {
+				g.domains = append(g.domains, "."+d)
+			}
+			continue
+		}
+		if host := Host(h); host != "" {
+			g.hosts[host] = true
+		}
+	}
+	return g
+}
+
+// Allows reports whether host, as returned by Host, may be connected to.
+func (g *Guard) Allows(host string) bool {
+	if host == "" || isLoopback(host) || g.hosts[host] {
+		return true
+	}
+	for _, d := range g.domains {
+		if strings.HasSuffix(host, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hosts returns the allowed entries, sorted, for logging.
+func (g *Guard) Hosts() []string {
+	out := make([]string, 0, len(g.hosts)+len(g.domains))
+	for h := range g.hosts {
+		out = append(out, h)
+	}
+	for _, d := range g.domains {
+		out = append(out, "*"+d)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Dial returns next restricted to the hosts g allows. A nil next dials
+// with a zero net.Dialer.
+func (g *Guard) Dial(next DialFunc) DialFunc {
+	next = orDefault(next)
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if err := g.check(network, addr); err != nil {
+			return nil, err
+		}
+		return next(ctx, network, addr)
+	}
+}
+
+func (g *Guard) check(network, addr string) error {
+	if strings.HasPrefix(network, "unix") {
+		return nil
+	}
+	host := Host(addr)
+	if g.Allows(host) {
+		return nil
+	}
+	if g.OnBlock != nil {
+		g.OnBlock(network, addr)
+	}
+	return fmt.Errorf("%w: %s", ErrBlocked, host)
+}
+
+var (
+	active      atomic.Pointer[Guard]
+	defaultOnce sync.Once
+)
+
+// Install makes g the process-wide guard; nil removes it. The first call
+// also routes http.DefaultTransport, and the clients and clones built on it
+// later, through Wrap.
+func Install(g *Guard) {
+	active.Store(g)
+	if g == nil {
+		return
+	}
+	defaultOnce.Do(func() {
+		if tr, ok := http.DefaultTransport.(*http.Transport); ok {
+			tr.DialContext = Wrap(tr.DialContext)
+		}
+	})
+}
+
+// Active returns the installed guard, or nil.
+func Active() *Guard {
+	return active.Load()
+}
+
+// Wrap returns next checked against the guard installed at the time of
+// each dial, so a transport built before Install is covered as well. A nil
+// next dials with a zero net.Dialer, as http.Transport does.
+func Wrap(next DialFunc) DialFunc {
+	next = orDefault(next)
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if g := active.Load(); g != nil {
+			if err := g.check(network, addr); err != nil {
+				return nil, err
+			}
+		}
+		return next(ctx, network, addr)
+	}
+}
+
+// DialContext dials addr through the installed guard with a zero
+// net.Dialer. It can be used as http.Transport.DialContext.
+func DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return Wrap(nil)(ctx, network, addr)
+}
+
+// Host returns the lower-case host name or IP of addr, which may be a bare
+// host, a host:port or a URL. It returns "" for Unix socket addresses
+// ("unix:/path") and for addr "".
+func Host(addr string) string {
+	addr = strings.TrimSpace(addr)
+	if addr == "" || strings.HasPrefix(addr, "unix:") || strings.HasPrefix(addr, "/") {
+		return ""
+	}
+	if strings.Contains(addr, "://") {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return ""
+		}
+		return strings.ToLower(u.Hostname())
+	}
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		addr = h
+	}
+	return strings.ToLower(strings.Trim(addr, "[]"))
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func orDefault(next DialFunc) DialFunc {
+	if next != nil {
+		return next
+	}
+	var d net.Dialer
+	return d.DialContext
+}
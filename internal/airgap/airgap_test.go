@@ -0,0 +1,103 @@
+package airgap
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestHost(t *testing.T) {
+	tests := []struct {
+		addr, want string
+	}{
+		{"minio.internal", "minio.internal"},
+		{"MinIO.Internal:9000", "minio.internal"},
+		{"https://s3.example.com:8443/path", "s3.example.com"},
+		{"icap://scanner:1344/avscan", "scanner"},
+		{"[::1]:4317", "::1"},
+		{"10.0.0.5:6379", "10.0.0.5"},
+		{"unix:/var/run/clamd.sock", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Host(tt.addr); got != tt.want {
+			t.Errorf("Host(%q) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestGuard_Allows(t *testing.T) {
+	g := NewGuard([]string{"https://minio.internal:9000", "kms.internal:5696", "*.s3.internal", "10.0.0.5"})
+	for _, host := range []string{"minio.internal", "kms.internal", "bucket.s3.internal", "10.0.0.5", "localhost", "127.0.0.1", "::1"} {
+		if !g.Allows(host) {
+			t.Errorf("Allows(%q) = false", host)
+		}
+	}
+	for _, host := range []string{"s3.internal", "169.254.169.254", "metadata.google.internal", "otel.example.com", "evilminio.internal"} {
+		if g.Allows(host) {
+			t.Errorf("Allows(%q) = true", host)
+		}
+	}
+	want := []string{"*.s3.internal", "10.0.0.5", "kms.internal", "minio.internal"}
+	if got := g.Hosts(); !slices.Equal(got, want) {
+		t.Errorf("Hosts() = %q, want %q", got, want)
+	}
+}
+
+func TestGuard_Dial(t *testing.T) {
+	g := NewGuard([]string{"minio.internal"})
+	var blocked []string
+	g.OnBlock = func(_, addr string) { blocked = append(blocked, addr) }
+	var dialed []string
+	dial := g.Dial(func(_ context.Context, _, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		return nil, nil
+	})
+
+	ctx := context.Background()
+	if _, err := dial(ctx, "tcp", "minio.internal:9000"); err != nil {
+		t.Fatalf("allowed dial: %v", err)
+	}
+	if _, err := dial(ctx, "unix", "/var/run/clamd.sock"); err != nil {
+		t.Fatalf("unix dial: %v", err)
+	}
+	if _, err := dial(ctx, "tcp", "169.254.169.254:80"); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("metadata dial: err = %v, want ErrBlocked", err)
+	}
+	if !slices.Equal(dialed, []string{"minio.internal:9000", "/var/run/clamd.sock"}) {
+		t.Errorf("dialed = %q", dialed)
+	}
+	if !slices.Equal(blocked, []string{"169.254.169.254:80"}) {
+		t.Errorf("blocked = %q", blocked)
+	}
+}
+
+func TestInstall_DefaultTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	defer srv.Close()
+	defer Install(nil)
+
+	Install(NewGuard(nil))
+	if Active() == nil {
+		t.Fatal("Active() = nil after Install")
+	}
+	// Loopback stays reachable.
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("loopback GET: %v", err)
+	}
+	resp.Body.Close()
+	// Anything else is refused before it is dialed.
+	if _, err := http.Get("http://telemetry.example.com/"); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("outbound GET: err = %v, want ErrBlocked", err)
+	}
+
+	Install(nil)
+	if _, err := Wrap(func(context.Context, string, string) (net.Conn, error) { return nil, nil })(context.Background(), "tcp", "telemetry.example.com:80"); err != nil {
+		t.Fatalf("dial after Install(nil): %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/accessstats"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func TestAccessStats_CountsReadsAndHidesStatsObjects(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, _ := crypto.NewEngineWithOpts([]byte("test-password-123456"), nil, crypto.WithChunking(true))
+	client := testsupport.NewMemoryClient()
+	h := NewHandlerWithFeatures(client, engine, logger, getTestMetrics(), nil, nil, nil, &config.Config{}, nil)
+	tracker := accessstats.New(client, config.AccessStatsConfig{Enabled: true, Prefix: ".s3eg-access/", FlushInterval: time.Second}, nil)
+	h.WithAccessStats(tracker)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	putObject(t, srv, "/bucket/hot", []byte("hot"))
+	putObject(t, srv, "/bucket/cold", []byte("cold"))
+	get := func(path, rng string) {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		if rng != "" {
+			req.Header.Set("Range", rng)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	get("/bucket/hot", "")
+	get("/bucket/hot", "bytes=0-1")
+	get("/bucket/cold", "")
+	get("/bucket/missing", "")
+	if err := tracker.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	entries, err := tracker.Query(context.Background(), accessstats.Query{Bucket: "bucket"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reads := map[string]int64{}
+	for _, e := range entries {
+		reads[e.Key] = e.Reads
+	}
+	if len(reads) != 2 || reads["hot"] != 2 || reads["cold"] != 1 {
+		t.Errorf("unexpected read counts: %v", reads)
+	}
+
+	resp, err := http.Get(srv.URL + "/bucket?list-type=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if strings.Contains(string(body), ".s3eg-access") {
+		t.Errorf("stats objects must be hidden from listings:\n%s", body)
+	}
+
+	req, _ := http.NewRequest("DELETE", srv.URL+"/bucket/hot", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	entries, _ = tracker.Query(context.Background(), accessstats.Query{Bucket: "bucket"})
+	if len(entries) != 1 || entries[0].Key != "cold" {
+		t.Errorf("deleted object still tracked: %+v", entries)
+	}
+}
+
+func TestAccessStats_StatsObjectsRefusedThroughS3API(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	client := testsupport.NewMemoryClient()
+	h := NewHandlerWithFeatures(client, engine, logger, getTestMetrics(), nil, nil, nil, &config.Config{}, nil)
+	tracker := accessstats.New(client, config.AccessStatsConfig{Enabled: true, Prefix: ".s3eg-access/", FlushInterval: time.Second}, nil)
+	h.WithAccessStats(tracker)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	tracker.Record("bucket", "obj")
+	if err := tracker.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	listed, err := client.ListObjects(context.Background(), "bucket", ".s3eg-access/", s3.ListOptions{})
+	if err != nil || len(listed.Objects) != 1 {
+		t.Fatalf("expected one stats object, got %+v, %v", listed.Objects, err)
+	}
+	statsPath := "/bucket/" + listed.Objects[0].Key
+
+	for _, method := range []string{"PUT", "DELETE"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, statsPath, strings.NewReader("{}")))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s %s: expected 403, got %d", method, statsPath, w.Code)
+		}
+	}
+	entries, err := tracker.Query(context.Background(), accessstats.Query{Bucket: "bucket"})
+	if err != nil || len(entries) != 1 || entries[0].Key != "obj" {
+		t.Errorf("stats object changed through the S3 API: %+v, %v", entries, err)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,7 +10,9 @@ import (
 
 	"github.com/kenneth/s3-encryption-gateway/internal/admin"
 	"github.com/kenneth/s3-encryption-gateway/internal/audit"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
 	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/keyhold"
 	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
 	"github.com/sirupsen/logrus"
 )
@@ -20,6 +23,16 @@ type AdminRotationHandler struct {
 	logger       *logrus.Logger
 	metrics      *metrics.Metrics
 	auditLogger  audit.Logger
+	keyHolds     KeyHoldReporter // nil when key holds are disabled
+	dryRunCfg    config.AdminRotationConfig
+	peerClient   *http.Client
+	dryRuns      dryRunState
+}
+
+// KeyHoldReporter reports the wrapping key versions held objects depend on.
+// *Handler implements it.
+type KeyHoldReporter interface {
+	KeyHoldInventory(ctx context.Context) (keyhold.Inventory, error)
 }
 
 // NewAdminRotationHandler creates new rotation admin handlers.
@@ -37,12 +50,21 @@ func NewAdminRotationHandler(
 	}
 }
 
+// WithKeyHolds makes retirement refuse any version a held object's data key
+// is wrapped under.
+func (h *AdminRotationHandler) WithKeyHolds(r KeyHoldReporter) {
+	h.keyHolds = r
+}
+
 // RegisterRoutes mounts the rotation endpoints on the admin mux.
 func (h *AdminRotationHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /admin/kms/rotate/dry-run", h.handleRotateDryRun)
+	mux.HandleFunc("POST /admin/kms/rotate/canary", h.handleRotateCanary)
 	mux.HandleFunc("POST /admin/kms/rotate/start", h.handleRotateStart)
 	mux.HandleFunc("GET /admin/kms/rotate/status", h.handleRotateStatus)
 	mux.HandleFunc("POST /admin/kms/rotate/commit", h.handleRotateCommit)
 	mux.HandleFunc("POST /admin/kms/rotate/abort", h.handleRotateAbort)
+	mux.HandleFunc("POST /admin/kms/retire", h.handleRetire)
 }
 
 // --- Request/Response types ---
@@ -65,6 +87,16 @@ type rotateCommitRequest struct {
 	Force bool `json:"force,omitempty"`
 }
 
+type retireRequest struct {
+	Version int `json:"version"`
+}
+
+type retireResponse struct {
+	RetiredVersion int    `json:"retired_version"`
+	Provider       string `json:"provider"`
+	Timestamp      string `json:"timestamp"`
+}
+
 // --- Handlers ---
 
 func (h *AdminRotationHandler) handleRotateStart(w http.ResponseWriter, r *http.Request) {
@@ -76,7 +108,7 @@ func (h *AdminRotationHandler) handleRotateStart(w http.ResponseWriter, r *http.
 		return
 	}
 
-	rkm, ok := km.(crypto.RotatableKeyManager)
+	rkm, ok := crypto.KeyManagerAs[crypto.RotatableKeyManager](km)
 	if !ok {
 		admin.WriteAdminErrorWithRotation(w, http.StatusNotImplemented, "NotImplemented",
 			fmt.Sprintf("key manager %q does not support rotation", km.Provider()), "")
@@ -121,6 +153,13 @@ func (h *AdminRotationHandler) handleRotateStart(w http.ResponseWriter, r *http.
 		return
 	}
 
+	if h.dryRunCfg.RequireDryRun && !h.dryRunPassed(plan.TargetVersion) {
+		admin.WriteAdminErrorWithRotation(w, http.StatusConflict, "DryRunRequired",
+			fmt.Sprintf("no dry run for version %d has passed on this replica in the last %s", plan.TargetVersion, h.dryRunCfg.DryRunMaxAge), "")
+		h.recordMetric("start", "dry_run_required", start)
+		return
+	}
+
 	// Generate rotation ID
 	rotationID := fmt.Sprintf("rot-%d-%d-to-%d", time.Now().UnixMilli(), plan.CurrentVersion, plan.TargetVersion)
 
@@ -138,7 +177,7 @@ func (h *AdminRotationHandler) handleRotateStart(w http.ResponseWriter, r *http.
 	}
 
 	// Audit
-	h.auditRotation("key_rotation.start", rotationID, plan.CurrentVersion, plan.TargetVersion, km.Provider(), "")
+	h.auditRotation(r.Context(), "key_rotation.start", rotationID, plan.CurrentVersion, plan.TargetVersion, km.Provider(), "")
 
 	// Update active version metric
 	if h.metrics != nil {
@@ -180,7 +219,7 @@ func (h *AdminRotationHandler) handleRotateCommit(w http.ResponseWriter, r *http
 		return
 	}
 
-	rkm, ok := km.(crypto.RotatableKeyManager)
+	rkm, ok := crypto.KeyManagerAs[crypto.RotatableKeyManager](km)
 	if !ok {
 		admin.WriteAdminErrorWithRotation(w, http.StatusNotImplemented, "NotImplemented", "key manager does not support rotation", "")
 		h.recordMetric("commit", "unsupported", start)
@@ -220,14 +259,14 @@ func (h *AdminRotationHandler) handleRotateCommit(w http.ResponseWriter, r *http
 
 	if err := rkm.PromoteActiveVersion(r.Context(), plan); err != nil {
 		rs.MarkFailed(err)
-		h.auditRotation("key_rotation.commit_failed", snap.RotationID, snap.CurrentVersion, snap.TargetVersion, km.Provider(), err.Error())
+		h.auditRotation(r.Context(), "key_rotation.commit_failed", snap.RotationID, snap.CurrentVersion, snap.TargetVersion, km.Provider(), err.Error())
 		admin.WriteAdminErrorWithRotation(w, http.StatusInternalServerError, "PromoteFailed", err.Error(), snap.RotationID)
 		h.recordMetric("commit", "error", start)
 		return
 	}
 
 	rs.MarkCommitted()
-	h.auditRotation("key_rotation.committed", snap.RotationID, snap.CurrentVersion, snap.TargetVersion, km.Provider(), "")
+	h.auditRotation(r.Context(), "key_rotation.committed", snap.RotationID, snap.CurrentVersion, snap.TargetVersion, km.Provider(), "")
 
 	// Update metric
 	if h.metrics != nil {
@@ -258,7 +297,7 @@ func (h *AdminRotationHandler) handleRotateAbort(w http.ResponseWriter, r *http.
 	if km != nil {
 		provider = km.Provider()
 	}
-	h.auditRotation("key_rotation.aborted", snap.RotationID, snap.CurrentVersion, snap.TargetVersion, provider, "")
+	h.auditRotation(r.Context(), "key_rotation.aborted", snap.RotationID, snap.CurrentVersion, snap.TargetVersion, provider, "")
 	h.recordMetric("abort", "ok", start)
 
 	finalSnap := rs.Snapshot()
@@ -266,6 +305,83 @@ func (h *AdminRotationHandler) handleRotateAbort(w http.ResponseWriter, r *http.
 	json.NewEncoder(w).Encode(finalSnap)
 }
 
+// handleRetire destroys an inactive wrapping key version. It is refused
+// while a rotation is in flight, and while any object under a key hold
+// depends on the version or could not be inspected.
+func (h *AdminRotationHandler) handleRetire(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	km := crypto.GetKeyManager(h.engine)
+	if km == nil {
+		admin.WriteAdminErrorWithRotation(w, http.StatusNotImplemented, "NotImplemented", "no key manager configured", "")
+		h.recordMetric("retire", "error", start)
+		return
+	}
+	rkm, ok := crypto.KeyManagerAs[crypto.RetirableKeyManager](km)
+	if !ok {
+		admin.WriteAdminErrorWithRotation(w, http.StatusNotImplemented, "NotImplemented",
+			fmt.Sprintf("key manager %q does not support key version retirement", km.Provider()), "")
+		h.recordMetric("retire", "unsupported", start)
+		return
+	}
+
+	var req retireRequest
+	if r.Body == nil || json.NewDecoder(r.Body).Decode(&req) != nil || req.Version <= 0 {
+		admin.WriteAdminErrorWithRotation(w, http.StatusBadRequest, "BadRequest", "body must be {\"version\": <positive integer>}", "")
+		return
+	}
+
+	snap := crypto.GetRotationState(h.engine).Snapshot()
+	switch snap.Phase {
+	case crypto.RotationDraining.String(), crypto.RotationReadyToCutover.String(), crypto.RotationCommitting.String():
+		admin.WriteAdminErrorWithRotation(w, http.StatusConflict, "RotationConflict", "a key rotation is in progress", snap.RotationID)
+		h.recordMetric("retire", "conflict", start)
+		return
+	}
+
+	if h.keyHolds != nil {
+		inv, err := h.keyHolds.KeyHoldInventory(r.Context())
+		if err != nil {
+			admin.WriteAdminErrorWithRotation(w, http.StatusServiceUnavailable, "KeyHoldUnavailable", "cannot verify key holds: "+err.Error(), "")
+			h.recordMetric("retire", "error", start)
+			return
+		}
+		msg := ""
+		if n := inv.KeyVersions[req.Version]; n > 0 {
+			msg = fmt.Sprintf("%d held objects depend on version %d", n, req.Version)
+		} else if inv.Unresolved > 0 {
+			msg = fmt.Sprintf("%d held objects could not be inspected", inv.Unresolved)
+		}
+		if msg != "" {
+			h.auditRotation(r.Context(), "key_rotation.retire_refused", "", req.Version, 0, km.Provider(), msg)
+			admin.WriteAdminErrorWithRotation(w, http.StatusConflict, "KeyVersionHeld", msg, "")
+			h.recordMetric("retire", "held", start)
+			return
+		}
+	}
+
+	if err := rkm.RetireVersion(r.Context(), req.Version); err != nil {
+		status, code := http.StatusInternalServerError, "InternalError"
+		switch {
+		case errors.Is(err, crypto.ErrKeyNotFound):
+			status, code = http.StatusNotFound, "KeyNotFound"
+		case errors.Is(err, crypto.ErrRotationConflict):
+			status, code = http.StatusConflict, "ActiveKeyVersion"
+		}
+		admin.WriteAdminErrorWithRotation(w, status, code, err.Error(), "")
+		h.recordMetric("retire", "error", start)
+		return
+	}
+
+	h.auditRotation(r.Context(), "key_rotation.retired", "", req.Version, 0, km.Provider(), "")
+	h.recordMetric("retire", "ok", start)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(retireResponse{
+		RetiredVersion: req.Version,
+		Provider:       km.Provider(),
+		Timestamp:      time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
 // --- Helpers ---
 
 func (h *AdminRotationHandler) recordMetric(step, result string, start time.Time) {
@@ -274,7 +390,7 @@ func (h *AdminRotationHandler) recordMetric(step, result string, start time.Time
 	}
 }
 
-func (h *AdminRotationHandler) auditRotation(eventType, rotationID string, currentVersion, targetVersion int, provider, errMsg string) {
+func (h *AdminRotationHandler) auditRotation(ctx context.Context, eventType, rotationID string, currentVersion, targetVersion int, provider, errMsg string) {
 	if h.auditLogger == nil {
 		return
 	}
@@ -295,7 +411,7 @@ func (h *AdminRotationHandler) auditRotation(eventType, rotationID string, curre
 		auditErr = fmt.Errorf("%s", errMsg)
 	}
 
-	h.auditLogger.LogAccessWithMetadata(
+	h.auditLogger.WithContext(ctx).LogAccessWithMetadata(
 		eventType,          // eventType
 		"",                 // bucket
 		"kms/rotation",     // key
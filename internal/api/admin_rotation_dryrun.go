@@ -0,0 +1,236 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/admin"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+)
+
+// localReplica names this process in a dry run report.
+const localReplica = "local"
+
+type canaryRequest struct {
+	Version int `json:"version"`
+}
+
+// canaryResult is one replica's answer to a dry run.
+type canaryResult struct {
+	Replica    string `json:"replica"`
+	Ready      bool   `json:"ready"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+type dryRunRequest struct {
+	TargetVersion *int `json:"target_version,omitempty"`
+}
+
+type dryRunResponse struct {
+	CurrentVersion int            `json:"current_version"`
+	TargetVersion  int            `json:"target_version"`
+	Provider       string         `json:"provider"`
+	Ready          bool           `json:"ready"`
+	Replicas       []canaryResult `json:"replicas"`
+}
+
+// dryRunState remembers the newest passing dry run for the start check.
+type dryRunState struct {
+	mu      sync.Mutex
+	version int
+	at      time.Time
+}
+
+// WithDryRun configures rotation dry runs: the peers asked to take part and
+// whether a rotation may only start after one has passed.
+func (h *AdminRotationHandler) WithDryRun(cfg config.AdminRotationConfig) error {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.PeerCAFile != "" {
+		pem, err := os.ReadFile(cfg.PeerCAFile)
+		if err != nil {
+			return fmt.Errorf("read admin.rotation.peer_ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("admin.rotation.peer_ca_file %s holds no certificates", cfg.PeerCAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	if cfg.PeerTimeout <= 0 {
+		cfg.PeerTimeout = config.DefaultRotationPeerTimeout
+	}
+	if cfg.DryRunMaxAge <= 0 {
+		cfg.DryRunMaxAge = config.DefaultRotationDryRunMaxAge
+	}
+	h.dryRunCfg = cfg
+	h.peerClient = &http.Client{Transport: transport, Timeout: cfg.PeerTimeout}
+	return nil
+}
+
+// handleRotateCanary runs the canary for one version on this replica only.
+// Dry runs on other replicas call it.
+func (h *AdminRotationHandler) handleRotateCanary(w http.ResponseWriter, r *http.Request) {
+	km := crypto.GetKeyManager(h.engine)
+	if km == nil {
+		admin.WriteAdminErrorWithRotation(w, http.StatusNotImplemented, "NotImplemented", "no key manager configured", "")
+		return
+	}
+	var req canaryRequest
+	if r.Body == nil || json.NewDecoder(r.Body).Decode(&req) != nil || req.Version <= 0 {
+		admin.WriteAdminErrorWithRotation(w, http.StatusBadRequest, "BadRequest", "body must be {\"version\": <positive integer>}", "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runCanary(r.Context(), km, localReplica, req.Version))
+}
+
+// handleRotateDryRun checks that this replica and every configured peer
+// can wrap and unwrap under the rotation target before anything changes.
+func (h *AdminRotationHandler) handleRotateDryRun(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	km := crypto.GetKeyManager(h.engine)
+	if km == nil {
+		admin.WriteAdminErrorWithRotation(w, http.StatusNotImplemented, "NotImplemented", "no key manager configured", "")
+		h.recordMetric("dry_run", "error", start)
+		return
+	}
+	rkm, ok := crypto.KeyManagerAs[crypto.RotatableKeyManager](km)
+	if !ok {
+		admin.WriteAdminErrorWithRotation(w, http.StatusNotImplemented, "NotImplemented",
+			fmt.Sprintf("key manager %q does not support rotation", km.Provider()), "")
+		h.recordMetric("dry_run", "unsupported", start)
+		return
+	}
+
+	var req dryRunRequest
+	if r.Body != nil && r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			admin.WriteAdminErrorWithRotation(w, http.StatusBadRequest, "BadRequest", "invalid request body: "+err.Error(), "")
+			return
+		}
+	}
+	plan, err := rkm.PrepareRotation(r.Context(), req.TargetVersion)
+	if err != nil {
+		status, code := http.StatusInternalServerError, "InternalError"
+		if errors.Is(err, crypto.ErrRotationAmbiguous) {
+			status, code = http.StatusBadRequest, "AmbiguousTarget"
+		} else if errors.Is(err, crypto.ErrKeyNotFound) {
+			status, code = http.StatusNotFound, "KeyNotFound"
+		}
+		admin.WriteAdminErrorWithRotation(w, status, code, err.Error(), "")
+		h.recordMetric("dry_run", "error", start)
+		return
+	}
+
+	resp := dryRunResponse{
+		CurrentVersion: plan.CurrentVersion,
+		TargetVersion:  plan.TargetVersion,
+		Provider:       km.Provider(),
+		Ready:          true,
+		Replicas:       make([]canaryResult, 1+len(h.dryRunCfg.Peers)),
+	}
+	var wg sync.WaitGroup
+	for i, peer := range h.dryRunCfg.Peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp.Replicas[i+1] = h.peerCanary(r.Context(), peer, r.Header.Get("Authorization"), plan.TargetVersion)
+		}()
+	}
+	resp.Replicas[0] = runCanary(r.Context(), km, localReplica, plan.TargetVersion)
+	wg.Wait()
+
+	var failed []string
+	for _, res := range resp.Replicas {
+		if !res.Ready {
+			resp.Ready = false
+			failed = append(failed, res.Replica)
+		}
+	}
+	result, errMsg := "ok", ""
+	if resp.Ready {
+		h.dryRuns.mu.Lock()
+		h.dryRuns.version, h.dryRuns.at = plan.TargetVersion, time.Now()
+		h.dryRuns.mu.Unlock()
+	} else {
+		result, errMsg = "not_ready", "not ready: "+strings.Join(failed, ", ")
+	}
+	h.auditRotation(r.Context(), "key_rotation.dry_run", "", plan.CurrentVersion, plan.TargetVersion, km.Provider(), errMsg)
+	h.recordMetric("dry_run", result, start)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// dryRunPassed reports whether a dry run for version passed recently
+// enough to start a rotation to it.
+func (h *AdminRotationHandler) dryRunPassed(version int) bool {
+	h.dryRuns.mu.Lock()
+	defer h.dryRuns.mu.Unlock()
+	return h.dryRuns.version == version && !h.dryRuns.at.IsZero() && time.Since(h.dryRuns.at) <= h.dryRunCfg.DryRunMaxAge
+}
+
+func runCanary(ctx context.Context, km crypto.KeyManager, replica string, version int) canaryResult {
+	start := time.Now()
+	res := canaryResult{Replica: replica, Ready: true}
+	if err := crypto.CanaryWrap(ctx, km, version); err != nil {
+		res.Ready, res.Error = false, err.Error()
+	}
+	res.DurationMS = time.Since(start).Milliseconds()
+	return res
+}
+
+// peerCanary asks the replica whose admin API is at base to run the canary.
+// auth is the caller's Authorization header, so the peer applies the same
+// role check.
+func (h *AdminRotationHandler) peerCanary(ctx context.Context, base, auth string, version int) canaryResult {
+	start := time.Now()
+	res := canaryResult{Replica: base}
+	fail := func(err error) canaryResult {
+		res.Error = err.Error()
+		res.DurationMS = time.Since(start).Milliseconds()
+		return res
+	}
+
+	body, _ := json.Marshal(canaryRequest{Version: version})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(base, "/")+"/admin/kms/rotate/canary", bytes.NewReader(body))
+	if err != nil {
+		return fail(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	httpResp, err := h.peerClient.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer httpResp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(httpResp.Body, 64<<10))
+	if err != nil {
+		return fail(err)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return fail(fmt.Errorf("peer returned %d: %s", httpResp.StatusCode, strings.TrimSpace(string(data))))
+	}
+	var peer canaryResult
+	if err := json.Unmarshal(data, &peer); err != nil {
+		return fail(fmt.Errorf("decode peer response: %w", err))
+	}
+	res.Ready, res.Error = peer.Ready, peer.Error
+	res.DurationMS = time.Since(start).Milliseconds()
+	return res
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+)
+
+// newDryRunReplica returns a rotation handler whose key manager holds the
+// given versions, version 1 being active.
+func newDryRunReplica(t *testing.T, versions ...int) *AdminRotationHandler {
+	t.Helper()
+	key := func() []byte {
+		k := make([]byte, 32)
+		rand.Read(k)
+		return k
+	}
+	km := crypto.NewInMemoryKeyManagerForTestWithKeys(key(), 1)
+	for _, v := range versions {
+		if v != 1 {
+			km.AddVersion(context.Background(), v, key())
+		}
+	}
+	eng, err := crypto.NewEngineWithChunking([]byte("test-password1234"), nil, "", nil, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crypto.SetKeyManager(eng, km)
+	return NewAdminRotationHandler(eng, testRotationLogger(), testMetrics(), nil)
+}
+
+// servePeer exposes h's admin routes, refusing requests without the
+// expected bearer token.
+func servePeer(t *testing.T, h *AdminRotationHandler) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func postDryRun(t *testing.T, h *AdminRotationHandler) dryRunResponse {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/admin/kms/rotate/dry-run", bytes.NewBufferString(`{"target_version": 2}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	h.handleRotateDryRun(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("dry run: %d %s", w.Code, w.Body.String())
+	}
+	var resp dryRunResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestAdminRotateDryRun_AllReplicasReady(t *testing.T) {
+	peer := servePeer(t, newDryRunReplica(t, 1, 2))
+	h := newDryRunReplica(t, 1, 2)
+	if err := h.WithDryRun(config.AdminRotationConfig{Peers: []string{peer}, RequireDryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	start := func() int {
+		w := httptest.NewRecorder()
+		h.handleRotateStart(w, httptest.NewRequest("POST", "/admin/kms/rotate/start", bytes.NewBufferString(`{"target_version": 2}`)))
+		return w.Code
+	}
+	if code := start(); code != http.StatusConflict {
+		t.Fatalf("start without a dry run = %d, want 409", code)
+	}
+
+	resp := postDryRun(t, h)
+	if !resp.Ready || resp.CurrentVersion != 1 || resp.TargetVersion != 2 || len(resp.Replicas) != 2 {
+		t.Fatalf("dry run = %+v", resp)
+	}
+	if resp.Replicas[0].Replica != localReplica || resp.Replicas[1].Replica != peer {
+		t.Errorf("replicas = %+v", resp.Replicas)
+	}
+	// Nothing was promoted.
+	if v, _ := crypto.GetKeyManager(h.engine).ActiveKeyVersion(context.Background()); v != 1 {
+		t.Errorf("active version after dry run = %d", v)
+	}
+
+	if code := start(); code != http.StatusAccepted {
+		t.Fatalf("start after a passing dry run = %d, want 202", code)
+	}
+}
+
+func TestAdminRotateDryRun_ReplicaMissingVersion(t *testing.T) {
+	ready := servePeer(t, newDryRunReplica(t, 1, 2))
+	behind := servePeer(t, newDryRunReplica(t, 1))
+	h := newDryRunReplica(t, 1, 2)
+	if err := h.WithDryRun(config.AdminRotationConfig{Peers: []string{ready, behind, "http://127.0.0.1:1"}, RequireDryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := postDryRun(t, h)
+	if resp.Ready {
+		t.Fatalf("dry run passed with a replica missing the target: %+v", resp)
+	}
+	for i, want := range []bool{true, true, false, false} {
+		if resp.Replicas[i].Ready != want {
+			t.Errorf("replica %s ready = %v, want %v (%s)", resp.Replicas[i].Replica, resp.Replicas[i].Ready, want, resp.Replicas[i].Error)
+		}
+	}
+	if !strings.Contains(resp.Replicas[2].Error, "version 2") {
+		t.Errorf("missing version error = %q", resp.Replicas[2].Error)
+	}
+	if h.dryRunPassed(2) {
+		t.Error("a failed dry run allows the rotation to start")
+	}
+}
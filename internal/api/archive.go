@@ -0,0 +1,270 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/archive"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/sirupsen/logrus"
+)
+
+// ExportArchive streams every object under bucket/prefix to w as an archive
+// of decrypted objects. Each object is decrypted through the same engine as
+// GET and spooled to a temporary file first, because a tar header needs the
+// exact plaintext size before the body. MPU manifest companions and size
+// index objects are not exported.
+func (h *Handler) ExportArchive(ctx context.Context, w io.Writer, bucket, prefix string, format archive.Format) (archive.Stats, error) {
+	var stats archive.Stats
+
+	aw, err := archive.NewWriter(w, format)
+	if err != nil {
+		return stats, err
+	}
+
+	opts := s3.ListOptions{MaxKeys: 1000}
+	for {
+		page, err := h.s3Client.ListObjects(ctx, bucket, prefix, opts)
+		if err != nil {
+			return stats, fmt.Errorf("export: list %s/%s: %w", bucket, prefix, err)
+		}
+		for _, obj := range page.Objects {
+			if strings.HasSuffix(obj.Key, ".mpu-manifest") || h.isReservedKey(bucket, obj.Key) {
+				continue
+			}
+			name := strings.TrimPrefix(obj.Key, prefix)
+			if name == "" || strings.HasSuffix(name, "/") {
+				stats.Skipped = append(stats.Skipped, obj.Key)
+				continue
+			}
+			n, err := h.exportObject(ctx, aw, bucket, obj.Key, name)
+			if err != nil {
+				return stats, err
+			}
+			stats.Objects++
+			stats.Bytes += n
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		opts.ContinuationToken = page.NextContinuationToken
+	}
+
+	if err := aw.Close(); err != nil {
+		return stats, fmt.Errorf("export: finish archive: %w", err)
+	}
+	return stats, nil
+}
+
+// exportObject decrypts bucket/key into a spool file and appends it to aw.
+func (h *Handler) exportObject(ctx context.Context, aw *archive.Writer, bucket, key, name string) (int64, error) {
+	plaintext, metadata, err := h.openPlaintext(ctx, bucket, key)
+	if err != nil {
+		return 0, fmt.Errorf("export: %w", err)
+	}
+	defer plaintext.Close()
+
+	spool, err := os.CreateTemp("", "s3eg-export-*")
+	if err != nil {
+		return 0, fmt.Errorf("export: spool: %w", err)
+	}
+	defer func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}()
+	size, err := io.Copy(spool, plaintext)
+	if err != nil {
+		return 0, fmt.Errorf("export: decrypt %s/%s: %w", bucket, key, err)
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("export: spool: %w", err)
+	}
+
+	entry := archive.Entry{
+		Name:        name,
+		Size:        size,
+		ContentType: metadata["Content-Type"],
+		Metadata:    exportableMetadata(metadata),
+	}
+	if lm, err := time.Parse(time.RFC1123, metadata["Last-Modified"]); err == nil {
+		entry.ModTime = lm
+	}
+	if err := aw.WriteEntry(entry, spool); err != nil {
+		return 0, err
+	}
+
+	if h.auditLogger != nil {
+		h.auditLogger.WithContext(ctx).LogAccess("export", bucket, key, "", "", "", true, nil, 0)
+	}
+	return size, nil
+}
+
+// OpenPlaintext returns the decrypted body of bucket/key, read through the
+// same decryption paths as GET.
+func (h *Handler) OpenPlaintext(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	rc, _, err := h.openPlaintext(ctx, bucket, key)
+	return rc, err
+}
+
+// plaintextBody closes the backend body underneath a decrypting reader.
+type plaintextBody struct {
+	io.Reader
+	io.Closer
+}
+
+func (h *Handler) openPlaintext(ctx context.Context, bucket, key string) (io.ReadCloser, map[string]string, error) {
+	engine, err := h.getEncryptionEngine(bucket)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load encryption engine: %w", err)
+	}
+	reader, metadata, err := h.s3Client.GetObject(ctx, bucket, key, nil, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get %s/%s: %w", bucket, key, err)
+	}
+	if !h.checkMarker(engine, bucket, key, metadata) {
+		reader.Close()
+		return nil, nil, fmt.Errorf("get %s/%s: no valid gateway identity marker", bucket, key)
+	}
+	metadata = h.unsealMetadata(bucket, key, metadata)
+
+	var plaintext io.Reader = reader
+	switch {
+	case metadata[crypto.MetaMPUEncrypted] == "true":
+		plaintext, err = h.decryptMPUObject(ctx, bucket, key, metadata, reader, h.s3Client)
+	case engine.IsEncrypted(metadata):
+		plaintext, _, err = engine.Decrypt(ctx, reader, metadata)
+	}
+	if err != nil {
+		reader.Close()
+		h.noteFormatSkew(err, bucket, key)
+		return nil, nil, fmt.Errorf("decrypt %s/%s: %w", bucket, key, err)
+	}
+	return plaintextBody{Reader: plaintext, Closer: reader}, metadata, nil
+}
+
+// exportableMetadata returns the user metadata of an object, without the
+// gateway's own encryption and MPU bookkeeping.
+func exportableMetadata(metadata map[string]string) map[string]string {
+	out := make(map[string]string)
+	for k, v := range metadata {
+		lk := strings.ToLower(k)
+		if !strings.HasPrefix(lk, "x-amz-meta-") || isEncryptionMetadata(lk) || crypto.IsEncryptionMetadata(lk) {
+			continue
+		}
+		if lk == crypto.MetaMPUEncrypted || lk == crypto.MetaFallbackMode || lk == crypto.MetaFallbackPointer {
+			continue
+		}
+		out[lk] = v
+	}
+	return out
+}
+
+// ImportArchive writes every file in the archive read from r to
+// bucket/prefix+name, encrypting it exactly as a PUT through the gateway
+// would. Entries with unsafe names are skipped and reported.
+func (h *Handler) ImportArchive(ctx context.Context, r io.Reader, bucket, prefix string, format archive.Format) (archive.Stats, error) {
+	var stats archive.Stats
+
+	ar, err := archive.NewReader(r, format)
+	if err != nil {
+		return stats, err
+	}
+	defer ar.Close()
+
+	for {
+		entry, body, err := ar.Next()
+		if err == io.EOF {
+			return stats, nil
+		}
+		if err != nil {
+			if entry.Name != "" {
+				stats.Skipped = append(stats.Skipped, entry.Name)
+				continue
+			}
+			return stats, fmt.Errorf("import: read archive: %w", err)
+		}
+
+		key := prefix + entry.Name
+		if err := h.storePlaintext(ctx, "Import", bucket, key, body, entry.Size, entry.ContentType, entry.Metadata); err != nil {
+			return stats, fmt.Errorf("import: %w", err)
+		}
+		stats.Objects++
+		stats.Bytes += entry.Size
+	}
+}
+
+// PutDerived stores a hook's output at bucket/key, encrypted like any other
+// write through the gateway.
+func (h *Handler) PutDerived(ctx context.Context, bucket, key string, body io.Reader, size int64, contentType string, metadata map[string]string) error {
+	return h.storePlaintext(ctx, "Hook", bucket, key, body, size, contentType, metadata)
+}
+
+// storePlaintext encrypts size bytes of body and writes them to bucket/key
+// the way a PUT through the gateway would: metadata sealing, journal, cache
+// invalidation, size index and audit included. operation names the caller in
+// the journal and audit log.
+func (h *Handler) storePlaintext(ctx context.Context, operation, bucket, key string, body io.Reader, size int64, contentType string, userMeta map[string]string) error {
+	engine, err := h.getEncryptionEngine(bucket)
+	if err != nil {
+		return fmt.Errorf("load encryption engine: %w", err)
+	}
+	metadata := make(map[string]string, len(userMeta)+2)
+	for k, v := range userMeta {
+		metadata[k] = v
+	}
+	metadata, err = h.metaSealer.Seal(metadata)
+	if err != nil {
+		return fmt.Errorf("seal metadata %s/%s: %w", bucket, key, err)
+	}
+	metadata["x-amz-meta-original-content-length"] = strconv.FormatInt(size, 10)
+	metadata["Content-Type"] = contentType
+	if metadata["Content-Type"] == "" {
+		metadata["Content-Type"] = "application/octet-stream"
+	}
+
+	encryptedReader, encMetadata, err := engine.Encrypt(ctx, body, metadata)
+	if err != nil {
+		return fmt.Errorf("encrypt %s/%s: %w", bucket, key, err)
+	}
+	var filterKeys []string
+	if h.config != nil {
+		filterKeys = h.config.Backend.FilterMetadataKeys
+	}
+	s3Metadata := filterS3Metadata(encMetadata, filterKeys)
+	h.stampMarker(s3Metadata)
+	if err := h.checkMetadataOverflow(encMetadata, s3Metadata); err != nil {
+		return fmt.Errorf("put %s/%s: %w", bucket, key, err)
+	}
+
+	journalID, err := h.journal.Begin(operation, bucket, key, true)
+	if err != nil {
+		return fmt.Errorf("journal %s/%s: %w", bucket, key, err)
+	}
+	err = h.s3Client.PutObject(ctx, bucket, key, encryptedReader, s3Metadata, chunkedCiphertextLength(encMetadata, size), "", nil)
+	h.journal.End(journalID, err)
+	if err != nil {
+		return fmt.Errorf("put %s/%s: %w", bucket, key, err)
+	}
+
+	if h.cache != nil {
+		h.cache.Delete(ctx, bucket, key)
+	}
+	h.headMeta.invalidate(bucket, key)
+	h.indexWrite(bucket, key, encMetadata)
+	if h.auditLogger != nil {
+		h.auditLogger.WithContext(ctx).LogAccess(strings.ToLower(operation), bucket, key, "", "", "", true, nil, 0)
+	}
+	h.logger.WithFields(logrus.Fields{
+		"bucket":    bucket,
+		"key":       key,
+		"size":      size,
+		"operation": operation,
+	}).Debug("Stored object through the encryption pipeline")
+	return nil
+}
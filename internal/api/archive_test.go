@@ -0,0 +1,71 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kenneth/s3-encryption-gateway/internal/archive"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/sirupsen/logrus"
+)
+
+func TestExportImportArchive_RoundTrip(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	mockClient := newMockS3Client()
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	h := NewHandler(mockClient, engine, logger, getTestMetrics())
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	objects := map[string]string{
+		"src/a.txt":     "alpha",
+		"src/dir/b.txt": "bravo bravo",
+		"other/c.txt":   "not exported",
+	}
+	for key, body := range objects {
+		req := httptest.NewRequest("PUT", "/b/"+key, bytes.NewReader([]byte(body)))
+		req.Header.Set("x-amz-meta-owner", "ops")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if bytes.Contains(mockClient.objects["b/src/a.txt"], []byte("alpha")) {
+		t.Fatal("test object was stored unencrypted")
+	}
+
+	var buf bytes.Buffer
+	stats, err := h.ExportArchive(context.Background(), &buf, "b", "src/", archive.FormatTarZstd)
+	if err != nil {
+		t.Fatalf("ExportArchive: %v", err)
+	}
+	if stats.Objects != 2 || stats.Bytes != int64(len("alpha")+len("bravo bravo")) {
+		t.Errorf("export stats = %+v", stats)
+	}
+
+	stats, err = h.ImportArchive(context.Background(), &buf, "b", "restored/", archive.FormatTarZstd)
+	if err != nil {
+		t.Fatalf("ImportArchive: %v", err)
+	}
+	if stats.Objects != 2 {
+		t.Errorf("import stats = %+v", stats)
+	}
+
+	for name, want := range map[string]string{"a.txt": "alpha", "dir/b.txt": "bravo bravo"} {
+		key := "restored/" + name
+		if bytes.Contains(mockClient.objects["b/"+key], []byte(want)) {
+			t.Errorf("%s imported without encryption", key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/b/"+key, nil))
+		got, _ := io.ReadAll(w.Body)
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+		if owner := mockClient.metadata["b/"+key]["x-amz-meta-owner"]; owner != "ops" {
+			t.Errorf("%s lost user metadata, owner = %q", key, owner)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/audit"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func TestAudit_EventsCarryRequestTraceID(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, _ := crypto.NewEngineWithOpts([]byte("test-password-123456"), nil, crypto.WithChunking(true))
+	auditLogger := audit.NewLogger(100, &discardWriter{})
+	h := NewHandlerWithFeatures(testsupport.NewMemoryClient(), engine, logger, getTestMetrics(), nil, nil, auditLogger, &config.Config{}, nil)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	traceID, _ := trace.TraceIDFromHex("0af7651916cd43dd8448eb211c80319c")
+	spanID, _ := trace.SpanIDFromHex("b7ad6b7169203331")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("PUT", "/bucket/key", strings.NewReader("hello")),
+		httptest.NewRequest("DELETE", "/bucket/key", nil),
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req.WithContext(ctx))
+		if rec.Code >= 300 {
+			t.Fatalf("%s = %d", req.Method, rec.Code)
+		}
+	}
+
+	events := auditLogger.GetEvents()
+	if len(events) < 2 {
+		t.Fatalf("expected encrypt and delete events, got %d", len(events))
+	}
+	for _, e := range events {
+		if e.TraceID != traceID.String() || e.SpanID != spanID.String() {
+			t.Errorf("%s event: trace %q span %q", e.EventType, e.TraceID, e.SpanID)
+		}
+	}
+}
+
+type discardWriter struct{}
+
+func (discardWriter) WriteEvent(*audit.AuditEvent) error { return nil }
@@ -4,12 +4,14 @@ import (
 	"crypto/hmac"
 	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -34,7 +36,9 @@ import (
 // The mitigation against enumeration is that err.Error() (which may contain
 // computed HMAC signatures or internal detail) is NEVER included in the
 // response body; only the fixed per-class message string is returned. This is
-// enforced and regression-tested in auth_error_test.go.
+// enforced and regression-tested in auth_error_test.go. The session token
+// and clock skew sentinels likewise map to S3's InvalidToken, ExpiredToken
+// and RequestTimeTooSkewed; a replayed request is refused as AccessDenied.
 var (
 	// ErrSignatureMismatch indicates SigV4 validation failed (bad signature).
 	ErrSignatureMismatch = errors.New("signature validation failed")
@@ -46,6 +50,22 @@ var (
 	// were incomplete (missing access key or secret key).
 	ErrMissingCredentials = errors.New("missing or incomplete credentials")
 
+	// ErrInvalidToken indicates the request's X-Amz-Security-Token does not
+	// match the session token configured for its access key, or that one of
+	// the two is missing.
+	ErrInvalidToken = errors.New("invalid session token")
+
+	// ErrExpiredToken indicates the request was signed with temporary
+	// credentials past their expiration.
+	ErrExpiredToken = errors.New("expired session token")
+
+	// ErrRequestTimeTooSkewed indicates the request timestamp is outside the
+	// clock skew tolerance.
+	ErrRequestTimeTooSkewed = errors.New("request timestamp outside clock skew window")
+
+	// ErrReplayedRequest indicates the request's signature was already
+	// accepted once (see ReplayCache).
+	ErrReplayedRequest = errors.New("request signature already used")
 )
 
 const defaultClockSkew = 5 * time.Minute
@@ -57,6 +77,12 @@ const defaultClockSkew = 5 * time.Minute
 // timestamp and server time; zero or negative values fall back to
 // defaultClockSkew (5 minutes).
 func ValidateSignatureV4(r *http.Request, secretKey string, clockSkew time.Duration) error {
+	return ValidateSignatureV4At(r, secretKey, clockSkew, time.Now())
+}
+
+// ValidateSignatureV4At is ValidateSignatureV4 with now as the server time
+// the timestamp and presigned URL expiry are checked against.
+func ValidateSignatureV4At(r *http.Request, secretKey string, clockSkew time.Duration, now time.Time) error {
 	if clockSkew <= 0 {
 		clockSkew = defaultClockSkew
 	}
@@ -123,10 +149,10 @@ func ValidateSignatureV4(r *http.Request, secretKey string, clockSkew time.Durat
 	if err != nil {
 		return fmt.Errorf("invalid timestamp format")
 	}
-	now := time.Now().UTC()
+	now = now.UTC()
 	skew := now.Sub(t).Abs()
 	if skew > clockSkew {
-		return fmt.Errorf("request timestamp outside clock skew window")
+		return ErrRequestTimeTooSkewed
 	}
 
 	// Cross-validate credential-scope date against X-Amz-Date.
@@ -188,6 +214,54 @@ func ValidateSignatureV4(r *http.Request, secretKey string, clockSkew time.Durat
 	return nil
 }
 
+// securityTokenHeader carries the session token of temporary credentials,
+// as a header or, for presigned URLs, as a query parameter.
+const securityTokenHeader = "X-Amz-Security-Token"
+
+// ValidateSessionToken checks the request's X-Amz-Security-Token against
+// want, the session token of the credential the request was signed with.
+// Call it after the signature has been validated. Credentials without a
+// session token (want empty) reject requests that present one, as S3 does
+// for long-term keys.
+//
+// Presigned SigV4 URLs carry the token as a query parameter, which the
+// signature already covers. Header-signed SigV4 requests must list
+// x-amz-security-token in SignedHeaders so the token is bound to the
+// signature; SigV2 always signs x-amz-* headers.
+func ValidateSessionToken(r *http.Request, want string) error {
+	query := r.URL.Query()
+	got := r.Header.Get(securityTokenHeader)
+	switch {
+	case query.Get("X-Amz-Algorithm") == "AWS4-HMAC-SHA256":
+		got = query.Get(securityTokenHeader)
+	case strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 "):
+		if got != "" && !slices.Contains(authorizationSignedHeaders(r), "x-amz-security-token") {
+			return fmt.Errorf("%w: x-amz-security-token is not a signed header", ErrInvalidToken)
+		}
+	case got == "":
+		// SigV2 query authentication passes the token as a parameter.
+		got = query.Get(securityTokenHeader)
+	}
+	if got == "" && want == "" {
+		return nil
+	}
+	if got == "" || want == "" || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// authorizationSignedHeaders returns the SignedHeaders list of a SigV4
+// Authorization header.
+func authorizationSignedHeaders(r *http.Request) []string {
+	for _, p := range strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 "), ",") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(p), "SignedHeaders="); ok {
+			return strings.Split(v, ";")
+		}
+	}
+	return nil
+}
+
 // ValidateSignatureV2 validates an AWS Signature Version 2 request.
 // It supports both the Authorization header and query-parameter styles.
 // secretKey is the stored secret for the access key identified in the request.
@@ -196,9 +270,16 @@ func ValidateSignatureV4(r *http.Request, secretKey string, clockSkew time.Durat
 // fall back to defaultClockSkew (5 minutes). For query-parameter style with
 // Expires, the expiry is checked against the current time directly.
 func ValidateSignatureV2(r *http.Request, secretKey string, clockSkew time.Duration) error {
+	return ValidateSignatureV2At(r, secretKey, clockSkew, time.Now())
+}
+
+// ValidateSignatureV2At is ValidateSignatureV2 with now as the server time
+// the timestamp and Expires are checked against.
+func ValidateSignatureV2At(r *http.Request, secretKey string, clockSkew time.Duration, now time.Time) error {
 	if clockSkew <= 0 {
 		clockSkew = defaultClockSkew
 	}
+	now = now.UTC()
 
 	// Query-parameter style
 	q := r.URL.Query()
@@ -211,7 +292,7 @@ func ValidateSignatureV2(r *http.Request, secretKey string, clockSkew time.Durat
 			if err != nil {
 				return fmt.Errorf("invalid Expires format")
 			}
-			if time.Now().Unix() > expires {
+			if now.Unix() > expires {
 				return fmt.Errorf("request has expired")
 			}
 		} else {
@@ -224,10 +305,9 @@ func ValidateSignatureV2(r *http.Request, secretKey string, clockSkew time.Durat
 			if err != nil {
 				return fmt.Errorf("invalid date format")
 			}
-			now := time.Now().UTC()
 			skew := now.Sub(t).Abs()
 			if skew > clockSkew {
-				return fmt.Errorf("request timestamp outside clock skew window")
+				return ErrRequestTimeTooSkewed
 			}
 		}
 		// Build string-to-sign
@@ -259,10 +339,9 @@ func ValidateSignatureV2(r *http.Request, secretKey string, clockSkew time.Durat
 		if err != nil {
 			return fmt.Errorf("invalid date format")
 		}
-		now := time.Now().UTC()
 		skew := now.Sub(t).Abs()
 		if skew > clockSkew {
-			return fmt.Errorf("request timestamp outside clock skew window")
+			return ErrRequestTimeTooSkewed
 		}
 		stringToSign := buildV2StringToSign(r)
 		expectedSig := base64.StdEncoding.EncodeToString(hmacSHA1([]byte(secretKey), []byte(stringToSign)))
@@ -48,6 +48,30 @@ func TestClassifyAuthError_Table(t *testing.T) {
 			wantCode:   "AccessDenied",
 			wantStatus: http.StatusForbidden,
 		},
+		{
+			name:       "invalid session token",
+			err:        fmt.Errorf("%w: x-amz-security-token is not a signed header", ErrInvalidToken),
+			wantCode:   "InvalidToken",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "expired session token",
+			err:        ErrExpiredToken,
+			wantCode:   "ExpiredToken",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "request time too skewed",
+			err:        ErrRequestTimeTooSkewed,
+			wantCode:   "RequestTimeTooSkewed",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "replayed request",
+			err:        ErrReplayedRequest,
+			wantCode:   "AccessDenied",
+			wantStatus: http.StatusForbidden,
+		},
 		{
 			name:       "unknown error falls through to 500",
 			err:        errors.New(secretShapedInternal),
@@ -7,23 +7,37 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/clock"
+	"github.com/kenneth/s3-encryption-gateway/internal/identity"
+	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
 )
 
 // contextKey is a private type for context keys to avoid collisions.
 type contextKey int
 
 const (
-	// credentialLabelKey stores the resolved credential label in the request context.
-	credentialLabelKey contextKey = iota
+	// rawAccessKey stores the credential's raw access mode.
+	rawAccessKey contextKey = iota
 )
 
-// CredentialLabelFromContext returns the credential label attached to the
-// request context by AuthMiddleware, or empty string if none is present.
+// rawAccessStore is implemented by credential stores that can grant raw
+// reads (see RawHeader).
+type rawAccessStore interface {
+	RawAccess(accessKey string) string
+}
+
+// sessionTokenStore is implemented by credential stores that hold temporary
+// credentials (see ValidateSessionToken).
+type sessionTokenStore interface {
+	SessionToken(accessKey string) (token string, expires time.Time)
+}
+
+// CredentialLabelFromContext returns the label of the credential the request
+// was authenticated with, or empty string if none is present.
 func CredentialLabelFromContext(r *http.Request) string {
-	if label, ok := r.Context().Value(credentialLabelKey).(string); ok {
-		return label
-	}
-	return ""
+	id, _ := identity.FromContext(r.Context())
+	return id.Label
 }
 
 // writeS3ClientError writes an S3-formatted error response for authentication
@@ -34,23 +48,77 @@ func writeS3ClientError(w http.ResponseWriter, r *http.Request, err error, metho
 	s3Err.WriteXML(w)
 }
 
+// Reasons recorded by AuthOptions.Metrics for refused requests.
+const (
+	authRejectMissingCredentials = "missing_credentials"
+	authRejectUnknownAccessKey   = "unknown_access_key"
+	authRejectSignatureMismatch  = "signature_mismatch"
+	authRejectInvalidSignature   = "invalid_signature"
+	authRejectClockSkew          = "clock_skew"
+	authRejectReplay             = "replay"
+	authRejectInvalidToken       = "invalid_token"
+	authRejectExpiredToken       = "expired_token"
+)
+
+// AuthOptions configures AuthMiddlewareWithOptions.
+type AuthOptions struct {
+	// ClockSkew is the accepted difference between the request timestamp
+	// and Clock; zero uses the 5 minute default.
+	ClockSkew time.Duration
+	// Clock checks request timestamps and expiry; nil uses the real clock.
+	Clock clock.Clock
+	// Replay, when set, refuses header-signed requests whose signature it
+	// has already seen.
+	Replay *ReplayCache
+	// Metrics, when set, counts refused requests by reason.
+	Metrics *metrics.Metrics
+}
+
 // AuthMiddleware returns an HTTP middleware that validates every request
 // against the credential store before passing it to next.
 //
 // Auth flow:
 //  1. ExtractCredentials — extract access key from request.
 //  2. store.Lookup       — check access key is known.
-//  3. ValidateSignature  — verify HMAC (V4 or V2) using stored secret.
-//  4. Attach resolved label to request context for audit logging.
+//  3. ValidateSignature  — verify HMAC (V4 or V2) and request timestamp
+//     using stored secret, then the session token and expiration of
+//     temporary credentials, then (with a ReplayCache) that the signature
+//     has not been used before.
+//  4. Attach the resolved identity (access key and label, see
+//     identity.FromContext) and raw access mode to the request context.
 //  5. Call next; on any failure return S3-formatted error.
 func AuthMiddleware(store CredentialStore, clockSkew time.Duration, logger *logrus.Logger) func(http.Handler) http.Handler {
+	return AuthMiddlewareWithOptions(store, AuthOptions{ClockSkew: clockSkew}, logger)
+}
+
+// AuthMiddlewareWithClock is AuthMiddleware checking request timestamps and
+// presigned URL expiry against clk.
+func AuthMiddlewareWithClock(store CredentialStore, clockSkew time.Duration, clk clock.Clock, logger *logrus.Logger) func(http.Handler) http.Handler {
+	return AuthMiddlewareWithOptions(store, AuthOptions{ClockSkew: clockSkew, Clock: clk}, logger)
+}
+
+// AuthMiddlewareWithOptions is AuthMiddleware configured by opts.
+func AuthMiddlewareWithOptions(store CredentialStore, opts AuthOptions, logger *logrus.Logger) func(http.Handler) http.Handler {
+	clk := clock.OrReal(opts.Clock)
+	clockSkew := opts.ClockSkew
+	reject := func(w http.ResponseWriter, r *http.Request, err error, reason string) {
+		opts.Metrics.RecordAuthRejection(reason)
+		writeS3ClientError(w, r, err, r.Method)
+	}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Allow health check, readiness, liveness, and metrics endpoints
-			// without authentication so Kubernetes probes and Prometheus
-			// scraping work without credentials.
+			// Allow health check, readiness, liveness, version and metrics
+			// endpoints without authentication so Kubernetes probes,
+			// Prometheus scraping and fleet inventory work without credentials.
 			path := r.URL.Path
-			if path == "/health" || path == "/ready" || path == "/live" || path == "/metrics" || strings.HasPrefix(path, "/metrics") {
+			if path == "/health" || path == "/ready" || path == "/live" || path == "/version" || path == "/metrics" || strings.HasPrefix(path, "/metrics") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// Component probes share their paths with objects in a bucket
+			// named "ready", so only the GET that the probe routes serve is
+			// exempt.
+			if r.Method == http.MethodGet && isReadyComponentPath(path) {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -59,7 +127,7 @@ func AuthMiddleware(store CredentialStore, clockSkew time.Duration, logger *logr
 			creds, err := ExtractCredentials(r)
 			if err != nil {
 				logger.WithField("path", r.URL.Path).Warn("Request with no credentials")
-				writeS3ClientError(w, r, ErrMissingCredentials, r.Method)
+				reject(w, r, ErrMissingCredentials, authRejectMissingCredentials)
 				return
 			}
 
@@ -68,42 +136,80 @@ func AuthMiddleware(store CredentialStore, clockSkew time.Duration, logger *logr
 			if err != nil {
 				if err == ErrUnknownAccessKey {
 					logger.WithField("access_key", creds.AccessKey).Warn("Unknown access key")
-					writeS3ClientError(w, r, ErrUnknownAccessKey, r.Method)
+					reject(w, r, ErrUnknownAccessKey, authRejectUnknownAccessKey)
 					return
 				}
 				logger.WithError(err).WithField("access_key", creds.AccessKey).Warn("Credential store lookup failed")
-				writeS3ClientError(w, r, ErrUnknownAccessKey, r.Method)
+				reject(w, r, ErrUnknownAccessKey, authRejectUnknownAccessKey)
 				return
 			}
 
 			// 3. Validate signature
+			now := clk.Now()
 			var sigErr error
 			if IsSignatureV4Request(r) {
-				sigErr = ValidateSignatureV4(r, secretKey, clockSkew)
+				sigErr = ValidateSignatureV4At(r, secretKey, clockSkew, now)
 			} else if IsSignatureV2Request(r) {
-				sigErr = ValidateSignatureV2(r, secretKey, clockSkew)
+				sigErr = ValidateSignatureV2At(r, secretKey, clockSkew, now)
 			} else {
 				// Credentials were extracted but no recognizable signature format
 				logger.WithField("access_key", creds.AccessKey).Warn("No recognizable signature in request")
-				writeS3ClientError(w, r, ErrMissingCredentials, r.Method)
+				reject(w, r, ErrMissingCredentials, authRejectMissingCredentials)
 				return
 			}
 
 			if sigErr != nil {
 				if sigErr == ErrSignatureMismatch {
 					logger.WithField("access_key", creds.AccessKey).Warn("Signature mismatch")
-					writeS3ClientError(w, r, ErrSignatureMismatch, r.Method)
+					reject(w, r, ErrSignatureMismatch, authRejectSignatureMismatch)
+					return
+				}
+				if sigErr == ErrRequestTimeTooSkewed {
+					logger.WithField("access_key", creds.AccessKey).Warn("Request time too skewed")
+					reject(w, r, ErrRequestTimeTooSkewed, authRejectClockSkew)
 					return
 				}
 				// Other validation errors (expired, bad format, etc.)
 				logger.WithError(sigErr).WithField("access_key", creds.AccessKey).Warn("Signature validation failed")
-				writeS3ClientError(w, r, ErrSignatureMismatch, r.Method)
+				reject(w, r, ErrSignatureMismatch, authRejectInvalidSignature)
+				return
+			}
+
+			// Temporary credentials: the request must carry their session
+			// token and arrive before they expire. Without a token store every
+			// credential is long-term and must not be sent a token.
+			var token string
+			var expires time.Time
+			if ts, ok := store.(sessionTokenStore); ok {
+				token, expires = ts.SessionToken(creds.AccessKey)
+			}
+			if err := ValidateSessionToken(r, token); err != nil {
+				logger.WithError(err).WithField("access_key", creds.AccessKey).Warn("Session token rejected")
+				reject(w, r, err, authRejectInvalidToken)
+				return
+			}
+			if !expires.IsZero() && !now.Before(expires) {
+				logger.WithField("access_key", creds.AccessKey).WithField("expired_at", expires).Warn("Expired credential")
+				reject(w, r, ErrExpiredToken, authRejectExpiredToken)
 				return
 			}
 
-			// 4. Attach label to context for downstream audit logging
-			if label != "" {
-				r = r.WithContext(context.WithValue(r.Context(), credentialLabelKey, label))
+			// Only a request that passed every other check is remembered, so
+			// forged or stale requests cannot evict real signatures.
+			if opts.Replay != nil {
+				if key, ok := replayKey(r, creds.AccessKey); ok && opts.Replay.Seen(key, now) {
+					logger.WithField("access_key", creds.AccessKey).Warn("Replayed request signature")
+					reject(w, r, ErrReplayedRequest, authRejectReplay)
+					return
+				}
+			}
+
+			// 4. Attach the identity for handlers and audit logging
+			r = r.WithContext(identity.NewContext(r.Context(), identity.Identity{AccessKey: creds.AccessKey, Label: label}))
+			if rs, ok := store.(rawAccessStore); ok {
+				if mode := rs.RawAccess(creds.AccessKey); mode != "" {
+					r = r.WithContext(context.WithValue(r.Context(), rawAccessKey, mode))
+				}
 			}
 
 			// 5. Call next handler
@@ -862,6 +862,28 @@ func TestValidateSignatureV2_QueryParam_ValidFuture(t *testing.T) {
 	}
 }
 
+// TestValidateSessionToken_SigV2 verifies that SigV2 requests pass the
+// session token as the x-amz-security-token header or, with query
+// authentication, as a parameter.
+func TestValidateSessionToken_SigV2(t *testing.T) {
+	header := httptest.NewRequest("GET", "/bucket/key", nil)
+	header.Header.Set("Authorization", "AWS AKIATEST:c2lnbmF0dXJl")
+	header.Header.Set("X-Amz-Security-Token", "session-token-1")
+	query := httptest.NewRequest("GET", "/bucket/key?AWSAccessKeyId=AKIATEST&Signature=c2ln&Expires=1&X-Amz-Security-Token=session-token-1", nil)
+
+	for name, req := range map[string]*http.Request{"header": header, "query": query} {
+		if err := ValidateSessionToken(req, "session-token-1"); err != nil {
+			t.Errorf("%s: ValidateSessionToken() = %v, want nil", name, err)
+		}
+		if err := ValidateSessionToken(req, "session-token-2"); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: ValidateSessionToken() with another token = %v, want ErrInvalidToken", name, err)
+		}
+		if err := ValidateSessionToken(req, ""); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: ValidateSessionToken() for a long-term key = %v, want ErrInvalidToken", name, err)
+		}
+	}
+}
+
 // TestIsSignatureV4Request verifies that IsSignatureV4Request only matches
 // AWS4-HMAC-SHA256 and not the legacy "AWS " prefix.
 func TestIsSignatureV4Request(t *testing.T) {
@@ -2,12 +2,20 @@ package api
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
 	"strings"
 )
 
+// maxChunkHeaderSize bounds a chunk header line. A signed header is the
+// size plus an 81 byte chunk-signature extension, so this leaves ample room
+// while keeping a client from making the gateway buffer an endless line.
+const maxChunkHeaderSize = 4096
+
+var errChunkHeaderTooLong = errors.New("chunk header too long")
+
 // AwsChunkedReader wraps an io.Reader and decodes AWS chunked encoding.
 // Format: chunk-size;chunk-extensions(optional)\r\nchunk-data\r\n
 type AwsChunkedReader struct {
@@ -20,7 +28,7 @@ type AwsChunkedReader struct {
 // NewAwsChunkedReader creates a new reader that decodes AWS chunked format.
 func NewAwsChunkedReader(r io.Reader) *AwsChunkedReader {
 	return &AwsChunkedReader{
-		reader: bufio.NewReader(r),
+		reader: bufio.NewReaderSize(r, maxChunkHeaderSize),
 	}
 }
 
@@ -38,14 +46,17 @@ func (r *AwsChunkedReader) Read(p []byte) (n int, err error) {
 		if r.left == 0 {
 			// Read chunk header
 			// Format: hex-chunk-size [; key=value] \r\n
-			line, err := r.reader.ReadString('\n')
+			raw, err := r.reader.ReadSlice('\n')
+			if err == bufio.ErrBufferFull {
+				err = errChunkHeaderTooLong
+			}
 			if err != nil {
 				r.err = err
 				return totalRead, err
 			}
 
 			// Trim CRLF
-			line = strings.TrimSpace(line)
+			line := strings.TrimSpace(string(raw))
 			if line == "" {
 				continue // Skip empty lines if any? (Should shouldn't happen in valid stream but being robust)
 			}
@@ -59,6 +70,10 @@ func (r *AwsChunkedReader) Read(p []byte) (n int, err error) {
 				r.err = fmt.Errorf("invalid chunk size: %w", err)
 				return totalRead, r.err
 			}
+			if size < 0 {
+				r.err = fmt.Errorf("invalid chunk size: %d", size)
+				return totalRead, r.err
+			}
 
 			if size == 0 {
 				r.finished = true
@@ -89,12 +104,15 @@ func (r *AwsChunkedReader) Read(p []byte) (n int, err error) {
 
 		if r.left == 0 {
 			// Expect CRLF after chunk data
-			// We can read 2 bytes
-			_, err := r.reader.Discard(2) // \r\n
+			crlf, err := r.reader.Peek(2)
+			if err == nil && string(crlf) != "\r\n" {
+				err = errors.New("missing CRLF after chunk data")
+			}
 			if err != nil {
 				r.err = err
 				return totalRead, err
 			}
+			r.reader.Discard(2)
 		}
 
 		// If we filled the buffer, return
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/kenneth/s3-encryption-gateway/internal/tiering"
+	"github.com/sirupsen/logrus"
+)
+
+// The methods below carry out batch job operations on single objects. They
+// run with the gateway's backend credentials.
+
+// ReencryptObject decrypts bucket/key and stores it again through the
+// encryption pipeline, so it is written under the bucket's current engine
+// and the active key version. Plaintext objects come out encrypted, and an
+// MPU object becomes a single-part object whose manifest is then removed.
+func (h *Handler) ReencryptObject(ctx context.Context, bucket, key string) error {
+	unlock := h.keyLocks.Lock(bucket + "/" + key)
+	defer unlock()
+
+	plaintext, meta, err := h.openPlaintext(ctx, bucket, key)
+	if err != nil {
+		return err
+	}
+	defer plaintext.Close()
+
+	// The size must be known before the body is encrypted again.
+	spool, err := os.CreateTemp("", "s3eg-reencrypt-*")
+	if err != nil {
+		return fmt.Errorf("reencrypt: spool: %w", err)
+	}
+	defer func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}()
+	size, err := io.Copy(spool, plaintext)
+	if err != nil {
+		return fmt.Errorf("reencrypt: decrypt %s/%s: %w", bucket, key, err)
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("reencrypt: spool: %w", err)
+	}
+
+	// Only replace the version that was read.
+	unchanged := s3.WithWriteConditions(ctx, s3.WriteConditions{IfMatch: meta["ETag"]})
+	if err := h.storePlaintext(unchanged, "Reencrypt", bucket, key, spool, size, meta["Content-Type"], exportableMetadata(meta)); err != nil {
+		return err
+	}
+	if meta[crypto.MetaMPUEncrypted] == "true" {
+		manifestKey := manifestKeyOf(key, meta)
+		if err := h.s3Client.DeleteObject(ctx, bucket, manifestKey, nil); err != nil && !errors.Is(err, s3.ErrNotFound) {
+			h.logger.WithError(err).WithFields(logrus.Fields{
+				"bucket": bucket,
+				"key":    manifestKey,
+			}).Warn("Failed to delete the manifest of a re-encrypted MPU object")
+		}
+	}
+	return nil
+}
+
+// CopyStored copies bucket/key to destBucket/destKey as stored, without
+// decrypting it, replacing any object already there.
+func (h *Handler) CopyStored(ctx context.Context, bucket, key, destBucket, destKey string) error {
+	if err := h.sameEncryption(bucket, destBucket); err != nil {
+		return err
+	}
+	unlock := h.keyLocks.Lock(destBucket + "/" + destKey)
+	defer unlock()
+
+	meta, err := h.s3Client.HeadObject(ctx, bucket, key, nil)
+	if err != nil {
+		return err
+	}
+	if meta[tiering.MetaLocation] != "" {
+		return fmt.Errorf("%s/%s is tiered to secondary storage", bucket, key)
+	}
+	if _, err := h.copyStored(ctx, h.s3Client, bucket, key, destBucket, destKey, meta, nil); err != nil {
+		return err
+	}
+	if h.cache != nil {
+		h.cache.Delete(ctx, destBucket, destKey)
+	}
+	h.headMeta.invalidate(destBucket, destKey)
+	h.indexWrite(destBucket, destKey, meta)
+	return nil
+}
+
+// RemoveObject deletes bucket/key as a client DELETE would, through the
+// trash when deletes on bucket are deferred.
+func (h *Handler) RemoveObject(ctx context.Context, bucket, key string) (err error) {
+	defer func() {
+		if h.auditLogger != nil {
+			h.auditLogger.WithContext(ctx).LogAccess("delete", bucket, key, "", "", "", err == nil, err, 0)
+		}
+	}()
+	if h.trash.Applies(bucket, key) {
+		if _, err := h.moveToTrash(ctx, h.s3Client, bucket, key); err != nil {
+			return err
+		}
+	}
+	if err := h.s3Client.DeleteObject(ctx, bucket, key, nil); err != nil {
+		return err
+	}
+	if h.cache != nil {
+		h.cache.Delete(ctx, bucket, key)
+	}
+	h.headMeta.invalidate(bucket, key)
+	h.sizeIndex.Remove(bucket, key)
+	h.accessStats.Remove(bucket, key)
+	if err := h.s3Client.DeleteObject(ctx, bucket, key+".mpu-manifest", nil); err != nil && !errors.Is(err, s3.ErrNotFound) {
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"bucket": bucket,
+			"key":    key + ".mpu-manifest",
+		}).Warn("Failed to clean up MPU manifest companion object")
+	}
+	return nil
+}
+
+// RetagObject replaces the tags of bucket/key with tags, in the
+// URL-encoded form of x-amz-tagging. The backend client has no tagging
+// call, so the object is rewritten as stored with the new tags; MPU
+// objects are refused because they may exceed a single PUT.
+func (h *Handler) RetagObject(ctx context.Context, bucket, key, tags string) error {
+	unlock := h.keyLocks.Lock(bucket + "/" + key)
+	defer unlock()
+
+	body, meta, err := h.s3Client.GetObject(ctx, bucket, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if meta[crypto.MetaMPUEncrypted] == "true" {
+		return fmt.Errorf("%s/%s is a multipart object and cannot be retagged in place", bucket, key)
+	}
+	size, err := strconv.ParseInt(meta["Content-Length"], 10, 64)
+	if err != nil {
+		return fmt.Errorf("%s/%s has no content length", bucket, key)
+	}
+	stored := make(map[string]string)
+	for k, v := range meta {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-meta-") {
+			stored[lk] = v
+		}
+	}
+
+	unchanged := s3.WithWriteConditions(ctx, s3.WriteConditions{IfMatch: meta["ETag"]})
+	journalID, err := h.journal.Begin("Retag", bucket, key, true)
+	if err != nil {
+		return fmt.Errorf("journal %s/%s: %w", bucket, key, err)
+	}
+	err = h.s3Client.PutObject(unchanged, bucket, key, body, stored, &size, tags, nil)
+	h.journal.End(journalID, err)
+	if err != nil {
+		return fmt.Errorf("rewrite %s/%s: %w", bucket, key, err)
+	}
+	h.headMeta.invalidate(bucket, key)
+	return nil
+}
+
+// VerifyObject decrypts bucket/key in full and discards the plaintext, so
+// any chunk or manifest that fails authentication is reported.
+func (h *Handler) VerifyObject(ctx context.Context, bucket, key string) error {
+	plaintext, _, err := h.openPlaintext(ctx, bucket, key)
+	if err != nil {
+		return err
+	}
+	defer plaintext.Close()
+	if _, err := io.Copy(io.Discard, plaintext); err != nil {
+		return fmt.Errorf("verify %s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// sameEncryption refuses to carry ciphertext from bucket to destBucket
+// when their encryption engines differ, since it could not be decrypted
+// there.
+func (h *Handler) sameEncryption(bucket, destBucket string) error {
+	if bucket == destBucket {
+		return nil
+	}
+	src, err := h.getEncryptionEngine(bucket)
+	if err != nil {
+		return fmt.Errorf("load encryption engine: %w", err)
+	}
+	dst, err := h.getEncryptionEngine(destBucket)
+	if err != nil {
+		return fmt.Errorf("load encryption engine: %w", err)
+	}
+	if src != dst {
+		return fmt.Errorf("%s and %s use different encryption settings", bucket, destBucket)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func TestBatchExecutor(t *testing.T) {
+	client := testsupport.NewMemoryClient()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	h := NewHandlerWithFeatures(client, engine, logger, getTestMetrics(), nil, nil, nil, &config.Config{}, nil)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+	ctx := context.Background()
+
+	expect := func(path, want string) {
+		t.Helper()
+		resp, body := getResponse(t, srv, path, "")
+		if resp.StatusCode != http.StatusOK || !bytes.Equal(body, []byte(want)) {
+			t.Errorf("GET %s = %d %q, want %q", path, resp.StatusCode, body, want)
+		}
+	}
+
+	// A legacy object stored before the gateway comes out encrypted.
+	size := int64(len("legacy"))
+	if err := client.PutObject(ctx, "bucket", "legacy.txt", bytes.NewReader([]byte("legacy")), nil, &size, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.ReencryptObject(ctx, "bucket", "legacy.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _, _ := client.Object("bucket", "legacy.txt"); bytes.Contains(data, []byte("legacy")) {
+		t.Error("re-encrypted object is still plaintext on the backend")
+	}
+	expect("/bucket/legacy.txt", "legacy")
+
+	putObject(t, srv, "/bucket/doc.txt", []byte("document"))
+	if err := h.VerifyObject(ctx, "bucket", "doc.txt"); err != nil {
+		t.Errorf("VerifyObject = %v", err)
+	}
+	if err := h.RetagObject(ctx, "bucket", "doc.txt", "class=cold"); err != nil {
+		t.Fatal(err)
+	}
+	if tags := client.Tags("bucket", "doc.txt"); tags != "class=cold" {
+		t.Errorf("tags = %q", tags)
+	}
+	expect("/bucket/doc.txt", "document")
+
+	if err := h.CopyStored(ctx, "bucket", "doc.txt", "bucket", "copy/doc.txt"); err != nil {
+		t.Fatal(err)
+	}
+	expect("/bucket/copy/doc.txt", "document")
+	if err := h.RemoveObject(ctx, "bucket", "doc.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := client.Object("bucket", "doc.txt"); ok {
+		t.Error("removed object still exists")
+	}
+	if err := h.VerifyObject(ctx, "bucket", "doc.txt"); !errors.Is(err, s3.ErrNotFound) {
+		t.Errorf("VerifyObject of a removed object = %v", err)
+	}
+
+	// Tampered ciphertext fails verification.
+	data, meta, _ := client.Object("bucket", "copy/doc.txt")
+	data[len(data)-1] ^= 1
+	size = int64(len(data))
+	if err := client.PutObject(ctx, "bucket", "copy/doc.txt", bytes.NewReader(data), meta, &size, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.VerifyObject(ctx, "bucket", "copy/doc.txt"); err == nil {
+		t.Error("VerifyObject accepted tampered ciphertext")
+	}
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/cache"
+	"github.com/kenneth/s3-encryption-gateway/internal/clock"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// conditionalClient answers GETs whose If-None-Match names the current
+// ETag with NotModified, and counts the GETs that returned a body.
+type conditionalClient struct {
+	etagClient
+	bodies      atomic.Int32
+	notModified atomic.Int32
+}
+
+func (c *conditionalClient) GetObject(ctx context.Context, bucket, key string, versionID, rangeHeader *string) (io.ReadCloser, map[string]string, error) {
+	if conds, ok := s3.ReadConditionsFromContext(ctx); ok && conds.IfNoneMatch == c.etag.Load().(string) {
+		c.notModified.Add(1)
+		return nil, nil, s3.NewAPIError("NotModified", "Not Modified")
+	}
+	c.bodies.Add(1)
+	return c.etagClient.GetObject(ctx, bucket, key, versionID, rangeHeader)
+}
+
+func TestHandleGetObject_CacheRevalidation(t *testing.T) {
+	engine, err := crypto.NewEngine([]byte("test-password-123456"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &conditionalClient{etagClient: etagClient{mockS3Client: newMockS3Client()}}
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	clk := clock.NewFake(time.Now())
+	objects := cache.NewMemoryCache(1<<20, 100, time.Minute, cache.WithClock(clk), cache.WithRevalidation(time.Hour))
+	h := NewHandlerWithFeatures(client, engine, logger, getTestMetrics(), nil, objects, nil, nil, nil)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	// store writes data to the backend directly, as another gateway would.
+	store := func(data []byte, etag string) {
+		t.Helper()
+		encReader, meta, err := engine.Encrypt(context.Background(), bytes.NewReader(data), map[string]string{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc, _ := io.ReadAll(encReader)
+		if err := client.PutObject(context.Background(), "bucket", "obj", bytes.NewReader(enc), meta, nil, "", nil); err != nil {
+			t.Fatal(err)
+		}
+		client.etag.Store(etag)
+	}
+	get := func(want []byte, wantBodies, wantNotModified int32) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/bucket/obj", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET status = %d: %s", w.Code, w.Body.String())
+		}
+		if !bytes.Equal(w.Body.Bytes(), want) {
+			t.Errorf("GET returned %q, want %q", w.Body.Bytes(), want)
+		}
+		if got := client.bodies.Load(); got != wantBodies {
+			t.Errorf("backend GETs with a body = %d, want %d", got, wantBodies)
+		}
+		if got := client.notModified.Load(); got != wantNotModified {
+			t.Errorf("backend GETs answered 304 = %d, want %d", got, wantNotModified)
+		}
+	}
+
+	v1 := []byte("first version of the object")
+	store(v1, `"v1"`)
+	get(v1, 1, 0)
+	get(v1, 1, 0) // fresh in the cache
+
+	// Expired but unchanged: revalidated with a 304 and served from the
+	// cache, which is then fresh again.
+	clk.Advance(2 * time.Minute)
+	get(v1, 1, 1)
+	get(v1, 1, 1)
+
+	// Expired and overwritten: the conditional GET returns the new object.
+	clk.Advance(2 * time.Minute)
+	v2 := []byte("second version")
+	store(v2, `"v2"`)
+	get(v2, 2, 1)
+
+	// Past the revalidation window the entry is gone and the GET is plain.
+	clk.Advance(2 * time.Hour)
+	get(v2, 3, 1)
+	if st := objects.Stats(); st.Revalidations != 1 {
+		t.Errorf("revalidations = %d, want 1", st.Revalidations)
+	}
+}
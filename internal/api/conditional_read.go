@@ -0,0 +1,117 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// metaOriginalLastModified records the Last-Modified clients saw before the
+// gateway rewrote an object without changing its content, so that a lazy
+// upgrade does not make every cached copy look stale.
+const metaOriginalLastModified = "x-amz-meta-original-last-modified"
+
+// restoreLastModified replaces the backend's Last-Modified in metadata with
+// the one recorded before any rewrite by the gateway.
+func restoreLastModified(metadata map[string]string) {
+	if v, ok := metadata[metaOriginalLastModified]; ok {
+		if v != "" {
+			metadata["Last-Modified"] = v
+		}
+		delete(metadata, metaOriginalLastModified)
+	}
+}
+
+// readPrecondition evaluates the If-Match, If-Unmodified-Since,
+// If-None-Match and If-Modified-Since headers of a GET or HEAD against the
+// object's client-visible ETag and Last-Modified, in the order of RFC 7232
+// section 6. It returns 0 when the object should be served,
+// http.StatusNotModified or http.StatusPreconditionFailed. As on S3,
+// If-Unmodified-Since is ignored when If-Match holds and If-Modified-Since
+// when If-None-Match is present.
+func readPrecondition(r *http.Request, etag, lastModified string) int {
+	modified, hasModified := time.Time{}, false
+	if lastModified != "" {
+		if t, err := http.ParseTime(lastModified); err == nil {
+			modified, hasModified = t, true
+		}
+	}
+
+	if im := r.Header.Get("If-Match"); im != "" {
+		if !etagListMatches(im, etag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ius := r.Header.Get("If-Unmodified-Since"); ius != "" && hasModified {
+		if t, err := http.ParseTime(ius); err == nil && modified.After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etagListMatches(inm, etag, true) {
+			return http.StatusNotModified
+		}
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" && hasModified {
+		if t, err := http.ParseTime(ims); err == nil && !modified.After(t) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// etagListMatches reports whether the comma-separated entity tags of an
+// If-Match or If-None-Match header match etag. "*" matches any object.
+// Weak comparison ignores the W/ prefix; strong comparison never matches a
+// weak tag. Quotes are optional, as S3 clients are not consistent about
+// them.
+func etagListMatches(header, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if etag == "" {
+		return false
+	}
+	want := strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = tag[2:]
+		}
+		if strings.Trim(tag, `"`) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// checkReadPreconditions answers a GET or HEAD whose preconditions rule out
+// serving the object: 304 with the object's validators, or 412
+// PreconditionFailed. It reports whether it wrote a response.
+func (h *Handler) checkReadPreconditions(w http.ResponseWriter, r *http.Request, etag, lastModified string, start time.Time) bool {
+	switch readPrecondition(r, etag, lastModified) {
+	case http.StatusNotModified:
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+		if lastModified != "" {
+			w.Header().Set("Last-Modified", lastModified)
+		}
+		w.WriteHeader(http.StatusNotModified)
+		h.metrics.RecordHTTPRequest(r.Context(), r.Method, r.URL.Path, http.StatusNotModified, time.Since(start), 0)
+		return true
+	case http.StatusPreconditionFailed:
+		s3Err := &S3Error{
+			Code:       "PreconditionFailed",
+			Message:    "At least one of the pre-conditions you specified did not hold",
+			Resource:   r.URL.Path,
+			HTTPStatus: http.StatusPreconditionFailed,
+		}
+		s3Err.WriteXML(w)
+		h.metrics.RecordHTTPRequest(r.Context(), r.Method, r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/cache"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func TestReadPrecondition(t *testing.T) {
+	const etag = `"abc"`
+	modified := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	lastModified := modified.Format(http.TimeFormat)
+	before := modified.Add(-time.Hour).Format(http.TimeFormat)
+	after := modified.Add(time.Hour).Format(http.TimeFormat)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"none", nil, 0},
+		{"if-match hit", map[string]string{"If-Match": `"abc"`}, 0},
+		{"if-match unquoted", map[string]string{"If-Match": "abc"}, 0},
+		{"if-match list", map[string]string{"If-Match": `"x", "abc"`}, 0},
+		{"if-match star", map[string]string{"If-Match": "*"}, 0},
+		{"if-match miss", map[string]string{"If-Match": `"x"`}, http.StatusPreconditionFailed},
+		{"if-match weak", map[string]string{"If-Match": `W/"abc"`}, http.StatusPreconditionFailed},
+		{"if-none-match hit", map[string]string{"If-None-Match": `"abc"`}, http.StatusNotModified},
+		{"if-none-match weak", map[string]string{"If-None-Match": `W/"abc"`}, http.StatusNotModified},
+		{"if-none-match star", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"if-none-match miss", map[string]string{"If-None-Match": `"x"`}, 0},
+		{"if-modified-since same", map[string]string{"If-Modified-Since": lastModified}, http.StatusNotModified},
+		{"if-modified-since later", map[string]string{"If-Modified-Since": after}, http.StatusNotModified},
+		{"if-modified-since earlier", map[string]string{"If-Modified-Since": before}, 0},
+		{"if-modified-since malformed", map[string]string{"If-Modified-Since": "yesterday"}, 0},
+		{"if-unmodified-since earlier", map[string]string{"If-Unmodified-Since": before}, http.StatusPreconditionFailed},
+		{"if-unmodified-since same", map[string]string{"If-Unmodified-Since": lastModified}, 0},
+		// S3: If-Match holding overrides a failing If-Unmodified-Since ...
+		{"if-match beats if-unmodified-since", map[string]string{"If-Match": etag, "If-Unmodified-Since": before}, 0},
+		// ... and a failing If-None-Match overrides If-Modified-Since.
+		{"if-none-match beats if-modified-since", map[string]string{"If-None-Match": `"x"`, "If-Modified-Since": after}, 0},
+		{"412 before 304", map[string]string{"If-Match": `"x"`, "If-None-Match": etag}, http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/bucket/key", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := readPrecondition(r, etag, lastModified); got != tt.want {
+				t.Errorf("readPrecondition = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	r := httptest.NewRequest("GET", "/bucket/key", nil)
+	r.Header.Set("If-Match", `"abc"`)
+	if got := readPrecondition(r, "", ""); got != http.StatusPreconditionFailed {
+		t.Errorf("If-Match on an object without an ETag = %d, want 412", got)
+	}
+}
+
+func TestGetHeadObject_Preconditions(t *testing.T) {
+	for _, chunked := range []bool{false, true} {
+		client := testsupport.NewMemoryClient()
+		_, srv := newUpgradeTestServer(t, client, chunked)
+		putObject(t, srv, "/bucket/key", []byte("conditional object"))
+
+		// Chunked objects stored without a plaintext ETag show theirs on
+		// HEAD only, so the validators come from there.
+		head, err := http.Head(srv.URL + "/bucket/key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		head.Body.Close()
+		etag, lastModified := head.Header.Get("ETag"), head.Header.Get("Last-Modified")
+		if etag == "" || lastModified == "" {
+			t.Fatalf("chunked=%v: HEAD returned ETag %q, Last-Modified %q", chunked, etag, lastModified)
+		}
+		modified, _ := http.ParseTime(lastModified)
+		earlier := modified.Add(-time.Hour).Format(http.TimeFormat)
+
+		tests := []struct {
+			header, value string
+			want          int
+		}{
+			{"If-None-Match", etag, http.StatusNotModified},
+			{"If-None-Match", `"other"`, http.StatusOK},
+			{"If-Match", etag, http.StatusOK},
+			{"If-Match", `"other"`, http.StatusPreconditionFailed},
+			{"If-Modified-Since", lastModified, http.StatusNotModified},
+			{"If-Modified-Since", earlier, http.StatusOK},
+			{"If-Unmodified-Since", earlier, http.StatusPreconditionFailed},
+		}
+		for _, method := range []string{"GET", "HEAD"} {
+			for _, tt := range tests {
+				req, _ := http.NewRequest(method, srv.URL+"/bucket/key", nil)
+				req.Header.Set(tt.header, tt.value)
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode != tt.want {
+					t.Errorf("chunked=%v %s %s: %s = %d, want %d", chunked, method, tt.header, tt.value, resp.StatusCode, tt.want)
+					continue
+				}
+				switch {
+				case tt.want == http.StatusNotModified:
+					if len(body) != 0 || resp.Header.Get("ETag") != etag {
+						t.Errorf("chunked=%v %s %s: 304 with %d body bytes, ETag %q", chunked, method, tt.header, len(body), resp.Header.Get("ETag"))
+					}
+				case method == "GET" && tt.want == http.StatusOK:
+					if string(body) != "conditional object" {
+						t.Errorf("chunked=%v GET %s: body %q", chunked, tt.header, body)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestGetObject_PreconditionsOnCacheHit(t *testing.T) {
+	engine, err := crypto.NewEngineWithOpts([]byte("test-password-123456"), nil, crypto.WithChunking(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	objects := cache.NewMemoryCache(1<<20, 100, time.Hour)
+	h := NewHandlerWithFeatures(testsupport.NewMemoryClient(), engine, logger, getTestMetrics(), nil, objects, nil, nil, nil)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	putObject(t, srv, "/bucket/key", []byte("cached object"))
+	getResponse(t, srv, "/bucket/key", "")
+	head, err := http.Head(srv.URL + "/bucket/key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	head.Body.Close()
+	etag := head.Header.Get("ETag")
+
+	for header, want := range map[string]int{"If-Match": http.StatusOK, "If-None-Match": http.StatusNotModified} {
+		req, _ := http.NewRequest("GET", srv.URL+"/bucket/key", nil)
+		req.Header.Set(header, etag)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s: %s = %d, want %d", header, etag, resp.StatusCode, want)
+		}
+	}
+	if st := objects.Stats(); st.Hits != 2 {
+		t.Errorf("cache hits = %d, want 2", st.Hits)
+	}
+}
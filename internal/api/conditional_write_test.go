@@ -0,0 +1,139 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/sirupsen/logrus"
+)
+
+// conditionalMockClient records the write preconditions seen by PutObject and
+// rejects writes whose If-Match does not match the stored ETag.
+type conditionalMockClient struct {
+	*mockS3Client
+	mu    sync.Mutex
+	seen  []s3.WriteConditions
+	etags map[string]string
+}
+
+func (c *conditionalMockClient) PutObject(ctx context.Context, bucket, key string, reader io.Reader, metadata map[string]string, contentLength *int64, tags string, lock *s3.ObjectLockInput) error {
+	conds, _ := s3.WriteConditionsFromContext(ctx)
+	c.mu.Lock()
+	c.seen = append(c.seen, conds)
+	current := c.etags[bucket+"/"+key]
+	c.mu.Unlock()
+	if conds.IfMatch != "" && conds.IfMatch != current {
+		return &mockAPIError{code: "PreconditionFailed", message: "At least one of the pre-conditions you specified did not hold"}
+	}
+	return c.mockS3Client.PutObject(ctx, bucket, key, reader, metadata, contentLength, tags, lock)
+}
+
+func newConditionalTestRouter(mode string) (*mux.Router, *conditionalMockClient) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	client := &conditionalMockClient{mockS3Client: newMockS3Client(), etags: make(map[string]string)}
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	cfg := &config.Config{Backend: config.BackendConfig{ConditionalWrites: mode}}
+	h := NewHandlerWithFeatures(client, engine, logger, getTestMetrics(), nil, nil, nil, cfg, nil)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	return router, client
+}
+
+func TestPutObject_ConditionalWrites(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		existing string // stored ETag; empty means the key does not exist
+		ifMatch  string
+		want     s3.WriteConditions
+		wantCode int
+	}{
+		{name: "off ignores client headers", mode: config.ConditionalWritesOff, ifMatch: `"abc"`, wantCode: http.StatusOK},
+		{name: "client forwards If-Match", mode: config.ConditionalWritesClient, existing: `"abc"`, ifMatch: `"abc"`, want: s3.WriteConditions{IfMatch: `"abc"`}, wantCode: http.StatusOK},
+		{name: "client stale If-Match fails", mode: config.ConditionalWritesClient, existing: `"new"`, ifMatch: `"old"`, want: s3.WriteConditions{IfMatch: `"old"`}, wantCode: http.StatusPreconditionFailed},
+		{name: "optimistic new key", mode: config.ConditionalWritesOptimistic, want: s3.WriteConditions{IfNoneMatch: "*"}, wantCode: http.StatusOK},
+		{name: "optimistic existing key", mode: config.ConditionalWritesOptimistic, existing: `"abc"`, want: s3.WriteConditions{IfMatch: `"abc"`}, wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, client := newConditionalTestRouter(tt.mode)
+			if tt.existing != "" {
+				client.objects["b/k"] = []byte("old")
+				client.metadata["b/k"] = map[string]string{"ETag": tt.existing}
+				client.etags["b/k"] = tt.existing
+			} else {
+				client.errors["b/k/head"] = &mockAPIError{code: "NoSuchKey", message: "The specified key does not exist."}
+			}
+
+			req := httptest.NewRequest("PUT", "/b/k", bytes.NewReader([]byte("data")))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			if len(client.seen) != 1 || client.seen[0] != tt.want {
+				t.Errorf("backend conditions = %+v, want %+v", client.seen, tt.want)
+			}
+			if tt.wantCode == http.StatusPreconditionFailed && !bytes.Contains(w.Body.Bytes(), []byte("<Code>PreconditionFailed</Code>")) {
+				t.Errorf("expected PreconditionFailed error body, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestKeyLocker_SerialisesSameKey(t *testing.T) {
+	l := newKeyLocker()
+	unlock := l.Lock("b/k")
+
+	acquired := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		release := l.Lock("b/k")
+		close(acquired)
+		release()
+		close(done)
+	}()
+
+	// A different key is never blocked by the held one.
+	l.Lock("b/other")()
+
+	select {
+	case <-acquired:
+		t.Fatal("second writer acquired a held key")
+	case <-time.After(20 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second writer never acquired the key")
+	}
+	<-done
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.locks) != 0 {
+		t.Errorf("expected no retained lock entries, got %d", len(l.locks))
+	}
+}
+
+func TestKeyLocker_NilIsNoop(t *testing.T) {
+	var l *keyLocker
+	l.Lock("b/k")()
+}
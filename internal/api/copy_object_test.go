@@ -0,0 +1,157 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func newCopyTestServer(t *testing.T) (*httptest.Server, *testsupport.MemoryClient, crypto.RotatableKeyManager) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	km := crypto.NewInMemoryKeyManagerForTestWithKeys(bytes.Repeat([]byte{1}, 32), 1)
+	if err := km.AddVersion(context.Background(), 2, bytes.Repeat([]byte{2}, 32)); err != nil {
+		t.Fatal(err)
+	}
+	engine, err := crypto.NewEngineWithChunking([]byte("test-password-copy-object"), nil, "", nil, true, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crypto.SetKeyManager(engine, km)
+	client := testsupport.NewMemoryClient()
+	router := mux.NewRouter()
+	NewHandlerWithFeatures(client, engine, logger, getTestMetrics(), km, nil, nil, &config.Config{}, nil).RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv, client, km
+}
+
+func doRequest(t *testing.T, method, url string, body []byte, headers map[string]string) (*http.Response, []byte) {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, bytes.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, data
+}
+
+// storedKeyVersion returns the key version recorded on the stored object,
+// in either full or compacted form.
+func storedKeyVersion(metadata map[string]string) string {
+	if v := metadata[crypto.MetaKeyVersion]; v != "" {
+		return v
+	}
+	return metadata["x-amz-meta-kv"]
+}
+
+func TestCopyObject_MetadataDirective(t *testing.T) {
+	srv, _, _ := newCopyTestServer(t)
+	plaintext := bytes.Repeat([]byte("copy me "), 4096)
+	if resp, _ := doRequest(t, "PUT", srv.URL+"/bucket/src", plaintext, map[string]string{
+		"x-amz-meta-team": "blue",
+	}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT source = %d", resp.StatusCode)
+	}
+
+	tests := []struct {
+		name        string
+		headers     map[string]string
+		wantTeam    string
+		wantOwner   string
+		wantFailure bool
+	}{
+		{name: "default copies", wantTeam: "blue"},
+		{name: "COPY ignores request metadata", headers: map[string]string{
+			"x-amz-metadata-directive": "COPY",
+			"x-amz-meta-owner":         "alice",
+		}, wantTeam: "blue"},
+		{name: "REPLACE uses request metadata", headers: map[string]string{
+			"x-amz-metadata-directive": "REPLACE",
+			"x-amz-meta-owner":         "alice",
+		}, wantOwner: "alice"},
+		{name: "unknown directive", headers: map[string]string{
+			"x-amz-metadata-directive": "MERGE",
+		}, wantFailure: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{"x-amz-copy-source": "bucket/src"}
+			for k, v := range tt.headers {
+				headers[k] = v
+			}
+			resp, body := doRequest(t, "PUT", srv.URL+"/bucket/dst", nil, headers)
+			if tt.wantFailure {
+				if resp.StatusCode != http.StatusBadRequest || !bytes.Contains(body, []byte("InvalidArgument")) {
+					t.Fatalf("copy = %d %s, want 400 InvalidArgument", resp.StatusCode, body)
+				}
+				return
+			}
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("copy = %d %s", resp.StatusCode, body)
+			}
+
+			resp, got := doRequest(t, "GET", srv.URL+"/bucket/dst", nil, nil)
+			if resp.StatusCode != http.StatusOK || !bytes.Equal(got, plaintext) {
+				t.Fatalf("GET copy = %d, %d bytes, match %v", resp.StatusCode, len(got), bytes.Equal(got, plaintext))
+			}
+			if v := resp.Header.Get("x-amz-meta-team"); v != tt.wantTeam {
+				t.Errorf("x-amz-meta-team = %q, want %q", v, tt.wantTeam)
+			}
+			if v := resp.Header.Get("x-amz-meta-owner"); v != tt.wantOwner {
+				t.Errorf("x-amz-meta-owner = %q, want %q", v, tt.wantOwner)
+			}
+		})
+	}
+}
+
+// TestCopyObject_ReencryptsWithActiveKey checks that copying an object onto
+// itself re-encrypts it under the key version active at copy time.
+func TestCopyObject_ReencryptsWithActiveKey(t *testing.T) {
+	srv, client, km := newCopyTestServer(t)
+	ctx := context.Background()
+	plaintext := bytes.Repeat([]byte("rotate me "), 1000)
+	if resp, _ := doRequest(t, "PUT", srv.URL+"/bucket/obj", plaintext, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT = %d", resp.StatusCode)
+	}
+	if _, meta, _ := client.Object("bucket", "obj"); storedKeyVersion(meta) != "1" {
+		t.Fatalf("stored key version = %q, want 1", storedKeyVersion(meta))
+	}
+
+	plan, err := km.PrepareRotation(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := km.PromoteActiveVersion(ctx, plan); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp, body := doRequest(t, "PUT", srv.URL+"/bucket/obj", nil, map[string]string{
+		"x-amz-copy-source": "bucket/obj",
+	}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("copy = %d %s", resp.StatusCode, body)
+	}
+	if _, meta, _ := client.Object("bucket", "obj"); storedKeyVersion(meta) != "2" {
+		t.Errorf("stored key version after copy = %q, want 2", storedKeyVersion(meta))
+	}
+	resp, got := doRequest(t, "GET", srv.URL+"/bucket/obj", nil, nil)
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(got, plaintext) {
+		t.Fatalf("GET after copy = %d, match %v", resp.StatusCode, bytes.Equal(got, plaintext))
+	}
+}
@@ -604,6 +604,49 @@ func TestHandleHeadObject_WithEncryptionMeta(t *testing.T) {
 	}
 }
 
+// TestHandleHeadObject_EncryptedHidesInternals checks that HEAD on an object
+// written through the gateway reports the client's Content-Type and none of
+// the engine's metadata.
+func TestHandleHeadObject_EncryptedHidesInternals(t *testing.T) {
+	mockClient := newMockS3Client()
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	mockEngine, err := crypto.NewEngine([]byte("test-password-coverage-gaps-12345"))
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	h := NewHandler(mockClient, mockEngine, logger, getTestMetrics())
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	putReq := httptest.NewRequest("PUT", "/testbucket/typed", bytes.NewBufferString("hello"))
+	putReq.Header.Set("Content-Type", "text/plain")
+	putReq.Header.Set("x-amz-meta-owner", "alice")
+	putW := httptest.NewRecorder()
+	router.ServeHTTP(putW, putReq)
+	if putW.Code != http.StatusOK {
+		t.Fatalf("PUT failed: %d %s", putW.Code, putW.Body.String())
+	}
+
+	req := httptest.NewRequest("HEAD", "/testbucket/typed", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("HEAD: expected 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/plain" {
+		t.Errorf("Content-Type = %q, want text/plain", got)
+	}
+	if got := w.Header().Get("x-amz-meta-owner"); got != "alice" {
+		t.Errorf("x-amz-meta-owner = %q, want alice", got)
+	}
+	for k := range w.Header() {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-meta-") && lk != "x-amz-meta-owner" {
+			t.Errorf("HEAD leaked internal metadata header %s", k)
+		}
+	}
+}
+
 // ---- handleGetObject with decryption (happy path) --------------------------
 
 // TestHandleGetObject_EncryptedObject tests the full encrypt-then-decrypt path
@@ -1410,6 +1453,8 @@ func TestIsEncryptionMetadata(t *testing.T) {
 		{"x-amz-meta-encryption-iv", true},
 		{"x-amz-meta-encryption-chunked", true},
 		{"x-amz-meta-original-content-length", true},
+		{crypto.MetaContentType, true},
+		{crypto.MetaKDFParams, true},
 		{"x-amz-meta-other", false},
 		{"Content-Type", false},
 		{"x-amz-meta-custom-user-data", false},
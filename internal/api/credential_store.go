@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/kenneth/s3-encryption-gateway/internal/config"
 )
@@ -22,11 +23,14 @@ type StaticCredentialStore struct {
 type credentialEntry struct {
 	secretKey string
 	label     string
+	raw       string
+	token     string
+	expires   time.Time
 }
 
 // NewStaticCredentialStore creates a credential store from the provided credentials.
-// It returns an error if the credential list is empty or if any entry is missing
-// an access key or secret key.
+// It returns an error if the credential list is empty, if any entry is missing
+// an access key or secret key, or if an access key appears twice.
 func NewStaticCredentialStore(creds []config.GatewayCredential) (*StaticCredentialStore, error) {
 	if len(creds) == 0 {
 		return nil, fmt.Errorf("credential store requires at least one credential")
@@ -36,7 +40,16 @@ func NewStaticCredentialStore(creds []config.GatewayCredential) (*StaticCredenti
 		if c.AccessKey == "" || c.SecretKey == "" {
 			return nil, fmt.Errorf("credential entry is missing access key or secret key")
 		}
-		m[c.AccessKey] = credentialEntry{secretKey: c.SecretKey, label: c.Label}
+		if _, dup := m[c.AccessKey]; dup {
+			return nil, fmt.Errorf("duplicate credential for access key %q", c.AccessKey)
+		}
+		m[c.AccessKey] = credentialEntry{
+			secretKey: c.SecretKey,
+			label:     c.Label,
+			raw:       c.Raw,
+			token:     c.SessionToken,
+			expires:   c.Expiration,
+		}
 	}
 	return &StaticCredentialStore{m: m}, nil
 }
@@ -49,3 +62,16 @@ func (s *StaticCredentialStore) Lookup(accessKey string) (string, string, error)
 	}
 	return entry.secretKey, entry.label, nil
 }
+
+// RawAccess returns the raw access mode configured for the access key
+// (config.RawAccessOnRequest, config.RawAccessAlways or empty).
+func (s *StaticCredentialStore) RawAccess(accessKey string) string {
+	return s.m[accessKey].raw
+}
+
+// SessionToken returns the session token and expiration configured for the
+// access key. Both are zero for long-term credentials.
+func (s *StaticCredentialStore) SessionToken(accessKey string) (string, time.Time) {
+	entry := s.m[accessKey]
+	return entry.token, entry.expires
+}
@@ -40,9 +40,28 @@ func cosmianFactory(_ context.Context, cfg map[string]any) (crypto.KeyManager, e
 //
 // For providers "cosmian" and "kmip" it builds the typed options struct and
 // calls the registered factory; for "memory" and "hsm" it delegates directly
-// to the registry via [crypto.Open].
+// to the registry via [crypto.Open]. The result is wrapped so that every
+// call follows cfg.Calls.
 func BuildKeyManager(cfg *config.KeyManagerConfig, logger *logrus.Logger) (crypto.KeyManager, error) {
 	_ = logger // reserved for future structured logging
+	km, err := openKeyManager(cfg)
+	if err != nil {
+		return nil, err
+	}
+	calls := cfg.Calls
+	calls.Normalize()
+	return crypto.NewDeadlineKeyManager(km, crypto.CallPolicy{
+		Timeout:        calls.Timeout,
+		MaxAttempts:    calls.MaxAttempts,
+		InitialBackoff: calls.InitialBackoff,
+		MaxBackoff:     calls.MaxBackoff,
+		RetryBudget:    calls.RetryBudget,
+		RetryBurst:     calls.RetryBurst,
+	}), nil
+}
+
+// openKeyManager opens the configured provider's adapter.
+func openKeyManager(cfg *config.KeyManagerConfig) (crypto.KeyManager, error) {
 	provider := strings.ToLower(cfg.Provider)
 	if provider == "" {
 		provider = "cosmian"
@@ -108,6 +127,7 @@ func buildCosmianOptions(kmCfg *config.KeyManagerConfig) (crypto.CosmianKMIPOpti
 		Timeout:        kmCfg.Cosmian.Timeout,
 		Provider:       "cosmian-kmip",
 		DualReadWindow: kmCfg.DualReadWindow,
+		LazyConnect:    kmCfg.Cosmian.LazyConnect,
 	}, nil
 }
 
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/sirupsen/logrus"
+)
+
+// errDecryptedTooLarge ends a plaintext stream that has passed
+// server.max_decrypted_bytes.
+var errDecryptedTooLarge = errors.New("decrypted object exceeds server.max_decrypted_bytes")
+
+// maxDecryptedBytes returns the per-request plaintext cap, or 0 for none.
+func (h *Handler) maxDecryptedBytes() int64 {
+	if h.config == nil {
+		return 0
+	}
+	return h.config.Server.MaxDecryptedBytes
+}
+
+// declaredPlaintextSize returns the plaintext size recorded in an object's
+// backend metadata, falling back to the stored size. Metadata can be stale
+// or forged, so this only lets honest oversized objects fail before any
+// work is done; capDecrypted is what enforces the limit.
+func declaredPlaintextSize(metadata map[string]string) int64 {
+	var size int64
+	for _, k := range []string{"x-amz-meta-original-content-length", crypto.MetaOriginalSize, crypto.MetaCompressionOriginalSize} {
+		if n, err := strconv.ParseInt(metadata[k], 10, 64); err == nil && n > size {
+			size = n
+		}
+	}
+	if size == 0 {
+		size, _ = strconv.ParseInt(metadata["Content-Length"], 10, 64)
+	}
+	return size
+}
+
+// decryptedTooLarge reports whether an object with the given metadata is
+// known to exceed the cap before it is decrypted.
+func (h *Handler) decryptedTooLarge(metadata map[string]string) bool {
+	limit := h.maxDecryptedBytes()
+	return limit > 0 && declaredPlaintextSize(metadata) > limit
+}
+
+// decryptedTooLargeError is the response for a GET over the cap.
+func (h *Handler) decryptedTooLargeError(resource string) *S3Error {
+	return &S3Error{
+		Code:       "EntityTooLarge",
+		Message:    fmt.Sprintf("The decrypted object exceeds the gateway's limit of %d bytes per request.", h.maxDecryptedBytes()),
+		Resource:   resource,
+		HTTPStatus: http.StatusBadRequest,
+	}
+}
+
+// noteDecryptedTooLarge logs and counts a GET refused or cut off by the cap.
+func (h *Handler) noteDecryptedTooLarge(ctx context.Context, bucket, key string) {
+	h.metrics.RecordEncryptionError(ctx, "decrypt", "decrypted_too_large")
+	h.logger.WithFields(logrus.Fields{
+		"bucket": bucket,
+		"key":    key,
+		"limit":  h.maxDecryptedBytes(),
+	}).Warn("Decrypted object exceeds server.max_decrypted_bytes")
+}
+
+// rejectDecryptedTooLarge answers a GET whose object is known to be over
+// the cap.
+func (h *Handler) rejectDecryptedTooLarge(w http.ResponseWriter, r *http.Request, bucket, key string, start time.Time) {
+	h.noteDecryptedTooLarge(r.Context(), bucket, key)
+	s3Err := h.decryptedTooLargeError(r.URL.Path)
+	s3Err.WriteXML(w)
+	h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+}
+
+// capDecrypted bounds a plaintext stream by the cap. Reading past it fails
+// with errDecryptedTooLarge, which is logged and counted once.
+func (h *Handler) capDecrypted(ctx context.Context, bucket, key string, r io.Reader) io.Reader {
+	limit := h.maxDecryptedBytes()
+	if limit <= 0 {
+		return r
+	}
+	return &cappedReader{r: r, remaining: limit, exceeded: func() { h.noteDecryptedTooLarge(ctx, bucket, key) }}
+}
+
+type cappedReader struct {
+	r         io.Reader
+	remaining int64
+	exceeded  func()
+	err       error
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	// Allow one byte past the cap so an object of exactly the cap passes.
+	if int64(len(p)) > c.remaining+1 {
+		p = p[:c.remaining+1]
+	}
+	n, err := c.r.Read(p)
+	if int64(n) > c.remaining {
+		c.err = errDecryptedTooLarge
+		c.exceeded()
+		n = int(c.remaining)
+		c.remaining = 0
+		return n, c.err
+	}
+	c.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func TestGetObject_MaxDecryptedBytes(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, _ := crypto.NewEngineWithOpts([]byte("test-password-123456"), nil, crypto.WithChunking(true))
+	cfg := &config.Config{Server: config.ServerConfig{MaxDecryptedBytes: 1000}}
+	h := NewHandlerWithFeatures(testsupport.NewMemoryClient(), engine, logger, getTestMetrics(), nil, nil, nil, cfg, nil)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	putObject(t, srv, "/bucket/small", bytes.Repeat([]byte("a"), 1000))
+	putObject(t, srv, "/bucket/large", bytes.Repeat([]byte("b"), 2000))
+
+	for _, tc := range []struct {
+		path, rng string
+		code      int
+		size      int
+	}{
+		{"/bucket/small", "", http.StatusOK, 1000},
+		{"/bucket/large", "", http.StatusBadRequest, -1},
+		{"/bucket/large", "bytes=0-9", http.StatusPartialContent, 10},
+	} {
+		resp, body := getResponse(t, srv, tc.path, tc.rng)
+		if resp.StatusCode != tc.code {
+			t.Errorf("GET %s %s = %d %s", tc.path, tc.rng, resp.StatusCode, body)
+			continue
+		}
+		if tc.size < 0 {
+			if !strings.Contains(string(body), "EntityTooLarge") || !strings.Contains(string(body), "1000 bytes") {
+				t.Errorf("GET %s: unexpected error body %s", tc.path, body)
+			}
+		} else if len(body) != tc.size {
+			t.Errorf("GET %s %s returned %d bytes, want %d", tc.path, tc.rng, len(body), tc.size)
+		}
+	}
+}
+
+func TestCappedReader(t *testing.T) {
+	exceeded := 0
+	read := func(n int) ([]byte, error) {
+		exceeded = 0
+		c := &cappedReader{r: bytes.NewReader(make([]byte, n)), remaining: 100, exceeded: func() { exceeded++ }}
+		return io.ReadAll(c)
+	}
+
+	if b, err := read(100); err != nil || len(b) != 100 || exceeded != 0 {
+		t.Errorf("stream at the cap: %d bytes, %v, exceeded %d", len(b), err, exceeded)
+	}
+	b, err := read(101)
+	if !errors.Is(err, errDecryptedTooLarge) || len(b) != 100 || exceeded != 1 {
+		t.Errorf("stream over the cap: %d bytes, %v, exceeded %d", len(b), err, exceeded)
+	}
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+)
+
+// gatewayEncrypted reports whether metadata, the x-amz-meta-* headers of a
+// write, already describe an object encrypted by the gateway. Headers under
+// a custom metadata prefix count too: they are what a copy taken straight
+// from the backend carries.
+func (h *Handler) gatewayEncrypted(metadata map[string]string) bool {
+	if h.metaCodec != nil {
+		metadata = h.metaCodec.DecodeMetadata(metadata)
+	}
+	return metadata[crypto.MetaEncrypted] == "true" ||
+		metadata["x-amz-meta-e"] == "true" ||
+		metadata[crypto.MetaMPUEncrypted] == "true" ||
+		metadata[crypto.MetaGatewayMarker] != ""
+}
+
+func (h *Handler) doubleEncryptionMode() string {
+	if h.config == nil || h.config.Encryption.DoubleEncryption == "" {
+		return config.DoubleEncryptionReject
+	}
+	return h.config.Encryption.DoubleEncryption
+}
+
+// guardDoubleEncryption applies encryption.double_encryption to a PUT or
+// CreateMultipartUpload whose metadata is already gateway-encrypted, and
+// reports whether it answered the request. Encrypting such a body again
+// would leave an object that decrypts to ciphertext.
+func (h *Handler) guardDoubleEncryption(w http.ResponseWriter, r *http.Request, bucket, key string, metadata map[string]string, start time.Time) bool {
+	if !h.gatewayEncrypted(metadata) {
+		return false
+	}
+	mode := h.doubleEncryptionMode()
+	multipart := r.Method == http.MethodPost
+	log := h.logger.WithFields(logrus.Fields{
+		"bucket": bucket,
+		"key":    key,
+		"mode":   mode,
+	})
+
+	switch {
+	case mode == config.DoubleEncryptionEncrypt:
+		h.metrics.RecordDoubleEncryption("encrypted")
+		log.Warn("Write carries gateway encryption metadata; encrypting it again")
+		return false
+	case mode == config.DoubleEncryptionPassthrough && !multipart:
+		engine, err := h.getEncryptionEngine(bucket)
+		if err == nil {
+			decoded := metadata
+			if h.metaCodec != nil {
+				decoded = h.metaCodec.DecodeMetadata(metadata)
+			}
+			if h.checkMarker(engine, bucket, key, decoded) {
+				h.metrics.RecordDoubleEncryption("passed_through")
+				log.Info("Write carries gateway encryption metadata; storing it as sent")
+				h.serveRawPut(w, r, bucket, key, false, start)
+				return true
+			}
+		}
+	}
+
+	h.metrics.RecordDoubleEncryption("rejected")
+	log.Warn("Rejected a write that carries gateway encryption metadata")
+	msg := "The request carries the gateway's encryption metadata; the object is already encrypted."
+	if mode == config.DoubleEncryptionPassthrough && multipart {
+		msg = "Already encrypted objects cannot be stored through a multipart upload; use a single PUT."
+	}
+	s3Err := &S3Error{
+		Code:       "InvalidRequest",
+		Message:    msg,
+		Resource:   r.URL.Path,
+		HTTPStatus: http.StatusBadRequest,
+	}
+	s3Err.WriteXML(w)
+	h.metrics.RecordHTTPRequest(r.Context(), r.Method, r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+	return true
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func newDoubleEncryptionRouter(backend *testsupport.MemoryClient, mode string) http.Handler {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, _ := crypto.NewEngineWithOpts([]byte("test-password-123456"), nil, crypto.WithChunking(true))
+	cfg := &config.Config{}
+	cfg.Encryption.DoubleEncryption = mode
+	router := mux.NewRouter()
+	NewHandlerWithFeatures(backend, engine, logger, getTestMetrics(), nil, nil, nil, cfg, nil).RegisterRoutes(router)
+	return router
+}
+
+func TestDoubleEncryptionGuard(t *testing.T) {
+	plaintext := bytes.Repeat([]byte("already encrypted "), 500)
+
+	// Write an object through the gateway and take its stored form, as a
+	// misconfigured replication job copying from the backend would.
+	source := testsupport.NewMemoryClient()
+	rec := httptest.NewRecorder()
+	newDoubleEncryptionRouter(source, "").ServeHTTP(rec, httptest.NewRequest("PUT", "/bucket/obj", bytes.NewReader(plaintext)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT = %d", rec.Code)
+	}
+	ciphertext, storedMeta, _ := source.Object("bucket", "obj")
+
+	copyBack := func(mode, method, target string) (*httptest.ResponseRecorder, *testsupport.MemoryClient) {
+		backend := testsupport.NewMemoryClient()
+		req := httptest.NewRequest(method, target, bytes.NewReader(ciphertext))
+		for k, v := range storedMeta {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		newDoubleEncryptionRouter(backend, mode).ServeHTTP(rec, req)
+		return rec, backend
+	}
+
+	for _, mode := range []string{"", config.DoubleEncryptionReject} {
+		if rec, backend := copyBack(mode, "PUT", "/bucket/obj"); rec.Code != http.StatusBadRequest {
+			t.Errorf("mode %q: PUT = %d, want 400", mode, rec.Code)
+		} else if _, _, ok := backend.Object("bucket", "obj"); ok {
+			t.Errorf("mode %q: rejected object was stored", mode)
+		}
+	}
+
+	rec, backend := copyBack(config.DoubleEncryptionPassthrough, "PUT", "/bucket/obj")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("passthrough PUT = %d: %s", rec.Code, rec.Body)
+	}
+	if stored, _, _ := backend.Object("bucket", "obj"); !bytes.Equal(stored, ciphertext) {
+		t.Error("passthrough did not store the body as sent")
+	}
+	get := httptest.NewRecorder()
+	newDoubleEncryptionRouter(backend, "").ServeHTTP(get, httptest.NewRequest("GET", "/bucket/obj", nil))
+	if body, _ := io.ReadAll(get.Body); get.Code != http.StatusOK || !bytes.Equal(body, plaintext) {
+		t.Errorf("GET after passthrough = %d, plaintext match %v", get.Code, bytes.Equal(body, plaintext))
+	}
+
+	if rec, _ := copyBack(config.DoubleEncryptionPassthrough, "POST", "/bucket/obj?uploads"); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "multipart upload") {
+		t.Errorf("passthrough multipart upload = %d: %s", rec.Code, rec.Body)
+	}
+
+	rec, backend = copyBack(config.DoubleEncryptionEncrypt, "PUT", "/bucket/obj")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("encrypt PUT = %d: %s", rec.Code, rec.Body)
+	}
+	if stored, _, _ := backend.Object("bucket", "obj"); bytes.Equal(stored, ciphertext) {
+		t.Error("encrypt mode stored the body unencrypted")
+	}
+
+	// Ordinary user metadata is not mistaken for gateway metadata.
+	req := httptest.NewRequest("PUT", "/bucket/plain", bytes.NewReader(plaintext))
+	req.Header.Set("x-amz-meta-owner", "team-a")
+	rec = httptest.NewRecorder()
+	newDoubleEncryptionRouter(testsupport.NewMemoryClient(), "").ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("plain PUT = %d", rec.Code)
+	}
+}
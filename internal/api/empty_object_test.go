@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func TestEmptyObject_PutGetHead(t *testing.T) {
+	formats := []struct {
+		name    string
+		chunked bool
+		comp    crypto.CompressionEngine
+	}{
+		{"legacy", false, nil},
+		{"chunked", true, nil},
+		{"compressed", true, crypto.NewCompressionEngine(true, 0, nil, "gzip", 6)},
+	}
+	for _, f := range formats {
+		t.Run(f.name, func(t *testing.T) {
+			engine, err := crypto.NewEngineWithOpts([]byte("test-password-123456"), f.comp, crypto.WithChunking(f.chunked))
+			if err != nil {
+				t.Fatal(err)
+			}
+			logger := logrus.New()
+			logger.SetLevel(logrus.FatalLevel)
+			client := testsupport.NewMemoryClient()
+			h := NewHandlerWithFeatures(client, engine, logger, getTestMetrics(), nil, nil, nil, &config.Config{}, nil)
+			router := mux.NewRouter()
+			h.RegisterRoutes(router)
+			srv := httptest.NewServer(router)
+			defer srv.Close()
+
+			putObject(t, srv, "/bucket/empty", nil)
+			data, meta, _ := client.Object("bucket", "empty")
+			if len(data) != 0 || !crypto.IsEmptyObject(meta) {
+				t.Fatalf("backend holds %d bytes, empty marker %v", len(data), crypto.IsEmptyObject(meta))
+			}
+			if upgradable(engine, meta) {
+				t.Error("an empty object must not be queued for a lazy upgrade")
+			}
+
+			get, body := getResponse(t, srv, "/bucket/empty", "")
+			head, err := http.Head(srv.URL + "/bucket/empty")
+			if err != nil {
+				t.Fatal(err)
+			}
+			head.Body.Close()
+			for _, resp := range []*http.Response{get, head} {
+				method := resp.Request.Method
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("%s = %d", method, resp.StatusCode)
+				}
+				if cl := resp.Header.Get("Content-Length"); cl != "0" {
+					t.Errorf("%s Content-Length = %q, want 0", method, cl)
+				}
+				if etag := resp.Header.Get("ETag"); etag != `"d41d8cd98f00b204e9800998ecf8427e"` {
+					t.Errorf("%s ETag = %q, want the MD5 of no content", method, etag)
+				}
+				if v := resp.Header.Get(crypto.MetaEmpty); v != "" {
+					t.Errorf("%s leaked the empty marker", method)
+				}
+			}
+			if len(body) != 0 {
+				t.Errorf("GET returned %d bytes", len(body))
+			}
+			if resp, _ := getResponse(t, srv, "/bucket/empty", "bytes=0-0"); resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+				t.Errorf("ranged GET of an empty object = %d, want 416", resp.StatusCode)
+			}
+		})
+	}
+}
@@ -211,6 +211,15 @@ func (m *mpuMockS3Client) PutObjectLockConfiguration(ctx context.Context, bucket
 func (m *mpuMockS3Client) GetObjectLockConfiguration(ctx context.Context, bucket string) (*s3.ObjectLockConfiguration, error) {
 	return nil, nil
 }
+func (m *mpuMockS3Client) PutObjectTagging(ctx context.Context, bucket, key string, versionID *string, tags []s3.Tag) error {
+	return nil
+}
+func (m *mpuMockS3Client) GetObjectTagging(ctx context.Context, bucket, key string, versionID *string) ([]s3.Tag, error) {
+	return nil, nil
+}
+func (m *mpuMockS3Client) DeleteObjectTagging(ctx context.Context, bucket, key string, versionID *string) error {
+	return nil
+}
 
 // ─────────────────────────────────────────────────────────────────────────────
 // newMPUTestHandler — stand up a handler with miniredis state + PasswordKeyManager
@@ -1285,3 +1294,78 @@ func TestMPU_GetObject_Tamper_MidStream(t *testing.T) {
 func TestMPU_GetObject_Tamper_Manifest(t *testing.T) {
 	// Function covered by TestMPU_Issue2_ManifestEncryptedAtRest
 }
+
+// ─────────────────────────────────────────────────────────────────────────────
+// aws-chunked UploadPart bodies (the default of current AWS SDKs and the CLI)
+// must be decoded before they are encrypted or forwarded.
+// ─────────────────────────────────────────────────────────────────────────────
+
+func TestMPU_UploadPart_AwsChunkedBody(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		bucket string
+	}{
+		{name: "encrypted", bucket: "chunked-bucket"},
+		{name: "plaintext", bucket: "test-bucket"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, _, _ := newMPUTestHandler(t, "chunked-*")
+			router := mux.NewRouter()
+			handler.RegisterRoutes(router)
+			key := "chunked.bin"
+
+			req := httptest.NewRequest("POST", "/"+tc.bucket+"/"+key+"?uploads=", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Create: %d %s", w.Code, w.Body.String())
+			}
+			uploadID := extractUploadID(t, w.Body.String())
+
+			var want []byte
+			var etags []string
+			for i := 0; i < 2; i++ {
+				partData := bytes.Repeat([]byte{byte('a' + i)}, 3000+i)
+				want = append(want, partData...)
+				// Two data chunks followed by the final zero-length chunk.
+				framed := fmt.Sprintf("%x;chunk-signature=sig\r\n%s\r\n%x;chunk-signature=sig\r\n%s\r\n0;chunk-signature=sig\r\n\r\n",
+					1024, partData[:1024], len(partData)-1024, partData[1024:])
+
+				req := httptest.NewRequest("PUT",
+					fmt.Sprintf("/%s/%s?partNumber=%d&uploadId=%s", tc.bucket, key, i+1, uploadID),
+					strings.NewReader(framed))
+				req.Header.Set("x-amz-content-sha256", "STREAMING-AWS4-HMAC-SHA256-PAYLOAD")
+				req.Header.Set("x-amz-decoded-content-length", strconv.Itoa(len(partData)))
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				if w.Code != http.StatusOK {
+					t.Fatalf("UploadPart %d: %d %s", i+1, w.Code, w.Body.String())
+				}
+				etags = append(etags, w.Header().Get("ETag"))
+			}
+
+			var partsXML strings.Builder
+			partsXML.WriteString(`<?xml version="1.0"?><CompleteMultipartUpload>`)
+			for i, etag := range etags {
+				partsXML.WriteString(fmt.Sprintf(`<Part><PartNumber>%d</PartNumber><ETag>%s</ETag></Part>`, i+1, etag))
+			}
+			partsXML.WriteString(`</CompleteMultipartUpload>`)
+			req = httptest.NewRequest("POST", "/"+tc.bucket+"/"+key+"?uploadId="+uploadID, strings.NewReader(partsXML.String()))
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Complete: %d %s", w.Code, w.Body.String())
+			}
+
+			req = httptest.NewRequest("GET", "/"+tc.bucket+"/"+key, nil)
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET: %d %s", w.Code, w.Body.String())
+			}
+			if got := w.Body.Bytes(); !bytes.Equal(got, want) {
+				t.Fatalf("round-trip mismatch: want %d bytes, got %d bytes", len(want), len(got))
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -8,6 +9,11 @@ import (
 	"strings"
 
 	"github.com/aws/smithy-go"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/middleware"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/kenneth/s3-encryption-gateway/internal/storage"
 )
 
 // S3Error represents an S3 API error response.
@@ -24,7 +30,9 @@ func (e *S3Error) Error() string {
 	return fmt.Sprintf("S3 Error: %s - %s", e.Code, e.Message)
 }
 
-// WriteXML writes the S3 error response in XML format.
+// WriteXML writes the S3 error response in XML format. Without a RequestID
+// of its own, such as the backend's, the error carries the gateway's
+// request ID from the response headers.
 func (e *S3Error) WriteXML(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/xml")
 	w.WriteHeader(e.HTTPStatus)
@@ -44,6 +52,9 @@ func (e *S3Error) WriteXML(w http.ResponseWriter) {
 		Resource:  e.Resource,
 		RequestID: e.RequestID,
 	}
+	if response.RequestID == "" {
+		response.RequestID = w.Header().Get(middleware.RequestIDHeader)
+	}
 
 	xmlData, err := xml.MarshalIndent(response, "", "  ")
 	if err != nil {
@@ -62,14 +73,8 @@ func TranslateError(err error, bucket, key string) *S3Error {
 		return nil
 	}
 
-	// Extract request ID if available
-	requestID := ""
-	var opErr *smithy.OperationError
-	if errors.As(err, &opErr) {
-		if reqID := extractRequestID(opErr); reqID != "" {
-			requestID = reqID
-		}
-	}
+	// Report the backend's request ID so the client can quote it too.
+	requestID := extractRequestID(err)
 
 	resource := ""
 	if bucket != "" {
@@ -116,6 +121,62 @@ func TranslateError(err error, bucket, key string) *S3Error {
 				RequestID:  requestID,
 				HTTPStatus: http.StatusBadRequest,
 			}
+		case "PreconditionFailed":
+			return &S3Error{
+				Code:       "PreconditionFailed",
+				Message:    "At least one of the pre-conditions you specified did not hold",
+				Resource:   resource,
+				RequestID:  requestID,
+				HTTPStatus: http.StatusPreconditionFailed,
+			}
+		case "InvalidRange":
+			return &S3Error{
+				Code:       "InvalidRange",
+				Message:    "The requested range is not satisfiable",
+				Resource:   resource,
+				RequestID:  requestID,
+				HTTPStatus: http.StatusRequestedRangeNotSatisfiable,
+			}
+		case "NoSuchUpload":
+			return &S3Error{
+				Code:       "NoSuchUpload",
+				Message:    "The specified upload does not exist. The upload ID may be invalid, or the upload may have been aborted or completed.",
+				Resource:   resource,
+				RequestID:  requestID,
+				HTTPStatus: http.StatusNotFound,
+			}
+		case "EntityTooSmall":
+			return &S3Error{
+				Code:       "EntityTooSmall",
+				Message:    "Your proposed upload is smaller than the minimum allowed object size.",
+				Resource:   resource,
+				RequestID:  requestID,
+				HTTPStatus: http.StatusBadRequest,
+			}
+		case "InvalidPart":
+			return &S3Error{
+				Code:       "InvalidPart",
+				Message:    "One or more of the specified parts could not be found. The part may not have been uploaded, or the specified entity tag may not match the part's entity tag.",
+				Resource:   resource,
+				RequestID:  requestID,
+				HTTPStatus: http.StatusBadRequest,
+			}
+		case "InvalidPartOrder":
+			return &S3Error{
+				Code:       "InvalidPartOrder",
+				Message:    "The list of parts was not in ascending order. The parts list must be specified in order by part number.",
+				Resource:   resource,
+				RequestID:  requestID,
+				HTTPStatus: http.StatusBadRequest,
+			}
+		case "ConditionalRequestConflict":
+			return &S3Error{
+				Code:       "ConditionalRequestConflict",
+				Message:    "A conflicting conditional operation is currently in progress against this resource.",
+				Resource:   resource,
+				RequestID:  requestID,
+				HTTPStatus: http.StatusConflict,
+			}
 		case "InvalidArgument":
 			// SECURITY: Deliberately do NOT include apiErr.ErrorMessage() in
 			// the response. The ErrorMessage comes from the backend and is
@@ -133,6 +194,10 @@ func TranslateError(err error, bucket, key string) *S3Error {
 		}
 	}
 
+	if c := classOf(err); c != nil {
+		return c.s3Error(resource, requestID)
+	}
+
 	// Default to internal error.
 	//
 	// SECURITY: Do NOT embed err.Error() or %v-formatted err into the Message.
@@ -154,6 +219,81 @@ func TranslateError(err error, bucket, key string) *S3Error {
 	}
 }
 
+// errorClass maps one of the typed errors exported by the crypto and s3
+// packages to the S3 error a client sees and the error_type label used in
+// metrics. The messages are fixed strings for the same reason as in the
+// default branch of TranslateError.
+type errorClass struct {
+	target     error
+	code       string
+	message    string
+	httpStatus int
+	label      string
+}
+
+func (c *errorClass) s3Error(resource, requestID string) *S3Error {
+	return &S3Error{
+		Code:       c.code,
+		Message:    c.message,
+		Resource:   resource,
+		RequestID:  requestID,
+		HTTPStatus: c.httpStatus,
+	}
+}
+
+// errorClasses is checked in order; the first class err matches wins.
+var errorClasses = []*errorClass{
+	{crypto.ErrShredded, "InvalidObjectState", "The object's encryption key has been destroyed.", http.StatusForbidden, "shredded"},
+	{crypto.ErrKMSUnavailable, "ServiceUnavailable", "The key management service is unavailable. Please try again.", http.StatusServiceUnavailable, "kms_unavailable"},
+	{crypto.ErrUnwrapFailed, "InternalError", "We encountered an internal error. Please try again.", http.StatusInternalServerError, "kms_unwrap_failed"},
+	{crypto.ErrKeyNotFound, "InternalError", "We encountered an internal error. Please try again.", http.StatusInternalServerError, "kms_unwrap_failed"},
+	{crypto.ErrFormatTooNew, "InternalError", "We encountered an internal error. Please try again.", http.StatusInternalServerError, "format_too_new"},
+	{crypto.ErrManifestCorrupt, "InternalError", "We encountered an internal error. Please try again.", http.StatusInternalServerError, "manifest_corrupt"},
+	{crypto.ErrIntegrity, "InternalError", "We encountered an internal error. Please try again.", http.StatusInternalServerError, "auth_tag_mismatch"},
+	{s3.ErrThrottled, "SlowDown", "Please reduce your request rate.", http.StatusServiceUnavailable, "throttled"},
+	{s3.ErrNotFound, "NoSuchKey", "The specified key does not exist.", http.StatusNotFound, "not_found"},
+	{storage.ErrNotSupported, "NotImplemented", "A header you provided implies functionality that is not implemented.", http.StatusNotImplemented, "not_supported"},
+	{errMetadataTooLarge, "MetadataTooLarge", "Your metadata headers exceed the maximum allowed metadata size.", http.StatusBadRequest, "metadata_too_large"},
+}
+
+func classOf(err error) *errorClass {
+	for _, c := range errorClasses {
+		if errors.Is(err, c.target) {
+			return c
+		}
+	}
+	return nil
+}
+
+// ErrorType returns the metrics error_type label for err, or fallback when
+// err is not one of the typed errors in errorClasses. A cancelled or timed
+// out request is reported as context_canceled whatever layer noticed it, so
+// client disconnects do not show up as corruption or KMS failures.
+func ErrorType(err error, fallback string) string {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return "context_canceled"
+	}
+	if c := classOf(err); c != nil {
+		return c.label
+	}
+	return fallback
+}
+
+// translateCryptoError builds the response for a failed encrypt or decrypt.
+// Classes with their own status (a KMS outage is 503) keep it; everything
+// else is an InternalError carrying msg.
+func translateCryptoError(err error, resource, msg string) *S3Error {
+	if c := classOf(err); c != nil && c.httpStatus != http.StatusInternalServerError {
+		return c.s3Error(resource, "")
+	}
+	return &S3Error{
+		Code:       "InternalError",
+		Message:    msg,
+		Resource:   resource,
+		HTTPStatus: http.StatusInternalServerError,
+	}
+}
+
 // isS3NotFoundError reports whether err represents an S3 "not found" condition
 // (NoSuchKey or NotFound). This is used to treat missing companion objects
 // (e.g. MPU manifests) as no-ops during cleanup.
@@ -172,11 +312,10 @@ func isS3NotFoundError(err error) bool {
 	return strings.Contains(msg, "NoSuchKey") || strings.Contains(msg, "NotFound")
 }
 
-// extractRequestID attempts to extract request ID from error.
+// extractRequestID returns the backend request ID err carries, if any.
 func extractRequestID(err error) string {
-	// Request ID extraction from AWS SDK errors would go here
-	// For now, return empty string
-	return ""
+	id, _ := s3.BackendRequestIDFromError(err)
+	return id.RequestID
 }
 
 // Predefined S3 errors
@@ -7,6 +7,11 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/middleware"
+	"github.com/sirupsen/logrus"
 )
 
 // TestS3Error_Error verifies the error message format.
@@ -183,6 +188,22 @@ func TestTranslateError_InternalError(t *testing.T) {
 	}
 }
 
+type backendRespErr struct{ error }
+
+func (backendRespErr) ServiceRequestID() string { return "BACKEND-REQ" }
+
+// TestTranslateError_BackendRequestID verifies that the backend's request ID
+// is carried into the error response.
+func TestTranslateError_BackendRequestID(t *testing.T) {
+	err := fmt.Errorf("failed to get object b/k: %w", backendRespErr{fmt.Errorf("boom")})
+	if got := TranslateError(err, "b", "k").RequestID; got != "BACKEND-REQ" {
+		t.Errorf("RequestID = %q, want BACKEND-REQ", got)
+	}
+	if got := TranslateError(fmt.Errorf("boom"), "b", "k").RequestID; got != "" {
+		t.Errorf("RequestID = %q without a backend response", got)
+	}
+}
+
 // TestPredefinedErrors_Shape verifies that predefined S3 error vars have the
 // expected code, message, and HTTP status.
 func TestPredefinedErrors_Shape(t *testing.T) {
@@ -233,3 +254,53 @@ func TestS3Error_WriteXML_EmptyResource(t *testing.T) {
 		t.Errorf("WriteXML() included empty <Resource> element: %s", body)
 	}
 }
+
+// TestS3Error_WriteXML_RequestID verifies that errors without a request ID
+// of their own carry the gateway's.
+func TestS3Error_WriteXML_RequestID(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set(middleware.RequestIDHeader, "0123456789ABCDEF")
+	(&S3Error{Code: "NoSuchKey", Message: "gone", HTTPStatus: http.StatusNotFound}).WriteXML(w)
+	if !strings.Contains(w.Body.String(), "<RequestId>0123456789ABCDEF</RequestId>") {
+		t.Errorf("gateway request ID missing: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	w.Header().Set(middleware.RequestIDHeader, "0123456789ABCDEF")
+	(&S3Error{Code: "NoSuchKey", Message: "gone", RequestID: "BACKEND", HTTPStatus: http.StatusNotFound}).WriteXML(w)
+	if !strings.Contains(w.Body.String(), "<RequestId>BACKEND</RequestId>") {
+		t.Errorf("backend request ID replaced: %s", w.Body.String())
+	}
+}
+
+// TestRegisterRoutes_UnmatchedRequestsGetXML verifies that requests no route
+// serves are answered with S3 errors rather than the router's plain text.
+func TestRegisterRoutes_UnmatchedRequestsGetXML(t *testing.T) {
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	router := mux.NewRouter()
+	NewHandler(newMockS3Client(), engine, logrus.New(), getTestMetrics()).RegisterRoutes(router)
+
+	tests := []struct {
+		method, target string
+		status         int
+		code           string
+	}{
+		{"GET", "/bucket/", http.StatusNotFound, "NoSuchKey"},
+		{"PATCH", "/bucket/key", http.StatusMethodNotAllowed, "MethodNotAllowed"},
+		{"PATCH", "/bucket", http.StatusMethodNotAllowed, "MethodNotAllowed"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+		var body struct {
+			Code     string `xml:"Code"`
+			Resource string `xml:"Resource"`
+		}
+		if err := xml.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s %s: %v in %q", tt.method, tt.target, err, w.Body.String())
+		}
+		if w.Code != tt.status || body.Code != tt.code || body.Resource != tt.target {
+			t.Errorf("%s %s = %d %+v", tt.method, tt.target, w.Code, body)
+		}
+	}
+}
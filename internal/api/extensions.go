@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/sandbox"
+)
+
+// WithMetadataHooks runs metadata extension modules over the user metadata
+// of PutObject and CopyObject, before it is sealed and the object encrypted.
+func (h *Handler) WithMetadataHooks(hooks []*sandbox.Hook) {
+	h.metadataHooks = hooks
+}
+
+// runMetadataHooks passes the x-amz-meta-* entries of metadata through
+// every matching metadata module in turn and returns metadata with them
+// replaced by the last module's output; other entries are kept. A failing
+// module fails the write: it reports false after writing the error
+// response.
+func (h *Handler) runMetadataHooks(w http.ResponseWriter, r *http.Request, operation, bucket, key, contentType string, metadata map[string]string, start time.Time) (map[string]string, bool) {
+	var matched bool
+	user := make(map[string]string)
+	for k, v := range metadata {
+		if strings.HasPrefix(strings.ToLower(k), "x-amz-meta-") {
+			user[k] = v
+		}
+	}
+	for _, hook := range h.metadataHooks {
+		if !hook.Matches(bucket, key) {
+			continue
+		}
+		matched = true
+		var err error
+		user, err = sandbox.TransformMetadata(r.Context(), hook.Module, sandbox.MetadataRequest{
+			Operation:   operation,
+			Bucket:      bucket,
+			Key:         key,
+			ContentType: contentType,
+			Metadata:    user,
+		})
+		if err != nil {
+			h.logger.WithError(err).WithFields(logrus.Fields{
+				"bucket": bucket,
+				"key":    key,
+				"module": hook.Module.Name(),
+			}).Error("Metadata extension failed")
+			s3Err := &S3Error{
+				Code:       "InternalError",
+				Message:    "Metadata extension failed",
+				Resource:   r.URL.Path,
+				HTTPStatus: http.StatusInternalServerError,
+			}
+			s3Err.WriteXML(w)
+			h.metrics.RecordHTTPRequest(r.Context(), r.Method, r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+			return nil, false
+		}
+	}
+	if !matched {
+		return metadata, true
+	}
+	out := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		if !strings.HasPrefix(strings.ToLower(k), "x-amz-meta-") {
+			out[k] = v
+		}
+	}
+	for k, v := range user {
+		out[strings.ToLower(k)] = v
+	}
+	return out, true
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
+	"github.com/kenneth/s3-encryption-gateway/internal/sandbox"
+)
+
+// stampModule adds x-amz-meta-stamped: <operation> and fails for the key
+// "broken".
+type stampModule struct{ seen []sandbox.MetadataRequest }
+
+func (m *stampModule) Name() string                { return "stamp" }
+func (m *stampModule) Exports(fn string) bool      { return fn == sandbox.ExportMetadata }
+func (m *stampModule) Close(context.Context) error { return nil }
+
+func (m *stampModule) Call(_ context.Context, _ string, input []byte) ([]byte, error) {
+	var req sandbox.MetadataRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, err
+	}
+	m.seen = append(m.seen, req)
+	if req.Key == "broken" {
+		return nil, errors.New("trap")
+	}
+	out := map[string]string{}
+	for k, v := range req.Metadata {
+		out[k] = v
+	}
+	out["x-amz-meta-stamped"] = req.Operation
+	return json.Marshal(out)
+}
+
+func TestMetadataHooks(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	mockClient := newMockS3Client()
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	h := NewHandlerWithFeatures(mockClient, engine, logger, metrics.NewMetricsWithRegistry(prometheus.NewRegistry()), nil, nil, nil, nil, nil)
+	mod := &stampModule{}
+	h.WithMetadataHooks([]*sandbox.Hook{{Module: mod, Bucket: "b"}})
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	head := func(path string) http.Header {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodHead, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("HEAD %s: %d", path, w.Code)
+		}
+		return w.Header()
+	}
+
+	if w := putBody(router, "/b/a.txt", "hello", map[string]string{"Content-Type": "text/plain", "X-Amz-Meta-Owner": "alice"}); w.Code != http.StatusOK {
+		t.Fatalf("PUT: %d %s", w.Code, w.Body.String())
+	}
+	hdr := head("/b/a.txt")
+	if hdr.Get("X-Amz-Meta-Stamped") != "PutObject" || hdr.Get("X-Amz-Meta-Owner") != "alice" {
+		t.Errorf("stored metadata = %v", hdr)
+	}
+	if got := mod.seen[0]; got.ContentType != "text/plain" || got.Metadata["x-amz-meta-owner"] != "alice" || len(got.Metadata) != 1 {
+		t.Errorf("module received %+v", got)
+	}
+
+	copyReq := httptest.NewRequest(http.MethodPut, "/b/copy.txt", nil)
+	copyReq.Header.Set("X-Amz-Copy-Source", "/b/a.txt")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, copyReq)
+	if w.Code != http.StatusOK {
+		t.Fatalf("copy: %d %s", w.Code, w.Body.String())
+	}
+	if got := head("/b/copy.txt").Get("X-Amz-Meta-Stamped"); got != "CopyObject" {
+		t.Errorf("copy stamped %q", got)
+	}
+
+	if w := putBody(router, "/b/broken", "x", nil); w.Code != http.StatusInternalServerError {
+		t.Errorf("failing module: status %d, want 500", w.Code)
+	}
+	if _, ok := mockClient.objects["b/broken"]; ok {
+		t.Error("write reached the backend after the module failed")
+	}
+
+	// Other buckets are not passed to the module.
+	n := len(mod.seen)
+	putBody(router, "/other/a.txt", "x", nil)
+	if len(mod.seen) != n {
+		t.Error("module ran outside its bucket")
+	}
+}
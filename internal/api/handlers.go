@@ -17,17 +17,35 @@ import (
 	"syscall"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/kenneth/s3-encryption-gateway/internal/accessstats"
 	"github.com/kenneth/s3-encryption-gateway/internal/admin"
 	"github.com/kenneth/s3-encryption-gateway/internal/audit"
+	"github.com/kenneth/s3-encryption-gateway/internal/authz"
 	"github.com/kenneth/s3-encryption-gateway/internal/cache"
 	"github.com/kenneth/s3-encryption-gateway/internal/config"
 	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/dlp"
+	"github.com/kenneth/s3-encryption-gateway/internal/featureflag"
+	"github.com/kenneth/s3-encryption-gateway/internal/hooks"
+	"github.com/kenneth/s3-encryption-gateway/internal/identity"
+	"github.com/kenneth/s3-encryption-gateway/internal/journal"
+	"github.com/kenneth/s3-encryption-gateway/internal/keyhold"
+	"github.com/kenneth/s3-encryption-gateway/internal/membudget"
 	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
+	"github.com/kenneth/s3-encryption-gateway/internal/sandbox"
 	"github.com/kenneth/s3-encryption-gateway/internal/mpu"
+	"github.com/kenneth/s3-encryption-gateway/internal/quota"
 	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/kenneth/s3-encryption-gateway/internal/scan"
+	"github.com/kenneth/s3-encryption-gateway/internal/sizeindex"
+	"github.com/kenneth/s3-encryption-gateway/internal/tiering"
+	"github.com/kenneth/s3-encryption-gateway/internal/transform"
+	"github.com/kenneth/s3-encryption-gateway/internal/trash"
+	"github.com/kenneth/s3-encryption-gateway/internal/upgrade"
 	"github.com/sirupsen/logrus"
 )
 
@@ -43,8 +61,37 @@ type Handler struct {
 	auditLogger      audit.Logger
 	config           *config.Config
 	policyManager    *config.PolicyManager
-	engineCache      *ttlEngineCache // TTL cache for per-policy engines (V1.0-SEC-20)
-	mpuStateStore    mpu.StateStore  // nil when encrypted MPU is not configured
+	engineCache      *ttlEngineCache        // TTL cache for per-policy engines (V1.0-SEC-20)
+	mpuStateStore    mpu.StateStore         // nil when encrypted MPU is not configured
+	journal          *journal.Journal       // nil when the write-ahead journal is disabled
+	keyLocks         *keyLocker             // same-key write serialisation (optimistic conditional writes)
+	listSizes        *listSizeResolver      // nil when ListObjects size enrichment is disabled
+	sizeIndex        *sizeindex.Index       // nil when the sidecar size index is disabled
+	accessStats      *accessstats.Tracker   // nil when access counters are disabled
+	keyHolds         *keyhold.Store         // nil when key holds are disabled
+	trash            *trash.Bin             // nil when deletes are not deferred
+	quotas           *quota.Manager         // nil when no quotas are enforced
+	authzPolicy      *authz.Policy          // nil when every authenticated client may do anything
+	metaSealer       *crypto.MetadataSealer // nil when user metadata is stored as sent
+	marker           *crypto.ObjectMarker   // nil when objects carry no identity marker
+	keyCodec         s3.KeyCodec            // nil unless object keys are obfuscated on the backend
+	metaCodec        s3.MetadataCodec       // nil unless gateway metadata uses a custom prefix
+	hooks            *hooks.Pipeline        // nil when post-PUT hooks are disabled
+	upgrader         *upgrade.Upgrader      // nil when legacy objects are not upgraded on read
+	scanner          scan.Scanner           // nil when uploads are not malware-scanned
+	scanCfg          config.ScanningConfig
+	inspector        *dlp.Inspector // nil when upload content inspection is disabled
+	inspectTagKey    string
+	transformer      *transform.Transformer // nil when GET responses are returned as stored
+	metadataHooks    []*sandbox.Hook        // metadata extension modules, run on PutObject and CopyObject
+	rangeHedger      *s3.RangeHedger   // nil when range fetches are not hedged
+	headMeta         *headMetaCache    // nil when range reads always HEAD first
+	featureFlags     *featureflag.Set  // nil when every flag is off
+	memBudget        *membudget.Budget // nil when buffers are not budgeted
+	readyChecks      []metrics.ReadyCheck
+	configCheck      func(context.Context) error // nil when the configuration is never reloaded
+	componentsOnce   sync.Once
+	components       map[string]metrics.ReadyCheck // built by componentChecks
 }
 
 // NewHandler creates a new API handler (backward compatibility).
@@ -74,11 +121,19 @@ func NewHandlerWithFeatures(
 		auditLogger:      auditLogger,
 		config:           config,
 		policyManager:    policyManager,
+		keyLocks:         newKeyLocker(),
+		listSizes:        newListSizeResolver(listEnrichmentConfig(config)),
 	}
 	// Create client factory for per-request credential support.
 	// V0.6-PERF-2: inject metrics so the factory can emit retry counters.
 	if config != nil {
-		h.clientFactory = s3.NewClientFactory(&config.Backend, s3.WithMetrics(m))
+		// Per-request clients only reach backend, so with sharding every
+		// call goes through the sharded client passed in.
+		if usesClientFactory(&config.Backend) && !config.Sharding.Enabled {
+			h.clientFactory = s3.NewClientFactory(&config.Backend, s3.WithMetrics(m))
+		}
+		h.rangeHedger = s3.NewRangeHedger(config.Backend.RangeHedging, m)
+		h.headMeta = newHeadMetaCache(config.Backend.HeadElision)
 	}
 	if policyManager != nil {
 		// Initialise the TTL cache with a 1-hour default TTL and 5-minute sweep.
@@ -93,6 +148,197 @@ func (h *Handler) WithMPUStateStore(store mpu.StateStore) {
 	h.mpuStateStore = store
 }
 
+// WithJournal attaches a write-ahead journal. Object writes are recorded
+// before they are sent to the backend and marked complete afterwards.
+func (h *Handler) WithJournal(j *journal.Journal) {
+	h.journal = j
+}
+
+// WithAccessStats attaches per-object read counters. Successful GETs are
+// counted and deletes drop the object's counters.
+func (h *Handler) WithAccessStats(t *accessstats.Tracker) {
+	h.accessStats = t
+}
+
+// WithQuotas attaches quota enforcement. Writes that would exceed a
+// bucket's or tenant's quota are refused, and successful ones are counted.
+func (h *Handler) WithQuotas(q *quota.Manager) {
+	h.quotas = q
+}
+
+// WithAuthorization attaches the per-client authorization policy. The
+// authorization middleware enforces it on whole requests; the handler only
+// checks the individual keys of batch deletes.
+func (h *Handler) WithAuthorization(p *authz.Policy) {
+	h.authzPolicy = p
+}
+
+// WithKeyHolds attaches the key hold list. Held objects are never shredded
+// and the hold list object is hidden from listings.
+func (h *Handler) WithKeyHolds(s *keyhold.Store) {
+	h.keyHolds = s
+}
+
+// WithFeatureFlags attaches the feature flags gating staged behaviours.
+func (h *Handler) WithFeatureFlags(f *featureflag.Set) {
+	h.featureFlags = f
+}
+
+// WithSizeIndex attaches the sidecar size index. Object writes and deletes
+// update it, and ListObjects enrichment consults it before issuing HEADs.
+func (h *Handler) WithSizeIndex(ix *sizeindex.Index) {
+	h.sizeIndex = ix
+	if h.listSizes != nil {
+		h.listSizes.index = ix
+	}
+}
+
+// WithMetadataSealer attaches the user-metadata sealer. Writes seal
+// x-amz-meta-* values according to its mode; reads always unseal.
+func (h *Handler) WithMetadataSealer(s *crypto.MetadataSealer) {
+	h.metaSealer = s
+}
+
+// WithHooks attaches the post-PUT hook pipeline.
+func (h *Handler) WithHooks(p *hooks.Pipeline) {
+	h.hooks = p
+}
+
+// WithReadyCheck adds a check to /ready alongside the built-in KMS and
+// Valkey checks.
+func (h *Handler) WithReadyCheck(c metrics.ReadyCheck) {
+	h.readyChecks = append(h.readyChecks, c)
+}
+
+// WithKeyCodec makes every backend call address objects by their encoded
+// name. It wraps the handler's client and every client from the factory.
+func (h *Handler) WithKeyCodec(codec s3.KeyCodec) {
+	h.keyCodec = codec
+	if h.s3Client != nil && codec != nil {
+		h.s3Client = s3.NewKeyMappingClient(h.s3Client, codec)
+	}
+}
+
+// WithMetadataCodec stores gateway metadata under the codec's names on the
+// backend. Like WithKeyCodec it wraps the handler's client and every client
+// from the factory.
+func (h *Handler) WithMetadataCodec(codec s3.MetadataCodec) {
+	h.metaCodec = codec
+	if h.s3Client != nil && codec != nil {
+		h.s3Client = s3.NewMetadataMappingClient(h.s3Client, codec)
+	}
+}
+
+// noteFormatSkew logs and counts a read that failed because the object was
+// written in a newer on-disk format version. During a rolling upgrade that
+// means an upgraded replica wrote it and this one still runs the old build.
+func (h *Handler) noteFormatSkew(err error, bucket, key string) {
+	var fe *crypto.FormatVersionError
+	if !errors.As(err, &fe) {
+		return
+	}
+	h.metrics.RecordFormatVersionSkew(fe.Kind)
+	h.logger.WithFields(logrus.Fields{
+		"bucket":        bucket,
+		"key":           key,
+		"format":        fe.Kind,
+		"version":       fe.Version,
+		"max_supported": fe.Max,
+	}).Warn("Object was written in a newer format version than this gateway reads; upgrade this replica")
+}
+
+// unsealMetadata restores sealed user metadata read from the backend. A
+// sealed entry that fails authentication is dropped and logged rather than
+// failing the request: the object body is still intact and readable.
+func (h *Handler) unsealMetadata(bucket, key string, metadata map[string]string) map[string]string {
+	out, err := h.metaSealer.Unseal(metadata)
+	if err != nil {
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"bucket": bucket,
+			"key":    key,
+		}).Warn("Failed to unseal object metadata")
+	}
+	return out
+}
+
+// indexWrite queues the plaintext size of a freshly written object for the
+// sidecar index. Writes whose size is not known up front drop any stale
+// entry instead.
+func (h *Handler) indexWrite(bucket, key string, encMetadata map[string]string) {
+	if e, ok := sizeindex.EntryFromMetadata(encMetadata); ok {
+		h.sizeIndex.Record(bucket, key, e)
+		return
+	}
+	h.sizeIndex.Remove(bucket, key)
+}
+
+// isReservedKey reports whether bucket/key belongs to one of the gateway's
+// own bookkeeping objects rather than to a client.
+func (h *Handler) isReservedKey(bucket, key string) bool {
+	return h.sizeIndex.IsIndexKey(key) || h.accessStats.IsStatsKey(key) || h.keyHolds.IsHoldKey(bucket, key)
+}
+
+// guardReservedKeys refuses object requests that name one of the gateway's
+// own bookkeeping objects, as the target or as the copy source, with 403
+// AccessDenied. Listings hide those objects separately.
+func (h *Handler) guardReservedKeys(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		vars := mux.Vars(r)
+		reserved := vars["key"] != "" && h.isReservedKey(vars["bucket"], vars["key"])
+		if src := r.Header.Get("x-amz-copy-source"); src != "" && !reserved {
+			reserved = h.isReservedCopySource(src)
+		}
+		if !reserved {
+			next.ServeHTTP(w, r)
+			return
+		}
+		s3Err := *ErrAccessDenied
+		s3Err.Resource = r.URL.Path
+		s3Err.WriteXML(w)
+		h.metrics.RecordHTTPRequest(r.Context(), r.Method, r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+	})
+}
+
+// isReservedCopySource reports whether an x-amz-copy-source header names a
+// reserved object, in either its raw or its URL-decoded form.
+func (h *Handler) isReservedCopySource(copySource string) bool {
+	sources := []string{copySource}
+	if decoded, err := url.PathUnescape(copySource); err == nil && decoded != copySource {
+		sources = append(sources, decoded)
+	}
+	for _, src := range sources {
+		if bucket, key, _, err := parseCopySource(src); err == nil && h.isReservedKey(bucket, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// beginJournal records the intent to write bucket/key. If the journal cannot
+// be written the request is failed — proceeding would silently drop the
+// crash-consistency guarantee the operator opted into — and ok is false.
+func (h *Handler) beginJournal(w http.ResponseWriter, r *http.Request, operation, bucket, key string, encrypted bool, start time.Time) (id string, ok bool) {
+	id, err := h.journal.Begin(operation, bucket, key, encrypted)
+	if err != nil {
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"bucket":    bucket,
+			"key":       key,
+			"operation": operation,
+		}).Error("Failed to write journal record")
+		s3Err := &S3Error{
+			Code:       "InternalError",
+			Message:    "We encountered an internal error. Please try again.",
+			Resource:   r.URL.Path,
+			HTTPStatus: http.StatusInternalServerError,
+		}
+		s3Err.WriteXML(w)
+		h.metrics.RecordHTTPRequest(r.Context(), r.Method, r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return "", false
+	}
+	return id, true
+}
+
 // Close stops the per-policy engine cache sweeper and calls Close() on every
 // cached engine so that password bytes are zeroised (V1.0-SEC-20).
 func (h *Handler) Close() {
@@ -222,13 +468,18 @@ func (h *Handler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/healthz", h.handleHealth).Methods("GET") // k8s-convention alias
 	r.HandleFunc("/ready", h.handleReady).Methods("GET")
 	r.HandleFunc("/readyz", h.handleReady).Methods("GET") // k8s-convention alias
+	for _, name := range readyComponents {
+		r.HandleFunc("/ready/"+name, h.handleReadyComponent(name)).Methods("GET")
+	}
 	r.HandleFunc("/live", h.handleLive).Methods("GET")
 	r.HandleFunc("/livez", h.handleLive).Methods("GET") // k8s-convention alias
+	r.HandleFunc("/version", h.handleVersion).Methods("GET")
 
 	r.HandleFunc("/", h.handleListBuckets).Methods("GET")
 
 	// S3 API routes
 	s3Router := r.PathPrefix("/").Subrouter()
+	s3Router.Use(h.guardReservedKeys)
 
 	// Multipart upload routes (must be registered first to ensure query parameter matching)
 	s3Router.HandleFunc("/{bucket:[^/]+}/{key:.+}", h.handleCreateMultipartUpload).Methods("POST").Queries("uploads", "")
@@ -321,6 +572,11 @@ func (h *Handler) RegisterRoutes(r *mux.Router) {
 	s3Router.HandleFunc("/{bucket}", h.handleGetBucketLogging).Methods("GET").Queries("logging", "")
 	s3Router.HandleFunc("/{bucket}", h.handlePutBucketLogging).Methods("PUT").Queries("logging", "")
 
+	// Bucket tagging subresources
+	s3Router.HandleFunc("/{bucket}", h.handleGetBucketTagging).Methods("GET").Queries("tagging", "")
+	s3Router.HandleFunc("/{bucket}", h.handlePutBucketTagging).Methods("PUT").Queries("tagging", "")
+	s3Router.HandleFunc("/{bucket}", h.handleDeleteBucketTagging).Methods("DELETE").Queries("tagging", "")
+
 	// Bucket requestPayment subresources
 	s3Router.HandleFunc("/{bucket}", h.handleGetBucketRequestPayment).Methods("GET").Queries("requestPayment", "")
 	s3Router.HandleFunc("/{bucket}", h.handlePutBucketRequestPayment).Methods("PUT").Queries("requestPayment", "")
@@ -352,6 +608,24 @@ func (h *Handler) RegisterRoutes(r *mux.Router) {
 
 	// Batch operations
 	s3Router.HandleFunc("/{bucket}", h.handleDeleteObjects).Methods("POST").Queries("delete", "")
+
+	// Requests no route serves get S3 errors rather than the router's
+	// plain-text 404.
+	r.NotFoundHandler = http.HandlerFunc(h.handleUnrouted)
+}
+
+// handleUnrouted answers a request no route serves: MethodNotAllowed for a
+// method S3 does not use, NoSuchKey otherwise. The catch-all S3 subrouter
+// keeps gorilla/mux from ever reporting a method mismatch itself.
+func (h *Handler) handleUnrouted(w http.ResponseWriter, r *http.Request) {
+	s3Err := *ErrNoSuchKey
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPost, http.MethodDelete, http.MethodOptions:
+	default:
+		s3Err = *ErrMethodNotAllowed
+	}
+	s3Err.Resource = r.URL.Path
+	s3Err.WriteXML(w)
 }
 
 // writeS3ClientError writes an appropriate S3 error response for client
@@ -407,6 +681,34 @@ func classifyAuthError(err error, resource string) *S3Error {
 			Resource:   resource,
 			HTTPStatus: http.StatusForbidden,
 		}
+	case errors.Is(err, ErrInvalidToken):
+		return &S3Error{
+			Code:       "InvalidToken",
+			Message:    "The provided token is malformed or otherwise invalid.",
+			Resource:   resource,
+			HTTPStatus: http.StatusBadRequest,
+		}
+	case errors.Is(err, ErrExpiredToken):
+		return &S3Error{
+			Code:       "ExpiredToken",
+			Message:    "The provided token has expired.",
+			Resource:   resource,
+			HTTPStatus: http.StatusBadRequest,
+		}
+	case errors.Is(err, ErrRequestTimeTooSkewed):
+		return &S3Error{
+			Code:       "RequestTimeTooSkewed",
+			Message:    "The difference between the request time and the server's time is too large.",
+			Resource:   resource,
+			HTTPStatus: http.StatusForbidden,
+		}
+	case errors.Is(err, ErrReplayedRequest):
+		return &S3Error{
+			Code:       "AccessDenied",
+			Message:    "The request signature has already been used.",
+			Resource:   resource,
+			HTTPStatus: http.StatusForbidden,
+		}
 	case errors.Is(err, ErrMissingCredentials):
 		return &S3Error{
 			Code:       "AccessDenied",
@@ -538,28 +840,40 @@ func (h *Handler) forwardSignatureV4Request(w http.ResponseWriter, r *http.Reque
 	}).Debug("Forwarding Signature V4 request to backend")
 
 	// Make request to backend
-	httpClient := &http.Client{
-		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
-			// V1.0-SEC-F6: enforce minimum TLS 1.2 and restricted cipher
-			// suites consistent with the main S3 client and Cosmian KMS
-			// client. The bare &http.Client{} default uses Go's
-			// http.DefaultTransport which has no cipher restrictions.
-			TLSClientConfig: &tls.Config{
-				MinVersion: tls.VersionTLS12,
-				CipherSuites: []uint16{
-					tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-					tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
-					tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
-					tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
-				},
-				CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+	transport := &http.Transport{
+		// V1.0-SEC-F6: enforce minimum TLS 1.2 and restricted cipher
+		// suites consistent with the main S3 client and Cosmian KMS
+		// client. The bare &http.Client{} default uses Go's
+		// http.DefaultTransport which has no cipher restrictions.
+		TLSClientConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			CipherSuites: []uint16{
+				tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+				tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
 			},
-			IdleConnTimeout:       90 * time.Second,
-			ResponseHeaderTimeout: 10 * time.Second,
-			MaxIdleConnsPerHost:   10,
+			CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
 		},
+		IdleConnTimeout:       90 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+		MaxIdleConnsPerHost:   10,
+	}
+	if h.clientFactory != nil {
+		if err := h.clientFactory.ConfigureTransport(transport); err != nil {
+			h.logger.WithError(err).Error("Invalid backend transport configuration")
+			s3Err := &S3Error{
+				Code:       "InternalError",
+				Message:    "Backend connection is misconfigured",
+				Resource:   r.URL.Path,
+				HTTPStatus: http.StatusInternalServerError,
+			}
+			s3Err.WriteXML(w)
+			h.metrics.RecordHTTPRequest(r.Context(), method, r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+			return
+		}
 	}
+	httpClient := &http.Client{Timeout: 30 * time.Second, Transport: transport}
 	backendResp, err := httpClient.Do(backendReq)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to forward request to backend")
@@ -665,9 +979,9 @@ func (h *Handler) forwardSignatureV4Request(w http.ResponseWriter, r *http.Reque
 		proxyWriteTimeout = h.config.Server.WriteTimeout
 	}
 	if isEncrypted && decryptedReader != nil {
-		copyWithDeadlineRefresh(w, decryptedReader, proxyWriteTimeout)
+		streamResponse(r.Context(), w, decryptedReader, proxyWriteTimeout)
 	} else {
-		copyWithDeadlineRefresh(w, backendResp.Body, proxyWriteTimeout)
+		streamResponse(r.Context(), w, backendResp.Body, proxyWriteTimeout)
 	}
 
 	// Record metrics - use 0 if ContentLength is unknown (-1)
@@ -686,11 +1000,24 @@ func (h *Handler) getS3Client(r *http.Request) (s3.Client, error) {
 		return h.s3Client, nil
 	}
 	if h.clientFactory != nil {
-		return h.clientFactory.GetClient()
+		return h.factoryClient()
 	}
 	return nil, fmt.Errorf("no S3 client available")
 }
 
+// factoryClient returns a per-request client from the factory, with key and
+// metadata translation applied when configured.
+func (h *Handler) factoryClient() (s3.Client, error) {
+	client, err := h.clientFactory.GetClient()
+	if err != nil {
+		return nil, err
+	}
+	if h.metaCodec != nil {
+		client = s3.NewMetadataMappingClient(client, h.metaCodec)
+	}
+	return s3.NewKeyMappingClient(client, h.keyCodec), nil
+}
+
 // getEncryptionEngine returns the appropriate encryption engine for the bucket.
 // It checks if a specific policy exists for the bucket and returns a configured engine,
 // otherwise returns the default global engine.
@@ -730,6 +1057,7 @@ func (h *Handler) getEncryptionEngine(bucket string) (crypto.EncryptionEngine, e
 			effectiveConfig.Compression.ContentTypes,
 			effectiveConfig.Compression.Algorithm,
 			effectiveConfig.Compression.Level,
+			crypto.WithMaxRatio(effectiveConfig.Compression.MaxRatio),
 		)
 	}
 
@@ -793,23 +1121,40 @@ func (h *Handler) handleHealth(w http.ResponseWriter, r *http.Request) {
 	h.metrics.RecordHTTPRequest(r.Context(), "GET", "/health", http.StatusOK, time.Since(start), 0)
 }
 
+// handleVersion serves the build information and enabled features.
+func (h *Handler) handleVersion(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	metrics.VersionHandler()(w, r)
+	h.metrics.RecordHTTPRequest(r.Context(), "GET", "/version", http.StatusOK, time.Since(start), 0)
+}
+
 // handleReady handles readiness check requests.
-// It runs a health check against every configured dependency (KMS, Valkey state
-// store) and returns 503 if any check fails, 200 otherwise. The response body
+// It runs a health check against every configured dependency (KMS, backend,
+// Valkey state store, checks added with WithReadyCheck) and returns 503 if any check fails, 200 otherwise. The response body
 // includes a per-component "checks" map so Kubernetes and operators can see
-// exactly which dependency is unhealthy.
+// exactly which dependency is unhealthy. The configuration check is only
+// served on /ready/config: a rejected reload leaves the gateway serving with
+// its previous configuration, which is no reason to take it out of service.
 func (h *Handler) handleReady(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 
-	// Build the list of named dependency checks. Only add a check when the
-	// dependency is actually configured — omitting it keeps the map clean for
-	// deployments that don't use that optional feature.
+	// Wrap w so we can read back the status code for the metric without
+	// re-running every health check a second time.
+	rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+	metrics.ReadinessHandler(h.readinessChecks()...)(rec, r)
+	h.metrics.RecordHTTPRequest(r.Context(), "GET", "/readyz", rec.code, time.Since(start), 0)
+}
+
+// readinessChecks lists the dependency checks behind /ready. Only add a
+// check when the dependency is actually configured — omitting it keeps the
+// map clean for deployments that don't use that optional feature.
+func (h *Handler) readinessChecks() []metrics.ReadyCheck {
 	var checks []metrics.ReadyCheck
-	if h.keyManager != nil {
-		checks = append(checks, metrics.ReadyCheck{
-			Name:  "kms",
-			Check: h.keyManager.HealthCheck,
-		})
+	components := h.componentChecks()
+	for _, name := range []string{"kms", "backend"} {
+		if c, ok := components[name]; ok {
+			checks = append(checks, c)
+		}
 	}
 	if h.mpuStateStore != nil {
 		checks = append(checks, metrics.ReadyCheck{
@@ -821,12 +1166,18 @@ func (h *Handler) handleReady(w http.ResponseWriter, r *http.Request) {
 			},
 		})
 	}
+	return append(checks, h.readyChecks...)
+}
 
-	// Wrap w so we can read back the status code for the metric without
-	// re-running every health check a second time.
-	rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
-	metrics.ReadinessHandler(checks...)(rec, r)
-	h.metrics.RecordHTTPRequest(r.Context(), "GET", "/readyz", rec.code, time.Since(start), 0)
+// Ready runs the /ready checks in-process and returns the first failure,
+// for reporting readiness to a service manager.
+func (h *Handler) Ready(ctx context.Context) error {
+	for _, c := range h.readinessChecks() {
+		if err := c.Check(ctx); err != nil {
+			return fmt.Errorf("%s: %w", c.Name, err)
+		}
+	}
+	return nil
 }
 
 // statusRecorder wraps http.ResponseWriter to capture the written status code.
@@ -876,10 +1227,28 @@ func (h *Handler) handleGetObject(w http.ResponseWriter, r *http.Request) {
 		versionID = &vid
 	}
 
-	// Get range header if present
+	if raw, denied := rawAccess(r); raw || denied {
+		h.serveRaw(w, r, bucket, key, versionID, denied, start)
+		return
+	}
+
+	// A transformed object has no stable byte offsets, so its GETs always
+	// return the whole transformed body.
+	transformRule := h.transformer.Match(bucket, key)
+
+	// Get range header if present. One that is malformed or names several
+	// ranges is ignored and the whole object served, as S3 does.
 	var rangeHeader *string
-	if rg := r.Header.Get("Range"); rg != "" {
-		rangeHeader = &rg
+	if rg := r.Header.Get("Range"); rg != "" && transformRule == nil {
+		if canonical, ok := canonicalRange(rg); ok {
+			rangeHeader = &canonical
+		} else {
+			h.logger.WithFields(logrus.Fields{
+				"bucket": bucket,
+				"key":    key,
+				"range":  rg,
+			}).Debug("Ignoring unusable Range header")
+		}
 	}
 
 	// Get encryption engine for this bucket
@@ -898,20 +1267,14 @@ func (h *Handler) handleGetObject(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check cache first if enabled and no range request
-	if h.cache != nil && rangeHeader == nil && versionID == nil {
+	var staleEntry *cache.CacheEntry
+	if h.cache != nil && rangeHeader == nil && versionID == nil && transformRule == nil {
 		if cachedEntry, ok := h.cache.Get(ctx, bucket, key); ok {
-			// Serve from cache
-			for k, v := range cachedEntry.Metadata {
-				w.Header().Set(k, v)
-			}
-			w.WriteHeader(http.StatusOK)
-			w.Write(cachedEntry.Data)
-			h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, http.StatusOK, time.Since(start), int64(len(cachedEntry.Data)))
-			if h.auditLogger != nil {
-				h.auditLogger.LogAccess("get", bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), true, nil, time.Since(start))
-			}
+			h.serveCachedObject(w, r, bucket, key, cachedEntry, start)
 			return
 		}
+		// An expired copy is revalidated by the backend GET below.
+		staleEntry, _ = h.cache.GetStale(ctx, bucket, key)
 	}
 
 	// If Range is requested, optimize for chunked encryption format
@@ -945,7 +1308,16 @@ func (h *Handler) handleGetObject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if rangeHeader != nil {
+	// With head elision, a chunked object whose layout is cached is fetched
+	// without a HEAD; reader is then already open.
+	var reader io.ReadCloser
+	var metadata map[string]string
+	if rangeHeader != nil && h.headMeta != nil {
+		reader, metadata, plaintextStart, plaintextEnd = h.getCachedRange(ctx, s3Client, bucket, key, versionID, *rangeHeader)
+		useRangeOptimization = reader != nil
+	}
+
+	if rangeHeader != nil && reader == nil {
 		// Determine the backend byte range to request. The decision depends on
 		// the encryption format of the object:
 		//
@@ -964,40 +1336,37 @@ func (h *Handler) handleGetObject(w http.ResponseWriter, r *http.Request) {
 			// MPU-encrypted ranged GET: serve via a dedicated path that maps
 			// the plaintext range to backend ciphertext offsets from the
 			// manifest and fetches only those bytes.
+			if !h.checkMarker(engine, bucket, key, headMeta) {
+				h.rejectUnmarked(w, r, start)
+				return
+			}
+			if h.checkReadPreconditions(w, r, headMeta["ETag"], headMeta["Last-Modified"], start) {
+				return
+			}
 			h.serveMPURangedGet(w, r, ctx, bucket, key, versionID, headMeta, *rangeHeader, s3Client, start)
 			return
 		} else if headErr == nil && engine.IsEncrypted(headMeta) {
 			// Single-PUT chunked or legacy encrypted object.
 			if crypto.IsChunkedFormat(headMeta) {
-				// Get plaintext size for range parsing
-				plaintextSize, err := crypto.GetPlaintextSizeFromMetadata(headMeta)
+				pStart, pEnd, encryptedRange, err := chunkedBackendRange(headMeta, *rangeHeader)
+				if errors.Is(err, crypto.ErrRangeNotSatisfiable) {
+					size, _ := crypto.GetPlaintextSizeFromMetadata(headMeta)
+					h.writeRangeNotSatisfiable(w, r, size, start)
+					return
+				}
 				if err == nil {
-					// Parse range header to get plaintext byte range
-					start, end, err := crypto.ParseHTTPRangeHeader(*rangeHeader, plaintextSize)
-					if err == nil {
-						plaintextStart, plaintextEnd = start, end
-						// Calculate encrypted byte range for needed chunks
-						encryptedStart, encryptedEnd, err := crypto.CalculateEncryptedRangeForPlaintextRange(headMeta, start, end)
-						if err == nil {
-							encryptedRange := fmt.Sprintf("bytes=%d-%d", encryptedStart, encryptedEnd)
-							backendRange = &encryptedRange
-							useRangeOptimization = true
-							h.logger.WithFields(logrus.Fields{
-								"bucket":          bucket,
-								"key":             key,
-								"plaintext_range": fmt.Sprintf("%d-%d", start, end),
-								"encrypted_range": encryptedRange,
-							}).Debug("Using optimized range request for chunked encryption")
-						} else {
-							h.logger.WithError(err).Warn("Failed to calculate encrypted range, falling back to full fetch")
-							backendRange = nil
-						}
-					} else {
-						h.logger.WithError(err).Warn("Failed to parse range header, falling back to full fetch")
-						backendRange = nil
-					}
+					plaintextStart, plaintextEnd = pStart, pEnd
+					backendRange = &encryptedRange
+					useRangeOptimization = true
+					h.headMeta.put(bucket, key, versionID, headMeta)
+					h.logger.WithFields(logrus.Fields{
+						"bucket":          bucket,
+						"key":             key,
+						"plaintext_range": fmt.Sprintf("%d-%d", pStart, pEnd),
+						"encrypted_range": encryptedRange,
+					}).Debug("Using optimized range request for chunked encryption")
 				} else {
-					h.logger.WithError(err).Warn("Failed to get plaintext size, falling back to full fetch")
+					h.logger.WithError(err).Warn("Failed to compute encrypted range, falling back to full fetch")
 					backendRange = nil
 				}
 			} else {
@@ -1010,7 +1379,37 @@ func (h *Handler) handleGetObject(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	reader, metadata, err := s3Client.GetObject(ctx, bucket, key, versionID, backendRange)
+	getObject := s3Client.GetObject
+	if useRangeOptimization {
+		getObject = func(ctx context.Context, bucket, key string, versionID, rangeHeader *string) (io.ReadCloser, map[string]string, error) {
+			return h.getCiphertextRange(ctx, s3Client, bucket, key, versionID, *rangeHeader)
+		}
+	}
+	if reader == nil {
+		getCtx := ctx
+		if staleEntry != nil {
+			getCtx = s3.WithReadConditions(ctx, s3.ReadConditions{IfNoneMatch: staleEntry.ETag})
+		}
+		reader, metadata, err = getObject(getCtx, bucket, key, versionID, backendRange)
+		if staleEntry != nil {
+			switch {
+			case errors.Is(err, s3.ErrNotModified):
+				h.metrics.RecordConditionalGet("not_modified")
+				if renewed, ok := h.cache.Revalidate(ctx, bucket, key, staleEntry.ETag, 0); ok {
+					staleEntry = renewed
+				}
+				h.serveCachedObject(w, r, bucket, key, staleEntry, start)
+				return
+			case err != nil:
+				h.metrics.RecordConditionalGet("error")
+				if errors.Is(err, s3.ErrNotFound) {
+					h.cache.Delete(ctx, bucket, key)
+				}
+			default:
+				h.metrics.RecordConditionalGet("modified")
+			}
+		}
+	}
 	if err != nil {
 		s3Err := TranslateError(err, bucket, key)
 		s3Err.WriteXML(w)
@@ -1023,25 +1422,48 @@ func (h *Handler) handleGetObject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer reader.Close()
+	backendETag := metadata["ETag"]
+	h.accessStats.Record(bucket, key)
+	if !h.checkMarker(engine, bucket, key, metadata) {
+		h.rejectUnmarked(w, r, start)
+		return
+	}
+	metadata = h.unsealMetadata(bucket, key, metadata)
+	restoreLastModified(metadata)
+
+	// Objects known to be over the plaintext cap are refused before any
+	// decryption. Range reads that decrypt only the requested chunks are
+	// bounded by the range instead.
+	if !useRangeOptimization && (engine.IsEncrypted(metadata) || metadata[crypto.MetaMPUEncrypted] == "true") && h.decryptedTooLarge(metadata) {
+		h.rejectDecryptedTooLarge(w, r, bucket, key, start)
+		return
+	}
 
 	// For MPU-encrypted objects, delegate to the MPU decrypt path.
 	if metadata[crypto.MetaMPUEncrypted] == "true" {
+		if transformRule == nil && h.checkReadPreconditions(w, r, metadata["ETag"], metadata["Last-Modified"], start) {
+			return
+		}
 		decryptedReader, err := h.decryptMPUObject(ctx, bucket, key, metadata, reader, s3Client)
 		if err != nil {
+			h.noteFormatSkew(err, bucket, key)
 			h.logger.WithError(err).WithFields(logrus.Fields{
 				"bucket": bucket,
 				"key":    key,
 			}).Error("Failed to decrypt MPU object")
-			s3Err := &S3Error{
-				Code:       "InternalError",
-				Message:    "Failed to decrypt multipart encrypted object",
-				Resource:   r.URL.Path,
-				HTTPStatus: http.StatusInternalServerError,
-			}
+			s3Err := translateCryptoError(err, r.URL.Path, "Failed to decrypt multipart encrypted object")
 			s3Err.WriteXML(w)
 			h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
 			return
 		}
+		if transformRule != nil {
+			h.serveTransformed(w, r, transformRule, bucket, key, versionID, metadata, decryptedReader, start)
+			return
+		}
+		ir := h.integrityReader(r, decryptedReader)
+		if ir != nil {
+			decryptedReader = ir
+		}
 		// Read the first chunk up front so any AEAD authentication failure
 		// surfaces as a 5xx response rather than a 200 with partial/empty
 		// bytes. `io.Copy` discards the error after WriteHeader, so we must
@@ -1055,7 +1477,7 @@ func (h *Handler) handleGetObject(w http.ResponseWriter, r *http.Request) {
 			}).Error("MPU decrypt failed on first chunk (tamper or corruption)")
 			h.metrics.RecordEncryptionError(r.Context(), "decrypt", "mpu_tamper_detected")
 			if h.auditLogger != nil {
-				h.auditLogger.Log(&audit.AuditEvent{
+				h.auditLogger.WithContext(r.Context()).Log(&audit.AuditEvent{
 					EventType: audit.EventTypeMPUTamperDetected,
 					Timestamp: time.Now().UTC(),
 					Bucket:    bucket,
@@ -1081,14 +1503,17 @@ func (h *Handler) handleGetObject(w http.ResponseWriter, r *http.Request) {
 				w.Header().Set(k, v)
 			}
 		}
+		if ir != nil {
+			ir.declare(w)
+		}
 		w.WriteHeader(http.StatusOK)
-		written, _ := w.Write(firstChunk)
+		written, streamErr := w.Write(firstChunk)
 		if firstErr == nil { // more data to stream
 			var writeTimeout time.Duration
 			if h.config != nil {
 				writeTimeout = h.config.Server.WriteTimeout
 			}
-			extra, copyErr := copyWithDeadlineRefresh(w, decryptedReader, writeTimeout)
+			extra, copyErr := streamResponse(ctx, w, decryptedReader, writeTimeout)
 			if copyErr != nil {
 				if isNetworkError(copyErr) {
 					// Client disconnect or network timeout — not a tamper event.
@@ -1106,7 +1531,7 @@ func (h *Handler) handleGetObject(w http.ResponseWriter, r *http.Request) {
 					}).Error("MPU decrypt failed mid-stream after 200 OK; connection terminated")
 					h.metrics.RecordEncryptionError(r.Context(), "decrypt", "mpu_tamper_detected_midstream")
 					if h.auditLogger != nil {
-						h.auditLogger.Log(&audit.AuditEvent{
+						h.auditLogger.WithContext(r.Context()).Log(&audit.AuditEvent{
 							EventType: audit.EventTypeMPUTamperDetected,
 							Timestamp: time.Now().UTC(),
 							Bucket:    bucket,
@@ -1118,8 +1543,14 @@ func (h *Handler) handleGetObject(w http.ResponseWriter, r *http.Request) {
 				}
 			}
 			written += int(extra)
+			if streamErr == nil {
+				streamErr = copyErr
+			}
 		}
 		h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, http.StatusOK, time.Since(start), int64(written))
+		if ir != nil {
+			ir.finish(w, streamErr)
+		}
 		return
 	}
 
@@ -1154,29 +1585,56 @@ func (h *Handler) handleGetObject(w http.ResponseWriter, r *http.Request) {
 	}
 	decryptDuration := time.Since(decryptStart)
 	if err != nil {
+		h.noteFormatSkew(err, bucket, key)
 		h.logger.WithError(err).WithFields(logrus.Fields{
 			"bucket": bucket,
 			"key":    key,
 		}).Error("Failed to decrypt object")
-		h.metrics.RecordEncryptionError(r.Context(), "decrypt", "decryption_failed")
-		s3Err := &S3Error{
-			Code:       "InternalError",
-			Message:    "Failed to decrypt object",
-			Resource:   r.URL.Path,
-			HTTPStatus: http.StatusInternalServerError,
-		}
+		h.metrics.RecordEncryptionError(r.Context(), "decrypt", ErrorType(err, "decryption_failed"))
+		s3Err := translateCryptoError(err, r.URL.Path, "Failed to decrypt object")
 		s3Err.WriteXML(w)
 		h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
 		return
 	}
+	// Preconditions are checked against the validators HEAD reports, before
+	// any plaintext is read. Transformed bodies are always served whole.
+	if transformRule == nil {
+		etag := decMetadata["ETag"]
+		if etag == "" {
+			etag = backendETag
+		}
+		if h.checkReadPreconditions(w, r, etag, decMetadata["Last-Modified"], start) {
+			return
+		}
+	}
+	// Metadata may understate the plaintext (a compressed object can expand
+	// far beyond its stored size), so the stream itself is capped too.
+	if !useRangeOptimization && engine.IsEncrypted(metadata) {
+		decryptedReader = h.capDecrypted(r.Context(), bucket, key, decryptedReader)
+	}
 
-	// For range optimization, we already have the exact range in decryptedReader
-	// For non-optimized ranges, we need to buffer and apply range
+	// For range optimization, we already have the exact range in decryptedReader.
+	// Other ranges of an encrypted object are cut from the plaintext as it
+	// streams when its size is known; failing that, the plaintext is buffered
+	// and the range applied to it.
 	var decryptedData []byte
 	var decryptedSize int64
-	if rangeHeader != nil && *rangeHeader != "" && !useRangeOptimization {
+	streamedRangeTotal := int64(-1)
+	if rangeHeader != nil && *rangeHeader != "" && !useRangeOptimization && engine.IsEncrypted(metadata) {
+		if size, err := strconv.ParseInt(decMetadata["Content-Length"], 10, 64); err == nil && size >= 0 {
+			streamedRangeTotal = size
+			decryptedSize = size
+		}
+	}
+	if rangeHeader != nil && *rangeHeader != "" && !useRangeOptimization && streamedRangeTotal < 0 {
 		// Buffer for range processing (only if not using optimization)
 		dd, err := io.ReadAll(decryptedReader)
+		if errors.Is(err, errDecryptedTooLarge) {
+			s3Err := h.decryptedTooLargeError(r.URL.Path)
+			s3Err.WriteXML(w)
+			h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+			return
+		}
 		if err != nil {
 			h.logger.WithError(err).Error("Failed to read decrypted data")
 			s3Err := &S3Error{
@@ -1192,7 +1650,7 @@ func (h *Handler) handleGetObject(w http.ResponseWriter, r *http.Request) {
 				if alg == "" {
 					alg = crypto.AlgorithmAES256GCM
 				}
-				h.auditLogger.LogDecrypt(bucket, key, alg, 0, false, err, decryptDuration, nil)
+				h.auditLogger.WithContext(r.Context()).LogDecrypt(bucket, key, alg, 0, false, err, decryptDuration, nil)
 			}
 			return
 		}
@@ -1244,17 +1702,19 @@ func (h *Handler) handleGetObject(w http.ResponseWriter, r *http.Request) {
 		auditMetadata["active_key_version"] = activeKeyVersion
 	}
 	if h.auditLogger != nil {
-		h.auditLogger.LogDecrypt(bucket, key, algorithm, keyVersion, true, nil, decryptDuration, auditMetadata)
+		h.auditLogger.WithContext(r.Context()).LogDecrypt(bucket, key, algorithm, keyVersion, true, nil, decryptDuration, auditMetadata)
 	}
 
-	// Store in cache if enabled and no range/version request
+	if transformRule != nil {
+		h.serveTransformed(w, r, transformRule, bucket, key, versionID, decMetadata, decryptedReader, start)
+		return
+	}
+
+	// Full reads are cached as they stream to the client; see below.
+	var fill *cacheFill
 	if h.cache != nil && rangeHeader == nil && versionID == nil {
-		if err := h.cache.Set(ctx, bucket, key, decryptedData, decMetadata, 0); err != nil {
-			h.logger.WithError(err).WithFields(logrus.Fields{
-				"bucket": bucket,
-				"key":    key,
-			}).Warn("Failed to cache object")
-		}
+		fill = h.newCacheFill(decryptedReader)
+		decryptedReader = fill
 	}
 
 	// Apply range request if present (after decryption) and set headers BEFORE WriteHeader
@@ -1287,10 +1747,11 @@ func (h *Handler) handleGetObject(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusPartialContent)
 
 			// Stream range bytes directly — no intermediate buffer.
-			pool := crypto.GetGlobalBufferPool()
-			buf := pool.Get64K()
-			defer pool.Put(buf)
-			n64, copyErr := io.CopyBuffer(w, decryptedReader, buf)
+			var writeTimeout time.Duration
+			if h.config != nil {
+				writeTimeout = h.config.Server.WriteTimeout
+			}
+			n64, copyErr := streamResponse(ctx, w, decryptedReader, writeTimeout)
 			if copyErr != nil {
 				h.logger.WithError(copyErr).Error("Failed to write optimized range data")
 				// Headers already sent; log only.
@@ -1298,18 +1759,14 @@ func (h *Handler) handleGetObject(w http.ResponseWriter, r *http.Request) {
 			h.metrics.RecordS3Operation(r.Context(), "GetObject", bucket, time.Since(start))
 			h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, http.StatusPartialContent, time.Since(start), n64)
 			return
+		} else if streamedRangeTotal >= 0 {
+			h.serveStreamedRange(w, r, bucket, key, versionID, decMetadata, decryptedReader, *rangeHeader, streamedRangeTotal, start)
+			return
 		} else {
 			// Non-optimized: apply range to buffered data
 			outputData, err = applyRangeRequest(decryptedData, *rangeHeader)
 			if err != nil {
-				s3Err := &S3Error{
-					Code:       "InvalidRange",
-					Message:    fmt.Sprintf("Invalid range request: %v", err),
-					Resource:   r.URL.Path,
-					HTTPStatus: http.StatusRequestedRangeNotSatisfiable,
-				}
-				s3Err.WriteXML(w)
-				h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+				h.writeRangeNotSatisfiable(w, r, int64(len(decryptedData)), start)
 				return
 			}
 
@@ -1344,12 +1801,20 @@ func (h *Handler) handleGetObject(w http.ResponseWriter, r *http.Request) {
 		if versionID != nil && *versionID != "" {
 			w.Header().Set("x-amz-version-id", *versionID)
 		}
+		ir := h.integrityReader(r, decryptedReader)
+		if ir != nil {
+			ir.declare(w)
+			decryptedReader = ir
+		}
 		w.WriteHeader(http.StatusOK)
 		var writeTimeout time.Duration
 		if h.config != nil {
 			writeTimeout = h.config.Server.WriteTimeout
 		}
-		n64, err := copyWithDeadlineRefresh(w, decryptedReader, writeTimeout)
+		n64, err := streamResponse(ctx, w, decryptedReader, writeTimeout)
+		if ir != nil {
+			defer ir.finish(w, err)
+		}
 		if err != nil {
 			if isNetworkError(err) {
 				h.logger.WithError(err).WithFields(logrus.Fields{
@@ -1366,6 +1831,15 @@ func (h *Handler) handleGetObject(w http.ResponseWriter, r *http.Request) {
 			h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, http.StatusOK, time.Since(start), n64)
 			return
 		}
+		h.noteLegacyRead(engine, bucket, key, versionID, metadata)
+		if data, ok := fill.data(); ok {
+			if err := h.cache.SetWithETag(ctx, bucket, key, backendETag, data, decMetadata, 0); err != nil {
+				h.logger.WithError(err).WithFields(logrus.Fields{
+					"bucket": bucket,
+					"key":    key,
+				}).Warn("Failed to cache object")
+			}
+		}
 		h.metrics.RecordS3Operation(r.Context(), "GetObject", bucket, time.Since(start))
 		h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, http.StatusOK, time.Since(start), n64)
 		return
@@ -1403,6 +1877,11 @@ func (h *Handler) handlePutObject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if raw, denied := rawAccess(r); (raw || denied) && r.Header.Get("x-amz-copy-source") == "" {
+		h.serveRawPut(w, r, bucket, key, denied, start)
+		return
+	}
+
 	ctx := r.Context()
 
 	// Get S3 client (may use client credentials if enabled)
@@ -1435,6 +1914,14 @@ func (h *Handler) handlePutObject(w http.ResponseWriter, r *http.Request) {
 		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
 		return
 	}
+	tagging, err = h.sealTagging(tagging)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to seal tagging header")
+		s3Err := TranslateError(err, bucket, key)
+		s3Err.WriteXML(w)
+		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return
+	}
 
 	// Extract metadata from headers (preserve original metadata)
 	// Only include x-amz-meta-* headers - standard headers should NOT be included
@@ -1451,6 +1938,29 @@ func (h *Handler) handlePutObject(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
+	if h.guardDoubleEncryption(w, r, bucket, key, metadata, start) {
+		return
+	}
+	metadata, ok := h.runMetadataHooks(w, r, "PutObject", bucket, key, r.Header.Get("Content-Type"), metadata, start)
+	if !ok {
+		return
+	}
+	metadata, err = h.metaSealer.Seal(metadata)
+	if err != nil {
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"bucket": bucket,
+			"key":    key,
+		}).Error("Failed to seal object metadata")
+		s3Err := &S3Error{
+			Code:       "InternalError",
+			Message:    "Failed to encrypt object metadata",
+			Resource:   r.URL.Path,
+			HTTPStatus: http.StatusInternalServerError,
+		}
+		s3Err.WriteXML(w)
+		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return
+	}
 
 	// Store original content length if available (as x-amz-meta- header)
 	// For AWS Chunked Uploads, we should use x-amz-decoded-content-length if present
@@ -1468,6 +1978,9 @@ func (h *Handler) handlePutObject(w http.ResponseWriter, r *http.Request) {
 			originalBytes = v
 		}
 	}
+	if !h.checkQuota(w, r, bucket, originalBytes, start) {
+		return
+	}
 
 	// Extract Content-Type for encryption engine (for compression decisions)
 	// The encryption engine reads it from metadata, but we'll filter it out before S3
@@ -1508,6 +2021,18 @@ func (h *Handler) handlePutObject(w http.ResponseWriter, r *http.Request) {
 		}).Debug("Detected AWS Chunked Upload, decoding stream before encryption")
 	}
 
+	// Scan the plaintext before anything reaches the backend.
+	inputReader, tagging, scanCleanup, ok := h.scanPut(w, r, bucket, key, inputReader, originalBytes, tagging, start)
+	if !ok {
+		return
+	}
+	defer scanCleanup()
+
+	inputReader, tagging, ok = h.inspectPut(w, r, bucket, key, inputReader, contentType, tagging, start)
+	if !ok {
+		return
+	}
+
 	// Encrypt the object
 	encryptStart := time.Now()
 	encryptedReader, encMetadata, err := engine.Encrypt(r.Context(), inputReader, metadata)
@@ -1528,19 +2053,14 @@ func (h *Handler) handlePutObject(w http.ResponseWriter, r *http.Request) {
 			"bucket": bucket,
 			"key":    key,
 		}).Error("Failed to encrypt object")
-		h.metrics.RecordEncryptionError(r.Context(), "encrypt", "encryption_failed")
+		h.metrics.RecordEncryptionError(r.Context(), "encrypt", ErrorType(err, "encryption_failed"))
 
 		// Audit logging for failed encryption
 		if h.auditLogger != nil {
-			h.auditLogger.LogEncrypt(bucket, key, algorithm, keyVersion, false, err, encryptDuration, nil)
+			h.auditLogger.WithContext(r.Context()).LogEncrypt(bucket, key, algorithm, keyVersion, false, err, encryptDuration, nil)
 		}
 
-		s3Err := &S3Error{
-			Code:       "InternalError",
-			Message:    "Failed to encrypt object",
-			Resource:   r.URL.Path,
-			HTTPStatus: http.StatusInternalServerError,
-		}
+		s3Err := translateCryptoError(err, r.URL.Path, "Failed to encrypt object")
 		s3Err.WriteXML(w)
 		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
 		return
@@ -1548,13 +2068,14 @@ func (h *Handler) handlePutObject(w http.ResponseWriter, r *http.Request) {
 
 	// Audit logging for successful encryption
 	if h.auditLogger != nil {
-		h.auditLogger.LogEncrypt(bucket, key, algorithm, keyVersion, true, nil, encryptDuration, nil)
+		h.auditLogger.WithContext(r.Context()).LogEncrypt(bucket, key, algorithm, keyVersion, true, nil, encryptDuration, nil)
 	}
 
 	// Invalidate cache for this object if cache is enabled
 	if h.cache != nil {
 		h.cache.Delete(ctx, bucket, key)
 	}
+	h.headMeta.invalidate(bucket, key)
 
 	// Record encryption metrics using original bytes
 	h.metrics.RecordEncryptionOperation(r.Context(), "encrypt", encryptDuration, originalBytes)
@@ -1591,6 +2112,19 @@ func (h *Handler) handlePutObject(w http.ResponseWriter, r *http.Request) {
 		filterKeys = h.config.Backend.FilterMetadataKeys
 	}
 	s3Metadata := filterS3Metadata(encMetadata, filterKeys)
+	h.stampMarker(s3Metadata)
+	if err := h.checkMetadataOverflow(encMetadata, s3Metadata); err != nil {
+		s3Err := TranslateError(err, bucket, key)
+		s3Err.WriteXML(w)
+		h.logger.WithFields(logrus.Fields{
+			"bucket":        bucket,
+			"key":           key,
+			"metadata_size": crypto.EstimateMetadataSize(s3Metadata),
+		}).Warn("Refused PUT: metadata exceeds the provider header limit")
+		h.metrics.RecordS3Error(r.Context(), "PutObject", bucket, s3Err.Code)
+		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return
+	}
 
 	h.logger.WithFields(logrus.Fields{
 		"bucket": bucket,
@@ -1620,20 +2154,11 @@ func (h *Handler) handlePutObject(w http.ResponseWriter, r *http.Request) {
 	}).Debug("Metadata keys after filtering (being sent to S3)")
 
 	// Compute encrypted content length for chunked mode if possible to avoid chunked transfer
-	var contentLengthPtr *int64
-	if encMetadata[crypto.MetaChunkedFormat] == "true" && originalBytes > 0 {
-		// Determine chunk size from metadata
-		chunkSize := crypto.DefaultChunkSize
-		if csStr, ok := encMetadata[crypto.MetaChunkSize]; ok && csStr != "" {
-			if cs, err := strconv.Atoi(csStr); err == nil && cs > 0 {
-				chunkSize = cs
-			}
-		}
-		// AEAD tag size for AES-GCM and ChaCha20-Poly1305 is 16 bytes
-		const aeadTagSize = 16
-		chunkCount := (originalBytes + int64(chunkSize) - 1) / int64(chunkSize)
-		encLen := originalBytes + chunkCount*int64(aeadTagSize)
-		contentLengthPtr = &encLen
+	contentLengthPtr := chunkedCiphertextLength(encMetadata, originalBytes)
+	if crypto.IsEmptyObject(encMetadata) {
+		// Empty objects are stored without a body.
+		zero := int64(0)
+		contentLengthPtr = &zero
 	}
 
 	// Extract lock headers
@@ -1643,8 +2168,28 @@ func (h *Handler) handlePutObject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	conds, release, err := h.writeConditions(ctx, r, s3Client, bucket, key)
+	if err != nil {
+		s3Err := TranslateError(err, bucket, key)
+		s3Err.WriteXML(w)
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"bucket": bucket,
+			"key":    key,
+		}).Error("Failed to resolve write preconditions")
+		h.metrics.RecordS3Error(r.Context(), "PutObject", bucket, s3Err.Code)
+		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return
+	}
+	defer release()
+
+	journalID, ok := h.beginJournal(w, r, "PutObject", bucket, key, true, start)
+	if !ok {
+		return
+	}
+
 	// Upload encrypted object with filtered metadata (streaming)
-	err = s3Client.PutObject(ctx, bucket, key, encryptedReader, s3Metadata, contentLengthPtr, tagging, lockInput)
+	err = s3Client.PutObject(s3.WithWriteConditions(ctx, conds), bucket, key, h.journal.Body(journalID, encryptedReader), s3Metadata, contentLengthPtr, tagging, lockInput)
+	h.journal.End(journalID, err)
 	if err != nil {
 		s3Err := TranslateError(err, bucket, key)
 		s3Err.WriteXML(w)
@@ -1657,12 +2202,50 @@ func (h *Handler) handlePutObject(w http.ResponseWriter, r *http.Request) {
 		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
 		return
 	}
+	h.indexWrite(bucket, key, encMetadata)
+	h.hooks.Notify(hooks.Event{Bucket: bucket, Key: key, ContentType: contentType, Size: originalBytes})
+	h.recordQuota(w, bucket, originalBytes, 1)
 
 	w.WriteHeader(http.StatusOK)
 	h.metrics.RecordS3Operation(r.Context(), "PutObject", bucket, time.Since(start))
 	h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, http.StatusOK, time.Since(start), 0)
 }
 
+// chunkedCiphertextLength returns the ciphertext length of a chunked-format
+// object with originalBytes of plaintext, or nil when it cannot be known
+// up front.
+func chunkedCiphertextLength(encMetadata map[string]string, originalBytes int64) *int64 {
+	if encMetadata[crypto.MetaChunkedFormat] != "true" || originalBytes <= 0 {
+		return nil
+	}
+	// Determine chunk size from metadata
+	chunkSize := crypto.DefaultChunkSize
+	if csStr, ok := encMetadata[crypto.MetaChunkSize]; ok && csStr != "" {
+		if cs, err := strconv.Atoi(csStr); err == nil && cs > 0 {
+			chunkSize = cs
+		}
+	}
+	// AEAD tag size for AES-GCM and ChaCha20-Poly1305 is 16 bytes
+	const aeadTagSize = 16
+	chunkCount := (originalBytes + int64(chunkSize) - 1) / int64(chunkSize)
+	encLen := crypto.BodyHeaderLength(encMetadata) + originalBytes + chunkCount*int64(aeadTagSize)
+	return &encLen
+}
+
+// streamsUnknownLength reports whether the backend client uploads a body
+// of unknown length without buffering it: the Azure and filesystem backends
+// always do, the S3 client with backend.streaming_upload enabled.
+func (h *Handler) streamsUnknownLength() bool {
+	if h.config == nil {
+		return false
+	}
+	switch h.config.Backend.StorageType() {
+	case config.BackendTypeFilesystem, config.BackendTypeAzure:
+		return true
+	}
+	return h.config.Backend.StreamingUpload.Enabled
+}
+
 // isStandardMetadata checks if a header is a standard HTTP metadata header.
 func isStandardMetadata(key string) bool {
 	standardHeaders := map[string]bool{
@@ -1718,6 +2301,25 @@ func (h *Handler) handleDeleteObject(w http.ResponseWriter, r *http.Request) {
 		versionID = &vid
 	}
 
+	// Deferred deletes keep a copy in the trash; without one the delete
+	// must not proceed.
+	if versionID == nil && h.trash.Applies(bucket, key) {
+		if _, err := h.moveToTrash(ctx, s3Client, bucket, key); err != nil {
+			s3Err := TranslateError(err, bucket, key)
+			s3Err.WriteXML(w)
+			h.logger.WithError(err).WithFields(logrus.Fields{
+				"bucket": bucket,
+				"key":    key,
+			}).Error("Failed to move object to trash")
+			h.metrics.RecordS3Error(r.Context(), "DeleteObject", bucket, s3Err.Code)
+			h.metrics.RecordHTTPRequest(r.Context(), "DELETE", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+			if h.auditLogger != nil {
+				h.auditLogger.WithContext(r.Context()).LogAccess("delete", bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), false, err, time.Since(start))
+			}
+			return
+		}
+	}
+
 	err = s3Client.DeleteObject(ctx, bucket, key, versionID)
 	if err != nil {
 		s3Err := TranslateError(err, bucket, key)
@@ -1729,7 +2331,7 @@ func (h *Handler) handleDeleteObject(w http.ResponseWriter, r *http.Request) {
 		h.metrics.RecordS3Error(r.Context(), "DeleteObject", bucket, s3Err.Code)
 		h.metrics.RecordHTTPRequest(r.Context(), "DELETE", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
 		if h.auditLogger != nil {
-			h.auditLogger.LogAccess("delete", bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), false, err, time.Since(start))
+			h.auditLogger.WithContext(r.Context()).LogAccess("delete", bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), false, err, time.Since(start))
 		}
 		return
 	}
@@ -1738,6 +2340,9 @@ func (h *Handler) handleDeleteObject(w http.ResponseWriter, r *http.Request) {
 	if h.cache != nil {
 		h.cache.Delete(ctx, bucket, key)
 	}
+	h.headMeta.invalidate(bucket, key)
+	h.sizeIndex.Remove(bucket, key)
+	h.accessStats.Remove(bucket, key)
 
 	// Clean up MPU manifest companion object (best-effort).
 	// Non-MPU objects have no manifest, so a 404 on the companion key is
@@ -1765,7 +2370,7 @@ func (h *Handler) handleDeleteObject(w http.ResponseWriter, r *http.Request) {
 
 	// Audit logging
 	if h.auditLogger != nil {
-		h.auditLogger.LogAccess("delete", bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), true, nil, time.Since(start))
+		h.auditLogger.WithContext(r.Context()).LogAccess("delete", bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), true, nil, time.Since(start))
 	}
 
 	w.WriteHeader(http.StatusNoContent)
@@ -1790,6 +2395,17 @@ func (h *Handler) handleHeadObject(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
+	// Extract version ID if provided
+	var versionID *string
+	if vid := r.URL.Query().Get("versionId"); vid != "" {
+		versionID = &vid
+	}
+
+	if raw, denied := rawAccess(r); raw || denied {
+		h.serveRaw(w, r, bucket, key, versionID, denied, start)
+		return
+	}
+
 	// Get S3 client (may use client credentials if enabled)
 	s3Client, err := h.getS3Client(r)
 	if err != nil {
@@ -1798,12 +2414,6 @@ func (h *Handler) handleHeadObject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Extract version ID if provided
-	var versionID *string
-	if vid := r.URL.Query().Get("versionId"); vid != "" {
-		versionID = &vid
-	}
-
 	metadata, err := s3Client.HeadObject(ctx, bucket, key, versionID)
 	if err != nil {
 		s3Err := TranslateError(err, bucket, key)
@@ -1816,6 +2426,12 @@ func (h *Handler) handleHeadObject(w http.ResponseWriter, r *http.Request) {
 		h.metrics.RecordHTTPRequest(r.Context(), "HEAD", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
 		return
 	}
+	if engine, err := h.getEncryptionEngine(bucket); err == nil && !h.checkMarker(engine, bucket, key, metadata) {
+		h.rejectUnmarked(w, r, start)
+		return
+	}
+	metadata = h.unsealMetadata(bucket, key, metadata)
+	restoreLastModified(metadata)
 
 	// Filter out encryption metadata and restore original metadata
 	filteredMetadata := make(map[string]string)
@@ -1831,6 +2447,10 @@ func (h *Handler) handleHeadObject(w http.ResponseWriter, r *http.Request) {
 		filteredMetadata["Content-Length"] = originalSize
 	} else if originalSize, ok := metadata["x-amz-meta-original-content-length"]; ok {
 		filteredMetadata["Content-Length"] = originalSize
+	} else if size, err := crypto.GetPlaintextSizeFromMetadata(metadata); err == nil {
+		// Chunked objects uploaded without a length: the manifest or the
+		// stored length still gives the exact size.
+		filteredMetadata["Content-Length"] = strconv.FormatInt(size, 10)
 	}
 
 	// Restore original ETag if available
@@ -1838,6 +2458,16 @@ func (h *Handler) handleHeadObject(w http.ResponseWriter, r *http.Request) {
 		filteredMetadata["ETag"] = originalETag
 	}
 
+	// The backend stores ciphertext as application/octet-stream; report the
+	// type the client uploaded, as GET does.
+	if contentType := metadata[crypto.MetaContentType]; contentType != "" {
+		filteredMetadata["Content-Type"] = contentType
+	}
+
+	if h.checkReadPreconditions(w, r, filteredMetadata["ETag"], filteredMetadata["Last-Modified"], start) {
+		return
+	}
+
 	// Set headers from filtered metadata
 	for k, v := range filteredMetadata {
 		w.Header().Set(k, v)
@@ -1876,13 +2506,28 @@ func isEncryptionMetadata(key string) bool {
 		"x-amz-meta-enc-legacy-no-aad",
 		// Original content length (set by gateway)
 		"x-amz-meta-original-content-length",
+		// Last-Modified from before a lazy upgrade (set by gateway)
+		metaOriginalLastModified,
+		// Sealed user metadata blob (only left over if it failed to unseal)
+		crypto.MetaSealedMetadata,
+		// Tiering markers
+		tiering.MetaLocation,
+		tiering.MetaStorageClass,
+		// Empty object marker
+		crypto.MetaEmpty,
+		// Crypto-shredding marker
+		crypto.MetaShredded,
+		// Gateway identity marker
+		crypto.MetaGatewayMarker,
 	}
 	for _, ek := range encryptionKeys {
 		if key == ek {
 			return true
 		}
 	}
-	return false
+	// Anything else the engine writes (content type, KDF params, key
+	// wrapping) is internal too.
+	return crypto.IsEncryptionMetadata(key)
 }
 
 func decryptedSizeForMPU(metadata map[string]string) int64 {
@@ -1987,50 +2632,33 @@ func (h *Handler) handleListObjects(w http.ResponseWriter, r *http.Request) {
 		h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
 		return
 	}
-
-	// Translate metadata for encrypted objects
-	translatedObjects := make([]s3.ObjectInfo, len(listResult.Objects))
-
-	// Get encryption engine for this bucket to check if encryption is enabled/configured
-	engine, err := h.getEncryptionEngine(bucket)
-	isEncryptionEnabled := false
-	if err == nil {
-		// Check if this engine has encryption enabled/configured
-		// Using the IsEncrypted with empty map check pattern from existing code
-		// Note: Ideally we should check policy configuration, but engine doesn't expose it directly
-		// For now, assuming all engines support encryption
-		isEncryptionEnabled = true
-	}
-
-	for i, obj := range listResult.Objects {
-		translatedObjects[i] = obj
-		// If object is encrypted, translate size and ETag
-		if isEncryptionEnabled {
-			// We need to fetch HEAD metadata for each object to get encryption info
-			// This is expensive but necessary for accurate listings
-			if headMeta, headErr := s3Client.HeadObject(ctx, bucket, obj.Key, nil); headErr == nil {
-				if engine.IsEncrypted(headMeta) {
-					// Restore original size
-					if originalSize, ok := headMeta["x-amz-meta-encryption-original-size"]; ok {
-						if parsedSize, err := strconv.ParseInt(originalSize, 10, 64); err == nil {
-							translatedObjects[i].Size = parsedSize
-						}
-					} else if originalSize, ok := headMeta["x-amz-meta-original-content-length"]; ok {
-						if parsedSize, err := strconv.ParseInt(originalSize, 10, 64); err == nil {
-							translatedObjects[i].Size = parsedSize
-						}
-					}
-					// Restore original ETag
-					if originalETag, ok := headMeta["x-amz-meta-encryption-original-etag"]; ok {
-						translatedObjects[i].ETag = originalETag
-					}
-				}
+
+	// Translate metadata for encrypted objects, hiding the gateway's own
+	// objects from the client.
+	translatedObjects := make([]s3.ObjectInfo, 0, len(listResult.Objects))
+	for _, obj := range listResult.Objects {
+		if !h.isReservedKey(bucket, obj.Key) {
+			translatedObjects = append(translatedObjects, obj)
+		}
+	}
+	commonPrefixes := listResult.CommonPrefixes
+	if h.sizeIndex != nil || h.accessStats != nil || h.keyHolds != nil {
+		commonPrefixes = make([]string, 0, len(listResult.CommonPrefixes))
+		for _, cp := range listResult.CommonPrefixes {
+			if !h.isReservedKey(bucket, cp) {
+				commonPrefixes = append(commonPrefixes, cp)
 			}
 		}
 	}
 
+	// Listed sizes and ETags are the ciphertext's; resolve the plaintext
+	// values from object metadata unless enrichment is disabled.
+	if engine, err := h.getEncryptionEngine(bucket); err == nil {
+		h.listSizes.Resolve(ctx, s3Client, engine, bucket, translatedObjects)
+	}
+
 	// Generate proper S3 ListBucketResult XML response
-	xmlResponse := generateListObjectsXML(bucket, prefix, delimiter, translatedObjects, listResult.CommonPrefixes, listResult.NextContinuationToken, listResult.IsTruncated)
+	xmlResponse := generateListObjectsXML(bucket, prefix, delimiter, translatedObjects, commonPrefixes, listResult.NextContinuationToken, listResult.IsTruncated)
 
 	w.Header().Set("Content-Type", "application/xml")
 	w.WriteHeader(http.StatusOK)
@@ -2244,8 +2872,11 @@ func applyRangeRequest(data []byte, rangeHeader string) ([]byte, error) {
 		}
 	}
 
-	// Validate range
-	if start < 0 || start >= dataLen || end < start || end >= dataLen {
+	// Validate range; a last byte beyond the data is clamped (RFC 7233).
+	if end >= dataLen {
+		end = dataLen - 1
+	}
+	if start < 0 || start >= dataLen || end < start {
 		return nil, fmt.Errorf("range not satisfiable: %d-%d (size: %d)", start, end, dataLen)
 	}
 
@@ -2343,6 +2974,9 @@ func (h *Handler) handleCreateMultipartUpload(w http.ResponseWriter, r *http.Req
 	if h.mpuGuardMisconfig(w, r, bucket, "POST", start) {
 		return
 	}
+	if !h.checkQuota(w, r, bucket, -1, start) {
+		return
+	}
 
 	ctx := r.Context()
 
@@ -2366,6 +3000,25 @@ func (h *Handler) handleCreateMultipartUpload(w http.ResponseWriter, r *http.Req
 			}
 		}
 	}
+	if h.guardDoubleEncryption(w, r, bucket, key, metadata, start) {
+		return
+	}
+	metadata, err = h.metaSealer.Seal(metadata)
+	if err != nil {
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"bucket": bucket,
+			"key":    key,
+		}).Error("Failed to seal object metadata")
+		s3Err := &S3Error{
+			Code:       "InternalError",
+			Message:    "Failed to encrypt object metadata",
+			Resource:   r.URL.Path,
+			HTTPStatus: http.StatusInternalServerError,
+		}
+		s3Err.WriteXML(w)
+		h.metrics.RecordHTTPRequest(r.Context(), "POST", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return
+	}
 
 	// If encrypted MPU is enabled, pre-set markers in metadata so the final
 	// object automatically carries the manifest pointer (metadata is frozen at
@@ -2374,6 +3027,7 @@ func (h *Handler) handleCreateMultipartUpload(w http.ResponseWriter, r *http.Req
 		metadata[crypto.MetaMPUEncrypted] = "true"
 		metadata[crypto.MetaFallbackMode] = "mpu"
 		metadata[crypto.MetaFallbackPointer] = key + ".mpu-manifest"
+		h.stampMarker(metadata)
 	}
 
 	uploadID, err := s3Client.CreateMultipartUpload(ctx, bucket, key, metadata)
@@ -2422,7 +3076,7 @@ func (h *Handler) handleCreateMultipartUpload(w http.ResponseWriter, r *http.Req
 
 		h.metrics.RecordMPUEncrypted("success")
 		if h.auditLogger != nil {
-			h.auditLogger.Log(&audit.AuditEvent{
+			h.auditLogger.WithContext(r.Context()).Log(&audit.AuditEvent{
 				EventType: audit.EventTypeMPUCreate,
 				Timestamp: time.Now().UTC(),
 				Bucket:    bucket,
@@ -2527,12 +3181,16 @@ func zeroBytes(b []byte) {
 }
 
 // isNetworkError reports whether err is a client-side network error
-// (timeout, connection reset, broken pipe) rather than a decryption or
-// authentication failure.
+// (timeout, connection reset, broken pipe, disconnect cancelling the
+// request) rather than a decryption or authentication failure.
 func isNetworkError(err error) bool {
 	if err == nil {
 		return false
 	}
+	// The client went away and the request context was cancelled.
+	if errors.Is(err, context.Canceled) {
+		return true
+	}
 	// syscall-level connection errors.
 	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
 		return true
@@ -2547,37 +3205,6 @@ func isNetworkError(err error) bool {
 	return false
 }
 
-// copyWithDeadlineRefresh wraps io.Copy and, when timeout > 0, extends the
-// HTTP write deadline every timeout/2 interval while the copy is active.
-// This prevents a fixed Server.WriteTimeout from killing long-running S3
-// object streams.
-func copyWithDeadlineRefresh(w http.ResponseWriter, src io.Reader, timeout time.Duration) (int64, error) {
-	if timeout <= 0 {
-		return io.Copy(w, src)
-	}
-	rc := http.NewResponseController(w)
-	// Pre-flight: ensure the controller supports write deadlines.
-	if err := rc.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
-		// Fallback: the underlying writer doesn't support deadline control.
-		return io.Copy(w, src)
-	}
-	done := make(chan struct{})
-	defer close(done)
-	go func() {
-		ticker := time.NewTicker(timeout / 2)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				rc.SetWriteDeadline(time.Now().Add(timeout))
-			case <-done:
-				return
-			}
-		}
-	}()
-	return io.Copy(w, src)
-}
-
 // CompleteMultipartUpload represents the XML structure for completing multipart uploads.
 type CompleteMultipartUpload struct {
 	XMLName xml.Name `xml:"CompleteMultipartUpload"`
@@ -2786,9 +3413,24 @@ func (h *Handler) handleUploadPart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Current AWS SDKs and the CLI send parts aws-chunked, with chunk
+	// signatures and checksum trailers around the data; only the data may
+	// reach the cipher or the backend.
+	var body io.Reader = r.Body
+	bodyLen := r.ContentLength
+	if strings.HasPrefix(r.Header.Get("x-amz-content-sha256"), "STREAMING-") {
+		body, bodyLen = NewAwsChunkedReader(r.Body), -1
+		if v, err := strconv.ParseInt(r.Header.Get("x-amz-decoded-content-length"), 10, 64); err == nil && v >= 0 {
+			bodyLen = v
+		}
+	}
+	if !h.checkQuota(w, r, bucket, bodyLen, start) {
+		return
+	}
+
 	// Default: no encryption layer added here (plaintext parts per ADR 0002, or
 	// encrypted per-upload DEK below when the upload has a Valkey state record).
-	var encryptedReader io.Reader = r.Body
+	var encryptedReader io.Reader = body
 	var contentLengthPtr *int64
 	// encMPUState is non-nil only for encrypted MPU parts; used after UploadPart
 	// to record the PartRecord without a second Valkey round-trip.
@@ -2808,7 +3450,7 @@ func (h *Handler) handleUploadPart(w http.ResponseWriter, r *http.Request) {
 		}).Error("mpu.state.unavailable: cannot determine upload encryption status; failing closed")
 		h.metrics.RecordS3Error(r.Context(), "UploadPart", bucket, "StateUnavailable")
 		if h.auditLogger != nil {
-			h.auditLogger.Log(&audit.AuditEvent{
+			h.auditLogger.WithContext(r.Context()).Log(&audit.AuditEvent{
 				EventType: audit.EventTypeMPUValkeyUnavail,
 				Timestamp: time.Now().UTC(),
 				Bucket:    bucket,
@@ -2830,18 +3472,13 @@ func (h *Handler) handleUploadPart(w http.ResponseWriter, r *http.Request) {
 		// Encrypted multipart path — decision based on PolicySnapshot stored at
 		// CreateMultipartUpload, not live policy (ADR-0009 §Security Considerations).
 		// Determine the plaintext length for encryption metadata.
-		// Handle STREAMING-* (AWS chunked encoding) by checking x-amz-decoded-content-length.
 		var plainLen int64
-		if decodedLen := r.Header.Get("x-amz-decoded-content-length"); decodedLen != "" {
-			if v, e := strconv.ParseInt(decodedLen, 10, 64); e == nil && v >= 0 {
-				plainLen = v
-			}
-		} else if r.ContentLength >= 0 {
-			plainLen = r.ContentLength
+		if bodyLen >= 0 {
+			plainLen = bodyLen
 		}
 
 		// Pass the pre-fetched state to avoid a second Valkey round-trip inside encryptMPUPart.
-		encReader, encLen, err := h.encryptMPUPartWithState(ctx, bucket, uploadID, int32(partNumber), r.Body, plainLen, uploadState)
+		encReader, encLen, err := h.encryptMPUPartWithState(ctx, bucket, uploadID, int32(partNumber), body, plainLen, uploadState)
 		if err != nil {
 			h.logger.WithError(err).WithFields(logrus.Fields{
 				"bucket":     bucket,
@@ -2862,6 +3499,14 @@ func (h *Handler) handleUploadPart(w http.ResponseWriter, r *http.Request) {
 		// V0.6-PERF-1 Phase D: use a pooled seekable wrapper bounded by
 		// MaxPartBuffer instead of io.ReadAll. This satisfies the AWS SDK V2
 		// SigV4 seekable-body requirement while capping heap per part.
+		releaseBuf, err := h.reservePartBuffer(ctx, encLen)
+		if err != nil {
+			s3Err := TranslateError(err, bucket, key)
+			s3Err.WriteXML(w)
+			h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+			return
+		}
+		defer releaseBuf()
 		maxBuf := effectiveMaxPartBuffer(h.config)
 		sb, sbErr := s3.NewSeekableBody(encReader, maxBuf)
 		if sbErr != nil {
@@ -2913,8 +3558,16 @@ func (h *Handler) handleUploadPart(w http.ResponseWriter, r *http.Request) {
 		// Plaintext multipart path (ADR 0002): buffer to make body seekable for
 		// the AWS SDK's retry behaviour.
 		// V0.6-PERF-1 Phase D: use pooled seekable wrapper instead of io.ReadAll.
+		releaseBuf, err := h.reservePartBuffer(ctx, bodyLen)
+		if err != nil {
+			s3Err := TranslateError(err, bucket, key)
+			s3Err.WriteXML(w)
+			h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+			return
+		}
+		defer releaseBuf()
 		maxBuf := effectiveMaxPartBuffer(h.config)
-		sb, sbErr := s3.NewSeekableBody(r.Body, maxBuf)
+		sb, sbErr := s3.NewSeekableBody(body, maxBuf)
 		if sbErr != nil {
 			h.logger.WithError(sbErr).Error("Failed to read multipart upload part")
 			code := "InternalError"
@@ -2977,7 +3630,7 @@ func (h *Handler) handleUploadPart(w http.ResponseWriter, r *http.Request) {
 			h.metrics.RecordMPUStateStoreOp("AppendPart", "success", time.Since(opStart))
 			h.metrics.RecordMPUPart("success")
 			if h.auditLogger != nil {
-				h.auditLogger.Log(&audit.AuditEvent{
+				h.auditLogger.WithContext(r.Context()).Log(&audit.AuditEvent{
 					EventType: audit.EventTypeMPUPart,
 					Timestamp: time.Now().UTC(),
 					Bucket:    bucket,
@@ -2997,7 +3650,7 @@ func (h *Handler) handleUploadPart(w http.ResponseWriter, r *http.Request) {
 			}).Error("mpu.append_part.failure: backend part written but state not recorded; returning 500 so client retries")
 			h.metrics.RecordS3Error(r.Context(), "AppendMPUPartState", bucket, "StateUnavailable")
 			if h.auditLogger != nil {
-				h.auditLogger.Log(&audit.AuditEvent{
+				h.auditLogger.WithContext(r.Context()).Log(&audit.AuditEvent{
 					EventType: audit.EventTypeMPUValkeyUnavail,
 					Timestamp: time.Now().UTC(),
 					Bucket:    bucket,
@@ -3018,6 +3671,7 @@ func (h *Handler) handleUploadPart(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	h.recordQuota(w, bucket, max(bodyLen, 0), 0)
 	w.Header().Set("ETag", etag)
 	w.WriteHeader(http.StatusOK)
 	h.metrics.RecordS3Operation(r.Context(), "UploadPart", bucket, time.Since(start))
@@ -3116,7 +3770,7 @@ func (h *Handler) handleCompleteMultipartUpload(w http.ResponseWriter, r *http.R
 		}).Error("mpu.state.unavailable: cannot determine encryption state at Complete; failing closed")
 		h.metrics.RecordS3Error(r.Context(), "CompleteMultipartUpload", bucket, "StateUnavailable")
 		if h.auditLogger != nil {
-			h.auditLogger.Log(&audit.AuditEvent{
+			h.auditLogger.WithContext(r.Context()).Log(&audit.AuditEvent{
 				EventType: audit.EventTypeMPUValkeyUnavail,
 				Timestamp: time.Now().UTC(),
 				Bucket:    bucket,
@@ -3154,7 +3808,7 @@ func (h *Handler) handleCompleteMultipartUpload(w http.ResponseWriter, r *http.R
 		}
 
 		if h.auditLogger != nil {
-			h.auditLogger.Log(&audit.AuditEvent{
+			h.auditLogger.WithContext(r.Context()).Log(&audit.AuditEvent{
 				EventType: audit.EventTypeMPUComplete,
 				Timestamp: time.Now().UTC(),
 				Bucket:    bucket,
@@ -3165,7 +3819,32 @@ func (h *Handler) handleCompleteMultipartUpload(w http.ResponseWriter, r *http.R
 		}
 	}
 
-	etag, err := s3Client.CompleteMultipartUpload(ctx, bucket, key, uploadID, parts, lockInput)
+	conds, release, err := h.writeConditions(ctx, r, s3Client, bucket, key)
+	if err != nil {
+		s3Err := TranslateError(err, bucket, key)
+		s3Err.WriteXML(w)
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"bucket":   bucket,
+			"key":      key,
+			"uploadID": uploadID,
+		}).Error("Failed to resolve write preconditions")
+		h.metrics.RecordS3Error(r.Context(), "CompleteMultipartUpload", bucket, s3Err.Code)
+		h.metrics.RecordHTTPRequest(r.Context(), "POST", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return
+	}
+	defer release()
+
+	journalID, ok := h.beginJournal(w, r, "CompleteMultipartUpload", bucket, key, completeIsEnc, start)
+	if !ok {
+		return
+	}
+	partETags := make([]string, len(parts))
+	for i, p := range parts {
+		partETags[i] = p.ETag
+	}
+	h.journal.Sent(journalID, journal.MultipartETag(partETags))
+	etag, err := s3Client.CompleteMultipartUpload(s3.WithWriteConditions(ctx, conds), bucket, key, uploadID, parts, lockInput)
+	h.journal.End(journalID, err)
 	if err != nil {
 		s3Err := TranslateError(err, bucket, key)
 		s3Err.WriteXML(w)
@@ -3187,6 +3866,12 @@ func (h *Handler) handleCompleteMultipartUpload(w http.ResponseWriter, r *http.R
 		}
 	}
 
+	// The plaintext size of a multipart object is not known here; drop any
+	// stale index entry so listings fall back to HEAD.
+	h.sizeIndex.Remove(bucket, key)
+	// The parts' bytes were counted as they were uploaded.
+	h.recordQuota(w, bucket, 0, 1)
+
 	// Return XML response
 	type CompleteMultipartUploadResult struct {
 		XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
@@ -3296,7 +3981,7 @@ func (h *Handler) handleAbortMultipartUpload(w http.ResponseWriter, r *http.Requ
 		}
 
 		if h.auditLogger != nil {
-			h.auditLogger.Log(&audit.AuditEvent{
+			h.auditLogger.WithContext(r.Context()).Log(&audit.AuditEvent{
 				EventType: audit.EventTypeMPUAbort,
 				Timestamp: time.Now().UTC(),
 				Bucket:    bucket,
@@ -3389,8 +4074,9 @@ func (h *Handler) serveMPURangedGet(
 	manifestPlainReader, _, err := engine.Decrypt(r.Context(), manifestReader, manifestMeta)
 	if err != nil {
 		h.logger.WithError(err).Error("serveMPURangedGet: decrypt manifest")
-		(&S3Error{Code: "InternalError", Message: "Failed to decrypt manifest", Resource: r.URL.Path, HTTPStatus: http.StatusInternalServerError}).WriteXML(w)
-		h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, http.StatusInternalServerError, time.Since(start), 0)
+		s3Err := translateCryptoError(err, r.URL.Path, "Failed to decrypt manifest")
+		s3Err.WriteXML(w)
+		h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
 		return
 	}
 	manifestJSON, err := io.ReadAll(manifestPlainReader)
@@ -3403,6 +4089,7 @@ func (h *Handler) serveMPURangedGet(
 	manifest, err := crypto.UnmarshalMultipartManifest(manifestJSON)
 	if err != nil {
 		h.logger.WithError(err).Error("serveMPURangedGet: parse manifest")
+		h.noteFormatSkew(err, bucket, key)
 		(&S3Error{Code: "InternalError", Message: "Invalid manifest", Resource: r.URL.Path, HTTPStatus: http.StatusInternalServerError}).WriteXML(w)
 		h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, http.StatusInternalServerError, time.Since(start), 0)
 		return
@@ -3411,9 +4098,7 @@ func (h *Handler) serveMPURangedGet(
 	// ── 2. Parse plaintext range ─────────────────────────────────────────────
 	pStart, pEnd, err := crypto.ParseHTTPRangeHeader(rangeHeader, manifest.TotalPlainSize)
 	if err != nil {
-		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", manifest.TotalPlainSize))
-		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
-		h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, http.StatusRequestedRangeNotSatisfiable, time.Since(start), 0)
+		h.writeRangeNotSatisfiable(w, r, manifest.TotalPlainSize, start)
 		return
 	}
 
@@ -3428,7 +4113,7 @@ func (h *Handler) serveMPURangedGet(
 
 	// ── 4. Fetch only the needed ciphertext bytes ────────────────────────────
 	encRangeHdr := fmt.Sprintf("bytes=%d-%d", rangeResult.EncStart, rangeResult.EncEnd)
-	objReader, _, err := s3Client.GetObject(ctx, bucket, key, versionID, &encRangeHdr)
+	objReader, _, err := h.rangeHedger.GetObject(ctx, s3Client, bucket, key, versionID, &encRangeHdr)
 	if err != nil {
 		s3Err := TranslateError(err, bucket, key)
 		s3Err.WriteXML(w)
@@ -3520,7 +4205,7 @@ func (h *Handler) serveMPURangedGet(
 			}).Error("serveMPURangedGet: tamper detected")
 			h.metrics.RecordEncryptionError(r.Context(), "decrypt", "mpu_tamper_detected")
 			if h.auditLogger != nil {
-				h.auditLogger.Log(&audit.AuditEvent{
+				h.auditLogger.WithContext(r.Context()).Log(&audit.AuditEvent{
 					EventType: audit.EventTypeMPUTamperDetected,
 					Timestamp: time.Now().UTC(),
 					Bucket:    bucket,
@@ -3558,6 +4243,7 @@ func (h *Handler) serveMPURangedGet(
 	w.Header().Set("Content-Type", "application/octet-stream")
 	w.WriteHeader(http.StatusPartialContent)
 	written, _ := io.Copy(w, bytes.NewReader(plaintext))
+	h.accessStats.Record(bucket, key)
 	h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, http.StatusPartialContent, time.Since(start), written)
 }
 
@@ -3659,32 +4345,9 @@ func (h *Handler) unwrapMPUDEK(ctx context.Context, state *mpu.UploadState, buck
 // The caller retains ownership of reader and must close it after the returned
 // reader is fully consumed (the caller's defer reader.Close() handles this).
 func (h *Handler) decryptMPUObject(ctx context.Context, bucket, key string, metadata map[string]string, reader io.ReadCloser, s3Client s3.Client) (io.Reader, error) {
-	// Fetch and decrypt the manifest companion object.
-	manifestKey := metadata[crypto.MetaFallbackPointer]
-	if manifestKey == "" {
-		manifestKey = key + ".mpu-manifest"
-	}
-	manifestReader, manifestMeta, err := s3Client.GetObject(ctx, bucket, manifestKey, nil, nil)
-	if err != nil {
-		return nil, fmt.Errorf("decryptMPUObject: fetch manifest %q: %w", manifestKey, err)
-	}
-	defer manifestReader.Close()
-
-	engine, err := h.getEncryptionEngine(bucket)
-	if err != nil {
-		return nil, fmt.Errorf("decryptMPUObject: get engine: %w", err)
-	}
-	manifestPlainReader, _, err := engine.Decrypt(ctx, manifestReader, manifestMeta)
+	manifest, err := h.loadMPUManifest(ctx, bucket, key, metadata, s3Client)
 	if err != nil {
-		return nil, fmt.Errorf("decryptMPUObject: decrypt manifest: %w", err)
-	}
-	manifestJSON, err := io.ReadAll(manifestPlainReader)
-	if err != nil {
-		return nil, fmt.Errorf("decryptMPUObject: read manifest: %w", err)
-	}
-	manifest, err := crypto.UnmarshalMultipartManifest(manifestJSON)
-	if err != nil {
-		return nil, fmt.Errorf("decryptMPUObject: parse manifest: %w", err)
+		return nil, fmt.Errorf("decryptMPUObject: %w", err)
 	}
 
 	dek, err := h.unwrapMPUDEKFromManifest(ctx, manifest, bucket, key)
@@ -3716,6 +4379,38 @@ func (h *Handler) decryptMPUObject(ctx context.Context, bucket, key string, meta
 	return &mpuDecryptCloser{Reader: inner, dek: dek}, nil
 }
 
+// loadMPUManifest fetches and decrypts the manifest companion object of the
+// MPU object bucket/key.
+func (h *Handler) loadMPUManifest(ctx context.Context, bucket, key string, metadata map[string]string, s3Client s3.Client) (*crypto.MultipartManifest, error) {
+	manifestKey := metadata[crypto.MetaFallbackPointer]
+	if manifestKey == "" {
+		manifestKey = key + ".mpu-manifest"
+	}
+	manifestReader, manifestMeta, err := s3Client.GetObject(ctx, bucket, manifestKey, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch manifest %q: %w", manifestKey, err)
+	}
+	defer manifestReader.Close()
+
+	engine, err := h.getEncryptionEngine(bucket)
+	if err != nil {
+		return nil, fmt.Errorf("get engine: %w", err)
+	}
+	manifestPlainReader, _, err := engine.Decrypt(ctx, manifestReader, manifestMeta)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt manifest: %w", err)
+	}
+	manifestJSON, err := io.ReadAll(manifestPlainReader)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	manifest, err := crypto.UnmarshalMultipartManifest(manifestJSON)
+	if err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	return manifest, nil
+}
+
 // mpuDecryptCloser wraps an io.Reader and zeros the DEK when the stream is
 // exhausted or explicitly closed.
 type mpuDecryptCloser struct {
@@ -3786,10 +4481,17 @@ func decodeBase64ToFixed32(s string) ([32]byte, error) {
 	return out, nil
 }
 
+// usesClientFactory reports whether per-request clients can be built for
+// the backend. Other storage types only have the client passed to the
+// handler.
+func usesClientFactory(b *config.BackendConfig) bool {
+	return b.StorageType() == config.BackendTypeS3
+}
+
 // getS3ClientFromBucket returns an S3 client (uses clientFactory if available).
 func (h *Handler) getS3ClientFromBucket(ctx context.Context, bucket string) (s3.Client, error) {
 	if h.clientFactory != nil {
-		return h.clientFactory.GetClient()
+		return h.factoryClient()
 	}
 	return h.s3Client, nil
 }
@@ -3922,6 +4624,29 @@ func (h *Handler) handleCopyObject(w http.ResponseWriter, r *http.Request, dstBu
 		return
 	}
 
+	// COPY (the default) carries the source's user metadata and
+	// Content-Type over; REPLACE takes them from this request.
+	directive := strings.ToUpper(r.Header.Get("x-amz-metadata-directive"))
+	if directive == "" {
+		directive = "COPY"
+	}
+	if directive != "COPY" && directive != "REPLACE" {
+		s3Err := &S3Error{
+			Code:       "InvalidArgument",
+			Message:    "Unknown metadata directive.",
+			Resource:   r.URL.Path,
+			HTTPStatus: http.StatusBadRequest,
+		}
+		s3Err.WriteXML(w)
+		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return
+	}
+	// The copy's size is not known yet, so only a quota that is already
+	// used up refuses it.
+	if !h.checkQuota(w, r, dstBucket, -1, start) {
+		return
+	}
+
 	ctx := r.Context()
 
 	// Extract tagging header
@@ -3938,6 +4663,14 @@ func (h *Handler) handleCopyObject(w http.ResponseWriter, r *http.Request, dstBu
 		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
 		return
 	}
+	tagging, err = h.sealTagging(tagging)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to seal tagging header")
+		s3Err := TranslateError(err, dstBucket, dstKey)
+		s3Err.WriteXML(w)
+		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return
+	}
 
 	// Get source object (decrypt if encrypted)
 	srcReader, srcMetadata, err := s3Client.GetObject(ctx, srcBucket, srcKey, srcVersionID, nil)
@@ -3955,6 +4688,7 @@ func (h *Handler) handleCopyObject(w http.ResponseWriter, r *http.Request, dstBu
 		return
 	}
 	defer srcReader.Close()
+	srcMetadata = h.unsealMetadata(srcBucket, srcKey, srcMetadata)
 
 	// Get source encryption engine
 	srcEngine, err := h.getEncryptionEngine(srcBucket)
@@ -4001,8 +4735,15 @@ func (h *Handler) handleCopyObject(w http.ResponseWriter, r *http.Request, dstBu
 	// V0.6-PERF-1 Phase C: pass srcReader directly to Decrypt — the engine
 	// already handles buffering for legacy AEAD and streams for chunked.
 	// The intermediate decryptedData []byte allocation is eliminated here.
-	decryptedReader, _, err := srcEngine.Decrypt(r.Context(), srcReader, srcMetadata)
+	// Encrypted multipart sources go through the same part-wise path as GET.
+	var decryptedReader io.Reader
+	if srcMetadata[crypto.MetaMPUEncrypted] == "true" {
+		decryptedReader, err = h.decryptMPUObject(ctx, srcBucket, srcKey, srcMetadata, srcReader, s3Client)
+	} else {
+		decryptedReader, _, err = srcEngine.Decrypt(r.Context(), srcReader, srcMetadata)
+	}
 	if err != nil {
+		h.noteFormatSkew(err, srcBucket, srcKey)
 		h.logger.WithError(err).Error("Failed to decrypt source object for copy")
 		s3Err := &S3Error{
 			Code:       "InternalError",
@@ -4015,24 +4756,49 @@ func (h *Handler) handleCopyObject(w http.ResponseWriter, r *http.Request, dstBu
 		return
 	}
 
-	// Extract destination metadata from headers.
+	// Extract destination metadata from the source or from headers.
 	// Case-insensitive x-amz-meta-* match — Go canonicalises headers to
 	// X-Amz-Meta-Foo on parse, so strings.HasPrefix against the lowercase
-	// prefix is the correct comparison.
-	dstMetadata := make(map[string]string)
-	for k, v := range r.Header {
-		if len(v) > 0 {
-			if strings.HasPrefix(strings.ToLower(k), "x-amz-meta-") || isStandardMetadata(k) {
-				dstMetadata[strings.ToLower(k)] = v[0]
+	// prefix is the correct comparison. Content-Type is set separately below.
+	var dstMetadata map[string]string
+	contentType := srcMetadata["Content-Type"]
+	if directive == "COPY" {
+		dstMetadata = exportableMetadata(srcMetadata)
+	} else {
+		dstMetadata = make(map[string]string)
+		for k, v := range r.Header {
+			if len(v) > 0 && k != "Content-Type" {
+				if strings.HasPrefix(strings.ToLower(k), "x-amz-meta-") || isStandardMetadata(k) {
+					dstMetadata[strings.ToLower(k)] = v[0]
+				}
 			}
 		}
+		if ct := r.Header.Get("Content-Type"); ct != "" {
+			contentType = ct
+		}
 	}
 
-	// Preserve Content-Type from source object if not specified in copy request
-	if _, hasContentType := dstMetadata["Content-Type"]; !hasContentType {
-		if srcContentType, ok := srcMetadata["Content-Type"]; ok {
-			dstMetadata["Content-Type"] = srcContentType
+	dstMetadata, ok := h.runMetadataHooks(w, r, "CopyObject", dstBucket, dstKey, contentType, dstMetadata, start)
+	if !ok {
+		return
+	}
+	dstMetadata, err = h.metaSealer.Seal(dstMetadata)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to seal destination object metadata")
+		s3Err := &S3Error{
+			Code:       "InternalError",
+			Message:    "Failed to encrypt object metadata",
+			Resource:   r.URL.Path,
+			HTTPStatus: http.StatusInternalServerError,
 		}
+		s3Err.WriteXML(w)
+		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return
+	}
+
+	// Preserve Content-Type from source object if not specified in copy request
+	if contentType != "" {
+		dstMetadata["Content-Type"] = contentType
 	}
 
 	// Get destination encryption engine
@@ -4062,28 +4828,31 @@ func (h *Handler) handleCopyObject(w http.ResponseWriter, r *http.Request, dstBu
 		return
 	}
 
-	// V0.6-PERF-1: intentionally buffered — the engine returns a fully-sealed
-	// ciphertext reader (legacy AEAD: single allocation inside engine.Encrypt;
-	// chunked: backing bytes.Buffer from the chunked writer). We must call
-	// io.ReadAll once here to obtain the exact byte count for PutObject's
-	// ContentLength field; no additional copy of the plaintext or intermediate
-	// decryptedData exists at this point. A full-pipeline io.Pipe (decode →
-	// encode → PUT without buffering) requires the SDK to accept a non-seekable
-	// body with a pre-computed CiphertextLen — deferred to V0.6-PERF-2 behind
-	// a Backend.SupportsStreamingChecksums capability flag. See ADR 0006
-	// addendum and docs/plans/V0.6-PERF-1-plan.md §4.4.
-	encryptedData, err := io.ReadAll(encryptedReader)
-	if err != nil {
-		h.logger.WithError(err).Error("Failed to read encrypted destination object")
-		s3Err := &S3Error{
-			Code:       "InternalError",
-			Message:    "Failed to read encrypted destination object",
-			Resource:   r.URL.Path,
-			HTTPStatus: http.StatusInternalServerError,
+	// The ciphertext is streamed to the backend when its length is known
+	// (legacy AEAD returns a sealed in-memory reader) or the backend client
+	// can upload a body of unknown length in parts. Otherwise it is buffered
+	// once to learn the length for PutObject's ContentLength.
+	var encLen *int64
+	if lr, ok := encryptedReader.(interface{ Len() int }); ok {
+		n := int64(lr.Len())
+		encLen = &n
+	} else if !h.streamsUnknownLength() {
+		encryptedData, err := io.ReadAll(encryptedReader)
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to read encrypted destination object")
+			s3Err := &S3Error{
+				Code:       "InternalError",
+				Message:    "Failed to read encrypted destination object",
+				Resource:   r.URL.Path,
+				HTTPStatus: http.StatusInternalServerError,
+			}
+			s3Err.WriteXML(w)
+			h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+			return
 		}
-		s3Err.WriteXML(w)
-		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
-		return
+		n := int64(len(encryptedData))
+		encLen = &n
+		encryptedReader = bytes.NewReader(encryptedData)
 	}
 
 	// Filter out standard HTTP headers from metadata before sending to S3
@@ -4092,6 +4861,19 @@ func (h *Handler) handleCopyObject(w http.ResponseWriter, r *http.Request, dstBu
 		filterKeys = h.config.Backend.FilterMetadataKeys
 	}
 	s3Metadata := filterS3Metadata(encMetadata, filterKeys)
+	h.stampMarker(s3Metadata)
+	if err := h.checkMetadataOverflow(encMetadata, s3Metadata); err != nil {
+		s3Err := TranslateError(err, dstBucket, dstKey)
+		s3Err.WriteXML(w)
+		h.logger.WithFields(logrus.Fields{
+			"dstBucket":     dstBucket,
+			"dstKey":        dstKey,
+			"metadata_size": crypto.EstimateMetadataSize(s3Metadata),
+		}).Warn("Refused copy: metadata exceeds the provider header limit")
+		h.metrics.RecordS3Error(r.Context(), "CopyObject", dstBucket, s3Err.Code)
+		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return
+	}
 
 	lockInput, s3Err := extractObjectLockInput(r)
 	if s3Err != nil {
@@ -4099,9 +4881,28 @@ func (h *Handler) handleCopyObject(w http.ResponseWriter, r *http.Request, dstBu
 		return
 	}
 
-	// Upload encrypted copy with filtered metadata and known content length
-	encLen := int64(len(encryptedData))
-	err = s3Client.PutObject(ctx, dstBucket, dstKey, bytes.NewReader(encryptedData), s3Metadata, &encLen, tagging, lockInput)
+	conds, release, err := h.writeConditions(ctx, r, s3Client, dstBucket, dstKey)
+	if err != nil {
+		s3Err := TranslateError(err, dstBucket, dstKey)
+		s3Err.WriteXML(w)
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"dstBucket": dstBucket,
+			"dstKey":    dstKey,
+		}).Error("Failed to resolve write preconditions")
+		h.metrics.RecordS3Error(r.Context(), "CopyObject", dstBucket, s3Err.Code)
+		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return
+	}
+	defer release()
+
+	journalID, ok := h.beginJournal(w, r, "CopyObject", dstBucket, dstKey, true, start)
+	if !ok {
+		return
+	}
+
+	// Upload encrypted copy with filtered metadata
+	err = s3Client.PutObject(s3.WithWriteConditions(ctx, conds), dstBucket, dstKey, h.journal.Body(journalID, encryptedReader), s3Metadata, encLen, tagging, lockInput)
+	h.journal.End(journalID, err)
 	if err != nil {
 		s3Err := TranslateError(err, dstBucket, dstKey)
 		s3Err.WriteXML(w)
@@ -4115,10 +4916,13 @@ func (h *Handler) handleCopyObject(w http.ResponseWriter, r *http.Request, dstBu
 		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
 		return
 	}
+	h.indexWrite(dstBucket, dstKey, encMetadata)
 
 	// Fetch ETag via HEAD to return accurate ETag
 	headMeta, _ := s3Client.HeadObject(ctx, dstBucket, dstKey, nil)
 	etag := headMeta["ETag"]
+	copied, _ := strconv.ParseInt(headMeta["Content-Length"], 10, 64)
+	h.recordQuota(w, dstBucket, copied, 1)
 
 	// Return CopyObjectResult XML
 	type CopyObjectResult struct {
@@ -4194,26 +4998,57 @@ func (h *Handler) handleDeleteObjects(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Convert to ObjectIdentifier slice
-	identifiers := make([]s3.ObjectIdentifier, len(deleteReq.Objects))
-	for i, obj := range deleteReq.Objects {
-		identifiers[i] = s3.ObjectIdentifier{
+	// Convert to ObjectIdentifier slice. The gateway's own objects, keys the
+	// client may not delete, and keys that cannot be moved to the trash are
+	// reported as errors and left in place.
+	identifiers := make([]s3.ObjectIdentifier, 0, len(deleteReq.Objects))
+	var keyErrors []s3.ErrorObject
+	client, _ := identity.FromContext(ctx)
+	for _, obj := range deleteReq.Objects {
+		if h.isReservedKey(bucket, obj.Key) || !h.authzPolicy.Evaluate(authz.Request{Identity: client, Operation: "DeleteObject", Bucket: bucket, Key: obj.Key}).Allowed {
+			keyErrors = append(keyErrors, s3.ErrorObject{
+				Key:     obj.Key,
+				Code:    ErrAccessDenied.Code,
+				Message: ErrAccessDenied.Message,
+			})
+			continue
+		}
+		if obj.VersionID == "" && h.trash.Applies(bucket, obj.Key) {
+			if _, err := h.moveToTrash(ctx, s3Client, bucket, obj.Key); err != nil {
+				h.logger.WithError(err).WithFields(logrus.Fields{
+					"bucket": bucket,
+					"key":    obj.Key,
+				}).Error("Failed to move object to trash")
+				keyErrors = append(keyErrors, s3.ErrorObject{
+					Key:     obj.Key,
+					Code:    TranslateError(err, bucket, obj.Key).Code,
+					Message: "object could not be moved to the trash",
+				})
+				continue
+			}
+		}
+		identifiers = append(identifiers, s3.ObjectIdentifier{
 			Key:       obj.Key,
 			VersionID: obj.VersionID,
-		}
+		})
 	}
 
-	deleted, errors, err := s3Client.DeleteObjects(ctx, bucket, identifiers)
-	if err != nil {
-		s3Err := TranslateError(err, bucket, "")
-		s3Err.WriteXML(w)
-		h.logger.WithError(err).WithFields(logrus.Fields{
-			"bucket": bucket,
-		}).Error("Failed to delete objects")
-		h.metrics.RecordS3Error(r.Context(), "DeleteObjects", bucket, s3Err.Code)
-		h.metrics.RecordHTTPRequest(r.Context(), "POST", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
-		return
+	var deleted []s3.DeletedObject
+	var errors []s3.ErrorObject
+	if len(identifiers) > 0 {
+		deleted, errors, err = s3Client.DeleteObjects(ctx, bucket, identifiers)
+		if err != nil {
+			s3Err := TranslateError(err, bucket, "")
+			s3Err.WriteXML(w)
+			h.logger.WithError(err).WithFields(logrus.Fields{
+				"bucket": bucket,
+			}).Error("Failed to delete objects")
+			h.metrics.RecordS3Error(r.Context(), "DeleteObjects", bucket, s3Err.Code)
+			h.metrics.RecordHTTPRequest(r.Context(), "POST", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+			return
+		}
 	}
+	errors = append(errors, keyErrors...)
 
 	// Invalidate cache for deleted objects
 	if h.cache != nil {
@@ -4221,6 +5056,11 @@ func (h *Handler) handleDeleteObjects(w http.ResponseWriter, r *http.Request) {
 			h.cache.Delete(ctx, bucket, del.Key)
 		}
 	}
+	for _, del := range deleted {
+		h.headMeta.invalidate(bucket, del.Key)
+		h.sizeIndex.Remove(bucket, del.Key)
+		h.accessStats.Remove(bucket, del.Key)
+	}
 
 	// Clean up MPU manifest companion objects for successfully deleted keys
 	// (best-effort). Non-MPU objects have no manifest, so 404s are expected
@@ -4267,10 +5107,10 @@ func (h *Handler) handleDeleteObjects(w http.ResponseWriter, r *http.Request) {
 	// Audit logging for batch delete
 	if h.auditLogger != nil {
 		for _, del := range deleted {
-			h.auditLogger.LogAccess("delete", bucket, del.Key, getClientIP(r), r.UserAgent(), getRequestID(r), true, nil, time.Since(start))
+			h.auditLogger.WithContext(r.Context()).LogAccess("delete", bucket, del.Key, getClientIP(r), r.UserAgent(), getRequestID(r), true, nil, time.Since(start))
 		}
 		for _, errObj := range errors {
-			h.auditLogger.LogAccess("delete", bucket, errObj.Key, getClientIP(r), r.UserAgent(), getRequestID(r), false, fmt.Errorf("%s: %s", errObj.Code, errObj.Message), time.Since(start))
+			h.auditLogger.WithContext(r.Context()).LogAccess("delete", bucket, errObj.Key, getClientIP(r), r.UserAgent(), getRequestID(r), false, fmt.Errorf("%s: %s", errObj.Code, errObj.Message), time.Since(start))
 		}
 	}
 
@@ -4384,6 +5224,77 @@ func effectiveMaxPartBuffer(cfg *config.Config) int64 {
 	return config.DefaultMaxPartBuffer
 }
 
+// cacheFill copies an object into memory as it streams to the client, for
+// the object cache. Objects larger than limit are not kept.
+type cacheFill struct {
+	r     io.Reader
+	buf   bytes.Buffer
+	limit int64 // zero for no limit
+	over  bool
+	done  bool
+}
+
+// newCacheFill wraps r, bounded by the configured cache size.
+func (h *Handler) newCacheFill(r io.Reader) *cacheFill {
+	f := &cacheFill{r: r}
+	if h.config != nil {
+		f.limit = h.config.Cache.MaxSize
+	}
+	return f
+}
+
+func (f *cacheFill) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if !f.over {
+		if f.limit > 0 && int64(f.buf.Len()+n) > f.limit {
+			f.over = true
+			f.buf = bytes.Buffer{}
+		} else {
+			f.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF {
+		f.done = true
+	}
+	return n, err
+}
+
+// data returns the object once it has been read to the end within the
+// limit. A nil *cacheFill has nothing.
+func (f *cacheFill) data() ([]byte, bool) {
+	if f == nil || f.over || !f.done {
+		return nil, false
+	}
+	return f.buf.Bytes(), true
+}
+
+// serveCachedObject answers a full GET from a cache entry. Age tells the
+// client how long ago the gateway last confirmed the copy with the backend.
+func (h *Handler) serveCachedObject(w http.ResponseWriter, r *http.Request, bucket, key string, entry *cache.CacheEntry, start time.Time) {
+	etag := entry.Metadata["ETag"]
+	if etag == "" {
+		etag = entry.ETag
+	}
+	if h.checkReadPreconditions(w, r, etag, entry.Metadata["Last-Modified"], start) {
+		return
+	}
+	for k, v := range entry.Metadata {
+		w.Header().Set(k, v)
+	}
+	if !entry.ValidatedAt.IsZero() {
+		age := max(time.Since(entry.ValidatedAt), 0)
+		w.Header().Set("Age", strconv.FormatInt(int64(age/time.Second), 10))
+	}
+	h.setIntegrityHeader(w, entry.Data)
+	w.WriteHeader(http.StatusOK)
+	w.Write(entry.Data)
+	h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, http.StatusOK, time.Since(start), int64(len(entry.Data)))
+	h.accessStats.Record(bucket, key)
+	if h.auditLogger != nil {
+		h.auditLogger.WithContext(r.Context()).LogAccess("get", bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), true, nil, time.Since(start))
+	}
+}
+
 // handleListBuckets handles GET / — ListBuckets.
 func (h *Handler) handleListBuckets(w http.ResponseWriter, r *http.Request) {
 	h.handlePassthrough(w, r, "ListBuckets", "", "")
@@ -4528,6 +5439,21 @@ func (h *Handler) handlePutBucketLogging(w http.ResponseWriter, r *http.Request)
 	h.handlePassthrough(w, r, "PutBucketLogging", mux.Vars(r)["bucket"], "")
 }
 
+// handleGetBucketTagging handles GET /{bucket}?tagging — GetBucketTagging.
+func (h *Handler) handleGetBucketTagging(w http.ResponseWriter, r *http.Request) {
+	h.handlePassthrough(w, r, "GetBucketTagging", mux.Vars(r)["bucket"], "")
+}
+
+// handlePutBucketTagging handles PUT /{bucket}?tagging — PutBucketTagging.
+func (h *Handler) handlePutBucketTagging(w http.ResponseWriter, r *http.Request) {
+	h.handlePassthrough(w, r, "PutBucketTagging", mux.Vars(r)["bucket"], "")
+}
+
+// handleDeleteBucketTagging handles DELETE /{bucket}?tagging — DeleteBucketTagging.
+func (h *Handler) handleDeleteBucketTagging(w http.ResponseWriter, r *http.Request) {
+	h.handlePassthrough(w, r, "DeleteBucketTagging", mux.Vars(r)["bucket"], "")
+}
+
 // handleGetBucketRequestPayment handles GET /{bucket}?requestPayment — GetBucketRequestPayment.
 func (h *Handler) handleGetBucketRequestPayment(w http.ResponseWriter, r *http.Request) {
 	h.handlePassthrough(w, r, "GetBucketRequestPayment", mux.Vars(r)["bucket"], "")
@@ -4578,21 +5504,6 @@ func (h *Handler) handlePutBucketIntelligentTiering(w http.ResponseWriter, r *ht
 	h.handlePassthrough(w, r, "PutBucketIntelligentTiering", mux.Vars(r)["bucket"], "")
 }
 
-// handleGetObjectTagging handles GET /{bucket}/{key}?tagging — GetObjectTagging.
-func (h *Handler) handleGetObjectTagging(w http.ResponseWriter, r *http.Request) {
-	h.handlePassthrough(w, r, "GetObjectTagging", mux.Vars(r)["bucket"], mux.Vars(r)["key"])
-}
-
-// handlePutObjectTagging handles PUT /{bucket}/{key}?tagging — PutObjectTagging.
-func (h *Handler) handlePutObjectTagging(w http.ResponseWriter, r *http.Request) {
-	h.handlePassthrough(w, r, "PutObjectTagging", mux.Vars(r)["bucket"], mux.Vars(r)["key"])
-}
-
-// handleDeleteObjectTagging handles DELETE /{bucket}/{key}?tagging — DeleteObjectTagging.
-func (h *Handler) handleDeleteObjectTagging(w http.ResponseWriter, r *http.Request) {
-	h.handlePassthrough(w, r, "DeleteObjectTagging", mux.Vars(r)["bucket"], mux.Vars(r)["key"])
-}
-
 // handleGetObjectACL handles GET /{bucket}/{key}?acl — GetObjectACL.
 func (h *Handler) handleGetObjectACL(w http.ResponseWriter, r *http.Request) {
 	h.handlePassthrough(w, r, "GetObjectACL", mux.Vars(r)["bucket"], mux.Vars(r)["key"])
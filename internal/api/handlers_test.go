@@ -3,6 +3,7 @@ package api
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -16,10 +17,14 @@ import (
 
 	"github.com/aws/smithy-go"
 	"github.com/gorilla/mux"
+	"github.com/kenneth/s3-encryption-gateway/internal/authz"
 	"github.com/kenneth/s3-encryption-gateway/internal/config"
 	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/identity"
 	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
 	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 )
 
@@ -60,6 +65,7 @@ type mockS3Client struct {
 	retentions       map[string]*s3.RetentionConfig
 	legalHolds       map[string]string
 	lockConfigs      map[string]*s3.ObjectLockConfiguration
+	lastPutTags      string
 }
 
 func newMockS3Client() *mockS3Client {
@@ -82,6 +88,7 @@ func (m *mockS3Client) PutObject(ctx context.Context, bucket, key string, reader
 	m.metadata[bucket+"/"+key] = metadata
 	m.locksMu.Lock()
 	m.lastPutLock = lock
+	m.lastPutTags = tags
 	m.locksMu.Unlock()
 	return nil
 }
@@ -358,6 +365,25 @@ func TestHandler_HandleHealth(t *testing.T) {
 	}
 }
 
+func TestHandler_HandleVersion(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	mockEngine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	handler := NewHandler(newMockS3Client(), mockEngine, logger, getTestMetrics())
+
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/version", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"commit"`) {
+		t.Errorf("version body = %s", w.Body)
+	}
+}
+
 func TestHandler_HandlePutObject(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
@@ -1408,6 +1434,18 @@ func (m *mockS3Client) GetObjectLockConfiguration(ctx context.Context, bucket st
 	return nil, nil
 }
 
+func (m *mockS3Client) PutObjectTagging(ctx context.Context, bucket, key string, versionID *string, tags []s3.Tag) error {
+	return nil
+}
+
+func (m *mockS3Client) GetObjectTagging(ctx context.Context, bucket, key string, versionID *string) ([]s3.Tag, error) {
+	return nil, nil
+}
+
+func (m *mockS3Client) DeleteObjectTagging(ctx context.Context, bucket, key string, versionID *string) error {
+	return nil
+}
+
 // ---------------------------------------------------------------------------
 // MPU manifest cleanup tests
 // ---------------------------------------------------------------------------
@@ -1556,6 +1594,44 @@ func TestDeleteObjects_CleansUpMPUManifests(t *testing.T) {
 	}
 }
 
+// TestDeleteObjects_AuthorizedPerKey checks that a batch delete removes
+// only the keys the client may delete and reports the rest as AccessDenied.
+func TestDeleteObjects_AuthorizedPerKey(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	mockClient := newMockS3Client()
+	mockEngine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	handler := NewHandler(mockClient, mockEngine, logger, getTestMetrics())
+	handler.WithAuthorization(authz.New(config.AuthPolicyConfig{Rules: []config.AuthPolicyRule{
+		{Effect: config.AuthPolicyDeny, Identities: []string{"ci"}, Prefixes: []string{"locked/"}, Operations: []string{"DeleteObject"}},
+	}}))
+
+	mockClient.PutObject(context.Background(), "test-bucket", "tmp/a", bytes.NewReader([]byte("a")), nil, nil, "", nil)
+	mockClient.PutObject(context.Background(), "test-bucket", "locked/b", bytes.NewReader([]byte("b")), nil, nil, "", nil)
+
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	body := `<Delete><Object><Key>tmp/a</Key></Object><Object><Key>locked/b</Key></Object></Delete>`
+	req := httptest.NewRequest("POST", "/test-bucket?delete", bytes.NewReader([]byte(body)))
+	req = req.WithContext(identity.NewContext(req.Context(), identity.Identity{AccessKey: "AKIACI", Label: "ci"}))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "<Key>locked/b</Key><Code>AccessDenied</Code>") {
+		t.Errorf("response does not report locked/b as AccessDenied: %s", w.Body.String())
+	}
+	if _, _, err := mockClient.GetObject(context.Background(), "test-bucket", "tmp/a", nil, nil); err == nil {
+		t.Error("tmp/a should have been deleted")
+	}
+	if _, _, err := mockClient.GetObject(context.Background(), "test-bucket", "locked/b", nil, nil); err != nil {
+		t.Errorf("locked/b should have been kept: %v", err)
+	}
+}
+
 func TestDeleteObjects_ManifestNotFoundIsNoop(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
@@ -1912,27 +1988,6 @@ func TestObjectKeyPassthrough_RoutingVars(t *testing.T) {
 		wantBackendPath string
 		wantQuery       string
 	}{
-		{
-			name:            "GetObjectTagging",
-			method:          "GET",
-			url:             "/my-bucket/path/to/object?tagging",
-			wantBackendPath: "/my-bucket/path/to/object",
-			wantQuery:       "tagging",
-		},
-		{
-			name:            "PutObjectTagging",
-			method:          "PUT",
-			url:             "/my-bucket/path/to/object?tagging",
-			wantBackendPath: "/my-bucket/path/to/object",
-			wantQuery:       "tagging",
-		},
-		{
-			name:            "DeleteObjectTagging",
-			method:          "DELETE",
-			url:             "/my-bucket/path/to/object?tagging",
-			wantBackendPath: "/my-bucket/path/to/object",
-			wantQuery:       "tagging",
-		},
 		{
 			name:            "GetObjectACL",
 			method:          "GET",
@@ -1998,3 +2053,189 @@ func TestObjectKeyPassthrough_RoutingVars(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_RoundTripWithMemoryClient(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	backend := testsupport.NewMemoryClient()
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	router := mux.NewRouter()
+	NewHandler(backend, engine, logger, getTestMetrics()).RegisterRoutes(router)
+
+	plaintext := []byte("quarterly numbers")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("PUT", "/bucket/report.txt", bytes.NewReader(plaintext)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d: %s", w.Code, w.Body.String())
+	}
+	stored, meta, ok := backend.Object("bucket", "report.txt")
+	if !ok || bytes.Contains(stored, plaintext) || len(meta) == 0 {
+		t.Fatalf("backend should hold ciphertext with encryption metadata, got %q %v", stored, meta)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/bucket/report.txt", nil))
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), plaintext) {
+		t.Errorf("GET = %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/bucket/report.txt", nil))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/bucket/report.txt", nil))
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "NoSuchKey") {
+		t.Errorf("GET after DELETE = %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandler_WithReadyCheck(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	handler := NewHandler(testsupport.NewMemoryClient(), engine, logger, getTestMetrics())
+	checkErr := errors.New("self-test has not completed")
+	handler.WithReadyCheck(metrics.ReadyCheck{Name: "self_test", Check: func(context.Context) error { return checkErr }})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/ready", nil))
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), `"self_test":"unavailable: self-test has not completed"`) {
+		t.Errorf("failing check: %d %s", w.Code, w.Body.String())
+	}
+
+	checkErr = nil
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/ready", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"self_test":"ok"`) {
+		t.Errorf("passing check: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandler_GetNewerFormatVersion(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	backend := testsupport.NewMemoryClient()
+	engine, _ := crypto.NewEngineWithOpts([]byte("test-password-123456"), nil, crypto.WithChunking(true))
+	reg := prometheus.NewRegistry()
+	router := mux.NewRouter()
+	NewHandler(backend, engine, logger, metrics.NewMetricsWithRegistry(reg)).RegisterRoutes(router)
+
+	// Store an object the way an upgraded replica with the next chunk
+	// manifest version would.
+	enc, meta, err := engine.Encrypt(context.Background(), strings.NewReader("from the future"), map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, _ := base64.StdEncoding.DecodeString(meta[crypto.MetaManifest])
+	meta[crypto.MetaManifest] = base64.StdEncoding.EncodeToString(bytes.Replace(manifest,
+		[]byte(fmt.Sprintf(`"v":%d`, crypto.ChunkManifestVersion)), []byte(fmt.Sprintf(`"v":%d`, crypto.ChunkManifestVersion+1)), 1))
+	if err := backend.PutObject(context.Background(), "bucket", "new.bin", enc, meta, nil, "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/bucket/new.bin", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("GET status = %d, want 500", w.Code)
+	}
+	mfs, _ := reg.Gather()
+	var skew float64
+	for _, mf := range mfs {
+		if mf.GetName() == "gateway_format_version_skew_total" {
+			for _, m := range mf.GetMetric() {
+				skew += m.GetCounter().GetValue()
+			}
+		}
+	}
+	if skew != 1 {
+		t.Errorf("gateway_format_version_skew_total = %v, want 1", skew)
+	}
+}
+
+func TestHandler_WithMetadataCodec(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	backend := testsupport.NewMemoryClient()
+	engine, _ := crypto.NewEngineWithOpts([]byte("test-password-123456"), nil, crypto.WithChunking(true))
+	prefixer, err := crypto.NewMetadataPrefixer("gw-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An object written before the prefix was configured.
+	enc, meta, err := engine.Encrypt(context.Background(), strings.NewReader("old"), map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.PutObject(context.Background(), "bucket", "old.txt", enc, meta, nil, "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(backend, engine, logger, getTestMetrics())
+	handler.WithMetadataCodec(prefixer)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("PUT", "/bucket/new.txt", strings.NewReader("new")))
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d: %s", w.Code, w.Body.String())
+	}
+	_, stored, _ := backend.Object("bucket", "new.txt")
+	for k := range stored {
+		if strings.HasPrefix(k, "x-amz-meta-encryption-") || k == crypto.MetaEncrypted {
+			t.Errorf("stored metadata uses the default name %s", k)
+		}
+	}
+	if stored["x-amz-meta-gw-enabled"] != "true" {
+		t.Errorf("stored metadata = %v, want gw- names", stored)
+	}
+
+	for key, want := range map[string]string{"new.txt": "new", "old.txt": "old"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/bucket/"+key, nil))
+		if w.Code != http.StatusOK || w.Body.String() != want {
+			t.Errorf("GET %s = %d %q, want %q", key, w.Code, w.Body.String(), want)
+		}
+		for h := range w.Header() {
+			if strings.HasPrefix(strings.ToLower(h), "x-amz-meta-gw-") {
+				t.Errorf("GET %s exposes gateway header %s", key, h)
+			}
+		}
+	}
+}
+
+func TestChunkedObject_ExactSizeWithoutContentLength(t *testing.T) {
+	client := testsupport.NewMemoryClient()
+	_, srv := newUpgradeTestServer(t, client, true)
+	plaintext := bytes.Repeat([]byte("s"), 3*crypto.DefaultChunkSize+17)
+
+	// A reader without a known length makes the client stream the body
+	// with chunked transfer encoding, so no size is recorded at write time.
+	req, _ := http.NewRequest("PUT", srv.URL+"/bucket/streamed", io.MultiReader(bytes.NewReader(plaintext)))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT = %d", resp.StatusCode)
+	}
+
+	want := fmt.Sprint(len(plaintext))
+	head, _ := http.NewRequest("HEAD", srv.URL+"/bucket/streamed", nil)
+	resp, err = http.DefaultClient.Do(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("Content-Length"); got != want {
+		t.Errorf("HEAD Content-Length = %s, want %s", got, want)
+	}
+
+	resp, body := getResponse(t, srv, "/bucket/streamed", "")
+	if got := resp.Header.Get("Content-Length"); got != want || !bytes.Equal(body, plaintext) {
+		t.Errorf("GET Content-Length = %s (%d bytes), want %s", got, len(body), want)
+	}
+}
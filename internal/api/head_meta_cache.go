@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// headMetaCache remembers HEAD metadata of chunked objects so a range read
+// can compute the encrypted byte range without asking the backend first.
+// Entries are only hints: the caller checks the ETag of the GET it sends and
+// starts over with a HEAD when it differs.
+//
+// A nil *headMetaCache never hits.
+type headMetaCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	objects map[string]map[string]headMetaEntry // bucket/key → version → entry
+	size    int
+}
+
+type headMetaEntry struct {
+	meta      map[string]string
+	expiresAt time.Time
+}
+
+// newHeadMetaCache returns a cache for cfg, or nil when elision is disabled.
+func newHeadMetaCache(cfg config.BackendHeadElisionConfig) *headMetaCache {
+	if !cfg.Enabled {
+		return nil
+	}
+	ttl, maxEntries := cfg.TTL, cfg.MaxEntries
+	if ttl <= 0 {
+		ttl = config.DefaultHeadElisionTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = config.DefaultHeadElisionMaxEntries
+	}
+	return &headMetaCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		objects:    make(map[string]map[string]headMetaEntry),
+	}
+}
+
+func headMetaObjectKey(bucket, key string) string { return bucket + "/" + key }
+
+func headMetaVersion(versionID *string) string {
+	if versionID == nil {
+		return ""
+	}
+	return *versionID
+}
+
+// get returns the cached metadata of bucket/key at versionID.
+func (c *headMetaCache) get(bucket, key string, versionID *string) (map[string]string, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	versions := c.objects[headMetaObjectKey(bucket, key)]
+	e, ok := versions[headMetaVersion(versionID)]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expiresAt) {
+		c.remove(headMetaObjectKey(bucket, key), headMetaVersion(versionID))
+		return nil, false
+	}
+	return e.meta, true
+}
+
+// put caches meta for bucket/key at versionID. Metadata without an ETag is
+// not cached because a later GET could not be checked against it.
+func (c *headMetaCache) put(bucket, key string, versionID *string, meta map[string]string) {
+	if c == nil || meta["ETag"] == "" {
+		return
+	}
+	objKey, version := headMetaObjectKey(bucket, key), headMetaVersion(versionID)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	versions := c.objects[objKey]
+	if _, exists := versions[version]; !exists && c.size >= c.maxEntries {
+		c.evict()
+	}
+	if versions == nil {
+		versions = make(map[string]headMetaEntry)
+		c.objects[objKey] = versions
+	}
+	if _, exists := versions[version]; !exists {
+		c.size++
+	}
+	versions[version] = headMetaEntry{meta: meta, expiresAt: time.Now().Add(c.ttl)}
+}
+
+// invalidate drops every cached version of bucket/key, after a write or
+// delete through this gateway.
+func (c *headMetaCache) invalidate(bucket, key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	objKey := headMetaObjectKey(bucket, key)
+	c.size -= len(c.objects[objKey])
+	delete(c.objects, objKey)
+}
+
+// remove deletes one entry. The caller must hold c.mu.
+func (c *headMetaCache) remove(objKey, version string) {
+	versions := c.objects[objKey]
+	if _, ok := versions[version]; !ok {
+		return
+	}
+	delete(versions, version)
+	c.size--
+	if len(versions) == 0 {
+		delete(c.objects, objKey)
+	}
+}
+
+// evict makes room for one entry, preferring expired ones and otherwise
+// dropping an arbitrary object. The caller must hold c.mu.
+func (c *headMetaCache) evict() {
+	now := time.Now()
+	for objKey, versions := range c.objects {
+		for version, e := range versions {
+			if now.After(e.expiresAt) {
+				c.remove(objKey, version)
+			}
+		}
+	}
+	for objKey, versions := range c.objects {
+		if c.size < c.maxEntries {
+			return
+		}
+		c.size -= len(versions)
+		delete(c.objects, objKey)
+	}
+}
+
+// chunkedBackendRange maps rangeHeader onto the chunks of a chunked object
+// described by meta and returns the plaintext bounds and the backend Range.
+func chunkedBackendRange(meta map[string]string, rangeHeader string) (start, end int64, backendRange string, err error) {
+	plaintextSize, err := crypto.GetPlaintextSizeFromMetadata(meta)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("plaintext size: %w", err)
+	}
+	start, end, err = crypto.ParseHTTPRangeHeader(rangeHeader, plaintextSize)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("range header: %w", err)
+	}
+	encryptedStart, encryptedEnd, err := crypto.CalculateEncryptedRangeForPlaintextRange(meta, start, end)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("encrypted range: %w", err)
+	}
+	return start, end, fmt.Sprintf("bytes=%d-%d", encryptedStart, encryptedEnd), nil
+}
+
+// getCachedRange fetches rangeHeader of a chunked object using its cached
+// HEAD metadata. It returns a nil reader when nothing is cached or the GET
+// does not match the cache, and the caller then takes the HEAD path.
+func (h *Handler) getCachedRange(ctx context.Context, s3Client s3.Client, bucket, key string, versionID *string, rangeHeader string) (io.ReadCloser, map[string]string, int64, int64) {
+	cached, ok := h.headMeta.get(bucket, key, versionID)
+	if !ok {
+		h.metrics.RecordHeadElision("miss")
+		return nil, nil, 0, 0
+	}
+	start, end, backendRange, err := chunkedBackendRange(cached, rangeHeader)
+	if err != nil {
+		// Unsatisfiable ranges and the like are reported by the HEAD path.
+		h.metrics.RecordHeadElision("miss")
+		return nil, nil, 0, 0
+	}
+	reader, meta, err := h.getCiphertextRange(ctx, s3Client, bucket, key, versionID, backendRange)
+	if err == nil && meta["ETag"] == cached["ETag"] {
+		h.metrics.RecordHeadElision("hit")
+		return reader, meta, start, end
+	}
+	// The object changed or vanished since it was cached; the bytes fetched
+	// belong to a different layout.
+	if err == nil {
+		reader.Close()
+	}
+	h.headMeta.invalidate(bucket, key)
+	h.metrics.RecordHeadElision("stale")
+	return nil, nil, 0, 0
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+)
+
+func TestHeadMetaCache(t *testing.T) {
+	c := newHeadMetaCache(config.BackendHeadElisionConfig{Enabled: true, TTL: time.Minute, MaxEntries: 2})
+	v1 := "v1"
+
+	c.put("b", "no-etag", nil, map[string]string{"x": "1"})
+	if _, ok := c.get("b", "no-etag", nil); ok {
+		t.Error("metadata without an ETag must not be cached")
+	}
+
+	c.put("b", "k", nil, map[string]string{"ETag": `"a"`})
+	c.put("b", "k", &v1, map[string]string{"ETag": `"b"`})
+	if meta, ok := c.get("b", "k", &v1); !ok || meta["ETag"] != `"b"` {
+		t.Errorf("get(v1) = %v, %v", meta, ok)
+	}
+	c.invalidate("b", "k")
+	if _, ok := c.get("b", "k", nil); ok {
+		t.Error("invalidate should drop the latest version")
+	}
+	if _, ok := c.get("b", "k", &v1); ok {
+		t.Error("invalidate should drop every version")
+	}
+
+	for i := range 5 {
+		c.put("b", fmt.Sprintf("k%d", i), nil, map[string]string{"ETag": "e"})
+	}
+	if c.size > 2 {
+		t.Errorf("size = %d, want at most 2", c.size)
+	}
+	if _, ok := c.get("b", "k4", nil); !ok {
+		t.Error("most recent entry should be cached")
+	}
+
+	var nilCache *headMetaCache
+	nilCache.put("b", "k", nil, map[string]string{"ETag": "e"})
+	if _, ok := nilCache.get("b", "k", nil); ok {
+		t.Error("nil cache should never hit")
+	}
+}
+
+func TestHeadMetaCache_Expiry(t *testing.T) {
+	c := newHeadMetaCache(config.BackendHeadElisionConfig{Enabled: true, TTL: time.Millisecond, MaxEntries: 10})
+	c.put("b", "k", nil, map[string]string{"ETag": "e"})
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.get("b", "k", nil); ok {
+		t.Error("expired entry should not be returned")
+	}
+	if c.size != 0 {
+		t.Errorf("size = %d after expiry, want 0", c.size)
+	}
+}
+
+// etagClient reports etag on every response and counts HEAD requests.
+type etagClient struct {
+	*mockS3Client
+	etag  atomic.Value
+	heads atomic.Int32
+}
+
+func (c *etagClient) HeadObject(ctx context.Context, bucket, key string, versionID *string) (map[string]string, error) {
+	c.heads.Add(1)
+	meta, err := c.mockS3Client.HeadObject(ctx, bucket, key, versionID)
+	if err != nil {
+		return nil, err
+	}
+	meta = maps.Clone(meta)
+	meta["ETag"] = c.etag.Load().(string)
+	return meta, nil
+}
+
+func (c *etagClient) GetObject(ctx context.Context, bucket, key string, versionID, rangeHeader *string) (io.ReadCloser, map[string]string, error) {
+	body, meta, err := c.mockS3Client.GetObject(ctx, bucket, key, versionID, rangeHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+	meta = maps.Clone(meta)
+	meta["ETag"] = c.etag.Load().(string)
+	return body, meta, nil
+}
+
+func TestHandleGetObject_HeadElision(t *testing.T) {
+	engine, err := crypto.NewEngineWithChunking([]byte("test-password-123456"), nil, "", nil, true, 16*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &etagClient{mockS3Client: newMockS3Client()}
+	client.etag.Store(`"v1"`)
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	h := NewHandler(client, engine, logger, getTestMetrics())
+	h.headMeta = newHeadMetaCache(config.BackendHeadElisionConfig{Enabled: true, TTL: time.Minute, MaxEntries: 10})
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	// store writes data to the backend directly, as another gateway would.
+	store := func(data []byte, etag string) {
+		t.Helper()
+		encReader, meta, err := engine.Encrypt(context.Background(), bytes.NewReader(data), map[string]string{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc, _ := io.ReadAll(encReader)
+		meta[crypto.MetaChunkCount] = fmt.Sprint(len(data) / (16 * 1024))
+		meta[crypto.MetaChunkSize] = "16384"
+		if err := client.PutObject(context.Background(), "bucket", "obj", bytes.NewReader(enc), meta, nil, "", nil); err != nil {
+			t.Fatal(err)
+		}
+		client.etag.Store(etag)
+	}
+	get := func(want []byte, wantHead bool) {
+		t.Helper()
+		before := client.heads.Load()
+		req := httptest.NewRequest("GET", "/bucket/obj", nil)
+		req.Header.Set("Range", "bytes=20000-20099")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusPartialContent {
+			t.Fatalf("GET status = %d: %s", w.Code, w.Body.String())
+		}
+		if !bytes.Equal(w.Body.Bytes(), want[20000:20100]) {
+			t.Error("ranged GET returned the wrong bytes")
+		}
+		if headed := client.heads.Load() > before; headed != wantHead {
+			t.Errorf("GET sent a HEAD = %v, want %v", headed, wantHead)
+		}
+	}
+
+	data := make([]byte, 48*1024)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	store(data, `"v1"`)
+	get(data, true)
+	get(data, false) // served from the cached layout
+
+	// Overwritten behind the gateway's back: the ETag no longer matches.
+	other := bytes.Repeat([]byte("z"), 32*1024)
+	store(other, `"v2"`)
+	get(other, true)
+	get(other, false)
+
+	// A delete through this gateway drops the cached layout.
+	req := httptest.NewRequest("DELETE", "/bucket/obj", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	store(data, `"v2"`)
+	get(data, true)
+}
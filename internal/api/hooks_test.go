@@ -0,0 +1,85 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/hooks"
+	"github.com/sirupsen/logrus"
+)
+
+func TestHooks_DerivedObjectIsEncrypted(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	mockClient := newMockS3Client()
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	h := NewHandler(mockClient, engine, logger, getTestMetrics())
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	var seen []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "image/jpeg")
+		_, _ = w.Write([]byte("thumbnail-of-" + r.Header.Get("X-S3EG-Key")))
+	}))
+	defer srv.Close()
+
+	cfg := config.HooksConfig{
+		Enabled: true, Workers: 1, QueueSize: 10,
+		MaxObjectSize: 1 << 20, MaxOutputSize: 1 << 20,
+		Rules: []config.HookRule{{
+			Name: "thumbs", ContentTypes: []string{"image/*"},
+			Webhook: srv.URL, Output: "thumbs/{key}.jpg",
+		}},
+	}
+	p, err := hooks.New(cfg, h, logger)
+	if err != nil {
+		t.Fatalf("hooks.New: %v", err)
+	}
+	p.Start()
+	h.WithHooks(p)
+
+	for key, ct := range map[string]string{"img/cat.png": "image/png", "doc.txt": "text/plain"} {
+		req := httptest.NewRequest("PUT", "/b/"+key, bytes.NewReader([]byte("pixels")))
+		req.Header.Set("Content-Type", ct)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("PUT %s: %d", key, w.Code)
+		}
+	}
+	if err := p.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	if string(seen) != "pixels" {
+		t.Errorf("webhook received %q, want the plaintext", seen)
+	}
+	stored := mockClient.objects["b/thumbs/img/cat.png.jpg"]
+	if len(stored) == 0 {
+		t.Fatal("derived object was not stored")
+	}
+	if bytes.Contains(stored, []byte("thumbnail-of")) {
+		t.Error("derived object was stored unencrypted")
+	}
+	if _, ok := mockClient.objects["b/thumbs/doc.txt.jpg"]; ok {
+		t.Error("non-matching content type triggered the hook")
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/b/thumbs/img/cat.png.jpg", nil))
+	if got := w.Body.String(); got != "thumbnail-of-img/cat.png" {
+		t.Errorf("GET derived = %q", got)
+	}
+	if from := w.Header().Get(hooks.MetaDerivedFrom); from != "img/cat.png" {
+		t.Errorf("%s = %q", hooks.MetaDerivedFrom, from)
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/dlp"
+	"github.com/sirupsen/logrus"
+)
+
+// WithInspector enables content inspection of PutObject uploads. Tag rules
+// set tagKey on matching objects.
+func (h *Handler) WithInspector(in *dlp.Inspector, tagKey string) {
+	h.inspector = in
+	h.inspectTagKey = tagKey
+}
+
+// inspectPut runs content inspection on a PutObject body. It returns the
+// body (replaying the inspected prefix) and the tagging to store, or
+// ok=false after writing the error response.
+func (h *Handler) inspectPut(w http.ResponseWriter, r *http.Request, bucket, key string, body io.Reader, contentType, tagging string, start time.Time) (io.Reader, string, bool) {
+	if !h.inspector.Applies(contentType) {
+		return body, tagging, true
+	}
+
+	inspectStart := time.Now()
+	limit := h.inspector.MaxSize()
+	head, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"bucket": bucket,
+			"key":    key,
+		}).Error("Failed to read upload for content inspection")
+		s3Err := &S3Error{
+			Code:       "IncompleteBody",
+			Message:    "Failed to read the request body",
+			Resource:   r.URL.Path,
+			HTTPStatus: http.StatusBadRequest,
+		}
+		s3Err.WriteXML(w)
+		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return nil, "", false
+	}
+	body = io.MultiReader(bytes.NewReader(head), body)
+	truncated := int64(len(head)) > limit
+	if truncated {
+		head = head[:limit]
+	}
+
+	res := h.inspector.Inspect(head)
+	res.Truncated = truncated
+	if res.Action == "" {
+		h.metrics.RecordDLPInspection("clean")
+		return body, tagging, true
+	}
+
+	h.metrics.RecordDLPInspection(res.Action)
+	counts := make(map[string]interface{}, len(res.Matches))
+	for _, m := range res.Matches {
+		h.metrics.RecordDLPMatch(m.Rule, m.Action, m.Count)
+		counts[m.Rule] = m.Count
+	}
+	rules := res.Rules(config.InspectionActionAudit)
+	h.logger.WithFields(logrus.Fields{
+		"bucket":    bucket,
+		"key":       key,
+		"action":    res.Action,
+		"rules":     rules,
+		"truncated": res.Truncated,
+	}).Warn("Content inspection rules matched upload")
+	if h.auditLogger != nil {
+		h.auditLogger.WithContext(r.Context()).LogAccessWithMetadata("dlp_match", bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r),
+			res.Action != config.InspectionActionBlock, nil, time.Since(inspectStart), map[string]interface{}{
+				"action":    res.Action,
+				"matches":   counts,
+				"truncated": res.Truncated,
+			})
+	}
+
+	switch res.Action {
+	case config.InspectionActionBlock:
+		s3Err := &S3Error{
+			Code:       "AccessDenied",
+			Message:    "Upload rejected by content inspection policy: " + strings.Join(res.Rules(config.InspectionActionBlock), ", "),
+			Resource:   r.URL.Path,
+			HTTPStatus: http.StatusForbidden,
+		}
+		s3Err.WriteXML(w)
+		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return nil, "", false
+	case config.InspectionActionTag:
+		tagging = setTag(tagging, h.inspectTagKey, strings.Join(res.Rules(config.InspectionActionTag), "+"))
+	}
+	return body, tagging, true
+}
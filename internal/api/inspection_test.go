@@ -0,0 +1,126 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kenneth/s3-encryption-gateway/internal/audit"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/dlp"
+	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+func newInspectionTestHandler(t *testing.T, maxSize int64) (*mockS3Client, *mux.Router, audit.Logger, *prometheus.Registry) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	mockClient := newMockS3Client()
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	reg := prometheus.NewRegistry()
+	auditLogger := audit.NewLogger(100, nil)
+	h := NewHandlerWithFeatures(mockClient, engine, logger, metrics.NewMetricsWithRegistry(reg), nil, nil, auditLogger, nil, nil)
+
+	in, err := dlp.New(config.InspectionConfig{
+		Enabled: true,
+		MaxSize: maxSize,
+		Rules: []config.InspectionRule{
+			{Name: "cards", Builtin: "credit_card", Action: config.InspectionActionBlock},
+			{Name: "confidential", Keywords: []string{"confidential"}, Action: config.InspectionActionTag},
+			{Name: "codename", Pattern: `project-[a-z]+`, Action: config.InspectionActionAudit},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.WithInspector(in, config.DefaultInspectionTagKey)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	return mockClient, router, auditLogger, reg
+}
+
+func TestInspection_Actions(t *testing.T) {
+	mockClient, router, auditLogger, reg := newInspectionTestHandler(t, 1<<10)
+	text := map[string]string{"Content-Type": "text/plain"}
+
+	w := putBody(router, "/b/cards.txt", "card: 4111 1111 1111 1111", text)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("blocked PUT: %d, want 403", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "4111") {
+		t.Error("error response echoes the matched content")
+	}
+	if _, ok := mockClient.objects["b/cards.txt"]; ok {
+		t.Error("blocked upload reached the backend")
+	}
+
+	w = putBody(router, "/b/memo.txt", "Confidential memo about project-x", text)
+	if w.Code != http.StatusOK {
+		t.Fatalf("tagged PUT: %d", w.Code)
+	}
+	if mockClient.lastPutTags != "s3eg-dlp=confidential" {
+		t.Errorf("tags = %q", mockClient.lastPutTags)
+	}
+	g := httptest.NewRecorder()
+	router.ServeHTTP(g, httptest.NewRequest("GET", "/b/memo.txt", nil))
+	if g.Body.String() != "Confidential memo about project-x" {
+		t.Errorf("stored body = %q", g.Body.String())
+	}
+
+	putBody(router, "/b/plan.txt", "notes on project-y", text)
+	if mockClient.lastPutTags != "" {
+		t.Errorf("audit-only match set tags %q", mockClient.lastPutTags)
+	}
+
+	// Binary content types are not inspected.
+	if w := putBody(router, "/b/cards.bin", "4111 1111 1111 1111", map[string]string{"Content-Type": "application/octet-stream"}); w.Code != http.StatusOK {
+		t.Errorf("binary PUT: %d", w.Code)
+	}
+
+	var events int
+	for _, ev := range auditLogger.GetEvents() {
+		if ev.Operation == "dlp_match" {
+			events++
+			if _, ok := ev.Metadata["matches"]; !ok {
+				t.Errorf("audit event without match counts: %+v", ev)
+			}
+		}
+	}
+	if events != 3 {
+		t.Errorf("dlp audit events = %d, want 3", events)
+	}
+
+	mfs, _ := reg.Gather()
+	var found bool
+	for _, mf := range mfs {
+		if mf.GetName() == "gateway_dlp_matches_total" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("gateway_dlp_matches_total not recorded")
+	}
+}
+
+func TestInspection_OnlyLeadingBytes(t *testing.T) {
+	mockClient, router, _, _ := newInspectionTestHandler(t, 16)
+	body := strings.Repeat("a", 32) + " 4111 1111 1111 1111"
+	w := putBody(router, "/b/long.txt", body, map[string]string{"Content-Type": "text/plain"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT: %d", w.Code)
+	}
+	g := httptest.NewRecorder()
+	router.ServeHTTP(g, httptest.NewRequest("GET", "/b/long.txt", nil))
+	if g.Body.String() != body {
+		t.Errorf("upload was not replayed in full: %d bytes", g.Body.Len())
+	}
+	if bytes.Contains(mockClient.objects["b/long.txt"], []byte("4111")) {
+		t.Error("upload stored unencrypted")
+	}
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// IntegrityTrailer reports, when server.integrity_trailer is enabled, that
+// a full-object GET decrypted with every authentication tag verified, along
+// with the SHA-256 and length of the plaintext sent:
+//
+//	X-S3eg-Integrity: verified; sha256=<base64>; length=<bytes>
+//
+// Streamed responses carry it as an HTTP trailer; cache hits, whose bytes
+// are known up front, carry it as a header.
+const IntegrityTrailer = "X-S3eg-Integrity"
+
+func integrityValue(sum []byte, length int64) string {
+	return fmt.Sprintf("verified; sha256=%s; length=%d", base64.StdEncoding.EncodeToString(sum), length)
+}
+
+// setIntegrityHeader sets the integrity header for a body held in memory,
+// which was verified when it was decrypted.
+func (h *Handler) setIntegrityHeader(w http.ResponseWriter, plaintext []byte) {
+	if h.config == nil || !h.config.Server.IntegrityTrailer {
+		return
+	}
+	sum := sha256.Sum256(plaintext)
+	w.Header().Set(IntegrityTrailer, integrityValue(sum[:], int64(len(plaintext))))
+}
+
+// Checksum trailers sent, when server.checksum_trailers is enabled, to
+// clients that ask for them with "TE: trailers" or
+// "x-amz-checksum-mode: ENABLED". Values are base64 as in S3, and cover the
+// plaintext.
+const (
+	ChecksumSHA256Trailer = "X-Amz-Checksum-Sha256"
+	ChecksumCRC32CTrailer = "X-Amz-Checksum-Crc32c"
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// integrityReader hashes plaintext as it is streamed to the client.
+type integrityReader struct {
+	r         io.Reader
+	sha       hash.Hash
+	crc       hash.Hash32
+	n         int64
+	trailers  []string
+	integrity bool
+	checksums bool
+}
+
+// integrityReader wraps r when the response to req should end with the
+// integrity trailer or checksum trailers, and returns nil otherwise. It only
+// suits full-object bodies streamed after WriteHeader.
+func (h *Handler) integrityReader(req *http.Request, r io.Reader) *integrityReader {
+	if h.config == nil {
+		return nil
+	}
+	ir := &integrityReader{
+		integrity: h.config.Server.IntegrityTrailer,
+		checksums: h.config.Server.ChecksumTrailers && wantsChecksumTrailers(req),
+	}
+	if ir.integrity {
+		ir.trailers = append(ir.trailers, IntegrityTrailer)
+	}
+	if ir.checksums {
+		ir.trailers = append(ir.trailers, ChecksumSHA256Trailer, ChecksumCRC32CTrailer)
+		ir.crc = crc32.New(crc32cTable)
+	}
+	if len(ir.trailers) == 0 {
+		return nil
+	}
+	ir.r = r
+	ir.sha = sha256.New()
+	return ir
+}
+
+func wantsChecksumTrailers(req *http.Request) bool {
+	if strings.EqualFold(req.Header.Get("x-amz-checksum-mode"), "ENABLED") {
+		return true
+	}
+	for _, te := range strings.Split(req.Header.Get("TE"), ",") {
+		if name, _, _ := strings.Cut(strings.TrimSpace(te), ";"); strings.EqualFold(name, "trailers") {
+			return true
+		}
+	}
+	return false
+}
+
+func (ir *integrityReader) Read(p []byte) (int, error) {
+	n, err := ir.r.Read(p)
+	ir.sha.Write(p[:n])
+	if ir.crc != nil {
+		ir.crc.Write(p[:n])
+	}
+	ir.n += int64(n)
+	return n, err
+}
+
+// declare announces the trailers and removes headers of the same names,
+// which would describe the stored ciphertext. Content-Length is dropped
+// because only a chunked response can carry trailers. Call it before
+// WriteHeader.
+func (ir *integrityReader) declare(w http.ResponseWriter) {
+	for _, t := range ir.trailers {
+		w.Header().Del(t)
+	}
+	w.Header().Set("Trailer", strings.Join(ir.trailers, ", "))
+	w.Header().Del("Content-Length")
+}
+
+// finish sets the trailers once the body has been sent in full. After a
+// failed stream it aborts the connection instead: without Content-Length a
+// cleanly ended chunked body would otherwise look complete.
+func (ir *integrityReader) finish(w http.ResponseWriter, streamErr error) {
+	if streamErr != nil {
+		panic(http.ErrAbortHandler)
+	}
+	sum := ir.sha.Sum(nil)
+	if ir.integrity {
+		w.Header().Set(IntegrityTrailer, integrityValue(sum, ir.n))
+	}
+	if ir.checksums {
+		w.Header().Set(ChecksumSHA256Trailer, base64.StdEncoding.EncodeToString(sum))
+		w.Header().Set(ChecksumCRC32CTrailer, base64.StdEncoding.EncodeToString(ir.crc.Sum(nil)))
+	}
+}
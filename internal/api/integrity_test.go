@@ -0,0 +1,156 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func newIntegrityTestServer(t *testing.T, server config.ServerConfig) (*httptest.Server, *testsupport.MemoryClient) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	backend := testsupport.NewMemoryClient()
+	engine, _ := crypto.NewEngineWithOpts([]byte("test-password-123456"), nil, crypto.WithChunking(true))
+	cfg := &config.Config{Server: server}
+	router := mux.NewRouter()
+	NewHandlerWithFeatures(backend, engine, logger, getTestMetrics(), nil, nil, nil, cfg, nil).RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv, backend
+}
+
+func putObject(t *testing.T, srv *httptest.Server, path string, body []byte) {
+	t.Helper()
+	req, _ := http.NewRequest("PUT", srv.URL+path, bytes.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT %s = %d", path, resp.StatusCode)
+	}
+}
+
+func TestGetObject_IntegrityTrailer(t *testing.T) {
+	srv, _ := newIntegrityTestServer(t, config.ServerConfig{IntegrityTrailer: true})
+	plaintext := bytes.Repeat([]byte("integrity"), 20000)
+	putObject(t, srv, "/bucket/obj", plaintext)
+
+	resp, err := http.Get(srv.URL + "/bucket/obj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	got, err := io.ReadAll(resp.Body)
+	if err != nil || !bytes.Equal(got, plaintext) {
+		t.Fatalf("body: %d bytes, err %v", len(got), err)
+	}
+	sum := sha256.Sum256(plaintext)
+	want := fmt.Sprintf("verified; sha256=%s; length=%d", base64.StdEncoding.EncodeToString(sum[:]), len(plaintext))
+	if v := resp.Trailer.Get(IntegrityTrailer); v != want {
+		t.Errorf("trailer = %q, want %q", v, want)
+	}
+}
+
+func TestGetObject_IntegrityTrailerDisabled(t *testing.T) {
+	srv, _ := newIntegrityTestServer(t, config.ServerConfig{})
+	putObject(t, srv, "/bucket/obj", []byte("plain"))
+	resp, err := http.Get(srv.URL + "/bucket/obj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.Trailer.Get(IntegrityTrailer) != "" || resp.Header.Get(IntegrityTrailer) != "" {
+		t.Error("integrity trailer sent while disabled")
+	}
+}
+
+func TestGetObject_IntegrityTrailerAbortsOnTamper(t *testing.T) {
+	srv, backend := newIntegrityTestServer(t, config.ServerConfig{IntegrityTrailer: true})
+	putObject(t, srv, "/bucket/obj", bytes.Repeat([]byte("x"), 3*crypto.DefaultChunkSize))
+
+	data, meta, _ := backend.Object("bucket", "obj")
+	data[len(data)-1] ^= 0xff
+	if err := backend.PutObject(context.Background(), "bucket", "obj", bytes.NewReader(data), meta, nil, "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get(srv.URL + "/bucket/obj")
+	if err != nil {
+		return // aborted before the status line: also acceptable
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); err == nil {
+		t.Error("a tampered stream must not end like a complete response")
+	}
+	if resp.Trailer.Get(IntegrityTrailer) != "" {
+		t.Error("tampered object reported as verified")
+	}
+}
+
+func TestGetObject_ChecksumTrailers(t *testing.T) {
+	srv, _ := newIntegrityTestServer(t, config.ServerConfig{ChecksumTrailers: true})
+	plaintext := bytes.Repeat([]byte("checksum"), 20000)
+	putObject(t, srv, "/bucket/obj", plaintext)
+
+	sum := sha256.Sum256(plaintext)
+	crc := crc32.Checksum(plaintext, crc32.MakeTable(crc32.Castagnoli))
+	wantSHA := base64.StdEncoding.EncodeToString(sum[:])
+	wantCRC := base64.StdEncoding.EncodeToString([]byte{byte(crc >> 24), byte(crc >> 16), byte(crc >> 8), byte(crc)})
+
+	for _, tc := range []struct {
+		name   string
+		header string
+		value  string
+		want   bool
+	}{
+		{"te trailers", "TE", "trailers", true},
+		{"checksum mode", "x-amz-checksum-mode", "ENABLED", true},
+		{"not requested", "", "", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", srv.URL+"/bucket/obj", nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			got, err := io.ReadAll(resp.Body)
+			if err != nil || !bytes.Equal(got, plaintext) {
+				t.Fatalf("body: %d bytes, err %v", len(got), err)
+			}
+			if !tc.want {
+				if resp.Trailer.Get(ChecksumSHA256Trailer) != "" || resp.ContentLength != int64(len(plaintext)) {
+					t.Error("checksum trailers sent to a client that did not ask for them")
+				}
+				return
+			}
+			if v := resp.Trailer.Get(ChecksumSHA256Trailer); v != wantSHA {
+				t.Errorf("sha256 trailer = %q, want %q", v, wantSHA)
+			}
+			if v := resp.Trailer.Get(ChecksumCRC32CTrailer); v != wantCRC {
+				t.Errorf("crc32c trailer = %q, want %q", v, wantCRC)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/journal"
+	"github.com/sirupsen/logrus"
+)
+
+func newJournalTestHandler(t *testing.T) (*Handler, *mockS3Client, *journal.Journal, string) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	mockClient := newMockS3Client()
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	h := NewHandler(mockClient, engine, logger, getTestMetrics())
+
+	path := filepath.Join(t.TempDir(), "journal.log")
+	j, err := journal.Open(path, true)
+	if err != nil {
+		t.Fatalf("journal.Open: %v", err)
+	}
+	h.WithJournal(j)
+	return h, mockClient, j, path
+}
+
+func TestPutObject_JournalRecordsCompletion(t *testing.T) {
+	h, mockClient, j, path := newJournalTestHandler(t)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	mockClient.errors["b/fails/put"] = &mockAPIError{code: "AccessDenied", message: "denied"}
+
+	for _, key := range []string{"ok", "fails"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("PUT", "/b/"+key, bytes.NewReader([]byte("data"))))
+	}
+	if st := j.Status(); st.InFlight != 0 {
+		t.Errorf("expected no in-flight writes, got %d", st.InFlight)
+	}
+	j.Close()
+
+	reopened, err := journal.Open(path, true)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	if p := reopened.Pending(); len(p) != 0 {
+		t.Errorf("completed writes must not be pending after restart, got %+v", p)
+	}
+}
+
+func TestPutObject_JournalFailureRejectsWrite(t *testing.T) {
+	h, mockClient, j, _ := newJournalTestHandler(t)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	j.Close() // every Begin now fails
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("PUT", "/b/k", bytes.NewReader([]byte("data"))))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 when the journal is unwritable, got %d", w.Code)
+	}
+	if _, ok := mockClient.objects["b/k"]; ok {
+		t.Error("object must not reach the backend without a journal record")
+	}
+}
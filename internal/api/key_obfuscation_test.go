@@ -0,0 +1,69 @@
+package api
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/sirupsen/logrus"
+)
+
+func TestKeyObfuscation_PutGetList(t *testing.T) {
+	for _, scheme := range []string{crypto.KeyObfuscationSegment, crypto.KeyObfuscationPath, crypto.KeyObfuscationFull} {
+		t.Run(scheme, func(t *testing.T) {
+			testKeyObfuscationPutGetList(t, scheme)
+		})
+	}
+}
+
+func testKeyObfuscationPutGetList(t *testing.T, scheme string) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	mockClient := newMockS3Client()
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	obf, err := crypto.NewKeyObfuscator([]byte("test-password-123456"), 1000, scheme)
+	if err != nil {
+		t.Fatalf("NewKeyObfuscator: %v", err)
+	}
+	h := NewHandler(mockClient, engine, logger, getTestMetrics())
+	h.WithKeyCodec(obf)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("PUT", "/b/reports/q3-payroll.txt", bytes.NewReader([]byte("numbers"))))
+	if w.Code != 200 {
+		t.Fatalf("PUT status = %d: %s", w.Code, w.Body.String())
+	}
+	for key := range mockClient.objects {
+		if strings.Contains(key, "reports") || strings.Contains(key, "payroll") {
+			t.Errorf("backend key %q holds the real name", key)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/b/reports/q3-payroll.txt", nil))
+	if w.Body.String() != "numbers" {
+		t.Errorf("GET body = %q", w.Body.String())
+	}
+
+	for query, want := range map[string]string{
+		"?list-type=2&prefix=reports/":        "<Key>reports/q3-payroll.txt</Key>",
+		"?list-type=2&prefix=rep&delimiter=/": "<Prefix>reports/</Prefix>",
+	} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/b"+query, nil))
+		if body := w.Body.String(); !strings.Contains(body, want) {
+			t.Errorf("LIST %s: want %s in %s", query, want, body)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/b?list-type=2&prefix=reports/q4", nil))
+	if body := w.Body.String(); strings.Contains(body, "<Key>") {
+		t.Errorf("LIST with a non-matching partial prefix returned keys: %s", body)
+	}
+}
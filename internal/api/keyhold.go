@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/keyhold"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// ListKeyHolds returns every key hold.
+func (h *Handler) ListKeyHolds(ctx context.Context) (keyhold.Holds, error) {
+	return h.keyHolds.List(ctx)
+}
+
+// PlaceKeyHold holds the data key of bucket/key, or of every object under
+// bucket/prefix when key is empty.
+func (h *Handler) PlaceKeyHold(ctx context.Context, bucket, key, prefix, reason string) (keyhold.Hold, error) {
+	return h.keyHolds.Place(ctx, bucket, key, prefix, reason)
+}
+
+// ReleaseKeyHold removes a hold. It reports false when there was none.
+func (h *Handler) ReleaseKeyHold(ctx context.Context, bucket, key, prefix string) (bool, error) {
+	return h.keyHolds.Release(ctx, bucket, key, prefix)
+}
+
+// KeyHoldInventory lists the objects every hold currently covers and the
+// wrapping key version each one's data key depends on. Objects that cannot
+// be inspected are counted as unresolved rather than failing the report, so
+// one unreadable object does not hide the rest.
+func (h *Handler) KeyHoldInventory(ctx context.Context) (keyhold.Inventory, error) {
+	inv := keyhold.Inventory{Holds: []keyhold.HoldReport{}, KeyVersions: map[int]int{}}
+	holds, err := h.keyHolds.List(ctx)
+	if err != nil {
+		return inv, err
+	}
+	for _, hold := range holds {
+		inv.Holds = append(inv.Holds, keyhold.HoldReport{Hold: hold, Objects: []keyhold.HeldObject{}})
+		if hold.Key != "" {
+			if o, ok := h.heldObject(ctx, hold.Bucket, hold.Key); ok {
+				inv.Add(o)
+			}
+			continue
+		}
+		opts := s3.ListOptions{MaxKeys: 1000}
+		for {
+			page, err := h.s3Client.ListObjects(ctx, hold.Bucket, hold.Prefix, opts)
+			if err != nil {
+				return inv, fmt.Errorf("keyhold inventory: list %s/%s: %w", hold.Bucket, hold.Prefix, err)
+			}
+			for _, obj := range page.Objects {
+				if strings.HasSuffix(obj.Key, ".mpu-manifest") || h.isReservedKey(hold.Bucket, obj.Key) {
+					continue
+				}
+				if o, ok := h.heldObject(ctx, hold.Bucket, obj.Key); ok {
+					inv.Add(o)
+				}
+			}
+			if !page.IsTruncated || page.NextContinuationToken == "" {
+				break
+			}
+			opts.ContinuationToken = page.NextContinuationToken
+		}
+	}
+	return inv, nil
+}
+
+// heldObject inspects one held object. It reports false when the object
+// does not exist.
+func (h *Handler) heldObject(ctx context.Context, bucket, key string) (keyhold.HeldObject, bool) {
+	o := keyhold.HeldObject{Key: key}
+	meta, err := h.s3Client.HeadObject(ctx, bucket, key, nil)
+	if err != nil {
+		if isS3NotFoundError(err) {
+			return o, false
+		}
+		o.Error = err.Error()
+		return o, true
+	}
+	if crypto.IsShredded(meta) {
+		o.Shredded = true
+		return o, true
+	}
+	ref, wrapped, err := h.objectKeyRef(ctx, bucket, key, meta)
+	switch {
+	case err != nil:
+		o.Error = err.Error()
+	case wrapped:
+		o.WrappingKey = &ref
+	}
+	return o, true
+}
+
+// objectKeyRef returns the wrapping key of bucket/key given its metadata.
+// Multipart objects keep it in their manifest companion and version 2
+// fallback objects in a prefix of their body; both are fetched.
+func (h *Handler) objectKeyRef(ctx context.Context, bucket, key string, meta map[string]string) (crypto.KeyRef, bool, error) {
+	if meta[crypto.MetaMPUEncrypted] == "true" {
+		manifest, err := h.loadMPUManifest(ctx, bucket, key, meta, h.s3Client)
+		if err != nil {
+			return crypto.KeyRef{}, false, err
+		}
+		ref := crypto.KeyRef{Provider: manifest.KMSProvider, KeyID: manifest.KMSKeyID, Version: manifest.KMSKeyVersion}
+		return ref, manifest.WrappedDEK != "", nil
+	}
+	if meta[crypto.MetaFallbackMode] != "true" {
+		return crypto.ObjectKeyRef(meta, nil)
+	}
+	body, full, err := h.s3Client.GetObject(ctx, bucket, key, nil, nil)
+	if err != nil {
+		return crypto.KeyRef{}, false, fmt.Errorf("get %s/%s: %w", bucket, key, err)
+	}
+	defer body.Close()
+	return crypto.ObjectKeyRef(full, body)
+}
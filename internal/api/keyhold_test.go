@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/keyhold"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func newKeyHoldTestServer(t *testing.T) (*Handler, crypto.KeyManager, *httptest.Server) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	km, err := crypto.NewInMemoryKeyManager(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, _ := crypto.NewEngineWithOpts([]byte("test-password-123456"), nil, crypto.WithChunking(true), crypto.WithKeyManager(km))
+	client := testsupport.NewMemoryClient()
+	h := NewHandlerWithFeatures(client, engine, logger, getTestMetrics(), nil, nil, nil, &config.Config{}, nil)
+	h.WithKeyHolds(keyhold.New(client, config.KeyHoldConfig{Enabled: true, Bucket: "bucket", Key: ".s3eg-keyholds.json"}))
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return h, km, srv
+}
+
+// rotateTo stages version and makes it the active wrapping key.
+func rotateTo(t *testing.T, km crypto.KeyManager, version int) {
+	t.Helper()
+	ctx := context.Background()
+	material := make([]byte, 32)
+	material[0] = byte(version)
+	if err := km.(interface {
+		AddVersion(context.Context, int, []byte) error
+	}).AddVersion(ctx, version, material); err != nil {
+		t.Fatal(err)
+	}
+	rkm := km.(crypto.RotatableKeyManager)
+	plan, err := rkm.PrepareRotation(ctx, &version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rkm.PromoteActiveVersion(ctx, plan); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestShredObjects_SkipsHeldObjects(t *testing.T) {
+	h, _, srv := newKeyHoldTestServer(t)
+	ctx := context.Background()
+	putObject(t, srv, "/bucket/case/a", []byte("evidence"))
+	putObject(t, srv, "/bucket/other/b", []byte("not held"))
+	if _, err := h.PlaceKeyHold(ctx, "bucket", "", "case/", "litigation"); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := h.ShredObjects(ctx, "bucket", "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Held) != 1 || res.Held[0] != "case/a" || len(res.Shredded) != 1 || res.Shredded[0] != "other/b" {
+		t.Fatalf("result = %+v", res)
+	}
+	if status, body := getStatus(t, srv, "/bucket/case/a", ""); status != http.StatusOK || body != "evidence" {
+		t.Errorf("held object after shred = %d %q", status, body)
+	}
+	if status, body := getStatus(t, srv, "/bucket?list-type=2", ""); !strings.Contains(body, "other/b") || strings.Contains(body, ".s3eg-keyholds.json") {
+		t.Errorf("hold list visible in listing (%d): %s", status, body)
+	}
+}
+
+func TestShredObjects_RefusedWhenHoldListIsDeleted(t *testing.T) {
+	h, _, srv := newKeyHoldTestServer(t)
+	ctx := context.Background()
+	putObject(t, srv, "/bucket/case/a", []byte("evidence"))
+	if _, err := h.PlaceKeyHold(ctx, "bucket", "case/a", "", "litigation"); err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, srv.URL+"/bucket/.s3eg-keyholds.json", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("DELETE of the hold list = %d, want 403", resp.StatusCode)
+	}
+	if holds, err := h.ListKeyHolds(ctx); err != nil || len(holds) != 1 {
+		t.Fatalf("holds after refused DELETE = %v, %v", holds, err)
+	}
+
+	// Removed behind the gateway's back, the list must not read as empty.
+	if err := h.s3Client.DeleteObject(ctx, "bucket", ".s3eg-keyholds.json", nil); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := h.ShredObjects(ctx, "bucket", "case/a", "", false); !errors.Is(err, keyhold.ErrListMissing) {
+		t.Fatalf("shred with the hold list gone = %+v, %v", res, err)
+	}
+	if status, body := getStatus(t, srv, "/bucket/case/a", ""); status != http.StatusOK || body != "evidence" {
+		t.Errorf("held object after refused shred = %d %q", status, body)
+	}
+}
+
+func TestKeyHoldInventory_ReportsWrappingVersions(t *testing.T) {
+	h, km, srv := newKeyHoldTestServer(t)
+	ctx := context.Background()
+	putObject(t, srv, "/bucket/case/v1", []byte("written under v1"))
+	rotateTo(t, km, 2)
+	putObject(t, srv, "/bucket/case/v2", []byte("written under v2"))
+	if _, err := h.PlaceKeyHold(ctx, "bucket", "", "case/", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.PlaceKeyHold(ctx, "bucket", "gone", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	inv, err := h.KeyHoldInventory(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inv.Holds) != 2 || inv.Unresolved != 0 {
+		t.Fatalf("inventory = %+v", inv)
+	}
+	if inv.KeyVersions[1] != 1 || inv.KeyVersions[2] != 1 {
+		t.Errorf("key versions = %v", inv.KeyVersions)
+	}
+	for _, r := range inv.Holds {
+		if r.Key == "gone" && len(r.Objects) != 0 {
+			t.Errorf("missing object reported: %+v", r.Objects)
+		}
+		for _, o := range r.Objects {
+			if o.WrappingKey == nil || o.WrappingKey.Provider != "memory" {
+				t.Errorf("%s: wrapping key = %+v", o.Key, o.WrappingKey)
+			}
+		}
+	}
+}
+
+func TestAdminRetire_RefusesHeldVersion(t *testing.T) {
+	h, km, srv := newKeyHoldTestServer(t)
+	ctx := context.Background()
+	putObject(t, srv, "/bucket/case/a", []byte("evidence"))
+	rotateTo(t, km, 2)
+	if _, err := h.PlaceKeyHold(ctx, "bucket", "case/a", "", "litigation"); err != nil {
+		t.Fatal(err)
+	}
+
+	rh := NewAdminRotationHandler(h.encryptionEngine, testRotationLogger(), testMetrics(), nil)
+	rh.WithKeyHolds(h)
+	adminMux := http.NewServeMux()
+	rh.RegisterRoutes(adminMux)
+	retire := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		adminMux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/kms/retire", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := retire(`{"version":1}`); rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "KeyVersionHeld") {
+		t.Fatalf("retire held version = %d %s", rec.Code, rec.Body)
+	}
+	if rec := retire(`{"version":2}`); rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "ActiveKeyVersion") {
+		t.Errorf("retire active version = %d %s", rec.Code, rec.Body)
+	}
+
+	if _, err := h.ReleaseKeyHold(ctx, "bucket", "case/a", ""); err != nil {
+		t.Fatal(err)
+	}
+	if rec := retire(`{"version":1}`); rec.Code != http.StatusOK {
+		t.Fatalf("retire after release = %d %s", rec.Code, rec.Body)
+	}
+	if rec := retire(`{"version":1}`); rec.Code != http.StatusNotFound {
+		t.Errorf("retire twice = %d %s", rec.Code, rec.Body)
+	}
+	resp, err := http.Get(srv.URL + "/bucket/case/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		t.Error("object wrapped under a retired version is still readable")
+	}
+}
+
+func TestAdminRetire_Unsupported(t *testing.T) {
+	eng, err := crypto.NewEngine([]byte("test-password1234"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewAdminRotationHandler(eng, testRotationLogger(), testMetrics(), nil)
+	rec := httptest.NewRecorder()
+	h.handleRetire(rec, httptest.NewRequest(http.MethodPost, "/admin/kms/retire", strings.NewReader(`{"version":1}`)))
+	if rec.Code != http.StatusNotImplemented {
+		t.Fatalf("expected 501, got %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/kenneth/s3-encryption-gateway/internal/upgrade"
+)
+
+// WithUpgrader attaches the lazy upgrader. Legacy objects served in full by
+// GET are then queued to be rewritten in chunked format.
+func (h *Handler) WithUpgrader(u *upgrade.Upgrader) {
+	h.upgrader = u
+}
+
+// noteLegacyRead offers bucket/key to the upgrader after a GET served the
+// whole current version. metadata is the backend metadata of what was read.
+func (h *Handler) noteLegacyRead(engine crypto.EncryptionEngine, bucket, key string, versionID *string, metadata map[string]string) {
+	if h.upgrader == nil || h.s3Client == nil || versionID != nil || !upgradable(engine, metadata) {
+		return
+	}
+	size, _ := strconv.ParseInt(metadata["Content-Length"], 10, 64)
+	h.upgrader.Notify(upgrade.Candidate{Bucket: bucket, Key: key, ETag: metadata["ETag"], Size: size})
+}
+
+// upgradable reports whether an object with the given backend metadata is in
+// the legacy single-IV format and can safely be rewritten in place.
+// Versioned and Object Lock protected objects are left alone: a rewrite
+// would add a version or be refused.
+func upgradable(engine crypto.EncryptionEngine, metadata map[string]string) bool {
+	if !engine.IsEncrypted(metadata) || crypto.IsChunkedFormat(metadata) || crypto.IsShredded(metadata) || crypto.IsEmptyObject(metadata) {
+		return false
+	}
+	if metadata[crypto.MetaMPUEncrypted] == "true" || metadata["ETag"] == "" {
+		return false
+	}
+	if v := metadata["x-amz-version-id"]; v != "" && v != "null" {
+		return false
+	}
+	return metadata["x-amz-object-lock-mode"] == "" && metadata["x-amz-object-lock-legal-hold"] != "ON"
+}
+
+// UpgradeObject rewrites bucket/key in chunked format, provided it still has
+// the given backend ETag and is still in the legacy format. The plaintext,
+// user metadata, Content-Type and client-visible ETag and Last-Modified are
+// kept; tags, ACLs and storage class are not, as with any rewrite through
+// the gateway. It reports whether the object was rewritten.
+func (h *Handler) UpgradeObject(ctx context.Context, bucket, key, etag string) (bool, error) {
+	engine, err := h.getEncryptionEngine(bucket)
+	if err != nil {
+		return false, fmt.Errorf("load encryption engine: %w", err)
+	}
+	// A bucket policy may still write the legacy format.
+	if !crypto.WritesChunked(engine) {
+		return false, nil
+	}
+
+	unlock := h.keyLocks.Lock(bucket + "/" + key)
+	defer unlock()
+
+	body, meta, err := h.s3Client.GetObject(ctx, bucket, key, nil, nil)
+	if errors.Is(err, s3.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("get %s/%s: %w", bucket, key, err)
+	}
+	defer body.Close()
+	if meta["ETag"] != etag || !upgradable(engine, meta) {
+		return false, nil
+	}
+	meta = h.unsealMetadata(bucket, key, meta)
+	restoreLastModified(meta)
+
+	plaintext, plainMeta, err := engine.Decrypt(ctx, body, meta)
+	if err != nil {
+		h.noteFormatSkew(err, bucket, key)
+		return false, fmt.Errorf("decrypt %s/%s: %w", bucket, key, err)
+	}
+	data, err := io.ReadAll(plaintext)
+	if err != nil {
+		return false, fmt.Errorf("decrypt %s/%s: %w", bucket, key, err)
+	}
+
+	userMeta := exportableMetadata(meta)
+	// The content is unchanged, so clients keep the ETag they have seen.
+	if e := plainMeta["ETag"]; e != "" {
+		userMeta["ETag"] = e
+	}
+	if lm := meta["Last-Modified"]; lm != "" {
+		userMeta[metaOriginalLastModified] = lm
+	}
+	// Only replace the version that was read, so a concurrent overwrite
+	// through another gateway is not clobbered with stale plaintext.
+	unchanged := s3.WithWriteConditions(ctx, s3.WriteConditions{IfMatch: etag})
+	err = h.storePlaintext(unchanged, "LazyUpgrade", bucket, key, bytes.NewReader(data), int64(len(data)), meta["Content-Type"], userMeta)
+	if err != nil && TranslateError(err, bucket, key).Code == "PreconditionFailed" {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/upgrade"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func newUpgradeTestServer(t *testing.T, client *testsupport.MemoryClient, chunked bool) (*Handler, *httptest.Server) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, err := crypto.NewEngineWithOpts([]byte("test-password-123456"), nil, crypto.WithChunking(chunked))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandlerWithFeatures(client, engine, logger, getTestMetrics(), nil, nil, nil, &config.Config{}, nil)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return h, srv
+}
+
+func getResponse(t *testing.T, srv *httptest.Server, path, rng string) (*http.Response, []byte) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", srv.URL+path, nil)
+	if rng != "" {
+		req.Header.Set("Range", rng)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, body
+}
+
+func TestLazyUpgrade_RewritesLegacyObjectOnFullRead(t *testing.T) {
+	client := testsupport.NewMemoryClient()
+	_, legacy := newUpgradeTestServer(t, client, false)
+	payload := bytes.Repeat([]byte("legacy object "), 1000)
+	req, _ := http.NewRequest("PUT", legacy.URL+"/bucket/old", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("x-amz-meta-owner", "alice")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	putObject(t, legacy, "/bucket/ranged", payload)
+
+	h, srv := newUpgradeTestServer(t, client, true)
+	u := upgrade.New(config.LazyUpgradeConfig{Enabled: true, Rate: 1000, MaxObjectSize: 1 << 20, QueueSize: 10}, h, h.logger)
+	u.Start()
+	defer u.Stop(context.Background())
+	h.WithUpgrader(u)
+
+	before, _ := getResponse(t, srv, "/bucket/old", "")
+	getResponse(t, srv, "/bucket/ranged", "bytes=0-9")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, meta, _ := client.Object("bucket", "old")
+		if crypto.IsChunkedFormat(meta) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("object was not upgraded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	after, body := getResponse(t, srv, "/bucket/old", "")
+	if !bytes.Equal(body, payload) {
+		t.Fatalf("upgraded object body differs (%d bytes)", len(body))
+	}
+	for _, hdr := range []string{"ETag", "Last-Modified", "Content-Type", "x-amz-meta-owner"} {
+		if after.Header.Get(hdr) != before.Header.Get(hdr) || after.Header.Get(hdr) == "" {
+			t.Errorf("%s = %q after upgrade, was %q", hdr, after.Header.Get(hdr), before.Header.Get(hdr))
+		}
+	}
+	// The rewrite may land within the same second; check the record too.
+	if _, meta, _ := client.Object("bucket", "old"); meta[metaOriginalLastModified] != before.Header.Get("Last-Modified") {
+		t.Errorf("upgraded object records Last-Modified %q, want %q", meta[metaOriginalLastModified], before.Header.Get("Last-Modified"))
+	}
+	if after.Header.Get(metaOriginalLastModified) != "" {
+		t.Error("the recorded Last-Modified leaked into the response as user metadata")
+	}
+	if _, meta, _ := client.Object("bucket", "ranged"); crypto.IsChunkedFormat(meta) {
+		t.Error("a range read must not queue an upgrade")
+	}
+}
+
+func TestUpgradeObject_SkipsChangedObject(t *testing.T) {
+	client := testsupport.NewMemoryClient()
+	_, legacy := newUpgradeTestServer(t, client, false)
+	etag := func() string {
+		meta, err := client.HeadObject(context.Background(), "bucket", "k", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return meta["ETag"]
+	}
+	putObject(t, legacy, "/bucket/k", []byte("v1"))
+	stale := etag()
+	putObject(t, legacy, "/bucket/k", []byte("v2"))
+
+	h, _ := newUpgradeTestServer(t, client, true)
+	done, err := h.UpgradeObject(context.Background(), "bucket", "k", stale)
+	if err != nil || done {
+		t.Fatalf("UpgradeObject with a stale ETag = %v, %v", done, err)
+	}
+	if done, err := h.UpgradeObject(context.Background(), "bucket", "missing", `"x"`); err != nil || done {
+		t.Fatalf("UpgradeObject on a missing key = %v, %v", done, err)
+	}
+
+	if done, err := h.UpgradeObject(context.Background(), "bucket", "k", etag()); err != nil || !done {
+		t.Fatalf("UpgradeObject = %v, %v", done, err)
+	}
+	// Already chunked: nothing to do.
+	if done, err := h.UpgradeObject(context.Background(), "bucket", "k", etag()); err != nil || done {
+		t.Fatalf("second UpgradeObject = %v, %v", done, err)
+	}
+}
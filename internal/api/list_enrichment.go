@@ -0,0 +1,196 @@
+package api
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/kenneth/s3-encryption-gateway/internal/sizeindex"
+)
+
+// listSizeResolver rewrites the ciphertext sizes and ETags reported by a
+// backend listing into the plaintext values clients expect. Each key costs a
+// HEAD request unless its result is cached or found in the sidecar size
+// index; the cache is keyed on the backend ETag of the listed object, so an
+// overwrite is always re-resolved.
+type listSizeResolver struct {
+	concurrency int
+	ttl         time.Duration
+	maxEntries  int
+	index       *sizeindex.Index
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type listSizeEntry struct {
+	cacheKey    string
+	backendETag string
+	encrypted   bool
+	size        int64
+	etag        string
+	expiresAt   time.Time
+}
+
+// newListSizeResolver returns nil when enrichment is disabled.
+func newListSizeResolver(cfg config.ListEnrichmentConfig) *listSizeResolver {
+	if !cfg.Enabled {
+		return nil
+	}
+	concurrency := cfg.Concurrency
+	if concurrency < 1 {
+		concurrency = config.DefaultListEnrichmentConcurrency
+	}
+	return &listSizeResolver{
+		concurrency: concurrency,
+		ttl:         cfg.CacheTTL,
+		maxEntries:  cfg.CacheSize,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+	}
+}
+
+// listEnrichmentConfig returns the handler's list enrichment settings,
+// falling back to the defaults when the handler was built without a config.
+func listEnrichmentConfig(cfg *config.Config) config.ListEnrichmentConfig {
+	if cfg == nil {
+		return config.ListEnrichmentConfig{
+			Enabled:     true,
+			Concurrency: config.DefaultListEnrichmentConcurrency,
+			CacheSize:   config.DefaultListEnrichmentCacheSize,
+			CacheTTL:    config.DefaultListEnrichmentCacheTTL,
+		}
+	}
+	return cfg.ListEnrichment
+}
+
+// Resolve rewrites objects in place. Keys whose HEAD fails keep the values
+// from the backend listing.
+func (r *listSizeResolver) Resolve(ctx context.Context, client s3.Client, engine crypto.EncryptionEngine, bucket string, objects []s3.ObjectInfo) {
+	if r == nil || len(objects) == 0 {
+		return
+	}
+
+	sem := make(chan struct{}, r.concurrency)
+	var wg sync.WaitGroup
+	for i := range objects {
+		obj := &objects[i]
+		if e, ok := r.lookup(bucket, obj.Key, obj.ETag); ok {
+			e.apply(obj)
+			continue
+		}
+		if ie, ok := r.index.Lookup(ctx, bucket, obj.Key); ok && ie.Matches(obj.ETag, obj.LastModified) {
+			e := listSizeEntry{backendETag: obj.ETag, encrypted: true, size: ie.Size, etag: ie.ETag}
+			e.apply(obj)
+			r.store(bucket, obj.Key, e)
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			meta, err := client.HeadObject(ctx, bucket, obj.Key, nil)
+			if err != nil {
+				return
+			}
+			e := plaintextListEntry(engine, meta)
+			e.backendETag = obj.ETag
+			e.apply(obj)
+			r.store(bucket, obj.Key, e)
+			if ie, ok := sizeindex.EntryFromMetadata(meta); ok && e.encrypted {
+				ie.BackendETag = obj.ETag
+				r.index.Record(bucket, obj.Key, ie)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// plaintextListEntry derives the listing values of an object from its HEAD
+// metadata.
+func plaintextListEntry(engine crypto.EncryptionEngine, meta map[string]string) listSizeEntry {
+	e := listSizeEntry{size: -1}
+	if !engine.IsEncrypted(meta) {
+		return e
+	}
+	e.encrypted = true
+	for _, k := range []string{crypto.MetaOriginalSize, "x-amz-meta-original-content-length"} {
+		if v, ok := meta[k]; ok {
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				e.size = n
+				break
+			}
+		}
+	}
+	e.etag = meta[crypto.MetaOriginalETag]
+	return e
+}
+
+// apply copies the resolved plaintext values onto obj.
+func (e listSizeEntry) apply(obj *s3.ObjectInfo) {
+	if !e.encrypted {
+		return
+	}
+	if e.size >= 0 {
+		obj.Size = e.size
+	}
+	if e.etag != "" {
+		obj.ETag = e.etag
+	}
+}
+
+func (r *listSizeResolver) lookup(bucket, key, backendETag string) (listSizeEntry, bool) {
+	if r.maxEntries <= 0 || backendETag == "" {
+		return listSizeEntry{}, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	el, ok := r.entries[bucket+"/"+key]
+	if !ok {
+		return listSizeEntry{}, false
+	}
+	e := el.Value.(listSizeEntry)
+	if e.backendETag != backendETag || time.Now().After(e.expiresAt) {
+		r.lru.Remove(el)
+		delete(r.entries, e.cacheKey)
+		return listSizeEntry{}, false
+	}
+	r.lru.MoveToFront(el)
+	return e, true
+}
+
+func (r *listSizeResolver) store(bucket, key string, e listSizeEntry) {
+	if r.maxEntries <= 0 || e.backendETag == "" {
+		return
+	}
+	e.cacheKey = bucket + "/" + key
+	e.expiresAt = time.Now().Add(r.ttl)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if el, ok := r.entries[e.cacheKey]; ok {
+		el.Value = e
+		r.lru.MoveToFront(el)
+		return
+	}
+	r.entries[e.cacheKey] = r.lru.PushFront(e)
+	for r.lru.Len() > r.maxEntries {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.entries, oldest.Value.(listSizeEntry).cacheKey)
+	}
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/kenneth/s3-encryption-gateway/internal/sizeindex"
+	"github.com/sirupsen/logrus"
+)
+
+// listingMockClient lists fixed objects with ciphertext sizes and counts HEADs.
+type listingMockClient struct {
+	*mockS3Client
+	listed []s3.ObjectInfo
+	heads  atomic.Int32
+}
+
+func (c *listingMockClient) ListObjects(ctx context.Context, bucket, prefix string, opts s3.ListOptions) (s3.ListResult, error) {
+	return s3.ListResult{Objects: append([]s3.ObjectInfo(nil), c.listed...)}, nil
+}
+
+func (c *listingMockClient) HeadObject(ctx context.Context, bucket, key string, versionID *string) (map[string]string, error) {
+	c.heads.Add(1)
+	return c.mockS3Client.HeadObject(ctx, bucket, key, versionID)
+}
+
+func newListingTestRouter(t *testing.T, le config.ListEnrichmentConfig) (*mux.Router, *listingMockClient) {
+	router, client, _ := newListingTestHandler(t, le)
+	return router, client
+}
+
+func newListingTestHandler(t *testing.T, le config.ListEnrichmentConfig) (*mux.Router, *listingMockClient, *Handler) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	client := &listingMockClient{mockS3Client: newMockS3Client()}
+	client.listed = []s3.ObjectInfo{
+		{Key: "enc", Size: 1044, ETag: `"cipher-1"`},
+		{Key: "plain", Size: 5, ETag: `"plain-1"`},
+	}
+	client.metadata["b/enc"] = map[string]string{
+		crypto.MetaEncrypted:    "true",
+		crypto.MetaOriginalSize: "1000",
+		crypto.MetaOriginalETag: `"plain-etag"`,
+	}
+	client.metadata["b/plain"] = map[string]string{}
+
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	cfg := &config.Config{ListEnrichment: le}
+	h := NewHandlerWithFeatures(client, engine, logger, getTestMetrics(), nil, nil, nil, cfg, nil)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	return router, client, h
+}
+
+func listBody(router *mux.Router) string {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/b", nil))
+	return w.Body.String()
+}
+
+func TestListObjects_EnrichmentReportsPlaintextSizes(t *testing.T) {
+	router, client := newListingTestRouter(t, config.ListEnrichmentConfig{
+		Enabled: true, Concurrency: 2, CacheSize: 10, CacheTTL: config.DefaultListEnrichmentCacheTTL,
+	})
+
+	body := listBody(router)
+	for _, want := range []string{"<Size>1000</Size>", "plain-etag", "<Size>5</Size>"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("listing missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "<Size>1044</Size>") {
+		t.Error("listing reports the ciphertext size")
+	}
+
+	// A second listing is served from the cache.
+	listBody(router)
+	if got := client.heads.Load(); got != 2 {
+		t.Errorf("expected 2 HEAD requests across both listings, got %d", got)
+	}
+
+	// An overwrite changes the backend ETag and forces a fresh HEAD.
+	client.listed[0].ETag = `"cipher-2"`
+	listBody(router)
+	if got := client.heads.Load(); got != 3 {
+		t.Errorf("expected overwritten key to be re-resolved, got %d HEADs", got)
+	}
+}
+
+func TestListObjects_EnrichmentDisabled(t *testing.T) {
+	router, client := newListingTestRouter(t, config.ListEnrichmentConfig{})
+
+	body := listBody(router)
+	if !strings.Contains(body, "<Size>1044</Size>") {
+		t.Errorf("expected backend sizes when enrichment is off:\n%s", body)
+	}
+	if got := client.heads.Load(); got != 0 {
+		t.Errorf("expected no HEAD requests, got %d", got)
+	}
+}
+
+func TestListSizeResolver_EvictsLeastRecentlyUsed(t *testing.T) {
+	r := newListSizeResolver(config.ListEnrichmentConfig{Enabled: true, CacheSize: 2, CacheTTL: config.DefaultListEnrichmentCacheTTL})
+	for _, k := range []string{"a", "b", "c"} {
+		r.store("b", k, listSizeEntry{backendETag: "e", encrypted: true, size: 1})
+	}
+	if _, ok := r.lookup("b", "a", "e"); ok {
+		t.Error("oldest entry should have been evicted")
+	}
+	for _, k := range []string{"b", "c"} {
+		if _, ok := r.lookup("b", k, "e"); !ok {
+			t.Errorf("entry %q should be cached", k)
+		}
+	}
+}
+
+func TestListObjects_UsesSizeIndex(t *testing.T) {
+	router, client, h := newListingTestHandler(t, config.ListEnrichmentConfig{Enabled: true, Concurrency: 2})
+
+	ix := sizeindex.New(client, config.SizeIndexConfig{Enabled: true, Prefix: ".s3eg-index/", FlushInterval: time.Second}, nil)
+	client.errors["b/.s3eg-index/index.json/get"] = s3.NewAPIError("NoSuchKey", "The specified key does not exist.")
+	ix.Record("b", "enc", sizeindex.Entry{Size: 1000, ETag: `"indexed-etag"`, BackendETag: `"cipher-1"`})
+	if err := ix.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	delete(client.errors, "b/.s3eg-index/index.json/get")
+	h.WithSizeIndex(ix)
+	client.listed = append(client.listed, s3.ObjectInfo{Key: ".s3eg-index/index.json", Size: 100, ETag: `"idx"`})
+
+	body := listBody(router)
+	if !strings.Contains(body, "<Size>1000</Size>") || !strings.Contains(body, "indexed-etag") {
+		t.Errorf("indexed values not used:\n%s", body)
+	}
+	if strings.Contains(body, ".s3eg-index") {
+		t.Errorf("index objects must be hidden from listings:\n%s", body)
+	}
+	// Only the unindexed plaintext object needs a HEAD.
+	if got := client.heads.Load(); got != 1 {
+		t.Errorf("expected 1 HEAD request, got %d", got)
+	}
+}
+
+func TestSizeIndex_ObjectsRefusedThroughS3API(t *testing.T) {
+	router, client, h := newListingTestHandler(t, config.ListEnrichmentConfig{Enabled: true})
+	h.WithSizeIndex(sizeindex.New(client, config.SizeIndexConfig{Enabled: true, Prefix: ".s3eg-index/", FlushInterval: time.Second}, nil))
+	const indexKey = "b/.s3eg-index/index.json"
+	client.objects[indexKey] = []byte(`{}`)
+
+	requests := []*http.Request{
+		httptest.NewRequest("GET", "/"+indexKey, nil),
+		httptest.NewRequest("HEAD", "/"+indexKey, nil),
+		httptest.NewRequest("PUT", "/"+indexKey, strings.NewReader("forged")),
+		httptest.NewRequest("DELETE", "/"+indexKey, nil),
+	}
+	copyReq := httptest.NewRequest("PUT", "/b/stolen", nil)
+	copyReq.Header.Set("x-amz-copy-source", "/"+indexKey)
+	requests = append(requests, copyReq)
+
+	for _, req := range requests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s %s: expected 403, got %d", req.Method, req.URL.Path, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	body := `<Delete><Object><Key>.s3eg-index/index.json</Key></Object><Object><Key>plain</Key></Object></Delete>`
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/b?delete", strings.NewReader(body)))
+	if !strings.Contains(w.Body.String(), "<Key>.s3eg-index/index.json</Key><Code>AccessDenied</Code>") {
+		t.Errorf("expected a per-entry AccessDenied for the index object:\n%s", w.Body.String())
+	}
+
+	if string(client.objects[indexKey]) != `{}` {
+		t.Errorf("index object was modified through the S3 API: %q", client.objects[indexKey])
+	}
+	if _, ok := client.objects["b/stolen"]; ok {
+		t.Error("index object was copied through the S3 API")
+	}
+}
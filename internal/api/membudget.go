@@ -0,0 +1,29 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/membudget"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// WithMemoryBudget makes UploadPart reserve the parts it buffers from b.
+func (h *Handler) WithMemoryBudget(b *membudget.Budget) {
+	h.memBudget = b
+}
+
+// reservePartBuffer reserves the memory a part buffer of size bytes will
+// hold, or the MaxPartBuffer cap when the size is unknown or larger. The
+// returned release must be called once the buffer is dropped. A budget
+// that cannot supply the bytes yields an error translating to SlowDown.
+func (h *Handler) reservePartBuffer(ctx context.Context, size int64) (func(), error) {
+	n := effectiveMaxPartBuffer(h.config)
+	if size >= 0 && size < n {
+		n = size
+	}
+	if err := h.memBudget.Acquire(ctx, membudget.SubsystemUploadPart, n); err != nil {
+		return nil, fmt.Errorf("%w: buffer part: %v", s3.ErrThrottled, err)
+	}
+	return func() { h.memBudget.Release(membudget.SubsystemUploadPart, n) }, nil
+}
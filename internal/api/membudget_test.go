@@ -0,0 +1,49 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/membudget"
+)
+
+func TestHandleUploadPart_MemoryBudget(t *testing.T) {
+	budget := membudget.New(config.MemoryBudgetConfig{Enabled: true, Limit: 100}, nil)
+	handler, mockClient := newHandlerWithConfig(t, newConfigWithMaxPartBuffer(1000))
+	handler.WithMemoryBudget(budget)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	uploadID, err := mockClient.CreateMultipartUpload(context.Background(), "test-bucket", "obj", nil)
+	if err != nil {
+		t.Fatalf("CreateMultipartUpload: %v", err)
+	}
+	put := func(part, size int) *httptest.ResponseRecorder {
+		url := fmt.Sprintf("/test-bucket/obj?partNumber=%d&uploadId=%s", part, uploadID)
+		req := httptest.NewRequest("PUT", url, bytes.NewReader(bytes.Repeat([]byte("Z"), size)))
+		req.ContentLength = int64(size)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := put(1, 100); w.Code != http.StatusOK {
+		t.Fatalf("part within the budget: %d %s", w.Code, w.Body.String())
+	}
+	if st := budget.Stats(); st.Reserved != 0 {
+		t.Errorf("part buffer still reserved after upload: %+v", st)
+	}
+
+	// A part larger than the whole budget is refused before it is read.
+	w := put(2, 101)
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "SlowDown") {
+		t.Errorf("part beyond the budget: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+)
+
+// errMetadataTooLarge refuses a write whose metadata cannot be stored in the
+// backend's object headers.
+var errMetadataTooLarge = errors.New("object metadata exceeds the provider header limit")
+
+// checkMetadataOverflow applies backend.metadata_overflow to the metadata
+// about to be sent with a write. The engine has already moved encryption
+// metadata into the body if it would not fit; under the reject policy that
+// write is refused instead. Whatever the policy, headers still over the
+// limit, such as oversized user metadata the engine keeps in the headers
+// even in fallback mode, are refused here: some backends truncate them
+// silently and the object could then never be decrypted.
+func (h *Handler) checkMetadataOverflow(encMetadata, s3Metadata map[string]string) error {
+	if encMetadata[crypto.MetaFallbackMode] == "true" {
+		if h.config != nil && h.config.Backend.MetadataOverflow == config.MetadataOverflowReject {
+			h.metrics.RecordMetadataOverflow("rejected")
+			return errMetadataTooLarge
+		}
+		h.metrics.RecordMetadataOverflow("fallback")
+	}
+	if limit := crypto.ProviderDefault.TotalHeaderLimit; limit > 0 && crypto.EstimateMetadataSize(s3Metadata) > limit {
+		h.metrics.RecordMetadataOverflow("rejected")
+		return errMetadataTooLarge
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func TestPutObject_MetadataOverflow(t *testing.T) {
+	// 7800 bytes of user metadata fit the 8 KB header limit only once the
+	// encryption metadata moves into the body; 9000 bytes never fit.
+	tests := []struct {
+		name   string
+		policy string
+		size   int
+		want   int
+	}{
+		{"fallback by default", "", 7800, http.StatusOK},
+		{"fallback", config.MetadataOverflowFallback, 7800, http.StatusOK},
+		{"reject", config.MetadataOverflowReject, 7800, http.StatusBadRequest},
+		{"over the limit in fallback", config.MetadataOverflowFallback, 9000, http.StatusBadRequest},
+		{"small metadata under reject", config.MetadataOverflowReject, 100, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := testsupport.NewMemoryClient()
+			h, srv := newUpgradeTestServer(t, client, true)
+			h.config.Backend.MetadataOverflow = tt.policy
+
+			big := strings.Repeat("a", tt.size)
+			req, _ := http.NewRequest("PUT", srv.URL+"/bucket/key", strings.NewReader("overflow object"))
+			req.Header.Set("x-amz-meta-big", big)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Fatalf("PUT = %d, want %d: %s", resp.StatusCode, tt.want, body)
+			}
+
+			stored, err := client.HeadObject(t.Context(), "bucket", "key", nil)
+			if tt.want != http.StatusOK {
+				if !strings.Contains(string(body), "<Code>MetadataTooLarge</Code>") {
+					t.Errorf("error body = %s", body)
+				}
+				if err == nil {
+					t.Errorf("refused PUT stored the object with %d bytes of metadata", crypto.EstimateMetadataSize(stored))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (tt.size > 1000) != (stored[crypto.MetaFallbackMode] == "true") {
+				t.Errorf("fallback marker = %q for %d bytes of user metadata", stored[crypto.MetaFallbackMode], tt.size)
+			}
+			resp, data := getResponse(t, srv, "/bucket/key", "")
+			if resp.StatusCode != http.StatusOK || string(data) != "overflow object" || resp.Header.Get("x-amz-meta-big") != big {
+				t.Errorf("GET = %d, body %q", resp.StatusCode, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMetadataSealing_PutHeadGet(t *testing.T) {
+	for _, mode := range []string{crypto.MetadataSealValues, crypto.MetadataSealAll} {
+		t.Run(mode, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetLevel(logrus.FatalLevel)
+			mockClient := newMockS3Client()
+			engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+			sealer, err := crypto.NewMetadataSealer([]byte("test-password-123456"), 1000, mode)
+			if err != nil {
+				t.Fatalf("NewMetadataSealer: %v", err)
+			}
+			h := NewHandler(mockClient, engine, logger, getTestMetrics())
+			h.WithMetadataSealer(sealer)
+			router := mux.NewRouter()
+			h.RegisterRoutes(router)
+
+			req := httptest.NewRequest("PUT", "/b/k", bytes.NewReader([]byte("body")))
+			req.Header.Set("x-amz-meta-filename", "secret-plans.pdf")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != 200 {
+				t.Fatalf("PUT status = %d: %s", w.Code, w.Body.String())
+			}
+
+			for k, v := range mockClient.metadata["b/k"] {
+				if strings.Contains(v, "secret-plans") {
+					t.Errorf("backend metadata %s holds plaintext %q", k, v)
+				}
+			}
+
+			for _, method := range []string{"HEAD", "GET"} {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(method, "/b/k", nil))
+				if got := w.Header().Get("x-amz-meta-filename"); got != "secret-plans.pdf" {
+					t.Errorf("%s x-amz-meta-filename = %q", method, got)
+				}
+				if w.Header().Get(crypto.MetaSealedMetadata) != "" {
+					t.Errorf("%s exposed the sealed blob", method)
+				}
+			}
+		})
+	}
+}
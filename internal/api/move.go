@@ -0,0 +1,211 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/admin"
+	"github.com/kenneth/s3-encryption-gateway/internal/audit"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/kenneth/s3-encryption-gateway/internal/tiering"
+	"github.com/sirupsen/logrus"
+)
+
+// errMoveConflict reports a destination that already exists.
+var errMoveConflict = errors.New("destination exists")
+
+// MoveObjects renames one object, or every object under a prefix, on the
+// backend. Ciphertext and encryption metadata are copied as stored, so
+// nothing is decrypted, and the source is deleted only once the copy is in
+// place; if that delete fails the copy is removed again. An MPU object's
+// manifest companion moves with it.
+//
+// Moves are serialised with other writes to the same keys in this gateway
+// only. Objects under object lock or tiered to the secondary backend are
+// refused, and a move between buckets is refused when the two buckets do
+// not share an encryption engine.
+func (h *Handler) MoveObjects(ctx context.Context, req admin.MoveRequest) (admin.MoveResult, error) {
+	var res admin.MoveResult
+	if err := h.sameEncryption(req.Bucket, req.DestBucket); err != nil {
+		return res, fmt.Errorf("move: %w", err)
+	}
+
+	move := func(key, destKey string) {
+		err := h.moveObject(ctx, req.Bucket, key, req.DestBucket, destKey, req.Overwrite)
+		switch {
+		case errors.Is(err, errMoveConflict):
+			res.Conflicts = append(res.Conflicts, key)
+		case errors.Is(err, s3.ErrNotFound):
+			res.Skipped = append(res.Skipped, key)
+		case err != nil:
+			res.Failed = append(res.Failed, key)
+			h.logger.WithError(err).WithFields(logrus.Fields{
+				"bucket":      req.Bucket,
+				"key":         key,
+				"dest_bucket": req.DestBucket,
+				"dest_key":    destKey,
+			}).Error("Failed to move object")
+		default:
+			res.Moved = append(res.Moved, key)
+		}
+	}
+
+	if req.Key != "" {
+		move(req.Key, req.DestKey)
+		return res, nil
+	}
+	// Collect the keys first: moving while paging would shift the listing.
+	var keys []string
+	opts := s3.ListOptions{MaxKeys: 1000}
+	for {
+		page, err := h.s3Client.ListObjects(ctx, req.Bucket, req.Prefix, opts)
+		if err != nil {
+			return res, fmt.Errorf("move: list %s/%s: %w", req.Bucket, req.Prefix, err)
+		}
+		for _, obj := range page.Objects {
+			if strings.HasSuffix(obj.Key, ".mpu-manifest") || h.isReservedKey(req.Bucket, obj.Key) {
+				continue
+			}
+			keys = append(keys, obj.Key)
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		opts.ContinuationToken = page.NextContinuationToken
+	}
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+		move(key, req.DestPrefix+strings.TrimPrefix(key, req.Prefix))
+	}
+	return res, nil
+}
+
+// moveObject moves bucket/key to destBucket/destKey.
+func (h *Handler) moveObject(ctx context.Context, bucket, key, destBucket, destKey string, overwrite bool) (err error) {
+	// Lock both keys in a fixed order so two opposite moves cannot deadlock.
+	first, second := bucket+"/"+key, destBucket+"/"+destKey
+	if second < first {
+		first, second = second, first
+	}
+	unlockFirst := h.keyLocks.Lock(first)
+	defer unlockFirst()
+	unlockSecond := h.keyLocks.Lock(second)
+	defer unlockSecond()
+
+	defer func() {
+		if h.auditLogger != nil && !errors.Is(err, s3.ErrNotFound) {
+			h.auditLogger.WithContext(ctx).LogAccessWithMetadata(string(audit.EventTypeObjectMove), bucket, key, "", "", "", err == nil, err, 0,
+				map[string]interface{}{"dest_bucket": destBucket, "dest_key": destKey})
+		}
+	}()
+
+	meta, err := h.s3Client.HeadObject(ctx, bucket, key, nil)
+	if err != nil {
+		return err
+	}
+	switch {
+	case meta["x-amz-object-lock-mode"] != "":
+		return fmt.Errorf("%s/%s is under object lock", bucket, key)
+	case meta[tiering.MetaLocation] != "":
+		return fmt.Errorf("%s/%s is tiered to secondary storage", bucket, key)
+	}
+	if !overwrite {
+		if _, err := h.s3Client.HeadObject(ctx, destBucket, destKey, nil); err == nil {
+			return errMoveConflict
+		} else if !errors.Is(err, s3.ErrNotFound) {
+			return fmt.Errorf("head %s/%s: %w", destBucket, destKey, err)
+		}
+	}
+
+	manifestCopy, err := h.copyStored(ctx, h.s3Client, bucket, key, destBucket, destKey, meta, nil)
+	if err != nil {
+		return err
+	}
+	if err := h.s3Client.DeleteObject(ctx, bucket, key, nil); err != nil {
+		h.removeCopy(ctx, h.s3Client, destBucket, destKey, manifestCopy)
+		return fmt.Errorf("delete %s/%s: %w", bucket, key, err)
+	}
+	if manifestCopy != "" {
+		manifestKey := manifestKeyOf(key, meta)
+		if err := h.s3Client.DeleteObject(ctx, bucket, manifestKey, nil); err != nil && !errors.Is(err, s3.ErrNotFound) {
+			h.logger.WithError(err).WithFields(logrus.Fields{
+				"bucket": bucket,
+				"key":    manifestKey,
+			}).Warn("Failed to delete moved MPU manifest companion object")
+		}
+	}
+
+	for _, k := range [][2]string{{bucket, key}, {destBucket, destKey}} {
+		if h.cache != nil {
+			h.cache.Delete(ctx, k[0], k[1])
+		}
+		h.headMeta.invalidate(k[0], k[1])
+		h.accessStats.Remove(k[0], k[1])
+	}
+	h.sizeIndex.Remove(bucket, key)
+	h.indexWrite(destBucket, destKey, meta)
+	return nil
+}
+
+// manifestKeyOf returns the manifest companion of the MPU object key.
+func manifestKeyOf(key string, meta map[string]string) string {
+	if ptr := meta[crypto.MetaFallbackPointer]; ptr != "" {
+		return ptr
+	}
+	return key + ".mpu-manifest"
+}
+
+// copyStored copies bucket/key, whose backend metadata is meta, to
+// destBucket/destKey exactly as stored, with extra added to its metadata.
+// An MPU object's manifest companion is copied first and the copy pointed
+// at it; its key is returned so the caller can remove it again.
+func (h *Handler) copyStored(ctx context.Context, client s3.Client, bucket, key, destBucket, destKey string, meta, extra map[string]string) (string, error) {
+	stored := make(map[string]string, len(meta)+len(extra))
+	for k, v := range meta {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-meta-") {
+			stored[lk] = v
+		}
+	}
+	for k, v := range extra {
+		stored[k] = v
+	}
+
+	manifestCopy := ""
+	if stored[crypto.MetaMPUEncrypted] == "true" {
+		manifestKey := manifestKeyOf(key, stored)
+		manifestCopy = destKey + ".mpu-manifest"
+		if _, _, err := client.CopyObject(ctx, destBucket, manifestCopy, bucket, manifestKey, nil, nil, nil); err != nil {
+			return "", fmt.Errorf("copy manifest %s/%s: %w", bucket, manifestKey, err)
+		}
+		// The pointer is bound into the identity marker.
+		stored[crypto.MetaFallbackPointer] = manifestCopy
+		h.stampMarker(stored)
+	}
+
+	if _, _, err := client.CopyObject(ctx, destBucket, destKey, bucket, key, nil, stored, nil); err != nil {
+		h.removeCopy(ctx, client, destBucket, "", manifestCopy)
+		return "", fmt.Errorf("copy %s/%s to %s/%s: %w", bucket, key, destBucket, destKey, err)
+	}
+	return manifestCopy, nil
+}
+
+// removeCopy deletes an object copied by copyStored and its manifest copy,
+// best-effort. Either key may be empty.
+func (h *Handler) removeCopy(ctx context.Context, client s3.Client, bucket, key, manifestCopy string) {
+	for _, k := range []string{key, manifestCopy} {
+		if k == "" {
+			continue
+		}
+		if err := client.DeleteObject(ctx, bucket, k, nil); err != nil && !errors.Is(err, s3.ErrNotFound) {
+			h.logger.WithError(err).WithFields(logrus.Fields{
+				"bucket": bucket,
+				"key":    k,
+			}).Warn("Failed to remove copy after an incomplete move")
+		}
+	}
+}
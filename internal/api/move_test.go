@@ -0,0 +1,66 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/admin"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func TestMoveObjects(t *testing.T) {
+	client := testsupport.NewMemoryClient()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	h := NewHandlerWithFeatures(client, engine, logger, getTestMetrics(), nil, nil, nil, &config.Config{}, nil)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	putObject(t, srv, "/bucket/docs/a.txt", []byte("first"))
+	putObject(t, srv, "/bucket/docs/sub/b.txt", []byte("second"))
+	putObject(t, srv, "/bucket/archive/docs/a.txt", []byte("already there"))
+	ctx := context.Background()
+
+	res, err := h.MoveObjects(ctx, admin.MoveRequest{Bucket: "bucket", Prefix: "docs/", DestBucket: "bucket", DestPrefix: "archive/docs/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Moved) != 1 || res.Moved[0] != "docs/sub/b.txt" || len(res.Conflicts) != 1 || res.Conflicts[0] != "docs/a.txt" {
+		t.Fatalf("MoveObjects = %+v", res)
+	}
+	if _, _, ok := client.Object("bucket", "docs/sub/b.txt"); ok {
+		t.Error("moved object still at its source")
+	}
+	expect := func(path, want string) {
+		t.Helper()
+		resp, body := getResponse(t, srv, path, "")
+		if resp.StatusCode != http.StatusOK || !bytes.Equal(body, []byte(want)) {
+			t.Errorf("GET %s = %d %q, want %q", path, resp.StatusCode, body, want)
+		}
+	}
+	expect("/bucket/archive/docs/sub/b.txt", "second")
+	expect("/bucket/docs/a.txt", "first")
+	expect("/bucket/archive/docs/a.txt", "already there")
+
+	res, err = h.MoveObjects(ctx, admin.MoveRequest{Bucket: "bucket", Key: "docs/a.txt", DestBucket: "bucket", DestKey: "archive/docs/a.txt", Overwrite: true})
+	if err != nil || len(res.Moved) != 1 {
+		t.Fatalf("MoveObjects with overwrite = %+v, %v", res, err)
+	}
+	expect("/bucket/archive/docs/a.txt", "first")
+
+	res, err = h.MoveObjects(ctx, admin.MoveRequest{Bucket: "bucket", Key: "docs/missing", DestBucket: "bucket", DestKey: "elsewhere"})
+	if err != nil || len(res.Skipped) != 1 {
+		t.Errorf("MoveObjects of a missing object = %+v, %v", res, err)
+	}
+}
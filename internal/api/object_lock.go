@@ -71,7 +71,7 @@ func refuseBypassGovernanceRetention(w http.ResponseWriter, r *http.Request, h *
 	}
 	s3Err.WriteXML(w)
 	if h.auditLogger != nil {
-		h.auditLogger.LogAccessWithMetadata(
+		h.auditLogger.WithContext(r.Context()).LogAccessWithMetadata(
 			"bypass_governance_refused", bucket, key,
 			getClientIP(r), r.UserAgent(), getRequestID(r),
 			false, fmt.Errorf("bypass not authorised"), time.Since(start),
@@ -221,7 +221,7 @@ func (h *Handler) handlePutObjectRetention(w http.ResponseWriter, r *http.Reques
 	}
 
 	if h.auditLogger != nil {
-		h.auditLogger.LogAccess("put_object_retention", bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), true, nil, time.Since(start))
+		h.auditLogger.WithContext(r.Context()).LogAccess("put_object_retention", bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), true, nil, time.Since(start))
 	}
 	if h.metrics != nil {
 		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, http.StatusOK, time.Since(start), 0)
@@ -317,7 +317,7 @@ func (h *Handler) handlePutObjectLegalHold(w http.ResponseWriter, r *http.Reques
 	}
 
 	if h.auditLogger != nil {
-		h.auditLogger.LogAccess("put_object_legal_hold", bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), true, nil, time.Since(start))
+		h.auditLogger.WithContext(r.Context()).LogAccess("put_object_legal_hold", bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), true, nil, time.Since(start))
 	}
 	if h.metrics != nil {
 		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, http.StatusOK, time.Since(start), 0)
@@ -422,7 +422,7 @@ func (h *Handler) handlePutObjectLockConfiguration(w http.ResponseWriter, r *htt
 	}
 
 	if h.auditLogger != nil {
-		h.auditLogger.LogAccess("put_object_lock_configuration", bucket, "", getClientIP(r), r.UserAgent(), getRequestID(r), true, nil, time.Since(start))
+		h.auditLogger.WithContext(r.Context()).LogAccess("put_object_lock_configuration", bucket, "", getClientIP(r), r.UserAgent(), getRequestID(r), true, nil, time.Since(start))
 	}
 	if h.metrics != nil {
 		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, http.StatusOK, time.Since(start), 0)
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/sirupsen/logrus"
+)
+
+// WithObjectMarker attaches the identity marker. Writes are stamped with it
+// and reads of encrypted objects check it.
+func (h *Handler) WithObjectMarker(m *crypto.ObjectMarker) {
+	h.marker = m
+}
+
+// stampMarker adds the identity marker to metadata about to be stored.
+func (h *Handler) stampMarker(s3Metadata map[string]string) {
+	h.marker.Stamp(s3Metadata)
+}
+
+// checkMarker verifies the identity marker of an object read from the
+// backend, before its metadata is unsealed. Unencrypted objects are not
+// checked. A missing or invalid marker is logged and counted; it reports
+// false when identity_marker.reject says the read must then be refused.
+func (h *Handler) checkMarker(engine crypto.EncryptionEngine, bucket, key string, metadata map[string]string) bool {
+	if h.marker == nil || !(engine.IsEncrypted(metadata) || metadata[crypto.MetaMPUEncrypted] == "true") {
+		return true
+	}
+	result, gatewayID := h.marker.Verify(metadata)
+	if result == crypto.MarkerValid {
+		return true
+	}
+	h.metrics.RecordObjectMarkerFailure(result)
+	reject := h.config != nil && h.config.Encryption.IdentityMarker.Reject
+	h.logger.WithFields(logrus.Fields{
+		"bucket":     bucket,
+		"key":        key,
+		"marker":     result,
+		"gateway_id": gatewayID,
+		"rejected":   reject,
+	}).Warn("Object does not carry a valid gateway identity marker; it was written by another tool or its metadata was altered")
+	return !reject
+}
+
+// rejectUnmarked answers a read refused by checkMarker.
+func (h *Handler) rejectUnmarked(w http.ResponseWriter, r *http.Request, start time.Time) {
+	s3Err := &S3Error{
+		Code:       "InvalidObjectState",
+		Message:    "The object was not written by this gateway or its metadata was altered.",
+		Resource:   r.URL.Path,
+		HTTPStatus: http.StatusForbidden,
+	}
+	s3Err.WriteXML(w)
+	h.metrics.RecordHTTPRequest(r.Context(), r.Method, r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+}
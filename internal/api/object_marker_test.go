@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func TestObjectMarker_FlagsAndRejectsUnmarkedObjects(t *testing.T) {
+	client := testsupport.NewMemoryClient()
+	newServer := func(marked, reject bool) *httptest.Server {
+		logger := logrus.New()
+		logger.SetLevel(logrus.FatalLevel)
+		engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+		cfg := &config.Config{}
+		cfg.Encryption.IdentityMarker = config.IdentityMarkerConfig{Enabled: marked, Reject: reject}
+		h := NewHandlerWithFeatures(client, engine, logger, getTestMetrics(), nil, nil, nil, cfg, nil)
+		if marked {
+			m, err := crypto.NewObjectMarker([]byte("test-password-123456"), 1000, "gw-test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			h.WithObjectMarker(m)
+		}
+		router := mux.NewRouter()
+		h.RegisterRoutes(router)
+		srv := httptest.NewServer(router)
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	plain := newServer(false, false)
+	flagging := newServer(true, false)
+	rejecting := newServer(true, true)
+
+	putObject(t, plain, "/bucket/unmarked", []byte("written before markers"))
+	putObject(t, rejecting, "/bucket/marked", []byte("written with a marker"))
+	if _, meta, _ := client.Object("bucket", "marked"); meta[crypto.MetaGatewayMarker] == "" {
+		t.Fatal("PUT did not stamp the identity marker")
+	}
+
+	head := func(srv *httptest.Server, path string) *http.Response {
+		resp, err := http.Head(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	for _, tc := range []struct {
+		name string
+		srv  *httptest.Server
+		path string
+		code int
+	}{
+		{"marked", rejecting, "/bucket/marked", http.StatusOK},
+		{"unmarked, flag only", flagging, "/bucket/unmarked", http.StatusOK},
+		{"unmarked, reject", rejecting, "/bucket/unmarked", http.StatusForbidden},
+	} {
+		resp, body := getResponse(t, tc.srv, tc.path, "")
+		if resp.StatusCode != tc.code {
+			t.Errorf("%s: GET = %d %s", tc.name, resp.StatusCode, body)
+		}
+		if resp.Header.Get(crypto.MetaGatewayMarker) != "" {
+			t.Errorf("%s: GET exposed the identity marker", tc.name)
+		}
+		if resp := head(tc.srv, tc.path); resp.StatusCode != tc.code || resp.Header.Get(crypto.MetaGatewayMarker) != "" {
+			t.Errorf("%s: HEAD = %d, marker header %q", tc.name, resp.StatusCode, resp.Header.Get(crypto.MetaGatewayMarker))
+		}
+	}
+}
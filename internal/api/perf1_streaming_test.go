@@ -7,7 +7,7 @@ package api
 // coverage for:
 //   - OversizePart refused with HTTP 413 (Phase D)
 //   - Legacy-source cap enforced on handleCopyObject (Phase C)
-//   - Optimised-range streaming path exercises io.CopyBuffer (Phase B)
+//   - Optimised-range streaming path streams to the writer (Phase B)
 //   - Plaintext UploadPart seekable wrapper (Phase D)
 //
 // The "BoundedHeap" assertion tests from the plan (Phase G §G-2) are implemented
@@ -206,7 +206,7 @@ func TestHandleCopyObject_Legacy_CapEnforced(t *testing.T) {
 
 // TestHandleGetObject_Streaming_BoundedHeap verifies that the optimised range
 // path (Phase B) of handleGetObject streams the response directly to the
-// writer via io.CopyBuffer rather than accumulating a full-object buffer.
+// writer (streamResponse) rather than accumulating a full-object buffer.
 // We use a chunked-encrypted object to exercise the optimised code path,
 // then verify that a range request returns the correct bytes (functional proxy
 // for the heap-bound assertion; the actual allocation bound is enforced at the
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/quota"
+)
+
+// Quota headers, set on writes to buckets with a quota once its usage is
+// known. They give what the bucket's quota and its tenant's still allow,
+// the smaller of the two, after the write; a dimension without a limit has
+// no header.
+const (
+	QuotaRemainingBytesHeader   = "X-S3eg-Quota-Remaining-Bytes"
+	QuotaRemainingObjectsHeader = "X-S3eg-Quota-Remaining-Objects"
+)
+
+// checkQuota answers 403 QuotaExceeded, as Ceph RGW does, when storing size
+// more bytes as a new object in bucket would exceed a quota. It reports
+// whether the write may go ahead.
+func (h *Handler) checkQuota(w http.ResponseWriter, r *http.Request, bucket string, size int64, start time.Time) bool {
+	err := h.quotas.Check(bucket, size)
+	if err == nil {
+		return true
+	}
+	msg := "The storage quota is exhausted."
+	var exceeded *quota.ExceededError
+	if errors.As(err, &exceeded) {
+		msg = fmt.Sprintf("The storage quota of %s %q is exhausted.", exceeded.Scope, exceeded.Name)
+	}
+	s3Err := &S3Error{
+		Code:       "QuotaExceeded",
+		Message:    msg,
+		Resource:   r.URL.Path,
+		HTTPStatus: http.StatusForbidden,
+	}
+	h.setQuotaHeaders(w, bucket)
+	s3Err.WriteXML(w)
+	h.metrics.RecordHTTPRequest(r.Context(), r.Method, r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+	return false
+}
+
+// setQuotaHeaders adds the quota headers for bucket to the response.
+func (h *Handler) setQuotaHeaders(w http.ResponseWriter, bucket string) {
+	rem, ok := h.quotas.Remaining(bucket)
+	if !ok {
+		return
+	}
+	if rem.Bytes != nil {
+		w.Header().Set(QuotaRemainingBytesHeader, strconv.FormatInt(*rem.Bytes, 10))
+	}
+	if rem.Objects != nil {
+		w.Header().Set(QuotaRemainingObjectsHeader, strconv.FormatInt(*rem.Objects, 10))
+	}
+}
+
+// recordQuota adds a successful write to the usage of bucket and sets the
+// quota headers accordingly.
+func (h *Handler) recordQuota(w http.ResponseWriter, bucket string, bytes, objects int64) {
+	h.quotas.Record(bucket, bytes, objects)
+	h.setQuotaHeaders(w, bucket)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/quota"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func TestPutObject_Quota(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, err := crypto.NewEngineWithChunking([]byte("test-password-quota-1234"), nil, "", nil, true, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := testsupport.NewMemoryClient()
+	quotas := quota.New(client, config.QuotaConfig{
+		Enabled:      true,
+		ScanInterval: time.Hour,
+		Buckets:      map[string]config.QuotaLimit{"limited": {MaxObjects: 2}},
+	}, nil, nil, nil)
+	if err := quotas.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandlerWithFeatures(client, engine, logger, getTestMetrics(), nil, nil, nil, &config.Config{}, nil)
+	h.WithQuotas(quotas)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	body := bytes.Repeat([]byte("q"), 1024)
+	for i, want := range []string{"1", "0"} {
+		resp, data := doRequest(t, "PUT", srv.URL+"/limited/obj-"+want, body, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("PUT %d = %d %s", i, resp.StatusCode, data)
+		}
+		if got := resp.Header.Get(QuotaRemainingObjectsHeader); got != want {
+			t.Errorf("PUT %d remaining objects = %q, want %q", i, got, want)
+		}
+		if got := resp.Header.Get(QuotaRemainingBytesHeader); got != "" {
+			t.Errorf("PUT %d reports a byte quota: %q", i, got)
+		}
+	}
+
+	resp, data := doRequest(t, "PUT", srv.URL+"/limited/obj-2", body, nil)
+	if resp.StatusCode != http.StatusForbidden || !bytes.Contains(data, []byte("QuotaExceeded")) {
+		t.Fatalf("PUT over quota = %d %s", resp.StatusCode, data)
+	}
+	if _, _, ok := client.Object("limited", "obj-2"); ok {
+		t.Error("object stored despite the exhausted quota")
+	}
+	if resp, _ := doRequest(t, "PUT", srv.URL+"/limited/copy", nil, map[string]string{
+		"x-amz-copy-source": "limited/obj-1",
+	}); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("copy over quota = %d", resp.StatusCode)
+	}
+	if resp, _ := doRequest(t, "PUT", srv.URL+"/other/obj", body, nil); resp.StatusCode != http.StatusOK || resp.Header.Get(QuotaRemainingObjectsHeader) != "" {
+		t.Errorf("PUT without quota = %d, header %q", resp.StatusCode, resp.Header.Get(QuotaRemainingObjectsHeader))
+	}
+}
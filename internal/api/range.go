@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/featureflag"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// canonicalRange checks a GET Range header and returns it in the
+// "bytes=first-last", "bytes=first-" or "bytes=-suffix" form the range code
+// parses. ok is false when the header does not name exactly one
+// syntactically valid byte range; RFC 7233 lets a server ignore such a
+// header, and S3 does so for malformed and multi-range headers alike, so the
+// caller serves the whole object instead.
+//
+// Whether the range is satisfiable depends on the object size and is
+// checked later.
+func canonicalRange(header string) (string, bool) {
+	unit, spec, found := strings.Cut(strings.TrimSpace(header), "=")
+	if !found || !strings.EqualFold(strings.TrimSpace(unit), "bytes") {
+		return "", false
+	}
+	spec = strings.TrimSpace(spec)
+	if strings.Contains(spec, ",") {
+		return "", false
+	}
+	first, last, found := strings.Cut(spec, "-")
+	if !found {
+		return "", false
+	}
+	first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+	switch {
+	case first == "":
+		if !isRangeNumber(last) {
+			return "", false
+		}
+	case last == "":
+		if !isRangeNumber(first) {
+			return "", false
+		}
+	default:
+		if !isRangeNumber(first) || !isRangeNumber(last) {
+			return "", false
+		}
+		f, _ := strconv.ParseInt(first, 10, 64)
+		l, _ := strconv.ParseInt(last, 10, 64)
+		if l < f {
+			return "", false
+		}
+	}
+	return "bytes=" + first + "-" + last, true
+}
+
+// isRangeNumber reports whether s is a run of ASCII digits that fits an
+// int64.
+func isRangeNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	_, err := strconv.ParseInt(s, 10, 64)
+	return err == nil
+}
+
+// writeRangeNotSatisfiable answers a GET whose range starts beyond an
+// object of size bytes, as S3 does: 416 with an InvalidRange error and
+// "Content-Range: bytes */size".
+func (h *Handler) writeRangeNotSatisfiable(w http.ResponseWriter, r *http.Request, size int64, start time.Time) {
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+	s3Err := &S3Error{
+		Code:       "InvalidRange",
+		Message:    "The requested range is not satisfiable",
+		Resource:   r.URL.Path,
+		HTTPStatus: http.StatusRequestedRangeNotSatisfiable,
+	}
+	s3Err.WriteXML(w)
+	h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+}
+
+// getCiphertextRange fetches backendRange ("bytes=first-last") of a chunked
+// object. With the parallel_range_fetch flag on for the object, a range
+// longer than one part is fetched as concurrent ranged GETs; otherwise, and
+// for short ranges, it is a single (possibly hedged) GET. A partial body
+// reports its offset in the object so DecryptRange knows where it starts.
+func (h *Handler) getCiphertextRange(ctx context.Context, s3Client s3.Client, bucket, key string, versionID *string, backendRange string) (io.ReadCloser, map[string]string, error) {
+	get := func(ctx context.Context, rangeHeader *string) (io.ReadCloser, map[string]string, error) {
+		return h.rangeHedger.GetObject(ctx, s3Client, bucket, key, versionID, rangeHeader)
+	}
+	var first, last int64
+	if _, err := fmt.Sscanf(backendRange, "bytes=%d-%d", &first, &last); err != nil {
+		return get(ctx, &backendRange)
+	}
+	body, meta, err := h.fetchCiphertextRange(ctx, get, bucket, key, backendRange, first, last)
+	if err != nil {
+		return nil, nil, err
+	}
+	// A backend that ignores Range answers with the whole object.
+	if meta["Content-Range"] == "" {
+		return body, meta, nil
+	}
+	return &rangedCiphertext{ReadCloser: body, offset: first}, meta, nil
+}
+
+func (h *Handler) fetchCiphertextRange(ctx context.Context, get s3.RangeGetter, bucket, key, backendRange string, first, last int64) (io.ReadCloser, map[string]string, error) {
+	if !h.featureFlags.Enabled(featureflag.ParallelRangeFetch, bucket, key) {
+		return get(ctx, &backendRange)
+	}
+	partSize := int64(config.DefaultParallelRangePartSize)
+	concurrency := config.DefaultParallelRangeConcurrency
+	if h.config != nil {
+		if n := h.config.Backend.ParallelRange.PartSize; n > 0 {
+			partSize = n
+		}
+		if n := h.config.Backend.ParallelRange.Concurrency; n > 0 {
+			concurrency = n
+		}
+	}
+	if last-first+1 <= partSize {
+		return get(ctx, &backendRange)
+	}
+	h.logger.WithFields(logrus.Fields{
+		"bucket":    bucket,
+		"key":       key,
+		"range":     backendRange,
+		"part_size": partSize,
+	}).Debug("Fetching range in parallel")
+	return s3.GetRangeParallel(ctx, get, first, last, partSize, concurrency)
+}
+
+// rangedCiphertext is the body of a ranged backend GET, starting offset
+// bytes into the stored object.
+type rangedCiphertext struct {
+	io.ReadCloser
+	offset int64
+}
+
+// CiphertextOffset implements crypto.CiphertextOffsetter.
+func (r *rangedCiphertext) CiphertextOffset() int64 { return r.offset }
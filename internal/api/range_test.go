@@ -0,0 +1,306 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/featureflag"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func TestCanonicalRange(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		{"bytes=0-9", "bytes=0-9", true},
+		{"bytes=10-", "bytes=10-", true},
+		{"bytes=-5", "bytes=-5", true},
+		{"Bytes = 3 - 4", "bytes=3-4", true},
+		{"bytes=5-2", "", false},
+		{"bytes=0-1,4-5", "", false},
+		{"bytes=a-b", "", false},
+		{"bytes=+1-2", "", false},
+		{"bytes=-", "", false},
+		{"bytes=5", "", false},
+		{"items=0-1", "", false},
+		{"bytes=99999999999999999999-", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := canonicalRange(tt.header)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("canonicalRange(%q) = %q, %v; want %q, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGetObject_RangeSemantics(t *testing.T) {
+	// Chunked objects decrypt only the chunks in range; single-AEAD objects
+	// are cut from the streamed plaintext.
+	for _, mode := range []struct {
+		name    string
+		chunked bool
+	}{{"chunked", true}, {"buffered", false}} {
+		t.Run(mode.name, func(t *testing.T) { testGetObjectRangeSemantics(t, mode.chunked) })
+	}
+}
+
+func testGetObjectRangeSemantics(t *testing.T, chunked bool) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, _ := crypto.NewEngineWithOpts([]byte("test-password-123456"), nil, crypto.WithChunking(chunked))
+	router := mux.NewRouter()
+	NewHandlerWithFeatures(testsupport.NewMemoryClient(), engine, logger, getTestMetrics(), nil, nil, nil, &config.Config{}, nil).RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	plaintext := bytes.Repeat([]byte("0123456789"), 10)
+	putObject(t, srv, "/bucket/obj", plaintext)
+
+	tests := []struct {
+		name         string
+		rangeHeader  string
+		wantStatus   int
+		wantBody     []byte
+		contentRange string
+	}{
+		{"bounded", "bytes=10-19", http.StatusPartialContent, plaintext[10:20], "bytes 10-19/100"},
+		{"suffix", "bytes=-5", http.StatusPartialContent, plaintext[95:], "bytes 95-99/100"},
+		{"suffix longer than object", "bytes=-500", http.StatusPartialContent, plaintext, "bytes 0-99/100"},
+		{"last byte past end", "bytes=90-200", http.StatusPartialContent, plaintext[90:], "bytes 90-99/100"},
+		{"start past end", "bytes=100-", http.StatusRequestedRangeNotSatisfiable, nil, "bytes */100"},
+		{"empty suffix", "bytes=-0", http.StatusRequestedRangeNotSatisfiable, nil, "bytes */100"},
+		{"malformed", "bytes=abc", http.StatusOK, plaintext, ""},
+		{"reversed", "bytes=20-10", http.StatusOK, plaintext, ""},
+		{"multiple ranges", "bytes=0-1,5-6", http.StatusOK, plaintext, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", srv.URL+"/bucket/obj", nil)
+			req.Header.Set("Range", tt.rangeHeader)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", resp.StatusCode, tt.wantStatus, body)
+			}
+			if got := resp.Header.Get("Content-Range"); got != tt.contentRange {
+				t.Errorf("Content-Range = %q, want %q", got, tt.contentRange)
+			}
+			if tt.wantStatus == http.StatusRequestedRangeNotSatisfiable {
+				if !bytes.Contains(body, []byte("<Code>InvalidRange</Code>")) {
+					t.Errorf("body = %s, want InvalidRange error", body)
+				}
+				return
+			}
+			if !bytes.Equal(body, tt.wantBody) {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+			if resp.ContentLength != int64(len(tt.wantBody)) {
+				t.Errorf("Content-Length = %d, want %d", resp.ContentLength, len(tt.wantBody))
+			}
+		})
+	}
+}
+
+func TestGetObject_ParallelRangeFetch(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, _ := crypto.NewEngineWithOpts([]byte("test-password-123456"), nil, crypto.WithChunking(true))
+	client := testsupport.NewMemoryClient()
+	cfg := &config.Config{}
+	cfg.Backend.ParallelRange = config.BackendParallelRangeConfig{PartSize: 64 << 10, Concurrency: 2}
+	h := NewHandlerWithFeatures(client, engine, logger, getTestMetrics(), nil, nil, nil, cfg, nil)
+	flags, err := featureflag.New(map[string]config.FeatureFlag{
+		featureflag.ParallelRangeFetch: {Enabled: true, Buckets: []string{"canary"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.WithFeatureFlags(flags)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	plaintext := make([]byte, 400<<10)
+	for i := range plaintext {
+		plaintext[i] = byte(i * 7)
+	}
+	putObject(t, srv, "/canary/obj", plaintext)
+	putObject(t, srv, "/prod/obj", plaintext)
+
+	for _, tc := range []struct {
+		bucket   string
+		parallel bool
+	}{
+		{"canary", true},
+		{"prod", false},
+	} {
+		before := client.Calls("GetObject")
+		req, _ := http.NewRequest("GET", srv.URL+"/"+tc.bucket+"/obj", nil)
+		req.Header.Set("Range", "bytes=1000-300000")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, plaintext[1000:300001]) {
+			t.Fatalf("%s: status %d, %d bytes", tc.bucket, resp.StatusCode, len(body))
+		}
+		if calls := client.Calls("GetObject") - before; (calls > 1) != tc.parallel {
+			t.Errorf("%s: %d backend GETs", tc.bucket, calls)
+		}
+	}
+}
+
+func TestGetObject_RangeBeyondFirstChunk(t *testing.T) {
+	plaintext := make([]byte, 300<<10)
+	for i := range plaintext {
+		plaintext[i] = byte(i * 13)
+	}
+	for _, bodyHeader := range []bool{false, true} {
+		logger := logrus.New()
+		logger.SetLevel(logrus.FatalLevel)
+		engine, _ := crypto.NewEngineWithOpts([]byte("test-password-123456"), nil,
+			crypto.WithChunking(true), crypto.WithBodyHeader(bodyHeader))
+		client := testsupport.NewMemoryClient()
+		router := mux.NewRouter()
+		NewHandlerWithFeatures(client, engine, logger, getTestMetrics(), nil, nil, nil, &config.Config{}, nil).RegisterRoutes(router)
+		srv := httptest.NewServer(router)
+
+		putObject(t, srv, "/bucket/obj", plaintext)
+		if stored, _, _ := client.Object("bucket", "obj"); bytes.HasPrefix(stored, []byte("S3EG")) != bodyHeader {
+			t.Errorf("body header %v: stored body starts with %q", bodyHeader, stored[:4])
+		}
+
+		for _, r := range [][2]int{{150000, 150099}, {70000, 250000}, {0, 10}} {
+			req, _ := http.NewRequest("GET", fmt.Sprintf("%s/bucket/obj", srv.URL), nil)
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", r[0], r[1]))
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, plaintext[r[0]:r[1]+1]) {
+				t.Errorf("body header %v, range %d-%d: status %d, %d bytes, match %v",
+					bodyHeader, r[0], r[1], resp.StatusCode, len(body), bytes.Equal(body, plaintext[r[0]:r[1]+1]))
+			}
+		}
+		srv.Close()
+	}
+}
+
+// rangeRecordingClient records the Range headers of backend GETs.
+type rangeRecordingClient struct {
+	*testsupport.MemoryClient
+	mu     sync.Mutex
+	ranges []string
+}
+
+func (c *rangeRecordingClient) GetObject(ctx context.Context, bucket, key string, versionID, rangeHeader *string) (io.ReadCloser, map[string]string, error) {
+	c.mu.Lock()
+	if rangeHeader != nil {
+		c.ranges = append(c.ranges, *rangeHeader)
+	} else {
+		c.ranges = append(c.ranges, "")
+	}
+	c.mu.Unlock()
+	return c.MemoryClient.GetObject(ctx, bucket, key, versionID, rangeHeader)
+}
+
+// TestGetObject_RangeFetchesOnlyNeededChunks checks that a ranged GET on a
+// chunked object asks the backend for the chunks holding the range rather
+// than the whole ciphertext.
+func TestGetObject_RangeFetchesOnlyNeededChunks(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, _ := crypto.NewEngineWithOpts([]byte("test-password-123456"), nil, crypto.WithChunking(true))
+	client := &rangeRecordingClient{MemoryClient: testsupport.NewMemoryClient()}
+	router := mux.NewRouter()
+	NewHandlerWithFeatures(client, engine, logger, getTestMetrics(), nil, nil, nil, &config.Config{}, nil).RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	plaintext := make([]byte, 8*crypto.DefaultChunkSize+100)
+	for i := range plaintext {
+		plaintext[i] = byte(i * 31)
+	}
+	putObject(t, srv, "/bucket/obj", plaintext)
+
+	tests := []struct {
+		name       string
+		header     string
+		start, end int
+		maxChunks  int
+	}{
+		{"inside one chunk", "bytes=200000-200099", 200000, 200099, 1},
+		{"across a boundary", fmt.Sprintf("bytes=%d-%d", 3*crypto.DefaultChunkSize-10, 3*crypto.DefaultChunkSize+10), 3*crypto.DefaultChunkSize - 10, 3*crypto.DefaultChunkSize + 10, 2},
+		{"partial last chunk", "bytes=-50", len(plaintext) - 50, len(plaintext) - 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client.mu.Lock()
+			client.ranges = nil
+			client.mu.Unlock()
+
+			req, _ := http.NewRequest("GET", srv.URL+"/bucket/obj", nil)
+			req.Header.Set("Range", tt.header)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, plaintext[tt.start:tt.end+1]) {
+				t.Fatalf("status %d, %d bytes, want 206 with %d bytes", resp.StatusCode, len(body), tt.end-tt.start+1)
+			}
+			wantRange := fmt.Sprintf("bytes %d-%d/%d", tt.start, tt.end, len(plaintext))
+			if got := resp.Header.Get("Content-Range"); got != wantRange {
+				t.Errorf("Content-Range = %q, want %q", got, wantRange)
+			}
+
+			client.mu.Lock()
+			ranges := client.ranges
+			client.mu.Unlock()
+			if len(ranges) != 1 {
+				t.Fatalf("backend GETs with ranges %q, want one", ranges)
+			}
+			var from, to int
+			if _, err := fmt.Sscanf(ranges[0], "bytes=%d-%d", &from, &to); err != nil {
+				t.Fatalf("backend range %q: %v", ranges[0], err)
+			}
+			// Each encrypted chunk carries a 16-byte tag.
+			if n := to - from + 1; n > tt.maxChunks*(crypto.DefaultChunkSize+16) {
+				t.Errorf("backend range %q is %d bytes, want at most %d chunks", ranges[0], n, tt.maxChunks)
+			}
+		})
+	}
+}
+
+func TestApplyRangeRequest_ClampsLastByte(t *testing.T) {
+	got, err := applyRangeRequest([]byte("0123456789"), "bytes=7-100")
+	if err != nil || string(got) != "789" {
+		t.Errorf("applyRangeRequest = %q, %v; want %q", got, err, "789")
+	}
+	if _, err := applyRangeRequest(nil, "bytes=0-"); err == nil {
+		t.Error("a range over empty data should not be satisfiable")
+	}
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+)
+
+// RawHeader asks for an object as it is stored: GET returns the ciphertext
+// and HEAD and GET return the encryption metadata as x-amz-meta-* headers,
+// neither decrypted nor filtered. PUT stores a body and metadata obtained
+// that way as they are, without encrypting again. Backup tools and
+// gateway-to-gateway replication use it to copy encrypted objects byte for
+// byte. Only credentials whose raw setting allows it may send it; responses
+// served raw carry the header too.
+const RawHeader = "X-S3eg-Raw"
+
+// rawAccess reports whether r is to be served raw, and whether it asked for
+// raw mode without being allowed to.
+func rawAccess(r *http.Request) (raw, denied bool) {
+	mode, _ := r.Context().Value(rawAccessKey).(string)
+	if mode == config.RawAccessAlways {
+		return true, false
+	}
+	if !strings.EqualFold(r.Header.Get(RawHeader), "true") {
+		return false, false
+	}
+	return mode == config.RawAccessOnRequest, mode != config.RawAccessOnRequest
+}
+
+// serveRaw answers a GET or HEAD for which rawAccess returned true or
+// denied. The Range header is ignored: raw reads always return the whole
+// stored object.
+func (h *Handler) serveRaw(w http.ResponseWriter, r *http.Request, bucket, key string, versionID *string, denied bool, start time.Time) {
+	method := r.Method
+	if denied {
+		h.writeRawDenied(w, r, bucket, key, start)
+		return
+	}
+
+	s3Client, err := h.getS3Client(r)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get S3 client")
+		h.writeS3ClientError(w, r, err, method, start)
+		return
+	}
+
+	var body io.ReadCloser
+	var metadata map[string]string
+	op := "HeadObject"
+	if method == http.MethodHead {
+		metadata, err = s3Client.HeadObject(r.Context(), bucket, key, versionID)
+	} else {
+		op = "GetObject"
+		body, metadata, err = s3Client.GetObject(r.Context(), bucket, key, versionID, nil)
+	}
+	if err != nil {
+		s3Err := TranslateError(err, bucket, key)
+		s3Err.WriteXML(w)
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"bucket": bucket,
+			"key":    key,
+		}).Error("Failed to read object raw")
+		h.metrics.RecordS3Error(r.Context(), op, bucket, s3Err.Code)
+		h.metrics.RecordHTTPRequest(r.Context(), method, r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return
+	}
+
+	for k, v := range metadata {
+		w.Header().Set(k, v)
+	}
+	w.Header().Set(RawHeader, "true")
+	w.WriteHeader(http.StatusOK)
+
+	var n int64
+	if body != nil {
+		defer body.Close()
+		pool := crypto.GetGlobalBufferPool()
+		buf := pool.Get64K()
+		defer pool.Put(buf)
+		if n, err = io.CopyBuffer(w, body, buf); err != nil {
+			h.logger.WithError(err).WithFields(logrus.Fields{
+				"bucket": bucket,
+				"key":    key,
+			}).Error("Failed to stream raw object")
+		}
+	}
+	h.metrics.RecordS3Operation(r.Context(), op, bucket, time.Since(start))
+	h.metrics.RecordHTTPRequest(r.Context(), method, r.URL.Path, http.StatusOK, time.Since(start), n)
+	if h.auditLogger != nil {
+		h.auditLogger.WithContext(r.Context()).LogAccessWithMetadata(strings.ToLower(method), bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), err == nil, err, time.Since(start), map[string]interface{}{
+			"raw":        true,
+			"credential": CredentialLabelFromContext(r),
+		})
+	}
+}
+
+// serveRawPut stores the request body and its x-amz-meta-* headers as they
+// are. The metadata must describe a gateway-encrypted object, so a raw
+// write cannot be used to store plaintext behind the gateway's back.
+func (h *Handler) serveRawPut(w http.ResponseWriter, r *http.Request, bucket, key string, denied bool, start time.Time) {
+	if denied {
+		h.writeRawDenied(w, r, bucket, key, start)
+		return
+	}
+
+	metadata := make(map[string]string)
+	for k, v := range r.Header {
+		if len(v) > 0 && strings.HasPrefix(strings.ToLower(k), "x-amz-meta-") {
+			metadata[strings.ToLower(k)] = v[0]
+		}
+	}
+	// Metadata copied from the backend may use a custom prefix; store it
+	// under the names the metadata client expects.
+	if h.metaCodec != nil {
+		metadata = h.metaCodec.DecodeMetadata(metadata)
+	}
+	engine, err := h.getEncryptionEngine(bucket)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get encryption engine")
+		s3Err := &S3Error{
+			Code:       "InternalError",
+			Message:    "Failed to load encryption configuration",
+			Resource:   r.URL.Path,
+			HTTPStatus: http.StatusInternalServerError,
+		}
+		s3Err.WriteXML(w)
+		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return
+	}
+	if !engine.IsEncrypted(metadata) && metadata[crypto.MetaMPUEncrypted] != "true" {
+		s3Err := &S3Error{
+			Code:       "InvalidRequest",
+			Message:    "A raw write must carry the encryption metadata of a gateway-encrypted object.",
+			Resource:   r.URL.Path,
+			HTTPStatus: http.StatusBadRequest,
+		}
+		s3Err.WriteXML(w)
+		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return
+	}
+
+	var body io.Reader = r.Body
+	lengthHeader := r.Header.Get("Content-Length")
+	if strings.HasPrefix(r.Header.Get("x-amz-content-sha256"), "STREAMING-") {
+		body = NewAwsChunkedReader(r.Body)
+		lengthHeader = r.Header.Get("x-amz-decoded-content-length")
+	}
+	var contentLength *int64
+	var n int64
+	if v, err := strconv.ParseInt(lengthHeader, 10, 64); err == nil {
+		contentLength, n = &v, v
+	}
+	if !h.checkQuota(w, r, bucket, n, start) {
+		return
+	}
+
+	s3Client, err := h.getS3Client(r)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get S3 client")
+		h.writeS3ClientError(w, r, err, "PUT", start)
+		return
+	}
+	err = s3Client.PutObject(r.Context(), bucket, key, body, metadata, contentLength, "", nil)
+	if h.auditLogger != nil {
+		h.auditLogger.WithContext(r.Context()).LogAccessWithMetadata("put", bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), err == nil, err, time.Since(start), map[string]interface{}{
+			"raw":        true,
+			"credential": CredentialLabelFromContext(r),
+		})
+	}
+	if err != nil {
+		s3Err := TranslateError(err, bucket, key)
+		s3Err.WriteXML(w)
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"bucket": bucket,
+			"key":    key,
+		}).Error("Failed to write object raw")
+		h.metrics.RecordS3Error(r.Context(), "PutObject", bucket, s3Err.Code)
+		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return
+	}
+	h.headMeta.invalidate(bucket, key)
+	if h.cache != nil {
+		h.cache.Delete(r.Context(), bucket, key)
+	}
+	h.recordQuota(w, bucket, n, 1)
+	w.Header().Set(RawHeader, "true")
+	w.WriteHeader(http.StatusOK)
+	h.metrics.RecordS3Operation(r.Context(), "PutObject", bucket, time.Since(start))
+	h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, http.StatusOK, time.Since(start), n)
+}
+
+func (h *Handler) writeRawDenied(w http.ResponseWriter, r *http.Request, bucket, key string, start time.Time) {
+	h.logger.WithFields(logrus.Fields{
+		"bucket":     bucket,
+		"key":        key,
+		"credential": CredentialLabelFromContext(r),
+	}).Warn("Raw access requested by a credential without raw access")
+	s3Err := &S3Error{
+		Code:       "AccessDenied",
+		Message:    "Raw access is not permitted for this credential.",
+		Resource:   r.URL.Path,
+		HTTPStatus: http.StatusForbidden,
+	}
+	s3Err.WriteXML(w)
+	h.metrics.RecordHTTPRequest(r.Context(), r.Method, r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+// newRawTestRouter serves the handler as if AuthMiddleware had resolved a
+// credential with the given raw access mode.
+func newRawTestRouter(t *testing.T, backend *testsupport.MemoryClient, mode string) http.Handler {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, _ := crypto.NewEngineWithOpts([]byte("test-password-123456"), nil, crypto.WithChunking(true))
+	router := mux.NewRouter()
+	NewHandlerWithFeatures(backend, engine, logger, getTestMetrics(), nil, nil, nil, &config.Config{}, nil).RegisterRoutes(router)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if mode != "" {
+			r = r.WithContext(context.WithValue(r.Context(), rawAccessKey, mode))
+		}
+		router.ServeHTTP(w, r)
+	})
+}
+
+func TestRawMode(t *testing.T) {
+	backend := testsupport.NewMemoryClient()
+	plaintext := bytes.Repeat([]byte("raw mode "), 1000)
+	rec := httptest.NewRecorder()
+	newRawTestRouter(t, backend, "").ServeHTTP(rec, httptest.NewRequest("PUT", "/bucket/obj", bytes.NewReader(plaintext)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT = %d", rec.Code)
+	}
+	stored, storedMeta, _ := backend.Object("bucket", "obj")
+
+	tests := []struct {
+		name       string
+		mode       string
+		method     string
+		header     bool
+		wantStatus int
+		wantRaw    bool
+	}{
+		{"on request with header", config.RawAccessOnRequest, "GET", true, http.StatusOK, true},
+		{"on request without header", config.RawAccessOnRequest, "GET", false, http.StatusOK, false},
+		{"always", config.RawAccessAlways, "GET", false, http.StatusOK, true},
+		{"head on request", config.RawAccessOnRequest, "HEAD", true, http.StatusOK, true},
+		{"not allowed", "", "GET", true, http.StatusForbidden, false},
+		{"head not allowed", "", "HEAD", true, http.StatusForbidden, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/bucket/obj", nil)
+			if tt.header {
+				req.Header.Set(RawHeader, "true")
+			}
+			rec := httptest.NewRecorder()
+			newRawTestRouter(t, backend, tt.mode).ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := rec.Header().Get(RawHeader) == "true"; got != tt.wantRaw {
+				t.Errorf("%s response header present = %v, want %v", RawHeader, got, tt.wantRaw)
+			}
+			body, _ := io.ReadAll(rec.Body)
+			if !tt.wantRaw {
+				if !bytes.Equal(body, plaintext) {
+					t.Error("non-raw GET did not return the plaintext")
+				}
+				return
+			}
+			for k, v := range storedMeta {
+				if rec.Header().Get(k) != v {
+					t.Errorf("header %s = %q, want stored %q", k, rec.Header().Get(k), v)
+				}
+			}
+			if rec.Header().Get(crypto.MetaEncrypted) == "" {
+				t.Error("encryption metadata missing from raw response")
+			}
+			if tt.method == "GET" && !bytes.Equal(body, stored) {
+				t.Error("raw GET did not return the stored ciphertext")
+			}
+		})
+	}
+}
+
+func TestStaticCredentialStore_RawAccess(t *testing.T) {
+	store, err := NewStaticCredentialStore([]config.GatewayCredential{
+		{AccessKey: "BACKUP", SecretKey: "s", Raw: config.RawAccessOnRequest},
+		{AccessKey: "APP", SecretKey: "s"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := store.RawAccess("BACKUP"); got != config.RawAccessOnRequest {
+		t.Errorf("RawAccess(BACKUP) = %q", got)
+	}
+	if got := store.RawAccess("APP"); got != "" {
+		t.Errorf("RawAccess(APP) = %q, want empty", got)
+	}
+}
+
+func TestRawPut_RoundTrip(t *testing.T) {
+	srcBackend, dstBackend := testsupport.NewMemoryClient(), testsupport.NewMemoryClient()
+	src := newRawTestRouter(t, srcBackend, config.RawAccessOnRequest)
+	dst := newRawTestRouter(t, dstBackend, config.RawAccessOnRequest)
+	plaintext := bytes.Repeat([]byte("replicate "), 5000)
+	rec := httptest.NewRecorder()
+	src.ServeHTTP(rec, httptest.NewRequest("PUT", "/bucket/obj", bytes.NewReader(plaintext)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT = %d", rec.Code)
+	}
+
+	get := httptest.NewRequest("GET", "/bucket/obj", nil)
+	get.Header.Set(RawHeader, "true")
+	rawGet := httptest.NewRecorder()
+	src.ServeHTTP(rawGet, get)
+
+	put := httptest.NewRequest("PUT", "/bucket/obj", bytes.NewReader(rawGet.Body.Bytes()))
+	put.Header.Set(RawHeader, "true")
+	for k, v := range rawGet.Header() {
+		if strings.HasPrefix(strings.ToLower(k), "x-amz-meta-") {
+			put.Header[k] = v
+		}
+	}
+	rec = httptest.NewRecorder()
+	dst.ServeHTTP(rec, put)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("raw PUT = %d: %s", rec.Code, rec.Body.String())
+	}
+	stored, _, _ := srcBackend.Object("bucket", "obj")
+	replica, _, _ := dstBackend.Object("bucket", "obj")
+	if !bytes.Equal(stored, replica) {
+		t.Error("replica ciphertext differs from the source")
+	}
+
+	rec = httptest.NewRecorder()
+	dst.ServeHTTP(rec, httptest.NewRequest("GET", "/bucket/obj", nil))
+	if !bytes.Equal(rec.Body.Bytes(), plaintext) {
+		t.Error("replica does not decrypt to the original plaintext")
+	}
+}
+
+func TestRawPut_Rejections(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		meta       map[string]string
+		wantStatus int
+	}{
+		{"not allowed", "", map[string]string{"x-amz-meta-encrypted": "true"}, http.StatusForbidden},
+		{"plaintext", config.RawAccessOnRequest, map[string]string{"x-amz-meta-note": "hi"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := testsupport.NewMemoryClient()
+			req := httptest.NewRequest("PUT", "/bucket/obj", bytes.NewReader([]byte("data")))
+			req.Header.Set(RawHeader, "true")
+			for k, v := range tt.meta {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			newRawTestRouter(t, backend, tt.mode).ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if backend.Calls("PutObject") != 0 {
+				t.Error("rejected raw write reached the backend")
+			}
+		})
+	}
+}
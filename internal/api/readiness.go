@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// readyComponents are the dependencies with their own probe endpoint,
+// /ready/{name}.
+var readyComponents = []string{"kms", "backend", "config"}
+
+// isReadyComponentPath reports whether path is a component probe endpoint.
+func isReadyComponentPath(path string) bool {
+	for _, name := range readyComponents {
+		if path == "/ready/"+name {
+			return true
+		}
+	}
+	return false
+}
+
+// WithConfigCheck sets the check behind /ready/config, typically the
+// config reloader's LastError. Without it /ready/config always passes: the
+// configuration in use is the one validated at startup.
+func (h *Handler) WithConfigCheck(check func(context.Context) error) {
+	h.configCheck = check
+}
+
+// componentChecks returns the configured component checks by name, each
+// bounded by its readiness timeout and cached for its TTL. The checks are
+// built once so their caches are shared by /ready and /ready/{name}.
+func (h *Handler) componentChecks() map[string]metrics.ReadyCheck {
+	h.componentsOnce.Do(func() {
+		var rc config.ReadinessConfig
+		var proxiedBucket string
+		if h.config != nil {
+			rc = h.config.Readiness
+			proxiedBucket = h.config.ProxiedBucket
+		}
+		h.components = make(map[string]metrics.ReadyCheck, len(readyComponents))
+		if h.keyManager != nil {
+			h.components["kms"] = metrics.CachedReadyCheck(metrics.ReadyCheck{
+				Name:  "kms",
+				Check: func(ctx context.Context) error { return h.keyManager.HealthCheck(ctx) },
+			}, readinessTimeout(rc.KMS), rc.KMS.CacheTTL)
+		}
+		bucket := rc.BackendBucket
+		if bucket == "" {
+			bucket = proxiedBucket
+		}
+		if bucket != "" && h.s3Client != nil {
+			h.components["backend"] = metrics.CachedReadyCheck(metrics.ReadyCheck{
+				Name: "backend",
+				Check: func(ctx context.Context) error {
+					_, err := h.s3Client.ListObjects(ctx, bucket, "", s3.ListOptions{MaxKeys: 1})
+					return err
+				},
+			}, readinessTimeout(rc.Backend), rc.Backend.CacheTTL)
+		}
+		h.components["config"] = metrics.ReadyCheck{
+			Name: "config",
+			Check: func(ctx context.Context) error {
+				if h.configCheck == nil {
+					return nil
+				}
+				return h.configCheck(ctx)
+			},
+		}
+	})
+	return h.components
+}
+
+func readinessTimeout(c config.ReadinessCheckConfig) time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return config.DefaultReadinessTimeout
+}
+
+// handleReadyComponent returns the handler for /ready/{name}. It runs only
+// that component's check, so a failing probe names the failing dependency.
+// A component that is not configured reports ready with no checks.
+func (h *Handler) handleReadyComponent(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		var checks []metrics.ReadyCheck
+		if c, ok := h.componentChecks()[name]; ok {
+			checks = append(checks, c)
+		}
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		metrics.ReadinessHandler(checks...)(rec, r)
+		h.metrics.RecordHTTPRequest(r.Context(), "GET", "/ready/"+name, rec.code, time.Since(start), 0)
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func TestHandler_ReadyComponents(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	backend := testsupport.NewMemoryClient()
+	backendErr := errors.New("connection refused")
+	backend.OnCall = func(op, bucket, key string) error {
+		if op == "ListObjects" && bucket == "probe-bucket" {
+			return backendErr
+		}
+		return nil
+	}
+	cfg := &config.Config{Readiness: config.ReadinessConfig{
+		Backend:       config.ReadinessCheckConfig{CacheTTL: time.Minute},
+		BackendBucket: "probe-bucket",
+	}}
+	handler := NewHandlerWithFeatures(backend, engine, logger, getTestMetrics(), &mockKeyManager{}, nil, nil, cfg, nil)
+	handler.WithConfigCheck(func(context.Context) error { return errors.New("configuration reload failed") })
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	for _, tc := range []struct {
+		path    string
+		code    int
+		want    string
+		notWant string
+	}{
+		{"/ready/kms", http.StatusOK, `"kms":"ok"`, `"backend"`},
+		{"/ready/backend", http.StatusServiceUnavailable, `"backend":"unavailable: connection refused"`, `"kms"`},
+		{"/ready/config", http.StatusServiceUnavailable, `"config":"unavailable: configuration reload failed"`, `"kms"`},
+		{"/ready", http.StatusServiceUnavailable, `"backend":"unavailable`, `"config"`},
+	} {
+		w := get(tc.path)
+		body := w.Body.String()
+		if w.Code != tc.code || !strings.Contains(body, tc.want) || strings.Contains(body, tc.notWant) {
+			t.Errorf("GET %s = %d %s", tc.path, w.Code, body)
+		}
+	}
+	if got := backend.Calls("ListObjects"); got != 1 {
+		t.Errorf("backend listed %d times; /ready should reuse the cached result", got)
+	}
+}
+
+func TestHandler_ReadyComponentNotConfigured(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	handler := NewHandler(testsupport.NewMemoryClient(), engine, logger, getTestMetrics())
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	for _, path := range []string{"/ready/kms", "/ready/backend", "/ready/config"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s = %d %s", path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAuthMiddleware_ReadyComponentsExempt(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	h := AuthMiddleware(nil, time.Minute, logger)(next)
+
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/ready/backend", http.StatusOK},
+		{http.MethodPut, "/ready/backend", http.StatusForbidden},
+		{http.MethodGet, "/ready/other", http.StatusForbidden},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		if w.Code != tc.want {
+			t.Errorf("%s %s = %d, want %d", tc.method, tc.path, w.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReplayCache_Seen(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := NewReplayCache(2, 10*time.Minute)
+
+	if c.Seen("a", start) {
+		t.Fatal("first a reported as seen")
+	}
+	if !c.Seen("a", start.Add(9*time.Minute)) {
+		t.Fatal("a not seen within ttl")
+	}
+	// Expiry is measured from first use, not extended by replays.
+	if c.Seen("a", start.Add(10*time.Minute)) {
+		t.Fatal("a still seen after ttl")
+	}
+
+	// At capacity the oldest live entry is evicted.
+	c.Seen("b", start.Add(11*time.Minute))
+	c.Seen("c", start.Add(12*time.Minute))
+	if c.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", c.Len())
+	}
+	if c.Seen("a", start.Add(13*time.Minute)) {
+		t.Fatal("evicted a reported as seen")
+	}
+	if !c.Seen("c", start.Add(13*time.Minute)) {
+		t.Fatal("c not seen")
+	}
+}
+
+func TestReplayKey(t *testing.T) {
+	tests := []struct {
+		name, auth, target string
+		want               string
+	}{
+		{"sigv4", "AWS4-HMAC-SHA256 Credential=AK/20260301/us-east-1/s3/aws4_request, SignedHeaders=host, Signature=abc123", "/b/k", "AK:abc123"},
+		{"sigv2", "AWS AK:c2lnbmF0dXJl", "/b/k", "AK:c2lnbmF0dXJl"},
+		{"presigned v4", "", "/b/k?X-Amz-Signature=abc123", ""},
+		{"presigned v2", "", "/b/k?AWSAccessKeyId=AK&Signature=abc", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.target, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			got, ok := replayKey(req, "AK")
+			if got != tt.want || ok != (tt.want != "") {
+				t.Errorf("replayKey() = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplayWindow(t *testing.T) {
+	if got := ReplayWindow(0); got != 10*time.Minute {
+		t.Errorf("ReplayWindow(0) = %v, want 10m", got)
+	}
+	if got := ReplayWindow(time.Minute); got != 2*time.Minute {
+		t.Errorf("ReplayWindow(1m) = %v, want 2m", got)
+	}
+}
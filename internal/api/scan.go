@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/scan"
+	"github.com/sirupsen/logrus"
+)
+
+// errScanTooLarge is returned for uploads above scanning.max_object_size.
+var errScanTooLarge = errors.New("upload exceeds scanning.max_object_size")
+
+// quarantineValue is the tag value set on infected objects in quarantine
+// mode.
+const quarantineValue = "infected"
+
+// WithScanner enables malware scanning of PutObject uploads.
+func (h *Handler) WithScanner(s scan.Scanner, cfg config.ScanningConfig) {
+	h.scanner = s
+	h.scanCfg = cfg
+}
+
+// scanAction returns the scanning action for bucket.
+func (h *Handler) scanAction(bucket string) string {
+	if h.scanner == nil {
+		return config.ScanActionOff
+	}
+	return h.policyManager.BucketScanAction(bucket, h.scanCfg.Action)
+}
+
+// scanUpload spools body to a temporary file, scans it and returns a reader
+// that replays the whole upload, plus a cleanup func for the spool. When the
+// scan itself fails the reader is still returned so fail-open callers can
+// store the upload; it is nil only when the upload could not be read.
+func (h *Handler) scanUpload(ctx context.Context, body io.Reader, size int64) (io.Reader, scan.Verdict, func(), error) {
+	noop := func() {}
+	if size > h.scanCfg.MaxObjectSize {
+		return body, scan.Verdict{}, noop, errScanTooLarge
+	}
+	f, err := os.CreateTemp("", "s3eg-scan-*")
+	if err != nil {
+		return body, scan.Verdict{}, noop, fmt.Errorf("spool upload: %w", err)
+	}
+	cleanup := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+
+	n, err := io.CopyN(f, body, h.scanCfg.MaxObjectSize+1)
+	if err != nil && err != io.EOF {
+		cleanup()
+		return nil, scan.Verdict{}, noop, fmt.Errorf("read upload: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, scan.Verdict{}, noop, fmt.Errorf("spool upload: %w", err)
+	}
+	if n > h.scanCfg.MaxObjectSize {
+		return io.MultiReader(f, body), scan.Verdict{}, cleanup, errScanTooLarge
+	}
+
+	scanCtx, cancel := context.WithTimeout(ctx, h.scanCfg.Timeout)
+	verdict, scanErr := h.scanner.Scan(scanCtx, f, n)
+	cancel()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, scan.Verdict{}, noop, fmt.Errorf("spool upload: %w", err)
+	}
+	if scanErr != nil {
+		return f, scan.Verdict{}, cleanup, scanErr
+	}
+	return f, verdict, cleanup, nil
+}
+
+// scanPut applies the bucket's scanning action to a PutObject body. It
+// returns the body and tagging to store, or ok=false after writing the
+// error response. The returned cleanup must run once the body is consumed.
+func (h *Handler) scanPut(w http.ResponseWriter, r *http.Request, bucket, key string, body io.Reader, size int64, tagging string, start time.Time) (io.Reader, string, func(), bool) {
+	action := h.scanAction(bucket)
+	if action == config.ScanActionOff {
+		return body, tagging, func() {}, true
+	}
+
+	scanStart := time.Now()
+	out, verdict, cleanup, err := h.scanUpload(r.Context(), body, size)
+	fields := logrus.Fields{
+		"bucket": bucket,
+		"key":    key,
+		"action": action,
+	}
+	if h.auditLogger != nil {
+		meta := map[string]interface{}{"action": action, "infected": verdict.Infected}
+		if verdict.Signature != "" {
+			meta["signature"] = verdict.Signature
+		}
+		h.auditLogger.WithContext(r.Context()).LogAccessWithMetadata("malware_scan", bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r),
+			err == nil && !verdict.Infected, err, time.Since(scanStart), meta)
+	}
+
+	var s3Err *S3Error
+	switch {
+	case err != nil && out != nil && h.scanCfg.FailOpen:
+		h.logger.WithError(err).WithFields(fields).Warn("Malware scan failed; storing unscanned upload (fail_open)")
+		return out, tagging, cleanup, true
+	case err != nil:
+		cleanup()
+		h.logger.WithError(err).WithFields(fields).Error("Malware scan failed; upload rejected")
+		switch {
+		case errors.Is(err, errScanTooLarge):
+			s3Err = &S3Error{Code: "EntityTooLarge", Message: "Object exceeds the maximum size that can be scanned", HTTPStatus: http.StatusBadRequest}
+		case out == nil:
+			s3Err = &S3Error{Code: "IncompleteBody", Message: "Failed to read the request body", HTTPStatus: http.StatusBadRequest}
+		default:
+			s3Err = &S3Error{Code: "ServiceUnavailable", Message: "Malware scanner unavailable", HTTPStatus: http.StatusServiceUnavailable}
+		}
+	case !verdict.Infected:
+		return out, tagging, cleanup, true
+	case action == config.ScanActionQuarantine:
+		h.logger.WithFields(fields).WithField("signature", verdict.Signature).Warn("Malware detected; storing upload in quarantine")
+		return out, setTag(tagging, h.scanCfg.QuarantineTag, quarantineValue), cleanup, true
+	default:
+		cleanup()
+		h.logger.WithFields(fields).WithField("signature", verdict.Signature).Warn("Malware detected; upload rejected")
+		s3Err = &S3Error{Code: "AccessDenied", Message: "Upload rejected by malware scan: " + verdict.Signature, HTTPStatus: http.StatusForbidden}
+	}
+	s3Err.Resource = r.URL.Path
+	s3Err.WriteXML(w)
+	h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+	return nil, "", nil, false
+}
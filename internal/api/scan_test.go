@@ -0,0 +1,175 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/scan"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeScanner flags any upload containing "EICAR".
+type fakeScanner struct {
+	err     error
+	scanned []byte
+}
+
+func (f *fakeScanner) Scan(_ context.Context, r io.Reader, _ int64) (scan.Verdict, error) {
+	if f.err != nil {
+		return scan.Verdict{}, f.err
+	}
+	f.scanned, _ = io.ReadAll(r)
+	if bytes.Contains(f.scanned, []byte("EICAR")) {
+		return scan.Verdict{Infected: true, Signature: "Eicar-Test-Signature"}, nil
+	}
+	return scan.Verdict{}, nil
+}
+
+func newScanTestHandler(t *testing.T, scanner scan.Scanner, cfg config.ScanningConfig, pm *config.PolicyManager) (*mockS3Client, *mux.Router) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	mockClient := newMockS3Client()
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	h := NewHandlerWithFeatures(mockClient, engine, logger, getTestMetrics(), nil, nil, nil, nil, pm)
+	h.WithScanner(scanner, cfg)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	return mockClient, router
+}
+
+func scanTestConfig(action string) config.ScanningConfig {
+	return config.ScanningConfig{
+		Enabled:       true,
+		Timeout:       time.Second,
+		MaxObjectSize: 1 << 20,
+		Action:        action,
+		QuarantineTag: config.DefaultQuarantineTag,
+	}
+}
+
+func putBody(router *mux.Router, path, body string, hdr map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("PUT", path, bytes.NewReader([]byte(body)))
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestScan_BlockRejectsInfectedUpload(t *testing.T) {
+	scanner := &fakeScanner{}
+	mockClient, router := newScanTestHandler(t, scanner, scanTestConfig(config.ScanActionBlock), nil)
+
+	w := putBody(router, "/b/clean.txt", "hello", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("clean PUT: %d %s", w.Code, w.Body.String())
+	}
+	if string(scanner.scanned) != "hello" {
+		t.Errorf("scanner received %q, want the plaintext", scanner.scanned)
+	}
+	if _, ok := mockClient.objects["b/clean.txt"]; !ok {
+		t.Error("clean upload was not stored")
+	}
+
+	w = putBody(router, "/b/bad.txt", "X5O!P%@AP EICAR", nil)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("infected PUT: %d, want 403", w.Code)
+	}
+	if !bytes.Contains(w.Body.Bytes(), []byte("Eicar-Test-Signature")) {
+		t.Errorf("error does not name the signature: %s", w.Body.String())
+	}
+	if _, ok := mockClient.objects["b/bad.txt"]; ok {
+		t.Error("infected upload reached the backend")
+	}
+}
+
+func TestScan_QuarantineTagsInfectedUpload(t *testing.T) {
+	mockClient, router := newScanTestHandler(t, &fakeScanner{}, scanTestConfig(config.ScanActionQuarantine), nil)
+
+	w := putBody(router, "/b/bad.txt", "EICAR", map[string]string{"x-amz-tagging": "team=ops"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT: %d %s", w.Code, w.Body.String())
+	}
+	if got, want := mockClient.lastPutTags, "s3eg-quarantine=infected&team=ops"; got != want {
+		t.Errorf("tags = %q, want %q", got, want)
+	}
+
+	// The stored object is still encrypted and readable.
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/b/bad.txt", nil))
+	if w.Body.String() != "EICAR" {
+		t.Errorf("GET = %q", w.Body.String())
+	}
+
+	putBody(router, "/b/clean.txt", "hello", map[string]string{"x-amz-tagging": "team=ops"})
+	if mockClient.lastPutTags != "team=ops" {
+		t.Errorf("clean upload tags = %q", mockClient.lastPutTags)
+	}
+}
+
+func TestScan_FailureHandling(t *testing.T) {
+	scanner := &fakeScanner{err: errors.New("connection refused")}
+	cfg := scanTestConfig(config.ScanActionBlock)
+	mockClient, router := newScanTestHandler(t, scanner, cfg, nil)
+	if w := putBody(router, "/b/k", "hello", nil); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("fail-closed PUT: %d, want 503", w.Code)
+	}
+	if _, ok := mockClient.objects["b/k"]; ok {
+		t.Error("unscanned upload stored while failing closed")
+	}
+
+	cfg.MaxObjectSize = 4
+	scanner.err = nil
+	_, router = newScanTestHandler(t, scanner, cfg, nil)
+	if w := putBody(router, "/b/k", "hello", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("oversized PUT: %d, want 400", w.Code)
+	}
+
+	cfg.FailOpen = true
+	_, router = newScanTestHandler(t, scanner, cfg, nil)
+	if w := putBody(router, "/b/k", "hello world", nil); w.Code != http.StatusOK {
+		t.Fatalf("fail-open PUT: %d", w.Code)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/b/k", nil))
+	if w.Body.String() != "hello world" {
+		t.Errorf("fail-open upload stored as %q", w.Body.String())
+	}
+}
+
+func TestScan_PolicyOverride(t *testing.T) {
+	dir := t.TempDir()
+	policy := "id: backups\nbuckets: [\"backups\"]\nscan_action: \"off\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "p.yaml"), []byte(policy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pm := config.NewPolicyManager()
+	if err := pm.LoadPolicies([]string{filepath.Join(dir, "*.yaml")}); err != nil {
+		t.Fatal(err)
+	}
+	scanner := &fakeScanner{}
+	_, router := newScanTestHandler(t, scanner, scanTestConfig(config.ScanActionBlock), pm)
+
+	if w := putBody(router, "/backups/k", "EICAR", nil); w.Code != http.StatusOK {
+		t.Errorf("PUT to unscanned bucket: %d", w.Code)
+	}
+	if scanner.scanned != nil {
+		t.Error("bucket with scan_action off was scanned")
+	}
+	if w := putBody(router, "/other/k", "EICAR", nil); w.Code != http.StatusForbidden {
+		t.Errorf("PUT to scanned bucket: %d, want 403", w.Code)
+	}
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/admin"
+	"github.com/kenneth/s3-encryption-gateway/internal/audit"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/sirupsen/logrus"
+)
+
+// ShredObjects destroys the data keys of bucket/key, or of every object
+// under bucket/prefix when key is empty, so their ciphertext can never be
+// decrypted again. The objects themselves are not deleted.
+//
+// A single-part object is rewritten in place with its salt and wrapped key
+// removed from the envelope (and from the in-body metadata of fallback
+// objects); the ciphertext is copied through unchanged. A multipart object
+// keeps its wrapped DEK in the encrypted manifest companion, which is
+// replaced by an empty tombstone instead. Either way GET returns
+// InvalidObjectState from then on.
+//
+// Older versions on a versioned bucket keep their key material: shred
+// before enabling versioning or expire noncurrent versions. Objects under
+// object lock are refused for the same reason, and objects covered by a key
+// hold are reported as held. With dryRun set, the objects that would be
+// shredded are listed and nothing is written.
+func (h *Handler) ShredObjects(ctx context.Context, bucket, key, prefix string, dryRun bool) (admin.ShredResult, error) {
+	var res admin.ShredResult
+	// The hold list is read once up front: a hold placed while a large
+	// prefix is being shredded only protects objects not yet reached.
+	holds, err := h.keyHolds.List(ctx)
+	if err != nil {
+		return res, fmt.Errorf("shred: %w", err)
+	}
+	shred := func(k string) {
+		if holds.Match(bucket, k) != nil {
+			res.Held = append(res.Held, k)
+			return
+		}
+		done, err := h.shredObject(ctx, bucket, k, dryRun)
+		switch {
+		case err != nil:
+			res.Failed = append(res.Failed, k)
+			h.logger.WithError(err).WithFields(logrus.Fields{
+				"bucket": bucket,
+				"key":    k,
+			}).Error("Failed to shred object")
+		case done:
+			res.Shredded = append(res.Shredded, k)
+		default:
+			res.Skipped = append(res.Skipped, k)
+		}
+	}
+
+	if key != "" {
+		shred(key)
+		return res, nil
+	}
+	opts := s3.ListOptions{MaxKeys: 1000}
+	for {
+		page, err := h.s3Client.ListObjects(ctx, bucket, prefix, opts)
+		if err != nil {
+			return res, fmt.Errorf("shred: list %s/%s: %w", bucket, prefix, err)
+		}
+		for _, obj := range page.Objects {
+			if strings.HasSuffix(obj.Key, ".mpu-manifest") || h.isReservedKey(bucket, obj.Key) {
+				continue
+			}
+			shred(obj.Key)
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return res, nil
+		}
+		opts.ContinuationToken = page.NextContinuationToken
+	}
+}
+
+// shredObject destroys the data key of one object. It reports false without
+// an error when there is nothing to destroy.
+func (h *Handler) shredObject(ctx context.Context, bucket, key string, dryRun bool) (bool, error) {
+	meta, err := h.s3Client.HeadObject(ctx, bucket, key, nil)
+	if err != nil {
+		if isS3NotFoundError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("head %s/%s: %w", bucket, key, err)
+	}
+	engine, err := h.getEncryptionEngine(bucket)
+	if err != nil {
+		return false, fmt.Errorf("load encryption engine: %w", err)
+	}
+	mpu := meta[crypto.MetaMPUEncrypted] == "true"
+	switch {
+	case crypto.IsShredded(meta):
+		return false, nil
+	case !mpu && !engine.IsEncrypted(meta):
+		return false, nil
+	case meta["x-amz-object-lock-mode"] != "":
+		return false, fmt.Errorf("%s/%s is under object lock, which retains its key material", bucket, key)
+	}
+
+	manifestKey := ""
+	if mpu {
+		manifestKey = meta[crypto.MetaFallbackPointer]
+		if manifestKey == "" {
+			manifestKey = key + ".mpu-manifest"
+		}
+		manifestMeta, err := h.s3Client.HeadObject(ctx, bucket, manifestKey, nil)
+		if err != nil && !isS3NotFoundError(err) {
+			return false, fmt.Errorf("head %s/%s: %w", bucket, manifestKey, err)
+		}
+		if err != nil || crypto.IsShredded(manifestMeta) {
+			return false, nil
+		}
+	}
+	if dryRun {
+		return true, nil
+	}
+
+	if mpu {
+		err = h.shredManifest(ctx, bucket, manifestKey)
+	} else {
+		err = h.shredInPlace(ctx, bucket, key)
+	}
+	if h.cache != nil {
+		h.cache.Delete(ctx, bucket, key)
+	}
+	h.headMeta.invalidate(bucket, key)
+	if h.auditLogger != nil {
+		h.auditLogger.WithContext(ctx).LogAccess(string(audit.EventTypeCryptoShred), bucket, key, "", "", "", err == nil, err, 0)
+	}
+	return err == nil, err
+}
+
+// shredManifest replaces a multipart manifest companion, and the wrapped DEK
+// in it, by an empty tombstone.
+func (h *Handler) shredManifest(ctx context.Context, bucket, manifestKey string) error {
+	tombstone := map[string]string{
+		crypto.MetaShredded: time.Now().UTC().Format(time.RFC3339),
+	}
+	var zero int64
+	if err := h.s3Client.PutObject(ctx, bucket, manifestKey, bytes.NewReader(nil), tombstone, &zero, "", nil); err != nil {
+		return fmt.Errorf("overwrite %s/%s: %w", bucket, manifestKey, err)
+	}
+	return nil
+}
+
+// shredInPlace rewrites a single-part object without its key material.
+func (h *Handler) shredInPlace(ctx context.Context, bucket, key string) error {
+	body, meta, err := h.s3Client.GetObject(ctx, bucket, key, nil, nil)
+	if err != nil {
+		return fmt.Errorf("get %s/%s: %w", bucket, key, err)
+	}
+	defer body.Close()
+	size, err := strconv.ParseInt(meta["Content-Length"], 10, 64)
+	if err != nil {
+		return fmt.Errorf("%s/%s has no content length", bucket, key)
+	}
+	shredded, err := crypto.ShredBody(body, meta)
+	if err != nil {
+		return fmt.Errorf("%s/%s: %w", bucket, key, err)
+	}
+
+	stored := make(map[string]string)
+	for k, v := range meta {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-meta-") {
+			stored[lk] = v
+		}
+	}
+	stored = crypto.ShredMetadata(stored, time.Now())
+	if ct := meta["Content-Type"]; ct != "" {
+		stored["Content-Type"] = ct
+	}
+
+	// Only replace the object that was read, so a concurrent overwrite is
+	// not clobbered with stale ciphertext.
+	unchanged := s3.WithWriteConditions(ctx, s3.WriteConditions{IfMatch: meta["ETag"]})
+	journalID, err := h.journal.Begin("Shred", bucket, key, true)
+	if err != nil {
+		return fmt.Errorf("journal %s/%s: %w", bucket, key, err)
+	}
+	err = h.s3Client.PutObject(unchanged, bucket, key, shredded, stored, &size, "", nil)
+	h.journal.End(journalID, err)
+	if err != nil {
+		return fmt.Errorf("rewrite %s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func newShredTestServer(t *testing.T) (*Handler, *testsupport.MemoryClient, *httptest.Server) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, _ := crypto.NewEngineWithOpts([]byte("test-password-123456"), nil, crypto.WithChunking(true))
+	client := testsupport.NewMemoryClient()
+	h := NewHandlerWithFeatures(client, engine, logger, getTestMetrics(), nil, nil, nil, &config.Config{}, nil)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return h, client, srv
+}
+
+func getStatus(t *testing.T, srv *httptest.Server, path, rng string) (int, string) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", srv.URL+path, nil)
+	if rng != "" {
+		req.Header.Set("Range", rng)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestShredObjects_DestroysKeyMaterial(t *testing.T) {
+	h, client, srv := newShredTestServer(t)
+	plaintext := bytes.Repeat([]byte("personal data "), 5000)
+	putObject(t, srv, "/bucket/users/42/profile", plaintext)
+	putObject(t, srv, "/bucket/users/42/avatar", []byte("avatar"))
+	putObject(t, srv, "/bucket/users/43/profile", []byte("someone else"))
+	before, _, _ := client.Object("bucket", "users/42/profile")
+
+	res, err := h.ShredObjects(context.Background(), "bucket", "", "users/42/", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Shredded) != 2 || len(res.Failed) != 0 {
+		t.Fatalf("result = %+v", res)
+	}
+
+	after, meta, ok := client.Object("bucket", "users/42/profile")
+	if !ok || !bytes.Equal(before, after) {
+		t.Error("ciphertext was not kept on the backend")
+	}
+	for _, k := range []string{crypto.MetaKeySalt, crypto.MetaWrappedKeyCiphertext, "x-amz-meta-s", "x-amz-meta-wk"} {
+		if meta[k] != "" {
+			t.Errorf("key material %s left in metadata", k)
+		}
+	}
+	if !crypto.IsShredded(meta) {
+		t.Error("object not marked as shredded")
+	}
+
+	for _, rng := range []string{"", "bytes=0-99"} {
+		status, body := getStatus(t, srv, "/bucket/users/42/profile", rng)
+		if status != http.StatusForbidden || !strings.Contains(body, "InvalidObjectState") {
+			t.Errorf("GET (range %q) after shred = %d %s", rng, status, body)
+		}
+	}
+	if status, body := getStatus(t, srv, "/bucket/users/43/profile", ""); status != http.StatusOK || body != "someone else" {
+		t.Errorf("object outside the prefix = %d %q", status, body)
+	}
+
+	res, err = h.ShredObjects(context.Background(), "bucket", "users/42/profile", "", false)
+	if err != nil || len(res.Shredded) != 0 || len(res.Skipped) != 1 {
+		t.Errorf("second shred = %+v, %v", res, err)
+	}
+}
+
+func TestShredObjects_DryRunWritesNothing(t *testing.T) {
+	h, client, srv := newShredTestServer(t)
+	putObject(t, srv, "/bucket/k", []byte("still readable"))
+
+	res, err := h.ShredObjects(context.Background(), "bucket", "k", "", true)
+	if err != nil || len(res.Shredded) != 1 {
+		t.Fatalf("dry run = %+v, %v", res, err)
+	}
+	if n := client.Calls("PutObject"); n != 1 {
+		t.Errorf("dry run wrote %d objects", n-1)
+	}
+	if status, body := getStatus(t, srv, "/bucket/k", ""); status != http.StatusOK || body != "still readable" {
+		t.Errorf("GET after dry run = %d %q", status, body)
+	}
+}
+
+func TestShredObjects_SkipsPlaintextAndMissing(t *testing.T) {
+	h, client, _ := newShredTestServer(t)
+	size := int64(5)
+	if err := client.PutObject(context.Background(), "bucket", "plain", strings.NewReader("plain"), nil, &size, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"plain", "missing"} {
+		res, err := h.ShredObjects(context.Background(), "bucket", key, "", false)
+		if err != nil || len(res.Skipped) != 1 || len(res.Shredded) != 0 {
+			t.Errorf("%s: result = %+v, %v", key, res, err)
+		}
+	}
+}
+
+func TestShredObjects_MultipartTombstonesManifest(t *testing.T) {
+	h, client, srv := newShredTestServer(t)
+	ctx := context.Background()
+	size := int64(10)
+	mpuMeta := map[string]string{
+		crypto.MetaMPUEncrypted:    "true",
+		crypto.MetaFallbackPointer: "big.mpu-manifest",
+	}
+	if err := client.PutObject(ctx, "bucket", "big", bytes.NewReader(make([]byte, size)), mpuMeta, &size, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	manifestSize := int64(8)
+	if err := client.PutObject(ctx, "bucket", "big.mpu-manifest", bytes.NewReader(make([]byte, manifestSize)), map[string]string{crypto.MetaEncrypted: "true"}, &manifestSize, "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := h.ShredObjects(ctx, "bucket", "", "big", false)
+	if err != nil || len(res.Shredded) != 1 || res.Shredded[0] != "big" {
+		t.Fatalf("result = %+v, %v", res, err)
+	}
+	manifest, meta, _ := client.Object("bucket", "big.mpu-manifest")
+	if len(manifest) != 0 || !crypto.IsShredded(meta) {
+		t.Errorf("manifest companion = %d bytes %v", len(manifest), meta)
+	}
+	if data, _, ok := client.Object("bucket", "big"); !ok || len(data) != int(size) {
+		t.Error("multipart ciphertext was not kept")
+	}
+	if status, body := getStatus(t, srv, "/bucket/big", ""); status != http.StatusForbidden || !strings.Contains(body, "InvalidObjectState") {
+		t.Errorf("GET after shred = %d %s", status, body)
+	}
+}
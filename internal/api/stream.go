@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+)
+
+// streamFlushInterval is how long written bytes may sit in the server's
+// response buffer before streamResponse pushes them to the client.
+const streamFlushInterval = 100 * time.Millisecond
+
+// streamResponse copies src to the client as it is produced: each block
+// read (a decrypted chunk, for chunked objects) is written straight to w,
+// and the response is flushed at least every streamFlushInterval so the
+// client sees bytes while the rest of the object is still being fetched and
+// decrypted.
+//
+// The copy stops with ctx's error once ctx is done, which for r.Context()
+// means the client has gone away, so no further chunks are fetched or
+// decrypted for a reader nobody is listening to. When timeout > 0 the write
+// deadline is pushed out before any write that comes more than timeout/2
+// after the last extension, so a fixed Server.WriteTimeout does not cut off
+// a long stream.
+func streamResponse(ctx context.Context, w http.ResponseWriter, src io.Reader, timeout time.Duration) (int64, error) {
+	rc := http.NewResponseController(w)
+	extendDeadline := timeout > 0 && rc.SetWriteDeadline(time.Now().Add(timeout)) == nil
+
+	pool := crypto.GetGlobalBufferPool()
+	buf := pool.Get64K()
+	defer pool.Put64K(buf)
+
+	var written int64
+	var lastFlush time.Time // zero: the first block goes out at once
+	lastDeadline := time.Now()
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		n, readErr := src.Read(buf)
+		if n > 0 {
+			now := time.Now()
+			if extendDeadline && now.Sub(lastDeadline) >= timeout/2 {
+				rc.SetWriteDeadline(now.Add(timeout))
+				lastDeadline = now
+			}
+			m, err := w.Write(buf[:n])
+			written += int64(m)
+			if err != nil {
+				return written, err
+			}
+			if now.Sub(lastFlush) >= streamFlushInterval {
+				// Writers that cannot flush are left to buffer as before.
+				_ = rc.Flush()
+				lastFlush = now
+			}
+		}
+		if readErr == io.EOF {
+			return written, nil
+		}
+		if readErr != nil {
+			return written, readErr
+		}
+	}
+}
+
+// serveStreamedRange answers a range GET from the full plaintext of an
+// object whose size, total, is known: bytes before the range are read and
+// discarded, and the range itself is streamed to the client, so the
+// plaintext is never held in memory. It serves ranges that could not be
+// mapped onto whole chunks, such as those of single-AEAD objects.
+func (h *Handler) serveStreamedRange(w http.ResponseWriter, r *http.Request, bucket, key string, versionID *string, decMetadata map[string]string, src io.Reader, rangeHeader string, total int64, start time.Time) {
+	log := h.logger.WithFields(logrus.Fields{"bucket": bucket, "key": key})
+	// ParseHTTPRangeHeader treats a zero size as unknown; an empty object
+	// has no satisfiable range.
+	first, last, err := crypto.ParseHTTPRangeHeader(rangeHeader, total)
+	if err != nil || total == 0 {
+		h.writeRangeNotSatisfiable(w, r, total, start)
+		return
+	}
+
+	// Errors up to the first byte of the range can still be reported.
+	if _, err := io.CopyN(io.Discard, src, first); err != nil {
+		s3Err := &S3Error{
+			Code:       "InternalError",
+			Message:    "Failed to read decrypted data",
+			Resource:   r.URL.Path,
+			HTTPStatus: http.StatusInternalServerError,
+		}
+		if errors.Is(err, errDecryptedTooLarge) {
+			s3Err = h.decryptedTooLargeError(r.URL.Path)
+		} else {
+			log.WithError(err).Error("Failed to read decrypted data")
+		}
+		s3Err.WriteXML(w)
+		h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return
+	}
+
+	for k, v := range decMetadata {
+		if !isEncryptionMetadata(k) {
+			w.Header().Set(k, v)
+		}
+	}
+	if versionID != nil && *versionID != "" {
+		w.Header().Set("x-amz-version-id", *versionID)
+	}
+	length := last - first + 1
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", first, last, total))
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", length))
+	w.WriteHeader(http.StatusPartialContent)
+
+	var writeTimeout time.Duration
+	if h.config != nil {
+		writeTimeout = h.config.Server.WriteTimeout
+	}
+	n, err := streamResponse(r.Context(), w, io.LimitReader(src, length), writeTimeout)
+	switch {
+	case err != nil && isNetworkError(err):
+		log.WithError(err).Warn("Range stream aborted by network error after 206")
+	case err != nil:
+		log.WithError(err).Error("Failed to stream range; connection terminated")
+	case n < length:
+		log.WithField("written", n).Error("Plaintext ended before the end of the range; connection terminated")
+	}
+	h.metrics.RecordS3Operation(r.Context(), "GetObject", bucket, time.Since(start))
+	h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, http.StatusPartialContent, time.Since(start), n)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestStreamResponse_ClientSeesBytesBeforeEnd checks that the first block
+// reaches the client while the source is still producing: the source does
+// not hand over its second block until the client has read the first.
+func TestStreamResponse_ClientSeesBytesBeforeEnd(t *testing.T) {
+	first := bytes.Repeat([]byte("a"), 100)
+	second := bytes.Repeat([]byte("b"), 100)
+	clientGotFirst := make(chan struct{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.Write(first)
+			select {
+			case <-clientGotFirst:
+				pw.Write(second)
+				pw.Close()
+			case <-time.After(5 * time.Second):
+				pw.CloseWithError(errors.New("client never saw the first block"))
+			}
+		}()
+		w.WriteHeader(http.StatusOK)
+		if _, err := streamResponse(r.Context(), w, pr, 0); err != nil {
+			t.Errorf("streamResponse: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	got := make([]byte, len(first))
+	if _, err := io.ReadFull(resp.Body, got); err != nil {
+		t.Fatalf("reading first block: %v", err)
+	}
+	close(clientGotFirst)
+	rest, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(append(got, rest...), append(first, second...)) {
+		t.Errorf("body = %q", append(got, rest...))
+	}
+}
+
+// endlessReader yields blocks forever and counts how many were read.
+type endlessReader struct{ reads atomic.Int64 }
+
+func (e *endlessReader) Read(p []byte) (int, error) {
+	e.reads.Add(1)
+	return len(p), nil
+}
+
+// cancelOnWrite cancels the request context on its first write, as a
+// client disconnect does.
+type cancelOnWrite struct {
+	*httptest.ResponseRecorder
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnWrite) Write(p []byte) (int, error) {
+	c.cancel()
+	return c.ResponseRecorder.Write(p)
+}
+
+func TestStreamResponse_StopsWhenClientGoes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &cancelOnWrite{ResponseRecorder: httptest.NewRecorder(), cancel: cancel}
+	src := &endlessReader{}
+
+	n, err := streamResponse(ctx, w, src, time.Minute)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if !isNetworkError(err) {
+		t.Error("a cancelled request should be treated as a client-side error")
+	}
+	if reads := src.reads.Load(); reads != 1 {
+		t.Errorf("source read %d times after the client went away, want 1", reads)
+	}
+	if n != int64(w.Body.Len()) || n == 0 {
+		t.Errorf("written = %d, recorder holds %d", n, w.Body.Len())
+	}
+}
@@ -0,0 +1,246 @@
+package api
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// maxObjectTags is the most tags S3 accepts on one object.
+const maxObjectTags = 10
+
+// validateTagSet applies the rules validateTags enforces on x-amz-tagging to
+// the tag set of a PutObjectTagging request.
+func validateTagSet(tags []s3.Tag) error {
+	if len(tags) > maxObjectTags {
+		return fmt.Errorf("too many tags: max %d allowed", maxObjectTags)
+	}
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		if t.Key == "" || len(t.Key) > 128 || !isValidTagChars(t.Key) {
+			return fmt.Errorf("invalid tag key: %q", t.Key)
+		}
+		if len(t.Value) > 256 || !isValidTagChars(t.Value) {
+			return fmt.Errorf("invalid tag value for key %q", t.Key)
+		}
+		if seen[t.Key] {
+			return fmt.Errorf("duplicate tag key: %q", t.Key)
+		}
+		seen[t.Key] = true
+	}
+	return nil
+}
+
+// tagEncryption reports whether client tag values are sealed before they
+// reach the backend.
+func (h *Handler) tagEncryption() bool {
+	return h.config != nil && h.config.Encryption.TagEncryption && h.metaSealer != nil
+}
+
+// gatewayTag reports whether key is a tag the gateway itself sets, which
+// stays readable so backend lifecycle rules can act on it.
+func (h *Handler) gatewayTag(key string) bool {
+	return key != "" && (key == h.inspectTagKey || key == h.scanCfg.QuarantineTag)
+}
+
+// sealTagging seals the values of an x-amz-tagging header when tag
+// encryption is on.
+func (h *Handler) sealTagging(tagging string) (string, error) {
+	if tagging == "" || !h.tagEncryption() {
+		return tagging, nil
+	}
+	q, err := url.ParseQuery(tagging)
+	if err != nil {
+		return "", err
+	}
+	out := url.Values{}
+	for k, vs := range q {
+		for _, v := range vs {
+			if !h.gatewayTag(k) {
+				if v, err = h.metaSealer.SealTag(k, v); err != nil {
+					return "", err
+				}
+			}
+			out.Add(k, v)
+		}
+	}
+	return out.Encode(), nil
+}
+
+// writeTaggingError answers a tagging request with err and records it.
+func (h *Handler) writeTaggingError(w http.ResponseWriter, r *http.Request, operation, bucket, key string, err error, start time.Time) {
+	s3Err := TranslateError(err, bucket, key)
+	s3Err.WriteXML(w)
+	if s3Err.HTTPStatus >= http.StatusInternalServerError {
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"bucket": bucket,
+			"key":    key,
+		}).Errorf("%s failed", operation)
+	}
+	h.metrics.RecordS3Error(r.Context(), operation, bucket, s3Err.Code)
+	h.metrics.RecordHTTPRequest(r.Context(), r.Method, r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+	if h.auditLogger != nil {
+		h.auditLogger.WithContext(r.Context()).LogAccess(operation, bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), false, err, time.Since(start))
+	}
+}
+
+// handleGetObjectTagging handles GET /{bucket}/{key}?tagging — GetObjectTagging.
+// Sealed tag values are returned decrypted; a value that fails to
+// authenticate is left out and logged, as unsealMetadata does.
+func (h *Handler) handleGetObjectTagging(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	key := vars["key"]
+
+	s3Client, err := h.getS3Client(r)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get S3 client")
+		h.writeS3ClientError(w, r, err, "GET", start)
+		return
+	}
+
+	versionID := r.URL.Query().Get("versionId")
+	var vidPtr *string
+	if versionID != "" {
+		vidPtr = &versionID
+	}
+
+	tags, err := s3Client.GetObjectTagging(r.Context(), bucket, key, vidPtr)
+	if err != nil {
+		h.writeTaggingError(w, r, "GetObjectTagging", bucket, key, err, start)
+		return
+	}
+
+	resp := s3.Tagging{TagSet: make([]s3.Tag, 0, len(tags))}
+	for _, t := range tags {
+		value, err := h.metaSealer.UnsealTag(t.Key, t.Value)
+		if err != nil {
+			h.logger.WithError(err).WithFields(logrus.Fields{
+				"bucket": bucket,
+				"key":    key,
+			}).Warn("Failed to unseal object tag")
+			continue
+		}
+		resp.TagSet = append(resp.TagSet, s3.Tag{Key: t.Key, Value: value})
+	}
+
+	b, _ := xml.Marshal(resp)
+	w.Header().Set("Content-Type", "application/xml")
+	if vidPtr != nil {
+		w.Header().Set("x-amz-version-id", versionID)
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(b)
+	h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, http.StatusOK, time.Since(start), int64(len(b)))
+}
+
+// handlePutObjectTagging handles PUT /{bucket}/{key}?tagging — PutObjectTagging.
+// With encryption.tag_encryption on, tag values other than the gateway's
+// own are sealed first.
+func (h *Handler) handlePutObjectTagging(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	key := vars["key"]
+
+	s3Client, err := h.getS3Client(r)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get S3 client")
+		h.writeS3ClientError(w, r, err, "PUT", start)
+		return
+	}
+
+	body, errBody := readLimitedBody(r)
+	if errBody != nil {
+		errBody.WriteXML(w)
+		return
+	}
+	var req s3.Tagging
+	if errXML := decodeStrictXML(body, &req, r.URL.Path); errXML != nil {
+		errXML.WriteXML(w)
+		return
+	}
+	if err := validateTagSet(req.TagSet); err != nil {
+		h.logger.WithError(err).Debug("Invalid tag set")
+		s3Err := &S3Error{Code: "InvalidTag", Message: "The tag provided was not a valid tag.", Resource: r.URL.Path, HTTPStatus: http.StatusBadRequest}
+		s3Err.WriteXML(w)
+		h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return
+	}
+
+	if h.tagEncryption() {
+		for i, t := range req.TagSet {
+			if h.gatewayTag(t.Key) {
+				continue
+			}
+			if req.TagSet[i].Value, err = h.metaSealer.SealTag(t.Key, t.Value); err != nil {
+				h.writeTaggingError(w, r, "PutObjectTagging", bucket, key, err, start)
+				return
+			}
+		}
+	}
+
+	versionID := r.URL.Query().Get("versionId")
+	var vidPtr *string
+	if versionID != "" {
+		vidPtr = &versionID
+	}
+
+	if err := s3Client.PutObjectTagging(r.Context(), bucket, key, vidPtr, req.TagSet); err != nil {
+		h.writeTaggingError(w, r, "PutObjectTagging", bucket, key, err, start)
+		return
+	}
+
+	if h.auditLogger != nil {
+		h.auditLogger.WithContext(r.Context()).LogAccess("put_object_tagging", bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), true, nil, time.Since(start))
+	}
+	if vidPtr != nil {
+		w.Header().Set("x-amz-version-id", versionID)
+	}
+	h.metrics.RecordHTTPRequest(r.Context(), "PUT", r.URL.Path, http.StatusOK, time.Since(start), 0)
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleDeleteObjectTagging handles DELETE /{bucket}/{key}?tagging — DeleteObjectTagging.
+func (h *Handler) handleDeleteObjectTagging(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	key := vars["key"]
+
+	s3Client, err := h.getS3Client(r)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get S3 client")
+		h.writeS3ClientError(w, r, err, "DELETE", start)
+		return
+	}
+
+	versionID := r.URL.Query().Get("versionId")
+	var vidPtr *string
+	if versionID != "" {
+		vidPtr = &versionID
+	}
+
+	if err := s3Client.DeleteObjectTagging(r.Context(), bucket, key, vidPtr); err != nil {
+		h.writeTaggingError(w, r, "DeleteObjectTagging", bucket, key, err, start)
+		return
+	}
+
+	if h.auditLogger != nil {
+		h.auditLogger.WithContext(r.Context()).LogAccess("delete_object_tagging", bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), true, nil, time.Since(start))
+	}
+	if vidPtr != nil {
+		w.Header().Set("x-amz-version-id", versionID)
+	}
+	h.metrics.RecordHTTPRequest(r.Context(), "DELETE", r.URL.Path, http.StatusNoContent, time.Since(start), 0)
+	w.WriteHeader(http.StatusNoContent)
+}
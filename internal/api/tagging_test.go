@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func doTagging(t *testing.T, srv *httptest.Server, method, path, body string) (*http.Response, []byte) {
+	t.Helper()
+	req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	return resp, data
+}
+
+func tagMap(t *testing.T, body []byte) map[string]string {
+	t.Helper()
+	var tagging s3.Tagging
+	if err := xml.Unmarshal(body, &tagging); err != nil {
+		t.Fatalf("GetObjectTagging body %q: %v", body, err)
+	}
+	tags := make(map[string]string, len(tagging.TagSet))
+	for _, tag := range tagging.TagSet {
+		tags[tag.Key] = tag.Value
+	}
+	return tags
+}
+
+func TestObjectTagging(t *testing.T) {
+	for _, sealed := range []bool{false, true} {
+		client := testsupport.NewMemoryClient()
+		h, srv := newUpgradeTestServer(t, client, true)
+		h.config.Encryption.TagEncryption = sealed
+		sealer, err := crypto.NewMetadataSealer([]byte("test-password-123456"), 1000, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.WithMetadataSealer(sealer)
+		h.inspectTagKey = "s3eg-dlp"
+
+		req, _ := http.NewRequest("PUT", srv.URL+"/bucket/dir/key", strings.NewReader("tagged object"))
+		req.Header.Set("x-amz-tagging", "project=apollo&owner=alice")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("sealed=%v: PUT = %d", sealed, resp.StatusCode)
+		}
+		stored, _ := url.ParseQuery(client.Tags("bucket", "dir/key"))
+		if got := stored.Get("project") != "apollo"; got != sealed {
+			t.Errorf("sealed=%v: backend holds project=%q", sealed, stored.Get("project"))
+		}
+
+		resp, body := doTagging(t, srv, "GET", "/bucket/dir/key?tagging", "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("sealed=%v: GET tagging = %d: %s", sealed, resp.StatusCode, body)
+		}
+		if tags := tagMap(t, body); tags["project"] != "apollo" || tags["owner"] != "alice" || len(tags) != 2 {
+			t.Errorf("sealed=%v: tags after PUT = %v", sealed, tags)
+		}
+
+		resp, body = doTagging(t, srv, "PUT", "/bucket/dir/key?tagging",
+			`<Tagging><TagSet><Tag><Key>stage</Key><Value>prod</Value></Tag><Tag><Key>s3eg-dlp</Key><Value>pii</Value></Tag></TagSet></Tagging>`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("sealed=%v: PUT tagging = %d: %s", sealed, resp.StatusCode, body)
+		}
+		stored, _ = url.ParseQuery(client.Tags("bucket", "dir/key"))
+		if got := stored.Get("stage") != "prod"; got != sealed {
+			t.Errorf("sealed=%v: backend holds stage=%q", sealed, stored.Get("stage"))
+		}
+		if stored.Get("s3eg-dlp") != "pii" {
+			t.Errorf("sealed=%v: gateway tag stored as %q", sealed, stored.Get("s3eg-dlp"))
+		}
+		_, body = doTagging(t, srv, "GET", "/bucket/dir/key?tagging", "")
+		if tags := tagMap(t, body); tags["stage"] != "prod" || tags["s3eg-dlp"] != "pii" || len(tags) != 2 {
+			t.Errorf("sealed=%v: tags after PutObjectTagging = %v", sealed, tags)
+		}
+
+		if resp, _ = doTagging(t, srv, "DELETE", "/bucket/dir/key?tagging", ""); resp.StatusCode != http.StatusNoContent {
+			t.Errorf("sealed=%v: DELETE tagging = %d", sealed, resp.StatusCode)
+		}
+		_, body = doTagging(t, srv, "GET", "/bucket/dir/key?tagging", "")
+		if tags := tagMap(t, body); len(tags) != 0 {
+			t.Errorf("sealed=%v: tags after DeleteObjectTagging = %v", sealed, tags)
+		}
+	}
+}
+
+func TestPutObjectTagging_Errors(t *testing.T) {
+	client := testsupport.NewMemoryClient()
+	_, srv := newUpgradeTestServer(t, client, true)
+	putObject(t, srv, "/bucket/dir/key", []byte("tagged object"))
+
+	tests := []struct {
+		name, path, body string
+		want             int
+		code             string
+	}{
+		{"malformed", "/bucket/dir/key?tagging", "<Tagging><TagSet>", http.StatusBadRequest, "MalformedXML"},
+		{"bad value", "/bucket/dir/key?tagging", "<Tagging><TagSet><Tag><Key>k</Key><Value>a b</Value></Tag></TagSet></Tagging>", http.StatusBadRequest, "InvalidTag"},
+		{"duplicate key", "/bucket/dir/key?tagging", "<Tagging><TagSet><Tag><Key>k</Key><Value>1</Value></Tag><Tag><Key>k</Key><Value>2</Value></Tag></TagSet></Tagging>", http.StatusBadRequest, "InvalidTag"},
+		{"missing object", "/bucket/other?tagging", "<Tagging><TagSet/></Tagging>", http.StatusNotFound, "NoSuchKey"},
+	}
+	for _, tt := range tests {
+		resp, body := doTagging(t, srv, "PUT", tt.path, tt.body)
+		if resp.StatusCode != tt.want || !strings.Contains(string(body), "<Code>"+tt.code+"</Code>") {
+			t.Errorf("%s: PUT tagging = %d %s, want %d %s", tt.name, resp.StatusCode, body, tt.want, tt.code)
+		}
+	}
+}
+
+func TestBucketTagging_Passthrough(t *testing.T) {
+	var got []string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer backend.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	cfg := &config.Config{Backend: config.BackendConfig{Endpoint: backend.URL}}
+	h := NewHandlerWithFeatures(testsupport.NewMemoryClient(), engine, logger, getTestMetrics(), nil, nil, nil, cfg, nil)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+
+	for _, method := range []string{"GET", "PUT", "DELETE"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/bucket?tagging", nil))
+		if w.Code != http.StatusNoContent {
+			t.Errorf("%s /bucket?tagging = %d", method, w.Code)
+		}
+	}
+	want := []string{"GET /bucket?tagging", "PUT /bucket?tagging", "DELETE /bucket?tagging"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("backend saw %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/tiering"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func TestTiering_MovedObjectReadsBackThroughGateway(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, _ := crypto.NewEngineWithOpts([]byte("test-password-123456"), nil, crypto.WithChunking(true))
+	primary, secondary := testsupport.NewMemoryClient(), testsupport.NewMemoryClient()
+	h := NewHandlerWithFeatures(tiering.NewClient(primary, secondary), engine, logger, getTestMetrics(), nil, nil, nil, &config.Config{}, nil)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	plaintext := bytes.Repeat([]byte("tiered object "), 10000)
+	putObject(t, srv, "/bucket/archive/a", plaintext)
+
+	w := tiering.NewWorker(primary, secondary, config.TieringConfig{
+		Enabled:  true,
+		Interval: time.Hour,
+		Rules:    []config.TieringRule{{Bucket: "bucket", Prefix: "archive/", Secondary: true, SecondaryBucket: "cold"}},
+	}, nil, nil, nil)
+	if res, err := w.Run(context.Background()); err != nil || res.Transitioned != 1 {
+		t.Fatalf("Run = %+v, %v", res, err)
+	}
+	if _, meta, _ := primary.Object("bucket", "archive/a"); meta[tiering.MetaLocation] != "cold" {
+		t.Fatalf("expected a stub on the primary backend, got %v", meta)
+	}
+
+	do := func(method, rng string) (*http.Response, []byte) {
+		req, _ := http.NewRequest(method, srv.URL+"/bucket/archive/a", nil)
+		if rng != "" {
+			req.Header.Set("Range", rng)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, body
+	}
+
+	resp, body := do("GET", "")
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, plaintext) {
+		t.Fatalf("GET = %d, %d bytes", resp.StatusCode, len(body))
+	}
+	for k := range resp.Header {
+		if http.CanonicalHeaderKey(k) == http.CanonicalHeaderKey(tiering.MetaLocation) {
+			t.Errorf("tiering metadata leaked: %s", k)
+		}
+	}
+	resp, body = do("GET", "bytes=100-199")
+	if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, plaintext[100:200]) {
+		t.Errorf("ranged GET = %d, %q", resp.StatusCode, body)
+	}
+	resp, _ = do("HEAD", "")
+	if resp.StatusCode != http.StatusOK || resp.ContentLength != int64(len(plaintext)) {
+		t.Errorf("HEAD = %d, length %d", resp.StatusCode, resp.ContentLength)
+	}
+}
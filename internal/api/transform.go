@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/transform"
+	"github.com/sirupsen/logrus"
+)
+
+// WithTransformer enables response transformation of GetObject.
+func (h *Handler) WithTransformer(t *transform.Transformer) {
+	h.transformer = t
+}
+
+// serveTransformed streams a decrypted object through rule's service and
+// writes the service's output as the response. Object metadata is kept
+// except for the headers describing the stored bytes, which no longer apply.
+func (h *Handler) serveTransformed(w http.ResponseWriter, r *http.Request, rule *transform.Rule, bucket, key string, versionID *string, headers map[string]string, body io.Reader, start time.Time) {
+	log := h.logger.WithFields(logrus.Fields{
+		"bucket": bucket,
+		"key":    key,
+		"rule":   rule.Name,
+	})
+	res, err := h.transformer.Apply(r.Context(), rule, transform.Request{
+		Bucket:      bucket,
+		Key:         key,
+		ContentType: headers["Content-Type"],
+	}, body)
+	if err != nil {
+		log.WithError(err).Error("Response transformation failed")
+		h.metrics.RecordTransform(rule.Name, "failed")
+		s3Err := &S3Error{
+			Code:       "InternalError",
+			Message:    "Response transformation failed",
+			Resource:   r.URL.Path,
+			HTTPStatus: http.StatusBadGateway,
+		}
+		s3Err.WriteXML(w)
+		h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
+		return
+	}
+	defer res.Body.Close()
+
+	for k, v := range headers {
+		if isEncryptionMetadata(k) || k == crypto.MetaMPUEncrypted || k == crypto.MetaFallbackMode || k == crypto.MetaFallbackPointer {
+			continue
+		}
+		switch strings.ToLower(k) {
+		case "content-length", "content-range", "etag", "content-md5":
+			continue
+		}
+		w.Header().Set(k, v)
+	}
+	if res.ContentType != "" {
+		w.Header().Set("Content-Type", res.ContentType)
+	}
+	if versionID != nil && *versionID != "" {
+		w.Header().Set("x-amz-version-id", *versionID)
+	}
+	var out io.Reader = res.Body
+	ir := h.integrityReader(r, out)
+	if ir != nil {
+		ir.declare(w)
+		out = ir
+	}
+	w.WriteHeader(http.StatusOK)
+
+	var writeTimeout time.Duration
+	if h.config != nil {
+		writeTimeout = h.config.Server.WriteTimeout
+	}
+	n64, err := streamResponse(r.Context(), w, out, writeTimeout)
+	if ir != nil {
+		ir.finish(w, err)
+	}
+	if err != nil {
+		// Headers are out; the client sees a truncated response.
+		switch {
+		case isNetworkError(err):
+			log.WithError(err).Warn("Transformed stream aborted by network error after 200 OK")
+		case errors.Is(err, transform.ErrOutputTooLarge):
+			log.Error("Transformed response exceeded max_output_size; connection terminated")
+		default:
+			log.WithError(err).Error("Transformation failed mid-stream after 200 OK; connection terminated")
+		}
+		h.metrics.RecordTransform(rule.Name, "aborted")
+		h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, http.StatusOK, time.Since(start), n64)
+		return
+	}
+	h.metrics.RecordTransform(rule.Name, "ok")
+	h.metrics.RecordS3Operation(r.Context(), "GetObject", bucket, time.Since(start))
+	h.metrics.RecordHTTPRequest(r.Context(), "GET", r.URL.Path, http.StatusOK, time.Since(start), n64)
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
+	"github.com/kenneth/s3-encryption-gateway/internal/transform"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// upperService upper-cases text and records what it was sent.
+type upperService struct {
+	got    []byte
+	bucket string
+	key    string
+	ctype  string
+}
+
+func (s *upperService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.got, _ = io.ReadAll(r.Body)
+	s.bucket = r.Header.Get("X-S3EG-Bucket")
+	s.key = r.Header.Get("X-S3EG-Key")
+	s.ctype = r.Header.Get("Content-Type")
+	if bytes.Contains(s.got, []byte("fail")) {
+		http.Error(w, "cannot transform", http.StatusUnprocessableEntity)
+		return
+	}
+	w.Header().Set("Content-Type", "text/x-upper")
+	w.Write(bytes.ToUpper(s.got))
+}
+
+func newTransformTestHandler(t *testing.T, maxOutput int64) (*mockS3Client, *mux.Router, *upperService) {
+	t.Helper()
+	svc := &upperService{}
+	srv := httptest.NewServer(svc)
+	t.Cleanup(srv.Close)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	mockClient := newMockS3Client()
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	h := NewHandlerWithFeatures(mockClient, engine, logger, metrics.NewMetricsWithRegistry(prometheus.NewRegistry()), nil, nil, nil, nil, nil)
+	tr, err := transform.New(config.TransformsConfig{
+		Enabled:       true,
+		MaxOutputSize: maxOutput,
+		Rules:         []config.TransformRule{{Name: "upper", Bucket: "b", Prefix: "reports/", URL: srv.URL}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.WithTransformer(tr)
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	return mockClient, router, svc
+}
+
+func TestTransform_MatchingGet(t *testing.T) {
+	mockClient, router, svc := newTransformTestHandler(t, 1<<20)
+	text := map[string]string{"Content-Type": "text/plain"}
+	if w := putBody(router, "/b/reports/q1.txt", "quarterly figures", text); w.Code != http.StatusOK {
+		t.Fatalf("PUT: %d", w.Code)
+	}
+	if bytes.Contains(mockClient.objects["b/reports/q1.txt"], []byte("quarterly")) {
+		t.Fatal("object stored unencrypted")
+	}
+	// The mock backend does not keep the Content-Type of a PUT.
+	mockClient.metadata["b/reports/q1.txt"]["Content-Type"] = "text/plain"
+
+	// A range is ignored: the transformed object has no stable offsets.
+	req := httptest.NewRequest("GET", "/b/reports/q1.txt", nil)
+	req.Header.Set("Range", "bytes=0-3")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET: %d %s", w.Code, w.Body.String())
+	}
+	if w.Body.String() != "QUARTERLY FIGURES" {
+		t.Errorf("body = %q", w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/x-upper" {
+		t.Errorf("Content-Type = %q, want the service's", got)
+	}
+	if w.Header().Get("Content-Length") != "" || w.Header().Get("ETag") != "" {
+		t.Errorf("stored object's length or ETag leaked into the response: %v", w.Header())
+	}
+	for k := range w.Header() {
+		if strings.HasPrefix(strings.ToLower(k), "x-amz-meta-encrypt") {
+			t.Errorf("encryption metadata %s in the response", k)
+		}
+	}
+	if string(svc.got) != "quarterly figures" || svc.bucket != "b" || svc.key != "reports/q1.txt" || svc.ctype != "text/plain" {
+		t.Errorf("service received %q (bucket=%q key=%q type=%q)", svc.got, svc.bucket, svc.key, svc.ctype)
+	}
+}
+
+func TestTransform_OtherKeysUnchanged(t *testing.T) {
+	_, router, svc := newTransformTestHandler(t, 1<<20)
+	putBody(router, "/b/raw/q1.txt", "quarterly figures", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/b/raw/q1.txt", nil))
+	if w.Body.String() != "quarterly figures" {
+		t.Errorf("body = %q", w.Body.String())
+	}
+	if svc.got != nil {
+		t.Error("service called for a key outside the rule")
+	}
+}
+
+func TestTransform_ServiceError(t *testing.T) {
+	_, router, _ := newTransformTestHandler(t, 1<<20)
+	putBody(router, "/b/reports/bad.txt", "please fail", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/b/reports/bad.txt", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("GET: %d, want 502", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "please") {
+		t.Error("error response echoes the plaintext")
+	}
+}
+
+func TestTransform_OutputLimit(t *testing.T) {
+	_, router, _ := newTransformTestHandler(t, 8)
+	putBody(router, "/b/reports/long.txt", "more than eight bytes", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/b/reports/long.txt", nil))
+	if w.Body.Len() > 8 {
+		t.Errorf("response carries %d bytes past max_output_size", w.Body.Len())
+	}
+}
@@ -1,12 +1,16 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/aws/smithy-go"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
 )
 
 // apiErrorStub satisfies smithy.APIError correctly (unlike the shared
@@ -125,6 +129,10 @@ func TestTranslateError_KnownCodes_StillFixedMessages(t *testing.T) {
 		{"NotFound", "The specified key does not exist", 404},
 		{"AccessDenied", "Access Denied", 403},
 		{"InvalidBucketName", "The specified bucket is not valid", 400},
+		{"NoSuchUpload", "The specified upload does not exist", 404},
+		{"EntityTooSmall", "Your proposed upload is smaller than the minimum", 400},
+		{"InvalidPart", "One or more of the specified parts could not be found", 400},
+		{"InvalidPartOrder", "The list of parts was not in ascending order", 400},
 	}
 	for _, tc := range cases {
 		t.Run(tc.code, func(t *testing.T) {
@@ -149,3 +157,49 @@ func TestTranslateError_NilReturnsNil(t *testing.T) {
 		t.Errorf("TranslateError(nil) = %+v, want nil", got)
 	}
 }
+
+// TestTranslateError_TypedErrors checks the central mapping of the typed
+// errors from the crypto and s3 packages.
+func TestTranslateError_TypedErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		code     string
+		status   int
+		label    string
+		fallback string
+	}{
+		{"integrity", fmt.Errorf("decrypt: %w", crypto.ErrIntegrity), "InternalError", 500, "auth_tag_mismatch", ""},
+		{"manifest", fmt.Errorf("load: %w", crypto.ErrManifestCorrupt), "InternalError", 500, "manifest_corrupt", ""},
+		{"unwrap", fmt.Errorf("unwrap: %w", crypto.ErrUnwrapFailed), "InternalError", 500, "kms_unwrap_failed", ""},
+		{"kms outage during unwrap", fmt.Errorf("%w: %w", crypto.ErrUnwrapFailed, crypto.ErrKMSUnavailable), "ServiceUnavailable", 503, "kms_unavailable", ""},
+		{"canceled", fmt.Errorf("read: %w", context.Canceled), "InternalError", 500, "context_canceled", ""},
+		{"kms", fmt.Errorf("unwrap: %w", crypto.ErrKMSUnavailable), "ServiceUnavailable", 503, "kms_unavailable", ""},
+		{"throttled", fmt.Errorf("put: %w", s3.ErrThrottled), "SlowDown", 503, "throttled", ""},
+		{"not found", fmt.Errorf("head: %w", s3.ErrNotFound), "NoSuchKey", 404, "not_found", ""},
+		{"untyped", errors.New(canaryInternalState), "InternalError", 500, "other", "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TranslateError(tt.err, "b", "k")
+			if got.Code != tt.code || got.HTTPStatus != tt.status {
+				t.Errorf("TranslateError() = %s/%d, want %s/%d", got.Code, got.HTTPStatus, tt.code, tt.status)
+			}
+			if strings.Contains(got.Message, canaryInternalState) {
+				t.Errorf("message leaks error text: %q", got.Message)
+			}
+			if l := ErrorType(tt.err, tt.fallback); l != tt.label {
+				t.Errorf("ErrorType() = %q, want %q", l, tt.label)
+			}
+		})
+	}
+}
+
+func TestTranslateCryptoError(t *testing.T) {
+	if e := translateCryptoError(crypto.ErrIntegrity, "/b/k", "Failed to decrypt object"); e.HTTPStatus != 500 || e.Message != "Failed to decrypt object" {
+		t.Errorf("integrity: got %d %q", e.HTTPStatus, e.Message)
+	}
+	if e := translateCryptoError(crypto.ErrKMSUnavailable, "/b/k", "Failed to decrypt object"); e.HTTPStatus != 503 || e.Code != "ServiceUnavailable" {
+		t.Errorf("kms: got %d %s", e.HTTPStatus, e.Code)
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/kenneth/s3-encryption-gateway/internal/trash"
+	"github.com/sirupsen/logrus"
+)
+
+// WithTrash defers deletes on the trash's buckets: objects are copied into
+// the trash before they are removed.
+func (h *Handler) WithTrash(b *trash.Bin) {
+	h.trash = b
+}
+
+// moveToTrash copies bucket/key, still encrypted, to its trash key so the
+// delete that follows can be undone until the trash is purged. An MPU
+// object's manifest companion is copied alongside it. It returns the trash
+// key, or "" when there is no object to keep.
+func (h *Handler) moveToTrash(ctx context.Context, client s3.Client, bucket, key string) (string, error) {
+	meta, err := client.HeadObject(ctx, bucket, key, nil)
+	if err != nil {
+		if errors.Is(err, s3.ErrNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	now := h.trash.Now()
+	trashKey := h.trash.Key(key, now)
+	if _, err := h.copyStored(ctx, client, bucket, key, bucket, trashKey, meta, map[string]string{
+		trash.MetaOriginalKey: key,
+		trash.MetaDeletedAt:   now.UTC().Format(time.RFC3339),
+	}); err != nil {
+		return "", fmt.Errorf("move to trash: %w", err)
+	}
+	h.logger.WithFields(logrus.Fields{
+		"bucket":    bucket,
+		"key":       key,
+		"trash_key": trashKey,
+	}).Debug("Moved deleted object to trash")
+	return trashKey, nil
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/kenneth/s3-encryption-gateway/internal/trash"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func TestDeleteObject_MovesToTrash(t *testing.T) {
+	client := testsupport.NewMemoryClient()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	engine, _ := crypto.NewEngine([]byte("test-password-123456"))
+	h := NewHandlerWithFeatures(client, engine, logger, getTestMetrics(), nil, nil, nil, &config.Config{}, nil)
+	h.WithTrash(trash.New(client, config.TrashConfig{
+		Enabled:   true,
+		Buckets:   []string{"bucket"},
+		Prefix:    ".trash/",
+		Retention: time.Hour,
+		Interval:  time.Hour,
+	}, logger))
+	router := mux.NewRouter()
+	h.RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	send := func(method, path string, body string) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	trashed := func(key string) string {
+		t.Helper()
+		res, err := client.ListObjects(t.Context(), "bucket", ".trash/", s3.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, obj := range res.Objects {
+			if strings.HasSuffix(obj.Key, "/"+key) {
+				return obj.Key
+			}
+		}
+		return ""
+	}
+
+	putObject(t, srv, "/bucket/notes.txt", []byte("cannot be recovered elsewhere"))
+	if code := send("DELETE", "/bucket/notes.txt", ""); code != http.StatusNoContent {
+		t.Fatalf("DELETE = %d", code)
+	}
+	if _, _, ok := client.Object("bucket", "notes.txt"); ok {
+		t.Fatal("object still present after DELETE")
+	}
+	trashKey := trashed("notes.txt")
+	if trashKey == "" {
+		t.Fatal("deleted object not in the trash")
+	}
+	if _, meta, _ := client.Object("bucket", trashKey); meta[trash.MetaOriginalKey] != "notes.txt" {
+		t.Errorf("original key = %q", meta[trash.MetaOriginalKey])
+	}
+	resp, body := getResponse(t, srv, "/bucket/"+trashKey, "")
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, []byte("cannot be recovered elsewhere")) {
+		t.Errorf("GET trashed object = %d %q", resp.StatusCode, body)
+	}
+
+	// Deleting from the trash is final.
+	if code := send("DELETE", "/bucket/"+trashKey, ""); code != http.StatusNoContent {
+		t.Fatalf("DELETE from trash = %d", code)
+	}
+	if trashed("notes.txt") != "" {
+		t.Error("object deleted from the trash was trashed again")
+	}
+
+	putObject(t, srv, "/bucket/a", []byte("a"))
+	putObject(t, srv, "/bucket/b", []byte("b"))
+	batch := `<Delete><Object><Key>a</Key></Object><Object><Key>b</Key></Object></Delete>`
+	if code := send("POST", "/bucket?delete", batch); code != http.StatusOK {
+		t.Fatalf("DeleteObjects = %d", code)
+	}
+	for _, key := range []string{"a", "b"} {
+		if _, _, ok := client.Object("bucket", key); ok {
+			t.Errorf("%s still present after DeleteObjects", key)
+		}
+		if trashed(key) == "" {
+			t.Errorf("%s not in the trash after DeleteObjects", key)
+		}
+	}
+}
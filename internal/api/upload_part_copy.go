@@ -148,6 +148,11 @@ func (h *Handler) handleUploadPartCopy(w http.ResponseWriter, r *http.Request) {
 		h.writeS3ClientError(w, r, err, "PUT", start)
 		return
 	}
+	// The part's size is only known once copied, so only a quota that is
+	// already used up refuses it.
+	if !h.checkQuota(w, r, bucket, -1, start) {
+		return
+	}
 
 	// Parse x-amz-copy-source header.
 	copySource := r.Header.Get("x-amz-copy-source")
@@ -267,7 +272,7 @@ func (h *Handler) handleUploadPartCopy(w http.ResponseWriter, r *http.Request) {
 		s3Err.WriteXML(w)
 		// Audit the refusal so operators see config-drift incidents.
 		if h.auditLogger != nil {
-			h.auditLogger.LogAccessWithMetadata(
+			h.auditLogger.WithContext(r.Context()).LogAccessWithMetadata(
 				"copy_part_refused", bucket, key,
 				getClientIP(r), r.UserAgent(), getRequestID(r),
 				false, fmt.Errorf("%s", msg), time.Since(start),
@@ -403,7 +408,7 @@ func (h *Handler) handleUploadPartCopy(w http.ResponseWriter, r *http.Request) {
 		if srcRange != nil {
 			auditMetadata["src_range"] = map[string]int64{"first": srcRange.First, "last": srcRange.Last}
 		}
-		h.auditLogger.LogAccessWithMetadata(
+		h.auditLogger.WithContext(r.Context()).LogAccessWithMetadata(
 			"copy_part", bucket, key,
 			getClientIP(r), r.UserAgent(), getRequestID(r),
 			true, nil, time.Since(start), auditMetadata,
@@ -416,6 +421,7 @@ func (h *Handler) handleUploadPartCopy(w http.ResponseWriter, r *http.Request) {
 		LastModified: copyResult.LastModified.UTC().Format("2006-01-02T15:04:05.000Z"),
 	}
 
+	h.recordQuota(w, bucket, bytesCopied, 0)
 	w.Header().Set("Content-Type", "application/xml")
 	w.WriteHeader(http.StatusOK)
 	_ = xml.NewEncoder(w).Encode(result)
@@ -864,7 +870,7 @@ func (h *Handler) uploadPartCopyReencryptMPU(
 		}).Error("mpu.append_part.failure: backend part written but state not recorded; returning 503 so client retries")
 		h.metrics.RecordS3Error(ctx, "AppendMPUPartState", dstBucket, "StateUnavailable")
 		if h.auditLogger != nil {
-			h.auditLogger.Log(&audit.AuditEvent{
+			h.auditLogger.WithContext(ctx).Log(&audit.AuditEvent{
 				EventType: audit.EventTypeMPUValkeyUnavail,
 				Timestamp: time.Now().UTC(),
 				Bucket:    dstBucket,
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"bytes"
 	"crypto/sha256"
 	"crypto/tls"
 	"encoding/hex"
@@ -10,11 +9,17 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 
 	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/kenneth/s3-encryption-gateway/internal/airgap"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/middleware"
+	"github.com/kenneth/s3-encryption-gateway/internal/storage"
 	"github.com/kenneth/s3-encryption-gateway/internal/util"
 )
 
@@ -40,15 +45,13 @@ func getClientIP(r *http.Request) string {
 	return util.ExtractIP(r.RemoteAddr)
 }
 
-// getRequestID extracts or generates a request ID from the request.
+// getRequestID returns the client's X-Request-ID, or else the ID the
+// gateway assigned the request.
 func getRequestID(r *http.Request) string {
-	// Check for existing request ID header
 	if rid := r.Header.Get("X-Request-ID"); rid != "" {
 		return rid
 	}
-
-	// Could generate a new one, but for now return empty if not present
-	return ""
+	return middleware.RequestIDFromContext(r.Context())
 }
 
 // validateTags validates the x-amz-tagging header value.
@@ -91,6 +94,17 @@ func validateTags(tagging string) error {
 	return nil
 }
 
+// setTag returns tagging (an x-amz-tagging value) with key set to value,
+// replacing any tag the client sent under the same key.
+func setTag(tagging, key, value string) string {
+	q, err := url.ParseQuery(tagging)
+	if err != nil {
+		q = url.Values{}
+	}
+	q.Set(key, value)
+	return q.Encode()
+}
+
 // isValidTagChars checks if the string contains only allowed characters for S3 tags.
 // Allowed: a-z, A-Z, 0-9, + - = . _ : /
 func isValidTagChars(s string) bool {
@@ -106,6 +120,10 @@ func isValidTagChars(s string) bool {
 // ErrBackendNotConfigured is returned when the backend endpoint has not been configured.
 var ErrBackendNotConfigured = errors.New("backend not configured")
 
+// ErrMissingContentLength is returned when an aws-chunked body does not carry
+// X-Amz-Decoded-Content-Length, so its decoded size cannot be sent upstream.
+var ErrMissingContentLength = errors.New("missing decoded content length")
+
 // hopByHopHeaders lists HTTP headers that must not be forwarded by a proxy.
 var hopByHopHeaders = []string{
 	"Connection",
@@ -134,14 +152,144 @@ func copyProxyResponse(w http.ResponseWriter, resp *http.Response) {
 	io.Copy(w, resp.Body)
 }
 
+// clientAuthHeaders lists the SigV4 request headers that belong to the
+// client's signature. They are meaningless (and usually invalid) against the
+// backend, so they are stripped before the request is re-signed.
+var clientAuthHeaders = []string{
+	"Authorization",
+	"X-Amz-Date",
+	"X-Amz-Security-Token",
+	"X-Amz-Content-Sha256",
+}
+
+// awsChunkedHeaders describe the aws-chunked framing of the client body,
+// including its trailing checksum. The body is decoded and the trailer
+// dropped before forwarding, so the backend must not be told to expect them.
+var awsChunkedHeaders = []string{
+	"X-Amz-Decoded-Content-Length",
+	"X-Amz-Trailer",
+	"X-Amz-Sdk-Checksum-Algorithm",
+}
+
+// presignQueryParams lists the query parameters carried by a presigned URL.
+var presignQueryParams = []string{
+	"X-Amz-Algorithm",
+	"X-Amz-Credential",
+	"X-Amz-Date",
+	"X-Amz-Expires",
+	"X-Amz-SignedHeaders",
+	"X-Amz-Signature",
+	"X-Amz-Security-Token",
+}
+
+// emptyPayloadHash is the hex SHA-256 of an empty body.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// unsignedPayload is the SigV4 payload hash sentinel for bodies that are
+// streamed without being hashed up front.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// backendPayload prepares the client body for forwarding and returns the
+// reader, its length (-1 when unknown) and the payload hash to sign with.
+// aws-chunked bodies without a valid X-Amz-Decoded-Content-Length are refused
+// with ErrMissingContentLength rather than forwarded with chunked encoding.
+//
+// The body is never buffered for signing:
+//   - aws-chunked bodies (STREAMING-*) are decoded on the fly, since the
+//     per-chunk signatures are bound to the client's signing key;
+//   - a literal hex SHA-256 supplied by the client is reused as-is, because
+//     the forwarded bytes are identical and the backend re-verifies them;
+//   - anything else is sent as UNSIGNED-PAYLOAD.
+func backendPayload(r *http.Request) (io.Reader, int64, string, error) {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return nil, 0, emptyPayloadHash, nil
+	}
+
+	contentSha256 := r.Header.Get("X-Amz-Content-Sha256")
+	if strings.HasPrefix(contentSha256, "STREAMING-") {
+		n, err := strconv.ParseInt(r.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+		if err != nil || n < 0 {
+			return nil, 0, "", ErrMissingContentLength
+		}
+		return NewAwsChunkedReader(r.Body), n, unsignedPayload, nil
+	}
+
+	if isHexSHA256(contentSha256) {
+		return r.Body, r.ContentLength, strings.ToLower(contentSha256), nil
+	}
+	return r.Body, r.ContentLength, unsignedPayload, nil
+}
+
+// isHexSHA256 reports whether s is a 64-character hex string.
+func isHexSHA256(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// stripClientAuth removes the client's SigV4 material (headers, presigned
+// query parameters and aws-chunked framing headers) from proxyReq so it can
+// be re-signed with the backend credentials.
+func stripClientAuth(proxyReq *http.Request) {
+	for _, hdr := range clientAuthHeaders {
+		proxyReq.Header.Del(hdr)
+	}
+	for _, hdr := range awsChunkedHeaders {
+		proxyReq.Header.Del(hdr)
+	}
+	if ce := proxyReq.Header.Get("Content-Encoding"); ce != "" {
+		var kept []string
+		for _, enc := range strings.Split(ce, ",") {
+			enc = strings.TrimSpace(enc)
+			if enc != "" && !strings.EqualFold(enc, "aws-chunked") {
+				kept = append(kept, enc)
+			}
+		}
+		if len(kept) == 0 {
+			proxyReq.Header.Del("Content-Encoding")
+		} else {
+			proxyReq.Header.Set("Content-Encoding", strings.Join(kept, ","))
+		}
+	}
+
+	q := proxyReq.URL.Query()
+	stripped := false
+	for _, p := range presignQueryParams {
+		if q.Has(p) {
+			q.Del(p)
+			stripped = true
+		}
+	}
+	if stripped {
+		proxyReq.URL.RawQuery = q.Encode()
+	}
+}
+
 // forwardToBackend creates and sends a request to the configured S3 backend.
 // It builds the backend URL from h.config.Backend.Endpoint, preserves the
 // original path and query, copies all headers (replacing Host with the backend
-// hostname), and sets Content-Length if present. A minimal http.Client with
-// TLS 1.2 minimum is used. The raw *http.Response is returned directly without
-// writing to the ResponseWriter.
+// hostname), and streams the body through without buffering it.
+//
+// When backend credentials are configured the client's signature is stripped
+// and the request is re-signed for the backend (see backendPayload for how
+// the payload hash is chosen). Without backend credentials the request is
+// forwarded with the client's own signature untouched. A minimal http.Client
+// with TLS 1.2 minimum is used. The raw *http.Response is returned directly
+// without writing to the ResponseWriter.
+//
+// Backends that do not speak the S3 API (filesystem, Azure) have nothing to
+// forward to; their requests fail with storage.ErrNotSupported.
 func (h *Handler) forwardToBackend(r *http.Request) (*http.Response, error) {
-	if h.config == nil || h.config.Backend.Endpoint == "" {
+	if h.config == nil {
+		return nil, ErrBackendNotConfigured
+	}
+	switch t := h.config.Backend.StorageType(); t {
+	case config.BackendTypeFilesystem, config.BackendTypeAzure:
+		return nil, fmt.Errorf("%s backend: %s %s: %w", t, r.Method, r.URL.Path, storage.ErrNotSupported)
+	}
+	if h.config.Backend.Endpoint == "" {
 		return nil, ErrBackendNotConfigured
 	}
 
@@ -159,30 +307,35 @@ func (h *Handler) forwardToBackend(r *http.Request) (*http.Response, error) {
 	u.Path = r.URL.Path
 	u.RawQuery = r.URL.RawQuery
 
-	var bodyBytes []byte
-	if r.Body != nil {
+	resign := h.config.Backend.AccessKey != ""
+
+	var (
+		body        io.Reader = r.Body
+		length                = r.ContentLength
+		payloadHash           = ""
+	)
+	if resign {
 		var err error
-		bodyBytes, err = io.ReadAll(r.Body)
+		body, length, payloadHash, err = backendPayload(r)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read request body: %w", err)
+			return nil, err
 		}
-		r.Body.Close()
+	}
+	if body == nil || body == http.NoBody || length == 0 {
+		body = http.NoBody
 	}
 
-	proxyReq, err := http.NewRequestWithContext(r.Context(), r.Method, u.String(), bytes.NewReader(bodyBytes))
+	proxyReq, err := http.NewRequestWithContext(r.Context(), r.Method, u.String(), body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create proxy request: %w", err)
 	}
 	proxyReq.Header = r.Header.Clone()
 	proxyReq.Host = u.Host
 	proxyReq.Header.Set("Host", u.Host)
-	if len(bodyBytes) > 0 {
-		proxyReq.ContentLength = int64(len(bodyBytes))
-	}
+	proxyReq.ContentLength = length
 
-	if proxyReq.Header.Get("Authorization") == "" && h.config.Backend.AccessKey != "" {
-		bodyHash := sha256.Sum256(bodyBytes)
-		payloadHash := hex.EncodeToString(bodyHash[:])
+	if resign {
+		stripClientAuth(proxyReq)
 
 		credsProvider := credentials.NewStaticCredentialsProvider(h.config.Backend.AccessKey, h.config.Backend.SecretKey, "")
 		credsVal, err := credsProvider.Retrieve(r.Context())
@@ -201,6 +354,7 @@ func (h *Handler) forwardToBackend(r *http.Request) (*http.Response, error) {
 	}
 
 	transport := &http.Transport{
+		DialContext: airgap.DialContext,
 		TLSClientConfig: &tls.Config{
 			MinVersion: tls.VersionTLS12,
 		},
@@ -233,6 +387,15 @@ func (h *Handler) handlePassthrough(w http.ResponseWriter, r *http.Request, oper
 				Resource:   r.URL.Path,
 				HTTPStatus: http.StatusInternalServerError,
 			}
+		} else if errors.Is(err, storage.ErrNotSupported) {
+			s3Err = classOf(err).s3Error(r.URL.Path, "")
+		} else if errors.Is(err, ErrMissingContentLength) {
+			s3Err = &S3Error{
+				Code:       "MissingContentLength",
+				Message:    "You must provide the Content-Length HTTP header.",
+				Resource:   r.URL.Path,
+				HTTPStatus: http.StatusLengthRequired,
+			}
 		} else {
 			s3Err = &S3Error{
 				Code:       "BadGateway",
@@ -244,7 +407,7 @@ func (h *Handler) handlePassthrough(w http.ResponseWriter, r *http.Request, oper
 		s3Err.WriteXML(w)
 		h.metrics.RecordHTTPRequest(r.Context(), r.Method, r.URL.Path, s3Err.HTTPStatus, time.Since(start), 0)
 		if h.auditLogger != nil {
-			h.auditLogger.LogAccess(operation, bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), false, err, time.Since(start))
+			h.auditLogger.WithContext(r.Context()).LogAccess(operation, bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), false, err, time.Since(start))
 		}
 		return
 	}
@@ -253,6 +416,6 @@ func (h *Handler) handlePassthrough(w http.ResponseWriter, r *http.Request, oper
 	copyProxyResponse(w, resp)
 	h.metrics.RecordHTTPRequest(r.Context(), r.Method, r.URL.Path, resp.StatusCode, time.Since(start), resp.ContentLength)
 	if h.auditLogger != nil {
-		h.auditLogger.LogAccess(operation, bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), true, nil, time.Since(start))
+		h.auditLogger.WithContext(r.Context()).LogAccess(operation, bucket, key, getClientIP(r), r.UserAgent(), getRequestID(r), true, nil, time.Since(start))
 	}
 }
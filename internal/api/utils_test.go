@@ -2,9 +2,11 @@ package api
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 
@@ -151,3 +153,188 @@ func TestHandlePassthrough_BackendNotConfigured(t *testing.T) {
 		t.Errorf("expected InternalError in response, got: %s", body)
 	}
 }
+
+func TestHandlePassthrough_NonS3Backend(t *testing.T) {
+	for _, typ := range []string{config.BackendTypeFilesystem, config.BackendTypeAzure} {
+		t.Run(typ, func(t *testing.T) {
+			h := &Handler{
+				config:  &config.Config{Backend: config.BackendConfig{Type: typ}},
+				logger:  logrus.New(),
+				metrics: getTestMetrics(),
+			}
+
+			req := httptest.NewRequest("GET", "/", nil)
+			w := httptest.NewRecorder()
+
+			h.handlePassthrough(w, req, "ListBuckets", "", "")
+
+			if w.Code != http.StatusNotImplemented {
+				t.Errorf("expected status %d, got %d", http.StatusNotImplemented, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), "<Code>NotImplemented</Code>") {
+				t.Errorf("expected NotImplemented in response, got: %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestForwardToBackend_ResignsStreamingBody(t *testing.T) {
+	payload := strings.Repeat("x", 4096)
+
+	var (
+		gotAuth, gotSha, gotEncoding, gotDecoded, gotQuery string
+		gotTrailer, gotChecksumAlgo                        string
+		gotBody                                            []byte
+		gotLength                                          int64
+	)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotSha = r.Header.Get("X-Amz-Content-Sha256")
+		gotEncoding = r.Header.Get("Content-Encoding")
+		gotDecoded = r.Header.Get("X-Amz-Decoded-Content-Length")
+		gotTrailer = r.Header.Get("X-Amz-Trailer")
+		gotChecksumAlgo = r.Header.Get("X-Amz-Sdk-Checksum-Algorithm")
+		gotQuery = r.URL.RawQuery
+		gotLength = r.ContentLength
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	h := &Handler{
+		config: &config.Config{
+			Backend: config.BackendConfig{
+				Endpoint:  backend.URL,
+				AccessKey: "backend-ak",
+				SecretKey: "backend-sk",
+			},
+		},
+		logger:  logrus.New(),
+		metrics: getTestMetrics(),
+	}
+
+	chunked := fmt.Sprintf("%x;chunk-signature=abc\r\n%s\r\n0;chunk-signature=def\r\nx-amz-checksum-crc32:AAAAAA==\r\n\r\n", len(payload), payload)
+	req := httptest.NewRequest("PUT", "/test-bucket?policy", strings.NewReader(chunked))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=client-ak/20240101/us-east-1/s3/aws4_request, SignedHeaders=host, Signature=deadbeef")
+	req.Header.Set("X-Amz-Content-Sha256", "STREAMING-AWS4-HMAC-SHA256-PAYLOAD-TRAILER")
+	req.Header.Set("X-Amz-Decoded-Content-Length", fmt.Sprint(len(payload)))
+	req.Header.Set("X-Amz-Trailer", "x-amz-checksum-crc32")
+	req.Header.Set("X-Amz-Sdk-Checksum-Algorithm", "CRC32")
+	req.Header.Set("Content-Encoding", "aws-chunked")
+
+	resp, err := h.forwardToBackend(req)
+	if err != nil {
+		t.Fatalf("forwardToBackend: %v", err)
+	}
+	resp.Body.Close()
+
+	if !strings.Contains(gotAuth, "Credential=backend-ak/") {
+		t.Errorf("expected request re-signed with backend credentials, got %q", gotAuth)
+	}
+	if gotSha != unsignedPayload {
+		t.Errorf("expected %s payload hash, got %q", unsignedPayload, gotSha)
+	}
+	if gotEncoding != "" || gotDecoded != "" {
+		t.Errorf("expected aws-chunked framing headers stripped, got encoding=%q decoded=%q", gotEncoding, gotDecoded)
+	}
+	if gotTrailer != "" || gotChecksumAlgo != "" {
+		t.Errorf("expected trailer headers stripped, got trailer=%q checksum-algorithm=%q", gotTrailer, gotChecksumAlgo)
+	}
+	if q, _ := url.ParseQuery(gotQuery); !q.Has("policy") {
+		t.Errorf("expected query preserved, got %q", gotQuery)
+	}
+	if gotLength != int64(len(payload)) {
+		t.Errorf("expected Content-Length %d, got %d", len(payload), gotLength)
+	}
+	if string(gotBody) != payload {
+		t.Errorf("expected decoded body forwarded, got %d bytes", len(gotBody))
+	}
+}
+
+func TestHandlePassthrough_StreamingWithoutDecodedLength(t *testing.T) {
+	var called bool
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	h := &Handler{
+		config: &config.Config{
+			Backend: config.BackendConfig{
+				Endpoint:  backend.URL,
+				AccessKey: "backend-ak",
+				SecretKey: "backend-sk",
+			},
+		},
+		logger:  logrus.New(),
+		metrics: getTestMetrics(),
+	}
+
+	req := httptest.NewRequest("PUT", "/test-bucket?policy", strings.NewReader("4;chunk-signature=abc\r\ntest\r\n0;chunk-signature=def\r\n\r\n"))
+	req.Header.Set("X-Amz-Content-Sha256", "STREAMING-AWS4-HMAC-SHA256-PAYLOAD")
+	req.Header.Set("Content-Encoding", "aws-chunked")
+	w := httptest.NewRecorder()
+
+	h.handlePassthrough(w, req, "PutBucketPolicy", "test-bucket", "")
+
+	if w.Code != http.StatusLengthRequired {
+		t.Errorf("expected status %d, got %d", http.StatusLengthRequired, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "<Code>MissingContentLength</Code>") {
+		t.Errorf("expected MissingContentLength in response, got: %s", w.Body.String())
+	}
+	if called {
+		t.Error("expected request refused before reaching the backend")
+	}
+}
+
+func TestBackendPayload(t *testing.T) {
+	const hash = "9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08"
+
+	tests := []struct {
+		name     string
+		body     string
+		sha      string
+		wantHash string
+	}{
+		{name: "empty body", body: "", wantHash: emptyPayloadHash},
+		{name: "client hash reused", body: "test", sha: hash, wantHash: strings.ToLower(hash)},
+		{name: "unsigned payload", body: "test", sha: "UNSIGNED-PAYLOAD", wantHash: unsignedPayload},
+		{name: "no hash header", body: "test", wantHash: unsignedPayload},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", "/b/k", strings.NewReader(tt.body))
+			if tt.sha != "" {
+				req.Header.Set("X-Amz-Content-Sha256", tt.sha)
+			}
+			_, length, got, err := backendPayload(req)
+			if err != nil {
+				t.Fatalf("backendPayload: %v", err)
+			}
+			if got != tt.wantHash {
+				t.Errorf("hash = %q, want %q", got, tt.wantHash)
+			}
+			if length != int64(len(tt.body)) {
+				t.Errorf("length = %d, want %d", length, len(tt.body))
+			}
+		})
+	}
+}
+
+func TestStripClientAuth_PresignedQuery(t *testing.T) {
+	req := httptest.NewRequest("GET", "/b?acl&X-Amz-Algorithm=AWS4-HMAC-SHA256&X-Amz-Signature=abc&X-Amz-Credential=x", nil)
+	req.Header.Set("X-Amz-Security-Token", "token")
+	stripClientAuth(req)
+
+	if req.URL.Query().Has("X-Amz-Signature") || req.URL.Query().Has("X-Amz-Credential") {
+		t.Errorf("expected presign parameters removed, got %q", req.URL.RawQuery)
+	}
+	if !req.URL.Query().Has("acl") {
+		t.Errorf("expected subresource preserved, got %q", req.URL.RawQuery)
+	}
+	if req.Header.Get("X-Amz-Security-Token") != "" {
+		t.Error("expected security token header removed")
+	}
+}
@@ -0,0 +1,216 @@
+// Package archive implements the export/import format for bucket prefixes:
+// a POSIX tar stream of plaintext objects, optionally zstd-compressed.
+//
+// Each object becomes one regular file named after its key relative to the
+// exported prefix. The Content-Type and user metadata travel in PAX records
+// under the "S3EG." namespace so an import restores them; tools that do not
+// know the namespace simply see ordinary files.
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Format is an archive encoding.
+type Format string
+
+// Supported formats.
+const (
+	FormatTar     Format = "tar"
+	FormatTarZstd Format = "tar.zst"
+)
+
+// PAX record keys.
+const (
+	paxContentType = "S3EG.content-type"
+	paxMetaPrefix  = "S3EG.meta."
+)
+
+// ParseFormat maps a query/flag value onto a Format; "" selects FormatTar.
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "", FormatTar:
+		return FormatTar, nil
+	case FormatTarZstd, "tar.zstd", "zst":
+		return FormatTarZstd, nil
+	}
+	return "", fmt.Errorf("unsupported archive format %q (want tar or tar.zst)", s)
+}
+
+// ContentType returns the MIME type of the encoded archive.
+func (f Format) ContentType() string {
+	if f == FormatTarZstd {
+		return "application/zstd"
+	}
+	return "application/x-tar"
+}
+
+// Entry describes one archived object.
+type Entry struct {
+	Name        string // key relative to the archive prefix
+	Size        int64
+	ModTime     time.Time
+	ContentType string
+	// Metadata holds user metadata keyed by the full header name
+	// ("x-amz-meta-…").
+	Metadata map[string]string
+}
+
+// Stats summarises an export or import.
+type Stats struct {
+	Objects int      `json:"objects"`
+	Bytes   int64    `json:"bytes"`
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// Writer encodes entries into an archive.
+type Writer struct {
+	tw *tar.Writer
+	zw *zstd.Encoder
+}
+
+// NewWriter starts an archive of format f on w.
+func NewWriter(w io.Writer, f Format) (*Writer, error) {
+	aw := &Writer{}
+	if f == FormatTarZstd {
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, fmt.Errorf("archive: zstd: %w", err)
+		}
+		aw.zw = zw
+		w = zw
+	}
+	aw.tw = tar.NewWriter(w)
+	return aw, nil
+}
+
+// WriteEntry appends e with exactly e.Size bytes read from body.
+func (w *Writer) WriteEntry(e Entry, body io.Reader) error {
+	hdr := &tar.Header{
+		Typeflag:   tar.TypeReg,
+		Name:       e.Name,
+		Size:       e.Size,
+		Mode:       0o644,
+		ModTime:    e.ModTime,
+		Format:     tar.FormatPAX,
+		PAXRecords: make(map[string]string, len(e.Metadata)+1),
+	}
+	if e.ContentType != "" {
+		hdr.PAXRecords[paxContentType] = e.ContentType
+	}
+	for k, v := range e.Metadata {
+		hdr.PAXRecords[paxMetaPrefix+strings.TrimPrefix(k, "x-amz-meta-")] = v
+	}
+	if err := w.tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("archive: %s: %w", e.Name, err)
+	}
+	n, err := io.Copy(w.tw, body)
+	if err != nil {
+		return fmt.Errorf("archive: %s: %w", e.Name, err)
+	}
+	if n != e.Size {
+		return fmt.Errorf("archive: %s: wrote %d of %d bytes", e.Name, n, e.Size)
+	}
+	return nil
+}
+
+// Close finishes the archive. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.tw.Close(); err != nil {
+		return err
+	}
+	if w.zw != nil {
+		return w.zw.Close()
+	}
+	return nil
+}
+
+// Reader decodes an archive.
+type Reader struct {
+	tr *tar.Reader
+	zr *zstd.Decoder
+}
+
+// NewReader opens an archive of format f read from r.
+func NewReader(r io.Reader, f Format) (*Reader, error) {
+	ar := &Reader{}
+	if f == FormatTarZstd {
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("archive: zstd: %w", err)
+		}
+		ar.zr = zr
+		r = zr
+	}
+	ar.tr = tar.NewReader(r)
+	return ar, nil
+}
+
+// ErrUnsafeName is returned for entries whose name would escape the import
+// prefix.
+var ErrUnsafeName = errors.New("archive: unsafe entry name")
+
+// Next returns the next regular file and a reader for its contents, valid
+// until the following call. Directories and other non-file entries are
+// skipped. io.EOF marks the end of the archive.
+func (r *Reader) Next() (Entry, io.Reader, error) {
+	for {
+		hdr, err := r.tr.Next()
+		if err != nil {
+			return Entry{}, nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name, err := cleanName(hdr.Name)
+		if err != nil {
+			return Entry{Name: hdr.Name}, nil, err
+		}
+		e := Entry{
+			Name:        name,
+			Size:        hdr.Size,
+			ModTime:     hdr.ModTime,
+			ContentType: hdr.PAXRecords[paxContentType],
+			Metadata:    make(map[string]string),
+		}
+		for k, v := range hdr.PAXRecords {
+			if strings.HasPrefix(k, paxMetaPrefix) {
+				e.Metadata["x-amz-meta-"+strings.TrimPrefix(k, paxMetaPrefix)] = v
+			}
+		}
+		return e, r.tr, nil
+	}
+}
+
+// Close releases decoder resources. It does not close the underlying reader.
+func (r *Reader) Close() {
+	if r.zr != nil {
+		r.zr.Close()
+	}
+}
+
+// cleanName rejects absolute names and ".." segments and strips a leading
+// "./".
+func cleanName(name string) (string, error) {
+	if name == "" || strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("%w: %q", ErrUnsafeName, name)
+	}
+	for _, seg := range strings.Split(name, "/") {
+		if seg == ".." {
+			return "", fmt.Errorf("%w: %q", ErrUnsafeName, name)
+		}
+	}
+	name = strings.TrimPrefix(name, "./")
+	if name == "" || path.Clean(name) == "." {
+		return "", fmt.Errorf("%w: %q", ErrUnsafeName, name)
+	}
+	return name, nil
+}
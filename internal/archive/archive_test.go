@@ -0,0 +1,118 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriterReader_RoundTrip(t *testing.T) {
+	for _, f := range []Format{FormatTar, FormatTarZstd} {
+		t.Run(string(f), func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mod := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+			in := []Entry{
+				{Name: "a.txt", Size: 5, ModTime: mod, ContentType: "text/plain", Metadata: map[string]string{"x-amz-meta-owner": "ops"}},
+				{Name: "dir/b.bin", Size: 0, ModTime: mod},
+			}
+			bodies := []string{"hello", ""}
+			for i, e := range in {
+				if err := w.WriteEntry(e, strings.NewReader(bodies[i])); err != nil {
+					t.Fatalf("WriteEntry: %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := NewReader(&buf, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			for i, want := range in {
+				got, body, err := r.Next()
+				if err != nil {
+					t.Fatalf("Next: %v", err)
+				}
+				data, _ := io.ReadAll(body)
+				if got.Name != want.Name || got.Size != want.Size || got.ContentType != want.ContentType || string(data) != bodies[i] {
+					t.Errorf("entry %d = %+v %q", i, got, data)
+				}
+				if !got.ModTime.Equal(mod) {
+					t.Errorf("entry %d ModTime = %v", i, got.ModTime)
+				}
+				for k, v := range want.Metadata {
+					if got.Metadata[k] != v {
+						t.Errorf("entry %d metadata %s = %q, want %q", i, k, got.Metadata[k], v)
+					}
+				}
+			}
+			if _, _, err := r.Next(); err != io.EOF {
+				t.Errorf("expected EOF, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWriter_RejectsShortBody(t *testing.T) {
+	w, _ := NewWriter(io.Discard, FormatTar)
+	if err := w.WriteEntry(Entry{Name: "x", Size: 10}, strings.NewReader("short")); err == nil {
+		t.Fatal("expected error for a body shorter than the declared size")
+	}
+}
+
+func TestReader_RejectsUnsafeNames(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range []string{"../escape", "/abs", "ok/../../up", "./fine"} {
+		_ = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Size: 1, Mode: 0o644})
+		_, _ = tw.Write([]byte("x"))
+	}
+	_ = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: "dir/", Mode: 0o755})
+	tw.Close()
+
+	r, _ := NewReader(&buf, FormatTar)
+	var unsafe int
+	var names []string
+	for {
+		e, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, ErrUnsafeName) {
+			unsafe++
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, e.Name)
+	}
+	if unsafe != 3 {
+		t.Errorf("expected 3 unsafe entries, got %d", unsafe)
+	}
+	if len(names) != 1 || names[0] != "fine" {
+		t.Errorf("expected only \"fine\", got %v", names)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]Format{"": FormatTar, "tar": FormatTar, "tar.zst": FormatTarZstd, "zst": FormatTarZstd} {
+		got, err := ParseFormat(in)
+		if err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseFormat("zip"); err == nil {
+		t.Error("expected error for zip")
+	}
+}
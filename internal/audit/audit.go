@@ -1,11 +1,15 @@
 package audit
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/identity"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // EventType represents the type of audit event.
@@ -38,6 +42,14 @@ const (
 	// Emitted on every profile fetch via the admin /debug/pprof/* endpoints.
 	// Satisfies Adkins et al., BSRS Ch. 15 "auditable debug interface" mandate.
 	EventTypePprofFetch EventType = "pprof_fetch"
+
+	// EventTypeCryptoShred is emitted for every object whose data key is
+	// destroyed through /admin/shred.
+	EventTypeCryptoShred EventType = "crypto_shred"
+
+	// EventTypeObjectMove is emitted for every object renamed through
+	// /admin/move.
+	EventTypeObjectMove EventType = "object_move"
 )
 
 // AuditEvent represents a single audit log event.
@@ -56,6 +68,22 @@ type AuditEvent struct {
 	Error      string                 `json:"error,omitempty"`
 	Duration   time.Duration          `json:"duration_ms"`
 	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	// TraceID and SpanID identify the OpenTelemetry span of the request, the
+	// same trace ID carried by metric exemplars. Set by loggers obtained
+	// from Logger.WithContext.
+	TraceID string `json:"trace_id,omitempty"`
+	SpanID  string `json:"span_id,omitempty"`
+	// BackendRequestID and BackendHostID are the x-amz-request-id and
+	// x-amz-id-2 of the last backend response to the request, for support
+	// tickets with the storage provider. Set by loggers obtained from
+	// Logger.WithContext.
+	BackendRequestID string `json:"backend_request_id,omitempty"`
+	BackendHostID    string `json:"backend_host_id,omitempty"`
+	// AccessKey and Credential identify the client: the access key the
+	// request was signed with and the label of its auth.credentials entry.
+	// Set by loggers obtained from Logger.WithContext.
+	AccessKey  string `json:"access_key,omitempty"`
+	Credential string `json:"credential,omitempty"`
 }
 
 // Logger is the interface for audit logging.
@@ -85,15 +113,32 @@ type Logger interface {
 
 	// Close closes the logger and its underlying writer.
 	Close() error
+
+	// WithContext returns a logger that stamps events with the trace and
+	// span ID of the span in ctx, the backend request IDs recorded for ctx
+	// and the client identity authenticated for it. It shares storage and
+	// sink with the receiver. With none of these in ctx the receiver is
+	// returned.
+	WithContext(ctx context.Context) Logger
 }
 
 // auditLogger implements the Logger interface.
 type auditLogger struct {
+	*loggerState
+	traceID  string
+	spanID   string
+	backends *s3.RequestIDRecorder
+	client   identity.Identity
+}
+
+// loggerState is shared by a logger and those derived with WithContext.
+type loggerState struct {
 	mu         sync.Mutex
 	events     []*AuditEvent
 	maxEvents  int
 	writer     EventWriter
 	redactKeys []string
+	filter     *eventFilter
 }
 
 // EventWriter is an interface for writing audit events.
@@ -112,12 +157,12 @@ func NewLoggerWithRedaction(maxEvents int, writer EventWriter, redactKeys []stri
 		writer = &StdoutSink{}
 	}
 
-	return &auditLogger{
+	return &auditLogger{loggerState: &loggerState{
 		events:     make([]*AuditEvent, 0, maxEvents),
 		maxEvents:  maxEvents,
 		writer:     writer,
 		redactKeys: redactKeys,
-	}
+	}}
 }
 
 // NewLoggerFromConfig creates a new audit logger from configuration.
@@ -141,23 +186,58 @@ func NewLoggerFromConfig(cfg config.AuditConfig) (Logger, error) {
 		} else {
 			writer = NewFileSink(cfg.Sink.FilePath)
 		}
+	case "cloudwatch":
+		sink, err := NewCloudWatchSink(context.Background(), cfg.Sink.CloudWatch)
+		if err != nil {
+			return nil, err
+		}
+		writer = sink
+	case "gcp_logging":
+		sink, err := NewGCPLoggingSink(context.Background(), cfg.Sink.GCPLogging)
+		if err != nil {
+			return nil, err
+		}
+		writer = sink
 	case "stdout", "":
 		writer = &StdoutSink{}
 	default:
 		return nil, fmt.Errorf("unknown sink type: %s", cfg.Sink.Type)
 	}
 
-	// Wrap with batch sink if configured
-	if cfg.Sink.BatchSize > 0 || cfg.Sink.FlushInterval > 0 {
+	// Wrap with batch sink if configured. Cloud log APIs are always batched:
+	// one request per event would quickly hit their rate limits.
+	cloudSink := cfg.Sink.Type == "cloudwatch" || cfg.Sink.Type == "gcp_logging"
+	if cloudSink || cfg.Sink.BatchSize > 0 || cfg.Sink.FlushInterval > 0 {
 		// Default values handled in NewBatchSink if 0
 		writer = NewBatchSink(writer, cfg.Sink.BatchSize, cfg.Sink.FlushInterval, cfg.Sink.RetryCount, cfg.Sink.RetryBackoff, cfg.Sink.MaxConcurrentFlushes)
 	}
 
-	return NewLoggerWithRedaction(cfg.MaxEvents, writer, cfg.RedactMetadataKeys), nil
+	logger := NewLoggerWithRedaction(cfg.MaxEvents, writer, cfg.RedactMetadataKeys).(*auditLogger)
+	logger.filter = newEventFilter(cfg.Sampling)
+	return logger, nil
 }
 
 // Log logs an audit event.
 func (l *auditLogger) Log(event *AuditEvent) error {
+	if event.TraceID == "" && l.traceID != "" {
+		event.TraceID = l.traceID
+		event.SpanID = l.spanID
+	}
+	if event.AccessKey == "" && event.Credential == "" {
+		event.AccessKey = l.client.AccessKey
+		event.Credential = l.client.Label
+	}
+	if event.BackendRequestID == "" && event.BackendHostID == "" {
+		if id, n := l.backends.Last(); n > 0 {
+			event.BackendRequestID = id.RequestID
+			event.BackendHostID = id.HostID
+		}
+	}
+	if !l.filter.keep(event) {
+		sampledOutAuditEventsTotal.Inc()
+		return nil
+	}
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
@@ -193,6 +273,24 @@ func (l *auditLogger) Close() error {
 	return nil
 }
 
+// WithContext returns a logger that stamps events with the span, backend
+// request IDs and client identity of ctx. The backend IDs are read when an
+// event is logged, so they are those of the last backend response before it.
+func (l *auditLogger) WithContext(ctx context.Context) Logger {
+	sc := trace.SpanContextFromContext(ctx)
+	backends := s3.RequestIDRecorderFromContext(ctx)
+	client, hasClient := identity.FromContext(ctx)
+	if !sc.IsValid() && backends == nil && !hasClient {
+		return l
+	}
+	derived := &auditLogger{loggerState: l.loggerState, backends: backends, client: client}
+	if sc.IsValid() {
+		derived.traceID = sc.TraceID().String()
+		derived.spanID = sc.SpanID().String()
+	}
+	return derived
+}
+
 // redactMetadata removes sensitive keys from metadata.
 func (l *auditLogger) redactMetadata(metadata map[string]interface{}) map[string]interface{} {
 	if len(l.redactKeys) == 0 || len(metadata) == 0 {
@@ -1,8 +1,14 @@
 package audit
 
 import (
+	"context"
 	"testing"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/identity"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
 )
 
 func TestAuditLogger_LogEncrypt(t *testing.T) {
@@ -250,3 +256,83 @@ func TestAuditLogger_BackendRetryGiveUp(t *testing.T) {
 		t.Errorf("expected final_error_class=throttle_503, got %v", event.Metadata["final_error_class"])
 	}
 }
+
+func TestAuditLogger_WithContextStampsTrace(t *testing.T) {
+	mock := &mockWriter{}
+	logger := NewLogger(100, mock)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	if logger.WithContext(context.Background()) != logger {
+		t.Error("expected the same logger without a span in the context")
+	}
+	logger.WithContext(ctx).LogAccess("get", "b", "k", "", "", "req-1", true, nil, 0)
+	logger.LogAccess("get", "b", "k", "", "", "req-2", true, nil, 0)
+
+	events := logger.GetEvents()
+	if len(events) != 2 {
+		t.Fatalf("expected both events in the shared buffer, got %d", len(events))
+	}
+	if events[0].TraceID != traceID.String() || events[0].SpanID != spanID.String() {
+		t.Errorf("trace not stamped: %q/%q", events[0].TraceID, events[0].SpanID)
+	}
+	if events[1].TraceID != "" {
+		t.Errorf("unscoped logger stamped trace %q", events[1].TraceID)
+	}
+	if len(mock.events) != 2 {
+		t.Errorf("expected both events written to the sink, got %d", len(mock.events))
+	}
+}
+
+func TestAuditLogger_WithContextStampsIdentity(t *testing.T) {
+	logger := NewLogger(100, nil)
+	alice := identity.NewContext(context.Background(), identity.Identity{AccessKey: "AKIAALICE", Label: "alice"})
+	bob := identity.NewContext(context.Background(), identity.Identity{AccessKey: "AKIABOB"})
+
+	logger.WithContext(alice).LogAccess("get", "b", "k", "", "", "", true, nil, 0)
+	logger.WithContext(bob).LogAccess("put", "b", "k", "", "", "", true, nil, 0)
+	logger.LogAccess("get", "b", "k", "", "", "", true, nil, 0)
+
+	events := logger.GetEvents()
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+	if events[0].AccessKey != "AKIAALICE" || events[0].Credential != "alice" {
+		t.Errorf("alice's event = %+v", events[0])
+	}
+	if events[1].AccessKey != "AKIABOB" || events[1].Credential != "" {
+		t.Errorf("bob's event = %+v", events[1])
+	}
+	if events[2].AccessKey != "" {
+		t.Errorf("unscoped logger stamped access key %q", events[2].AccessKey)
+	}
+}
+
+func TestAuditLogger_WithContextStampsBackendRequestID(t *testing.T) {
+	logger := NewLogger(100, nil)
+	ctx, rec := s3.WithRequestIDRecorder(context.Background())
+	scoped := logger.WithContext(ctx)
+
+	// Nothing recorded yet.
+	scoped.LogAccess("head", "b", "k", "", "", "", true, nil, 0)
+	// The IDs are read when the event is logged, not when the logger is
+	// derived.
+	rec.Record(s3.BackendRequestID{RequestID: "REQ1", HostID: "HOST1"})
+	scoped.LogAccess("get", "b", "k", "", "", "", false, nil, 0)
+
+	events := logger.GetEvents()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].BackendRequestID != "" {
+		t.Errorf("stamped %q before any backend response", events[0].BackendRequestID)
+	}
+	if events[1].BackendRequestID != "REQ1" || events[1].BackendHostID != "HOST1" || events[1].TraceID != "" {
+		t.Errorf("event = %+v", events[1])
+	}
+}
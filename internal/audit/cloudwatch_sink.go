@@ -0,0 +1,181 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+// PutLogEvents limits. Each event counts its message size plus 26 bytes
+// towards the request limit, and one request may not span more than 24h.
+const (
+	cloudWatchMaxBatchEvents = 10000
+	cloudWatchMaxBatchBytes  = 1048576
+	cloudWatchEventOverhead  = 26
+	cloudWatchMaxEventBytes  = 256*1024 - cloudWatchEventOverhead
+	cloudWatchMaxBatchSpan   = 24 * time.Hour
+	cloudWatchRequestTimeout = 30 * time.Second
+)
+
+// cloudWatchAPI is the subset of the CloudWatch Logs client the sink uses.
+type cloudWatchAPI interface {
+	PutLogEvents(ctx context.Context, in *cloudwatchlogs.PutLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error)
+	CreateLogStream(ctx context.Context, in *cloudwatchlogs.CreateLogStreamInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogStreamOutput, error)
+}
+
+// CloudWatchSink writes events to a CloudWatch Logs stream, one JSON log
+// event per audit event. It implements BatchWriter and splits batches at
+// the PutLogEvents limits; wrap it in a BatchSink to batch writes.
+type CloudWatchSink struct {
+	client cloudWatchAPI
+	group  string
+	stream string
+	logger *slog.Logger
+
+	mu          sync.Mutex // serialises requests to the stream
+	streamReady bool
+}
+
+// NewCloudWatchSink creates a CloudWatch Logs sink using the AWS default
+// credential chain.
+func NewCloudWatchSink(ctx context.Context, cfg config.SinkCloudWatchConfig) (*CloudWatchSink, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if cfg.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(cfg.Region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	client := cloudwatchlogs.NewFromConfig(awsCfg, func(o *cloudwatchlogs.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+	})
+	stream := cfg.LogStream
+	if stream == "" {
+		if stream, err = os.Hostname(); err != nil || stream == "" {
+			stream = "s3-encryption-gateway"
+		}
+	}
+	return &CloudWatchSink{
+		client: client,
+		group:  cfg.LogGroup,
+		stream: stream,
+		logger: slog.Default(),
+	}, nil
+}
+
+// WriteEvent writes a single event.
+func (s *CloudWatchSink) WriteEvent(event *AuditEvent) error {
+	return s.WriteBatch([]*AuditEvent{event})
+}
+
+// WriteBatch writes events in timestamp order, in as many PutLogEvents
+// requests as the service limits require. Events that cannot be encoded or
+// exceed the per-event size limit are dropped and counted.
+func (s *CloudWatchSink) WriteBatch(events []*AuditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	logEvents := make([]cwtypes.InputLogEvent, 0, len(events))
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil || len(data) > cloudWatchMaxEventBytes {
+			droppedAuditEventsTotal.Inc()
+			s.logger.Error("audit: dropping event not accepted by CloudWatch Logs",
+				slog.String("event_type", string(event.EventType)),
+				slog.Int("size", len(data)),
+			)
+			continue
+		}
+		logEvents = append(logEvents, cwtypes.InputLogEvent{
+			Message:   aws.String(string(data)),
+			Timestamp: aws.Int64(event.Timestamp.UnixMilli()),
+		})
+	}
+	sort.SliceStable(logEvents, func(i, j int) bool {
+		return *logEvents[i].Timestamp < *logEvents[j].Timestamp
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(logEvents) > 0 {
+		n := cloudWatchBatchLen(logEvents)
+		if err := s.put(logEvents[:n]); err != nil {
+			return fmt.Errorf("failed to send audit events to CloudWatch Logs: %w", err)
+		}
+		logEvents = logEvents[n:]
+	}
+	return nil
+}
+
+// cloudWatchBatchLen returns how many of the sorted events fit one request.
+func cloudWatchBatchLen(events []cwtypes.InputLogEvent) int {
+	size := 0
+	first := *events[0].Timestamp
+	for i, e := range events {
+		size += len(*e.Message) + cloudWatchEventOverhead
+		if i == cloudWatchMaxBatchEvents || size > cloudWatchMaxBatchBytes ||
+			time.Duration(*e.Timestamp-first)*time.Millisecond > cloudWatchMaxBatchSpan {
+			return i
+		}
+	}
+	return len(events)
+}
+
+// put sends one request, creating the log stream on first use.
+func (s *CloudWatchSink) put(events []cwtypes.InputLogEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cloudWatchRequestTimeout)
+	defer cancel()
+	if !s.streamReady {
+		if err := s.createStream(ctx); err != nil {
+			return err
+		}
+	}
+	in := &cloudwatchlogs.PutLogEventsInput{
+		LogGroupName:  aws.String(s.group),
+		LogStreamName: aws.String(s.stream),
+		LogEvents:     events,
+	}
+	_, err := s.client.PutLogEvents(ctx, in)
+	var notFound *cwtypes.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		// The stream was deleted since we created it.
+		if err = s.createStream(ctx); err == nil {
+			_, err = s.client.PutLogEvents(ctx, in)
+		}
+	}
+	return err
+}
+
+func (s *CloudWatchSink) createStream(ctx context.Context) error {
+	_, err := s.client.CreateLogStream(ctx, &cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(s.group),
+		LogStreamName: aws.String(s.stream),
+	})
+	var exists *cwtypes.ResourceAlreadyExistsException
+	if err != nil && !errors.As(err, &exists) {
+		return fmt.Errorf("failed to create log stream %s/%s: %w", s.group, s.stream, err)
+	}
+	s.streamReady = true
+	return nil
+}
+
+// SetLogger sets the structured logger for the sink.
+func (s *CloudWatchSink) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
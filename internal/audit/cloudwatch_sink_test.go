@@ -0,0 +1,152 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+// fakeCloudWatch records PutLogEvents requests and can pretend the stream is
+// missing.
+type fakeCloudWatch struct {
+	mu            sync.Mutex
+	streamExists  bool
+	createCalls   int
+	requests      [][]cwtypes.InputLogEvent
+	failCreateErr error
+}
+
+func (f *fakeCloudWatch) PutLogEvents(ctx context.Context, in *cloudwatchlogs.PutLogEventsInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.streamExists {
+		return nil, &cwtypes.ResourceNotFoundException{Message: strPtr("stream not found")}
+	}
+	f.requests = append(f.requests, in.LogEvents)
+	return &cloudwatchlogs.PutLogEventsOutput{}, nil
+}
+
+func (f *fakeCloudWatch) CreateLogStream(ctx context.Context, in *cloudwatchlogs.CreateLogStreamInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogStreamOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.createCalls++
+	if f.failCreateErr != nil {
+		return nil, f.failCreateErr
+	}
+	if f.streamExists {
+		return nil, &cwtypes.ResourceAlreadyExistsException{Message: strPtr("exists")}
+	}
+	f.streamExists = true
+	return &cloudwatchlogs.CreateLogStreamOutput{}, nil
+}
+
+func strPtr(s string) *string { return &s }
+
+func newFakeCloudWatchSink(api cloudWatchAPI) *CloudWatchSink {
+	return &CloudWatchSink{client: api, group: "audit", stream: "gw-1", logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+}
+
+func TestCloudWatchSink_CreatesStreamAndSortsEvents(t *testing.T) {
+	api := &fakeCloudWatch{}
+	sink := newFakeCloudWatchSink(api)
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	err := sink.WriteBatch([]*AuditEvent{
+		{Timestamp: base.Add(time.Second), Operation: "second"},
+		{Timestamp: base, Operation: "first"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, sink.WriteEvent(&AuditEvent{Timestamp: base.Add(time.Minute), Operation: "third"}))
+
+	assert.Equal(t, 1, api.createCalls)
+	require.Len(t, api.requests, 2)
+	require.Len(t, api.requests[0], 2)
+	var ev AuditEvent
+	require.NoError(t, json.Unmarshal([]byte(*api.requests[0][0].Message), &ev))
+	assert.Equal(t, "first", ev.Operation)
+	assert.Equal(t, base.UnixMilli(), *api.requests[0][0].Timestamp)
+}
+
+func TestCloudWatchSink_RecreatesDeletedStream(t *testing.T) {
+	api := &fakeCloudWatch{streamExists: true}
+	sink := newFakeCloudWatchSink(api)
+	sink.streamReady = true
+	api.streamExists = false
+
+	require.NoError(t, sink.WriteEvent(&AuditEvent{Timestamp: time.Now(), Operation: "op"}))
+	assert.Equal(t, 1, api.createCalls)
+	assert.Len(t, api.requests, 1)
+}
+
+func TestCloudWatchSink_SplitsAtServiceLimits(t *testing.T) {
+	api := &fakeCloudWatch{streamExists: true}
+	sink := newFakeCloudWatchSink(api)
+	base := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	big := strings.Repeat("x", 200*1024)
+	var events []*AuditEvent
+	for i := 0; i < 6; i++ {
+		events = append(events, &AuditEvent{Timestamp: base, Operation: "op", Metadata: map[string]interface{}{"pad": big}})
+	}
+	// More than 24h after the first event: must go in its own request.
+	events = append(events, &AuditEvent{Timestamp: base.Add(25 * time.Hour), Operation: "late"})
+	// Larger than a single log event may be: dropped.
+	events = append(events, &AuditEvent{Timestamp: base, Metadata: map[string]interface{}{"pad": strings.Repeat("y", 300*1024)}})
+
+	require.NoError(t, sink.WriteBatch(events))
+	total := 0
+	for _, req := range api.requests {
+		size := 0
+		for _, e := range req {
+			size += len(*e.Message) + cloudWatchEventOverhead
+		}
+		assert.LessOrEqual(t, size, cloudWatchMaxBatchBytes)
+		total += len(req)
+	}
+	assert.Equal(t, 7, total)
+	assert.Len(t, api.requests, 3)
+	assert.Len(t, api.requests[2], 1)
+}
+
+func TestNewCloudWatchSink_UsesEnvironmentCredentials(t *testing.T) {
+	var mu sync.Mutex
+	var targets []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		targets = append(targets, r.Header.Get("X-Amz-Target"))
+		mu.Unlock()
+		assert.Contains(t, r.Header.Get("Authorization"), "Credential=AKIDAUDIT/")
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDAUDIT")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_CONFIG_FILE", "/nonexistent")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/nonexistent")
+
+	sink, err := NewCloudWatchSink(context.Background(), config.SinkCloudWatchConfig{
+		LogGroup: "audit", LogStream: "gw-1", Region: "eu-west-1", Endpoint: ts.URL,
+	})
+	require.NoError(t, err)
+	require.NoError(t, sink.WriteEvent(&AuditEvent{Timestamp: time.Now(), Operation: "op"}))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"Logs_20140328.CreateLogStream", "Logs_20140328.PutLogEvents"}, targets)
+}
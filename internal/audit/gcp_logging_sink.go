@@ -0,0 +1,160 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+const (
+	gcpLoggingEndpoint   = "https://logging.googleapis.com"
+	gcpLoggingScope      = "https://www.googleapis.com/auth/logging.write"
+	gcpLoggingDefaultLog = "s3-gateway-audit"
+	// entries.write accepts up to 10 MB per request; stay well below it.
+	gcpLoggingMaxBatchEntries = 1000
+	gcpLoggingMaxBatchBytes   = 5 * 1024 * 1024
+)
+
+// GCPLoggingSink writes events to Google Cloud Logging through the
+// entries.write REST method, as structured entries with the event as
+// jsonPayload. It implements BatchWriter; wrap it in a BatchSink to batch
+// writes.
+type GCPLoggingSink struct {
+	client   *http.Client
+	endpoint string
+	project  string
+	logName  string
+	logger   *slog.Logger
+}
+
+// gcpLogEntry is the subset of LogEntry the sink sets.
+type gcpLogEntry struct {
+	Timestamp   string      `json:"timestamp"`
+	Severity    string      `json:"severity"`
+	Trace       string      `json:"trace,omitempty"`
+	SpanID      string      `json:"spanId,omitempty"`
+	JSONPayload *AuditEvent `json:"jsonPayload"`
+}
+
+// NewGCPLoggingSink creates a Cloud Logging sink using Application Default
+// Credentials. The project defaults to the one the credentials belong to.
+func NewGCPLoggingSink(ctx context.Context, cfg config.SinkGCPLoggingConfig) (*GCPLoggingSink, error) {
+	creds, err := google.FindDefaultCredentials(ctx, gcpLoggingScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find Google default credentials: %w", err)
+	}
+	project := cfg.ProjectID
+	if project == "" {
+		project = creds.ProjectID
+	}
+	if project == "" {
+		return nil, fmt.Errorf("audit.sink.gcp_logging.project_id is required: the credentials do not name a project")
+	}
+	client := oauth2.NewClient(context.Background(), creds.TokenSource)
+	client.Timeout = 30 * time.Second
+	return newGCPLoggingSink(client, cfg.Endpoint, project, cfg.LogID), nil
+}
+
+func newGCPLoggingSink(client *http.Client, endpoint, project, logID string) *GCPLoggingSink {
+	if endpoint == "" {
+		endpoint = gcpLoggingEndpoint
+	}
+	if logID == "" {
+		logID = gcpLoggingDefaultLog
+	}
+	return &GCPLoggingSink{
+		client:   client,
+		endpoint: strings.TrimRight(endpoint, "/") + "/v2/entries:write",
+		project:  project,
+		logName:  "projects/" + project + "/logs/" + url.PathEscape(logID),
+		logger:   slog.Default(),
+	}
+}
+
+// WriteEvent writes a single event.
+func (s *GCPLoggingSink) WriteEvent(event *AuditEvent) error {
+	return s.WriteBatch([]*AuditEvent{event})
+}
+
+// WriteBatch writes events in as many entries.write requests as needed.
+// Failed operations are logged with severity WARNING, the rest as NOTICE.
+func (s *GCPLoggingSink) WriteBatch(events []*AuditEvent) error {
+	var batch []json.RawMessage
+	size := 0
+	for _, event := range events {
+		entry := gcpLogEntry{
+			Timestamp:   event.Timestamp.UTC().Format(time.RFC3339Nano),
+			Severity:    "NOTICE",
+			JSONPayload: event,
+		}
+		if !event.Success {
+			entry.Severity = "WARNING"
+		}
+		if event.TraceID != "" {
+			// Links the entry to the trace in Cloud Trace.
+			entry.Trace = "projects/" + s.project + "/traces/" + event.TraceID
+			entry.SpanID = event.SpanID
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			droppedAuditEventsTotal.Inc()
+			s.logger.Error("audit: failed to marshal event for Cloud Logging", slog.String("error", err.Error()))
+			continue
+		}
+		if len(batch) > 0 && (len(batch) == gcpLoggingMaxBatchEntries || size+len(data) > gcpLoggingMaxBatchBytes) {
+			if err := s.write(batch); err != nil {
+				return err
+			}
+			batch, size = nil, 0
+		}
+		batch = append(batch, data)
+		size += len(data)
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	return s.write(batch)
+}
+
+func (s *GCPLoggingSink) write(entries []json.RawMessage) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"logName":  s.logName,
+		"resource": map[string]string{"type": "global"},
+		"entries":  entries,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit events: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create Cloud Logging request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send audit events to Cloud Logging: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("cloud logging returned status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// SetLogger sets the structured logger for the sink.
+func (s *GCPLoggingSink) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+type gcpWriteRequest struct {
+	LogName  string            `json:"logName"`
+	Resource map[string]string `json:"resource"`
+	Entries  []struct {
+		Timestamp   string     `json:"timestamp"`
+		Severity    string     `json:"severity"`
+		Trace       string     `json:"trace"`
+		SpanID      string     `json:"spanId"`
+		JSONPayload AuditEvent `json:"jsonPayload"`
+	} `json:"entries"`
+}
+
+func TestGCPLoggingSink_WriteBatch(t *testing.T) {
+	var got []gcpWriteRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/entries:write", r.URL.Path)
+		var req gcpWriteRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		got = append(got, req)
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	sink := newGCPLoggingSink(ts.Client(), ts.URL, "my-project", "gateway/audit")
+	ts1 := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, sink.WriteBatch([]*AuditEvent{
+		{Timestamp: ts1, Operation: "put", Success: true},
+		{Timestamp: ts1, Operation: "get", Success: false, Error: "denied", TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+	}))
+
+	require.Len(t, got, 1)
+	assert.Equal(t, "projects/my-project/logs/gateway%2Faudit", got[0].LogName)
+	assert.Equal(t, "global", got[0].Resource["type"])
+	require.Len(t, got[0].Entries, 2)
+	assert.Equal(t, "NOTICE", got[0].Entries[0].Severity)
+	assert.Equal(t, "WARNING", got[0].Entries[1].Severity)
+	assert.Equal(t, "denied", got[0].Entries[1].JSONPayload.Error)
+	assert.Empty(t, got[0].Entries[0].Trace)
+	assert.Equal(t, "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736", got[0].Entries[1].Trace)
+	assert.Equal(t, "00f067aa0ba902b7", got[0].Entries[1].SpanID)
+	assert.Equal(t, "2026-05-01T12:00:00Z", got[0].Entries[0].Timestamp)
+}
+
+func TestGCPLoggingSink_SplitsLargeBatches(t *testing.T) {
+	var sizes []int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req gcpWriteRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		sizes = append(sizes, len(req.Entries))
+	}))
+	defer ts.Close()
+
+	sink := newGCPLoggingSink(ts.Client(), ts.URL, "p", "")
+	events := make([]*AuditEvent, gcpLoggingMaxBatchEntries+5)
+	for i := range events {
+		events[i] = &AuditEvent{Timestamp: time.Now(), Operation: "op"}
+	}
+	require.NoError(t, sink.WriteBatch(events))
+	assert.Equal(t, []int{gcpLoggingMaxBatchEntries, 5}, sizes)
+}
+
+func TestGCPLoggingSink_ErrorStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"status":"PERMISSION_DENIED"}}`, http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	sink := newGCPLoggingSink(ts.Client(), ts.URL, "p", "")
+	err := sink.WriteEvent(&AuditEvent{Timestamp: time.Now()})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "PERMISSION_DENIED")
+}
+
+func TestNewGCPLoggingSink_ProjectFromCredentials(t *testing.T) {
+	creds := `{"type":"authorized_user","client_id":"id","client_secret":"secret","refresh_token":"token","quota_project_id":"quota"}`
+	path := filepath.Join(t.TempDir(), "creds.json")
+	require.NoError(t, os.WriteFile(path, []byte(creds), 0600))
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+
+	_, err := NewGCPLoggingSink(context.Background(), config.SinkGCPLoggingConfig{})
+	require.Error(t, err, "user credentials carry no project")
+	assert.Contains(t, err.Error(), "project_id")
+
+	sink, err := NewGCPLoggingSink(context.Background(), config.SinkGCPLoggingConfig{ProjectID: "explicit"})
+	require.NoError(t, err)
+	assert.Equal(t, "projects/explicit/logs/s3-gateway-audit", sink.logName)
+}
@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// sampledOutAuditEventsTotal counts events dropped on purpose by the
+// sampling configuration, as opposed to droppedAuditEventsTotal.
+var sampledOutAuditEventsTotal = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Name: "audit_events_sampled_out_total",
+		Help: "Total number of audit events dropped by audit.sampling",
+	},
+)
+
+// Severity classifies audit events for filtering.
+type Severity int
+
+const (
+	// SeverityInfo is a successful routine operation.
+	SeverityInfo Severity = iota
+	// SeverityWarning is a failed operation.
+	SeverityWarning
+	// SeverityCritical is a security event. It is never sampled out.
+	SeverityCritical
+)
+
+// criticalEventTypes are kept whatever the sampling configuration says.
+var criticalEventTypes = map[EventType]bool{
+	EventTypeKeyRotation:            true,
+	EventTypeMPUTamperDetected:      true,
+	EventTypeMPUValkeyUnavail:       true,
+	EventTypeBackendRetryGiveUp:     true,
+	EventTypePprofFetch:             true,
+	EventTypeCryptoShred:            true,
+	"put_object_retention":          true,
+	"put_object_legal_hold":         true,
+	"put_object_lock_configuration": true,
+}
+
+// Severity returns the severity the event is filtered by.
+func (e *AuditEvent) Severity() Severity {
+	switch {
+	case criticalEventTypes[e.EventType], strings.HasPrefix(string(e.EventType), "key_rotation."):
+		return SeverityCritical
+	case !e.Success:
+		return SeverityWarning
+	default:
+		return SeverityInfo
+	}
+}
+
+// eventFilter applies audit.sampling. A nil filter keeps everything.
+type eventFilter struct {
+	minSeverity Severity
+	successRate float64
+	typeRates   map[EventType]float64
+	excluded    map[EventType]bool
+}
+
+// newEventFilter returns the filter for cfg, or nil when cfg keeps every
+// event.
+func newEventFilter(cfg config.AuditSamplingConfig) *eventFilter {
+	f := &eventFilter{successRate: 1}
+	switch cfg.MinSeverity {
+	case "warning":
+		f.minSeverity = SeverityWarning
+	case "critical":
+		f.minSeverity = SeverityCritical
+	}
+	if cfg.SuccessRate != nil {
+		f.successRate = *cfg.SuccessRate
+	}
+	if len(cfg.EventTypeRates) > 0 {
+		f.typeRates = make(map[EventType]float64, len(cfg.EventTypeRates))
+		for t, rate := range cfg.EventTypeRates {
+			f.typeRates[EventType(t)] = rate
+		}
+	}
+	if len(cfg.ExcludeEventTypes) > 0 {
+		f.excluded = make(map[EventType]bool, len(cfg.ExcludeEventTypes))
+		for _, t := range cfg.ExcludeEventTypes {
+			f.excluded[EventType(t)] = true
+		}
+	}
+	if f.minSeverity == SeverityInfo && f.successRate >= 1 && f.typeRates == nil && f.excluded == nil {
+		return nil
+	}
+	return f
+}
+
+// keep reports whether event passes the filter.
+func (f *eventFilter) keep(event *AuditEvent) bool {
+	if f == nil {
+		return true
+	}
+	sev := event.Severity()
+	switch {
+	case sev == SeverityCritical:
+		return true
+	case sev < f.minSeverity, f.excluded[event.EventType]:
+		return false
+	case sev != SeverityInfo:
+		return true
+	}
+	rate, ok := f.typeRates[event.EventType]
+	if !ok {
+		rate = f.successRate
+	}
+	if rate >= 1 {
+		return true
+	}
+	return samplePoint(event.RequestID) < rate
+}
+
+// samplePoint maps a request ID onto [0, 1) so that every event of one
+// request gets the same decision. Events without one are sampled at random.
+func samplePoint(requestID string) float64 {
+	if requestID == "" {
+		return rand.Float64()
+	}
+	h := fnv.New64a()
+	h.Write([]byte(requestID))
+	// FNV alone spreads sequential IDs poorly over the high bits; finish
+	// with the splitmix64 mixer.
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return float64(x>>11) / (1 << 53)
+}
@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+func rate(v float64) *float64 { return &v }
+
+func TestEventFilter_NilKeepsEverything(t *testing.T) {
+	assert.Nil(t, newEventFilter(config.AuditSamplingConfig{}))
+	assert.Nil(t, newEventFilter(config.AuditSamplingConfig{MinSeverity: "info", SuccessRate: rate(1)}))
+	var f *eventFilter
+	assert.True(t, f.keep(&AuditEvent{EventType: "get", Success: true}))
+}
+
+func TestEventFilter_Severity(t *testing.T) {
+	f := newEventFilter(config.AuditSamplingConfig{MinSeverity: "warning"})
+	assert.False(t, f.keep(&AuditEvent{EventType: "get", Success: true}))
+	assert.True(t, f.keep(&AuditEvent{EventType: "get", Success: false}))
+	assert.True(t, f.keep(&AuditEvent{EventType: EventTypeKeyRotation, Success: true}))
+
+	f = newEventFilter(config.AuditSamplingConfig{MinSeverity: "critical"})
+	assert.False(t, f.keep(&AuditEvent{EventType: "delete", Success: false}))
+	assert.True(t, f.keep(&AuditEvent{EventType: "put_object_legal_hold", Success: true}))
+	assert.True(t, f.keep(&AuditEvent{EventType: "key_rotation.committed", Success: true}))
+}
+
+func TestEventFilter_ExcludeNeverDropsCritical(t *testing.T) {
+	f := newEventFilter(config.AuditSamplingConfig{ExcludeEventTypes: []string{"head", string(EventTypePprofFetch)}})
+	assert.False(t, f.keep(&AuditEvent{EventType: "head", Success: true}))
+	assert.False(t, f.keep(&AuditEvent{EventType: "head", Success: false}))
+	assert.True(t, f.keep(&AuditEvent{EventType: "get", Success: true}))
+	assert.True(t, f.keep(&AuditEvent{EventType: EventTypePprofFetch, Success: true}))
+}
+
+func TestEventFilter_SamplesSuccessesOnly(t *testing.T) {
+	f := newEventFilter(config.AuditSamplingConfig{
+		SuccessRate:    rate(0.5),
+		EventTypeRates: map[string]float64{"get": 0.01, "put": 1},
+	})
+	kept := map[EventType]int{}
+	for i := 0; i < 10000; i++ {
+		id := fmt.Sprintf("req-%d", i)
+		for _, typ := range []EventType{"get", "put", "delete"} {
+			if f.keep(&AuditEvent{EventType: typ, Success: true, RequestID: id}) {
+				kept[typ]++
+			}
+		}
+		require.True(t, f.keep(&AuditEvent{EventType: "get", Success: false, RequestID: id}), "failures are never sampled")
+	}
+	assert.InDelta(t, 100, kept["get"], 60)
+	assert.Equal(t, 10000, kept["put"])
+	assert.InDelta(t, 5000, kept["delete"], 300)
+}
+
+func TestEventFilter_SameDecisionPerRequest(t *testing.T) {
+	f := newEventFilter(config.AuditSamplingConfig{SuccessRate: rate(0.3)})
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("req-%d", i)
+		first := f.keep(&AuditEvent{EventType: "encrypt", Success: true, RequestID: id})
+		assert.Equal(t, first, f.keep(&AuditEvent{EventType: "get", Success: true, RequestID: id}))
+	}
+}
+
+func TestNewLoggerFromConfig_AppliesSampling(t *testing.T) {
+	mock := &mockWriter{}
+	logger, err := NewLoggerFromConfig(config.AuditConfig{
+		Enabled:   true,
+		MaxEvents: 10,
+		Sampling:  config.AuditSamplingConfig{SuccessRate: rate(0)},
+	})
+	require.NoError(t, err)
+	logger.(*auditLogger).writer = mock
+
+	logger.LogAccess("get", "b", "k", "", "", "req-1", true, nil, 0)
+	logger.LogAccess("get", "b", "k", "", "", "req-2", false, fmt.Errorf("denied"), 0)
+	logger.LogKeyRotation(2, true, nil)
+
+	events := logger.GetEvents()
+	require.Len(t, events, 2)
+	assert.False(t, events[0].Success)
+	assert.Equal(t, EventTypeKeyRotation, events[1].EventType)
+	assert.Len(t, mock.events, 2)
+}
@@ -13,6 +13,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/kenneth/s3-encryption-gateway/internal/airgap"
+	"github.com/kenneth/s3-encryption-gateway/internal/clock"
 	"github.com/kenneth/s3-encryption-gateway/internal/config"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -46,10 +48,21 @@ type BatchSink struct {
 	retryBackoff         time.Duration
 	maxConcurrentFlushes int
 	flushSem             chan struct{}
+	clock                clock.Clock
+}
+
+// BatchSinkOption configures a BatchSink.
+type BatchSinkOption func(*BatchSink)
+
+// WithClock drives the periodic flush from clk instead of the wall clock.
+func WithClock(clk clock.Clock) BatchSinkOption {
+	return func(s *BatchSink) {
+		s.clock = clock.OrReal(clk)
+	}
 }
 
 // NewBatchSink creates a new batched sink.
-func NewBatchSink(wrapped EventWriter, size int, interval time.Duration, retryCount int, retryBackoff time.Duration, maxConcurrentFlushes int) *BatchSink {
+func NewBatchSink(wrapped EventWriter, size int, interval time.Duration, retryCount int, retryBackoff time.Duration, maxConcurrentFlushes int, opts ...BatchSinkOption) *BatchSink {
 	if size <= 0 {
 		size = 100
 	}
@@ -70,10 +83,16 @@ func NewBatchSink(wrapped EventWriter, size int, interval time.Duration, retryCo
 		retryBackoff:         retryBackoff,
 		maxConcurrentFlushes: maxConcurrentFlushes,
 		flushSem:             make(chan struct{}, maxConcurrentFlushes),
+		clock:                clock.Real,
+	}
+	for _, o := range opts {
+		o(s)
 	}
 
+	// The ticker is created before returning so a fake clock advanced
+	// right after construction already drives the flush loop.
 	s.wg.Add(1)
-	go s.run()
+	go s.run(s.clock.NewTicker(s.flushInterval))
 
 	return s
 }
@@ -114,14 +133,13 @@ func (s *BatchSink) Close() error {
 	return nil
 }
 
-func (s *BatchSink) run() {
+func (s *BatchSink) run(ticker clock.Ticker) {
 	defer s.wg.Done()
-	ticker := time.NewTicker(s.flushInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			s.mu.Lock()
 			events := s.drainBufferLocked()
 			s.mu.Unlock()
@@ -242,6 +260,7 @@ func NewHTTPSinkWithConfig(endpoint string, headers map[string]string, cfg confi
 	}
 
 	transport := &http.Transport{
+		DialContext:           airgap.DialContext,
 		TLSHandshakeTimeout:   tlsHandshakeTimeout,
 		ResponseHeaderTimeout: responseHeaderTimeout,
 		MaxIdleConns:          maxIdleConns,
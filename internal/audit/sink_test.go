@@ -14,6 +14,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/kenneth/s3-encryption-gateway/internal/clock"
 	"github.com/kenneth/s3-encryption-gateway/internal/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -196,6 +197,29 @@ func TestNewLoggerFromConfig(t *testing.T) {
 }
 
 // TestBatchSink_WriteWithRetry_FailingWriter exercises the retry loop in writeWithRetry.
+func TestBatchSink_FlushIntervalFakeClock(t *testing.T) {
+	mock := &mockWriter{}
+	clk := clock.NewFake(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	sink := NewBatchSink(mock, 100, time.Hour, 0, 0, 0, WithClock(clk))
+	defer sink.Close()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, sink.WriteEvent(&AuditEvent{Operation: fmt.Sprintf("op-%d", i)}))
+	}
+	// No tick is due before the interval, so nothing can have been flushed.
+	clk.Advance(59 * time.Minute)
+	mock.mu.Lock()
+	assert.Empty(t, mock.events)
+	mock.mu.Unlock()
+
+	clk.Advance(time.Minute)
+	require.Eventually(t, func() bool {
+		mock.mu.Lock()
+		defer mock.mu.Unlock()
+		return len(mock.events) == 3
+	}, 5*time.Second, time.Millisecond)
+}
+
 func TestBatchSink_WriteWithRetry_FailingWriter(t *testing.T) {
 	// Use an errorWriter that always fails.
 	errWriter := &errorWriter{err: fmt.Errorf("write failed")}
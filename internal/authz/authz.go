@@ -0,0 +1,193 @@
+// Package authz decides whether an authenticated client may perform a
+// request, according to the auth.policy rules.
+//
+// Evaluation follows the usual deny-overrides model: a request is refused
+// when any matching rule denies it, allowed when a matching rule allows it,
+// and otherwise allowed or refused by the policy's default. Rules are
+// matched with path.Match patterns; key prefixes are matched segment by
+// segment so a wildcard never spans a "/".
+package authz
+
+import (
+	"path"
+	"strings"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/identity"
+)
+
+// Request is one authorization question.
+type Request struct {
+	Identity  identity.Identity
+	Operation string
+	Bucket    string
+	// Key is the object key, or for a listing the prefix listed. It is
+	// empty for other bucket-level requests.
+	Key string
+	// Listing marks Key as a listed prefix rather than an object key.
+	Listing bool
+}
+
+// Decision is the outcome of evaluating a Request.
+type Decision struct {
+	Allowed bool
+	// Rule is the index in auth.policy.rules of the rule that decided, or
+	// -1 when the default applied.
+	Rule int
+}
+
+// Policy evaluates requests against configured rules. A nil *Policy allows
+// everything.
+type Policy struct {
+	rules       []config.AuthPolicyRule
+	defaultDeny bool
+}
+
+// New returns the policy cfg describes, or nil when cfg cannot refuse
+// anything. cfg must have passed config validation.
+func New(cfg config.AuthPolicyConfig) *Policy {
+	if !cfg.Enabled() {
+		return nil
+	}
+	return &Policy{
+		rules:       append([]config.AuthPolicyRule(nil), cfg.Rules...),
+		defaultDeny: cfg.DefaultDeny,
+	}
+}
+
+// Evaluate decides req.
+func (p *Policy) Evaluate(req Request) Decision {
+	if p == nil {
+		return Decision{Allowed: true, Rule: -1}
+	}
+	allowedBy := -1
+	for i, rule := range p.rules {
+		deny := rule.Effect == config.AuthPolicyDeny
+		if !ruleMatches(rule, req, deny) {
+			continue
+		}
+		if deny {
+			return Decision{Allowed: false, Rule: i}
+		}
+		if allowedBy < 0 {
+			allowedBy = i
+		}
+	}
+	if allowedBy >= 0 {
+		return Decision{Allowed: true, Rule: allowedBy}
+	}
+	return Decision{Allowed: !p.defaultDeny, Rule: -1}
+}
+
+// ruleMatches reports whether rule applies to req. For a listing, a deny
+// rule applies as soon as the listing could return a key it covers, while
+// an allow rule applies only when everything listed is covered.
+func ruleMatches(rule config.AuthPolicyRule, req Request, deny bool) bool {
+	if !matchesIdentity(rule.Identities, req.Identity) ||
+		!matchesAny(rule.Buckets, req.Bucket) ||
+		!matchesAny(rule.Operations, req.Operation) {
+		return false
+	}
+	if len(rule.Prefixes) == 0 {
+		return true
+	}
+	if req.Key == "" && !req.Listing {
+		return false
+	}
+	for _, pattern := range rule.Prefixes {
+		if prefixMatch(pattern, req.Key) {
+			return true
+		}
+		if deny && req.Listing && listingOverlaps(pattern, req.Key) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesIdentity reports whether id's access key or label matches one of
+// patterns. No patterns match every client.
+func matchesIdentity(patterns []string, id identity.Identity) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, id.AccessKey); ok {
+			return true
+		}
+		if id.Label != "" {
+			if ok, _ := path.Match(pattern, id.Label); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesAny reports whether s matches one of patterns. No patterns match
+// everything.
+func matchesAny(patterns []string, s string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// prefixMatch reports whether key begins with a string matching pattern.
+// Every "/"-separated segment of pattern but the last must match a whole
+// segment of key; the last must match the start of the next one.
+func prefixMatch(pattern, key string) bool {
+	patternSegs := strings.Split(pattern, "/")
+	keySegs := strings.SplitN(key, "/", len(patternSegs))
+	if len(keySegs) < len(patternSegs) {
+		return false
+	}
+	last := len(patternSegs) - 1
+	for i := 0; i < last; i++ {
+		if ok, _ := path.Match(patternSegs[i], keySegs[i]); !ok {
+			return false
+		}
+	}
+	seg, _, _ := strings.Cut(keySegs[last], "/")
+	ok, _ := path.Match(patternSegs[last]+"*", seg)
+	return ok
+}
+
+// listingOverlaps reports whether a listing of prefix could return a key
+// that pattern covers, as when listing "tenants/" above a denied
+// "tenants/*/private/". It errs towards true once prefix reaches a
+// wildcard.
+func listingOverlaps(pattern, prefix string) bool {
+	patternSegs := strings.Split(pattern, "/")
+	prefixSegs := strings.Split(prefix, "/")
+	if len(prefixSegs) > len(patternSegs) {
+		// prefix fixes every segment pattern constrains, so prefixMatch
+		// has already decided.
+		return false
+	}
+	last := len(prefixSegs) - 1
+	for i := 0; i < last; i++ {
+		if ok, _ := path.Match(patternSegs[i], prefixSegs[i]); !ok {
+			return false
+		}
+	}
+	return couldBegin(patternSegs[last], prefixSegs[last])
+}
+
+// couldBegin reports whether s is the start of some string matching the
+// single-segment pattern.
+func couldBegin(pattern, s string) bool {
+	i := strings.IndexAny(pattern, `*?[\`)
+	if i < 0 {
+		return strings.HasPrefix(pattern, s)
+	}
+	if len(s) <= i {
+		return strings.HasPrefix(pattern[:i], s)
+	}
+	return strings.HasPrefix(s, pattern[:i])
+}
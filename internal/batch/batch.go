@@ -0,0 +1,574 @@
+// Package batch runs bulk operations over a manifest of object keys.
+//
+// An operator submits a job naming an operation and the keys to apply it
+// to, either inline or as a manifest object with one key per line. Jobs are
+// queued and run one at a time; within a job a fixed number of workers
+// process keys concurrently. A key whose operation fails is retried with
+// backoff, except when the object does not exist. When the job ends, a JSON
+// report with the counts and every failed key is written to the backend.
+//
+// Jobs are kept in memory: a restart forgets queued and running jobs, and
+// the reports already written are the lasting record.
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/sirupsen/logrus"
+)
+
+// Operations a job can apply.
+const (
+	OpReencrypt = "reencrypt"
+	OpCopy      = "copy"
+	OpDelete    = "delete"
+	OpRetag     = "retag"
+	OpVerify    = "verify"
+)
+
+// Job states.
+const (
+	StateQueued    = "queued"
+	StateRunning   = "running"
+	StateCompleted = "completed"
+	StateFailed    = "failed"
+	StateCancelled = "cancelled"
+)
+
+// Errors returned by the Manager.
+var (
+	ErrInvalidSpec = errors.New("batch: invalid job")
+	ErrNotFound    = errors.New("batch: no such job")
+	ErrFinished    = errors.New("batch: job already finished")
+	ErrStopped     = errors.New("batch: shutting down")
+	ErrQueueFull   = errors.New("batch: too many jobs queued")
+)
+
+// maxQueued bounds the jobs waiting for the running one.
+const maxQueued = 64
+
+// Executor applies operations to single objects through the gateway.
+type Executor interface {
+	// ReencryptObject decrypts bucket/key and stores it again under the
+	// current encryption settings and key version.
+	ReencryptObject(ctx context.Context, bucket, key string) error
+	// CopyStored copies bucket/key as stored to destBucket/destKey.
+	CopyStored(ctx context.Context, bucket, key, destBucket, destKey string) error
+	// RemoveObject deletes bucket/key as a client DELETE would.
+	RemoveObject(ctx context.Context, bucket, key string) error
+	// RetagObject replaces the tags of bucket/key. tags is URL-encoded.
+	RetagObject(ctx context.Context, bucket, key, tags string) error
+	// VerifyObject decrypts bucket/key in full, failing when it does not
+	// authenticate.
+	VerifyObject(ctx context.Context, bucket, key string) error
+	// OpenPlaintext returns the decrypted body of bucket/key, for manifest
+	// objects.
+	OpenPlaintext(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+}
+
+// Reporter writes job reports.
+type Reporter interface {
+	PutObject(ctx context.Context, bucket, key string, reader io.Reader, metadata map[string]string, contentLength *int64, tags string, lock *s3.ObjectLockInput) error
+}
+
+// ManifestRef names a manifest object: one key per line, blank lines
+// ignored.
+type ManifestRef struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+}
+
+// Spec is a job submission.
+type Spec struct {
+	Operation string `json:"operation"`
+	Bucket    string `json:"bucket"`
+	// Keys lists the objects inline. Exactly one of Keys and Manifest is
+	// set.
+	Keys     []string     `json:"keys,omitempty"`
+	Manifest *ManifestRef `json:"manifest,omitempty"`
+	// DestBucket (default Bucket) and DestPrefix, prepended to each key,
+	// say where copy puts its copies.
+	DestBucket string `json:"dest_bucket,omitempty"`
+	DestPrefix string `json:"dest_prefix,omitempty"`
+	// Tags replace the object tags for retag.
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// Job is a snapshot of a job's progress.
+type Job struct {
+	ID         string     `json:"id"`
+	Operation  string     `json:"operation"`
+	Bucket     string     `json:"bucket"`
+	State      string     `json:"state"`
+	Total      int        `json:"total"`
+	Succeeded  int        `json:"succeeded"`
+	Failed     int        `json:"failed"`
+	Retries    int        `json:"retries"`
+	Created    time.Time  `json:"created"`
+	Started    *time.Time `json:"started,omitempty"`
+	Finished   *time.Time `json:"finished,omitempty"`
+	Report     string     `json:"report,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	DestBucket string     `json:"dest_bucket,omitempty"`
+	DestPrefix string     `json:"dest_prefix,omitempty"`
+}
+
+// Failure is a key the operation could not be applied to.
+type Failure struct {
+	Key      string `json:"key"`
+	Error    string `json:"error"`
+	Attempts int    `json:"attempts"`
+}
+
+// Report is the object written when a job ends.
+type Report struct {
+	Job
+	Failures []Failure `json:"failures"`
+}
+
+type job struct {
+	Job
+	spec     Spec
+	keys     []string
+	tags     string
+	failures []Failure
+	cancel   context.CancelFunc
+}
+
+// Manager queues and runs jobs. All methods are safe on a nil *Manager,
+// which refuses submissions.
+type Manager struct {
+	exec     Executor
+	reporter Reporter
+	cfg      config.BatchConfig
+	logger   *logrus.Logger
+	backoff  time.Duration
+
+	mu      sync.Mutex
+	jobs    map[string]*job
+	order   []string // submission order
+	queue   chan *job
+	seq     int
+	stopped bool
+	ctx     context.Context
+	stop    context.CancelFunc
+	done    chan struct{}
+}
+
+// New returns a manager running jobs through exec and writing reports with
+// reporter, or nil when cfg disables batch jobs.
+func New(exec Executor, reporter Reporter, cfg config.BatchConfig, logger *logrus.Logger) *Manager {
+	if !cfg.Enabled {
+		return nil
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	m := &Manager{
+		exec:     exec,
+		reporter: reporter,
+		cfg:      cfg,
+		logger:   logger,
+		backoff:  time.Second,
+		jobs:     make(map[string]*job),
+		queue:    make(chan *job, maxQueued),
+		ctx:      ctx,
+		stop:     stop,
+		done:     make(chan struct{}),
+	}
+	go m.loop()
+	return m
+}
+
+// Submit validates spec, resolves its keys and queues the job.
+func (m *Manager) Submit(ctx context.Context, spec Spec) (Job, error) {
+	if m == nil {
+		return Job{}, ErrStopped
+	}
+	j, err := m.prepare(ctx, spec)
+	if err != nil {
+		return Job{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped {
+		return Job{}, ErrStopped
+	}
+	m.seq++
+	j.ID = fmt.Sprintf("batch-%d-%d", j.Created.UnixMilli(), m.seq)
+	select {
+	case m.queue <- j:
+	default:
+		return Job{}, ErrQueueFull
+	}
+	m.jobs[j.ID] = j
+	m.order = append(m.order, j.ID)
+	m.prune()
+	return j.Job, nil
+}
+
+func (m *Manager) prepare(ctx context.Context, spec Spec) (*job, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidSpec, fmt.Sprintf(format, args...))
+	}
+	if spec.Bucket == "" {
+		return nil, invalid("bucket is required")
+	}
+	j := &job{spec: spec}
+	switch spec.Operation {
+	case OpReencrypt, OpDelete, OpVerify:
+	case OpCopy:
+		if spec.DestBucket == "" {
+			spec.DestBucket = spec.Bucket
+		}
+		if spec.DestBucket == spec.Bucket && spec.DestPrefix == "" {
+			return nil, invalid("copy needs dest_prefix or another dest_bucket")
+		}
+		j.spec = spec
+	case OpRetag:
+		if len(spec.Tags) == 0 {
+			return nil, invalid("retag needs tags")
+		}
+		j.tags = encodeTags(spec.Tags)
+	default:
+		return nil, invalid("unknown operation %q", spec.Operation)
+	}
+
+	switch {
+	case (len(spec.Keys) == 0) == (spec.Manifest == nil):
+		return nil, invalid("exactly one of keys and manifest is required")
+	case spec.Manifest != nil:
+		keys, err := m.readManifest(ctx, *spec.Manifest)
+		if err != nil {
+			return nil, err
+		}
+		j.keys = keys
+	default:
+		for _, k := range spec.Keys {
+			if k == "" {
+				return nil, invalid("keys must not be empty")
+			}
+		}
+		j.keys = append([]string(nil), spec.Keys...)
+	}
+	if len(j.keys) == 0 {
+		return nil, invalid("the manifest lists no keys")
+	}
+	if len(j.keys) > m.cfg.MaxKeys {
+		return nil, invalid("%d keys exceed batch.max_keys (%d)", len(j.keys), m.cfg.MaxKeys)
+	}
+
+	j.Job = Job{
+		Operation:  spec.Operation,
+		Bucket:     spec.Bucket,
+		State:      StateQueued,
+		Total:      len(j.keys),
+		Created:    time.Now().UTC(),
+		DestBucket: j.spec.DestBucket,
+		DestPrefix: j.spec.DestPrefix,
+	}
+	return j, nil
+}
+
+func (m *Manager) readManifest(ctx context.Context, ref ManifestRef) ([]string, error) {
+	if ref.Bucket == "" || ref.Key == "" {
+		return nil, fmt.Errorf("%w: manifest needs bucket and key", ErrInvalidSpec)
+	}
+	body, err := m.exec.OpenPlaintext(ctx, ref.Bucket, ref.Key)
+	if err != nil {
+		return nil, fmt.Errorf("batch: read manifest: %w", err)
+	}
+	defer body.Close()
+	var keys []string
+	sc := bufio.NewScanner(body)
+	sc.Buffer(make([]byte, 0, 4096), 64<<10)
+	for sc.Scan() {
+		if k := strings.TrimRight(sc.Text(), "\r"); strings.TrimSpace(k) != "" {
+			if len(keys) == m.cfg.MaxKeys {
+				return nil, fmt.Errorf("%w: manifest exceeds batch.max_keys (%d)", ErrInvalidSpec, m.cfg.MaxKeys)
+			}
+			keys = append(keys, k)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("batch: read manifest: %w", err)
+	}
+	return keys, nil
+}
+
+// prune forgets the oldest finished jobs beyond RetainJobs. m.mu is held.
+func (m *Manager) prune() {
+	finished := 0
+	for _, id := range m.order {
+		if isFinished(m.jobs[id].State) {
+			finished++
+		}
+	}
+	kept := m.order[:0]
+	for _, id := range m.order {
+		if finished > m.cfg.RetainJobs && isFinished(m.jobs[id].State) {
+			delete(m.jobs, id)
+			finished--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	m.order = kept
+}
+
+func isFinished(state string) bool {
+	return state == StateCompleted || state == StateFailed || state == StateCancelled
+}
+
+// Get returns the job with id.
+func (m *Manager) Get(id string) (Job, bool) {
+	if m == nil {
+		return Job{}, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return j.Job, true
+}
+
+// List returns every retained job, newest first.
+func (m *Manager) List() []Job {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Job, 0, len(m.order))
+	for i := len(m.order) - 1; i >= 0; i-- {
+		out = append(out, m.jobs[m.order[i]].Job)
+	}
+	return out
+}
+
+// Cancel stops a queued or running job. Keys already processed stay
+// processed; the report lists how far the job got.
+func (m *Manager) Cancel(id string) (Job, error) {
+	if m == nil {
+		return Job{}, ErrNotFound
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	switch {
+	case !ok:
+		return Job{}, ErrNotFound
+	case isFinished(j.State):
+		return j.Job, ErrFinished
+	case j.State == StateQueued:
+		j.State = StateCancelled
+		now := time.Now().UTC()
+		j.Finished = &now
+	case j.cancel != nil:
+		j.cancel()
+	}
+	return j.Job, nil
+}
+
+// Stop refuses new jobs, cancels the running one and waits for its report
+// to be written or ctx to end. Queued jobs are dropped.
+func (m *Manager) Stop(ctx context.Context) error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	if !m.stopped {
+		m.stopped = true
+		close(m.queue)
+		m.stop()
+	}
+	m.mu.Unlock()
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *Manager) loop() {
+	defer close(m.done)
+	for j := range m.queue {
+		if m.ctx.Err() != nil {
+			continue
+		}
+		m.run(j)
+	}
+}
+
+func (m *Manager) run(j *job) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	defer cancel()
+	m.mu.Lock()
+	if j.State != StateQueued {
+		m.mu.Unlock()
+		return
+	}
+	now := time.Now().UTC()
+	j.State, j.Started, j.cancel = StateRunning, &now, cancel
+	m.mu.Unlock()
+
+	keys := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < m.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range keys {
+				m.apply(ctx, j, key)
+			}
+		}()
+	}
+feed:
+	for _, key := range j.keys {
+		select {
+		case keys <- key:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(keys)
+	wg.Wait()
+
+	m.mu.Lock()
+	finished := time.Now().UTC()
+	j.Finished, j.cancel = &finished, nil
+	switch {
+	case ctx.Err() != nil:
+		j.State = StateCancelled
+	case j.Failed > 0:
+		j.State = StateFailed
+	default:
+		j.State = StateCompleted
+	}
+	report := Report{Job: j.Job, Failures: append([]Failure(nil), j.failures...)}
+	m.mu.Unlock()
+
+	reportKey, err := m.writeReport(report)
+	m.mu.Lock()
+	if err != nil {
+		j.Error = "write report: " + err.Error()
+	} else {
+		j.Report = reportKey
+	}
+	snap := j.Job
+	m.prune()
+	m.mu.Unlock()
+
+	if m.logger != nil {
+		entry := m.logger.WithFields(logrus.Fields{
+			"job":       snap.ID,
+			"operation": snap.Operation,
+			"bucket":    snap.Bucket,
+			"state":     snap.State,
+			"succeeded": snap.Succeeded,
+			"failed":    snap.Failed,
+		})
+		if err != nil {
+			entry.WithError(err).Warn("Batch job finished without a report")
+		} else {
+			entry.Info("Batch job finished")
+		}
+	}
+}
+
+// apply runs the job's operation on key, retrying failures other than a
+// missing object.
+func (m *Manager) apply(ctx context.Context, j *job, key string) {
+	var err error
+	attempt := 0
+	for attempt < m.cfg.MaxAttempts {
+		if attempt > 0 {
+			m.mu.Lock()
+			j.Retries++
+			m.mu.Unlock()
+			select {
+			case <-time.After(m.backoff << (attempt - 1)):
+			case <-ctx.Done():
+				return
+			}
+		}
+		attempt++
+		if err = m.do(ctx, j, key); err == nil || errors.Is(err, s3.ErrNotFound) || ctx.Err() != nil {
+			break
+		}
+	}
+	if ctx.Err() != nil && err != nil {
+		// Interrupted, not failed: the report counts it as neither.
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err == nil {
+		j.Succeeded++
+		return
+	}
+	j.Failed++
+	j.failures = append(j.failures, Failure{Key: key, Error: err.Error(), Attempts: attempt})
+}
+
+func (m *Manager) do(ctx context.Context, j *job, key string) error {
+	switch j.Operation {
+	case OpReencrypt:
+		return m.exec.ReencryptObject(ctx, j.Bucket, key)
+	case OpCopy:
+		return m.exec.CopyStored(ctx, j.Bucket, key, j.spec.DestBucket, j.spec.DestPrefix+key)
+	case OpDelete:
+		return m.exec.RemoveObject(ctx, j.Bucket, key)
+	case OpRetag:
+		return m.exec.RetagObject(ctx, j.Bucket, key, j.tags)
+	case OpVerify:
+		return m.exec.VerifyObject(ctx, j.Bucket, key)
+	}
+	return fmt.Errorf("unknown operation %q", j.Operation)
+}
+
+// writeReport stores report and returns its bucket/key. It outlives a
+// cancelled job, so it runs on its own deadline.
+func (m *Manager) writeReport(report Report) (string, error) {
+	sort.Slice(report.Failures, func(a, b int) bool { return report.Failures[a].Key < report.Failures[b].Key })
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	bucket := m.cfg.ReportBucket
+	if bucket == "" {
+		bucket = report.Bucket
+	}
+	key := m.cfg.ReportPrefix + report.ID + ".json"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	size := int64(len(data))
+	if err := m.reporter.PutObject(ctx, bucket, key, bytes.NewReader(data), nil, &size, "", nil); err != nil {
+		return "", err
+	}
+	return bucket + "/" + key, nil
+}
+
+// encodeTags returns tags in the URL-encoded form of x-amz-tagging.
+func encodeTags(tags map[string]string) string {
+	v := url.Values{}
+	for k, val := range tags {
+		v.Set(k, val)
+	}
+	return v.Encode()
+}
@@ -0,0 +1,243 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+type fakeExecutor struct {
+	mu       sync.Mutex
+	calls    map[string]int
+	failures map[string]int // key -> failing attempts before success
+	missing  map[string]bool
+	tags     string
+	copies   []string
+	manifest string
+	block    chan struct{}
+}
+
+func newFakeExecutor() *fakeExecutor {
+	return &fakeExecutor{calls: map[string]int{}, failures: map[string]int{}, missing: map[string]bool{}}
+}
+
+func (f *fakeExecutor) record(ctx context.Context, key string) error {
+	if f.block != nil {
+		select {
+		case <-f.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[key]++
+	if f.missing[key] {
+		return s3.ErrNotFound
+	}
+	if f.calls[key] <= f.failures[key] {
+		return errors.New("backend unavailable")
+	}
+	return nil
+}
+
+func (f *fakeExecutor) ReencryptObject(ctx context.Context, bucket, key string) error {
+	return f.record(ctx, key)
+}
+
+func (f *fakeExecutor) CopyStored(ctx context.Context, bucket, key, destBucket, destKey string) error {
+	f.mu.Lock()
+	f.copies = append(f.copies, destBucket+"/"+destKey)
+	f.mu.Unlock()
+	return f.record(ctx, key)
+}
+
+func (f *fakeExecutor) RemoveObject(ctx context.Context, bucket, key string) error {
+	return f.record(ctx, key)
+}
+
+func (f *fakeExecutor) RetagObject(ctx context.Context, bucket, key, tags string) error {
+	f.mu.Lock()
+	f.tags = tags
+	f.mu.Unlock()
+	return f.record(ctx, key)
+}
+
+func (f *fakeExecutor) VerifyObject(ctx context.Context, bucket, key string) error {
+	return f.record(ctx, key)
+}
+
+func (f *fakeExecutor) OpenPlaintext(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader([]byte(f.manifest))), nil
+}
+
+func newManager(t *testing.T, exec Executor, reporter Reporter) *Manager {
+	t.Helper()
+	m := New(exec, reporter, config.BatchConfig{
+		Enabled:      true,
+		Workers:      2,
+		MaxAttempts:  3,
+		MaxKeys:      10,
+		ReportPrefix: ".s3eg-batch/",
+		RetainJobs:   10,
+	}, nil)
+	m.backoff = time.Millisecond
+	t.Cleanup(func() { _ = m.Stop(context.Background()) })
+	return m
+}
+
+func waitFinished(t *testing.T, m *Manager, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if j, ok := m.Get(id); ok && isFinished(j.State) && (j.Report != "" || j.Error != "") {
+			return j
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Job{}
+}
+
+func TestManager_RetriesAndReports(t *testing.T) {
+	exec := newFakeExecutor()
+	exec.failures["flaky"] = 2
+	exec.failures["broken"] = 10
+	exec.missing["gone"] = true
+	reports := testsupport.NewMemoryClient()
+	m := newManager(t, exec, reports)
+
+	job, err := m.Submit(context.Background(), Spec{Operation: OpVerify, Bucket: "bucket", Keys: []string{"ok", "flaky", "broken", "gone"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job = waitFinished(t, m, job.ID)
+	if job.State != StateFailed || job.Succeeded != 2 || job.Failed != 2 || job.Total != 4 {
+		t.Fatalf("job = %+v", job)
+	}
+	if exec.calls["flaky"] != 3 || exec.calls["broken"] != 3 || exec.calls["gone"] != 1 {
+		t.Errorf("attempts = %v", exec.calls)
+	}
+
+	data, _, ok := reports.Object("bucket", ".s3eg-batch/"+job.ID+".json")
+	if !ok || job.Report != "bucket/.s3eg-batch/"+job.ID+".json" {
+		t.Fatalf("report %q not written", job.Report)
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Failures) != 2 || report.Failures[0].Key != "broken" || report.Failures[0].Attempts != 3 || report.Failures[1].Key != "gone" {
+		t.Errorf("report failures = %+v", report.Failures)
+	}
+}
+
+func TestManager_Operations(t *testing.T) {
+	exec := newFakeExecutor()
+	exec.manifest = "a\r\n\nb\n"
+	m := newManager(t, exec, testsupport.NewMemoryClient())
+	ctx := context.Background()
+
+	job, err := m.Submit(ctx, Spec{Operation: OpCopy, Bucket: "bucket", Manifest: &ManifestRef{Bucket: "bucket", Key: "list.txt"}, DestBucket: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Total != 2 {
+		t.Errorf("manifest resolved to %d keys", job.Total)
+	}
+	if job = waitFinished(t, m, job.ID); job.State != StateCompleted {
+		t.Fatalf("copy job = %+v", job)
+	}
+	if len(exec.copies) != 2 || (exec.copies[0] != "other/a" && exec.copies[1] != "other/a") {
+		t.Errorf("copies = %v", exec.copies)
+	}
+
+	job, err = m.Submit(ctx, Spec{Operation: OpRetag, Bucket: "bucket", Keys: []string{"a"}, Tags: map[string]string{"class": "cold", "owner": "a b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFinished(t, m, job.ID)
+	if tags, _ := url.ParseQuery(exec.tags); tags.Get("class") != "cold" || tags.Get("owner") != "a b" {
+		t.Errorf("tags = %q", exec.tags)
+	}
+
+	for _, spec := range []Spec{
+		{Operation: OpVerify, Keys: []string{"a"}},
+		{Operation: "compress", Bucket: "bucket", Keys: []string{"a"}},
+		{Operation: OpVerify, Bucket: "bucket"},
+		{Operation: OpVerify, Bucket: "bucket", Keys: []string{"a"}, Manifest: &ManifestRef{Bucket: "bucket", Key: "list.txt"}},
+		{Operation: OpCopy, Bucket: "bucket", Keys: []string{"a"}},
+		{Operation: OpRetag, Bucket: "bucket", Keys: []string{"a"}},
+		{Operation: OpDelete, Bucket: "bucket", Keys: make([]string, 11)},
+	} {
+		if _, err := m.Submit(ctx, spec); !errors.Is(err, ErrInvalidSpec) {
+			t.Errorf("Submit(%+v) = %v, want ErrInvalidSpec", spec, err)
+		}
+	}
+}
+
+func TestManager_Cancel(t *testing.T) {
+	exec := newFakeExecutor()
+	exec.block = make(chan struct{})
+	m := newManager(t, exec, testsupport.NewMemoryClient())
+	ctx := context.Background()
+
+	running, err := m.Submit(ctx, Spec{Operation: OpDelete, Bucket: "bucket", Keys: []string{"a", "b", "c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queued, err := m.Submit(ctx, Spec{Operation: OpDelete, Bucket: "bucket", Keys: []string{"d"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j, err := m.Cancel(queued.ID); err != nil || j.State != StateCancelled {
+		t.Fatalf("Cancel(queued) = %+v, %v", j, err)
+	}
+	for {
+		if j, _ := m.Get(running.ID); j.State == StateRunning {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := m.Cancel(running.ID); err != nil {
+		t.Fatal(err)
+	}
+	if j := waitFinished(t, m, running.ID); j.State != StateCancelled || j.Succeeded != 0 || j.Failed != 0 {
+		t.Errorf("cancelled job = %+v", j)
+	}
+	if _, err := m.Cancel(running.ID); !errors.Is(err, ErrFinished) {
+		t.Errorf("second Cancel = %v, want ErrFinished", err)
+	}
+	if _, err := m.Cancel("batch-0-0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Cancel of an unknown job = %v", err)
+	}
+	if exec.calls["d"] != 0 {
+		t.Error("cancelled queued job ran")
+	}
+	if jobs := m.List(); len(jobs) != 2 || jobs[0].ID != queued.ID {
+		t.Errorf("List = %+v", jobs)
+	}
+}
+
+func TestManager_Disabled(t *testing.T) {
+	var m *Manager
+	if New(newFakeExecutor(), testsupport.NewMemoryClient(), config.BatchConfig{}, nil) != nil {
+		t.Fatal("disabled config built a manager")
+	}
+	if _, err := m.Submit(context.Background(), Spec{}); !errors.Is(err, ErrStopped) {
+		t.Errorf("Submit on nil manager = %v", err)
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,335 @@
+// Package billing meters the traffic of each tenant and periodically
+// exports it, together with the storage measured by the quota scans, for
+// ingestion by billing pipelines.
+//
+// A tenant is a group of buckets in quota.tenants; a bucket outside every
+// group is billed as a tenant of its own name. Each export covers the
+// period since the previous one and is a single CSV or Parquet object with
+// one row per tenant. Counters are kept in memory: a failed export is
+// retried, merged into the next period, but the traffic of a period that is
+// never exported because the gateway crashed is lost.
+package billing
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
+	"github.com/kenneth/s3-encryption-gateway/internal/quota"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/sirupsen/logrus"
+)
+
+// Columns are the columns of an export, in order.
+var Columns = []string{
+	"period_start", "period_end", "tenant",
+	"requests", "bytes_in", "bytes_out",
+	"storage_bytes", "storage_objects", "storage_known",
+}
+
+// Record is one row of an export: the traffic of a tenant over the period
+// and its storage when the period ended.
+type Record struct {
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Tenant      string
+	// Requests counts S3 requests to the tenant's buckets; BytesIn and
+	// BytesOut the request and response bodies, as clients sent and
+	// received them.
+	Requests int64
+	BytesIn  int64
+	BytesOut int64
+	// StorageBytes and StorageObjects are the stored size measured by the
+	// quota scans. StorageKnown is false, and both are zero, when no quota
+	// covers the tenant or its buckets have not all been scanned yet.
+	StorageBytes   int64
+	StorageObjects int64
+	StorageKnown   bool
+}
+
+// Writer is the subset of s3.Client the exports are written with.
+type Writer interface {
+	PutObject(ctx context.Context, bucket, key string, reader io.Reader, metadata map[string]string, contentLength *int64, tags string, lock *s3.ObjectLockInput) error
+}
+
+// Storage reports measured storage; *quota.Manager implements it.
+type Storage interface {
+	Statuses() []quota.Status
+}
+
+type traffic struct {
+	requests, bytesIn, bytesOut int64
+}
+
+func (t *traffic) add(o traffic) {
+	t.requests += o.requests
+	t.bytesIn += o.bytesIn
+	t.bytesOut += o.bytesOut
+}
+
+// Exporter meters traffic and writes the exports. All methods are safe on
+// a nil *Exporter, which meters nothing.
+type Exporter struct {
+	client   Writer
+	storage  Storage
+	tenantOf map[string]string
+	bucket   string
+	prefix   string
+	format   string
+	interval time.Duration
+	m        *metrics.Metrics
+	logger   *logrus.Logger
+	now      func() time.Time
+
+	mu      sync.Mutex
+	start   time.Time
+	traffic map[string]*traffic
+
+	runMu   sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started atomic.Bool
+	done    chan struct{}
+}
+
+// New returns the billing exporter for cfg, or nil when cfg disables it.
+// tenants groups buckets into tenants, as in the quota configuration;
+// storage may be nil, leaving the storage columns unknown. m may be nil.
+func New(client Writer, storage Storage, cfg config.BillingConfig, tenants map[string]config.TenantQuota, m *metrics.Metrics, logger *logrus.Logger) *Exporter {
+	if !cfg.Enabled || client == nil {
+		return nil
+	}
+	e := &Exporter{
+		client:   client,
+		storage:  storage,
+		tenantOf: make(map[string]string),
+		bucket:   cfg.Bucket,
+		prefix:   cfg.Prefix,
+		format:   cfg.Format,
+		interval: cfg.Interval,
+		m:        m,
+		logger:   logger,
+		now:      time.Now,
+		traffic:  make(map[string]*traffic),
+		done:     make(chan struct{}),
+	}
+	for name, tenant := range tenants {
+		for _, bucket := range tenant.Buckets {
+			e.tenantOf[bucket] = name
+		}
+	}
+	e.start = e.now().UTC()
+	e.ctx, e.cancel = context.WithCancel(context.Background())
+	return e
+}
+
+// Tenant returns the tenant bucket is billed to.
+func (e *Exporter) Tenant(bucket string) string {
+	if name, ok := e.tenantOf[bucket]; ok {
+		return name
+	}
+	return bucket
+}
+
+// Record meters one request to bucket that received bytesIn and sent
+// bytesOut body bytes.
+func (e *Exporter) Record(bucket string, bytesIn, bytesOut int64) {
+	if e == nil || bucket == "" {
+		return
+	}
+	tenant := e.Tenant(bucket)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	t, ok := e.traffic[tenant]
+	if !ok {
+		t = &traffic{}
+		e.traffic[tenant] = t
+	}
+	t.add(traffic{requests: 1, bytesIn: bytesIn, bytesOut: bytesOut})
+}
+
+// Export closes the current period and writes its export. When the write
+// fails the period's traffic is carried into the next one, whose export
+// then starts where this one did.
+func (e *Exporter) Export(ctx context.Context) error {
+	if e == nil {
+		return nil
+	}
+	e.runMu.Lock()
+	defer e.runMu.Unlock()
+
+	e.mu.Lock()
+	start, end := e.start, e.now().UTC()
+	counted := e.traffic
+	e.start, e.traffic = end, make(map[string]*traffic)
+	e.mu.Unlock()
+
+	err := e.write(ctx, start, end, e.records(start, end, counted))
+	e.m.RecordBillingExport(err == nil)
+	if err != nil {
+		e.mu.Lock()
+		e.start = start
+		for tenant, t := range e.traffic {
+			if c, ok := counted[tenant]; ok {
+				c.add(*t)
+			} else {
+				counted[tenant] = t
+			}
+		}
+		e.traffic = counted
+		e.mu.Unlock()
+		if e.logger != nil {
+			e.logger.WithError(err).WithField("period_start", start).Warn("Billing export failed; retrying with the next period")
+		}
+		return err
+	}
+	if e.logger != nil {
+		e.logger.WithFields(logrus.Fields{
+			"period_start": start,
+			"period_end":   end,
+			"tenants":      len(counted),
+		}).Debug("Billing export written")
+	}
+	return nil
+}
+
+// records joins the traffic of a period with the measured storage, one
+// record per tenant sorted by name.
+func (e *Exporter) records(start, end time.Time, counted map[string]*traffic) []Record {
+	byTenant := make(map[string]*Record)
+	get := func(tenant string) *Record {
+		r, ok := byTenant[tenant]
+		if !ok {
+			r = &Record{PeriodStart: start, PeriodEnd: end, Tenant: tenant}
+			byTenant[tenant] = r
+		}
+		return r
+	}
+	for tenant, t := range counted {
+		r := get(tenant)
+		r.Requests, r.BytesIn, r.BytesOut = t.requests, t.bytesIn, t.bytesOut
+	}
+	if e.storage != nil {
+		for _, s := range e.storage.Statuses() {
+			// A bucket in a tenant is billed through the tenant's status.
+			if s.Scope == quota.ScopeBucket && e.Tenant(s.Name) != s.Name {
+				continue
+			}
+			r := get(s.Name)
+			r.StorageKnown = s.Known
+			if s.Known {
+				r.StorageBytes, r.StorageObjects = s.Used.Bytes, s.Used.Objects
+			}
+		}
+	}
+	out := make([]Record, 0, len(byTenant))
+	for _, r := range byTenant {
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Tenant < out[j].Tenant })
+	return out
+}
+
+// ObjectKey returns the key, relative to the prefix, of the export of the
+// period from start to end in format.
+func ObjectKey(start, end time.Time, format string) string {
+	const layout = "20060102T150405Z"
+	ext := ".csv"
+	if format == config.BillingFormatParquet {
+		ext = ".parquet"
+	}
+	return start.UTC().Format(layout) + "-" + end.UTC().Format(layout) + ext
+}
+
+// write stores records as the export of the period from start to end.
+func (e *Exporter) write(ctx context.Context, start, end time.Time, records []Record) error {
+	var buf bytes.Buffer
+	contentType := "text/csv"
+	if e.format == config.BillingFormatParquet {
+		contentType = "application/vnd.apache.parquet"
+		if err := WriteParquet(&buf, records); err != nil {
+			return err
+		}
+	} else if err := WriteCSV(&buf, records); err != nil {
+		return err
+	}
+	key := e.prefix + ObjectKey(start, end, e.format)
+	n := int64(buf.Len())
+	if err := e.client.PutObject(ctx, e.bucket, key, &buf, map[string]string{"Content-Type": contentType}, &n, "", nil); err != nil {
+		return fmt.Errorf("write %s/%s: %w", e.bucket, key, err)
+	}
+	return nil
+}
+
+// WriteCSV writes records as CSV with a header row. Times are RFC 3339 in
+// UTC.
+func WriteCSV(w io.Writer, records []Record) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(Columns); err != nil {
+		return err
+	}
+	for _, r := range records {
+		row := []string{
+			r.PeriodStart.UTC().Format(time.RFC3339),
+			r.PeriodEnd.UTC().Format(time.RFC3339),
+			r.Tenant,
+			strconv.FormatInt(r.Requests, 10),
+			strconv.FormatInt(r.BytesIn, 10),
+			strconv.FormatInt(r.BytesOut, 10),
+			strconv.FormatInt(r.StorageBytes, 10),
+			strconv.FormatInt(r.StorageObjects, 10),
+			strconv.FormatBool(r.StorageKnown),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Start runs an export every interval until Stop.
+func (e *Exporter) Start() {
+	if e == nil || !e.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer close(e.done)
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_ = e.Export(e.ctx)
+			case <-e.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the export loop and writes a final export for the traffic
+// metered since the last one, giving up when ctx expires.
+func (e *Exporter) Stop(ctx context.Context) error {
+	if e == nil {
+		return nil
+	}
+	e.cancel()
+	if e.started.Load() {
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return e.Export(ctx)
+}
@@ -0,0 +1,154 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/quota"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+type fakeWriter struct {
+	mu      sync.Mutex
+	objects map[string]string
+	meta    map[string]map[string]string
+	fail    bool
+}
+
+func (f *fakeWriter) PutObject(ctx context.Context, bucket, key string, reader io.Reader, metadata map[string]string, contentLength *int64, tags string, lock *s3.ObjectLockInput) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return errors.New("backend down")
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if f.objects == nil {
+		f.objects, f.meta = map[string]string{}, map[string]map[string]string{}
+	}
+	f.objects[bucket+"/"+key] = string(data)
+	f.meta[bucket+"/"+key] = metadata
+	return nil
+}
+
+type fakeStorage []quota.Status
+
+func (f fakeStorage) Statuses() []quota.Status { return f }
+
+// newTestExporter returns an exporter whose clock starts at t0 and is
+// advanced by the returned function.
+func newTestExporter(t *testing.T, w Writer, storage Storage, format string) (*Exporter, func(time.Duration)) {
+	t.Helper()
+	cfg := config.BillingConfig{Enabled: true, Interval: time.Hour, Bucket: "billing", Prefix: "usage/", Format: format}
+	tenants := map[string]config.TenantQuota{"acme": {Buckets: []string{"acme-logs", "acme-data"}}}
+	e := New(w, storage, cfg, tenants, nil, nil)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+	e.start = now
+	return e, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestExporter_CSV(t *testing.T) {
+	w := &fakeWriter{}
+	storage := fakeStorage{
+		{Scope: quota.ScopeBucket, Name: "acme-logs", Known: true, Used: quota.Usage{Bytes: 1, Objects: 1}},
+		{Scope: quota.ScopeBucket, Name: "solo", Known: true, Used: quota.Usage{Bytes: 700, Objects: 7}},
+		{Scope: quota.ScopeTenant, Name: "acme", Known: true, Used: quota.Usage{Bytes: 5000, Objects: 12}},
+		{Scope: quota.ScopeBucket, Name: "fresh"},
+	}
+	e, advance := newTestExporter(t, w, storage, config.BillingFormatCSV)
+
+	e.Record("acme-logs", 100, 0)
+	e.Record("acme-data", 0, 250)
+	e.Record("solo", 10, 20)
+	e.Record("", 1, 1)
+	advance(time.Hour)
+	if err := e.Export(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	key := "billing/usage/20261001T120000Z-20261001T130000Z.csv"
+	want := `period_start,period_end,tenant,requests,bytes_in,bytes_out,storage_bytes,storage_objects,storage_known
+2026-10-01T12:00:00Z,2026-10-01T13:00:00Z,acme,2,100,250,5000,12,true
+2026-10-01T12:00:00Z,2026-10-01T13:00:00Z,fresh,0,0,0,0,0,false
+2026-10-01T12:00:00Z,2026-10-01T13:00:00Z,solo,1,10,20,700,7,true
+`
+	if got := w.objects[key]; got != want {
+		t.Fatalf("export %s =\n%s\nwant\n%s (objects %v)", key, got, want, w.objects)
+	}
+	if ct := w.meta[key]["Content-Type"]; ct != "text/csv" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	// The next period starts empty.
+	advance(time.Hour)
+	if err := e.Export(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	next := w.objects["billing/usage/20261001T130000Z-20261001T140000Z.csv"]
+	if !strings.Contains(next, ",acme,0,0,0,5000,12,true") {
+		t.Errorf("second export = %q", next)
+	}
+}
+
+func TestExporter_FailedExportCarriesOver(t *testing.T) {
+	w := &fakeWriter{fail: true}
+	e, advance := newTestExporter(t, w, nil, config.BillingFormatCSV)
+
+	e.Record("solo", 10, 0)
+	advance(time.Hour)
+	if err := e.Export(context.Background()); err == nil {
+		t.Fatal("failed write not reported")
+	}
+	e.Record("solo", 5, 0)
+	e.Record("acme-data", 0, 3)
+	advance(time.Hour)
+	w.fail = false
+	if err := e.Export(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got := w.objects["billing/usage/20261001T120000Z-20261001T140000Z.csv"]
+	for _, row := range []string{
+		"2026-10-01T12:00:00Z,2026-10-01T14:00:00Z,acme,1,0,3,0,0,false",
+		"2026-10-01T12:00:00Z,2026-10-01T14:00:00Z,solo,2,15,0,0,0,false",
+	} {
+		if !strings.Contains(got, row) {
+			t.Errorf("export missing %q:\n%s (objects %v)", row, got, w.objects)
+		}
+	}
+}
+
+func TestExporter_StopWritesFinalExport(t *testing.T) {
+	w := &fakeWriter{}
+	e, advance := newTestExporter(t, w, nil, config.BillingFormatParquet)
+	e.Start()
+	e.Record("solo", 1, 2)
+	advance(time.Minute)
+	if err := e.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	key := "billing/usage/20261001T120000Z-20261001T120100Z.parquet"
+	if _, ok := w.objects[key]; !ok || w.meta[key]["Content-Type"] != "application/vnd.apache.parquet" {
+		t.Fatalf("final export missing: %v", w.meta)
+	}
+}
+
+func TestExporter_Nil(t *testing.T) {
+	if New(&fakeWriter{}, nil, config.BillingConfig{}, nil, nil, nil) != nil {
+		t.Error("disabled config returned an exporter")
+	}
+	var none *Exporter
+	none.Record("solo", 1, 1)
+	none.Start()
+	if none.Export(context.Background()) != nil || none.Stop(context.Background()) != nil {
+		t.Error("nil exporter returned an error")
+	}
+}
@@ -0,0 +1,253 @@
+package billing
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// The exports are small, so WriteParquet produces the simplest file the
+// format allows rather than pulling in a Parquet library: one row group,
+// one uncompressed PLAIN-encoded data page per column, all columns
+// required. The page headers and the footer are Thrift structures in the
+// compact protocol, written by compactWriter below.
+
+// Parquet enum values used here, from parquet.thrift.
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetRequired = 0
+
+	convertedNone            = -1
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecUncompressed = 0
+	pageData          = 0
+)
+
+var parquetMagic = []byte("PAR1")
+
+type parquetColumn struct {
+	name      string
+	typ       int32
+	converted int32
+	plain     func(records []Record) []byte
+}
+
+func int64Column(name string, converted int32, value func(Record) int64) parquetColumn {
+	return parquetColumn{name: name, typ: parquetInt64, converted: converted, plain: func(records []Record) []byte {
+		out := make([]byte, 0, 8*len(records))
+		for _, r := range records {
+			out = binary.LittleEndian.AppendUint64(out, uint64(value(r)))
+		}
+		return out
+	}}
+}
+
+// parquetColumns follow Columns.
+var parquetColumns = []parquetColumn{
+	int64Column("period_start", convertedTimestampMillis, func(r Record) int64 { return r.PeriodStart.UnixMilli() }),
+	int64Column("period_end", convertedTimestampMillis, func(r Record) int64 { return r.PeriodEnd.UnixMilli() }),
+	{name: "tenant", typ: parquetByteArray, converted: convertedUTF8, plain: func(records []Record) []byte {
+		var out []byte
+		for _, r := range records {
+			out = binary.LittleEndian.AppendUint32(out, uint32(len(r.Tenant)))
+			out = append(out, r.Tenant...)
+		}
+		return out
+	}},
+	int64Column("requests", convertedNone, func(r Record) int64 { return r.Requests }),
+	int64Column("bytes_in", convertedNone, func(r Record) int64 { return r.BytesIn }),
+	int64Column("bytes_out", convertedNone, func(r Record) int64 { return r.BytesOut }),
+	int64Column("storage_bytes", convertedNone, func(r Record) int64 { return r.StorageBytes }),
+	int64Column("storage_objects", convertedNone, func(r Record) int64 { return r.StorageObjects }),
+	{name: "storage_known", typ: parquetBoolean, converted: convertedNone, plain: func(records []Record) []byte {
+		// Booleans are bit-packed, least significant bit first.
+		out := make([]byte, (len(records)+7)/8)
+		for i, r := range records {
+			if r.StorageKnown {
+				out[i/8] |= 1 << (i % 8)
+			}
+		}
+		return out
+	}},
+}
+
+// WriteParquet writes records as a Parquet file. Times are timestamps in
+// milliseconds, UTC.
+func WriteParquet(w io.Writer, records []Record) error {
+	type chunk struct {
+		offset, size int64
+	}
+	file := append([]byte(nil), parquetMagic...)
+	var chunks []chunk
+	if len(records) > 0 {
+		for _, col := range parquetColumns {
+			data := col.plain(records)
+			offset := int64(len(file))
+			file = append(file, pageHeader(len(data), len(records))...)
+			file = append(file, data...)
+			chunks = append(chunks, chunk{offset: offset, size: int64(len(file)) - offset})
+		}
+	}
+
+	var f compactWriter
+	f.begin()
+	f.i32(1, 1) // version
+	f.list(2, ctStruct, len(parquetColumns)+1)
+	f.begin()
+	f.binary(4, "schema")
+	f.i32(5, int32(len(parquetColumns)))
+	f.end()
+	for _, col := range parquetColumns {
+		f.begin()
+		f.i32(1, col.typ)
+		f.i32(3, parquetRequired)
+		f.binary(4, col.name)
+		if col.converted != convertedNone {
+			f.i32(6, col.converted)
+		}
+		f.end()
+	}
+	f.i64(3, int64(len(records)))
+	f.list(4, ctStruct, min(len(chunks), 1))
+	if len(chunks) > 0 {
+		var total int64
+		f.begin()
+		f.list(1, ctStruct, len(chunks))
+		for i, col := range parquetColumns {
+			c := chunks[i]
+			total += c.size
+			f.begin()
+			f.i64(2, c.offset)
+			f.beginField(3)
+			f.i32(1, col.typ)
+			f.list(2, ctI32, 2)
+			f.zigzag(encodingPlain)
+			f.zigzag(encodingRLE)
+			f.list(3, ctBinary, 1)
+			f.bytes(col.name)
+			f.i32(4, codecUncompressed)
+			f.i64(5, int64(len(records)))
+			f.i64(6, c.size)
+			f.i64(7, c.size)
+			f.i64(9, c.offset)
+			f.end()
+			f.end()
+		}
+		f.i64(2, total)
+		f.i64(3, int64(len(records)))
+		f.end()
+	}
+	f.binary(6, "s3-encryption-gateway")
+	f.end()
+
+	file = append(file, f.buf...)
+	file = binary.LittleEndian.AppendUint32(file, uint32(len(f.buf)))
+	file = append(file, parquetMagic...)
+	_, err := w.Write(file)
+	return err
+}
+
+// pageHeader encodes the header of a PLAIN data page of n required values.
+func pageHeader(size, n int) []byte {
+	var h compactWriter
+	h.begin()
+	h.i32(1, pageData)
+	h.i32(2, int32(size))
+	h.i32(3, int32(size))
+	h.beginField(5)
+	h.i32(1, int32(n))
+	h.i32(2, encodingPlain)
+	h.i32(3, encodingRLE)
+	h.i32(4, encodingRLE)
+	h.end()
+	h.end()
+	return h.buf
+}
+
+// Thrift compact protocol type codes.
+const (
+	ctI32    = 5
+	ctI64    = 6
+	ctBinary = 8
+	ctList   = 9
+	ctStruct = 12
+)
+
+// compactWriter encodes Thrift structures in the compact protocol. Field
+// ids are delta-encoded against the previous field of the same struct, so
+// it keeps the last id of every open struct.
+type compactWriter struct {
+	buf  []byte
+	last []int16
+}
+
+// begin opens a struct: the top-level one or an element of a list.
+func (w *compactWriter) begin() {
+	w.last = append(w.last, 0)
+}
+
+// beginField opens a struct-valued field.
+func (w *compactWriter) beginField(id int16) {
+	w.field(id, ctStruct)
+	w.begin()
+}
+
+// end writes the stop byte of the innermost open struct.
+func (w *compactWriter) end() {
+	w.buf = append(w.buf, 0)
+	w.last = w.last[:len(w.last)-1]
+}
+
+func (w *compactWriter) field(id int16, typ byte) {
+	last := &w.last[len(w.last)-1]
+	if d := id - *last; d > 0 && d <= 15 {
+		w.buf = append(w.buf, byte(d)<<4|typ)
+	} else {
+		w.buf = append(w.buf, typ)
+		w.zigzag(int64(id))
+	}
+	*last = id
+}
+
+func (w *compactWriter) zigzag(v int64) {
+	w.buf = binary.AppendUvarint(w.buf, uint64(v<<1)^uint64(v>>63))
+}
+
+func (w *compactWriter) bytes(s string) {
+	w.buf = binary.AppendUvarint(w.buf, uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *compactWriter) i32(id int16, v int32) {
+	w.field(id, ctI32)
+	w.zigzag(int64(v))
+}
+
+func (w *compactWriter) i64(id int16, v int64) {
+	w.field(id, ctI64)
+	w.zigzag(v)
+}
+
+func (w *compactWriter) binary(id int16, s string) {
+	w.field(id, ctBinary)
+	w.bytes(s)
+}
+
+// list writes the header of a list field of n elements of type elem; the
+// elements follow.
+func (w *compactWriter) list(id int16, elem byte, n int) {
+	w.field(id, ctList)
+	if n < 15 {
+		w.buf = append(w.buf, byte(n)<<4|elem)
+		return
+	}
+	w.buf = append(w.buf, 0xf0|elem)
+	w.buf = binary.AppendUvarint(w.buf, uint64(n))
+}
@@ -0,0 +1,170 @@
+package billing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// compactReader decodes the Thrift compact protocol into maps from field
+// id to value: int64, []byte, bool, []any or a nested map.
+type compactReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *compactReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		panic("bad varint")
+	}
+	r.pos += n
+	return v
+}
+
+func (r *compactReader) zigzag() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *compactReader) value(typ byte) any {
+	switch typ {
+	case 1:
+		return true
+	case 2:
+		return false
+	case ctI32, ctI64:
+		return r.zigzag()
+	case ctBinary:
+		n := int(r.uvarint())
+		b := r.buf[r.pos : r.pos+n]
+		r.pos += n
+		return b
+	case ctList:
+		h := r.buf[r.pos]
+		r.pos++
+		n, elem := int(h>>4), h&0x0f
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		out := make([]any, n)
+		for i := range out {
+			out[i] = r.value(elem)
+		}
+		return out
+	case ctStruct:
+		return r.structure()
+	}
+	panic(fmt.Sprintf("unexpected type %d", typ))
+}
+
+func (r *compactReader) structure() map[int16]any {
+	out := map[int16]any{}
+	var last int16
+	for {
+		h := r.buf[r.pos]
+		r.pos++
+		if h == 0 {
+			return out
+		}
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		out[id] = r.value(h & 0x0f)
+		last = id
+	}
+}
+
+func TestWriteParquet(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	var records []Record
+	for i := 0; i < 20; i++ {
+		records = append(records, Record{
+			PeriodStart: start, PeriodEnd: end,
+			Tenant:   fmt.Sprintf("tenant-%02d", i),
+			Requests: int64(i), BytesIn: int64(i * 100), BytesOut: -1,
+			StorageBytes: 1 << 40, StorageObjects: 3, StorageKnown: i%3 == 0,
+		})
+	}
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+	file := buf.Bytes()
+	if !bytes.HasPrefix(file, parquetMagic) || !bytes.HasSuffix(file, parquetMagic) {
+		t.Fatal("missing magic")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	footer := &compactReader{buf: file[len(file)-8-footerLen : len(file)-8]}
+	meta := footer.structure()
+	if footer.pos != footerLen {
+		t.Fatalf("footer decoded %d of %d bytes", footer.pos, footerLen)
+	}
+	if meta[3].(int64) != 20 {
+		t.Errorf("num_rows = %v", meta[3])
+	}
+	schema := meta[2].([]any)
+	if len(schema) != len(Columns)+1 || schema[0].(map[int16]any)[5].(int64) != int64(len(Columns)) {
+		t.Fatalf("schema = %v", schema)
+	}
+	for i, name := range Columns {
+		if got := string(schema[i+1].(map[int16]any)[4].([]byte)); got != name {
+			t.Errorf("column %d = %q, want %q", i, got, name)
+		}
+	}
+
+	// Read every column back from its data page.
+	groups := meta[4].([]any)
+	chunks := groups[0].(map[int16]any)[1].([]any)
+	values := map[string][]byte{}
+	for i, c := range chunks {
+		cm := c.(map[int16]any)[3].(map[int16]any)
+		page := &compactReader{buf: file, pos: int(cm[9].(int64))}
+		header := page.structure()
+		size := int(header[3].(int64))
+		if n := header[5].(map[int16]any)[1].(int64); n != 20 {
+			t.Errorf("%s page holds %d values", Columns[i], n)
+		}
+		values[Columns[i]] = file[page.pos : page.pos+size]
+		if got := int64(page.pos+size) - cm[9].(int64); got != cm[6].(int64) {
+			t.Errorf("%s chunk size %d, metadata says %d", Columns[i], got, cm[6])
+		}
+	}
+	int64At := func(col string, i int) int64 {
+		return int64(binary.LittleEndian.Uint64(values[col][8*i:]))
+	}
+	if int64At("period_end", 5) != end.UnixMilli() || int64At("bytes_in", 7) != 700 || int64At("bytes_out", 0) != -1 || int64At("storage_bytes", 19) != 1<<40 {
+		t.Error("int64 column values differ")
+	}
+	tenants := values["tenant"]
+	for i := 0; i < 20; i++ {
+		n := int(binary.LittleEndian.Uint32(tenants))
+		if got, want := string(tenants[4:4+n]), records[i].Tenant; got != want {
+			t.Fatalf("tenant %d = %q, want %q", i, got, want)
+		}
+		tenants = tenants[4+n:]
+		if known := values["storage_known"][i/8]>>(i%8)&1 == 1; known != records[i].StorageKnown {
+			t.Errorf("storage_known %d = %v", i, known)
+		}
+	}
+}
+
+func TestWriteParquet_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	file := buf.Bytes()
+	footerLen := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	if 4+footerLen+8 != len(file) {
+		t.Fatalf("empty file is %d bytes with a %d byte footer", len(file), footerLen)
+	}
+	meta := (&compactReader{buf: file[4 : 4+footerLen]}).structure()
+	if meta[3].(int64) != 0 || len(meta[4].([]any)) != 0 {
+		t.Errorf("empty file metadata = %v", meta)
+	}
+}
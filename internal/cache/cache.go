@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/clock"
+	"github.com/kenneth/s3-encryption-gateway/internal/membudget"
 )
 
 // CacheEntry represents a cached item.
@@ -12,11 +15,28 @@ type CacheEntry struct {
 	Data      []byte
 	Metadata  map[string]string
 	ExpiresAt time.Time
+	// ETag is the backend ETag of the object Data was read from, empty when
+	// unknown. Only entries with one can be revalidated.
+	ETag string
+	// ValidatedAt is when Data was last known to match the backend.
+	ValidatedAt time.Time
+
+	staleUntil time.Time // end of the revalidation window; zero when none
 }
 
 // IsExpired checks if the cache entry has expired.
 func (e *CacheEntry) IsExpired() bool {
-	return time.Now().After(e.ExpiresAt)
+	return e.expiredAt(time.Now())
+}
+
+func (e *CacheEntry) expiredAt(now time.Time) bool {
+	return now.After(e.ExpiresAt)
+}
+
+// droppedAt reports whether the entry is past any use at now: expired and
+// outside its revalidation window.
+func (e *CacheEntry) droppedAt(now time.Time) bool {
+	return e.expiredAt(now) && !now.Before(e.staleUntil)
 }
 
 // Cache is an interface for caching objects.
@@ -26,6 +46,21 @@ type Cache interface {
 	
 	// Set stores an object in the cache.
 	Set(ctx context.Context, bucket, key string, data []byte, metadata map[string]string, ttl time.Duration) error
+
+	// SetWithETag stores an object read from the backend when its ETag was
+	// etag. Once the entry expires it is kept for the revalidation window,
+	// if the cache has one, for GetStale and Revalidate.
+	SetWithETag(ctx context.Context, bucket, key, etag string, data []byte, metadata map[string]string, ttl time.Duration) error
+
+	// GetStale returns an expired entry still inside its revalidation
+	// window. It does not count as a hit or a miss.
+	GetStale(ctx context.Context, bucket, key string) (*CacheEntry, bool)
+
+	// Revalidate makes the stale entry of bucket/key fresh again for ttl,
+	// after the backend confirmed that the object still has etag, and
+	// returns it. It reports false when the entry is gone or was replaced by
+	// one read at another ETag.
+	Revalidate(ctx context.Context, bucket, key, etag string, ttl time.Duration) (*CacheEntry, bool)
 	
 	// Delete removes an object from the cache.
 	Delete(ctx context.Context, bucket, key string) error
@@ -41,9 +76,10 @@ type Cache interface {
 type CacheStats struct {
 	Size      int64
 	Items     int
-	Hits      int64
-	Misses    int64
-	Evictions int64
+	Hits          int64
+	Misses        int64
+	Evictions     int64
+	Revalidations int64
 }
 
 // memoryCache is an in-memory implementation of Cache.
@@ -54,16 +90,55 @@ type memoryCache struct {
 	maxItems int
 	stats    CacheStats
 	ttl      time.Duration
+	budget   *membudget.Budget // nil when entries are bounded by maxSize alone
+	clock    clock.Clock
+	// revalidateFor is how long entries with an ETag are kept past their
+	// TTL for revalidation; zero drops them on expiry.
+	revalidateFor time.Duration
+}
+
+// Option configures an in-memory cache.
+type Option func(*memoryCache)
+
+// WithClock makes entry expiry follow clk instead of the wall clock.
+func WithClock(clk clock.Clock) Option {
+	return func(c *memoryCache) {
+		c.clock = clock.OrReal(clk)
+	}
+}
+
+// WithRevalidation keeps entries stored with an ETag for window past their
+// TTL, so a read can revalidate them with a conditional backend GET instead
+// of fetching the object again. Stale entries still count against the size
+// limits and are evicted before fresh ones.
+func WithRevalidation(window time.Duration) Option {
+	return func(c *memoryCache) {
+		c.revalidateFor = window
+	}
 }
 
 // NewMemoryCache creates a new in-memory cache.
-func NewMemoryCache(maxSize int64, maxItems int, defaultTTL time.Duration) Cache {
-	return &memoryCache{
+func NewMemoryCache(maxSize int64, maxItems int, defaultTTL time.Duration, opts ...Option) Cache {
+	c := &memoryCache{
 		entries: make(map[string]*CacheEntry),
 		maxSize: maxSize,
 		maxItems: maxItems,
 		ttl:     defaultTTL,
+		clock:   clock.Real,
+	}
+	for _, o := range opts {
+		o(c)
 	}
+	return c
+}
+
+// NewMemoryCacheWithBudget creates an in-memory cache whose entries also
+// reserve their size from budget. An entry the budget cannot hold right
+// away is not cached.
+func NewMemoryCacheWithBudget(maxSize int64, maxItems int, defaultTTL time.Duration, budget *membudget.Budget, opts ...Option) Cache {
+	c := NewMemoryCache(maxSize, maxItems, defaultTTL, opts...).(*memoryCache)
+	c.budget = budget
+	return c
 }
 
 // cacheKey generates a cache key from bucket and object key.
@@ -83,7 +158,7 @@ func (c *memoryCache) Get(ctx context.Context, bucket, key string) (*CacheEntry,
 		return nil, false
 	}
 	
-	if entry.IsExpired() {
+	if entry.expiredAt(c.clock.Now()) {
 		c.stats.Misses++
 		return nil, false
 	}
@@ -92,16 +167,66 @@ func (c *memoryCache) Get(ctx context.Context, bucket, key string) (*CacheEntry,
 	return entry, true
 }
 
+// GetStale returns an expired entry that can still be revalidated.
+func (c *memoryCache) GetStale(ctx context.Context, bucket, key string) (*CacheEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[cacheKey(bucket, key)]
+	now := c.clock.Now()
+	if !ok || entry.ETag == "" || !entry.expiredAt(now) || entry.droppedAt(now) {
+		return nil, false
+	}
+	return entry, true
+}
+
+// Revalidate renews a stale entry the backend has confirmed.
+func (c *memoryCache) Revalidate(ctx context.Context, bucket, key, etag string, ttl time.Duration) (*CacheEntry, bool) {
+	if ttl == 0 {
+		ttl = c.ttl
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keyStr := cacheKey(bucket, key)
+	entry, ok := c.entries[keyStr]
+	now := c.clock.Now()
+	if !ok || etag == "" || entry.ETag != etag || entry.droppedAt(now) {
+		return nil, false
+	}
+	// Replace rather than update the entry: callers may still be reading
+	// the old one.
+	renewed := *entry
+	renewed.ExpiresAt = now.Add(ttl)
+	renewed.ValidatedAt = now
+	renewed.staleUntil = renewed.ExpiresAt.Add(c.revalidateFor)
+	c.entries[keyStr] = &renewed
+	c.stats.Revalidations++
+	return &renewed, true
+}
+
 // Set stores an object in the cache.
 func (c *memoryCache) Set(ctx context.Context, bucket, key string, data []byte, metadata map[string]string, ttl time.Duration) error {
+	return c.SetWithETag(ctx, bucket, key, "", data, metadata, ttl)
+}
+
+// SetWithETag stores an object in the cache together with its backend ETag.
+func (c *memoryCache) SetWithETag(ctx context.Context, bucket, key, etag string, data []byte, metadata map[string]string, ttl time.Duration) error {
 	if ttl == 0 {
 		ttl = c.ttl
 	}
 	
+	now := c.clock.Now()
 	entry := &CacheEntry{
-		Data:      data,
-		Metadata:  metadata,
-		ExpiresAt: time.Now().Add(ttl),
+		Data:        data,
+		Metadata:    metadata,
+		ExpiresAt:   now.Add(ttl),
+		ETag:        etag,
+		ValidatedAt: now,
+	}
+	if etag != "" && c.revalidateFor > 0 {
+		entry.staleUntil = entry.ExpiresAt.Add(c.revalidateFor)
 	}
 	
 	c.mu.Lock()
@@ -122,6 +247,10 @@ func (c *memoryCache) Set(ctx context.Context, bucket, key string, data []byte,
 	}
 	
 	keyStr := cacheKey(bucket, key)
+	c.removeLocked(keyStr)
+	if !c.budget.TryAcquire(membudget.SubsystemCache, entrySize) {
+		return fmt.Errorf("cache: memory budget exhausted")
+	}
 	c.entries[keyStr] = entry
 	
 	return nil
@@ -132,8 +261,7 @@ func (c *memoryCache) Delete(ctx context.Context, bucket, key string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
-	keyStr := cacheKey(bucket, key)
-	delete(c.entries, keyStr)
+	c.removeLocked(cacheKey(bucket, key))
 	
 	return nil
 }
@@ -143,7 +271,9 @@ func (c *memoryCache) Clear(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
-	c.entries = make(map[string]*CacheEntry)
+	for keyStr := range c.entries {
+		c.removeLocked(keyStr)
+	}
 	c.stats = CacheStats{}
 	
 	return nil
@@ -164,19 +294,22 @@ func (c *memoryCache) Stats() CacheStats {
 // getCurrentSizeLocked calculates the current cache size (must be called with lock held).
 func (c *memoryCache) getCurrentSizeLocked() int64 {
 	var size int64
+	now := c.clock.Now()
 	for _, entry := range c.entries {
-		if !entry.IsExpired() {
+		if !entry.droppedAt(now) {
 			size += int64(len(entry.Data))
 		}
 	}
 	return size
 }
 
-// evictExpiredLocked removes expired entries (must be called with lock held).
+// evictExpiredLocked removes expired entries outside their revalidation
+// window (must be called with lock held).
 func (c *memoryCache) evictExpiredLocked() {
+	now := c.clock.Now()
 	for key, entry := range c.entries {
-		if entry.IsExpired() {
-			delete(c.entries, key)
+		if entry.droppedAt(now) {
+			c.removeLocked(key)
 			c.stats.Evictions++
 		}
 	}
@@ -195,17 +328,39 @@ func (c *memoryCache) evictForSpaceLocked(neededSpace int64) bool {
 		return true
 	}
 	
+	// Remove stale entries kept for revalidation before fresh ones.
+	targetSize := c.maxSize - neededSpace
+	now := c.clock.Now()
+	for key, entry := range c.entries {
+		if currentSize <= targetSize && len(c.entries) < c.maxItems {
+			return true
+		}
+		if entry.expiredAt(now) {
+			c.removeLocked(key)
+			c.stats.Evictions++
+			currentSize -= int64(len(entry.Data))
+		}
+	}
+
 	// Remove oldest entries (simplified - in production use proper LRU)
 	// For now, just remove enough entries
-	targetSize := c.maxSize - neededSpace
 	for key, entry := range c.entries {
 		if currentSize <= targetSize && len(c.entries) < c.maxItems {
 			break
 		}
-		delete(c.entries, key)
+		c.removeLocked(key)
 		c.stats.Evictions++
 		currentSize -= int64(len(entry.Data))
 	}
 	
 	return true
 }
+
+// removeLocked deletes an entry and returns its size to the memory budget
+// (must be called with lock held).
+func (c *memoryCache) removeLocked(keyStr string) {
+	if entry, ok := c.entries[keyStr]; ok {
+		delete(c.entries, keyStr)
+		c.budget.Release(membudget.SubsystemCache, int64(len(entry.Data)))
+	}
+}
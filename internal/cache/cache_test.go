@@ -5,6 +5,10 @@ import (
 	"fmt"
 	"testing"
 	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/clock"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/membudget"
 )
 
 func TestMemoryCache_GetSet(t *testing.T) {
@@ -61,6 +65,76 @@ func TestMemoryCache_Expiration(t *testing.T) {
 	}
 }
 
+func TestMemoryCache_ExpirationFakeClock(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	cache := NewMemoryCache(1024*1024, 100, time.Hour, WithClock(clk))
+	ctx := context.Background()
+	if err := cache.Set(ctx, "bucket", "key", []byte("test data"), nil, 0); err != nil {
+		t.Fatalf("failed to set cache: %v", err)
+	}
+
+	clk.Advance(time.Hour)
+	if _, ok := cache.Get(ctx, "bucket", "key"); !ok {
+		t.Fatal("entry expired at its TTL, want it served until the TTL has passed")
+	}
+	clk.Advance(time.Nanosecond)
+	if _, ok := cache.Get(ctx, "bucket", "key"); ok {
+		t.Fatal("entry served after its TTL")
+	}
+	if st := cache.Stats(); st.Size != 0 {
+		t.Errorf("expired entry counted in size %d", st.Size)
+	}
+}
+
+func TestMemoryCache_Revalidate(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	cache := NewMemoryCache(1024*1024, 100, time.Minute, WithClock(clk), WithRevalidation(time.Hour))
+	ctx := context.Background()
+	if err := cache.SetWithETag(ctx, "bucket", "key", `"v1"`, []byte("test data"), nil, 0); err != nil {
+		t.Fatalf("failed to set cache: %v", err)
+	}
+	if err := cache.Set(ctx, "bucket", "no-etag", []byte("test data"), nil, 0); err != nil {
+		t.Fatalf("failed to set cache: %v", err)
+	}
+
+	if _, ok := cache.GetStale(ctx, "bucket", "key"); ok {
+		t.Fatal("fresh entry returned as stale")
+	}
+	clk.Advance(2 * time.Minute)
+	if _, ok := cache.Get(ctx, "bucket", "key"); ok {
+		t.Fatal("entry served after its TTL")
+	}
+	if _, ok := cache.GetStale(ctx, "bucket", "no-etag"); ok {
+		t.Fatal("entry without an ETag kept for revalidation")
+	}
+	stale, ok := cache.GetStale(ctx, "bucket", "key")
+	if !ok || stale.ETag != `"v1"` {
+		t.Fatalf("GetStale = %+v, %v; want the v1 entry", stale, ok)
+	}
+
+	if _, ok := cache.Revalidate(ctx, "bucket", "key", `"v2"`, 0); ok {
+		t.Fatal("revalidated with a different ETag")
+	}
+	entry, ok := cache.Revalidate(ctx, "bucket", "key", `"v1"`, 0)
+	if !ok || string(entry.Data) != "test data" || !entry.ValidatedAt.Equal(clk.Now()) {
+		t.Fatalf("Revalidate = %+v, %v", entry, ok)
+	}
+	if _, ok := cache.Get(ctx, "bucket", "key"); !ok {
+		t.Fatal("revalidated entry not served")
+	}
+	if st := cache.Stats(); st.Revalidations != 1 {
+		t.Errorf("Revalidations = %d, want 1", st.Revalidations)
+	}
+
+	clk.Advance(2 * time.Hour)
+	if _, ok := cache.GetStale(ctx, "bucket", "key"); ok {
+		t.Fatal("entry kept past its revalidation window")
+	}
+	if st := cache.Stats(); st.Size != 0 {
+		t.Errorf("dropped entry counted in size %d", st.Size)
+	}
+}
+
 func TestMemoryCache_Delete(t *testing.T) {
 	cache := NewMemoryCache(1024*1024, 100, 5*time.Minute)
 	ctx := context.Background()
@@ -218,3 +292,36 @@ func TestMemoryCache_Clear(t *testing.T) {
 		t.Fatalf("expected 0 items after clear, got %d", stats.Items)
 	}
 }
+
+func TestMemoryCache_Budget(t *testing.T) {
+	budget := membudget.New(config.MemoryBudgetConfig{Enabled: true, Limit: 100}, nil)
+	cache := NewMemoryCacheWithBudget(1024, 100, 5*time.Minute, budget)
+	ctx := context.Background()
+	reserved := func() int64 { return budget.Stats().BySystem[membudget.SubsystemCache] }
+
+	if err := cache.Set(ctx, "bucket", "a", make([]byte, 60), nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Set(ctx, "bucket", "b", make([]byte, 60), nil, 0); err == nil {
+		t.Fatal("entry beyond the memory budget was cached")
+	}
+	if _, ok := cache.Get(ctx, "bucket", "b"); ok {
+		t.Fatal("refused entry is readable")
+	}
+	// Overwriting an entry returns the old size first.
+	if err := cache.Set(ctx, "bucket", "a", make([]byte, 90), nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := reserved(); got != 90 {
+		t.Fatalf("reserved = %d, want 90", got)
+	}
+	cache.Delete(ctx, "bucket", "a")
+	if got := reserved(); got != 0 {
+		t.Fatalf("reserved after delete = %d", got)
+	}
+	cache.Set(ctx, "bucket", "c", make([]byte, 40), nil, 0)
+	cache.Clear(ctx)
+	if got := reserved(); got != 0 {
+		t.Fatalf("reserved after clear = %d", got)
+	}
+}
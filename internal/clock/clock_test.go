@@ -0,0 +1,75 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFake_Advance(t *testing.T) {
+	f := NewFake(epoch)
+	f.Advance(90 * time.Second)
+	if got := f.Now(); !got.Equal(epoch.Add(90 * time.Second)) {
+		t.Fatalf("Now() = %v", got)
+	}
+	f.Set(epoch)
+	if got := f.Now(); !got.Equal(epoch) {
+		t.Fatalf("Now() after Set = %v", got)
+	}
+}
+
+func TestFake_Ticker(t *testing.T) {
+	f := NewFake(epoch)
+	tk := f.NewTicker(time.Second)
+	if f.Tickers() != 1 {
+		t.Fatalf("Tickers() = %d", f.Tickers())
+	}
+
+	f.Advance(999 * time.Millisecond)
+	select {
+	case <-tk.C():
+		t.Fatal("ticker fired early")
+	default:
+	}
+
+	f.Advance(time.Millisecond)
+	select {
+	case at := <-tk.C():
+		if !at.Equal(epoch.Add(time.Second)) {
+			t.Errorf("tick at %v", at)
+		}
+	default:
+		t.Fatal("ticker did not fire when due")
+	}
+
+	// Like time.Ticker, ticks nobody receives are dropped, not queued.
+	f.Advance(5 * time.Second)
+	<-tk.C()
+	select {
+	case <-tk.C():
+		t.Fatal("missed ticks were queued")
+	default:
+	}
+
+	tk.Stop()
+	f.Advance(time.Minute)
+	select {
+	case <-tk.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+	if f.Tickers() != 0 {
+		t.Errorf("Tickers() after Stop = %d", f.Tickers())
+	}
+}
+
+func TestOrReal(t *testing.T) {
+	if OrReal(nil) != Real {
+		t.Error("OrReal(nil) is not Real")
+	}
+	f := NewFake(epoch)
+	if OrReal(f) != f {
+		t.Error("OrReal dropped the given clock")
+	}
+}
@@ -8,9 +8,13 @@ import (
 	"io/fs"
 	"log/slog"
 	"net"
+	"net/url"
 	"os"
 	"os/signal"
+	"regexp"
+	"slices"
 	"strconv"
+	"path"
 	"strings"
 	"sync"
 	"syscall"
@@ -19,6 +23,8 @@ import (
 	"github.com/fsnotify/fsnotify"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/airgap"
 )
 
 // Config holds the complete application configuration.
@@ -41,6 +47,35 @@ type Config struct {
 	Auth           AuthConfig           `yaml:"auth"`
 	PolicyFiles    []string             `yaml:"policies" env:"POLICIES"`
 	MultipartState MultipartStateConfig `yaml:"multipart_state"`
+	SLO            SLOConfig            `yaml:"slo"`
+	Journal        JournalConfig        `yaml:"journal"`
+	ListEnrichment ListEnrichmentConfig `yaml:"list_enrichment"`
+	SizeIndex      SizeIndexConfig      `yaml:"size_index"`
+	AccessStats    AccessStatsConfig    `yaml:"access_stats"`
+	Tiering        TieringConfig        `yaml:"tiering"`
+	Sharding       ShardingConfig       `yaml:"sharding"`
+	Quota          QuotaConfig          `yaml:"quota"`
+	Billing        BillingConfig        `yaml:"billing"`
+	DevMode        DevModeConfig        `yaml:"dev_mode"`
+	KeyHold        KeyHoldConfig        `yaml:"key_hold"`
+	Trash          TrashConfig          `yaml:"trash"`
+	Batch          BatchConfig          `yaml:"batch"`
+	Scheduler      SchedulerConfig      `yaml:"scheduler"`
+	MemoryBudget   MemoryBudgetConfig   `yaml:"memory_budget"`
+	Admission      AdmissionConfig      `yaml:"admission"`
+	// FeatureFlags stage risky behaviours per bucket or share of objects,
+	// keyed by flag name. See internal/featureflag for the known flags.
+	FeatureFlags map[string]FeatureFlag `yaml:"feature_flags"`
+	Hooks          HooksConfig          `yaml:"hooks"`
+	Scanning       ScanningConfig       `yaml:"scanning"`
+	Inspection     InspectionConfig     `yaml:"inspection"`
+	Transforms     TransformsConfig     `yaml:"transforms"`
+	Extensions     ExtensionsConfig     `yaml:"extensions"`
+	SelfTest       SelfTestConfig       `yaml:"self_test"`
+	Canary         CanaryConfig         `yaml:"canary"`
+	Readiness      ReadinessConfig      `yaml:"readiness"`
+	WarmPool       WarmPoolConfig       `yaml:"warm_pool"`
+	AirGapped      AirGappedConfig      `yaml:"air_gapped"`
 }
 
 // ResolvedCredentials returns a copy of the auth credentials with SecretKeyEnv
@@ -55,12 +90,29 @@ func (c *Config) ResolvedCredentials() []GatewayCredential {
 				resolved[i].SecretKey = v
 			}
 		}
+		if cred.SessionTokenEnv != "" {
+			if v := os.Getenv(cred.SessionTokenEnv); v != "" {
+				resolved[i].SessionToken = v
+			}
+		}
 	}
 	return resolved
 }
 
+// Storage backend types for BackendConfig.Type.
+const (
+	BackendTypeS3         = "s3"
+	BackendTypeFilesystem = "filesystem"
+	BackendTypeAzure      = "azure"
+	BackendTypeGCS        = "gcs"
+)
+
 // BackendConfig holds S3 backend configuration.
 type BackendConfig struct {
+	// Type selects the object store: "s3" (default, any S3-compatible API),
+	// "filesystem", "azure" (Azure Blob Storage) or "gcs" (Google Cloud
+	// Storage through its S3-compatible XML API with HMAC keys).
+	Type         string `yaml:"type" env:"BACKEND_TYPE"`
 	Endpoint     string `yaml:"endpoint" env:"BACKEND_ENDPOINT"`
 	Region       string `yaml:"region" env:"BACKEND_REGION"`
 	AccessKey    string `yaml:"access_key" env:"BACKEND_ACCESS_KEY"`
@@ -73,8 +125,289 @@ type BackendConfig struct {
 	// Retry governs the S3 backend retry policy (V0.6-PERF-2).
 	// All fields are optional; zero values fall back to the DefaultBackendRetry* constants.
 	Retry BackendRetryConfig `yaml:"retry"`
+	// ConditionalWrites controls optimistic concurrency for object writes.
+	//   "off" (default) — writes are unconditional; client If-Match /
+	//                     If-None-Match headers are ignored
+	//   "client"        — forward client If-Match / If-None-Match to the backend
+	//   "optimistic"    — as "client", and when the client sends no
+	//                     precondition the gateway conditions the write on the
+	//                     ETag it observed before writing (or on absence),
+	//                     serialising same-key writers within this instance
+	// Backends without conditional-write support ignore the preconditions.
+	ConditionalWrites string `yaml:"conditional_writes" env:"BACKEND_CONDITIONAL_WRITES"`
+	// MetadataOverflow decides what a write does when its encryption
+	// metadata would not fit in the provider's object header limit.
+	//   "fallback" (default) — store the metadata in the object body
+	//                          instead, as the engine does on its own
+	//   "reject"             — fail the write with MetadataTooLarge
+	// Either way, a write whose headers would still exceed the limit is
+	// refused rather than left to the backend to truncate.
+	MetadataOverflow string `yaml:"metadata_overflow" env:"BACKEND_METADATA_OVERFLOW"`
+	// ReadReplicas lists further endpoints serving the same buckets with the
+	// same credentials (other nodes of a MinIO or Ceph cluster, a
+	// replicated site). Only GetObject and HeadObject use them.
+	ReadReplicas BackendReadReplicasConfig `yaml:"read_replicas"`
+	// RangeHedging duplicates slow ciphertext fetches of range reads.
+	RangeHedging BackendRangeHedgingConfig `yaml:"range_hedging"`
+	// HeadElision lets range reads skip the metadata HEAD when it is cached.
+	HeadElision BackendHeadElisionConfig `yaml:"head_elision"`
+	// ParallelRange tunes the parallel_range_fetch feature flag.
+	ParallelRange BackendParallelRangeConfig `yaml:"parallel_range"`
+	// StreamingUpload sends large and unknown-length PUTs to the backend
+	// as multipart uploads.
+	StreamingUpload BackendStreamingUploadConfig `yaml:"streaming_upload"`
+	// Proxy is an http://, https:// or socks5:// proxy URL for all backend
+	// connections, including read replicas. Empty uses HTTPS_PROXY,
+	// HTTP_PROXY and NO_PROXY from the environment.
+	Proxy string `yaml:"proxy" env:"BACKEND_PROXY"`
+	// TLS configures verification of the backend's certificate.
+	TLS BackendTLSConfig `yaml:"tls"`
+	// Filesystem configures the "filesystem" backend type.
+	Filesystem BackendFilesystemConfig `yaml:"filesystem"`
+	// Azure configures the "azure" backend type.
+	Azure BackendAzureConfig `yaml:"azure"`
+	// HeaderRules rewrite every request sent to an S3-compatible backend
+	// just before it is signed, for providers that reject or require
+	// particular headers.
+	HeaderRules []HeaderRule `yaml:"header_rules"`
+}
+
+// StorageType returns the configured backend type, "s3" when unset.
+func (b *BackendConfig) StorageType() string {
+	if b.Type == "" {
+		return BackendTypeS3
+	}
+	return strings.ToLower(b.Type)
+}
+
+// validateStore checks the settings the backend type needs, naming them
+// under field. Credentials are always required for S3-compatible backends;
+// the other types carry their own.
+func (b *BackendConfig) validateStore(field string) error {
+	switch b.StorageType() {
+	case BackendTypeS3, BackendTypeGCS:
+		if b.AccessKey == "" {
+			return fmt.Errorf("%s.access_key is required", field)
+		}
+		if b.SecretKey == "" {
+			return fmt.Errorf("%s.secret_key is required", field)
+		}
+	case BackendTypeFilesystem:
+		if b.Filesystem.Root == "" {
+			return fmt.Errorf("%s.filesystem.root is required for the filesystem backend", field)
+		}
+	case BackendTypeAzure:
+		if b.Azure.AccountName == "" {
+			return fmt.Errorf("%s.azure.account_name is required for the azure backend", field)
+		}
+		if _, err := base64.StdEncoding.DecodeString(b.Azure.AccountKey); err != nil || b.Azure.AccountKey == "" {
+			return fmt.Errorf("%s.azure.account_key must be a base64 storage account key", field)
+		}
+	default:
+		return fmt.Errorf("invalid %s.type: %q (must be s3, filesystem, azure or gcs)", field, b.Type)
+	}
+	return nil
+}
+
+// BackendFilesystemConfig configures a local directory as the object store,
+// for edge devices and tests. Each bucket is a subdirectory of Root.
+type BackendFilesystemConfig struct {
+	Root string `yaml:"root" env:"BACKEND_FILESYSTEM_ROOT"`
+}
+
+// BackendAzureConfig configures Azure Blob Storage. Buckets map to
+// containers and objects to block blobs.
+type BackendAzureConfig struct {
+	AccountName string `yaml:"account_name" env:"BACKEND_AZURE_ACCOUNT_NAME"`
+	// AccountKey is the base64 storage account key used for Shared Key
+	// authorization.
+	AccountKey string `yaml:"account_key" env:"BACKEND_AZURE_ACCOUNT_KEY"`
+	// Endpoint overrides https://<account_name>.blob.core.windows.net, for
+	// sovereign clouds and the Azurite emulator.
+	Endpoint string `yaml:"endpoint" env:"BACKEND_AZURE_ENDPOINT"`
+}
+
+// BackendTLSConfig holds certificate verification settings for backend
+// connections.
+type BackendTLSConfig struct {
+	// CAFile is a PEM bundle trusted in addition to the system roots, for
+	// appliances and proxies with a private CA.
+	CAFile string `yaml:"ca_file" env:"BACKEND_TLS_CA_FILE"`
+	// InsecureSkipVerify disables certificate verification. Development only.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify" env:"BACKEND_TLS_INSECURE_SKIP_VERIFY"`
+}
+
+// Range hedging defaults.
+const (
+	DefaultRangeHedgeMinDelay = 10 * time.Millisecond
+	DefaultRangeHedgeMaxDelay = time.Second
+)
+
+// BackendRangeHedgingConfig configures hedged fetches for range reads of
+// chunked and multipart-encrypted objects. A fetch that has not answered
+// within the recent P99 latency of such fetches, clamped to
+// [MinDelay, MaxDelay], is sent a second time and the slower copy is
+// cancelled. Until enough fetches have been seen, MaxDelay is used.
+type BackendRangeHedgingConfig struct {
+	Enabled  bool          `yaml:"enabled" env:"BACKEND_RANGE_HEDGING_ENABLED"`
+	MinDelay time.Duration `yaml:"min_delay" env:"BACKEND_RANGE_HEDGING_MIN_DELAY"`
+	MaxDelay time.Duration `yaml:"max_delay" env:"BACKEND_RANGE_HEDGING_MAX_DELAY"`
+}
+
+// Validate checks the delay bounds.
+func (r BackendRangeHedgingConfig) Validate() error {
+	if r.MinDelay <= 0 {
+		return fmt.Errorf("backend.range_hedging.min_delay must be positive")
+	}
+	if r.MaxDelay < r.MinDelay {
+		return fmt.Errorf("backend.range_hedging.max_delay must not be less than min_delay")
+	}
+	return nil
+}
+
+// Parallel range fetch defaults.
+const (
+	DefaultParallelRangePartSize    = 8 << 20
+	DefaultParallelRangeConcurrency = 4
+)
+
+// BackendParallelRangeConfig configures parallel ciphertext fetches for
+// range reads of chunked objects, enabled by the parallel_range_fetch
+// feature flag. A range longer than PartSize is fetched as several ranged
+// GETs, up to Concurrency at a time, each buffered in memory, so a read
+// holds at most PartSize*Concurrency bytes. Zero values use the defaults.
+type BackendParallelRangeConfig struct {
+	PartSize    int64 `yaml:"part_size" env:"BACKEND_PARALLEL_RANGE_PART_SIZE"`
+	Concurrency int   `yaml:"concurrency" env:"BACKEND_PARALLEL_RANGE_CONCURRENCY"`
+}
+
+// Streaming upload defaults. The threshold is the largest object S3
+// accepts in a single PUT, and parts other than the last must be at least
+// MinStreamingUploadPartSize.
+const (
+	DefaultStreamingUploadThreshold   = 5 << 30
+	DefaultStreamingUploadPartSize    = 16 << 20
+	DefaultStreamingUploadConcurrency = 4
+	MinStreamingUploadPartSize        = 5 << 20
+)
+
+// BackendStreamingUploadConfig configures how the S3 backend client uploads
+// objects whose ciphertext length is unknown (no Content-Length from the
+// client, compression) or above Threshold. Instead of a single PUT these
+// are sent as a multipart upload of PartSize parts, up to Concurrency at a
+// time, each buffered in memory, so an upload holds at most
+// PartSize*Concurrency bytes whatever the object size. When the length is
+// known the part size grows as needed to stay within 10,000 parts. Zero
+// values use the defaults.
+type BackendStreamingUploadConfig struct {
+	Enabled     bool  `yaml:"enabled" env:"BACKEND_STREAMING_UPLOAD_ENABLED"`
+	Threshold   int64 `yaml:"threshold" env:"BACKEND_STREAMING_UPLOAD_THRESHOLD"`
+	PartSize    int64 `yaml:"part_size" env:"BACKEND_STREAMING_UPLOAD_PART_SIZE"`
+	Concurrency int   `yaml:"concurrency" env:"BACKEND_STREAMING_UPLOAD_CONCURRENCY"`
+}
+
+// Validate checks the upload bounds.
+func (s BackendStreamingUploadConfig) Validate() error {
+	if s.Threshold < 0 || s.Concurrency < 0 {
+		return fmt.Errorf("backend.streaming_upload.threshold and concurrency must not be negative")
+	}
+	if s.PartSize != 0 && s.PartSize < MinStreamingUploadPartSize {
+		return fmt.Errorf("backend.streaming_upload.part_size must be at least %d bytes", MinStreamingUploadPartSize)
+	}
+	return nil
+}
+
+// Head elision defaults.
+const (
+	DefaultHeadElisionTTL        = 30 * time.Second
+	DefaultHeadElisionMaxEntries = 10000
+)
+
+// BackendHeadElisionConfig configures a cache of object metadata for range
+// reads of chunked objects. A range read normally costs a HEAD, to learn the
+// chunk layout, and then a GET of the encrypted range; with a cached layout
+// the GET is sent straight away. The GET's ETag must match the cached one,
+// otherwise the response is dropped and the read starts over with a HEAD.
+type BackendHeadElisionConfig struct {
+	Enabled    bool          `yaml:"enabled" env:"BACKEND_HEAD_ELISION_ENABLED"`
+	TTL        time.Duration `yaml:"ttl" env:"BACKEND_HEAD_ELISION_TTL"`
+	MaxEntries int           `yaml:"max_entries" env:"BACKEND_HEAD_ELISION_MAX_ENTRIES"`
+}
+
+// Validate checks the cache bounds.
+func (e BackendHeadElisionConfig) Validate() error {
+	if e.TTL <= 0 {
+		return fmt.Errorf("backend.head_elision.ttl must be positive")
+	}
+	if e.MaxEntries <= 0 {
+		return fmt.Errorf("backend.head_elision.max_entries must be positive")
+	}
+	return nil
+}
+
+// DefaultReadProbeInterval is how often a failed-over primary is retried
+// when backend.read_replicas.probe_interval is unset.
+const DefaultReadProbeInterval = 30 * time.Second
+
+// BackendReadReplicasConfig configures gateway-level retries of idempotent
+// reads across backend endpoints. These sit above the SDK retries: an
+// endpoint is abandoned only once its own retry policy has given up, or,
+// with hedging, once it has been slower than HedgeAfter.
+type BackendReadReplicasConfig struct {
+	// Endpoints are tried in order after backend.endpoint.
+	Endpoints []string `yaml:"endpoints" env:"BACKEND_READ_REPLICAS"`
+	// HedgeAfter, when positive, sends the same read to the next endpoint
+	// if no response has arrived after this long; the first success wins
+	// and the others are cancelled. Zero only fails over on errors.
+	HedgeAfter time.Duration `yaml:"hedge_after" env:"BACKEND_READ_HEDGE_AFTER"`
+	// FailoverAfter, when positive, takes the primary out of the read path
+	// after this many consecutive reads on which it failed with a
+	// retryable error: reads then go to the replicas first and to the
+	// primary last. Zero fails over per read only.
+	FailoverAfter int `yaml:"failover_after" env:"BACKEND_READ_FAILOVER_AFTER"`
+	// ProbeInterval is how often, while failed over, one read is sent to
+	// the primary first; its first success switches reads back.
+	ProbeInterval time.Duration `yaml:"probe_interval" env:"BACKEND_READ_PROBE_INTERVAL"`
+}
+
+// Validate checks the replica endpoints.
+func (r BackendReadReplicasConfig) Validate() error {
+	for i, ep := range r.Endpoints {
+		if strings.TrimSpace(ep) == "" {
+			return fmt.Errorf("backend.read_replicas.endpoints[%d] is empty", i)
+		}
+	}
+	if r.HedgeAfter < 0 {
+		return fmt.Errorf("backend.read_replicas.hedge_after must not be negative")
+	}
+	if r.HedgeAfter > 0 && len(r.Endpoints) == 0 {
+		return fmt.Errorf("backend.read_replicas.hedge_after requires at least one endpoint")
+	}
+	if r.FailoverAfter < 0 {
+		return fmt.Errorf("backend.read_replicas.failover_after must not be negative")
+	}
+	if r.FailoverAfter > 0 && len(r.Endpoints) == 0 {
+		return fmt.Errorf("backend.read_replicas.failover_after requires at least one endpoint")
+	}
+	if r.ProbeInterval < 0 {
+		return fmt.Errorf("backend.read_replicas.probe_interval must not be negative")
+	}
+	return nil
 }
 
+// Conditional write modes (see BackendConfig.ConditionalWrites).
+const (
+	ConditionalWritesOff        = "off"
+	ConditionalWritesClient     = "client"
+	ConditionalWritesOptimistic = "optimistic"
+)
+
+// Metadata overflow policies (see BackendConfig.MetadataOverflow).
+const (
+	MetadataOverflowFallback = "fallback"
+	MetadataOverflowReject   = "reject"
+)
+
 // BackendRetryConfig governs retries emitted by the S3 backend client.
 // All fields optional; zero values fall back to safe defaults (see
 // DefaultBackendRetry* constants). See docs/adr/0010-backend-retry-policy.md.
@@ -242,8 +575,162 @@ type EncryptionConfig struct {
 	ChunkSize           int              `yaml:"chunk_size" env:"ENCRYPTION_CHUNK_SIZE"`     // Size of each encryption chunk in bytes
 	Hardware            HardwareConfig   `yaml:"hardware"`
 	KDF                 KDFConfig        `yaml:"kdf"`
+	// MetadataEncryption controls how user metadata (x-amz-meta-*) is stored
+	// on the backend.
+	//   "off" (default) — stored as sent
+	//   "values"        — each value is encrypted; header names stay visible
+	//   "all"           — names and values are sealed into a single header
+	// Sealed metadata is always unsealed on GET/HEAD, whatever this is set to.
+	MetadataEncryption string `yaml:"metadata_encryption" env:"ENCRYPTION_METADATA_ENCRYPTION"`
+	// TagEncryption encrypts object tag values, in x-amz-tagging and
+	// PutObjectTagging alike, before they reach the backend; tag keys stay
+	// visible. Backend lifecycle rules and policies can then no longer
+	// match on the values. Sealed values are decrypted on GetObjectTagging
+	// whatever this is set to.
+	TagEncryption bool `yaml:"tag_encryption" env:"ENCRYPTION_TAG_ENCRYPTION"`
+	// KeyObfuscation stores objects under deterministically encrypted names
+	// so the backend never sees real keys. Objects written before it was
+	// enabled are no longer reachable through the gateway.
+	KeyObfuscation bool `yaml:"key_obfuscation" env:"ENCRYPTION_KEY_OBFUSCATION"`
+	// KeyObfuscationScheme selects how names are encoded when KeyObfuscation
+	// is on:
+	//   "path" (default) — per segment, bound to the parent path; prefix and
+	//                      delimiter listing run on the backend
+	//   "segment"        — per segment only; a name reused in different
+	//                      directories encodes identically
+	//   "full"           — whole key as one name; hides depth and shared
+	//                      parents, but every listing scans the bucket
+	// Objects written under one scheme are not readable under another.
+	KeyObfuscationScheme string `yaml:"key_obfuscation_scheme" env:"ENCRYPTION_KEY_OBFUSCATION_SCHEME"`
+	// MetadataKeyPrefix renames the gateway's own metadata headers on the
+	// backend from x-amz-meta-encryption-* (and every other gateway name:
+	// the encrypted markers, compression-*, original-*, s3eg-* and the
+	// compacted short names) to x-amz-meta-<prefix>*. Empty keeps the
+	// default names. Objects stored under the default names stay readable.
+	MetadataKeyPrefix string `yaml:"metadata_key_prefix" env:"ENCRYPTION_METADATA_KEY_PREFIX"`
+	// LazyUpgrade rewrites legacy (single-IV) objects in chunked format
+	// after they have been read in full through the gateway.
+	LazyUpgrade LazyUpgradeConfig `yaml:"lazy_upgrade"`
+	// IdentityMarker stamps a signed gateway marker on every object written
+	// and checks it when the object is read.
+	IdentityMarker IdentityMarkerConfig `yaml:"identity_marker"`
+	// DoubleEncryption decides what a plain PUT or multipart upload does
+	// when its x-amz-meta-* headers already carry the gateway's encryption
+	// metadata, as happens when objects copied from the backend are written
+	// back through the gateway:
+	//   "reject" (default) — refuse the request
+	//   "passthrough"      — store body and metadata as sent, like a raw
+	//                        write; multipart uploads are still refused
+	//   "encrypt"          — encrypt again, nesting the ciphertext
+	DoubleEncryption string `yaml:"double_encryption" env:"ENCRYPTION_DOUBLE_ENCRYPTION"`
+	// BodyHeader prepends a small header holding the encryption metadata
+	// (magic bytes, format version, chunk manifest) to the body of chunked
+	// objects, so copies to stores that strip user metadata can still be
+	// decrypted with s3eg-migrate decrypt-file.
+	BodyHeader bool `yaml:"body_header" env:"ENCRYPTION_BODY_HEADER"`
+	// HybridWrap additionally wraps every KMS data key to a post-quantum
+	// hybrid public key, for archives that must stay recoverable offline.
+	HybridWrap HybridWrapConfig `yaml:"hybrid_wrap"`
+}
+
+// HybridWrapConfig configures the X25519 + ML-KEM-768 wrap of data keys.
+// The gateway only needs the public key; the private key, generated with
+// s3eg-migrate hybrid-keygen, is kept offline and given to
+// s3eg-migrate decrypt-file to recover objects without the KMS.
+type HybridWrapConfig struct {
+	Enabled       bool   `yaml:"enabled" env:"ENCRYPTION_HYBRID_WRAP_ENABLED"`
+	PublicKeyFile string `yaml:"public_key_file" env:"ENCRYPTION_HYBRID_WRAP_PUBLIC_KEY_FILE"`
+}
+
+// Validate checks an enabled hybrid wrap.
+func (h HybridWrapConfig) Validate() error {
+	if h.PublicKeyFile == "" {
+		return fmt.Errorf("encryption.hybrid_wrap.public_key_file is required when hybrid_wrap is enabled")
+	}
+	return nil
+}
+
+// IdentityMarkerConfig configures the signed marker that tells objects
+// written through the gateway apart from ones written or altered by other
+// tools. The marker key is derived from the encryption password, so every
+// replica sharing it accepts the others' markers.
+type IdentityMarkerConfig struct {
+	Enabled bool `yaml:"enabled" env:"ENCRYPTION_IDENTITY_MARKER_ENABLED"`
+	// GatewayID is recorded in the marker to show which gateway wrote an
+	// object. Empty uses the host name.
+	GatewayID string `yaml:"gateway_id" env:"ENCRYPTION_IDENTITY_MARKER_GATEWAY_ID"`
+	// Reject fails reads of encrypted objects whose marker is missing or
+	// invalid instead of only counting them. Objects written before markers
+	// were enabled have none, so turn this on only once they are rewritten.
+	Reject bool `yaml:"reject" env:"ENCRYPTION_IDENTITY_MARKER_REJECT"`
+}
+
+var gatewayIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Validate checks an enabled identity marker.
+func (m IdentityMarkerConfig) Validate() error {
+	if m.GatewayID != "" && !gatewayIDPattern.MatchString(m.GatewayID) {
+		return fmt.Errorf("encryption.identity_marker.gateway_id must be 1-64 letters, digits, '.', '_' or '-'")
+	}
+	return nil
+}
+
+// LazyUpgradeConfig configures the background rewrite of legacy objects.
+// Upgrades are best effort: candidates beyond QueueSize are dropped, and
+// an object that changed since it was read is left alone.
+type LazyUpgradeConfig struct {
+	Enabled bool `yaml:"enabled" env:"ENCRYPTION_LAZY_UPGRADE_ENABLED"`
+	// Rate is the maximum number of objects rewritten per second.
+	Rate float64 `yaml:"rate" env:"ENCRYPTION_LAZY_UPGRADE_RATE"`
+	// MaxObjectSize skips objects larger than this many bytes. Legacy
+	// objects are decrypted in memory, so this bounds the rewrite's heap.
+	MaxObjectSize int64 `yaml:"max_object_size" env:"ENCRYPTION_LAZY_UPGRADE_MAX_OBJECT_SIZE"`
+	// QueueSize bounds the objects waiting to be rewritten.
+	QueueSize int `yaml:"queue_size" env:"ENCRYPTION_LAZY_UPGRADE_QUEUE_SIZE"`
+}
+
+// Default lazy upgrade settings.
+const (
+	DefaultLazyUpgradeRate          = 1.0
+	DefaultLazyUpgradeMaxObjectSize = 64 << 20
+	DefaultLazyUpgradeQueueSize     = 1000
+)
+
+// Validate checks an enabled lazy upgrade.
+func (u LazyUpgradeConfig) Validate() error {
+	if u.Rate <= 0 {
+		return fmt.Errorf("encryption.lazy_upgrade.rate must be positive")
+	}
+	if u.MaxObjectSize <= 0 {
+		return fmt.Errorf("encryption.lazy_upgrade.max_object_size must be positive")
+	}
+	if u.QueueSize < 1 {
+		return fmt.Errorf("encryption.lazy_upgrade.queue_size must be at least 1")
+	}
+	return nil
 }
 
+// Key obfuscation schemes (see EncryptionConfig.KeyObfuscationScheme).
+const (
+	KeyObfuscationPath    = "path"
+	KeyObfuscationSegment = "segment"
+	KeyObfuscationFull    = "full"
+)
+
+// Double-encryption modes (see EncryptionConfig.DoubleEncryption).
+const (
+	DoubleEncryptionReject      = "reject"
+	DoubleEncryptionPassthrough = "passthrough"
+	DoubleEncryptionEncrypt     = "encrypt"
+)
+
+// Metadata encryption modes (see EncryptionConfig.MetadataEncryption).
+const (
+	MetadataEncryptionOff    = "off"
+	MetadataEncryptionValues = "values"
+	MetadataEncryptionAll    = "all"
+)
+
 // HardwareConfig holds hardware acceleration configuration.
 type HardwareConfig struct {
 	// EnableAESNI enables AES-NI hardware acceleration on x86_64 architectures.
@@ -276,11 +763,99 @@ type KeyManagerConfig struct {
 	RotationPolicy RotationPolicyConfig `yaml:"rotation_policy"`
 	Cosmian        CosmianConfig        `yaml:"cosmian"`
 	Memory         MemoryKMConfig       `yaml:"memory"`
+	// Calls bounds the latency every key manager call can add to a request.
+	Calls KeyManagerCallConfig `yaml:"calls"`
+	// VersionWatch follows key rotations made in the KMS.
+	VersionWatch KeyVersionWatchConfig `yaml:"version_watch"`
 	// TODO(v1.0): Add AWS and Vault config fields when adapters are implemented
 	// AWS        AWSKMSConfig  `yaml:"aws"`
 	// Vault      VaultConfig   `yaml:"vault"`
 }
 
+// Default values for KeyManagerCallConfig.
+const (
+	DefaultKMSCallTimeout        = 5 * time.Second
+	DefaultKMSCallMaxAttempts    = 3
+	DefaultKMSCallInitialBackoff = 50 * time.Millisecond
+	DefaultKMSCallMaxBackoff     = time.Second
+	DefaultKMSCallRetryBudget    = 0.1
+	DefaultKMSCallRetryBurst     = 10
+)
+
+// KeyManagerCallConfig bounds key manager calls. Each attempt of a wrap,
+// unwrap or version lookup gets Timeout; transient failures (timeouts,
+// KMS unavailable) are retried up to MaxAttempts in total with full-jitter
+// exponential backoff. Retries draw on a budget shared by all calls: every
+// call earns RetryBudget retries, up to RetryBurst banked, so when the KMS
+// is down the gateway stops multiplying its load and fails fast instead.
+// Zero values use the defaults.
+type KeyManagerCallConfig struct {
+	Timeout        time.Duration `yaml:"timeout" env:"KEY_MANAGER_CALL_TIMEOUT"`
+	MaxAttempts    int           `yaml:"max_attempts" env:"KEY_MANAGER_CALL_MAX_ATTEMPTS"`
+	InitialBackoff time.Duration `yaml:"initial_backoff" env:"KEY_MANAGER_CALL_INITIAL_BACKOFF"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" env:"KEY_MANAGER_CALL_MAX_BACKOFF"`
+	RetryBudget    float64       `yaml:"retry_budget" env:"KEY_MANAGER_CALL_RETRY_BUDGET"`
+	RetryBurst     int           `yaml:"retry_burst" env:"KEY_MANAGER_CALL_RETRY_BURST"`
+}
+
+// Normalize fills unset fields with the defaults.
+func (k *KeyManagerCallConfig) Normalize() {
+	if k.Timeout <= 0 {
+		k.Timeout = DefaultKMSCallTimeout
+	}
+	if k.MaxAttempts <= 0 {
+		k.MaxAttempts = DefaultKMSCallMaxAttempts
+	}
+	if k.InitialBackoff <= 0 {
+		k.InitialBackoff = DefaultKMSCallInitialBackoff
+	}
+	if k.MaxBackoff <= 0 {
+		k.MaxBackoff = DefaultKMSCallMaxBackoff
+	}
+	if k.RetryBudget <= 0 {
+		k.RetryBudget = DefaultKMSCallRetryBudget
+	}
+	if k.RetryBurst <= 0 {
+		k.RetryBurst = DefaultKMSCallRetryBurst
+	}
+}
+
+// Validate checks the bounds. Normalize must be called first.
+func (k *KeyManagerCallConfig) Validate() error {
+	if k.MaxAttempts > 10 {
+		return fmt.Errorf("encryption.key_manager.calls.max_attempts must be <= 10 (got %d)", k.MaxAttempts)
+	}
+	if k.MaxBackoff < k.InitialBackoff {
+		return fmt.Errorf("encryption.key_manager.calls.max_backoff (%s) must be >= initial_backoff (%s)", k.MaxBackoff, k.InitialBackoff)
+	}
+	if k.RetryBudget > 1 {
+		return fmt.Errorf("encryption.key_manager.calls.retry_budget must be <= 1 (got %g)", k.RetryBudget)
+	}
+	return nil
+}
+
+// KeyVersionWatchConfig configures the periodic check of the active key
+// version. Adapters that can read key states from the KMS (Cosmian binary
+// KMIP) switch wrapping to the key the KMS reports as active; with other
+// adapters changes made through the admin API are still reported. Every
+// change emits an audit event and updates kms_active_key_version.
+type KeyVersionWatchConfig struct {
+	Enabled  bool          `yaml:"enabled" env:"KEY_MANAGER_VERSION_WATCH_ENABLED"`
+	Interval time.Duration `yaml:"interval" env:"KEY_MANAGER_VERSION_WATCH_INTERVAL"`
+}
+
+// DefaultKeyVersionWatchInterval is how often the active key version is
+// checked when the watch is enabled.
+const DefaultKeyVersionWatchInterval = time.Minute
+
+// Validate checks an enabled key version watch.
+func (w KeyVersionWatchConfig) Validate() error {
+	if w.Interval < time.Second {
+		return fmt.Errorf("encryption.key_manager.version_watch.interval must be at least 1s")
+	}
+	return nil
+}
+
 // MemoryKMConfig captures settings for the in-memory key manager adapter.
 //
 // The master key is loaded from the configured source once at startup and is
@@ -316,6 +891,9 @@ type CosmianConfig struct {
 	ClientKey          string                `yaml:"client_key" env:"COSMIAN_KMS_CLIENT_KEY"`
 	CACert             string                `yaml:"ca_cert" env:"COSMIAN_KMS_CA_CERT"`
 	InsecureSkipVerify bool                  `yaml:"insecure_skip_verify" env:"COSMIAN_KMS_INSECURE_SKIP_VERIFY"`
+	// LazyConnect defers dialing a binary KMIP endpoint to the first key
+	// operation, so startup does not wait on the KMS.
+	LazyConnect bool `yaml:"lazy_connect" env:"COSMIAN_KMS_LAZY_CONNECT"`
 }
 
 // CosmianKeyReference maps wrapping key identifiers to metadata versions.
@@ -331,6 +909,12 @@ type CompressionConfig struct {
 	ContentTypes []string `yaml:"content_types" env:"COMPRESSION_CONTENT_TYPES"`
 	Algorithm    string   `yaml:"algorithm" env:"COMPRESSION_ALGORITHM"`
 	Level        int      `yaml:"level" env:"COMPRESSION_LEVEL"`
+	// MaxRatio is the largest expansion accepted when decompressing on GET,
+	// in output bytes per stored byte. Objects expanding further fail as
+	// corrupt. 0 uses the DEFLATE maximum of 1032, which refuses nothing
+	// the gateway wrote; lower it to bound objects whose recorded original
+	// size cannot be trusted.
+	MaxRatio int `yaml:"max_ratio" env:"COMPRESSION_MAX_RATIO"`
 }
 
 // TLSConfig holds TLS configuration.
@@ -376,6 +960,85 @@ type ServerConfig struct {
 	// behind a TLS-terminating reverse proxy (nginx, ALB, Traefik, etc.)
 	// where r.TLS is always nil on the Go side.
 	ForceHTTPS bool `yaml:"force_https" env:"SERVER_FORCE_HTTPS"`
+	// IntegrityTrailer adds an X-S3eg-Integrity trailer to full-object GETs
+	// carrying the auth-tag verification result and the plaintext SHA-256.
+	// Those responses are sent chunked, without Content-Length.
+	IntegrityTrailer bool `yaml:"integrity_trailer" env:"SERVER_INTEGRITY_TRAILER"`
+	// ChecksumTrailers sends x-amz-checksum-sha256 and -crc32c trailers over
+	// the plaintext on streamed full-object GETs whose client sends
+	// "TE: trailers" or "x-amz-checksum-mode: ENABLED".
+	ChecksumTrailers bool `yaml:"checksum_trailers" env:"SERVER_CHECKSUM_TRAILERS"`
+	// MaxDecryptedBytes caps the plaintext a single GET may decrypt,
+	// including objects that decompress after decryption. Requests over the
+	// cap fail with 400 EntityTooLarge. 0 (the default) means no cap.
+	MaxDecryptedBytes int64 `yaml:"max_decrypted_bytes" env:"SERVER_MAX_DECRYPTED_BYTES"`
+	// ResponseHeaderRules rewrite every response sent to clients.
+	ResponseHeaderRules []HeaderRule `yaml:"response_header_rules"`
+}
+
+// Header rule actions.
+const (
+	HeaderRuleSet    = "set"
+	HeaderRuleAdd    = "add"
+	HeaderRuleRemove = "remove"
+	HeaderRuleRename = "rename"
+)
+
+// HeaderRule rewrites one HTTP header. Rules are applied in order.
+type HeaderRule struct {
+	// Action is "set" (replace any value), "add" (append a value),
+	// "remove" or "rename".
+	Action string `yaml:"action"`
+	// Name is the header acted on, matched case-insensitively. A "remove"
+	// rule may end in "*" to match every header with that prefix.
+	Name string `yaml:"name"`
+	// Value is the value written by "set" and "add".
+	Value string `yaml:"value"`
+	// To is the new name for "rename".
+	To string `yaml:"to"`
+}
+
+// ProtectedBackendHeaders cannot be named by backend header rules, and
+// wildcard removals skip them: the request would no longer be signed or
+// framed correctly.
+var ProtectedBackendHeaders = []string{"Authorization", "Host", "Content-Length", "X-Amz-Date", "X-Amz-Content-Sha256", "X-Amz-Security-Token"}
+
+// ProtectedResponseHeaders cannot be named by response header rules.
+var ProtectedResponseHeaders = []string{"Content-Length", "Transfer-Encoding"}
+
+var headerRuleName = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+// validateHeaderRules checks the rules listed under field.
+func validateHeaderRules(field string, rules []HeaderRule, protected []string) error {
+	for n, r := range rules {
+		name := strings.TrimSuffix(r.Name, "*")
+		wildcard := name != r.Name
+		if !headerRuleName.MatchString(name) {
+			return fmt.Errorf("%s[%d].name must be a header name, got %q", field, n, r.Name)
+		}
+		if wildcard && r.Action != HeaderRuleRemove {
+			return fmt.Errorf("%s[%d].name: only remove rules may use a \"*\" wildcard", field, n)
+		}
+		for _, prot := range protected {
+			if (!wildcard && strings.EqualFold(name, prot)) || strings.EqualFold(r.To, prot) {
+				return fmt.Errorf("%s[%d]: header %s cannot be rewritten", field, n, prot)
+			}
+		}
+		switch r.Action {
+		case HeaderRuleSet, HeaderRuleAdd:
+			if strings.ContainsAny(r.Value, "\r\n") {
+				return fmt.Errorf("%s[%d].value must not contain line breaks", field, n)
+			}
+		case HeaderRuleRemove:
+		case HeaderRuleRename:
+			if !headerRuleName.MatchString(r.To) {
+				return fmt.Errorf("%s[%d].to must be a header name, got %q", field, n, r.To)
+			}
+		default:
+			return fmt.Errorf("%s[%d].action must be set, add, remove or rename, got %q", field, n, r.Action)
+		}
+	}
+	return nil
 }
 
 // DefaultMaxLegacyCopySourceBytes is the default cap for the legacy
@@ -399,6 +1062,11 @@ type CacheConfig struct {
 	MaxSize    int64         `yaml:"max_size" env:"CACHE_MAX_SIZE"`       // Max size in bytes
 	MaxItems   int           `yaml:"max_items" env:"CACHE_MAX_ITEMS"`     // Max number of items
 	DefaultTTL time.Duration `yaml:"default_ttl" env:"CACHE_DEFAULT_TTL"` // Default TTL
+	// RevalidateFor keeps an expired object for this long, so the next full
+	// GET of it asks the backend with If-None-Match and, on 304 Not
+	// Modified, is answered from the cache without transferring the object
+	// again. Zero drops objects on expiry.
+	RevalidateFor time.Duration `yaml:"revalidate_for" env:"CACHE_REVALIDATE_FOR"`
 }
 
 // AuditConfig holds audit logging configuration.
@@ -407,11 +1075,53 @@ type AuditConfig struct {
 	MaxEvents          int        `yaml:"max_events" env:"AUDIT_MAX_EVENTS"` // Max events to keep in memory
 	Sink               SinkConfig `yaml:"sink"`
 	RedactMetadataKeys []string   `yaml:"redact_metadata_keys" env:"AUDIT_REDACT_METADATA_KEYS"`
+	// Sampling thins out routine events before they are stored or sent to
+	// the sink.
+	Sampling AuditSamplingConfig `yaml:"sampling"`
+}
+
+// AuditSamplingConfig decides which audit events are kept. Events are
+// classed by severity: successful operations are info, failed ones warning,
+// and security events (key rotation, tamper detection, retention and legal
+// hold changes, debug profile fetches, exhausted write retries) critical.
+// Critical events are always kept.
+type AuditSamplingConfig struct {
+	// MinSeverity drops events below it: info (default), warning or
+	// critical.
+	MinSeverity string `yaml:"min_severity" env:"AUDIT_SAMPLING_MIN_SEVERITY"`
+	// SuccessRate is the fraction of info events kept, from 0 to 1. Unset
+	// keeps all of them. Events of one request are kept or dropped together.
+	SuccessRate *float64 `yaml:"success_rate" env:"AUDIT_SAMPLING_SUCCESS_RATE"`
+	// EventTypeRates overrides SuccessRate per event type, e.g. get: 0.01.
+	EventTypeRates map[string]float64 `yaml:"event_type_rates"`
+	// ExcludeEventTypes drops every non-critical event of these types.
+	ExcludeEventTypes []string `yaml:"exclude_event_types" env:"AUDIT_SAMPLING_EXCLUDE_EVENT_TYPES"`
+}
+
+// Validate checks the audit.sampling section.
+func (s AuditSamplingConfig) Validate() error {
+	switch s.MinSeverity {
+	case "", "info", "warning", "critical":
+	default:
+		return fmt.Errorf("audit.sampling.min_severity must be info, warning or critical, got %q", s.MinSeverity)
+	}
+	if s.SuccessRate != nil && (*s.SuccessRate < 0 || *s.SuccessRate > 1) {
+		return fmt.Errorf("audit.sampling.success_rate must be between 0 and 1")
+	}
+	for t, rate := range s.EventTypeRates {
+		if t == "" {
+			return fmt.Errorf("audit.sampling.event_type_rates: event type must not be empty")
+		}
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("audit.sampling.event_type_rates[%s] must be between 0 and 1", t)
+		}
+	}
+	return nil
 }
 
 // SinkConfig holds audit sink configuration.
 type SinkConfig struct {
-	Type          string            `yaml:"type" env:"AUDIT_SINK_TYPE"` // stdout, file, http
+	Type          string            `yaml:"type" env:"AUDIT_SINK_TYPE"` // stdout, file, http, cloudwatch, gcp_logging
 	Endpoint      string            `yaml:"endpoint" env:"AUDIT_SINK_ENDPOINT"`
 	FilePath      string            `yaml:"file_path" env:"AUDIT_SINK_FILE_PATH"`
 	// FileMode sets the Unix permission bits for the audit log file.
@@ -431,6 +1141,38 @@ type SinkConfig struct {
 	HTTP HTTPTransportConfig `yaml:"http"`
 	// TLS configuration for HTTP sink (V1.0-SEC-H07)
 	TLS SinkTLSConfig `yaml:"tls"`
+	// CloudWatch configures the cloudwatch sink.
+	CloudWatch SinkCloudWatchConfig `yaml:"cloudwatch"`
+	// GCPLogging configures the gcp_logging sink.
+	GCPLogging SinkGCPLoggingConfig `yaml:"gcp_logging"`
+}
+
+// SinkCloudWatchConfig holds settings for the CloudWatch Logs audit sink.
+// Credentials come from the AWS default chain (environment, shared config,
+// web identity, instance or task role), not from backend.access_key.
+type SinkCloudWatchConfig struct {
+	LogGroup string `yaml:"log_group" env:"AUDIT_SINK_CLOUDWATCH_LOG_GROUP"`
+	// LogStream defaults to the host name. It is created if missing.
+	LogStream string `yaml:"log_stream" env:"AUDIT_SINK_CLOUDWATCH_LOG_STREAM"`
+	// Region defaults to the region of the AWS default chain.
+	Region string `yaml:"region" env:"AUDIT_SINK_CLOUDWATCH_REGION"`
+	// Endpoint overrides the service endpoint (VPC endpoints, LocalStack).
+	Endpoint string `yaml:"endpoint" env:"AUDIT_SINK_CLOUDWATCH_ENDPOINT"`
+}
+
+// gcpLogID matches the characters Cloud Logging accepts in a log ID.
+var gcpLogID = regexp.MustCompile(`^[A-Za-z0-9/_.-]{1,511}$`)
+
+// SinkGCPLoggingConfig holds settings for the Google Cloud Logging audit
+// sink. Credentials come from Application Default Credentials
+// (GOOGLE_APPLICATION_CREDENTIALS, gcloud, or the metadata server).
+type SinkGCPLoggingConfig struct {
+	// ProjectID defaults to the project of the credentials.
+	ProjectID string `yaml:"project_id" env:"AUDIT_SINK_GCP_PROJECT_ID"`
+	// LogID names the log entries are written to. Default: s3-gateway-audit
+	LogID string `yaml:"log_id" env:"AUDIT_SINK_GCP_LOG_ID"`
+	// Endpoint overrides the Cloud Logging API endpoint.
+	Endpoint string `yaml:"endpoint" env:"AUDIT_SINK_GCP_ENDPOINT"`
 }
 
 // SinkTLSConfig holds TLS settings for the audit HTTP sink.
@@ -497,6 +1239,33 @@ type MetricsConfig struct {
 type LoggingConfig struct {
 	AccessLogFormat string   `yaml:"access_log_format" env:"LOGGING_ACCESS_LOG_FORMAT"` // Access log format: default, json, clf
 	RedactHeaders   []string `yaml:"redact_headers" env:"LOGGING_REDACT_HEADERS"`       // Headers to redact in access logs (comma-separated)
+	// Sampling thins high-volume log streams, keyed by logger: "access" for
+	// the HTTP access log, a component name for messages carrying that
+	// "component" field, and "default" for everything else.
+	// LOGGING_SAMPLING takes "logger=every[:level];...".
+	Sampling map[string]LogSamplingRule `yaml:"sampling" env:"LOGGING_SAMPLING"`
+}
+
+// LogSamplingRule keeps one in Every messages of a logger at Level or
+// less severe. More severe messages, and access log entries for failed
+// requests, are always written.
+type LogSamplingRule struct {
+	Every int `yaml:"every" json:"every"`
+	// Level is the most severe level sampled; empty means info.
+	Level string `yaml:"level" json:"level,omitempty"`
+}
+
+// Validate checks a sampling rule.
+func (r LogSamplingRule) Validate() error {
+	if r.Every < 1 {
+		return fmt.Errorf("every must be at least 1")
+	}
+	if r.Level != "" {
+		if _, err := logrus.ParseLevel(r.Level); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // GatewayCredential is a single access-key/secret-key pair managed by the gateway.
@@ -508,21 +1277,228 @@ type GatewayCredential struct {
 	// SecretKeyEnv is the name of the environment variable that holds the
 	// plaintext secret key.  Takes precedence over SecretKey.
 	SecretKeyEnv string `yaml:"secret_key_env"`
-	// Label is an optional human-readable name used in audit log entries.
+	// Label is an optional human-readable name, recorded in audit events
+	// alongside the access key.
 	Label        string `yaml:"label"`
+	// Raw lets the credential read and write objects as stored, ciphertext
+	// and encryption metadata included: RawAccessOnRequest when the request
+	// sends "x-s3eg-raw: true", RawAccessAlways for every GET, HEAD and PUT.
+	// Empty (the default) refuses raw access.
+	Raw string `yaml:"raw"`
+	// SessionToken makes this a temporary credential, such as one issued by
+	// AWS STS or MinIO AssumeRole: requests must carry the same
+	// X-Amz-Security-Token, and requests that omit it are rejected.
+	SessionToken string `yaml:"session_token"`
+	// SessionTokenEnv names the environment variable holding SessionToken.
+	// Takes precedence over SessionToken.
+	SessionTokenEnv string `yaml:"session_token_env"`
+	// Expiration, when set, rejects requests signed with this credential
+	// from that time on, as S3 does for expired temporary credentials.
+	Expiration time.Time `yaml:"expiration"`
 }
 
+// Raw access modes for GatewayCredential.Raw.
+const (
+	RawAccessOnRequest = "on_request"
+	RawAccessAlways    = "always"
+)
+
 // AuthConfig holds authentication-related configuration for the S3 API.
 type AuthConfig struct {
 	// ClockSkewTolerance is the maximum acceptable difference between the
 	// request timestamp (X-Amz-Date) and server time. Requests outside this
-	// window are rejected to prevent replay attacks.
-	// Default: 15 minutes (matching AWS SigV4 specification).
+	// window are rejected with RequestTimeTooSkewed to limit replay of
+	// captured requests. Default: 5 minutes.
 	ClockSkewTolerance time.Duration       `yaml:"clock_skew_tolerance" env:"AUTH_CLOCK_SKEW_TOLERANCE"`
+	// ReplayProtection rejects header-signed requests whose signature has
+	// already been accepted within the clock skew window.
+	ReplayProtection AuthReplayProtectionConfig `yaml:"replay_protection"`
 	// Credentials holds the gateway-managed credential store.
 	// Every inbound S3 request must present one of these access keys with a
 	// valid signature.
 	Credentials        []GatewayCredential `yaml:"credentials"`
+	// CredentialsFile names a YAML file holding a list of further
+	// credentials in the same form as Credentials, typically a mounted
+	// secret. Its entries are added to Credentials when the configuration
+	// is loaded, replacing inline entries with the same access key. An
+	// unreadable or malformed file fails the load.
+	CredentialsFile string `yaml:"credentials_file" env:"AUTH_CREDENTIALS_FILE"`
+	// Policy restricts which buckets, keys and operations each credential
+	// may use once its signature has been verified.
+	Policy AuthPolicyConfig `yaml:"policy"`
+}
+
+// AuthReplayProtectionConfig remembers the signatures of accepted
+// header-signed requests for twice the clock skew tolerance, the longest a
+// signature can pass the timestamp check, and refuses a request carrying
+// one of them again. Presigned URLs are meant to be reused and are not
+// checked. Each gateway instance keeps its own cache.
+type AuthReplayProtectionConfig struct {
+	Enabled bool `yaml:"enabled" env:"AUTH_REPLAY_PROTECTION_ENABLED"`
+	// CacheSize bounds the remembered signatures; the oldest are dropped
+	// first. Size it above the requests a gateway accepts in twice the
+	// clock skew tolerance, or dropped signatures can be replayed.
+	CacheSize int `yaml:"cache_size" env:"AUTH_REPLAY_PROTECTION_CACHE_SIZE"`
+}
+
+// DefaultReplayCacheSize is the replay protection cache size when
+// auth.replay_protection.cache_size is unset.
+const DefaultReplayCacheSize = 100000
+
+// AuthPolicyConfig is the per-client authorization policy. A request is
+// refused when any matching rule denies it; otherwise it is allowed when a
+// matching rule allows it or, with DefaultDeny off, when no rule matches.
+type AuthPolicyConfig struct {
+	// DefaultDeny refuses requests no rule allows. Off by default, so a
+	// policy made only of deny rules leaves everything else open.
+	DefaultDeny bool             `yaml:"default_deny" env:"AUTH_POLICY_DEFAULT_DENY"`
+	Rules       []AuthPolicyRule `yaml:"rules"`
+}
+
+// Enabled reports whether the policy can refuse anything.
+func (p AuthPolicyConfig) Enabled() bool {
+	return p.DefaultDeny || len(p.Rules) > 0
+}
+
+// AuthPolicyRule matches requests by client, bucket, key prefix and
+// operation. An empty list matches anything; patterns use path.Match
+// wildcards ("*", "?", "[...]").
+type AuthPolicyRule struct {
+	// Effect is "allow" or "deny".
+	Effect string `yaml:"effect"`
+	// Identities match a client's access key or credential label.
+	Identities []string `yaml:"identities"`
+	// Buckets match bucket names.
+	Buckets []string `yaml:"buckets"`
+	// Prefixes match the start of object keys. Wildcards do not cross "/",
+	// so "tenants/*/public/" covers tenants/a/public/x but not
+	// tenants/a/b/public/x. A rule with prefixes only applies to requests
+	// naming a key, or to listings of a prefix.
+	Prefixes []string `yaml:"prefixes"`
+	// Operations match the S3 operation names in AuthPolicyOperations.
+	Operations []string `yaml:"operations"`
+}
+
+// Auth policy rule effects.
+const (
+	AuthPolicyAllow = "allow"
+	AuthPolicyDeny  = "deny"
+)
+
+// AuthPolicyOperations are the operation names auth policy rules can
+// match. Bucket and object subresources (tagging, ACLs, lifecycle and the
+// like) are grouped as BucketConfig and ObjectConfig.
+var AuthPolicyOperations = []string{
+	"ListBuckets",
+	"CreateBucket",
+	"DeleteBucket",
+	"HeadBucket",
+	"ListObjects",
+	"ListMultipartUploads",
+	"BucketConfig",
+	"GetObject",
+	"HeadObject",
+	"PutObject",
+	"CopyObject",
+	"DeleteObject",
+	"ObjectConfig",
+	"CreateMultipartUpload",
+	"UploadPart",
+	"UploadPartCopy",
+	"CompleteMultipartUpload",
+	"AbortMultipartUpload",
+	"ListParts",
+	"Other",
+}
+
+// validate checks the policy's rules.
+func (p AuthPolicyConfig) validate() error {
+	allows := 0
+	for i, rule := range p.Rules {
+		field := fmt.Sprintf("auth.policy.rules[%d]", i)
+		switch rule.Effect {
+		case AuthPolicyAllow:
+			allows++
+		case AuthPolicyDeny:
+		default:
+			return fmt.Errorf("%s.effect must be %q or %q (got %q)", field, AuthPolicyAllow, AuthPolicyDeny, rule.Effect)
+		}
+		for _, list := range []struct {
+			name     string
+			patterns []string
+		}{
+			{"identities", rule.Identities},
+			{"buckets", rule.Buckets},
+			{"prefixes", rule.Prefixes},
+		} {
+			for _, pattern := range list.patterns {
+				if pattern == "" {
+					return fmt.Errorf("%s.%s: empty pattern", field, list.name)
+				}
+				if _, err := path.Match(pattern, ""); err != nil {
+					return fmt.Errorf("%s.%s: invalid pattern %q", field, list.name, pattern)
+				}
+			}
+		}
+		for _, pattern := range rule.Operations {
+			matched := false
+			for _, op := range AuthPolicyOperations {
+				ok, err := path.Match(pattern, op)
+				if err != nil {
+					return fmt.Errorf("%s.operations: invalid pattern %q", field, pattern)
+				}
+				if ok {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return fmt.Errorf("%s.operations: %q matches no operation (known: %s)", field, pattern, strings.Join(AuthPolicyOperations, ", "))
+			}
+		}
+	}
+	if p.DefaultDeny && allows == 0 {
+		return fmt.Errorf("auth.policy.default_deny requires at least one allow rule")
+	}
+	return nil
+}
+
+// loadCredentialsFile merges the credentials listed in CredentialsFile into
+// Credentials. A file entry replaces an inline entry with the same access
+// key, so a rotated secret can be mounted without editing the main file.
+func (a *AuthConfig) loadCredentialsFile() error {
+	if a.CredentialsFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(a.CredentialsFile)
+	if err != nil {
+		return fmt.Errorf("failed to read auth.credentials_file: %w", err)
+	}
+	var fileCreds []GatewayCredential
+	if err := yaml.Unmarshal(data, &fileCreds); err != nil {
+		return fmt.Errorf("failed to parse auth.credentials_file %s: %w", a.CredentialsFile, err)
+	}
+	for _, fc := range fileCreds {
+		if fc.SecretKeyEnv != "" {
+			if v := os.Getenv(fc.SecretKeyEnv); v != "" {
+				fc.SecretKey = v
+			}
+		}
+		if fc.SessionTokenEnv != "" {
+			if v := os.Getenv(fc.SessionTokenEnv); v != "" {
+				fc.SessionToken = v
+			}
+		}
+		i := slices.IndexFunc(a.Credentials, func(c GatewayCredential) bool {
+			return fc.AccessKey != "" && c.AccessKey == fc.AccessKey
+		})
+		if i >= 0 {
+			a.Credentials[i] = fc
+		} else {
+			a.Credentials = append(a.Credentials, fc)
+		}
+	}
+	return nil
 }
 
 // AdminConfig holds admin API configuration.
@@ -538,6 +1514,49 @@ type AdminConfig struct {
 	Auth           AdminAuthConfig      `yaml:"auth"`
 	RateLimit      AdminRateLimitConfig `yaml:"rate_limit"`
 	Profiling      AdminProfilingConfig `yaml:"profiling"`
+	Rotation       AdminRotationConfig  `yaml:"rotation"`
+}
+
+// AdminRotationConfig configures key rotation dry runs, which check that
+// every replica can wrap and unwrap under the target key version before it
+// is made active.
+type AdminRotationConfig struct {
+	// Peers are the admin API base URLs of the other replicas, e.g.
+	// https://gw-2.internal:8081. A dry run asks each of them to run the
+	// check and forwards the caller's bearer token.
+	Peers []string `yaml:"peers" env:"ADMIN_ROTATION_PEERS"`
+	// PeerTimeout bounds each peer's check.
+	PeerTimeout time.Duration `yaml:"peer_timeout" env:"ADMIN_ROTATION_PEER_TIMEOUT"`
+	// PeerCAFile verifies the peers' admin TLS certificates. Empty uses the
+	// system roots.
+	PeerCAFile string `yaml:"peer_ca_file" env:"ADMIN_ROTATION_PEER_CA_FILE"`
+	// RequireDryRun refuses to start a rotation unless a dry run for the
+	// same target version passed on this replica within DryRunMaxAge.
+	RequireDryRun bool          `yaml:"require_dry_run" env:"ADMIN_ROTATION_REQUIRE_DRY_RUN"`
+	DryRunMaxAge  time.Duration `yaml:"dry_run_max_age" env:"ADMIN_ROTATION_DRY_RUN_MAX_AGE"`
+}
+
+// Default rotation dry run settings.
+const (
+	DefaultRotationPeerTimeout  = 10 * time.Second
+	DefaultRotationDryRunMaxAge = 15 * time.Minute
+)
+
+// Validate checks the rotation dry run settings.
+func (r AdminRotationConfig) Validate() error {
+	for i, p := range r.Peers {
+		u, err := url.Parse(p)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("admin.rotation.peers[%d] must be an http(s) URL (got %q)", i, p)
+		}
+	}
+	if r.PeerTimeout < 0 {
+		return fmt.Errorf("admin.rotation.peer_timeout must not be negative")
+	}
+	if r.DryRunMaxAge < 0 {
+		return fmt.Errorf("admin.rotation.dry_run_max_age must not be negative")
+	}
+	return nil
 }
 
 // AdminProfilingConfig controls the /admin/debug/pprof/* routes.
@@ -582,6 +1601,20 @@ type AdminAuthConfig struct {
 	Type      string `yaml:"type" env:"ADMIN_AUTH_TYPE"`             // Only "bearer" in v0.6
 	TokenFile string `yaml:"token_file" env:"ADMIN_AUTH_TOKEN_FILE"` // File path; 0600, never inline
 	Token     string `yaml:"token" env:"ADMIN_AUTH_TOKEN"`           // Inline only with ADMIN_ALLOW_INLINE_TOKEN=1
+	// Tokens are additional bearer tokens limited to one role each. The
+	// token above grants every role and may be left unset when these are
+	// configured.
+	Tokens []AdminRoleToken `yaml:"tokens"`
+}
+
+// AdminRoleToken is a bearer token, read from a file like token_file,
+// that grants a single role: "observer" (reads only), "key_operator"
+// (rotation, retirement, shredding and key holds) or "config_admin"
+// (every other change). Env: ADMIN_AUTH_<ROLE>_TOKEN_FILE adds one token
+// per role, e.g. ADMIN_AUTH_OBSERVER_TOKEN_FILE.
+type AdminRoleToken struct {
+	Role      string `yaml:"role"`
+	TokenFile string `yaml:"token_file"`
 }
 
 // AdminRateLimitConfig holds rate-limiting settings for the admin API.
@@ -637,20 +1670,1389 @@ const (
 	ValkeyDefaultTTLSeconds = 7 * 24 * 60 * 60
 )
 
-// LoadConfig loads configuration from a file and environment variables.
-func LoadConfig(path string) (*Config, error) {
-	config := &Config{
-		ListenAddr: ":8080",
-		LogLevel:   "info",
-		Encryption: EncryptionConfig{
-			KeyManager: KeyManagerConfig{
-				Provider:       "cosmian",
-				DualReadWindow: 1,
-				RotationPolicy: RotationPolicyConfig{
-					Enabled:     false,
-					GraceWindow: 0, // Use DualReadWindow by default
-				},
-			},
+// SLOConfig configures the error-budget tracker. Every S3 request is
+// classified as good or bad against the objective for its operation, and
+// burn rates are exported per operation and look-back window so standard
+// multi-window, multi-burn-rate alerts can be built directly on the gateway's
+// metrics.
+type SLOConfig struct {
+	Enabled bool `yaml:"enabled" env:"SLO_ENABLED"`
+	// Windows are the look-back windows burn rates are computed over.
+	// Default: 5m, 30m, 1h, 6h (the pairs used by the classic
+	// fast-burn/slow-burn alerting recipe).
+	Windows []time.Duration `yaml:"windows" env:"SLO_WINDOWS"`
+	// Default applies to any operation without an explicit objective.
+	Default SLOObjective `yaml:"default"`
+	// Objectives overrides the default per S3 operation name
+	// (e.g. "GetObject", "PutObject").
+	Objectives []SLOObjective `yaml:"objectives"`
+}
+
+// SLOObjective is the target for a single operation. A request is bad when
+// it returns a 5xx status, or when LatencyThreshold is set and the request
+// took longer than it.
+type SLOObjective struct {
+	Operation        string        `yaml:"operation"`
+	Target           float64       `yaml:"target"`            // e.g. 0.999
+	LatencyThreshold time.Duration `yaml:"latency_threshold"` // 0 = availability only
+}
+
+// Default SLO settings.
+const (
+	DefaultSLOTarget = 0.999
+)
+
+// JournalConfig configures the write-ahead journal of in-flight object
+// writes. After a crash the journal tells the gateway which PUTs may have
+// been interrupted; on startup each one is checked against the backend and
+// reported (and, with CleanupInconsistent, objects that landed without their
+// encryption envelope are deleted if their ETag is the one journalled for
+// the write).
+type JournalConfig struct {
+	Enabled bool   `yaml:"enabled" env:"JOURNAL_ENABLED"`
+	Path    string `yaml:"path" env:"JOURNAL_PATH"`
+	// Sync fsyncs every journal record before the backend write proceeds.
+	// Disabling it trades crash consistency for latency. Default: true.
+	Sync                bool `yaml:"sync" env:"JOURNAL_SYNC"`
+	CleanupInconsistent bool `yaml:"cleanup_inconsistent" env:"JOURNAL_CLEANUP_INCONSISTENT"`
+}
+
+// ListEnrichmentConfig controls how ListObjects reports sizes and ETags of
+// encrypted objects. The backend lists ciphertext sizes; with enrichment on,
+// each listed key is resolved to its plaintext size and ETag from HEAD
+// metadata, which costs one backend HEAD per uncached key.
+type ListEnrichmentConfig struct {
+	Enabled bool `yaml:"enabled" env:"LIST_ENRICHMENT_ENABLED"`
+	// Concurrency caps the HEAD requests issued in parallel for one listing.
+	Concurrency int `yaml:"concurrency" env:"LIST_ENRICHMENT_CONCURRENCY"`
+	// CacheSize is the number of resolved keys kept in memory (0 disables
+	// the cache). Entries are keyed on the backend ETag, so an overwritten
+	// object is never served a stale size.
+	CacheSize int           `yaml:"cache_size" env:"LIST_ENRICHMENT_CACHE_SIZE"`
+	CacheTTL  time.Duration `yaml:"cache_ttl" env:"LIST_ENRICHMENT_CACHE_TTL"`
+}
+
+// Default list enrichment settings.
+const (
+	DefaultListEnrichmentConcurrency = 8
+	DefaultListEnrichmentCacheSize   = 10000
+	DefaultListEnrichmentCacheTTL    = 10 * time.Minute
+)
+
+// SizeIndexConfig configures the sidecar size index: one JSON object per key
+// prefix, stored on the backend under Prefix, that maps each key to its
+// plaintext size, ETag and key version. Writes update it asynchronously and
+// ListObjects enrichment consults it before falling back to HEAD.
+type SizeIndexConfig struct {
+	Enabled bool `yaml:"enabled" env:"SIZE_INDEX_ENABLED"`
+	// Prefix is the reserved backend key prefix holding the index objects.
+	// Keys under it are hidden from listings.
+	Prefix        string        `yaml:"prefix" env:"SIZE_INDEX_PREFIX"`
+	FlushInterval time.Duration `yaml:"flush_interval" env:"SIZE_INDEX_FLUSH_INTERVAL"`
+}
+
+// AccessStatsConfig configures per-object read counters: one JSON object per
+// key prefix, stored on the backend under Prefix, that maps each key to its
+// read count and last access time. GETs update it asynchronously and the
+// admin API reports it, so operators can find cold data for tiering.
+type AccessStatsConfig struct {
+	Enabled bool `yaml:"enabled" env:"ACCESS_STATS_ENABLED"`
+	// Prefix is the reserved backend key prefix holding the stats objects.
+	// Keys under it are hidden from listings.
+	Prefix        string        `yaml:"prefix" env:"ACCESS_STATS_PREFIX"`
+	FlushInterval time.Duration `yaml:"flush_interval" env:"ACCESS_STATS_FLUSH_INTERVAL"`
+}
+
+// PrimaryShardName names backend as a member of sharding.shards.
+const PrimaryShardName = "primary"
+
+// DefaultShardVirtualNodes is the number of points each shard gets on the
+// hash ring when sharding.virtual_nodes is unset.
+const DefaultShardVirtualNodes = 128
+
+// ShardingConfig spreads buckets over several backends (accounts,
+// endpoints or providers), so one gateway namespace can exceed the limits
+// of a single account. Each bucket lives on exactly one shard: the one
+// named in Overrides, or else the one consistent hashing of the bucket
+// name selects among backend (as "primary") and Shards.
+//
+// Adding a shard moves roughly 1/N of the hashed buckets to it, and the
+// gateway does not migrate objects. Pin existing buckets in Overrides
+// before changing the shard list.
+type ShardingConfig struct {
+	Enabled bool `yaml:"enabled" env:"SHARDING_ENABLED"`
+	// Shards are the backends besides backend. They are configured from
+	// the file only.
+	Shards []BackendShard `yaml:"shards"`
+	// Overrides maps bucket names to shard names.
+	Overrides map[string]string `yaml:"overrides"`
+	// VirtualNodes is the number of ring points per shard; more points
+	// spread buckets more evenly.
+	VirtualNodes int `yaml:"virtual_nodes"`
+}
+
+// BackendShard is one named member of sharding.shards.
+type BackendShard struct {
+	Name    string        `yaml:"name"`
+	Backend BackendConfig `yaml:"backend"`
+}
+
+// Validate checks enabled sharding.
+func (s ShardingConfig) Validate() error {
+	if len(s.Shards) == 0 {
+		return fmt.Errorf("sharding.shards must contain at least one shard when sharding is enabled")
+	}
+	if s.VirtualNodes < 0 {
+		return fmt.Errorf("sharding.virtual_nodes must not be negative")
+	}
+	names := map[string]bool{PrimaryShardName: true}
+	for i, shard := range s.Shards {
+		if shard.Name == "" {
+			return fmt.Errorf("sharding.shards[%d].name is required", i)
+		}
+		if names[shard.Name] {
+			return fmt.Errorf("sharding.shards[%d].name %q is used twice (%q is backend itself)", i, shard.Name, PrimaryShardName)
+		}
+		names[shard.Name] = true
+		field := fmt.Sprintf("sharding.shards[%d].backend", i)
+		if err := shard.Backend.validateStore(field); err != nil {
+			return err
+		}
+		shard.Backend.Retry.Normalize()
+		if err := shard.Backend.Retry.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+	}
+	for bucket, name := range s.Overrides {
+		if !names[name] {
+			return fmt.Errorf("sharding.overrides[%q]: unknown shard %q", bucket, name)
+		}
+	}
+	return nil
+}
+
+// Billing export formats for BillingConfig.Format.
+const (
+	BillingFormatCSV     = "csv"
+	BillingFormatParquet = "parquet"
+)
+
+// BillingConfig configures the billing export. Every Interval the gateway
+// writes one object under Prefix in Bucket with a row per tenant: the
+// requests and bytes received and sent since the previous export, and the
+// stored bytes and objects measured by the quota scans. Tenants are the
+// groups in quota.tenants; a bucket outside them is its own tenant. The
+// export is written to the backend as is, unencrypted, so billing pipelines
+// can read it without the gateway.
+type BillingConfig struct {
+	Enabled  bool          `yaml:"enabled" env:"BILLING_ENABLED"`
+	Interval time.Duration `yaml:"interval" env:"BILLING_INTERVAL"`
+	Bucket   string        `yaml:"bucket" env:"BILLING_BUCKET"`
+	Prefix   string        `yaml:"prefix" env:"BILLING_PREFIX"`
+	// Format is "csv" (default) or "parquet".
+	Format string `yaml:"format" env:"BILLING_FORMAT"`
+}
+
+// Validate checks an enabled billing export.
+func (b BillingConfig) Validate() error {
+	if b.Bucket == "" {
+		return fmt.Errorf("billing.bucket is required when the billing export is enabled")
+	}
+	if strings.HasPrefix(b.Prefix, "/") || (b.Prefix != "" && !strings.HasSuffix(b.Prefix, "/")) {
+		return fmt.Errorf("billing.prefix must be empty or a relative prefix ending in \"/\" (got %q)", b.Prefix)
+	}
+	if b.Interval < time.Minute {
+		return fmt.Errorf("billing.interval must be at least 1m")
+	}
+	switch b.Format {
+	case BillingFormatCSV, BillingFormatParquet:
+	default:
+		return fmt.Errorf("billing.format must be %q or %q (got %q)", BillingFormatCSV, BillingFormatParquet, b.Format)
+	}
+	return nil
+}
+
+// DevModeConfig enables the developer mode, which logs a trace of the
+// crypto work of every request: chunk boundaries, IV derivation inputs,
+// chunk manifests and metadata before and after transformation. Traces
+// never include keys or object data, but they do expose IVs, wrapped keys
+// and user metadata, so the mode is refused in release builds unless
+// AllowInRelease is also set.
+type DevModeConfig struct {
+	Enabled        bool `yaml:"enabled" env:"DEV_MODE_ENABLED"`
+	AllowInRelease bool `yaml:"allow_in_release" env:"DEV_MODE_ALLOW_IN_RELEASE"`
+}
+
+// CheckBuild reports whether the developer mode may run in a build of
+// version. Release builds carry a semantic version such as v1.2.3; anything
+// else, like "dev" or a git describe with commits past a tag, is a
+// development build.
+func (d DevModeConfig) CheckBuild(version string) error {
+	if !d.Enabled || d.AllowInRelease || !releaseVersion.MatchString(version) {
+		return nil
+	}
+	return fmt.Errorf("dev_mode is enabled in release build %s; set dev_mode.allow_in_release to enable it anyway", version)
+}
+
+var releaseVersion = regexp.MustCompile(`^v?\d+\.\d+\.\d+$`)
+
+// DefaultQuotaScanInterval is how often bucket usage is re-measured when
+// quota.scan_interval is unset.
+const DefaultQuotaScanInterval = 15 * time.Minute
+
+// QuotaConfig limits the bytes and objects stored per bucket, and per
+// tenant, a named group of buckets sharing one limit. Usage is measured by
+// listing the buckets every ScanInterval, in stored (encrypted) bytes.
+// Between scans, writes through the gateway are added as they succeed,
+// while deletes and overwrites only show at the next scan, so usage errs on
+// the high side.
+type QuotaConfig struct {
+	Enabled      bool                   `yaml:"enabled" env:"QUOTA_ENABLED"`
+	ScanInterval time.Duration          `yaml:"scan_interval" env:"QUOTA_SCAN_INTERVAL"`
+	Buckets      map[string]QuotaLimit  `yaml:"buckets"`
+	Tenants      map[string]TenantQuota `yaml:"tenants"`
+}
+
+// QuotaLimit caps stored bytes and object count. Zero leaves that
+// dimension unlimited; a quota with neither is measured, for the billing
+// export, but never refuses a write.
+type QuotaLimit struct {
+	MaxBytes   int64 `yaml:"max_bytes" json:"max_bytes,omitempty"`
+	MaxObjects int64 `yaml:"max_objects" json:"max_objects,omitempty"`
+}
+
+func (l QuotaLimit) validate(field string) error {
+	if l.MaxBytes < 0 || l.MaxObjects < 0 {
+		return fmt.Errorf("%s: max_bytes and max_objects must not be negative", field)
+	}
+	return nil
+}
+
+// TenantQuota is a limit on the combined usage of Buckets.
+type TenantQuota struct {
+	Buckets    []string `yaml:"buckets"`
+	QuotaLimit `yaml:",inline"`
+}
+
+// Validate checks enabled quotas.
+func (q QuotaConfig) Validate() error {
+	if len(q.Buckets) == 0 && len(q.Tenants) == 0 {
+		return fmt.Errorf("quota.buckets or quota.tenants must define a limit when quotas are enabled")
+	}
+	if q.ScanInterval < time.Minute {
+		return fmt.Errorf("quota.scan_interval must be at least 1m")
+	}
+	for bucket, limit := range q.Buckets {
+		if err := limit.validate(fmt.Sprintf("quota.buckets[%q]", bucket)); err != nil {
+			return err
+		}
+	}
+	tenantOf := map[string]string{}
+	for name, tenant := range q.Tenants {
+		field := fmt.Sprintf("quota.tenants[%q]", name)
+		if len(tenant.Buckets) == 0 {
+			return fmt.Errorf("%s.buckets must name at least one bucket", field)
+		}
+		for _, b := range tenant.Buckets {
+			if b == "" {
+				return fmt.Errorf("%s.buckets must not contain an empty name", field)
+			}
+			if other, ok := tenantOf[b]; ok {
+				return fmt.Errorf("%s: bucket %q already belongs to tenant %q", field, b, other)
+			}
+			tenantOf[b] = name
+		}
+		if err := tenant.validate(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TieringConfig configures the tiering worker. Every Interval it applies
+// Rules to the backend: matching objects are re-uploaded as stored, either
+// in place with another storage class or to the secondary Backend, where a
+// stub left on the primary backend records their location and reads follow
+// it transparently.
+type TieringConfig struct {
+	Enabled  bool          `yaml:"enabled" env:"TIERING_ENABLED"`
+	Interval time.Duration `yaml:"interval" env:"TIERING_INTERVAL"`
+	// Backend is the secondary store for rules with secondary set. It is
+	// configured from the file only.
+	Backend BackendConfig `yaml:"backend"`
+	Rules   []TieringRule `yaml:"rules"`
+}
+
+// TieringRule selects objects to transition. An object matches when it was
+// last written at least MinAge ago and, if ColdAfter is set, last read at
+// least ColdAfter ago; objects never read count from their last write.
+// ColdAfter requires access_stats.
+type TieringRule struct {
+	Bucket    string        `yaml:"bucket"`
+	Prefix    string        `yaml:"prefix"`
+	MinAge    time.Duration `yaml:"min_age"`
+	ColdAfter time.Duration `yaml:"cold_after"`
+	// StorageClass is the backend storage class to store matching objects
+	// with, e.g. "STANDARD_IA" or "GLACIER_IR".
+	StorageClass string `yaml:"storage_class"`
+	// Secondary moves matching objects to tiering.backend, into
+	// SecondaryBucket (default Bucket).
+	Secondary       bool   `yaml:"secondary"`
+	SecondaryBucket string `yaml:"secondary_bucket"`
+}
+
+// Validate checks an enabled tiering worker.
+func (t TieringConfig) Validate() error {
+	if t.Interval < time.Minute {
+		return fmt.Errorf("tiering.interval must be at least 1m")
+	}
+	if len(t.Rules) == 0 {
+		return fmt.Errorf("tiering.rules must contain at least one rule when tiering is enabled")
+	}
+	secondary := false
+	for i, r := range t.Rules {
+		if r.Bucket == "" {
+			return fmt.Errorf("tiering.rules[%d].bucket is required", i)
+		}
+		if r.MinAge < 0 || r.ColdAfter < 0 {
+			return fmt.Errorf("tiering.rules[%d]: min_age and cold_after must not be negative", i)
+		}
+		if r.MinAge == 0 && r.ColdAfter == 0 {
+			return fmt.Errorf("tiering.rules[%d]: at least one of min_age and cold_after is required", i)
+		}
+		if r.StorageClass == "" && !r.Secondary {
+			return fmt.Errorf("tiering.rules[%d]: storage_class or secondary is required", i)
+		}
+		if r.SecondaryBucket != "" && !r.Secondary {
+			return fmt.Errorf("tiering.rules[%d].secondary_bucket requires secondary", i)
+		}
+		secondary = secondary || r.Secondary
+	}
+	if secondary {
+		if err := t.Backend.validateStore("tiering.backend"); err != nil {
+			return err
+		}
+		t.Backend.Retry.Normalize()
+		if err := t.Backend.Retry.Validate(); err != nil {
+			return fmt.Errorf("tiering.backend: %w", err)
+		}
+	}
+	return nil
+}
+
+// TrashConfig configures deferred deletes. A DELETE without a version ID
+// on one of Buckets copies the object under Prefix, in a key recording the
+// original key and the time of deletion, before removing it. Every Interval
+// the purge worker deletes trashed objects older than Retention. Deleting a
+// key under Prefix removes it for good.
+type TrashConfig struct {
+	Enabled   bool          `yaml:"enabled" env:"TRASH_ENABLED"`
+	Buckets   []string      `yaml:"buckets" env:"TRASH_BUCKETS"`
+	Prefix    string        `yaml:"prefix" env:"TRASH_PREFIX"`
+	Retention time.Duration `yaml:"retention" env:"TRASH_RETENTION"`
+	Interval  time.Duration `yaml:"interval" env:"TRASH_INTERVAL"`
+}
+
+// Validate checks enabled deferred deletes.
+func (t TrashConfig) Validate() error {
+	if len(t.Buckets) == 0 {
+		return fmt.Errorf("trash.buckets must name at least one bucket when trash is enabled")
+	}
+	for i, b := range t.Buckets {
+		if b == "" {
+			return fmt.Errorf("trash.buckets[%d] is empty", i)
+		}
+	}
+	if p := t.Prefix; p == "" || strings.HasPrefix(p, "/") || !strings.HasSuffix(p, "/") {
+		return fmt.Errorf("trash.prefix must be a relative prefix ending in \"/\" (got %q)", p)
+	}
+	if t.Retention <= 0 {
+		return fmt.Errorf("trash.retention must be positive")
+	}
+	if t.Interval < time.Minute {
+		return fmt.Errorf("trash.interval must be at least 1m")
+	}
+	return nil
+}
+
+// KeyHoldConfig configures key holds: objects and prefixes whose data keys
+// must not be destroyed, for legal holds and litigation. The holds are kept
+// in one JSON object, Key in Bucket, written conditionally so several
+// gateway instances can share it. Held objects cannot be shredded and
+// wrapping key versions they depend on cannot be retired.
+type KeyHoldConfig struct {
+	Enabled bool   `yaml:"enabled" env:"KEY_HOLD_ENABLED"`
+	Bucket  string `yaml:"bucket" env:"KEY_HOLD_BUCKET"`
+	// Key is the reserved object name of the hold list. It is hidden from
+	// listings.
+	Key string `yaml:"key" env:"KEY_HOLD_KEY"`
+}
+
+// FeatureFlag is the rollout rule of one feature flag. A flag is off unless
+// Enabled; when enabled it applies to objects in Buckets (every bucket when
+// empty) and, of those, to the share of objects given by Percentage (all of
+// them when 0). The share is chosen by hashing the bucket and key, so an
+// object stays on the same side of the rollout as it grows.
+type FeatureFlag struct {
+	Enabled    bool     `yaml:"enabled" json:"enabled"`
+	Buckets    []string `yaml:"buckets" json:"buckets,omitempty"`
+	Percentage int      `yaml:"percentage" json:"percentage,omitempty"`
+}
+
+// Validate checks the percentage and bucket names.
+func (f FeatureFlag) Validate() error {
+	if f.Percentage < 0 || f.Percentage > 100 {
+		return fmt.Errorf("percentage must be between 0 and 100 (got %d)", f.Percentage)
+	}
+	for _, b := range f.Buckets {
+		if b == "" {
+			return fmt.Errorf("buckets must not contain an empty name")
+		}
+	}
+	return nil
+}
+
+// HooksConfig configures the post-PUT hook pipeline. After a successful
+// PutObject, objects matching a rule are read back decrypted and handed to
+// an external processor (a local command or a webhook); whatever the
+// processor returns is stored, encrypted, as a derived object. Processors
+// only ever see plaintext and never receive key material.
+type HooksConfig struct {
+	Enabled bool `yaml:"enabled" env:"HOOKS_ENABLED"`
+	// Workers is the number of hook invocations run concurrently.
+	Workers int `yaml:"workers" env:"HOOKS_WORKERS"`
+	// QueueSize bounds the pending invocations; events beyond it are dropped
+	// and logged rather than slowing down PUTs.
+	QueueSize int `yaml:"queue_size" env:"HOOKS_QUEUE_SIZE"`
+	// MaxObjectSize skips source objects larger than this many bytes.
+	MaxObjectSize int64 `yaml:"max_object_size" env:"HOOKS_MAX_OBJECT_SIZE"`
+	// MaxOutputSize caps the size of a derived object.
+	MaxOutputSize int64      `yaml:"max_output_size" env:"HOOKS_MAX_OUTPUT_SIZE"`
+	Rules         []HookRule `yaml:"rules"`
+}
+
+// HookRule selects objects and the processor that derives a new object from
+// them. Exactly one of Exec and Webhook is set.
+type HookRule struct {
+	Name string `yaml:"name"`
+	// ContentTypes are media types to match; "image/*" matches a whole type
+	// and an empty list matches everything.
+	ContentTypes []string `yaml:"content_types"`
+	// Prefix restricts the rule to keys starting with it.
+	Prefix string `yaml:"prefix"`
+	// Exec is the command (argv) to run. The object is written to its stdin
+	// and its stdout becomes the derived object. The environment holds only
+	// PATH and S3EG_BUCKET, S3EG_KEY, S3EG_CONTENT_TYPE and S3EG_SIZE.
+	Exec []string `yaml:"exec"`
+	// Webhook is a URL the object is POSTed to. A 200 response body becomes
+	// the derived object; 204 means there is nothing to store.
+	Webhook string `yaml:"webhook"`
+	// Output is the derived object key. "{key}", "{dir}" (with trailing
+	// "/", or empty) and "{name}" expand to parts of the source key.
+	Output string `yaml:"output"`
+	// OutputContentType is the derived object's Content-Type. When empty,
+	// a webhook's response Content-Type is used.
+	OutputContentType string        `yaml:"output_content_type"`
+	Timeout           time.Duration `yaml:"timeout"`
+}
+
+// BatchConfig configures batch jobs: an operation applied asynchronously
+// to every key of a submitted manifest, with a JSON report written to the
+// backend when the job ends.
+type BatchConfig struct {
+	Enabled bool `yaml:"enabled" env:"BATCH_ENABLED"`
+	// Workers is the number of keys of a job processed concurrently. Jobs
+	// themselves run one at a time.
+	Workers int `yaml:"workers" env:"BATCH_WORKERS"`
+	// MaxAttempts bounds the tries per key; errors other than a missing
+	// object are retried with backoff.
+	MaxAttempts int `yaml:"max_attempts" env:"BATCH_MAX_ATTEMPTS"`
+	// MaxKeys caps the size of one manifest.
+	MaxKeys int `yaml:"max_keys" env:"BATCH_MAX_KEYS"`
+	// ReportBucket receives the job reports; empty uses the job's bucket.
+	ReportBucket string `yaml:"report_bucket" env:"BATCH_REPORT_BUCKET"`
+	ReportPrefix string `yaml:"report_prefix" env:"BATCH_REPORT_PREFIX"`
+	// RetainJobs is the number of finished jobs kept for the status API.
+	RetainJobs int `yaml:"retain_jobs" env:"BATCH_RETAIN_JOBS"`
+}
+
+// Default batch job settings.
+const (
+	DefaultBatchWorkers     = 4
+	DefaultBatchMaxAttempts = 3
+	DefaultBatchMaxKeys     = 100000
+	DefaultBatchRetainJobs  = 100
+)
+
+// Validate checks enabled batch jobs.
+func (b BatchConfig) Validate() error {
+	if b.Workers < 1 {
+		return fmt.Errorf("batch.workers must be at least 1")
+	}
+	if b.MaxAttempts < 1 {
+		return fmt.Errorf("batch.max_attempts must be at least 1")
+	}
+	if b.MaxKeys < 1 {
+		return fmt.Errorf("batch.max_keys must be at least 1")
+	}
+	if b.RetainJobs < 1 {
+		return fmt.Errorf("batch.retain_jobs must be at least 1")
+	}
+	if p := b.ReportPrefix; p == "" || strings.HasPrefix(p, "/") || !strings.HasSuffix(p, "/") {
+		return fmt.Errorf("batch.report_prefix must be a relative prefix ending in \"/\" (got %q)", p)
+	}
+	return nil
+}
+
+// SchedulerConfig moves the periodic background jobs from their own
+// interval loops onto one scheduler with cron expressions, per-job metrics
+// and admin controls.
+type SchedulerConfig struct {
+	Enabled bool `yaml:"enabled" env:"SCHEDULER_ENABLED"`
+	// Jobs maps a job name (see SchedulerJobs) to its schedule: a
+	// five-field cron expression, a descriptor such as @hourly, or
+	// "@every <duration>". A job left out keeps the interval of its own
+	// settings. SCHEDULER_JOBS takes "name=expr;name=expr".
+	Jobs map[string]string `yaml:"jobs" env:"SCHEDULER_JOBS"`
+}
+
+// SchedulerJobs names the background jobs the scheduler can run.
+var SchedulerJobs = []string{"billing", "canary", "key_version_watch", "quota", "tiering", "trash"}
+
+// Validate checks scheduler settings. Expressions are parsed when the jobs
+// are registered at startup.
+func (s SchedulerConfig) Validate() error {
+	for name, expr := range s.Jobs {
+		known := false
+		for _, j := range SchedulerJobs {
+			known = known || j == name
+		}
+		if !known {
+			return fmt.Errorf("scheduler.jobs: unknown job %q (known: %s)", name, strings.Join(SchedulerJobs, ", "))
+		}
+		if strings.TrimSpace(expr) == "" {
+			return fmt.Errorf("scheduler.jobs.%s: schedule is empty", name)
+		}
+	}
+	return nil
+}
+
+// MemoryBudgetConfig caps the memory the object cache, multipart part
+// buffers and in-flight request pipelines may reserve between them.
+type MemoryBudgetConfig struct {
+	Enabled bool `yaml:"enabled" env:"MEMORY_BUDGET_ENABLED"`
+	// Limit is the size of the budget in bytes.
+	Limit int64 `yaml:"limit" env:"MEMORY_BUDGET_LIMIT"`
+	// MaxWait is how long a request queues for memory before it is
+	// refused with 503 SlowDown; 0 refuses at once.
+	MaxWait time.Duration `yaml:"max_wait" env:"MEMORY_BUDGET_MAX_WAIT"`
+	// RequestReserve is reserved for the streaming buffers of every object
+	// request while it is served. Part buffers and cache entries reserve
+	// their own size on top.
+	RequestReserve int64 `yaml:"request_reserve" env:"MEMORY_BUDGET_REQUEST_RESERVE"`
+}
+
+// Default memory budget settings.
+const (
+	DefaultMemoryBudgetLimit          int64 = 1 << 30
+	DefaultMemoryBudgetMaxWait              = 5 * time.Second
+	DefaultMemoryBudgetRequestReserve int64 = 256 << 10
+)
+
+// Validate checks an enabled memory budget.
+func (m MemoryBudgetConfig) Validate() error {
+	if m.Limit <= 0 {
+		return fmt.Errorf("memory_budget.limit must be positive")
+	}
+	if m.MaxWait < 0 {
+		return fmt.Errorf("memory_budget.max_wait must not be negative")
+	}
+	if m.RequestReserve < 0 || m.RequestReserve > m.Limit {
+		return fmt.Errorf("memory_budget.request_reserve must be between 0 and memory_budget.limit")
+	}
+	return nil
+}
+
+// AdmissionConfig bounds the object requests served at once. Past the
+// limit, requests wait in a bounded queue that admits metadata operations
+// first and large uploads last; requests that cannot be queued, or wait too
+// long, are refused with 503 SlowDown and Retry-After.
+type AdmissionConfig struct {
+	Enabled bool `yaml:"enabled" env:"ADMISSION_ENABLED"`
+	// MaxInFlight is the number of requests served concurrently. 0 uses
+	// four per available CPU, since encryption is CPU-bound.
+	MaxInFlight int `yaml:"max_in_flight" env:"ADMISSION_MAX_IN_FLIGHT"`
+	// QueueSize bounds the waiting requests. When it is full, a request
+	// displaces the newest waiter of a lower priority, if any.
+	QueueSize int `yaml:"queue_size" env:"ADMISSION_QUEUE_SIZE"`
+	// MaxWait is how long a request may wait for a slot; 0 refuses at
+	// once.
+	MaxWait time.Duration `yaml:"max_wait" env:"ADMISSION_MAX_WAIT"`
+	// RetryAfter is advertised to refused clients, rounded up to seconds.
+	RetryAfter time.Duration `yaml:"retry_after" env:"ADMISSION_RETRY_AFTER"`
+	// LargeRequestSize is the body size from which PUT and POST requests,
+	// and those without a Content-Length, get the lowest priority.
+	LargeRequestSize int64 `yaml:"large_request_size" env:"ADMISSION_LARGE_REQUEST_SIZE"`
+	// PriorityBuckets and PriorityAccessKeys select high-priority traffic,
+	// which is admitted at once, even past MaxInFlight, and never queued
+	// or shed.
+	PriorityBuckets    []string `yaml:"priority_buckets" env:"ADMISSION_PRIORITY_BUCKETS"`
+	PriorityAccessKeys []string `yaml:"priority_access_keys" env:"ADMISSION_PRIORITY_ACCESS_KEYS"`
+}
+
+// Default admission control settings.
+const (
+	DefaultAdmissionQueueSize              = 256
+	DefaultAdmissionMaxWait                = 2 * time.Second
+	DefaultAdmissionRetryAfter             = time.Second
+	DefaultAdmissionLargeRequestSize int64 = 16 << 20
+)
+
+// Validate checks enabled admission control.
+func (a AdmissionConfig) Validate() error {
+	if a.MaxInFlight < 0 {
+		return fmt.Errorf("admission.max_in_flight must not be negative")
+	}
+	if a.QueueSize < 0 {
+		return fmt.Errorf("admission.queue_size must not be negative")
+	}
+	if a.MaxWait < 0 {
+		return fmt.Errorf("admission.max_wait must not be negative")
+	}
+	if a.RetryAfter <= 0 {
+		return fmt.Errorf("admission.retry_after must be positive")
+	}
+	if a.LargeRequestSize <= 0 {
+		return fmt.Errorf("admission.large_request_size must be positive")
+	}
+	for _, b := range a.PriorityBuckets {
+		if b == "" {
+			return fmt.Errorf("admission.priority_buckets must not contain an empty name")
+		}
+	}
+	for _, k := range a.PriorityAccessKeys {
+		if k == "" {
+			return fmt.Errorf("admission.priority_access_keys must not contain an empty key")
+		}
+	}
+	return nil
+}
+
+// Default hook pipeline settings.
+const (
+	DefaultHooksWorkers       = 2
+	DefaultHooksQueueSize     = 100
+	DefaultHooksMaxObjectSize = 100 << 20
+	DefaultHooksMaxOutputSize = 100 << 20
+	DefaultHookTimeout        = time.Minute
+)
+
+// Validate checks an enabled hook pipeline.
+func (h HooksConfig) Validate() error {
+	if h.Workers < 1 {
+		return fmt.Errorf("hooks.workers must be at least 1")
+	}
+	if h.QueueSize < 1 {
+		return fmt.Errorf("hooks.queue_size must be at least 1")
+	}
+	if h.MaxObjectSize <= 0 || h.MaxOutputSize <= 0 {
+		return fmt.Errorf("hooks.max_object_size and hooks.max_output_size must be positive")
+	}
+	if len(h.Rules) == 0 {
+		return fmt.Errorf("hooks.rules must contain at least one rule when hooks are enabled")
+	}
+	for i, r := range h.Rules {
+		name := r.Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		if (len(r.Exec) == 0) == (r.Webhook == "") {
+			return fmt.Errorf("hooks.rules[%s]: exactly one of exec and webhook is required", name)
+		}
+		if r.Webhook != "" {
+			u, err := url.Parse(r.Webhook)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("hooks.rules[%s].webhook must be an http(s) URL", name)
+			}
+		}
+		if r.Output == "" || !strings.Contains(r.Output, "{") {
+			return fmt.Errorf("hooks.rules[%s].output must be a key template using {key}, {dir} or {name}", name)
+		}
+		if r.Timeout < 0 {
+			return fmt.Errorf("hooks.rules[%s].timeout must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// ScanningConfig configures malware scanning of uploads. A PutObject on a
+// scanned bucket is spooled, streamed in plaintext to the scanner and only
+// then encrypted and written to the backend.
+type ScanningConfig struct {
+	Enabled bool `yaml:"enabled" env:"SCANNING_ENABLED"`
+	// Protocol is "clamd" (INSTREAM) or "icap" (RESPMOD).
+	Protocol string `yaml:"protocol" env:"SCANNING_PROTOCOL"`
+	// Address is host:port or unix:/path for clamd, and an
+	// icap://host[:port]/service URL for ICAP.
+	Address string        `yaml:"address" env:"SCANNING_ADDRESS"`
+	Timeout time.Duration `yaml:"timeout" env:"SCANNING_TIMEOUT"`
+	// MaxObjectSize is the largest upload that is scanned. Larger uploads
+	// are handled like a scanner failure (see FailOpen).
+	MaxObjectSize int64 `yaml:"max_object_size" env:"SCANNING_MAX_OBJECT_SIZE"`
+	// Action is applied to infected uploads: "block" rejects them,
+	// "quarantine" stores them with QuarantineTag set. Bucket policies
+	// override it with scan_action, which may also be "off".
+	Action string `yaml:"action" env:"SCANNING_ACTION"`
+	// FailOpen stores uploads that could not be scanned instead of
+	// rejecting them with 503.
+	FailOpen bool `yaml:"fail_open" env:"SCANNING_FAIL_OPEN"`
+	// QuarantineTag is the object tag key set to "infected" in quarantine
+	// mode.
+	QuarantineTag string `yaml:"quarantine_tag" env:"SCANNING_QUARANTINE_TAG"`
+}
+
+// Scanning actions.
+const (
+	ScanActionBlock      = "block"
+	ScanActionQuarantine = "quarantine"
+	ScanActionOff        = "off"
+)
+
+// Default scanning settings.
+const (
+	DefaultScanTimeout       = 30 * time.Second
+	DefaultScanMaxObjectSize = 100 << 20
+	DefaultQuarantineTag     = "s3eg-quarantine"
+)
+
+// Validate checks enabled scanning settings.
+func (s ScanningConfig) Validate() error {
+	switch s.Protocol {
+	case "clamd":
+		if s.Address == "" {
+			return fmt.Errorf("scanning.address is required")
+		}
+	case "icap":
+		u, err := url.Parse(s.Address)
+		if err != nil || u.Scheme != "icap" || u.Host == "" {
+			return fmt.Errorf("scanning.address must be an icap://host[:port]/service URL for the icap protocol")
+		}
+	default:
+		return fmt.Errorf("scanning.protocol must be one of: clamd, icap (got %q)", s.Protocol)
+	}
+	if s.Timeout <= 0 {
+		return fmt.Errorf("scanning.timeout must be positive")
+	}
+	if s.MaxObjectSize <= 0 {
+		return fmt.Errorf("scanning.max_object_size must be positive")
+	}
+	if s.Action != ScanActionBlock && s.Action != ScanActionQuarantine {
+		return fmt.Errorf("scanning.action must be one of: block, quarantine (got %q)", s.Action)
+	}
+	if s.QuarantineTag == "" || len(s.QuarantineTag) > 128 {
+		return fmt.Errorf("scanning.quarantine_tag must be 1-128 characters")
+	}
+	return nil
+}
+
+// SelfTestConfig configures the startup self-test. When enabled the gateway
+// round-trips a canary buffer through the encryption engine, wraps and
+// unwraps a data key through the key manager and writes, reads back and
+// deletes a canary object in Bucket. Readiness reports failure until the
+// self-test has passed.
+type SelfTestConfig struct {
+	Enabled bool `yaml:"enabled" env:"SELF_TEST_ENABLED"`
+	// Bucket receives the canary object. Empty falls back to
+	// proxied_bucket; when both are empty the backend check is skipped.
+	Bucket string `yaml:"bucket" env:"SELF_TEST_BUCKET"`
+	// KeyPrefix is prepended to the canary object key.
+	KeyPrefix string `yaml:"key_prefix" env:"SELF_TEST_KEY_PREFIX"`
+	// Timeout bounds one self-test run.
+	Timeout time.Duration `yaml:"timeout" env:"SELF_TEST_TIMEOUT"`
+	// RetryInterval is the delay before a failed self-test is run again.
+	// Zero runs it once and leaves the gateway unready on failure.
+	RetryInterval time.Duration `yaml:"retry_interval" env:"SELF_TEST_RETRY_INTERVAL"`
+}
+
+// Default self-test settings.
+const (
+	DefaultSelfTestKeyPrefix     = ".s3eg-self-test/"
+	DefaultSelfTestTimeout       = 30 * time.Second
+	DefaultSelfTestRetryInterval = 30 * time.Second
+)
+
+// Validate checks enabled self-test settings.
+func (s SelfTestConfig) Validate() error {
+	if s.Timeout <= 0 {
+		return fmt.Errorf("self_test.timeout must be positive")
+	}
+	if s.RetryInterval < 0 {
+		return fmt.Errorf("self_test.retry_interval must not be negative")
+	}
+	return nil
+}
+
+// CanaryConfig configures the periodic canary probe: the self-test round
+// trip repeated while the gateway runs. Results only feed the
+// gateway_canary_* metrics; readiness is not affected.
+type CanaryConfig struct {
+	Enabled  bool          `yaml:"enabled" env:"CANARY_ENABLED"`
+	Interval time.Duration `yaml:"interval" env:"CANARY_INTERVAL"`
+	// Bucket receives the canary object. Empty falls back to
+	// proxied_bucket; when both are empty only the crypto and key manager
+	// steps run.
+	Bucket    string        `yaml:"bucket" env:"CANARY_BUCKET"`
+	KeyPrefix string        `yaml:"key_prefix" env:"CANARY_KEY_PREFIX"`
+	Timeout   time.Duration `yaml:"timeout" env:"CANARY_TIMEOUT"`
+}
+
+// Default canary probe settings.
+const (
+	DefaultCanaryInterval  = 5 * time.Minute
+	DefaultCanaryKeyPrefix = ".s3eg-canary/"
+	DefaultCanaryTimeout   = 30 * time.Second
+)
+
+// Validate checks enabled canary settings.
+func (c CanaryConfig) Validate() error {
+	if c.Interval < time.Second {
+		return fmt.Errorf("canary.interval must be at least 1s")
+	}
+	if c.Timeout <= 0 || c.Timeout > c.Interval {
+		return fmt.Errorf("canary.timeout must be positive and no longer than canary.interval")
+	}
+	return nil
+}
+
+// WarmPoolConfig keeps connections to the backend and the KMS open between
+// requests: every Interval the gateway runs Connections concurrent health
+// checks against each, so that many connections stay in the idle pool and
+// a failing dependency shows up before a client request hits it.
+type WarmPoolConfig struct {
+	Enabled     bool          `yaml:"enabled" env:"WARM_POOL_ENABLED"`
+	Interval    time.Duration `yaml:"interval" env:"WARM_POOL_INTERVAL"`
+	Connections int           `yaml:"connections" env:"WARM_POOL_CONNECTIONS"`
+	Timeout     time.Duration `yaml:"timeout" env:"WARM_POOL_TIMEOUT"`
+}
+
+// Default warm pool settings. The interval stays below the 90s idle
+// timeout of Go's default transport.
+const (
+	DefaultWarmPoolInterval    = 30 * time.Second
+	DefaultWarmPoolConnections = 4
+	DefaultWarmPoolTimeout     = 5 * time.Second
+	maxWarmPoolConnections     = 64
+)
+
+// Validate checks enabled warm pool settings.
+func (w WarmPoolConfig) Validate() error {
+	if w.Interval < time.Second {
+		return fmt.Errorf("warm_pool.interval must be at least 1s")
+	}
+	if w.Connections < 1 || w.Connections > maxWarmPoolConnections {
+		return fmt.Errorf("warm_pool.connections must be between 1 and %d", maxWarmPoolConnections)
+	}
+	if w.Timeout <= 0 || w.Timeout > w.Interval {
+		return fmt.Errorf("warm_pool.timeout must be positive and no longer than warm_pool.interval")
+	}
+	return nil
+}
+
+// AirGappedConfig restricts the gateway's outbound connections to the
+// backend and the KMS, for regulated and air-gapped environments. Startup
+// fails while the configuration names any other destination (a tracing
+// collector, an audit sink, a webhook, a scanner, a rotation peer, Valkey)
+// that Allow does not list, and while the gateway runs connections to any
+// other host are refused (see internal/airgap). Loopback addresses and Unix
+// sockets are always allowed.
+type AirGappedConfig struct {
+	Enabled bool `yaml:"enabled" env:"AIR_GAPPED_ENABLED"`
+	// Allow lists further hosts the gateway may connect to, each a host
+	// name or IP, a host:port, a URL, or "*.example.com" for every
+	// subdomain of example.com.
+	Allow []string `yaml:"allow" env:"AIR_GAPPED_ALLOW"`
+}
+
+// airGappedBackend is a backend the gateway connects to, named by its
+// config path.
+type airGappedBackend struct {
+	field   string
+	backend *BackendConfig
+}
+
+// airGappedBackends returns every backend in use: the primary, the shards
+// and the tiering backend.
+func (c *Config) airGappedBackends() []airGappedBackend {
+	backends := []airGappedBackend{{"backend", &c.Backend}}
+	if c.Sharding.Enabled {
+		for i := range c.Sharding.Shards {
+			backends = append(backends, airGappedBackend{fmt.Sprintf("sharding.shards[%d].backend", i), &c.Sharding.Shards[i].Backend})
+		}
+	}
+	if c.Tiering.Enabled && slices.ContainsFunc(c.Tiering.Rules, func(r TieringRule) bool { return r.Secondary }) {
+		backends = append(backends, airGappedBackend{"tiering.backend", &c.Tiering.Backend})
+	}
+	return backends
+}
+
+// airGappedHosts returns the hosts b is reached at: its endpoint and read
+// replicas, or its proxy. Without path-style addressing S3 requests go to
+// <bucket>.<endpoint host>, so the endpoints' subdomains are included.
+func (b *BackendConfig) airGappedHosts() []string {
+	var hosts []string
+	switch b.StorageType() {
+	case BackendTypeS3:
+		for _, ep := range append([]string{b.Endpoint}, b.ReadReplicas.Endpoints...) {
+			if ep == "" {
+				continue
+			}
+			hosts = append(hosts, ep)
+			if !b.UsePathStyle && b.UseSSL {
+				hosts = append(hosts, "*."+airgap.Host(ep))
+			}
+		}
+	case BackendTypeGCS:
+		if b.Endpoint != "" {
+			hosts = append(hosts, b.Endpoint)
+		} else {
+			hosts = append(hosts, "storage.googleapis.com")
+		}
+	case BackendTypeAzure:
+		if b.Azure.Endpoint != "" {
+			hosts = append(hosts, b.Azure.Endpoint)
+		} else {
+			hosts = append(hosts, b.Azure.AccountName+".blob.core.windows.net")
+		}
+	}
+	if b.Proxy != "" {
+		hosts = append(hosts, b.Proxy)
+	}
+	return hosts
+}
+
+// AirGappedHosts returns the hosts air-gapped mode allows connections to:
+// those of every backend (shards, read replicas, the tiering backend and
+// backend proxies included), the KMS, and air_gapped.allow.
+func (c *Config) AirGappedHosts() []string {
+	hosts := slices.Clone(c.AirGapped.Allow)
+	for _, b := range c.airGappedBackends() {
+		hosts = append(hosts, b.backend.airGappedHosts()...)
+	}
+	km := c.Encryption.KeyManager
+	if km.Enabled {
+		switch strings.ToLower(km.Provider) {
+		case "cosmian", "kmip":
+			hosts = append(hosts, km.Cosmian.Endpoint)
+		}
+	}
+	return hosts
+}
+
+// outboundDestination is an address outside the backend and the KMS that
+// the configuration makes the gateway connect to, named by its config path.
+type outboundDestination struct {
+	field, addr string
+}
+
+// outboundDestinations lists the addresses of the enabled features that
+// connect to something other than the backend or the KMS.
+func (c *Config) outboundDestinations() []outboundDestination {
+	var out []outboundDestination
+	add := func(field, addr string) {
+		if addr != "" {
+			out = append(out, outboundDestination{field, addr})
+		}
+	}
+	if c.Tracing.Enabled {
+		switch c.Tracing.Exporter {
+		case "jaeger":
+			add("tracing.jaeger_endpoint", c.Tracing.JaegerEndpoint)
+		case "otlp":
+			add("tracing.otlp_endpoint", c.Tracing.OtlpEndpoint)
+		}
+	}
+	if c.Audit.Enabled {
+		switch c.Audit.Sink.Type {
+		case "http":
+			add("audit.sink.endpoint", c.Audit.Sink.Endpoint)
+		case "cloudwatch":
+			add("audit.sink.cloudwatch.endpoint", c.Audit.Sink.CloudWatch.Endpoint)
+		case "gcp_logging":
+			if c.Audit.Sink.GCPLogging.Endpoint != "" {
+				add("audit.sink.gcp_logging.endpoint", c.Audit.Sink.GCPLogging.Endpoint)
+			} else {
+				add("audit.sink.gcp_logging.endpoint", "logging.googleapis.com")
+			}
+		}
+	}
+	if c.Hooks.Enabled {
+		for i, r := range c.Hooks.Rules {
+			add(fmt.Sprintf("hooks.rules[%d].webhook", i), r.Webhook)
+		}
+	}
+	if c.Transforms.Enabled {
+		for i, r := range c.Transforms.Rules {
+			add(fmt.Sprintf("transforms.rules[%d].url", i), r.URL)
+		}
+	}
+	if c.Scanning.Enabled {
+		add("scanning.address", c.Scanning.Address)
+	}
+	if c.Admin.Enabled {
+		for i, p := range c.Admin.Rotation.Peers {
+			add(fmt.Sprintf("admin.rotation.peers[%d]", i), p)
+		}
+	}
+	add("multipart_state.valkey.addr", c.MultipartState.Valkey.Addr)
+	return out
+}
+
+// validateAirGapped checks that air-gapped mode can reach every backend
+// without leaving the allowed hosts, and that every other destination in
+// the configuration is listed in air_gapped.allow.
+func (c *Config) validateAirGapped() error {
+	for i, h := range c.AirGapped.Allow {
+		if airgap.Host(strings.TrimPrefix(strings.TrimSpace(h), "*.")) == "" {
+			return fmt.Errorf("air_gapped.allow[%d]: %q is not a host", i, h)
+		}
+	}
+	for _, b := range c.airGappedBackends() {
+		if b.backend.StorageType() == BackendTypeS3 && b.backend.Endpoint == "" {
+			return fmt.Errorf("%s.endpoint is required in air-gapped mode; the default AWS endpoint is not reachable offline", b.field)
+		}
+	}
+	if c.Audit.Enabled && c.Audit.Sink.Type == "cloudwatch" && c.Audit.Sink.CloudWatch.Endpoint == "" {
+		return fmt.Errorf("audit.sink.cloudwatch.endpoint is required in air-gapped mode")
+	}
+	guard := airgap.NewGuard(c.AirGappedHosts())
+	for _, d := range c.outboundDestinations() {
+		if host := airgap.Host(d.addr); !guard.Allows(host) {
+			return fmt.Errorf("air_gapped: %s connects to %s, which is neither the backend nor the KMS; list it in air_gapped.allow or disable it", d.field, host)
+		}
+	}
+	return nil
+}
+
+// ReadinessConfig tunes the component checks behind /ready/kms and
+// /ready/backend, which /ready also runs.
+type ReadinessConfig struct {
+	KMS     ReadinessCheckConfig `yaml:"kms"`
+	Backend ReadinessCheckConfig `yaml:"backend"`
+	// BackendBucket is listed to check the backend. Empty falls back to
+	// proxied_bucket; when both are empty the backend check is skipped.
+	BackendBucket string `yaml:"backend_bucket" env:"READINESS_BACKEND_BUCKET"`
+}
+
+// ReadinessCheckConfig bounds one component check and how long its result
+// is reused, so frequent probes do not load the dependency.
+type ReadinessCheckConfig struct {
+	// Timeout bounds one check. Zero uses DefaultReadinessTimeout.
+	Timeout time.Duration `yaml:"timeout"`
+	// CacheTTL is how long a result is served before the check runs again.
+	// Zero runs the check on every probe.
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
+
+// Default readiness check settings. The timeout leaves room within the
+// Helm chart's 3s probe timeout for the 503 that names the failing check.
+const (
+	DefaultReadinessTimeout  = 2 * time.Second
+	DefaultReadinessCacheTTL = 5 * time.Second
+)
+
+// Validate checks the readiness settings.
+func (r ReadinessConfig) Validate() error {
+	if r.KMS.Timeout < 0 || r.KMS.CacheTTL < 0 {
+		return fmt.Errorf("readiness.kms.timeout and cache_ttl must not be negative")
+	}
+	if r.Backend.Timeout < 0 || r.Backend.CacheTTL < 0 {
+		return fmt.Errorf("readiness.backend.timeout and cache_ttl must not be negative")
+	}
+	return nil
+}
+
+// InspectionConfig configures content inspection (DLP) of text uploads.
+// The start of each matching PutObject body is checked against the rules
+// before it is encrypted.
+type InspectionConfig struct {
+	Enabled bool `yaml:"enabled" env:"INSPECTION_ENABLED"`
+	// MaxSize is the number of leading bytes inspected per upload. Bytes
+	// beyond it are stored without inspection.
+	MaxSize int64 `yaml:"max_size" env:"INSPECTION_MAX_SIZE"`
+	// ContentTypes are Content-Type prefixes to inspect. Empty selects the
+	// text types that compression also treats as compressible.
+	ContentTypes []string `yaml:"content_types" env:"INSPECTION_CONTENT_TYPES"`
+	// TagKey is the object tag set by "tag" rules; its value lists the
+	// matching rule names separated by "+".
+	TagKey string           `yaml:"tag_key" env:"INSPECTION_TAG_KEY"`
+	Rules  []InspectionRule `yaml:"rules"`
+}
+
+// InspectionRule is one content pattern. Exactly one of Pattern, Keywords
+// and Builtin is set.
+type InspectionRule struct {
+	// Name identifies the rule in tags, audit events and metrics. Letters,
+	// digits, "_", "-", "." and ":" only.
+	Name string `yaml:"name"`
+	// Pattern is an RE2 regular expression.
+	Pattern string `yaml:"pattern"`
+	// Keywords match case-insensitively anywhere in the content.
+	Keywords []string `yaml:"keywords"`
+	// Builtin selects a validated detector: credit_card (Luhn-checked),
+	// us_ssn, iban (checksum-verified) or aws_access_key.
+	Builtin string `yaml:"builtin"`
+	// Action is "block" (reject the upload), "tag" (store it tagged) or
+	// "audit" (store it and emit an audit event only).
+	Action string `yaml:"action"`
+}
+
+// Inspection rule actions, strongest first.
+const (
+	InspectionActionBlock = "block"
+	InspectionActionTag   = "tag"
+	InspectionActionAudit = "audit"
+)
+
+// Default inspection settings.
+const (
+	DefaultInspectionMaxSize = 1 << 20
+	DefaultInspectionTagKey  = "s3eg-dlp"
+)
+
+var metadataKeyPrefixPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*-$`)
+
+var inspectionRuleName = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+
+// Validate checks enabled inspection settings.
+func (i InspectionConfig) Validate() error {
+	if i.MaxSize <= 0 {
+		return fmt.Errorf("inspection.max_size must be positive")
+	}
+	if i.TagKey == "" || len(i.TagKey) > 128 {
+		return fmt.Errorf("inspection.tag_key must be 1-128 characters")
+	}
+	if len(i.Rules) == 0 {
+		return fmt.Errorf("inspection.rules must contain at least one rule when inspection is enabled")
+	}
+	seen := make(map[string]bool, len(i.Rules))
+	for n, r := range i.Rules {
+		if !inspectionRuleName.MatchString(r.Name) {
+			return fmt.Errorf("inspection.rules[%d].name must be non-empty and use only letters, digits, _ - . :", n)
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("inspection.rules[%s]: duplicate rule name", r.Name)
+		}
+		seen[r.Name] = true
+		set := 0
+		for _, present := range []bool{r.Pattern != "", len(r.Keywords) > 0, r.Builtin != ""} {
+			if present {
+				set++
+			}
+		}
+		if set != 1 {
+			return fmt.Errorf("inspection.rules[%s]: exactly one of pattern, keywords and builtin is required", r.Name)
+		}
+		if r.Pattern != "" {
+			if _, err := regexp.Compile(r.Pattern); err != nil {
+				return fmt.Errorf("inspection.rules[%s].pattern: %w", r.Name, err)
+			}
+		}
+		switch r.Builtin {
+		case "", "credit_card", "us_ssn", "iban", "aws_access_key":
+		default:
+			return fmt.Errorf("inspection.rules[%s].builtin must be one of: credit_card, us_ssn, iban, aws_access_key (got %q)", r.Name, r.Builtin)
+		}
+		switch r.Action {
+		case InspectionActionBlock, InspectionActionTag, InspectionActionAudit:
+		default:
+			return fmt.Errorf("inspection.rules[%s].action must be one of: block, tag, audit (got %q)", r.Name, r.Action)
+		}
+	}
+	return nil
+}
+
+// TransformsConfig configures response transformation on GetObject. A
+// full-object GET matching a rule is decrypted as usual and the plaintext is
+// streamed to the rule's service, whose response is returned to the client
+// in place of the object, for example to redact columns or resize images.
+// Services never receive ciphertext or key material.
+type TransformsConfig struct {
+	Enabled bool `yaml:"enabled" env:"TRANSFORMS_ENABLED"`
+	// MaxOutputSize caps a transformed response; a service producing more
+	// has the response cut off.
+	MaxOutputSize int64           `yaml:"max_output_size" env:"TRANSFORMS_MAX_OUTPUT_SIZE"`
+	Rules         []TransformRule `yaml:"rules"`
+}
+
+// TransformRule routes GETs on a bucket and key prefix to a transformation
+// service. The first matching rule applies.
+type TransformRule struct {
+	// Name identifies the rule in logs and metrics. Letters, digits, "_",
+	// "-", "." and ":" only.
+	Name   string `yaml:"name"`
+	Bucket string `yaml:"bucket"`
+	// Prefix restricts the rule to keys starting with it.
+	Prefix string `yaml:"prefix"`
+	// URL is the service the plaintext is POSTed to. A 200 response body
+	// is returned to the client with the response's Content-Type; any other
+	// status fails the GET.
+	URL string `yaml:"url"`
+	// Module names an extensions.modules entry whose s3eg_filter export
+	// transforms the plaintext in-process instead of a service. Set either
+	// URL or Module.
+	Module  string        `yaml:"module"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// DefaultTransformsMaxOutputSize is the default cap on a transformed response.
+const DefaultTransformsMaxOutputSize = 100 << 20
+
+// Validate checks enabled response transformation settings.
+func (t TransformsConfig) Validate() error {
+	if t.MaxOutputSize <= 0 {
+		return fmt.Errorf("transforms.max_output_size must be positive")
+	}
+	if len(t.Rules) == 0 {
+		return fmt.Errorf("transforms.rules must contain at least one rule when transforms are enabled")
+	}
+	seen := make(map[string]bool, len(t.Rules))
+	for n, r := range t.Rules {
+		if !inspectionRuleName.MatchString(r.Name) {
+			return fmt.Errorf("transforms.rules[%d].name must be non-empty and use only letters, digits, _ - . :", n)
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("transforms.rules[%s]: duplicate rule name", r.Name)
+		}
+		seen[r.Name] = true
+		if r.Bucket == "" {
+			return fmt.Errorf("transforms.rules[%s].bucket is required", r.Name)
+		}
+		if r.Module != "" {
+			if r.URL != "" {
+				return fmt.Errorf("transforms.rules[%s]: set url or module, not both", r.Name)
+			}
+		} else if u, err := url.Parse(r.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("transforms.rules[%s].url must be an http(s) URL", r.Name)
+		}
+		if r.Timeout < 0 {
+			return fmt.Errorf("transforms.rules[%s].timeout must not be negative", r.Name)
+		}
+	}
+	return nil
+}
+
+// ExtensionsConfig loads operator-supplied WebAssembly modules that run
+// in-process in a sandbox, with no filesystem, network or clock access and
+// under per-module memory, time and size limits. See internal/sandbox and
+// docs/adr/0013-wasm-extension-sandbox.md for the module ABI.
+type ExtensionsConfig struct {
+	Enabled bool              `yaml:"enabled" env:"EXTENSIONS_ENABLED"`
+	Modules []ExtensionModule `yaml:"modules"`
+}
+
+// Extension hooks a module can be attached to. Filters are attached by
+// naming the module in a transforms rule.
+const (
+	ExtensionHookPolicy   = "policy"
+	ExtensionHookMetadata = "metadata"
+)
+
+// ExtensionModule is one WebAssembly module and the hooks it runs in.
+type ExtensionModule struct {
+	// Name identifies the module in logs, metrics and transforms rules.
+	// Letters, digits, "_", "-", "." and ":" only.
+	Name string `yaml:"name"`
+	// Path is the compiled module (.wasm), read at startup.
+	Path string `yaml:"path"`
+	// Hooks lists where the module runs: "policy" decides on every
+	// authenticated request, "metadata" rewrites the user metadata of
+	// PutObject and CopyObject before encryption.
+	Hooks []string `yaml:"hooks"`
+	// Bucket and Prefix restrict the hooks to matching objects; empty
+	// Bucket matches every bucket.
+	Bucket string `yaml:"bucket"`
+	Prefix string `yaml:"prefix"`
+	// MemoryPages caps the module's linear memory in 64 KiB pages.
+	// Default: 256 (16 MiB).
+	MemoryPages uint32 `yaml:"memory_pages"`
+	// Timeout is the deadline of one call. Default: 1s.
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxInputSize bounds the input and output of one call, and so the
+	// objects a filter can handle. Default: 8 MiB.
+	MaxInputSize int64 `yaml:"max_input_size"`
+}
+
+// Validate checks enabled extension settings.
+func (e ExtensionsConfig) Validate() error {
+	if len(e.Modules) == 0 {
+		return fmt.Errorf("extensions.modules must contain at least one module when extensions are enabled")
+	}
+	seen := make(map[string]bool, len(e.Modules))
+	for n, m := range e.Modules {
+		if !inspectionRuleName.MatchString(m.Name) {
+			return fmt.Errorf("extensions.modules[%d].name must be non-empty and use only letters, digits, _ - . :", n)
+		}
+		if seen[m.Name] {
+			return fmt.Errorf("extensions.modules[%s]: duplicate module name", m.Name)
+		}
+		seen[m.Name] = true
+		if m.Path == "" {
+			return fmt.Errorf("extensions.modules[%s].path is required", m.Name)
+		}
+		for _, h := range m.Hooks {
+			if h != ExtensionHookPolicy && h != ExtensionHookMetadata {
+				return fmt.Errorf("extensions.modules[%s].hooks must contain only %q or %q (got %q)", m.Name, ExtensionHookPolicy, ExtensionHookMetadata, h)
+			}
+		}
+		if m.MemoryPages > 65536 {
+			return fmt.Errorf("extensions.modules[%s].memory_pages must be at most 65536", m.Name)
+		}
+		if m.Timeout < 0 {
+			return fmt.Errorf("extensions.modules[%s].timeout must not be negative", m.Name)
+		}
+		if m.MaxInputSize < 0 {
+			return fmt.Errorf("extensions.modules[%s].max_input_size must not be negative", m.Name)
+		}
+	}
+	return nil
+}
+
+// hasModule reports whether name is a module of enabled extensions.
+func (e ExtensionsConfig) hasModule(name string) bool {
+	if !e.Enabled {
+		return false
+	}
+	for _, m := range e.Modules {
+		if m.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultSLOWindows returns the default burn-rate look-back windows.
+func DefaultSLOWindows() []time.Duration {
+	return []time.Duration{5 * time.Minute, 30 * time.Minute, time.Hour, 6 * time.Hour}
+}
+
+// Default returns the configuration LoadConfig starts from, before the
+// file and environment are applied.
+func Default() *Config {
+	return &Config{
+		ListenAddr: ":8080",
+		LogLevel:   "info",
+		Encryption: EncryptionConfig{
+			KeyManager: KeyManagerConfig{
+				Provider:       "cosmian",
+				DualReadWindow: 1,
+				RotationPolicy: RotationPolicyConfig{
+					Enabled:     false,
+					GraceWindow: 0, // Use DualReadWindow by default
+				},
+				VersionWatch: KeyVersionWatchConfig{
+					Interval: DefaultKeyVersionWatchInterval,
+				},
+			},
 			Hardware: HardwareConfig{
 				EnableAESNI:    true,
 				EnableARMv8AES: true,
@@ -660,6 +3062,13 @@ func LoadConfig(path string) (*Config, error) {
 					Iterations: 600000,
 				},
 			},
+			LazyUpgrade: LazyUpgradeConfig{
+				Enabled:       false,
+				Rate:          DefaultLazyUpgradeRate,
+				MaxObjectSize: DefaultLazyUpgradeMaxObjectSize,
+				QueueSize:     DefaultLazyUpgradeQueueSize,
+			},
+			DoubleEncryption: DoubleEncryptionReject,
 		},
 		Backend: BackendConfig{
 			Endpoint: "", // Leave empty for AWS default, or set for any S3-compatible endpoint
@@ -672,6 +3081,27 @@ func LoadConfig(path string) (*Config, error) {
 				MaxBackoff:     DefaultBackendRetryMaxBackoff,
 				Jitter:         DefaultBackendRetryJitter,
 			},
+			ReadReplicas: BackendReadReplicasConfig{
+				ProbeInterval: DefaultReadProbeInterval,
+			},
+			RangeHedging: BackendRangeHedgingConfig{
+				MinDelay: DefaultRangeHedgeMinDelay,
+				MaxDelay: DefaultRangeHedgeMaxDelay,
+			},
+			HeadElision: BackendHeadElisionConfig{
+				TTL:        DefaultHeadElisionTTL,
+				MaxEntries: DefaultHeadElisionMaxEntries,
+			},
+			ParallelRange: BackendParallelRangeConfig{
+				PartSize:    DefaultParallelRangePartSize,
+				Concurrency: DefaultParallelRangeConcurrency,
+			},
+			StreamingUpload: BackendStreamingUploadConfig{
+				Enabled:     true,
+				Threshold:   DefaultStreamingUploadThreshold,
+				PartSize:    DefaultStreamingUploadPartSize,
+				Concurrency: DefaultStreamingUploadConcurrency,
+			},
 		},
 		Compression: CompressionConfig{
 			Enabled:   false,
@@ -739,6 +3169,10 @@ func LoadConfig(path string) (*Config, error) {
 				MaxConcurrentProfiles: 2,
 				MaxProfileSeconds:     60,
 			},
+			Rotation: AdminRotationConfig{
+				PeerTimeout:  DefaultRotationPeerTimeout,
+				DryRunMaxAge: DefaultRotationDryRunMaxAge,
+			},
 		},
 		MultipartState: MultipartStateConfig{
 			Valkey: ValkeyConfig{
@@ -754,7 +3188,132 @@ func LoadConfig(path string) (*Config, error) {
 				},
 			},
 		},
+		Journal: JournalConfig{
+			Enabled: false,
+			Path:    "/var/lib/s3-encryption-gateway/journal.log",
+			Sync:    true,
+		},
+		ListEnrichment: ListEnrichmentConfig{
+			Enabled:     true,
+			Concurrency: DefaultListEnrichmentConcurrency,
+			CacheSize:   DefaultListEnrichmentCacheSize,
+			CacheTTL:    DefaultListEnrichmentCacheTTL,
+		},
+		SizeIndex: SizeIndexConfig{
+			Enabled:       false,
+			Prefix:        ".s3eg-index/",
+			FlushInterval: 5 * time.Second,
+		},
+		AccessStats: AccessStatsConfig{
+			Enabled:       false,
+			Prefix:        ".s3eg-access/",
+			FlushInterval: 30 * time.Second,
+		},
+		Tiering: TieringConfig{
+			Enabled:  false,
+			Interval: time.Hour,
+		},
+		Sharding: ShardingConfig{
+			VirtualNodes: DefaultShardVirtualNodes,
+		},
+		Quota: QuotaConfig{
+			ScanInterval: DefaultQuotaScanInterval,
+		},
+		Billing: BillingConfig{
+			Interval: time.Hour,
+			Prefix:   "billing/",
+			Format:   BillingFormatCSV,
+		},
+		KeyHold: KeyHoldConfig{
+			Enabled: false,
+			Key:     ".s3eg-keyholds.json",
+		},
+		Trash: TrashConfig{
+			Enabled:   false,
+			Prefix:    ".trash/",
+			Retention: 7 * 24 * time.Hour,
+			Interval:  time.Hour,
+		},
+		Batch: BatchConfig{
+			Enabled:      false,
+			Workers:      DefaultBatchWorkers,
+			MaxAttempts:  DefaultBatchMaxAttempts,
+			MaxKeys:      DefaultBatchMaxKeys,
+			ReportPrefix: ".s3eg-batch/",
+			RetainJobs:   DefaultBatchRetainJobs,
+		},
+		MemoryBudget: MemoryBudgetConfig{
+			Enabled:        false,
+			Limit:          DefaultMemoryBudgetLimit,
+			MaxWait:        DefaultMemoryBudgetMaxWait,
+			RequestReserve: DefaultMemoryBudgetRequestReserve,
+		},
+		Admission: AdmissionConfig{
+			Enabled:          false,
+			QueueSize:        DefaultAdmissionQueueSize,
+			MaxWait:          DefaultAdmissionMaxWait,
+			RetryAfter:       DefaultAdmissionRetryAfter,
+			LargeRequestSize: DefaultAdmissionLargeRequestSize,
+		},
+		Hooks: HooksConfig{
+			Enabled:       false,
+			Workers:       DefaultHooksWorkers,
+			QueueSize:     DefaultHooksQueueSize,
+			MaxObjectSize: DefaultHooksMaxObjectSize,
+			MaxOutputSize: DefaultHooksMaxOutputSize,
+		},
+		Scanning: ScanningConfig{
+			Enabled:       false,
+			Protocol:      "clamd",
+			Timeout:       DefaultScanTimeout,
+			MaxObjectSize: DefaultScanMaxObjectSize,
+			Action:        ScanActionBlock,
+			QuarantineTag: DefaultQuarantineTag,
+		},
+		Inspection: InspectionConfig{
+			Enabled: false,
+			MaxSize: DefaultInspectionMaxSize,
+			TagKey:  DefaultInspectionTagKey,
+		},
+		Transforms: TransformsConfig{
+			Enabled:       false,
+			MaxOutputSize: DefaultTransformsMaxOutputSize,
+		},
+		SelfTest: SelfTestConfig{
+			Enabled:       false,
+			KeyPrefix:     DefaultSelfTestKeyPrefix,
+			Timeout:       DefaultSelfTestTimeout,
+			RetryInterval: DefaultSelfTestRetryInterval,
+		},
+		Canary: CanaryConfig{
+			Enabled:   false,
+			Interval:  DefaultCanaryInterval,
+			KeyPrefix: DefaultCanaryKeyPrefix,
+			Timeout:   DefaultCanaryTimeout,
+		},
+		Readiness: ReadinessConfig{
+			KMS:     ReadinessCheckConfig{Timeout: DefaultReadinessTimeout, CacheTTL: DefaultReadinessCacheTTL},
+			Backend: ReadinessCheckConfig{Timeout: DefaultReadinessTimeout, CacheTTL: DefaultReadinessCacheTTL},
+		},
+		WarmPool: WarmPoolConfig{
+			Enabled:     false,
+			Interval:    DefaultWarmPoolInterval,
+			Connections: DefaultWarmPoolConnections,
+			Timeout:     DefaultWarmPoolTimeout,
+		},
+		SLO: SLOConfig{
+			Enabled: false,
+			Windows: DefaultSLOWindows(),
+			Default: SLOObjective{
+				Target: DefaultSLOTarget,
+			},
+		},
 	}
+}
+
+// LoadConfig loads configuration from a file and environment variables.
+func LoadConfig(path string) (*Config, error) {
+	config := Default()
 
 	// Load from file if provided
 	if path != "" {
@@ -774,6 +3333,10 @@ func LoadConfig(path string) (*Config, error) {
 	// Override with environment variables
 	loadFromEnv(config)
 
+	if err := config.Auth.loadCredentialsFile(); err != nil {
+		return nil, err
+	}
+
 	// Set default hardware acceleration flags if not specified (default true)
 	// This logic is now handled by initialization values above, but we check env vars here
 	// if they were set to override default initialization.
@@ -826,6 +3389,113 @@ func loadFromEnv(config *Config) {
 			config.Backend.FilterMetadataKeys[i] = strings.TrimSpace(config.Backend.FilterMetadataKeys[i])
 		}
 	}
+	if v := os.Getenv("BACKEND_CONDITIONAL_WRITES"); v != "" {
+		config.Backend.ConditionalWrites = v
+	}
+	if v := os.Getenv("BACKEND_METADATA_OVERFLOW"); v != "" {
+		config.Backend.MetadataOverflow = v
+	}
+	if v := os.Getenv("BACKEND_READ_REPLICAS"); v != "" {
+		config.Backend.ReadReplicas.Endpoints = nil
+		for _, ep := range strings.Split(v, ",") {
+			if ep = strings.TrimSpace(ep); ep != "" {
+				config.Backend.ReadReplicas.Endpoints = append(config.Backend.ReadReplicas.Endpoints, ep)
+			}
+		}
+	}
+	if v := os.Getenv("BACKEND_READ_HEDGE_AFTER"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Backend.ReadReplicas.HedgeAfter = d
+		}
+	}
+	if v := os.Getenv("BACKEND_READ_FAILOVER_AFTER"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Backend.ReadReplicas.FailoverAfter = n
+		}
+	}
+	if v := os.Getenv("BACKEND_READ_PROBE_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Backend.ReadReplicas.ProbeInterval = d
+		}
+	}
+	if v := os.Getenv("BACKEND_TYPE"); v != "" {
+		config.Backend.Type = v
+	}
+	if v := os.Getenv("BACKEND_FILESYSTEM_ROOT"); v != "" {
+		config.Backend.Filesystem.Root = v
+	}
+	if v := os.Getenv("BACKEND_AZURE_ACCOUNT_NAME"); v != "" {
+		config.Backend.Azure.AccountName = v
+	}
+	if v := os.Getenv("BACKEND_AZURE_ACCOUNT_KEY"); v != "" {
+		config.Backend.Azure.AccountKey = v
+	}
+	if v := os.Getenv("BACKEND_AZURE_ENDPOINT"); v != "" {
+		config.Backend.Azure.Endpoint = v
+	}
+	if v := os.Getenv("BACKEND_PROXY"); v != "" {
+		config.Backend.Proxy = v
+	}
+	if v := os.Getenv("BACKEND_TLS_CA_FILE"); v != "" {
+		config.Backend.TLS.CAFile = v
+	}
+	if v := os.Getenv("BACKEND_TLS_INSECURE_SKIP_VERIFY"); v != "" {
+		config.Backend.TLS.InsecureSkipVerify = v == "true" || v == "1"
+	}
+	if v := os.Getenv("BACKEND_RANGE_HEDGING_ENABLED"); v != "" {
+		config.Backend.RangeHedging.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("BACKEND_RANGE_HEDGING_MIN_DELAY"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Backend.RangeHedging.MinDelay = d
+		}
+	}
+	if v := os.Getenv("BACKEND_RANGE_HEDGING_MAX_DELAY"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Backend.RangeHedging.MaxDelay = d
+		}
+	}
+	if v := os.Getenv("BACKEND_PARALLEL_RANGE_PART_SIZE"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			config.Backend.ParallelRange.PartSize = n
+		}
+	}
+	if v := os.Getenv("BACKEND_PARALLEL_RANGE_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Backend.ParallelRange.Concurrency = n
+		}
+	}
+	if v := os.Getenv("BACKEND_STREAMING_UPLOAD_ENABLED"); v != "" {
+		config.Backend.StreamingUpload.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("BACKEND_STREAMING_UPLOAD_THRESHOLD"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			config.Backend.StreamingUpload.Threshold = n
+		}
+	}
+	if v := os.Getenv("BACKEND_STREAMING_UPLOAD_PART_SIZE"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			config.Backend.StreamingUpload.PartSize = n
+		}
+	}
+	if v := os.Getenv("BACKEND_STREAMING_UPLOAD_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Backend.StreamingUpload.Concurrency = n
+		}
+	}
+	if v := os.Getenv("BACKEND_HEAD_ELISION_ENABLED"); v != "" {
+		config.Backend.HeadElision.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("BACKEND_HEAD_ELISION_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Backend.HeadElision.TTL = d
+		}
+	}
+	if v := os.Getenv("BACKEND_HEAD_ELISION_MAX_ENTRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Backend.HeadElision.MaxEntries = n
+		}
+	}
 	// V0.6-PERF-2 — backend retry config env vars.
 	if v := os.Getenv("BACKEND_RETRY_MODE"); v != "" {
 		config.Backend.Retry.Mode = v
@@ -868,6 +3538,60 @@ func loadFromEnv(config *Config) {
 			config.Encryption.SupportedAlgorithms[i] = strings.TrimSpace(config.Encryption.SupportedAlgorithms[i])
 		}
 	}
+	if v := os.Getenv("ENCRYPTION_METADATA_ENCRYPTION"); v != "" {
+		config.Encryption.MetadataEncryption = v
+	}
+	if v := os.Getenv("ENCRYPTION_TAG_ENCRYPTION"); v != "" {
+		config.Encryption.TagEncryption = v == "true" || v == "1"
+	}
+	if v := os.Getenv("ENCRYPTION_KEY_OBFUSCATION"); v != "" {
+		config.Encryption.KeyObfuscation = v == "true" || v == "1"
+	}
+	if v := os.Getenv("ENCRYPTION_KEY_OBFUSCATION_SCHEME"); v != "" {
+		config.Encryption.KeyObfuscationScheme = v
+	}
+	if v := os.Getenv("ENCRYPTION_METADATA_KEY_PREFIX"); v != "" {
+		config.Encryption.MetadataKeyPrefix = v
+	}
+	if v := os.Getenv("ENCRYPTION_LAZY_UPGRADE_ENABLED"); v != "" {
+		config.Encryption.LazyUpgrade.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("ENCRYPTION_LAZY_UPGRADE_RATE"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			config.Encryption.LazyUpgrade.Rate = f
+		}
+	}
+	if v := os.Getenv("ENCRYPTION_LAZY_UPGRADE_MAX_OBJECT_SIZE"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			config.Encryption.LazyUpgrade.MaxObjectSize = n
+		}
+	}
+	if v := os.Getenv("ENCRYPTION_LAZY_UPGRADE_QUEUE_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Encryption.LazyUpgrade.QueueSize = n
+		}
+	}
+	if v := os.Getenv("ENCRYPTION_IDENTITY_MARKER_ENABLED"); v != "" {
+		config.Encryption.IdentityMarker.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("ENCRYPTION_IDENTITY_MARKER_GATEWAY_ID"); v != "" {
+		config.Encryption.IdentityMarker.GatewayID = v
+	}
+	if v := os.Getenv("ENCRYPTION_IDENTITY_MARKER_REJECT"); v != "" {
+		config.Encryption.IdentityMarker.Reject = v == "true" || v == "1"
+	}
+	if v := os.Getenv("ENCRYPTION_DOUBLE_ENCRYPTION"); v != "" {
+		config.Encryption.DoubleEncryption = v
+	}
+	if v := os.Getenv("ENCRYPTION_HYBRID_WRAP_ENABLED"); v != "" {
+		config.Encryption.HybridWrap.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("ENCRYPTION_HYBRID_WRAP_PUBLIC_KEY_FILE"); v != "" {
+		config.Encryption.HybridWrap.PublicKeyFile = v
+	}
+	if v := os.Getenv("ENCRYPTION_BODY_HEADER"); v != "" {
+		config.Encryption.BodyHeader = v == "true" || v == "1"
+	}
 	if v := os.Getenv("HARDWARE_ENABLE_AESNI"); v != "" {
 		config.Encryption.Hardware.EnableAESNI = v == "true" || v == "1"
 	}
@@ -890,22 +3614,63 @@ func loadFromEnv(config *Config) {
 			config.Encryption.KeyManager.DualReadWindow = n
 		}
 	}
+	if v := os.Getenv("KEY_MANAGER_VERSION_WATCH_ENABLED"); v != "" {
+		config.Encryption.KeyManager.VersionWatch.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("KEY_MANAGER_VERSION_WATCH_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Encryption.KeyManager.VersionWatch.Interval = d
+		}
+	}
 	if v := os.Getenv("KEY_MANAGER_ROTATION_POLICY_ENABLED"); v != "" {
 		config.Encryption.KeyManager.RotationPolicy.Enabled = v == "true" || v == "1"
 	}
-	if v := os.Getenv("KEY_MANAGER_ROTATION_GRACE_WINDOW"); v != "" {
+	if v := os.Getenv("KEY_MANAGER_ROTATION_GRACE_WINDOW"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Encryption.KeyManager.RotationPolicy.GraceWindow = d
+		}
+	}
+	if v := os.Getenv("COSMIAN_KMS_ENDPOINT"); v != "" {
+		config.Encryption.KeyManager.Cosmian.Endpoint = v
+	}
+	if v := os.Getenv("COSMIAN_KMS_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Encryption.KeyManager.Cosmian.Timeout = d
+		}
+	}
+	if v := os.Getenv("KEY_MANAGER_CALL_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Encryption.KeyManager.Calls.Timeout = d
+		}
+	}
+	if v := os.Getenv("KEY_MANAGER_CALL_MAX_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Encryption.KeyManager.Calls.MaxAttempts = n
+		}
+	}
+	if v := os.Getenv("KEY_MANAGER_CALL_INITIAL_BACKOFF"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Encryption.KeyManager.Calls.InitialBackoff = d
+		}
+	}
+	if v := os.Getenv("KEY_MANAGER_CALL_MAX_BACKOFF"); v != "" {
 		if d, err := time.ParseDuration(v); err == nil {
-			config.Encryption.KeyManager.RotationPolicy.GraceWindow = d
+			config.Encryption.KeyManager.Calls.MaxBackoff = d
 		}
 	}
-	if v := os.Getenv("COSMIAN_KMS_ENDPOINT"); v != "" {
-		config.Encryption.KeyManager.Cosmian.Endpoint = v
+	if v := os.Getenv("KEY_MANAGER_CALL_RETRY_BUDGET"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			config.Encryption.KeyManager.Calls.RetryBudget = f
+		}
 	}
-	if v := os.Getenv("COSMIAN_KMS_TIMEOUT"); v != "" {
-		if d, err := time.ParseDuration(v); err == nil {
-			config.Encryption.KeyManager.Cosmian.Timeout = d
+	if v := os.Getenv("KEY_MANAGER_CALL_RETRY_BURST"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Encryption.KeyManager.Calls.RetryBurst = n
 		}
 	}
+	if v := os.Getenv("COSMIAN_KMS_LAZY_CONNECT"); v != "" {
+		config.Encryption.KeyManager.Cosmian.LazyConnect = v == "true" || v == "1"
+	}
 	if v := os.Getenv("COSMIAN_KMS_CLIENT_CERT"); v != "" {
 		config.Encryption.KeyManager.Cosmian.ClientCert = v
 	}
@@ -956,6 +3721,17 @@ func loadFromEnv(config *Config) {
 			config.Server.MaxHeaderBytes = maxBytes
 		}
 	}
+	if v := os.Getenv("SERVER_INTEGRITY_TRAILER"); v != "" {
+		config.Server.IntegrityTrailer = v == "true" || v == "1"
+	}
+	if v := os.Getenv("SERVER_CHECKSUM_TRAILERS"); v != "" {
+		config.Server.ChecksumTrailers = v == "true" || v == "1"
+	}
+	if v := os.Getenv("SERVER_MAX_DECRYPTED_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			config.Server.MaxDecryptedBytes = n
+		}
+	}
 	if v := os.Getenv("SERVER_FORCE_HTTPS"); v != "" {
 		config.Server.ForceHTTPS = v == "true" || v == "1"
 	}
@@ -991,6 +3767,11 @@ func loadFromEnv(config *Config) {
 			config.Cache.DefaultTTL = d
 		}
 	}
+	if v := os.Getenv("CACHE_REVALIDATE_FOR"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Cache.RevalidateFor = d
+		}
+	}
 	// Audit configuration
 	if v := os.Getenv("AUDIT_ENABLED"); v != "" {
 		config.Audit.Enabled = v == "true" || v == "1"
@@ -1088,12 +3869,47 @@ func loadFromEnv(config *Config) {
 	if v := os.Getenv("AUDIT_SINK_TLS_MIN_VERSION"); v != "" {
 		config.Audit.Sink.TLS.MinVersion = v
 	}
+	if v := os.Getenv("AUDIT_SINK_CLOUDWATCH_LOG_GROUP"); v != "" {
+		config.Audit.Sink.CloudWatch.LogGroup = v
+	}
+	if v := os.Getenv("AUDIT_SINK_CLOUDWATCH_LOG_STREAM"); v != "" {
+		config.Audit.Sink.CloudWatch.LogStream = v
+	}
+	if v := os.Getenv("AUDIT_SINK_CLOUDWATCH_REGION"); v != "" {
+		config.Audit.Sink.CloudWatch.Region = v
+	}
+	if v := os.Getenv("AUDIT_SINK_CLOUDWATCH_ENDPOINT"); v != "" {
+		config.Audit.Sink.CloudWatch.Endpoint = v
+	}
+	if v := os.Getenv("AUDIT_SINK_GCP_PROJECT_ID"); v != "" {
+		config.Audit.Sink.GCPLogging.ProjectID = v
+	}
+	if v := os.Getenv("AUDIT_SINK_GCP_LOG_ID"); v != "" {
+		config.Audit.Sink.GCPLogging.LogID = v
+	}
+	if v := os.Getenv("AUDIT_SINK_GCP_ENDPOINT"); v != "" {
+		config.Audit.Sink.GCPLogging.Endpoint = v
+	}
 	if v := os.Getenv("AUDIT_REDACT_METADATA_KEYS"); v != "" {
 		config.Audit.RedactMetadataKeys = strings.Split(v, ",")
 		for i := range config.Audit.RedactMetadataKeys {
 			config.Audit.RedactMetadataKeys[i] = strings.TrimSpace(config.Audit.RedactMetadataKeys[i])
 		}
 	}
+	if v := os.Getenv("AUDIT_SAMPLING_MIN_SEVERITY"); v != "" {
+		config.Audit.Sampling.MinSeverity = v
+	}
+	if v := os.Getenv("AUDIT_SAMPLING_SUCCESS_RATE"); v != "" {
+		if rate, err := strconv.ParseFloat(v, 64); err == nil {
+			config.Audit.Sampling.SuccessRate = &rate
+		}
+	}
+	if v := os.Getenv("AUDIT_SAMPLING_EXCLUDE_EVENT_TYPES"); v != "" {
+		config.Audit.Sampling.ExcludeEventTypes = strings.Split(v, ",")
+		for i := range config.Audit.Sampling.ExcludeEventTypes {
+			config.Audit.Sampling.ExcludeEventTypes[i] = strings.TrimSpace(config.Audit.Sampling.ExcludeEventTypes[i])
+		}
+	}
 	// Proxied bucket configuration
 	if v := os.Getenv("PROXIED_BUCKET"); v != "" {
 		config.ProxiedBucket = v
@@ -1136,6 +3952,21 @@ func loadFromEnv(config *Config) {
 	if v := os.Getenv("LOGGING_ACCESS_LOG_FORMAT"); v != "" {
 		config.Logging.AccessLogFormat = v
 	}
+	if v := os.Getenv("LOGGING_SAMPLING"); v != "" {
+		config.Logging.Sampling = make(map[string]LogSamplingRule)
+		for _, entry := range strings.Split(v, ";") {
+			name, spec, ok := strings.Cut(entry, "=")
+			if name = strings.TrimSpace(name); !ok || name == "" {
+				continue
+			}
+			every, level, _ := strings.Cut(strings.TrimSpace(spec), ":")
+			n, err := strconv.Atoi(every)
+			if err != nil {
+				continue
+			}
+			config.Logging.Sampling[name] = LogSamplingRule{Every: n, Level: level}
+		}
+	}
 	if v := os.Getenv("LOGGING_REDACT_HEADERS"); v != "" {
 		// Comma-separated list of headers to redact
 		config.Logging.RedactHeaders = strings.Split(v, ",")
@@ -1155,6 +3986,14 @@ func loadFromEnv(config *Config) {
 			config.Auth.ClockSkewTolerance = d
 		}
 	}
+	if v := os.Getenv("AUTH_REPLAY_PROTECTION_ENABLED"); v != "" {
+		config.Auth.ReplayProtection.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("AUTH_REPLAY_PROTECTION_CACHE_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Auth.ReplayProtection.CacheSize = n
+		}
+	}
 	// Resolve credential secrets from environment variables (V1.0-AUTH-1)
 	for i := range config.Auth.Credentials {
 		if config.Auth.Credentials[i].SecretKeyEnv != "" {
@@ -1162,6 +4001,11 @@ func loadFromEnv(config *Config) {
 				config.Auth.Credentials[i].SecretKey = v
 			}
 		}
+		if config.Auth.Credentials[i].SessionTokenEnv != "" {
+			if v := os.Getenv(config.Auth.Credentials[i].SessionTokenEnv); v != "" {
+				config.Auth.Credentials[i].SessionToken = v
+			}
+		}
 	}
 	// Read indexed gateway credentials from Helm-injected env vars.
 	// Helm sets GW_CRED_0_ACCESS_KEY, GW_CRED_0_SECRET_KEY, etc.
@@ -1189,19 +4033,12 @@ func loadFromEnv(config *Config) {
 			})
 		}
 	}
-	// Load credentials from external file (AUTH_CREDENTIALS_FILE).
-	if path := os.Getenv("AUTH_CREDENTIALS_FILE"); path != "" {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			slog.Error("failed to read AUTH_CREDENTIALS_FILE", "path", path, "error", err)
-		} else {
-			var fileCreds []GatewayCredential
-			if err := yaml.Unmarshal(data, &fileCreds); err != nil {
-				slog.Error("failed to parse AUTH_CREDENTIALS_FILE", "path", path, "error", err)
-			} else {
-				config.Auth.Credentials = append(config.Auth.Credentials, fileCreds...)
-			}
-		}
+	// The credentials file itself is read by LoadConfig, which can fail.
+	if v := os.Getenv("AUTH_CREDENTIALS_FILE"); v != "" {
+		config.Auth.CredentialsFile = v
+	}
+	if v := os.Getenv("AUTH_POLICY_DEFAULT_DENY"); v != "" {
+		config.Auth.Policy.DefaultDeny = v == "true" || v == "1"
 	}
 	// Admin configuration
 	if v := os.Getenv("ADMIN_ENABLED"); v != "" {
@@ -1228,6 +4065,11 @@ func loadFromEnv(config *Config) {
 	if v := os.Getenv("ADMIN_AUTH_TOKEN"); v != "" {
 		config.Admin.Auth.Token = v
 	}
+	for _, role := range []string{"observer", "key_operator", "config_admin"} {
+		if v := os.Getenv("ADMIN_AUTH_" + strings.ToUpper(role) + "_TOKEN_FILE"); v != "" {
+			config.Admin.Auth.Tokens = append(config.Admin.Auth.Tokens, AdminRoleToken{Role: role, TokenFile: v})
+		}
+	}
 	if v := os.Getenv("ADMIN_RATE_LIMIT_RPM"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
 			config.Admin.RateLimit.RequestsPerMinute = n
@@ -1257,6 +4099,30 @@ func loadFromEnv(config *Config) {
 			config.Admin.Profiling.MaxProfileSeconds = n
 		}
 	}
+	if v := os.Getenv("ADMIN_ROTATION_PEERS"); v != "" {
+		config.Admin.Rotation.Peers = nil
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				config.Admin.Rotation.Peers = append(config.Admin.Rotation.Peers, p)
+			}
+		}
+	}
+	if v := os.Getenv("ADMIN_ROTATION_PEER_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Admin.Rotation.PeerTimeout = d
+		}
+	}
+	if v := os.Getenv("ADMIN_ROTATION_PEER_CA_FILE"); v != "" {
+		config.Admin.Rotation.PeerCAFile = v
+	}
+	if v := os.Getenv("ADMIN_ROTATION_REQUIRE_DRY_RUN"); v != "" {
+		config.Admin.Rotation.RequireDryRun = v == "true" || v == "1"
+	}
+	if v := os.Getenv("ADMIN_ROTATION_DRY_RUN_MAX_AGE"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Admin.Rotation.DryRunMaxAge = d
+		}
+	}
 
 	// Multipart-state / Valkey env bindings. Needed so the Helm chart can wire
 	// the Valkey subchart's service name into the gateway without requiring a
@@ -1264,43 +4130,475 @@ func loadFromEnv(config *Config) {
 	if v := os.Getenv("VALKEY_ADDR"); v != "" {
 		config.MultipartState.Valkey.Addr = v
 	}
-	if v := os.Getenv("VALKEY_USERNAME"); v != "" {
-		config.MultipartState.Valkey.Username = v
+	if v := os.Getenv("VALKEY_USERNAME"); v != "" {
+		config.MultipartState.Valkey.Username = v
+	}
+	if v := os.Getenv("VALKEY_PASSWORD_ENV"); v != "" {
+		config.MultipartState.Valkey.PasswordEnv = v
+	}
+	if v := os.Getenv("VALKEY_DB"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			config.MultipartState.Valkey.DB = n
+		}
+	}
+	if v := os.Getenv("VALKEY_TLS_ENABLED"); v != "" {
+		config.MultipartState.Valkey.TLS.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("VALKEY_TLS_CA_FILE"); v != "" {
+		config.MultipartState.Valkey.TLS.CAFile = v
+	}
+	if v := os.Getenv("VALKEY_TLS_CERT_FILE"); v != "" {
+		config.MultipartState.Valkey.TLS.CertFile = v
+	}
+	if v := os.Getenv("VALKEY_TLS_KEY_FILE"); v != "" {
+		config.MultipartState.Valkey.TLS.KeyFile = v
+	}
+	if v := os.Getenv("VALKEY_TLS_INSECURE_SKIP_VERIFY"); v != "" {
+		config.MultipartState.Valkey.TLS.InsecureSkipVerify = v == "true" || v == "1"
+	}
+	if v := os.Getenv("VALKEY_INSECURE_ALLOW_PLAINTEXT"); v != "" {
+		config.MultipartState.Valkey.InsecureAllowPlaintext = v == "true" || v == "1"
+	}
+	if v := os.Getenv("VALKEY_TTL_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			config.MultipartState.Valkey.TTLSeconds = n
+		}
+	}
+	if v := os.Getenv("VALKEY_POOL_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			config.MultipartState.Valkey.PoolSize = n
+		}
+	}
+
+	// Write-ahead journal configuration
+	if v := os.Getenv("JOURNAL_ENABLED"); v != "" {
+		config.Journal.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("JOURNAL_PATH"); v != "" {
+		config.Journal.Path = v
+	}
+	if v := os.Getenv("JOURNAL_SYNC"); v != "" {
+		config.Journal.Sync = v == "true" || v == "1"
+	}
+	if v := os.Getenv("JOURNAL_CLEANUP_INCONSISTENT"); v != "" {
+		config.Journal.CleanupInconsistent = v == "true" || v == "1"
+	}
+
+	// ListObjects size enrichment
+	if v := os.Getenv("LIST_ENRICHMENT_ENABLED"); v != "" {
+		config.ListEnrichment.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("LIST_ENRICHMENT_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.ListEnrichment.Concurrency = n
+		}
+	}
+	if v := os.Getenv("LIST_ENRICHMENT_CACHE_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.ListEnrichment.CacheSize = n
+		}
+	}
+	if v := os.Getenv("LIST_ENRICHMENT_CACHE_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.ListEnrichment.CacheTTL = d
+		}
+	}
+
+	// Sidecar size index
+	if v := os.Getenv("SIZE_INDEX_ENABLED"); v != "" {
+		config.SizeIndex.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("SIZE_INDEX_PREFIX"); v != "" {
+		config.SizeIndex.Prefix = v
+	}
+	if v := os.Getenv("SIZE_INDEX_FLUSH_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.SizeIndex.FlushInterval = d
+		}
+	}
+
+	// Per-object access counters
+	if v := os.Getenv("ACCESS_STATS_ENABLED"); v != "" {
+		config.AccessStats.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("ACCESS_STATS_PREFIX"); v != "" {
+		config.AccessStats.Prefix = v
+	}
+	if v := os.Getenv("ACCESS_STATS_FLUSH_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.AccessStats.FlushInterval = d
+		}
+	}
+
+	// Bucket sharding
+	if v := os.Getenv("SHARDING_ENABLED"); v != "" {
+		config.Sharding.Enabled = v == "true" || v == "1"
+	}
+
+	// Quotas
+	if v := os.Getenv("QUOTA_ENABLED"); v != "" {
+		config.Quota.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("QUOTA_SCAN_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Quota.ScanInterval = d
+		}
+	}
+
+	// Billing export
+	if v := os.Getenv("BILLING_ENABLED"); v != "" {
+		config.Billing.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("BILLING_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Billing.Interval = d
+		}
+	}
+	if v := os.Getenv("BILLING_BUCKET"); v != "" {
+		config.Billing.Bucket = v
+	}
+	if v := os.Getenv("BILLING_PREFIX"); v != "" {
+		config.Billing.Prefix = v
+	}
+	if v := os.Getenv("BILLING_FORMAT"); v != "" {
+		config.Billing.Format = strings.ToLower(v)
+	}
+
+	// Developer mode
+	if v := os.Getenv("DEV_MODE_ENABLED"); v != "" {
+		config.DevMode.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("DEV_MODE_ALLOW_IN_RELEASE"); v != "" {
+		config.DevMode.AllowInRelease = v == "true" || v == "1"
+	}
+
+	// Tiering worker
+	if v := os.Getenv("TIERING_ENABLED"); v != "" {
+		config.Tiering.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("TIERING_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Tiering.Interval = d
+		}
+	}
+
+	// Deferred deletes
+	if v := os.Getenv("TRASH_ENABLED"); v != "" {
+		config.Trash.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("TRASH_BUCKETS"); v != "" {
+		config.Trash.Buckets = nil
+		for _, b := range strings.Split(v, ",") {
+			if b = strings.TrimSpace(b); b != "" {
+				config.Trash.Buckets = append(config.Trash.Buckets, b)
+			}
+		}
+	}
+	if v := os.Getenv("TRASH_PREFIX"); v != "" {
+		config.Trash.Prefix = v
+	}
+	if v := os.Getenv("TRASH_RETENTION"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Trash.Retention = d
+		}
+	}
+	if v := os.Getenv("TRASH_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Trash.Interval = d
+		}
+	}
+
+	// Key holds
+	if v := os.Getenv("KEY_HOLD_ENABLED"); v != "" {
+		config.KeyHold.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("KEY_HOLD_BUCKET"); v != "" {
+		config.KeyHold.Bucket = v
+	}
+	if v := os.Getenv("KEY_HOLD_KEY"); v != "" {
+		config.KeyHold.Key = v
+	}
+	// FEATURE_FLAGS enables the listed flags for every bucket and object.
+	if v := os.Getenv("FEATURE_FLAGS"); v != "" {
+		if config.FeatureFlags == nil {
+			config.FeatureFlags = make(map[string]FeatureFlag)
+		}
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				config.FeatureFlags[name] = FeatureFlag{Enabled: true}
+			}
+		}
+	}
+	if v := os.Getenv("BATCH_ENABLED"); v != "" {
+		config.Batch.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("BATCH_WORKERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Batch.Workers = n
+		}
+	}
+	if v := os.Getenv("BATCH_MAX_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Batch.MaxAttempts = n
+		}
+	}
+	if v := os.Getenv("BATCH_MAX_KEYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Batch.MaxKeys = n
+		}
+	}
+	if v := os.Getenv("BATCH_REPORT_BUCKET"); v != "" {
+		config.Batch.ReportBucket = v
+	}
+	if v := os.Getenv("BATCH_REPORT_PREFIX"); v != "" {
+		config.Batch.ReportPrefix = v
+	}
+	if v := os.Getenv("BATCH_RETAIN_JOBS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Batch.RetainJobs = n
+		}
+	}
+	if v := os.Getenv("SCHEDULER_ENABLED"); v != "" {
+		config.Scheduler.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("SCHEDULER_JOBS"); v != "" {
+		config.Scheduler.Jobs = make(map[string]string)
+		for _, entry := range strings.Split(v, ";") {
+			name, expr, ok := strings.Cut(entry, "=")
+			if name = strings.TrimSpace(name); ok && name != "" {
+				config.Scheduler.Jobs[name] = strings.TrimSpace(expr)
+			}
+		}
+	}
+	if v := os.Getenv("MEMORY_BUDGET_ENABLED"); v != "" {
+		config.MemoryBudget.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("MEMORY_BUDGET_LIMIT"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			config.MemoryBudget.Limit = n
+		}
+	}
+	if v := os.Getenv("MEMORY_BUDGET_MAX_WAIT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.MemoryBudget.MaxWait = d
+		}
+	}
+	if v := os.Getenv("MEMORY_BUDGET_REQUEST_RESERVE"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			config.MemoryBudget.RequestReserve = n
+		}
+	}
+	if v := os.Getenv("ADMISSION_ENABLED"); v != "" {
+		config.Admission.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("ADMISSION_MAX_IN_FLIGHT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Admission.MaxInFlight = n
+		}
+	}
+	if v := os.Getenv("ADMISSION_QUEUE_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Admission.QueueSize = n
+		}
+	}
+	if v := os.Getenv("ADMISSION_MAX_WAIT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Admission.MaxWait = d
+		}
+	}
+	if v := os.Getenv("ADMISSION_RETRY_AFTER"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Admission.RetryAfter = d
+		}
+	}
+	if v := os.Getenv("ADMISSION_LARGE_REQUEST_SIZE"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			config.Admission.LargeRequestSize = n
+		}
+	}
+	if v := os.Getenv("ADMISSION_PRIORITY_BUCKETS"); v != "" {
+		config.Admission.PriorityBuckets = strings.Split(v, ",")
+	}
+	if v := os.Getenv("ADMISSION_PRIORITY_ACCESS_KEYS"); v != "" {
+		config.Admission.PriorityAccessKeys = strings.Split(v, ",")
+	}
+	if v := os.Getenv("HOOKS_ENABLED"); v != "" {
+		config.Hooks.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("HOOKS_WORKERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Hooks.Workers = n
+		}
+	}
+	if v := os.Getenv("HOOKS_QUEUE_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Hooks.QueueSize = n
+		}
+	}
+	if v := os.Getenv("HOOKS_MAX_OBJECT_SIZE"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			config.Hooks.MaxObjectSize = n
+		}
+	}
+	if v := os.Getenv("HOOKS_MAX_OUTPUT_SIZE"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			config.Hooks.MaxOutputSize = n
+		}
+	}
+	if v := os.Getenv("SCANNING_ENABLED"); v != "" {
+		config.Scanning.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("SCANNING_PROTOCOL"); v != "" {
+		config.Scanning.Protocol = v
+	}
+	if v := os.Getenv("SCANNING_ADDRESS"); v != "" {
+		config.Scanning.Address = v
+	}
+	if v := os.Getenv("SCANNING_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Scanning.Timeout = d
+		}
+	}
+	if v := os.Getenv("SCANNING_MAX_OBJECT_SIZE"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			config.Scanning.MaxObjectSize = n
+		}
+	}
+	if v := os.Getenv("SCANNING_ACTION"); v != "" {
+		config.Scanning.Action = v
+	}
+	if v := os.Getenv("SCANNING_FAIL_OPEN"); v != "" {
+		config.Scanning.FailOpen = v == "true" || v == "1"
+	}
+	if v := os.Getenv("SCANNING_QUARANTINE_TAG"); v != "" {
+		config.Scanning.QuarantineTag = v
+	}
+	if v := os.Getenv("INSPECTION_ENABLED"); v != "" {
+		config.Inspection.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("INSPECTION_MAX_SIZE"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			config.Inspection.MaxSize = n
+		}
+	}
+	if v := os.Getenv("INSPECTION_CONTENT_TYPES"); v != "" {
+		config.Inspection.ContentTypes = strings.Split(v, ",")
+	}
+	if v := os.Getenv("INSPECTION_TAG_KEY"); v != "" {
+		config.Inspection.TagKey = v
+	}
+	if v := os.Getenv("TRANSFORMS_ENABLED"); v != "" {
+		config.Transforms.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("TRANSFORMS_MAX_OUTPUT_SIZE"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			config.Transforms.MaxOutputSize = n
+		}
+	}
+	if v := os.Getenv("EXTENSIONS_ENABLED"); v != "" {
+		config.Extensions.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("SELF_TEST_ENABLED"); v != "" {
+		config.SelfTest.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("SELF_TEST_BUCKET"); v != "" {
+		config.SelfTest.Bucket = v
+	}
+	if v := os.Getenv("SELF_TEST_KEY_PREFIX"); v != "" {
+		config.SelfTest.KeyPrefix = v
+	}
+	if v := os.Getenv("SELF_TEST_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.SelfTest.Timeout = d
+		}
+	}
+	if v := os.Getenv("SELF_TEST_RETRY_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.SelfTest.RetryInterval = d
+		}
+	}
+	if v := os.Getenv("CANARY_ENABLED"); v != "" {
+		config.Canary.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("CANARY_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Canary.Interval = d
+		}
+	}
+	if v := os.Getenv("CANARY_BUCKET"); v != "" {
+		config.Canary.Bucket = v
 	}
-	if v := os.Getenv("VALKEY_PASSWORD_ENV"); v != "" {
-		config.MultipartState.Valkey.PasswordEnv = v
+	if v := os.Getenv("CANARY_KEY_PREFIX"); v != "" {
+		config.Canary.KeyPrefix = v
 	}
-	if v := os.Getenv("VALKEY_DB"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
-			config.MultipartState.Valkey.DB = n
+	if v := os.Getenv("CANARY_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Canary.Timeout = d
 		}
 	}
-	if v := os.Getenv("VALKEY_TLS_ENABLED"); v != "" {
-		config.MultipartState.Valkey.TLS.Enabled = v == "true" || v == "1"
-	}
-	if v := os.Getenv("VALKEY_TLS_CA_FILE"); v != "" {
-		config.MultipartState.Valkey.TLS.CAFile = v
+
+	// Readiness check tuning
+	for _, c := range []struct {
+		prefix string
+		check  *ReadinessCheckConfig
+	}{
+		{"READINESS_KMS_", &config.Readiness.KMS},
+		{"READINESS_BACKEND_", &config.Readiness.Backend},
+	} {
+		if v := os.Getenv(c.prefix + "TIMEOUT"); v != "" {
+			if d, err := time.ParseDuration(v); err == nil {
+				c.check.Timeout = d
+			}
+		}
+		if v := os.Getenv(c.prefix + "CACHE_TTL"); v != "" {
+			if d, err := time.ParseDuration(v); err == nil {
+				c.check.CacheTTL = d
+			}
+		}
 	}
-	if v := os.Getenv("VALKEY_TLS_CERT_FILE"); v != "" {
-		config.MultipartState.Valkey.TLS.CertFile = v
+	if v := os.Getenv("READINESS_BACKEND_BUCKET"); v != "" {
+		config.Readiness.BackendBucket = v
 	}
-	if v := os.Getenv("VALKEY_TLS_KEY_FILE"); v != "" {
-		config.MultipartState.Valkey.TLS.KeyFile = v
+
+	// Warm connection pool
+	if v := os.Getenv("WARM_POOL_ENABLED"); v != "" {
+		config.WarmPool.Enabled = v == "true" || v == "1"
 	}
-	if v := os.Getenv("VALKEY_TLS_INSECURE_SKIP_VERIFY"); v != "" {
-		config.MultipartState.Valkey.TLS.InsecureSkipVerify = v == "true" || v == "1"
+	if v := os.Getenv("WARM_POOL_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.WarmPool.Interval = d
+		}
 	}
-	if v := os.Getenv("VALKEY_INSECURE_ALLOW_PLAINTEXT"); v != "" {
-		config.MultipartState.Valkey.InsecureAllowPlaintext = v == "true" || v == "1"
+	if v := os.Getenv("WARM_POOL_CONNECTIONS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.WarmPool.Connections = n
+		}
 	}
-	if v := os.Getenv("VALKEY_TTL_SECONDS"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			config.MultipartState.Valkey.TTLSeconds = n
+	if v := os.Getenv("WARM_POOL_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.WarmPool.Timeout = d
 		}
 	}
-	if v := os.Getenv("VALKEY_POOL_SIZE"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			config.MultipartState.Valkey.PoolSize = n
+	if v := os.Getenv("AIR_GAPPED_ENABLED"); v != "" {
+		config.AirGapped.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("AIR_GAPPED_ALLOW"); v != "" {
+		config.AirGapped.Allow = strings.Split(v, ",")
+	}
+
+	// SLO / error-budget configuration
+	if v := os.Getenv("SLO_ENABLED"); v != "" {
+		config.SLO.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("SLO_WINDOWS"); v != "" {
+		var windows []time.Duration
+		for _, part := range strings.Split(v, ",") {
+			if d, err := time.ParseDuration(strings.TrimSpace(part)); err == nil {
+				windows = append(windows, d)
+			}
+		}
+		if len(windows) > 0 {
+			config.SLO.Windows = windows
 		}
 	}
 }
@@ -1330,6 +4628,20 @@ func parseCosmianKeyRefs(value string) []CosmianKeyReference {
 	return refs
 }
 
+var (
+	customAlgorithmsMu sync.RWMutex
+	customAlgorithms   = map[string]bool{}
+)
+
+// RegisterAlgorithm adds name to the encryption algorithms Validate accepts
+// besides the built-in ones. crypto.RegisterCipher calls it for each custom
+// cipher.
+func RegisterAlgorithm(name string) {
+	customAlgorithmsMu.Lock()
+	defer customAlgorithmsMu.Unlock()
+	customAlgorithms[name] = true
+}
+
 // Validate validates the configuration and returns an error if invalid.
 func (c *Config) Validate() error {
 	if c.ListenAddr == "" {
@@ -1352,21 +4664,41 @@ func (c *Config) Validate() error {
 	if len(c.Auth.Credentials) == 0 {
 		return fmt.Errorf("auth.credentials must not be empty; at least one credential is required")
 	}
+	seenAccessKeys := make(map[string]int, len(c.Auth.Credentials))
 	for i, cred := range c.Auth.Credentials {
 		if cred.AccessKey == "" {
 			return fmt.Errorf("auth.credentials[%d]: access_key is required", i)
 		}
+		if j, dup := seenAccessKeys[cred.AccessKey]; dup {
+			return fmt.Errorf("auth.credentials[%d]: access_key duplicates auth.credentials[%d]", i, j)
+		}
+		seenAccessKeys[cred.AccessKey] = i
 		if cred.SecretKey == "" && cred.SecretKeyEnv == "" {
 			return fmt.Errorf("auth.credentials[%d]: either secret_key or secret_key_env is required", i)
 		}
+		if cred.SessionTokenEnv != "" && cred.SessionToken == "" && os.Getenv(cred.SessionTokenEnv) == "" {
+			// An unset variable would silently turn a temporary credential
+			// into a long-term one.
+			return fmt.Errorf("auth.credentials[%d]: session_token_env %q is not set", i, cred.SessionTokenEnv)
+		}
+		switch cred.Raw {
+		case "", RawAccessOnRequest, RawAccessAlways:
+		default:
+			return fmt.Errorf("auth.credentials[%d]: raw must be %q or %q (got %q)", i, RawAccessOnRequest, RawAccessAlways, cred.Raw)
+		}
 	}
-
-	// Backend credentials are always required
-	if c.Backend.AccessKey == "" {
-		return fmt.Errorf("backend.access_key is required")
+	if c.Auth.ClockSkewTolerance < 0 {
+		return fmt.Errorf("auth.clock_skew_tolerance must not be negative")
 	}
-	if c.Backend.SecretKey == "" {
-		return fmt.Errorf("backend.secret_key is required")
+	if c.Auth.ReplayProtection.CacheSize < 0 {
+		return fmt.Errorf("auth.replay_protection.cache_size must not be negative")
+	}
+	if err := c.Auth.Policy.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Backend.validateStore("backend"); err != nil {
+		return err
 	}
 
 	if c.Encryption.Password == "" && c.Encryption.KeyFile == "" {
@@ -1400,6 +4732,11 @@ func (c *Config) Validate() error {
 		"AES256-GCM":        true,
 		"ChaCha20-Poly1305": true,
 	}
+	customAlgorithmsMu.RLock()
+	for alg := range customAlgorithms {
+		allowed[alg] = true
+	}
+	customAlgorithmsMu.RUnlock()
 	if alg := strings.TrimSpace(c.Encryption.PreferredAlgorithm); alg != "" {
 		if !allowed[alg] {
 			return fmt.Errorf("invalid encryption.preferred_algorithm: %s", alg)
@@ -1413,6 +4750,45 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	switch c.Encryption.MetadataEncryption {
+	case "", MetadataEncryptionOff, MetadataEncryptionValues, MetadataEncryptionAll:
+	default:
+		return fmt.Errorf("invalid encryption.metadata_encryption: %q (must be off, values, or all)", c.Encryption.MetadataEncryption)
+	}
+	switch c.Encryption.DoubleEncryption {
+	case "", DoubleEncryptionReject, DoubleEncryptionPassthrough, DoubleEncryptionEncrypt:
+	default:
+		return fmt.Errorf("invalid encryption.double_encryption: %q (must be reject, passthrough, or encrypt)", c.Encryption.DoubleEncryption)
+	}
+	switch c.Encryption.KeyObfuscationScheme {
+	case "", KeyObfuscationPath, KeyObfuscationSegment, KeyObfuscationFull:
+	default:
+		return fmt.Errorf("invalid encryption.key_obfuscation_scheme: %q (must be path, segment, or full)", c.Encryption.KeyObfuscationScheme)
+	}
+	if p := c.Encryption.MetadataKeyPrefix; p != "" && (len(p) > 32 || !metadataKeyPrefixPattern.MatchString(p)) {
+		return fmt.Errorf("invalid encryption.metadata_key_prefix: %q (lower-case letters, digits and \"-\", ending in \"-\", at most 32 characters)", p)
+	}
+	if c.Encryption.KeyObfuscation && c.SizeIndex.Enabled {
+		// Index shards record plaintext key names.
+		return fmt.Errorf("encryption.key_obfuscation cannot be combined with size_index.enabled")
+	}
+	if c.Encryption.KeyObfuscation && c.Tiering.Enabled {
+		// Tiering rules and stubs address plaintext key names.
+		return fmt.Errorf("encryption.key_obfuscation cannot be combined with tiering.enabled")
+	}
+	if c.Encryption.KeyObfuscation && c.AccessStats.Enabled {
+		// Stats shards record plaintext key names.
+		return fmt.Errorf("encryption.key_obfuscation cannot be combined with access_stats.enabled")
+	}
+	if c.Encryption.KeyObfuscation && c.Trash.Enabled {
+		// Trash keys embed the original key name and are listed by prefix.
+		return fmt.Errorf("encryption.key_obfuscation cannot be combined with trash.enabled")
+	}
+	if c.Encryption.KeyObfuscation && c.KeyHold.Enabled {
+		// The hold list records plaintext key names.
+		return fmt.Errorf("encryption.key_obfuscation cannot be combined with key_hold.enabled")
+	}
+
 	if c.Encryption.KDF.PBKDF2.Iterations < 100000 {
 		return fmt.Errorf("encryption.kdf.pbkdf2.iterations must be >= 100000 (got %d)", c.Encryption.KDF.PBKDF2.Iterations)
 	}
@@ -1440,6 +4816,16 @@ func (c *Config) Validate() error {
 		default:
 			return fmt.Errorf("unsupported key manager provider: %s (supported: cosmian, kmip, memory, hsm)", c.Encryption.KeyManager.Provider)
 		}
+		calls := c.Encryption.KeyManager.Calls
+		calls.Normalize()
+		if err := calls.Validate(); err != nil {
+			return err
+		}
+		if c.Encryption.KeyManager.VersionWatch.Enabled {
+			if err := c.Encryption.KeyManager.VersionWatch.Validate(); err != nil {
+				return err
+			}
+		}
 	}
 
 	// Validate tracing configuration
@@ -1478,6 +4864,11 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("invalid logging.access_log_format: %s (must be default, json, or clf)", c.Logging.AccessLogFormat)
 		}
 	}
+	for name, rule := range c.Logging.Sampling {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("logging.sampling.%s: %w", name, err)
+		}
+	}
 
 	// Validate audit configuration
 	if c.Audit.Enabled {
@@ -1492,8 +4883,19 @@ func (c *Config) Validate() error {
 			if c.Audit.Sink.Endpoint == "" {
 				return fmt.Errorf("audit.sink.endpoint is required when sink type is http")
 			}
+		case "cloudwatch":
+			if c.Audit.Sink.CloudWatch.LogGroup == "" {
+				return fmt.Errorf("audit.sink.cloudwatch.log_group is required when sink type is cloudwatch")
+			}
+		case "gcp_logging":
+			if id := c.Audit.Sink.GCPLogging.LogID; id != "" && !gcpLogID.MatchString(id) {
+				return fmt.Errorf("audit.sink.gcp_logging.log_id %q may only contain letters, digits, '/', '_', '-' and '.'", id)
+			}
 		default:
-			return fmt.Errorf("invalid audit.sink.type: %s (must be stdout, file, or http)", c.Audit.Sink.Type)
+			return fmt.Errorf("invalid audit.sink.type: %s (must be stdout, file, http, cloudwatch, or gcp_logging)", c.Audit.Sink.Type)
+		}
+		if err := c.Audit.Sampling.Validate(); err != nil {
+			return err
 		}
 	}
 
@@ -1514,6 +4916,62 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	switch c.Backend.ConditionalWrites {
+	case "", ConditionalWritesOff, ConditionalWritesClient, ConditionalWritesOptimistic:
+	default:
+		return fmt.Errorf("invalid backend.conditional_writes: %q (must be off, client, or optimistic)", c.Backend.ConditionalWrites)
+	}
+	switch c.Backend.MetadataOverflow {
+	case "", MetadataOverflowFallback, MetadataOverflowReject:
+	default:
+		return fmt.Errorf("invalid backend.metadata_overflow: %q (must be fallback or reject)", c.Backend.MetadataOverflow)
+	}
+	if err := c.Backend.ReadReplicas.Validate(); err != nil {
+		return err
+	}
+	if c.Backend.RangeHedging.Enabled {
+		if err := c.Backend.RangeHedging.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Backend.ParallelRange.PartSize < 0 || c.Backend.ParallelRange.Concurrency < 0 {
+		return fmt.Errorf("backend.parallel_range.part_size and concurrency must not be negative")
+	}
+	if c.Backend.StreamingUpload.Enabled {
+		if err := c.Backend.StreamingUpload.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Backend.HeadElision.Enabled {
+		if err := c.Backend.HeadElision.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Backend.Proxy != "" {
+		u, err := url.Parse(c.Backend.Proxy)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid backend.proxy: %q (must be a URL such as http://proxy:3128)", c.Backend.Proxy)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("invalid backend.proxy scheme %q (must be http, https or socks5)", u.Scheme)
+		}
+	}
+
+	if err := validateHeaderRules("backend.header_rules", c.Backend.HeaderRules, ProtectedBackendHeaders); err != nil {
+		return err
+	}
+	if c.Compression.MaxRatio < 0 {
+		return fmt.Errorf("compression.max_ratio must not be negative")
+	}
+	if c.Server.MaxDecryptedBytes < 0 {
+		return fmt.Errorf("server.max_decrypted_bytes must not be negative")
+	}
+	if err := validateHeaderRules("server.response_header_rules", c.Server.ResponseHeaderRules, ProtectedResponseHeaders); err != nil {
+		return err
+	}
+
 	// Validate admin configuration
 	if c.Admin.Enabled {
 		if c.Admin.Address == "" {
@@ -1545,11 +5003,12 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("admin.auth.type must be \"bearer\" (got %q)", c.Admin.Auth.Type)
 		}
 
-		// Exactly one of token_file / token must be set
+		// At most one of token_file / token may be set, and one of them
+		// is required unless role tokens are configured.
 		hasTokenFile := c.Admin.Auth.TokenFile != ""
 		hasInlineToken := c.Admin.Auth.Token != ""
-		if !hasTokenFile && !hasInlineToken {
-			return fmt.Errorf("one of admin.auth.token_file or admin.auth.token is required when admin is enabled")
+		if !hasTokenFile && !hasInlineToken && len(c.Admin.Auth.Tokens) == 0 {
+			return fmt.Errorf("one of admin.auth.token_file, admin.auth.token or admin.auth.tokens is required when admin is enabled")
 		}
 		if hasTokenFile && hasInlineToken {
 			return fmt.Errorf("only one of admin.auth.token_file or admin.auth.token may be set, not both")
@@ -1560,39 +5019,50 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("admin.auth.token (inline) requires ADMIN_ALLOW_INLINE_TOKEN=1 environment variable")
 		}
 
-		// Validate token minimum length (32 bytes decoded)
+		// Validate token minimum length (32 bytes decoded). Every token must
+		// be distinct: a token shared between entries would grant the
+		// union of their roles.
+		seen := make(map[string]string)
 		if hasTokenFile {
-			// Check file permissions — use Lstat to avoid following symlinks (TOCTOU).
-			info, err := os.Lstat(c.Admin.Auth.TokenFile)
+			token, err := readAdminTokenFile(c.Admin.Auth.TokenFile)
 			if err != nil {
 				return fmt.Errorf("admin.auth.token_file: %w", err)
 			}
-			if info.Mode()&os.ModeSymlink != 0 {
-				return fmt.Errorf("admin.auth.token_file must not be a symbolic link")
-			}
-			mode := info.Mode().Perm()
-			if mode&0077 != 0 {
-				return fmt.Errorf("admin.auth.token_file %s is too permissive (mode %04o); must be 0600 or stricter", c.Admin.Auth.TokenFile, mode)
-			}
-			// Read and validate token length
-			tokenBytes, err := os.ReadFile(c.Admin.Auth.TokenFile)
-			if err != nil {
-				return fmt.Errorf("admin.auth.token_file: failed to read: %w", err)
-			}
-			if err := validateAdminTokenLength(strings.TrimSpace(string(tokenBytes))); err != nil {
-				return fmt.Errorf("admin.auth.token_file: %w", err)
-			}
+			seen[token] = "admin.auth.token_file"
 		}
 		if hasInlineToken {
 			if err := validateAdminTokenLength(c.Admin.Auth.Token); err != nil {
 				return fmt.Errorf("admin.auth.token: %w", err)
 			}
+			seen[c.Admin.Auth.Token] = "admin.auth.token"
+		}
+		for i, rt := range c.Admin.Auth.Tokens {
+			name := fmt.Sprintf("admin.auth.tokens[%d]", i)
+			switch rt.Role {
+			case "observer", "key_operator", "config_admin":
+			default:
+				return fmt.Errorf("%s.role must be observer, key_operator or config_admin (got %q)", name, rt.Role)
+			}
+			if rt.TokenFile == "" {
+				return fmt.Errorf("%s.token_file is required", name)
+			}
+			token, err := readAdminTokenFile(rt.TokenFile)
+			if err != nil {
+				return fmt.Errorf("%s.token_file: %w", name, err)
+			}
+			if prev, dup := seen[token]; dup {
+				return fmt.Errorf("%s.token_file holds the same token as %s", name, prev)
+			}
+			seen[token] = name
 		}
 
 		// Validate rate limit
 		if c.Admin.RateLimit.RequestsPerMinute <= 0 {
 			return fmt.Errorf("admin.rate_limit.requests_per_minute must be positive")
 		}
+		if err := c.Admin.Rotation.Validate(); err != nil {
+			return err
+		}
 	}
 
 	// V0.6-OBS-1 — profiling validation.
@@ -1614,6 +5084,249 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.Journal.Enabled && c.Journal.Path == "" {
+		return fmt.Errorf("journal.path is required when journal is enabled")
+	}
+
+	if c.ListEnrichment.Enabled {
+		if c.ListEnrichment.Concurrency < 1 || c.ListEnrichment.Concurrency > 256 {
+			return fmt.Errorf("list_enrichment.concurrency must be between 1 and 256")
+		}
+		if c.ListEnrichment.CacheSize < 0 {
+			return fmt.Errorf("list_enrichment.cache_size must be >= 0")
+		}
+		if c.ListEnrichment.CacheSize > 0 && c.ListEnrichment.CacheTTL <= 0 {
+			return fmt.Errorf("list_enrichment.cache_ttl must be positive when the cache is enabled")
+		}
+	}
+
+	if c.SizeIndex.Enabled {
+		p := c.SizeIndex.Prefix
+		if p == "" || strings.HasPrefix(p, "/") || !strings.HasSuffix(p, "/") {
+			return fmt.Errorf("size_index.prefix must be non-empty, relative and end with \"/\" (got %q)", p)
+		}
+		if c.SizeIndex.FlushInterval < 100*time.Millisecond {
+			return fmt.Errorf("size_index.flush_interval must be at least 100ms")
+		}
+	}
+
+	if c.AccessStats.Enabled {
+		p := c.AccessStats.Prefix
+		if p == "" || strings.HasPrefix(p, "/") || !strings.HasSuffix(p, "/") {
+			return fmt.Errorf("access_stats.prefix must be non-empty, relative and end with \"/\" (got %q)", p)
+		}
+		if c.SizeIndex.Enabled && (strings.HasPrefix(p, c.SizeIndex.Prefix) || strings.HasPrefix(c.SizeIndex.Prefix, p)) {
+			return fmt.Errorf("access_stats.prefix and size_index.prefix must not overlap")
+		}
+		if c.AccessStats.FlushInterval < 100*time.Millisecond {
+			return fmt.Errorf("access_stats.flush_interval must be at least 100ms")
+		}
+	}
+
+	if c.Sharding.Enabled {
+		if err := c.Sharding.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Quota.Enabled {
+		if err := c.Quota.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Billing.Enabled {
+		if err := c.Billing.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Tiering.Enabled {
+		c.Tiering.Backend.Retry.Normalize()
+		if err := c.Tiering.Validate(); err != nil {
+			return err
+		}
+		for i, r := range c.Tiering.Rules {
+			if r.ColdAfter > 0 && !c.AccessStats.Enabled {
+				return fmt.Errorf("tiering.rules[%d].cold_after requires access_stats.enabled", i)
+			}
+		}
+	}
+
+	if c.Trash.Enabled {
+		if err := c.Trash.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.KeyHold.Enabled {
+		if c.KeyHold.Bucket == "" {
+			return fmt.Errorf("key_hold.bucket is required when key holds are enabled")
+		}
+		if k := c.KeyHold.Key; k == "" || strings.HasPrefix(k, "/") || strings.HasSuffix(k, "/") {
+			return fmt.Errorf("key_hold.key must be a non-empty, relative object name (got %q)", k)
+		}
+	}
+
+	for name, f := range c.FeatureFlags {
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("feature_flags.%s: %w", name, err)
+		}
+	}
+
+	if c.Encryption.LazyUpgrade.Enabled {
+		if err := c.Encryption.LazyUpgrade.Validate(); err != nil {
+			return err
+		}
+		// Chunked mode is on unless chunk_size is set with chunked_mode off.
+		if !c.Encryption.ChunkedMode && c.Encryption.ChunkSize != 0 {
+			return fmt.Errorf("encryption.lazy_upgrade requires encryption.chunked_mode")
+		}
+	}
+
+	if c.Encryption.IdentityMarker.Enabled {
+		if err := c.Encryption.IdentityMarker.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Encryption.HybridWrap.Enabled {
+		if !c.Encryption.KeyManager.Enabled {
+			return fmt.Errorf("encryption.hybrid_wrap requires encryption.key_manager")
+		}
+		if err := c.Encryption.HybridWrap.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Batch.Enabled {
+		if err := c.Batch.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Scheduler.Enabled {
+		if err := c.Scheduler.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.MemoryBudget.Enabled {
+		if err := c.MemoryBudget.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Admission.Enabled {
+		if err := c.Admission.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Hooks.Enabled {
+		if err := c.Hooks.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Scanning.Enabled {
+		if err := c.Scanning.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Inspection.Enabled {
+		if err := c.Inspection.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Transforms.Enabled {
+		if err := c.Transforms.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Extensions.Enabled {
+		if err := c.Extensions.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Transforms.Enabled {
+		for _, r := range c.Transforms.Rules {
+			if r.Module != "" && !c.Extensions.hasModule(r.Module) {
+				return fmt.Errorf("transforms.rules[%s].module %q is not an enabled extensions module", r.Name, r.Module)
+			}
+		}
+	}
+
+	if c.SelfTest.Enabled {
+		if err := c.SelfTest.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Canary.Enabled {
+		if err := c.Canary.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Readiness.Validate(); err != nil {
+		return err
+	}
+
+	if c.WarmPool.Enabled {
+		if err := c.WarmPool.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.AirGapped.Enabled {
+		if err := c.validateAirGapped(); err != nil {
+			return err
+		}
+	}
+
+	if c.SLO.Enabled {
+		if len(c.SLO.Windows) == 0 {
+			return fmt.Errorf("slo.windows must include at least one window")
+		}
+		for _, w := range c.SLO.Windows {
+			if w < time.Minute {
+				return fmt.Errorf("slo.windows entries must be at least 1m (got %s)", w)
+			}
+		}
+		if err := c.SLO.Default.validate("slo.default"); err != nil {
+			return err
+		}
+		seen := make(map[string]bool, len(c.SLO.Objectives))
+		for i, o := range c.SLO.Objectives {
+			field := fmt.Sprintf("slo.objectives[%d]", i)
+			if o.Operation == "" {
+				return fmt.Errorf("%s.operation is required", field)
+			}
+			if seen[o.Operation] {
+				return fmt.Errorf("%s.operation %q is duplicated", field, o.Operation)
+			}
+			seen[o.Operation] = true
+			if err := o.validate(field); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validate checks an SLO objective; field is the config path used in errors.
+func (o SLOObjective) validate(field string) error {
+	if o.Target <= 0 || o.Target >= 1 {
+		return fmt.Errorf("%s.target must be between 0 and 1 exclusive (got %g)", field, o.Target)
+	}
+	if o.LatencyThreshold < 0 {
+		return fmt.Errorf("%s.latency_threshold must be >= 0", field)
+	}
 	return nil
 }
 
@@ -1632,6 +5345,32 @@ func isLoopbackAddress(addr string) bool {
 
 // validateAdminTokenLength validates that the token is at least 32 bytes when
 // decoded from hex or base64, or 32 characters if it is a raw string.
+// readAdminTokenFile checks that an admin token file is a regular file
+// readable only by its owner and returns its validated, trimmed contents.
+func readAdminTokenFile(path string) (string, error) {
+	// Check file permissions — use Lstat to avoid following symlinks (TOCTOU).
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return "", fmt.Errorf("must not be a symbolic link")
+	}
+	mode := info.Mode().Perm()
+	if mode&0077 != 0 {
+		return "", fmt.Errorf("%s is too permissive (mode %04o); must be 0600 or stricter", path, mode)
+	}
+	tokenBytes, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read: %w", err)
+	}
+	token := strings.TrimSpace(string(tokenBytes))
+	if err := validateAdminTokenLength(token); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
 func validateAdminTokenLength(token string) error {
 	if token == "" {
 		return fmt.Errorf("token is empty")
@@ -1667,6 +5406,7 @@ type ConfigReloader struct {
 	stopChan      chan struct{}
 	mu            sync.RWMutex
 	onReload      func(*Config, *Config) error // callback for applying config changes
+	lastErr       error                        // outcome of the latest reload; nil before the first
 }
 
 // NewConfigReloader creates a new configuration reloader that watches for file changes
@@ -1757,6 +5497,7 @@ func (r *ConfigReloader) reloadConfig() {
 	newConfig, err := LoadConfig(r.configPath)
 	if err != nil {
 		r.logger.WithError(err).Error("Failed to reload configuration")
+		r.setLastError(fmt.Errorf("load: %w", err))
 		return
 	}
 
@@ -1767,6 +5508,7 @@ func (r *ConfigReloader) reloadConfig() {
 	// Validate that only safe fields have changed
 	if err := r.validateReloadSafety(&oldConfig, newConfig); err != nil {
 		r.logger.WithError(err).Error("Configuration reload rejected: unsafe changes detected")
+		r.setLastError(fmt.Errorf("rejected: %w", err))
 		return
 	}
 
@@ -1774,6 +5516,7 @@ func (r *ConfigReloader) reloadConfig() {
 	if r.onReload != nil {
 		if err := r.onReload(&oldConfig, newConfig); err != nil {
 			r.logger.WithError(err).Error("Failed to apply configuration changes")
+			r.setLastError(fmt.Errorf("apply: %w", err))
 			return
 		}
 	}
@@ -1781,11 +5524,30 @@ func (r *ConfigReloader) reloadConfig() {
 	// Update current config
 	r.mu.Lock()
 	r.currentConfig = newConfig
+	r.lastErr = nil
 	r.mu.Unlock()
 
 	r.logger.Info("Configuration reloaded successfully")
 }
 
+func (r *ConfigReloader) setLastError(err error) {
+	r.mu.Lock()
+	r.lastErr = err
+	r.mu.Unlock()
+}
+
+// LastError returns why the latest reload failed, or nil when it succeeded
+// or no reload has happened. The gateway keeps serving with the previous
+// configuration after a failed reload.
+func (r *ConfigReloader) LastError() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.lastErr != nil {
+		return fmt.Errorf("configuration reload failed, serving previous configuration: %w", r.lastErr)
+	}
+	return nil
+}
+
 // validateReloadSafety ensures that only non-crypto settings have changed.
 func (r *ConfigReloader) validateReloadSafety(old, new *Config) error {
 	// Crypto settings that MUST NOT change during hot reload
@@ -1815,6 +5577,27 @@ func (r *ConfigReloader) validateReloadSafety(old, new *Config) error {
 	if old.Encryption.ChunkSize != new.Encryption.ChunkSize {
 		return fmt.Errorf("encryption.chunk_size cannot be changed during hot reload")
 	}
+	if old.Encryption.BodyHeader != new.Encryption.BodyHeader {
+		return fmt.Errorf("encryption.body_header cannot be changed during hot reload")
+	}
+	if old.Encryption.MetadataEncryption != new.Encryption.MetadataEncryption {
+		return fmt.Errorf("encryption.metadata_encryption cannot be changed during hot reload")
+	}
+	if old.Encryption.IdentityMarker != new.Encryption.IdentityMarker {
+		return fmt.Errorf("encryption.identity_marker cannot be changed during hot reload")
+	}
+	if old.Encryption.HybridWrap != new.Encryption.HybridWrap {
+		return fmt.Errorf("encryption.hybrid_wrap cannot be changed during hot reload")
+	}
+	if old.Encryption.KeyObfuscation != new.Encryption.KeyObfuscation {
+		return fmt.Errorf("encryption.key_obfuscation cannot be changed during hot reload")
+	}
+	if old.Encryption.KeyObfuscationScheme != new.Encryption.KeyObfuscationScheme {
+		return fmt.Errorf("encryption.key_obfuscation_scheme cannot be changed during hot reload")
+	}
+	if old.Encryption.MetadataKeyPrefix != new.Encryption.MetadataKeyPrefix {
+		return fmt.Errorf("encryption.metadata_key_prefix cannot be changed during hot reload")
+	}
 	if old.Encryption.Hardware.EnableAESNI != new.Encryption.Hardware.EnableAESNI {
 		return fmt.Errorf("encryption.hardware.enable_aesni cannot be changed during hot reload")
 	}
@@ -1832,11 +5615,17 @@ func (r *ConfigReloader) validateReloadSafety(old, new *Config) error {
 	if old.Compression.Level != new.Compression.Level {
 		return fmt.Errorf("compression.level cannot be changed during hot reload")
 	}
+	if old.Compression.MaxRatio != new.Compression.MaxRatio {
+		return fmt.Errorf("compression.max_ratio cannot be changed during hot reload")
+	}
 
 	// Backend settings that affect encryption/decryption compatibility
 	if old.Backend.Provider != new.Backend.Provider {
 		return fmt.Errorf("backend.provider cannot be changed during hot reload")
 	}
+	if old.Backend.StorageType() != new.Backend.StorageType() {
+		return fmt.Errorf("backend.type cannot be changed during hot reload")
+	}
 
 	// Admin settings — listener is only started/stopped at process start
 	if old.Admin.Enabled != new.Admin.Enabled {
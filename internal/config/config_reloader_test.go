@@ -322,3 +322,39 @@ func TestGetCurrentConfig(t *testing.T) {
 	current.LogLevel = "debug"
 	assert.Equal(t, "info", reloader.GetCurrentConfig().LogLevel)
 }
+
+func TestConfigReloader_LastError(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(password, logLevel string) {
+		yaml := "log_level: " + logLevel + `
+backend:
+  access_key: test-key
+  secret_key: test-secret
+encryption:
+  password: ` + password + `
+auth:
+  credentials:
+    - access_key: "gateway-key"
+      secret_key: "gateway-secret"
+`
+		require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+	}
+	write("test-password", "info")
+	cfg, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	reloader, err := NewConfigReloader(configPath, cfg, logger)
+	require.NoError(t, err)
+	defer reloader.Stop()
+
+	assert.NoError(t, reloader.LastError(), "no reload yet")
+
+	write("changed-password", "info")
+	reloader.reloadConfig()
+	assert.ErrorContains(t, reloader.LastError(), "encryption.password cannot be changed")
+
+	write("test-password", "debug")
+	reloader.reloadConfig()
+	assert.NoError(t, reloader.LastError())
+}
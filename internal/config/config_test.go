@@ -4,6 +4,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -492,6 +494,211 @@ func TestConfig_Validate_ValidCredentials(t *testing.T) {
 	}
 }
 
+func TestConfig_Validate_DuplicateAccessKey(t *testing.T) {
+	cfg := minValidConfig()
+	cfg.Auth.Credentials = []GatewayCredential{
+		{AccessKey: "key-1", SecretKey: "secret-1", Label: "alice"},
+		{AccessKey: "key-2", SecretKey: "secret-2", Label: "bob"},
+		{AccessKey: "key-1", SecretKey: "secret-3", Label: "mallory"},
+	}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected error for duplicate access key, got nil")
+	}
+	if !strings.Contains(err.Error(), "auth.credentials[2]: access_key duplicates auth.credentials[0]") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestConfig_Validate_SessionTokenEnvUnset(t *testing.T) {
+	cfg := minValidConfig()
+	cfg.Auth.Credentials = []GatewayCredential{
+		{AccessKey: "ASIATEMPORARY", SecretKey: "temporary-secret", SessionTokenEnv: "UNSET_SESSION_TOKEN"},
+	}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "auth.credentials[0]: session_token_env") {
+		t.Fatalf("Validate() = %v, want a session_token_env error", err)
+	}
+	t.Setenv("UNSET_SESSION_TOKEN", "session-token-1")
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() with the variable set = %v", err)
+	}
+}
+
+func TestConfig_Validate_AuthPolicy(t *testing.T) {
+	allowAll := AuthPolicyRule{Effect: AuthPolicyAllow}
+	tests := []struct {
+		name    string
+		policy  AuthPolicyConfig
+		wantErr string
+	}{
+		{"empty", AuthPolicyConfig{}, ""},
+		{"valid", AuthPolicyConfig{DefaultDeny: true, Rules: []AuthPolicyRule{
+			{Effect: AuthPolicyAllow, Identities: []string{"ci-*"}, Buckets: []string{"builds"}, Prefixes: []string{"artifacts/*/"}, Operations: []string{"Get*", "HeadObject"}},
+			{Effect: AuthPolicyDeny, Operations: []string{"DeleteBucket"}},
+		}}, ""},
+		{"bad effect", AuthPolicyConfig{Rules: []AuthPolicyRule{{Effect: "permit"}}}, `auth.policy.rules[0].effect must be "allow" or "deny"`},
+		{"bad pattern", AuthPolicyConfig{Rules: []AuthPolicyRule{allowAll, {Effect: AuthPolicyDeny, Buckets: []string{"logs-["}}}}, `auth.policy.rules[1].buckets: invalid pattern "logs-["`},
+		{"empty pattern", AuthPolicyConfig{Rules: []AuthPolicyRule{{Effect: AuthPolicyDeny, Prefixes: []string{""}}}}, "auth.policy.rules[0].prefixes: empty pattern"},
+		{"unknown operation", AuthPolicyConfig{Rules: []AuthPolicyRule{{Effect: AuthPolicyDeny, Operations: []string{"DeleteObjcet"}}}}, `auth.policy.rules[0].operations: "DeleteObjcet" matches no operation`},
+		{"wildcard matching nothing", AuthPolicyConfig{Rules: []AuthPolicyRule{{Effect: AuthPolicyDeny, Operations: []string{"Gte*"}}}}, `"Gte*" matches no operation`},
+		{"default deny without allow", AuthPolicyConfig{DefaultDeny: true, Rules: []AuthPolicyRule{{Effect: AuthPolicyDeny}}}, "auth.policy.default_deny requires at least one allow rule"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Auth.Policy = tt.policy
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_AuthPolicy(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	data := `
+listen_addr: ":8080"
+backend:
+  endpoint: "http://localhost:9000"
+  access_key: "test"
+  secret_key: "test"
+encryption:
+  password: "test-password-123456"
+auth:
+  credentials:
+    - access_key: "alice-key"
+      secret_key: "alice-secret"
+      label: "alice"
+  policy:
+    default_deny: true
+    rules:
+      - effect: allow
+        identities: ["alice"]
+        buckets: ["alice-*"]
+      - effect: deny
+        prefixes: ["locked/"]
+        operations: ["DeleteObject"]
+`
+	if err := os.WriteFile(configPath, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	p := cfg.Auth.Policy
+	if !p.DefaultDeny || len(p.Rules) != 2 || !p.Enabled() {
+		t.Fatalf("policy = %+v", p)
+	}
+	if p.Rules[1].Effect != AuthPolicyDeny || p.Rules[1].Prefixes[0] != "locked/" || p.Rules[1].Operations[0] != "DeleteObject" {
+		t.Errorf("rules[1] = %+v", p.Rules[1])
+	}
+}
+
+func TestLoadConfig_CredentialsFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	credsPath := filepath.Join(tmpDir, "creds.yaml")
+	creds := `
+- access_key: "alice-key"
+  secret_key: "rotated-secret"
+  label: "alice"
+- access_key: "bob-key"
+  secret_key_env: "BOB_SECRET"
+  label: "bob"
+- access_key: "ASIATEMPORARY"
+  secret_key: "temporary-secret"
+  session_token_env: "TEMP_SESSION_TOKEN"
+  expiration: 2026-03-01T13:00:00Z
+`
+	if err := os.WriteFile(credsPath, []byte(creds), 0600); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	content := `
+backend:
+  access_key: "test-key"
+  secret_key: "test-secret"
+encryption:
+  password: "test-password"
+auth:
+  credentials_file: "` + credsPath + `"
+  credentials:
+    - access_key: "alice-key"
+      secret_key: "old-secret"
+    - access_key: "ci-key"
+      secret_key: "ci-secret"
+      label: "ci"
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BOB_SECRET", "bob-secret")
+	t.Setenv("TEMP_SESSION_TOKEN", "session-token-1")
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	want := []GatewayCredential{
+		{AccessKey: "alice-key", SecretKey: "rotated-secret", Label: "alice"},
+		{AccessKey: "ci-key", SecretKey: "ci-secret", Label: "ci"},
+		{AccessKey: "bob-key", SecretKey: "bob-secret", SecretKeyEnv: "BOB_SECRET", Label: "bob"},
+		{
+			AccessKey:       "ASIATEMPORARY",
+			SecretKey:       "temporary-secret",
+			SessionToken:    "session-token-1",
+			SessionTokenEnv: "TEMP_SESSION_TOKEN",
+			Expiration:      time.Date(2026, 3, 1, 13, 0, 0, 0, time.UTC),
+		},
+	}
+	if !reflect.DeepEqual(cfg.Auth.Credentials, want) {
+		t.Errorf("credentials = %+v\nwant %+v", cfg.Auth.Credentials, want)
+	}
+}
+
+func TestLoadConfig_CredentialsFileErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	bad := filepath.Join(tmpDir, "bad.yaml")
+	if err := os.WriteFile(bad, []byte("access_key: k\nsecret_key: not-a-list\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for name, path := range map[string]string{
+		"missing":   filepath.Join(tmpDir, "missing.yaml"),
+		"malformed": bad,
+	} {
+		t.Run(name, func(t *testing.T) {
+			configPath := filepath.Join(tmpDir, name+"-config.yaml")
+			content := `
+backend:
+  access_key: "test-key"
+  secret_key: "test-secret"
+encryption:
+  password: "test-password"
+auth:
+  credentials:
+    - access_key: "gateway-key"
+      secret_key: "gateway-secret"
+`
+			if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("AUTH_CREDENTIALS_FILE", path)
+			if _, err := LoadConfig(configPath); err == nil || !strings.Contains(err.Error(), "auth.credentials_file") {
+				t.Errorf("LoadConfig error = %v, want an auth.credentials_file error", err)
+			}
+		})
+	}
+}
+
 // ---- V0.6-PERF-2 retry config tests ----------------------------------------
 
 // TestBackendRetryConfig_Defaults verifies that a zero-value config normalises
@@ -1191,6 +1398,38 @@ func TestValidate_InvalidSupportedAlgorithm(t *testing.T) {
 	}
 }
 
+func TestValidate_RegisteredAlgorithm(t *testing.T) {
+	cfg := minValidConfig()
+	cfg.Encryption.PreferredAlgorithm = "TEST-REGISTERED-GCM"
+	cfg.Encryption.SupportedAlgorithms = []string{"TEST-REGISTERED-GCM", "AES256-GCM"}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected an error before the algorithm is registered")
+	}
+	RegisterAlgorithm("TEST-REGISTERED-GCM")
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected registered algorithm to validate, got %v", err)
+	}
+}
+
+func TestValidate_HybridWrap(t *testing.T) {
+	cfg := minValidConfig()
+	cfg.Encryption.HybridWrap = HybridWrapConfig{Enabled: true, PublicKeyFile: "hybrid.pub"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "key_manager") {
+		t.Errorf("expected key_manager error, got %v", err)
+	}
+
+	cfg.Encryption.KeyManager.Enabled = true
+	cfg.Encryption.KeyManager.Provider = "memory"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	cfg.Encryption.HybridWrap.PublicKeyFile = ""
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "public_key_file") {
+		t.Errorf("expected public_key_file error, got %v", err)
+	}
+}
+
 func TestValidate_KeyManagerMissingProvider(t *testing.T) {
 	cfg := minValidConfig()
 	cfg.Encryption.KeyManager.Enabled = true
@@ -1353,6 +1592,56 @@ func TestValidate_AuditConfig(t *testing.T) {
 			t.Errorf("expected no error, got %v", err)
 		}
 	})
+
+	t.Run("audit cloudwatch sink missing log group", func(t *testing.T) {
+		cfg := *base
+		cfg.Audit.Enabled = true
+		cfg.Audit.Sink.Type = "cloudwatch"
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "log_group") {
+			t.Errorf("expected log_group error, got %v", err)
+		}
+		cfg.Audit.Sink.CloudWatch.LogGroup = "/gateway/audit"
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("audit gcp_logging sink log id", func(t *testing.T) {
+		cfg := *base
+		cfg.Audit.Enabled = true
+		cfg.Audit.Sink.Type = "gcp_logging"
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("expected no error with default log id, got %v", err)
+		}
+		cfg.Audit.Sink.GCPLogging.LogID = "audit log"
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "log_id") {
+			t.Errorf("expected log_id error, got %v", err)
+		}
+	})
+
+	t.Run("audit sampling", func(t *testing.T) {
+		rate := func(v float64) *float64 { return &v }
+		for _, tc := range []struct {
+			sampling AuditSamplingConfig
+			wantErr  string
+		}{
+			{sampling: AuditSamplingConfig{MinSeverity: "warning", SuccessRate: rate(0.01), EventTypeRates: map[string]float64{"get": 0}}},
+			{sampling: AuditSamplingConfig{MinSeverity: "debug"}, wantErr: "min_severity"},
+			{sampling: AuditSamplingConfig{SuccessRate: rate(1.5)}, wantErr: "success_rate"},
+			{sampling: AuditSamplingConfig{EventTypeRates: map[string]float64{"get": -0.1}}, wantErr: "event_type_rates[get]"},
+		} {
+			cfg := *base
+			cfg.Audit.Enabled = true
+			cfg.Audit.Sampling = tc.sampling
+			err := cfg.Validate()
+			if tc.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Errorf("expected %q error, got %v", tc.wantErr, err)
+			}
+		}
+	})
 }
 
 // TestValidate_InvalidValkeyMinVersion verifies that an invalid Valkey TLS
@@ -1474,6 +1763,18 @@ func TestValidateReloadSafety_Coverage(t *testing.T) {
 			wantErr: true,
 			wantMsg: "chunk_size",
 		},
+		{
+			name:    "body_header changed",
+			mutate:  func(c *Config) { c.Encryption.BodyHeader = true },
+			wantErr: true,
+			wantMsg: "body_header",
+		},
+		{
+			name:    "hybrid_wrap changed",
+			mutate:  func(c *Config) { c.Encryption.HybridWrap.Enabled = true },
+			wantErr: true,
+			wantMsg: "hybrid_wrap",
+		},
 		{
 			name:    "backend.provider changed",
 			mutate:  func(c *Config) { c.Backend.Provider = "garage" },
@@ -2068,3 +2369,1655 @@ auth:
 		t.Errorf("expected Metrics.Addr \":9091\", got %q", cfg.Metrics.Addr)
 	}
 }
+
+func TestSLOConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*SLOConfig)
+		wantErr string
+	}{
+		{name: "defaults", mutate: func(*SLOConfig) {}},
+		{name: "no windows", mutate: func(s *SLOConfig) { s.Windows = nil }, wantErr: "slo.windows must include"},
+		{name: "window too short", mutate: func(s *SLOConfig) { s.Windows = []time.Duration{30 * time.Second} }, wantErr: "at least 1m"},
+		{name: "default target out of range", mutate: func(s *SLOConfig) { s.Default.Target = 1 }, wantErr: "slo.default.target"},
+		{name: "objective without operation", mutate: func(s *SLOConfig) {
+			s.Objectives = []SLOObjective{{Target: 0.99}}
+		}, wantErr: "slo.objectives[0].operation is required"},
+		{name: "duplicate objective", mutate: func(s *SLOConfig) {
+			s.Objectives = []SLOObjective{{Operation: "GetObject", Target: 0.99}, {Operation: "GetObject", Target: 0.9}}
+		}, wantErr: "duplicated"},
+		{name: "negative latency", mutate: func(s *SLOConfig) {
+			s.Objectives = []SLOObjective{{Operation: "PutObject", Target: 0.99, LatencyThreshold: -time.Second}}
+		}, wantErr: "latency_threshold"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.SLO = SLOConfig{
+				Enabled: true,
+				Windows: DefaultSLOWindows(),
+				Default: SLOObjective{Target: DefaultSLOTarget},
+			}
+			tt.mutate(&cfg.SLO)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSLOConfig_EnvOverrides(t *testing.T) {
+	t.Setenv("SLO_ENABLED", "true")
+	t.Setenv("SLO_WINDOWS", "5m, 1h")
+
+	cfg := &Config{}
+	loadFromEnv(cfg)
+	if !cfg.SLO.Enabled {
+		t.Error("expected SLO enabled from env")
+	}
+	if len(cfg.SLO.Windows) != 2 || cfg.SLO.Windows[1] != time.Hour {
+		t.Errorf("unexpected windows: %v", cfg.SLO.Windows)
+	}
+}
+
+func TestJournalConfig_Validate_RequiresPath(t *testing.T) {
+	cfg := minValidConfig()
+	cfg.Journal = JournalConfig{Enabled: true}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "journal.path is required") {
+		t.Fatalf("expected journal.path error, got %v", err)
+	}
+}
+
+func TestBackendConditionalWrites_Validate(t *testing.T) {
+	for _, mode := range []string{"", ConditionalWritesOff, ConditionalWritesClient, ConditionalWritesOptimistic} {
+		cfg := minValidConfig()
+		cfg.Backend.ConditionalWrites = mode
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("mode %q: unexpected error: %v", mode, err)
+		}
+	}
+
+	cfg := minValidConfig()
+	cfg.Backend.ConditionalWrites = "always"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "conditional_writes") {
+		t.Fatalf("expected conditional_writes error, got %v", err)
+	}
+}
+
+func TestBackendMetadataOverflow_Validate(t *testing.T) {
+	for _, policy := range []string{"", MetadataOverflowFallback, MetadataOverflowReject} {
+		cfg := minValidConfig()
+		cfg.Backend.MetadataOverflow = policy
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("policy %q: unexpected error: %v", policy, err)
+		}
+	}
+
+	cfg := minValidConfig()
+	cfg.Backend.MetadataOverflow = "truncate"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "metadata_overflow") {
+		t.Fatalf("expected metadata_overflow error, got %v", err)
+	}
+}
+
+func TestEncryptionMetadataEncryption_Validate(t *testing.T) {
+	for _, mode := range []string{"", MetadataEncryptionOff, MetadataEncryptionValues, MetadataEncryptionAll} {
+		cfg := minValidConfig()
+		cfg.Encryption.MetadataEncryption = mode
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("mode %q: unexpected error: %v", mode, err)
+		}
+	}
+
+	cfg := minValidConfig()
+	cfg.Encryption.MetadataEncryption = "keys"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "metadata_encryption") {
+		t.Fatalf("expected metadata_encryption error, got %v", err)
+	}
+}
+
+func TestEncryptionDoubleEncryption_Validate(t *testing.T) {
+	for _, mode := range []string{"", DoubleEncryptionReject, DoubleEncryptionPassthrough, DoubleEncryptionEncrypt} {
+		cfg := minValidConfig()
+		cfg.Encryption.DoubleEncryption = mode
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("mode %q: unexpected error: %v", mode, err)
+		}
+	}
+
+	cfg := minValidConfig()
+	cfg.Encryption.DoubleEncryption = "ignore"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "double_encryption") {
+		t.Fatalf("expected double_encryption error, got %v", err)
+	}
+
+	t.Setenv("ENCRYPTION_DOUBLE_ENCRYPTION", DoubleEncryptionPassthrough)
+	cfg = &Config{}
+	loadFromEnv(cfg)
+	if cfg.Encryption.DoubleEncryption != DoubleEncryptionPassthrough {
+		t.Errorf("DoubleEncryption = %q", cfg.Encryption.DoubleEncryption)
+	}
+}
+
+func TestEncryptionKeyObfuscationScheme_Validate(t *testing.T) {
+	for _, scheme := range []string{"", KeyObfuscationPath, KeyObfuscationSegment, KeyObfuscationFull} {
+		cfg := minValidConfig()
+		cfg.Encryption.KeyObfuscation = true
+		cfg.Encryption.KeyObfuscationScheme = scheme
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("scheme %q: unexpected error: %v", scheme, err)
+		}
+	}
+
+	cfg := minValidConfig()
+	cfg.Encryption.KeyObfuscationScheme = "hmac"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "key_obfuscation_scheme") {
+		t.Fatalf("expected key_obfuscation_scheme error, got %v", err)
+	}
+}
+
+func TestEncryptionMetadataKeyPrefix_Validate(t *testing.T) {
+	for _, prefix := range []string{"", "encryption-", "gw-", "acme-store-", "x1-"} {
+		cfg := minValidConfig()
+		cfg.Encryption.MetadataKeyPrefix = prefix
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("prefix %q: unexpected error: %v", prefix, err)
+		}
+	}
+	for _, prefix := range []string{"gw", "GW-", "gw_", "-gw-", "gw--", "a-very-long-metadata-key-prefix-name-"} {
+		cfg := minValidConfig()
+		cfg.Encryption.MetadataKeyPrefix = prefix
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "metadata_key_prefix") {
+			t.Errorf("prefix %q: expected metadata_key_prefix error, got %v", prefix, err)
+		}
+	}
+}
+
+func TestEncryptionKeyObfuscation_RejectsSizeIndex(t *testing.T) {
+	cfg := minValidConfig()
+	cfg.Encryption.KeyObfuscation = true
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.SizeIndex = SizeIndexConfig{Enabled: true, Prefix: ".s3eg-index/", FlushInterval: time.Second}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "key_obfuscation") {
+		t.Fatalf("expected key_obfuscation error, got %v", err)
+	}
+}
+
+func TestListEnrichmentConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     ListEnrichmentConfig
+		wantErr string
+	}{
+		{name: "disabled", cfg: ListEnrichmentConfig{}},
+		{name: "valid", cfg: ListEnrichmentConfig{Enabled: true, Concurrency: 8, CacheSize: 100, CacheTTL: time.Minute}},
+		{name: "no cache", cfg: ListEnrichmentConfig{Enabled: true, Concurrency: 1}},
+		{name: "zero concurrency", cfg: ListEnrichmentConfig{Enabled: true}, wantErr: "list_enrichment.concurrency"},
+		{name: "negative cache", cfg: ListEnrichmentConfig{Enabled: true, Concurrency: 1, CacheSize: -1}, wantErr: "list_enrichment.cache_size"},
+		{name: "cache without ttl", cfg: ListEnrichmentConfig{Enabled: true, Concurrency: 1, CacheSize: 10}, wantErr: "list_enrichment.cache_ttl"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.ListEnrichment = tt.cfg
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSizeIndexConfig_Validate(t *testing.T) {
+	for _, prefix := range []string{"", "/abs/", "no-slash"} {
+		cfg := minValidConfig()
+		cfg.SizeIndex = SizeIndexConfig{Enabled: true, Prefix: prefix, FlushInterval: time.Second}
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "size_index.prefix") {
+			t.Errorf("prefix %q: expected size_index.prefix error, got %v", prefix, err)
+		}
+	}
+
+	cfg := minValidConfig()
+	cfg.SizeIndex = SizeIndexConfig{Enabled: true, Prefix: ".s3eg-index/", FlushInterval: time.Millisecond}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "size_index.flush_interval") {
+		t.Errorf("expected flush_interval error, got %v", err)
+	}
+
+	cfg.SizeIndex.FlushInterval = time.Second
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHooksConfig_Validate(t *testing.T) {
+	valid := func() HooksConfig {
+		return HooksConfig{
+			Enabled: true, Workers: 1, QueueSize: 1, MaxObjectSize: 1, MaxOutputSize: 1,
+			Rules: []HookRule{{Name: "thumbs", Exec: []string{"convert", "-", "jpg:-"}, Output: "thumbs/{key}"}},
+		}
+	}
+	tests := []struct {
+		name    string
+		mutate  func(*HooksConfig)
+		wantErr string
+	}{
+		{name: "valid", mutate: func(*HooksConfig) {}},
+		{name: "no workers", mutate: func(h *HooksConfig) { h.Workers = 0 }, wantErr: "hooks.workers"},
+		{name: "no rules", mutate: func(h *HooksConfig) { h.Rules = nil }, wantErr: "hooks.rules"},
+		{name: "exec and webhook", mutate: func(h *HooksConfig) { h.Rules[0].Webhook = "http://x" }, wantErr: "exactly one of exec and webhook"},
+		{name: "bad webhook", mutate: func(h *HooksConfig) { h.Rules[0].Exec = nil; h.Rules[0].Webhook = "ftp://x" }, wantErr: "webhook must be an http(s) URL"},
+		{name: "fixed output", mutate: func(h *HooksConfig) { h.Rules[0].Output = "thumb.jpg" }, wantErr: "output must be a key template"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Hooks = valid()
+			tt.mutate(&cfg.Hooks)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestScanningConfig_Validate(t *testing.T) {
+	valid := func() ScanningConfig {
+		return ScanningConfig{
+			Enabled: true, Protocol: "clamd", Address: "localhost:3310", Timeout: time.Second,
+			MaxObjectSize: 1, Action: ScanActionBlock, QuarantineTag: DefaultQuarantineTag,
+		}
+	}
+	tests := []struct {
+		name    string
+		mutate  func(*ScanningConfig)
+		wantErr string
+	}{
+		{name: "clamd", mutate: func(*ScanningConfig) {}},
+		{name: "icap", mutate: func(s *ScanningConfig) { s.Protocol = "icap"; s.Address = "icap://av:1344/avscan" }},
+		{name: "icap without url", mutate: func(s *ScanningConfig) { s.Protocol = "icap" }, wantErr: "scanning.address"},
+		{name: "unknown protocol", mutate: func(s *ScanningConfig) { s.Protocol = "sophos" }, wantErr: "scanning.protocol"},
+		{name: "no address", mutate: func(s *ScanningConfig) { s.Address = "" }, wantErr: "scanning.address"},
+		{name: "bad action", mutate: func(s *ScanningConfig) { s.Action = ScanActionOff }, wantErr: "scanning.action"},
+		{name: "no timeout", mutate: func(s *ScanningConfig) { s.Timeout = 0 }, wantErr: "scanning.timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Scanning = valid()
+			tt.mutate(&cfg.Scanning)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestInspectionConfig_Validate(t *testing.T) {
+	valid := func() InspectionConfig {
+		return InspectionConfig{
+			Enabled: true, MaxSize: 1, TagKey: DefaultInspectionTagKey,
+			Rules: []InspectionRule{{Name: "cards", Builtin: "credit_card", Action: InspectionActionBlock}},
+		}
+	}
+	tests := []struct {
+		name    string
+		mutate  func(*InspectionConfig)
+		wantErr string
+	}{
+		{name: "valid", mutate: func(*InspectionConfig) {}},
+		{name: "no rules", mutate: func(i *InspectionConfig) { i.Rules = nil }, wantErr: "inspection.rules"},
+		{name: "bad name", mutate: func(i *InspectionConfig) { i.Rules[0].Name = "credit cards" }, wantErr: "name must be non-empty"},
+		{name: "two matchers", mutate: func(i *InspectionConfig) { i.Rules[0].Pattern = `\d+` }, wantErr: "exactly one of pattern, keywords and builtin"},
+		{name: "bad pattern", mutate: func(i *InspectionConfig) { i.Rules[0].Builtin = ""; i.Rules[0].Pattern = `(` }, wantErr: "inspection.rules[cards].pattern"},
+		{name: "unknown builtin", mutate: func(i *InspectionConfig) { i.Rules[0].Builtin = "passport" }, wantErr: "builtin must be one of"},
+		{name: "bad action", mutate: func(i *InspectionConfig) { i.Rules[0].Action = "quarantine" }, wantErr: "action must be one of"},
+		{name: "duplicate", mutate: func(i *InspectionConfig) { i.Rules = append(i.Rules, i.Rules[0]) }, wantErr: "duplicate rule name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Inspection = valid()
+			tt.mutate(&cfg.Inspection)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTransformsConfig_Validate(t *testing.T) {
+	valid := func() TransformsConfig {
+		return TransformsConfig{
+			Enabled: true, MaxOutputSize: 1,
+			Rules: []TransformRule{{Name: "redact", Bucket: "reports", Prefix: "csv/", URL: "http://redactor:8080/"}},
+		}
+	}
+	tests := []struct {
+		name    string
+		mutate  func(*TransformsConfig)
+		wantErr string
+	}{
+		{name: "valid", mutate: func(*TransformsConfig) {}},
+		{name: "no rules", mutate: func(c *TransformsConfig) { c.Rules = nil }, wantErr: "transforms.rules"},
+		{name: "no output limit", mutate: func(c *TransformsConfig) { c.MaxOutputSize = 0 }, wantErr: "max_output_size"},
+		{name: "bad name", mutate: func(c *TransformsConfig) { c.Rules[0].Name = "" }, wantErr: "name must be non-empty"},
+		{name: "no bucket", mutate: func(c *TransformsConfig) { c.Rules[0].Bucket = "" }, wantErr: "bucket is required"},
+		{name: "bad url", mutate: func(c *TransformsConfig) { c.Rules[0].URL = "redactor:8080" }, wantErr: "http(s) URL"},
+		{name: "negative timeout", mutate: func(c *TransformsConfig) { c.Rules[0].Timeout = -time.Second }, wantErr: "timeout"},
+		{name: "duplicate", mutate: func(c *TransformsConfig) { c.Rules = append(c.Rules, c.Rules[0]) }, wantErr: "duplicate rule name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Transforms = valid()
+			tt.mutate(&cfg.Transforms)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestBackendReadReplicasConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     BackendReadReplicasConfig
+		wantErr string
+	}{
+		{name: "unset"},
+		{name: "failover only", cfg: BackendReadReplicasConfig{Endpoints: []string{"http://minio-2:9000"}}},
+		{name: "hedged", cfg: BackendReadReplicasConfig{Endpoints: []string{"http://minio-2:9000"}, HedgeAfter: 200 * time.Millisecond}},
+		{name: "empty endpoint", cfg: BackendReadReplicasConfig{Endpoints: []string{" "}}, wantErr: "endpoints[0]"},
+		{name: "negative hedge", cfg: BackendReadReplicasConfig{Endpoints: []string{"http://minio-2:9000"}, HedgeAfter: -time.Second}, wantErr: "hedge_after"},
+		{name: "hedge without replicas", cfg: BackendReadReplicasConfig{HedgeAfter: time.Second}, wantErr: "hedge_after"},
+		{name: "persistent failover", cfg: BackendReadReplicasConfig{Endpoints: []string{"http://minio-2:9000"}, FailoverAfter: 5, ProbeInterval: 10 * time.Second}},
+		{name: "negative failover_after", cfg: BackendReadReplicasConfig{Endpoints: []string{"http://minio-2:9000"}, FailoverAfter: -1}, wantErr: "failover_after"},
+		{name: "failover without replicas", cfg: BackendReadReplicasConfig{FailoverAfter: 3}, wantErr: "failover_after"},
+		{name: "negative probe interval", cfg: BackendReadReplicasConfig{Endpoints: []string{"http://minio-2:9000"}, ProbeInterval: -time.Second}, wantErr: "probe_interval"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Backend.ReadReplicas = tt.cfg
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackendRangeHedgingConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     BackendRangeHedgingConfig
+		wantErr string
+	}{
+		{name: "disabled ignores bounds", cfg: BackendRangeHedgingConfig{MinDelay: -1}},
+		{name: "defaults", cfg: BackendRangeHedgingConfig{Enabled: true, MinDelay: DefaultRangeHedgeMinDelay, MaxDelay: DefaultRangeHedgeMaxDelay}},
+		{name: "no min", cfg: BackendRangeHedgingConfig{Enabled: true, MaxDelay: time.Second}, wantErr: "min_delay"},
+		{name: "max below min", cfg: BackendRangeHedgingConfig{Enabled: true, MinDelay: time.Second, MaxDelay: time.Millisecond}, wantErr: "max_delay"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Backend.RangeHedging = tt.cfg
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackendStreamingUploadConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     BackendStreamingUploadConfig
+		wantErr string
+	}{
+		{name: "disabled ignores bounds", cfg: BackendStreamingUploadConfig{PartSize: 1}},
+		{name: "zero values use defaults", cfg: BackendStreamingUploadConfig{Enabled: true}},
+		{name: "defaults", cfg: BackendStreamingUploadConfig{Enabled: true, Threshold: DefaultStreamingUploadThreshold, PartSize: DefaultStreamingUploadPartSize, Concurrency: DefaultStreamingUploadConcurrency}},
+		{name: "part below S3 minimum", cfg: BackendStreamingUploadConfig{Enabled: true, PartSize: 1 << 20}, wantErr: "part_size"},
+		{name: "negative concurrency", cfg: BackendStreamingUploadConfig{Enabled: true, Concurrency: -1}, wantErr: "concurrency"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Backend.StreamingUpload = tt.cfg
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackendProxy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxy   string
+		wantErr string
+	}{
+		{name: "unset", proxy: ""},
+		{name: "http", proxy: "http://proxy.internal:3128"},
+		{name: "socks5", proxy: "socks5://127.0.0.1:1080"},
+		{name: "no host", proxy: "proxy.internal:3128", wantErr: "backend.proxy"},
+		{name: "bad scheme", proxy: "ftp://proxy.internal", wantErr: "scheme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Backend.Proxy = tt.proxy
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackendHeadElisionConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     BackendHeadElisionConfig
+		wantErr string
+	}{
+		{name: "disabled ignores bounds", cfg: BackendHeadElisionConfig{TTL: -1}},
+		{name: "defaults", cfg: BackendHeadElisionConfig{Enabled: true, TTL: DefaultHeadElisionTTL, MaxEntries: DefaultHeadElisionMaxEntries}},
+		{name: "no ttl", cfg: BackendHeadElisionConfig{Enabled: true, MaxEntries: 10}, wantErr: "ttl"},
+		{name: "no entries", cfg: BackendHeadElisionConfig{Enabled: true, TTL: time.Second}, wantErr: "max_entries"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Backend.HeadElision = tt.cfg
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackendType_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(b *BackendConfig)
+		wantErr string
+	}{
+		{name: "default s3", mutate: func(b *BackendConfig) {}},
+		{name: "unknown type", mutate: func(b *BackendConfig) { b.Type = "ftp" }, wantErr: "invalid backend.type"},
+		{name: "gcs needs hmac keys", mutate: func(b *BackendConfig) { b.Type = "gcs"; b.SecretKey = "" }, wantErr: "secret_key"},
+		{name: "filesystem", mutate: func(b *BackendConfig) {
+			b.Type = "filesystem"
+			b.AccessKey, b.SecretKey = "", ""
+			b.Filesystem.Root = "/var/lib/gateway"
+		}},
+		{name: "filesystem without root", mutate: func(b *BackendConfig) { b.Type = "filesystem" }, wantErr: "filesystem.root"},
+		{name: "azure", mutate: func(b *BackendConfig) {
+			b.Type = "Azure"
+			b.Azure = BackendAzureConfig{AccountName: "acct", AccountKey: "a2V5"}
+		}},
+		{name: "azure key not base64", mutate: func(b *BackendConfig) {
+			b.Type = "azure"
+			b.Azure = BackendAzureConfig{AccountName: "acct", AccountKey: "not base64!"}
+		}, wantErr: "account_key"},
+		{name: "azure without account", mutate: func(b *BackendConfig) {
+			b.Type = "azure"
+			b.Azure.AccountKey = "a2V5"
+		}, wantErr: "account_name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			tt.mutate(&cfg.Backend)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSelfTestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*SelfTestConfig)
+		wantErr string
+	}{
+		{name: "defaults", mutate: func(*SelfTestConfig) {}},
+		{name: "run once", mutate: func(s *SelfTestConfig) { s.RetryInterval = 0 }},
+		{name: "no timeout", mutate: func(s *SelfTestConfig) { s.Timeout = 0 }, wantErr: "self_test.timeout"},
+		{name: "negative retry", mutate: func(s *SelfTestConfig) { s.RetryInterval = -time.Second }, wantErr: "self_test.retry_interval"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.SelfTest = SelfTestConfig{
+				Enabled: true, Bucket: "canary", KeyPrefix: DefaultSelfTestKeyPrefix,
+				Timeout: DefaultSelfTestTimeout, RetryInterval: DefaultSelfTestRetryInterval,
+			}
+			tt.mutate(&cfg.SelfTest)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCanaryConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*CanaryConfig)
+		wantErr string
+	}{
+		{name: "defaults", mutate: func(*CanaryConfig) {}},
+		{name: "short interval", mutate: func(c *CanaryConfig) { c.Interval = 100 * time.Millisecond }, wantErr: "canary.interval"},
+		{name: "no timeout", mutate: func(c *CanaryConfig) { c.Timeout = 0 }, wantErr: "canary.timeout"},
+		{name: "timeout over interval", mutate: func(c *CanaryConfig) { c.Timeout = 2 * c.Interval }, wantErr: "canary.timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Canary = CanaryConfig{
+				Enabled: true, Interval: DefaultCanaryInterval,
+				KeyPrefix: DefaultCanaryKeyPrefix, Timeout: DefaultCanaryTimeout,
+			}
+			tt.mutate(&cfg.Canary)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestWarmPoolConfig(t *testing.T) {
+	t.Setenv("WARM_POOL_ENABLED", "true")
+	t.Setenv("WARM_POOL_INTERVAL", "20s")
+	t.Setenv("WARM_POOL_CONNECTIONS", "8")
+	t.Setenv("WARM_POOL_TIMEOUT", "2s")
+	t.Setenv("COSMIAN_KMS_LAZY_CONNECT", "true")
+	cfg := &Config{}
+	loadFromEnv(cfg)
+	want := WarmPoolConfig{Enabled: true, Interval: 20 * time.Second, Connections: 8, Timeout: 2 * time.Second}
+	if cfg.WarmPool != want {
+		t.Fatalf("WarmPool = %+v", cfg.WarmPool)
+	}
+	if !cfg.Encryption.KeyManager.Cosmian.LazyConnect {
+		t.Error("COSMIAN_KMS_LAZY_CONNECT not applied")
+	}
+	if err := cfg.WarmPool.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	tests := []struct {
+		name    string
+		edit    func(*WarmPoolConfig)
+		wantErr string
+	}{
+		{"short interval", func(w *WarmPoolConfig) { w.Interval = 100 * time.Millisecond }, "warm_pool.interval"},
+		{"no connections", func(w *WarmPoolConfig) { w.Connections = 0 }, "warm_pool.connections"},
+		{"too many connections", func(w *WarmPoolConfig) { w.Connections = 65 }, "warm_pool.connections"},
+		{"no timeout", func(w *WarmPoolConfig) { w.Timeout = 0 }, "warm_pool.timeout"},
+		{"timeout over interval", func(w *WarmPoolConfig) { w.Timeout = time.Minute }, "warm_pool.timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := want
+			tt.edit(&w)
+			if err := w.Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHeaderRules_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*Config)
+		wantErr string
+	}{
+		{name: "none", mutate: func(*Config) {}},
+		{name: "valid backend rules", mutate: func(c *Config) {
+			c.Backend.HeaderRules = []HeaderRule{
+				{Action: HeaderRuleRemove, Name: "x-amz-checksum-*"},
+				{Action: HeaderRuleSet, Name: "X-Hint", Value: "1"},
+				{Action: HeaderRuleRename, Name: "x-a", To: "x-b"},
+			}
+		}},
+		{name: "wildcard over protected names", mutate: func(c *Config) {
+			c.Backend.HeaderRules = []HeaderRule{{Action: HeaderRuleRemove, Name: "x-amz-*"}}
+		}},
+		{name: "unknown action", mutate: func(c *Config) {
+			c.Backend.HeaderRules = []HeaderRule{{Action: "drop", Name: "x-a"}}
+		}, wantErr: "backend.header_rules[0].action"},
+		{name: "wildcard set", mutate: func(c *Config) {
+			c.Backend.HeaderRules = []HeaderRule{{Action: HeaderRuleSet, Name: "x-*", Value: "1"}}
+		}, wantErr: "wildcard"},
+		{name: "protected backend header", mutate: func(c *Config) {
+			c.Backend.HeaderRules = []HeaderRule{{Action: HeaderRuleRemove, Name: "authorization"}}
+		}, wantErr: "Authorization cannot be rewritten"},
+		{name: "rename onto protected header", mutate: func(c *Config) {
+			c.Server.ResponseHeaderRules = []HeaderRule{{Action: HeaderRuleRename, Name: "x-len", To: "content-length"}}
+		}, wantErr: "server.response_header_rules[0]"},
+		{name: "header injection", mutate: func(c *Config) {
+			c.Server.ResponseHeaderRules = []HeaderRule{{Action: HeaderRuleAdd, Name: "x-a", Value: "1\r\nSet-Cookie: a=b"}}
+		}, wantErr: "line breaks"},
+		{name: "rename without target", mutate: func(c *Config) {
+			c.Server.ResponseHeaderRules = []HeaderRule{{Action: HeaderRuleRename, Name: "x-a"}}
+		}, wantErr: "to must be a header name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			tt.mutate(cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGatewayCredentialRaw_Validate(t *testing.T) {
+	for _, tt := range []struct {
+		raw     string
+		wantErr bool
+	}{
+		{"", false},
+		{RawAccessOnRequest, false},
+		{RawAccessAlways, false},
+		{"yes", true},
+	} {
+		cfg := minValidConfig()
+		cfg.Auth.Credentials[0].Raw = tt.raw
+		err := cfg.Validate()
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "auth.credentials[0]: raw") {
+				t.Errorf("raw %q: expected raw error, got %v", tt.raw, err)
+			}
+		} else if err != nil {
+			t.Errorf("raw %q: unexpected error: %v", tt.raw, err)
+		}
+	}
+}
+
+func TestAccessStatsConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     AccessStatsConfig
+		index   SizeIndexConfig
+		wantErr string
+	}{
+		{name: "disabled", cfg: AccessStatsConfig{}},
+		{name: "valid", cfg: AccessStatsConfig{Enabled: true, Prefix: ".s3eg-access/", FlushInterval: time.Second}},
+		{name: "empty prefix", cfg: AccessStatsConfig{Enabled: true, FlushInterval: time.Second}, wantErr: "access_stats.prefix"},
+		{name: "absolute prefix", cfg: AccessStatsConfig{Enabled: true, Prefix: "/stats/", FlushInterval: time.Second}, wantErr: "access_stats.prefix"},
+		{name: "short interval", cfg: AccessStatsConfig{Enabled: true, Prefix: ".s3eg-access/", FlushInterval: time.Millisecond}, wantErr: "access_stats.flush_interval"},
+		{
+			name:    "inside size index",
+			cfg:     AccessStatsConfig{Enabled: true, Prefix: ".s3eg-index/access/", FlushInterval: time.Second},
+			index:   SizeIndexConfig{Enabled: true, Prefix: ".s3eg-index/", FlushInterval: time.Second},
+			wantErr: "must not overlap",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.AccessStats = tt.cfg
+			cfg.SizeIndex = tt.index
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	cfg := minValidConfig()
+	cfg.Encryption.KeyObfuscation = true
+	cfg.AccessStats = AccessStatsConfig{Enabled: true, Prefix: ".s3eg-access/", FlushInterval: time.Second}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "key_obfuscation") {
+		t.Errorf("expected key_obfuscation error, got %v", err)
+	}
+}
+
+func TestShardingConfig_Validate(t *testing.T) {
+	fsShard := func(name string) BackendShard {
+		return BackendShard{Name: name, Backend: BackendConfig{Type: BackendTypeFilesystem, Filesystem: BackendFilesystemConfig{Root: "/srv/" + name}}}
+	}
+	tests := []struct {
+		name    string
+		cfg     ShardingConfig
+		wantErr string
+	}{
+		{name: "disabled", cfg: ShardingConfig{}},
+		{name: "two shards", cfg: ShardingConfig{Enabled: true, Shards: []BackendShard{fsShard("b")}}},
+		{
+			name: "overrides",
+			cfg: ShardingConfig{Enabled: true, Shards: []BackendShard{fsShard("b")}, Overrides: map[string]string{
+				"logs": "b", "legacy": PrimaryShardName,
+			}},
+		},
+		{name: "no shards", cfg: ShardingConfig{Enabled: true}, wantErr: "at least one shard"},
+		{name: "unnamed shard", cfg: ShardingConfig{Enabled: true, Shards: []BackendShard{fsShard("")}}, wantErr: "name is required"},
+		{name: "duplicate shard", cfg: ShardingConfig{Enabled: true, Shards: []BackendShard{fsShard("b"), fsShard("b")}}, wantErr: "used twice"},
+		{name: "shard named primary", cfg: ShardingConfig{Enabled: true, Shards: []BackendShard{fsShard(PrimaryShardName)}}, wantErr: "used twice"},
+		{
+			name:    "shard without credentials",
+			cfg:     ShardingConfig{Enabled: true, Shards: []BackendShard{{Name: "b", Backend: BackendConfig{Endpoint: "https://s3.example"}}}},
+			wantErr: "sharding.shards[0].backend.access_key",
+		},
+		{
+			name:    "override to unknown shard",
+			cfg:     ShardingConfig{Enabled: true, Shards: []BackendShard{fsShard("b")}, Overrides: map[string]string{"logs": "c"}},
+			wantErr: "unknown shard",
+		},
+		{name: "negative virtual nodes", cfg: ShardingConfig{Enabled: true, Shards: []BackendShard{fsShard("b")}, VirtualNodes: -1}, wantErr: "virtual_nodes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Sharding = tt.cfg
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestQuotaConfig_Validate(t *testing.T) {
+	limit := QuotaLimit{MaxBytes: 1 << 30}
+	tests := []struct {
+		name    string
+		cfg     QuotaConfig
+		wantErr string
+	}{
+		{name: "disabled", cfg: QuotaConfig{}},
+		{name: "bucket limit", cfg: QuotaConfig{Enabled: true, ScanInterval: time.Hour, Buckets: map[string]QuotaLimit{"logs": limit}}},
+		{
+			name: "tenant limit",
+			cfg: QuotaConfig{Enabled: true, ScanInterval: time.Hour, Tenants: map[string]TenantQuota{
+				"acme": {Buckets: []string{"acme-a", "acme-b"}, QuotaLimit: QuotaLimit{MaxObjects: 1000}},
+			}},
+		},
+		{name: "no limits", cfg: QuotaConfig{Enabled: true, ScanInterval: time.Hour}, wantErr: "must define a limit"},
+		{name: "short scan interval", cfg: QuotaConfig{Enabled: true, ScanInterval: time.Second, Buckets: map[string]QuotaLimit{"logs": limit}}, wantErr: "scan_interval"},
+		{name: "measured only", cfg: QuotaConfig{Enabled: true, ScanInterval: time.Hour, Buckets: map[string]QuotaLimit{"logs": {}}}},
+		{name: "negative limit", cfg: QuotaConfig{Enabled: true, ScanInterval: time.Hour, Buckets: map[string]QuotaLimit{"logs": {MaxObjects: -1}}}, wantErr: "must not be negative"},
+		{
+			name:    "tenant without buckets",
+			cfg:     QuotaConfig{Enabled: true, ScanInterval: time.Hour, Tenants: map[string]TenantQuota{"acme": {QuotaLimit: limit}}},
+			wantErr: "at least one bucket",
+		},
+		{
+			name: "bucket in two tenants",
+			cfg: QuotaConfig{Enabled: true, ScanInterval: time.Hour, Tenants: map[string]TenantQuota{
+				"a": {Buckets: []string{"shared"}, QuotaLimit: limit},
+				"b": {Buckets: []string{"shared"}, QuotaLimit: limit},
+			}},
+			wantErr: "already belongs to tenant",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Quota = tt.cfg
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestBillingConfig_Validate(t *testing.T) {
+	valid := BillingConfig{Enabled: true, Interval: time.Hour, Bucket: "billing", Prefix: "usage/", Format: BillingFormatCSV}
+	tests := []struct {
+		name    string
+		mutate  func(*BillingConfig)
+		wantErr string
+	}{
+		{name: "csv", mutate: func(b *BillingConfig) {}},
+		{name: "parquet", mutate: func(b *BillingConfig) { b.Format = BillingFormatParquet }},
+		{name: "no prefix", mutate: func(b *BillingConfig) { b.Prefix = "" }},
+		{name: "disabled ignores errors", mutate: func(b *BillingConfig) { b.Enabled = false; b.Bucket = "" }},
+		{name: "no bucket", mutate: func(b *BillingConfig) { b.Bucket = "" }, wantErr: "billing.bucket"},
+		{name: "absolute prefix", mutate: func(b *BillingConfig) { b.Prefix = "/usage/" }, wantErr: "billing.prefix"},
+		{name: "prefix without slash", mutate: func(b *BillingConfig) { b.Prefix = "usage" }, wantErr: "billing.prefix"},
+		{name: "short interval", mutate: func(b *BillingConfig) { b.Interval = time.Second }, wantErr: "billing.interval"},
+		{name: "unknown format", mutate: func(b *BillingConfig) { b.Format = "xlsx" }, wantErr: "billing.format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Billing = valid
+			tt.mutate(&cfg.Billing)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTieringConfig_Validate(t *testing.T) {
+	classRule := TieringRule{Bucket: "b", MinAge: 24 * time.Hour, StorageClass: "STANDARD_IA"}
+	fsBackend := BackendConfig{Type: BackendTypeFilesystem, Filesystem: BackendFilesystemConfig{Root: "/srv/cold"}}
+	tests := []struct {
+		name    string
+		cfg     TieringConfig
+		stats   bool
+		wantErr string
+	}{
+		{name: "disabled", cfg: TieringConfig{}},
+		{name: "storage class", cfg: TieringConfig{Enabled: true, Interval: time.Hour, Rules: []TieringRule{classRule}}},
+		{
+			name: "secondary",
+			cfg: TieringConfig{Enabled: true, Interval: time.Hour, Backend: fsBackend, Rules: []TieringRule{
+				{Bucket: "b", MinAge: time.Hour, Secondary: true, SecondaryBucket: "cold"},
+			}},
+		},
+		{name: "short interval", cfg: TieringConfig{Enabled: true, Interval: time.Second, Rules: []TieringRule{classRule}}, wantErr: "tiering.interval"},
+		{name: "no rules", cfg: TieringConfig{Enabled: true, Interval: time.Hour}, wantErr: "at least one rule"},
+		{name: "no bucket", cfg: TieringConfig{Enabled: true, Interval: time.Hour, Rules: []TieringRule{{MinAge: time.Hour, StorageClass: "X"}}}, wantErr: "bucket is required"},
+		{name: "no condition", cfg: TieringConfig{Enabled: true, Interval: time.Hour, Rules: []TieringRule{{Bucket: "b", StorageClass: "X"}}}, wantErr: "min_age and cold_after"},
+		{name: "no target", cfg: TieringConfig{Enabled: true, Interval: time.Hour, Rules: []TieringRule{{Bucket: "b", MinAge: time.Hour}}}, wantErr: "storage_class or secondary"},
+		{
+			name:    "secondary bucket without secondary",
+			cfg:     TieringConfig{Enabled: true, Interval: time.Hour, Rules: []TieringRule{{Bucket: "b", MinAge: time.Hour, StorageClass: "X", SecondaryBucket: "cold"}}},
+			wantErr: "requires secondary",
+		},
+		{
+			name:    "secondary without backend",
+			cfg:     TieringConfig{Enabled: true, Interval: time.Hour, Rules: []TieringRule{{Bucket: "b", MinAge: time.Hour, Secondary: true}}},
+			wantErr: "tiering.backend.access_key",
+		},
+		{
+			name:    "cold_after without access stats",
+			cfg:     TieringConfig{Enabled: true, Interval: time.Hour, Rules: []TieringRule{{Bucket: "b", ColdAfter: time.Hour, StorageClass: "X"}}},
+			wantErr: "requires access_stats.enabled",
+		},
+		{
+			name:  "cold_after with access stats",
+			cfg:   TieringConfig{Enabled: true, Interval: time.Hour, Rules: []TieringRule{{Bucket: "b", ColdAfter: time.Hour, StorageClass: "X"}}},
+			stats: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Tiering = tt.cfg
+			if tt.stats {
+				cfg.AccessStats = AccessStatsConfig{Enabled: true, Prefix: ".s3eg-access/", FlushInterval: time.Second}
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	cfg := minValidConfig()
+	cfg.Encryption.KeyObfuscation = true
+	cfg.Tiering = TieringConfig{Enabled: true, Interval: time.Hour, Rules: []TieringRule{classRule}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "key_obfuscation") {
+		t.Errorf("expected key_obfuscation error, got %v", err)
+	}
+}
+
+func TestKeyHoldConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     KeyHoldConfig
+		wantErr string
+	}{
+		{name: "disabled", cfg: KeyHoldConfig{}},
+		{name: "valid", cfg: KeyHoldConfig{Enabled: true, Bucket: "gateway-state", Key: ".s3eg-keyholds.json"}},
+		{name: "no bucket", cfg: KeyHoldConfig{Enabled: true, Key: ".s3eg-keyholds.json"}, wantErr: "key_hold.bucket"},
+		{name: "empty key", cfg: KeyHoldConfig{Enabled: true, Bucket: "b"}, wantErr: "key_hold.key"},
+		{name: "absolute key", cfg: KeyHoldConfig{Enabled: true, Bucket: "b", Key: "/holds.json"}, wantErr: "key_hold.key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.KeyHold = tt.cfg
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	cfg := minValidConfig()
+	cfg.Encryption.KeyObfuscation = true
+	cfg.KeyHold = KeyHoldConfig{Enabled: true, Bucket: "b", Key: ".s3eg-keyholds.json"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "key_obfuscation") {
+		t.Errorf("expected key_obfuscation error, got %v", err)
+	}
+}
+
+func TestValidate_FeatureFlags(t *testing.T) {
+	cfg := minValidConfig()
+	cfg.FeatureFlags = map[string]FeatureFlag{"parallel_range_fetch": {Enabled: true, Buckets: []string{"canary"}, Percentage: 10}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.FeatureFlags["parallel_range_fetch"] = FeatureFlag{Enabled: true, Percentage: 120}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "feature_flags.parallel_range_fetch") {
+		t.Errorf("expected percentage error, got %v", err)
+	}
+
+	t.Setenv("FEATURE_FLAGS", "parallel_range_fetch, other")
+	loaded := &Config{}
+	loadFromEnv(loaded)
+	if !loaded.FeatureFlags["parallel_range_fetch"].Enabled || !loaded.FeatureFlags["other"].Enabled {
+		t.Errorf("FEATURE_FLAGS = %v", loaded.FeatureFlags)
+	}
+}
+
+func TestValidate_Readiness(t *testing.T) {
+	cfg := minValidConfig()
+	cfg.Readiness.Backend.CacheTTL = -time.Second
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "readiness.backend") {
+		t.Errorf("expected readiness.backend error, got %v", err)
+	}
+
+	t.Setenv("READINESS_KMS_TIMEOUT", "750ms")
+	t.Setenv("READINESS_BACKEND_CACHE_TTL", "0s")
+	t.Setenv("READINESS_BACKEND_BUCKET", "probe")
+	loaded := &Config{Readiness: ReadinessConfig{Backend: ReadinessCheckConfig{CacheTTL: DefaultReadinessCacheTTL}}}
+	loadFromEnv(loaded)
+	if loaded.Readiness.KMS.Timeout != 750*time.Millisecond || loaded.Readiness.Backend.CacheTTL != 0 || loaded.Readiness.BackendBucket != "probe" {
+		t.Errorf("readiness from env = %+v", loaded.Readiness)
+	}
+}
+
+func TestValidate_LazyUpgrade(t *testing.T) {
+	cfg := minValidConfig()
+	cfg.Encryption.LazyUpgrade = LazyUpgradeConfig{Enabled: true, Rate: 2, MaxObjectSize: 1 << 20, QueueSize: 10}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("valid lazy upgrade rejected: %v", err)
+	}
+	cfg.Encryption.ChunkSize = 1 << 16
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "chunked_mode") {
+		t.Errorf("expected chunked_mode error, got %v", err)
+	}
+	cfg.Encryption.ChunkedMode = true
+	cfg.Encryption.LazyUpgrade.Rate = 0
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "lazy_upgrade.rate") {
+		t.Errorf("expected lazy_upgrade.rate error, got %v", err)
+	}
+}
+
+func TestValidate_IdentityMarker(t *testing.T) {
+	cfg := minValidConfig()
+	cfg.Encryption.IdentityMarker = IdentityMarkerConfig{Enabled: true}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("marker without gateway_id rejected: %v", err)
+	}
+	cfg.Encryption.IdentityMarker.GatewayID = "gw-eu.1"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("valid gateway_id rejected: %v", err)
+	}
+	cfg.Encryption.IdentityMarker.GatewayID = "gw:1"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "gateway_id") {
+		t.Errorf("expected gateway_id error, got %v", err)
+	}
+}
+
+func TestAdminRotationConfig_Validate(t *testing.T) {
+	for _, tc := range []struct {
+		cfg     AdminRotationConfig
+		wantErr string
+	}{
+		{AdminRotationConfig{Peers: []string{"https://gw-2:8081", "http://127.0.0.1:8081/"}}, ""},
+		{AdminRotationConfig{Peers: []string{"gw-2:8081"}}, "peers[0]"},
+		{AdminRotationConfig{Peers: []string{"ftp://gw-2"}}, "peers[0]"},
+		{AdminRotationConfig{PeerTimeout: -time.Second}, "peer_timeout"},
+		{AdminRotationConfig{DryRunMaxAge: -time.Second}, "dry_run_max_age"},
+	} {
+		err := tc.cfg.Validate()
+		if tc.wantErr == "" && err != nil {
+			t.Errorf("%+v: unexpected error %v", tc.cfg, err)
+		}
+		if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+			t.Errorf("%+v: error = %v, want %q", tc.cfg, err, tc.wantErr)
+		}
+	}
+}
+
+func TestValidate_AdminRoleTokens(t *testing.T) {
+	dir := t.TempDir()
+	writeToken := func(name, token string, mode os.FileMode) string {
+		path := dir + "/" + name
+		if err := os.WriteFile(path, []byte(token), mode); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	observer := writeToken("observer", strings.Repeat("a", 64), 0600)
+	operator := writeToken("operator", strings.Repeat("b", 64), 0600)
+	same := writeToken("same", strings.Repeat("a", 64), 0600)
+	loose := writeToken("loose", strings.Repeat("c", 64), 0644)
+
+	tests := []struct {
+		name    string
+		tokens  []AdminRoleToken
+		wantErr string
+	}{
+		{name: "role tokens without primary token", tokens: []AdminRoleToken{{Role: "observer", TokenFile: observer}, {Role: "key_operator", TokenFile: operator}}},
+		{name: "unknown role", tokens: []AdminRoleToken{{Role: "root", TokenFile: observer}}, wantErr: "tokens[0].role"},
+		{name: "missing file", tokens: []AdminRoleToken{{Role: "observer"}}, wantErr: "tokens[0].token_file is required"},
+		{name: "permissive file", tokens: []AdminRoleToken{{Role: "observer", TokenFile: loose}}, wantErr: "too permissive"},
+		{name: "shared token", tokens: []AdminRoleToken{{Role: "observer", TokenFile: observer}, {Role: "config_admin", TokenFile: same}}, wantErr: "same token as admin.auth.tokens[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Admin.Enabled = true
+			cfg.Admin.Address = "127.0.0.1:8081"
+			cfg.Admin.RateLimit.RequestsPerMinute = 30
+			cfg.Admin.Auth.Tokens = tt.tokens
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Setenv("ADMIN_AUTH_OBSERVER_TOKEN_FILE", observer)
+	t.Setenv("ADMIN_AUTH_KEY_OPERATOR_TOKEN_FILE", operator)
+	cfg := minValidConfig()
+	loadFromEnv(cfg)
+	if len(cfg.Admin.Auth.Tokens) != 2 || cfg.Admin.Auth.Tokens[1] != (AdminRoleToken{Role: "key_operator", TokenFile: operator}) {
+		t.Errorf("tokens from env = %+v", cfg.Admin.Auth.Tokens)
+	}
+}
+
+func TestTrashConfig_Validate(t *testing.T) {
+	valid := func() TrashConfig {
+		return TrashConfig{Enabled: true, Buckets: []string{"data"}, Prefix: ".trash/", Retention: 7 * 24 * time.Hour, Interval: time.Hour}
+	}
+	for _, tc := range []struct {
+		edit    func(*TrashConfig)
+		wantErr string
+	}{
+		{func(*TrashConfig) {}, ""},
+		{func(c *TrashConfig) { c.Buckets = nil }, "trash.buckets"},
+		{func(c *TrashConfig) { c.Buckets = []string{""} }, "trash.buckets[0]"},
+		{func(c *TrashConfig) { c.Prefix = "" }, "trash.prefix"},
+		{func(c *TrashConfig) { c.Prefix = ".trash" }, "trash.prefix"},
+		{func(c *TrashConfig) { c.Prefix = "/trash/" }, "trash.prefix"},
+		{func(c *TrashConfig) { c.Retention = 0 }, "trash.retention"},
+		{func(c *TrashConfig) { c.Interval = time.Second }, "trash.interval"},
+	} {
+		cfg := valid()
+		tc.edit(&cfg)
+		err := cfg.Validate()
+		if tc.wantErr == "" && err != nil {
+			t.Errorf("%+v: unexpected error %v", cfg, err)
+		}
+		if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+			t.Errorf("%+v: error = %v, want %q", cfg, err, tc.wantErr)
+		}
+	}
+}
+
+func TestBatchConfig_Validate(t *testing.T) {
+	valid := func() BatchConfig {
+		return BatchConfig{Enabled: true, Workers: 4, MaxAttempts: 3, MaxKeys: 1000, ReportPrefix: ".s3eg-batch/", RetainJobs: 10}
+	}
+	for _, tc := range []struct {
+		edit    func(*BatchConfig)
+		wantErr string
+	}{
+		{func(*BatchConfig) {}, ""},
+		{func(c *BatchConfig) { c.Workers = 0 }, "batch.workers"},
+		{func(c *BatchConfig) { c.MaxAttempts = 0 }, "batch.max_attempts"},
+		{func(c *BatchConfig) { c.MaxKeys = 0 }, "batch.max_keys"},
+		{func(c *BatchConfig) { c.RetainJobs = 0 }, "batch.retain_jobs"},
+		{func(c *BatchConfig) { c.ReportPrefix = "reports" }, "batch.report_prefix"},
+		{func(c *BatchConfig) { c.ReportPrefix = "/reports/" }, "batch.report_prefix"},
+	} {
+		cfg := valid()
+		tc.edit(&cfg)
+		err := cfg.Validate()
+		if tc.wantErr == "" && err != nil {
+			t.Errorf("%+v: unexpected error %v", cfg, err)
+		}
+		if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+			t.Errorf("%+v: error = %v, want %q", cfg, err, tc.wantErr)
+		}
+	}
+}
+
+func TestSchedulerConfig(t *testing.T) {
+	t.Setenv("SCHEDULER_ENABLED", "true")
+	t.Setenv("SCHEDULER_JOBS", "tiering=*/15 * * * *; trash=@hourly")
+	cfg := &Config{}
+	loadFromEnv(cfg)
+	if !cfg.Scheduler.Enabled || cfg.Scheduler.Jobs["tiering"] != "*/15 * * * *" || cfg.Scheduler.Jobs["trash"] != "@hourly" {
+		t.Fatalf("Scheduler = %+v", cfg.Scheduler)
+	}
+	if err := cfg.Scheduler.Validate(); err != nil {
+		t.Error(err)
+	}
+	for _, jobs := range []map[string]string{
+		{"inventory": "@daily"},
+		{"canary": " "},
+	} {
+		if err := (SchedulerConfig{Enabled: true, Jobs: jobs}).Validate(); err == nil {
+			t.Errorf("%v accepted", jobs)
+		}
+	}
+}
+
+func TestMemoryBudgetConfig(t *testing.T) {
+	t.Setenv("MEMORY_BUDGET_ENABLED", "1")
+	t.Setenv("MEMORY_BUDGET_LIMIT", "536870912")
+	t.Setenv("MEMORY_BUDGET_MAX_WAIT", "2s")
+	t.Setenv("MEMORY_BUDGET_REQUEST_RESERVE", "131072")
+	cfg := &Config{}
+	loadFromEnv(cfg)
+	want := MemoryBudgetConfig{Enabled: true, Limit: 512 << 20, MaxWait: 2 * time.Second, RequestReserve: 128 << 10}
+	if cfg.MemoryBudget != want {
+		t.Fatalf("MemoryBudget = %+v", cfg.MemoryBudget)
+	}
+	if err := cfg.MemoryBudget.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	tests := []struct {
+		name    string
+		edit    func(*MemoryBudgetConfig)
+		wantErr string
+	}{
+		{"no limit", func(m *MemoryBudgetConfig) { m.Limit = 0 }, "memory_budget.limit"},
+		{"negative wait", func(m *MemoryBudgetConfig) { m.MaxWait = -time.Second }, "memory_budget.max_wait"},
+		{"reserve above limit", func(m *MemoryBudgetConfig) { m.RequestReserve = m.Limit + 1 }, "memory_budget.request_reserve"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := want
+			tt.edit(&m)
+			if err := m.Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAdmissionConfig(t *testing.T) {
+	t.Setenv("ADMISSION_ENABLED", "true")
+	t.Setenv("ADMISSION_MAX_IN_FLIGHT", "32")
+	t.Setenv("ADMISSION_QUEUE_SIZE", "64")
+	t.Setenv("ADMISSION_MAX_WAIT", "500ms")
+	t.Setenv("ADMISSION_RETRY_AFTER", "3s")
+	t.Setenv("ADMISSION_LARGE_REQUEST_SIZE", "1048576")
+	t.Setenv("ADMISSION_PRIORITY_BUCKETS", "critical,billing")
+	t.Setenv("ADMISSION_PRIORITY_ACCESS_KEYS", "AKPRIO")
+	cfg := &Config{}
+	loadFromEnv(cfg)
+	want := AdmissionConfig{
+		Enabled: true, MaxInFlight: 32, QueueSize: 64, MaxWait: 500 * time.Millisecond, RetryAfter: 3 * time.Second, LargeRequestSize: 1 << 20,
+		PriorityBuckets: []string{"critical", "billing"}, PriorityAccessKeys: []string{"AKPRIO"},
+	}
+	if !reflect.DeepEqual(cfg.Admission, want) {
+		t.Fatalf("Admission = %+v", cfg.Admission)
+	}
+	if err := cfg.Admission.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	tests := []struct {
+		name    string
+		edit    func(*AdmissionConfig)
+		wantErr string
+	}{
+		{"negative in flight", func(a *AdmissionConfig) { a.MaxInFlight = -1 }, "admission.max_in_flight"},
+		{"negative queue", func(a *AdmissionConfig) { a.QueueSize = -1 }, "admission.queue_size"},
+		{"negative wait", func(a *AdmissionConfig) { a.MaxWait = -time.Second }, "admission.max_wait"},
+		{"no retry after", func(a *AdmissionConfig) { a.RetryAfter = 0 }, "admission.retry_after"},
+		{"no large size", func(a *AdmissionConfig) { a.LargeRequestSize = 0 }, "admission.large_request_size"},
+		{"empty priority bucket", func(a *AdmissionConfig) { a.PriorityBuckets = []string{"critical", ""} }, "admission.priority_buckets"},
+		{"empty priority key", func(a *AdmissionConfig) { a.PriorityAccessKeys = []string{""} }, "admission.priority_access_keys"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := want
+			tt.edit(&a)
+			if err := a.Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLogSamplingConfig(t *testing.T) {
+	t.Setenv("LOGGING_SAMPLING", "access=100; cache=10:debug")
+	cfg := &Config{}
+	loadFromEnv(cfg)
+	want := map[string]LogSamplingRule{
+		"access": {Every: 100},
+		"cache":  {Every: 10, Level: "debug"},
+	}
+	if !reflect.DeepEqual(cfg.Logging.Sampling, want) {
+		t.Fatalf("Sampling = %+v", cfg.Logging.Sampling)
+	}
+
+	tests := []struct {
+		name    string
+		rule    LogSamplingRule
+		wantErr string
+	}{
+		{"zero", LogSamplingRule{Every: 0}, "every"},
+		{"bad level", LogSamplingRule{Every: 2, Level: "loud"}, "loud"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyManagerCallConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     KeyManagerCallConfig
+		wantErr string
+	}{
+		{name: "zero values use defaults", cfg: KeyManagerCallConfig{}},
+		{name: "explicit", cfg: KeyManagerCallConfig{Timeout: time.Second, MaxAttempts: 2, InitialBackoff: 10 * time.Millisecond, MaxBackoff: 100 * time.Millisecond, RetryBudget: 0.5, RetryBurst: 5}},
+		{name: "too many attempts", cfg: KeyManagerCallConfig{MaxAttempts: 11}, wantErr: "max_attempts"},
+		{name: "max backoff below initial", cfg: KeyManagerCallConfig{InitialBackoff: time.Second, MaxBackoff: time.Millisecond}, wantErr: "max_backoff"},
+		{name: "budget above one retry per call", cfg: KeyManagerCallConfig{RetryBudget: 1.5}, wantErr: "retry_budget"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Encryption.KeyManager = KeyManagerConfig{Enabled: true, Provider: "memory", Calls: tt.cfg}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyVersionWatchConfig_Validate(t *testing.T) {
+	cfg := minValidConfig()
+	cfg.Encryption.KeyManager = KeyManagerConfig{Enabled: true, Provider: "memory"}
+	cfg.Encryption.KeyManager.VersionWatch = KeyVersionWatchConfig{Enabled: true, Interval: 100 * time.Millisecond}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "version_watch.interval") {
+		t.Fatalf("error = %v, want version_watch.interval", err)
+	}
+	cfg.Encryption.KeyManager.VersionWatch.Interval = DefaultKeyVersionWatchInterval
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.Encryption.KeyManager.VersionWatch = KeyVersionWatchConfig{Interval: time.Millisecond}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("disabled watch: unexpected error: %v", err)
+	}
+}
+
+func TestDevModeConfig_CheckBuild(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     DevModeConfig
+		version string
+		wantErr bool
+	}{
+		{name: "disabled release", cfg: DevModeConfig{}, version: "v1.4.0"},
+		{name: "dev build", cfg: DevModeConfig{Enabled: true}, version: "dev"},
+		{name: "commits past a tag", cfg: DevModeConfig{Enabled: true}, version: "v1.4.0-3-gabc1234"},
+		{name: "dirty tree", cfg: DevModeConfig{Enabled: true}, version: "v1.4.0-dirty"},
+		{name: "release", cfg: DevModeConfig{Enabled: true}, version: "v1.4.0", wantErr: true},
+		{name: "release without v", cfg: DevModeConfig{Enabled: true}, version: "1.4.0", wantErr: true},
+		{name: "release with override", cfg: DevModeConfig{Enabled: true, AllowInRelease: true}, version: "v1.4.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.CheckBuild(tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckBuild(%q) = %v, wantErr %v", tt.version, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAirGappedConfig(t *testing.T) {
+	t.Setenv("AIR_GAPPED_ENABLED", "true")
+	t.Setenv("AIR_GAPPED_ALLOW", "otel.internal,*.example.com")
+	cfg := &Config{}
+	loadFromEnv(cfg)
+	if !cfg.AirGapped.Enabled || !slices.Equal(cfg.AirGapped.Allow, []string{"otel.internal", "*.example.com"}) {
+		t.Fatalf("AirGapped = %+v", cfg.AirGapped)
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(*Config)
+		wantErr string
+	}{
+		{name: "backend only", mutate: func(*Config) {}},
+		{name: "default AWS endpoint", mutate: func(c *Config) {
+			c.Backend.Endpoint = ""
+		}, wantErr: "backend.endpoint is required in air-gapped mode"},
+		{name: "unlisted collector", mutate: func(c *Config) {
+			c.Tracing = TracingConfig{Enabled: true, ServiceName: "gw", Exporter: "otlp", OtlpEndpoint: "otel.example.org:4317"}
+		}, wantErr: "tracing.otlp_endpoint connects to otel.example.org"},
+		{name: "listed collector", mutate: func(c *Config) {
+			c.Tracing = TracingConfig{Enabled: true, ServiceName: "gw", Exporter: "otlp", OtlpEndpoint: "otel.example.org:4317"}
+			c.AirGapped.Allow = []string{"otel.example.org"}
+		}},
+		{name: "loopback collector", mutate: func(c *Config) {
+			c.Tracing = TracingConfig{Enabled: true, ServiceName: "gw", Exporter: "otlp", OtlpEndpoint: "localhost:4317"}
+		}},
+		{name: "audit sink under wildcard", mutate: func(c *Config) {
+			c.Audit = AuditConfig{Enabled: true, Sink: SinkConfig{Type: "http", Endpoint: "https://siem.example.com/ingest"}}
+			c.AirGapped.Allow = []string{"*.example.com"}
+		}},
+		{name: "valkey on the backend host", mutate: func(c *Config) {
+			c.MultipartState.Valkey.Addr = "minio.internal:6379"
+		}},
+		{name: "unlisted valkey", mutate: func(c *Config) {
+			c.MultipartState.Valkey.Addr = "valkey.internal:6379"
+		}, wantErr: "multipart_state.valkey.addr"},
+		{name: "valkey on the KMS host", mutate: func(c *Config) {
+			c.Encryption.KeyManager = KeyManagerConfig{Enabled: true, Provider: "cosmian", Cosmian: CosmianConfig{
+				Endpoint: "https://kms.internal:9998", Keys: []CosmianKeyReference{{ID: "k1", Version: 1}},
+			}}
+			c.MultipartState.Valkey.Addr = "kms.internal:6379"
+		}},
+		{name: "empty allow entry", mutate: func(c *Config) {
+			c.AirGapped.Allow = []string{" "}
+		}, wantErr: "air_gapped.allow[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Backend.Endpoint = "http://minio.internal:9000"
+			cfg.AirGapped.Enabled = true
+			tt.mutate(cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestConfig_AirGappedHosts(t *testing.T) {
+	cfg := minValidConfig()
+	cfg.Backend.Endpoint = "https://s3.internal"
+	cfg.Backend.UseSSL = true
+	cfg.Backend.Proxy = "http://proxy.internal:3128"
+	cfg.AirGapped.Allow = []string{"otel.internal"}
+	got := cfg.AirGappedHosts()
+	want := []string{"otel.internal", "https://s3.internal", "*.s3.internal", "http://proxy.internal:3128"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("AirGappedHosts() = %q, want %q", got, want)
+	}
+}
+
+func TestAuthReplayProtectionConfig(t *testing.T) {
+	t.Setenv("AUTH_REPLAY_PROTECTION_ENABLED", "true")
+	t.Setenv("AUTH_REPLAY_PROTECTION_CACHE_SIZE", "5000")
+	cfg := &Config{}
+	loadFromEnv(cfg)
+	if !cfg.Auth.ReplayProtection.Enabled || cfg.Auth.ReplayProtection.CacheSize != 5000 {
+		t.Fatalf("ReplayProtection = %+v", cfg.Auth.ReplayProtection)
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(*Config)
+		wantErr string
+	}{
+		{name: "enabled with default size", mutate: func(c *Config) {
+			c.Auth.ReplayProtection.Enabled = true
+		}},
+		{name: "negative cache size", mutate: func(c *Config) {
+			c.Auth.ReplayProtection = AuthReplayProtectionConfig{Enabled: true, CacheSize: -1}
+		}, wantErr: "auth.replay_protection.cache_size"},
+		{name: "negative clock skew", mutate: func(c *Config) {
+			c.Auth.ClockSkewTolerance = -time.Minute
+		}, wantErr: "auth.clock_skew_tolerance"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			tt.mutate(cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestExtensionsConfig(t *testing.T) {
+	t.Setenv("EXTENSIONS_ENABLED", "true")
+	cfg := &Config{}
+	loadFromEnv(cfg)
+	if !cfg.Extensions.Enabled {
+		t.Fatal("EXTENSIONS_ENABLED not applied")
+	}
+
+	module := ExtensionModule{Name: "redact", Path: "/etc/s3eg/redact.wasm", Hooks: []string{ExtensionHookPolicy}}
+	tests := []struct {
+		name    string
+		mutate  func(*Config)
+		wantErr string
+	}{
+		{name: "valid", mutate: func(*Config) {}},
+		{name: "no modules", mutate: func(c *Config) {
+			c.Extensions.Modules = nil
+		}, wantErr: "extensions.modules must contain"},
+		{name: "duplicate name", mutate: func(c *Config) {
+			c.Extensions.Modules = append(c.Extensions.Modules, module)
+		}, wantErr: "duplicate module name"},
+		{name: "no path", mutate: func(c *Config) {
+			c.Extensions.Modules[0].Path = ""
+		}, wantErr: "extensions.modules[redact].path"},
+		{name: "unknown hook", mutate: func(c *Config) {
+			c.Extensions.Modules[0].Hooks = []string{"filter"}
+		}, wantErr: "extensions.modules[redact].hooks"},
+		{name: "memory over 4 GiB", mutate: func(c *Config) {
+			c.Extensions.Modules[0].MemoryPages = 65537
+		}, wantErr: "memory_pages"},
+		{name: "transform rule on module", mutate: func(c *Config) {
+			c.Transforms = TransformsConfig{Enabled: true, MaxOutputSize: 1 << 20, Rules: []TransformRule{{Name: "r", Bucket: "b", Module: "redact"}}}
+		}},
+		{name: "transform rule on unknown module", mutate: func(c *Config) {
+			c.Transforms = TransformsConfig{Enabled: true, MaxOutputSize: 1 << 20, Rules: []TransformRule{{Name: "r", Bucket: "b", Module: "resize"}}}
+		}, wantErr: `transforms.rules[r].module "resize"`},
+		{name: "transform rule with url and module", mutate: func(c *Config) {
+			c.Transforms = TransformsConfig{Enabled: true, MaxOutputSize: 1 << 20, Rules: []TransformRule{{Name: "r", Bucket: "b", Module: "redact", URL: "http://svc"}}}
+		}, wantErr: "set url or module"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := minValidConfig()
+			cfg.Extensions = ExtensionsConfig{Enabled: true, Modules: []ExtensionModule{module}}
+			tt.mutate(cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -30,6 +30,10 @@ type PolicyConfig struct {
 	// Default is true (nil pointer = unset = enabled). Set explicitly to
 	// false to opt a bucket out.
 	EncryptMultipartUploads *bool `yaml:"encrypt_multipart_uploads,omitempty"`
+	// ScanAction overrides scanning.action for matching buckets: "block",
+	// "quarantine", or "off" to skip malware scanning. Empty inherits the
+	// global setting.
+	ScanAction string `yaml:"scan_action,omitempty"`
 }
 
 // PolicyManager manages loading and matching policies
@@ -76,6 +80,11 @@ func (pm *PolicyManager) LoadPolicies(patterns []string) error {
 			if len(policy.Buckets) == 0 {
 				return fmt.Errorf("policy %s must specify at least one bucket pattern", policy.ID)
 			}
+			switch policy.ScanAction {
+			case "", ScanActionBlock, ScanActionQuarantine, ScanActionOff:
+			default:
+				return fmt.Errorf("policy %s: scan_action must be one of: block, quarantine, off (got %q)", policy.ID, policy.ScanAction)
+			}
 
 			pm.policies = append(pm.policies, &policy)
 		}
@@ -193,6 +202,19 @@ func (pm *PolicyManager) AnyPolicyRequiresMPUEncryption() bool {
 	return false
 }
 
+// BucketScanAction returns the malware scanning action for bucket: the
+// matching policy's scan_action, or def when no policy sets one.
+func (pm *PolicyManager) BucketScanAction(bucket, def string) string {
+	if pm == nil {
+		return def
+	}
+	policy := pm.GetPolicyForBucket(bucket)
+	if policy == nil || policy.ScanAction == "" {
+		return def
+	}
+	return policy.ScanAction
+}
+
 // BucketDisallowsLockBypass returns true if the bucket policy disallows lock bypass.
 func (pm *PolicyManager) BucketDisallowsLockBypass(bucket string) bool {
 	pm.mu.RLock()
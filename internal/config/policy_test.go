@@ -237,11 +237,30 @@ disallow_lock_bypass: true
 // explicit nil guards do not panic. GetPolicyForBucket is not nil-safe by
 // design (it acquires a lock), so it is excluded from this test.
 // BucketEncryptsMultipart returns true on a nil manager (safe default: encrypt).
+func TestBucketScanAction(t *testing.T) {
+	tmpDir := t.TempDir()
+	for name, content := range map[string]string{
+		"uploads.yaml": "id: uploads\nbuckets: [\"uploads-*\"]\nscan_action: quarantine\n",
+		"backups.yaml": "id: backups\nbuckets: [\"backups\"]\nscan_action: \"off\"\n",
+		"other.yaml":   "id: other\nbuckets: [\"other\"]\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644))
+	}
+	pm := NewPolicyManager()
+	require.NoError(t, pm.LoadPolicies([]string{filepath.Join(tmpDir, "*.yaml")}))
+
+	assert.Equal(t, ScanActionQuarantine, pm.BucketScanAction("uploads-eu", ScanActionBlock))
+	assert.Equal(t, ScanActionOff, pm.BucketScanAction("backups", ScanActionBlock))
+	assert.Equal(t, ScanActionBlock, pm.BucketScanAction("other", ScanActionBlock))
+	assert.Equal(t, ScanActionBlock, pm.BucketScanAction("unmatched", ScanActionBlock))
+}
+
 func TestPolicyManager_NilSafe(t *testing.T) {
 	var pm *PolicyManager
 	assert.False(t, pm.BucketRequiresEncryption("any-bucket"))
 	assert.True(t, pm.BucketEncryptsMultipart("any-bucket")) // default-on: nil manager = encrypt
 	assert.False(t, pm.AnyPolicyRequiresMPUEncryption())
+	assert.Equal(t, ScanActionBlock, pm.BucketScanAction("any-bucket", ScanActionBlock))
 }
 
 // TestLoadPolicies_MissingRequiredFields verifies that policies with missing
@@ -262,6 +281,15 @@ buckets:
 			name: "missing buckets",
 			content: `
 id: "test-policy"
+`,
+		},
+		{
+			name: "unknown scan action",
+			content: `
+id: "test-policy"
+buckets:
+  - "test-bucket"
+scan_action: "delete"
 `,
 		},
 	}
@@ -91,6 +91,9 @@ func getNonceSize(algorithm string) (int, error) {
 	case AlgorithmChaCha20Poly1305:
 		return chacha20NonceSize, nil
 	default:
+		if _, ok := lookupCipher(algorithm); ok {
+			return nonceSize, nil
+		}
 		return 0, fmt.Errorf("unsupported algorithm: %s", algorithm)
 	}
 }
@@ -99,7 +102,11 @@ func getNonceSize(algorithm string) (int, error) {
 func isAlgorithmSupported(algorithm string, supported []string) bool {
 	if len(supported) == 0 {
 		// If no supported list, allow all known algorithms
-		return algorithm == AlgorithmAES256GCM || algorithm == AlgorithmChaCha20Poly1305
+		if algorithm == AlgorithmAES256GCM || algorithm == AlgorithmChaCha20Poly1305 {
+			return true
+		}
+		_, ok := lookupCipher(algorithm)
+		return ok
 	}
 
 	for _, alg := range supported {
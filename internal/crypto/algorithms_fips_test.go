@@ -29,6 +29,15 @@ func TestChaCha20Rejected(t *testing.T) {
 	}
 }
 
+// TestCustomCipherRequiresFIPSApproval verifies that a registered cipher
+// not marked FIPSApproved cannot be used in FIPS mode.
+func TestCustomCipherRequiresFIPSApproval(t *testing.T) {
+	_, err := createAEADCipher(testCipherName, make([]byte, aesKeySize))
+	if !errors.Is(err, ErrAlgorithmNotApproved) {
+		t.Errorf("expected ErrAlgorithmNotApproved, got %v", err)
+	}
+}
+
 // TestAESGCMApproved verifies that AES-256-GCM works in FIPS mode.
 func TestAESGCMApproved(t *testing.T) {
 	key := make([]byte, aesKeySize)
@@ -0,0 +1,138 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// MetaBodyHeader records that the object body starts with a body header and
+// holds the offset of the ciphertext that follows it.
+const MetaBodyHeader = "x-amz-meta-encryption-body-header"
+
+// A body header makes an object decryptable from its body alone, for copies
+// that lose their user metadata on the way to another store. It is written
+// in front of the ciphertext, in addition to the metadata:
+//
+//	magic       4 bytes  "S3EG"
+//	version     1 byte   BodyHeaderVersion
+//	flags       1 byte   reserved, zero
+//	data offset 4 bytes  big-endian offset of the ciphertext in the body
+//	manifest    JSON object of the encryption metadata, chunk manifest
+//	            included, up to the data offset
+//
+// The manifest is a snapshot taken when the object is written. Key rotation
+// rewraps data keys in the object metadata only, so a header keeps the
+// wrapped key it was written with.
+var bodyHeaderMagic = [4]byte{'S', '3', 'E', 'G'}
+
+const (
+	bodyHeaderFixedLen = 10
+	// maxBodyHeaderLen bounds the manifest read from an untrusted body.
+	maxBodyHeaderLen = 1 << 20
+)
+
+// ErrNoBodyHeader is returned by ReadBodyHeader when the body does not
+// start with a body header.
+var ErrNoBodyHeader = errors.New("crypto: object body has no header")
+
+// encodeBodyHeader returns the body header recording metadata's encryption
+// and compression entries.
+func encodeBodyHeader(metadata map[string]string) ([]byte, error) {
+	manifest := make(map[string]string)
+	for k, v := range metadata {
+		if IsEncryptionMetadata(k) || IsCompressionMetadata(k) {
+			manifest[k] = v
+		}
+	}
+	manifestJSON, err := encodeMetadataToJSON(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode body header: %w", err)
+	}
+	n := bodyHeaderFixedLen + len(manifestJSON)
+	if n > maxBodyHeaderLen {
+		return nil, fmt.Errorf("body header of %d bytes exceeds limit %d", n, maxBodyHeaderLen)
+	}
+	header := make([]byte, bodyHeaderFixedLen, n)
+	copy(header, bodyHeaderMagic[:])
+	header[4] = BodyHeaderVersion
+	binary.BigEndian.PutUint32(header[6:], uint32(n))
+	return append(header, manifestJSON...), nil
+}
+
+// ReadBodyHeader reads the body header at the start of r and returns the
+// encryption metadata it records. r is left at the ciphertext, which
+// decrypts with that metadata. Offline tools use it to recover objects
+// whose metadata was stripped.
+func ReadBodyHeader(r io.Reader) (map[string]string, error) {
+	var fixed [bodyHeaderFixedLen]byte
+	if _, err := io.ReadFull(r, fixed[:]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrNoBodyHeader
+		}
+		return nil, fmt.Errorf("body header: %w", err)
+	}
+	if !bytes.Equal(fixed[:4], bodyHeaderMagic[:]) {
+		return nil, ErrNoBodyHeader
+	}
+	if err := checkFormatVersion(FormatBodyHeader, int(fixed[4])); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(fixed[6:])
+	if n < bodyHeaderFixedLen || n > maxBodyHeaderLen {
+		return nil, fmt.Errorf("body header: invalid data offset %d", n)
+	}
+	manifestJSON := make([]byte, n-bodyHeaderFixedLen)
+	if _, err := io.ReadFull(r, manifestJSON); err != nil {
+		return nil, fmt.Errorf("body header: failed to read manifest (%d bytes): %w", len(manifestJSON), err)
+	}
+	metadata, err := decodeMetadataFromJSON(manifestJSON)
+	if err != nil {
+		return nil, fmt.Errorf("body header: %w", err)
+	}
+	return metadata, nil
+}
+
+// BodyHeaderLength returns the length of the body header recorded in
+// metadata, full or compacted, or 0 when the object has none.
+func BodyHeaderLength(metadata map[string]string) int64 {
+	v := metadata[MetaBodyHeader]
+	if v == "" {
+		v = metadata["x-amz-meta-bh"]
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < bodyHeaderFixedLen {
+		return 0
+	}
+	return n
+}
+
+// skipBodyHeader consumes the body header of an object whose metadata
+// records one, checking that it is where the metadata says.
+func skipBodyHeader(r io.Reader, metadata map[string]string) error {
+	n := BodyHeaderLength(metadata)
+	if n == 0 {
+		return nil
+	}
+	var fixed [bodyHeaderFixedLen]byte
+	if _, err := io.ReadFull(r, fixed[:]); err != nil {
+		return fmt.Errorf("body header: %w", err)
+	}
+	if !bytes.Equal(fixed[:4], bodyHeaderMagic[:]) || int64(binary.BigEndian.Uint32(fixed[6:])) != n {
+		return fmt.Errorf("body header: does not match object metadata")
+	}
+	if _, err := io.CopyN(io.Discard, r, n-bodyHeaderFixedLen); err != nil {
+		return fmt.Errorf("body header: %w", err)
+	}
+	return nil
+}
+
+// CiphertextOffsetter is implemented by readers that hold an object's body
+// from some offset onwards, such as the body of a ranged backend GET.
+// DecryptRange reads any other reader as the body from its first byte.
+type CiphertextOffsetter interface {
+	CiphertextOffset() int64
+}
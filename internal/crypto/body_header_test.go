@@ -0,0 +1,150 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+)
+
+type offsetReader struct {
+	io.Reader
+	offset int64
+}
+
+func (r offsetReader) CiphertextOffset() int64 { return r.offset }
+
+func encryptWithBodyHeader(t *testing.T, plaintext []byte) (EncryptionEngine, []byte, map[string]string) {
+	t.Helper()
+	engine, err := NewEngineWithOpts([]byte("test-password-12345"), nil,
+		WithChunking(true), WithChunkSize(MinChunkSize), WithBodyHeader(true))
+	if err != nil {
+		t.Fatalf("NewEngineWithOpts() error: %v", err)
+	}
+	r, meta, err := engine.Encrypt(context.Background(), bytes.NewReader(plaintext), map[string]string{
+		"Content-Length": strconv.Itoa(len(plaintext)),
+	})
+	if err != nil {
+		t.Fatalf("Encrypt() error: %v", err)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading ciphertext: %v", err)
+	}
+	return engine, body, meta
+}
+
+func TestBodyHeader_RoundTrip(t *testing.T) {
+	plaintext := bytes.Repeat([]byte("0123456789"), 8000)
+	engine, body, meta := encryptWithBodyHeader(t, plaintext)
+
+	headerLen := BodyHeaderLength(meta)
+	if headerLen == 0 {
+		t.Fatalf("metadata does not record a body header: %v", meta)
+	}
+	if !bytes.HasPrefix(body, []byte("S3EG")) || body[4] != BodyHeaderVersion {
+		t.Fatalf("body starts with %q", body[:5])
+	}
+	if want := headerLen + int64(len(plaintext)) + 5*tagSize; int64(len(body)) != want {
+		t.Errorf("body length = %d, want %d", len(body), want)
+	}
+
+	dec, _, err := engine.Decrypt(context.Background(), bytes.NewReader(body), meta)
+	if err != nil {
+		t.Fatalf("Decrypt() error: %v", err)
+	}
+	if got, _ := io.ReadAll(dec); !bytes.Equal(got, plaintext) {
+		t.Error("Decrypt() does not return the plaintext")
+	}
+
+	// With the metadata gone the header alone is enough.
+	src := bytes.NewReader(body)
+	recovered, err := ReadBodyHeader(src)
+	if err != nil {
+		t.Fatalf("ReadBodyHeader() error: %v", err)
+	}
+	if _, ok := recovered[MetaBodyHeader]; ok {
+		t.Error("recovered metadata claims a header the reader has already consumed")
+	}
+	dec, _, err = engine.Decrypt(context.Background(), src, recovered)
+	if err != nil {
+		t.Fatalf("Decrypt() with recovered metadata error: %v", err)
+	}
+	if got, _ := io.ReadAll(dec); !bytes.Equal(got, plaintext) {
+		t.Error("Decrypt() with recovered metadata does not return the plaintext")
+	}
+}
+
+func TestBodyHeader_Range(t *testing.T) {
+	plaintext := make([]byte, 80000)
+	for i := range plaintext {
+		plaintext[i] = byte(i)
+	}
+	engine, body, meta := encryptWithBodyHeader(t, plaintext)
+	start, end := int64(2*MinChunkSize+100), int64(4*MinChunkSize-1)
+
+	encStart, encEnd, err := CalculateEncryptedRangeForPlaintextRange(meta, start, end)
+	if err != nil {
+		t.Fatalf("CalculateEncryptedRangeForPlaintextRange() error: %v", err)
+	}
+	if want := BodyHeaderLength(meta) + 2*(MinChunkSize+tagSize); encStart != want {
+		t.Errorf("encrypted range starts at %d, want %d", encStart, want)
+	}
+
+	for name, src := range map[string]io.Reader{
+		"whole body":  bytes.NewReader(body),
+		"ranged body": offsetReader{bytes.NewReader(body[encStart : encEnd+1]), encStart},
+	} {
+		dec, _, err := engine.DecryptRange(context.Background(), src, meta, start, end)
+		if err != nil {
+			t.Errorf("%s: DecryptRange() error: %v", name, err)
+			continue
+		}
+		if got, _ := io.ReadAll(dec); !bytes.Equal(got, plaintext[start:end+1]) {
+			t.Errorf("%s: DecryptRange() returned the wrong bytes", name)
+		}
+	}
+}
+
+func TestBodyHeader_Compacted(t *testing.T) {
+	meta := map[string]string{MetaEncrypted: "true", MetaBodyHeader: "321"}
+	compactor := NewMetadataCompactor(&ProviderProfile{CompactionStrategy: "base64url"})
+	compacted, err := compactor.CompactMetadata(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if BodyHeaderLength(compacted) != 321 {
+		t.Errorf("compacted metadata %v loses the header length", compacted)
+	}
+	expanded, err := compactor.ExpandMetadata(compacted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expanded[MetaBodyHeader] != "321" {
+		t.Errorf("expanded metadata %v", expanded)
+	}
+}
+
+func TestReadBodyHeader_Errors(t *testing.T) {
+	if _, err := ReadBodyHeader(bytes.NewReader([]byte("plain object body"))); !errors.Is(err, ErrNoBodyHeader) {
+		t.Errorf("no header: error = %v, want ErrNoBodyHeader", err)
+	}
+	if _, err := ReadBodyHeader(bytes.NewReader([]byte("S3"))); !errors.Is(err, ErrNoBodyHeader) {
+		t.Errorf("short body: error = %v, want ErrNoBodyHeader", err)
+	}
+
+	header, err := encodeBodyHeader(map[string]string{MetaEncrypted: "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header[4] = BodyHeaderVersion + 1
+	if _, err := ReadBodyHeader(bytes.NewReader(header)); !errors.Is(err, ErrFormatTooNew) {
+		t.Errorf("newer version: error = %v, want ErrFormatTooNew", err)
+	}
+	header[4] = BodyHeaderVersion
+	if _, err := ReadBodyHeader(bytes.NewReader(header[:len(header)-1])); err == nil {
+		t.Error("truncated manifest: no error")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"runtime"
 	"sync"
 
+	"github.com/kenneth/s3-encryption-gateway/internal/devtrace"
 	"golang.org/x/crypto/hkdf"
 )
 
@@ -37,12 +38,40 @@ const (
 // It stores the IV for each chunk, allowing decryption without reading
 // the entire object first.
 type ChunkManifest struct {
-	Version      int      `json:"v"` // Format version (currently 1)
+	Version      int      `json:"v"` // Format version (currently 2)
 	ChunkSize    int      `json:"cs"` // Size of each chunk in bytes
 	ChunkCount   int      `json:"cc"` // Number of chunks
 	BaseIV       string   `json:"iv"` // Base64-encoded base IV (for IV derivation)
 	IVs          []string `json:"ivs,omitempty"` // Optional: explicit IVs per chunk (if baseIV not used)
 	IVDerivation string   `json:"ivd,omitempty"` // IV derivation method: "hkdf-sha256" or "" (legacy XOR)
+
+	// Version 2: the exact plaintext length and the plaintext bytes in the
+	// last chunk. Set only when the size is known before the body is
+	// encrypted; zero otherwise.
+	PlaintextSize int64 `json:"ps,omitempty"`
+	LastChunkSize int   `json:"lcs,omitempty"`
+}
+
+// recordPlaintextSize fills in the version 2 size fields for an object of
+// size plaintext bytes.
+func (m *ChunkManifest) recordPlaintextSize(size int64) {
+	if size <= 0 || m.ChunkSize <= 0 {
+		return
+	}
+	m.PlaintextSize = size
+	m.LastChunkSize = int(size - (size-1)/int64(m.ChunkSize)*int64(m.ChunkSize))
+}
+
+// chunkCountForStoredLength returns how many chunks a chunked body of
+// length bytes holds, given that every chunk but the last is
+// chunkSize+tagSize bytes. ok is false when length cannot be such a body.
+func chunkCountForStoredLength(length, chunkSize int64) (count int64, ok bool) {
+	encChunk := chunkSize + tagSize
+	if length <= 0 || chunkSize <= 0 {
+		return 0, false
+	}
+	count = (length + encChunk - 1) / encChunk
+	return count, length-(count-1)*encChunk > tagSize
 }
 
 // chunkedEncryptReader implements streaming encryption in chunks.
@@ -61,6 +90,7 @@ type chunkedEncryptReader struct {
 	closed       bool
 	err          error
 	ctx          context.Context // Context for cancellation
+	trace        *devtrace.Trace
 
 	// Parallel processing
 	parallel   bool
@@ -97,7 +127,7 @@ func newChunkedEncryptReaderWithContext(ctx context.Context, source io.Reader, a
 	}
 
 	manifest := &ChunkManifest{
-		Version:      1,
+		Version:      ChunkManifestVersion,
 		ChunkSize:    chunkSize,
 		BaseIV:       encodeBase64(baseIV),
 		IVDerivation: "hkdf-sha256",
@@ -114,6 +144,7 @@ func newChunkedEncryptReaderWithContext(ctx context.Context, source io.Reader, a
 		manifest:     manifest,
 		bufferPool:   bufferPool,
 		ctx:          ctx,
+		trace:        devtrace.From(ctx),
 		parallel:     true,
 	}, manifest
 }
@@ -334,6 +365,7 @@ func (r *chunkedEncryptReader) encryptChunkParallel(index int, plaintext, outBuf
 
 	// Derive IV for this chunk
 	chunkIV := r.deriveChunkIV(index)
+	traceChunk(r.trace, "encrypt_chunk", r.manifest, r.baseIV, chunkIV, index, len(plaintext), len(plaintext)+r.aead.Overhead())
 
 	// Encrypt the chunk
 	// Seal appends to dst. Use outBuf if provided.
@@ -361,6 +393,7 @@ type chunkedDecryptReader struct {
 	closed       bool
 	err          error
 	ctx          context.Context // Context for cancellation
+	trace        *devtrace.Trace
 
 	// Parallel processing
 	parallel   bool
@@ -395,6 +428,7 @@ func newChunkedDecryptReaderWithContext(ctx context.Context, source io.Reader, a
 		chunkIndex:   0,
 		bufferPool:   bufferPool,
 		ctx:          ctx,
+		trace:        devtrace.From(ctx),
 		parallel:     true,
 	}, nil
 }
@@ -613,7 +647,12 @@ func (r *chunkedDecryptReader) decryptChunkParallel(index int, ciphertext, outBu
 	}
 
 	chunkIV := r.deriveChunkIV(index)
-	return r.aead.Open(outBuf, chunkIV, ciphertext, nil)
+	traceChunk(r.trace, "decrypt_chunk", r.manifest, r.baseIV, chunkIV, index, len(ciphertext)-r.aead.Overhead(), len(ciphertext))
+	plaintext, err := r.aead.Open(outBuf, chunkIV, ciphertext, nil)
+	if err != nil {
+		return nil, integrityError(err)
+	}
+	return plaintext, nil
 }
 
 // Close finalizes the decryption.
@@ -635,12 +674,15 @@ func encodeManifest(manifest *ChunkManifest) (string, error) {
 func decodeManifest(encoded string) (*ChunkManifest, error) {
 	data, err := decodeBase64(encoded)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+		return nil, fmt.Errorf("%w: decode: %w", ErrManifestCorrupt, err)
 	}
 
 	var manifest ChunkManifest
 	if err := json.Unmarshal(data, &manifest); err != nil {
-		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		return nil, fmt.Errorf("%w: parse: %w", ErrManifestCorrupt, err)
+	}
+	if err := checkFormatVersion(FormatChunkManifest, manifest.Version); err != nil {
+		return nil, err
 	}
 
 	return &manifest, nil
@@ -664,7 +706,7 @@ func isChunkedFormat(metadata map[string]string) bool {
 func loadManifestFromMetadata(metadata map[string]string) (*ChunkManifest, error) {
 	manifestEncoded, ok := metadata[MetaManifest]
 	if !ok {
-		return nil, fmt.Errorf("manifest not found in metadata")
+		return nil, fmt.Errorf("%w: manifest not found in metadata", ErrManifestCorrupt)
 	}
 
 	return decodeManifest(manifestEncoded)
@@ -796,3 +796,54 @@ func TestChunkedEngineDecrypt_LegacyObject(t *testing.T) {
 		t.Errorf("Full-engine legacy-object round-trip failed: lengths %d vs %d", len(plaintext), len(decryptedData))
 	}
 }
+
+func TestChunkedEncrypt_ExactPlaintextSize(t *testing.T) {
+	engine, err := NewEngineWithChunking([]byte("test-password-12345"), nil, "", nil, true, MinChunkSize)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	ctx := context.Background()
+	const size = 2*MinChunkSize + 123
+
+	for _, known := range []bool{true, false} {
+		meta := map[string]string{}
+		if known {
+			meta["Content-Length"] = fmt.Sprint(size)
+		}
+		r, encMeta, err := engine.Encrypt(ctx, bytes.NewReader(make([]byte, size)), meta)
+		if err != nil {
+			t.Fatalf("Encrypt() error: %v", err)
+		}
+		ct, _ := io.ReadAll(r)
+
+		manifest, err := decodeManifest(encMeta[MetaManifest])
+		if err != nil {
+			t.Fatalf("decodeManifest() error: %v", err)
+		}
+		if manifest.Version != 2 {
+			t.Errorf("known=%v: manifest version = %d, want 2", known, manifest.Version)
+		}
+		wantPS, wantLCS := int64(0), 0
+		if known {
+			wantPS, wantLCS = size, 123
+		}
+		if manifest.PlaintextSize != wantPS || manifest.LastChunkSize != wantLCS {
+			t.Errorf("known=%v: manifest sizes = %d/%d, want %d/%d", known, manifest.PlaintextSize, manifest.LastChunkSize, wantPS, wantLCS)
+		}
+
+		// As returned by GetObject: the stored length is all a size-less
+		// object has to go on.
+		stored := make(map[string]string, len(encMeta)+1)
+		for k, v := range encMeta {
+			stored[k] = v
+		}
+		stored["Content-Length"] = fmt.Sprint(len(ct))
+		_, decMeta, err := engine.Decrypt(ctx, bytes.NewReader(ct), stored)
+		if err != nil {
+			t.Fatalf("Decrypt() error: %v", err)
+		}
+		if decMeta["Content-Length"] != fmt.Sprint(size) {
+			t.Errorf("known=%v: Content-Length = %q, want %d", known, decMeta["Content-Length"], size)
+		}
+	}
+}
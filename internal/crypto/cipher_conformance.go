@@ -0,0 +1,244 @@
+package crypto
+
+// CipherConformanceSuite runs a shared contract test suite against a cipher
+// registered with [RegisterCipher]. Like [ConformanceSuite] it lives outside
+// a _test.go file so that the package registering the cipher can run it:
+//
+//	func TestSM4GCM(t *testing.T) {
+//	    crypto.CipherConformanceSuite(t, "SM4-GCM")
+//	}
+//
+// The suite verifies:
+//   - The AEAD uses the 12-byte nonce and 16-byte tag the object format needs.
+//   - Seal → Open round-trips, with and without additional data.
+//   - Open rejects a modified ciphertext, a wrong key, a wrong nonce and
+//     different additional data.
+//   - Objects round-trip through the engine in single-part and chunked
+//     format, record the algorithm by name, and serve ranged reads.
+//   - 64 concurrent goroutines can Seal/Open with one AEAD (run with -race).
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// CipherConformanceSuite runs the full cipher conformance suite for the
+// algorithm registered under name. Built-in algorithms pass it too.
+func CipherConformanceSuite(t *testing.T, name string) {
+	t.Helper()
+
+	if _, err := getNonceSize(name); err != nil {
+		t.Fatalf("algorithm %q is not registered: %v", name, err)
+	}
+
+	t.Run("FormatSizes", func(t *testing.T) {
+		c := newConformanceCipher(t, name, conformanceKey(t))
+		if c.NonceSize() != nonceSize {
+			t.Errorf("NonceSize() = %d, want %d", c.NonceSize(), nonceSize)
+		}
+		if c.Overhead() != tagSize {
+			t.Errorf("Overhead() = %d, want %d", c.Overhead(), tagSize)
+		}
+		if c.Algorithm() != name {
+			t.Errorf("Algorithm() = %q, want %q", c.Algorithm(), name)
+		}
+	})
+	t.Run("SealOpenRoundTrip", func(t *testing.T) {
+		conformanceSealOpen(t, name)
+	})
+	t.Run("OpenRejectsTampering", func(t *testing.T) {
+		conformanceTampering(t, name)
+	})
+	t.Run("EngineRoundTrip", func(t *testing.T) {
+		conformanceEngineRoundTrip(t, name)
+	})
+	t.Run("ConcurrentSealOpen", func(t *testing.T) {
+		conformanceConcurrentSealOpen(t, name)
+	})
+}
+
+// ---- individual sub-tests ---------------------------------------------------
+
+func conformanceSealOpen(t *testing.T, name string) {
+	t.Helper()
+	c := newConformanceCipher(t, name, conformanceKey(t))
+	for _, tc := range []struct {
+		label     string
+		plaintext []byte
+		aad       []byte
+	}{
+		{"empty", nil, nil},
+		{"short", []byte("s3 encryption gateway"), nil},
+		{"with-aad", bytes.Repeat([]byte{0xa5}, 4096), []byte("object-key")},
+	} {
+		t.Run(tc.label, func(t *testing.T) {
+			nonce := conformanceNonce(t)
+			sealed := c.Seal(nil, nonce, tc.plaintext, tc.aad)
+			if len(sealed) != len(tc.plaintext)+tagSize {
+				t.Fatalf("Seal() returned %d bytes, want %d", len(sealed), len(tc.plaintext)+tagSize)
+			}
+			opened, err := c.Open(nil, nonce, sealed, tc.aad)
+			if err != nil {
+				t.Fatalf("Open(): %v", err)
+			}
+			if !bytes.Equal(opened, tc.plaintext) {
+				t.Fatal("Open() does not return the sealed plaintext")
+			}
+		})
+	}
+}
+
+func conformanceTampering(t *testing.T, name string) {
+	t.Helper()
+	key := conformanceKey(t)
+	c := newConformanceCipher(t, name, key)
+	nonce := conformanceNonce(t)
+	aad := []byte("object-key")
+	sealed := c.Seal(nil, nonce, []byte("authenticated payload"), aad)
+
+	modified := append([]byte(nil), sealed...)
+	modified[0] ^= 0x01
+	if _, err := c.Open(nil, nonce, modified, aad); err == nil {
+		t.Error("Open() accepted a modified ciphertext")
+	}
+
+	other := newConformanceCipher(t, name, conformanceKey(t))
+	if _, err := other.Open(nil, nonce, sealed, aad); err == nil {
+		t.Error("Open() accepted a ciphertext sealed with another key")
+	}
+
+	if _, err := c.Open(nil, conformanceNonce(t), sealed, aad); err == nil {
+		t.Error("Open() accepted a wrong nonce")
+	}
+
+	if _, err := c.Open(nil, nonce, sealed, []byte("other-key")); err == nil {
+		t.Error("Open() accepted different additional data")
+	}
+}
+
+func conformanceEngineRoundTrip(t *testing.T, name string) {
+	t.Helper()
+	plaintext := make([]byte, 3*MinChunkSize+1000)
+	if _, err := rand.Read(plaintext); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, chunked := range []bool{false, true} {
+		t.Run("chunked="+strconv.FormatBool(chunked), func(t *testing.T) {
+			ctx := context.Background()
+			engine, err := NewEngineWithOpts([]byte("conformance-password-123"), nil,
+				WithPreferredAlgorithm(name),
+				WithSupportedAlgorithms([]string{name}),
+				WithChunking(chunked),
+				WithChunkSize(MinChunkSize))
+			if err != nil {
+				t.Fatalf("NewEngineWithOpts(): %v", err)
+			}
+
+			r, meta, err := engine.Encrypt(ctx, bytes.NewReader(plaintext), map[string]string{
+				"Content-Length": strconv.Itoa(len(plaintext)),
+			})
+			if err != nil {
+				t.Fatalf("Encrypt(): %v", err)
+			}
+			body, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("reading ciphertext: %v", err)
+			}
+			if bytes.Contains(body, plaintext[:64]) {
+				t.Fatal("ciphertext contains the plaintext")
+			}
+			expanded, err := NewMetadataCompactor(GetProviderProfile("default")).ExpandMetadata(meta)
+			if err != nil {
+				t.Fatalf("ExpandMetadata(): %v", err)
+			}
+			if got := expanded[MetaAlgorithm]; got != name {
+				t.Errorf("object records algorithm %q, want %q", got, name)
+			}
+
+			dec, _, err := engine.Decrypt(ctx, bytes.NewReader(body), meta)
+			if err != nil {
+				t.Fatalf("Decrypt(): %v", err)
+			}
+			if got, err := io.ReadAll(dec); err != nil || !bytes.Equal(got, plaintext) {
+				t.Fatalf("Decrypt() does not return the plaintext (err=%v)", err)
+			}
+
+			if !chunked {
+				return
+			}
+			start, end := int64(MinChunkSize+100), int64(2*MinChunkSize+99)
+			dec, _, err = engine.DecryptRange(ctx, bytes.NewReader(body), meta, start, end)
+			if err != nil {
+				t.Fatalf("DecryptRange(): %v", err)
+			}
+			if got, err := io.ReadAll(dec); err != nil || !bytes.Equal(got, plaintext[start:end+1]) {
+				t.Fatalf("DecryptRange() returned the wrong bytes (err=%v)", err)
+			}
+		})
+	}
+}
+
+func conformanceConcurrentSealOpen(t *testing.T, name string) {
+	t.Helper()
+	c := newConformanceCipher(t, name, conformanceKey(t))
+
+	const n = 64
+	var wg sync.WaitGroup
+	wg.Add(n)
+	failed := make([]bool, n)
+	for i := 0; i < n; i++ {
+		i := i
+		go func() {
+			defer wg.Done()
+			plaintext := bytes.Repeat([]byte{byte(i)}, 1024)
+			nonce := make([]byte, nonceSize)
+			if _, err := rand.Read(nonce); err != nil {
+				failed[i] = true
+				return
+			}
+			opened, err := c.Open(nil, nonce, c.Seal(nil, nonce, plaintext, nil), nil)
+			failed[i] = err != nil || !bytes.Equal(opened, plaintext)
+		}()
+	}
+	wg.Wait()
+
+	for i, f := range failed {
+		if f {
+			t.Errorf("goroutine %d: round-trip failed", i)
+		}
+	}
+}
+
+func newConformanceCipher(t *testing.T, name string, key []byte) AEADCipher {
+	t.Helper()
+	c, err := createAEADCipher(name, key)
+	if err != nil {
+		t.Fatalf("creating %s cipher: %v", name, err)
+	}
+	return c
+}
+
+// conformanceKey returns a random key of the size the engine hands ciphers.
+func conformanceKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, aesKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func conformanceNonce(t *testing.T) []byte {
+	t.Helper()
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		t.Fatal(err)
+	}
+	return nonce
+}
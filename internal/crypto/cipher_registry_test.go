@@ -0,0 +1,82 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testCipherName is a stand-in for a national algorithm with a 128-bit key:
+// AES-128-GCM keyed from the first half of the data key.
+const testCipherName = "TEST-AES128-GCM"
+
+func init() {
+	RegisterCipher(CipherSpec{
+		AlgorithmEntry: AlgorithmEntry{Name: testCipherName},
+		New: func(key []byte) (cipher.AEAD, error) {
+			block, err := aes.NewCipher(key[:16])
+			if err != nil {
+				return nil, err
+			}
+			return cipher.NewGCM(block)
+		},
+	})
+}
+
+func TestCipherConformance_Custom(t *testing.T) {
+	if _, err := createAEADCipher(testCipherName, make([]byte, aesKeySize)); errors.Is(err, ErrAlgorithmNotApproved) {
+		t.Skip("the test cipher is not registered as FIPS-approved")
+	}
+	CipherConformanceSuite(t, testCipherName)
+}
+
+func TestCipherConformance_BuiltIn(t *testing.T) {
+	CipherConformanceSuite(t, AlgorithmAES256GCM)
+}
+
+func TestRegisterCipher_Panics(t *testing.T) {
+	newGCM := func(key []byte) (cipher.AEAD, error) {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	}
+	for name, spec := range map[string]CipherSpec{
+		"duplicate": {AlgorithmEntry: AlgorithmEntry{Name: testCipherName}, New: newGCM},
+		"built-in":  {AlgorithmEntry: AlgorithmEntry{Name: AlgorithmAES256GCM}, New: newGCM},
+		"no name":   {New: newGCM},
+		"no New":    {AlgorithmEntry: AlgorithmEntry{Name: "TEST-NO-FACTORY"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("RegisterCipher() did not panic")
+				}
+			}()
+			RegisterCipher(spec)
+		})
+	}
+	if got := Ciphers(); len(got) != 1 || got[0] != testCipherName {
+		t.Errorf("Ciphers() = %v, want [%s]", got, testCipherName)
+	}
+}
+
+func TestCreateCustomCipher_RejectsFormatMismatch(t *testing.T) {
+	spec := CipherSpec{
+		AlgorithmEntry: AlgorithmEntry{Name: "TEST-SHORT-TAG"},
+		New: func(key []byte) (cipher.AEAD, error) {
+			block, err := aes.NewCipher(key)
+			if err != nil {
+				return nil, err
+			}
+			return cipher.NewGCMWithTagSize(block, 12)
+		},
+	}
+	_, err := createCustomCipher(spec, make([]byte, aesKeySize))
+	if err == nil || !strings.Contains(err.Error(), "12-byte nonce and 12-byte tag") {
+		t.Errorf("createCustomCipher() error = %v, want a format mismatch", err)
+	}
+}
@@ -4,9 +4,15 @@ import (
 	"compress/gzip"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 )
 
+// DefaultMaxCompressionRatio is the largest expansion Decompress accepts
+// unless configured otherwise. DEFLATE cannot exceed about 1032:1, so no
+// stream written by Compress is refused at this ratio.
+const DefaultMaxCompressionRatio = 1032
+
 // CompressionEngine provides compression and decompression functionality.
 type CompressionEngine interface {
 	// Compress compresses data from the reader and returns a compressed reader
@@ -27,17 +33,36 @@ type compressionEngine struct {
 	contentTypes []string
 	algorithm    string
 	level        int
+	maxRatio     int64
+}
+
+// CompressionOption configures a compression engine.
+type CompressionOption func(*compressionEngine)
+
+// WithMaxRatio sets the largest expansion Decompress accepts, as output
+// bytes per compressed byte. Values below 1 keep DefaultMaxCompressionRatio.
+func WithMaxRatio(ratio int) CompressionOption {
+	return func(c *compressionEngine) {
+		if ratio >= 1 {
+			c.maxRatio = int64(ratio)
+		}
+	}
 }
 
 // NewCompressionEngine creates a new compression engine from configuration.
-func NewCompressionEngine(enabled bool, minSize int64, contentTypes []string, algorithm string, level int) CompressionEngine {
-	return &compressionEngine{
+func NewCompressionEngine(enabled bool, minSize int64, contentTypes []string, algorithm string, level int, opts ...CompressionOption) CompressionEngine {
+	c := &compressionEngine{
 		enabled:      enabled,
 		minSize:      minSize,
 		contentTypes: contentTypes,
 		algorithm:    algorithm,
 		level:        level,
+		maxRatio:     DefaultMaxCompressionRatio,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // ShouldCompress determines if data should be compressed.
@@ -183,21 +208,75 @@ func (c *compressionEngine) Decompress(reader io.Reader, metadata map[string]str
 		return nil, fmt.Errorf("compression algorithm not specified in metadata")
 	}
 
+	// Bound the output by the recorded original size, when there is one,
+	// and by the maximum ratio over the compressed input consumed so far.
+	limit := int64(-1)
+	if v, ok := metadata[MetaCompressionOriginalSize]; ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%w: invalid compression original size %q", ErrIntegrity, v)
+		}
+		limit = n
+	}
+	in := &countingReader{r: reader}
+
 	switch algorithm {
 	case "gzip":
 		// Wrap the source reader directly — no full-payload buffer.
-		gzipReader, err := gzip.NewReader(reader)
+		gzipReader, err := gzip.NewReader(in)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
 		}
-		// Return the gzip.Reader as an io.Reader. gzip.Reader implements
-		// io.ReadCloser; the Close is a no-op on the underlying reader,
-		// so callers that exhaust the stream will receive io.EOF from gzip
-		// transparently. For legacy engine.Decrypt the AEAD has already
-		// authenticated the ciphertext before decompression begins, so
-		// streaming is safe here (commit-before-release rule satisfied).
-		return gzipReader, nil
+		// The gzip.Reader's Close is a no-op on the underlying reader, so
+		// callers that exhaust the stream receive io.EOF transparently. For
+		// legacy engine.Decrypt the AEAD has already authenticated the
+		// ciphertext before decompression begins, so streaming is safe here
+		// (commit-before-release rule satisfied).
+		return &boundedDecompressor{r: gzipReader, in: in, limit: limit, ratio: c.maxRatio}, nil
 	default:
 		return nil, fmt.Errorf("unsupported decompression algorithm: %s", algorithm)
 	}
 }
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// boundedDecompressor fails with ErrIntegrity once the decompressed output
+// exceeds limit (when limit >= 0) or ratio times the compressed input
+// consumed. The input count includes the decompressor's read-ahead, so the
+// ratio check errs on the side of accepting.
+type boundedDecompressor struct {
+	r     io.Reader
+	in    *countingReader
+	out   int64
+	limit int64
+	ratio int64
+	err   error
+}
+
+func (b *boundedDecompressor) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	n, err := b.r.Read(p)
+	b.out += int64(n)
+	switch {
+	case b.limit >= 0 && b.out > b.limit:
+		b.err = fmt.Errorf("%w: decompressed output exceeds the recorded original size of %d bytes", ErrIntegrity, b.limit)
+	case b.out > b.in.n*b.ratio:
+		b.err = fmt.Errorf("%w: decompressed output exceeds a %d:1 expansion of %d compressed bytes", ErrIntegrity, b.ratio, b.in.n)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return n, err
+}
@@ -0,0 +1,159 @@
+package crypto
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressionBenchSize is the plaintext size of every corpus. Large enough
+// to span many 64 KiB chunks, small enough for a quick -benchtime.
+const compressionBenchSize = 4 * 1024 * 1024
+
+// compressionBenchCorpora returns the two ends of the spectrum: structured
+// log text that compresses several-fold, and random bytes that do not
+// compress at all (the stand-in for media, archives and ciphertext).
+func compressionBenchCorpora() map[string][]byte {
+	rng := rand.New(rand.NewSource(1))
+
+	var text bytes.Buffer
+	levels := []string{"INFO", "WARN", "DEBUG", "ERROR"}
+	paths := []string{"/api/v1/objects", "/api/v1/buckets", "/healthz", "/metrics"}
+	for text.Len() < compressionBenchSize {
+		fmt.Fprintf(&text, `{"ts":"2026-01-%02dT%02d:%02d:%02d.%03dZ","level":%q,"path":%q,"status":%d,"bytes":%d,"request_id":"%016x"}`+"\n",
+			rng.Intn(28)+1, rng.Intn(24), rng.Intn(60), rng.Intn(60), rng.Intn(1000),
+			levels[rng.Intn(len(levels))], paths[rng.Intn(len(paths))],
+			200+rng.Intn(4)*100, rng.Intn(1<<20), rng.Uint64())
+	}
+
+	random := make([]byte, compressionBenchSize)
+	rng.Read(random)
+
+	return map[string][]byte{
+		"text":   text.Bytes()[:compressionBenchSize],
+		"random": random,
+	}
+}
+
+// compressionBenchCodec is one compression setting under test. compress is
+// nil for the uncompressed baseline.
+type compressionBenchCodec struct {
+	name      string
+	gzipLevel int // > 0 when the gateway's own gzip engine can run it
+	compress  func(dst *bytes.Buffer, src []byte) error
+}
+
+func compressionBenchCodecs(b *testing.B) []compressionBenchCodec {
+	codecs := []compressionBenchCodec{{name: "none"}}
+	for _, level := range []int{gzip.BestSpeed, 6, gzip.BestCompression} {
+		codecs = append(codecs, compressionBenchCodec{
+			name:      fmt.Sprintf("gzip-%d", level),
+			gzipLevel: level,
+			compress: func(dst *bytes.Buffer, src []byte) error {
+				gw, err := gzip.NewWriterLevel(dst, level)
+				if err != nil {
+					return err
+				}
+				if _, err := gw.Write(src); err != nil {
+					return err
+				}
+				return gw.Close()
+			},
+		})
+	}
+	for _, level := range []zstd.EncoderLevel{zstd.SpeedFastest, zstd.SpeedDefault, zstd.SpeedBetterCompression, zstd.SpeedBestCompression} {
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(level), zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			b.Fatalf("zstd encoder: %v", err)
+		}
+		codecs = append(codecs, compressionBenchCodec{
+			name: "zstd-" + level.String(),
+			compress: func(dst *bytes.Buffer, src []byte) error {
+				dst.Write(enc.EncodeAll(src, dst.AvailableBuffer()))
+				return nil
+			},
+		})
+	}
+	return codecs
+}
+
+// BenchmarkCompressionEncrypt measures compression and encryption together
+// for each corpus, codec and encryption mode, so the cost of a compression
+// setting is seen next to what it saves. Throughput (MB/s) is over the
+// plaintext; the stored/plain metric is the size written to the backend as a
+// fraction of the plaintext. Key derivation runs at a token iteration count:
+// it costs the same whatever the compression setting and would otherwise
+// dominate the comparison.
+//
+// gzip in buffered mode runs through the engine exactly as the gateway does.
+// The engine does not compress in chunked mode and has no zstd codec, so
+// those combinations compress up front and encrypt the result: they show
+// what such a setting would cost rather than what is deployable today.
+//
+// The matrix takes minutes at the baseline -benchtime, so it only runs with
+// BENCH_COMPRESSION=1; scripts/bench-compression.sh sets it and turns the
+// output into a recommendation table.
+func BenchmarkCompressionEncrypt(b *testing.B) {
+	if os.Getenv("BENCH_COMPRESSION") == "" {
+		b.Skip("set BENCH_COMPRESSION=1 (or run scripts/bench-compression.sh)")
+	}
+	ctx := context.Background()
+	password := []byte("test-password-12345")
+	corpora := compressionBenchCorpora()
+
+	for _, corpusName := range []string{"text", "random"} {
+		data := corpora[corpusName]
+		for _, codec := range compressionBenchCodecs(b) {
+			for _, mode := range []string{"buffered", "chunked"} {
+				name := fmt.Sprintf("corpus=%s/codec=%s/mode=%s", corpusName, codec.name, mode)
+				b.Run(name, func(b *testing.B) {
+					var comp CompressionEngine
+					precompress := codec.compress
+					if codec.gzipLevel > 0 && mode == "buffered" {
+						comp = NewCompressionEngine(true, 0, nil, "gzip", codec.gzipLevel)
+						precompress = nil
+					}
+					engine, err := NewEngineWithChunkingAndProvider(password, comp, "", nil, mode == "chunked", 64*1024, "default", 1000)
+					if err != nil {
+						b.Fatalf("Failed to create engine: %v", err)
+					}
+					meta := map[string]string{"Content-Type": "text/plain"}
+
+					var compressed bytes.Buffer
+					var stored int64
+					b.SetBytes(int64(len(data)))
+					b.ResetTimer()
+					b.ReportAllocs()
+
+					for i := 0; i < b.N; i++ {
+						input := data
+						if precompress != nil {
+							compressed.Reset()
+							if err := precompress(&compressed, data); err != nil {
+								b.Fatalf("Compression failed: %v", err)
+							}
+							input = compressed.Bytes()
+						}
+						encrypted, _, err := engine.Encrypt(ctx, bytes.NewReader(input), meta)
+						if err != nil {
+							b.Fatalf("Encryption failed: %v", err)
+						}
+						stored, err = io.Copy(io.Discard, encrypted)
+						if err != nil {
+							b.Fatalf("Failed to read encrypted data: %v", err)
+						}
+					}
+
+					b.ReportMetric(float64(stored)/float64(len(data)), "stored/plain")
+				})
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package crypto
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"testing"
 )
@@ -157,3 +158,61 @@ func TestCompressionEngine_DecompressUncompressed(t *testing.T) {
 		t.Errorf("Decompress() should return original data when not compressed")
 	}
 }
+
+func TestCompressionEngine_DecompressBounds(t *testing.T) {
+	data := bytes.Repeat([]byte("A"), 100*1024)
+	compressed, meta := func() ([]byte, map[string]string) {
+		r, meta, err := NewCompressionEngine(true, 0, nil, "gzip", 6).Compress(bytes.NewReader(data), "text/plain", int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b, meta
+	}()
+	with := func(k, v string) map[string]string {
+		m := map[string]string{}
+		for mk, mv := range meta {
+			m[mk] = mv
+		}
+		if v == "" {
+			delete(m, k)
+		} else {
+			m[k] = v
+		}
+		return m
+	}
+
+	for _, tc := range []struct {
+		name    string
+		engine  CompressionEngine
+		meta    map[string]string
+		wantErr bool
+	}{
+		{"recorded size", NewCompressionEngine(true, 0, nil, "gzip", 6), meta, false},
+		{"understated size", NewCompressionEngine(true, 0, nil, "gzip", 6), with(MetaCompressionOriginalSize, "1000"), true},
+		{"no size, default ratio", NewCompressionEngine(true, 0, nil, "gzip", 6), with(MetaCompressionOriginalSize, ""), false},
+		{"no size, low ratio", NewCompressionEngine(true, 0, nil, "gzip", 6, WithMaxRatio(10)), with(MetaCompressionOriginalSize, ""), true},
+	} {
+		r, err := tc.engine.Decompress(bytes.NewReader(compressed), tc.meta)
+		if err != nil {
+			t.Fatalf("%s: Decompress: %v", tc.name, err)
+		}
+		out, err := io.ReadAll(r)
+		if tc.wantErr {
+			if !errors.Is(err, ErrIntegrity) {
+				t.Errorf("%s: error = %v, want ErrIntegrity", tc.name, err)
+			}
+			continue
+		}
+		if err != nil || !bytes.Equal(out, data) {
+			t.Errorf("%s: got %d bytes, %v", tc.name, len(out), err)
+		}
+	}
+
+	if _, err := NewCompressionEngine(true, 0, nil, "gzip", 6).Decompress(bytes.NewReader(compressed), with(MetaCompressionOriginalSize, "many")); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("invalid original size: error = %v, want ErrIntegrity", err)
+	}
+}
@@ -9,7 +9,8 @@ import (
 )
 
 // createAEADCipher creates an AEAD cipher for the given algorithm and key.
-// In non-FIPS builds, both AES-256-GCM and ChaCha20-Poly1305 are available.
+// In non-FIPS builds, both AES-256-GCM and ChaCha20-Poly1305 are available,
+// along with any cipher registered with RegisterCipher.
 func createAEADCipher(algorithm string, key []byte) (AEADCipher, error) {
 	switch algorithm {
 	case AlgorithmAES256GCM:
@@ -17,6 +18,9 @@ func createAEADCipher(algorithm string, key []byte) (AEADCipher, error) {
 	case AlgorithmChaCha20Poly1305:
 		return createChaCha20Poly1305Cipher(key)
 	default:
+		if spec, ok := lookupCipher(algorithm); ok {
+			return createCustomCipher(spec, key)
+		}
 		return nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
 	}
 }
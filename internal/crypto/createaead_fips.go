@@ -8,7 +8,8 @@ import (
 
 // createAEADCipher creates an AEAD cipher for the given algorithm and key.
 // In FIPS mode, only AES-256-GCM is available. ChaCha20-Poly1305 is rejected
-// as it is not on the FIPS 140-3 approved list.
+// as it is not on the FIPS 140-3 approved list. Registered custom ciphers
+// are available only when their CipherSpec is marked FIPSApproved.
 func createAEADCipher(algorithm string, key []byte) (AEADCipher, error) {
 	switch algorithm {
 	case AlgorithmAES256GCM:
@@ -16,6 +17,12 @@ func createAEADCipher(algorithm string, key []byte) (AEADCipher, error) {
 	case AlgorithmChaCha20Poly1305:
 		return nil, ErrAlgorithmNotApproved
 	default:
+		if spec, ok := lookupCipher(algorithm); ok {
+			if !spec.FIPSApproved {
+				return nil, ErrAlgorithmNotApproved
+			}
+			return createCustomCipher(spec, key)
+		}
 		return nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
 	}
 }
@@ -30,7 +30,7 @@ func newDecryptReader(source io.Reader, gcm cipher.AEAD, iv []byte) (*decryptRea
 	// Decrypt the data using GCM
 	plaintext, err := gcm.Open(nil, iv, ciphertext, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt data: %w", err)
+		return nil, fmt.Errorf("failed to decrypt data: %w", integrityError(err))
 	}
 
 	return &decryptReader{
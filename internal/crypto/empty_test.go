@@ -0,0 +1,92 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEmptyObject_RoundTrip(t *testing.T) {
+	password := []byte("test-password-123456")
+	compression := NewCompressionEngine(true, 0, nil, "gzip", 6)
+	engines := []struct {
+		name string
+		opts []Option
+		comp CompressionEngine
+		meta map[string]string
+	}{
+		{"legacy", []Option{WithChunking(false)}, nil, map[string]string{}},
+		{"legacy compressed", []Option{WithChunking(false)}, compression, map[string]string{}},
+		{"chunked declared", []Option{WithChunking(true)}, nil, map[string]string{"Content-Length": "0"}},
+		{"chunked undeclared", []Option{WithChunking(true)}, nil, map[string]string{}},
+		{"chunked compressed", []Option{WithChunking(true)}, compression, map[string]string{}},
+	}
+	for _, tt := range engines {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewEngineWithOpts(password, tt.comp, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.meta["Content-Type"] = "text/plain"
+			tt.meta["x-amz-meta-owner"] = "alice"
+			encReader, encMeta, err := engine.Encrypt(context.Background(), strings.NewReader(""), tt.meta)
+			if err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			body, _ := io.ReadAll(encReader)
+			if len(body) != 0 || !IsEmptyObject(encMeta) || !engine.IsEncrypted(encMeta) {
+				t.Fatalf("stored %d bytes, marker %v, encrypted %v", len(body), IsEmptyObject(encMeta), engine.IsEncrypted(encMeta))
+			}
+			for _, k := range []string{MetaKeySalt, MetaIV, MetaWrappedKeyCiphertext, MetaManifest, MetaCompressionEnabled} {
+				if v, ok := encMeta[k]; ok {
+					t.Errorf("empty object stored %s = %q", k, v)
+				}
+			}
+
+			plain, decMeta, err := engine.Decrypt(context.Background(), bytes.NewReader(body), encMeta)
+			if err != nil {
+				t.Fatalf("Decrypt: %v", err)
+			}
+			data, _ := io.ReadAll(plain)
+			if len(data) != 0 {
+				t.Errorf("Decrypt returned %d bytes", len(data))
+			}
+			if decMeta["Content-Length"] != "0" || decMeta["ETag"] != emptyObjectETag() {
+				t.Errorf("Content-Length %q, ETag %q", decMeta["Content-Length"], decMeta["ETag"])
+			}
+			if decMeta["x-amz-meta-owner"] != "alice" || decMeta[MetaEmpty] != "" {
+				t.Errorf("decrypted metadata = %v", decMeta)
+			}
+
+			// A body where none should be is tampering.
+			if _, _, err := engine.Decrypt(context.Background(), strings.NewReader("x"), encMeta); !errors.Is(err, ErrIntegrity) {
+				t.Errorf("Decrypt of a marked empty object with a body = %v, want ErrIntegrity", err)
+			}
+		})
+	}
+}
+
+func TestEmptyObject_Compaction(t *testing.T) {
+	compactor := NewMetadataCompactor(&ProviderProfile{Name: "test", CompactionStrategy: "base64url"})
+	compacted, err := compactor.CompactMetadata(map[string]string{
+		MetaEncrypted: "true",
+		MetaAlgorithm: AlgorithmAES256GCM,
+		MetaEmpty:     "true",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := compacted[MetaEmpty]; ok || !IsEmptyObject(compacted) {
+		t.Fatalf("compacted metadata = %v", compacted)
+	}
+	expanded, err := compactor.ExpandMetadata(compacted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expanded[MetaEmpty] != "true" {
+		t.Errorf("expanded metadata = %v", expanded)
+	}
+}
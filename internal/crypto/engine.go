@@ -16,6 +16,7 @@ import (
 	"strconv"
 
 	"github.com/kenneth/s3-encryption-gateway/internal/debug"
+	"github.com/kenneth/s3-encryption-gateway/internal/devtrace"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -111,6 +112,11 @@ type engine struct {
 	kmsManager KeyManager
 	// Rotation state machine for drain-and-cutover tracking
 	rotationState *RotationState
+	// Prepend a body header to chunked objects (see body_header.go)
+	bodyHeader bool
+	// Post-quantum hybrid wrap of KMS data keys (see hybrid_wrap.go)
+	hybridWrapKey   *HybridPublicKey
+	hybridUnwrapKey *HybridPrivateKey
 }
 
 // NewEngine creates a new encryption engine with the given password.
@@ -262,6 +268,20 @@ func (e *engine) deriveKey(salt []byte) ([]byte, error) {
 	return e.deriveKeyWithParams(salt, DefaultKDFParams(e.pbkdf2Iterations))
 }
 
+// unwrapHybridKey recovers an object's data key with the engine's hybrid
+// private key instead of the KMS.
+func (e *engine) unwrapHybridKey(metadata map[string]string, keySize int) ([]byte, error) {
+	key, err := e.hybridUnwrapKey.unwrapHybridMetadata(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	if len(key) != keySize {
+		zeroBytes(key)
+		return nil, fmt.Errorf("failed to unwrap data key: hybrid wrap holds a key of size %d, expected %d", len(key), keySize)
+	}
+	return key, nil
+}
+
 // generateSalt generates a cryptographically secure random salt.
 func (e *engine) generateSalt() ([]byte, error) {
 	salt := make([]byte, saltSize)
@@ -276,6 +296,18 @@ func (e *engine) PreferredAlgorithm() string {
 	return e.preferredAlgorithm
 }
 
+// Chunked reports whether the engine writes new objects in chunked format.
+func (e *engine) Chunked() bool {
+	return e.chunkedMode
+}
+
+// WritesChunked reports whether e writes new objects in chunked format.
+// Engines that do not say are assumed not to.
+func WritesChunked(e EncryptionEngine) bool {
+	c, ok := e.(interface{ Chunked() bool })
+	return ok && c.Chunked()
+}
+
 func (e *engine) generateNonce() ([]byte, error) {
 	return e.generateNonceForAlgorithm(e.preferredAlgorithm)
 }
@@ -330,14 +362,36 @@ func (e *engine) Encrypt(ctx context.Context, reader io.Reader, metadata map[str
 		),
 	)
 	defer span.End()
+	dt := devtrace.From(ctx)
+	traceMetadata(dt, "encrypt_input", metadata)
 
 	// If chunked mode is enabled, use streaming chunked encryption
 	if e.chunkedMode {
+		// An empty body gets the empty marker rather than a zero-chunk
+		// manifest. Bodies of unknown size are peeked at to find out; the
+		// rest are not touched before the chunk pipeline reads them.
+		if size, known := declaredSize(metadata); !known || size == 0 {
+			var first [1]byte
+			n, err := io.ReadFull(reader, first[:])
+			if err != nil && err != io.EOF {
+				span.SetStatus(codes.Error, err.Error())
+				return nil, nil, fmt.Errorf("failed to read plaintext: %w", err)
+			}
+			if n == 0 {
+				if body, meta, ok := e.encryptEmpty(metadata); ok {
+					traceMetadata(dt, "encrypt_stored", meta)
+					span.SetStatus(codes.Ok, "")
+					return body, meta, nil
+				}
+			}
+			reader = io.MultiReader(bytes.NewReader(first[:n]), reader)
+		}
 		encryptedReader, meta, err := e.encryptChunked(ctx, reader, metadata)
 		if err != nil {
 			span.SetStatus(codes.Error, err.Error())
 			return nil, nil, err
 		}
+		traceMetadata(dt, "encrypt_stored", meta)
 		span.SetStatus(codes.Ok, "")
 		return encryptedReader, meta, nil
 	}
@@ -350,6 +404,13 @@ func (e *engine) Encrypt(ctx context.Context, reader io.Reader, metadata map[str
 		return nil, nil, fmt.Errorf("failed to read plaintext: %w", err)
 	}
 	originalSize := int64(len(plaintext))
+	if originalSize == 0 {
+		if body, meta, ok := e.encryptEmpty(metadata); ok {
+			traceMetadata(dt, "encrypt_stored", meta)
+			span.SetStatus(codes.Ok, "")
+			return body, meta, nil
+		}
+	}
 
 	// Extract content type from metadata
 	contentType := ""
@@ -482,6 +543,11 @@ func (e *engine) Encrypt(ctx context.Context, reader io.Reader, metadata map[str
 			encMetadata[MetaKMSProvider] = envelope.Provider
 		}
 		encMetadata[MetaWrappedKeyCiphertext] = encodeBase64(envelope.Ciphertext)
+		if e.hybridWrapKey != nil {
+			if err := e.hybridWrapKey.addHybridWrap(encMetadata, key); err != nil {
+				return nil, nil, err
+			}
+		}
 	} else if kv, ok := metadata[MetaKeyVersion]; ok && kv != "" {
 		encMetadata[MetaKeyVersion] = kv
 	}
@@ -546,6 +612,7 @@ func (e *engine) Encrypt(ctx context.Context, reader io.Reader, metadata map[str
 		return nil, nil, fmt.Errorf("failed to compact metadata: %w", err)
 	}
 
+	traceMetadata(dt, "encrypt_stored", compactedMetadata)
 	span.SetStatus(codes.Ok, "")
 	return encryptedReader, compactedMetadata, nil
 }
@@ -559,10 +626,15 @@ func (e *engine) Decrypt(ctx context.Context, reader io.Reader, metadata map[str
 		),
 	)
 	defer span.End()
+	if IsShredded(metadata) {
+		return nil, nil, ErrShredded
+	}
 	if !e.IsEncrypted(metadata) {
 		// Not encrypted, return as-is
 		return reader, metadata, nil
 	}
+	dt := devtrace.From(ctx)
+	traceMetadata(dt, "decrypt_stored", metadata)
 
 	// Check if this is fallback mode (metadata stored in object body)
 	if e.isFallbackMode(metadata) {
@@ -574,6 +646,15 @@ func (e *engine) Decrypt(ctx context.Context, reader io.Reader, metadata map[str
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to expand metadata: %w", err)
 	}
+	traceMetadata(dt, "decrypt_expanded", expandedMetadata)
+
+	if IsEmptyObject(expandedMetadata) {
+		return decryptEmpty(reader, expandedMetadata)
+	}
+
+	if err := skipBodyHeader(reader, expandedMetadata); err != nil {
+		return nil, nil, err
+	}
 
 	// Check if this is chunked format
 	if isChunkedFormat(expandedMetadata) {
@@ -607,7 +688,12 @@ func (e *engine) Decrypt(ctx context.Context, reader io.Reader, metadata map[str
 
 	var key []byte
 
-	if e.kmsManager != nil && expandedMetadata[MetaWrappedKeyCiphertext] != "" {
+	if e.hybridUnwrapKey != nil && expandedMetadata[MetaHybridWrappedKey] != "" {
+		key, err = e.unwrapHybridKey(expandedMetadata, keySize)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if e.kmsManager != nil && expandedMetadata[MetaWrappedKeyCiphertext] != "" {
 		wrappedKeyB64 := expandedMetadata[MetaWrappedKeyCiphertext]
 		ciphertext, err := decodeBase64(wrappedKeyB64)
 		if err != nil {
@@ -731,7 +817,7 @@ func (e *engine) Decrypt(ctx context.Context, reader io.Reader, metadata map[str
 	}
 
 	if openErr != nil {
-		return nil, nil, fmt.Errorf("failed to decrypt data (algorithm=%s, keySize=%d, ivSize=%d, ciphertextSize=%d): %w", algorithm, len(key), len(iv), len(ciphertext), openErr)
+		return nil, nil, fmt.Errorf("failed to decrypt data (algorithm=%s, keySize=%d, ivSize=%d, ciphertextSize=%d): %w", algorithm, len(key), len(iv), len(ciphertext), integrityError(openErr))
 	}
 
 	// V0.6-PERF-1 Phase F: Apply decompression if compression was used.
@@ -744,14 +830,9 @@ func (e *engine) Decrypt(ctx context.Context, reader io.Reader, metadata map[str
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to decompress data: %w", err)
 		}
+		// V1.0-SEC-M05: Decompress bounds the output by the recorded
+		// original size and its maximum ratio, failing with ErrIntegrity.
 		finalReader = decompressedReader
-		// V1.0-SEC-M05: bound decompressed output to prevent decompression bombs
-		if originalSizeStr, ok := expandedMetadata[MetaCompressionOriginalSize]; ok {
-			if originalSize, err := strconv.ParseInt(originalSizeStr, 10, 64); err == nil && originalSize > 0 {
-				limit := originalSize + 65536 // 64 KiB tolerance for format overhead
-				finalReader = io.LimitReader(finalReader, limit)
-			}
-		}
 	}
 
 	// Prepare decrypted metadata (remove encryption and compression markers)
@@ -773,6 +854,7 @@ func (e *engine) Decrypt(ctx context.Context, reader io.Reader, metadata map[str
 	if originalETag, ok := expandedMetadata[MetaOriginalETag]; ok {
 		decMetadata["ETag"] = originalETag
 	}
+	traceMetadata(dt, "decrypt_output", decMetadata)
 
 	return finalReader, decMetadata, nil
 }
@@ -824,6 +906,27 @@ func (e *engine) encryptChunked(ctx context.Context, reader io.Reader, metadata
 	// Add chunked-specific metadata
 	encMetadata[MetaChunkedFormat] = "true"
 	encMetadata[MetaChunkSize] = fmt.Sprintf("%d", e.chunkSize)
+	// Salt, IV and manifest are generated below; reserve their encoded size
+	// so that the check sees the headers as they will be stored.
+	encMetadata[MetaKeySalt] = encodeBase64(make([]byte, saltSize))
+	if nonceSize, err := getNonceSize(e.preferredAlgorithm); err == nil {
+		placeholderIV := encodeBase64(make([]byte, nonceSize))
+		encMetadata[MetaIV] = placeholderIV
+		placeholder := &ChunkManifest{Version: ChunkManifestVersion, ChunkSize: e.chunkSize, BaseIV: placeholderIV, IVDerivation: "hkdf-sha256"}
+		placeholder.recordPlaintextSize(originalSize)
+		if manifest, err := encodeManifest(placeholder); err == nil {
+			encMetadata[MetaManifest] = manifest
+		}
+	}
+	encMetadata[MetaIVDerivation] = "hkdf-sha256"
+	encMetadata[MetaKDFParams] = FormatKDFParams(DefaultKDFParams(e.pbkdf2Iterations))
+	if e.kmsManager != nil && e.hybridWrapKey != nil {
+		// The hybrid-wrapped key alone is about 1.6 KB; reserve its size
+		// until the real value replaces it below.
+		encMetadata[MetaHybridWrapAlgorithm] = HybridWrapX25519MLKEM768
+		encMetadata[MetaHybridWrappedKey] = encodeBase64(make([]byte, hybridWrapOverhead+aesKeySize))
+		encMetadata[MetaHybridKeyID] = e.hybridWrapKey.KeyID()
+	}
 
 	// Check if we need fallback metadata storage
 	if e.needsMetadataFallback(encMetadata) {
@@ -904,6 +1007,8 @@ func (e *engine) encryptChunked(ctx context.Context, reader io.Reader, metadata
 	// Create chunked encrypt reader directly from the source stream.
 	// No io.ReadAll — memory usage is bounded by the chunk pipeline.
 	chunkedReader, manifest := newChunkedEncryptReaderWithContext(ctx, reader, aead, baseIV, e.chunkSize, e.bufferPool)
+	manifest.recordPlaintextSize(originalSize)
+	traceManifest(chunkedReader.trace, "encrypt_manifest", manifest)
 
 	// Encode manifest for storage
 	manifestEncoded, err := encodeManifest(manifest)
@@ -948,17 +1053,32 @@ func (e *engine) encryptChunked(ctx context.Context, reader io.Reader, metadata
 			encMetadata[MetaKMSProvider] = envelope.Provider
 		}
 		encMetadata[MetaWrappedKeyCiphertext] = encodeBase64(envelope.Ciphertext)
+		if e.hybridWrapKey != nil {
+			if err := e.hybridWrapKey.addHybridWrap(encMetadata, key); err != nil {
+				return nil, nil, err
+			}
+		}
 	} else if kv, ok := metadata[MetaKeyVersion]; ok && kv != "" {
 		encMetadata[MetaKeyVersion] = kv
 	}
 
+	var body io.Reader = chunkedReader
+	if e.bodyHeader {
+		header, err := encodeBodyHeader(encMetadata)
+		if err != nil {
+			return nil, nil, err
+		}
+		encMetadata[MetaBodyHeader] = strconv.Itoa(len(header))
+		body = io.MultiReader(bytes.NewReader(header), chunkedReader)
+	}
+
 	// Compact metadata according to provider profile
 	compactedMetadata, err := e.compactor.CompactMetadata(encMetadata)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to compact metadata: %w", err)
 	}
 
-	return chunkedReader, compactedMetadata, nil
+	return body, compactedMetadata, nil
 }
 
 // encryptChunkedWithMetadataFallback encrypts chunked data with metadata stored in object body
@@ -1036,6 +1156,7 @@ func (e *engine) encryptChunkedWithMetadataFallback(ctx context.Context, reader
 	// by the chunked AEAD, so a second full-object Seal is both redundant and
 	// forces 2× peak memory allocation (chunkedBuf + Seal output).
 	chunkedReader, manifest := newChunkedEncryptReaderWithContext(ctx, reader, aead, baseIV, e.chunkSize, e.bufferPool)
+	manifest.recordPlaintextSize(originalSize)
 
 	// Encode manifest
 	manifestEncoded, err := encodeManifest(manifest)
@@ -1067,6 +1188,11 @@ func (e *engine) encryptChunkedWithMetadataFallback(ctx context.Context, reader
 			fullMetadata[MetaKMSProvider] = envelope.Provider
 		}
 		fullMetadata[MetaWrappedKeyCiphertext] = encodeBase64(envelope.Ciphertext)
+		if e.hybridWrapKey != nil {
+			if err := e.hybridWrapKey.addHybridWrap(fullMetadata, key); err != nil {
+				return nil, nil, err
+			}
+		}
 	}
 
 	// Serialize full metadata to JSON (stored in the object body as a prefix)
@@ -1169,7 +1295,12 @@ func (e *engine) decryptChunked(ctx context.Context, reader io.Reader, metadata
 		key []byte
 	)
 
-	if e.kmsManager != nil && metadata[MetaWrappedKeyCiphertext] != "" {
+	if e.hybridUnwrapKey != nil && metadata[MetaHybridWrappedKey] != "" {
+		key, err = e.unwrapHybridKey(metadata, keySize)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if e.kmsManager != nil && metadata[MetaWrappedKeyCiphertext] != "" {
 		wrapped, err := decodeBase64(metadata[MetaWrappedKeyCiphertext])
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to decode wrapped data key: %w", err)
@@ -1219,6 +1350,7 @@ func (e *engine) decryptChunked(ctx context.Context, reader io.Reader, metadata
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create chunked decrypt reader: %w", err)
 	}
+	traceManifest(chunkedReader.trace, "decrypt_manifest", manifest)
 
 	// Prepare decrypted metadata (remove encryption markers)
 	decMetadata := make(map[string]string)
@@ -1236,25 +1368,17 @@ func (e *engine) decryptChunked(ctx context.Context, reader io.Reader, metadata
 		decMetadata[k] = v
 	}
 
-	// Restore original size if available (prefer MetaOriginalSize, fallback to calculation)
-	if originalSize, ok := metadata[MetaOriginalSize]; ok {
-		decMetadata["Content-Length"] = originalSize
-	} else if chunkCount, ok := metadata[MetaChunkCount]; ok {
-		if chunkSize, ok2 := metadata[MetaChunkSize]; ok2 {
-			count, err1 := strconv.Atoi(chunkCount)
-			size, err2 := strconv.Atoi(chunkSize)
-			if err1 == nil && err2 == nil {
-				// Approximate original size (last chunk might be smaller)
-				approxSize := int64((count-1)*size + size)
-				decMetadata["Content-Length"] = fmt.Sprintf("%d", approxSize)
-			}
-		}
+	// Restore the plaintext size: from the manifest or MetaOriginalSize when
+	// recorded, otherwise computed from the stored length.
+	if size, err := GetPlaintextSizeFromMetadata(metadata); err == nil {
+		decMetadata["Content-Length"] = strconv.FormatInt(size, 10)
 	}
 
 	// Restore original ETag if available (only restore if we have it, otherwise don't include ETag)
 	if originalETag, ok := metadata[MetaOriginalETag]; ok && originalETag != "" {
 		decMetadata["ETag"] = originalETag
 	}
+	traceMetadata(chunkedReader.trace, "decrypt_output", decMetadata)
 
 	return chunkedReader, decMetadata, nil
 }
@@ -1262,15 +1386,21 @@ func (e *engine) decryptChunked(ctx context.Context, reader io.Reader, metadata
 // DecryptRange decrypts only the chunks needed for a specific plaintext range.
 // This optimizes range requests by decrypting only necessary chunks.
 func (e *engine) DecryptRange(ctx context.Context, reader io.Reader, metadata map[string]string, plaintextStart, plaintextEnd int64) (io.Reader, map[string]string, error) {
+	if IsShredded(metadata) {
+		return nil, nil, ErrShredded
+	}
 	if !e.IsEncrypted(metadata) {
 		return nil, nil, fmt.Errorf("object is not encrypted")
 	}
+	dt := devtrace.From(ctx)
+	traceMetadata(dt, "decrypt_stored", metadata)
 
 	// Expand compacted metadata first
 	expandedMetadata, err := e.compactor.ExpandMetadata(metadata)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to expand metadata: %w", err)
 	}
+	traceMetadata(dt, "decrypt_expanded", expandedMetadata)
 
 	// Only supports chunked format for range optimization
 	if !isChunkedFormat(expandedMetadata) {
@@ -1335,7 +1465,12 @@ func (e *engine) DecryptRange(ctx context.Context, reader io.Reader, metadata ma
 		key []byte
 	)
 
-	if e.kmsManager != nil && expandedMetadata[MetaWrappedKeyCiphertext] != "" {
+	if e.hybridUnwrapKey != nil && expandedMetadata[MetaHybridWrappedKey] != "" {
+		key, err = e.unwrapHybridKey(expandedMetadata, keySize)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if e.kmsManager != nil && expandedMetadata[MetaWrappedKeyCiphertext] != "" {
 		wrapped, err := decodeBase64(expandedMetadata[MetaWrappedKeyCiphertext])
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to decode wrapped data key: %w", err)
@@ -1380,11 +1515,27 @@ func (e *engine) DecryptRange(ctx context.Context, reader io.Reader, metadata ma
 	}
 	aead := aeadCipher.(cipher.AEAD)
 
+	// Work out where in the chunk stream reader starts: after the body
+	// header for a whole body, or wherever a ranged backend read began.
+	headerLen := BodyHeaderLength(expandedMetadata)
+	var streamOffset int64
+	if o, ok := reader.(CiphertextOffsetter); ok {
+		streamOffset = o.CiphertextOffset() - headerLen
+		if streamOffset < 0 {
+			return nil, nil, fmt.Errorf("ranged ciphertext starts inside the body header")
+		}
+	} else if err := skipBodyHeader(reader, expandedMetadata); err != nil {
+		return nil, nil, err
+	}
+
 	// Create range-aware decrypt reader
-	rangeReader, err := newRangeDecryptReader(reader, aead, manifest, baseIV, plaintextStart, plaintextEnd, e.bufferPool)
+	rangeReader, err := newRangeDecryptReader(reader, aead, manifest, baseIV, plaintextStart, plaintextEnd, streamOffset, e.bufferPool)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create range reader: %w", err)
 	}
+	rangeReader.trace = dt
+	traceManifest(dt, "decrypt_manifest", manifest)
+	traceRangePlan(dt, rangeReader, streamOffset)
 
 	// Prepare decrypted metadata
 	decMetadata := make(map[string]string)
@@ -1398,6 +1549,7 @@ func (e *engine) DecryptRange(ctx context.Context, reader io.Reader, metadata ma
 	// Set Content-Length to the range size
 	rangeSize := plaintextEnd - plaintextStart + 1
 	decMetadata["Content-Length"] = fmt.Sprintf("%d", rangeSize)
+	traceMetadata(dt, "decrypt_output", decMetadata)
 
 	return rangeReader, decMetadata, nil
 }
@@ -1448,6 +1600,11 @@ func (e *engine) encryptWithMetadataFallback(plaintext []byte, fullMetadata map[
 	for k, v := range compressionMetadata {
 		fullMetadata[k] = v
 	}
+	// This format encrypts with the password-derived key, so a hybrid-wrapped
+	// data key would not open it.
+	delete(fullMetadata, MetaHybridWrapAlgorithm)
+	delete(fullMetadata, MetaHybridWrappedKey)
+	delete(fullMetadata, MetaHybridKeyID)
 
 	// Generate encryption parameters
 	salt, err := e.generateSalt()
@@ -1566,7 +1723,11 @@ func (e *engine) decryptWithMetadataFallback(ctx context.Context, reader io.Read
 	// Legacy / absent version: the object was encrypted by the old code which
 	// wrapped the chunked ciphertext in a second outer AEAD Seal. Handled by
 	// decryptFallbackV1 for backward compatibility.
-	if metadata[MetaFallbackVersion] == "2" {
+	version := fallbackVersion(metadata)
+	if err := checkFormatVersion(FormatMetadataFallback, version); err != nil {
+		return nil, nil, err
+	}
+	if version == 2 {
 		return e.decryptFallbackV2(ctx, reader, metadata)
 	}
 	return e.decryptFallbackV1(reader, metadata)
@@ -1576,10 +1737,24 @@ func (e *engine) decryptWithMetadataFallback(ctx context.Context, reader io.Read
 // encrypt path. Format: [4-byte BE metadata_length][metadata_json][chunked_stream].
 // No outer AEAD — integrity comes from the per-chunk AEAD in the chunked layer.
 func (e *engine) decryptFallbackV2(ctx context.Context, reader io.Reader, headerMetadata map[string]string) (io.Reader, map[string]string, error) {
+	fullMetadata, err := readFallbackMetadata(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The remainder of reader is the raw chunked ciphertext stream. Delegate
+	// to decryptChunked which is fully streaming — no io.ReadAll required.
+	return e.decryptChunked(ctx, reader, fullMetadata)
+}
+
+// readFallbackMetadata reads the [4-byte BE metadata_length][metadata_json]
+// prefix of a version 2 fallback object body, leaving reader positioned at
+// the chunked ciphertext stream.
+func readFallbackMetadata(reader io.Reader) (map[string]string, error) {
 	// Read the 4-byte big-endian metadata length prefix.
 	var lenBuf [4]byte
 	if _, err := io.ReadFull(reader, lenBuf[:]); err != nil {
-		return nil, nil, fmt.Errorf("fallback-v2: failed to read metadata length prefix: %w", err)
+		return nil, fmt.Errorf("fallback-v2: failed to read metadata length prefix: %w", err)
 	}
 	metadataLen := uint32(lenBuf[0])<<24 | uint32(lenBuf[1])<<16 | uint32(lenBuf[2])<<8 | uint32(lenBuf[3])
 
@@ -1587,24 +1762,21 @@ func (e *engine) decryptFallbackV2(ctx context.Context, reader io.Reader, header
 	// (headers < 8 KiB in practice); cap at 1 MiB to prevent heap abuse.
 	const maxFallbackMetadataBytes = 1 << 20 // 1 MiB
 	if metadataLen > maxFallbackMetadataBytes {
-		return nil, nil, fmt.Errorf("fallback-v2: metadata length %d exceeds sanity limit %d", metadataLen, maxFallbackMetadataBytes)
+		return nil, fmt.Errorf("fallback-v2: metadata length %d exceeds sanity limit %d", metadataLen, maxFallbackMetadataBytes)
 	}
 
 	// Read the metadata JSON (bounded by metadataLen).
 	metadataJSON := make([]byte, metadataLen)
 	if _, err := io.ReadFull(reader, metadataJSON); err != nil {
-		return nil, nil, fmt.Errorf("fallback-v2: failed to read metadata JSON (%d bytes): %w", metadataLen, err)
+		return nil, fmt.Errorf("fallback-v2: failed to read metadata JSON (%d bytes): %w", metadataLen, err)
 	}
 
 	// Parse the full metadata that was embedded in the object body.
 	fullMetadata, err := decodeMetadataFromJSON(metadataJSON)
 	if err != nil {
-		return nil, nil, fmt.Errorf("fallback-v2: failed to decode in-body metadata: %w", err)
+		return nil, fmt.Errorf("fallback-v2: failed to decode in-body metadata: %w", err)
 	}
-
-	// The remainder of reader is the raw chunked ciphertext stream. Delegate
-	// to decryptChunked which is fully streaming — no io.ReadAll required.
-	return e.decryptChunked(ctx, reader, fullMetadata)
+	return fullMetadata, nil
 }
 
 // decryptFallbackV1 decrypts objects written by the legacy fallback encrypt path
@@ -1702,7 +1874,7 @@ func (e *engine) decryptFallbackV1(reader io.Reader, metadata map[string]string)
 		aadLegacy := buildAADLegacy(algorithm, salt, iv, aadMeta)
 		plaintext, err = aeadCipher.Open(nil, iv, ciphertext, aadLegacy)
 		if err != nil {
-			return nil, nil, fmt.Errorf("failed to decrypt data: %w", err)
+			return nil, nil, fmt.Errorf("failed to decrypt data: %w", integrityError(err))
 		}
 	}
 
@@ -1734,14 +1906,9 @@ func (e *engine) decryptFallbackV1(reader io.Reader, metadata map[string]string)
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to decompress data: %w", err)
 		}
+		// V1.0-SEC-M05: Decompress bounds the output by the recorded
+		// original size and its maximum ratio, failing with ErrIntegrity.
 		finalReader = decompressedReader
-		// V1.0-SEC-M05: bound decompressed output to prevent decompression bombs
-		if originalSizeStr, ok := fullMetadata[MetaCompressionOriginalSize]; ok {
-			if originalSize, err := strconv.ParseInt(originalSizeStr, 10, 64); err == nil && originalSize > 0 {
-				limit := originalSize + 65536 // 64 KiB tolerance for format overhead
-				finalReader = io.LimitReader(finalReader, limit)
-			}
-		}
 	}
 
 	// Prepare decrypted metadata (remove encryption and compression markers)
@@ -1813,7 +1980,12 @@ func IsEncryptionMetadata(key string) bool {
 		key == MetaFallbackVersion ||
 		key == MetaIVDerivation ||
 		key == MetaLegacyNoAAD ||
-		key == MetaKDFParams
+		key == MetaKDFParams ||
+		key == MetaBodyHeader ||
+		key == MetaHybridWrapAlgorithm ||
+		key == MetaHybridWrappedKey ||
+		key == MetaHybridKeyID ||
+		key == MetaEmpty
 }
 
 // IsCompressionMetadata checks if a metadata key is related to compression.
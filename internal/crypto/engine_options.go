@@ -1,5 +1,7 @@
 package crypto
 
+import "github.com/kenneth/s3-encryption-gateway/internal/clock"
+
 // Option is a functional option for configuring an engine at construction time.
 // This pattern replaces the out-of-band SetKeyManager mutators
 // for new callers (see [NewEngineWithOpts]).
@@ -60,6 +62,31 @@ func WithChunkSize(size int) Option {
 	}
 }
 
+// WithBodyHeader prepends a body header holding the encryption metadata to
+// chunked objects, so copies that lose their metadata stay decryptable.
+func WithBodyHeader(enabled bool) Option {
+	return func(e *engine) {
+		e.bodyHeader = enabled
+	}
+}
+
+// WithHybridWrapKey additionally wraps every KMS data key to pub, so objects
+// can later be decrypted offline with the matching private key. It has no
+// effect without a KeyManager. A nil key disables the wrap.
+func WithHybridWrapKey(pub *HybridPublicKey) Option {
+	return func(e *engine) {
+		e.hybridWrapKey = pub
+	}
+}
+
+// WithHybridUnwrapKey makes the engine unwrap data keys with priv, instead
+// of the KMS, for objects that carry a hybrid-wrapped key.
+func WithHybridUnwrapKey(priv *HybridPrivateKey) Option {
+	return func(e *engine) {
+		e.hybridUnwrapKey = priv
+	}
+}
+
 // WithProvider sets the provider profile used for metadata compaction.
 func WithProvider(provider string) Option {
 	return func(e *engine) {
@@ -70,6 +97,14 @@ func WithProvider(provider string) Option {
 	}
 }
 
+// WithRotationClock makes the engine's rotation state machine follow clk,
+// so tests can step through drain polling and grace deadlines.
+func WithRotationClock(clk clock.Clock) Option {
+	return func(e *engine) {
+		e.rotationState = NewRotationStateWithClock(clk)
+	}
+}
+
 // NewEngineWithOpts creates a new encryption engine with full options and zero or
 // more functional Option values. This is the preferred constructor for new callers.
 //
@@ -0,0 +1,44 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error classes shared across the crypto package, for use with errors.Is.
+// Callers map them to client responses and metrics without inspecting
+// messages.
+var (
+	// ErrIntegrity is returned when authenticated decryption fails: the
+	// ciphertext, its metadata or the key does not match what was sealed.
+	ErrIntegrity = errors.New("crypto: integrity check failed")
+
+	// ErrManifestCorrupt is returned when a chunk or multipart manifest
+	// cannot be decoded, so the object layout is unknown.
+	ErrManifestCorrupt = errors.New("crypto: manifest corrupt")
+
+	// ErrRangeNotSatisfiable is returned when a well-formed byte range
+	// starts at or beyond the end of the object.
+	ErrRangeNotSatisfiable = errors.New("crypto: range not satisfiable")
+
+	// ErrKMSUnavailable is returned when the key management service cannot
+	// be reached or is not operational. It is the same value as
+	// ErrProviderUnavailable, so existing checks keep matching.
+	ErrKMSUnavailable = ErrProviderUnavailable
+)
+
+// integrityError tags an AEAD Open failure with ErrIntegrity.
+func integrityError(err error) error {
+	if err == nil || errors.Is(err, ErrIntegrity) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrIntegrity, err)
+}
+
+// kmsUnavailable tags a KMS transport failure with ErrKMSUnavailable.
+func kmsUnavailable(err error) error {
+	if err == nil || errors.Is(err, ErrKMSUnavailable) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrKMSUnavailable, err)
+}
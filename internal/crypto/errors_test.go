@@ -0,0 +1,78 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestDecrypt_TamperedCiphertextIsIntegrityError(t *testing.T) {
+	engine, err := NewEngine([]byte("test-password-12345"))
+	if err != nil {
+		t.Fatalf("NewEngine() error: %v", err)
+	}
+	encReader, meta, err := engine.Encrypt(context.Background(), bytes.NewReader([]byte("integrity matters")), map[string]string{"Content-Type": "text/plain"})
+	if err != nil {
+		t.Fatalf("Encrypt() error: %v", err)
+	}
+	ct, err := io.ReadAll(encReader)
+	if err != nil {
+		t.Fatalf("read ciphertext: %v", err)
+	}
+	ct[len(ct)/2] ^= 0x01
+
+	r, _, err := engine.Decrypt(context.Background(), bytes.NewReader(ct), meta)
+	if err == nil {
+		_, err = io.ReadAll(r)
+	}
+	if !errors.Is(err, ErrIntegrity) {
+		t.Fatalf("Decrypt() error = %v, want ErrIntegrity", err)
+	}
+}
+
+func TestMetadataSealer_TamperedValueIsIntegrityError(t *testing.T) {
+	s, err := NewMetadataSealer([]byte("test-password-12345"), 1000, MetadataSealValues)
+	if err != nil {
+		t.Fatalf("NewMetadataSealer() error: %v", err)
+	}
+	sealed, err := s.Seal(map[string]string{"x-amz-meta-owner": "alice"})
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+	// Moving a sealed value to another key breaks its associated data.
+	moved := map[string]string{"x-amz-meta-other": sealed["x-amz-meta-owner"]}
+	if _, err := s.Unseal(moved); !errors.Is(err, ErrIntegrity) {
+		t.Fatalf("Unseal() error = %v, want ErrIntegrity", err)
+	}
+}
+
+func TestErrorClassHelpers(t *testing.T) {
+	if !errors.Is(ErrKMSUnavailable, ErrProviderUnavailable) {
+		t.Error("ErrKMSUnavailable should match ErrProviderUnavailable")
+	}
+	base := errors.New("boom")
+	err := kmsUnavailable(base)
+	if !errors.Is(err, ErrKMSUnavailable) || !errors.Is(err, base) {
+		t.Errorf("kmsUnavailable() = %v, want both sentinel and cause", err)
+	}
+	if kmsUnavailable(err) != err {
+		t.Error("kmsUnavailable() should not wrap twice")
+	}
+	if integrityError(nil) != nil || kmsUnavailable(nil) != nil {
+		t.Error("helpers should pass nil through")
+	}
+}
+
+func TestManifestErrorsAreManifestCorrupt(t *testing.T) {
+	if _, err := decodeManifest("!!not-base64!!"); !errors.Is(err, ErrManifestCorrupt) {
+		t.Errorf("decodeManifest() error = %v, want ErrManifestCorrupt", err)
+	}
+	if _, err := loadManifestFromMetadata(map[string]string{}); !errors.Is(err, ErrManifestCorrupt) {
+		t.Errorf("loadManifestFromMetadata() error = %v, want ErrManifestCorrupt", err)
+	}
+	if _, err := UnmarshalMultipartManifest([]byte("{")); !errors.Is(err, ErrManifestCorrupt) {
+		t.Errorf("UnmarshalMultipartManifest() error = %v, want ErrManifestCorrupt", err)
+	}
+}
@@ -170,6 +170,36 @@ func TestEngine_FallbackDetection(t *testing.T) {
 	}
 }
 
+func TestEngine_ChunkedFallbackCountsStoredFields(t *testing.T) {
+	// User metadata this large fits on its own but not with the salt, IV
+	// and manifest the chunked format adds; the headers must not be sent
+	// over the limit.
+	encEngine, err := NewEngineWithOpts([]byte("test-password-123456"), nil, WithChunking(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := map[string]string{
+		"Content-Type":   "text/plain",
+		"x-amz-meta-big": strings.Repeat("a", 7800),
+	}
+	encReader, encMeta, err := encEngine.Encrypt(context.Background(), strings.NewReader("chunked fallback"), metadata)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if encMeta[MetaFallbackMode] != "true" {
+		t.Fatalf("expected fallback mode for %d bytes of headers", EstimateMetadataSize(encMeta))
+	}
+	body, _ := io.ReadAll(encReader)
+	plain, decMeta, err := encEngine.Decrypt(context.Background(), bytes.NewReader(body), encMeta)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	data, _ := io.ReadAll(plain)
+	if string(data) != "chunked fallback" || decMeta["x-amz-meta-big"] != metadata["x-amz-meta-big"] {
+		t.Errorf("round trip returned %q", data)
+	}
+}
+
 func TestEngine_IsFallbackMode(t *testing.T) {
 	encEngine, err := NewEngine([]byte("test-password-123"))
 	if err != nil {
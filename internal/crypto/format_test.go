@@ -0,0 +1,72 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDecrypt_NewerChunkManifestIsFormatTooNew(t *testing.T) {
+	engine, err := NewEngineWithOpts([]byte("test-password-12345"), nil, WithChunking(true))
+	if err != nil {
+		t.Fatalf("NewEngineWithOpts() error: %v", err)
+	}
+	r, meta, err := engine.Encrypt(context.Background(), bytes.NewReader([]byte("written by a newer replica")), map[string]string{})
+	if err != nil {
+		t.Fatalf("Encrypt() error: %v", err)
+	}
+	var ct bytes.Buffer
+	ct.ReadFrom(r)
+
+	manifest, err := decodeManifest(meta[MetaManifest])
+	if err != nil {
+		t.Fatalf("decodeManifest() error: %v", err)
+	}
+	manifest.Version = ChunkManifestVersion + 1
+	if meta[MetaManifest], err = encodeManifest(manifest); err != nil {
+		t.Fatalf("encodeManifest() error: %v", err)
+	}
+
+	_, _, err = engine.Decrypt(context.Background(), &ct, meta)
+	var fe *FormatVersionError
+	if !errors.Is(err, ErrFormatTooNew) || !errors.As(err, &fe) {
+		t.Fatalf("Decrypt() error = %v, want ErrFormatTooNew", err)
+	}
+	if fe.Kind != FormatChunkManifest || fe.Version != ChunkManifestVersion+1 || fe.Max != ChunkManifestVersion {
+		t.Errorf("FormatVersionError = %+v", fe)
+	}
+}
+
+func TestUnmarshalMultipartManifest_Versions(t *testing.T) {
+	if _, err := UnmarshalMultipartManifest([]byte(`{"v":2}`)); !errors.Is(err, ErrFormatTooNew) {
+		t.Errorf("newer version: error = %v, want ErrFormatTooNew", err)
+	}
+	_, err := UnmarshalMultipartManifest([]byte(`{"v":0}`))
+	if !errors.Is(err, ErrManifestCorrupt) || errors.Is(err, ErrFormatTooNew) {
+		t.Errorf("version 0: error = %v, want ErrManifestCorrupt only", err)
+	}
+}
+
+func TestDecrypt_NewerFallbackVersionIsFormatTooNew(t *testing.T) {
+	engine, err := NewEngine([]byte("test-password-12345"))
+	if err != nil {
+		t.Fatalf("NewEngine() error: %v", err)
+	}
+	meta := map[string]string{
+		MetaEncrypted:       "true",
+		MetaFallbackMode:    "true",
+		MetaFallbackVersion: "3",
+	}
+	if _, _, err := engine.Decrypt(context.Background(), bytes.NewReader(nil), meta); !errors.Is(err, ErrFormatTooNew) {
+		t.Errorf("Decrypt() error = %v, want ErrFormatTooNew", err)
+	}
+}
+
+func TestFormatVersions_ReadCoversWrite(t *testing.T) {
+	for kind, v := range FormatVersions() {
+		if v.Read < v.Write {
+			t.Errorf("%s: reads up to %d but writes %d", kind, v.Read, v.Write)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/mlkem"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// Hybrid key wrapping seals each data key a second time, next to the KMS
+// envelope, to a post-quantum hybrid public key. The matching private key
+// is kept offline; it can decrypt an object when the KMS is no longer
+// available, and an archive harvested today stays protected against a
+// future quantum attack on X25519 as long as ML-KEM holds (and vice versa).
+const (
+	// HybridWrapX25519MLKEM768 combines X25519 and ML-KEM-768 (FIPS 203).
+	HybridWrapX25519MLKEM768 = "X25519-MLKEM768"
+
+	// MetaHybridWrapAlgorithm records the hybrid KEM the data key is wrapped with.
+	MetaHybridWrapAlgorithm = "x-amz-meta-encryption-pq-alg"
+	// MetaHybridWrappedKey holds the hybrid-wrapped data key.
+	MetaHybridWrappedKey = "x-amz-meta-encryption-pq-wrapped-key"
+	// MetaHybridKeyID identifies the hybrid public key the data key is wrapped to.
+	MetaHybridKeyID = "x-amz-meta-encryption-pq-key-id"
+
+	hybridPublicKeyPEMType  = "S3EG HYBRID PUBLIC KEY"
+	hybridPrivateKeyPEMType = "S3EG HYBRID PRIVATE KEY"
+
+	x25519KeySize = 32
+	// hybridWrapOverhead is the X25519 ephemeral key, the ML-KEM ciphertext
+	// and the GCM tag that precede and follow the sealed data key.
+	hybridWrapOverhead = x25519KeySize + mlkem.CiphertextSize768 + tagSize
+)
+
+// ErrHybridKeyMismatch is returned when an object's data key was wrapped to
+// a different hybrid public key than the one offered to unwrap it.
+var ErrHybridKeyMismatch = errors.New("crypto: object is wrapped to a different hybrid key")
+
+// HybridPublicKey is an X25519 + ML-KEM-768 public key data keys are wrapped to.
+type HybridPublicKey struct {
+	x25519 *ecdh.PublicKey
+	mlkem  *mlkem.EncapsulationKey768
+}
+
+// HybridPrivateKey is the private half of a [HybridPublicKey].
+type HybridPrivateKey struct {
+	x25519 *ecdh.PrivateKey
+	mlkem  *mlkem.DecapsulationKey768
+}
+
+// GenerateHybridKey generates a new X25519 + ML-KEM-768 key pair.
+func GenerateHybridKey() (*HybridPrivateKey, error) {
+	x, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate X25519 key: %w", err)
+	}
+	m, err := mlkem.GenerateKey768()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ML-KEM-768 key: %w", err)
+	}
+	return &HybridPrivateKey{x25519: x, mlkem: m}, nil
+}
+
+// Public returns the public key for k.
+func (k *HybridPrivateKey) Public() *HybridPublicKey {
+	return &HybridPublicKey{x25519: k.x25519.PublicKey(), mlkem: k.mlkem.EncapsulationKey()}
+}
+
+// MarshalPEM encodes k as a PEM block holding the X25519 scalar followed by
+// the ML-KEM-768 seed.
+func (k *HybridPrivateKey) MarshalPEM() []byte {
+	b := append(append([]byte(nil), k.x25519.Bytes()...), k.mlkem.Bytes()...)
+	out := pem.EncodeToMemory(&pem.Block{Type: hybridPrivateKeyPEMType, Bytes: b})
+	zeroBytes(b)
+	return out
+}
+
+// ParseHybridPrivateKeyPEM parses a private key written by
+// [HybridPrivateKey.MarshalPEM].
+func ParseHybridPrivateKeyPEM(data []byte) (*HybridPrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != hybridPrivateKeyPEMType {
+		return nil, fmt.Errorf("hybrid private key: no %q PEM block", hybridPrivateKeyPEMType)
+	}
+	if len(block.Bytes) != x25519KeySize+mlkem.SeedSize {
+		return nil, fmt.Errorf("hybrid private key: %d bytes, want %d", len(block.Bytes), x25519KeySize+mlkem.SeedSize)
+	}
+	defer zeroBytes(block.Bytes)
+	x, err := ecdh.X25519().NewPrivateKey(block.Bytes[:x25519KeySize])
+	if err != nil {
+		return nil, fmt.Errorf("hybrid private key: %w", err)
+	}
+	m, err := mlkem.NewDecapsulationKey768(block.Bytes[x25519KeySize:])
+	if err != nil {
+		return nil, fmt.Errorf("hybrid private key: %w", err)
+	}
+	return &HybridPrivateKey{x25519: x, mlkem: m}, nil
+}
+
+// bytes returns the X25519 public key followed by the ML-KEM-768
+// encapsulation key.
+func (k *HybridPublicKey) bytes() []byte {
+	return append(append([]byte(nil), k.x25519.Bytes()...), k.mlkem.Bytes()...)
+}
+
+// MarshalPEM encodes k as a PEM block.
+func (k *HybridPublicKey) MarshalPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: hybridPublicKeyPEMType, Bytes: k.bytes()})
+}
+
+// ParseHybridPublicKeyPEM parses a public key written by
+// [HybridPublicKey.MarshalPEM].
+func ParseHybridPublicKeyPEM(data []byte) (*HybridPublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != hybridPublicKeyPEMType {
+		return nil, fmt.Errorf("hybrid public key: no %q PEM block", hybridPublicKeyPEMType)
+	}
+	if len(block.Bytes) != x25519KeySize+mlkem.EncapsulationKeySize768 {
+		return nil, fmt.Errorf("hybrid public key: %d bytes, want %d", len(block.Bytes), x25519KeySize+mlkem.EncapsulationKeySize768)
+	}
+	x, err := ecdh.X25519().NewPublicKey(block.Bytes[:x25519KeySize])
+	if err != nil {
+		return nil, fmt.Errorf("hybrid public key: %w", err)
+	}
+	m, err := mlkem.NewEncapsulationKey768(block.Bytes[x25519KeySize:])
+	if err != nil {
+		return nil, fmt.Errorf("hybrid public key: %w", err)
+	}
+	return &HybridPublicKey{x25519: x, mlkem: m}, nil
+}
+
+// KeyID returns a short fingerprint of k, recorded with each wrapped key so
+// the right private key can be found.
+func (k *HybridPublicKey) KeyID() string {
+	sum := sha256.Sum256(k.bytes())
+	return hex.EncodeToString(sum[:8])
+}
+
+// hybridKEK combines both shared secrets into the key-encryption key. The
+// transcript binds it to the ciphertexts and the recipient, so neither KEM
+// can be swapped out on its own.
+func hybridKEK(mlkemSecret, x25519Secret, ephemeral, mlkemCiphertext []byte, recipient *HybridPublicKey) ([]byte, error) {
+	secret := append(append([]byte(nil), mlkemSecret...), x25519Secret...)
+	defer zeroBytes(secret)
+	info := []byte(HybridWrapX25519MLKEM768)
+	info = append(info, ephemeral...)
+	info = append(info, mlkemCiphertext...)
+	info = append(info, recipient.x25519.Bytes()...)
+	return hkdf.Key(sha256.New, secret, nil, string(info), aesKeySize)
+}
+
+// hybridSeal returns the AES-256-GCM AEAD for a single-use KEK. Each KEK
+// seals exactly one data key, so a fixed zero nonce is safe.
+func hybridSeal(kek []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Wrap seals dek to k. The result is the X25519 ephemeral public key, the
+// ML-KEM-768 ciphertext and the sealed data key.
+func (k *HybridPublicKey) Wrap(dek []byte) ([]byte, error) {
+	eph, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("hybrid wrap: %w", err)
+	}
+	xSecret, err := eph.ECDH(k.x25519)
+	if err != nil {
+		return nil, fmt.Errorf("hybrid wrap: %w", err)
+	}
+	defer zeroBytes(xSecret)
+	mSecret, mCiphertext := k.mlkem.Encapsulate()
+	defer zeroBytes(mSecret)
+
+	ephPub := eph.PublicKey().Bytes()
+	kek, err := hybridKEK(mSecret, xSecret, ephPub, mCiphertext, k)
+	if err != nil {
+		return nil, fmt.Errorf("hybrid wrap: %w", err)
+	}
+	defer zeroBytes(kek)
+	aead, err := hybridSeal(kek)
+	if err != nil {
+		return nil, fmt.Errorf("hybrid wrap: %w", err)
+	}
+
+	out := make([]byte, 0, hybridWrapOverhead+len(dek))
+	out = append(out, ephPub...)
+	out = append(out, mCiphertext...)
+	return aead.Seal(out, make([]byte, nonceSize), dek, []byte(HybridWrapX25519MLKEM768)), nil
+}
+
+// Unwrap opens a data key sealed by [HybridPublicKey.Wrap].
+func (k *HybridPrivateKey) Unwrap(wrapped []byte) ([]byte, error) {
+	if len(wrapped) <= hybridWrapOverhead {
+		return nil, fmt.Errorf("hybrid unwrap: wrapped key has %d bytes", len(wrapped))
+	}
+	ephPub := wrapped[:x25519KeySize]
+	mCiphertext := wrapped[x25519KeySize : x25519KeySize+mlkem.CiphertextSize768]
+	sealed := wrapped[x25519KeySize+mlkem.CiphertextSize768:]
+
+	eph, err := ecdh.X25519().NewPublicKey(ephPub)
+	if err != nil {
+		return nil, fmt.Errorf("hybrid unwrap: %w", err)
+	}
+	xSecret, err := k.x25519.ECDH(eph)
+	if err != nil {
+		return nil, fmt.Errorf("hybrid unwrap: %w", err)
+	}
+	defer zeroBytes(xSecret)
+	mSecret, err := k.mlkem.Decapsulate(mCiphertext)
+	if err != nil {
+		return nil, fmt.Errorf("hybrid unwrap: %w", err)
+	}
+	defer zeroBytes(mSecret)
+
+	kek, err := hybridKEK(mSecret, xSecret, ephPub, mCiphertext, k.Public())
+	if err != nil {
+		return nil, fmt.Errorf("hybrid unwrap: %w", err)
+	}
+	defer zeroBytes(kek)
+	aead, err := hybridSeal(kek)
+	if err != nil {
+		return nil, fmt.Errorf("hybrid unwrap: %w", err)
+	}
+	dek, err := aead.Open(nil, make([]byte, nonceSize), sealed, []byte(HybridWrapX25519MLKEM768))
+	if err != nil {
+		return nil, fmt.Errorf("hybrid unwrap: %w", err)
+	}
+	return dek, nil
+}
+
+// addHybridWrap records dek wrapped to k in metadata.
+func (k *HybridPublicKey) addHybridWrap(metadata map[string]string, dek []byte) error {
+	wrapped, err := k.Wrap(dek)
+	if err != nil {
+		return err
+	}
+	metadata[MetaHybridWrapAlgorithm] = HybridWrapX25519MLKEM768
+	metadata[MetaHybridWrappedKey] = encodeBase64(wrapped)
+	metadata[MetaHybridKeyID] = k.KeyID()
+	return nil
+}
+
+// unwrapHybridMetadata recovers the data key recorded by addHybridWrap.
+func (k *HybridPrivateKey) unwrapHybridMetadata(metadata map[string]string) ([]byte, error) {
+	if alg := metadata[MetaHybridWrapAlgorithm]; alg != HybridWrapX25519MLKEM768 {
+		return nil, fmt.Errorf("hybrid unwrap: unsupported algorithm %q", alg)
+	}
+	if id, want := metadata[MetaHybridKeyID], k.Public().KeyID(); id != "" && id != want {
+		return nil, fmt.Errorf("%w: object key %s, have %s", ErrHybridKeyMismatch, id, want)
+	}
+	wrapped, err := decodeBase64(metadata[MetaHybridWrappedKey])
+	if err != nil {
+		return nil, fmt.Errorf("hybrid unwrap: failed to decode wrapped key: %w", err)
+	}
+	return k.Unwrap(wrapped)
+}
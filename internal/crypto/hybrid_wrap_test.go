@@ -0,0 +1,237 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+)
+
+func TestHybridWrap_RoundTrip(t *testing.T) {
+	priv, err := GenerateHybridKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := ParseHybridPublicKeyPEM(priv.Public().MarshalPEM())
+	if err != nil {
+		t.Fatalf("ParseHybridPublicKeyPEM() error: %v", err)
+	}
+	if pub.KeyID() != priv.Public().KeyID() {
+		t.Error("public key changes across a PEM round-trip")
+	}
+
+	dek := bytes.Repeat([]byte{0x42}, aesKeySize)
+	wrapped, err := pub.Wrap(dek)
+	if err != nil {
+		t.Fatalf("Wrap() error: %v", err)
+	}
+	if len(wrapped) != hybridWrapOverhead+len(dek) {
+		t.Errorf("wrapped key has %d bytes, want %d", len(wrapped), hybridWrapOverhead+len(dek))
+	}
+
+	restored, err := ParseHybridPrivateKeyPEM(priv.MarshalPEM())
+	if err != nil {
+		t.Fatalf("ParseHybridPrivateKeyPEM() error: %v", err)
+	}
+	got, err := restored.Unwrap(wrapped)
+	if err != nil {
+		t.Fatalf("Unwrap() error: %v", err)
+	}
+	if !bytes.Equal(got, dek) {
+		t.Error("Unwrap() does not return the data key")
+	}
+
+	// Each half of the hybrid is bound into the key-encryption key.
+	for name, offset := range map[string]int{
+		"x25519 ephemeral":  0,
+		"ml-kem ciphertext": x25519KeySize + 1,
+		"sealed key":        len(wrapped) - 1,
+	} {
+		tampered := append([]byte(nil), wrapped...)
+		tampered[offset] ^= 0x01
+		if _, err := priv.Unwrap(tampered); err == nil {
+			t.Errorf("Unwrap() accepted a modified %s", name)
+		}
+	}
+
+	other, err := GenerateHybridKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Unwrap(wrapped); err == nil {
+		t.Error("Unwrap() with another private key succeeded")
+	}
+}
+
+func TestHybridWrap_ParseErrors(t *testing.T) {
+	priv, err := GenerateHybridKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseHybridPublicKeyPEM(priv.MarshalPEM()); err == nil {
+		t.Error("ParseHybridPublicKeyPEM() accepted a private key")
+	}
+	if _, err := ParseHybridPrivateKeyPEM(priv.Public().MarshalPEM()); err == nil {
+		t.Error("ParseHybridPrivateKeyPEM() accepted a public key")
+	}
+	if _, err := ParseHybridPublicKeyPEM([]byte("not pem")); err == nil {
+		t.Error("ParseHybridPublicKeyPEM() accepted garbage")
+	}
+}
+
+func TestHybridWrap_EngineOfflineUnwrap(t *testing.T) {
+	ctx := context.Background()
+	priv, err := GenerateHybridKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	km, err := NewInMemoryKeyManager(bytes.Repeat([]byte{0x11}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := bytes.Repeat([]byte("long retention archive "), 3000)
+
+	// A small header limit cannot hold the hybrid-wrapped key, which moves
+	// the metadata into the object body.
+	small := &ProviderProfile{Name: "test-small", TotalHeaderLimit: 1536, CompactionStrategy: "base64url"}
+	for _, tc := range []struct {
+		limited bool
+		chunked bool
+	}{{false, false}, {false, true}, {true, true}} {
+		chunked := tc.chunked
+		t.Run("limited="+strconv.FormatBool(tc.limited)+"/chunked="+strconv.FormatBool(chunked), func(t *testing.T) {
+			gateway, err := NewEngineWithOpts([]byte("gateway-password-123"), nil,
+				WithKeyManager(km), WithHybridWrapKey(priv.Public()),
+				WithChunking(chunked), WithChunkSize(MinChunkSize))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.limited {
+				gateway.(*engine).providerProfile = small
+				gateway.(*engine).compactor = NewMetadataCompactor(small)
+			}
+			r, meta, err := gateway.Encrypt(ctx, bytes.NewReader(plaintext), map[string]string{
+				"Content-Length": strconv.Itoa(len(plaintext)),
+			})
+			if err != nil {
+				t.Fatalf("Encrypt() error: %v", err)
+			}
+			body, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tc.limited {
+				expanded, err := NewMetadataCompactor(GetProviderProfile("default")).ExpandMetadata(meta)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if expanded[MetaHybridWrapAlgorithm] != HybridWrapX25519MLKEM768 || expanded[MetaHybridKeyID] != priv.Public().KeyID() {
+					t.Fatalf("metadata does not record the hybrid wrap: alg=%q id=%q", expanded[MetaHybridWrapAlgorithm], expanded[MetaHybridKeyID])
+				}
+				if expanded[MetaWrappedKeyCiphertext] == "" {
+					t.Fatal("the KMS envelope is missing")
+				}
+			} else if meta[MetaFallbackMode] != "true" {
+				t.Fatalf("metadata of %d bytes was not moved into the body", EstimateMetadataSize(meta))
+			}
+
+			// The gateway keeps reading through the KMS.
+			dec, _, err := gateway.Decrypt(ctx, bytes.NewReader(body), meta)
+			if err != nil {
+				t.Fatalf("Decrypt() via KMS error: %v", err)
+			}
+			if got, _ := io.ReadAll(dec); !bytes.Equal(got, plaintext) {
+				t.Fatal("Decrypt() via KMS does not return the plaintext")
+			}
+
+			// Offline: no KMS and a different password, only the private key.
+			offline, err := NewEngineWithOpts([]byte("unrelated-password-456"), nil, WithHybridUnwrapKey(priv))
+			if err != nil {
+				t.Fatal(err)
+			}
+			dec, _, err = offline.Decrypt(ctx, bytes.NewReader(body), meta)
+			if err != nil {
+				t.Fatalf("Decrypt() offline error: %v", err)
+			}
+			if got, _ := io.ReadAll(dec); !bytes.Equal(got, plaintext) {
+				t.Fatal("Decrypt() offline does not return the plaintext")
+			}
+			if chunked && !tc.limited {
+				dec, _, err = offline.DecryptRange(ctx, bytes.NewReader(body), meta, 20000, 40000)
+				if err != nil {
+					t.Fatalf("DecryptRange() offline error: %v", err)
+				}
+				if got, _ := io.ReadAll(dec); !bytes.Equal(got, plaintext[20000:40001]) {
+					t.Fatal("DecryptRange() offline returned the wrong bytes")
+				}
+			}
+
+			wrongKey, err := GenerateHybridKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			wrong, err := NewEngineWithOpts([]byte("unrelated-password-456"), nil, WithHybridUnwrapKey(wrongKey))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := wrong.Decrypt(ctx, bytes.NewReader(body), meta); !errors.Is(err, ErrHybridKeyMismatch) {
+				t.Errorf("Decrypt() with another hybrid key: error = %v, want ErrHybridKeyMismatch", err)
+			}
+		})
+	}
+}
+
+func TestHybridWrap_PasswordModeUnchanged(t *testing.T) {
+	priv, err := GenerateHybridKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, err := NewEngineWithOpts([]byte("gateway-password-123"), nil, WithHybridWrapKey(priv.Public()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, meta, err := engine.Encrypt(context.Background(), bytes.NewReader([]byte("data")), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := meta[MetaHybridWrappedKey]; ok {
+		t.Error("password-derived keys are hybrid-wrapped")
+	}
+}
+
+func TestHybridWrap_SinglePartFallbackNotWrapped(t *testing.T) {
+	priv, err := GenerateHybridKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	km, err := NewInMemoryKeyManager(bytes.Repeat([]byte{0x11}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engineIface, err := NewEngineWithOpts([]byte("gateway-password-123"), nil, WithKeyManager(km), WithHybridWrapKey(priv.Public()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	small := &ProviderProfile{Name: "test-small", TotalHeaderLimit: 1536, CompactionStrategy: "base64url"}
+	engineIface.(*engine).providerProfile = small
+	engineIface.(*engine).compactor = NewMetadataCompactor(small)
+
+	r, meta, err := engineIface.Encrypt(context.Background(), bytes.NewReader([]byte("fallback data")), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta[MetaFallbackMode] != "true" {
+		t.Fatal("metadata was not moved into the body")
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The password-keyed fallback format must not advertise a wrapped key
+	// that cannot open it.
+	if bytes.Contains(body, []byte(MetaHybridWrappedKey)) {
+		t.Error("single-part fallback object carries a hybrid-wrapped key")
+	}
+}
@@ -0,0 +1,41 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+)
+
+// CanaryWrap wraps a random data key under version and unwraps it again,
+// showing that this process could serve objects written once version is
+// active. The canary key is never stored. It returns ErrRotationNotSupported
+// when the key manager cannot wrap under a chosen version.
+func CanaryWrap(ctx context.Context, km KeyManager, version int) error {
+	vw, ok := KeyManagerAs[VersionWrapper](km)
+	if !ok {
+		return fmt.Errorf("%w: %s cannot wrap under a chosen version", ErrRotationNotSupported, km.Provider())
+	}
+	dek := make([]byte, aesKeySize)
+	if _, err := rand.Read(dek); err != nil {
+		return fmt.Errorf("canary: generate key: %w", err)
+	}
+	defer zeroBytes(dek)
+
+	env, err := vw.WrapKeyWithVersion(ctx, dek, version)
+	if err != nil {
+		return fmt.Errorf("canary: wrap under version %d: %w", version, err)
+	}
+	if env.KeyVersion != version {
+		return fmt.Errorf("canary: wrapped under version %d, want %d", env.KeyVersion, version)
+	}
+	got, err := km.UnwrapKey(ctx, env, nil)
+	if err != nil {
+		return fmt.Errorf("canary: unwrap version %d: %w", version, err)
+	}
+	defer zeroBytes(got)
+	if !bytes.Equal(got, dek) {
+		return fmt.Errorf("canary: version %d unwrapped to a different key", version)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCanaryWrap(t *testing.T) {
+	ctx := context.Background()
+	km := NewInMemoryKeyManagerForTestWithKeys(bytes.Repeat([]byte{1}, 32), 1)
+	if err := km.AddVersion(ctx, 2, bytes.Repeat([]byte{2}, 32)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []int{1, 2} {
+		if err := CanaryWrap(ctx, km, v); err != nil {
+			t.Errorf("CanaryWrap(%d): %v", v, err)
+		}
+	}
+	if err := CanaryWrap(ctx, km, 3); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("CanaryWrap(unknown version) = %v, want ErrKeyNotFound", err)
+	}
+	if active, _ := km.ActiveKeyVersion(ctx); active != 1 {
+		t.Errorf("active version = %d after canaries", active)
+	}
+
+	pkm, err := NewPasswordKeyManager([]byte("test-password-123456"), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CanaryWrap(ctx, pkm, 1); !errors.Is(err, ErrRotationNotSupported) {
+		t.Errorf("CanaryWrap(password manager) = %v, want ErrRotationNotSupported", err)
+	}
+}
@@ -0,0 +1,194 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// keyObfuscationSalt domain-separates the object-name keys from every other
+// key derived from the gateway password.
+var keyObfuscationSalt = []byte("s3-encryption-gateway/key-obfuscation/v1")
+
+// sivSize is the length of the synthetic IV prepended to each encoded
+// segment.
+const sivSize = aes.BlockSize
+
+// ErrNotObfuscated is returned by DecodeKey for backend names that were not
+// produced by EncodeKey under the same password and bucket.
+var ErrNotObfuscated = errors.New("key obfuscation: name was not produced by this gateway")
+
+// Key obfuscation schemes. They trade what the backend can infer from the
+// encoded names against how much listing work the backend can still do.
+const (
+	// KeyObfuscationSegment encrypts every "/"-separated segment on its own.
+	// Prefix and delimiter listings map directly onto the backend, but equal
+	// segment names encode identically wherever they appear.
+	KeyObfuscationSegment = "segment"
+	// KeyObfuscationPath also binds each segment to its parent path, so a
+	// name repeated in different directories no longer encodes identically.
+	// Listing works as for KeyObfuscationSegment.
+	KeyObfuscationPath = "path"
+	// KeyObfuscationFull encrypts the whole key as one name, hiding depth and
+	// shared parents. The backend can no longer filter by prefix: every
+	// listing scans the bucket and is filtered in the gateway.
+	KeyObfuscationFull = "full"
+)
+
+// KeyObfuscator deterministically encrypts object keys so the backend never
+// sees real names.
+//
+// Each encoded unit (a segment, or the whole key under KeyObfuscationFull)
+// uses an SIV construction: the IV is an HMAC-SHA256 over the bucket, the
+// scheme's context and the plaintext, truncated to one AES block, and the
+// plaintext is AES-CTR encrypted under it. Equal inputs therefore map to
+// equal backend names (GET and overwrite keep working) and decoding
+// authenticates the result by recomputing the IV. The backend still learns
+// approximate name lengths.
+type KeyObfuscator struct {
+	scheme string
+	block  cipher.Block
+	macKey []byte
+}
+
+// NewKeyObfuscator derives the name-encryption keys from password with
+// PBKDF2. An empty scheme selects KeyObfuscationPath.
+func NewKeyObfuscator(password []byte, iterations int, scheme string) (*KeyObfuscator, error) {
+	switch scheme {
+	case "":
+		scheme = KeyObfuscationPath
+	case KeyObfuscationSegment, KeyObfuscationPath, KeyObfuscationFull:
+	default:
+		return nil, fmt.Errorf("unsupported key obfuscation scheme %q", scheme)
+	}
+	if len(password) == 0 {
+		return nil, errors.New("key obfuscation: password is required")
+	}
+	if iterations <= 0 {
+		iterations = DefaultPBKDF2Iterations
+	}
+	keys, err := pbkdf2.Key(sha256.New, string(password), keyObfuscationSalt, iterations, 2*aesKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("key obfuscation: derive key: %w", err)
+	}
+	defer zeroBytes(keys)
+	block, err := aes.NewCipher(keys[:aesKeySize])
+	if err != nil {
+		return nil, fmt.Errorf("key obfuscation: %w", err)
+	}
+	macKey := make([]byte, aesKeySize)
+	copy(macKey, keys[aesKeySize:])
+	return &KeyObfuscator{scheme: scheme, block: block, macKey: macKey}, nil
+}
+
+// Scheme returns the configured obfuscation scheme.
+func (o *KeyObfuscator) Scheme() string {
+	return o.scheme
+}
+
+// EncodeKey returns the backend name for key in bucket. Under the segment
+// and path schemes empty segments (a leading, doubled or trailing "/") stay
+// empty so directory markers keep their shape.
+func (o *KeyObfuscator) EncodeKey(bucket, key string) string {
+	if o.scheme == KeyObfuscationFull {
+		if key == "" {
+			return ""
+		}
+		return o.encodeUnit(bucket, "", key)
+	}
+	segs := strings.Split(key, "/")
+	parent := ""
+	for i, seg := range segs {
+		if seg != "" {
+			segs[i] = o.encodeUnit(bucket, o.context(parent), seg)
+		}
+		parent += seg + "/"
+	}
+	return strings.Join(segs, "/")
+}
+
+// DecodeKey reverses EncodeKey. It returns ErrNotObfuscated when any segment
+// fails to decode or authenticate.
+func (o *KeyObfuscator) DecodeKey(bucket, name string) (string, error) {
+	if o.scheme == KeyObfuscationFull {
+		if name == "" {
+			return "", nil
+		}
+		return o.decodeUnit(bucket, "", name)
+	}
+	segs := strings.Split(name, "/")
+	parent := ""
+	for i, seg := range segs {
+		if seg != "" {
+			plain, err := o.decodeUnit(bucket, o.context(parent), seg)
+			if err != nil {
+				return "", err
+			}
+			segs[i] = plain
+		}
+		parent += segs[i] + "/"
+	}
+	return strings.Join(segs, "/"), nil
+}
+
+// EncodePrefix maps a listing prefix onto the backend. Only whole segments
+// can be translated, so a prefix ending inside a segment ("photos/20") is
+// cut back to its last "/" ("photos/"); exact is then false and the caller
+// must filter decoded keys against the original prefix. Under
+// KeyObfuscationFull no prefix translates and the whole bucket is listed.
+func (o *KeyObfuscator) EncodePrefix(bucket, prefix string) (backendPrefix string, exact bool) {
+	if o.scheme == KeyObfuscationFull {
+		return "", prefix == ""
+	}
+	i := strings.LastIndex(prefix, "/")
+	whole := prefix[:i+1]
+	return o.EncodeKey(bucket, whole), i+1 == len(prefix)
+}
+
+// context returns what a segment is bound to besides the bucket.
+func (o *KeyObfuscator) context(parent string) string {
+	if o.scheme == KeyObfuscationPath {
+		return parent
+	}
+	return ""
+}
+
+func (o *KeyObfuscator) siv(bucket, context, plain string) []byte {
+	mac := hmac.New(sha256.New, o.macKey)
+	mac.Write([]byte(o.scheme))
+	mac.Write([]byte{0})
+	mac.Write([]byte(bucket))
+	mac.Write([]byte{0})
+	mac.Write([]byte(context))
+	mac.Write([]byte{0})
+	mac.Write([]byte(plain))
+	return mac.Sum(nil)[:sivSize]
+}
+
+func (o *KeyObfuscator) encodeUnit(bucket, context, plain string) string {
+	iv := o.siv(bucket, context, plain)
+	out := make([]byte, sivSize+len(plain))
+	copy(out, iv)
+	cipher.NewCTR(o.block, iv).XORKeyStream(out[sivSize:], []byte(plain))
+	return base64.RawURLEncoding.EncodeToString(out)
+}
+
+func (o *KeyObfuscator) decodeUnit(bucket, context, enc string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(enc)
+	if err != nil || len(raw) <= sivSize {
+		return "", ErrNotObfuscated
+	}
+	iv, ct := raw[:sivSize], raw[sivSize:]
+	plain := make([]byte, len(ct))
+	cipher.NewCTR(o.block, iv).XORKeyStream(plain, ct)
+	if !hmac.Equal(iv, o.siv(bucket, context, string(plain))) {
+		return "", ErrNotObfuscated
+	}
+	return string(plain), nil
+}
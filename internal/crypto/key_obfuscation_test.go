@@ -0,0 +1,127 @@
+package crypto
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var keyObfuscationSchemes = []string{KeyObfuscationSegment, KeyObfuscationPath, KeyObfuscationFull}
+
+func newTestObfuscator(t *testing.T, password, scheme string) *KeyObfuscator {
+	t.Helper()
+	o, err := NewKeyObfuscator([]byte(password), 1000, scheme)
+	if err != nil {
+		t.Fatalf("NewKeyObfuscator: %v", err)
+	}
+	return o
+}
+
+func TestKeyObfuscator_RoundTrip(t *testing.T) {
+	for _, scheme := range keyObfuscationSchemes {
+		t.Run(scheme, func(t *testing.T) {
+			o := newTestObfuscator(t, "test-password-123456", scheme)
+			for _, key := range []string{"a", "reports/2024/q3-payroll.xlsx", "dir/", "/lead", "x//y", "ünïcode/名前"} {
+				name := o.EncodeKey("bucket", key)
+				if strings.Contains(name, "payroll") || strings.Contains(name, "reports") {
+					t.Errorf("EncodeKey(%q) leaks plaintext: %q", key, name)
+				}
+				wantSlashes := strings.Count(key, "/")
+				if scheme == KeyObfuscationFull {
+					wantSlashes = 0
+				}
+				if strings.Count(name, "/") != wantSlashes {
+					t.Errorf("EncodeKey(%q) = %q: unexpected hierarchy", key, name)
+				}
+				got, err := o.DecodeKey("bucket", name)
+				if err != nil || got != key {
+					t.Errorf("DecodeKey(EncodeKey(%q)) = %q, %v", key, got, err)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyObfuscator_Deterministic(t *testing.T) {
+	for _, scheme := range keyObfuscationSchemes {
+		o := newTestObfuscator(t, "test-password-123456", scheme)
+		a := o.EncodeKey("bucket", "docs/readme.txt")
+		if b := o.EncodeKey("bucket", "docs/readme.txt"); a != b {
+			t.Errorf("%s: encoding is not deterministic: %q vs %q", scheme, a, b)
+		}
+		if c := o.EncodeKey("other", "docs/readme.txt"); c == a {
+			t.Errorf("%s: the same key encodes identically in different buckets", scheme)
+		}
+	}
+}
+
+func TestKeyObfuscator_SchemeTradeoffs(t *testing.T) {
+	lastSeg := func(name string) string { return name[strings.LastIndex(name, "/")+1:] }
+
+	seg := newTestObfuscator(t, "test-password-123456", KeyObfuscationSegment)
+	if lastSeg(seg.EncodeKey("b", "a/readme.txt")) != lastSeg(seg.EncodeKey("b", "z/readme.txt")) {
+		t.Error("segment: a repeated file name should encode identically")
+	}
+
+	path := newTestObfuscator(t, "test-password-123456", KeyObfuscationPath)
+	a, z := path.EncodeKey("b", "a/readme.txt"), path.EncodeKey("b", "z/readme.txt")
+	if lastSeg(a) == lastSeg(z) {
+		t.Error("path: a repeated file name must not encode identically under different parents")
+	}
+	sib := path.EncodeKey("b", "a/other.txt")
+	if a[:strings.Index(a, "/")] != sib[:strings.Index(sib, "/")] {
+		t.Error("path: siblings should share an encoded parent")
+	}
+
+	full := newTestObfuscator(t, "test-password-123456", KeyObfuscationFull)
+	if strings.Contains(full.EncodeKey("b", "a/b/c"), "/") {
+		t.Error("full: encoded names must not reveal the hierarchy")
+	}
+}
+
+func TestKeyObfuscator_DecodeRejectsForeignNames(t *testing.T) {
+	o := newTestObfuscator(t, "test-password-123456", KeyObfuscationPath)
+	other := newTestObfuscator(t, "another-password-123", KeyObfuscationPath)
+	seg := newTestObfuscator(t, "test-password-123456", KeyObfuscationSegment)
+	for _, name := range []string{
+		"plain-name.txt",
+		other.EncodeKey("bucket", "k"),
+		o.EncodeKey("other-bucket", "k"),
+		seg.EncodeKey("bucket", "k"),
+	} {
+		if _, err := o.DecodeKey("bucket", name); !errors.Is(err, ErrNotObfuscated) {
+			t.Errorf("DecodeKey(%q) err = %v, want ErrNotObfuscated", name, err)
+		}
+	}
+}
+
+func TestKeyObfuscator_EncodePrefix(t *testing.T) {
+	o := newTestObfuscator(t, "test-password-123456", KeyObfuscationPath)
+	tests := []struct {
+		prefix    string
+		wantWhole string
+		wantExact bool
+	}{
+		{"", "", true},
+		{"photos/", "photos/", true},
+		{"photos/20", "photos/", false},
+		{"pho", "", false},
+	}
+	for _, tt := range tests {
+		got, exact := o.EncodePrefix("bucket", tt.prefix)
+		if want := o.EncodeKey("bucket", tt.wantWhole); got != want || exact != tt.wantExact {
+			t.Errorf("EncodePrefix(%q) = %q, %v; want %q, %v", tt.prefix, got, exact, want, tt.wantExact)
+		}
+	}
+
+	full := newTestObfuscator(t, "test-password-123456", KeyObfuscationFull)
+	if got, exact := full.EncodePrefix("bucket", "photos/"); got != "" || exact {
+		t.Errorf("full: EncodePrefix = %q, %v; want a bucket-wide scan", got, exact)
+	}
+}
+
+func TestNewKeyObfuscator_InvalidScheme(t *testing.T) {
+	if _, err := NewKeyObfuscator([]byte("test-password-123456"), 1000, "hash"); err == nil {
+		t.Fatal("expected error for unknown scheme")
+	}
+}
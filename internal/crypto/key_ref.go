@@ -0,0 +1,43 @@
+package crypto
+
+import (
+	"io"
+	"strconv"
+)
+
+// KeyRef identifies the KMS wrapping key an object's data key is wrapped
+// under.
+type KeyRef struct {
+	Provider string `json:"provider,omitempty"`
+	KeyID    string `json:"key_id,omitempty"`
+	Version  int    `json:"version"`
+}
+
+// ObjectKeyRef returns the wrapping key of a single-part object. It reports
+// false for objects whose data key is derived from the password rather than
+// wrapped by a KMS. body is only read for version 2 fallback objects, which
+// keep the envelope in a prefix of the body; it may be nil for the others.
+func ObjectKeyRef(metadata map[string]string, body io.Reader) (KeyRef, bool, error) {
+	if metadata[MetaFallbackMode] == "true" && fallbackVersion(metadata) == 2 && body != nil {
+		inBody, err := readFallbackMetadata(body)
+		if err != nil {
+			return KeyRef{}, false, err
+		}
+		metadata = inBody
+	}
+	pick := func(full, compact string) string {
+		if v := metadata[full]; v != "" {
+			return v
+		}
+		return metadata[compact]
+	}
+	if pick(MetaWrappedKeyCiphertext, "x-amz-meta-wk") == "" {
+		return KeyRef{}, false, nil
+	}
+	ref := KeyRef{
+		Provider: pick(MetaKMSProvider, "x-amz-meta-kp"),
+		KeyID:    pick(MetaKMSKeyID, "x-amz-meta-kid"),
+	}
+	ref.Version, _ = strconv.Atoi(pick(MetaKeyVersion, "x-amz-meta-kv"))
+	return ref, true, nil
+}
@@ -106,6 +106,37 @@ type RotatableKeyManager interface {
 	PromoteActiveVersion(ctx context.Context, plan RotationPlan) error
 }
 
+// RetirableKeyManager is an optional extension implemented by adapters that
+// can destroy an inactive wrapping key version. Every data key wrapped under
+// a retired version becomes permanently unrecoverable, so callers must check
+// that nothing still needed depends on it first.
+type RetirableKeyManager interface {
+	KeyManager
+
+	// RetireVersion destroys the given version. It returns ErrKeyNotFound if
+	// the version does not exist and ErrRotationConflict if it is active.
+	RetireVersion(ctx context.Context, version int) error
+}
+
+// VersionWrapper is an optional extension implemented by adapters that can
+// wrap a data key under a given version rather than the active one. Rotation
+// dry runs use it to exercise a target version before it is promoted.
+type VersionWrapper interface {
+	// WrapKeyWithVersion wraps plaintext under version. It returns
+	// ErrKeyNotFound if the version is unknown to the adapter.
+	WrapKeyWithVersion(ctx context.Context, plaintext []byte, version int) (*KeyEnvelope, error)
+}
+
+// KeyVersionSource is an optional extension implemented by adapters that can
+// ask the KMS which configured key it considers active. Deployments that
+// rotate keys in the KMS rather than through the admin API use it to switch
+// wrapping without a restart; see the key version watch.
+type KeyVersionSource interface {
+	// FetchActiveKeyVersion returns the version the KMS reports as active.
+	// It does not change the version the adapter wraps under.
+	FetchActiveKeyVersion(ctx context.Context) (int, error)
+}
+
 // RotationPlan describes a pending key rotation.
 type RotationPlan struct {
 	CurrentVersion int
@@ -29,6 +29,9 @@ type CosmianKMIPOptions struct {
 	Timeout        time.Duration
 	Provider       string
 	DualReadWindow int
+	// LazyConnect defers dialing a binary KMIP endpoint until the first
+	// operation that needs it. A failed dial is retried on the next one.
+	LazyConnect bool
 }
 
 type cosmianKeyState struct {
@@ -39,7 +42,8 @@ type cosmianKeyState struct {
 }
 
 type cosmianKMIPManager struct {
-	client *kmipclient.Client
+	client *kmipclient.Client // nil until dialed when LazyConnect is set
+	closed bool
 	state  *cosmianKeyState
 	mu     sync.RWMutex
 }
@@ -111,6 +115,7 @@ func prepareCosmianKeyState(opts CosmianKMIPOptions) (*cosmianKeyState, error) {
 			Timeout:        timeout,
 			Provider:       provider,
 			DualReadWindow: opts.DualReadWindow,
+			LazyConnect:    opts.LazyConnect,
 		},
 		keyLookup:     keyLookup,
 		versionLookup: versionLookup,
@@ -119,14 +124,54 @@ func prepareCosmianKeyState(opts CosmianKMIPOptions) (*cosmianKeyState, error) {
 }
 
 func newCosmianKMIPBinaryManager(state *cosmianKeyState) (KeyManager, error) {
-	client, err := kmipclient.Dial(state.opts.Endpoint, kmipclient.WithTlsConfig(state.opts.TLSConfig))
+	m := &cosmianKMIPManager{state: state}
+	if state.opts.LazyConnect {
+		return m, nil
+	}
+	client, err := state.dial(context.Background())
 	if err != nil {
-		return nil, fmt.Errorf("kms: failed to dial KMIP endpoint %s: %w", state.opts.Endpoint, err)
+		return nil, err
 	}
-	return &cosmianKMIPManager{
-		client: client,
-		state:  state,
-	}, nil
+	m.client = client
+	return m, nil
+}
+
+func (s *cosmianKeyState) dial(ctx context.Context) (*kmipclient.Client, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	client, err := kmipclient.DialContext(ctx, s.opts.Endpoint, kmipclient.WithTlsConfig(s.opts.TLSConfig))
+	if err != nil {
+		return nil, fmt.Errorf("kms: failed to dial KMIP endpoint %s: %w", s.opts.Endpoint, kmsUnavailable(err))
+	}
+	return client, nil
+}
+
+// conn returns the KMIP client, dialing it first if the manager was created
+// with LazyConnect and no operation has connected yet.
+func (m *cosmianKMIPManager) conn(ctx context.Context) (*kmipclient.Client, error) {
+	m.mu.RLock()
+	client, closed := m.client, m.closed
+	m.mu.RUnlock()
+	if client != nil {
+		return client, nil
+	}
+	if closed {
+		return nil, ErrProviderUnavailable
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrProviderUnavailable
+	}
+	if m.client != nil {
+		return m.client, nil
+	}
+	client, err := m.state.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m.client = client
+	return client, nil
 }
 
 // Provider implements KeyManager.
@@ -139,17 +184,15 @@ func (m *cosmianKMIPManager) WrapKey(ctx context.Context, plaintext []byte, _ ma
 	if len(plaintext) == 0 {
 		return nil, errors.New("kms: plaintext DEK is empty")
 	}
-	m.mu.RLock()
-	if m.client == nil {
-		m.mu.RUnlock()
-		return nil, ErrProviderUnavailable
+	client, err := m.conn(ctx)
+	if err != nil {
+		return nil, err
 	}
-	m.mu.RUnlock()
 	ctx, cancel := m.state.withTimeout(ctx)
 	defer cancel()
 	active := m.state.opts.Keys[0]
 
-	resp, err := m.client.
+	resp, err := client.
 		Encrypt(active.ID).
 		WithCryptographicParameters(m.defaultCryptoParams()).
 		Data(plaintext).
@@ -175,6 +218,41 @@ func (m *cosmianKMIPManager) WrapKey(ctx context.Context, plaintext []byte, _ ma
 	}, nil
 }
 
+// WrapKeyWithVersion implements VersionWrapper by encrypting under the key
+// reference configured for version.
+func (m *cosmianKMIPManager) WrapKeyWithVersion(ctx context.Context, plaintext []byte, version int) (*KeyEnvelope, error) {
+	if len(plaintext) == 0 {
+		return nil, errors.New("kms: plaintext DEK is empty")
+	}
+	client, err := m.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	ref, ok := m.state.versionLookup[version]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: version %d not found in configured keys", ErrKeyNotFound, version)
+	}
+	ctx, cancel := m.state.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := client.
+		Encrypt(ref.ID).
+		WithCryptographicParameters(m.defaultCryptoParams()).
+		Data(plaintext).
+		ExecContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("kms: encrypt failed (key ID: %s): %w", ref.ID, err)
+	}
+	return &KeyEnvelope{
+		KeyID:      ref.ID,
+		KeyVersion: version,
+		Provider:   m.Provider(),
+		Ciphertext: resp.Data,
+	}, nil
+}
+
 // UnwrapKey implements KeyManager.
 func (m *cosmianKMIPManager) UnwrapKey(ctx context.Context, envelope *KeyEnvelope, _ map[string]string) ([]byte, error) {
 	if envelope == nil {
@@ -183,12 +261,10 @@ func (m *cosmianKMIPManager) UnwrapKey(ctx context.Context, envelope *KeyEnvelop
 	if len(envelope.Ciphertext) == 0 {
 		return nil, fmt.Errorf("%w: wrapped key is empty", ErrInvalidEnvelope)
 	}
-	m.mu.RLock()
-	if m.client == nil {
-		m.mu.RUnlock()
-		return nil, ErrProviderUnavailable
+	client, err := m.conn(ctx)
+	if err != nil {
+		return nil, err
 	}
-	m.mu.RUnlock()
 	ctx, cancel := m.state.withTimeout(ctx)
 	defer cancel()
 
@@ -210,7 +286,7 @@ func (m *cosmianKMIPManager) UnwrapKey(ctx context.Context, envelope *KeyEnvelop
 		if attempts >= maxAttempts {
 			break
 		}
-		resp, err := m.client.
+		resp, err := client.
 			Decrypt(candidate).
 			WithCryptographicParameters(m.defaultCryptoParams()).
 			Data(envelope.Ciphertext).
@@ -238,11 +314,9 @@ func (m *cosmianKMIPManager) ActiveKeyVersion(_ context.Context) (int, error) {
 
 // HealthCheck implements KeyManager.
 func (m *cosmianKMIPManager) HealthCheck(ctx context.Context) error {
-	m.mu.RLock()
-	closed := m.client == nil
-	m.mu.RUnlock()
-	if closed {
-		return fmt.Errorf("kms: client not initialized: %w", ErrProviderUnavailable)
+	client, err := m.conn(ctx)
+	if err != nil {
+		return fmt.Errorf("kms: client not initialized: %w", err)
 	}
 	if len(m.state.opts.Keys) == 0 {
 		return errors.New("kms: no keys configured")
@@ -254,8 +328,7 @@ func (m *cosmianKMIPManager) HealthCheck(ctx context.Context) error {
 	// Perform a lightweight Get operation on the first key to verify connectivity
 	// This doesn't perform encryption/decryption, just verifies the KMS is reachable
 	active := m.state.opts.Keys[0]
-	_, err := m.client.Get(active.ID).ExecContext(ctx)
-	if err != nil {
+	if _, err := client.Get(active.ID).ExecContext(ctx); err != nil {
 		return fmt.Errorf("kms: health check failed (key ID: %s): %w", active.ID, err)
 	}
 	return nil
@@ -265,6 +338,7 @@ func (m *cosmianKMIPManager) HealthCheck(ctx context.Context) error {
 func (m *cosmianKMIPManager) Close(_ context.Context) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.closed = true
 	if m.client != nil {
 		err := m.client.Close()
 		m.client = nil
@@ -337,13 +411,50 @@ func endpointHasScheme(endpoint string) bool {
 // Compile-time assertion that *cosmianKMIPManager implements RotatableKeyManager.
 var _ RotatableKeyManager = (*cosmianKMIPManager)(nil)
 
+var _ VersionWrapper = (*cosmianKMIPManager)(nil)
+
+var _ KeyVersionSource = (*cosmianKMIPManager)(nil)
+
+// FetchActiveKeyVersion implements [KeyVersionSource]. It reads the KMIP
+// State of every configured key and returns the highest version whose key
+// is Active, so an operator rotates by activating the new key in the KMS
+// (and deactivating the old one) after adding it to the gateway's keys.
+func (m *cosmianKMIPManager) FetchActiveKeyVersion(ctx context.Context) (int, error) {
+	client, err := m.conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	m.mu.RLock()
+	keys := slices.Clone(m.state.opts.Keys)
+	m.mu.RUnlock()
+	ctx, cancel := m.state.withTimeout(ctx)
+	defer cancel()
+
+	active := -1
+	for _, ref := range keys {
+		resp, err := client.GetAttributes(ref.ID, kmip.AttributeNameState).ExecContext(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("kms: get state failed (key ID: %s): %w", ref.ID, err)
+		}
+		for _, attr := range resp.Attribute {
+			if attr.AttributeName == kmip.AttributeNameState && attr.AttributeValue == kmip.StateActive && ref.Version > active {
+				active = ref.Version
+			}
+		}
+	}
+	if active < 0 {
+		return 0, fmt.Errorf("%w: none of the configured keys is active in the KMS", ErrKeyNotFound)
+	}
+	return active, nil
+}
+
 // PrepareRotation implements [RotatableKeyManager]. For the Cosmian adapter,
 // rotation means promoting a different configured KMIPKeyReference to index 0.
 // If target is nil, it picks the next-higher version number not currently active.
 func (m *cosmianKMIPManager) PrepareRotation(_ context.Context, target *int) (RotationPlan, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	if m.client == nil {
+	if m.closed {
 		return RotationPlan{}, ErrProviderUnavailable
 	}
 
@@ -396,7 +507,7 @@ func (m *cosmianKMIPManager) PrepareRotation(_ context.Context, target *int) (Ro
 func (m *cosmianKMIPManager) PromoteActiveVersion(_ context.Context, plan RotationPlan) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if m.client == nil {
+	if m.closed {
 		return ErrProviderUnavailable
 	}
 
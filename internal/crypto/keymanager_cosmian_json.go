@@ -13,6 +13,8 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/airgap"
 )
 
 const (
@@ -45,6 +47,7 @@ func newCosmianKMIPJSONManager(state *cosmianKeyState) (KeyManager, error) {
 	}
 
 	var transport http.RoundTripper = &http.Transport{
+		DialContext:     airgap.DialContext,
 		TLSClientConfig: nil,
 	}
 	if t, ok := transport.(*http.Transport); ok {
@@ -324,7 +327,7 @@ func (m *cosmianKMIPJSONManager) doRequest(ctx context.Context, payload kmipJSON
 
 	resp, err := m.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("kms: KMIP request failed: %w", err)
+		return nil, fmt.Errorf("kms: KMIP request failed: %w", kmsUnavailable(err))
 	}
 	defer resp.Body.Close()
 
@@ -333,6 +336,9 @@ func (m *cosmianKMIPJSONManager) doRequest(ctx context.Context, payload kmipJSON
 		return nil, fmt.Errorf("kms: failed to read KMIP response: %w", err)
 	}
 
+	if resp.StatusCode >= 500 {
+		return nil, fmt.Errorf("kms: KMIP request failed (status %d): %w: %s", resp.StatusCode, ErrKMSUnavailable, strings.TrimSpace(string(respBody)))
+	}
 	if resp.StatusCode >= 400 {
 		return nil, fmt.Errorf("kms: KMIP request failed (status %d): %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
 	}
@@ -0,0 +1,209 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+)
+
+// CallPolicy bounds the time a KeyManager call can take. Each attempt gets
+// Timeout; failed attempts that may succeed on retry are retried up to
+// MaxAttempts in total, sleeping a random duration of up to
+// InitialBackoff*2^n (capped at MaxBackoff) between attempts.
+//
+// Retries are also limited by a budget shared by every call on the
+// manager: each call earns RetryBudget retries, and at most RetryBurst
+// are banked. While the KMS is healthy the budget stays full; once it
+// degrades, retries stop after roughly RetryBudget per call and requests
+// fail after a single Timeout instead of multiplying the load.
+type CallPolicy struct {
+	Timeout        time.Duration
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	RetryBudget    float64
+	RetryBurst     int
+}
+
+// deadlineKeyManager applies a CallPolicy to another KeyManager.
+type deadlineKeyManager struct {
+	inner  KeyManager
+	policy CallPolicy
+	budget *retryBudget
+}
+
+// NewDeadlineKeyManager wraps km so that WrapKey, UnwrapKey and
+// ActiveKeyVersion follow policy, and HealthCheck gets a single attempt of
+// policy.Timeout. An attempt that times out is reported as
+// ErrKMSUnavailable. Optional interfaces of km (rotation, versioned wraps)
+// are reached through KeyManagerAs.
+func NewDeadlineKeyManager(km KeyManager, policy CallPolicy) KeyManager {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return &deadlineKeyManager{
+		inner:  km,
+		policy: policy,
+		budget: newRetryBudget(policy.RetryBudget, policy.RetryBurst),
+	}
+}
+
+// Unwrap returns the wrapped key manager.
+func (d *deadlineKeyManager) Unwrap() KeyManager { return d.inner }
+
+func (d *deadlineKeyManager) Provider() string { return d.inner.Provider() }
+
+func (d *deadlineKeyManager) WrapKey(ctx context.Context, plaintext []byte, metadata map[string]string) (*KeyEnvelope, error) {
+	return callWithPolicy(ctx, d, "wrap", func(ctx context.Context) (*KeyEnvelope, error) {
+		return d.inner.WrapKey(ctx, plaintext, metadata)
+	})
+}
+
+func (d *deadlineKeyManager) UnwrapKey(ctx context.Context, envelope *KeyEnvelope, metadata map[string]string) ([]byte, error) {
+	return callWithPolicy(ctx, d, "unwrap", func(ctx context.Context) ([]byte, error) {
+		return d.inner.UnwrapKey(ctx, envelope, metadata)
+	})
+}
+
+func (d *deadlineKeyManager) ActiveKeyVersion(ctx context.Context) (int, error) {
+	return callWithPolicy(ctx, d, "active key version", d.inner.ActiveKeyVersion)
+}
+
+// HealthCheck is not retried: a probe should report what it sees.
+func (d *deadlineKeyManager) HealthCheck(ctx context.Context) error {
+	_, err := attempt(ctx, d, "health check", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, d.inner.HealthCheck(ctx)
+	})
+	return err
+}
+
+func (d *deadlineKeyManager) Close(ctx context.Context) error { return d.inner.Close(ctx) }
+
+// callWithPolicy runs fn under the policy of d, retrying while the error
+// is transient, attempts remain and the retry budget allows.
+func callWithPolicy[T any](ctx context.Context, d *deadlineKeyManager, op string, fn func(context.Context) (T, error)) (T, error) {
+	d.budget.deposit()
+	for n := 1; ; n++ {
+		v, err := attempt(ctx, d, op, fn)
+		if err == nil || n >= d.policy.MaxAttempts || !retryableKMSError(ctx, err) || !d.budget.withdraw() {
+			return v, err
+		}
+		if sleepCtx(ctx, d.backoff(n)) != nil {
+			return v, err
+		}
+	}
+}
+
+// attempt runs fn once with the per-attempt timeout.
+func attempt[T any](ctx context.Context, d *deadlineKeyManager, op string, fn func(context.Context) (T, error)) (T, error) {
+	if d.policy.Timeout <= 0 {
+		return fn(ctx)
+	}
+	actx, cancel := context.WithTimeout(ctx, d.policy.Timeout)
+	defer cancel()
+	v, err := fn(actx)
+	if err != nil && ctx.Err() == nil && errors.Is(actx.Err(), context.DeadlineExceeded) {
+		// Adapters do not always wrap the context error, so judge the
+		// timeout by the attempt context rather than by err.
+		err = kmsUnavailable(fmt.Errorf("%s %s timed out after %s: %w", d.inner.Provider(), op, d.policy.Timeout, err))
+	}
+	return v, err
+}
+
+// backoff returns the full-jitter delay before retry n (1-based).
+func (d *deadlineKeyManager) backoff(n int) time.Duration {
+	ceiling := d.policy.MaxBackoff
+	if shift := n - 1; shift < 30 {
+		if b := d.policy.InitialBackoff << shift; b > 0 && (ceiling <= 0 || b < ceiling) {
+			ceiling = b
+		}
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling + 1)
+}
+
+// retryableKMSError reports whether err is worth another attempt: the
+// caller is still waiting and the KMS was unreachable or too slow. Errors
+// about the key or the envelope are final.
+func retryableKMSError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, ErrKeyNotFound) || errors.Is(err, ErrUnwrapFailed) || errors.Is(err, ErrInvalidEnvelope) {
+		return false
+	}
+	if errors.Is(err, ErrKMSUnavailable) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// sleepCtx waits for d or until ctx is done.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryBudget is a token bucket of retries: each call deposits ratio
+// tokens, each retry withdraws one, and at most burst are held.
+type retryBudget struct {
+	mu     sync.Mutex
+	ratio  float64
+	burst  float64
+	tokens float64
+}
+
+func newRetryBudget(ratio float64, burst int) *retryBudget {
+	return &retryBudget{ratio: ratio, burst: float64(burst), tokens: float64(burst)}
+}
+
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	b.tokens = min(b.burst, b.tokens+b.ratio)
+	b.mu.Unlock()
+}
+
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// KeyManagerAs returns the first key manager implementing T in the chain
+// formed by km and the managers it wraps (via an Unwrap() KeyManager
+// method). Use it instead of a type assertion for optional interfaces such
+// as RotatableKeyManager, which wrappers do not implement themselves.
+func KeyManagerAs[T any](km KeyManager) (T, bool) {
+	for km != nil {
+		if t, ok := km.(T); ok {
+			return t, true
+		}
+		u, ok := km.(interface{ Unwrap() KeyManager })
+		if !ok {
+			break
+		}
+		km = u.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
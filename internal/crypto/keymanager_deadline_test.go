@@ -0,0 +1,153 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// flakyKeyManager fails or stalls the first calls to UnwrapKey before
+// passing them on.
+type flakyKeyManager struct {
+	KeyManager
+	calls    atomic.Int32
+	failures int32         // calls that fail before the KMS recovers
+	err      error         // returned by failing calls; nil means stall
+	stall    time.Duration // how long a stalled call blocks
+}
+
+func (f *flakyKeyManager) UnwrapKey(ctx context.Context, env *KeyEnvelope, meta map[string]string) ([]byte, error) {
+	if f.calls.Add(1) <= f.failures {
+		if f.err != nil {
+			return nil, f.err
+		}
+		select {
+		case <-time.After(f.stall):
+		case <-ctx.Done():
+			// Like some adapters, report a bare transport error.
+			return nil, errors.New("read: connection timed out")
+		}
+	}
+	return f.KeyManager.UnwrapKey(ctx, env, meta)
+}
+
+func testCallPolicy() CallPolicy {
+	return CallPolicy{
+		Timeout:        50 * time.Millisecond,
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		RetryBudget:    0.1,
+		RetryBurst:     10,
+	}
+}
+
+func newWrappedEnvelope(t *testing.T) (KeyManager, *KeyEnvelope) {
+	t.Helper()
+	km, err := NewInMemoryKeyManager(nil)
+	require.NoError(t, err)
+	env, err := km.WrapKey(context.Background(), make([]byte, 32), nil)
+	require.NoError(t, err)
+	return km, env
+}
+
+func TestDeadlineKeyManager_Conformance(t *testing.T) {
+	ConformanceSuite(t, func(t *testing.T) KeyManager {
+		t.Helper()
+		km, err := NewInMemoryKeyManager(nil)
+		require.NoError(t, err)
+		return NewDeadlineKeyManager(km, testCallPolicy())
+	})
+}
+
+// TestDeadlineKeyManager_RetriesTransientFailures checks that timeouts and
+// unavailability are retried, and that errors about the key are not.
+func TestDeadlineKeyManager_RetriesTransientFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		failures  int32
+		wantErr   error
+		wantCalls int32
+	}{
+		{name: "unavailable then recovers", err: ErrKMSUnavailable, failures: 2, wantCalls: 3},
+		{name: "stall then recovers", failures: 1, wantCalls: 2},
+		{name: "unavailable beyond max attempts", err: ErrKMSUnavailable, failures: 5, wantErr: ErrKMSUnavailable, wantCalls: 3},
+		{name: "stalls beyond max attempts", failures: 5, wantErr: ErrKMSUnavailable, wantCalls: 3},
+		{name: "unwrap failure is final", err: ErrUnwrapFailed, failures: 5, wantErr: ErrUnwrapFailed, wantCalls: 1},
+		{name: "unknown key is final", err: ErrKeyNotFound, failures: 5, wantErr: ErrKeyNotFound, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner, env := newWrappedEnvelope(t)
+			flaky := &flakyKeyManager{KeyManager: inner, failures: tt.failures, err: tt.err, stall: time.Minute}
+			km := NewDeadlineKeyManager(flaky, testCallPolicy())
+
+			_, err := km.UnwrapKey(context.Background(), env, nil)
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tt.wantErr)
+			}
+			require.Equal(t, tt.wantCalls, flaky.calls.Load())
+		})
+	}
+}
+
+// TestDeadlineKeyManager_RetryBudget checks that a KMS that stays down
+// drains the shared budget, after which calls fail after one attempt.
+func TestDeadlineKeyManager_RetryBudget(t *testing.T) {
+	inner, env := newWrappedEnvelope(t)
+	flaky := &flakyKeyManager{KeyManager: inner, failures: 1 << 30, err: ErrKMSUnavailable}
+	policy := testCallPolicy()
+	policy.RetryBudget = 0.25
+	policy.RetryBurst = 4
+	km := NewDeadlineKeyManager(flaky, policy)
+
+	for i := 0; i < 20; i++ {
+		_, err := km.UnwrapKey(context.Background(), env, nil)
+		require.ErrorIs(t, err, ErrKMSUnavailable)
+	}
+	// 20 calls, the 4 banked retries and 4 of the 5 earned ones (the
+	// first call's deposit is lost to the full bucket).
+	require.Equal(t, int32(20+4+4), flaky.calls.Load())
+}
+
+// TestDeadlineKeyManager_HonoursCallerDeadline checks that a caller's own
+// deadline is never extended by retries.
+func TestDeadlineKeyManager_HonoursCallerDeadline(t *testing.T) {
+	inner, env := newWrappedEnvelope(t)
+	flaky := &flakyKeyManager{KeyManager: inner, failures: 1 << 30, stall: time.Minute}
+	policy := testCallPolicy()
+	policy.Timeout = time.Second
+	km := NewDeadlineKeyManager(flaky, policy)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := km.UnwrapKey(ctx, env, nil)
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+	require.Equal(t, int32(1), flaky.calls.Load())
+}
+
+func TestKeyManagerAs_FindsWrappedInterfaces(t *testing.T) {
+	inner, err := NewInMemoryKeyManager(nil)
+	require.NoError(t, err)
+	km := NewDeadlineKeyManager(inner, testCallPolicy())
+
+	_, ok := km.(RotatableKeyManager)
+	require.False(t, ok, "the wrapper itself must not claim rotation support")
+	rkm, ok := KeyManagerAs[RotatableKeyManager](km)
+	require.True(t, ok)
+	require.Same(t, inner, rkm)
+
+	pkm, err := NewPasswordKeyManager([]byte("password-for-key-manager-as"), 1000)
+	require.NoError(t, err)
+	_, ok = KeyManagerAs[RotatableKeyManager](NewDeadlineKeyManager(pkm, testCallPolicy()))
+	require.False(t, ok)
+}
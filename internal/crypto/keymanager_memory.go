@@ -133,6 +133,38 @@ func (m *inMemoryKeyManager) WrapKey(ctx context.Context, plaintext []byte, _ ma
 	}, nil
 }
 
+// WrapKeyWithVersion implements [VersionWrapper].
+func (m *inMemoryKeyManager) WrapKeyWithVersion(ctx context.Context, plaintext []byte, version int) (*KeyEnvelope, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("keymanager/memory: %w", err)
+	}
+	if len(plaintext) == 0 {
+		return nil, errors.New("keymanager/memory: plaintext DEK is empty")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return nil, ErrProviderUnavailable
+	}
+
+	masterKey, ok := m.keys[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: version %d", ErrKeyNotFound, version)
+	}
+	ciphertext, err := aesKeyWrap(masterKey, plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("keymanager/memory: wrap failed: %w", err)
+	}
+	return &KeyEnvelope{
+		KeyID:      fmt.Sprintf("memory-v%d", version),
+		KeyVersion: version,
+		Provider:   m.providerName,
+		Ciphertext: ciphertext,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
 // UnwrapKey implements [KeyManager] using AES key-unwrap (RFC 3394).
 func (m *inMemoryKeyManager) UnwrapKey(ctx context.Context, envelope *KeyEnvelope, _ map[string]string) ([]byte, error) {
 	if err := ctx.Err(); err != nil {
@@ -347,6 +379,8 @@ func aesKeyUnwrap(kek, ciphertext []byte) ([]byte, error) {
 // Compile-time assertion that *inMemoryKeyManager implements RotatableKeyManager.
 var _ RotatableKeyManager = (*inMemoryKeyManager)(nil)
 
+var _ VersionWrapper = (*inMemoryKeyManager)(nil)
+
 // AddVersion stages a new master key version. The version number must not
 // collide with an existing version, and material must be exactly 32 bytes
 // (AES-256). This method is separate from PromoteActiveVersion so operators
@@ -442,3 +476,23 @@ func (m *inMemoryKeyManager) PromoteActiveVersion(_ context.Context, plan Rotati
 	m.activeVersion = plan.TargetVersion
 	return nil
 }
+
+// RetireVersion implements [RetirableKeyManager]. The version's material is
+// zeroed before it is dropped.
+func (m *inMemoryKeyManager) RetireVersion(_ context.Context, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrProviderUnavailable
+	}
+	if version == m.activeVersion {
+		return fmt.Errorf("%w: version %d is active", ErrRotationConflict, version)
+	}
+	key, ok := m.keys[version]
+	if !ok {
+		return fmt.Errorf("%w: version %d", ErrKeyNotFound, version)
+	}
+	zeroBytes(key)
+	delete(m.keys, version)
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"sync"
 	"testing"
 	"time"
 
@@ -104,6 +105,97 @@ func TestCosmianKMIPManager_WrapUnwrap(t *testing.T) {
 	}
 }
 
+func TestCosmianKMIPManager_LazyConnect(t *testing.T) {
+	// Nothing listens on the endpoint: construction must not dial, and
+	// operations fail as KMS outages until the endpoint is reachable.
+	unreachable, err := NewCosmianKMIPManager(CosmianKMIPOptions{
+		Endpoint:    "127.0.0.1:1",
+		Keys:        []KMIPKeyReference{{ID: "wrapping-key-1", Version: 1}},
+		Timeout:     time.Second,
+		LazyConnect: true,
+	})
+	require.NoError(t, err)
+	_, err = unreachable.WrapKey(context.Background(), []byte("plaintext-key"), nil)
+	require.ErrorIs(t, err, ErrKMSUnavailable)
+
+	exec := kmipserver.NewBatchExecutor()
+	handler := &testKMIPWrapHandler{}
+	exec.Route(kmip.OperationEncrypt, kmipserver.HandleFunc(handler.encrypt))
+	exec.Route(kmip.OperationDecrypt, kmipserver.HandleFunc(handler.decrypt))
+	addr, ca := kmiptest.NewServer(t, exec)
+
+	mgr, err := NewCosmianKMIPManager(CosmianKMIPOptions{
+		Endpoint:    addr,
+		Keys:        []KMIPKeyReference{{ID: "wrapping-key-1", Version: 1}},
+		TLSConfig:   mustTLSConfigFromPEM(t, ca),
+		Timeout:     time.Second,
+		LazyConnect: true,
+	})
+	require.NoError(t, err)
+	env, err := mgr.WrapKey(context.Background(), []byte("plaintext-key"), nil)
+	require.NoError(t, err)
+	unwrapped, err := mgr.UnwrapKey(context.Background(), env, nil)
+	require.NoError(t, err)
+	require.Equal(t, "plaintext-key", string(unwrapped))
+
+	require.NoError(t, mgr.Close(context.Background()))
+	_, err = mgr.WrapKey(context.Background(), []byte("plaintext-key"), nil)
+	require.ErrorIs(t, err, ErrProviderUnavailable)
+}
+
+func TestCosmianKMIPManager_FetchActiveKeyVersion(t *testing.T) {
+	states := map[string]kmip.State{
+		"wrapping-key-1": kmip.StateActive,
+		"wrapping-key-2": kmip.StatePreActive,
+	}
+	var mu sync.Mutex
+	exec := kmipserver.NewBatchExecutor()
+	exec.Route(kmip.OperationGetAttributes, kmipserver.HandleFunc(func(_ context.Context, req *payloads.GetAttributesRequestPayload) (*payloads.GetAttributesResponsePayload, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return &payloads.GetAttributesResponsePayload{
+			UniqueIdentifier: req.UniqueIdentifier,
+			Attribute:        []kmip.Attribute{{AttributeName: kmip.AttributeNameState, AttributeValue: states[req.UniqueIdentifier]}},
+		}, nil
+	}))
+	addr, ca := kmiptest.NewServer(t, exec)
+
+	mgr, err := NewCosmianKMIPManager(CosmianKMIPOptions{
+		Endpoint:  addr,
+		Keys:      []KMIPKeyReference{{ID: "wrapping-key-1", Version: 1}, {ID: "wrapping-key-2", Version: 2}},
+		TLSConfig: mustTLSConfigFromPEM(t, ca),
+		Timeout:   time.Second,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = mgr.Close(context.Background()) })
+	src, ok := mgr.(KeyVersionSource)
+	require.True(t, ok)
+
+	version, err := src.FetchActiveKeyVersion(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+
+	// Both active during the switch-over: the newer key wins.
+	mu.Lock()
+	states["wrapping-key-2"] = kmip.StateActive
+	mu.Unlock()
+	version, err = src.FetchActiveKeyVersion(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, version)
+
+	mu.Lock()
+	states["wrapping-key-1"] = kmip.StateDeactivated
+	states["wrapping-key-2"] = kmip.StateDeactivated
+	mu.Unlock()
+	_, err = src.FetchActiveKeyVersion(context.Background())
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	// Fetching never changes the version new keys are wrapped under.
+	active, err := mgr.ActiveKeyVersion(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, active)
+}
+
 type testKMIPWrapHandler struct{}
 
 func (h *testKMIPWrapHandler) encrypt(_ context.Context, req *payloads.EncryptRequestPayload) (*payloads.EncryptResponsePayload, error) {
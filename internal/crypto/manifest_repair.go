@@ -0,0 +1,122 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// storedMeta returns the key and value under which metadata holds full, or
+// its compacted alias short when the object was written with base64url
+// compaction. key is empty when neither is present.
+func storedMeta(metadata map[string]string, full, short string) (key, value string) {
+	if v, ok := metadata[full]; ok {
+		return full, v
+	}
+	if v, ok := metadata[short]; ok {
+		return short, v
+	}
+	return "", ""
+}
+
+// ChunkManifestDamaged reports whether metadata belongs to a chunked object
+// whose manifest is missing or cannot be decoded. Compacted keys are
+// recognised, so the metadata can be passed as HeadObject returned it.
+func ChunkManifestDamaged(metadata map[string]string) bool {
+	if _, v := storedMeta(metadata, MetaChunkedFormat, "x-amz-meta-c"); v != "true" {
+		return false
+	}
+	_, encoded := storedMeta(metadata, MetaManifest, "x-amz-meta-m")
+	if encoded == "" {
+		return true
+	}
+	m, err := decodeManifest(encoded)
+	return err != nil || m.ChunkSize <= 0 || m.BaseIV == ""
+}
+
+// RebuildChunkManifest reconstructs the manifest of a chunked object from
+// the rest of its stored metadata and the length of its body, and returns a
+// copy of metadata with the manifest re-attached.
+//
+// The base IV and IV derivation are copied from their own metadata entries.
+// Compaction drops the derivation entry, so compacted objects are assumed
+// to use HKDF, as every chunked object written by this version does. The
+// chunk size is taken from metadata when present, otherwise chunkSize
+// is used. When the original size is recorded it must agree with the
+// layout, which catches a wrong chunk size before anything is written; the
+// result is still a best effort and should be checked by decrypting the
+// object before it is stored.
+func RebuildChunkManifest(metadata map[string]string, objectSize int64, chunkSize int) (map[string]string, error) {
+	if _, v := storedMeta(metadata, MetaChunkedFormat, "x-amz-meta-c"); v != "true" {
+		return nil, errors.New("rebuild manifest: object is not in chunked format")
+	}
+	ivKey, baseIV := storedMeta(metadata, MetaIV, "x-amz-meta-i")
+	if baseIV == "" {
+		return nil, errors.New("rebuild manifest: base IV is missing")
+	}
+	if _, err := decodeBase64(baseIV); err != nil {
+		return nil, fmt.Errorf("rebuild manifest: base IV: %w", err)
+	}
+
+	csKey, cs := storedMeta(metadata, MetaChunkSize, "x-amz-meta-cs")
+	if n, err := strconv.Atoi(cs); err == nil && n > 0 {
+		chunkSize = n
+	}
+	if chunkSize < MinChunkSize || chunkSize > MaxChunkSize {
+		return nil, fmt.Errorf("rebuild manifest: chunk size %d outside %d-%d", chunkSize, MinChunkSize, MaxChunkSize)
+	}
+
+	bodySize := objectSize
+	if _, bh := storedMeta(metadata, MetaBodyHeader, "x-amz-meta-bh"); bh != "" {
+		n, err := strconv.ParseInt(bh, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("rebuild manifest: invalid body header length %q", bh)
+		}
+		bodySize -= n
+	}
+	count, ok := chunkCountForStoredLength(bodySize, int64(chunkSize))
+	if !ok {
+		return nil, fmt.Errorf("rebuild manifest: %d-byte body does not fit %d-byte chunks", bodySize, chunkSize)
+	}
+	plaintextSize := bodySize - count*tagSize
+	if _, orig := storedMeta(metadata, MetaOriginalSize, "x-amz-meta-os"); orig != "" {
+		if n, err := strconv.ParseInt(orig, 10, 64); err == nil && n != plaintextSize {
+			return nil, fmt.Errorf("rebuild manifest: %d-byte chunks give %d bytes of plaintext, metadata records %d", chunkSize, plaintextSize, n)
+		}
+	}
+
+	ivDerivation := metadata[MetaIVDerivation]
+	if ivDerivation == "" && ivKey != MetaIV {
+		ivDerivation = "hkdf-sha256"
+	}
+	manifest := &ChunkManifest{
+		Version:      ChunkManifestVersion,
+		ChunkSize:    chunkSize,
+		ChunkCount:   int(count),
+		BaseIV:       baseIV,
+		IVDerivation: ivDerivation,
+	}
+	manifest.recordPlaintextSize(plaintextSize)
+	encoded, err := encodeManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Write the manifest in the same form as the rest of the metadata.
+	manifestKey, sizeKey := MetaManifest, MetaChunkSize
+	if ivKey != MetaIV {
+		manifestKey, sizeKey = "x-amz-meta-m", "x-amz-meta-cs"
+	}
+	out := make(map[string]string, len(metadata)+2)
+	for k, v := range metadata {
+		out[k] = v
+	}
+	delete(out, MetaManifest)
+	delete(out, "x-amz-meta-m")
+	out[manifestKey] = encoded
+	if csKey == "" {
+		csKey = sizeKey
+	}
+	out[csKey] = strconv.Itoa(chunkSize)
+	return out, nil
+}
@@ -0,0 +1,84 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRebuildChunkManifest(t *testing.T) {
+	ctx := context.Background()
+	plaintext := bytes.Repeat([]byte("chunked repair "), 3*MinChunkSize/15+7)
+
+	for _, provider := range []string{"default", "aws"} {
+		eng, err := NewEngineWithChunkingAndProvider([]byte("test-password-123456"), nil, "", nil, true, MinChunkSize, provider, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, meta, err := eng.Encrypt(ctx, bytes.NewReader(plaintext), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(r)
+
+		if ChunkManifestDamaged(meta) {
+			t.Fatalf("%s: intact metadata reported as damaged", provider)
+		}
+		damaged := make(map[string]string, len(meta))
+		for k, v := range meta {
+			damaged[k] = v
+		}
+		delete(damaged, MetaManifest)
+		damaged["x-amz-meta-m"] = "not-base64!"
+		if !ChunkManifestDamaged(damaged) {
+			t.Fatalf("%s: corrupt manifest not detected", provider)
+		}
+
+		fixed, err := RebuildChunkManifest(damaged, int64(len(body)), DefaultChunkSize)
+		if err != nil {
+			t.Fatalf("%s: rebuild: %v", provider, err)
+		}
+		if ChunkManifestDamaged(fixed) {
+			t.Errorf("%s: rebuilt manifest still reported as damaged", provider)
+		}
+		dec, _, err := eng.Decrypt(ctx, bytes.NewReader(body), fixed)
+		if err != nil {
+			t.Fatalf("%s: decrypt with rebuilt manifest: %v", provider, err)
+		}
+		if got, err := io.ReadAll(dec); err != nil || !bytes.Equal(got, plaintext) {
+			t.Errorf("%s: decrypted %d bytes, %v; want %d bytes", provider, len(got), err, len(plaintext))
+		}
+	}
+}
+
+func TestRebuildChunkManifest_WrongChunkSize(t *testing.T) {
+	eng, err := NewEngineWithChunking([]byte("test-password-123456"), nil, "", nil, true, MinChunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, meta, err := eng.Encrypt(context.Background(), bytes.NewReader(make([]byte, 5*MinChunkSize)), map[string]string{"Content-Length": "81920"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(r)
+	delete(meta, MetaManifest)
+	delete(meta, MetaChunkSize)
+
+	if _, err := RebuildChunkManifest(meta, int64(len(body)), DefaultChunkSize); err == nil || !strings.Contains(err.Error(), "metadata records") {
+		t.Errorf("rebuild with the wrong chunk size = %v, want an original-size mismatch", err)
+	}
+	fixed, err := RebuildChunkManifest(meta, int64(len(body)), MinChunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixed[MetaChunkSize] != "16384" {
+		t.Errorf("chunk size entry = %q, want it restored", fixed[MetaChunkSize])
+	}
+
+	delete(meta, MetaIV)
+	if _, err := RebuildChunkManifest(meta, int64(len(body)), MinChunkSize); err == nil {
+		t.Error("rebuild without a base IV should fail")
+	}
+}
@@ -98,6 +98,9 @@ func (c *MetadataCompactor) compactEncryptionMetadata(metadata map[string]string
 		if v := metadata[MetaContentType]; v != "" {
 			compacted["x-amz-meta-ct"] = v // content type
 		}
+		if v := metadata[MetaEmpty]; v != "" {
+			compacted["x-amz-meta-em"] = v // empty object
+		}
 
 		// Chunked encryption metadata
 		if v := metadata[MetaChunkedFormat]; v != "" {
@@ -127,6 +130,18 @@ func (c *MetadataCompactor) compactEncryptionMetadata(metadata map[string]string
 		if v := metadata[MetaKDFParams]; v != "" {
 			compacted["x-amz-meta-kdf"] = v // kdf params
 		}
+		if v := metadata[MetaBodyHeader]; v != "" {
+			compacted["x-amz-meta-bh"] = v // body header length
+		}
+		if v := metadata[MetaHybridWrapAlgorithm]; v != "" {
+			compacted["x-amz-meta-pa"] = v // hybrid wrap algorithm
+		}
+		if v := metadata[MetaHybridWrappedKey]; v != "" {
+			compacted["x-amz-meta-pw"] = v // hybrid wrapped key
+		}
+		if v := metadata[MetaHybridKeyID]; v != "" {
+			compacted["x-amz-meta-pk"] = v // hybrid key id
+		}
 
 		// Compression metadata (only if present)
 		if v := metadata[MetaCompressionEnabled]; v != "" && v != "false" {
@@ -178,6 +193,9 @@ func (c *MetadataCompactor) expandEncryptionMetadata(metadata map[string]string)
 		if v := metadata["x-amz-meta-ct"]; v != "" {
 			expanded[MetaContentType] = v
 		}
+		if v := metadata["x-amz-meta-em"]; v != "" {
+			expanded[MetaEmpty] = v
+		}
 		if v := metadata["x-amz-meta-c"]; v != "" {
 			expanded[MetaChunkedFormat] = v
 		}
@@ -205,6 +223,18 @@ func (c *MetadataCompactor) expandEncryptionMetadata(metadata map[string]string)
 		if v := metadata["x-amz-meta-kdf"]; v != "" {
 			expanded[MetaKDFParams] = v
 		}
+		if v := metadata["x-amz-meta-bh"]; v != "" {
+			expanded[MetaBodyHeader] = v
+		}
+		if v := metadata["x-amz-meta-pa"]; v != "" {
+			expanded[MetaHybridWrapAlgorithm] = v
+		}
+		if v := metadata["x-amz-meta-pw"]; v != "" {
+			expanded[MetaHybridWrappedKey] = v
+		}
+		if v := metadata["x-amz-meta-pk"]; v != "" {
+			expanded[MetaHybridKeyID] = v
+		}
 		if v := metadata["x-amz-meta-ce"]; v != "" {
 			expanded[MetaCompressionEnabled] = v
 			if v := metadata["x-amz-meta-ca"]; v != "" {
@@ -237,7 +267,8 @@ func (c *MetadataCompactor) isCompactedKey(key string) bool {
 		"x-amz-meta-os", "x-amz-meta-oe", "x-amz-meta-c", "x-amz-meta-cs",
 		"x-amz-meta-cc", "x-amz-meta-m", "x-amz-meta-kv", "x-amz-meta-wk",
 		"x-amz-meta-kid", "x-amz-meta-kp", "x-amz-meta-ce",
-		"x-amz-meta-ca", "x-amz-meta-cos", "x-amz-meta-kdf",
+		"x-amz-meta-ca", "x-amz-meta-cos", "x-amz-meta-kdf", "x-amz-meta-bh",
+		"x-amz-meta-pa", "x-amz-meta-pw", "x-amz-meta-pk", "x-amz-meta-em",
 	}
 
 	for _, ck := range compactedKeys {
@@ -0,0 +1,157 @@
+package crypto
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultMetadataKeyPrefix is the prefix of most gateway metadata names
+// (x-amz-meta-encryption-*). A MetadataPrefixer replaces it on the backend.
+const DefaultMetadataKeyPrefix = "encryption-"
+
+const amzMetaPrefix = "x-amz-meta-"
+
+// metadataMarkers maps the gateway names outside the encryption-* namespace
+// to their suffix under a custom prefix. No suffix may equal the rest of an
+// encryption-* name, or DecodeMetadata could not tell them apart.
+var metadataMarkers = map[string]string{
+	"encrypted":         "enabled",
+	"encrypted-mpu":     "mpu-enabled",
+	"enc-legacy-no-aad": "legacy-no-aad",
+	"enc-iv-deriv":      "iv-deriv",
+
+	"compression-enabled":       "compression-enabled",
+	"compression-algorithm":     "compression-algorithm",
+	"compression-original-size": "compression-original-size",
+	"original-content-length":   "original-content-length",
+	"original-last-modified":    "original-last-modified",
+
+	"s3eg-sealed":   "sealed",
+	"s3eg-shredded": "shredded",
+	"s3eg-marker":   "marker",
+
+	// Written by the tiering package on stubs and moved objects.
+	"s3eg-tier":          "tier",
+	"s3eg-storage-class": "storage-class",
+
+	// Written by the trash package on trashed copies.
+	"s3eg-trash-key":        "trash-key",
+	"s3eg-trash-deleted-at": "trash-deleted-at",
+}
+
+// compactedMetadataNames are the short names written by MetadataCompactor.
+// They keep their name under a custom prefix.
+var compactedMetadataNames = []string{
+	"e", "a", "s", "i", "os", "oe", "ct", "em", "c", "cs", "cc", "m",
+	"kv", "wk", "kid", "kp", "kdf", "bh", "pa", "pw", "pk", "ce", "ca", "cos",
+}
+
+func init() {
+	for _, name := range compactedMetadataNames {
+		metadataMarkers[name] = name
+	}
+}
+
+// reservedMetadataPrefixes are first segments a custom prefix may not use,
+// because names under them are gateway names that are not renamed.
+var reservedMetadataPrefixes = []string{"encryption", "encrypted", "enc", "compression", "s3eg", "original"}
+
+// MetadataPrefixer renames the gateway's own metadata on the backend from
+// x-amz-meta-encryption-* to x-amz-meta-<prefix>*, so stored objects neither
+// collide with appliances that reserve those names nor advertise the
+// gateway. The engine keeps working with the default names; the prefixer
+// translates at the backend boundary and implements s3.MetadataCodec.
+//
+// Every other gateway name (the encrypted markers, compression-*,
+// original-*, s3eg-* and the compacted short names) is moved under the
+// prefix too, see metadataMarkers.
+type MetadataPrefixer struct {
+	prefix  string
+	markers map[string]string // suffix under prefix -> default name
+}
+
+// NewMetadataPrefixer returns a prefixer for prefix, which must be lower
+// case and end in "-" (config validation checks the syntax).
+func NewMetadataPrefixer(prefix string) (*MetadataPrefixer, error) {
+	if prefix == "" || !strings.HasSuffix(prefix, "-") || strings.ToLower(prefix) != prefix {
+		return nil, fmt.Errorf("metadata key prefix %q must be lower case and end in \"-\"", prefix)
+	}
+	first := prefix[:strings.Index(prefix, "-")]
+	for _, r := range reservedMetadataPrefixes {
+		if first == r {
+			return nil, fmt.Errorf("metadata key prefix %q is reserved", prefix)
+		}
+	}
+	p := &MetadataPrefixer{prefix: prefix, markers: make(map[string]string, len(metadataMarkers))}
+	for name, suffix := range metadataMarkers {
+		p.markers[suffix] = name
+	}
+	return p, nil
+}
+
+// Prefix returns the configured prefix.
+func (p *MetadataPrefixer) Prefix() string { return p.prefix }
+
+// EncodeMetadata renames gateway metadata for storage. Entries already in
+// the custom namespace are dropped: only the gateway may write there, and a
+// client-supplied header must not be read back as gateway metadata.
+func (p *MetadataPrefixer) EncodeMetadata(meta map[string]string) map[string]string {
+	if meta == nil {
+		return nil
+	}
+	out := make(map[string]string, len(meta))
+	for k, v := range meta {
+		name, ok := metaName(k)
+		if !ok {
+			out[k] = v
+			continue
+		}
+		switch {
+		case strings.HasPrefix(name, p.prefix):
+			continue
+		case strings.HasPrefix(name, DefaultMetadataKeyPrefix):
+			out[amzMetaPrefix+p.prefix+name[len(DefaultMetadataKeyPrefix):]] = v
+		case metadataMarkers[name] != "":
+			out[amzMetaPrefix+p.prefix+metadataMarkers[name]] = v
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// DecodeMetadata maps stored names back to the defaults. Default names pass
+// through, so objects written before the prefix was configured still read;
+// if both forms are present the prefixed one wins.
+func (p *MetadataPrefixer) DecodeMetadata(meta map[string]string) map[string]string {
+	if meta == nil {
+		return nil
+	}
+	out := make(map[string]string, len(meta))
+	var prefixed [][2]string
+	for k, v := range meta {
+		name, ok := metaName(k)
+		if !ok || !strings.HasPrefix(name, p.prefix) {
+			out[k] = v
+			continue
+		}
+		rest := name[len(p.prefix):]
+		if def, ok := p.markers[rest]; ok {
+			prefixed = append(prefixed, [2]string{amzMetaPrefix + def, v})
+		} else {
+			prefixed = append(prefixed, [2]string{amzMetaPrefix + DefaultMetadataKeyPrefix + rest, v})
+		}
+	}
+	for _, kv := range prefixed {
+		out[kv[0]] = kv[1]
+	}
+	return out
+}
+
+// metaName returns the lower-cased name after x-amz-meta-.
+func metaName(key string) (string, bool) {
+	if len(key) <= len(amzMetaPrefix) || !strings.EqualFold(key[:len(amzMetaPrefix)], amzMetaPrefix) {
+		return "", false
+	}
+	return strings.ToLower(key[len(amzMetaPrefix):]), true
+}
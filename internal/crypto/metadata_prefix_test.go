@@ -0,0 +1,108 @@
+package crypto
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewMetadataPrefixer(t *testing.T) {
+	for _, prefix := range []string{"gw-", "acme-store-"} {
+		if _, err := NewMetadataPrefixer(prefix); err != nil {
+			t.Errorf("%q: %v", prefix, err)
+		}
+	}
+	for _, prefix := range []string{"", "gw", "GW-", "encryption-", "enc-x-", "compression-", "s3eg-", "original-"} {
+		if _, err := NewMetadataPrefixer(prefix); err == nil {
+			t.Errorf("%q should be rejected", prefix)
+		}
+	}
+}
+
+func TestMetadataPrefixer_RoundTrip(t *testing.T) {
+	p, err := NewMetadataPrefixer("gw-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := map[string]string{
+		MetaEncrypted:          "true",
+		MetaMPUEncrypted:       "true",
+		MetaIVDerivation:       "hkdf",
+		MetaLegacyNoAAD:        "true",
+		MetaIV:                 "aXY=",
+		MetaManifest:           "bWFu",
+		MetaCompressionEnabled: "true",
+		MetaSealedMetadata:     "c2Vk",
+		MetaGatewayMarker:      "bWs=",
+		"x-amz-meta-e":         "true",
+		"x-amz-meta-user":      "kept",
+		"Content-Type":         "text/plain",
+	}
+	stored := p.EncodeMetadata(meta)
+	want := map[string]string{
+		"x-amz-meta-gw-enabled":             "true",
+		"x-amz-meta-gw-mpu-enabled":         "true",
+		"x-amz-meta-gw-iv-deriv":            "hkdf",
+		"x-amz-meta-gw-legacy-no-aad":       "true",
+		"x-amz-meta-gw-iv":                  "aXY=",
+		"x-amz-meta-gw-manifest":            "bWFu",
+		"x-amz-meta-gw-compression-enabled": "true",
+		"x-amz-meta-gw-sealed":              "c2Vk",
+		"x-amz-meta-gw-marker":              "bWs=",
+		"x-amz-meta-gw-e":                   "true",
+		"x-amz-meta-user":                   "kept",
+		"Content-Type":                      "text/plain",
+	}
+	if !reflect.DeepEqual(stored, want) {
+		t.Fatalf("EncodeMetadata = %v\nwant %v", stored, want)
+	}
+	if got := p.DecodeMetadata(stored); !reflect.DeepEqual(got, meta) {
+		t.Errorf("DecodeMetadata = %v\nwant %v", got, meta)
+	}
+}
+
+func TestMetadataPrefixer_RenamesEveryGatewayName(t *testing.T) {
+	p, _ := NewMetadataPrefixer("gw-")
+	suffixes := make(map[string]string)
+	for name, suffix := range metadataMarkers {
+		if other, ok := suffixes[suffix]; ok {
+			t.Errorf("%q and %q share suffix %q", name, other, suffix)
+		}
+		suffixes[suffix] = name
+
+		meta := map[string]string{amzMetaPrefix + name: "v"}
+		stored := p.EncodeMetadata(meta)
+		if stored[amzMetaPrefix+"gw-"+suffix] != "v" || len(stored) != 1 {
+			t.Errorf("%q stored as %v", name, stored)
+		}
+		if got := p.DecodeMetadata(stored); !reflect.DeepEqual(got, meta) {
+			t.Errorf("%q read back as %v", name, got)
+		}
+	}
+	for _, name := range []string{
+		MetaAlgorithm, MetaKeySalt, MetaIV, MetaAuthTag, MetaOriginalSize, MetaOriginalETag,
+		MetaCompression, MetaWrappedKeyCiphertext, MetaKMSKeyID, MetaKMSProvider, MetaContentType,
+		MetaChunkedFormat, MetaChunkSize, MetaChunkCount, MetaManifest, MetaKDFParams,
+		MetaFallbackMode, MetaFallbackPointer, MetaFallbackVersion, MetaHybridWrapAlgorithm,
+		MetaHybridWrappedKey, MetaHybridKeyID, MetaKeyVersion, MetaMPUManifest, MetaEmpty,
+	} {
+		if n, ok := metaName(name); !ok || suffixes[n[len(DefaultMetadataKeyPrefix):]] != "" {
+			t.Errorf("%q collides with a renamed gateway name", name)
+		}
+	}
+}
+
+func TestMetadataPrefixer_DropsInjectedNames(t *testing.T) {
+	p, _ := NewMetadataPrefixer("gw-")
+	stored := p.EncodeMetadata(map[string]string{"X-Amz-Meta-Gw-Enabled": "false", MetaEncrypted: "true"})
+	if len(stored) != 1 || stored["x-amz-meta-gw-enabled"] != "true" {
+		t.Errorf("EncodeMetadata = %v, want only the gateway's marker", stored)
+	}
+}
+
+func TestMetadataPrefixer_ReadsDefaultNames(t *testing.T) {
+	p, _ := NewMetadataPrefixer("gw-")
+	old := map[string]string{MetaEncrypted: "true", MetaIV: "b2xk"}
+	if got := p.DecodeMetadata(old); !reflect.DeepEqual(got, old) {
+		t.Errorf("objects stored under the default names should read unchanged, got %v", got)
+	}
+}
@@ -0,0 +1,241 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Metadata sealing modes.
+const (
+	MetadataSealOff    = "off"    // user metadata is stored as sent
+	MetadataSealValues = "values" // each x-amz-meta-* value is encrypted, keys stay visible
+	MetadataSealAll    = "all"    // keys and values are packed into one sealed blob
+)
+
+// MetaSealedMetadata holds the sealed blob written in MetadataSealAll mode.
+// It is deliberately not part of IsEncryptionMetadata: the engine must copy
+// it through like any other user metadata.
+const MetaSealedMetadata = "x-amz-meta-s3eg-sealed"
+
+// sealedValuePrefix marks a value sealed by MetadataSealer so plaintext values
+// written before sealing was enabled are passed through unchanged.
+const sealedValuePrefix = "s3eg1:"
+
+// metadataSealSalt domain-separates the metadata key from the per-object
+// data keys derived from the same password.
+var metadataSealSalt = []byte("s3-encryption-gateway/metadata-seal/v1")
+
+// MetadataSealer encrypts user metadata before it reaches the backend and
+// restores it on read. A nil *MetadataSealer seals nothing and unseals
+// nothing.
+type MetadataSealer struct {
+	mode string
+	aead cipher.AEAD
+}
+
+// NewMetadataSealer derives the metadata key from password with PBKDF2. The
+// key is derived once, so the iteration count only affects startup. mode
+// selects what Seal does; Unseal handles every mode regardless, so objects
+// written under an earlier setting stay readable.
+func NewMetadataSealer(password []byte, iterations int, mode string) (*MetadataSealer, error) {
+	switch mode {
+	case "", MetadataSealOff:
+		mode = MetadataSealOff
+	case MetadataSealValues, MetadataSealAll:
+	default:
+		return nil, fmt.Errorf("unsupported metadata seal mode %q", mode)
+	}
+	if len(password) == 0 {
+		return nil, errors.New("metadata sealer: password is required")
+	}
+	if iterations <= 0 {
+		iterations = DefaultPBKDF2Iterations
+	}
+	key, err := pbkdf2.Key(sha256.New, string(password), metadataSealSalt, iterations, aesKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("metadata sealer: derive key: %w", err)
+	}
+	defer zeroBytes(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("metadata sealer: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("metadata sealer: %w", err)
+	}
+	return &MetadataSealer{mode: mode, aead: aead}, nil
+}
+
+// Mode returns the configured sealing mode.
+func (s *MetadataSealer) Mode() string {
+	if s == nil {
+		return MetadataSealOff
+	}
+	return s.mode
+}
+
+// sealable reports whether key is client-supplied user metadata.
+func sealable(key string) bool {
+	key = strings.ToLower(key)
+	return strings.HasPrefix(key, "x-amz-meta-") &&
+		key != MetaSealedMetadata &&
+		!IsEncryptionMetadata(key) &&
+		!IsCompressionMetadata(key)
+}
+
+// Seal returns a copy of meta with its user metadata encrypted according to
+// the sealer's mode. Other entries (Content-Type, gateway markers) are left
+// untouched.
+func (s *MetadataSealer) Seal(meta map[string]string) (map[string]string, error) {
+	if s == nil || s.mode == MetadataSealOff {
+		return meta, nil
+	}
+	out := make(map[string]string, len(meta))
+	user := make(map[string]string)
+	for k, v := range meta {
+		if sealable(k) {
+			user[strings.ToLower(k)] = v
+			continue
+		}
+		out[k] = v
+	}
+	if len(user) == 0 {
+		return out, nil
+	}
+
+	if s.mode == MetadataSealValues {
+		for k, v := range user {
+			sealed, err := s.seal([]byte(v), []byte(k))
+			if err != nil {
+				return nil, err
+			}
+			out[k] = sealed
+		}
+		return out, nil
+	}
+
+	blob, err := json.Marshal(user)
+	if err != nil {
+		return nil, fmt.Errorf("metadata sealer: encode: %w", err)
+	}
+	sealed, err := s.seal(blob, []byte(MetaSealedMetadata))
+	if err != nil {
+		return nil, err
+	}
+	out[MetaSealedMetadata] = sealed
+	return out, nil
+}
+
+// Unseal returns a copy of meta with sealed values and any sealed blob
+// replaced by the original user metadata. Metadata that was never sealed is
+// returned unchanged. An error means a sealed entry failed authentication;
+// the returned map then still holds every entry that could be restored.
+func (s *MetadataSealer) Unseal(meta map[string]string) (map[string]string, error) {
+	if s == nil || !IsSealedMetadata(meta) {
+		return meta, nil
+	}
+	out := make(map[string]string, len(meta))
+	var firstErr error
+	for k, v := range meta {
+		lk := strings.ToLower(k)
+		switch {
+		case lk == MetaSealedMetadata:
+			plain, err := s.open(v, []byte(MetaSealedMetadata))
+			if err == nil {
+				var user map[string]string
+				if err = json.Unmarshal(plain, &user); err == nil {
+					for uk, uv := range user {
+						out[uk] = uv
+					}
+					continue
+				}
+			}
+			if firstErr == nil {
+				firstErr = fmt.Errorf("metadata sealer: sealed blob: %w", err)
+			}
+		case sealable(lk) && strings.HasPrefix(v, sealedValuePrefix):
+			plain, err := s.open(v, []byte(lk))
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("metadata sealer: %s: %w", lk, err)
+				}
+				continue
+			}
+			out[k] = string(plain)
+		default:
+			out[k] = v
+		}
+	}
+	return out, firstErr
+}
+
+// SealTag encrypts the value of the object tag key, bound to the key so
+// values cannot be swapped between tags. Unlike Seal it does not depend on
+// the sealer's mode. The sealed form uses only characters S3 accepts in tag
+// values, but it is longer: values over about 160 bytes no longer fit the
+// 256-character limit once sealed.
+func (s *MetadataSealer) SealTag(key, value string) (string, error) {
+	if s == nil {
+		return value, nil
+	}
+	return s.seal([]byte(value), []byte("tag:"+key))
+}
+
+// UnsealTag reverses SealTag. Values that were never sealed are returned
+// unchanged.
+func (s *MetadataSealer) UnsealTag(key, value string) (string, error) {
+	if s == nil || !strings.HasPrefix(value, sealedValuePrefix) {
+		return value, nil
+	}
+	plain, err := s.open(value, []byte("tag:"+key))
+	if err != nil {
+		return "", fmt.Errorf("metadata sealer: tag %s: %w", key, err)
+	}
+	return string(plain), nil
+}
+
+// IsSealedMetadata reports whether meta carries any sealed user metadata.
+func IsSealedMetadata(meta map[string]string) bool {
+	for k, v := range meta {
+		if strings.EqualFold(k, MetaSealedMetadata) || strings.HasPrefix(v, sealedValuePrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// seal encrypts plaintext bound to aad and encodes it as a header-safe string.
+func (s *MetadataSealer) seal(plaintext, aad []byte) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("metadata sealer: nonce: %w", err)
+	}
+	sealed := s.aead.Seal(nonce, nonce, plaintext, aad)
+	return sealedValuePrefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// open reverses seal.
+func (s *MetadataSealer) open(value string, aad []byte) ([]byte, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(value, sealedValuePrefix))
+	if err != nil {
+		return nil, err
+	}
+	ns := s.aead.NonceSize()
+	if len(raw) < ns+s.aead.Overhead() {
+		return nil, errors.New("sealed value too short")
+	}
+	plain, err := s.aead.Open(nil, raw[:ns], raw[ns:], aad)
+	if err != nil {
+		return nil, integrityError(err)
+	}
+	return plain, nil
+}
@@ -0,0 +1,145 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTestSealer(t *testing.T, mode string) *MetadataSealer {
+	t.Helper()
+	s, err := NewMetadataSealer([]byte("test-password-123456"), 1000, mode)
+	if err != nil {
+		t.Fatalf("NewMetadataSealer: %v", err)
+	}
+	return s
+}
+
+func TestMetadataSealer_RoundTrip(t *testing.T) {
+	meta := map[string]string{
+		"x-amz-meta-filename": "q3-payroll.xlsx",
+		"x-amz-meta-label":    "confidential",
+		"Content-Type":        "text/plain",
+		MetaEncrypted:         "true",
+	}
+
+	for _, mode := range []string{MetadataSealValues, MetadataSealAll} {
+		t.Run(mode, func(t *testing.T) {
+			s := newTestSealer(t, mode)
+			sealed, err := s.Seal(meta)
+			if err != nil {
+				t.Fatalf("Seal: %v", err)
+			}
+			for k, v := range sealed {
+				if strings.Contains(v, "payroll") || strings.Contains(v, "confidential") {
+					t.Errorf("%s leaks plaintext: %q", k, v)
+				}
+			}
+			if sealed["Content-Type"] != "text/plain" || sealed[MetaEncrypted] != "true" {
+				t.Errorf("non-user metadata was altered: %v", sealed)
+			}
+			_, hasName := sealed["x-amz-meta-filename"]
+			if mode == MetadataSealAll && hasName {
+				t.Error("all mode left a user key visible")
+			}
+			if mode == MetadataSealValues && !hasName {
+				t.Error("values mode dropped a user key")
+			}
+
+			got, err := s.Unseal(sealed)
+			if err != nil {
+				t.Fatalf("Unseal: %v", err)
+			}
+			if len(got) != len(meta) {
+				t.Fatalf("Unseal = %v, want %v", got, meta)
+			}
+			for k, v := range meta {
+				if got[k] != v {
+					t.Errorf("%s = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestMetadataSealer_OffAndNil(t *testing.T) {
+	meta := map[string]string{"x-amz-meta-label": "plain"}
+
+	off := newTestSealer(t, MetadataSealOff)
+	sealed, err := off.Seal(meta)
+	if err != nil || sealed["x-amz-meta-label"] != "plain" {
+		t.Fatalf("off mode Seal = %v, %v", sealed, err)
+	}
+
+	var nilSealer *MetadataSealer
+	if got, _ := nilSealer.Seal(meta); got["x-amz-meta-label"] != "plain" {
+		t.Errorf("nil Seal = %v", got)
+	}
+	if got, _ := nilSealer.Unseal(meta); got["x-amz-meta-label"] != "plain" {
+		t.Errorf("nil Unseal = %v", got)
+	}
+
+	// A sealer in off mode still opens metadata sealed earlier.
+	sealed, _ = newTestSealer(t, MetadataSealAll).Seal(meta)
+	got, err := off.Unseal(sealed)
+	if err != nil || got["x-amz-meta-label"] != "plain" {
+		t.Errorf("off mode Unseal = %v, %v", got, err)
+	}
+}
+
+func TestMetadataSealer_Tamper(t *testing.T) {
+	s := newTestSealer(t, MetadataSealValues)
+	sealed, err := s.Seal(map[string]string{"x-amz-meta-a": "one", "x-amz-meta-b": "two"})
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	// Swapping two sealed values must not authenticate: the header name is
+	// bound as associated data.
+	sealed["x-amz-meta-a"], sealed["x-amz-meta-b"] = sealed["x-amz-meta-b"], sealed["x-amz-meta-a"]
+	got, err := s.Unseal(sealed)
+	if err == nil {
+		t.Fatal("expected an error for swapped values")
+	}
+	if _, ok := got["x-amz-meta-a"]; ok {
+		t.Error("unauthenticated value was returned")
+	}
+
+	other, _ := NewMetadataSealer([]byte("another-password-123"), 1000, MetadataSealAll)
+	blob, _ := other.Seal(map[string]string{"x-amz-meta-a": "one"})
+	if _, err := s.Unseal(blob); err == nil {
+		t.Error("expected an error for a blob sealed under another password")
+	}
+}
+
+func TestMetadataSealer_Tags(t *testing.T) {
+	// Tags are sealed whatever the metadata mode.
+	s := newTestSealer(t, MetadataSealOff)
+	value := strings.Repeat("v", 159)
+	sealed, err := s.SealTag("project", value)
+	if err != nil {
+		t.Fatalf("SealTag: %v", err)
+	}
+	if len(sealed) > 256 || strings.Contains(sealed, value) {
+		t.Fatalf("sealed tag value %q (%d characters)", sealed, len(sealed))
+	}
+	for _, c := range sealed {
+		if !strings.ContainsRune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789+-=._:/", c) {
+			t.Fatalf("sealed tag value contains %q, which S3 rejects", c)
+		}
+	}
+	if got, err := s.UnsealTag("project", sealed); err != nil || got != value {
+		t.Errorf("UnsealTag = %q, %v", got, err)
+	}
+	if _, err := s.UnsealTag("owner", sealed); err == nil {
+		t.Error("a value moved to another tag key was accepted")
+	}
+	if got, err := s.UnsealTag("project", "plain"); err != nil || got != "plain" {
+		t.Errorf("UnsealTag of an unsealed value = %q, %v", got, err)
+	}
+}
+
+func TestNewMetadataSealer_InvalidMode(t *testing.T) {
+	if _, err := NewMetadataSealer([]byte("test-password-123456"), 1000, "keys"); err == nil {
+		t.Fatal("expected error for unknown mode")
+	}
+}
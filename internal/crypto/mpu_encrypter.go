@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"sync"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/devtrace"
 )
 
 const mpuAEADTagSize = 16 // AES-GCM and ChaCha20-Poly1305 authentication tag size
@@ -29,6 +31,7 @@ type mpuEncryptReader struct {
 	prefix   [12]byte
 	part     uint32
 	csz      int // chunk size (plaintext)
+	trace    *devtrace.Trace
 
 	// per-chunk state
 	plainBuf []byte // pooled, cap=csz
@@ -120,6 +123,7 @@ func NewMPUPartEncryptReader(
 		prefix: ivPrefix,
 		part:   uint32(partNumber),
 		csz:    chunkSize,
+		trace:  devtrace.From(ctx),
 
 		plainBuf: plainBuf,
 		srcDone:  plainLen == 0,
@@ -181,6 +185,7 @@ func (r *mpuEncryptReader) Read(p []byte) (int, error) {
 		}
 
 		iv := DeriveMultipartIV(r.dek, r.hash, r.prefix, r.part, r.chunkIdx)
+		traceMultipartChunk(r.trace, r.hash, r.prefix, r.part, r.chunkIdx, iv[:], n, r.csz)
 		r.cipherBuf = r.gcm.Seal(r.cipherBuf[:0], iv[:], r.plainBuf[:n], nil)
 		r.cipherOff = 0
 		r.chunkIdx++
@@ -356,7 +361,7 @@ func (r *mpuDecryptReader) decryptNextChunk(part MPUPartRecord) error {
 	iv := DeriveMultipartIV(r.dek, r.uploadIDHash, r.ivPrefix, uint32(part.PartNumber), uint32(r.chunkIdx))
 	plain, err := r.gcm.Open(nil, iv[:], encChunk, nil)
 	if err != nil {
-		return fmt.Errorf("mpu_decrypt: part %d chunk %d auth failure: %w", part.PartNumber, r.chunkIdx, err)
+		return fmt.Errorf("mpu_decrypt: part %d chunk %d auth failure: %w", part.PartNumber, r.chunkIdx, integrityError(err))
 	}
 
 	r.buf = plain
@@ -425,7 +430,7 @@ func DecryptMPUPartRange(
 		iv := DeriveMultipartIV(dek, uploadIDHash, ivPrefix, uint32(partNumber), chunkIndex)
 		plain, err := aead.Open(nil, iv[:], encChunk, nil)
 		if err != nil {
-			return nil, fmt.Errorf("mpu_encrypter: chunk %d auth failure in part %d: %w", chunkIndex, partNumber, err)
+			return nil, fmt.Errorf("mpu_encrypter: chunk %d auth failure in part %d: %w", chunkIndex, partNumber, integrityError(err))
 		}
 		out = append(out, plain...)
 		offset = end
@@ -471,7 +476,7 @@ func DecryptMPUPart(
 		iv := DeriveMultipartIV(dek, uploadIDHash, ivPrefix, uint32(partNumber), chunkIndex)
 		plain, err := aead.Open(nil, iv[:], encChunk, nil)
 		if err != nil {
-			return nil, fmt.Errorf("mpu_encrypter: chunk %d auth failure in part %d: %w", chunkIndex, partNumber, err)
+			return nil, fmt.Errorf("mpu_encrypter: chunk %d auth failure in part %d: %w", chunkIndex, partNumber, integrityError(err))
 		}
 		out = append(out, plain...)
 		offset = end
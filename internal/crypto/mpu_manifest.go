@@ -99,10 +99,13 @@ func (m *MultipartManifest) FitsInlineDefault() bool {
 func UnmarshalMultipartManifest(data []byte) (*MultipartManifest, error) {
 	var m MultipartManifest
 	if err := json.Unmarshal(data, &m); err != nil {
-		return nil, fmt.Errorf("mpu_manifest: unmarshal: %w", err)
+		return nil, fmt.Errorf("mpu_manifest: %w: unmarshal: %w", ErrManifestCorrupt, err)
+	}
+	if err := checkFormatVersion(FormatMultipartManifest, m.Version); err != nil {
+		return nil, fmt.Errorf("mpu_manifest: %w", err)
 	}
 	if m.Version != mpuManifestVersion {
-		return nil, fmt.Errorf("mpu_manifest: unsupported version %d (want %d)", m.Version, mpuManifestVersion)
+		return nil, fmt.Errorf("mpu_manifest: %w: unsupported version %d (want %d)", ErrManifestCorrupt, m.Version, mpuManifestVersion)
 	}
 	return &m, nil
 }
@@ -111,7 +114,7 @@ func UnmarshalMultipartManifest(data []byte) (*MultipartManifest, error) {
 func UnmarshalMultipartManifestBase64(s string) (*MultipartManifest, error) {
 	b, err := base64.URLEncoding.DecodeString(s)
 	if err != nil {
-		return nil, fmt.Errorf("mpu_manifest: base64 decode: %w", err)
+		return nil, fmt.Errorf("mpu_manifest: %w: base64 decode: %w", ErrManifestCorrupt, err)
 	}
 	return UnmarshalMultipartManifest(b)
 }
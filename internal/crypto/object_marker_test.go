@@ -0,0 +1,74 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestObjectMarker_StampAndVerify(t *testing.T) {
+	m, err := NewObjectMarker([]byte("test-password-123456"), 1000, "gw-a")
+	if err != nil {
+		t.Fatalf("NewObjectMarker: %v", err)
+	}
+	other, _ := NewObjectMarker([]byte("test-password-123456"), 1000, "gw-b")
+	foreign, _ := NewObjectMarker([]byte("another-password-123"), 1000, "gw-a")
+
+	stamped := func() map[string]string {
+		meta := map[string]string{MetaEncrypted: "true", MetaIV: "aXY=", MetaKeySalt: "c2FsdA==", "x-amz-meta-owner": "alice"}
+		m.Stamp(meta)
+		return meta
+	}
+
+	if result, id := m.Verify(stamped()); result != MarkerValid || id != "gw-a" {
+		t.Errorf("Verify = %s, %q", result, id)
+	}
+	// Replicas sharing the password accept each other's markers.
+	if result, id := other.Verify(stamped()); result != MarkerValid || id != "gw-a" {
+		t.Errorf("replica Verify = %s, %q", result, id)
+	}
+
+	tests := []struct {
+		name   string
+		marker *ObjectMarker
+		edit   func(map[string]string)
+		want   string
+	}{
+		{"no marker", m, func(meta map[string]string) { delete(meta, MetaGatewayMarker) }, MarkerMissing},
+		{"other password", foreign, func(map[string]string) {}, MarkerInvalid},
+		{"iv replaced", m, func(meta map[string]string) { meta[MetaIV] = "b3RoZXI=" }, MarkerInvalid},
+		{"gateway id edited", m, func(meta map[string]string) {
+			meta[MetaGatewayMarker] = strings.Replace(meta[MetaGatewayMarker], "gw-a", "gw-x", 1)
+		}, MarkerInvalid},
+		{"unknown version", m, func(meta map[string]string) { meta[MetaGatewayMarker] = "2" + meta[MetaGatewayMarker][1:] }, MarkerInvalid},
+		{"garbage", m, func(meta map[string]string) { meta[MetaGatewayMarker] = "junk" }, MarkerInvalid},
+		{"user metadata edited", m, func(meta map[string]string) { meta["x-amz-meta-owner"] = "bob" }, MarkerValid},
+	}
+	for _, tc := range tests {
+		meta := stamped()
+		tc.edit(meta)
+		if result, _ := tc.marker.Verify(meta); result != tc.want {
+			t.Errorf("%s: Verify = %s, want %s", tc.name, result, tc.want)
+		}
+	}
+
+	var none *ObjectMarker
+	meta := map[string]string{MetaEncrypted: "true"}
+	none.Stamp(meta)
+	if _, ok := meta[MetaGatewayMarker]; ok {
+		t.Error("nil marker stamped metadata")
+	}
+	if result, _ := none.Verify(meta); result != MarkerValid {
+		t.Errorf("nil marker Verify = %s", result)
+	}
+}
+
+func TestNewObjectMarker_InvalidInput(t *testing.T) {
+	if _, err := NewObjectMarker(nil, 1000, "gw"); err == nil {
+		t.Error("empty password accepted")
+	}
+	for _, id := range []string{"", "gw:1", "gw 1"} {
+		if _, err := NewObjectMarker([]byte("test-password-123456"), 1000, id); err == nil {
+			t.Errorf("gateway id %q accepted", id)
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/devtrace"
 )
 
 // rangeDecryptReader decrypts only the chunks needed for a specific plaintext range.
@@ -30,6 +32,7 @@ type rangeDecryptReader struct {
 	closed             bool
 	err                error
 	isOptimized        bool // Whether source contains only needed chunks
+	trace              *devtrace.Trace
 }
 
 // newRangeDecryptReader creates a decryption reader that only decrypts chunks needed for a range.
@@ -39,6 +42,7 @@ func newRangeDecryptReader(
 	manifest *ChunkManifest,
 	baseIV []byte,
 	plaintextStart, plaintextEnd int64,
+	sourceOffset int64,
 	bufferPool *BufferPool,
 ) (*rangeDecryptReader, error) {
 	// Calculate which chunks we need
@@ -54,11 +58,14 @@ func newRangeDecryptReader(
 		return nil, fmt.Errorf("invalid chunk range: %d-%d (total chunks: %d)", startChunk, endChunk, manifest.ChunkCount)
 	}
 
-	// Assume source contains full encrypted object (for backward compatibility)
-	// Skip to startChunk if needed
-	if startChunk > 0 {
-		encryptedChunkSize := manifest.ChunkSize + tagSize
-		skipBytes := int64(startChunk) * int64(encryptedChunkSize)
+	// source starts sourceOffset bytes into the encrypted chunk stream: at
+	// 0 for the full object, or wherever a ranged backend read began.
+	// Skip what remains up to startChunk.
+	encryptedStart := int64(startChunk) * int64(manifest.ChunkSize+tagSize)
+	if sourceOffset > encryptedStart {
+		return nil, fmt.Errorf("source starts at encrypted offset %d, after chunk %d", sourceOffset, startChunk)
+	}
+	if skipBytes := encryptedStart - sourceOffset; skipBytes > 0 {
 		skipped, err := io.CopyN(io.Discard, source, skipBytes)
 		if err != nil && err != io.EOF {
 			return nil, fmt.Errorf("failed to skip to start chunk: %w", err)
@@ -90,7 +97,7 @@ func newRangeDecryptReader(
 		bufferPool:         bufferPool,
 		closed:             false,
 		err:                nil,
-		isOptimized:        false, // Assume not optimized for backward compatibility
+		isOptimized:        sourceOffset > 0,
 	}, nil
 }
 
@@ -189,9 +196,10 @@ func (r *rangeDecryptReader) Read(p []byte) (int, error) {
 
 		// Decrypt the chunk
 		chunkIV := r.deriveChunkIV(r.currentChunkIndex)
+		traceChunk(r.trace, "decrypt_chunk", r.manifest, r.baseIV, chunkIV, r.currentChunkIndex, n-r.aead.Overhead(), n)
 		plaintext, err := r.aead.Open(nil, chunkIV, r.buffer[:n], nil)
 		if err != nil {
-			r.err = fmt.Errorf("failed to decrypt chunk %d: %w", r.currentChunkIndex, err)
+			r.err = fmt.Errorf("failed to decrypt chunk %d: %w", r.currentChunkIndex, integrityError(err))
 			return totalRead, r.err
 		}
 
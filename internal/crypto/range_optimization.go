@@ -94,7 +94,9 @@ func CalculateEncryptedRangeForPlaintextRange(metadata map[string]string, plaint
 		return 0, 0, fmt.Errorf("failed to calculate encrypted byte range: %w", err)
 	}
 
-	return encryptedStart, encryptedEnd, nil
+	// The chunk stream follows the body header, if there is one.
+	headerLen := BodyHeaderLength(metadata)
+	return encryptedStart + headerLen, encryptedEnd + headerLen, nil
 }
 
 // ParseHTTPRangeHeader parses an HTTP Range header and returns the plaintext byte range.
@@ -118,6 +120,9 @@ func ParseHTTPRangeHeader(rangeHeader string, totalSizeHint int64) (start, end i
 		if err != nil {
 			return 0, 0, fmt.Errorf("invalid suffix range: %w", err)
 		}
+		if suffix == 0 {
+			return 0, 0, fmt.Errorf("%w: empty suffix range", ErrRangeNotSatisfiable)
+		}
 		start = totalSizeHint - suffix
 		if start < 0 {
 			start = 0
@@ -157,29 +162,45 @@ func ParseHTTPRangeHeader(rangeHeader string, totalSizeHint int64) (start, end i
 		return 0, 0, fmt.Errorf("invalid range: end must be >= start")
 	}
 
-	// Validate range against total size if known
+	// Validate range against total size if known. As RFC 7233 requires, a
+	// last byte beyond the object is clamped rather than rejected.
 	if totalSizeHint > 0 {
-		if start >= totalSizeHint || end >= totalSizeHint {
-			return 0, 0, fmt.Errorf("range not satisfiable: %d-%d (size: %d)", start, end, totalSizeHint)
+		if start >= totalSizeHint {
+			return 0, 0, fmt.Errorf("%w: %d-%d (size: %d)", ErrRangeNotSatisfiable, start, end, totalSizeHint)
+		}
+		if end >= totalSizeHint {
+			end = totalSizeHint - 1
 		}
 	}
 
 	return start, end, nil
 }
 
-// GetPlaintextSizeFromMetadata extracts the approximate plaintext size from chunked metadata.
+// GetPlaintextSizeFromMetadata returns the plaintext size of an encrypted
+// object. The exact size is taken, in order, from a version 2 chunk
+// manifest, from the recorded original size, or from the stored length of
+// a chunked object (Content-Range or Content-Length, as a GetObject or
+// HeadObject response carries them). Only older chunked metadata that has
+// none of these falls back to the chunk count, which overstates the size
+// by the unused part of the last chunk.
 func GetPlaintextSizeFromMetadata(metadata map[string]string) (int64, error) {
+	if _, encoded := storedMeta(metadata, MetaManifest, "x-amz-meta-m"); encoded != "" {
+		if m, err := decodeManifest(encoded); err == nil && m.PlaintextSize > 0 {
+			return m.PlaintextSize, nil
+		}
+	}
+	if _, sizeStr := storedMeta(metadata, MetaOriginalSize, "x-amz-meta-os"); sizeStr != "" {
+		if size, err := strconv.ParseInt(sizeStr, 10, 64); err == nil {
+			return size, nil
+		}
+	}
+	if size, ok := plaintextSizeFromStoredLength(metadata); ok {
+		return size, nil
+	}
+
 	chunkCountStr, ok1 := metadata[MetaChunkCount]
 	chunkSizeStr, ok2 := metadata[MetaChunkSize]
-
 	if !ok1 || !ok2 {
-		// Try legacy format
-		if sizeStr, ok := metadata[MetaOriginalSize]; ok {
-			size, err := strconv.ParseInt(sizeStr, 10, 64)
-			if err == nil {
-				return size, nil
-			}
-		}
 		return 0, fmt.Errorf("size information not found in metadata")
 	}
 
@@ -195,3 +216,38 @@ func GetPlaintextSizeFromMetadata(metadata map[string]string) (int64, error) {
 	size := int64((chunkCount - 1) * chunkSize + chunkSize)
 	return size, nil
 }
+
+// plaintextSizeFromStoredLength computes the plaintext size of a chunked
+// object from its length in the backend: every chunk but the last is
+// ChunkSize+tagSize bytes, so the chunk count and with it the number of
+// authentication tags follow from the length alone.
+func plaintextSizeFromStoredLength(metadata map[string]string) (int64, bool) {
+	if _, v := storedMeta(metadata, MetaChunkedFormat, "x-amz-meta-c"); v != "true" {
+		return 0, false
+	}
+	_, cs := storedMeta(metadata, MetaChunkSize, "x-amz-meta-cs")
+	chunkSize, err := strconv.ParseInt(cs, 10, 64)
+	if err != nil || chunkSize <= 0 {
+		return 0, false
+	}
+	stored := metadata["Content-Length"]
+	if cr := metadata["Content-Range"]; cr != "" {
+		_, stored, _ = strings.Cut(cr, "/")
+	}
+	length, err := strconv.ParseInt(stored, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	if _, bh := storedMeta(metadata, MetaBodyHeader, "x-amz-meta-bh"); bh != "" {
+		n, err := strconv.ParseInt(bh, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		length -= n
+	}
+	count, ok := chunkCountForStoredLength(length, chunkSize)
+	if !ok {
+		return 0, false
+	}
+	return length - count*tagSize, true
+}
@@ -293,6 +293,19 @@ func TestParseHTTPRangeHeader(t *testing.T) {
 			totalSize:   1000,
 			expectedErr: true,
 		},
+		{
+			name:          "last byte past end is clamped",
+			rangeHeader:   "bytes=900-5000",
+			totalSize:     1000,
+			expectedStart: 900,
+			expectedEnd:   999,
+		},
+		{
+			name:        "empty suffix",
+			rangeHeader: "bytes=-0",
+			totalSize:   1000,
+			expectedErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -345,6 +358,34 @@ func TestGetPlaintextSizeFromMetadata(t *testing.T) {
 			expectedSize: 123456,
 			expectedErr:  false,
 		},
+		{
+			name: "version 2 manifest",
+			metadata: map[string]string{
+				MetaChunkCount: "10",
+				MetaChunkSize:  "65536",
+				MetaManifest:   mustEncodeManifest(t, &ChunkManifest{Version: 2, ChunkSize: 65536, PlaintextSize: 600001, LastChunkSize: 10177}),
+			},
+			expectedSize: 600001,
+		},
+		{
+			name: "chunked stored length",
+			metadata: map[string]string{
+				MetaChunkedFormat: "true",
+				MetaChunkSize:     "65536",
+				"Content-Length":  "131121", // two full chunks and a one-byte chunk
+			},
+			expectedSize: 131073,
+		},
+		{
+			name: "chunked ranged response",
+			metadata: map[string]string{
+				"x-amz-meta-c":   "true",
+				"x-amz-meta-cs":  "65536",
+				"Content-Length": "100",
+				"Content-Range":  "bytes 0-99/65569",
+			},
+			expectedSize: 65537,
+		},
 		{
 			name: "no size info",
 			metadata: map[string]string{
@@ -892,3 +933,12 @@ func TestRangeDecryptionChunkAlignment(t *testing.T) {
 		t.Error("Decrypted range does not match original data")
 	}
 }
+
+func mustEncodeManifest(t *testing.T, m *ChunkManifest) string {
+	t.Helper()
+	encoded, err := encodeManifest(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoded
+}
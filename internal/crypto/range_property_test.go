@@ -0,0 +1,198 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+	"testing/quick"
+)
+
+// rangeCase is a random object layout and Range header for the property
+// tests below. Most objects end in a partial chunk, and the ranges lean
+// towards that last chunk and towards suffix ranges, which is where the
+// chunk arithmetic has its edge cases.
+type rangeCase struct {
+	ChunkSize int
+	Size      int64
+	Header    string
+	Seed      int64
+}
+
+func (rangeCase) Generate(r *rand.Rand, _ int) reflect.Value {
+	chunkSizes := []int{MinChunkSize, MinChunkSize + 1, MinChunkSize + 4093, 2 * MinChunkSize}
+	cs := chunkSizes[r.Intn(len(chunkSizes))]
+
+	// Size of the last chunk: a single byte, one short of full, full, or
+	// anything in between.
+	var last int64
+	switch r.Intn(4) {
+	case 0:
+		last = 1
+	case 1:
+		last = int64(cs - 1)
+	case 2:
+		last = int64(cs)
+	default:
+		last = 1 + r.Int63n(int64(cs))
+	}
+	size := int64(r.Intn(4))*int64(cs) + last
+	lastChunkStart := size - last
+
+	var header string
+	switch r.Intn(5) {
+	case 0: // suffix range, possibly longer than the object
+		header = fmt.Sprintf("bytes=-%d", 1+r.Int63n(size+int64(cs)))
+	case 1: // open-ended range from inside the last chunk
+		header = fmt.Sprintf("bytes=%d-", lastChunkStart+r.Int63n(last))
+	case 2: // range inside the last chunk
+		start := lastChunkStart + r.Int63n(last)
+		header = fmt.Sprintf("bytes=%d-%d", start, start+r.Int63n(size-start))
+	case 3: // range whose last byte is past the end, clamped by the parser
+		start := r.Int63n(size)
+		header = fmt.Sprintf("bytes=%d-%d", start, size+r.Int63n(int64(cs)))
+	default:
+		start := r.Int63n(size)
+		header = fmt.Sprintf("bytes=%d-%d", start, start+r.Int63n(size-start))
+	}
+	return reflect.ValueOf(rangeCase{ChunkSize: cs, Size: size, Header: header, Seed: r.Int63()})
+}
+
+// TestDecryptRange_MatchesFullDecrypt checks that for any object layout
+// and Range header, DecryptRange returns exactly the requested slice of the
+// plaintext, both when given the whole ciphertext and when given only the
+// backend range CalculateEncryptedRangeForPlaintextRange asks for.
+func TestDecryptRange_MatchesFullDecrypt(t *testing.T) {
+	ctx := context.Background()
+	km, err := NewInMemoryKeyManager(nil)
+	if err != nil {
+		t.Fatalf("NewInMemoryKeyManager(): %v", err)
+	}
+	engines := make(map[int]EncryptionEngine)
+	engineFor := func(chunkSize int) EncryptionEngine {
+		if e, ok := engines[chunkSize]; ok {
+			return e
+		}
+		e, err := NewEngineWithOpts([]byte("range-property-password"), nil,
+			WithKeyManager(km), WithChunking(true), WithChunkSize(chunkSize))
+		if err != nil {
+			t.Fatalf("NewEngineWithOpts(): %v", err)
+		}
+		engines[chunkSize] = e
+		return e
+	}
+	compactor := NewMetadataCompactor(GetProviderProfile("default"))
+
+	prop := func(c rangeCase) bool {
+		plaintext := make([]byte, c.Size)
+		rand.New(rand.NewSource(c.Seed)).Read(plaintext)
+
+		engine := engineFor(c.ChunkSize)
+		r, meta, err := engine.Encrypt(ctx, bytes.NewReader(plaintext), map[string]string{
+			"Content-Length": strconv.FormatInt(c.Size, 10),
+		})
+		if err != nil {
+			t.Logf("%+v: Encrypt(): %v", c, err)
+			return false
+		}
+		body, err := io.ReadAll(r)
+		if err != nil {
+			t.Logf("%+v: reading ciphertext: %v", c, err)
+			return false
+		}
+		expanded, err := compactor.ExpandMetadata(meta)
+		if err != nil {
+			t.Logf("%+v: ExpandMetadata(): %v", c, err)
+			return false
+		}
+
+		// Resolve the header the way the GET handler does.
+		size, err := GetPlaintextSizeFromMetadata(expanded)
+		if err != nil || size != c.Size {
+			t.Logf("%+v: GetPlaintextSizeFromMetadata() = %d, %v", c, size, err)
+			return false
+		}
+		start, end, err := ParseHTTPRangeHeader(c.Header, size)
+		if err != nil {
+			t.Logf("%+v: ParseHTTPRangeHeader(): %v", c, err)
+			return false
+		}
+		want := plaintext[start : end+1]
+
+		decryptRange := func(src io.Reader) ([]byte, error) {
+			dec, _, err := engine.DecryptRange(ctx, src, meta, start, end)
+			if err != nil {
+				return nil, err
+			}
+			return io.ReadAll(dec)
+		}
+
+		got, err := decryptRange(bytes.NewReader(body))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Logf("%+v: DecryptRange(whole body) returned %d bytes, want %d (err=%v)", c, len(got), len(want), err)
+			return false
+		}
+
+		encStart, encEnd, err := CalculateEncryptedRangeForPlaintextRange(expanded, start, end)
+		if err != nil {
+			t.Logf("%+v: CalculateEncryptedRangeForPlaintextRange(): %v", c, err)
+			return false
+		}
+		// The backend clamps a last byte past the end of the object.
+		if encEnd >= int64(len(body)) {
+			encEnd = int64(len(body)) - 1
+		}
+		if encStart < 0 || encStart > encEnd {
+			t.Logf("%+v: backend range %d-%d of a %d byte body", c, encStart, encEnd, len(body))
+			return false
+		}
+		got, err = decryptRange(offsetReader{bytes.NewReader(body[encStart : encEnd+1]), encStart})
+		if err != nil || !bytes.Equal(got, want) {
+			t.Logf("%+v: DecryptRange(backend range) returned %d bytes, want %d (err=%v)", c, len(got), len(want), err)
+			return false
+		}
+		return true
+	}
+
+	if err := quick.Check(prop, &quick.Config{MaxCount: 150}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestChunkRange_CoversRange checks the pure chunk arithmetic for random
+// sizes: the chunks picked for a range hold its first and last byte, and
+// the encrypted byte range spans exactly those chunks.
+func TestChunkRange_CoversRange(t *testing.T) {
+	prop := func(sizeSeed, startSeed, lenSeed uint32, chunkSeed uint16) bool {
+		chunkSize := 1 + int(chunkSeed)
+		size := 1 + int64(sizeSeed%(1<<24))
+		totalChunks := int((size + int64(chunkSize) - 1) / int64(chunkSize))
+		start := int64(startSeed) % size
+		end := start + int64(lenSeed)%(size-start)
+
+		startChunk, endChunk, startOffset, endOffset := calculateChunkRangeFromPlaintext(start, end, chunkSize, totalChunks)
+		if startChunk < 0 || endChunk >= totalChunks || startChunk > endChunk {
+			return false
+		}
+		if int64(startChunk)*int64(chunkSize)+int64(startOffset) != start ||
+			int64(endChunk)*int64(chunkSize)+int64(endOffset) != end {
+			return false
+		}
+
+		encStart, encEnd, err := calculateEncryptedByteRange(startChunk, endChunk, chunkSize)
+		if err != nil {
+			return false
+		}
+		encChunk := int64(chunkSize + tagSize)
+		return encStart == int64(startChunk)*encChunk &&
+			encEnd-encStart+1 == int64(endChunk-startChunk+1)*encChunk
+	}
+
+	if err := quick.Check(prop, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Fatal(err)
+	}
+}
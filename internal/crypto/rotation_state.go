@@ -5,6 +5,8 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/clock"
 )
 
 // RotationPhase represents a stage in the key rotation state machine.
@@ -58,7 +60,8 @@ type RotationSnapshot struct {
 // RotationState manages the drain-and-cutover state machine for key rotation.
 // The in-flight counter uses atomic operations for the zero-allocation fast path.
 type RotationState struct {
-	mu sync.Mutex
+	mu    sync.Mutex
+	clock clock.Clock
 
 	phase          RotationPhase
 	rotationID     string
@@ -83,8 +86,15 @@ type RotationState struct {
 
 // NewRotationState creates a new idle rotation state machine.
 func NewRotationState() *RotationState {
+	return NewRotationStateWithClock(clock.Real)
+}
+
+// NewRotationStateWithClock creates an idle rotation state machine whose
+// timestamps, drain polling and grace deadline follow clk.
+func NewRotationStateWithClock(clk clock.Clock) *RotationState {
 	return &RotationState{
 		phase: RotationIdle,
+		clock: clock.OrReal(clk),
 	}
 }
 
@@ -128,7 +138,7 @@ func (rs *RotationState) StartDrain(rotationID string, currentVersion, targetVer
 	rs.targetVersion = targetVersion
 	rs.provider = provider
 	rs.plan = plan
-	rs.startedAt = time.Now()
+	rs.startedAt = rs.clock.Now()
 	rs.completedAt = time.Time{}
 	rs.lastError = ""
 
@@ -139,16 +149,16 @@ func (rs *RotationState) StartDrain(rotationID string, currentVersion, targetVer
 	rs.drainDone = make(chan struct{})
 	rs.stopDrain = make(chan struct{})
 
-	// Launch drain poller
-	go rs.drainPoller()
+	// Launch drain poller. The ticker is created before returning so a
+	// fake clock advanced right after StartDrain already drives it.
+	go rs.drainPoller(rs.clock.NewTicker(100 * time.Millisecond))
 
 	return nil
 }
 
 // drainPoller polls the in-flight counter at 100ms intervals and transitions
 // to ReadyToCutover when the count reaches zero or the grace deadline elapses.
-func (rs *RotationState) drainPoller() {
-	ticker := time.NewTicker(100 * time.Millisecond)
+func (rs *RotationState) drainPoller(ticker clock.Ticker) {
 	defer ticker.Stop()
 	defer close(rs.drainDone)
 
@@ -156,7 +166,7 @@ func (rs *RotationState) drainPoller() {
 		select {
 		case <-rs.stopDrain:
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			count := atomic.LoadInt64(&rs.inFlightWraps)
 			if count <= 0 {
 				rs.mu.Lock()
@@ -168,7 +178,7 @@ func (rs *RotationState) drainPoller() {
 			}
 			// Check grace deadline
 			rs.mu.Lock()
-			if !rs.graceDeadline.IsZero() && time.Now().After(rs.graceDeadline) {
+			if !rs.graceDeadline.IsZero() && rs.clock.Now().After(rs.graceDeadline) {
 				if rs.phase == RotationDraining {
 					rs.phase = RotationReadyToCutover
 				}
@@ -212,7 +222,7 @@ func (rs *RotationState) MarkCommitted() {
 	rs.mu.Lock()
 	defer rs.mu.Unlock()
 	rs.phase = RotationCommitted
-	rs.completedAt = time.Now()
+	rs.completedAt = rs.clock.Now()
 }
 
 // MarkFailed transitions from Committing to Aborted with an error.
@@ -220,7 +230,7 @@ func (rs *RotationState) MarkFailed(err error) {
 	rs.mu.Lock()
 	defer rs.mu.Unlock()
 	rs.phase = RotationAborted
-	rs.completedAt = time.Now()
+	rs.completedAt = rs.clock.Now()
 	if err != nil {
 		rs.lastError = err.Error()
 	}
@@ -234,7 +244,7 @@ func (rs *RotationState) Abort() error {
 	switch rs.phase {
 	case RotationDraining, RotationReadyToCutover:
 		rs.phase = RotationAborted
-		rs.completedAt = time.Now()
+		rs.completedAt = rs.clock.Now()
 		// Stop drain poller if running
 		if rs.stopDrain != nil {
 			select {
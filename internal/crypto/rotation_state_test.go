@@ -4,6 +4,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/clock"
 )
 
 func TestRotationState_InitialPhase(t *testing.T) {
@@ -152,6 +154,41 @@ func TestRotationState_GraceDeadlineElapsed(t *testing.T) {
 	rs.EndWrap()
 }
 
+func TestRotationState_GraceDeadlineFakeClock(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	rs := NewRotationStateWithClock(clk)
+	rs.BeginWrap()
+	defer rs.EndWrap()
+
+	if err := rs.StartDrain("rot-clock", 1, 2, "memory", &RotationPlan{CurrentVersion: 1, TargetVersion: 2}, time.Hour); err != nil {
+		t.Fatalf("StartDrain: %v", err)
+	}
+	snap := rs.Snapshot()
+	if !snap.StartedAt.Equal(start) || !snap.GraceDeadline.Equal(start.Add(time.Hour)) {
+		t.Fatalf("started %v, grace deadline %v", snap.StartedAt, snap.GraceDeadline)
+	}
+
+	// An hour of grace passes without a single real second elapsing.
+	clk.Advance(time.Hour + 100*time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for rs.Phase() != RotationReadyToCutover {
+		if time.Now().After(deadline) {
+			t.Fatalf("phase = %s after the grace deadline, want ready_for_cutover", rs.Phase())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := rs.Commit(false); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	clk.Advance(time.Minute)
+	rs.MarkCommitted()
+	if got := rs.Snapshot().CompletedAt; !got.Equal(start.Add(time.Hour + time.Minute + 100*time.Millisecond)) {
+		t.Errorf("completed at %v", got)
+	}
+}
+
 func TestRotationState_ConcurrentBeginEndWrap(t *testing.T) {
 	rs := NewRotationState()
 
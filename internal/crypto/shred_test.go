@@ -0,0 +1,84 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestShredMetadata_RemovesKeyMaterial(t *testing.T) {
+	meta := map[string]string{
+		MetaEncrypted:            "true",
+		MetaKeySalt:              "salt",
+		MetaWrappedKeyCiphertext: "wrapped",
+		"x-amz-meta-s":           "salt",
+		"x-amz-meta-wk":          "wrapped",
+		MetaIV:                   "iv",
+		"x-amz-meta-color":       "blue",
+	}
+	out := ShredMetadata(meta, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	for _, k := range keyMaterialMetadata {
+		if _, ok := out[k]; ok {
+			t.Errorf("%s kept", k)
+		}
+	}
+	if out[MetaEncrypted] != "true" || out[MetaIV] != "iv" || out["x-amz-meta-color"] != "blue" {
+		t.Errorf("unrelated metadata changed: %v", out)
+	}
+	if out[MetaShredded] != "2026-01-02T03:04:05Z" || !IsShredded(out) {
+		t.Errorf("shredded marker = %q", out[MetaShredded])
+	}
+	if meta[MetaKeySalt] != "salt" {
+		t.Error("input metadata modified")
+	}
+}
+
+func TestShredBody_ZeroesFallbackMetadataPrefix(t *testing.T) {
+	prefix := []byte(`{"x-amz-meta-encryption-key-salt":"c2FsdA=="}`)
+	body := append([]byte{0, 0, 0, byte(len(prefix))}, prefix...)
+	body = append(body, "ciphertext"...)
+	meta := map[string]string{MetaFallbackMode: "true", MetaFallbackVersion: "2"}
+
+	r, err := ShredBody(bytes.NewReader(body), meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(r)
+	want := append([]byte{0, 0, 0, byte(len(prefix))}, make([]byte, len(prefix))...)
+	want = append(want, "ciphertext"...)
+	if !bytes.Equal(got, want) {
+		t.Errorf("ShredBody = %q, want %q", got, want)
+	}
+
+	r, _ = ShredBody(bytes.NewReader(body), map[string]string{MetaEncrypted: "true"})
+	if got, _ := io.ReadAll(r); !bytes.Equal(got, body) {
+		t.Error("body of a header-only format was changed")
+	}
+	if _, err := ShredBody(bytes.NewReader(body[:10]), meta); err == nil {
+		t.Error("expected an error for a truncated prefix")
+	}
+}
+
+func TestDecrypt_ShreddedObject(t *testing.T) {
+	engine, err := NewEngineWithOpts([]byte("test-password-12345"), nil, WithChunking(true))
+	if err != nil {
+		t.Fatalf("NewEngineWithOpts() error: %v", err)
+	}
+	r, meta, err := engine.Encrypt(context.Background(), bytes.NewReader([]byte("erase me")), map[string]string{})
+	if err != nil {
+		t.Fatalf("Encrypt() error: %v", err)
+	}
+	var ct bytes.Buffer
+	ct.ReadFrom(r)
+	meta = ShredMetadata(meta, time.Now())
+
+	if _, _, err := engine.Decrypt(context.Background(), bytes.NewReader(ct.Bytes()), meta); !errors.Is(err, ErrShredded) {
+		t.Errorf("Decrypt() error = %v, want ErrShredded", err)
+	}
+	if _, _, err := engine.DecryptRange(context.Background(), bytes.NewReader(ct.Bytes()), meta, 0, 3); !errors.Is(err, ErrShredded) {
+		t.Errorf("DecryptRange() error = %v, want ErrShredded", err)
+	}
+}
@@ -0,0 +1,100 @@
+package crypto
+
+import (
+	"github.com/kenneth/s3-encryption-gateway/internal/devtrace"
+	"github.com/sirupsen/logrus"
+)
+
+// The developer mode's trace points. Each helper returns at once on a nil
+// trace, which is every request unless dev_mode is enabled. Keys are never
+// passed to them: IVs, salts and wrapped keys are fair game, key material
+// is not.
+
+// traceChunk reports the boundaries and IV of one chunk. Every chunk but
+// the last is full, so the offsets follow from the index.
+func traceChunk(t *devtrace.Trace, event string, manifest *ChunkManifest, baseIV, chunkIV []byte, index, plainLen, cipherLen int) {
+	if t == nil {
+		return
+	}
+	derivation := manifest.IVDerivation
+	if derivation == "" {
+		derivation = "legacy-xor"
+	}
+	t.Event(event, logrus.Fields{
+		"chunk":             index,
+		"plaintext_offset":  int64(index) * int64(manifest.ChunkSize),
+		"plaintext_length":  plainLen,
+		"ciphertext_offset": int64(index) * int64(manifest.ChunkSize+tagSize),
+		"ciphertext_length": cipherLen,
+		"iv_derivation":     derivation,
+		"base_iv":           devtrace.Hex(baseIV),
+		"chunk_iv":          devtrace.Hex(chunkIV),
+	})
+}
+
+// traceMultipartChunk reports the inputs and output of the IV derivation
+// for one chunk of an encrypted multipart part. The DEK, the remaining
+// input, is left out.
+func traceMultipartChunk(t *devtrace.Trace, uploadIDHash [32]byte, ivPrefix [12]byte, part, chunk uint32, iv []byte, plainLen, chunkSize int) {
+	if t == nil {
+		return
+	}
+	t.Event("encrypt_mpu_chunk", logrus.Fields{
+		"part":             part,
+		"chunk":            chunk,
+		"plaintext_offset": int64(chunk) * int64(chunkSize),
+		"plaintext_length": plainLen,
+		"upload_id_hash":   devtrace.Hex(uploadIDHash[:]),
+		"iv_prefix":        devtrace.Hex(ivPrefix[:]),
+		"chunk_iv":         devtrace.Hex(iv),
+	})
+}
+
+// traceManifest reports a chunk manifest.
+func traceManifest(t *devtrace.Trace, event string, manifest *ChunkManifest) {
+	if t == nil || manifest == nil {
+		return
+	}
+	t.Event(event, logrus.Fields{
+		"manifest_version": manifest.Version,
+		"chunk_size":       manifest.ChunkSize,
+		"chunk_count":      manifest.ChunkCount,
+		"base_iv":          manifest.BaseIV,
+		"iv_derivation":    manifest.IVDerivation,
+		"explicit_ivs":     len(manifest.IVs),
+	})
+}
+
+// traceMetadata reports object metadata at one stage of its
+// transformation. The map is copied, since callers go on to change it.
+func traceMetadata(t *devtrace.Trace, stage string, metadata map[string]string) {
+	if t == nil {
+		return
+	}
+	snapshot := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		snapshot[k] = v
+	}
+	t.Event("metadata", logrus.Fields{
+		"stage":    stage,
+		"metadata": snapshot,
+	})
+}
+
+// traceRangePlan reports which chunks a range read decrypts and where in
+// them the range starts and ends.
+func traceRangePlan(t *devtrace.Trace, r *rangeDecryptReader, streamOffset int64) {
+	if t == nil {
+		return
+	}
+	t.Event("range_plan", logrus.Fields{
+		"plaintext_start": r.plaintextStart,
+		"plaintext_end":   r.plaintextEnd,
+		"start_chunk":     r.startChunk,
+		"end_chunk":       r.endChunk,
+		"start_offset":    r.startOffsetInChunk,
+		"end_offset":      r.endOffsetInChunk,
+		"stream_offset":   streamOffset,
+		"ranged_source":   r.isOptimized,
+	})
+}
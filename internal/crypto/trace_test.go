@@ -0,0 +1,69 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/devtrace"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDevTrace_ChunkedRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	l := logrus.New()
+	l.SetOutput(&buf)
+	l.SetFormatter(&logrus.JSONFormatter{})
+	devtrace.Enable(l)
+	defer devtrace.Enable(nil)
+
+	password := "trace-password-12345"
+	engine, err := NewEngineWithChunking([]byte(password), nil, "", nil, true, MinChunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := bytes.Repeat([]byte("a"), MinChunkSize*2+100)
+	ctx := devtrace.WithTrace(context.Background(), devtrace.New(logrus.Fields{"request_id": "T"}))
+
+	encReader, encMeta, err := engine.Encrypt(ctx, bytes.NewReader(plaintext), map[string]string{"x-amz-meta-owner": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := io.ReadAll(encReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decReader, _, err := engine.Decrypt(ctx, bytes.NewReader(ciphertext), encMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(decReader)
+	if err != nil || !bytes.Equal(got, plaintext) {
+		t.Fatalf("round trip failed: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`"dev_trace":"encrypt_manifest"`, `"dev_trace":"decrypt_manifest"`,
+		`"stage":"encrypt_input"`, `"stage":"encrypt_stored"`,
+		`"stage":"decrypt_expanded"`, `"stage":"decrypt_output"`,
+		`"plaintext_offset":32768`, `"plaintext_length":100`,
+		`"iv_derivation":"hkdf-sha256"`, `"alice"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("trace missing %s", want)
+		}
+	}
+	if n := strings.Count(out, `"dev_trace":"encrypt_chunk"`); n != 3 {
+		t.Errorf("traced %d encrypted chunks, want 3", n)
+	}
+	if n := strings.Count(out, `"dev_trace":"decrypt_chunk"`); n != 3 {
+		t.Errorf("traced %d decrypted chunks, want 3", n)
+	}
+	if strings.Contains(out, password) || strings.Contains(out, hex.EncodeToString([]byte(password))) {
+		t.Error("trace contains the password")
+	}
+}
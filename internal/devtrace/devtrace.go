@@ -0,0 +1,105 @@
+// Package devtrace is the developer mode's per-request crypto trace. When
+// enabled, every request carries a Trace in its context and the crypto
+// engine reports what it does through it: chunk boundaries, the inputs and
+// outputs of IV derivation, chunk manifests and how object metadata is
+// transformed on the way to and from the backend. Events are logged at info
+// level, one line each, tagged with the request ID.
+//
+// Traces include IVs, salts, wrapped keys and user metadata, everything
+// short of key material and object data. They are for debugging the object
+// format on development builds, never for production.
+package devtrace
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+var logger atomic.Pointer[logrus.Logger]
+
+// Enable turns tracing on, logging to l. It is meant to be called once at
+// startup.
+func Enable(l *logrus.Logger) {
+	logger.Store(l)
+}
+
+// Enabled reports whether tracing is on.
+func Enabled() bool {
+	return logger.Load() != nil
+}
+
+// Trace records the events of one request. A nil *Trace records nothing,
+// so callers need no checks beyond skipping expensive field construction.
+type Trace struct {
+	entry *logrus.Entry
+	seq   atomic.Int64
+}
+
+type traceKey struct{}
+
+// New returns a trace whose events carry fields, or nil when tracing is
+// off.
+func New(fields logrus.Fields) *Trace {
+	l := logger.Load()
+	if l == nil {
+		return nil
+	}
+	return &Trace{entry: l.WithFields(fields)}
+}
+
+// WithTrace returns ctx carrying t.
+func WithTrace(ctx context.Context, t *Trace) context.Context {
+	if t == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// From returns the trace in ctx, or nil.
+func From(ctx context.Context) *Trace {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+// Event logs one event. seq orders the events of a request, which chunk
+// workers may log out of order.
+func (t *Trace) Event(event string, fields logrus.Fields) {
+	if t == nil {
+		return
+	}
+	t.entry.WithFields(fields).WithFields(logrus.Fields{
+		"dev_trace": event,
+		"seq":       t.seq.Add(1),
+	}).Info("dev trace")
+}
+
+// Hex formats b for an event field.
+func Hex(b []byte) string {
+	return hex.EncodeToString(b)
+}
+
+// Middleware attaches a Trace to every request while tracing is on. It
+// belongs inside the middleware that sets requestIDHeader on the response,
+// whose value tags the trace.
+func Middleware(requestIDHeader string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t := New(logrus.Fields{
+				"request_id": w.Header().Get(requestIDHeader),
+				"method":     r.Method,
+				"path":       r.URL.Path,
+			})
+			if t != nil {
+				r = r.WithContext(WithTrace(r.Context(), t))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package devtrace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestDisabled(t *testing.T) {
+	logger.Store(nil)
+	if New(logrus.Fields{}) != nil {
+		t.Fatal("trace created while disabled")
+	}
+	var none *Trace
+	none.Event("ignored", nil)
+	if From(context.Background()) != nil || From(WithTrace(context.Background(), nil)) != nil {
+		t.Fatal("trace found in a context without one")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	l := logrus.New()
+	l.SetOutput(&buf)
+	l.SetFormatter(&logrus.JSONFormatter{})
+	Enable(l)
+	defer logger.Store(nil)
+
+	h := Middleware("x-amz-request-id")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tr := From(r.Context())
+		tr.Event("first", logrus.Fields{"chunk": 0})
+		tr.Event("second", logrus.Fields{"iv": Hex([]byte{0xab, 0x01})})
+	}))
+	rec := httptest.NewRecorder()
+	rec.Header().Set("x-amz-request-id", "REQ1")
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bucket/key", nil))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines:\n%s", len(lines), buf.String())
+	}
+	var events []map[string]any
+	for _, line := range lines {
+		var e map[string]any
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	for i, e := range events {
+		if e["request_id"] != "REQ1" || e["method"] != "GET" || e["path"] != "/bucket/key" {
+			t.Errorf("event %d not tagged with the request: %v", i, e)
+		}
+		if e["seq"] != float64(i+1) {
+			t.Errorf("event %d seq = %v", i, e["seq"])
+		}
+	}
+	if events[0]["dev_trace"] != "first" || events[1]["iv"] != "ab01" {
+		t.Errorf("events = %v", events)
+	}
+}
@@ -0,0 +1,85 @@
+package dlp
+
+import (
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type builtin struct {
+	re    *regexp.Regexp
+	valid func(string) bool
+}
+
+// builtins are detectors whose regexp alone would flag too much; each hit
+// is confirmed by a checksum or structural check.
+var builtins = map[string]builtin{
+	"credit_card": {
+		re:    regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		valid: luhnValid,
+	},
+	"us_ssn": {
+		re:    regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+		valid: ssnValid,
+	},
+	"iban": {
+		re:    regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`),
+		valid: ibanValid,
+	},
+	"aws_access_key": {
+		re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	},
+}
+
+// luhnValid reports whether the digits in s form a 13-19 digit number with
+// a valid Luhn check digit.
+func luhnValid(s string) bool {
+	var digits []int
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			digits = append(digits, int(c-'0'))
+		}
+	}
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if (len(digits)-1-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// ssnValid rejects numbers the SSA never issues: area 000, 666 or 9xx,
+// group 00 and serial 0000.
+func ssnValid(s string) bool {
+	area, group, serial := s[0:3], s[4:6], s[7:11]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+// ibanValid checks the ISO 13616 mod-97 checksum.
+func ibanValid(s string) bool {
+	s = strings.ReplaceAll(s, " ", "")
+	rearranged := s[4:] + s[:4]
+	var num strings.Builder
+	for _, c := range rearranged {
+		switch {
+		case c >= '0' && c <= '9':
+			num.WriteRune(c)
+		case c >= 'A' && c <= 'Z':
+			num.WriteString(strconv.Itoa(int(c - 'A' + 10)))
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(num.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
@@ -0,0 +1,159 @@
+// Package dlp inspects upload content against data-loss-prevention rules
+// before it is encrypted.
+//
+// Only the leading bytes of text uploads are inspected, so the cost per
+// upload is bounded. Rules report what matched and how often; the content
+// that matched is never returned, logged or audited.
+package dlp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+// defaultContentTypes are inspected when no content types are configured.
+// They match the types compression treats as text.
+var defaultContentTypes = []string{
+	"text/",
+	"application/json",
+	"application/xml",
+	"application/javascript",
+	"application/x-javascript",
+	"application/x-sh",
+	"application/x-csh",
+	"application/x-perl",
+	"application/x-python",
+	"application/x-ruby",
+}
+
+// actionRank orders actions so the strongest match decides the outcome.
+var actionRank = map[string]int{
+	config.InspectionActionAudit: 1,
+	config.InspectionActionTag:   2,
+	config.InspectionActionBlock: 3,
+}
+
+// Match reports how often one rule matched.
+type Match struct {
+	Rule   string
+	Action string
+	Count  int
+}
+
+// Result is the outcome of inspecting one upload.
+type Result struct {
+	Matches []Match
+	// Action is the strongest action among Matches, or "" when nothing
+	// matched.
+	Action string
+	// Truncated is set when only part of the upload was inspected.
+	Truncated bool
+}
+
+// Rules returns the names of the matching rules whose action is at least
+// action, in rule order.
+func (r Result) Rules(action string) []string {
+	var names []string
+	for _, m := range r.Matches {
+		if actionRank[m.Action] >= actionRank[action] {
+			names = append(names, m.Rule)
+		}
+	}
+	return names
+}
+
+type rule struct {
+	name   string
+	action string
+	re     *regexp.Regexp
+	// valid filters regexp hits, e.g. by checksum. nil accepts every hit.
+	valid func(string) bool
+}
+
+// Inspector applies compiled rules to upload content.
+type Inspector struct {
+	rules        []*rule
+	maxSize      int64
+	contentTypes []string
+}
+
+// New compiles cfg into an inspector, or returns nil when inspection is
+// disabled.
+func New(cfg config.InspectionConfig) (*Inspector, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	in := &Inspector{maxSize: cfg.MaxSize, contentTypes: cfg.ContentTypes}
+	if len(in.contentTypes) == 0 {
+		in.contentTypes = defaultContentTypes
+	}
+	for _, rc := range cfg.Rules {
+		r := &rule{name: rc.Name, action: rc.Action}
+		switch {
+		case rc.Pattern != "":
+			re, err := regexp.Compile(rc.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("dlp: rule %s: %w", rc.Name, err)
+			}
+			r.re = re
+		case len(rc.Keywords) > 0:
+			quoted := make([]string, len(rc.Keywords))
+			for i, kw := range rc.Keywords {
+				quoted[i] = regexp.QuoteMeta(kw)
+			}
+			r.re = regexp.MustCompile(`(?i)(?:` + strings.Join(quoted, "|") + `)`)
+		default:
+			b, ok := builtins[rc.Builtin]
+			if !ok {
+				return nil, fmt.Errorf("dlp: rule %s: unknown builtin %q", rc.Name, rc.Builtin)
+			}
+			r.re, r.valid = b.re, b.valid
+		}
+		in.rules = append(in.rules, r)
+	}
+	return in, nil
+}
+
+// MaxSize returns the number of leading bytes inspected per upload.
+func (in *Inspector) MaxSize() int64 {
+	return in.maxSize
+}
+
+// Applies reports whether uploads of contentType are inspected. A nil
+// inspector inspects nothing.
+func (in *Inspector) Applies(contentType string) bool {
+	if in == nil {
+		return false
+	}
+	ct := strings.ToLower(strings.TrimSpace(contentType))
+	for _, p := range in.contentTypes {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" && strings.HasPrefix(ct, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Inspect runs every rule over data. Matches are reported in rule order.
+func (in *Inspector) Inspect(data []byte) Result {
+	var res Result
+	for _, r := range in.rules {
+		count := 0
+		for _, loc := range r.re.FindAllIndex(data, -1) {
+			if r.valid == nil || r.valid(string(data[loc[0]:loc[1]])) {
+				count++
+			}
+		}
+		if count == 0 {
+			continue
+		}
+		res.Matches = append(res.Matches, Match{Rule: r.name, Action: r.action, Count: count})
+		if actionRank[r.action] > actionRank[res.Action] {
+			res.Action = r.action
+		}
+	}
+	return res
+}
@@ -0,0 +1,91 @@
+package featureflag
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+func TestSet_Enabled(t *testing.T) {
+	s, err := New(map[string]config.FeatureFlag{
+		ParallelRangeFetch: {Enabled: true, Buckets: []string{"canary"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Enabled(ParallelRangeFetch, "canary", "a") || s.Enabled(ParallelRangeFetch, "prod", "a") {
+		t.Error("bucket rule not applied")
+	}
+	if s.Enabled("other", "canary", "a") {
+		t.Error("unconfigured flag is on")
+	}
+
+	var nilSet *Set
+	if nilSet.Enabled(ParallelRangeFetch, "canary", "a") {
+		t.Error("nil set reports a flag as on")
+	}
+}
+
+func TestSet_Percentage(t *testing.T) {
+	s, err := New(map[string]config.FeatureFlag{ParallelRangeFetch: {Enabled: true, Percentage: 25}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	on := 0
+	for i := 0; i < 4000; i++ {
+		key := fmt.Sprintf("obj-%d", i)
+		got := s.Enabled(ParallelRangeFetch, "b", key)
+		if got != s.Enabled(ParallelRangeFetch, "b", key) {
+			t.Fatalf("%s: rollout decision is not stable", key)
+		}
+		if got {
+			on++
+		}
+	}
+	if on < 800 || on > 1200 {
+		t.Errorf("%d of 4000 objects enabled at 25%%", on)
+	}
+}
+
+func TestSet_Override(t *testing.T) {
+	s, err := New(map[string]config.FeatureFlag{ParallelRangeFetch: {Enabled: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Override(ParallelRangeFetch, config.FeatureFlag{Enabled: false}); err != nil {
+		t.Fatal(err)
+	}
+	if s.Enabled(ParallelRangeFetch, "b", "k") {
+		t.Error("override did not switch the flag off")
+	}
+	if err := s.Reload(map[string]config.FeatureFlag{ParallelRangeFetch: {Enabled: true}}); err != nil {
+		t.Fatal(err)
+	}
+	if s.Enabled(ParallelRangeFetch, "b", "k") {
+		t.Error("reload dropped the override")
+	}
+	states := s.List()
+	if len(states) != 1 || states[0].Override == nil || states[0].Config == nil || states[0].Effective.Enabled {
+		t.Errorf("states = %+v", states)
+	}
+	if !s.ClearOverride(ParallelRangeFetch) || s.ClearOverride(ParallelRangeFetch) {
+		t.Error("ClearOverride did not report the override once")
+	}
+	if !s.Enabled(ParallelRangeFetch, "b", "k") {
+		t.Error("configured rule not restored")
+	}
+}
+
+func TestSet_RejectsUnknownAndInvalid(t *testing.T) {
+	if _, err := New(map[string]config.FeatureFlag{"paralel_range_fetch": {Enabled: true}}); err == nil {
+		t.Error("unknown flag accepted")
+	}
+	s, _ := New(nil)
+	if err := s.Override(ParallelRangeFetch, config.FeatureFlag{Enabled: true, Percentage: 101}); err == nil {
+		t.Error("percentage over 100 accepted")
+	}
+	if err := s.Override("nope", config.FeatureFlag{}); err == nil {
+		t.Error("override of an unknown flag accepted")
+	}
+}
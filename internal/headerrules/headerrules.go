@@ -0,0 +1,80 @@
+// Package headerrules applies configured header rewrites, so provider
+// quirks such as a backend rejecting an x-amz-* header the SDK sends can be
+// worked around without code changes.
+package headerrules
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+type rule struct {
+	action string
+	name   string // canonical; the prefix for wildcard removals
+	prefix bool
+	value  string
+	to     string
+}
+
+// Rules is a compiled, ordered list of header rules. A nil *Rules applies
+// nothing.
+type Rules struct {
+	rules     []rule
+	protected map[string]bool
+}
+
+// New compiles rules, which config validation has already checked.
+// Wildcard removals never remove a protected header. New returns nil when
+// there are no rules.
+func New(rules []config.HeaderRule, protected ...string) *Rules {
+	if len(rules) == 0 {
+		return nil
+	}
+	r := &Rules{protected: make(map[string]bool, len(protected))}
+	for _, p := range protected {
+		r.protected[http.CanonicalHeaderKey(p)] = true
+	}
+	for _, c := range rules {
+		name := strings.TrimSuffix(c.Name, "*")
+		r.rules = append(r.rules, rule{
+			action: c.Action,
+			name:   http.CanonicalHeaderKey(name),
+			prefix: name != c.Name,
+			value:  c.Value,
+			to:     http.CanonicalHeaderKey(c.To),
+		})
+	}
+	return r
+}
+
+// Apply rewrites h in place.
+func (r *Rules) Apply(h http.Header) {
+	if r == nil {
+		return
+	}
+	for _, rl := range r.rules {
+		switch rl.action {
+		case config.HeaderRuleSet:
+			h.Set(rl.name, rl.value)
+		case config.HeaderRuleAdd:
+			h.Add(rl.name, rl.value)
+		case config.HeaderRuleRemove:
+			if !rl.prefix {
+				h.Del(rl.name)
+				continue
+			}
+			for k := range h {
+				if strings.HasPrefix(http.CanonicalHeaderKey(k), rl.name) && !r.protected[http.CanonicalHeaderKey(k)] {
+					delete(h, k)
+				}
+			}
+		case config.HeaderRuleRename:
+			if v, ok := h[rl.name]; ok {
+				h.Del(rl.name)
+				h[rl.to] = append(h[rl.to], v...)
+			}
+		}
+	}
+}
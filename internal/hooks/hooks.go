@@ -0,0 +1,364 @@
+// Package hooks runs post-PUT processors on decrypted objects.
+//
+// A PUT through the gateway enqueues an event for every matching rule. A
+// worker later reads the object back in plaintext through the gateway,
+// streams it to the rule's processor (a local command or a webhook) and
+// stores whatever the processor returns as a derived object, encrypted like
+// any other write. Processors therefore never see ciphertext, and they are
+// never given the gateway's configuration, environment or key material.
+//
+// Hook delivery is best effort: the queue is bounded and events that do not
+// fit are dropped and logged, so a slow processor can never hold up PUTs.
+// Derived objects do not trigger hooks themselves.
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// MetaDerivedFrom names the user metadata entry recording the source key of
+// a derived object.
+const MetaDerivedFrom = "x-amz-meta-derived-from"
+
+// Event describes a completed PUT.
+type Event struct {
+	Bucket      string
+	Key         string
+	ContentType string
+	// Size is the plaintext size, or 0 when unknown.
+	Size int64
+}
+
+// Store reads and writes objects through the gateway's encryption pipeline.
+type Store interface {
+	// OpenPlaintext returns the decrypted body of bucket/key.
+	OpenPlaintext(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+	// PutDerived encrypts body (exactly size bytes) and stores it at
+	// bucket/key.
+	PutDerived(ctx context.Context, bucket, key string, body io.Reader, size int64, contentType string, metadata map[string]string) error
+}
+
+// Output is the result of one processor run.
+type Output struct {
+	// ContentType reported by the processor, if any.
+	ContentType string
+	// Empty is true when the processor chose not to produce an object.
+	Empty bool
+}
+
+// Processor derives an object from a plaintext stream. It writes the derived
+// object to out and must not write more than the caller allows; out fails
+// once the limit is exceeded.
+type Processor interface {
+	Process(ctx context.Context, ev Event, in io.Reader, out io.Writer) (Output, error)
+}
+
+// Rule is a compiled hook rule.
+type Rule struct {
+	Name              string
+	ContentTypes      []string
+	Prefix            string
+	Output            string
+	OutputContentType string
+	Timeout           time.Duration
+	Processor         Processor
+}
+
+// Matches reports whether ev should be processed by r.
+func (r *Rule) Matches(ev Event) bool {
+	if !strings.HasPrefix(ev.Key, r.Prefix) {
+		return false
+	}
+	if len(r.ContentTypes) == 0 {
+		return true
+	}
+	mt, _, err := mime.ParseMediaType(ev.ContentType)
+	if err != nil {
+		mt = strings.ToLower(strings.TrimSpace(ev.ContentType))
+	}
+	for _, want := range r.ContentTypes {
+		want = strings.ToLower(strings.TrimSpace(want))
+		switch {
+		case want == "*/*" || want == mt:
+			return true
+		case strings.HasSuffix(want, "/*") && strings.HasPrefix(mt, strings.TrimSuffix(want, "*")):
+			return true
+		}
+	}
+	return false
+}
+
+// OutputKey expands the rule's output template for a source key.
+func (r *Rule) OutputKey(key string) string {
+	dir, name := path.Split(key)
+	return strings.NewReplacer("{key}", key, "{dir}", dir, "{name}", name).Replace(r.Output)
+}
+
+type job struct {
+	rule *Rule
+	ev   Event
+}
+
+// Pipeline queues events and runs them on a fixed set of workers. All
+// methods are safe on a nil *Pipeline, which behaves as a disabled pipeline.
+type Pipeline struct {
+	rules     []*Rule
+	store     Store
+	logger    *logrus.Logger
+	workers   int
+	maxInput  int64
+	maxOutput int64
+
+	mu      sync.RWMutex // guards closing queue against concurrent Notify
+	queue   chan job
+	stopped bool
+	wg      sync.WaitGroup
+	dropped atomic.Int64
+}
+
+// New compiles cfg into a pipeline writing through store, or returns nil
+// when hooks are disabled.
+func New(cfg config.HooksConfig, store Store, logger *logrus.Logger) (*Pipeline, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	p := &Pipeline{
+		store:     store,
+		logger:    logger,
+		workers:   cfg.Workers,
+		maxInput:  cfg.MaxObjectSize,
+		maxOutput: cfg.MaxOutputSize,
+		queue:     make(chan job, cfg.QueueSize),
+	}
+	if p.workers < 1 {
+		p.workers = config.DefaultHooksWorkers
+	}
+	for i, rc := range cfg.Rules {
+		r := &Rule{
+			Name:              rc.Name,
+			ContentTypes:      rc.ContentTypes,
+			Prefix:            rc.Prefix,
+			Output:            rc.Output,
+			OutputContentType: rc.OutputContentType,
+			Timeout:           rc.Timeout,
+		}
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule-%d", i)
+		}
+		if r.Timeout <= 0 {
+			r.Timeout = config.DefaultHookTimeout
+		}
+		switch {
+		case len(rc.Exec) > 0:
+			r.Processor = &ExecProcessor{Argv: rc.Exec}
+		case rc.Webhook != "":
+			r.Processor = NewWebhookProcessor(rc.Webhook)
+		default:
+			return nil, fmt.Errorf("hooks: rule %s has no processor", r.Name)
+		}
+		p.rules = append(p.rules, r)
+	}
+	return p, nil
+}
+
+// Start launches the workers.
+func (p *Pipeline) Start() {
+	if p == nil {
+		return
+	}
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for j := range p.queue {
+				p.run(j)
+			}
+		}()
+	}
+}
+
+// Notify enqueues ev for every matching rule without blocking.
+func (p *Pipeline) Notify(ev Event) {
+	if p == nil || ev.Size > p.maxInput {
+		return
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stopped {
+		return
+	}
+	for _, r := range p.rules {
+		if !r.Matches(ev) {
+			continue
+		}
+		select {
+		case p.queue <- job{rule: r, ev: ev}:
+		default:
+			n := p.dropped.Add(1)
+			p.logger.WithFields(logrus.Fields{
+				"rule":    r.Name,
+				"bucket":  ev.Bucket,
+				"key":     ev.Key,
+				"dropped": n,
+			}).Warn("Hook queue full; event dropped")
+		}
+	}
+}
+
+// Dropped returns the number of events dropped because the queue was full.
+func (p *Pipeline) Dropped() int64 {
+	if p == nil {
+		return 0
+	}
+	return p.dropped.Load()
+}
+
+// Stop stops accepting events and waits for queued ones to finish, or for
+// ctx to end.
+func (p *Pipeline) Stop(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pipeline) run(j job) {
+	start := time.Now()
+	out := j.rule.OutputKey(j.ev.Key)
+	entry := p.logger.WithFields(logrus.Fields{
+		"rule":   j.rule.Name,
+		"bucket": j.ev.Bucket,
+		"key":    j.ev.Key,
+		"output": out,
+	})
+	size, err := p.process(j.rule, j.ev, out)
+	entry = entry.WithField("duration", time.Since(start))
+	switch {
+	case err != nil:
+		entry.WithError(err).Warn("Hook failed")
+	case size < 0:
+		entry.Debug("Hook produced no output")
+	default:
+		entry.WithField("size", size).Info("Hook stored derived object")
+	}
+}
+
+// process runs one rule and stores its output. It returns the stored size,
+// or -1 when the processor produced nothing.
+func (p *Pipeline) process(r *Rule, ev Event, outKey string) (int64, error) {
+	if outKey == ev.Key {
+		return -1, errors.New("output key equals the source key")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.Timeout)
+	defer cancel()
+
+	src, err := p.store.OpenPlaintext(ctx, ev.Bucket, ev.Key)
+	if err != nil {
+		return -1, fmt.Errorf("read source: %w", err)
+	}
+	defer src.Close()
+
+	spool, err := os.CreateTemp("", "s3eg-hook-*")
+	if err != nil {
+		return -1, fmt.Errorf("spool: %w", err)
+	}
+	defer func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}()
+
+	in := &limitReader{r: src, n: p.maxInput}
+	res, err := r.Processor.Process(ctx, ev, in, &limitWriter{w: spool, n: p.maxOutput})
+	if in.exceeded {
+		return -1, fmt.Errorf("source exceeds %d bytes", p.maxInput)
+	}
+	if err != nil {
+		return -1, err
+	}
+	size, err := spool.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1, fmt.Errorf("spool: %w", err)
+	}
+	if res.Empty || size == 0 {
+		return -1, nil
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return -1, fmt.Errorf("spool: %w", err)
+	}
+
+	contentType := r.OutputContentType
+	if contentType == "" {
+		contentType = res.ContentType
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	meta := map[string]string{MetaDerivedFrom: ev.Key}
+	if err := p.store.PutDerived(ctx, ev.Bucket, outKey, spool, size, contentType, meta); err != nil {
+		return -1, fmt.Errorf("store output: %w", err)
+	}
+	return size, nil
+}
+
+// ErrOutputTooLarge is returned by writes past the output limit.
+var ErrOutputTooLarge = errors.New("hooks: output exceeds max_output_size")
+
+type limitWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (l *limitWriter) Write(b []byte) (int, error) {
+	if int64(len(b)) > l.n {
+		return 0, ErrOutputTooLarge
+	}
+	n, err := l.w.Write(b)
+	l.n -= int64(n)
+	return n, err
+}
+
+// limitReader fails once more than n bytes are read.
+type limitReader struct {
+	r        io.Reader
+	n        int64
+	exceeded bool
+}
+
+func (l *limitReader) Read(b []byte) (int, error) {
+	n, err := l.r.Read(b)
+	l.n -= int64(n)
+	if l.n < 0 {
+		l.exceeded = true
+		return n, errors.New("hooks: source exceeds max_object_size")
+	}
+	return n, err
+}
@@ -0,0 +1,240 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+type memStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+	meta    map[string]map[string]string
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		objects: make(map[string][]byte),
+		types:   make(map[string]string),
+		meta:    make(map[string]map[string]string),
+	}
+}
+
+func (m *memStore) OpenPlaintext(_ context.Context, bucket, key string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.objects[bucket+"/"+key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (m *memStore) PutDerived(_ context.Context, bucket, key string, body io.Reader, size int64, contentType string, meta map[string]string) error {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if int64(len(b)) != size {
+		return errors.New("size mismatch")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[bucket+"/"+key] = b
+	m.types[bucket+"/"+key] = contentType
+	m.meta[bucket+"/"+key] = meta
+	return nil
+}
+
+func (m *memStore) get(k string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.objects[k]
+	return b, ok
+}
+
+type upperProcessor struct{}
+
+func (upperProcessor) Process(_ context.Context, _ Event, in io.Reader, out io.Writer) (Output, error) {
+	b, err := io.ReadAll(in)
+	if err != nil {
+		return Output{}, err
+	}
+	_, err = out.Write(bytes.ToUpper(b))
+	return Output{ContentType: "text/upper"}, err
+}
+
+func testLogger() *logrus.Logger {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	return l
+}
+
+func TestRule_Matches(t *testing.T) {
+	r := &Rule{ContentTypes: []string{"image/*", "application/pdf"}, Prefix: "uploads/"}
+	tests := []struct {
+		ev   Event
+		want bool
+	}{
+		{Event{Key: "uploads/a.png", ContentType: "image/png"}, true},
+		{Event{Key: "uploads/a.pdf", ContentType: "application/pdf; charset=binary"}, true},
+		{Event{Key: "uploads/a.txt", ContentType: "text/plain"}, false},
+		{Event{Key: "other/a.png", ContentType: "image/png"}, false},
+	}
+	for _, tt := range tests {
+		if got := r.Matches(tt.ev); got != tt.want {
+			t.Errorf("Matches(%+v) = %v, want %v", tt.ev, got, tt.want)
+		}
+	}
+	if !(&Rule{}).Matches(Event{Key: "x", ContentType: "anything/at-all"}) {
+		t.Error("a rule without content types should match everything")
+	}
+}
+
+func TestRule_OutputKey(t *testing.T) {
+	r := &Rule{Output: "{dir}thumbs/{name}.jpg"}
+	if got := r.OutputKey("photos/2024/cat.png"); got != "photos/2024/thumbs/cat.png.jpg" {
+		t.Errorf("OutputKey = %q", got)
+	}
+	if got := (&Rule{Output: "derived/{key}"}).OutputKey("a/b"); got != "derived/a/b" {
+		t.Errorf("OutputKey = %q", got)
+	}
+}
+
+func TestPipeline_StoresDerivedObject(t *testing.T) {
+	store := newMemStore()
+	store.objects["b/docs/a.txt"] = []byte("hello")
+	p := &Pipeline{
+		rules: []*Rule{{
+			Name: "upper", Output: "derived/{key}", Timeout: time.Second, Processor: upperProcessor{},
+		}},
+		store: store, logger: testLogger(), workers: 1, maxInput: 1 << 20, maxOutput: 1 << 20,
+		queue: make(chan job, 4),
+	}
+	p.Start()
+	p.Notify(Event{Bucket: "b", Key: "docs/a.txt", ContentType: "text/plain", Size: 5})
+	if err := p.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	got, ok := store.get("b/derived/docs/a.txt")
+	if !ok || string(got) != "HELLO" {
+		t.Fatalf("derived object = %q, %v", got, ok)
+	}
+	if store.types["b/derived/docs/a.txt"] != "text/upper" {
+		t.Errorf("content type = %q", store.types["b/derived/docs/a.txt"])
+	}
+	if store.meta["b/derived/docs/a.txt"][MetaDerivedFrom] != "docs/a.txt" {
+		t.Errorf("derived-from metadata = %v", store.meta["b/derived/docs/a.txt"])
+	}
+
+	// Notify after Stop is a no-op rather than a panic.
+	p.Notify(Event{Bucket: "b", Key: "docs/a.txt"})
+}
+
+func TestPipeline_Limits(t *testing.T) {
+	store := newMemStore()
+	store.objects["b/big"] = bytes.Repeat([]byte("x"), 64)
+	p := &Pipeline{store: store, logger: testLogger(), maxInput: 16, maxOutput: 1 << 20}
+	rule := &Rule{Output: "out/{key}", Timeout: time.Second, Processor: upperProcessor{}}
+	if _, err := p.process(rule, Event{Bucket: "b", Key: "big"}, "out/big"); err == nil {
+		t.Error("expected an error for a source over max_object_size")
+	}
+
+	p.maxInput, p.maxOutput = 1<<20, 16
+	if _, err := p.process(rule, Event{Bucket: "b", Key: "big"}, "out/big"); !errors.Is(err, ErrOutputTooLarge) {
+		t.Errorf("err = %v, want ErrOutputTooLarge", err)
+	}
+	if _, ok := store.get("b/out/big"); ok {
+		t.Error("oversized output was stored")
+	}
+
+	if _, err := p.process(rule, Event{Bucket: "b", Key: "big"}, "big"); err == nil {
+		t.Error("expected an error when the output would overwrite the source")
+	}
+}
+
+func TestPipeline_QueueFullDrops(t *testing.T) {
+	p := &Pipeline{
+		rules:  []*Rule{{Name: "r", Processor: upperProcessor{}}},
+		logger: testLogger(), maxInput: 1 << 20, queue: make(chan job, 1),
+	}
+	p.Notify(Event{Key: "a"})
+	p.Notify(Event{Key: "b"})
+	if p.Dropped() != 1 {
+		t.Errorf("Dropped = %d, want 1", p.Dropped())
+	}
+}
+
+func TestExecProcessor_MinimalEnvironment(t *testing.T) {
+	t.Setenv("ENCRYPTION_PASSWORD", "must-not-leak")
+	e := &ExecProcessor{Argv: []string{"sh", "-c", "cat; env"}}
+	var out bytes.Buffer
+	_, err := e.Process(context.Background(), Event{Bucket: "b", Key: "k", ContentType: "text/plain", Size: 3}, strings.NewReader("abc"), &out)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	got := out.String()
+	if !strings.HasPrefix(got, "abc") || !strings.Contains(got, "S3EG_KEY=k") {
+		t.Errorf("unexpected output %q", got)
+	}
+	if strings.Contains(got, "must-not-leak") {
+		t.Error("gateway environment leaked into the hook command")
+	}
+
+	e = &ExecProcessor{Argv: []string{"sh", "-c", "echo boom >&2; exit 3"}}
+	if _, err := e.Process(context.Background(), Event{}, strings.NewReader(""), io.Discard); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("err = %v, want stderr in error", err)
+	}
+}
+
+func TestWebhookProcessor(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.Header.Get("X-S3EG-Key") {
+		case "skip":
+			w.WriteHeader(http.StatusNoContent)
+		case "fail":
+			http.Error(w, "nope", http.StatusBadGateway)
+		default:
+			w.Header().Set("Content-Type", "image/jpeg")
+			_, _ = w.Write(append([]byte("thumb:"), body...))
+		}
+	}))
+	defer srv.Close()
+	wp := NewWebhookProcessor(srv.URL)
+
+	var out bytes.Buffer
+	res, err := wp.Process(context.Background(), Event{Key: "k", ContentType: "image/png"}, strings.NewReader("px"), &out)
+	if err != nil || out.String() != "thumb:px" || res.ContentType != "image/jpeg" {
+		t.Errorf("Process = %+v, %q, %v", res, out.String(), err)
+	}
+	if res, err := wp.Process(context.Background(), Event{Key: "skip"}, strings.NewReader("px"), io.Discard); err != nil || !res.Empty {
+		t.Errorf("204: %+v, %v", res, err)
+	}
+	if _, err := wp.Process(context.Background(), Event{Key: "fail"}, strings.NewReader("px"), io.Discard); err == nil {
+		t.Error("expected an error for a 502 response")
+	}
+}
+
+func TestNew_Disabled(t *testing.T) {
+	p, err := New(config.HooksConfig{}, newMemStore(), testLogger())
+	if err != nil || p != nil {
+		t.Fatalf("New(disabled) = %v, %v", p, err)
+	}
+	p.Notify(Event{Key: "k"})
+	if err := p.Stop(context.Background()); err != nil {
+		t.Errorf("nil Stop: %v", err)
+	}
+}
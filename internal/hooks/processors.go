@@ -0,0 +1,92 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// maxStderr bounds how much of a failing command's stderr is reported.
+const maxStderr = 4096
+
+// ExecProcessor runs a local command with the object on stdin and takes its
+// stdout as the derived object. The command gets a minimal environment so
+// nothing from the gateway's own environment (passwords, credentials) leaks
+// into it.
+type ExecProcessor struct {
+	Argv []string
+}
+
+// Process implements Processor.
+func (e *ExecProcessor) Process(ctx context.Context, ev Event, in io.Reader, out io.Writer) (Output, error) {
+	cmd := exec.CommandContext(ctx, e.Argv[0], e.Argv[1:]...)
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"S3EG_BUCKET=" + ev.Bucket,
+		"S3EG_KEY=" + ev.Key,
+		"S3EG_CONTENT_TYPE=" + ev.ContentType,
+		"S3EG_SIZE=" + strconv.FormatInt(ev.Size, 10),
+	}
+	cmd.Stdin = in
+	cmd.Stdout = out
+	var stderr bytes.Buffer
+	cmd.Stderr = &limitWriter{w: &stderr, n: maxStderr}
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return Output{}, fmt.Errorf("%s: %w: %s", e.Argv[0], err, msg)
+		}
+		return Output{}, fmt.Errorf("%s: %w", e.Argv[0], err)
+	}
+	return Output{}, nil
+}
+
+// WebhookProcessor POSTs the object to a URL. A 200 response body is the
+// derived object and its Content-Type is reported; 204 means no output.
+type WebhookProcessor struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookProcessor returns a processor posting to url.
+func NewWebhookProcessor(url string) *WebhookProcessor {
+	return &WebhookProcessor{URL: url, Client: &http.Client{}}
+}
+
+// Process implements Processor.
+func (wp *WebhookProcessor) Process(ctx context.Context, ev Event, in io.Reader, out io.Writer) (Output, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wp.URL, in)
+	if err != nil {
+		return Output{}, err
+	}
+	req.Header.Set("Content-Type", ev.ContentType)
+	req.Header.Set("X-S3EG-Bucket", ev.Bucket)
+	req.Header.Set("X-S3EG-Key", ev.Key)
+	if ev.Size > 0 {
+		req.ContentLength = ev.Size
+	}
+
+	resp, err := wp.Client.Do(req)
+	if err != nil {
+		return Output{}, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if _, err := io.Copy(out, resp.Body); err != nil {
+			return Output{}, fmt.Errorf("webhook response: %w", err)
+		}
+		return Output{ContentType: resp.Header.Get("Content-Type")}, nil
+	case http.StatusNoContent:
+		return Output{Empty: true}, nil
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxStderr))
+		return Output{}, fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+}
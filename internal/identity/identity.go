@@ -0,0 +1,42 @@
+// Package identity carries the authenticated client of a request through
+// its context.
+//
+// The gateway's auth middleware resolves each request's access key against
+// the configured credential store and attaches the result here, so handlers,
+// audit events and logs can tell clients apart without depending on the api
+// package.
+package identity
+
+import "context"
+
+// Identity is the client a request was authenticated as.
+type Identity struct {
+	// AccessKey is the access key the request was signed with.
+	AccessKey string
+	// Label is the operator-assigned name of the credential (auth.credentials
+	// label), empty when none was configured.
+	Label string
+}
+
+// Name returns the label, falling back to the access key, for places that
+// show a single identifier.
+func (id Identity) Name() string {
+	if id.Label != "" {
+		return id.Label
+	}
+	return id.AccessKey
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the identity attached to ctx, if any. Requests to
+// unauthenticated endpoints (health, metrics) carry none.
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(contextKey{}).(Identity)
+	return id, ok
+}
@@ -0,0 +1,26 @@
+package identity
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFromContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Fatal("empty context reported an identity")
+	}
+	want := Identity{AccessKey: "AKIAALICE", Label: "alice"}
+	got, ok := FromContext(NewContext(context.Background(), want))
+	if !ok || got != want {
+		t.Fatalf("FromContext = %+v, %v; want %+v", got, ok, want)
+	}
+}
+
+func TestIdentity_Name(t *testing.T) {
+	if got := (Identity{AccessKey: "AKIA1", Label: "ci"}).Name(); got != "ci" {
+		t.Errorf("Name() = %q, want label", got)
+	}
+	if got := (Identity{AccessKey: "AKIA1"}).Name(); got != "AKIA1" {
+		t.Errorf("Name() = %q, want access key", got)
+	}
+}
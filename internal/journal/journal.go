@@ -0,0 +1,488 @@
+// Package journal implements an optional write-ahead journal for object
+// writes.
+//
+// Before the gateway sends a PUT (or copy / multipart completion) to the
+// backend it appends a "begin" record; once the backend answers it appends an
+// "end" record. If the process dies in between, the begin record survives and
+// the next startup knows exactly which writes were in flight. Recover then
+// asks the backend what actually landed so operators get a consistent view
+// of which writes succeeded, and — optionally — removes objects that landed
+// without the encryption envelope the gateway intended to write.
+//
+// Once the whole body has been handed to the backend a "sent" record stores
+// the ETag the backend is expected to report for it. Recover only deletes an
+// object whose ETag matches, so a write from another client that landed in
+// the meantime is reported but never removed.
+//
+// The journal is a local JSON-lines file. It is compacted on open, after
+// recovery, and periodically while running, so it only ever holds records for
+// writes that are still unresolved.
+package journal
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// ErrClosed is returned by Begin after Close.
+var ErrClosed = errors.New("journal: closed")
+
+// Record types.
+const (
+	recordBegin = "begin"
+	recordSent  = "sent"
+	recordEnd   = "end"
+)
+
+// compactEvery is the number of completed writes after which the journal
+// file is rewritten to drop resolved records.
+const compactEvery = 1000
+
+// recoverySkew tolerates clock drift between the gateway and the backend when
+// comparing an object's Last-Modified with the journalled start time.
+const recoverySkew = 2 * time.Second
+
+// Resolution states reported by Recover.
+const (
+	StateApplied      = "applied"      // the write landed
+	StateNotApplied   = "not_applied"  // the backend never saw the write
+	StateInconsistent = "inconsistent" // landed, but without the expected encryption envelope
+	StateCleaned      = "cleaned"      // inconsistent object was deleted
+	StateUnknown      = "unknown"      // the backend could not be queried; retried next start
+)
+
+// Record is a single journal line.
+type Record struct {
+	Type      string    `json:"type"`
+	ID        string    `json:"id"`
+	Operation string    `json:"op,omitempty"`
+	Bucket    string    `json:"bucket,omitempty"`
+	Key       string    `json:"key,omitempty"`
+	Encrypted bool      `json:"encrypted,omitempty"`
+	ETag      string    `json:"etag,omitempty"`
+	Success   bool      `json:"success,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// Resolution is the outcome of recovering one interrupted write.
+type Resolution struct {
+	Record
+	State string `json:"state"`
+	Error string `json:"error,omitempty"`
+}
+
+// Backend is the subset of s3.Client used during recovery.
+type Backend interface {
+	HeadObject(ctx context.Context, bucket, key string, versionID *string) (map[string]string, error)
+	DeleteObject(ctx context.Context, bucket, key string, versionID *string) error
+}
+
+// Status is the admin view of the journal.
+type Status struct {
+	Path         string       `json:"path"`
+	InFlight     int          `json:"in_flight"`
+	Unresolved   []Record     `json:"unresolved"`
+	LastRecovery []Resolution `json:"last_recovery"`
+}
+
+// Journal is a crash-consistent log of in-flight object writes. All methods
+// are safe on a nil *Journal, which behaves as a disabled journal.
+type Journal struct {
+	mu       sync.Mutex
+	path     string
+	f        *os.File
+	sync     bool
+	prefix   string
+	seq      uint64
+	inFlight map[string]Record
+	pending  map[string]Record // interrupted writes from a previous run
+	ended    int
+	report   []Resolution
+}
+
+// Open opens (or creates) the journal at path and replays it. Writes that
+// were begun but never ended are kept as pending until Recover resolves them.
+// When syncWrites is true every record is fsynced before the call returns.
+func Open(path string, syncWrites bool) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("journal: create directory: %w", err)
+	}
+
+	j := &Journal{
+		path:     path,
+		sync:     syncWrites,
+		prefix:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		inFlight: make(map[string]Record),
+		pending:  make(map[string]Record),
+	}
+	if err := j.replay(); err != nil {
+		return nil, err
+	}
+	if err := j.compactLocked(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// replay loads unresolved begin records from the existing file.
+func (j *Journal) replay() error {
+	f, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("journal: open: %w", err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var rec Record
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			// A torn final line is the expected result of a crash mid-append.
+			continue
+		}
+		switch rec.Type {
+		case recordBegin:
+			j.pending[rec.ID] = rec
+		case recordSent:
+			if p, ok := j.pending[rec.ID]; ok {
+				p.ETag = rec.ETag
+				j.pending[rec.ID] = p
+			}
+		case recordEnd:
+			delete(j.pending, rec.ID)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("journal: read: %w", err)
+	}
+	return nil
+}
+
+// compactLocked rewrites the journal with only the unresolved begin records
+// and reopens it for appending. Caller holds j.mu (or has exclusive access).
+func (j *Journal) compactLocked() error {
+	tmp := j.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("journal: compact: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, recs := range []map[string]Record{j.pending, j.inFlight} {
+		for _, rec := range recs {
+			if err := enc.Encode(rec); err != nil {
+				f.Close()
+				return fmt.Errorf("journal: compact: %w", err)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("journal: compact: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("journal: compact: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("journal: compact: %w", err)
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		return fmt.Errorf("journal: compact: %w", err)
+	}
+
+	if j.f != nil {
+		j.f.Close()
+	}
+	j.f, err = os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("journal: reopen: %w", err)
+	}
+	j.ended = 0
+	return nil
+}
+
+// appendLocked writes rec to the journal. Caller holds j.mu.
+func (j *Journal) appendLocked(rec Record) error {
+	if j.f == nil {
+		return ErrClosed
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("journal: encode: %w", err)
+	}
+	line = append(line, '\n')
+	if _, err := j.f.Write(line); err != nil {
+		return fmt.Errorf("journal: write: %w", err)
+	}
+	if j.sync {
+		if err := j.f.Sync(); err != nil {
+			return fmt.Errorf("journal: sync: %w", err)
+		}
+	}
+	return nil
+}
+
+// Begin records the intent to write bucket/key and returns the id to pass to
+// End. encrypted states whether the object is expected to carry the
+// gateway's encryption metadata once written.
+func (j *Journal) Begin(operation, bucket, key string, encrypted bool) (string, error) {
+	if j == nil {
+		return "", nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.seq++
+	rec := Record{
+		Type:      recordBegin,
+		ID:        j.prefix + "-" + strconv.FormatUint(j.seq, 36),
+		Operation: operation,
+		Bucket:    bucket,
+		Key:       key,
+		Encrypted: encrypted,
+		Time:      time.Now().UTC(),
+	}
+	if err := j.appendLocked(rec); err != nil {
+		return "", err
+	}
+	j.inFlight[rec.ID] = rec
+	return rec.ID, nil
+}
+
+// Sent records the ETag the backend is expected to report for the write
+// identified by id once it lands. Recover will not delete an object whose
+// ETag differs. Like End, a failure to append is not returned: without the
+// record the object is only ever reported, never deleted.
+func (j *Journal) Sent(id, etag string) {
+	if j == nil || id == "" || etag == "" {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	rec, ok := j.inFlight[id]
+	if !ok {
+		return
+	}
+	if err := j.appendLocked(Record{Type: recordSent, ID: id, ETag: etag, Time: time.Now().UTC()}); err != nil {
+		return
+	}
+	rec.ETag = etag
+	j.inFlight[id] = rec
+}
+
+// Body wraps the body of a single-part write so that, once it has been read
+// to the end, its MD5 is recorded as the expected ETag of the write
+// identified by id. On a nil journal r is returned unchanged.
+func (j *Journal) Body(id string, r io.Reader) io.Reader {
+	if j == nil || id == "" {
+		return r
+	}
+	return &etagReader{r: r, h: md5.New(), sent: func(etag string) { j.Sent(id, etag) }}
+}
+
+type etagReader struct {
+	r    io.Reader
+	h    hash.Hash
+	sent func(string)
+	done bool
+}
+
+func (e *etagReader) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	e.h.Write(p[:n])
+	if err == io.EOF && !e.done {
+		e.done = true
+		e.sent(hex.EncodeToString(e.h.Sum(nil)))
+	}
+	return n, err
+}
+
+// MultipartETag returns the ETag S3 reports for a multipart object assembled
+// from parts with the given ETags, or "" when a part ETag is not an MD5.
+func MultipartETag(partETags []string) string {
+	h := md5.New()
+	for _, etag := range partETags {
+		sum, err := hex.DecodeString(strings.Trim(etag, `"`))
+		if err != nil || len(sum) != md5.Size {
+			return ""
+		}
+		h.Write(sum)
+	}
+	return hex.EncodeToString(h.Sum(nil)) + "-" + strconv.Itoa(len(partETags))
+}
+
+// End records the completion of the write identified by id. A failure to
+// append is not returned: the backend outcome is already decided and the
+// next Recover will resolve the dangling begin record.
+func (j *Journal) End(id string, writeErr error) {
+	if j == nil || id == "" {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, ok := j.inFlight[id]; !ok {
+		return
+	}
+	if err := j.appendLocked(Record{Type: recordEnd, ID: id, Success: writeErr == nil, Time: time.Now().UTC()}); err != nil {
+		return
+	}
+	delete(j.inFlight, id)
+	j.ended++
+	if j.ended >= compactEvery {
+		_ = j.compactLocked()
+	}
+}
+
+// Pending returns the writes interrupted by a previous crash that have not
+// been resolved yet, oldest first.
+func (j *Journal) Pending() []Record {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return sortedRecords(j.pending)
+}
+
+// Recover resolves every pending write against the backend. isEncrypted
+// reports whether object metadata carries the gateway's encryption envelope.
+// When cleanup is true, objects that landed without the expected envelope
+// are deleted, but only if their ETag is the one journalled for the write;
+// anything else may belong to another client and is left in place and
+// reported as inconsistent. Writes whose state cannot be determined stay
+// pending.
+func (j *Journal) Recover(ctx context.Context, backend Backend, isEncrypted func(map[string]string) bool, cleanup bool) ([]Resolution, error) {
+	if j == nil {
+		return nil, nil
+	}
+	pending := j.Pending()
+
+	results := make([]Resolution, 0, len(pending))
+	for _, rec := range pending {
+		res := Resolution{Record: rec, State: StateUnknown}
+		meta, err := backend.HeadObject(ctx, rec.Bucket, rec.Key, nil)
+		switch {
+		case err != nil && errors.Is(err, s3.ErrNotFound):
+			res.State = StateNotApplied
+		case err != nil:
+			res.Error = err.Error()
+		case !landedAfter(meta, rec.Time):
+			res.State = StateNotApplied
+		case rec.Encrypted && !isEncrypted(meta):
+			res.State = StateInconsistent
+			switch {
+			case cleanup && !sameETag(meta, rec.ETag):
+				res.Error = "object does not match the journalled write; left in place"
+			case cleanup:
+				if err := backend.DeleteObject(ctx, rec.Bucket, rec.Key, nil); err != nil {
+					res.Error = err.Error()
+				} else {
+					res.State = StateCleaned
+				}
+			}
+		default:
+			res.State = StateApplied
+		}
+		results = append(results, res)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, res := range results {
+		if res.State != StateUnknown {
+			delete(j.pending, res.ID)
+		}
+	}
+	j.report = results
+	if err := j.compactLocked(); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// Status returns a snapshot for the admin API.
+func (j *Journal) Status() Status {
+	if j == nil {
+		return Status{}
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	st := Status{
+		Path:         j.path,
+		InFlight:     len(j.inFlight),
+		Unresolved:   sortedRecords(j.pending),
+		LastRecovery: append([]Resolution(nil), j.report...),
+	}
+	if st.LastRecovery == nil {
+		st.LastRecovery = []Resolution{}
+	}
+	return st
+}
+
+// Close flushes and closes the journal file. In-flight writes remain
+// recorded as begun.
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return nil
+	}
+	err := j.f.Close()
+	j.f = nil
+	return err
+}
+
+// sameETag reports whether the object's ETag is the one journalled for the
+// write. Without a journalled ETag nothing matches.
+func sameETag(meta map[string]string, etag string) bool {
+	return etag != "" && strings.Trim(meta["ETag"], `"`) == etag
+}
+
+// landedAfter reports whether the object's Last-Modified is not older than
+// the time the write began. Objects without a parseable Last-Modified are
+// assumed to be the journalled write.
+func landedAfter(meta map[string]string, started time.Time) bool {
+	lm, ok := meta["Last-Modified"]
+	if !ok {
+		return true
+	}
+	t, err := time.Parse(time.RFC1123, lm)
+	if err != nil {
+		return true
+	}
+	return !t.Before(started.Add(-recoverySkew).Truncate(time.Second))
+}
+
+func sortedRecords(m map[string]Record) []Record {
+	out := make([]Record, 0, len(m))
+	for _, rec := range m {
+		out = append(out, rec)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Time.Before(out[b].Time) })
+	return out
+}
@@ -0,0 +1,273 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+type fakeBackend struct {
+	objects map[string]map[string]string
+	headErr map[string]error
+	deleted []string
+}
+
+func (f *fakeBackend) HeadObject(ctx context.Context, bucket, key string, versionID *string) (map[string]string, error) {
+	if err := f.headErr[bucket+"/"+key]; err != nil {
+		return nil, err
+	}
+	meta, ok := f.objects[bucket+"/"+key]
+	if !ok {
+		return nil, s3.NewAPIError("NotFound", "not found")
+	}
+	return meta, nil
+}
+
+func (f *fakeBackend) DeleteObject(ctx context.Context, bucket, key string, versionID *string) error {
+	f.deleted = append(f.deleted, bucket+"/"+key)
+	delete(f.objects, bucket+"/"+key)
+	return nil
+}
+
+func isEncrypted(meta map[string]string) bool { return meta["x-amz-meta-encrypted"] == "true" }
+
+func TestJournal_CompletedWritesAreNotPending(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.log")
+	j, err := Open(path, true)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	id, err := j.Begin("PutObject", "bucket", "done", true)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	j.End(id, nil)
+	if _, err := j.Begin("PutObject", "bucket", "crashed", true); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if st := j.Status(); st.InFlight != 1 {
+		t.Errorf("expected 1 in-flight write, got %d", st.InFlight)
+	}
+	// Simulate a crash: the process goes away without ending the second write.
+	j.Close()
+
+	j2, err := Open(path, true)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer j2.Close()
+	pending := j2.Pending()
+	if len(pending) != 1 || pending[0].Key != "crashed" || pending[0].Operation != "PutObject" {
+		t.Fatalf("expected only the interrupted write to be pending, got %+v", pending)
+	}
+}
+
+func TestJournal_TornLineIgnored(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.log")
+	j, err := Open(path, false)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := j.Begin("PutObject", "b", "k", true); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	j.Close()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"type":"end","id":"tru`)
+	f.Close()
+
+	j2, err := Open(path, false)
+	if err != nil {
+		t.Fatalf("reopen with torn line: %v", err)
+	}
+	defer j2.Close()
+	if len(j2.Pending()) != 1 {
+		t.Errorf("expected torn end record to be ignored")
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), `"tru`) {
+		t.Errorf("expected compaction to drop the torn line, got %q", data)
+	}
+}
+
+func TestJournal_Recover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.log")
+	j, err := Open(path, true)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for _, key := range []string{"applied", "missing", "stale", "plaintext", "foreign", "unreachable"} {
+		id, err := j.Begin("PutObject", "b", key, true)
+		if err != nil {
+			t.Fatalf("Begin: %v", err)
+		}
+		j.Sent(id, "0123456789abcdef0123456789abcdef")
+	}
+	j.Close()
+
+	j, err = Open(path, true)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer j.Close()
+
+	fresh := time.Now().UTC().Add(time.Minute).Format(time.RFC1123)
+	backend := &fakeBackend{
+		objects: map[string]map[string]string{
+			"b/applied":   {"x-amz-meta-encrypted": "true", "Last-Modified": fresh},
+			"b/stale":     {"x-amz-meta-encrypted": "true", "Last-Modified": "Mon, 02 Jan 2006 15:04:05 GMT"},
+			"b/plaintext": {"Last-Modified": fresh, "ETag": `"0123456789abcdef0123456789abcdef"`},
+			"b/foreign":   {"Last-Modified": fresh, "ETag": `"fedcba9876543210fedcba9876543210"`},
+		},
+		headErr: map[string]error{"b/unreachable": errors.New("connection refused")},
+	}
+
+	results, err := j.Recover(context.Background(), backend, isEncrypted, true)
+	if err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	got := make(map[string]string)
+	for _, r := range results {
+		got[r.Key] = r.State
+	}
+	want := map[string]string{
+		"applied":     StateApplied,
+		"missing":     StateNotApplied,
+		"stale":       StateNotApplied,
+		"plaintext":   StateCleaned,
+		"foreign":     StateInconsistent,
+		"unreachable": StateUnknown,
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: state %q, want %q", k, got[k], v)
+		}
+	}
+	if len(backend.deleted) != 1 || backend.deleted[0] != "b/plaintext" {
+		t.Errorf("expected only the plaintext object deleted, got %v", backend.deleted)
+	}
+
+	if _, ok := backend.objects["b/foreign"]; !ok {
+		t.Error("an object written by someone else must not be deleted")
+	}
+
+	// Only the unreachable write stays pending, and survives a restart.
+	if p := j.Pending(); len(p) != 1 || p[0].Key != "unreachable" {
+		t.Errorf("expected unreachable write to remain pending, got %+v", p)
+	}
+	j.Close()
+	j2, err := Open(path, true)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer j2.Close()
+	if p := j2.Pending(); len(p) != 1 || p[0].Key != "unreachable" {
+		t.Errorf("expected unreachable write to persist across restart, got %+v", p)
+	}
+}
+
+func TestJournal_RecoverReportOnlyWithoutCleanup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.log")
+	j, _ := Open(path, false)
+	j.Begin("CopyObject", "b", "plaintext", true)
+	j.Close()
+
+	j, _ = Open(path, false)
+	defer j.Close()
+	backend := &fakeBackend{objects: map[string]map[string]string{"b/plaintext": {}}}
+	results, err := j.Recover(context.Background(), backend, isEncrypted, false)
+	if err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	if len(results) != 1 || results[0].State != StateInconsistent {
+		t.Fatalf("expected inconsistent, got %+v", results)
+	}
+	if len(backend.deleted) != 0 {
+		t.Errorf("expected no deletes without cleanup, got %v", backend.deleted)
+	}
+	if st := j.Status(); len(st.LastRecovery) != 1 {
+		t.Errorf("expected recovery report in status, got %+v", st)
+	}
+}
+
+func TestJournal_RecoverKeepsObjectsWithoutJournalledETag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.log")
+	j, _ := Open(path, false)
+	j.Begin("PutObject", "b", "plaintext", true) // crashed before the body was sent
+	j.Close()
+
+	j, _ = Open(path, false)
+	defer j.Close()
+	backend := &fakeBackend{objects: map[string]map[string]string{"b/plaintext": {"ETag": `"0123456789abcdef0123456789abcdef"`}}}
+	results, err := j.Recover(context.Background(), backend, isEncrypted, true)
+	if err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	if len(results) != 1 || results[0].State != StateInconsistent || results[0].Error == "" {
+		t.Fatalf("expected inconsistent with an explanation, got %+v", results)
+	}
+	if len(backend.deleted) != 0 {
+		t.Errorf("expected no deletes without a journalled ETag, got %v", backend.deleted)
+	}
+}
+
+func TestJournal_BodyRecordsETag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.log")
+	j, _ := Open(path, true)
+	id, _ := j.Begin("PutObject", "b", "k", true)
+	if _, err := io.Copy(io.Discard, j.Body(id, strings.NewReader("hello"))); err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	j.Close()
+
+	j, _ = Open(path, true)
+	defer j.Close()
+	p := j.Pending()
+	if len(p) != 1 || p[0].ETag != "5d41402abc4b2a76b9719d911017c592" {
+		t.Fatalf("expected the body MD5 as ETag after restart, got %+v", p)
+	}
+}
+
+func TestMultipartETag(t *testing.T) {
+	got := MultipartETag([]string{`"5d41402abc4b2a76b9719d911017c592"`, "7d793037a0760186574b0282f2f435e7"})
+	if got != "065947336a2f2a95ba8899f3675c3be6-2" {
+		t.Errorf("MultipartETag = %q", got)
+	}
+	if got := MultipartETag([]string{"not-an-md5"}); got != "" {
+		t.Errorf("MultipartETag of a non-MD5 part = %q, want empty", got)
+	}
+}
+
+func TestJournal_NilIsDisabled(t *testing.T) {
+	var j *Journal
+	id, err := j.Begin("PutObject", "b", "k", true)
+	if err != nil || id != "" {
+		t.Fatalf("nil journal Begin = %q, %v", id, err)
+	}
+	j.End(id, nil)
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJournal_BeginAfterClose(t *testing.T) {
+	j, err := Open(filepath.Join(t.TempDir(), "journal.log"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+	if _, err := j.Begin("PutObject", "b", "k", true); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,271 @@
+// Package keyhold keeps the list of key holds: objects and prefixes whose
+// data keys must survive, typically because they are subject to a legal
+// hold. Crypto-shredding skips held objects, and a wrapping key version that
+// a held object depends on cannot be retired.
+//
+// Holds are stored as one JSON object on the backend. Every change re-reads
+// it and writes it back conditionally, so concurrent gateway instances do
+// not lose each other's changes on backends that honour If-Match; a change
+// that loses the race fails with ErrConflict and can simply be retried.
+//
+// The list starts out missing, which reads as no holds. Once a store has
+// read or written it, the list disappearing fails with ErrListMissing
+// rather than lifting every hold: the gateway refuses client requests for
+// the object, so only direct backend access can remove it.
+package keyhold
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// ErrConflict is returned when the hold list changed while it was being
+// updated.
+var ErrConflict = errors.New("keyhold: hold list changed concurrently, retry")
+
+// ErrListMissing is returned when the hold list was seen before and is now
+// gone. Nothing can be shredded until it is restored.
+var ErrListMissing = errors.New("keyhold: hold list has disappeared")
+
+// Hold protects the data key of one object, or of every object under a
+// prefix, in a bucket.
+type Hold struct {
+	Bucket   string    `json:"bucket"`
+	Key      string    `json:"key,omitempty"`
+	Prefix   string    `json:"prefix,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	PlacedAt time.Time `json:"placed_at"`
+}
+
+// Covers reports whether the hold applies to bucket/key.
+func (h Hold) Covers(bucket, key string) bool {
+	if h.Bucket != bucket {
+		return false
+	}
+	if h.Key != "" {
+		return h.Key == key
+	}
+	return strings.HasPrefix(key, h.Prefix)
+}
+
+func (h Hold) sameTarget(bucket, key, prefix string) bool {
+	return h.Bucket == bucket && h.Key == key && h.Prefix == prefix
+}
+
+// Holds is a snapshot of the hold list.
+type Holds []Hold
+
+// Match returns the first hold covering bucket/key, or nil.
+func (hs Holds) Match(bucket, key string) *Hold {
+	for i := range hs {
+		if hs[i].Covers(bucket, key) {
+			return &hs[i]
+		}
+	}
+	return nil
+}
+
+// Backend is the subset of s3.Client used to read and write the hold list.
+type Backend interface {
+	GetObject(ctx context.Context, bucket, key string, versionID *string, rangeHeader *string) (io.ReadCloser, map[string]string, error)
+	PutObject(ctx context.Context, bucket, key string, reader io.Reader, metadata map[string]string, contentLength *int64, tags string, lock *s3.ObjectLockInput) error
+}
+
+// Store reads and changes the hold list. All methods are safe on a nil
+// *Store, which holds nothing and refuses changes.
+type Store struct {
+	backend Backend
+	bucket  string
+	key     string
+	now     func() time.Time
+	// seen is set once the hold list has been read or written.
+	seen atomic.Bool
+}
+
+// New returns a store kept through backend, or nil when cfg disables key
+// holds.
+func New(backend Backend, cfg config.KeyHoldConfig) *Store {
+	if !cfg.Enabled || backend == nil {
+		return nil
+	}
+	return &Store{
+		backend: backend,
+		bucket:  cfg.Bucket,
+		key:     cfg.Key,
+		now:     time.Now,
+	}
+}
+
+// IsHoldKey reports whether bucket/key is the hold list object and must be
+// hidden from clients.
+func (s *Store) IsHoldKey(bucket, key string) bool {
+	return s != nil && bucket == s.bucket && key == s.key
+}
+
+// List returns every hold, ordered by bucket and target.
+func (s *Store) List(ctx context.Context) (Holds, error) {
+	if s == nil {
+		return nil, nil
+	}
+	holds, _, err := s.load(ctx)
+	return holds, err
+}
+
+// Place adds a hold on bucket/key, or on bucket/prefix when key is empty.
+// Placing a hold that exists updates its reason and keeps its placement
+// time. The stored hold is returned.
+func (s *Store) Place(ctx context.Context, bucket, key, prefix, reason string) (Hold, error) {
+	if s == nil {
+		return Hold{}, errors.New("keyhold: key holds are disabled")
+	}
+	if bucket == "" || (key == "") == (prefix == "") {
+		return Hold{}, errors.New("keyhold: a hold needs a bucket and exactly one of key and prefix")
+	}
+	holds, etag, err := s.load(ctx)
+	if err != nil {
+		return Hold{}, err
+	}
+	for i := range holds {
+		if holds[i].sameTarget(bucket, key, prefix) {
+			holds[i].Reason = reason
+			return holds[i], s.save(ctx, holds, etag)
+		}
+	}
+	h := Hold{Bucket: bucket, Key: key, Prefix: prefix, Reason: reason, PlacedAt: s.now().UTC()}
+	return h, s.save(ctx, append(holds, h), etag)
+}
+
+// Release removes the hold on bucket/key or bucket/prefix. It reports
+// false when there was no such hold.
+func (s *Store) Release(ctx context.Context, bucket, key, prefix string) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+	holds, etag, err := s.load(ctx)
+	if err != nil {
+		return false, err
+	}
+	for i := range holds {
+		if holds[i].sameTarget(bucket, key, prefix) {
+			return true, s.save(ctx, append(holds[:i], holds[i+1:]...), etag)
+		}
+	}
+	return false, nil
+}
+
+// load reads the hold list. A missing object is an empty list, with etag
+// "", until the list has been seen.
+func (s *Store) load(ctx context.Context) (Holds, string, error) {
+	body, meta, err := s.backend.GetObject(ctx, s.bucket, s.key, nil, nil)
+	if err != nil {
+		if errors.Is(err, s3.ErrNotFound) {
+			if s.seen.Load() {
+				return nil, "", fmt.Errorf("%w: %s/%s", ErrListMissing, s.bucket, s.key)
+			}
+			return Holds{}, "", nil
+		}
+		return nil, "", fmt.Errorf("keyhold: read %s/%s: %w", s.bucket, s.key, err)
+	}
+	defer body.Close()
+
+	var holds Holds
+	if err := json.NewDecoder(body).Decode(&holds); err != nil {
+		return nil, "", fmt.Errorf("keyhold: decode %s/%s: %w", s.bucket, s.key, err)
+	}
+	s.seen.Store(true)
+	return holds, meta["ETag"], nil
+}
+
+func (s *Store) save(ctx context.Context, holds Holds, etag string) error {
+	sort.SliceStable(holds, func(i, j int) bool {
+		a, b := holds[i], holds[j]
+		if a.Bucket != b.Bucket {
+			return a.Bucket < b.Bucket
+		}
+		return a.Key+a.Prefix < b.Key+b.Prefix
+	})
+	data, err := json.MarshalIndent(holds, "", "  ")
+	if err != nil {
+		return fmt.Errorf("keyhold: encode: %w", err)
+	}
+	conds := s3.WriteConditions{IfMatch: etag}
+	if etag == "" {
+		conds.IfNoneMatch = "*"
+	}
+	size := int64(len(data))
+	meta := map[string]string{"Content-Type": "application/json"}
+	if err := s.backend.PutObject(s3.WithWriteConditions(ctx, conds), s.bucket, s.key, bytes.NewReader(data), meta, &size, "", nil); err != nil {
+		if isPreconditionFailed(err) {
+			return ErrConflict
+		}
+		return fmt.Errorf("keyhold: write %s/%s: %w", s.bucket, s.key, err)
+	}
+	s.seen.Store(true)
+	return nil
+}
+
+// isPreconditionFailed reports whether a conditional write lost the race.
+func isPreconditionFailed(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code := apiErr.ErrorCode()
+		return code == "PreconditionFailed" || code == "ConditionalRequestConflict"
+	}
+	return false
+}
+
+// HeldObject is one object covered by a hold.
+type HeldObject struct {
+	Key string `json:"key"`
+	// WrappingKey is the KMS key version the object's data key is wrapped
+	// under; nil for data keys derived from the password.
+	WrappingKey *crypto.KeyRef `json:"wrapping_key,omitempty"`
+	Shredded    bool           `json:"shredded,omitempty"`
+	// Error is set when the wrapping key could not be determined.
+	Error string `json:"error,omitempty"`
+}
+
+// HoldReport is a hold with the objects it currently covers.
+type HoldReport struct {
+	Hold
+	Objects []HeldObject `json:"objects"`
+}
+
+// Inventory reports every hold, the objects it covers and the wrapping key
+// versions they depend on.
+type Inventory struct {
+	Holds []HoldReport `json:"holds"`
+	// KeyVersions counts held objects per wrapping key version.
+	KeyVersions map[int]int `json:"key_versions"`
+	// Unresolved counts held objects whose wrapping key could not be
+	// determined. While it is non-zero no version can be retired.
+	Unresolved int `json:"unresolved"`
+}
+
+// Add records o under the last hold in the inventory.
+func (inv *Inventory) Add(o HeldObject) {
+	r := &inv.Holds[len(inv.Holds)-1]
+	r.Objects = append(r.Objects, o)
+	switch {
+	case o.Error != "":
+		inv.Unresolved++
+	case o.WrappingKey != nil && !o.Shredded:
+		if inv.KeyVersions == nil {
+			inv.KeyVersions = make(map[int]int)
+		}
+		inv.KeyVersions[o.WrappingKey.Version]++
+	}
+}
@@ -0,0 +1,146 @@
+package keyhold
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func testConfig() config.KeyHoldConfig {
+	return config.KeyHoldConfig{Enabled: true, Bucket: "state", Key: ".s3eg-keyholds.json"}
+}
+
+func TestStore_PlaceListRelease(t *testing.T) {
+	ctx := context.Background()
+	backend := testsupport.NewMemoryClient()
+	s := New(backend, testConfig())
+	placedAt := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return placedAt }
+
+	if holds, err := s.List(ctx); err != nil || len(holds) != 0 {
+		t.Fatalf("empty store = %v, %v", holds, err)
+	}
+	if _, err := s.Place(ctx, "b", "", "case-17/", "litigation 17"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Place(ctx, "a", "report.pdf", "", "audit"); err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return placedAt.Add(time.Hour) }
+	h, err := s.Place(ctx, "b", "", "case-17/", "litigation 17, amended")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Reason != "litigation 17, amended" || !h.PlacedAt.Equal(placedAt) {
+		t.Errorf("re-placed hold = %+v", h)
+	}
+
+	holds, err := s.List(ctx)
+	if err != nil || len(holds) != 2 || holds[0].Bucket != "a" {
+		t.Fatalf("holds = %+v, %v", holds, err)
+	}
+	if holds.Match("b", "case-17/mail.eml") == nil || holds.Match("b", "case-18/mail.eml") != nil || holds.Match("a", "report.pdf.bak") != nil {
+		t.Error("Match does not follow the key and prefix targets")
+	}
+
+	if ok, err := s.Release(ctx, "b", "", "case-17/"); !ok || err != nil {
+		t.Fatalf("Release = %v, %v", ok, err)
+	}
+	if ok, err := s.Release(ctx, "b", "", "case-17/"); ok || err != nil {
+		t.Errorf("second Release = %v, %v", ok, err)
+	}
+	if holds, _ := s.List(ctx); len(holds) != 1 {
+		t.Errorf("holds after release = %+v", holds)
+	}
+}
+
+func TestStore_RejectsInvalidTargets(t *testing.T) {
+	s := New(testsupport.NewMemoryClient(), testConfig())
+	for _, tc := range [][3]string{{"", "k", ""}, {"b", "", ""}, {"b", "k", "p/"}} {
+		if _, err := s.Place(context.Background(), tc[0], tc[1], tc[2], ""); err == nil {
+			t.Errorf("Place%v succeeded", tc)
+		}
+	}
+}
+
+func TestStore_ConcurrentChangeConflicts(t *testing.T) {
+	ctx := context.Background()
+	backend := testsupport.NewMemoryClient()
+	s := New(backend, testConfig())
+	other := New(backend, testConfig())
+	if _, err := s.Place(ctx, "b", "k1", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	raced := false
+	backend.OnCall = func(op, _, _ string) error {
+		if op == "PutObject" && !raced {
+			raced = true
+			if _, err := other.Place(ctx, "b", "k2", "", ""); err != nil {
+				t.Errorf("concurrent Place: %v", err)
+			}
+		}
+		return nil
+	}
+	if _, err := s.Place(ctx, "b", "k3", "", ""); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Place after a concurrent change = %v, want ErrConflict", err)
+	}
+	backend.OnCall = nil
+	holds, _ := s.List(ctx)
+	if len(holds) != 2 || holds.Match("b", "k2") == nil {
+		t.Errorf("the concurrent change was lost: %+v", holds)
+	}
+}
+
+func TestStore_MissingListFailsClosed(t *testing.T) {
+	ctx := context.Background()
+	backend := testsupport.NewMemoryClient()
+	s := New(backend, testConfig())
+
+	if _, err := s.Place(ctx, "b", "k", "", "legal"); err != nil {
+		t.Fatalf("Place: %v", err)
+	}
+	if err := backend.DeleteObject(ctx, "state", ".s3eg-keyholds.json", nil); err != nil {
+		t.Fatalf("DeleteObject: %v", err)
+	}
+	if _, err := s.List(ctx); !errors.Is(err, ErrListMissing) {
+		t.Errorf("List after the list vanished = %v, want ErrListMissing", err)
+	}
+	if _, err := s.Place(ctx, "b", "k2", "", ""); !errors.Is(err, ErrListMissing) {
+		t.Errorf("Place after the list vanished = %v, want ErrListMissing", err)
+	}
+}
+
+func TestStore_NilIsDisabled(t *testing.T) {
+	var s *Store
+	if New(testsupport.NewMemoryClient(), config.KeyHoldConfig{}) != nil {
+		t.Error("New returned a store for a disabled config")
+	}
+	if holds, err := s.List(context.Background()); holds != nil || err != nil {
+		t.Errorf("nil List = %v, %v", holds, err)
+	}
+	if _, err := s.Place(context.Background(), "b", "k", "", ""); err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("nil Place = %v", err)
+	}
+	if s.IsHoldKey("state", ".s3eg-keyholds.json") {
+		t.Error("nil store reserves a key")
+	}
+}
+
+func TestInventory_Add(t *testing.T) {
+	inv := Inventory{Holds: []HoldReport{{Hold: Hold{Bucket: "b", Prefix: "p/"}}}}
+	inv.Add(HeldObject{Key: "p/a", WrappingKey: &crypto.KeyRef{Version: 1}})
+	inv.Add(HeldObject{Key: "p/b", WrappingKey: &crypto.KeyRef{Version: 1}})
+	inv.Add(HeldObject{Key: "p/c", WrappingKey: &crypto.KeyRef{Version: 2}, Shredded: true})
+	inv.Add(HeldObject{Key: "p/d"})
+	inv.Add(HeldObject{Key: "p/e", Error: "access denied"})
+	if len(inv.Holds[0].Objects) != 5 || inv.KeyVersions[1] != 2 || inv.KeyVersions[2] != 0 || inv.Unresolved != 1 {
+		t.Errorf("inventory = %+v", inv)
+	}
+}
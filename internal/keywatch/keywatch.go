@@ -0,0 +1,191 @@
+// Package keywatch follows the active key version of the key manager, so a
+// rotation done in the KMS is picked up without restarting the gateway.
+package keywatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/audit"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+)
+
+// Sources of a version change, used as the "source" metric label.
+const (
+	// SourceKMS means the watch switched wrapping to the version the KMS
+	// reports as active.
+	SourceKMS = "kms"
+	// SourceObserved means the version changed by other means, such as a
+	// rotation committed through the admin API.
+	SourceObserved = "observed"
+)
+
+// Recorder is the subset of metrics.Metrics used by the watch.
+type Recorder interface {
+	SetActiveKeyVersion(provider string, version int)
+	RecordKeyVersionChange(provider, source string)
+}
+
+// Watcher checks the active key version on a schedule. When the key
+// manager implements crypto.KeyVersionSource and crypto.RotatableKeyManager
+// it promotes the version the KMS reports; otherwise it only reports
+// changes. Promotion is skipped while an admin rotation is in progress.
+type Watcher struct {
+	km       crypto.KeyManager
+	rotation *crypto.RotationState
+	interval time.Duration
+	recorder Recorder
+	audit    audit.Logger
+	logger   *logrus.Logger
+
+	mu   sync.Mutex
+	last int // last version seen; 0 before the first check
+}
+
+// New returns a Watcher. rotation, recorder and auditLogger may be nil.
+func New(cfg config.KeyVersionWatchConfig, km crypto.KeyManager, rotation *crypto.RotationState, recorder Recorder, auditLogger audit.Logger, logger *logrus.Logger) *Watcher {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = config.DefaultKeyVersionWatchInterval
+	}
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	return &Watcher{
+		km:       km,
+		rotation: rotation,
+		interval: interval,
+		recorder: recorder,
+		audit:    auditLogger,
+		logger:   logger,
+	}
+}
+
+// Check compares the active key version with the KMS and with the previous
+// check, switching and reporting as needed.
+func (w *Watcher) Check(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	provider := w.km.Provider()
+	current, err := w.km.ActiveKeyVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("keywatch: active key version: %w", err)
+	}
+
+	previous, source := w.last, SourceObserved
+	want, err := w.fetch(ctx)
+	switch {
+	case errors.Is(err, crypto.ErrRotationNotSupported):
+		// Only changes made through the gateway can be seen.
+	case err != nil:
+		w.logger.WithError(err).WithField("provider", provider).Warn("Failed to read the active key version from the KMS")
+	case want == current:
+	case w.rotation != nil && w.rotation.Phase() != crypto.RotationIdle:
+		// The admin rotation decides; look again once it has finished.
+		w.logger.WithFields(logrus.Fields{
+			"provider": provider,
+			"version":  want,
+			"phase":    w.rotation.Phase().String(),
+		}).Info("KMS reports a new active key version; waiting for the rotation in progress")
+	default:
+		if err := w.promote(ctx, current, want); err != nil {
+			w.logAudit(provider, current, want, SourceKMS, err)
+			return err
+		}
+		previous, current, source = current, want, SourceKMS
+	}
+
+	if w.recorder != nil {
+		w.recorder.SetActiveKeyVersion(provider, current)
+	}
+	w.last = current
+	if previous == 0 || previous == current {
+		return nil
+	}
+	if w.recorder != nil {
+		w.recorder.RecordKeyVersionChange(provider, source)
+	}
+	w.logAudit(provider, previous, current, source, nil)
+	w.logger.WithFields(logrus.Fields{
+		"provider":         provider,
+		"previous_version": previous,
+		"version":          current,
+		"source":           source,
+	}).Info("Active key version changed")
+	return nil
+}
+
+// fetch asks the KMS for the active version. It returns
+// crypto.ErrRotationNotSupported when the key manager cannot tell.
+func (w *Watcher) fetch(ctx context.Context) (int, error) {
+	src, ok := crypto.KeyManagerAs[crypto.KeyVersionSource](w.km)
+	if !ok {
+		return 0, crypto.ErrRotationNotSupported
+	}
+	return src.FetchActiveKeyVersion(ctx)
+}
+
+// promote switches wrapping from current to target.
+func (w *Watcher) promote(ctx context.Context, current, target int) error {
+	rkm, ok := crypto.KeyManagerAs[crypto.RotatableKeyManager](w.km)
+	if !ok {
+		return fmt.Errorf("keywatch: %w", crypto.ErrRotationNotSupported)
+	}
+	plan, err := rkm.PrepareRotation(ctx, &target)
+	if err != nil {
+		return fmt.Errorf("keywatch: prepare switch from version %d to %d: %w", current, target, err)
+	}
+	if err := rkm.PromoteActiveVersion(ctx, plan); err != nil {
+		return fmt.Errorf("keywatch: switch from version %d to %d: %w", current, target, err)
+	}
+	return nil
+}
+
+func (w *Watcher) logAudit(provider string, previous, version int, source string, err error) {
+	if w.audit == nil {
+		return
+	}
+	event := &audit.AuditEvent{
+		Timestamp:  time.Now(),
+		EventType:  audit.EventTypeKeyRotation,
+		Operation:  "key_version_change",
+		KeyVersion: version,
+		Success:    err == nil,
+		Metadata: map[string]interface{}{
+			"provider":         provider,
+			"previous_version": previous,
+			"source":           source,
+		},
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	if logErr := w.audit.Log(event); logErr != nil {
+		w.logger.WithError(logErr).Warn("Failed to write key version audit event")
+	}
+}
+
+// Start checks immediately and then every interval until ctx is done.
+func (w *Watcher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			if err := w.Check(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				w.logger.WithError(err).Warn("Key version check failed")
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,153 @@
+package keywatch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/audit"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+)
+
+// kmsVersion adds a KMS-reported active version to a key manager.
+type kmsVersion struct {
+	crypto.KeyManager
+	mu      sync.Mutex
+	version int
+	err     error
+}
+
+func (k *kmsVersion) Unwrap() crypto.KeyManager { return k.KeyManager }
+
+func (k *kmsVersion) FetchActiveKeyVersion(context.Context) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.version, k.err
+}
+
+func (k *kmsVersion) set(version int, err error) {
+	k.mu.Lock()
+	k.version, k.err = version, err
+	k.mu.Unlock()
+}
+
+type recorder struct {
+	active  int
+	changes []string
+}
+
+func (r *recorder) SetActiveKeyVersion(_ string, version int) { r.active = version }
+func (r *recorder) RecordKeyVersionChange(_, source string)   { r.changes = append(r.changes, source) }
+
+type discardWriter struct{}
+
+func (discardWriter) WriteEvent(*audit.AuditEvent) error { return nil }
+
+func newMemoryKM(t *testing.T, versions ...int) crypto.KeyManager {
+	t.Helper()
+	km := crypto.NewInMemoryKeyManagerForTestDefault()
+	for _, v := range versions {
+		material := make([]byte, 32)
+		for i := range material {
+			material[i] = byte(v + i + 1)
+		}
+		if err := crypto.AddVersionForTest(km, v, material); err != nil {
+			t.Fatalf("AddVersionForTest(%d): %v", v, err)
+		}
+	}
+	return km
+}
+
+func quietLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	return logger
+}
+
+func TestWatcher_SwitchesToKMSVersion(t *testing.T) {
+	ctx := context.Background()
+	inner := newMemoryKM(t, 2)
+	km := &kmsVersion{KeyManager: inner, version: 1}
+	rec := &recorder{}
+	auditLogger := audit.NewLogger(10, discardWriter{})
+	w := New(config.KeyVersionWatchConfig{}, crypto.NewDeadlineKeyManager(km, crypto.CallPolicy{MaxAttempts: 1}), nil, rec, auditLogger, quietLogger())
+
+	if err := w.Check(ctx); err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	if rec.active != 1 || len(rec.changes) != 0 || len(auditLogger.GetEvents()) != 0 {
+		t.Fatalf("first check: active %d, changes %v, %d audit events", rec.active, rec.changes, len(auditLogger.GetEvents()))
+	}
+
+	km.set(2, nil)
+	if err := w.Check(ctx); err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	if v, _ := inner.ActiveKeyVersion(ctx); v != 2 {
+		t.Errorf("wrapping version = %d, want 2", v)
+	}
+	if rec.active != 2 || fmt.Sprint(rec.changes) != "[kms]" {
+		t.Errorf("active %d, changes %v; want 2, [kms]", rec.active, rec.changes)
+	}
+	events := auditLogger.GetEvents()
+	if len(events) != 1 || events[0].KeyVersion != 2 || events[0].Metadata["previous_version"] != 1 || !events[0].Success {
+		t.Fatalf("audit events = %+v, want one successful change 1 -> 2", events)
+	}
+
+	// A KMS that cannot be read leaves wrapping alone.
+	km.set(0, crypto.ErrKMSUnavailable)
+	if err := w.Check(ctx); err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	if v, _ := inner.ActiveKeyVersion(ctx); v != 2 || len(rec.changes) != 1 {
+		t.Errorf("version %d, changes %v after a failed KMS read", v, rec.changes)
+	}
+}
+
+func TestWatcher_WaitsForAdminRotation(t *testing.T) {
+	ctx := context.Background()
+	inner := newMemoryKM(t, 2)
+	km := &kmsVersion{KeyManager: inner, version: 2}
+	rotation := crypto.NewRotationState()
+	plan := crypto.RotationPlan{CurrentVersion: 1, TargetVersion: 2}
+	if err := rotation.StartDrain("r1", 1, 2, "memory", &plan, 0); err != nil {
+		t.Fatalf("StartDrain() error: %v", err)
+	}
+	w := New(config.KeyVersionWatchConfig{}, km, rotation, nil, nil, quietLogger())
+
+	if err := w.Check(ctx); err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	if v, _ := inner.ActiveKeyVersion(ctx); v != 1 {
+		t.Errorf("wrapping version = %d during a rotation, want 1", v)
+	}
+}
+
+func TestWatcher_ReportsObservedChanges(t *testing.T) {
+	ctx := context.Background()
+	km := newMemoryKM(t, 2)
+	rec := &recorder{}
+	w := New(config.KeyVersionWatchConfig{}, km, nil, rec, nil, quietLogger())
+
+	if err := w.Check(ctx); err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	rkm := km.(crypto.RotatableKeyManager)
+	plan, err := rkm.PrepareRotation(ctx, nil)
+	if err != nil {
+		t.Fatalf("PrepareRotation() error: %v", err)
+	}
+	if err := rkm.PromoteActiveVersion(ctx, plan); err != nil {
+		t.Fatalf("PromoteActiveVersion() error: %v", err)
+	}
+	if err := w.Check(ctx); err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	if rec.active != 2 || fmt.Sprint(rec.changes) != "[observed]" {
+		t.Errorf("active %d, changes %v; want 2, [observed]", rec.active, rec.changes)
+	}
+}
@@ -0,0 +1,238 @@
+// Package logsample keeps verbose logging usable at gateway request rates:
+// it thins high-volume log streams and lets operators raise the log level
+// at runtime for a limited time.
+//
+// Messages are grouped by logger: the HTTP access log, the value of an
+// entry's "component" field, or the default logger for everything else.
+// A logger with a rule keeps one in every N of its messages at the rule's
+// level or below; more severe messages are always written. Kept messages
+// carry a sample_rate field so counts can be scaled back up.
+package logsample
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Logger names.
+const (
+	AccessLogger  = "access"
+	DefaultLogger = "default"
+)
+
+// AccessMessage is the message of every access log entry.
+const AccessMessage = "HTTP request"
+
+// RateField is added to sampled messages that were kept.
+const RateField = "sample_rate"
+
+type rule struct {
+	every uint64
+	level logrus.Level
+	seen  atomic.Uint64
+}
+
+// Sampler decides which messages of each logger are kept.
+type Sampler struct {
+	mu    sync.RWMutex
+	cfg   map[string]config.LogSamplingRule
+	rules map[string]*rule
+}
+
+// New returns a sampler applying rules, which must have been validated.
+func New(rules map[string]config.LogSamplingRule) *Sampler {
+	s := &Sampler{}
+	s.SetRules(rules)
+	return s
+}
+
+// SetRules replaces the rules, restarting every logger's count.
+func (s *Sampler) SetRules(rules map[string]config.LogSamplingRule) {
+	compiled := make(map[string]*rule, len(rules))
+	copied := make(map[string]config.LogSamplingRule, len(rules))
+	for name, r := range rules {
+		level := logrus.InfoLevel
+		if r.Level != "" {
+			if l, err := logrus.ParseLevel(r.Level); err == nil {
+				level = l
+			}
+		}
+		every := uint64(1)
+		if r.Every > 1 {
+			every = uint64(r.Every)
+		}
+		compiled[name] = &rule{every: every, level: level}
+		copied[name] = r
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = compiled
+	s.cfg = copied
+}
+
+// Rules returns the current rules.
+func (s *Sampler) Rules() map[string]config.LogSamplingRule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[string]config.LogSamplingRule, len(s.cfg))
+	for name, r := range s.cfg {
+		out[name] = r
+	}
+	return out
+}
+
+// Sample reports whether a message of logger at level is kept, and the
+// rate it was sampled at (1 when every message is kept).
+func (s *Sampler) Sample(logger string, level logrus.Level) (bool, int) {
+	if s == nil {
+		return true, 1
+	}
+	s.mu.RLock()
+	r, ok := s.rules[logger]
+	s.mu.RUnlock()
+	// logrus levels grow less severe as they increase.
+	if !ok || r.every == 1 || level < r.level {
+		return true, 1
+	}
+	n := r.seen.Add(1) - 1
+	return n%r.every == 0, int(r.every)
+}
+
+// Formatter samples the entries it formats before passing them to Next.
+// Access log entries are passed through untouched: the access log
+// middleware samples them itself, since only it knows whether the request
+// failed.
+type Formatter struct {
+	Next    logrus.Formatter
+	Sampler *Sampler
+}
+
+// Format implements logrus.Formatter. A dropped entry formats to nothing.
+func (f *Formatter) Format(e *logrus.Entry) ([]byte, error) {
+	if e.Message != AccessMessage {
+		name := DefaultLogger
+		if c, ok := e.Data["component"].(string); ok && c != "" {
+			name = c
+		}
+		keep, rate := f.Sampler.Sample(name, e.Level)
+		if !keep {
+			return nil, nil
+		}
+		if rate > 1 {
+			// logrus hands formatters a copy of the entry's fields.
+			e.Data[RateField] = rate
+		}
+	}
+	return f.Next.Format(e)
+}
+
+// LevelState describes the logger's level.
+type LevelState struct {
+	Level      string `json:"level"`
+	Configured string `json:"configured_level"`
+	// RevertAt is when a temporary override returns to the configured
+	// level.
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+}
+
+// LevelControl lets an operator override the configured log level at
+// runtime, optionally for a limited time.
+type LevelControl struct {
+	logger   *logrus.Logger
+	onChange func(logrus.Level)
+
+	mu         sync.Mutex
+	configured logrus.Level
+	overridden bool
+	revertAt   time.Time
+	timer      *time.Timer
+}
+
+// NewLevelControl starts from logger's current level as the configured
+// one. onChange, when set, is called after every change of level.
+func NewLevelControl(logger *logrus.Logger, onChange func(logrus.Level)) *LevelControl {
+	return &LevelControl{logger: logger, onChange: onChange, configured: logger.GetLevel()}
+}
+
+// State returns the current and configured levels.
+func (c *LevelControl) State() LevelState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stateLocked()
+}
+
+// Override sets the level until Reset, or for d when d > 0.
+func (c *LevelControl) Override(level logrus.Level, d time.Duration) LevelState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopTimerLocked()
+	c.overridden = true
+	if d > 0 {
+		c.revertAt = time.Now().Add(d)
+		var timer *time.Timer
+		timer = time.AfterFunc(d, func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			// A later override replaced this timer.
+			if c.timer == timer {
+				c.resetLocked()
+			}
+		})
+		c.timer = timer
+	}
+	c.setLocked(level)
+	return c.stateLocked()
+}
+
+// Reset drops an override and returns to the configured level.
+func (c *LevelControl) Reset() LevelState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resetLocked()
+	return c.stateLocked()
+}
+
+// SetConfigured records a new configured level, as on a configuration
+// reload. It applies at once unless an override is in force.
+func (c *LevelControl) SetConfigured(level logrus.Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.configured = level
+	if !c.overridden {
+		c.setLocked(level)
+	}
+}
+
+func (c *LevelControl) resetLocked() {
+	c.stopTimerLocked()
+	c.overridden = false
+	c.setLocked(c.configured)
+}
+
+func (c *LevelControl) stopTimerLocked() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.revertAt = time.Time{}
+}
+
+func (c *LevelControl) setLocked(level logrus.Level) {
+	c.logger.SetLevel(level)
+	if c.onChange != nil {
+		c.onChange(level)
+	}
+}
+
+func (c *LevelControl) stateLocked() LevelState {
+	st := LevelState{Level: c.logger.GetLevel().String(), Configured: c.configured.String()}
+	if !c.revertAt.IsZero() {
+		at := c.revertAt
+		st.RevertAt = &at
+	}
+	return st
+}
@@ -0,0 +1,145 @@
+package logsample
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+func TestSampler_KeepsOneInN(t *testing.T) {
+	s := New(map[string]config.LogSamplingRule{"access": {Every: 3}})
+	kept := 0
+	for i := 0; i < 9; i++ {
+		keep, rate := s.Sample(AccessLogger, logrus.InfoLevel)
+		if rate != 3 {
+			t.Fatalf("rate = %d", rate)
+		}
+		if keep {
+			kept++
+		}
+	}
+	if kept != 3 {
+		t.Errorf("kept %d of 9", kept)
+	}
+	// Warnings are more severe than the rule's info level.
+	if keep, rate := s.Sample(AccessLogger, logrus.WarnLevel); !keep || rate != 1 {
+		t.Errorf("warning sampled: %v %d", keep, rate)
+	}
+	// Debug is less severe, so it is sampled too.
+	if _, rate := s.Sample(AccessLogger, logrus.DebugLevel); rate != 3 {
+		t.Errorf("debug rate = %d", rate)
+	}
+	if keep, _ := s.Sample("other", logrus.DebugLevel); !keep {
+		t.Error("logger without a rule sampled")
+	}
+	var none *Sampler
+	if keep, rate := none.Sample(AccessLogger, logrus.InfoLevel); !keep || rate != 1 {
+		t.Error("nil sampler dropped a message")
+	}
+
+	s.SetRules(nil)
+	if keep, _ := s.Sample(AccessLogger, logrus.InfoLevel); !keep {
+		t.Error("cleared rules still sample")
+	}
+	if len(s.Rules()) != 0 {
+		t.Errorf("Rules = %v", s.Rules())
+	}
+}
+
+func TestFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetLevel(logrus.DebugLevel)
+	logger.SetFormatter(&Formatter{
+		Next: &logrus.JSONFormatter{},
+		Sampler: New(map[string]config.LogSamplingRule{
+			"cache":       {Every: 2, Level: "debug"},
+			DefaultLogger: {Every: 1000},
+		}),
+	})
+
+	cache := logger.WithField("component", "cache")
+	for i := 0; i < 4; i++ {
+		cache.Debug("lookup")
+	}
+	cache.Info("evicted")
+	logger.Info(AccessMessage)
+	logger.Info("started")
+	logger.Info("dropped")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var msgs []string
+	for _, line := range lines {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		msgs = append(msgs, m["msg"].(string))
+		if m["msg"] == "lookup" && m[RateField] != float64(2) {
+			t.Errorf("lookup without %s: %v", RateField, m)
+		}
+		if m["msg"] == "evicted" || m["msg"] == AccessMessage {
+			if _, ok := m[RateField]; ok {
+				t.Errorf("unsampled message carries %s: %v", RateField, m)
+			}
+		}
+	}
+	want := "lookup,lookup,evicted," + AccessMessage + ",started"
+	if got := strings.Join(msgs, ","); got != want {
+		t.Errorf("messages = %s, want %s", got, want)
+	}
+}
+
+func TestLevelControl(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.InfoLevel)
+	var changes []logrus.Level
+	c := NewLevelControl(logger, func(l logrus.Level) { changes = append(changes, l) })
+
+	st := c.Override(logrus.DebugLevel, 0)
+	if st.Level != "debug" || st.Configured != "info" || st.RevertAt != nil {
+		t.Errorf("override state = %+v", st)
+	}
+	// A reload changes the configured level without ending the override.
+	c.SetConfigured(logrus.WarnLevel)
+	if logger.GetLevel() != logrus.DebugLevel {
+		t.Errorf("reload replaced the override: %s", logger.GetLevel())
+	}
+	if st := c.Reset(); st.Level != "warning" {
+		t.Errorf("reset state = %+v", st)
+	}
+
+	st = c.Override(logrus.TraceLevel, 20*time.Millisecond)
+	if st.RevertAt == nil {
+		t.Fatal("timed override has no revert time")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for c.State().Level != "warning" {
+		if time.Now().After(deadline) {
+			t.Fatal("timed override never reverted")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if st := c.State(); st.RevertAt != nil {
+		t.Errorf("reverted state = %+v", st)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	want := []logrus.Level{logrus.DebugLevel, logrus.WarnLevel, logrus.TraceLevel, logrus.WarnLevel}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Fatalf("changes = %v, want %v", changes, want)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+// Package membudget bounds the memory the gateway commits to buffered work.
+//
+// A Budget holds a fixed number of bytes. The object cache, multipart part
+// buffers and in-flight request pipelines reserve from it before they
+// allocate and release the reservation when they are done, so their
+// combined footprint stays under the limit however many requests arrive at
+// once. When the budget is exhausted new work waits, first come first
+// served, for up to a configured time and is then refused.
+package membudget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/metrics"
+	"sync"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+// Subsystems that draw from the budget.
+const (
+	SubsystemCache      = "cache"
+	SubsystemRequest    = "request"
+	SubsystemUploadPart = "upload_part"
+)
+
+// Errors returned by Acquire.
+var (
+	ErrExhausted = errors.New("membudget: memory budget exhausted")
+	ErrTooLarge  = errors.New("membudget: reservation exceeds the memory budget")
+)
+
+// Recorder receives the budget's gauges.
+type Recorder interface {
+	SetMemoryBudgetLimit(bytes int64)
+	SetMemoryBudgetReserved(subsystem string, bytes int64)
+	SetMemoryBudgetUsed(bytes int64)
+	SetMemoryBudgetWaiting(n int)
+	RecordMemoryBudgetRefusal(subsystem string)
+}
+
+// Stats is a snapshot of the budget.
+type Stats struct {
+	Limit    int64            `json:"limit_bytes"`
+	Reserved int64            `json:"reserved_bytes"`
+	BySystem map[string]int64 `json:"reserved_by_subsystem"`
+	// Used is the Go heap in use by live objects, for comparison with
+	// Reserved.
+	Used    int64 `json:"used_bytes"`
+	Waiting int   `json:"waiting"`
+}
+
+// usedSampleInterval limits how often the heap is sampled for the used
+// gauge.
+const usedSampleInterval = time.Second
+
+type waiter struct {
+	subsystem string
+	n         int64
+	ready     chan struct{}
+	granted   bool
+}
+
+// Budget is a shared pool of reservable bytes. A nil *Budget is unlimited:
+// every reservation succeeds immediately.
+type Budget struct {
+	limit    int64
+	maxWait  time.Duration
+	recorder Recorder
+	now      func() time.Time
+
+	mu          sync.Mutex
+	reserved    int64
+	bySystem    map[string]int64
+	queue       []*waiter
+	lastSampled time.Time
+}
+
+// New returns the budget described by cfg, or nil when it is disabled.
+// recorder may be nil.
+func New(cfg config.MemoryBudgetConfig, recorder Recorder) *Budget {
+	if !cfg.Enabled {
+		return nil
+	}
+	b := &Budget{
+		limit:    cfg.Limit,
+		maxWait:  cfg.MaxWait,
+		recorder: recorder,
+		now:      time.Now,
+		bySystem: make(map[string]int64),
+	}
+	if recorder != nil {
+		recorder.SetMemoryBudgetLimit(b.limit)
+		recorder.SetMemoryBudgetUsed(heapInUse())
+	}
+	return b
+}
+
+// Limit returns the size of the budget, or 0 for a nil budget.
+func (b *Budget) Limit() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.limit
+}
+
+// Acquire reserves n bytes for subsystem. When they are not available it
+// queues behind earlier callers until enough is released, ctx ends or the
+// configured wait elapses, and then returns ErrExhausted (or ctx's error).
+// A reservation larger than the whole budget fails at once with
+// ErrTooLarge. Every successful Acquire must be paired with a Release of
+// the same size.
+func (b *Budget) Acquire(ctx context.Context, subsystem string, n int64) error {
+	if b == nil || n <= 0 {
+		return nil
+	}
+	if n > b.limit {
+		b.refuse(subsystem)
+		return fmt.Errorf("%w: %d bytes requested, budget is %d", ErrTooLarge, n, b.limit)
+	}
+
+	b.mu.Lock()
+	if len(b.queue) == 0 && b.reserved+n <= b.limit {
+		b.reserveLocked(subsystem, n)
+		b.mu.Unlock()
+		return nil
+	}
+	if b.maxWait <= 0 {
+		b.mu.Unlock()
+		b.refuse(subsystem)
+		return ErrExhausted
+	}
+	w := &waiter{subsystem: subsystem, n: n, ready: make(chan struct{})}
+	b.queue = append(b.queue, w)
+	b.recordWaitingLocked()
+	b.mu.Unlock()
+
+	timer := time.NewTimer(b.maxWait)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-w.ready:
+		return nil
+	case <-timer.C:
+		err = ErrExhausted
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if w.granted {
+		// Granted while giving up; keep the reservation rather than
+		// discarding bytes the caller now owns.
+		return nil
+	}
+	for i, q := range b.queue {
+		if q == w {
+			b.queue = append(b.queue[:i], b.queue[i+1:]...)
+			break
+		}
+	}
+	// The head of the queue may have been blocking smaller requests behind
+	// it.
+	b.grantLocked()
+	b.recordWaitingLocked()
+	if errors.Is(err, ErrExhausted) {
+		b.refuse(subsystem)
+	}
+	return err
+}
+
+// TryAcquire reserves n bytes for subsystem only if they are available now
+// and nobody is waiting, for work that is better skipped than delayed.
+func (b *Budget) TryAcquire(subsystem string, n int64) bool {
+	if b == nil || n <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.queue) > 0 || b.reserved+n > b.limit {
+		b.refuse(subsystem)
+		return false
+	}
+	b.reserveLocked(subsystem, n)
+	return true
+}
+
+// Release returns n bytes reserved for subsystem and wakes the waiters they
+// satisfy.
+func (b *Budget) Release(subsystem string, n int64) {
+	if b == nil || n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reserved -= n
+	b.bySystem[subsystem] -= n
+	b.recordReservedLocked(subsystem)
+	b.grantLocked()
+	b.recordWaitingLocked()
+}
+
+// Stats returns a snapshot of the budget.
+func (b *Budget) Stats() Stats {
+	if b == nil {
+		return Stats{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := Stats{
+		Limit:    b.limit,
+		Reserved: b.reserved,
+		BySystem: make(map[string]int64, len(b.bySystem)),
+		Used:     heapInUse(),
+		Waiting:  len(b.queue),
+	}
+	for k, v := range b.bySystem {
+		st.BySystem[k] = v
+	}
+	return st
+}
+
+// grantLocked hands released bytes to waiters in arrival order, stopping at
+// the first that does not fit so large reservations are not starved.
+func (b *Budget) grantLocked() {
+	for len(b.queue) > 0 {
+		w := b.queue[0]
+		if b.reserved+w.n > b.limit {
+			return
+		}
+		b.queue = b.queue[1:]
+		b.reserveLocked(w.subsystem, w.n)
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+func (b *Budget) reserveLocked(subsystem string, n int64) {
+	b.reserved += n
+	b.bySystem[subsystem] += n
+	b.recordReservedLocked(subsystem)
+}
+
+func (b *Budget) recordReservedLocked(subsystem string) {
+	if b.recorder == nil {
+		return
+	}
+	b.recorder.SetMemoryBudgetReserved(subsystem, b.bySystem[subsystem])
+	if now := b.now(); now.Sub(b.lastSampled) >= usedSampleInterval {
+		b.lastSampled = now
+		b.recorder.SetMemoryBudgetUsed(heapInUse())
+	}
+}
+
+func (b *Budget) recordWaitingLocked() {
+	if b.recorder != nil {
+		b.recorder.SetMemoryBudgetWaiting(len(b.queue))
+	}
+}
+
+func (b *Budget) refuse(subsystem string) {
+	if b.recorder != nil {
+		b.recorder.RecordMemoryBudgetRefusal(subsystem)
+	}
+}
+
+// heapInUse reads the bytes held by live and not yet swept heap objects
+// without stopping the world, unlike runtime.ReadMemStats.
+func heapInUse() int64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return int64(sample[0].Value.Uint64())
+}
@@ -0,0 +1,174 @@
+package membudget
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+type fakeRecorder struct {
+	mu       sync.Mutex
+	limit    int64
+	reserved map[string]int64
+	waiting  int
+	refusals map[string]int
+}
+
+func newFakeRecorder() *fakeRecorder {
+	return &fakeRecorder{reserved: map[string]int64{}, refusals: map[string]int{}}
+}
+
+func (r *fakeRecorder) SetMemoryBudgetLimit(bytes int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.limit = bytes
+}
+
+func (r *fakeRecorder) SetMemoryBudgetReserved(subsystem string, bytes int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reserved[subsystem] = bytes
+}
+
+func (r *fakeRecorder) SetMemoryBudgetUsed(int64) {}
+
+func (r *fakeRecorder) SetMemoryBudgetWaiting(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.waiting = n
+}
+
+func (r *fakeRecorder) RecordMemoryBudgetRefusal(subsystem string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.refusals[subsystem]++
+}
+
+func (r *fakeRecorder) waitingNow() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.waiting
+}
+
+func TestBudget_AcquireRelease(t *testing.T) {
+	rec := newFakeRecorder()
+	b := New(config.MemoryBudgetConfig{Enabled: true, Limit: 100}, rec)
+	ctx := context.Background()
+
+	if err := b.Acquire(ctx, SubsystemRequest, 60); err != nil {
+		t.Fatal(err)
+	}
+	if !b.TryAcquire(SubsystemCache, 40) {
+		t.Fatal("TryAcquire refused bytes that fit")
+	}
+	if b.TryAcquire(SubsystemCache, 1) {
+		t.Fatal("TryAcquire went over the limit")
+	}
+	// MaxWait is 0, so a reservation that does not fit is refused at once.
+	if err := b.Acquire(ctx, SubsystemUploadPart, 10); !errors.Is(err, ErrExhausted) {
+		t.Fatalf("Acquire over the limit = %v", err)
+	}
+	if err := b.Acquire(ctx, SubsystemUploadPart, 101); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Acquire beyond the budget = %v", err)
+	}
+
+	st := b.Stats()
+	if st.Limit != 100 || st.Reserved != 100 || st.BySystem[SubsystemRequest] != 60 || st.BySystem[SubsystemCache] != 40 {
+		t.Errorf("Stats = %+v", st)
+	}
+	b.Release(SubsystemRequest, 60)
+	b.Release(SubsystemCache, 40)
+	if st := b.Stats(); st.Reserved != 0 {
+		t.Errorf("reserved after release = %d", st.Reserved)
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.limit != 100 || rec.reserved[SubsystemRequest] != 0 || rec.reserved[SubsystemCache] != 0 {
+		t.Errorf("recorded limit %d, reserved %v", rec.limit, rec.reserved)
+	}
+	if rec.refusals[SubsystemCache] != 1 || rec.refusals[SubsystemUploadPart] != 2 {
+		t.Errorf("recorded refusals %v", rec.refusals)
+	}
+}
+
+func TestBudget_QueuesInOrder(t *testing.T) {
+	rec := newFakeRecorder()
+	b := New(config.MemoryBudgetConfig{Enabled: true, Limit: 100, MaxWait: 5 * time.Second}, rec)
+	ctx := context.Background()
+	if err := b.Acquire(ctx, SubsystemRequest, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i, n := range []int64{80, 30} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := b.Acquire(ctx, SubsystemUploadPart, n); err != nil {
+				t.Error(err)
+			}
+		}()
+		// Queue the waiters one after the other.
+		deadline := time.Now().Add(5 * time.Second)
+		for rec.waitingNow() != i+1 {
+			if time.Now().After(deadline) {
+				t.Fatal("waiter never queued")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	// A newcomer that would fit is not let past the queue.
+	if b.TryAcquire(SubsystemCache, 1) {
+		t.Error("TryAcquire jumped the queue")
+	}
+
+	// 50 bytes would fit the second waiter but not the first, which keeps
+	// its place.
+	b.Release(SubsystemRequest, 50)
+	if st := b.Stats(); st.BySystem[SubsystemUploadPart] != 0 || st.Waiting != 2 {
+		t.Errorf("after a partial release: %+v", st)
+	}
+	b.Release(SubsystemRequest, 50)
+	if st := b.Stats(); st.BySystem[SubsystemUploadPart] != 80 || st.Waiting != 1 {
+		t.Errorf("after a full release: %+v", st)
+	}
+	// Handing back the first waiter's bytes lets the second in.
+	b.Release(SubsystemUploadPart, 80)
+	wg.Wait()
+	if st := b.Stats(); st.Reserved != 30 || st.Waiting != 0 {
+		t.Errorf("Stats = %+v", st)
+	}
+}
+
+func TestBudget_WaitExpires(t *testing.T) {
+	b := New(config.MemoryBudgetConfig{Enabled: true, Limit: 10, MaxWait: 20 * time.Millisecond}, nil)
+	ctx := context.Background()
+	if err := b.Acquire(ctx, SubsystemRequest, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Acquire(ctx, SubsystemRequest, 5); !errors.Is(err, ErrExhausted) {
+		t.Fatalf("Acquire = %v, want ErrExhausted", err)
+	}
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := b.Acquire(cctx, SubsystemRequest, 5); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Acquire with cancelled context = %v", err)
+	}
+	if st := b.Stats(); st.Reserved != 10 || st.Waiting != 0 {
+		t.Errorf("Stats = %+v", st)
+	}
+
+	var none *Budget
+	if err := none.Acquire(ctx, SubsystemRequest, 1<<40); err != nil || !none.TryAcquire(SubsystemCache, 1<<40) {
+		t.Error("nil budget is not unlimited")
+	}
+	none.Release(SubsystemCache, 1<<40)
+	if New(config.MemoryBudgetConfig{Limit: 10}, nil) != nil {
+		t.Error("disabled budget is not nil")
+	}
+}
@@ -3,7 +3,9 @@ package metrics
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -13,11 +15,49 @@ type HealthStatus struct {
 	Timestamp time.Time         `json:"timestamp"`
 	Version   string            `json:"version"`
 	Checks    map[string]string `json:"checks,omitempty"`
+	// Formats lists the on-disk format versions this binary reads and
+	// writes, so mixed-version fleets can be compared during an upgrade.
+	Formats map[string]FormatVersion `json:"formats,omitempty"`
+}
+
+// FormatVersion is the newest version of an on-disk format a binary reads
+// and the version it writes.
+type FormatVersion struct {
+	Read  int `json:"read"`
+	Write int `json:"write"`
+}
+
+// BuildInfo describes the running binary and the features it was started
+// with, so fleet tooling can spot instances that drifted from the rest.
+type BuildInfo struct {
+	Version   string        `json:"version"`
+	Commit    string        `json:"commit"`
+	BuildDate string        `json:"build_date"`
+	GoVersion string        `json:"go_version"`
+	Platform  string        `json:"platform"`
+	FIPS      bool          `json:"fips"`
+	Features  BuildFeatures `json:"features"`
+}
+
+// BuildFeatures lists the data-path features a gateway instance runs with.
+type BuildFeatures struct {
+	Chunked   bool `json:"chunked"`
+	ChunkSize int  `json:"chunk_size,omitempty"`
+	// Compression is the compression algorithm, or "" when disabled.
+	Compression string `json:"compression,omitempty"`
+	// KMSProvider is the key manager provider, or "" when data keys are
+	// derived from the password.
+	KMSProvider string `json:"kms_provider,omitempty"`
+	// HardwareAcceleration is "aes-ni" or "armv8-aes" when AES runs in
+	// hardware, or "" otherwise.
+	HardwareAcceleration string `json:"hardware_acceleration,omitempty"`
 }
 
 var (
 	startTime = time.Now()
 	version   = "dev"
+	formats   map[string]FormatVersion
+	buildInfo = BuildInfo{Version: "dev", Commit: "unknown", BuildDate: "unknown"}
 )
 
 // SetVersion sets the application version.
@@ -25,12 +65,71 @@ func SetVersion(v string) {
 	version = v
 }
 
+// SetFormatVersions sets the format versions reported by the health endpoint.
+func SetFormatVersions(f map[string]FormatVersion) {
+	formats = f
+}
+
+// SetBuildInfo sets the build information served by VersionHandler. It
+// also sets the version reported by the health endpoints.
+func SetBuildInfo(info BuildInfo) {
+	buildInfo = info
+	version = info.Version
+}
+
+// VersionHandler returns a handler serving the build information.
+func VersionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(buildInfo)
+	}
+}
+
 // ReadyCheck is a single named readiness dependency.
 type ReadyCheck struct {
 	Name  string
 	Check func(context.Context) error
 }
 
+// CachedReadyCheck wraps c so that each run is bounded by timeout and its
+// result is reused for ttl. Concurrent probes wait for the run in progress
+// instead of starting their own. A zero ttl runs the check every time.
+func CachedReadyCheck(c ReadyCheck, timeout, ttl time.Duration) ReadyCheck {
+	var (
+		mu      sync.Mutex
+		checked time.Time
+		result  error
+	)
+	return ReadyCheck{
+		Name: c.Name,
+		Check: func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if ttl > 0 && !checked.IsZero() && time.Since(checked) < ttl {
+				return result
+			}
+			checkCtx := ctx
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				checkCtx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			result = c.Check(checkCtx)
+			if result != nil && checkCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+				result = fmt.Errorf("timed out after %s: %w", timeout, result)
+			}
+			// A probe that gave up says nothing about the dependency.
+			if ctx.Err() == nil {
+				checked = time.Now()
+			} else {
+				checked = time.Time{}
+			}
+			return result
+		},
+	}
+}
+
 // HealthHandler returns a handler for health check endpoints.
 func HealthHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -38,6 +137,7 @@ func HealthHandler() http.HandlerFunc {
 			Status:    "healthy",
 			Timestamp: time.Now(),
 			Version:   version,
+			Formats:   formats,
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -63,9 +163,22 @@ func ReadinessHandler(checks ...ReadyCheck) http.HandlerFunc {
 			Checks:    make(map[string]string, len(checks)),
 		}
 
+		// Run the checks concurrently so a slow dependency costs its own
+		// timeout rather than the sum of all of them.
+		errs := make([]error, len(checks))
+		var wg sync.WaitGroup
+		for i, c := range checks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = c.Check(ctx)
+			}()
+		}
+		wg.Wait()
+
 		failed := false
-		for _, c := range checks {
-			if err := c.Check(ctx); err != nil {
+		for i, c := range checks {
+			if err := errs[i]; err != nil {
 				status.Checks[c.Name] = "unavailable: " + err.Error()
 				failed = true
 			} else {
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestHealthHandler(t *testing.T) {
@@ -154,3 +155,75 @@ func TestSetVersion(t *testing.T) {
 	}
 }
 
+
+func TestHealthHandler_Formats(t *testing.T) {
+	defer SetFormatVersions(nil)
+	SetFormatVersions(map[string]FormatVersion{"chunk_manifest": {Read: 2, Write: 1}})
+
+	w := httptest.NewRecorder()
+	HealthHandler()(w, httptest.NewRequest("GET", "/health", nil))
+	var body HealthStatus
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got := body.Formats["chunk_manifest"]; got.Read != 2 || got.Write != 1 {
+		t.Errorf("formats = %v", body.Formats)
+	}
+}
+
+func TestVersionHandler(t *testing.T) {
+	original, originalVersion := buildInfo, version
+	defer func() { buildInfo, version = original, originalVersion }()
+	SetBuildInfo(BuildInfo{Version: "v2.0.0", Commit: "abc123", Features: BuildFeatures{Compression: "zstd"}})
+
+	w := httptest.NewRecorder()
+	VersionHandler()(w, httptest.NewRequest("GET", "/version", nil))
+	var body BuildInfo
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Version != "v2.0.0" || body.Commit != "abc123" || body.Features.Compression != "zstd" {
+		t.Errorf("version body = %+v", body)
+	}
+	if version != "v2.0.0" {
+		t.Errorf("health version = %q, want the build version", version)
+	}
+}
+
+func TestCachedReadyCheck(t *testing.T) {
+	calls := 0
+	var err error
+	c := CachedReadyCheck(ReadyCheck{Name: "kms", Check: func(context.Context) error {
+		calls++
+		return err
+	}}, time.Second, time.Hour)
+
+	err = errors.New("down")
+	if got := c.Check(context.Background()); got == nil || c.Name != "kms" {
+		t.Fatalf("first check = %v", got)
+	}
+	err = nil
+	if got := c.Check(context.Background()); got == nil || calls != 1 {
+		t.Errorf("cached check = %v after %d calls, want the cached failure", got, calls)
+	}
+
+	// A probe that gave up does not leave its result in the cache.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	uncached := CachedReadyCheck(ReadyCheck{Name: "backend", Check: func(ctx context.Context) error { return ctx.Err() }}, time.Second, time.Hour)
+	_ = uncached.Check(ctx)
+	if got := uncached.Check(context.Background()); got != nil {
+		t.Errorf("check after cancelled probe = %v", got)
+	}
+}
+
+func TestCachedReadyCheck_Timeout(t *testing.T) {
+	c := CachedReadyCheck(ReadyCheck{Name: "backend", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}, 10*time.Millisecond, 0)
+	err := c.Check(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) || err.Error() != "timed out after 10ms: context deadline exceeded" {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -48,6 +48,7 @@ type Metrics struct {
 	memorySysBytes                    prometheus.Gauge
 	hardwareAccelerationEnabled       *prometheus.GaugeVec
 	fipsMode                          prometheus.Gauge
+	buildInfo                         *prometheus.GaugeVec
 	uploadPartCopyTotal               *prometheus.CounterVec
 	uploadPartCopyBytes               *prometheus.CounterVec
 	uploadPartCopyDuration            *prometheus.HistogramVec
@@ -55,6 +56,7 @@ type Metrics struct {
 	// Admin and rotation metrics
 	kmsActiveKeyVersion      *prometheus.GaugeVec
 	kmsRotationOpsTotal      *prometheus.CounterVec
+	kmsKeyVersionChanges     *prometheus.CounterVec
 	kmsRotationDuration      *prometheus.HistogramVec
 	kmsRotationInFlightWraps prometheus.Gauge
 	gatewayAdminAPIEnabled   prometheus.Gauge
@@ -93,6 +95,103 @@ type Metrics struct {
 	// s3BackendRetryBackoffSeconds is a histogram of backoff delays actually
 	// slept.
 	s3BackendRetryBackoffSeconds prometheus.Histogram
+	// s3BackendReadReplicaAttemptsTotal counts reads sent to a further
+	// backend endpoint. Labels: operation, kind (failover|hedge).
+	s3BackendReadReplicaAttemptsTotal *prometheus.CounterVec
+	// s3BackendReadsServedTotal counts GET/HEAD answers by the backend
+	// endpoint that gave them. Labels: operation, endpoint.
+	s3BackendReadsServedTotal *prometheus.CounterVec
+	// s3BackendReadFailoverActive is 1 while reads are failed over away
+	// from the primary endpoint.
+	s3BackendReadFailoverActive prometheus.Gauge
+	// s3BackendRangeHedgesTotal counts duplicate ranged GETs. Labels:
+	// outcome (issued|won).
+	s3BackendRangeHedgesTotal *prometheus.CounterVec
+	// s3BackendHeadElisionTotal counts range reads that consulted the
+	// cached object metadata. Labels: outcome (hit|miss|stale).
+	s3BackendHeadElisionTotal *prometheus.CounterVec
+	// s3BackendConditionalGetsTotal counts GETs that revalidated a cached
+	// object. Labels: outcome (not_modified|modified|error).
+	s3BackendConditionalGetsTotal *prometheus.CounterVec
+	// metadataOverflowTotal counts writes whose encryption metadata would
+	// not fit in the backend's headers. Labels: outcome (fallback|rejected).
+	metadataOverflowTotal *prometheus.CounterVec
+
+	// Error-budget / SLO metrics. Operation labels come from the bounded
+	// S3 operation classifier in the SLO middleware.
+	gatewaySLOEventsTotal          *prometheus.CounterVec
+	gatewaySLOBurnRate             *prometheus.GaugeVec
+	gatewaySLOErrorBudgetRemaining *prometheus.GaugeVec
+
+	// Upload content inspection (DLP). Rule labels are bounded by the
+	// configured rule set.
+	gatewayDLPInspectionsTotal *prometheus.CounterVec
+	gatewayDLPMatchesTotal     *prometheus.CounterVec
+
+	// GetObject response transformation. Rule labels are bounded by the
+	// configured rule set.
+	gatewayTransformsTotal *prometheus.CounterVec
+
+	// Calls into sandboxed extension modules. Module labels are the
+	// configured module names.
+	gatewayExtensionCallsTotal *prometheus.CounterVec
+
+	// Periodic canary probe. Step labels: encryption, key_manager, backend.
+	gatewayCanaryFailuresTotal        *prometheus.CounterVec
+	gatewayCanaryLastSuccessTimestamp prometheus.Gauge
+
+	// Warm pool health checks. Target labels: backend, kms.
+	gatewayWarmPoolChecksTotal *prometheus.CounterVec
+
+	// Connections refused in air-gapped mode. Host labels come from the
+	// configuration and the libraries the gateway uses, not from clients.
+	gatewayAirGapBlockedTotal *prometheus.CounterVec
+
+	// Requests refused by authentication. Reason labels are the fixed set
+	// recorded by the auth middleware.
+	gatewayAuthRejectionsTotal *prometheus.CounterVec
+
+	// Objects read in a format version newer than this binary supports.
+	// Kind labels are the fixed crypto format kinds.
+	gatewayFormatVersionSkewTotal *prometheus.CounterVec
+
+	// Encrypted objects read without a valid identity marker. Result
+	// labels: missing, invalid.
+	gatewayObjectMarkerFailuresTotal *prometheus.CounterVec
+
+	// Writes whose metadata was already gateway-encrypted. Action labels:
+	// rejected, passed_through, encrypted.
+	gatewayDoubleEncryptionTotal *prometheus.CounterVec
+
+	// Scheduled background jobs. Job labels are the fixed scheduler job
+	// names; result labels: success, failure.
+	gatewaySchedulerRunsTotal            *prometheus.CounterVec
+	gatewaySchedulerLastRunTimestamp     *prometheus.GaugeVec
+	gatewaySchedulerLastRunDuration      *prometheus.GaugeVec
+	gatewaySchedulerLastFailureTimestamp *prometheus.GaugeVec
+
+	// Global memory budget. Subsystem labels are the fixed membudget
+	// subsystems: cache, request, upload_part.
+	gatewayMemoryBudgetLimit    prometheus.Gauge
+	gatewayMemoryBudgetReserved *prometheus.GaugeVec
+	gatewayMemoryBudgetUsed     prometheus.Gauge
+	gatewayMemoryBudgetWaiting  prometheus.Gauge
+	gatewayMemoryBudgetRefusals *prometheus.CounterVec
+
+	// Admission control. Class labels: priority, metadata, normal, bulk.
+	gatewayAdmissionInFlight   *prometheus.GaugeVec
+	gatewayAdmissionQueued     *prometheus.GaugeVec
+	gatewayAdmissionAdmitted   *prometheus.CounterVec
+	gatewayAdmissionRejections *prometheus.CounterVec
+
+	// Quotas. Scope labels: bucket, tenant; name labels are the configured
+	// bucket and tenant names.
+	gatewayQuotaUsedBytes   *prometheus.GaugeVec
+	gatewayQuotaUsedObjects *prometheus.GaugeVec
+	gatewayQuotaRejections  *prometheus.CounterVec
+
+	// Billing export
+	gatewayBillingExports *prometheus.CounterVec
 }
 
 // NewMetrics creates a new metrics instance with default configuration.
@@ -111,6 +210,12 @@ func NewMetricsWithRegistry(reg prometheus.Registerer) *Metrics {
 	return newMetricsWithRegistry(reg, Config{EnableBucketLabel: true})
 }
 
+// NewMetricsWithConfigAndRegistry creates a metrics instance with the
+// provided configuration, registered with reg.
+func NewMetricsWithConfigAndRegistry(cfg Config, reg prometheus.Registerer) *Metrics {
+	return newMetricsWithRegistry(reg, cfg)
+}
+
 // newMetricsWithRegistry creates a new metrics instance with a custom registry (for testing).
 func newMetricsWithRegistry(reg prometheus.Registerer, cfg Config) *Metrics {
 	factory := promauto.With(reg)
@@ -252,6 +357,13 @@ func newMetricsWithRegistry(reg prometheus.Registerer, cfg Config) *Metrics {
 				Help: "FIPS 140-3 mode status (1=enabled, 0=disabled)",
 			},
 		),
+		buildInfo: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gateway_build_info",
+				Help: "Always 1; labels describe the running build and the features it was started with",
+			},
+			[]string{"version", "commit", "build_date", "go_version", "chunked", "compression", "kms_provider", "hw_accel"},
+		),
 		uploadPartCopyTotal: factory.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "gateway_upload_part_copy_total",
@@ -287,6 +399,13 @@ func newMetricsWithRegistry(reg prometheus.Registerer, cfg Config) *Metrics {
 			},
 			[]string{"provider"},
 		),
+		kmsKeyVersionChanges: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "kms_key_version_changes_total",
+				Help: "Active key version changes seen by the key version watch, by provider and source (kms: switched to the version the KMS reports; observed: changed through the admin API)",
+			},
+			[]string{"provider", "source"},
+		),
 		kmsRotationOpsTotal: factory.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "kms_rotation_operations_total",
@@ -409,6 +528,54 @@ func newMetricsWithRegistry(reg prometheus.Registerer, cfg Config) *Metrics {
 				Buckets: []float64{0.001, 0.01, 0.05, 0.1, 0.2, 0.5, 1, 2, 5, 10, 20},
 			},
 		),
+		s3BackendReadReplicaAttemptsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "s3_backend_read_replica_attempts_total",
+				Help: "GET/HEAD attempts sent to a further backend endpoint, labelled by operation and kind (failover or hedge).",
+			},
+			[]string{"operation", "kind"},
+		),
+		s3BackendReadsServedTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "s3_backend_reads_served_total",
+				Help: "GET/HEAD requests answered by each backend endpoint, labelled by operation and endpoint.",
+			},
+			[]string{"operation", "endpoint"},
+		),
+		s3BackendReadFailoverActive: factory.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "s3_backend_read_failover_active",
+				Help: "1 while reads are served by the read replicas first because the primary endpoint kept failing, 0 otherwise.",
+			},
+		),
+		s3BackendRangeHedgesTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "s3_backend_range_hedges_total",
+				Help: "Duplicate ranged GETs sent for slow chunk fetches (outcome=issued) and those that answered first (outcome=won).",
+			},
+			[]string{"outcome"},
+		),
+		s3BackendHeadElisionTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "s3_backend_head_elision_total",
+				Help: "Range reads that looked up cached object metadata: hit (GET sent without a HEAD), miss (HEAD needed) or stale (the GET failed or its ETag changed; HEAD needed).",
+			},
+			[]string{"outcome"},
+		),
+		s3BackendConditionalGetsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "s3_backend_conditional_gets_total",
+				Help: "Backend GETs with If-None-Match revalidating an expired cached object: not_modified (served from the cache), modified (the object changed and was fetched) or error.",
+			},
+			[]string{"outcome"},
+		),
+		metadataOverflowTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "metadata_overflow_total",
+				Help: "Writes whose encryption metadata exceeded the provider header limit: fallback (stored in the object body) or rejected (refused with MetadataTooLarge).",
+			},
+			[]string{"outcome"},
+		),
 
 		// V0.6-OBS-1 — admin pprof metrics.
 		s3GatewayAdminPprofRequestsTotal: factory.NewCounterVec(
@@ -424,6 +591,235 @@ func newMetricsWithRegistry(reg prometheus.Registerer, cfg Config) *Metrics {
 				Help: "Whether pprof profiling routes are mounted on the admin listener (1=enabled, 0=disabled).",
 			},
 		),
+
+		gatewaySLOEventsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_slo_events_total",
+				Help: "Requests classified against their SLO, labelled by S3 operation and outcome (good, error, slow).",
+			},
+			[]string{"operation", "outcome"},
+		),
+		gatewaySLOBurnRate: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gateway_slo_burn_rate",
+				Help: "Error-budget burn rate per S3 operation over a look-back window (1 = budget consumed exactly over the SLO period).",
+			},
+			[]string{"operation", "window"},
+		),
+		gatewaySLOErrorBudgetRemaining: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gateway_slo_error_budget_remaining",
+				Help: "Fraction of the error budget remaining over the longest configured window; negative when overspent.",
+			},
+			[]string{"operation"},
+		),
+
+		gatewayDLPInspectionsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_dlp_inspections_total",
+				Help: "Uploads inspected by the content inspection stage, labelled by outcome (clean, audit, tag, block).",
+			},
+			[]string{"outcome"},
+		),
+		gatewayDLPMatchesTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_dlp_matches_total",
+				Help: "Content inspection rule matches, labelled by rule and action. Counts occurrences, not uploads.",
+			},
+			[]string{"rule", "action"},
+		),
+		gatewayTransformsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_transforms_total",
+				Help: "GetObject responses passed through a transformation rule, labelled by rule and outcome (ok, failed, aborted).",
+			},
+			[]string{"rule", "outcome"},
+		),
+		gatewayExtensionCallsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_extension_calls_total",
+				Help: "Calls into sandboxed extension modules, labelled by module, export and result (ok, failed, limit_exceeded).",
+			},
+			[]string{"module", "export", "result"},
+		),
+
+		gatewayCanaryFailuresTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_canary_failures_total",
+				Help: "Failed canary probes, labelled by the step that failed (encryption, key_manager, backend).",
+			},
+			[]string{"step"},
+		),
+		gatewayCanaryLastSuccessTimestamp: factory.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "gateway_canary_last_success_timestamp_seconds",
+				Help: "Unix time of the last canary probe that passed every step; 0 until one has.",
+			},
+		),
+
+		gatewayWarmPoolChecksTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_warm_pool_checks_total",
+				Help: "Warm pool health checks, labelled by target (backend, kms) and outcome (ok, failed).",
+			},
+			[]string{"target", "outcome"},
+		),
+		gatewayAirGapBlockedTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_airgap_blocked_connections_total",
+				Help: "Outbound connections refused in air-gapped mode, labelled by destination host.",
+			},
+			[]string{"host"},
+		),
+		gatewayAuthRejectionsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_auth_rejections_total",
+				Help: "Requests refused by authentication, labelled by reason (missing_credentials, unknown_access_key, signature_mismatch, invalid_signature, clock_skew, replay, invalid_token, expired_token).",
+			},
+			[]string{"reason"},
+		),
+
+		gatewayFormatVersionSkewTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_format_version_skew_total",
+				Help: "Objects that could not be read because they were written in a newer on-disk format version, labelled by format kind.",
+			},
+			[]string{"kind"},
+		),
+
+		gatewayObjectMarkerFailuresTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_object_marker_failures_total",
+				Help: "Encrypted objects read without a valid gateway identity marker, labelled by result (missing, invalid).",
+			},
+			[]string{"result"},
+		),
+
+		gatewayDoubleEncryptionTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_double_encryption_total",
+				Help: "Writes whose metadata already carried the gateway's encryption metadata, labelled by action (rejected, passed_through, encrypted).",
+			},
+			[]string{"action"},
+		),
+
+		gatewaySchedulerRunsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_scheduler_runs_total",
+				Help: "Passes of scheduled background jobs, labelled by job and result (success, failure).",
+			},
+			[]string{"job", "result"},
+		),
+		gatewaySchedulerLastRunTimestamp: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gateway_scheduler_last_run_timestamp_seconds",
+				Help: "Unix time the last pass of a scheduled job started, labelled by job.",
+			},
+			[]string{"job"},
+		),
+		gatewaySchedulerLastRunDuration: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gateway_scheduler_last_run_duration_seconds",
+				Help: "Duration of the last pass of a scheduled job, labelled by job.",
+			},
+			[]string{"job"},
+		),
+		gatewaySchedulerLastFailureTimestamp: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gateway_scheduler_last_failure_timestamp_seconds",
+				Help: "Unix time the last failed pass of a scheduled job started, labelled by job; absent until one fails.",
+			},
+			[]string{"job"},
+		),
+
+		gatewayMemoryBudgetLimit: factory.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "gateway_memory_budget_limit_bytes",
+				Help: "Size of the global memory budget.",
+			},
+		),
+		gatewayMemoryBudgetReserved: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gateway_memory_budget_reserved_bytes",
+				Help: "Bytes reserved from the memory budget, labelled by subsystem (cache, request, upload_part).",
+			},
+			[]string{"subsystem"},
+		),
+		gatewayMemoryBudgetUsed: factory.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "gateway_memory_budget_used_bytes",
+				Help: "Go heap held by live objects, sampled by the memory budget for comparison with the reserved bytes.",
+			},
+		),
+		gatewayMemoryBudgetWaiting: factory.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "gateway_memory_budget_waiting",
+				Help: "Reservations queued for memory budget to be released.",
+			},
+		),
+		gatewayMemoryBudgetRefusals: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_memory_budget_refusals_total",
+				Help: "Reservations refused because the memory budget was exhausted, labelled by subsystem.",
+			},
+			[]string{"subsystem"},
+		),
+		gatewayAdmissionInFlight: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gateway_admission_in_flight",
+				Help: "Requests holding an admission control slot, labelled by class (priority, metadata, normal, bulk).",
+			},
+			[]string{"class"},
+		),
+		gatewayAdmissionQueued: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gateway_admission_queued",
+				Help: "Requests waiting for an admission control slot, labelled by class.",
+			},
+			[]string{"class"},
+		),
+		gatewayAdmissionAdmitted: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_admission_admitted_total",
+				Help: "Requests admitted by admission control, labelled by class.",
+			},
+			[]string{"class"},
+		),
+		gatewayAdmissionRejections: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_admission_rejections_total",
+				Help: "Requests refused with 503 SlowDown by admission control, labelled by class.",
+			},
+			[]string{"class"},
+		),
+		gatewayQuotaUsedBytes: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gateway_quota_used_bytes",
+				Help: "Stored bytes counted against each configured quota, labelled by scope (bucket, tenant) and name.",
+			},
+			[]string{"scope", "name"},
+		),
+		gatewayQuotaUsedObjects: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "gateway_quota_used_objects",
+				Help: "Objects counted against each configured quota, labelled by scope and name.",
+			},
+			[]string{"scope", "name"},
+		),
+		gatewayQuotaRejections: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_quota_rejections_total",
+				Help: "Writes refused with QuotaExceeded, labelled by the scope and name of the exhausted quota.",
+			},
+			[]string{"scope", "name"},
+		),
+		gatewayBillingExports: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_billing_exports_total",
+				Help: "Billing exports written, by result (success, failure).",
+			},
+			[]string{"result"},
+		),
 	}
 }
 
@@ -445,6 +841,23 @@ func (m *Metrics) SetFIPSMode(enabled bool) {
 	m.fipsMode.Set(val)
 }
 
+// SetBuildInfo publishes info as the gateway_build_info gauge and on the
+// /version endpoint.
+func (m *Metrics) SetBuildInfo(info BuildInfo) {
+	SetBuildInfo(info)
+	m.buildInfo.Reset()
+	m.buildInfo.WithLabelValues(
+		info.Version,
+		info.Commit,
+		info.BuildDate,
+		info.GoVersion,
+		strconv.FormatBool(info.Features.Chunked),
+		info.Features.Compression,
+		info.Features.KMSProvider,
+		info.Features.HardwareAcceleration,
+	).Set(1)
+}
+
 // GetHardwareAccelerationEnabledMetric returns the hardware acceleration enabled metric (for testing).
 func (m *Metrics) GetHardwareAccelerationEnabledMetric() *prometheus.GaugeVec {
 	return m.hardwareAccelerationEnabled
@@ -455,6 +868,15 @@ func (m *Metrics) SetActiveKeyVersion(provider string, version int) {
 	m.kmsActiveKeyVersion.WithLabelValues(provider).Set(float64(version))
 }
 
+// RecordKeyVersionChange counts an active key version change seen by the
+// key version watch.
+func (m *Metrics) RecordKeyVersionChange(provider, source string) {
+	if m == nil || m.kmsKeyVersionChanges == nil {
+		return
+	}
+	m.kmsKeyVersionChanges.WithLabelValues(provider, source).Inc()
+}
+
 // RecordRotationOperation records a rotation operation counter and duration.
 func (m *Metrics) RecordRotationOperation(step, result string, duration time.Duration) {
 	m.kmsRotationOpsTotal.WithLabelValues(step, result).Inc()
@@ -515,6 +937,250 @@ func (m *Metrics) GetRotatedReadsMetric() *prometheus.CounterVec {
 	return m.rotatedReads
 }
 
+// RecordSLOEvent counts a request classified against its SLO.
+func (m *Metrics) RecordSLOEvent(operation, outcome string) {
+	if m == nil || m.gatewaySLOEventsTotal == nil {
+		return
+	}
+	m.gatewaySLOEventsTotal.WithLabelValues(operation, outcome).Inc()
+}
+
+// SetSLOBurnRate sets the burn-rate gauge for operation over window.
+func (m *Metrics) SetSLOBurnRate(operation, window string, rate float64) {
+	if m == nil || m.gatewaySLOBurnRate == nil {
+		return
+	}
+	m.gatewaySLOBurnRate.WithLabelValues(operation, window).Set(rate)
+}
+
+// SetSLOErrorBudgetRemaining sets the remaining error-budget gauge for operation.
+func (m *Metrics) SetSLOErrorBudgetRemaining(operation string, remaining float64) {
+	if m == nil || m.gatewaySLOErrorBudgetRemaining == nil {
+		return
+	}
+	m.gatewaySLOErrorBudgetRemaining.WithLabelValues(operation).Set(remaining)
+}
+
+// RecordDLPInspection counts an inspected upload by outcome.
+func (m *Metrics) RecordDLPInspection(outcome string) {
+	if m == nil || m.gatewayDLPInspectionsTotal == nil {
+		return
+	}
+	m.gatewayDLPInspectionsTotal.WithLabelValues(outcome).Inc()
+}
+
+// RecordDLPMatch adds count occurrences of rule to the match counter.
+func (m *Metrics) RecordDLPMatch(rule, action string, count int) {
+	if m == nil || m.gatewayDLPMatchesTotal == nil {
+		return
+	}
+	m.gatewayDLPMatchesTotal.WithLabelValues(rule, action).Add(float64(count))
+}
+
+// RecordExtensionCall counts a call into an extension module.
+func (m *Metrics) RecordExtensionCall(module, export, result string) {
+	if m == nil || m.gatewayExtensionCallsTotal == nil {
+		return
+	}
+	m.gatewayExtensionCallsTotal.WithLabelValues(module, export, result).Inc()
+}
+
+// RecordTransform counts a GetObject response handled by a transformation
+// rule.
+func (m *Metrics) RecordTransform(rule, outcome string) {
+	if m == nil || m.gatewayTransformsTotal == nil {
+		return
+	}
+	m.gatewayTransformsTotal.WithLabelValues(rule, outcome).Inc()
+}
+
+// RecordCanaryFailure counts a failed canary probe by the step that failed.
+func (m *Metrics) RecordCanaryFailure(step string) {
+	if m == nil || m.gatewayCanaryFailuresTotal == nil {
+		return
+	}
+	m.gatewayCanaryFailuresTotal.WithLabelValues(step).Inc()
+}
+
+// SetCanaryLastSuccess records when a canary probe last passed.
+func (m *Metrics) SetCanaryLastSuccess(t time.Time) {
+	if m == nil || m.gatewayCanaryLastSuccessTimestamp == nil {
+		return
+	}
+	m.gatewayCanaryLastSuccessTimestamp.Set(float64(t.Unix()))
+}
+
+// RecordWarmPoolCheck counts a warm pool health check of target.
+func (m *Metrics) RecordWarmPoolCheck(target, outcome string) {
+	if m == nil || m.gatewayWarmPoolChecksTotal == nil {
+		return
+	}
+	m.gatewayWarmPoolChecksTotal.WithLabelValues(target, outcome).Inc()
+}
+
+// RecordAirGapBlocked counts an outbound connection to host refused in
+// air-gapped mode.
+func (m *Metrics) RecordAirGapBlocked(host string) {
+	if m == nil || m.gatewayAirGapBlockedTotal == nil {
+		return
+	}
+	m.gatewayAirGapBlockedTotal.WithLabelValues(host).Inc()
+}
+
+// RecordAuthRejection counts a request refused by authentication.
+func (m *Metrics) RecordAuthRejection(reason string) {
+	if m == nil || m.gatewayAuthRejectionsTotal == nil {
+		return
+	}
+	m.gatewayAuthRejectionsTotal.WithLabelValues(reason).Inc()
+}
+
+// RecordFormatVersionSkew counts an object written in a newer format
+// version of kind than this binary reads.
+func (m *Metrics) RecordFormatVersionSkew(kind string) {
+	if m == nil || m.gatewayFormatVersionSkewTotal == nil {
+		return
+	}
+	m.gatewayFormatVersionSkewTotal.WithLabelValues(kind).Inc()
+}
+
+// RecordScheduledJobRun records a pass of a scheduled background job.
+func (m *Metrics) RecordScheduledJobRun(job string, started time.Time, duration time.Duration, err error) {
+	if m == nil || m.gatewaySchedulerRunsTotal == nil {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "failure"
+		m.gatewaySchedulerLastFailureTimestamp.WithLabelValues(job).Set(float64(started.Unix()))
+	}
+	m.gatewaySchedulerRunsTotal.WithLabelValues(job, result).Inc()
+	m.gatewaySchedulerLastRunTimestamp.WithLabelValues(job).Set(float64(started.Unix()))
+	m.gatewaySchedulerLastRunDuration.WithLabelValues(job).Set(duration.Seconds())
+}
+
+// SetMemoryBudgetLimit records the size of the memory budget.
+func (m *Metrics) SetMemoryBudgetLimit(bytes int64) {
+	if m == nil || m.gatewayMemoryBudgetLimit == nil {
+		return
+	}
+	m.gatewayMemoryBudgetLimit.Set(float64(bytes))
+}
+
+// SetMemoryBudgetReserved records the bytes subsystem holds from the
+// memory budget.
+func (m *Metrics) SetMemoryBudgetReserved(subsystem string, bytes int64) {
+	if m == nil || m.gatewayMemoryBudgetReserved == nil {
+		return
+	}
+	m.gatewayMemoryBudgetReserved.WithLabelValues(subsystem).Set(float64(bytes))
+}
+
+// SetMemoryBudgetUsed records the sampled heap in use.
+func (m *Metrics) SetMemoryBudgetUsed(bytes int64) {
+	if m == nil || m.gatewayMemoryBudgetUsed == nil {
+		return
+	}
+	m.gatewayMemoryBudgetUsed.Set(float64(bytes))
+}
+
+// SetMemoryBudgetWaiting records the reservations queued on the budget.
+func (m *Metrics) SetMemoryBudgetWaiting(n int) {
+	if m == nil || m.gatewayMemoryBudgetWaiting == nil {
+		return
+	}
+	m.gatewayMemoryBudgetWaiting.Set(float64(n))
+}
+
+// RecordMemoryBudgetRefusal counts a reservation the budget refused.
+func (m *Metrics) RecordMemoryBudgetRefusal(subsystem string) {
+	if m == nil || m.gatewayMemoryBudgetRefusals == nil {
+		return
+	}
+	m.gatewayMemoryBudgetRefusals.WithLabelValues(subsystem).Inc()
+}
+
+// SetAdmissionInFlight records the requests of class holding an admission
+// slot.
+func (m *Metrics) SetAdmissionInFlight(class string, n int) {
+	if m == nil || m.gatewayAdmissionInFlight == nil {
+		return
+	}
+	m.gatewayAdmissionInFlight.WithLabelValues(class).Set(float64(n))
+}
+
+// RecordAdmissionAdmitted counts a request admitted by admission control.
+func (m *Metrics) RecordAdmissionAdmitted(class string) {
+	if m == nil || m.gatewayAdmissionAdmitted == nil {
+		return
+	}
+	m.gatewayAdmissionAdmitted.WithLabelValues(class).Inc()
+}
+
+// SetAdmissionQueued records the requests of class waiting for a slot.
+func (m *Metrics) SetAdmissionQueued(class string, n int) {
+	if m == nil || m.gatewayAdmissionQueued == nil {
+		return
+	}
+	m.gatewayAdmissionQueued.WithLabelValues(class).Set(float64(n))
+}
+
+// RecordAdmissionRejection counts a request shed by admission control.
+func (m *Metrics) RecordAdmissionRejection(class string) {
+	if m == nil || m.gatewayAdmissionRejections == nil {
+		return
+	}
+	m.gatewayAdmissionRejections.WithLabelValues(class).Inc()
+}
+
+// SetQuotaUsage records the usage counted against a quota.
+func (m *Metrics) SetQuotaUsage(scope, name string, bytes, objects int64) {
+	if m == nil || m.gatewayQuotaUsedBytes == nil {
+		return
+	}
+	m.gatewayQuotaUsedBytes.WithLabelValues(scope, name).Set(float64(bytes))
+	m.gatewayQuotaUsedObjects.WithLabelValues(scope, name).Set(float64(objects))
+}
+
+// RecordQuotaRejection counts a write refused because a quota was
+// exhausted.
+func (m *Metrics) RecordQuotaRejection(scope, name string) {
+	if m == nil || m.gatewayQuotaRejections == nil {
+		return
+	}
+	m.gatewayQuotaRejections.WithLabelValues(scope, name).Inc()
+}
+
+// RecordBillingExport counts a billing export attempt.
+func (m *Metrics) RecordBillingExport(ok bool) {
+	if m == nil || m.gatewayBillingExports == nil {
+		return
+	}
+	result := "success"
+	if !ok {
+		result = "failure"
+	}
+	m.gatewayBillingExports.WithLabelValues(result).Inc()
+}
+
+// RecordObjectMarkerFailure counts an encrypted object read whose identity
+// marker was missing or invalid.
+func (m *Metrics) RecordObjectMarkerFailure(result string) {
+	if m == nil || m.gatewayObjectMarkerFailuresTotal == nil {
+		return
+	}
+	m.gatewayObjectMarkerFailuresTotal.WithLabelValues(result).Inc()
+}
+
+// RecordDoubleEncryption counts a write that arrived already encrypted by
+// the gateway and what was done with it.
+func (m *Metrics) RecordDoubleEncryption(action string) {
+	if m == nil || m.gatewayDoubleEncryptionTotal == nil {
+		return
+	}
+	m.gatewayDoubleEncryptionTotal.WithLabelValues(action).Inc()
+}
+
 // RecordHTTPRequest records an HTTP request metric.
 func (m *Metrics) RecordHTTPRequest(ctx context.Context, method, path string, status int, duration time.Duration, bytes int64) {
 	label := sanitizePathLabel(path)
@@ -838,6 +1504,73 @@ func (m *Metrics) RecordBackendRetryBackoff(delay time.Duration) {
 	}
 }
 
+// RecordBackendReadReplicaAttempt counts a read sent to a further backend
+// endpoint, either after a failure (kind "failover") or because the
+// previous attempt was slow (kind "hedge").
+func (m *Metrics) RecordBackendReadReplicaAttempt(op, kind string) {
+	if m == nil || m.s3BackendReadReplicaAttemptsTotal == nil {
+		return
+	}
+	m.s3BackendReadReplicaAttemptsTotal.WithLabelValues(op, kind).Inc()
+}
+
+// RecordBackendReadServed counts a GET or HEAD answered by endpoint.
+func (m *Metrics) RecordBackendReadServed(op, endpoint string) {
+	if m == nil || m.s3BackendReadsServedTotal == nil {
+		return
+	}
+	m.s3BackendReadsServedTotal.WithLabelValues(op, endpoint).Inc()
+}
+
+// SetBackendReadFailover records whether reads are failed over away from
+// the primary endpoint.
+func (m *Metrics) SetBackendReadFailover(active bool) {
+	if m == nil || m.s3BackendReadFailoverActive == nil {
+		return
+	}
+	if active {
+		m.s3BackendReadFailoverActive.Set(1)
+	} else {
+		m.s3BackendReadFailoverActive.Set(0)
+	}
+}
+
+// RecordRangeHedge counts a hedged range fetch: outcome is "issued" when the
+// duplicate is sent and "won" when it answered before the original.
+func (m *Metrics) RecordRangeHedge(outcome string) {
+	if m == nil || m.s3BackendRangeHedgesTotal == nil {
+		return
+	}
+	m.s3BackendRangeHedgesTotal.WithLabelValues(outcome).Inc()
+}
+
+// RecordHeadElision counts a cached-metadata lookup for a range read with
+// outcome "hit", "miss" or "stale".
+func (m *Metrics) RecordHeadElision(outcome string) {
+	if m == nil || m.s3BackendHeadElisionTotal == nil {
+		return
+	}
+	m.s3BackendHeadElisionTotal.WithLabelValues(outcome).Inc()
+}
+
+// RecordConditionalGet counts a cache revalidation with outcome
+// "not_modified", "modified" or "error".
+func (m *Metrics) RecordConditionalGet(outcome string) {
+	if m == nil || m.s3BackendConditionalGetsTotal == nil {
+		return
+	}
+	m.s3BackendConditionalGetsTotal.WithLabelValues(outcome).Inc()
+}
+
+// RecordMetadataOverflow counts a write whose metadata overflowed the
+// provider header limit with outcome "fallback" or "rejected".
+func (m *Metrics) RecordMetadataOverflow(outcome string) {
+	if m == nil || m.metadataOverflowTotal == nil {
+		return
+	}
+	m.metadataOverflowTotal.WithLabelValues(outcome).Inc()
+}
+
 // getExemplar extracts trace ID from context and returns prometheus Labels for exemplar.
 func getExemplar(ctx context.Context) prometheus.Labels {
 	if ctx == nil {
@@ -220,6 +220,40 @@ func TestMetrics_SetFIPSMode(t *testing.T) {
 	}
 }
 
+// TestMetrics_SetBuildInfo verifies gateway_build_info keeps a single series
+// labelled with the latest build and feature set.
+func TestMetrics_SetBuildInfo(t *testing.T) {
+	original, originalVersion := buildInfo, version
+	defer func() { buildInfo, version = original, originalVersion }()
+	reg := prometheus.NewRegistry()
+	m := newMetricsWithRegistry(reg, Config{})
+
+	m.SetBuildInfo(BuildInfo{Version: "v1.0.0", Commit: "aaa"})
+	m.SetBuildInfo(BuildInfo{Version: "v1.1.0", Commit: "bbb", Features: BuildFeatures{Chunked: true, KMSProvider: "vault"}})
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error: %v", err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != "gateway_build_info" {
+			continue
+		}
+		if len(mf.GetMetric()) != 1 {
+			t.Fatalf("gateway_build_info has %d series, want 1", len(mf.GetMetric()))
+		}
+		labels := map[string]string{}
+		for _, lp := range mf.GetMetric()[0].GetLabel() {
+			labels[lp.GetName()] = lp.GetValue()
+		}
+		if labels["version"] != "v1.1.0" || labels["chunked"] != "true" || labels["kms_provider"] != "vault" {
+			t.Errorf("labels = %v", labels)
+		}
+		return
+	}
+	t.Error("gateway_build_info metric not found after SetBuildInfo")
+}
+
 // TestMetrics_GetHardwareAccelerationEnabledMetric verifies the getter.
 func TestMetrics_GetHardwareAccelerationEnabledMetric(t *testing.T) {
 	reg := prometheus.NewRegistry()
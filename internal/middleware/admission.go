@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/admission"
+	"github.com/sirupsen/logrus"
+)
+
+// subresourceParams mark object requests that read or write a small
+// sub-resource rather than the object body.
+var subresourceParams = []string{"tagging", "acl", "retention", "legal-hold", "attributes"}
+
+// AdmissionMiddleware takes an admission slot for every request while it
+// is served. Requests the controller sheds are answered with 503 SlowDown
+// and a Retry-After of retryAfter, rounded up to whole seconds. Probe and
+// scrape requests are never queued. largeRequestSize is the body size from
+// which writes are admitted last. Requests isPriority accepts bypass
+// queueing altogether; isPriority may be nil.
+func AdmissionMiddleware(ctrl *admission.Controller, retryAfter time.Duration, largeRequestSize int64, isPriority func(*http.Request) bool, logger *logrus.Logger) func(http.Handler) http.Handler {
+	retrySeconds := int((retryAfter + time.Second - 1) / time.Second)
+	if retrySeconds < 1 {
+		retrySeconds = 1
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if untrackedPaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			class := admission.ClassPriority
+			if isPriority == nil || !isPriority(r) {
+				class = classifyRequest(r, largeRequestSize)
+			}
+			if err := ctrl.Acquire(r.Context(), class); err != nil {
+				if errors.Is(err, admission.ErrOverloaded) {
+					logger.WithFields(logrus.Fields{
+						"method": r.Method,
+						"path":   r.URL.Path,
+						"class":  class.String(),
+					}).Warn("Gateway overloaded; shedding request")
+					writeSlowDownError(w, r.URL.Path, retrySeconds)
+				}
+				// Otherwise the client has gone away; nobody reads a reply.
+				return
+			}
+			defer ctrl.Release(class)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// PriorityMatcher returns a predicate accepting requests for one of buckets
+// or signed with one of accessKeys, or nil when both are empty. accessKey
+// reports the access key a request was signed with, or "" when unsigned.
+func PriorityMatcher(buckets, accessKeys []string, accessKey func(*http.Request) string) func(*http.Request) bool {
+	if len(buckets) == 0 && len(accessKeys) == 0 {
+		return nil
+	}
+	bucketSet := make(map[string]bool, len(buckets))
+	for _, b := range buckets {
+		bucketSet[b] = true
+	}
+	keySet := make(map[string]bool, len(accessKeys))
+	for _, k := range accessKeys {
+		keySet[k] = true
+	}
+	return func(r *http.Request) bool {
+		if bucket, _ := extractBucketAndKey(r.URL.Path); bucketSet[bucket] {
+			return true
+		}
+		if len(keySet) == 0 || accessKey == nil {
+			return false
+		}
+		k := accessKey(r)
+		return k != "" && keySet[k]
+	}
+}
+
+// classifyRequest assigns r its admission priority.
+func classifyRequest(r *http.Request, largeRequestSize int64) admission.Class {
+	if _, key := extractBucketAndKey(r.URL.Path); key == "" {
+		return admission.ClassMetadata
+	}
+	switch r.Method {
+	case http.MethodHead, http.MethodDelete:
+		return admission.ClassMetadata
+	}
+	q := r.URL.Query()
+	for _, p := range subresourceParams {
+		if q.Has(p) {
+			return admission.ClassMetadata
+		}
+	}
+	if (r.Method == http.MethodPut || r.Method == http.MethodPost) &&
+		(r.ContentLength < 0 || r.ContentLength >= largeRequestSize) {
+		return admission.ClassBulk
+	}
+	return admission.ClassNormal
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/admission"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestClassifyRequest(t *testing.T) {
+	large := strings.Repeat("x", 64)
+	for _, tc := range []struct {
+		method, target, body string
+		want                 admission.Class
+	}{
+		{"GET", "/bucket", "", admission.ClassMetadata},
+		{"HEAD", "/bucket/key", "", admission.ClassMetadata},
+		{"DELETE", "/bucket/key", "", admission.ClassMetadata},
+		{"PUT", "/bucket/key?tagging", "<Tagging/>", admission.ClassMetadata},
+		{"GET", "/bucket/key", "", admission.ClassNormal},
+		{"PUT", "/bucket/key", "small", admission.ClassNormal},
+		{"PUT", "/bucket/key", large, admission.ClassBulk},
+		{"PUT", "/bucket/key?partNumber=1&uploadId=u", large, admission.ClassBulk},
+	} {
+		r := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+		if got := classifyRequest(r, 64); got != tc.want {
+			t.Errorf("%s %s (%d bytes) = %s, want %s", tc.method, tc.target, len(tc.body), got, tc.want)
+		}
+	}
+
+	unsized := httptest.NewRequest("PUT", "/bucket/key", io.NopCloser(bytes.NewReader(nil)))
+	unsized.ContentLength = -1
+	if got := classifyRequest(unsized, 64); got != admission.ClassBulk {
+		t.Errorf("PUT without Content-Length = %s, want bulk", got)
+	}
+}
+
+func TestAdmissionMiddleware(t *testing.T) {
+	ctrl := admission.New(config.AdmissionConfig{Enabled: true, MaxInFlight: 1, QueueSize: 1}, nil)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	mw := AdmissionMiddleware(ctrl, 1500*time.Millisecond, 1<<20, nil, logger)
+
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bucket/busy" {
+			// The only slot is taken: object requests are shed, probes
+			// still get through.
+			rec := httptest.NewRecorder()
+			mw(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest("GET", "/bucket/other", nil))
+			if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "<Code>SlowDown</Code>") {
+				t.Errorf("request while saturated = %d %s", rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Retry-After"); got != "2" {
+				t.Errorf("Retry-After = %q, want 2", got)
+			}
+			rec = httptest.NewRecorder()
+			mw(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })).ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("probe while saturated = %d", rec.Code)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, target := range []string{"/bucket/busy", "/bucket/key"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status %d", target, rec.Code)
+		}
+	}
+	if st := ctrl.Stats(); st.InFlight != 0 {
+		t.Errorf("in flight after requests = %d", st.InFlight)
+	}
+}
+
+func TestPriorityMatcher(t *testing.T) {
+	if PriorityMatcher(nil, nil, nil) != nil {
+		t.Error("matcher without buckets or keys is not nil")
+	}
+	match := PriorityMatcher([]string{"critical"}, []string{"AKPRIO"}, func(r *http.Request) string {
+		return r.Header.Get("X-Test-Key")
+	})
+	for _, tc := range []struct {
+		target, key string
+		want        bool
+	}{
+		{"/critical/key", "", true},
+		{"/critical", "", true},
+		{"/other/key", "AKPRIO", true},
+		{"/other/key", "AKOTHER", false},
+		{"/other/key", "", false},
+	} {
+		r := httptest.NewRequest("GET", tc.target, nil)
+		r.Header.Set("X-Test-Key", tc.key)
+		if got := match(r); got != tc.want {
+			t.Errorf("%s signed by %q = %v, want %v", tc.target, tc.key, got, tc.want)
+		}
+	}
+}
+
+func TestAdmissionMiddleware_PriorityBypass(t *testing.T) {
+	ctrl := admission.New(config.AdmissionConfig{Enabled: true, MaxInFlight: 1, QueueSize: 1}, nil)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	mw := AdmissionMiddleware(ctrl, time.Second, 1<<20, PriorityMatcher([]string{"critical"}, nil, nil), logger)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		mw(ok).ServeHTTP(rec, httptest.NewRequest("GET", "/critical/key", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("priority request while saturated = %d", rec.Code)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/bucket/key", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status %d", rec.Code)
+	}
+	if st := ctrl.Stats(); st.InFlight != 0 {
+		t.Errorf("in flight after requests = %d", st.InFlight)
+	}
+}
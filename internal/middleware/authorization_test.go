@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/authz"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/identity"
+)
+
+func TestAuthorizationMiddleware(t *testing.T) {
+	policy := authz.New(config.AuthPolicyConfig{
+		DefaultDeny: true,
+		Rules: []config.AuthPolicyRule{
+			{Effect: config.AuthPolicyAllow, Identities: []string{"reader"}, Buckets: []string{"data"}, Prefixes: []string{"public/"}, Operations: []string{"GetObject", "HeadObject", "ListObjects"}},
+			{Effect: config.AuthPolicyAllow, Identities: []string{"reader"}, Buckets: []string{"scratch"}},
+			{Effect: config.AuthPolicyAllow, Identities: []string{"reader"}, Operations: []string{"DeleteObject"}},
+		},
+	})
+	reader := identity.Identity{AccessKey: "AKIAREADER", Label: "reader"}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		copySource string
+		id         *identity.Identity
+		wantStatus int
+	}{
+		{"allowed object", http.MethodGet, "/data/public/a.txt", "", &reader, http.StatusOK},
+		{"denied object", http.MethodGet, "/data/private/a.txt", "", &reader, http.StatusForbidden},
+		{"denied operation", http.MethodPut, "/data/public/a.txt", "", &reader, http.StatusForbidden},
+		{"listing within prefix", http.MethodGet, "/data?list-type=2&prefix=public/", "", &reader, http.StatusOK},
+		{"listing whole bucket", http.MethodGet, "/data?list-type=2", "", &reader, http.StatusForbidden},
+		{"copy from readable source", http.MethodPut, "/scratch/copy", "data/public/a.txt", &reader, http.StatusOK},
+		{"copy from unreadable source", http.MethodPut, "/scratch/copy", "/data/private/a.txt", &reader, http.StatusForbidden},
+		{"copy source decoded", http.MethodPut, "/scratch/copy", "data/public/a%20b.txt?versionId=3", &reader, http.StatusOK},
+		{"copy source encoded private", http.MethodPut, "/scratch/copy", "data/%70rivate/a.txt", &reader, http.StatusForbidden},
+		{"batch delete left to handler", http.MethodPost, "/data?delete", "", &reader, http.StatusOK},
+		{"unauthenticated probe", http.MethodGet, "/health", "", nil, http.StatusOK},
+		{"no identity", http.MethodGet, "/data/private/a.txt", "", nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &okHandler{}
+			mw := AuthorizationMiddleware(policy, silentLogger())(next)
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.copySource != "" {
+				req.Header.Set("X-Amz-Copy-Source", tt.copySource)
+			}
+			if tt.id != nil {
+				req = req.WithContext(identity.NewContext(req.Context(), *tt.id))
+			}
+			w := httptest.NewRecorder()
+			mw.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if next.called != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("next called = %v", next.called)
+			}
+			if tt.wantStatus == http.StatusForbidden && !strings.Contains(w.Body.String(), "<Code>AccessDenied</Code>") {
+				t.Errorf("body = %s, want AccessDenied error document", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthorizationMiddleware_NilPolicy(t *testing.T) {
+	next := &okHandler{}
+	mw := AuthorizationMiddleware(nil, silentLogger())(next)
+	if mw != http.Handler(next) {
+		t.Error("nil policy should not wrap the handler")
+	}
+}
+
+// TestAuthPolicyOperations_CoverS3Operation checks that every operation the
+// middleware can evaluate is one auth.policy rules may name.
+func TestAuthPolicyOperations_CoverS3Operation(t *testing.T) {
+	requests := []struct{ method, target string }{
+		{"GET", "/"}, {"POST", "/"},
+		{"GET", "/b"}, {"GET", "/b?uploads"}, {"GET", "/b?tagging"}, {"HEAD", "/b"},
+		{"PUT", "/b"}, {"PUT", "/b?tagging"}, {"DELETE", "/b"}, {"DELETE", "/b?tagging"}, {"POST", "/b?x"},
+		{"GET", "/b/k"}, {"GET", "/b/k?uploadId=1"}, {"GET", "/b/k?tagging"}, {"HEAD", "/b/k"},
+		{"PUT", "/b/k"}, {"PUT", "/b/k?uploadId=1"}, {"PUT", "/b/k?tagging"},
+		{"DELETE", "/b/k"}, {"DELETE", "/b/k?uploadId=1"}, {"DELETE", "/b/k?tagging"},
+		{"POST", "/b/k?uploads"}, {"POST", "/b/k?uploadId=1"}, {"POST", "/b/k?restore"},
+	}
+	for _, rq := range requests {
+		op := s3Operation(httptest.NewRequest(rq.method, rq.target, nil))
+		if !slices.Contains(config.AuthPolicyOperations, op) {
+			t.Errorf("%s %s: operation %q missing from config.AuthPolicyOperations", rq.method, rq.target, op)
+		}
+	}
+	for _, h := range []string{"", "src/k"} {
+		for _, target := range []string{"/b/k", "/b/k?uploadId=1"} {
+			req := httptest.NewRequest("PUT", target, nil)
+			if h != "" {
+				req.Header.Set("X-Amz-Copy-Source", h)
+			}
+			if op := s3Operation(req); !slices.Contains(config.AuthPolicyOperations, op) {
+				t.Errorf("PUT %s copy=%q: operation %q missing", target, h, op)
+			}
+		}
+	}
+}
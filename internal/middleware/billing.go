@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+)
+
+// BillingRecorder meters the traffic of a bucket; *billing.Exporter
+// implements it.
+type BillingRecorder interface {
+	Record(bucket string, bytesIn, bytesOut int64)
+}
+
+// BillingMiddleware meters every S3 request to a bucket with the request
+// and response body bytes that crossed the wire.
+func BillingMiddleware(recorder BillingRecorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if untrackedPaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			bucket, _ := extractBucketAndKey(r.URL.Path)
+			if bucket == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body := &countingReader{ReadCloser: r.Body}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = body
+			}
+			rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(rw, r)
+			recorder.Record(bucket, body.n, rw.bytesWritten)
+		})
+	}
+}
+
+// countingReader counts the bytes read from a request body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
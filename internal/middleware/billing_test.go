@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type billingCall struct {
+	bucket            string
+	bytesIn, bytesOut int64
+}
+
+type fakeBillingRecorder struct {
+	calls []billingCall
+}
+
+func (f *fakeBillingRecorder) Record(bucket string, bytesIn, bytesOut int64) {
+	f.calls = append(f.calls, billingCall{bucket, bytesIn, bytesOut})
+}
+
+func TestBillingMiddleware(t *testing.T) {
+	recorder := &fakeBillingRecorder{}
+	handler := BillingMiddleware(recorder)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		_, _ = w.Write([]byte("response"))
+	}))
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("PUT", "/bucket/key", strings.NewReader("twelve bytes")),
+		httptest.NewRequest("GET", "/bucket/key", nil),
+		httptest.NewRequest("GET", "/", nil),
+		httptest.NewRequest("GET", "/health", nil),
+	} {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	want := []billingCall{{"bucket", 12, 8}, {"bucket", 0, 8}}
+	if len(recorder.calls) != len(want) {
+		t.Fatalf("recorded %v, want %v", recorder.calls, want)
+	}
+	for i := range want {
+		if recorder.calls[i] != want[i] {
+			t.Errorf("call %d = %v, want %v", i, recorder.calls[i], want[i])
+		}
+	}
+}
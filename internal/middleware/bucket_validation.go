@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"encoding/xml"
 	"net/http"
 	"strings"
 
@@ -23,7 +22,8 @@ func BucketValidationMiddleware(proxiedBucket string, logger *logrus.Logger) fun
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Allow health check and metrics endpoints
 			path := r.URL.Path
-			if path == "/health" || path == "/ready" || path == "/live" || path == "/metrics" || strings.HasPrefix(path, "/metrics") {
+			if path == "/health" || path == "/ready" || path == "/live" || path == "/metrics" || strings.HasPrefix(path, "/metrics") ||
+				(r.Method == http.MethodGet && (path == "/ready/kms" || path == "/ready/backend" || path == "/ready/config")) {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -90,23 +90,6 @@ func BucketValidationMiddleware(proxiedBucket string, logger *logrus.Logger) fun
 
 // writeBucketAccessDeniedError writes an S3-compatible AccessDenied error response.
 func writeBucketAccessDeniedError(w http.ResponseWriter, bucket, resource string) {
-	type S3Error struct {
-		XMLName    xml.Name `xml:"Error"`
-		Code       string   `xml:"Code"`
-		Message    string   `xml:"Message"`
-		Resource   string   `xml:"Resource"`
-		HTTPStatus int
-	}
-
-	s3Err := S3Error{
-		Code:       "AccessDenied",
-		Message:    "Access Denied. This gateway is configured to proxy a single bucket only.",
-		Resource:   resource,
-		HTTPStatus: http.StatusForbidden,
-	}
-
-	w.Header().Set("Content-Type", "application/xml")
-	w.WriteHeader(s3Err.HTTPStatus)
-	xml.NewEncoder(w).Encode(s3Err)
+	writeS3Error(w, http.StatusForbidden, "AccessDenied", "Access Denied. This gateway is configured to proxy a single bucket only.", resource)
 }
 
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strconv"
+)
+
+// errorResponse is the S3 <Error> document, as the api package writes it
+// for handler errors.
+type errorResponse struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	Resource  string   `xml:"Resource,omitempty"`
+	RequestID string   `xml:"RequestId,omitempty"`
+}
+
+// writeS3Error answers a request refused before it reached the handlers
+// with an S3 error document, so SDK clients parse the code instead of
+// choking on plain text. RequestId is the ID RequestIDMiddleware put in the
+// response headers, if it ran.
+func writeS3Error(w http.ResponseWriter, status int, code, message, resource string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(errorResponse{
+		Code:      code,
+		Message:   message,
+		Resource:  resource,
+		RequestID: w.Header().Get(RequestIDHeader),
+	})
+}
+
+// writeSlowDownError writes an S3-compatible SlowDown error response asking
+// the client to retry after retryAfter seconds.
+func writeSlowDownError(w http.ResponseWriter, resource string, retryAfter int) {
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	writeS3Error(w, http.StatusServiceUnavailable, "SlowDown", "Please reduce your request rate.", resource)
+}
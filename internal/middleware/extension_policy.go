@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/identity"
+	"github.com/kenneth/s3-encryption-gateway/internal/sandbox"
+)
+
+// ExtensionPolicyMiddleware asks the matching policy extension modules
+// about every authenticated S3 request, in order. A request is refused with
+// AccessDenied when a module denies it or fails: policy modules never fail
+// open. Like AuthorizationMiddleware it must run inside the auth
+// middleware; requests without an identity pass through.
+func ExtensionPolicyMiddleware(hooks []*sandbox.Hook, logger *logrus.Logger) func(http.Handler) http.Handler {
+	if len(hooks) == 0 {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, ok := identity.FromContext(r.Context())
+			operation := s3Operation(r)
+			if !ok || operation == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			bucket, key := extractBucketAndKey(r.URL.Path)
+			req := sandbox.PolicyRequest{Operation: operation, Bucket: bucket, Key: key, Principal: id.AccessKey}
+			for k, v := range r.Header {
+				if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-meta-") && len(v) > 0 {
+					if req.Metadata == nil {
+						req.Metadata = make(map[string]string)
+					}
+					req.Metadata[lk] = v[0]
+				}
+			}
+
+			for _, hook := range hooks {
+				if !hook.Matches(bucket, key) {
+					continue
+				}
+				decision, err := sandbox.Decide(r.Context(), hook.Module, req)
+				if err == nil && decision.Allow {
+					continue
+				}
+				entry := logger.WithFields(logrus.Fields{
+					"access_key": id.AccessKey,
+					"operation":  operation,
+					"bucket":     bucket,
+					"key":        key,
+					"module":     hook.Module.Name(),
+				})
+				if err != nil {
+					entry.WithError(err).Error("Policy extension failed; denying request")
+				} else {
+					entry.WithField("reason", decision.Reason).Warn("Access denied by policy extension")
+				}
+				writeS3Error(w, http.StatusForbidden, "AccessDenied", "Access Denied", r.URL.Path)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/identity"
+	"github.com/kenneth/s3-encryption-gateway/internal/sandbox"
+)
+
+// policyModule allows requests whose key is not "secret" and fails on
+// "broken".
+type policyModule struct{ calls int }
+
+func (m *policyModule) Name() string                { return "policy" }
+func (m *policyModule) Exports(fn string) bool      { return fn == sandbox.ExportPolicy }
+func (m *policyModule) Close(context.Context) error { return nil }
+
+func (m *policyModule) Call(_ context.Context, _ string, input []byte) ([]byte, error) {
+	m.calls++
+	var req sandbox.PolicyRequest
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, err
+	}
+	switch {
+	case req.Key == "broken":
+		return nil, errors.New("trap")
+	case req.Key == "secret" || req.Metadata["x-amz-meta-class"] == "restricted":
+		return []byte(`{"allow":false,"reason":"restricted"}`), nil
+	}
+	return []byte(`{"allow":true}`), nil
+}
+
+func TestExtensionPolicyMiddleware(t *testing.T) {
+	mod := &policyModule{}
+	hooks := []*sandbox.Hook{{Module: mod, Bucket: "data"}}
+	id := identity.Identity{AccessKey: "AKIAREADER"}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		meta       string
+		id         *identity.Identity
+		wantStatus int
+	}{
+		{"allowed", http.MethodGet, "/data/a.txt", "", &id, http.StatusOK},
+		{"denied", http.MethodGet, "/data/secret", "", &id, http.StatusForbidden},
+		{"denied on metadata", http.MethodPut, "/data/a.txt", "restricted", &id, http.StatusForbidden},
+		{"module failure denies", http.MethodGet, "/data/broken", "", &id, http.StatusForbidden},
+		{"other bucket", http.MethodGet, "/logs/secret", "", &id, http.StatusOK},
+		{"no identity", http.MethodGet, "/data/secret", "", nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := ExtensionPolicyMiddleware(hooks, silentLogger())(&okHandler{})
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.meta != "" {
+				req.Header.Set("X-Amz-Meta-Class", tt.meta)
+			}
+			if tt.id != nil {
+				req = req.WithContext(identity.NewContext(req.Context(), *tt.id))
+			}
+			w := httptest.NewRecorder()
+			mw.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+	if mod.calls != 4 {
+		t.Errorf("module called %d times, want 4", mod.calls)
+	}
+}
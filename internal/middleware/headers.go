@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/headerrules"
+)
+
+// HeaderRulesMiddleware applies rules to every response header set just
+// before it is sent. A nil rules value returns next unchanged.
+func HeaderRulesMiddleware(rules *headerrules.Rules) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if rules == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&headerRulesWriter{ResponseWriter: w, rules: rules}, r)
+		})
+	}
+}
+
+type headerRulesWriter struct {
+	http.ResponseWriter
+	rules   *headerrules.Rules
+	applied bool
+}
+
+func (w *headerRulesWriter) apply() {
+	if !w.applied {
+		w.applied = true
+		w.rules.Apply(w.Header())
+	}
+}
+
+func (w *headerRulesWriter) WriteHeader(code int) {
+	w.apply()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *headerRulesWriter) Write(b []byte) (int, error) {
+	w.apply()
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *headerRulesWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
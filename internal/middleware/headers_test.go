@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/headerrules"
+)
+
+func TestHeaderRulesMiddleware(t *testing.T) {
+	rules := headerrules.New([]config.HeaderRule{
+		{Action: config.HeaderRuleRemove, Name: "Server"},
+		{Action: config.HeaderRuleRename, Name: "x-amz-meta-legacy", To: "x-amz-meta-current"},
+		{Action: config.HeaderRuleAdd, Name: "Vary", Value: "Origin"},
+	}, config.ProtectedResponseHeaders...)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "gateway")
+		w.Header().Set("X-Amz-Meta-Legacy", "v")
+		w.Header().Set("Vary", "Accept-Encoding")
+		w.Write([]byte("ok"))
+	})
+	w := httptest.NewRecorder()
+	HeaderRulesMiddleware(rules)(next).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	h := w.Result().Header
+	if h.Get("Server") != "" || h.Get("X-Amz-Meta-Legacy") != "" || h.Get("X-Amz-Meta-Current") != "v" {
+		t.Errorf("headers = %v", h)
+	}
+	if len(h.Values("Vary")) != 2 {
+		t.Errorf("Vary = %v, want both values", h.Values("Vary"))
+	}
+
+	if HeaderRulesMiddleware(nil)(next) == nil {
+		t.Error("nil rules should pass the handler through")
+	}
+}
@@ -10,6 +10,9 @@ import (
 	"time"
 
 	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/identity"
+	"github.com/kenneth/s3-encryption-gateway/internal/logsample"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
 	"github.com/sirupsen/logrus"
 )
 
@@ -46,6 +49,13 @@ func redactQueryString(rawQuery string) string {
 
 // LoggingMiddleware wraps handlers with request logging.
 func LoggingMiddleware(logger *logrus.Logger, cfg *config.LoggingConfig) func(http.Handler) http.Handler {
+	return SampledLoggingMiddleware(logger, cfg, nil)
+}
+
+// SampledLoggingMiddleware is LoggingMiddleware with the access log thinned
+// by sampler's "access" rule. Requests answered with a 4xx or 5xx status
+// are always logged.
+func SampledLoggingMiddleware(logger *logrus.Logger, cfg *config.LoggingConfig, sampler *logsample.Sampler) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -66,6 +76,10 @@ func LoggingMiddleware(logger *logrus.Logger, cfg *config.LoggingConfig) func(ht
 				statusCode:     http.StatusOK,
 			}
 
+			// Collect the backend's request IDs for the log entry.
+			ctx, backends := s3.WithRequestIDRecorder(r.Context())
+			r = r.WithContext(ctx)
+
 			next.ServeHTTP(rw, r)
 
 			duration := time.Since(start)
@@ -76,17 +90,32 @@ func LoggingMiddleware(logger *logrus.Logger, cfg *config.LoggingConfig) func(ht
 				bytesLogged = requestBytes
 			}
 
+			var out logrus.FieldLogger = logger
+			if rw.statusCode < http.StatusBadRequest {
+				keep, rate := sampler.Sample(logsample.AccessLogger, logrus.InfoLevel)
+				if !keep {
+					return
+				}
+				if rate > 1 {
+					out = logger.WithField(logsample.RateField, rate)
+				}
+			}
+
 			// Create log entry with redaction
 			logEntry := createLogEntry(r, rw, duration, bytesLogged, cfg)
+			if id, n := backends.Last(); n > 0 {
+				logEntry.BackendRequestID = id.RequestID
+				logEntry.BackendHostID = id.HostID
+			}
 
 			// Log based on configured format
 			switch cfg.AccessLogFormat {
 			case "json":
-				logJSON(logger, logEntry)
+				logJSON(out, logEntry)
 			case "clf":
-				logCLF(logger, logEntry)
+				logCLF(out, logEntry)
 			default:
-				logDefault(logger, logEntry)
+				logDefault(out, logEntry)
 			}
 		})
 	}
@@ -110,6 +139,11 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // LogEntry represents a structured log entry.
 type LogEntry struct {
 	Timestamp  string            `json:"timestamp"`
@@ -122,6 +156,12 @@ type LogEntry struct {
 	DurationMs int64             `json:"duration_ms"`
 	Bytes      int64             `json:"bytes"`
 	Headers    map[string]string `json:"headers,omitempty"`
+	// BackendRequestID and BackendHostID identify the last backend
+	// response to the request.
+	BackendRequestID string `json:"backend_request_id,omitempty"`
+	BackendHostID    string `json:"backend_host_id,omitempty"`
+	// AccessKey is the access key the request was authenticated with.
+	AccessKey string `json:"access_key,omitempty"`
 }
 
 // createLogEntry creates a log entry with header redaction.
@@ -137,6 +177,9 @@ func createLogEntry(r *http.Request, rw *responseWriter, duration time.Duration,
 		DurationMs: duration.Milliseconds(),
 		Bytes:      bytesLogged,
 	}
+	if id, ok := identity.FromContext(r.Context()); ok {
+		entry.AccessKey = id.AccessKey
+	}
 
 	// Add redacted headers for structured formats
 	if cfg.AccessLogFormat == "json" {
@@ -167,7 +210,7 @@ func shouldRedactHeader(headerName string, redactHeaders []string) bool {
 }
 
 // logDefault logs in the default structured format (backward compatible).
-func logDefault(logger *logrus.Logger, entry *LogEntry) {
+func logDefault(logger logrus.FieldLogger, entry *LogEntry) {
 	fields := logrus.Fields{
 		"method":      entry.Method,
 		"path":        entry.Path,
@@ -185,14 +228,22 @@ func logDefault(logger *logrus.Logger, entry *LogEntry) {
 		fields["user_agent"] = entry.UserAgent
 	}
 
-	logger.WithFields(fields).Info("HTTP request")
+	if entry.AccessKey != "" {
+		fields["access_key"] = entry.AccessKey
+	}
+
+	for k, v := range backendFields(entry) {
+		fields[k] = v
+	}
+
+	logger.WithFields(fields).Info(logsample.AccessMessage)
 }
 
 // logJSON logs in JSON format.
-func logJSON(logger *logrus.Logger, entry *LogEntry) {
+func logJSON(logger logrus.FieldLogger, entry *LogEntry) {
 	// Use logger to output JSON directly
 	if jsonData, err := json.Marshal(entry); err == nil {
-		logger.WithField("json", string(jsonData)).Info("HTTP request")
+		logger.WithField("json", string(jsonData)).Info(logsample.AccessMessage)
 	} else {
 		// Fallback to default logging on JSON marshal error
 		logDefault(logger, entry)
@@ -200,11 +251,16 @@ func logJSON(logger *logrus.Logger, entry *LogEntry) {
 }
 
 // logCLF logs in Common Log Format (similar to Apache CLF).
-func logCLF(logger *logrus.Logger, entry *LogEntry) {
+func logCLF(logger logrus.FieldLogger, entry *LogEntry) {
 	// CLF format: %h %l %u %t \"%r\" %>s %b
-	// Example: 127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326
-	clf := fmt.Sprintf(`%s - - [%s] "%s %s%s HTTP/1.1" %d %d`,
+	// Example: 127.0.0.1 - AKIAEXAMPLE [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.1" 200 2326
+	user := entry.AccessKey
+	if user == "" {
+		user = "-"
+	}
+	clf := fmt.Sprintf(`%s - %s [%s] "%s %s%s HTTP/1.1" %d %d`,
 		entry.RemoteAddr,
+		user,
 		entry.Timestamp,
 		entry.Method,
 		entry.Path,
@@ -218,5 +274,17 @@ func logCLF(logger *logrus.Logger, entry *LogEntry) {
 		entry.Bytes,
 	)
 
-	logger.WithField("clf", clf).Info("HTTP request")
+	logger.WithField("clf", clf).WithFields(backendFields(entry)).Info(logsample.AccessMessage)
+}
+
+// backendFields returns the backend request IDs of entry as log fields.
+func backendFields(entry *LogEntry) logrus.Fields {
+	fields := logrus.Fields{}
+	if entry.BackendRequestID != "" {
+		fields["backend_request_id"] = entry.BackendRequestID
+	}
+	if entry.BackendHostID != "" {
+		fields["backend_host_id"] = entry.BackendHostID
+	}
+	return fields
 }
@@ -10,6 +10,9 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/identity"
+	"github.com/kenneth/s3-encryption-gateway/internal/logsample"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
 )
 
 func TestLoggingMiddleware(t *testing.T) {
@@ -39,6 +42,83 @@ func TestLoggingMiddleware(t *testing.T) {
 	}
 }
 
+func TestSampledLoggingMiddleware(t *testing.T) {
+	var buf strings.Builder
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	sampler := logsample.New(map[string]config.LogSamplingRule{logsample.AccessLogger: {Every: 3}})
+	status := http.StatusOK
+	wrapped := SampledLoggingMiddleware(logger, &config.LoggingConfig{}, sampler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	for i := 0; i < 6; i++ {
+		wrapped.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/bucket/key", nil))
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 2 {
+		t.Errorf("logged %d of 6 successful requests, want 2", n)
+	}
+	if !strings.Contains(buf.String(), `"sample_rate":3`) {
+		t.Errorf("kept entry without sample_rate: %s", buf.String())
+	}
+
+	// Failed requests are never sampled.
+	buf.Reset()
+	status = http.StatusNotFound
+	for i := 0; i < 3; i++ {
+		wrapped.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/bucket/key", nil))
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 3 {
+		t.Errorf("logged %d of 3 failed requests", n)
+	}
+}
+
+func TestLoggingMiddleware_BackendRequestID(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s3.RequestIDRecorderFromContext(r.Context()).Record(s3.BackendRequestID{RequestID: "REQ1", HostID: "HOST1"})
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	for _, format := range []string{"default", "json", "clf"} {
+		t.Run(format, func(t *testing.T) {
+			var buf strings.Builder
+			logger := logrus.New()
+			logger.SetOutput(&buf)
+			logger.SetFormatter(&logrus.JSONFormatter{})
+			LoggingMiddleware(logger, &config.LoggingConfig{AccessLogFormat: format})(handler).
+				ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/bucket/key", nil))
+			out := strings.ReplaceAll(buf.String(), `\"`, `"`)
+			if !strings.Contains(out, `"backend_request_id":"REQ1"`) || !strings.Contains(out, `"backend_host_id":"HOST1"`) {
+				t.Errorf("log lacks backend IDs: %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestLoggingMiddleware_AccessKey(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for format, want := range map[string]string{
+		"default": `"access_key":"AKIAALICE"`,
+		"json":    `"access_key":"AKIAALICE"`,
+		"clf":     `10.0.0.1:1234 - AKIAALICE [`,
+	} {
+		t.Run(format, func(t *testing.T) {
+			var buf strings.Builder
+			logger := logrus.New()
+			logger.SetOutput(&buf)
+			logger.SetFormatter(&logrus.JSONFormatter{})
+			req := httptest.NewRequest("GET", "/bucket/key", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			req = req.WithContext(identity.NewContext(req.Context(), identity.Identity{AccessKey: "AKIAALICE", Label: "alice"}))
+			LoggingMiddleware(logger, &config.LoggingConfig{AccessLogFormat: format})(handler).
+				ServeHTTP(httptest.NewRecorder(), req)
+			if out := strings.ReplaceAll(buf.String(), `\"`, `"`); !strings.Contains(out, want) {
+				t.Errorf("log lacks %s: %s", want, buf.String())
+			}
+		})
+	}
+}
+
 func TestResponseWriter(t *testing.T) {
 	w := httptest.NewRecorder()
 	rw := &responseWriter{
@@ -63,6 +143,23 @@ func TestResponseWriter(t *testing.T) {
 	}
 }
 
+func TestResponseWriters_PassFlushesThrough(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("part"))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush: %v", err)
+		}
+	})
+	wrapped := TracingMiddleware(false, nil)(LoggingMiddleware(silentLogger(), &config.LoggingConfig{})(handler))
+
+	// The recorder only sees the flush if every wrapper unwraps to it.
+	w := httptest.NewRecorder()
+	wrapped.ServeHTTP(w, httptest.NewRequest("GET", "/bucket/key", nil))
+	if !w.Flushed {
+		t.Error("flush did not reach the underlying writer")
+	}
+}
+
 func TestLoggingFormats(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/membudget"
+	"github.com/sirupsen/logrus"
+)
+
+// MemoryBudgetMiddleware reserves reserve bytes of budget for every object
+// request while it is served, covering the chunk buffers its encryption or
+// decryption pipeline draws from the buffer pool. A request that cannot get
+// its reservation within the budget's wait is answered with 503 SlowDown so
+// S3 clients back off and retry. Probe, scrape and bucket-level requests
+// are not charged.
+func MemoryBudgetMiddleware(budget *membudget.Budget, reserve int64, logger *logrus.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, key := extractBucketAndKey(r.URL.Path); key == "" || untrackedPaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err := budget.Acquire(r.Context(), membudget.SubsystemRequest, reserve); err != nil {
+				logger.WithError(err).WithFields(logrus.Fields{
+					"method": r.Method,
+					"path":   r.URL.Path,
+				}).Warn("Memory budget exhausted; refusing request")
+				writeSlowDownError(w, r.URL.Path, 1)
+				return
+			}
+			defer budget.Release(membudget.SubsystemRequest, reserve)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
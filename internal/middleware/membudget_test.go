@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/membudget"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMemoryBudgetMiddleware(t *testing.T) {
+	budget := membudget.New(config.MemoryBudgetConfig{Enabled: true, Limit: 1000}, nil)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	var held int64
+	handler := MemoryBudgetMiddleware(budget, 600, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		held = budget.Stats().BySystem[membudget.SubsystemRequest]
+		if r.URL.Path == "/bucket/nested" {
+			// A second object request while this one is in flight does
+			// not fit.
+			rec := httptest.NewRecorder()
+			MemoryBudgetMiddleware(budget, 600, logger)(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest("GET", "/bucket/other", nil))
+			if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "<Code>SlowDown</Code>") {
+				t.Errorf("nested request = %d %s", rec.Code, rec.Body.String())
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tc := range []struct {
+		target string
+		want   int64
+	}{
+		{"/bucket/key", 600},
+		{"/bucket/nested", 600},
+		{"/bucket", 0},
+		{"/health", 0},
+		{"/ready/kms", 0},
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", tc.target, nil))
+		if rec.Code != http.StatusOK || held != tc.want {
+			t.Errorf("%s: status %d, held %d, want %d", tc.target, rec.Code, held, tc.want)
+		}
+	}
+	if st := budget.Stats(); st.Reserved != 0 {
+		t.Errorf("reserved after requests = %d", st.Reserved)
+	}
+}
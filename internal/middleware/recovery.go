@@ -13,6 +13,11 @@ func RecoveryMiddleware(logger *logrus.Logger) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if err := recover(); err != nil {
+					// A deliberate abort of a response already under way:
+					// let net/http close the connection.
+					if err == http.ErrAbortHandler {
+						panic(err)
+					}
 					logger.WithFields(logrus.Fields{
 						"error":   err,
 						"method":  r.Method,
@@ -20,7 +25,7 @@ func RecoveryMiddleware(logger *logrus.Logger) func(http.Handler) http.Handler {
 						"stack":   string(debug.Stack()),
 					}).Error("Panic recovered")
 
-					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					writeS3Error(w, http.StatusInternalServerError, "InternalError", "We encountered an internal error. Please try again.", r.URL.Path)
 				}
 			}()
 
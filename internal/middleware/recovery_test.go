@@ -3,6 +3,7 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/sirupsen/logrus"
@@ -70,8 +71,8 @@ func TestRecoveryMiddleware(t *testing.T) {
 			if tt.expectPanic {
 				// Check that error message was written
 				body := w.Body.String()
-				if body != "Internal Server Error\n" {
-					t.Errorf("expected error message, got %q", body)
+				if !strings.Contains(body, "<Code>InternalError</Code>") || w.Header().Get("Content-Type") != "application/xml" {
+					t.Errorf("expected an InternalError document, got %q", body)
 				}
 			}
 		})
@@ -139,7 +140,21 @@ func TestRecoveryMiddleware_OuterMiddlewarePanic(t *testing.T) {
 	}
 
 	body := w.Body.String()
-	if body != "Internal Server Error\n" {
-		t.Errorf("expected error message, got %q", body)
+	if !strings.Contains(body, "<Code>InternalError</Code>") {
+		t.Errorf("expected an InternalError document, got %q", body)
 	}
 }
+
+func TestRecoveryMiddleware_PropagatesAbort(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	h := RecoveryMiddleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if recover() != http.ErrAbortHandler {
+			t.Error("http.ErrAbortHandler should reach net/http")
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
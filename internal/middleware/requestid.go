@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// RequestIDHeader is the response header carrying the ID the gateway
+// assigned to a request, as S3 returns its own.
+const RequestIDHeader = "x-amz-request-id"
+
+type requestIDKey struct{}
+
+// RequestIDMiddleware gives every request an ID in the S3 format (16
+// upper-case hex digits), returned in the x-amz-request-id header and the
+// RequestId of error responses, and available to handlers through
+// RequestIDFromContext.
+func RequestIDMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := newRequestID()
+			w.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+		})
+	}
+}
+
+// RequestIDFromContext returns the ID RequestIDMiddleware assigned to the
+// request, or "" outside it.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return strings.ToUpper(hex.EncodeToString(b[:]))
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	handler := RequestIDMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+		writeS3Error(w, http.StatusForbidden, "AccessDenied", "Access Denied", r.URL.Path)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/bucket/key", nil))
+	id := rec.Header().Get(RequestIDHeader)
+	if !regexp.MustCompile(`^[0-9A-F]{16}$`).MatchString(id) || seen != id {
+		t.Fatalf("request ID %q, handler saw %q", id, seen)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "<RequestId>"+id+"</RequestId>") || !strings.Contains(body, "<Resource>/bucket/key</Resource>") {
+		t.Errorf("error document = %q", body)
+	}
+
+	again := httptest.NewRecorder()
+	handler.ServeHTTP(again, httptest.NewRequest("GET", "/bucket/key", nil))
+	if again.Header().Get(RequestIDHeader) == id {
+		t.Error("request ID reused")
+	}
+	if RequestIDFromContext(httptest.NewRequest("GET", "/", nil).Context()) != "" {
+		t.Error("ID outside the middleware")
+	}
+}
@@ -62,6 +62,7 @@ type RateLimiter struct {
 	window          time.Duration // time window
 	cleanupInterval time.Duration
 	stopCleanup     chan struct{}
+	stopOnce        sync.Once
 	logger          *logrus.Logger
 }
 
@@ -110,9 +111,9 @@ func (rl *RateLimiter) cleanup() {
 	}
 }
 
-// Stop stops the cleanup goroutine.
+// Stop stops the cleanup goroutine. It is safe to call more than once.
 func (rl *RateLimiter) Stop() {
-	close(rl.stopCleanup)
+	rl.stopOnce.Do(func() { close(rl.stopCleanup) })
 }
 
 // Allow checks if a request from the given key should be allowed.
@@ -169,6 +170,12 @@ func (rl *RateLimiter) Allow(key string) bool {
 	return false
 }
 
+// retryAfter is the Retry-After, in whole seconds, given to a throttled
+// client: one window, by which its bucket has refilled.
+func (rl *RateLimiter) retryAfter() int {
+	return max(int((rl.window+time.Second-1)/time.Second), 1)
+}
+
 // getClientKey extracts a key to identify the client (IP address).
 // It uses the same trusted proxy-aware logic as getClientIP to ensure
 // rate limiting cannot be bypassed via X-Forwarded-For spoofing.
@@ -181,6 +188,8 @@ func getClientKey(r *http.Request) string {
 }
 
 // RateLimitMiddleware creates a middleware that enforces rate limiting.
+// Throttled requests are answered with 503 SlowDown, which S3 SDKs back off
+// and retry on.
 func RateLimitMiddleware(limiter *RateLimiter) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -192,7 +201,7 @@ func RateLimitMiddleware(limiter *RateLimiter) func(http.Handler) http.Handler {
 					"path":   r.URL.Path,
 				}).Warn("Rate limit exceeded")
 
-				http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+				writeSlowDownError(w, r.URL.Path, limiter.retryAfter())
 				return
 			}
 
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -173,8 +174,11 @@ func TestRateLimitMiddleware(t *testing.T) {
 	// Third request should be rate limited
 	rr := httptest.NewRecorder()
 	handler.ServeHTTP(rr, req)
-	if rr.Code != http.StatusTooManyRequests {
-		t.Errorf("Expected status %d, got %d", http.StatusTooManyRequests, rr.Code)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "<Code>SlowDown</Code>") || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected a SlowDown document with Retry-After, got %q (headers %v)", rr.Body.String(), rr.Header())
 	}
 }
 
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/slo"
+)
+
+// untrackedPaths are probe and scrape endpoints that must not count against
+// any S3 operation's error budget.
+var untrackedPaths = map[string]bool{
+	"/health":        true,
+	"/healthz":       true,
+	"/ready":         true,
+	"/readyz":        true,
+	"/ready/kms":     true,
+	"/ready/backend": true,
+	"/ready/config":  true,
+	"/live":          true,
+	"/livez":         true,
+	"/metrics":       true,
+}
+
+// SLOMiddleware classifies every S3 request against its operation's
+// objective and records the outcome in tracker.
+func SLOMiddleware(tracker *slo.Tracker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			operation := s3Operation(r)
+			if operation == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(rw, r)
+			tracker.Record(operation, rw.statusCode, time.Since(start))
+		})
+	}
+}
+
+// s3Operation maps a path-style S3 request onto its API operation name.
+// The result set is fixed so it is safe to use as a metric label. An empty
+// string means the request is not tracked.
+func s3Operation(r *http.Request) string {
+	if untrackedPaths[r.URL.Path] {
+		return ""
+	}
+	bucket, key := extractBucketAndKey(r.URL.Path)
+	q := r.URL.Query()
+
+	if bucket == "" {
+		if r.Method == http.MethodGet {
+			return "ListBuckets"
+		}
+		return "Other"
+	}
+
+	if key == "" {
+		switch r.Method {
+		case http.MethodGet:
+			if q.Has("uploads") {
+				return "ListMultipartUploads"
+			}
+			if len(q) > 0 && !q.Has("list-type") && !q.Has("prefix") && !q.Has("delimiter") &&
+				!q.Has("max-keys") && !q.Has("marker") && !q.Has("continuation-token") {
+				return "BucketConfig"
+			}
+			return "ListObjects"
+		case http.MethodHead:
+			return "HeadBucket"
+		case http.MethodPut:
+			if len(q) > 0 {
+				return "BucketConfig"
+			}
+			return "CreateBucket"
+		case http.MethodDelete:
+			if len(q) > 0 {
+				return "BucketConfig"
+			}
+			return "DeleteBucket"
+		case http.MethodPost:
+			if q.Has("delete") {
+				return "DeleteObjects"
+			}
+		}
+		return "Other"
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if q.Has("uploadId") {
+			return "ListParts"
+		}
+		if q.Has("tagging") || q.Has("retention") || q.Has("legal-hold") || q.Has("acl") {
+			return "ObjectConfig"
+		}
+		return "GetObject"
+	case http.MethodHead:
+		return "HeadObject"
+	case http.MethodPut:
+		copySource := r.Header.Get("X-Amz-Copy-Source") != ""
+		switch {
+		case q.Has("uploadId") && copySource:
+			return "UploadPartCopy"
+		case q.Has("uploadId"):
+			return "UploadPart"
+		case copySource:
+			return "CopyObject"
+		case q.Has("tagging") || q.Has("retention") || q.Has("legal-hold") || q.Has("acl"):
+			return "ObjectConfig"
+		}
+		return "PutObject"
+	case http.MethodDelete:
+		if q.Has("uploadId") {
+			return "AbortMultipartUpload"
+		}
+		if q.Has("tagging") {
+			return "ObjectConfig"
+		}
+		return "DeleteObject"
+	case http.MethodPost:
+		if q.Has("uploads") {
+			return "CreateMultipartUpload"
+		}
+		if q.Has("uploadId") {
+			return "CompleteMultipartUpload"
+		}
+	}
+	return "Other"
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/slo"
+)
+
+func TestS3Operation(t *testing.T) {
+	tests := []struct {
+		method     string
+		target     string
+		copySource bool
+		want       string
+	}{
+		{"GET", "/", false, "ListBuckets"},
+		{"GET", "/health", false, ""},
+		{"GET", "/readyz", false, ""},
+		{"GET", "/bucket", false, "ListObjects"},
+		{"GET", "/bucket?list-type=2&prefix=a", false, "ListObjects"},
+		{"GET", "/bucket?versioning", false, "BucketConfig"},
+		{"GET", "/bucket?uploads", false, "ListMultipartUploads"},
+		{"HEAD", "/bucket", false, "HeadBucket"},
+		{"PUT", "/bucket", false, "CreateBucket"},
+		{"POST", "/bucket?delete", false, "DeleteObjects"},
+		{"GET", "/bucket/key", false, "GetObject"},
+		{"GET", "/bucket/key?tagging", false, "ObjectConfig"},
+		{"HEAD", "/bucket/a/b/c", false, "HeadObject"},
+		{"PUT", "/bucket/key", false, "PutObject"},
+		{"PUT", "/bucket/key", true, "CopyObject"},
+		{"PUT", "/bucket/key?partNumber=1&uploadId=x", false, "UploadPart"},
+		{"PUT", "/bucket/key?partNumber=1&uploadId=x", true, "UploadPartCopy"},
+		{"POST", "/bucket/key?uploads", false, "CreateMultipartUpload"},
+		{"POST", "/bucket/key?uploadId=x", false, "CompleteMultipartUpload"},
+		{"DELETE", "/bucket/key?uploadId=x", false, "AbortMultipartUpload"},
+		{"DELETE", "/bucket/key", false, "DeleteObject"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, nil)
+		if tt.copySource {
+			req.Header.Set("X-Amz-Copy-Source", "/src/key")
+		}
+		if got := s3Operation(req); got != tt.want {
+			t.Errorf("s3Operation(%s %s) = %q, want %q", tt.method, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestSLOMiddleware_RecordsOutcome(t *testing.T) {
+	tracker := slo.NewTracker(config.SLOConfig{
+		Windows: config.DefaultSLOWindows(),
+		Default: config.SLOObjective{Target: config.DefaultSLOTarget},
+	}, nil)
+
+	status := http.StatusOK
+	handler := SLOMiddleware(tracker)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/bucket/key", nil))
+	status = http.StatusInternalServerError
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/bucket/key", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+
+	summary := tracker.Summary()
+	if len(summary) != 1 || summary[0].Operation != "GetObject" {
+		t.Fatalf("expected only GetObject to be tracked, got %+v", summary)
+	}
+	w := summary[0].Windows[0]
+	if w.Total != 2 || w.Bad != 1 {
+		t.Errorf("expected 2 total / 1 bad, got %+v", w)
+	}
+}
@@ -219,3 +219,8 @@ func (w *tracingResponseWriter) Write(b []byte) (int, error) {
 	}
 	return w.ResponseWriter.Write(b)
 }
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *tracingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
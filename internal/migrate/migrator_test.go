@@ -94,7 +94,7 @@ func (m *mockS3ForMigrate) ListObjects(ctx context.Context, bucket, prefix strin
 		return s3.ListResult{}, err
 	}
 	var objects []s3.ObjectInfo
-	for key := range m.objects {
+	for key, data := range m.objects {
 		if !hasPrefix(key, bucket+"/") {
 			continue
 		}
@@ -102,7 +102,7 @@ func (m *mockS3ForMigrate) ListObjects(ctx context.Context, bucket, prefix strin
 		if prefix != "" && !hasPrefix(objKey, prefix) {
 			continue
 		}
-		objects = append(objects, s3.ObjectInfo{Key: objKey})
+		objects = append(objects, s3.ObjectInfo{Key: objKey, Size: int64(len(data))})
 	}
 	return s3.ListResult{Objects: objects}, nil
 }
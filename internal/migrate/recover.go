@@ -0,0 +1,32 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+)
+
+// DecryptBody decrypts an object body written with a body header
+// (encryption.body_header) without its S3 metadata, as found in copies on
+// stores that strip user metadata, and writes the plaintext to dst.
+func DecryptBody(ctx context.Context, engine crypto.EncryptionEngine, src io.Reader, dst io.Writer) (int64, error) {
+	meta, err := crypto.ReadBodyHeader(src)
+	if errors.Is(err, crypto.ErrNoBodyHeader) {
+		return 0, fmt.Errorf("%w: the object was not written with encryption.body_header", err)
+	}
+	if err != nil {
+		return 0, err
+	}
+	plaintext, _, err := engine.Decrypt(ctx, src, meta)
+	if err != nil {
+		return 0, fmt.Errorf("decrypt failed: %w", err)
+	}
+	n, err := io.Copy(dst, plaintext)
+	if err != nil {
+		return n, fmt.Errorf("decrypt failed: %w", err)
+	}
+	return n, nil
+}
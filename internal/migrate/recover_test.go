@@ -0,0 +1,97 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+)
+
+func TestDecryptBody(t *testing.T) {
+	ctx := context.Background()
+	password := []byte("recover-password-123")
+	plaintext := bytes.Repeat([]byte("metadata was stripped in transit "), 4000)
+
+	writer, err := crypto.NewEngineWithOpts(password, nil, crypto.WithChunking(true), crypto.WithChunkSize(16*1024), crypto.WithBodyHeader(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, _, err := writer.Encrypt(ctx, bytes.NewReader(plaintext), map[string]string{"Content-Type": "text/plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body bytes.Buffer
+	if _, err := body.ReadFrom(enc); err != nil {
+		t.Fatal(err)
+	}
+
+	// The offline tool runs with the gateway's password but none of its
+	// other settings.
+	reader, err := crypto.NewEngine(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	n, err := DecryptBody(ctx, reader, bytes.NewReader(body.Bytes()), &out)
+	if err != nil {
+		t.Fatalf("DecryptBody() error: %v", err)
+	}
+	if n != int64(len(plaintext)) || !bytes.Equal(out.Bytes(), plaintext) {
+		t.Errorf("DecryptBody() wrote %d bytes, plaintext match %v", n, bytes.Equal(out.Bytes(), plaintext))
+	}
+
+	plain, err := crypto.NewEngineWithOpts(password, nil, crypto.WithChunking(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, _, err = plain.Encrypt(ctx, bytes.NewReader(plaintext), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptBody(ctx, reader, enc, &out); !errors.Is(err, crypto.ErrNoBodyHeader) {
+		t.Errorf("DecryptBody() without a header: error = %v, want ErrNoBodyHeader", err)
+	}
+}
+
+func TestDecryptBody_HybridKey(t *testing.T) {
+	ctx := context.Background()
+	plaintext := bytes.Repeat([]byte("kms retired, archive kept "), 4000)
+
+	hybrid, err := crypto.GenerateHybridKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	km, err := crypto.NewInMemoryKeyManager(bytes.Repeat([]byte{0x07}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer, err := crypto.NewEngineWithOpts([]byte("gateway-password-123"), nil,
+		crypto.WithKeyManager(km), crypto.WithHybridWrapKey(hybrid.Public()),
+		crypto.WithChunking(true), crypto.WithBodyHeader(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, _, err := writer.Encrypt(ctx, bytes.NewReader(plaintext), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body bytes.Buffer
+	if _, err := body.ReadFrom(enc); err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither the KMS nor the gateway password is available offline.
+	reader, err := crypto.NewEngineWithOpts([]byte("offline-password-456"), nil, crypto.WithHybridUnwrapKey(hybrid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if _, err := DecryptBody(ctx, reader, bytes.NewReader(body.Bytes()), &out); err != nil {
+		t.Fatalf("DecryptBody() error: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), plaintext) {
+		t.Error("DecryptBody() does not return the plaintext")
+	}
+}
@@ -0,0 +1,58 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+)
+
+func TestRepairManifests(t *testing.T) {
+	eng, err := crypto.NewEngineWithChunking([]byte("test-migrate-password-1234"), nil, "", nil, true, crypto.MinChunkSize)
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+	ctx := context.Background()
+	plaintext := bytes.Repeat([]byte("repair me "), 5000)
+
+	mock := newMockS3ForMigrate()
+	for _, key := range []string{"intact", "damaged", "no-iv"} {
+		encReader, encMeta, err := eng.Encrypt(ctx, bytes.NewReader(plaintext), nil)
+		if err != nil {
+			t.Fatalf("encrypt %s: %v", key, err)
+		}
+		cipherdata, _ := io.ReadAll(encReader)
+		switch key {
+		case "damaged":
+			encMeta[crypto.MetaManifest] = "garbage"
+		case "no-iv":
+			delete(encMeta, crypto.MetaManifest)
+			delete(encMeta, crypto.MetaIV)
+		}
+		_ = mock.PutObject(ctx, "bucket", key, bytes.NewReader(cipherdata), encMeta, nil, "", nil)
+	}
+
+	m := &Migrator{S3Client: mock, SourceEngine: eng, Verify: true, DryRun: true}
+	if err := m.RepairManifests(ctx, "bucket", "", crypto.MinChunkSize); err == nil {
+		t.Error("expected an error for the object without a base IV")
+	}
+	if mock.copyObjectCalls != 0 {
+		t.Fatalf("dry run issued %d CopyObject calls", mock.copyObjectCalls)
+	}
+
+	m.DryRun = false
+	_ = m.RepairManifests(ctx, "bucket", "", crypto.MinChunkSize)
+	if mock.copyObjectCalls != 1 {
+		t.Errorf("CopyObject calls = %d, want 1 (the damaged object only)", mock.copyObjectCalls)
+	}
+	body, meta, _ := mock.GetObject(ctx, "bucket", "damaged", nil, nil)
+	dec, _, err := eng.Decrypt(ctx, body, meta)
+	if err != nil {
+		t.Fatalf("decrypt repaired object: %v", err)
+	}
+	if got, _ := io.ReadAll(dec); !bytes.Equal(got, plaintext) {
+		t.Errorf("repaired object decrypted to %d bytes, want %d", len(got), len(plaintext))
+	}
+}
@@ -134,7 +134,8 @@ func filterUserMetadata(meta map[string]string) map[string]string {
 	}
 	userMeta := make(map[string]string, len(meta))
 	for k, v := range meta {
-		if crypto.IsEncryptionMetadata(k) || crypto.IsCompressionMetadata(k) {
+		// The identity marker is bound to the old ciphertext.
+		if crypto.IsEncryptionMetadata(k) || crypto.IsCompressionMetadata(k) || k == crypto.MetaGatewayMarker {
 			continue
 		}
 		userMeta[k] = v
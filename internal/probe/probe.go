@@ -0,0 +1,322 @@
+// Package probe runs a battery of operations against a backend bucket and
+// reports what the provider actually supports: how much user metadata it
+// accepts, how it answers range requests, the smallest multipart part it
+// takes, whether it honours conditional writes and whether listings see
+// writes and deletes straight away. The report suggests a
+// crypto.ProviderProfile and backend settings for providers the gateway has
+// no built-in profile for.
+package probe
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// DefaultKeyPrefix is where probe objects are written unless another prefix
+// is given. Every object is deleted again before Run returns.
+const DefaultKeyPrefix = ".s3eg-probe/"
+
+// metadataSizes are the x-amz-meta-* sizes tried, smallest first, counted
+// the way crypto.ProviderProfile.ValidateMetadataSize counts them.
+var metadataSizes = []int{1024, 2048, 4096, 8192}
+
+// partSizes are the non-final multipart part sizes tried, smallest first.
+var partSizes = []int64{1 << 10, 5 << 20}
+
+// longKeyLength is the metadata name length (after x-amz-meta-) used to
+// check for long key support.
+const longKeyLength = 128
+
+// Report is the outcome of a probe run.
+type Report struct {
+	Bucket string `json:"bucket"`
+	// UserMetadataLimit is the largest x-amz-meta-* size tried that was
+	// stored and returned intact; 0 when even the smallest failed. When
+	// MetadataLimitIsLowerBound is set the largest size tried passed and the
+	// real limit may be higher.
+	UserMetadataLimit         int  `json:"user_metadata_limit"`
+	MetadataLimitIsLowerBound bool `json:"metadata_limit_is_lower_bound"`
+	LongMetadataKeys          bool `json:"long_metadata_keys"`
+
+	Range RangeSupport `json:"range"`
+	// MultipartMinPartSize is the smallest non-final part size tried that a
+	// completed upload accepted; 0 when none was.
+	MultipartMinPartSize int64              `json:"multipart_min_part_size"`
+	ConditionalWrites    ConditionalSupport `json:"conditional_writes"`
+	List                 ListConsistency    `json:"list"`
+
+	// Errors lists probes that could not run to completion.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// RangeSupport describes how GET with a Range header is answered.
+type RangeSupport struct {
+	Bounded              bool `json:"bounded"`               // bytes=10-19
+	Suffix               bool `json:"suffix"`                // bytes=-5
+	ClampsPastEnd        bool `json:"clamps_past_end"`       // bytes=90-200 on 100 bytes
+	RejectsUnsatisfiable bool `json:"rejects_unsatisfiable"` // bytes=200- on 100 bytes
+}
+
+// ConditionalSupport describes which write preconditions are enforced.
+type ConditionalSupport struct {
+	IfNoneMatch bool `json:"if_none_match"`
+	IfMatch     bool `json:"if_match"`
+}
+
+// ListConsistency describes whether a listing issued straight after a
+// write or delete reflects it.
+type ListConsistency struct {
+	ReadAfterWrite  bool `json:"read_after_write"`
+	ReadAfterDelete bool `json:"read_after_delete"`
+}
+
+// Profile returns a provider profile filled in from the report.
+func (r *Report) Profile(name string) *crypto.ProviderProfile {
+	p := *crypto.ProviderDefault
+	p.Name = name
+	if r.UserMetadataLimit > 0 {
+		p.UserMetadataLimit = r.UserMetadataLimit
+	}
+	p.SupportsLongKeys = r.LongMetadataKeys
+	switch {
+	case !r.LongMetadataKeys:
+		p.CompactionStrategy = "short-keys"
+	case r.UserMetadataLimit > 0 && r.UserMetadataLimit <= crypto.ProviderDefault.UserMetadataLimit:
+		p.CompactionStrategy = "base64url"
+	}
+	return &p
+}
+
+// ConditionalWritesMode returns the backend.conditional_writes setting the
+// provider can support.
+func (r *Report) ConditionalWritesMode() string {
+	switch {
+	case r.ConditionalWrites.IfNoneMatch && r.ConditionalWrites.IfMatch:
+		return "optimistic"
+	case r.ConditionalWrites.IfNoneMatch || r.ConditionalWrites.IfMatch:
+		return "client"
+	}
+	return "off"
+}
+
+type prober struct {
+	client s3.Client
+	bucket string
+	prefix string
+	report *Report
+}
+
+// Run probes bucket through client, writing under prefix (DefaultKeyPrefix
+// when empty). It fails only when a plain write and delete do not work;
+// individual probes that fail are listed in Report.Errors.
+func Run(ctx context.Context, client s3.Client, bucket, prefix string) (*Report, error) {
+	if prefix == "" {
+		prefix = DefaultKeyPrefix
+	}
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	p := &prober{
+		client: client,
+		bucket: bucket,
+		prefix: prefix + hex.EncodeToString(id) + "/",
+		report: &Report{Bucket: bucket},
+	}
+	key := p.key("baseline")
+	if err := p.put(ctx, key, []byte("probe"), nil); err != nil {
+		return nil, fmt.Errorf("write to bucket %q: %w", bucket, err)
+	}
+	if err := client.DeleteObject(ctx, bucket, key, nil); err != nil {
+		return nil, fmt.Errorf("delete from bucket %q: %w", bucket, err)
+	}
+
+	for _, step := range []struct {
+		name string
+		run  func(context.Context) error
+	}{
+		{"metadata", p.metadata},
+		{"range", p.ranges},
+		{"multipart", p.multipart},
+		{"conditional", p.conditional},
+		{"list", p.list},
+	} {
+		if err := step.run(ctx); err != nil {
+			p.report.Errors = append(p.report.Errors, fmt.Sprintf("%s: %v", step.name, err))
+		}
+	}
+	return p.report, nil
+}
+
+func (p *prober) key(name string) string { return p.prefix + name }
+
+func (p *prober) put(ctx context.Context, key string, body []byte, meta map[string]string) error {
+	size := int64(len(body))
+	return p.client.PutObject(ctx, p.bucket, key, bytes.NewReader(body), meta, &size, "", nil)
+}
+
+func (p *prober) remove(ctx context.Context, key string) {
+	_ = p.client.DeleteObject(ctx, p.bucket, key, nil)
+}
+
+// storesMetadata reports whether meta is stored and read back unchanged.
+func (p *prober) storesMetadata(ctx context.Context, key string, meta map[string]string) bool {
+	if err := p.put(ctx, key, nil, meta); err != nil {
+		return false
+	}
+	defer p.remove(ctx, key)
+	got, err := p.client.HeadObject(ctx, p.bucket, key, nil)
+	if err != nil {
+		return false
+	}
+	for k, v := range meta {
+		if got[strings.ToLower(k)] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *prober) metadata(ctx context.Context) error {
+	const name = "x-amz-meta-probe"
+	for _, size := range metadataSizes {
+		value := strings.Repeat("m", size-len(name)-4)
+		if !p.storesMetadata(ctx, p.key(fmt.Sprintf("meta-%d", size)), map[string]string{name: value}) {
+			break
+		}
+		p.report.UserMetadataLimit = size
+		p.report.MetadataLimitIsLowerBound = size == metadataSizes[len(metadataSizes)-1]
+	}
+	long := "x-amz-meta-" + strings.Repeat("k", longKeyLength)
+	p.report.LongMetadataKeys = p.storesMetadata(ctx, p.key("meta-long-key"), map[string]string{long: "v"})
+	return nil
+}
+
+func (p *prober) get(ctx context.Context, key, rng string) ([]byte, error) {
+	r, _, err := p.client.GetObject(ctx, p.bucket, key, nil, &rng)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func (p *prober) ranges(ctx context.Context) error {
+	body := bytes.Repeat([]byte("0123456789"), 10)
+	key := p.key("range")
+	if err := p.put(ctx, key, body, nil); err != nil {
+		return err
+	}
+	defer p.remove(ctx, key)
+
+	rs := &p.report.Range
+	got, err := p.get(ctx, key, "bytes=10-19")
+	rs.Bounded = err == nil && bytes.Equal(got, body[10:20])
+	got, err = p.get(ctx, key, "bytes=-5")
+	rs.Suffix = err == nil && bytes.Equal(got, body[95:])
+	got, err = p.get(ctx, key, "bytes=90-200")
+	rs.ClampsPastEnd = err == nil && bytes.Equal(got, body[90:])
+	_, err = p.get(ctx, key, "bytes=200-")
+	rs.RejectsUnsatisfiable = err != nil
+	return nil
+}
+
+func (p *prober) multipart(ctx context.Context) error {
+	var lastErr error
+	for _, size := range partSizes {
+		key := p.key(fmt.Sprintf("mpu-%d", size))
+		if lastErr = p.uploadTwoParts(ctx, key, size); lastErr == nil {
+			p.report.MultipartMinPartSize = size
+			return nil
+		}
+	}
+	return fmt.Errorf("no part size was accepted: %w", lastErr)
+}
+
+func (p *prober) uploadTwoParts(ctx context.Context, key string, size int64) error {
+	uploadID, err := p.client.CreateMultipartUpload(ctx, p.bucket, key, nil)
+	if err != nil {
+		return err
+	}
+	var parts []s3.CompletedPart
+	for n, partLen := range []int64{size, 1} {
+		etag, err := p.client.UploadPart(ctx, p.bucket, key, uploadID, int32(n+1), bytes.NewReader(make([]byte, partLen)), &partLen)
+		if err != nil {
+			_ = p.client.AbortMultipartUpload(ctx, p.bucket, key, uploadID)
+			return err
+		}
+		parts = append(parts, s3.CompletedPart{PartNumber: int32(n + 1), ETag: etag})
+	}
+	if _, err := p.client.CompleteMultipartUpload(ctx, p.bucket, key, uploadID, parts, nil); err != nil {
+		_ = p.client.AbortMultipartUpload(ctx, p.bucket, key, uploadID)
+		return err
+	}
+	p.remove(ctx, key)
+	return nil
+}
+
+func isPreconditionFailed(err error) bool {
+	var apiErr interface{ ErrorCode() string }
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "PreconditionFailed"
+}
+
+func (p *prober) conditional(ctx context.Context) error {
+	key := p.key("conditional")
+	if err := p.put(ctx, key, []byte("1"), nil); err != nil {
+		return err
+	}
+	defer p.remove(ctx, key)
+
+	create := s3.WithWriteConditions(ctx, s3.WriteConditions{IfNoneMatch: "*"})
+	p.report.ConditionalWrites.IfNoneMatch = isPreconditionFailed(p.put(create, key, []byte("2"), nil))
+	stale := s3.WithWriteConditions(ctx, s3.WriteConditions{IfMatch: `"00000000000000000000000000000000"`})
+	p.report.ConditionalWrites.IfMatch = isPreconditionFailed(p.put(stale, key, []byte("3"), nil))
+	return nil
+}
+
+func (p *prober) list(ctx context.Context) error {
+	const count = 5
+	listPrefix := p.key("list/")
+	var keys []string
+	for i := 0; i < count; i++ {
+		key := fmt.Sprintf("%s%d", listPrefix, i)
+		if err := p.put(ctx, key, []byte("x"), nil); err != nil {
+			for _, k := range keys {
+				p.remove(ctx, k)
+			}
+			return err
+		}
+		keys = append(keys, key)
+	}
+	n, err := p.countListed(ctx, listPrefix)
+	if err != nil {
+		return err
+	}
+	p.report.List.ReadAfterWrite = n == count
+	for _, k := range keys {
+		p.remove(ctx, k)
+	}
+	n, err = p.countListed(ctx, listPrefix)
+	if err != nil {
+		return err
+	}
+	p.report.List.ReadAfterDelete = n == 0
+	return nil
+}
+
+func (p *prober) countListed(ctx context.Context, prefix string) (int, error) {
+	res, err := p.client.ListObjects(ctx, p.bucket, prefix, s3.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+	return len(res.Objects), nil
+}
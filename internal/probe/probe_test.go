@@ -0,0 +1,81 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func TestRun_MemoryBackend(t *testing.T) {
+	client := testsupport.NewMemoryClient()
+	report, err := Run(context.Background(), client, "b", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Errors) != 0 {
+		t.Errorf("errors: %v", report.Errors)
+	}
+	if report.UserMetadataLimit != 8192 || !report.MetadataLimitIsLowerBound || !report.LongMetadataKeys {
+		t.Errorf("metadata = %d (lower bound %v), long keys %v", report.UserMetadataLimit, report.MetadataLimitIsLowerBound, report.LongMetadataKeys)
+	}
+	if report.Range != (RangeSupport{Bounded: true, Suffix: true, ClampsPastEnd: true, RejectsUnsatisfiable: true}) {
+		t.Errorf("range = %+v", report.Range)
+	}
+	if report.MultipartMinPartSize != 1<<10 {
+		t.Errorf("multipart min part size = %d", report.MultipartMinPartSize)
+	}
+	if report.ConditionalWritesMode() != "optimistic" {
+		t.Errorf("conditional writes = %+v", report.ConditionalWrites)
+	}
+	if !report.List.ReadAfterWrite || !report.List.ReadAfterDelete {
+		t.Errorf("list = %+v", report.List)
+	}
+	if res, _ := client.ListObjects(context.Background(), "b", DefaultKeyPrefix, s3.ListOptions{}); len(res.Objects) != 0 {
+		t.Errorf("probe objects left behind: %+v", res.Objects)
+	}
+}
+
+func TestRun_RestrictedProvider(t *testing.T) {
+	client := testsupport.NewMemoryClient()
+	client.OnCall = func(op, bucket, key string) error {
+		switch {
+		case op == "PutObject" && (strings.HasSuffix(key, "/meta-4096") || strings.HasSuffix(key, "/meta-8192") || strings.HasSuffix(key, "/meta-long-key")):
+			return s3.NewAPIError("MetadataTooLarge", "metadata too large")
+		case op == "CompleteMultipartUpload" && strings.HasSuffix(key, "/mpu-1024"):
+			return s3.NewAPIError("EntityTooSmall", "part too small")
+		}
+		return nil
+	}
+	report, err := Run(context.Background(), client, "b", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.UserMetadataLimit != 2048 || report.MetadataLimitIsLowerBound || report.LongMetadataKeys {
+		t.Errorf("metadata = %d (lower bound %v), long keys %v", report.UserMetadataLimit, report.MetadataLimitIsLowerBound, report.LongMetadataKeys)
+	}
+	if report.MultipartMinPartSize != 5<<20 {
+		t.Errorf("multipart min part size = %d", report.MultipartMinPartSize)
+	}
+	p := report.Profile("appliance")
+	if p.Name != "appliance" || p.UserMetadataLimit != 2048 || p.SupportsLongKeys || p.CompactionStrategy != "short-keys" {
+		t.Errorf("profile = %+v", p)
+	}
+}
+
+func TestRun_UnwritableBucket(t *testing.T) {
+	client := testsupport.NewMemoryClient()
+	denied := errors.New("AccessDenied")
+	client.OnCall = func(op, bucket, key string) error {
+		if op == "PutObject" {
+			return denied
+		}
+		return nil
+	}
+	if _, err := Run(context.Background(), client, "b", ""); !errors.Is(err, denied) {
+		t.Fatalf("Run = %v, want the write error", err)
+	}
+}
@@ -0,0 +1,434 @@
+// Package quota limits the bytes and objects stored per bucket and per
+// tenant, a named group of buckets sharing one limit.
+//
+// Usage is measured by listing each limited bucket, in stored sizes: the
+// encrypted bytes the provider keeps, not the plaintext clients sent. Scans
+// run every scan interval; between them, writes through the gateway are
+// added as they succeed. Deletes and overwrites are only seen by the next
+// scan, so usage can read high for a while but a burst of writes cannot run
+// far past a limit. A bucket's usage is unknown until its first scan
+// completes, and writes to it are allowed meanwhile.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/sirupsen/logrus"
+)
+
+// Quota scopes, as reported in errors, statuses and metrics.
+const (
+	ScopeBucket = "bucket"
+	ScopeTenant = "tenant"
+)
+
+// ErrExceeded is matched by every *ExceededError.
+var ErrExceeded = errors.New("quota exceeded")
+
+// ExceededError names the quota a refused write would have exceeded.
+type ExceededError struct {
+	Scope string
+	Name  string
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s quota %q exceeded", e.Scope, e.Name)
+}
+
+// Is makes errors.Is(err, ErrExceeded) true.
+func (e *ExceededError) Is(target error) bool {
+	return target == ErrExceeded
+}
+
+// Usage is an amount of stored data.
+type Usage struct {
+	Bytes   int64 `json:"bytes"`
+	Objects int64 `json:"objects"`
+}
+
+func (u *Usage) add(o Usage) {
+	u.Bytes += o.Bytes
+	u.Objects += o.Objects
+}
+
+// Remaining is what a quota still allows. A dimension without a limit is
+// nil.
+type Remaining struct {
+	Bytes   *int64 `json:"bytes,omitempty"`
+	Objects *int64 `json:"objects,omitempty"`
+}
+
+// Status is the state of one quota.
+type Status struct {
+	Scope   string            `json:"scope"`
+	Name    string            `json:"name"`
+	Buckets []string          `json:"buckets"`
+	Limit   config.QuotaLimit `json:"limit"`
+	Used    Usage             `json:"used"`
+	// Known is false until every bucket of the quota has been scanned once;
+	// Used and Remaining only cover the scanned buckets until then.
+	Known     bool      `json:"known"`
+	Remaining Remaining `json:"remaining"`
+}
+
+// Lister is the subset of s3.Client used to measure buckets.
+type Lister interface {
+	ListObjects(ctx context.Context, bucket, prefix string, opts s3.ListOptions) (s3.ListResult, error)
+}
+
+// bucketUsage is the usage of one bucket: base as of the last completed
+// scan, plus the writes recorded since that scan started.
+type bucketUsage struct {
+	base    Usage
+	delta   Usage
+	scanned bool
+}
+
+func (b *bucketUsage) current() Usage {
+	u := b.base
+	u.add(b.delta)
+	return u
+}
+
+// Manager tracks usage and enforces quotas. All methods are safe on a nil
+// *Manager, which enforces nothing.
+type Manager struct {
+	client   Lister
+	buckets  map[string]config.QuotaLimit
+	tenants  map[string]config.TenantQuota
+	tenantOf map[string]string
+	interval time.Duration
+	reserved func(bucket, key string) bool
+	m        *metrics.Metrics
+	logger   *logrus.Logger
+
+	mu    sync.Mutex
+	usage map[string]*bucketUsage
+
+	runMu   sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started atomic.Bool
+	done    chan struct{}
+}
+
+// New returns the quota manager for cfg, or nil when cfg disables quotas.
+// client lists the buckets; reserved, when not nil, reports gateway keys
+// that do not count against quotas. m may be nil.
+func New(client Lister, cfg config.QuotaConfig, reserved func(bucket, key string) bool, m *metrics.Metrics, logger *logrus.Logger) *Manager {
+	if !cfg.Enabled || client == nil {
+		return nil
+	}
+	q := &Manager{
+		client:   client,
+		buckets:  cfg.Buckets,
+		tenants:  cfg.Tenants,
+		tenantOf: make(map[string]string),
+		interval: cfg.ScanInterval,
+		reserved: reserved,
+		m:        m,
+		logger:   logger,
+		usage:    make(map[string]*bucketUsage),
+		done:     make(chan struct{}),
+	}
+	for bucket := range cfg.Buckets {
+		q.usage[bucket] = &bucketUsage{}
+	}
+	for name, tenant := range cfg.Tenants {
+		for _, bucket := range tenant.Buckets {
+			q.tenantOf[bucket] = name
+			q.usage[bucket] = &bucketUsage{}
+		}
+	}
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+	return q
+}
+
+// Limited reports whether writes to bucket count against a quota.
+func (q *Manager) Limited(bucket string) bool {
+	if q == nil {
+		return false
+	}
+	_, ok := q.usage[bucket]
+	return ok
+}
+
+// quotaUsage is one quota applying to a bucket, with its usage so far.
+type quotaUsage struct {
+	scope, name string
+	limit       config.QuotaLimit
+	used        Usage
+	known       bool
+}
+
+// applicableLocked returns the bucket's own quota and its tenant's, if
+// any. q.mu must be held.
+func (q *Manager) applicableLocked(bucket string) []quotaUsage {
+	var out []quotaUsage
+	if limit, ok := q.buckets[bucket]; ok {
+		b := q.usage[bucket]
+		out = append(out, quotaUsage{scope: ScopeBucket, name: bucket, limit: limit, used: b.current(), known: b.scanned})
+	}
+	if name, ok := q.tenantOf[bucket]; ok {
+		out = append(out, q.tenantLocked(name))
+	}
+	return out
+}
+
+func (q *Manager) tenantLocked(name string) quotaUsage {
+	tenant := q.tenants[name]
+	qu := quotaUsage{scope: ScopeTenant, name: name, limit: tenant.QuotaLimit, known: true}
+	for _, bucket := range tenant.Buckets {
+		b := q.usage[bucket]
+		qu.used.add(b.current())
+		qu.known = qu.known && b.scanned
+	}
+	return qu
+}
+
+// Check returns an *ExceededError when storing one more object of size
+// bytes in bucket would exceed its quota or its tenant's, or a byte limit
+// is already used up. A negative size is treated as unknown, leaving only
+// those checks. Quotas whose usage is not known yet allow the write.
+func (q *Manager) Check(bucket string, size int64) error {
+	if !q.Limited(bucket) {
+		return nil
+	}
+	if size < 0 {
+		size = 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, qu := range q.applicableLocked(bucket) {
+		if !qu.known {
+			continue
+		}
+		if (qu.limit.MaxObjects > 0 && qu.used.Objects+1 > qu.limit.MaxObjects) ||
+			(qu.limit.MaxBytes > 0 && (qu.used.Bytes >= qu.limit.MaxBytes || qu.used.Bytes+size > qu.limit.MaxBytes)) {
+			q.m.RecordQuotaRejection(qu.scope, qu.name)
+			return &ExceededError{Scope: qu.scope, Name: qu.name}
+		}
+	}
+	return nil
+}
+
+// Record adds a successful write to bucket's usage. Multipart uploads
+// record each part's bytes as it is stored and the object on completion.
+func (q *Manager) Record(bucket string, bytes, objects int64) {
+	if !q.Limited(bucket) {
+		return
+	}
+	q.mu.Lock()
+	q.usage[bucket].delta.add(Usage{Bytes: bytes, Objects: objects})
+	q.mu.Unlock()
+	q.publish(bucket)
+}
+
+// Remaining returns what the quotas of bucket still allow, the smaller of
+// its own and its tenant's in each dimension. ok is false when bucket has
+// no quota or its usage is not known yet.
+func (q *Manager) Remaining(bucket string) (r Remaining, ok bool) {
+	if !q.Limited(bucket) {
+		return r, false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, qu := range q.applicableLocked(bucket) {
+		if !qu.known {
+			return Remaining{}, false
+		}
+		r = lower(r, remaining(qu.limit, qu.used))
+	}
+	return r, true
+}
+
+func remaining(limit config.QuotaLimit, used Usage) Remaining {
+	var r Remaining
+	if limit.MaxBytes > 0 {
+		n := max(limit.MaxBytes-used.Bytes, 0)
+		r.Bytes = &n
+	}
+	if limit.MaxObjects > 0 {
+		n := max(limit.MaxObjects-used.Objects, 0)
+		r.Objects = &n
+	}
+	return r
+}
+
+func lower(a, b Remaining) Remaining {
+	pick := func(x, y *int64) *int64 {
+		if x == nil || (y != nil && *y < *x) {
+			return y
+		}
+		return x
+	}
+	return Remaining{Bytes: pick(a.Bytes, b.Bytes), Objects: pick(a.Objects, b.Objects)}
+}
+
+// Statuses returns every configured quota, buckets first, each group
+// sorted by name.
+func (q *Manager) Statuses() []Status {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var out []Status
+	for _, bucket := range sortedKeys(q.buckets) {
+		b := q.usage[bucket]
+		out = append(out, status(quotaUsage{scope: ScopeBucket, name: bucket, limit: q.buckets[bucket], used: b.current(), known: b.scanned}, []string{bucket}))
+	}
+	for _, name := range sortedKeys(q.tenants) {
+		out = append(out, status(q.tenantLocked(name), q.tenants[name].Buckets))
+	}
+	return out
+}
+
+func status(qu quotaUsage, buckets []string) Status {
+	return Status{
+		Scope:     qu.scope,
+		Name:      qu.name,
+		Buckets:   buckets,
+		Limit:     qu.limit,
+		Used:      qu.used,
+		Known:     qu.known,
+		Remaining: remaining(qu.limit, qu.used),
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// publish updates the usage metrics of bucket's quotas.
+func (q *Manager) publish(bucket string) {
+	if q.m == nil {
+		return
+	}
+	q.mu.Lock()
+	quotas := q.applicableLocked(bucket)
+	q.mu.Unlock()
+	for _, qu := range quotas {
+		q.m.SetQuotaUsage(qu.scope, qu.name, qu.used.Bytes, qu.used.Objects)
+	}
+}
+
+// Scan measures every limited bucket once. A bucket that fails to list
+// keeps its previous usage; the first such error is returned after the
+// other buckets have been scanned.
+func (q *Manager) Scan(ctx context.Context) error {
+	if q == nil {
+		return nil
+	}
+	q.runMu.Lock()
+	defer q.runMu.Unlock()
+	var firstErr error
+	for _, bucket := range sortedKeys(q.usage) {
+		if err := q.scan(ctx, bucket); err != nil {
+			if q.logger != nil && ctx.Err() == nil {
+				q.logger.WithError(err).WithField("bucket", bucket).Warn("Quota usage scan failed")
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			if ctx.Err() != nil {
+				return firstErr
+			}
+		}
+	}
+	return firstErr
+}
+
+func (q *Manager) scan(ctx context.Context, bucket string) error {
+	// Writes recorded from here on may or may not show in the listing.
+	// They are kept on top of the result, which may count them twice until
+	// the next scan, but never drops them.
+	q.mu.Lock()
+	b := q.usage[bucket]
+	pending := b.delta
+	b.delta = Usage{}
+	q.mu.Unlock()
+
+	var total Usage
+	opts := s3.ListOptions{MaxKeys: 1000}
+	for {
+		page, err := q.client.ListObjects(ctx, bucket, "", opts)
+		if err != nil {
+			q.mu.Lock()
+			b.delta.add(pending)
+			q.mu.Unlock()
+			return fmt.Errorf("quota: list %s: %w", bucket, err)
+		}
+		for _, obj := range page.Objects {
+			if q.reserved != nil && q.reserved(bucket, obj.Key) {
+				continue
+			}
+			total.add(Usage{Bytes: obj.Size, Objects: 1})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		opts.ContinuationToken = page.NextContinuationToken
+	}
+
+	q.mu.Lock()
+	b.base = total
+	b.scanned = true
+	q.mu.Unlock()
+	q.publish(bucket)
+	return nil
+}
+
+// Start scans every bucket now and then every scan interval until Stop is
+// called.
+func (q *Manager) Start() {
+	if q == nil || !q.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer close(q.done)
+		_ = q.Scan(q.ctx)
+		ticker := time.NewTicker(q.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_ = q.Scan(q.ctx)
+			case <-q.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the scan loop, cancelling a scan in progress, and waits for it
+// to return or ctx to expire.
+func (q *Manager) Stop(ctx context.Context) error {
+	if q == nil {
+		return nil
+	}
+	q.cancel()
+	if !q.started.Load() {
+		return nil
+	}
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
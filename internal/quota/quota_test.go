@@ -0,0 +1,171 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// fakeLister serves listings from sizes, two objects per page.
+type fakeLister struct {
+	mu    sync.Mutex
+	sizes map[string][]int64
+	fail  map[string]bool
+}
+
+func (f *fakeLister) ListObjects(ctx context.Context, bucket, prefix string, opts s3.ListOptions) (s3.ListResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail[bucket] {
+		return s3.ListResult{}, errors.New("backend down")
+	}
+	start := 0
+	if opts.ContinuationToken != "" {
+		fmt.Sscan(opts.ContinuationToken, &start)
+	}
+	sizes := f.sizes[bucket]
+	end := min(start+2, len(sizes))
+	var res s3.ListResult
+	for i := start; i < end; i++ {
+		res.Objects = append(res.Objects, s3.ObjectInfo{Key: fmt.Sprintf("obj-%d", i), Size: sizes[i]})
+	}
+	if end < len(sizes) {
+		res.IsTruncated = true
+		res.NextContinuationToken = fmt.Sprint(end)
+	}
+	return res, nil
+}
+
+func newTestManager(t *testing.T, lister *fakeLister, cfg config.QuotaConfig) *Manager {
+	t.Helper()
+	cfg.Enabled = true
+	cfg.ScanInterval = time.Hour
+	q := New(lister, cfg, nil, nil, nil)
+	t.Cleanup(func() { _ = q.Stop(context.Background()) })
+	return q
+}
+
+func TestManager_BucketQuota(t *testing.T) {
+	ctx := context.Background()
+	lister := &fakeLister{sizes: map[string][]int64{"logs": {100, 200, 300}}}
+	q := newTestManager(t, lister, config.QuotaConfig{Buckets: map[string]config.QuotaLimit{"logs": {MaxBytes: 1000, MaxObjects: 5}}})
+
+	// Unknown usage allows writes and reports no remaining quota.
+	if err := q.Check("logs", 5000); err != nil {
+		t.Fatalf("check before scan: %v", err)
+	}
+	if _, ok := q.Remaining("logs"); ok {
+		t.Error("remaining known before the first scan")
+	}
+	if err := q.Scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	rem, ok := q.Remaining("logs")
+	if !ok || *rem.Bytes != 400 || *rem.Objects != 2 {
+		t.Fatalf("remaining = %+v, %v", rem, ok)
+	}
+
+	if err := q.Check("logs", 400); err != nil {
+		t.Errorf("write that fills the quota: %v", err)
+	}
+	err := q.Check("logs", 401)
+	var exceeded *ExceededError
+	if !errors.As(err, &exceeded) || exceeded.Scope != ScopeBucket || exceeded.Name != "logs" || !errors.Is(err, ErrExceeded) {
+		t.Errorf("oversized write = %v", err)
+	}
+
+	q.Record("logs", 150, 1)
+	q.Record("logs", 150, 1)
+	if err := q.Check("logs", 0); err == nil {
+		t.Error("object limit not enforced")
+	}
+	if err := q.Check("other", 1<<40); err != nil {
+		t.Errorf("bucket without quota: %v", err)
+	}
+
+	// A scan replaces the counted writes with what the bucket holds.
+	lister.sizes["logs"] = []int64{100}
+	if err := q.Scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if rem, _ := q.Remaining("logs"); *rem.Bytes != 900 || *rem.Objects != 4 {
+		t.Errorf("remaining after rescan = %d bytes, %d objects", *rem.Bytes, *rem.Objects)
+	}
+}
+
+func TestManager_TenantQuota(t *testing.T) {
+	ctx := context.Background()
+	lister := &fakeLister{sizes: map[string][]int64{"a": {500}, "b": {300}}}
+	q := newTestManager(t, lister, config.QuotaConfig{
+		Buckets: map[string]config.QuotaLimit{"a": {MaxObjects: 10}},
+		Tenants: map[string]config.TenantQuota{
+			"acme": {Buckets: []string{"a", "b"}, QuotaLimit: config.QuotaLimit{MaxBytes: 1000}},
+		},
+	})
+	if err := q.Scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	rem, ok := q.Remaining("a")
+	if !ok || *rem.Bytes != 200 || *rem.Objects != 9 {
+		t.Fatalf("remaining for a = %+v, %v", rem, ok)
+	}
+	if rem, _ := q.Remaining("b"); *rem.Bytes != 200 || rem.Objects != nil {
+		t.Errorf("remaining for b = %+v", rem)
+	}
+	var exceeded *ExceededError
+	if err := q.Check("b", 201); !errors.As(err, &exceeded) || exceeded.Scope != ScopeTenant || exceeded.Name != "acme" {
+		t.Errorf("tenant limit on b = %v", err)
+	}
+
+	statuses := q.Statuses()
+	if len(statuses) != 2 || statuses[0].Scope != ScopeBucket || statuses[1].Name != "acme" {
+		t.Fatalf("statuses = %+v", statuses)
+	}
+	if s := statuses[1]; !s.Known || s.Used.Bytes != 800 || s.Used.Objects != 2 || *s.Remaining.Bytes != 200 {
+		t.Errorf("tenant status = %+v", s)
+	}
+}
+
+func TestManager_FailedScanKeepsWrites(t *testing.T) {
+	ctx := context.Background()
+	lister := &fakeLister{sizes: map[string][]int64{"logs": {100}}, fail: map[string]bool{}}
+	q := newTestManager(t, lister, config.QuotaConfig{Buckets: map[string]config.QuotaLimit{"logs": {MaxBytes: 1000}}})
+	if err := q.Scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	q.Record("logs", 300, 1)
+	lister.fail["logs"] = true
+	if err := q.Scan(ctx); err == nil {
+		t.Fatal("failed listing not reported")
+	}
+	if rem, _ := q.Remaining("logs"); *rem.Bytes != 600 {
+		t.Errorf("remaining after failed scan = %d, want 600", *rem.Bytes)
+	}
+}
+
+func TestManager_ReservedKeysAndNil(t *testing.T) {
+	lister := &fakeLister{sizes: map[string][]int64{"logs": {100, 200}}}
+	cfg := config.QuotaConfig{Enabled: true, ScanInterval: time.Hour, Buckets: map[string]config.QuotaLimit{"logs": {MaxBytes: 1000}}}
+	q := New(lister, cfg, func(bucket, key string) bool { return key == "obj-1" }, nil, nil)
+	if err := q.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if rem, _ := q.Remaining("logs"); *rem.Bytes != 900 {
+		t.Errorf("reserved key counted: remaining %d", *rem.Bytes)
+	}
+
+	if New(lister, config.QuotaConfig{}, nil, nil, nil) != nil {
+		t.Error("disabled config returned a manager")
+	}
+	var none *Manager
+	if err := none.Check("logs", 1<<40); err != nil || none.Statuses() != nil || none.Scan(context.Background()) != nil {
+		t.Error("nil manager enforced something")
+	}
+	none.Record("logs", 1, 1)
+}
@@ -0,0 +1,224 @@
+// Package replay reproduces recorded gateway traffic: it reads the
+// gateway's access log and re-issues the logged requests against a gateway,
+// at the original pace or scaled, with synthetic bodies of the logged sizes.
+//
+// A replay is deterministic: the same log, speed and seed produce the same
+// requests, bodies and schedule.
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request is one logged request to replay.
+type Request struct {
+	// Offset is when the request started, relative to the first one.
+	Offset time.Duration
+	Method string
+	Path   string
+	// Query is the logged query string without redacted and presigning
+	// parameters.
+	Query string
+	// Size is the logged request body size for PUT and POST, and the
+	// response size otherwise.
+	Size int64
+}
+
+// ParseResult is the outcome of reading an access log.
+type ParseResult struct {
+	Requests []Request
+	// Skipped counts access log lines that cannot be replayed: multipart
+	// upload steps, whose upload IDs only existed on the recorded gateway.
+	Skipped int
+	// Ignored counts lines that are not access log entries.
+	Ignored int
+}
+
+// accessEntry is an access log line in any of the gateway's formats.
+type accessEntry struct {
+	at       time.Time
+	duration time.Duration
+	method   string
+	path     string
+	query    string
+	bytes    int64
+}
+
+// clfLine matches the "clf" field written with logging.access_log_format clf.
+var clfLine = regexp.MustCompile(`^\S+ \S+ \S+ \[([^\]]+)\] "(\S+) (\S+) [^"]*" (\d+) (\d+)$`)
+
+// Parse reads an access log written by the gateway's JSON logger in any
+// access_log_format (default, json or clf). Other lines are ignored.
+//
+// Log timestamps have a resolution of one second and are taken when a
+// request completes. A request's start is its timestamp less its logged
+// duration, and requests that fall in the same second are spread evenly
+// across it in log order.
+func Parse(r io.Reader) (ParseResult, error) {
+	var res ParseResult
+	var entries []accessEntry
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 4<<20)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		e, ok := parseLine(line)
+		if !ok {
+			res.Ignored++
+			continue
+		}
+		if isMultipartStep(e.query) {
+			res.Skipped++
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return res, fmt.Errorf("replay: reading access log: %w", err)
+	}
+	res.Requests = schedule(entries)
+	return res, nil
+}
+
+func parseLine(line string) (accessEntry, bool) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &raw); err != nil {
+		return accessEntry{}, false
+	}
+	logTime, _ := time.Parse(time.RFC3339, str(raw["time"]))
+
+	// access_log_format json: the entry is a JSON string in "json", or the
+	// line is the entry itself.
+	if s, ok := raw["json"].(string); ok {
+		if err := json.Unmarshal([]byte(s), &raw); err != nil {
+			return accessEntry{}, false
+		}
+	}
+	if clf, ok := raw["clf"].(string); ok {
+		return parseCLF(clf, logTime)
+	}
+
+	e := accessEntry{
+		at:     logTime,
+		method: str(raw["method"]),
+		path:   str(raw["path"]),
+		query:  str(raw["query"]),
+	}
+	if e.method == "" || !strings.HasPrefix(e.path, "/") {
+		return accessEntry{}, false
+	}
+	if ts, err := time.Parse(time.RFC3339, str(raw["timestamp"])); err == nil {
+		e.at = ts
+	}
+	if e.at.IsZero() {
+		return accessEntry{}, false
+	}
+	if v, ok := raw["duration_ms"].(float64); ok {
+		e.duration = time.Duration(v) * time.Millisecond
+	}
+	if v, ok := raw["bytes"].(float64); ok {
+		e.bytes = int64(v)
+	}
+	return e, true
+}
+
+func parseCLF(clf string, logTime time.Time) (accessEntry, bool) {
+	m := clfLine.FindStringSubmatch(clf)
+	if m == nil {
+		return accessEntry{}, false
+	}
+	at, err := time.Parse(time.RFC3339, m[1])
+	if err != nil {
+		at = logTime
+	}
+	if at.IsZero() {
+		return accessEntry{}, false
+	}
+	path, query, _ := strings.Cut(m[3], "?")
+	size, _ := strconv.ParseInt(m[5], 10, 64)
+	return accessEntry{at: at, method: m[2], path: path, query: query, bytes: size}, true
+}
+
+func str(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+// isMultipartStep reports whether query belongs to a multipart upload.
+func isMultipartStep(query string) bool {
+	values, _ := url.ParseQuery(query)
+	_, uploads := values["uploads"]
+	_, uploadID := values["uploadId"]
+	return uploads || uploadID
+}
+
+// cleanQuery drops redacted values and presigned-URL authentication, which
+// cannot be replayed.
+func cleanQuery(query string) string {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return ""
+	}
+	for k, vs := range values {
+		if strings.HasPrefix(strings.ToLower(k), "x-amz-") || strings.EqualFold(k, "AWSAccessKeyId") || strings.EqualFold(k, "Signature") || strings.EqualFold(k, "Expires") {
+			delete(values, k)
+			continue
+		}
+		for _, v := range vs {
+			if v == "[REDACTED]" {
+				delete(values, k)
+				break
+			}
+		}
+	}
+	return values.Encode()
+}
+
+// schedule turns entries into requests ordered by start offset.
+func schedule(entries []accessEntry) []Request {
+	if len(entries) == 0 {
+		return nil
+	}
+	// Spread the entries of each logged second evenly across it.
+	perSecond := make(map[int64]int)
+	for _, e := range entries {
+		perSecond[e.at.Unix()]++
+	}
+	seen := make(map[int64]int)
+	starts := make([]time.Time, len(entries))
+	for i, e := range entries {
+		sec := e.at.Unix()
+		spread := time.Second * time.Duration(seen[sec]) / time.Duration(perSecond[sec])
+		seen[sec]++
+		starts[i] = e.at.Truncate(time.Second).Add(spread).Add(-e.duration)
+	}
+	first := starts[0]
+	for _, s := range starts {
+		if s.Before(first) {
+			first = s
+		}
+	}
+	reqs := make([]Request, len(entries))
+	for i, e := range entries {
+		reqs[i] = Request{
+			Offset: starts[i].Sub(first),
+			Method: e.method,
+			Path:   e.path,
+			Query:  cleanQuery(e.query),
+			Size:   e.bytes,
+		}
+	}
+	sort.SliceStable(reqs, func(i, j int) bool { return reqs[i].Offset < reqs[j].Offset })
+	return reqs
+}
@@ -0,0 +1,252 @@
+package replay
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// Player re-issues requests against a gateway.
+type Player struct {
+	// Target is the gateway's base URL.
+	Target *url.URL
+	// Client sends the requests; http.DefaultClient when nil.
+	Client *http.Client
+	// Speed scales the recorded pace: 2 replays twice as fast. Zero or
+	// less sends requests as fast as Concurrency allows.
+	Speed float64
+	// Concurrency bounds the requests in flight. A request whose start time
+	// comes while all slots are busy waits, and the wait counts as lag.
+	Concurrency int
+	// Seed selects the synthetic bodies.
+	Seed uint64
+	// BucketMap replaces the first path segment, to replay production
+	// traffic into staging buckets.
+	BucketMap map[string]string
+	// Credentials, when set, sign every request with Signature V4.
+	Credentials *aws.Credentials
+	Region      string
+}
+
+// Result is the outcome of one replayed request.
+type Result struct {
+	Request Request
+	Status  int
+	Err     error
+	Latency time.Duration
+	// Lag is how late the request was sent compared with its schedule.
+	Lag time.Duration
+}
+
+// Run replays reqs, which must be ordered by Offset, and returns a report.
+// Cancelling ctx stops the replay; requests not yet sent are not reported.
+func (p *Player) Run(ctx context.Context, reqs []Request) *Report {
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	slots := make(chan struct{}, max(p.Concurrency, 1))
+	results := make(chan Result, len(reqs))
+	var wg sync.WaitGroup
+	begin := time.Now()
+	for _, req := range reqs {
+		due := begin
+		if p.Speed > 0 {
+			due = begin.Add(time.Duration(float64(req.Offset) / p.Speed))
+		}
+		if wait := time.Until(due); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+			}
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		lag := max(time.Since(due), 0)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			res := p.send(ctx, client, req)
+			res.Lag = lag
+			results <- res
+		}()
+	}
+	wg.Wait()
+	close(results)
+	report := newReport()
+	for res := range results {
+		report.add(res)
+	}
+	report.finish(time.Since(begin))
+	return report
+}
+
+func (p *Player) send(ctx context.Context, client *http.Client, req Request) Result {
+	res := Result{Request: req}
+	u := *p.Target
+	u.Path = strings.TrimSuffix(u.Path, "/") + p.mapPath(req.Path)
+	u.RawQuery = req.Query
+
+	var body io.Reader
+	var length int64
+	if (req.Method == http.MethodPut || req.Method == http.MethodPost) && req.Size > 0 {
+		body = Body(p.Seed, req.Path, req.Size)
+		length = req.Size
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, u.String(), body)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	httpReq.ContentLength = length
+	if p.Credentials != nil {
+		const payload = "UNSIGNED-PAYLOAD"
+		httpReq.Header.Set("X-Amz-Content-Sha256", payload)
+		region := p.Region
+		if region == "" {
+			region = "us-east-1"
+		}
+		if err := v4.NewSigner().SignHTTP(ctx, *p.Credentials, httpReq, payload, "s3", region, time.Now()); err != nil {
+			res.Err = fmt.Errorf("sign: %w", err)
+			return res
+		}
+	}
+
+	start := time.Now()
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		res.Err = err
+		res.Latency = time.Since(start)
+		return res
+	}
+	_, err = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	res.Latency = time.Since(start)
+	res.Status = resp.StatusCode
+	res.Err = err
+	return res
+}
+
+// mapPath applies BucketMap to the bucket segment of path.
+func (p *Player) mapPath(path string) string {
+	bucket, rest, found := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	to, ok := p.BucketMap[bucket]
+	if !ok {
+		return path
+	}
+	if !found {
+		return "/" + to
+	}
+	return "/" + to + "/" + rest
+}
+
+// Body returns size bytes of pseudo-random data determined by seed and
+// path, so a replayed object has the same content on every run and does
+// not compress better than real data would.
+func Body(seed uint64, path string, size int64) io.Reader {
+	h := fnv.New64a()
+	h.Write([]byte(path))
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[:8], seed)
+	binary.LittleEndian.PutUint64(key[8:16], h.Sum64())
+	return io.LimitReader(rand.NewChaCha8(key), size)
+}
+
+// Report summarises a replay.
+type Report struct {
+	Requests int            `json:"requests"`
+	Errors   int            `json:"errors"`
+	ByMethod map[string]int `json:"by_method"`
+	// ByStatus counts responses by status class ("2xx", "4xx", ...).
+	ByStatus   map[string]int `json:"by_status"`
+	BytesSent  int64          `json:"bytes_sent"`
+	Elapsed    time.Duration  `json:"elapsed_ns"`
+	LatencyP50 time.Duration  `json:"latency_p50_ns"`
+	LatencyP99 time.Duration  `json:"latency_p99_ns"`
+	LatencyMax time.Duration  `json:"latency_max_ns"`
+	LagP99     time.Duration  `json:"lag_p99_ns"`
+	LagMax     time.Duration  `json:"lag_max_ns"`
+	// FirstErrors holds up to ten error messages.
+	FirstErrors []string `json:"first_errors,omitempty"`
+
+	latencies []time.Duration
+	lags      []time.Duration
+}
+
+func newReport() *Report {
+	return &Report{ByMethod: make(map[string]int), ByStatus: make(map[string]int)}
+}
+
+func (r *Report) add(res Result) {
+	r.Requests++
+	r.ByMethod[res.Request.Method]++
+	if res.Request.Method == http.MethodPut || res.Request.Method == http.MethodPost {
+		r.BytesSent += res.Request.Size
+	}
+	if res.Err != nil {
+		r.Errors++
+		if len(r.FirstErrors) < 10 {
+			r.FirstErrors = append(r.FirstErrors, fmt.Sprintf("%s %s: %v", res.Request.Method, res.Request.Path, res.Err))
+		}
+	} else {
+		r.ByStatus[fmt.Sprintf("%dxx", res.Status/100)]++
+	}
+	r.latencies = append(r.latencies, res.Latency)
+	r.lags = append(r.lags, res.Lag)
+}
+
+func (r *Report) finish(elapsed time.Duration) {
+	r.Elapsed = elapsed
+	slices.Sort(r.latencies)
+	slices.Sort(r.lags)
+	r.LatencyP50 = percentile(r.latencies, 50)
+	r.LatencyP99 = percentile(r.latencies, 99)
+	r.LatencyMax = percentile(r.latencies, 100)
+	r.LagP99 = percentile(r.lags, 99)
+	r.LagMax = percentile(r.lags, 100)
+}
+
+// percentile returns the p-th percentile of sorted.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[(len(sorted)-1)*p/100]
+}
+
+// String formats the report for a terminal.
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "requests: %d in %s (%d errors, %d bytes sent)\n", r.Requests, r.Elapsed.Round(time.Millisecond), r.Errors, r.BytesSent)
+	for _, m := range slices.Sorted(maps.Keys(r.ByMethod)) {
+		fmt.Fprintf(&b, "  %-7s %d\n", m, r.ByMethod[m])
+	}
+	for _, s := range slices.Sorted(maps.Keys(r.ByStatus)) {
+		fmt.Fprintf(&b, "  %-7s %d\n", s, r.ByStatus[s])
+	}
+	fmt.Fprintf(&b, "latency: p50 %s, p99 %s, max %s\n", r.LatencyP50, r.LatencyP99, r.LatencyMax)
+	fmt.Fprintf(&b, "lag: p99 %s, max %s\n", r.LagP99, r.LagMax)
+	for _, e := range r.FirstErrors {
+		fmt.Fprintf(&b, "error: %s\n", e)
+	}
+	return b.String()
+}
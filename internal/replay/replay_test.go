@@ -0,0 +1,109 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+const accessLog = `{"level":"info","msg":"Starting S3 Encryption Gateway","time":"2026-05-01T10:00:00Z"}
+{"bytes":2048,"duration_ms":500,"level":"info","method":"PUT","msg":"HTTP request","path":"/photos/a.jpg","remote_addr":"10.0.0.1:5000","status":200,"time":"2026-05-01T10:00:01Z"}
+{"bytes":2048,"duration_ms":3,"level":"info","method":"GET","msg":"HTTP request","path":"/photos/a.jpg","remote_addr":"10.0.0.1:5000","status":200,"time":"2026-05-01T10:00:01Z"}
+{"json":"{\"timestamp\":\"2026-05-01T10:00:03Z\",\"method\":\"GET\",\"path\":\"/photos\",\"query\":\"list-type=2&x-amz-tagging=%5BREDACTED%5D\",\"status\":200,\"duration_ms\":0,\"bytes\":512}","level":"info","msg":"HTTP request","time":"2026-05-01T10:00:03Z"}
+{"clf":"10.0.0.1:5000 - AKIAEXAMPLE [2026-05-01T10:00:04Z] \"DELETE /photos/a.jpg HTTP/1.1\" 204 0","level":"info","msg":"HTTP request","time":"2026-05-01T10:00:04Z"}
+{"bytes":0,"duration_ms":2,"level":"info","method":"POST","msg":"HTTP request","path":"/photos/big.bin","query":"uploads=","status":200,"time":"2026-05-01T10:00:04Z"}
+not json at all
+`
+
+func TestParse(t *testing.T) {
+	res, err := Parse(strings.NewReader(accessLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Skipped != 1 || res.Ignored != 2 {
+		t.Errorf("skipped %d, ignored %d", res.Skipped, res.Ignored)
+	}
+	// The PUT started 500 ms before its log second. The GET shares that
+	// second, so it is spread half a second into it, less its 3 ms.
+	want := []Request{
+		{Offset: 0, Method: "PUT", Path: "/photos/a.jpg", Size: 2048},
+		{Offset: 997 * time.Millisecond, Method: "GET", Path: "/photos/a.jpg", Size: 2048},
+		{Offset: 2500 * time.Millisecond, Method: "GET", Path: "/photos", Query: "list-type=2", Size: 512},
+		{Offset: 3500 * time.Millisecond, Method: "DELETE", Path: "/photos/a.jpg"},
+	}
+	if len(res.Requests) != len(want) {
+		t.Fatalf("requests = %+v", res.Requests)
+	}
+	for i, w := range want {
+		if res.Requests[i] != w {
+			t.Errorf("request %d = %+v, want %+v", i, res.Requests[i], w)
+		}
+	}
+}
+
+func TestBody_Deterministic(t *testing.T) {
+	a, _ := io.ReadAll(Body(1, "/b/k", 1000))
+	b, _ := io.ReadAll(Body(1, "/b/k", 1000))
+	c, _ := io.ReadAll(Body(2, "/b/k", 1000))
+	if len(a) != 1000 || !bytes.Equal(a, b) || bytes.Equal(a, c) {
+		t.Error("bodies are not determined by seed and path")
+	}
+}
+
+func TestPlayer_Run(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		mu.Lock()
+		seen = append(seen, r.Method+" "+r.URL.RequestURI()+" "+r.Header.Get("Authorization")[:16])
+		mu.Unlock()
+		if r.Method == http.MethodPut && n != 2048 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+	target, _ := url.Parse(srv.URL)
+
+	parsed, err := Parse(strings.NewReader(accessLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Player{
+		Target:      target,
+		Speed:       20,
+		Concurrency: 1,
+		BucketMap:   map[string]string{"photos": "staging-photos"},
+		Credentials: &aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+	}
+	start := time.Now()
+	report := p.Run(context.Background(), parsed.Requests)
+	if elapsed := time.Since(start); elapsed < 3500*time.Millisecond/20 {
+		t.Errorf("replay at 20x took %s, schedule spans 175ms", elapsed)
+	}
+	if report.Requests != 4 || report.Errors != 0 || report.ByStatus["2xx"] != 4 || report.BytesSent != 2048 {
+		t.Errorf("report = %+v", report)
+	}
+	want := []string{
+		"PUT /staging-photos/a.jpg AWS4-HMAC-SHA256",
+		"GET /staging-photos/a.jpg AWS4-HMAC-SHA256",
+		"GET /staging-photos?list-type=2 AWS4-HMAC-SHA256",
+		"DELETE /staging-photos/a.jpg AWS4-HMAC-SHA256",
+	}
+	if strings.Join(seen, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests:\n%s", strings.Join(seen, "\n"))
+	}
+}
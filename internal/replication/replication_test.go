@@ -0,0 +1,123 @@
+package replication
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func quietLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func seed(t *testing.T, c *testsupport.MemoryClient, keys ...string) {
+	t.Helper()
+	for _, k := range keys {
+		body := []byte("ciphertext of " + k)
+		size := int64(len(body))
+		meta := map[string]string{"x-amz-meta-encrypted": "true", "x-amz-meta-encryption-iv": "iv-" + k}
+		if err := c.PutObject(context.Background(), "src", k, bytes.NewReader(body), meta, &size, "", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReplicator_CopiesAndSkips(t *testing.T) {
+	source, target := testsupport.NewMemoryClient(), testsupport.NewMemoryClient()
+	seed(t, source, "a", "b", "dir/c")
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	r := &Replicator{Source: source, Target: target, SourceBucket: "src", TargetBucket: "dst", StateFile: stateFile, Logger: quietLogger()}
+
+	state, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Stats.Copied != 3 || !state.Complete {
+		t.Fatalf("first pass: %+v", state)
+	}
+	for _, k := range []string{"a", "b", "dir/c"} {
+		want, wantMeta, _ := source.Object("src", k)
+		got, gotMeta, ok := target.Object("dst", k)
+		if !ok || !bytes.Equal(got, want) {
+			t.Errorf("%s: target body %q, want %q", k, got, want)
+		}
+		if gotMeta["x-amz-meta-encryption-iv"] != wantMeta["x-amz-meta-encryption-iv"] {
+			t.Errorf("%s: metadata not preserved: %v", k, gotMeta)
+		}
+	}
+
+	state, err = r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Stats.Copied != 0 || state.Stats.Skipped != 3 {
+		t.Errorf("second pass should skip everything: %+v", state.Stats)
+	}
+}
+
+func TestReplicator_ResumesFromCheckpoint(t *testing.T) {
+	source, target := testsupport.NewMemoryClient(), testsupport.NewMemoryClient()
+	seed(t, source, "a", "b", "c", "d")
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	partial := newState("src", "src", "")
+	partial.Checkpoint = "b"
+	if err := partial.save(stateFile); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Replicator{Source: source, Target: target, SourceBucket: "src", StateFile: stateFile, Logger: quietLogger()}
+	state, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Stats.Copied != 2 {
+		t.Errorf("copied %d, want 2", state.Stats.Copied)
+	}
+	if _, _, ok := target.Object("src", "a"); ok {
+		t.Error("key before the checkpoint was copied again")
+	}
+	if _, _, ok := target.Object("src", "d"); !ok {
+		t.Error("key after the checkpoint was not copied")
+	}
+}
+
+func TestReplicator_FailuresAreRetriedNextPass(t *testing.T) {
+	source, target := testsupport.NewMemoryClient(), testsupport.NewMemoryClient()
+	seed(t, source, "a", "b")
+	fail := true
+	target.OnCall = func(op, bucket, key string) error {
+		if op == "PutObject" && key == "a" && fail {
+			return errors.New("boom")
+		}
+		return nil
+	}
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	r := &Replicator{Source: source, Target: target, SourceBucket: "src", StateFile: stateFile, Logger: quietLogger()}
+
+	state, err := r.Run(context.Background())
+	if err == nil || state.Stats.Failed != 1 || len(state.Failed) != 1 || state.Failed[0].Key != "a" {
+		t.Fatalf("Run = %v, state %+v", err, state)
+	}
+	fail = false
+	state, err = r.Run(context.Background())
+	if err != nil || state.Stats.Copied != 1 || state.Stats.Skipped != 1 {
+		t.Fatalf("retry pass = %v, stats %+v", err, state.Stats)
+	}
+}
+
+func TestReplicator_RejectsForeignState(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	if err := newState("other", "other", "").save(stateFile); err != nil {
+		t.Fatal(err)
+	}
+	r := &Replicator{Source: testsupport.NewMemoryClient(), Target: testsupport.NewMemoryClient(), SourceBucket: "src", StateFile: stateFile, Logger: quietLogger()}
+	if _, err := r.Run(context.Background()); err == nil {
+		t.Error("a state file for other buckets should be rejected")
+	}
+}
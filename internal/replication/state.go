@@ -0,0 +1,104 @@
+package replication
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Stats counts what a replication pass did.
+type Stats struct {
+	Copied  int64 `json:"copied"`
+	Skipped int64 `json:"skipped"` // already present on the target
+	Failed  int64 `json:"failed"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// FailedObject records an object that could not be replicated.
+type FailedObject struct {
+	Key   string `json:"key"`
+	Error string `json:"error"`
+}
+
+// State is the checkpointed progress of a replication pass. A pass that is
+// interrupted resumes after Checkpoint; once Complete, the next run starts
+// a new pass from the beginning.
+type State struct {
+	SourceBucket string         `json:"source_bucket"`
+	TargetBucket string         `json:"target_bucket"`
+	Prefix       string         `json:"prefix,omitempty"`
+	Checkpoint   string         `json:"checkpoint,omitempty"` // last key handled
+	Complete     bool           `json:"complete"`
+	Started      time.Time      `json:"started"`
+	Updated      time.Time      `json:"updated"`
+	Stats        Stats          `json:"stats"`
+	Failed       []FailedObject `json:"failed,omitempty"`
+}
+
+func newState(sourceBucket, targetBucket, prefix string) *State {
+	now := time.Now().UTC()
+	return &State{
+		SourceBucket: sourceBucket,
+		TargetBucket: targetBucket,
+		Prefix:       prefix,
+		Started:      now,
+		Updated:      now,
+	}
+}
+
+// loadState reads the state at path. A missing file, a completed pass or
+// an empty path yields a fresh state; a file written for other buckets or
+// another prefix is an error.
+func loadState(path, sourceBucket, targetBucket, prefix string) (*State, error) {
+	if path == "" {
+		return newState(sourceBucket, targetBucket, prefix), nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return newState(sourceBucket, targetBucket, prefix), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse state file: %w", err)
+	}
+	if s.SourceBucket != sourceBucket || s.TargetBucket != targetBucket || s.Prefix != prefix {
+		return nil, fmt.Errorf("state file %s is for %s -> %s (prefix %q), not %s -> %s (prefix %q)",
+			path, s.SourceBucket, s.TargetBucket, s.Prefix, sourceBucket, targetBucket, prefix)
+	}
+	if s.Complete {
+		return newState(sourceBucket, targetBucket, prefix), nil
+	}
+	return &s, nil
+}
+
+// save atomically writes the state to path; it is a no-op when path is
+// empty.
+func (s *State) save(path string) error {
+	if path == "" {
+		return nil
+	}
+	s.Updated = time.Now().UTC()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			return fmt.Errorf("failed to create state directory: %w", err)
+		}
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write temporary state file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename state file: %w", err)
+	}
+	return nil
+}
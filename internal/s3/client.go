@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -24,6 +25,7 @@ import (
 
 	"github.com/kenneth/s3-encryption-gateway/internal/config"
 	"github.com/kenneth/s3-encryption-gateway/internal/debug"
+	"github.com/kenneth/s3-encryption-gateway/internal/headerrules"
 	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -58,6 +60,11 @@ type Client interface {
 	GetObjectLegalHold(ctx context.Context, bucket, key string, versionID *string) (string, error)
 	PutObjectLockConfiguration(ctx context.Context, bucket string, config *ObjectLockConfiguration) error
 	GetObjectLockConfiguration(ctx context.Context, bucket string) (*ObjectLockConfiguration, error)
+
+	// Object tagging operations
+	PutObjectTagging(ctx context.Context, bucket, key string, versionID *string, tags []Tag) error
+	GetObjectTagging(ctx context.Context, bucket, key string, versionID *string) ([]Tag, error)
+	DeleteObjectTagging(ctx context.Context, bucket, key string, versionID *string) error
 }
 
 // ObjectLockInput contains object lock parameters for put/copy operations.
@@ -96,6 +103,19 @@ type DefaultRetention struct {
 	Years *int32 `xml:"Years,omitempty"` // exactly one of Days/Years
 }
 
+// Tag is one entry of an object's tag set.
+type Tag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+// Tagging is the XML document of the PutObjectTagging request and the
+// GetObjectTagging response.
+type Tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	TagSet  []Tag    `xml:"TagSet>Tag"`
+}
+
 // ListOptions holds options for listing objects.
 type ListOptions struct {
 	Delimiter         string
@@ -181,6 +201,10 @@ type ClientFactory struct {
 	retryerFactory *retryerFactory           // nil → use SDK default
 	m              *metrics.Metrics          // nil → no retry metrics
 	httpTransport  http.RoundTripper         // nil → use SDK default transport
+	transport      *backendTransport         // proxy and CA settings from config
+	transportErr   error                     // returned by every GetClient call
+	headerRules    *headerrules.Rules        // nil → requests sent as built
+	readHealth     *primaryHealth            // nil → no persistent read failover
 }
 
 // ClientFactoryOption is a functional option for NewClientFactory.
@@ -217,9 +241,12 @@ func NewClientFactory(cfg *config.BackendConfig, opts ...ClientFactoryOption) *C
 		baseConfig:  cfg,
 		retryConfig: rc,
 	}
+	f.transport, f.transportErr = newBackendTransport(cfg)
+	f.headerRules = headerrules.New(cfg.HeaderRules, config.ProtectedBackendHeaders...)
 	for _, opt := range opts {
 		opt(f)
 	}
+	f.readHealth = newPrimaryHealth(cfg.ReadReplicas, f.m)
 
 	// Build the retryer factory if mode != "off".
 	if rc.Mode != "off" {
@@ -257,7 +284,40 @@ func (f *ClientFactory) GetClientWithCredentials(accessKey, secretKey string) (C
 	if secretKey == "" {
 		return nil, fmt.Errorf("secret key is required")
 	}
+	if f.transportErr != nil {
+		return nil, f.transportErr
+	}
+
+	primary, err := f.newEndpointClient(accessKey, secretKey, f.baseConfig.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	replicas := f.baseConfig.ReadReplicas
+	if len(replicas.Endpoints) == 0 {
+		return primary, nil
+	}
+	clients := []Client{primary}
+	names := []string{f.baseConfig.Endpoint}
+	if names[0] == "" {
+		names[0] = "default"
+	}
+	for _, ep := range replicas.Endpoints {
+		c, err := f.newEndpointClient(accessKey, secretKey, ep)
+		if err != nil {
+			return nil, fmt.Errorf("read replica %s: %w", ep, err)
+		}
+		clients = append(clients, c)
+		names = append(names, ep)
+	}
+	rc := newReplicaClient(clients, replicas.HedgeAfter, f.m)
+	rc.names = names
+	rc.health = f.readHealth
+	return rc, nil
+}
 
+// newEndpointClient builds an SDK-backed client for one backend endpoint.
+// An empty endpoint selects the AWS default.
+func (f *ClientFactory) newEndpointClient(accessKey, secretKey, endpoint string) (*s3Client, error) {
 	// Use default region if not provided
 	region := f.baseConfig.Region
 	if region == "" {
@@ -274,6 +334,11 @@ func (f *ClientFactory) GetClientWithCredentials(accessKey, secretKey string) (C
 			if f.httpTransport != nil {
 				return awsconfig.WithHTTPClient(&http.Client{Transport: f.httpTransport})
 			}
+			// Proxy and CA overrides go on the SDK's own client so its
+			// timeouts and AWS_CA_BUNDLE handling are kept.
+			if f.transport.custom {
+				return awsconfig.WithHTTPClient(awshttp.NewBuildableClient().WithTransportOptions(f.transport.apply))
+			}
 			return func(*awsconfig.LoadOptions) error { return nil }
 		}(),
 		awsconfig.WithRegion(region),
@@ -326,8 +391,8 @@ func (f *ClientFactory) GetClientWithCredentials(accessKey, secretKey string) (C
 	s3Options := []func(*s3.Options){}
 
 	// Set custom endpoint if provided (for any S3-compatible provider)
-	if f.baseConfig.Endpoint != "" {
-		endpoint := normalizeEndpoint(f.baseConfig.Endpoint)
+	if endpoint != "" {
+		endpoint := normalizeEndpoint(endpoint)
 
 		// Validate endpoint URL
 		if err := validateEndpoint(endpoint); err != nil {
@@ -347,6 +412,19 @@ func (f *ClientFactory) GetClientWithCredentials(accessKey, secretKey string) (C
 		})
 	}
 
+	s3Options = append(s3Options, func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, addRequestIDMiddleware)
+	})
+
+	if f.headerRules != nil {
+		rules := f.headerRules
+		s3Options = append(s3Options, func(o *s3.Options) {
+			o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+				return addHeaderRulesMiddleware(stack, rules)
+			})
+		})
+	}
+
 	client := s3.NewFromConfig(awsCfg, s3Options...)
 
 	return &s3Client{
@@ -356,6 +434,16 @@ func (f *ClientFactory) GetClientWithCredentials(accessKey, secretKey string) (C
 	}, nil
 }
 
+// ConfigureTransport applies the backend proxy and CA settings to tr, for
+// HTTP clients that reach the backend without going through the SDK.
+func (f *ClientFactory) ConfigureTransport(tr *http.Transport) error {
+	if f.transportErr != nil {
+		return f.transportErr
+	}
+	f.transport.apply(tr)
+	return nil
+}
+
 // NewClient creates a new S3 backend client (backward compatibility).
 // It works with any S3-compatible API provider by configuring the endpoint.
 func NewClient(cfg *config.BackendConfig) (Client, error) {
@@ -443,6 +531,26 @@ func (c *s3Client) PutObject(ctx context.Context, bucket, key string, reader io.
 	if tags != "" {
 		input.Tagging = aws.String(tags)
 	}
+	if conds, ok := WriteConditionsFromContext(ctx); ok {
+		if conds.IfMatch != "" {
+			input.IfMatch = aws.String(conds.IfMatch)
+		}
+		if conds.IfNoneMatch != "" {
+			input.IfNoneMatch = aws.String(conds.IfNoneMatch)
+		}
+	}
+	if class, ok := StorageClassFromContext(ctx); ok {
+		input.StorageClass = types.StorageClass(class)
+	}
+
+	if partSize, concurrency, ok := c.streamingUploadPlan(reader, contentLength); ok {
+		if err := c.putObjectStreaming(ctx, input, lock, partSize, concurrency); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return fmt.Errorf("failed to put object %s/%s: %w", bucket, key, err)
+		}
+		span.SetStatus(codes.Ok, "")
+		return nil
+	}
 
 	// For non-seekable readers (e.g. streaming chunked encrypted data), the
 	// SigV4 ComputePayloadSHA256 middleware would fail because it reads the
@@ -460,7 +568,7 @@ func (c *s3Client) PutObject(ctx context.Context, bucket, key string, reader io.
 	_, err := c.client.PutObject(ctx, input, putOpts...)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
-		return fmt.Errorf("failed to put object %s/%s: %w", bucket, key, err)
+		return fmt.Errorf("failed to put object %s/%s: %w", bucket, key, classifyBackendError(err))
 	}
 
 	span.SetStatus(codes.Ok, "")
@@ -489,11 +597,14 @@ func (c *s3Client) GetObject(ctx context.Context, bucket, key string, versionID
 	if rangeHeader != nil && *rangeHeader != "" {
 		input.Range = rangeHeader
 	}
+	if conds, ok := ReadConditionsFromContext(ctx); ok {
+		input.IfNoneMatch = aws.String(conds.IfNoneMatch)
+	}
 
 	result, err := c.client.GetObject(ctx, input)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
-		return nil, nil, fmt.Errorf("failed to get object %s/%s: %w", bucket, key, err)
+		return nil, nil, fmt.Errorf("failed to get object %s/%s: %w", bucket, key, classifyBackendError(err))
 	}
 
 	metadata := extractMetadata(result.Metadata)
@@ -526,6 +637,9 @@ func (c *s3Client) GetObject(ctx context.Context, bucket, key string, versionID
 	if result.ContentLength != nil {
 		metadata["Content-Length"] = fmt.Sprintf("%d", *result.ContentLength)
 	}
+	if result.ContentRange != nil {
+		metadata["Content-Range"] = *result.ContentRange
+	}
 	if result.ContentType != nil {
 		metadata["Content-Type"] = *result.ContentType
 	}
@@ -578,7 +692,7 @@ func (c *s3Client) DeleteObject(ctx context.Context, bucket, key string, version
 	_, err := c.client.DeleteObject(ctx, input)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
-		return fmt.Errorf("failed to delete object %s/%s: %w", bucket, key, err)
+		return fmt.Errorf("failed to delete object %s/%s: %w", bucket, key, classifyBackendError(err))
 	}
 
 	span.SetStatus(codes.Ok, "")
@@ -598,7 +712,7 @@ func (c *s3Client) HeadObject(ctx context.Context, bucket, key string, versionID
 
 	result, err := c.client.HeadObject(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to head object %s/%s: %w", bucket, key, err)
+		return nil, fmt.Errorf("failed to head object %s/%s: %w", bucket, key, classifyBackendError(err))
 	}
 
 	metadata := extractMetadata(result.Metadata)
@@ -650,7 +764,7 @@ func (c *s3Client) ListObjects(ctx context.Context, bucket, prefix string, opts
 
 	result, err := c.client.ListObjectsV2(ctx, input)
 	if err != nil {
-		return ListResult{}, fmt.Errorf("failed to list objects in bucket %s: %w", bucket, err)
+		return ListResult{}, fmt.Errorf("failed to list objects in bucket %s: %w", bucket, classifyBackendError(err))
 	}
 
 	objects := make([]ObjectInfo, 0, len(result.Contents))
@@ -740,7 +854,7 @@ func (c *s3Client) CreateMultipartUpload(ctx context.Context, bucket, key string
 
 	result, err := c.client.CreateMultipartUpload(ctx, input)
 	if err != nil {
-		return "", fmt.Errorf("failed to create multipart upload %s/%s: %w", bucket, key, err)
+		return "", fmt.Errorf("failed to create multipart upload %s/%s: %w", bucket, key, classifyBackendError(err))
 	}
 
 	if result.UploadId == nil {
@@ -766,7 +880,7 @@ func (c *s3Client) UploadPart(ctx context.Context, bucket, key, uploadID string,
 
 	result, err := c.client.UploadPart(ctx, input)
 	if err != nil {
-		return "", fmt.Errorf("failed to upload part %d for %s/%s: %w", partNumber, bucket, key, err)
+		return "", fmt.Errorf("failed to upload part %d for %s/%s: %w", partNumber, bucket, key, classifyBackendError(err))
 	}
 
 	if result.ETag == nil {
@@ -794,10 +908,18 @@ func (c *s3Client) CompleteMultipartUpload(ctx context.Context, bucket, key, upl
 			Parts: completedParts,
 		},
 	}
+	if conds, ok := WriteConditionsFromContext(ctx); ok {
+		if conds.IfMatch != "" {
+			input.IfMatch = aws.String(conds.IfMatch)
+		}
+		if conds.IfNoneMatch != "" {
+			input.IfNoneMatch = aws.String(conds.IfNoneMatch)
+		}
+	}
 
 	result, err := c.client.CompleteMultipartUpload(ctx, input)
 	if err != nil {
-		return "", fmt.Errorf("failed to complete multipart upload %s/%s: %w", bucket, key, err)
+		return "", fmt.Errorf("failed to complete multipart upload %s/%s: %w", bucket, key, classifyBackendError(err))
 	}
 
 	if lock != nil {
@@ -835,7 +957,7 @@ func (c *s3Client) AbortMultipartUpload(ctx context.Context, bucket, key, upload
 
 	_, err := c.client.AbortMultipartUpload(ctx, input)
 	if err != nil {
-		return fmt.Errorf("failed to abort multipart upload %s/%s: %w", bucket, key, err)
+		return fmt.Errorf("failed to abort multipart upload %s/%s: %w", bucket, key, classifyBackendError(err))
 	}
 
 	return nil
@@ -851,7 +973,7 @@ func (c *s3Client) ListParts(ctx context.Context, bucket, key, uploadID string)
 
 	result, err := c.client.ListParts(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list parts for %s/%s: %w", bucket, key, err)
+		return nil, fmt.Errorf("failed to list parts for %s/%s: %w", bucket, key, classifyBackendError(err))
 	}
 
 	parts := make([]PartInfo, 0, len(result.Parts))
@@ -870,7 +992,8 @@ func (c *s3Client) ListParts(ctx context.Context, bucket, key, uploadID string)
 	return parts, nil
 }
 
-// CopyObject copies an object from source to destination.
+// CopyObject copies an object from source to destination. A non-nil
+// metadata replaces the source's metadata; nil keeps it.
 func (c *s3Client) CopyObject(ctx context.Context, dstBucket, dstKey string, srcBucket, srcKey string, srcVersionID *string, metadata map[string]string, lock *ObjectLockInput) (string, map[string]string, error) {
 	copySource := fmt.Sprintf("%s/%s", srcBucket, srcKey)
 	if srcVersionID != nil && *srcVersionID != "" {
@@ -883,6 +1006,11 @@ func (c *s3Client) CopyObject(ctx context.Context, dstBucket, dstKey string, src
 		CopySource: aws.String(copySource),
 		Metadata:   convertMetadata(metadata),
 	}
+	if metadata != nil {
+		// Without REPLACE the backend keeps the source's metadata and
+		// ignores the map.
+		input.MetadataDirective = types.MetadataDirectiveReplace
+	}
 	if lock != nil {
 		if lock.Mode != "" {
 			input.ObjectLockMode = types.ObjectLockMode(lock.Mode)
@@ -897,7 +1025,7 @@ func (c *s3Client) CopyObject(ctx context.Context, dstBucket, dstKey string, src
 
 	result, err := c.client.CopyObject(ctx, input)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to copy object from %s/%s to %s/%s: %w", srcBucket, srcKey, dstBucket, dstKey, err)
+		return "", nil, fmt.Errorf("failed to copy object from %s/%s to %s/%s: %w", srcBucket, srcKey, dstBucket, dstKey, classifyBackendError(err))
 	}
 
 	resultMetadata := make(map[string]string)
@@ -941,7 +1069,7 @@ func (c *s3Client) UploadPartCopy(ctx context.Context, dstBucket, dstKey, upload
 
 	result, err := c.client.UploadPartCopy(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to copy object part from %s/%s to %s/%s: %w", srcBucket, srcKey, dstBucket, dstKey, err)
+		return nil, fmt.Errorf("failed to copy object part from %s/%s to %s/%s: %w", srcBucket, srcKey, dstBucket, dstKey, classifyBackendError(err))
 	}
 
 	if result.CopyPartResult == nil {
@@ -1004,7 +1132,7 @@ func (c *s3Client) DeleteObjects(ctx context.Context, bucket string, keys []Obje
 			o.APIOptions = append(o.APIOptions, addContentMD5Middleware)
 		})
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to delete objects in bucket %s: %w", bucket, err)
+		return nil, nil, fmt.Errorf("failed to delete objects in bucket %s: %w", bucket, classifyBackendError(err))
 	}
 
 	deleted := make([]DeletedObject, 0, len(result.Deleted))
@@ -1055,7 +1183,7 @@ func (c *s3Client) PutObjectRetention(ctx context.Context, bucket, key string, v
 	}
 	_, err := c.client.PutObjectRetention(ctx, input)
 	if err != nil {
-		return fmt.Errorf("failed to put object retention %s/%s: %w", bucket, key, err)
+		return fmt.Errorf("failed to put object retention %s/%s: %w", bucket, key, classifyBackendError(err))
 	}
 	return nil
 }
@@ -1071,7 +1199,7 @@ func (c *s3Client) GetObjectRetention(ctx context.Context, bucket, key string, v
 	}
 	result, err := c.client.GetObjectRetention(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get object retention %s/%s: %w", bucket, key, err)
+		return nil, fmt.Errorf("failed to get object retention %s/%s: %w", bucket, key, classifyBackendError(err))
 	}
 	if result.Retention == nil {
 		return nil, nil
@@ -1096,7 +1224,7 @@ func (c *s3Client) PutObjectLegalHold(ctx context.Context, bucket, key string, v
 	}
 	_, err := c.client.PutObjectLegalHold(ctx, input)
 	if err != nil {
-		return fmt.Errorf("failed to put object legal hold %s/%s: %w", bucket, key, err)
+		return fmt.Errorf("failed to put object legal hold %s/%s: %w", bucket, key, classifyBackendError(err))
 	}
 	return nil
 }
@@ -1112,7 +1240,7 @@ func (c *s3Client) GetObjectLegalHold(ctx context.Context, bucket, key string, v
 	}
 	result, err := c.client.GetObjectLegalHold(ctx, input)
 	if err != nil {
-		return "", fmt.Errorf("failed to get object legal hold %s/%s: %w", bucket, key, err)
+		return "", fmt.Errorf("failed to get object legal hold %s/%s: %w", bucket, key, classifyBackendError(err))
 	}
 	if result.LegalHold == nil {
 		return "", nil
@@ -1146,7 +1274,7 @@ func (c *s3Client) PutObjectLockConfiguration(ctx context.Context, bucket string
 	}
 	_, err := c.client.PutObjectLockConfiguration(ctx, input)
 	if err != nil {
-		return fmt.Errorf("failed to put object lock configuration for bucket %s: %w", bucket, err)
+		return fmt.Errorf("failed to put object lock configuration for bucket %s: %w", bucket, classifyBackendError(err))
 	}
 	return nil
 }
@@ -1158,7 +1286,7 @@ func (c *s3Client) GetObjectLockConfiguration(ctx context.Context, bucket string
 	}
 	result, err := c.client.GetObjectLockConfiguration(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get object lock configuration for bucket %s: %w", bucket, err)
+		return nil, fmt.Errorf("failed to get object lock configuration for bucket %s: %w", bucket, classifyBackendError(err))
 	}
 	if result.ObjectLockConfiguration == nil {
 		return nil, nil
@@ -1182,6 +1310,62 @@ func (c *s3Client) GetObjectLockConfiguration(ctx context.Context, bucket string
 	return cfg, nil
 }
 
+// PutObjectTagging replaces the tag set of an object.
+func (c *s3Client) PutObjectTagging(ctx context.Context, bucket, key string, versionID *string, tags []Tag) error {
+	input := &s3.PutObjectTaggingInput{
+		Bucket:  aws.String(bucket),
+		Key:     aws.String(key),
+		Tagging: &types.Tagging{TagSet: make([]types.Tag, 0, len(tags))},
+	}
+	if versionID != nil && *versionID != "" {
+		input.VersionId = versionID
+	}
+	for _, t := range tags {
+		input.Tagging.TagSet = append(input.Tagging.TagSet, types.Tag{Key: aws.String(t.Key), Value: aws.String(t.Value)})
+	}
+	_, err := c.client.PutObjectTagging(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to put object tagging %s/%s: %w", bucket, key, classifyBackendError(err))
+	}
+	return nil
+}
+
+// GetObjectTagging returns the tag set of an object.
+func (c *s3Client) GetObjectTagging(ctx context.Context, bucket, key string, versionID *string) ([]Tag, error) {
+	input := &s3.GetObjectTaggingInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	if versionID != nil && *versionID != "" {
+		input.VersionId = versionID
+	}
+	result, err := c.client.GetObjectTagging(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object tagging %s/%s: %w", bucket, key, classifyBackendError(err))
+	}
+	tags := make([]Tag, 0, len(result.TagSet))
+	for _, t := range result.TagSet {
+		tags = append(tags, Tag{Key: aws.ToString(t.Key), Value: aws.ToString(t.Value)})
+	}
+	return tags, nil
+}
+
+// DeleteObjectTagging removes every tag from an object.
+func (c *s3Client) DeleteObjectTagging(ctx context.Context, bucket, key string, versionID *string) error {
+	input := &s3.DeleteObjectTaggingInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	if versionID != nil && *versionID != "" {
+		input.VersionId = versionID
+	}
+	_, err := c.client.DeleteObjectTagging(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to delete object tagging %s/%s: %w", bucket, key, classifyBackendError(err))
+	}
+	return nil
+}
+
 // addContentMD5Middleware registers a finalize-stage smithy middleware that
 // computes the MD5 digest of the request body and sets the Content-MD5 header.
 // Used for DeleteObjects against S3-compatible backends that still require the
@@ -1226,6 +1410,23 @@ func addContentMD5Middleware(stack *middleware.Stack) error {
 		}), middleware.Before)
 }
 
+// addHeaderRulesMiddleware applies the configured backend header rules to
+// every request. It runs after the retry middleware has added its headers
+// and before signing, so rewritten headers are the ones signed and sent.
+func addHeaderRulesMiddleware(stack *middleware.Stack, rules *headerrules.Rules) error {
+	mw := middleware.FinalizeMiddlewareFunc("HeaderRules",
+		func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			if req, ok := in.Request.(*smithyhttp.Request); ok {
+				rules.Apply(req.Header)
+			}
+			return next.HandleFinalize(ctx, in)
+		})
+	if _, ok := stack.Finalize.Get("Signing"); ok {
+		return stack.Finalize.Insert(mw, "Signing", middleware.Before)
+	}
+	return stack.Finalize.Add(mw, middleware.After)
+}
+
 // bytesReader is a minimal seekable reader for the middleware above.
 type bytesReader struct {
 	b   []byte
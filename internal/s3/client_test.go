@@ -1318,3 +1318,38 @@ func TestS3Client_ValidateEndpoint_EdgeCases(t *testing.T) {
 		t.Error("expected error for path-only URL")
 	}
 }
+
+func TestClientFactory_BackendHeaderRules(t *testing.T) {
+	var got http.Header
+	transport := &fakeS3Transport{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	})}
+	cfg := &config.BackendConfig{
+		Endpoint:  "http://localhost:9000",
+		Region:    "us-east-1",
+		AccessKey: "AKIATEST",
+		SecretKey: "secrettest",
+		HeaderRules: []config.HeaderRule{
+			{Action: config.HeaderRuleRemove, Name: "amz-sdk-*"},
+			{Action: config.HeaderRuleRemove, Name: "x-amz-*"},
+			{Action: config.HeaderRuleSet, Name: "X-Provider-Hint", Value: "legacy"},
+		},
+	}
+	c, err := NewClientFactory(cfg, WithHTTPTransport(transport)).GetClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.HeadObject(context.Background(), "bucket", "key", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get("Amz-Sdk-Invocation-Id") != "" || got.Get("Amz-Sdk-Request") != "" {
+		t.Errorf("amz-sdk-* headers not removed: %v", got)
+	}
+	if got.Get("X-Amz-Date") == "" || got.Get("X-Amz-Content-Sha256") == "" {
+		t.Errorf("wildcard removal must keep signing headers: %v", got)
+	}
+	if got.Get("X-Provider-Hint") != "legacy" || !strings.Contains(got.Get("Authorization"), "x-provider-hint") {
+		t.Errorf("set header missing or unsigned: %v", got)
+	}
+}
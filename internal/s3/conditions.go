@@ -0,0 +1,62 @@
+package s3
+
+import "context"
+
+// WriteConditions are backend preconditions for a single object write.
+// They map onto the If-Match / If-None-Match headers of PutObject and
+// CompleteMultipartUpload; backends that do not support conditional writes
+// ignore them.
+type WriteConditions struct {
+	IfMatch     string // ETag the current object must have
+	IfNoneMatch string // "*" to require that no object exists yet
+}
+
+// IsZero reports whether no precondition is set.
+func (c WriteConditions) IsZero() bool {
+	return c.IfMatch == "" && c.IfNoneMatch == ""
+}
+
+type writeConditionsKey struct{}
+
+// WithWriteConditions returns a context that makes the next PutObject or
+// CompleteMultipartUpload issued with it conditional. Derive it only for the
+// call that needs it so unrelated writes sharing the parent context stay
+// unconditional.
+func WithWriteConditions(ctx context.Context, c WriteConditions) context.Context {
+	if c.IsZero() {
+		return ctx
+	}
+	return context.WithValue(ctx, writeConditionsKey{}, c)
+}
+
+// WriteConditionsFromContext returns the conditions attached by
+// WithWriteConditions, if any.
+func WriteConditionsFromContext(ctx context.Context) (WriteConditions, bool) {
+	c, ok := ctx.Value(writeConditionsKey{}).(WriteConditions)
+	return c, ok
+}
+
+// ReadConditions are backend preconditions for a single object read. They
+// map onto the If-None-Match header of GetObject.
+type ReadConditions struct {
+	IfNoneMatch string // ETag of a copy the caller already holds
+}
+
+type readConditionsKey struct{}
+
+// WithReadConditions returns a context that makes the next GetObject issued
+// with it conditional. When IfNoneMatch still names the object's ETag the
+// read fails with ErrNotModified instead of returning the body.
+func WithReadConditions(ctx context.Context, c ReadConditions) context.Context {
+	if c.IfNoneMatch == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, readConditionsKey{}, c)
+}
+
+// ReadConditionsFromContext returns the conditions attached by
+// WithReadConditions, if any.
+func ReadConditionsFromContext(ctx context.Context) (ReadConditions, bool) {
+	c, ok := ctx.Value(readConditionsKey{}).(ReadConditions)
+	return c, ok
+}
@@ -0,0 +1,34 @@
+package s3
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWriteConditionsContext(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := WriteConditionsFromContext(WithWriteConditions(ctx, WriteConditions{})); ok {
+		t.Error("zero conditions must not be attached to the context")
+	}
+
+	want := WriteConditions{IfMatch: `"abc"`}
+	got, ok := WriteConditionsFromContext(WithWriteConditions(ctx, want))
+	if !ok || got != want {
+		t.Errorf("got %+v (ok=%v), want %+v", got, ok, want)
+	}
+	if _, ok := WriteConditionsFromContext(ctx); ok {
+		t.Error("parent context must stay unconditional")
+	}
+}
+
+func TestReadConditionsContext(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := ReadConditionsFromContext(WithReadConditions(ctx, ReadConditions{})); ok {
+		t.Error("zero conditions must not be attached to the context")
+	}
+	want := ReadConditions{IfNoneMatch: `"abc"`}
+	got, ok := ReadConditionsFromContext(WithReadConditions(ctx, want))
+	if !ok || got != want {
+		t.Errorf("got %+v (ok=%v), want %+v", got, ok, want)
+	}
+}
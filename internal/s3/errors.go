@@ -0,0 +1,100 @@
+package s3
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// Sentinel errors for backend failures, for use with errors.Is. Client
+// methods attach them to the error they return while keeping the SDK error
+// reachable through errors.As.
+var (
+	// ErrNotFound is returned when the backend reports that the bucket,
+	// object, version or upload does not exist.
+	ErrNotFound = errors.New("s3: not found")
+
+	// ErrThrottled is returned when the backend asks the caller to slow
+	// down (HTTP 429/503, SlowDown and similar codes) and retries have been
+	// exhausted.
+	ErrThrottled = errors.New("s3: throttled")
+
+	// ErrNotModified is returned by a GetObject made conditional with
+	// WithReadConditions when the object still has the ETag given.
+	ErrNotModified = errors.New("s3: not modified")
+)
+
+// classifiedError tags a backend error with one of the sentinels above.
+// Error() is the original message so logs are unchanged.
+type classifiedError struct {
+	class error
+	err   error
+}
+
+func (e *classifiedError) Error() string   { return e.err.Error() }
+func (e *classifiedError) Unwrap() []error { return []error{e.class, e.err} }
+
+var (
+	notFoundCodes = map[string]bool{
+		"NoSuchKey":     true,
+		"NotFound":      true,
+		"NoSuchBucket":  true,
+		"NoSuchUpload":  true,
+		"NoSuchVersion": true,
+	}
+	throttledCodes = map[string]bool{
+		"SlowDown":                 true,
+		"Throttling":               true,
+		"ThrottlingException":      true,
+		"RequestLimitExceeded":     true,
+		"RequestThrottled":         true,
+		"TooManyRequests":          true,
+		"TooManyRequestsException": true,
+	}
+)
+
+// classifyBackendError attaches one of the sentinels above to err when the
+// SDK error says so, and returns err unchanged otherwise.
+func classifyBackendError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var class error
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch code := apiErr.ErrorCode(); {
+		case notFoundCodes[code]:
+			class = ErrNotFound
+		case throttledCodes[code]:
+			class = ErrThrottled
+		case code == "NotModified":
+			class = ErrNotModified
+		}
+	}
+	if class == nil {
+		var respErr *smithyhttp.ResponseError
+		if errors.As(err, &respErr) {
+			switch respErr.HTTPStatusCode() {
+			case http.StatusNotFound:
+				class = ErrNotFound
+			case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+				class = ErrThrottled
+			case http.StatusNotModified:
+				class = ErrNotModified
+			}
+		}
+	}
+	if class == nil || errors.Is(err, class) {
+		return err
+	}
+	return &classifiedError{class: class, err: err}
+}
+
+// NewAPIError returns an error carrying an S3 error code, classified like one
+// returned by the backend, for Client implementations that do not sit on
+// the SDK.
+func NewAPIError(code, message string) error {
+	return classifyBackendError(&smithy.GenericAPIError{Code: code, Message: message})
+}
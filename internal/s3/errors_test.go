@@ -0,0 +1,55 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/smithy-go"
+)
+
+func TestClassifyBackendError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"no such key", &smithy.GenericAPIError{Code: "NoSuchKey"}, ErrNotFound},
+		{"no such upload", &smithy.GenericAPIError{Code: "NoSuchUpload"}, ErrNotFound},
+		{"slow down", &smithy.GenericAPIError{Code: "SlowDown"}, ErrThrottled},
+		{"http 404", makeHTTPRespErr(t, 404, 0), ErrNotFound},
+		{"http 429", makeHTTPRespErr(t, 429, 0), ErrThrottled},
+		{"http 503", makeHTTPRespErr(t, 503, 0), ErrThrottled},
+		{"not modified", &smithy.GenericAPIError{Code: "NotModified"}, ErrNotModified},
+		{"http 304", makeHTTPRespErr(t, 304, 0), ErrNotModified},
+		{"access denied", &smithy.GenericAPIError{Code: "AccessDenied"}, nil},
+		{"plain", errors.New("connection reset"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fmt.Errorf("failed to get object b/k: %w", classifyBackendError(tt.err))
+			for _, class := range []error{ErrNotFound, ErrThrottled, ErrNotModified} {
+				if got := errors.Is(err, class); got != (class == tt.want) {
+					t.Errorf("errors.Is(%v) = %v", class, got)
+				}
+			}
+			if !errors.Is(err, tt.err) {
+				t.Error("original error is no longer reachable")
+			}
+			if err.Error() != "failed to get object b/k: "+tt.err.Error() {
+				t.Errorf("message changed: %q", err.Error())
+			}
+		})
+	}
+	if classifyBackendError(nil) != nil {
+		t.Error("classifyBackendError(nil) != nil")
+	}
+}
+
+func TestClassifyBackendError_KeepsAPIError(t *testing.T) {
+	err := classifyBackendError(&smithy.GenericAPIError{Code: "NoSuchBucket"})
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "NoSuchBucket" {
+		t.Fatalf("errors.As lost the API error: %v", err)
+	}
+}
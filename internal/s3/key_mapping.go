@@ -0,0 +1,195 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// KeyCodec translates object keys between the names clients use and the
+// names stored on the backend. crypto.KeyObfuscator is the implementation.
+type KeyCodec interface {
+	EncodeKey(bucket, key string) string
+	DecodeKey(bucket, name string) (string, error)
+	// EncodePrefix maps a listing prefix; exact is false when the backend
+	// prefix is broader and decoded keys must be filtered.
+	EncodePrefix(bucket, prefix string) (backendPrefix string, exact bool)
+}
+
+// keyMappingClient wraps a Client so every operation addresses the backend
+// by encoded key, and listings report decoded keys.
+type keyMappingClient struct {
+	Client
+	codec KeyCodec
+}
+
+// NewKeyMappingClient wraps inner so that keys are translated through codec
+// on every call. Wrapping an already-mapped client returns it unchanged.
+func NewKeyMappingClient(inner Client, codec KeyCodec) Client {
+	if inner == nil || codec == nil {
+		return inner
+	}
+	if isWrapped[*keyMappingClient](inner) {
+		return inner
+	}
+	return &keyMappingClient{Client: inner, codec: codec}
+}
+
+func (c *keyMappingClient) unwrap() Client { return c.Client }
+
+func (c *keyMappingClient) enc(bucket, key string) string {
+	return c.codec.EncodeKey(bucket, key)
+}
+
+func (c *keyMappingClient) PutObject(ctx context.Context, bucket, key string, reader io.Reader, metadata map[string]string, contentLength *int64, tags string, lock *ObjectLockInput) error {
+	return c.Client.PutObject(ctx, bucket, c.enc(bucket, key), reader, metadata, contentLength, tags, lock)
+}
+
+func (c *keyMappingClient) GetObject(ctx context.Context, bucket, key string, versionID *string, rangeHeader *string) (io.ReadCloser, map[string]string, error) {
+	return c.Client.GetObject(ctx, bucket, c.enc(bucket, key), versionID, rangeHeader)
+}
+
+func (c *keyMappingClient) DeleteObject(ctx context.Context, bucket, key string, versionID *string) error {
+	return c.Client.DeleteObject(ctx, bucket, c.enc(bucket, key), versionID)
+}
+
+func (c *keyMappingClient) HeadObject(ctx context.Context, bucket, key string, versionID *string) (map[string]string, error) {
+	return c.Client.HeadObject(ctx, bucket, c.enc(bucket, key), versionID)
+}
+
+// ListObjects lists under the encoded prefix and decodes the results.
+// Backend names that do not decode (objects written around the gateway, or
+// before obfuscation was enabled) are not addressable through it and are
+// left out. When the encoding hides the hierarchy from the backend, "/"
+// common prefixes are rebuilt here from the decoded keys. Within a page,
+// entries are re-sorted by decoded key; across pages the order follows the
+// encoded names.
+func (c *keyMappingClient) ListObjects(ctx context.Context, bucket, prefix string, opts ListOptions) (ListResult, error) {
+	if opts.Delimiter != "" && opts.Delimiter != "/" {
+		return ListResult{}, fmt.Errorf("key obfuscation supports only the \"/\" delimiter, got %q", opts.Delimiter)
+	}
+	backendPrefix, exact := c.codec.EncodePrefix(bucket, prefix)
+	res, err := c.Client.ListObjects(ctx, bucket, backendPrefix, opts)
+	if err != nil {
+		return res, err
+	}
+
+	seen := make(map[string]bool)
+	var prefixes []string
+	addPrefix := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			prefixes = append(prefixes, p)
+		}
+	}
+	for _, cp := range res.CommonPrefixes {
+		p, err := c.codec.DecodeKey(bucket, cp)
+		if err != nil || (!exact && !strings.HasPrefix(p, prefix)) {
+			continue
+		}
+		addPrefix(p)
+	}
+
+	objects := make([]ObjectInfo, 0, len(res.Objects))
+	for _, obj := range res.Objects {
+		key, err := c.codec.DecodeKey(bucket, obj.Key)
+		if err != nil || (!exact && !strings.HasPrefix(key, prefix)) {
+			continue
+		}
+		if opts.Delimiter != "" {
+			if i := strings.Index(key[len(prefix):], opts.Delimiter); i >= 0 {
+				addPrefix(key[:len(prefix)+i+len(opts.Delimiter)])
+				continue
+			}
+		}
+		obj.Key = key
+		objects = append(objects, obj)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	sort.Strings(prefixes)
+	res.Objects = objects
+	res.CommonPrefixes = prefixes
+	return res, nil
+}
+
+func (c *keyMappingClient) CreateMultipartUpload(ctx context.Context, bucket, key string, metadata map[string]string) (string, error) {
+	return c.Client.CreateMultipartUpload(ctx, bucket, c.enc(bucket, key), metadata)
+}
+
+func (c *keyMappingClient) UploadPart(ctx context.Context, bucket, key, uploadID string, partNumber int32, reader io.Reader, contentLength *int64) (string, error) {
+	return c.Client.UploadPart(ctx, bucket, c.enc(bucket, key), uploadID, partNumber, reader, contentLength)
+}
+
+func (c *keyMappingClient) CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []CompletedPart, lock *ObjectLockInput) (string, error) {
+	return c.Client.CompleteMultipartUpload(ctx, bucket, c.enc(bucket, key), uploadID, parts, lock)
+}
+
+func (c *keyMappingClient) AbortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error {
+	return c.Client.AbortMultipartUpload(ctx, bucket, c.enc(bucket, key), uploadID)
+}
+
+func (c *keyMappingClient) ListParts(ctx context.Context, bucket, key, uploadID string) ([]PartInfo, error) {
+	return c.Client.ListParts(ctx, bucket, c.enc(bucket, key), uploadID)
+}
+
+func (c *keyMappingClient) CopyObject(ctx context.Context, dstBucket, dstKey string, srcBucket, srcKey string, srcVersionID *string, metadata map[string]string, lock *ObjectLockInput) (string, map[string]string, error) {
+	return c.Client.CopyObject(ctx, dstBucket, c.enc(dstBucket, dstKey), srcBucket, c.enc(srcBucket, srcKey), srcVersionID, metadata, lock)
+}
+
+func (c *keyMappingClient) UploadPartCopy(ctx context.Context, dstBucket, dstKey, uploadID string, partNumber int32, srcBucket, srcKey string, srcVersionID *string, srcRange *CopyPartRange) (*CopyPartResult, error) {
+	return c.Client.UploadPartCopy(ctx, dstBucket, c.enc(dstBucket, dstKey), uploadID, partNumber, srcBucket, c.enc(srcBucket, srcKey), srcVersionID, srcRange)
+}
+
+// DeleteObjects encodes the request keys and reports results under the keys
+// the caller passed in.
+func (c *keyMappingClient) DeleteObjects(ctx context.Context, bucket string, keys []ObjectIdentifier) ([]DeletedObject, []ErrorObject, error) {
+	plain := make(map[string]string, len(keys))
+	encoded := make([]ObjectIdentifier, len(keys))
+	for i, k := range keys {
+		name := c.enc(bucket, k.Key)
+		plain[name] = k.Key
+		encoded[i] = ObjectIdentifier{Key: name, VersionID: k.VersionID}
+	}
+	deleted, errs, err := c.Client.DeleteObjects(ctx, bucket, encoded)
+	for i := range deleted {
+		if k, ok := plain[deleted[i].Key]; ok {
+			deleted[i].Key = k
+		}
+	}
+	for i := range errs {
+		if k, ok := plain[errs[i].Key]; ok {
+			errs[i].Key = k
+		}
+	}
+	return deleted, errs, err
+}
+
+func (c *keyMappingClient) PutObjectRetention(ctx context.Context, bucket, key string, versionID *string, retention *RetentionConfig) error {
+	return c.Client.PutObjectRetention(ctx, bucket, c.enc(bucket, key), versionID, retention)
+}
+
+func (c *keyMappingClient) GetObjectRetention(ctx context.Context, bucket, key string, versionID *string) (*RetentionConfig, error) {
+	return c.Client.GetObjectRetention(ctx, bucket, c.enc(bucket, key), versionID)
+}
+
+func (c *keyMappingClient) PutObjectLegalHold(ctx context.Context, bucket, key string, versionID *string, status string) error {
+	return c.Client.PutObjectLegalHold(ctx, bucket, c.enc(bucket, key), versionID, status)
+}
+
+func (c *keyMappingClient) GetObjectLegalHold(ctx context.Context, bucket, key string, versionID *string) (string, error) {
+	return c.Client.GetObjectLegalHold(ctx, bucket, c.enc(bucket, key), versionID)
+}
+
+func (c *keyMappingClient) PutObjectTagging(ctx context.Context, bucket, key string, versionID *string, tags []Tag) error {
+	return c.Client.PutObjectTagging(ctx, bucket, c.enc(bucket, key), versionID, tags)
+}
+
+func (c *keyMappingClient) GetObjectTagging(ctx context.Context, bucket, key string, versionID *string) ([]Tag, error) {
+	return c.Client.GetObjectTagging(ctx, bucket, c.enc(bucket, key), versionID)
+}
+
+func (c *keyMappingClient) DeleteObjectTagging(ctx context.Context, bucket, key string, versionID *string) error {
+	return c.Client.DeleteObjectTagging(ctx, bucket, c.enc(bucket, key), versionID)
+}
@@ -0,0 +1,138 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// upperCodec "encodes" a key by upper-casing it; names containing lower-case
+// letters fail to decode.
+type upperCodec struct{}
+
+func (upperCodec) EncodeKey(_, key string) string { return strings.ToUpper(key) }
+
+func (upperCodec) DecodeKey(_, name string) (string, error) {
+	if strings.ToUpper(name) != name {
+		return "", errors.New("not encoded")
+	}
+	return strings.ToLower(name), nil
+}
+
+func (upperCodec) EncodePrefix(_, prefix string) (string, bool) {
+	i := strings.LastIndex(prefix, "/")
+	return strings.ToUpper(prefix[:i+1]), i+1 == len(prefix)
+}
+
+// recordingClient captures the keys it is called with.
+type recordingClient struct {
+	Client
+	keys       []string
+	listPrefix string
+	list       ListResult
+}
+
+func (c *recordingClient) HeadObject(_ context.Context, _, key string, _ *string) (map[string]string, error) {
+	c.keys = append(c.keys, key)
+	return map[string]string{}, nil
+}
+
+func (c *recordingClient) CopyObject(_ context.Context, _, dstKey string, _, srcKey string, _ *string, _ map[string]string, _ *ObjectLockInput) (string, map[string]string, error) {
+	c.keys = append(c.keys, dstKey, srcKey)
+	return "", nil, nil
+}
+
+func (c *recordingClient) ListObjects(_ context.Context, _, prefix string, _ ListOptions) (ListResult, error) {
+	c.listPrefix = prefix
+	return c.list, nil
+}
+
+func (c *recordingClient) DeleteObjects(_ context.Context, _ string, keys []ObjectIdentifier) ([]DeletedObject, []ErrorObject, error) {
+	return []DeletedObject{{Key: keys[0].Key}}, []ErrorObject{{Key: keys[1].Key, Code: "AccessDenied"}}, nil
+}
+
+func TestKeyMappingClient_EncodesKeys(t *testing.T) {
+	inner := &recordingClient{}
+	c := NewKeyMappingClient(inner, upperCodec{})
+	if again := NewKeyMappingClient(c, upperCodec{}); again != c {
+		t.Error("wrapping twice should return the same client")
+	}
+
+	_, _ = c.HeadObject(context.Background(), "b", "dir/obj", nil)
+	_, _, _ = c.CopyObject(context.Background(), "b", "dst", "b", "src", nil, nil, nil)
+	want := []string{"DIR/OBJ", "DST", "SRC"}
+	if strings.Join(inner.keys, ",") != strings.Join(want, ",") {
+		t.Errorf("backend keys = %v, want %v", inner.keys, want)
+	}
+
+	deleted, errs, _ := c.DeleteObjects(context.Background(), "b", []ObjectIdentifier{{Key: "one"}, {Key: "two"}})
+	if deleted[0].Key != "one" || errs[0].Key != "two" {
+		t.Errorf("DeleteObjects reported %v / %v, want caller keys", deleted, errs)
+	}
+}
+
+func TestKeyMappingClient_ListObjects(t *testing.T) {
+	inner := &recordingClient{list: ListResult{
+		Objects: []ObjectInfo{
+			{Key: "PHOTOS/2024.JPG"},
+			{Key: "PHOTOS/1999.JPG"},
+			{Key: "PHOTOS/stray.jpg"}, // written around the gateway
+		},
+		CommonPrefixes: []string{"PHOTOS/2023/", "PHOTOS/ALBUM/"},
+	}}
+	c := NewKeyMappingClient(inner, upperCodec{})
+
+	res, err := c.ListObjects(context.Background(), "b", "photos/20", ListOptions{Delimiter: "/"})
+	if err != nil {
+		t.Fatalf("ListObjects: %v", err)
+	}
+	if inner.listPrefix != "PHOTOS/" {
+		t.Errorf("backend prefix = %q, want PHOTOS/", inner.listPrefix)
+	}
+	if len(res.Objects) != 1 || res.Objects[0].Key != "photos/2024.jpg" {
+		t.Errorf("objects = %+v", res.Objects)
+	}
+	if len(res.CommonPrefixes) != 1 || res.CommonPrefixes[0] != "photos/2023/" {
+		t.Errorf("common prefixes = %v", res.CommonPrefixes)
+	}
+
+	if _, err := c.ListObjects(context.Background(), "b", "", ListOptions{Delimiter: "-"}); err == nil {
+		t.Error("expected an error for a non-slash delimiter")
+	}
+}
+
+// flatCodec hides the hierarchy the way a whole-key scheme does.
+type flatCodec struct{}
+
+func (flatCodec) EncodeKey(_, key string) string { return strings.ReplaceAll(key, "/", "|") }
+
+func (flatCodec) DecodeKey(_, name string) (string, error) {
+	return strings.ReplaceAll(name, "|", "/"), nil
+}
+
+func (flatCodec) EncodePrefix(_, prefix string) (string, bool) { return "", prefix == "" }
+
+func TestKeyMappingClient_ListObjectsRebuildsCommonPrefixes(t *testing.T) {
+	inner := &recordingClient{list: ListResult{Objects: []ObjectInfo{
+		{Key: "docs|a.txt"},
+		{Key: "docs|sub|b.txt"},
+		{Key: "docs|sub|c.txt"},
+		{Key: "other|d.txt"},
+	}}}
+	c := NewKeyMappingClient(inner, flatCodec{})
+
+	res, err := c.ListObjects(context.Background(), "b", "docs/", ListOptions{Delimiter: "/"})
+	if err != nil {
+		t.Fatalf("ListObjects: %v", err)
+	}
+	if inner.listPrefix != "" {
+		t.Errorf("backend prefix = %q, want a bucket-wide listing", inner.listPrefix)
+	}
+	if len(res.Objects) != 1 || res.Objects[0].Key != "docs/a.txt" {
+		t.Errorf("objects = %+v", res.Objects)
+	}
+	if len(res.CommonPrefixes) != 1 || res.CommonPrefixes[0] != "docs/sub/" {
+		t.Errorf("common prefixes = %v", res.CommonPrefixes)
+	}
+}
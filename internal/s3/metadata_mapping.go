@@ -0,0 +1,80 @@
+package s3
+
+import (
+	"context"
+	"io"
+)
+
+// MetadataCodec translates metadata names between those the gateway uses
+// and those stored on the backend. crypto.MetadataPrefixer is the
+// implementation.
+type MetadataCodec interface {
+	EncodeMetadata(meta map[string]string) map[string]string
+	DecodeMetadata(meta map[string]string) map[string]string
+}
+
+// metadataMappingClient wraps a Client so metadata is encoded on writes and
+// decoded on reads.
+type metadataMappingClient struct {
+	Client
+	codec MetadataCodec
+}
+
+// NewMetadataMappingClient wraps inner so that metadata names are translated
+// through codec. Wrapping an already-mapped client returns it unchanged.
+func NewMetadataMappingClient(inner Client, codec MetadataCodec) Client {
+	if inner == nil || codec == nil {
+		return inner
+	}
+	if isWrapped[*metadataMappingClient](inner) {
+		return inner
+	}
+	return &metadataMappingClient{Client: inner, codec: codec}
+}
+
+func (c *metadataMappingClient) unwrap() Client { return c.Client }
+
+func (c *metadataMappingClient) PutObject(ctx context.Context, bucket, key string, reader io.Reader, metadata map[string]string, contentLength *int64, tags string, lock *ObjectLockInput) error {
+	return c.Client.PutObject(ctx, bucket, key, reader, c.codec.EncodeMetadata(metadata), contentLength, tags, lock)
+}
+
+func (c *metadataMappingClient) GetObject(ctx context.Context, bucket, key string, versionID *string, rangeHeader *string) (io.ReadCloser, map[string]string, error) {
+	body, meta, err := c.Client.GetObject(ctx, bucket, key, versionID, rangeHeader)
+	return body, c.codec.DecodeMetadata(meta), err
+}
+
+func (c *metadataMappingClient) HeadObject(ctx context.Context, bucket, key string, versionID *string) (map[string]string, error) {
+	meta, err := c.Client.HeadObject(ctx, bucket, key, versionID)
+	return c.codec.DecodeMetadata(meta), err
+}
+
+func (c *metadataMappingClient) CreateMultipartUpload(ctx context.Context, bucket, key string, metadata map[string]string) (string, error) {
+	return c.Client.CreateMultipartUpload(ctx, bucket, key, c.codec.EncodeMetadata(metadata))
+}
+
+// CopyObject encodes replacement metadata. A nil map (copy the source's
+// metadata as is) is passed through, so stored names are kept verbatim.
+func (c *metadataMappingClient) CopyObject(ctx context.Context, dstBucket, dstKey string, srcBucket, srcKey string, srcVersionID *string, metadata map[string]string, lock *ObjectLockInput) (string, map[string]string, error) {
+	etag, meta, err := c.Client.CopyObject(ctx, dstBucket, dstKey, srcBucket, srcKey, srcVersionID, c.codec.EncodeMetadata(metadata), lock)
+	return etag, c.codec.DecodeMetadata(meta), err
+}
+
+// wrappingClient is implemented by the mapping wrappers so each can tell
+// whether it is already present further down a chain of wrappers.
+type wrappingClient interface {
+	unwrap() Client
+}
+
+func isWrapped[T Client](c Client) bool {
+	for c != nil {
+		if _, ok := c.(T); ok {
+			return true
+		}
+		w, ok := c.(wrappingClient)
+		if !ok {
+			return false
+		}
+		c = w.unwrap()
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+// swapCodec stores "x-amz-meta-a" as "x-amz-meta-b".
+type swapCodec struct{}
+
+func (swapCodec) EncodeMetadata(meta map[string]string) map[string]string {
+	return rename(meta, "x-amz-meta-a", "x-amz-meta-b")
+}
+
+func (swapCodec) DecodeMetadata(meta map[string]string) map[string]string {
+	return rename(meta, "x-amz-meta-b", "x-amz-meta-a")
+}
+
+func rename(meta map[string]string, from, to string) map[string]string {
+	if meta == nil {
+		return nil
+	}
+	out := make(map[string]string, len(meta))
+	for k, v := range meta {
+		if k == from {
+			k = to
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// metaRecordingClient stores the metadata of the last write and returns it
+// on reads.
+type metaRecordingClient struct {
+	Client
+	stored map[string]string
+}
+
+func (c *metaRecordingClient) PutObject(_ context.Context, _, _ string, _ io.Reader, meta map[string]string, _ *int64, _ string, _ *ObjectLockInput) error {
+	c.stored = meta
+	return nil
+}
+
+func (c *metaRecordingClient) HeadObject(_ context.Context, _, _ string, _ *string) (map[string]string, error) {
+	return c.stored, nil
+}
+
+func (c *metaRecordingClient) CopyObject(_ context.Context, _, _ string, _, _ string, _ *string, meta map[string]string, _ *ObjectLockInput) (string, map[string]string, error) {
+	c.stored = meta
+	return "etag", c.stored, nil
+}
+
+func TestMetadataMappingClient(t *testing.T) {
+	inner := &metaRecordingClient{}
+	c := NewMetadataMappingClient(inner, swapCodec{})
+	ctx := context.Background()
+
+	if err := c.PutObject(ctx, "b", "k", strings.NewReader(""), map[string]string{"x-amz-meta-a": "1"}, nil, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if inner.stored["x-amz-meta-b"] != "1" {
+		t.Errorf("stored metadata = %v", inner.stored)
+	}
+	if meta, _ := c.HeadObject(ctx, "b", "k", nil); meta["x-amz-meta-a"] != "1" {
+		t.Errorf("HeadObject metadata = %v", meta)
+	}
+	if _, meta, _ := c.CopyObject(ctx, "b", "k2", "b", "k", nil, nil, nil); meta != nil || inner.stored != nil {
+		t.Errorf("copy without replacement metadata should pass nil through, got %v", inner.stored)
+	}
+}
+
+func TestMetadataMappingClient_WrapsOnce(t *testing.T) {
+	inner := &metaRecordingClient{}
+	mapped := NewKeyMappingClient(NewMetadataMappingClient(inner, swapCodec{}), upperCodec{})
+	if again := NewMetadataMappingClient(mapped, swapCodec{}); again != mapped {
+		t.Error("metadata mapping below key mapping should not be applied twice")
+	}
+	if again := NewKeyMappingClient(NewMetadataMappingClient(mapped, swapCodec{}), upperCodec{}); again != mapped {
+		t.Error("key mapping should not be applied twice")
+	}
+	if err := mapped.PutObject(context.Background(), "b", "k", strings.NewReader(""), map[string]string{"x-amz-meta-a": "1"}, nil, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if inner.stored["x-amz-meta-b"] != "1" {
+		t.Errorf("stored metadata = %v", inner.stored)
+	}
+}
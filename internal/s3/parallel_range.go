@@ -0,0 +1,141 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrRangeChanged is returned when the parts of a parallel range fetch come
+// from different versions of the object.
+var ErrRangeChanged = errors.New("object changed during parallel range fetch")
+
+// RangeGetter fetches one byte range ("bytes=a-b") of an object.
+type RangeGetter func(ctx context.Context, rangeHeader *string) (io.ReadCloser, map[string]string, error)
+
+// rangePart is the result of fetching one part.
+type rangePart struct {
+	data []byte
+	meta map[string]string
+	err  error
+}
+
+// GetRangeParallel fetches bytes start-end (inclusive) through get as
+// consecutive ranged GETs of at most partSize bytes, up to concurrency of
+// them in flight, and returns their concatenation. A part is buffered until
+// the caller has read it, so at most partSize*concurrency bytes are held.
+//
+// The first part is fetched before GetRangeParallel returns so that a
+// missing object or an access error is reported the same way as a single
+// GET. The returned metadata is the first part's, with Content-Length and
+// Content-Range describing the whole range. A part whose ETag differs from the first part's fails the
+// read with ErrRangeChanged.
+func GetRangeParallel(ctx context.Context, get RangeGetter, start, end, partSize int64, concurrency int) (io.ReadCloser, map[string]string, error) {
+	if partSize <= 0 || concurrency < 1 || end < start {
+		return nil, nil, fmt.Errorf("parallel range fetch: invalid range %d-%d (part size %d, concurrency %d)", start, end, partSize, concurrency)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	var results []chan rangePart
+	for off := start; off <= end; off += partSize {
+		results = append(results, make(chan rangePart, 1))
+	}
+	slots := make(chan struct{}, concurrency)
+	go func() {
+		for i, res := range results {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			partStart := start + int64(i)*partSize
+			partEnd := min(partStart+partSize-1, end)
+			go func() { res <- fetchPart(ctx, get, partStart, partEnd) }()
+		}
+	}()
+
+	first := <-results[0]
+	if first.err != nil {
+		cancel()
+		return nil, nil, first.err
+	}
+	meta := make(map[string]string, len(first.meta))
+	for k, v := range first.meta {
+		meta[k] = v
+	}
+	meta["Content-Length"] = strconv.FormatInt(end-start+1, 10)
+	if cr, ok := meta["Content-Range"]; ok {
+		if _, total, found := strings.Cut(cr, "/"); found {
+			meta["Content-Range"] = fmt.Sprintf("bytes %d-%d/%s", start, end, total)
+		}
+	}
+	return &parallelRangeReader{
+		cancel:  cancel,
+		slots:   slots,
+		results: results[1:],
+		etag:    first.meta["ETag"],
+		cur:     bytes.NewReader(first.data),
+	}, meta, nil
+}
+
+func fetchPart(ctx context.Context, get RangeGetter, start, end int64) rangePart {
+	rangeHeader := fmt.Sprintf("bytes=%d-%d", start, end)
+	body, meta, err := get(ctx, &rangeHeader)
+	if err != nil {
+		return rangePart{err: err}
+	}
+	defer body.Close()
+	data, err := io.ReadAll(io.LimitReader(body, end-start+1))
+	if err == nil && int64(len(data)) != end-start+1 {
+		err = io.ErrUnexpectedEOF
+	}
+	return rangePart{data: data, meta: meta, err: err}
+}
+
+// parallelRangeReader returns the parts in order, freeing a fetch slot each
+// time a part has been read.
+type parallelRangeReader struct {
+	cancel  context.CancelFunc
+	slots   chan struct{}
+	results []chan rangePart
+	etag    string
+	cur     *bytes.Reader
+	err     error
+}
+
+func (r *parallelRangeReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if n, _ := r.cur.Read(p); n > 0 {
+			return n, nil
+		}
+		if len(r.results) == 0 {
+			return 0, io.EOF
+		}
+		<-r.slots
+		part := <-r.results[0]
+		r.results = r.results[1:]
+		switch {
+		case part.err != nil:
+			r.err = part.err
+		case part.meta["ETag"] != r.etag:
+			r.err = ErrRangeChanged
+		default:
+			r.cur = bytes.NewReader(part.data)
+		}
+	}
+}
+
+// Close stops fetches that have not completed.
+func (r *parallelRangeReader) Close() error {
+	r.cancel()
+	return nil
+}
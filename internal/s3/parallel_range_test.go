@@ -0,0 +1,97 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// rangeServer serves byte ranges of data and records the peak number of
+// fetches in flight.
+type rangeServer struct {
+	data     []byte
+	etag     func(call int32) string
+	calls    atomic.Int32
+	inFlight atomic.Int32
+	mu       sync.Mutex
+	peak     int32
+}
+
+func (s *rangeServer) get(_ context.Context, rangeHeader *string) (io.ReadCloser, map[string]string, error) {
+	call := s.calls.Add(1)
+	n := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	s.mu.Lock()
+	s.peak = max(s.peak, n)
+	s.mu.Unlock()
+	var start, end int
+	if _, err := fmt.Sscanf(*rangeHeader, "bytes=%d-%d", &start, &end); err != nil {
+		return nil, nil, err
+	}
+	etag := `"v1"`
+	if s.etag != nil {
+		etag = s.etag(call)
+	}
+	meta := map[string]string{"ETag": etag, "Content-Range": fmt.Sprintf("bytes %d-%d/%d", start, end, len(s.data))}
+	return io.NopCloser(bytes.NewReader(s.data[start : end+1])), meta, nil
+}
+
+func TestGetRangeParallel(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	srv := &rangeServer{data: data}
+	body, meta, err := GetRangeParallel(context.Background(), srv.get, 100, 949, 64, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data[100:950]) {
+		t.Fatal("parts were not concatenated in order")
+	}
+	if meta["Content-Length"] != "850" || meta["Content-Range"] != "bytes 100-949/1000" || meta["ETag"] != `"v1"` {
+		t.Errorf("metadata = %v", meta)
+	}
+	if calls := srv.calls.Load(); calls != 14 {
+		t.Errorf("%d GETs, want 14", calls)
+	}
+	if srv.peak > 3 {
+		t.Errorf("%d fetches in flight, concurrency is 3", srv.peak)
+	}
+}
+
+func TestGetRangeParallel_ObjectChanged(t *testing.T) {
+	srv := &rangeServer{data: make([]byte, 300), etag: func(call int32) string {
+		if call == 3 {
+			return `"v2"`
+		}
+		return `"v1"`
+	}}
+	body, _, err := GetRangeParallel(context.Background(), srv.get, 0, 299, 100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	if _, err := io.ReadAll(body); !errors.Is(err, ErrRangeChanged) {
+		t.Fatalf("read = %v, want ErrRangeChanged", err)
+	}
+}
+
+func TestGetRangeParallel_FirstPartError(t *testing.T) {
+	failing := func(context.Context, *string) (io.ReadCloser, map[string]string, error) {
+		return nil, nil, errors.New("NoSuchKey")
+	}
+	if _, _, err := GetRangeParallel(context.Background(), failing, 0, 999, 100, 4); err == nil {
+		t.Fatal("expected the first part's error")
+	}
+}
@@ -44,29 +44,36 @@ func NewProxyClient(cfg *config.BackendConfig) (*ProxyClient, error) {
 		}
 	}
 
+	bt, err := newBackendTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{
+		// V1.0-SEC-F6: Enforce minimum TLS 1.2 and restricted cipher
+		// suites consistent with the main S3 client and Cosmian KMS
+		// client. The bare &http.Client{} default uses Go's
+		// http.DefaultTransport which has no cipher restrictions.
+		TLSClientConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			CipherSuites: []uint16{
+				tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+				tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			},
+			CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+		},
+		IdleConnTimeout:       90 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+		MaxIdleConnsPerHost:   10,
+	}
+	bt.apply(transport)
+
 	return &ProxyClient{
 		backendURL: backendURL,
 		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
-			Transport: &http.Transport{
-				// V1.0-SEC-F6: Enforce minimum TLS 1.2 and restricted cipher
-				// suites consistent with the main S3 client and Cosmian KMS
-				// client. The bare &http.Client{} default uses Go's
-				// http.DefaultTransport which has no cipher restrictions.
-				TLSClientConfig: &tls.Config{
-					MinVersion: tls.VersionTLS12,
-					CipherSuites: []uint16{
-						tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-						tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
-						tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
-						tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
-					},
-					CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
-				},
-				IdleConnTimeout:       90 * time.Second,
-				ResponseHeaderTimeout: 10 * time.Second,
-				MaxIdleConnsPerHost:   10,
-			},
+			Timeout:   60 * time.Second,
+			Transport: transport,
 		},
 		config: cfg,
 	}, nil
@@ -111,6 +118,7 @@ func (p *ProxyClient) ForwardRequest(ctx context.Context, originalReq *http.Requ
 	if err != nil {
 		return nil, fmt.Errorf("failed to forward request to backend: %w", err)
 	}
+	recordResponseIDs(ctx, resp.Header)
 
 	return resp, nil
 }
@@ -202,6 +210,7 @@ func (p *ProxyClient) CopyObject(ctx context.Context, dstBucket, dstKey string,
 		return "", nil, fmt.Errorf("failed to forward copy request to backend: %w", err)
 	}
 	defer resp.Body.Close()
+	recordResponseIDs(ctx, resp.Header)
 
 	// Check for errors
 	if resp.StatusCode >= 400 {
@@ -210,7 +219,7 @@ func (p *ProxyClient) CopyObject(ctx context.Context, dstBucket, dstKey string,
 		// to application logs. 1 KiB is enough for a useful status
 		// message without leaking stack traces or infrastructure details.
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return "", nil, fmt.Errorf("backend returned %d: %s", resp.StatusCode, string(body))
+		return "", nil, withResponseIDs(fmt.Errorf("backend returned %d: %s", resp.StatusCode, string(body)), resp.Header)
 	}
 
 	// Parse CopyObjectResult XML
@@ -276,6 +285,7 @@ func (p *ProxyClient) UploadPartCopy(ctx context.Context, dstBucket, dstKey, upl
 		return nil, fmt.Errorf("failed to forward upload part copy request to backend: %w", err)
 	}
 	defer resp.Body.Close()
+	recordResponseIDs(ctx, resp.Header)
 
 	// Check for errors
 	if resp.StatusCode >= 400 {
@@ -284,7 +294,7 @@ func (p *ProxyClient) UploadPartCopy(ctx context.Context, dstBucket, dstKey, upl
 		// to application logs. 1 KiB is enough for a useful status
 		// message without leaking stack traces or infrastructure details.
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return nil, fmt.Errorf("backend returned %d: %s", resp.StatusCode, string(body))
+		return nil, withResponseIDs(fmt.Errorf("backend returned %d: %s", resp.StatusCode, string(body)), resp.Header)
 	}
 
 	// Parse CopyPartResult XML
@@ -351,3 +361,18 @@ func (p *ProxyClient) PutObjectLockConfiguration(ctx context.Context, bucket str
 func (p *ProxyClient) GetObjectLockConfiguration(ctx context.Context, bucket string) (*ObjectLockConfiguration, error) {
 	return nil, fmt.Errorf("ProxyClient.GetObjectLockConfiguration not implemented - use ForwardRequest in handler")
 }
+
+// PutObjectTagging is not implemented
+func (p *ProxyClient) PutObjectTagging(ctx context.Context, bucket, key string, versionID *string, tags []Tag) error {
+	return fmt.Errorf("ProxyClient.PutObjectTagging not implemented - use ForwardRequest in handler")
+}
+
+// GetObjectTagging is not implemented
+func (p *ProxyClient) GetObjectTagging(ctx context.Context, bucket, key string, versionID *string) ([]Tag, error) {
+	return nil, fmt.Errorf("ProxyClient.GetObjectTagging not implemented - use ForwardRequest in handler")
+}
+
+// DeleteObjectTagging is not implemented
+func (p *ProxyClient) DeleteObjectTagging(ctx context.Context, bucket, key string, versionID *string) error {
+	return fmt.Errorf("ProxyClient.DeleteObjectTagging not implemented - use ForwardRequest in handler")
+}
@@ -0,0 +1,118 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
+)
+
+const (
+	// hedgeWindow is the number of recent range fetch latencies kept.
+	hedgeWindow = 512
+	// hedgeMinSamples is how many latencies are needed before the P99 is
+	// trusted; until then the maximum delay is used.
+	hedgeMinSamples = 50
+	// hedgeRecompute is how often, in samples, the P99 is recomputed.
+	hedgeRecompute = 32
+)
+
+// RangeHedger sends a duplicate of a ranged GET when the first copy has not
+// answered within the recent P99 latency of such fetches, and keeps
+// whichever answers first. Range reads of chunked objects fetch a handful of
+// chunks, so one slow backend request is most of the client's latency;
+// retrying it early costs one extra request in a hundred.
+//
+// A nil *RangeHedger issues plain GETs.
+type RangeHedger struct {
+	minDelay time.Duration
+	maxDelay time.Duration
+	m        *metrics.Metrics
+
+	mu      sync.Mutex
+	samples []time.Duration // ring buffer of up to hedgeWindow latencies
+	next    int
+	added   int
+	delay   time.Duration
+}
+
+// NewRangeHedger returns a hedger for cfg, or nil when hedging is disabled.
+func NewRangeHedger(cfg config.BackendRangeHedgingConfig, m *metrics.Metrics) *RangeHedger {
+	if !cfg.Enabled {
+		return nil
+	}
+	minDelay, maxDelay := cfg.MinDelay, cfg.MaxDelay
+	if minDelay <= 0 {
+		minDelay = config.DefaultRangeHedgeMinDelay
+	}
+	if maxDelay < minDelay {
+		maxDelay = max(config.DefaultRangeHedgeMaxDelay, minDelay)
+	}
+	return &RangeHedger{
+		minDelay: minDelay,
+		maxDelay: maxDelay,
+		m:        m,
+		samples:  make([]time.Duration, 0, hedgeWindow),
+		delay:    maxDelay,
+	}
+}
+
+// Delay returns how long a fetch may run before it is duplicated.
+func (h *RangeHedger) Delay() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.delay
+}
+
+// observe records the latency of one fetch.
+func (h *RangeHedger) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.samples) < hedgeWindow {
+		h.samples = append(h.samples, d)
+	} else {
+		h.samples[h.next] = d
+	}
+	h.next = (h.next + 1) % hedgeWindow
+	h.added++
+	if len(h.samples) < hedgeMinSamples || h.added%hedgeRecompute != 0 {
+		return
+	}
+	sorted := slices.Clone(h.samples)
+	slices.Sort(sorted)
+	p99 := sorted[(len(sorted)*99)/100]
+	h.delay = min(max(p99, h.minDelay), h.maxDelay)
+}
+
+// GetObject fetches rangeHeader of bucket/key through c, hedging a slow
+// fetch with a second identical request. Errors are not retried here; the
+// client's retry policy has already run.
+func (h *RangeHedger) GetObject(ctx context.Context, c Client, bucket, key string, versionID *string, rangeHeader *string) (io.ReadCloser, map[string]string, error) {
+	if h == nil {
+		return c.GetObject(ctx, bucket, key, versionID, rangeHeader)
+	}
+	start := time.Now()
+	rr := readRace{
+		endpoints:  []Client{c, c},
+		hedgeAfter: h.Delay(),
+		onAttempt:  func(string) { h.m.RecordRangeHedge("issued") },
+	}
+	res, cancel, winner, err := raceRead(ctx, rr,
+		func(ctx context.Context, cl Client) (getResult, error) {
+			body, meta, err := cl.GetObject(ctx, bucket, key, versionID, rangeHeader)
+			return getResult{body, meta}, err
+		},
+		func(r getResult) { r.body.Close() })
+	if err != nil {
+		return nil, nil, err
+	}
+	h.observe(time.Since(start))
+	if winner > 0 {
+		h.m.RecordRangeHedge("won")
+	}
+	return &cancelOnClose{ReadCloser: res.body, cancel: cancel}, res.meta, nil
+}
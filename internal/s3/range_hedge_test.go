@@ -0,0 +1,91 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+// slowFirstClient answers its first GET after slow and later ones at once.
+type slowFirstClient struct {
+	endpointClient
+	slow time.Duration
+	n    atomic.Int32
+}
+
+func (c *slowFirstClient) GetObject(ctx context.Context, bucket, key string, versionID, rangeHeader *string) (io.ReadCloser, map[string]string, error) {
+	if c.n.Add(1) == 1 {
+		select {
+		case <-time.After(c.slow):
+		case <-ctx.Done():
+			c.canceled.Add(1)
+			return nil, nil, ctx.Err()
+		}
+	}
+	return c.endpointClient.GetObject(ctx, bucket, key, versionID, rangeHeader)
+}
+
+func TestNewRangeHedger_Disabled(t *testing.T) {
+	if NewRangeHedger(config.BackendRangeHedgingConfig{}, nil) != nil {
+		t.Fatal("disabled config should return nil")
+	}
+	var h *RangeHedger
+	c := &endpointClient{name: "primary"}
+	body, _, err := h.GetObject(context.Background(), c, "b", "k", nil, nil)
+	if err != nil {
+		t.Fatalf("nil hedger GetObject() error: %v", err)
+	}
+	body.Close()
+	if c.calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", c.calls.Load())
+	}
+}
+
+func TestRangeHedger_DelayTracksP99(t *testing.T) {
+	h := NewRangeHedger(config.BackendRangeHedgingConfig{Enabled: true, MinDelay: time.Millisecond, MaxDelay: time.Second}, nil)
+	if got := h.Delay(); got != time.Second {
+		t.Fatalf("Delay() before samples = %v, want max delay", got)
+	}
+	for i := 1; i <= 256; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	if got := h.Delay(); got < 250*time.Millisecond || got > 256*time.Millisecond {
+		t.Errorf("Delay() = %v, want the P99 of 1..256ms", got)
+	}
+	for i := 0; i < hedgeWindow; i++ {
+		h.observe(time.Microsecond)
+	}
+	if got := h.Delay(); got != time.Millisecond {
+		t.Errorf("Delay() = %v, want clamped to min delay", got)
+	}
+}
+
+func TestRangeHedger_DuplicateWins(t *testing.T) {
+	h := NewRangeHedger(config.BackendRangeHedgingConfig{Enabled: true, MinDelay: time.Millisecond, MaxDelay: 20 * time.Millisecond}, nil)
+	c := &slowFirstClient{endpointClient: endpointClient{name: "backend"}, slow: 5 * time.Second}
+
+	start := time.Now()
+	rng := "bytes=0-99"
+	body, _, err := h.GetObject(context.Background(), c, "b", "k", nil, &rng)
+	if err != nil {
+		t.Fatalf("GetObject() error: %v", err)
+	}
+	body.Close()
+	if time.Since(start) > time.Second {
+		t.Error("hedged fetch waited for the slow request")
+	}
+	if c.n.Load() != 2 {
+		t.Errorf("requests = %d, want 2", c.n.Load())
+	}
+	deadline := time.Now().Add(time.Second)
+	for c.canceled.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if c.canceled.Load() != 1 {
+		t.Error("slow request was not cancelled")
+	}
+}
@@ -0,0 +1,83 @@
+package s3
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
+)
+
+// primaryHealth decides whether reads start at the primary endpoint. It is
+// shared by every client of a ClientFactory, so the decision holds across
+// requests.
+//
+// After failoverAfter consecutive reads on which the primary failed with a
+// retryable error, reads start at the replicas instead. While failed over,
+// one read per probeInterval starts at the primary again, and the first
+// read the primary answers switches back.
+type primaryHealth struct {
+	failoverAfter int
+	probeInterval time.Duration
+	m             *metrics.Metrics
+	now           func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	down      bool
+	nextProbe time.Time
+}
+
+// newPrimaryHealth returns nil when persistent failover is disabled.
+func newPrimaryHealth(cfg config.BackendReadReplicasConfig, m *metrics.Metrics) *primaryHealth {
+	if cfg.FailoverAfter <= 0 || len(cfg.Endpoints) == 0 {
+		return nil
+	}
+	interval := cfg.ProbeInterval
+	if interval <= 0 {
+		interval = config.DefaultReadProbeInterval
+	}
+	return &primaryHealth{failoverAfter: cfg.FailoverAfter, probeInterval: interval, m: m, now: time.Now}
+}
+
+// primaryFirst reports whether the next read should start at the primary.
+// While failed over it returns true once per probe interval.
+func (h *primaryHealth) primaryFirst() bool {
+	if h == nil {
+		return true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.down {
+		return true
+	}
+	if now := h.now(); !now.Before(h.nextProbe) {
+		h.nextProbe = now.Add(h.probeInterval)
+		return true
+	}
+	return false
+}
+
+// report records the outcome of a read attempt on the primary. ok is true
+// for any answer, including a 404, and false for a retryable failure.
+func (h *primaryHealth) report(ok bool) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if ok {
+		h.failures = 0
+		if h.down {
+			h.down = false
+			h.m.SetBackendReadFailover(false)
+		}
+		return
+	}
+	h.failures++
+	if !h.down && h.failures >= h.failoverAfter {
+		h.down = true
+		h.nextProbe = h.now().Add(h.probeInterval)
+		h.m.SetBackendReadFailover(true)
+	}
+}
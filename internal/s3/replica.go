@@ -0,0 +1,260 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
+)
+
+// replicaClient sends every call to the primary endpoint except GetObject
+// and HeadObject, which fail over to the replica endpoints and, with a
+// hedge delay, race them against a slow primary.
+//
+// A read is only moved to the next endpoint when the error is one the retry
+// policy would retry (5xx, throttling, network failures); a 404 or 403 from
+// the primary is the answer, not a reason to ask another endpoint.
+//
+// With health set, a primary that keeps failing is moved to the end of the
+// read order until it answers again (see primaryHealth). The endpoint that
+// was asked first is then authoritative in its place.
+type replicaClient struct {
+	Client              // primary
+	endpoints  []Client // primary first
+	hedgeAfter time.Duration
+	m          *metrics.Metrics
+	names      []string       // endpoint labels for metrics; nil → not recorded
+	health     *primaryHealth // nil → every read starts at the primary
+}
+
+func newReplicaClient(endpoints []Client, hedgeAfter time.Duration, m *metrics.Metrics) *replicaClient {
+	return &replicaClient{Client: endpoints[0], endpoints: endpoints, hedgeAfter: hedgeAfter, m: m}
+}
+
+type getResult struct {
+	body io.ReadCloser
+	meta map[string]string
+}
+
+func (c *replicaClient) GetObject(ctx context.Context, bucket, key string, versionID *string, rangeHeader *string) (io.ReadCloser, map[string]string, error) {
+	rr, order := c.race(ctx, "GetObject")
+	res, cancel, idx, err := raceRead(ctx, rr,
+		func(ctx context.Context, cl Client) (getResult, error) {
+			body, meta, err := cl.GetObject(ctx, bucket, key, versionID, rangeHeader)
+			return getResult{body, meta}, err
+		},
+		func(r getResult) { r.body.Close() })
+	if err != nil {
+		return nil, nil, err
+	}
+	c.served("GetObject", order[idx])
+	// The body streams under the winning attempt's context, so that context
+	// lives until the caller closes it.
+	return &cancelOnClose{ReadCloser: res.body, cancel: cancel}, res.meta, nil
+}
+
+func (c *replicaClient) HeadObject(ctx context.Context, bucket, key string, versionID *string) (map[string]string, error) {
+	rr, order := c.race(ctx, "HeadObject")
+	meta, cancel, idx, err := raceRead(ctx, rr,
+		func(ctx context.Context, cl Client) (map[string]string, error) {
+			return cl.HeadObject(ctx, bucket, key, versionID)
+		}, nil)
+	if err != nil {
+		return nil, err
+	}
+	cancel()
+	c.served("HeadObject", order[idx])
+	return meta, nil
+}
+
+// race returns the read race for op and, for each position in its
+// endpoint list, the index of that endpoint in c.endpoints.
+func (c *replicaClient) race(ctx context.Context, op string) (readRace, []int) {
+	order := make([]int, len(c.endpoints))
+	for i := range order {
+		order[i] = i
+	}
+	endpoints := c.endpoints
+	if !c.health.primaryFirst() {
+		order = append(order[1:], 0)
+		endpoints = make([]Client, len(order))
+		for i, e := range order {
+			endpoints[i] = c.endpoints[e]
+		}
+	}
+	return readRace{
+		endpoints:  endpoints,
+		hedgeAfter: c.hedgeAfter,
+		failover:   true,
+		onAttempt:  func(kind string) { c.m.RecordBackendReadReplicaAttempt(op, kind) },
+		onResult: func(idx int, err error) {
+			// Attempts ended by the caller say nothing about the primary.
+			if order[idx] == 0 && ctx.Err() == nil {
+				c.health.report(err == nil || !retryableRead(err))
+			}
+		},
+	}, order
+}
+
+func (c *replicaClient) served(op string, endpoint int) {
+	if endpoint < len(c.names) {
+		c.m.RecordBackendReadServed(op, c.names[endpoint])
+	}
+}
+
+// readRace describes how raceRead spreads one read over endpoints.
+type readRace struct {
+	endpoints []Client
+	// hedgeAfter, when positive, starts the next endpoint once the latest
+	// attempt has been running this long without an answer.
+	hedgeAfter time.Duration
+	// failover starts the next endpoint when an attempt fails with a
+	// retryable error.
+	failover bool
+	// onAttempt, if set, is called for every attempt after the first with
+	// kind "failover" or "hedge".
+	onAttempt func(kind string)
+	// onResult, if set, is called with the endpoint index and error of
+	// every attempt that finishes before the race is decided.
+	onResult func(idx int, err error)
+}
+
+type readAttempt[T any] struct {
+	val T
+	err error
+	idx int
+}
+
+// raceRead runs call against rr.endpoints, starting with the first. With
+// failover, a retryable failure moves on to the next endpoint at once; with
+// hedging, a slow attempt also starts the next one. The first success wins, the other
+// attempts are cancelled and any late successes are passed to discard. On
+// success it returns the index of the winning endpoint and a cancel func
+// releasing the winning attempt's context.
+func raceRead[T any](ctx context.Context, rr readRace, call func(context.Context, Client) (T, error), discard func(T)) (T, context.CancelFunc, int, error) {
+	results := make(chan readAttempt[T], len(rr.endpoints))
+	cancels := make([]context.CancelFunc, 0, len(rr.endpoints))
+	launch := func(kind string) {
+		idx := len(cancels)
+		actx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		if idx > 0 && rr.onAttempt != nil {
+			rr.onAttempt(kind)
+		}
+		go func() {
+			v, err := call(actx, rr.endpoints[idx])
+			results <- readAttempt[T]{val: v, err: err, idx: idx}
+		}()
+	}
+
+	var hedge *time.Timer
+	var hedgeC <-chan time.Time
+	armHedge := func() {
+		if rr.hedgeAfter <= 0 || len(cancels) == len(rr.endpoints) {
+			hedgeC = nil
+			return
+		}
+		if hedge == nil {
+			hedge = time.NewTimer(rr.hedgeAfter)
+		} else {
+			hedge.Reset(rr.hedgeAfter)
+		}
+		hedgeC = hedge.C
+	}
+	defer func() {
+		if hedge != nil {
+			hedge.Stop()
+		}
+	}()
+
+	// finish cancels every attempt but keep and reaps the ones still running.
+	finish := func(keep, pending int) {
+		for i, cancel := range cancels {
+			if i != keep {
+				cancel()
+			}
+		}
+		go func() {
+			for ; pending > 0; pending-- {
+				if a := <-results; a.err == nil && discard != nil {
+					discard(a.val)
+				}
+			}
+		}()
+	}
+
+	fail := func(err error, pending int) (T, context.CancelFunc, int, error) {
+		finish(-1, pending)
+		var zero T
+		return zero, nil, -1, err
+	}
+
+	launch("")
+	armHedge()
+	inflight := 1
+	var primaryErr error
+	for {
+		select {
+		case <-hedgeC:
+			launch("hedge")
+			inflight++
+			armHedge()
+		case a := <-results:
+			inflight--
+			if rr.onResult != nil {
+				rr.onResult(a.idx, a.err)
+			}
+			if a.err == nil {
+				finish(a.idx, inflight)
+				return a.val, cancels[a.idx], a.idx, nil
+			}
+			if a.idx == 0 {
+				primaryErr = a.err
+			}
+			retryable := retryableRead(a.err)
+			switch {
+			case ctx.Err() != nil:
+				return fail(a.err, inflight)
+			case !retryable && a.idx == 0:
+				// The primary's answer is authoritative.
+				return fail(a.err, inflight)
+			case retryable && rr.failover && len(cancels) < len(rr.endpoints):
+				launch("failover")
+				inflight++
+				armHedge()
+			case inflight == 0:
+				// A replica may lag behind the primary, so its 404 only
+				// stands when the primary failed too; report the primary's
+				// error when there is one.
+				if primaryErr != nil {
+					return fail(primaryErr, 0)
+				}
+				return fail(a.err, 0)
+			}
+		}
+	}
+}
+
+// retryableRead reports whether a failed read is worth sending to another
+// endpoint.
+func retryableRead(err error) bool {
+	if errors.Is(err, ErrNotFound) {
+		return false
+	}
+	_, retryable := classify("", err)
+	return retryable
+}
+
+// cancelOnClose releases a context when the body it streams is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
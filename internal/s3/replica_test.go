@@ -0,0 +1,355 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
+)
+
+// endpointClient answers reads after delay with err, or with its name.
+type endpointClient struct {
+	Client
+	name     string
+	delay    time.Duration
+	err      error
+	calls    atomic.Int32
+	canceled atomic.Int32
+	closed   atomic.Int32
+}
+
+func (c *endpointClient) wait(ctx context.Context) error {
+	c.calls.Add(1)
+	select {
+	case <-time.After(c.delay):
+		return c.err
+	case <-ctx.Done():
+		c.canceled.Add(1)
+		return ctx.Err()
+	}
+}
+
+func (c *endpointClient) GetObject(ctx context.Context, _, _ string, _, _ *string) (io.ReadCloser, map[string]string, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, nil, err
+	}
+	return &trackedBody{Reader: strings.NewReader(c.name), closed: &c.closed}, map[string]string{"endpoint": c.name}, nil
+}
+
+func (c *endpointClient) HeadObject(ctx context.Context, _, _ string, _ *string) (map[string]string, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	return map[string]string{"endpoint": c.name}, nil
+}
+
+type trackedBody struct {
+	io.Reader
+	closed *atomic.Int32
+}
+
+func (b *trackedBody) Close() error { b.closed.Add(1); return nil }
+
+func unavailable(t *testing.T) error { return makeHTTPRespErr(t, 503, 0) }
+
+func headEndpoint(t *testing.T, c *replicaClient) string {
+	t.Helper()
+	meta, err := c.HeadObject(context.Background(), "b", "k", nil)
+	if err != nil {
+		t.Fatalf("HeadObject() error: %v", err)
+	}
+	return meta["endpoint"]
+}
+
+func TestReplicaClient_PrimaryServesReads(t *testing.T) {
+	primary := &endpointClient{name: "primary"}
+	replica := &endpointClient{name: "replica"}
+	c := newReplicaClient([]Client{primary, replica}, 0, nil)
+
+	if got := headEndpoint(t, c); got != "primary" {
+		t.Errorf("served by %q, want primary", got)
+	}
+	if replica.calls.Load() != 0 {
+		t.Error("replica should not be asked when the primary answers")
+	}
+}
+
+func TestReplicaClient_FailsOverOnRetryableError(t *testing.T) {
+	primary := &endpointClient{name: "primary", err: unavailable(t)}
+	broken := &endpointClient{name: "broken", err: unavailable(t)}
+	replica := &endpointClient{name: "replica"}
+	c := newReplicaClient([]Client{primary, broken, replica}, 0, nil)
+
+	if got := headEndpoint(t, c); got != "replica" {
+		t.Errorf("served by %q, want replica", got)
+	}
+}
+
+func TestReplicaClient_NoFailoverOnNotFound(t *testing.T) {
+	notFound := classifyBackendError(&smithy.GenericAPIError{Code: "NoSuchKey"})
+	primary := &endpointClient{name: "primary", err: notFound}
+	replica := &endpointClient{name: "replica"}
+	c := newReplicaClient([]Client{primary, replica}, 0, nil)
+
+	_, err := c.HeadObject(context.Background(), "b", "k", nil)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("HeadObject() error = %v, want ErrNotFound", err)
+	}
+	if replica.calls.Load() != 0 {
+		t.Error("a 404 from the primary must not be retried on a replica")
+	}
+}
+
+func TestReplicaClient_ReplicaNotFoundDefersToPrimary(t *testing.T) {
+	primary := &endpointClient{name: "primary", delay: 50 * time.Millisecond}
+	lagging := &endpointClient{name: "lagging", err: classifyBackendError(&smithy.GenericAPIError{Code: "NoSuchKey"})}
+	c := newReplicaClient([]Client{primary, lagging}, 5*time.Millisecond, nil)
+
+	if got := headEndpoint(t, c); got != "primary" {
+		t.Errorf("served by %q, want primary", got)
+	}
+}
+
+func TestReplicaClient_AllEndpointsFail(t *testing.T) {
+	primaryErr := unavailable(t)
+	primary := &endpointClient{name: "primary", err: primaryErr}
+	replica := &endpointClient{name: "replica", err: unavailable(t)}
+	c := newReplicaClient([]Client{primary, replica}, 0, nil)
+
+	_, err := c.HeadObject(context.Background(), "b", "k", nil)
+	if err != primaryErr {
+		t.Fatalf("HeadObject() error = %v, want the primary's error", err)
+	}
+}
+
+func TestReplicaClient_HedgesSlowPrimary(t *testing.T) {
+	primary := &endpointClient{name: "primary", delay: time.Second}
+	replica := &endpointClient{name: "replica"}
+	c := newReplicaClient([]Client{primary, replica}, 10*time.Millisecond, nil)
+
+	start := time.Now()
+	body, meta, err := c.GetObject(context.Background(), "b", "k", nil, nil)
+	if err != nil {
+		t.Fatalf("GetObject() error: %v", err)
+	}
+	if meta["endpoint"] != "replica" {
+		t.Errorf("served by %q, want replica", meta["endpoint"])
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("hedged read waited for the slow primary")
+	}
+	data, _ := io.ReadAll(body)
+	if string(data) != "replica" {
+		t.Errorf("body = %q", data)
+	}
+	if err := body.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for primary.canceled.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if primary.canceled.Load() != 1 {
+		t.Error("losing primary attempt was not cancelled")
+	}
+}
+
+func TestReplicaClient_LateWinnerBodyIsClosed(t *testing.T) {
+	// Both endpoints answer; whichever loses must have its body closed.
+	primary := &endpointClient{name: "primary", delay: 20 * time.Millisecond}
+	replica := &endpointClient{name: "replica", delay: 20 * time.Millisecond}
+	c := newReplicaClient([]Client{primary, replica}, time.Millisecond, nil)
+
+	body, _, err := c.GetObject(context.Background(), "b", "k", nil, nil)
+	if err != nil {
+		t.Fatalf("GetObject() error: %v", err)
+	}
+	body.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		closed := primary.closed.Load() + replica.closed.Load()
+		lost := primary.canceled.Load() + replica.canceled.Load()
+		if closed+lost == 2 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Error("losing attempt was neither cancelled nor closed")
+}
+
+func TestClientFactory_ReadReplicas(t *testing.T) {
+	cfg := &config.BackendConfig{
+		Endpoint:     "http://localhost:9000",
+		AccessKey:    "access",
+		SecretKey:    "secret",
+		ReadReplicas: config.BackendReadReplicasConfig{Endpoints: []string{"http://localhost:9001"}},
+	}
+	c, err := NewClientFactory(cfg).GetClient()
+	if err != nil {
+		t.Fatalf("GetClient() error: %v", err)
+	}
+	rc, ok := c.(*replicaClient)
+	if !ok {
+		t.Fatalf("GetClient() = %T, want *replicaClient", c)
+	}
+	if len(rc.endpoints) != 2 {
+		t.Errorf("endpoints = %d, want 2", len(rc.endpoints))
+	}
+
+	cfg.ReadReplicas.Endpoints = nil
+	if c, _ := NewClientFactory(cfg).GetClient(); c == nil {
+		t.Fatal("GetClient() returned nil")
+	} else if _, ok := c.(*replicaClient); ok {
+		t.Error("no replicas configured, but got a replica client")
+	}
+}
+
+func TestReplicaClient_PersistentFailoverAndSwitchback(t *testing.T) {
+	now := time.Unix(0, 0)
+	health := newPrimaryHealth(config.BackendReadReplicasConfig{
+		Endpoints:     []string{"replica"},
+		FailoverAfter: 2,
+		ProbeInterval: time.Minute,
+	}, nil)
+	health.now = func() time.Time { return now }
+	primary := &endpointClient{name: "primary", err: unavailable(t)}
+	replica := &endpointClient{name: "replica"}
+	newClient := func() *replicaClient {
+		c := newReplicaClient([]Client{primary, replica}, 0, nil)
+		c.health = health
+		return c
+	}
+
+	// Two failed reads (on separate clients, as per request) fail over.
+	for i := 0; i < 2; i++ {
+		if got := headEndpoint(t, newClient()); got != "replica" {
+			t.Fatalf("read %d served by %q, want replica", i, got)
+		}
+	}
+	if primary.calls.Load() != 2 {
+		t.Fatalf("primary calls = %d, want 2", primary.calls.Load())
+	}
+
+	// Failed over: the primary is not asked until the probe is due.
+	headEndpoint(t, newClient())
+	if primary.calls.Load() != 2 {
+		t.Fatalf("primary asked while failed over (%d calls)", primary.calls.Load())
+	}
+
+	// A failed probe keeps reads on the replica for another interval.
+	now = now.Add(time.Minute)
+	headEndpoint(t, newClient())
+	headEndpoint(t, newClient())
+	if primary.calls.Load() != 3 {
+		t.Fatalf("primary calls = %d, want one probe", primary.calls.Load())
+	}
+
+	// Once the primary answers a probe, reads switch back.
+	primary.err = nil
+	now = now.Add(time.Minute)
+	if got := headEndpoint(t, newClient()); got != "primary" {
+		t.Fatalf("probe served by %q, want primary", got)
+	}
+	replicaCalls := replica.calls.Load()
+	if got := headEndpoint(t, newClient()); got != "primary" || replica.calls.Load() != replicaCalls {
+		t.Fatalf("after switchback served by %q, replica calls %d -> %d", got, replicaCalls, replica.calls.Load())
+	}
+}
+
+func TestReplicaClient_FailedOverReplicaFallsBackToPrimary(t *testing.T) {
+	health := newPrimaryHealth(config.BackendReadReplicasConfig{Endpoints: []string{"replica"}, FailoverAfter: 1}, nil)
+	health.report(false)
+	primary := &endpointClient{name: "primary"}
+	replica := &endpointClient{name: "replica", err: unavailable(t)}
+	c := newReplicaClient([]Client{primary, replica}, 0, nil)
+	c.health = health
+
+	if got := headEndpoint(t, c); got != "primary" {
+		t.Fatalf("served by %q, want primary", got)
+	}
+	if !health.primaryFirst() {
+		t.Error("a primary answer should end the failover")
+	}
+}
+
+func TestReplicaClient_RecordsServingEndpoint(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.NewMetricsWithRegistry(reg)
+	primary := &endpointClient{name: "primary", err: unavailable(t)}
+	replica := &endpointClient{name: "replica"}
+	c := newReplicaClient([]Client{primary, replica}, 0, m)
+	c.names = []string{"https://eu.example", "https://us.example"}
+	c.health = newPrimaryHealth(config.BackendReadReplicasConfig{Endpoints: []string{"replica"}, FailoverAfter: 1}, m)
+
+	headEndpoint(t, c)
+	if _, _, err := c.GetObject(context.Background(), "b", "k", nil, nil); err != nil {
+		t.Fatalf("GetObject() error: %v", err)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := map[string]float64{}
+	failover := -1.0
+	for _, mf := range families {
+		switch mf.GetName() {
+		case "s3_backend_reads_served_total":
+			for _, metric := range mf.GetMetric() {
+				var op, endpoint string
+				for _, l := range metric.GetLabel() {
+					switch l.GetName() {
+					case "operation":
+						op = l.GetValue()
+					case "endpoint":
+						endpoint = l.GetValue()
+					}
+				}
+				served[op+" "+endpoint] = metric.GetCounter().GetValue()
+			}
+		case "s3_backend_read_failover_active":
+			failover = mf.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	want := map[string]float64{"HeadObject https://us.example": 1, "GetObject https://us.example": 1}
+	if fmt.Sprint(served) != fmt.Sprint(want) {
+		t.Errorf("served = %v, want %v", served, want)
+	}
+	if failover != 1 {
+		t.Errorf("s3_backend_read_failover_active = %v, want 1", failover)
+	}
+}
+
+func TestClientFactory_SharesReadHealth(t *testing.T) {
+	cfg := &config.BackendConfig{
+		Endpoint:     "http://localhost:9000",
+		AccessKey:    "access",
+		SecretKey:    "secret",
+		ReadReplicas: config.BackendReadReplicasConfig{Endpoints: []string{"http://localhost:9001"}, FailoverAfter: 3},
+	}
+	f := NewClientFactory(cfg)
+	a, _ := f.GetClient()
+	b, _ := f.GetClient()
+	ha, hb := a.(*replicaClient).health, b.(*replicaClient).health
+	if ha == nil || ha != hb {
+		t.Fatalf("clients of one factory must share read health (%p, %p)", ha, hb)
+	}
+	if ha.probeInterval != config.DefaultReadProbeInterval {
+		t.Errorf("probe interval = %v, want default %v", ha.probeInterval, config.DefaultReadProbeInterval)
+	}
+	if names := a.(*replicaClient).names; fmt.Sprint(names) != "[http://localhost:9000 http://localhost:9001]" {
+		t.Errorf("names = %v", names)
+	}
+}
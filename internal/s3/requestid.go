@@ -0,0 +1,138 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// Backend response headers identifying a request to the provider's support.
+const (
+	headerRequestID = "X-Amz-Request-Id"
+	headerHostID    = "X-Amz-Id-2"
+)
+
+// BackendRequestID identifies one backend response by its x-amz-request-id
+// and x-amz-id-2 headers. Either may be empty: not every S3-compatible
+// backend sends both.
+type BackendRequestID struct {
+	RequestID string `json:"request_id,omitempty"`
+	HostID    string `json:"host_id,omitempty"`
+}
+
+// IsZero reports whether neither ID is known.
+func (id BackendRequestID) IsZero() bool {
+	return id.RequestID == "" && id.HostID == ""
+}
+
+func backendRequestIDFromHeader(h http.Header) BackendRequestID {
+	return BackendRequestID{RequestID: h.Get(headerRequestID), HostID: h.Get(headerHostID)}
+}
+
+// BackendRequestIDFromError returns the IDs of the backend response err
+// reports, as carried by SDK response errors and ProxyClient errors.
+func BackendRequestIDFromError(err error) (BackendRequestID, bool) {
+	var id BackendRequestID
+	var withRequestID interface{ ServiceRequestID() string }
+	if errors.As(err, &withRequestID) {
+		id.RequestID = withRequestID.ServiceRequestID()
+	}
+	var withHostID interface{ ServiceHostID() string }
+	if errors.As(err, &withHostID) {
+		id.HostID = withHostID.ServiceHostID()
+	}
+	return id, !id.IsZero()
+}
+
+// responseIDError attaches a backend response's IDs to an error built
+// outside the SDK.
+type responseIDError struct {
+	err error
+	id  BackendRequestID
+}
+
+func (e *responseIDError) Error() string {
+	return fmt.Sprintf("%v, RequestID: %s, HostID: %s", e.err, e.id.RequestID, e.id.HostID)
+}
+func (e *responseIDError) Unwrap() error            { return e.err }
+func (e *responseIDError) ServiceRequestID() string { return e.id.RequestID }
+func (e *responseIDError) ServiceHostID() string    { return e.id.HostID }
+
+// withResponseIDs attaches the IDs in h to err, unless h has none.
+func withResponseIDs(err error, h http.Header) error {
+	id := backendRequestIDFromHeader(h)
+	if id.IsZero() {
+		return err
+	}
+	return &responseIDError{err: err, id: id}
+}
+
+// RequestIDRecorder collects the IDs of the backend responses received on
+// behalf of one gateway request.
+type RequestIDRecorder struct {
+	mu    sync.Mutex
+	last  BackendRequestID
+	count int
+}
+
+type recorderKey struct{}
+
+// WithRequestIDRecorder returns a context whose backend responses are
+// recorded in the returned recorder.
+func WithRequestIDRecorder(ctx context.Context) (context.Context, *RequestIDRecorder) {
+	rec := &RequestIDRecorder{}
+	return context.WithValue(ctx, recorderKey{}, rec), rec
+}
+
+// RequestIDRecorderFromContext returns the recorder installed in ctx, or nil.
+func RequestIDRecorderFromContext(ctx context.Context) *RequestIDRecorder {
+	rec, _ := ctx.Value(recorderKey{}).(*RequestIDRecorder)
+	return rec
+}
+
+// Last returns the IDs of the most recent backend response and the number
+// of responses recorded. A nil recorder has recorded nothing.
+func (r *RequestIDRecorder) Last() (BackendRequestID, int) {
+	if r == nil {
+		return BackendRequestID{}, 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last, r.count
+}
+
+// Record adds a backend response, for Client implementations that do not
+// go through the SDK or ProxyClient. Responses without IDs are ignored.
+func (r *RequestIDRecorder) Record(id BackendRequestID) {
+	if r == nil || id.IsZero() {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.last = id
+	r.count++
+}
+
+// recordResponseIDs records the IDs in h with the recorder in ctx, if any.
+func recordResponseIDs(ctx context.Context, h http.Header) {
+	RequestIDRecorderFromContext(ctx).Record(backendRequestIDFromHeader(h))
+}
+
+// addRequestIDMiddleware records the IDs of every backend response,
+// including each retried attempt and error responses. It sits below the
+// operation's deserializer so the raw response is always available.
+func addRequestIDMiddleware(stack *middleware.Stack) error {
+	return stack.Deserialize.Add(middleware.DeserializeMiddlewareFunc("BackendRequestID",
+		func(ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler) (middleware.DeserializeOutput, middleware.Metadata, error) {
+			out, metadata, err := next.HandleDeserialize(ctx, in)
+			if resp, ok := out.RawResponse.(*smithyhttp.Response); ok && resp != nil {
+				recordResponseIDs(ctx, resp.Header)
+			}
+			return out, metadata, err
+		}), middleware.After)
+}
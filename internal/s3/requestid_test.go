@@ -0,0 +1,84 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDs_SDKClient(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-amz-request-id", "REQ-"+r.Method)
+		w.Header().Set("x-amz-id-2", "HOST-"+r.Method)
+		if r.Method == http.MethodDelete {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message><RequestId>REQ-DELETE</RequestId><HostId>HOST-DELETE</HostId></Error>`))
+			return
+		}
+		w.Header().Set("Content-Length", "0")
+	})
+	client := buildTestS3Client(t, &fakeS3Transport{handler: mux})
+	ctx, rec := WithRequestIDRecorder(context.Background())
+
+	if _, err := client.HeadObject(ctx, "b", "k", nil); err != nil {
+		t.Fatalf("HeadObject: %v", err)
+	}
+	if id, n := rec.Last(); n != 1 || id != (BackendRequestID{RequestID: "REQ-HEAD", HostID: "HOST-HEAD"}) {
+		t.Errorf("after HeadObject: %+v (%d)", id, n)
+	}
+
+	err := client.DeleteObject(ctx, "b", "k", nil)
+	if err == nil {
+		t.Fatal("DeleteObject succeeded")
+	}
+	want := BackendRequestID{RequestID: "REQ-DELETE", HostID: "HOST-DELETE"}
+	if id, n := rec.Last(); n != 2 || id != want {
+		t.Errorf("after DeleteObject: %+v (%d)", id, n)
+	}
+	if id, ok := BackendRequestIDFromError(err); !ok || id != want {
+		t.Errorf("BackendRequestIDFromError(%v) = %+v", err, id)
+	}
+	if !strings.Contains(err.Error(), "REQ-DELETE") {
+		t.Errorf("error message lacks the request ID: %v", err)
+	}
+}
+
+func TestRequestIDs_ProxyClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-amz-request-id", "REQ-1")
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	pc := newTestProxyClient(t, srv.URL)
+	ctx, rec := WithRequestIDRecorder(context.Background())
+
+	_, _, err := pc.CopyObject(ctx, "dst", "k", "src", "k", nil, nil, nil)
+	if err == nil {
+		t.Fatal("CopyObject succeeded")
+	}
+	if id, ok := BackendRequestIDFromError(err); !ok || id.RequestID != "REQ-1" || id.HostID != "" {
+		t.Errorf("BackendRequestIDFromError(%v) = %+v", err, id)
+	}
+	if !strings.Contains(err.Error(), "backend returned 500") || !strings.Contains(err.Error(), "RequestID: REQ-1") {
+		t.Errorf("error = %v", err)
+	}
+	if id, n := rec.Last(); n != 1 || id.RequestID != "REQ-1" {
+		t.Errorf("recorded %+v (%d)", id, n)
+	}
+}
+
+func TestRequestIDs_NoRecorder(t *testing.T) {
+	// Responses outside a recorded request are ignored.
+	recordResponseIDs(context.Background(), http.Header{"X-Amz-Request-Id": {"x"}})
+	var rec *RequestIDRecorder
+	if _, n := rec.Last(); n != 0 {
+		t.Error("nil recorder recorded a response")
+	}
+	if _, ok := BackendRequestIDFromError(context.Canceled); ok {
+		t.Error("IDs found in an unrelated error")
+	}
+}
@@ -251,12 +251,14 @@ func classify(op string, err error) (retryReasonLabel, bool) {
 		return reasonNonRetry, false
 	}
 
-	// Definite 4xx HTTP responses (not transient).
+	// Definite 4xx HTTP responses (not transient), and 304 answering a
+	// conditional read.
 	var respErr *smithyhttp.ResponseError
 	if errors.As(err, &respErr) {
 		code := respErr.HTTPStatusCode()
 		switch code {
-		case http.StatusBadRequest,          // 400
+		case http.StatusNotModified, // 304
+			http.StatusBadRequest,          // 400
 			http.StatusUnauthorized,         // 401
 			http.StatusForbidden,            // 403
 			http.StatusNotFound,             // 404
@@ -0,0 +1,22 @@
+package s3
+
+import "context"
+
+type storageClassKey struct{}
+
+// WithStorageClass returns a context that makes the next PutObject issued
+// with it store the object in the given storage class. Backends without
+// storage classes ignore it.
+func WithStorageClass(ctx context.Context, class string) context.Context {
+	if class == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, storageClassKey{}, class)
+}
+
+// StorageClassFromContext returns the storage class attached by
+// WithStorageClass, if any.
+func StorageClassFromContext(ctx context.Context) (string, bool) {
+	class, ok := ctx.Value(storageClassKey{}).(string)
+	return class, ok
+}
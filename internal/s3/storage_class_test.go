@@ -0,0 +1,16 @@
+package s3
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStorageClassContext(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := StorageClassFromContext(WithStorageClass(ctx, "")); ok {
+		t.Error("an empty class must not be attached to the context")
+	}
+	if got, ok := StorageClassFromContext(WithStorageClass(ctx, "GLACIER_IR")); !ok || got != "GLACIER_IR" {
+		t.Errorf("got %q (ok=%v)", got, ok)
+	}
+}
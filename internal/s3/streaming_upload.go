@@ -0,0 +1,209 @@
+package s3
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+// maxUploadParts is the most parts S3 accepts in one multipart upload.
+const maxUploadParts = 10000
+
+// streamingUploadPlan returns the part size and concurrency for sending a
+// PutObject body as a multipart upload, and false when a single PUT should
+// be used. That is the case when streaming uploads are off, or when the
+// length is known (given, or from a seekable body) and within the
+// threshold.
+func (c *s3Client) streamingUploadPlan(reader io.Reader, contentLength *int64) (partSize int64, concurrency int, ok bool) {
+	if c.config == nil || !c.config.StreamingUpload.Enabled {
+		return 0, 0, false
+	}
+	cfg := c.config.StreamingUpload
+	threshold := cfg.Threshold
+	if threshold <= 0 {
+		threshold = config.DefaultStreamingUploadThreshold
+	}
+	partSize = cfg.PartSize
+	if partSize <= 0 {
+		partSize = config.DefaultStreamingUploadPartSize
+	}
+	concurrency = cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = config.DefaultStreamingUploadConcurrency
+	}
+
+	if contentLength != nil {
+		if *contentLength <= threshold {
+			return 0, 0, false
+		}
+		// Grow the parts so the whole object fits in the part limit.
+		if least := (*contentLength + maxUploadParts - 1) / maxUploadParts; partSize < least {
+			partSize = least
+		}
+		return partSize, concurrency, true
+	}
+	if _, seekable := reader.(io.Seeker); seekable {
+		return 0, 0, false
+	}
+	return partSize, concurrency, true
+}
+
+// putObjectStreaming uploads the body of in as a multipart upload, reading
+// it partSize bytes at a time with up to concurrency parts in flight. The
+// upload is aborted if any part fails.
+func (c *s3Client) putObjectStreaming(ctx context.Context, in *s3.PutObjectInput, lock *ObjectLockInput, partSize int64, concurrency int) error {
+	create := &s3.CreateMultipartUploadInput{
+		Bucket:       in.Bucket,
+		Key:          in.Key,
+		Metadata:     in.Metadata,
+		Tagging:      in.Tagging,
+		StorageClass: in.StorageClass,
+	}
+	if lock != nil {
+		if lock.Mode != "" && lock.RetainUntilDate != nil {
+			create.ObjectLockMode = types.ObjectLockMode(lock.Mode)
+			create.ObjectLockRetainUntilDate = lock.RetainUntilDate
+		}
+		if lock.LegalHoldStatus != "" {
+			create.ObjectLockLegalHoldStatus = types.ObjectLockLegalHoldStatus(lock.LegalHoldStatus)
+		}
+	}
+	created, err := c.client.CreateMultipartUpload(ctx, create)
+	if err != nil {
+		return classifyBackendError(err)
+	}
+	if created.UploadId == nil {
+		return fmt.Errorf("upload ID not returned from backend")
+	}
+	uploadID := created.UploadId
+
+	parts, err := c.uploadParts(ctx, in, uploadID, partSize, concurrency)
+	if err != nil {
+		// Abort even if the request was cancelled, so the backend does not
+		// keep the uploaded parts.
+		abortCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		if _, abortErr := c.client.AbortMultipartUpload(abortCtx, &s3.AbortMultipartUploadInput{
+			Bucket:   in.Bucket,
+			Key:      in.Key,
+			UploadId: uploadID,
+		}); abortErr != nil {
+			err = errors.Join(err, fmt.Errorf("abort multipart upload: %w", classifyBackendError(abortErr)))
+		}
+		return err
+	}
+
+	complete := &s3.CompleteMultipartUploadInput{
+		Bucket:          in.Bucket,
+		Key:             in.Key,
+		UploadId:        uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		IfMatch:         in.IfMatch,
+		IfNoneMatch:     in.IfNoneMatch,
+	}
+	if _, err := c.client.CompleteMultipartUpload(ctx, complete); err != nil {
+		return classifyBackendError(err)
+	}
+	return nil
+}
+
+// uploadParts reads the body of in into parts and uploads them, returning
+// them in part order. An empty body is uploaded as one empty part, and a
+// body shorter than a ContentLength set on in is an error.
+func (c *s3Client) uploadParts(ctx context.Context, in *s3.PutObjectInput, uploadID *string, partSize int64, concurrency int) ([]types.CompletedPart, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		parts []types.CompletedPart
+		total int64
+	)
+	// Each buffer is in use by at most one part, which bounds memory to
+	// concurrency buffers.
+	buffers := make(chan []byte, concurrency)
+	for i := 0; i < concurrency; i++ {
+		buffers <- nil
+	}
+
+	for number := int32(1); ; number++ {
+		var buf []byte
+		select {
+		case buf = <-buffers:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if buf == nil {
+			buf = make([]byte, partSize)
+		}
+
+		n, readErr := io.ReadFull(in.Body, buf)
+		total += int64(n)
+		last := readErr == io.EOF || readErr == io.ErrUnexpectedEOF
+		if readErr != nil && !last {
+			cancel(fmt.Errorf("read object body: %w", readErr))
+			break
+		}
+		if n == 0 && number > 1 {
+			break
+		}
+		if number > maxUploadParts {
+			cancel(fmt.Errorf("object exceeds %d parts of %d bytes", maxUploadParts, partSize))
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { buffers <- buf }()
+			out, err := c.client.UploadPart(ctx, &s3.UploadPartInput{
+				Bucket:        in.Bucket,
+				Key:           in.Key,
+				UploadId:      uploadID,
+				PartNumber:    aws.Int32(number),
+				Body:          bytes.NewReader(buf[:n]),
+				ContentLength: aws.Int64(int64(n)),
+			})
+			if err == nil && out.ETag == nil {
+				err = fmt.Errorf("ETag not returned from backend")
+			}
+			if err != nil {
+				cancel(fmt.Errorf("upload part %d: %w", number, classifyBackendError(err)))
+				return
+			}
+			mu.Lock()
+			parts = append(parts, types.CompletedPart{PartNumber: aws.Int32(number), ETag: out.ETag})
+			mu.Unlock()
+		}()
+		if last {
+			break
+		}
+	}
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+	if in.ContentLength != nil && total != *in.ContentLength {
+		return nil, fmt.Errorf("object body is %d bytes, want %d", total, *in.ContentLength)
+	}
+	slices.SortFunc(parts, func(a, b types.CompletedPart) int {
+		return cmp.Compare(*a.PartNumber, *b.PartNumber)
+	})
+	return parts, nil
+}
@@ -0,0 +1,151 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+// uploadRecorder is a fake S3 backend that records the single and
+// multipart uploads it receives.
+type uploadRecorder struct {
+	mu        sync.Mutex
+	puts      int
+	parts     map[string]int // part number -> size
+	aborted   bool
+	completed string
+	failPart  string
+}
+
+func (u *uploadRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body []byte
+	if r.Body != nil {
+		body, _ = io.ReadAll(r.Body)
+	}
+	q := r.URL.Query()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		fmt.Fprint(w, `<InitiateMultipartUploadResult><UploadId>up-1</UploadId></InitiateMultipartUploadResult>`)
+	case r.Method == http.MethodPut && q.Get("uploadId") != "":
+		if q.Get("partNumber") == u.failPart {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `<Error><Code>InvalidRequest</Code></Error>`)
+			return
+		}
+		u.parts[q.Get("partNumber")] = len(body)
+		w.Header().Set("ETag", `"etag-`+q.Get("partNumber")+`"`)
+	case r.Method == http.MethodPost && q.Get("uploadId") != "":
+		u.completed = string(body)
+		fmt.Fprint(w, `<CompleteMultipartUploadResult><ETag>"multi-3"</ETag></CompleteMultipartUploadResult>`)
+	case r.Method == http.MethodDelete && q.Get("uploadId") != "":
+		u.aborted = true
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		u.puts++
+		w.Header().Set("ETag", `"single"`)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func buildStreamingTestClient(t *testing.T, rec *uploadRecorder, cfg config.BackendStreamingUploadConfig) Client {
+	t.Helper()
+	factory := NewClientFactory(&config.BackendConfig{
+		Endpoint:        "http://localhost:9000",
+		Region:          "us-east-1",
+		AccessKey:       "AKIATEST",
+		SecretKey:       "secrettest",
+		StreamingUpload: cfg,
+	}, WithHTTPTransport(&fakeS3Transport{handler: rec}))
+	c, err := factory.GetClient()
+	if err != nil {
+		t.Fatalf("GetClient() error: %v", err)
+	}
+	return c
+}
+
+// TestPutObject_StreamsUnknownLength checks that a body of unknown length
+// is sent as a multipart upload of PartSize parts, completed in order.
+func TestPutObject_StreamsUnknownLength(t *testing.T) {
+	rec := &uploadRecorder{parts: map[string]int{}}
+	client := buildStreamingTestClient(t, rec, config.BackendStreamingUploadConfig{
+		Enabled: true, PartSize: config.MinStreamingUploadPartSize, Concurrency: 2,
+	})
+
+	size := 2*config.MinStreamingUploadPartSize + 1234
+	body := io.MultiReader(bytes.NewReader(make([]byte, size))) // not seekable
+	if err := client.PutObject(context.Background(), "bucket", "key", body, nil, nil, "", nil); err != nil {
+		t.Fatalf("PutObject() error: %v", err)
+	}
+
+	if rec.puts != 0 {
+		t.Errorf("single PUTs = %d, want 0", rec.puts)
+	}
+	want := map[string]int{"1": config.MinStreamingUploadPartSize, "2": config.MinStreamingUploadPartSize, "3": 1234}
+	if fmt.Sprint(rec.parts) != fmt.Sprint(want) {
+		t.Errorf("parts = %v, want %v", rec.parts, want)
+	}
+	i1, i2, i3 := strings.Index(rec.completed, "etag-1"), strings.Index(rec.completed, "etag-2"), strings.Index(rec.completed, "etag-3")
+	if i1 < 0 || !(i1 < i2 && i2 < i3) {
+		t.Errorf("complete request lists parts out of order: %s", rec.completed)
+	}
+}
+
+// TestPutObject_SinglePutWithinThreshold checks that bodies of known length
+// within the threshold, and all bodies with streaming off, use one PUT.
+func TestPutObject_SinglePutWithinThreshold(t *testing.T) {
+	for name, cfg := range map[string]config.BackendStreamingUploadConfig{
+		"known length": {Enabled: true, Threshold: 1 << 20},
+		"disabled":     {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := &uploadRecorder{parts: map[string]int{}}
+			client := buildStreamingTestClient(t, rec, cfg)
+			n := int64(100)
+			body := io.MultiReader(bytes.NewReader(make([]byte, n)))
+			if err := client.PutObject(context.Background(), "bucket", "key", body, nil, &n, "", nil); err != nil {
+				t.Fatalf("PutObject() error: %v", err)
+			}
+			if rec.puts != 1 || len(rec.parts) != 0 {
+				t.Errorf("puts = %d, parts = %v; want one single PUT", rec.puts, rec.parts)
+			}
+		})
+	}
+}
+
+// TestPutObject_StreamingAbortsOnFailure checks that a failed part aborts
+// the multipart upload, and that a body shorter than its declared length
+// is not completed.
+func TestPutObject_StreamingAbortsOnFailure(t *testing.T) {
+	cfg := config.BackendStreamingUploadConfig{Enabled: true, Threshold: 1, PartSize: config.MinStreamingUploadPartSize, Concurrency: 1}
+
+	rec := &uploadRecorder{parts: map[string]int{}, failPart: "2"}
+	client := buildStreamingTestClient(t, rec, cfg)
+	body := io.MultiReader(bytes.NewReader(make([]byte, 3*config.MinStreamingUploadPartSize)))
+	if err := client.PutObject(context.Background(), "bucket", "key", body, nil, nil, "", nil); err == nil {
+		t.Fatal("PutObject() succeeded with a failing part")
+	}
+	if !rec.aborted || rec.completed != "" {
+		t.Errorf("aborted = %v, completed = %q; want the upload aborted", rec.aborted, rec.completed)
+	}
+
+	rec = &uploadRecorder{parts: map[string]int{}}
+	client = buildStreamingTestClient(t, rec, cfg)
+	declared := int64(1000)
+	body = io.MultiReader(bytes.NewReader(make([]byte, 10)))
+	if err := client.PutObject(context.Background(), "bucket", "key", body, nil, &declared, "", nil); err == nil {
+		t.Fatal("PutObject() succeeded with a short body")
+	}
+	if !rec.aborted || rec.completed != "" {
+		t.Errorf("aborted = %v, completed = %q; want the upload aborted", rec.aborted, rec.completed)
+	}
+}
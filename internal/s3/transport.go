@@ -0,0 +1,73 @@
+package s3
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/airgap"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+// backendTransport holds the proxy and TLS settings shared by every HTTP
+// transport that talks to the backend: the SDK clients, the Signature V4
+// passthrough and ProxyClient.
+type backendTransport struct {
+	proxy    func(*http.Request) (*url.URL, error)
+	rootCAs  *x509.CertPool // nil → system roots
+	insecure bool
+	custom   bool // anything beyond the defaults is configured
+}
+
+// newBackendTransport loads cfg's proxy and CA settings. Without an explicit
+// proxy, HTTPS_PROXY, HTTP_PROXY and NO_PROXY from the environment apply.
+func newBackendTransport(cfg *config.BackendConfig) (*backendTransport, error) {
+	b := &backendTransport{proxy: http.ProxyFromEnvironment, insecure: cfg.TLS.InsecureSkipVerify}
+	if cfg.Proxy != "" {
+		u, err := url.Parse(cfg.Proxy)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid backend proxy URL %q", cfg.Proxy)
+		}
+		b.proxy = http.ProxyURL(u)
+	}
+	if cfg.TLS.CAFile != "" {
+		pem, err := os.ReadFile(cfg.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backend CA file: %w", err)
+		}
+		// Keep the system roots so a proxied public endpoint still verifies.
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("failed to parse backend CA file %s", cfg.TLS.CAFile)
+		}
+		b.rootCAs = pool
+	}
+	// In air-gapped mode the SDK clients need a transport that dials
+	// through the guard.
+	b.custom = cfg.Proxy != "" || b.rootCAs != nil || b.insecure || airgap.Active() != nil
+	return b, nil
+}
+
+// apply sets the proxy and certificate verification on tr, keeping any other
+// TLS settings it already has, and routes its dials through the air-gap
+// guard.
+func (b *backendTransport) apply(tr *http.Transport) {
+	tr.Proxy = b.proxy
+	tr.DialContext = airgap.Wrap(tr.DialContext)
+	if b.rootCAs == nil && !b.insecure {
+		return
+	}
+	if tr.TLSClientConfig == nil {
+		tr.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if b.rootCAs != nil {
+		tr.TLSClientConfig.RootCAs = b.rootCAs
+	}
+	tr.TLSClientConfig.InsecureSkipVerify = b.insecure //nolint:gosec // operator opt-in
+}
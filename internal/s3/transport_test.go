@@ -0,0 +1,105 @@
+package s3
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+func TestBackendTransport_ExplicitProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	f := NewClientFactory(&config.BackendConfig{Proxy: proxy.URL})
+	tr := &http.Transport{}
+	if err := f.ConfigureTransport(tr); err != nil {
+		t.Fatalf("ConfigureTransport() error: %v", err)
+	}
+	resp, err := (&http.Client{Transport: tr}).Get("http://backend.invalid/bucket/key")
+	if err != nil {
+		t.Fatalf("GET through proxy: %v", err)
+	}
+	resp.Body.Close()
+	if proxied != "http://backend.invalid/bucket/key" {
+		t.Errorf("proxy saw %q, want the backend URL", proxied)
+	}
+}
+
+func TestBackendTransport_EnvironmentProxy(t *testing.T) {
+	bt, err := newBackendTransport(&config.BackendConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bt.custom {
+		t.Error("no overrides configured, but transport is marked custom")
+	}
+	tr := &http.Transport{}
+	bt.apply(tr)
+	if tr.Proxy == nil {
+		t.Error("environment proxy settings should apply by default")
+	}
+	if tr.TLSClientConfig != nil {
+		t.Error("TLS config should be left alone without CA or skip-verify settings")
+	}
+}
+
+func TestBackendTransport_CAFile(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: backend.Certificate().Raw})
+	if err := os.WriteFile(caFile, pemBytes, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(cfg *config.BackendConfig) error {
+		tr := &http.Transport{}
+		if err := NewClientFactory(cfg).ConfigureTransport(tr); err != nil {
+			t.Fatalf("ConfigureTransport() error: %v", err)
+		}
+		resp, err := (&http.Client{Transport: tr}).Get(backend.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(&config.BackendConfig{}); err == nil {
+		t.Error("expected the self-signed backend to be rejected without a CA file")
+	}
+	if err := get(&config.BackendConfig{TLS: config.BackendTLSConfig{CAFile: caFile}}); err != nil {
+		t.Errorf("GET with CA file: %v", err)
+	}
+	if err := get(&config.BackendConfig{TLS: config.BackendTLSConfig{InsecureSkipVerify: true}}); err != nil {
+		t.Errorf("GET with insecure_skip_verify: %v", err)
+	}
+}
+
+func TestBackendTransport_BadCAFile(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f := NewClientFactory(&config.BackendConfig{
+		Endpoint:  "https://localhost:9000",
+		AccessKey: "access",
+		SecretKey: "secret",
+		TLS:       config.BackendTLSConfig{CAFile: caFile},
+	})
+	if _, err := f.GetClient(); err == nil || !strings.Contains(err.Error(), "CA file") {
+		t.Errorf("GetClient() error = %v, want CA file error", err)
+	}
+	if err := f.ConfigureTransport(&http.Transport{}); err == nil {
+		t.Error("ConfigureTransport() should report the CA file error")
+	}
+}
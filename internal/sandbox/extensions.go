@@ -0,0 +1,109 @@
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/transform"
+)
+
+// PolicyRequest is the envelope passed to a policy module.
+type PolicyRequest struct {
+	// Operation is the S3 operation, e.g. "GetObject".
+	Operation string `json:"operation"`
+	Bucket    string `json:"bucket"`
+	Key       string `json:"key,omitempty"`
+	// Principal is the access key of the caller.
+	Principal string `json:"principal,omitempty"`
+	// Metadata holds the user metadata of the request or object.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// PolicyDecision is a policy module's answer.
+type PolicyDecision struct {
+	Allow bool `json:"allow"`
+	// Reason is logged and audited with a denial; it is not returned to
+	// the client.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Decide asks a policy module about req. Any failure of the module is an
+// error, which callers treat as a denial.
+func Decide(ctx context.Context, m Module, req PolicyRequest) (PolicyDecision, error) {
+	in, err := json.Marshal(req)
+	if err != nil {
+		return PolicyDecision{}, err
+	}
+	out, err := m.Call(ctx, ExportPolicy, in)
+	if err != nil {
+		return PolicyDecision{}, fmt.Errorf("policy module %s: %w", m.Name(), err)
+	}
+	var d PolicyDecision
+	if err := json.Unmarshal(out, &d); err != nil {
+		return PolicyDecision{}, fmt.Errorf("policy module %s: invalid decision: %w", m.Name(), err)
+	}
+	return d, nil
+}
+
+// MetadataRequest is the envelope passed to a metadata module.
+type MetadataRequest struct {
+	Operation   string            `json:"operation"`
+	Bucket      string            `json:"bucket"`
+	Key         string            `json:"key"`
+	ContentType string            `json:"content_type,omitempty"`
+	Metadata    map[string]string `json:"metadata"`
+}
+
+// TransformMetadata runs a metadata module over req.Metadata and returns the
+// user metadata to store. Modules may only produce x-amz-meta-* entries and
+// never the gateway's encryption metadata.
+func TransformMetadata(ctx context.Context, m Module, req MetadataRequest) (map[string]string, error) {
+	in, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	out, err := m.Call(ctx, ExportMetadata, in)
+	if err != nil {
+		return nil, fmt.Errorf("metadata module %s: %w", m.Name(), err)
+	}
+	var md map[string]string
+	if err := json.Unmarshal(out, &md); err != nil {
+		return nil, fmt.Errorf("metadata module %s: invalid metadata: %w", m.Name(), err)
+	}
+	for k := range md {
+		lk := strings.ToLower(k)
+		if !strings.HasPrefix(lk, "x-amz-meta-") || crypto.IsEncryptionMetadata(lk) {
+			return nil, fmt.Errorf("metadata module %s: may not set %q", m.Name(), k)
+		}
+	}
+	return md, nil
+}
+
+// Filter runs a module's content filter as a [transform.Service], so it can
+// back a response transformation rule. The module receives the whole
+// plaintext, which is therefore bounded by the limits' MaxInputSize.
+type Filter struct {
+	Module Module
+	Limits Limits
+}
+
+// Transform implements transform.Service.
+func (f *Filter) Transform(ctx context.Context, req transform.Request, in io.Reader) (*transform.Result, error) {
+	limits := f.Limits.withDefaults()
+	content, err := io.ReadAll(io.LimitReader(in, limits.MaxInputSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > limits.MaxInputSize {
+		return nil, fmt.Errorf("filter module %s: %w: object over %d bytes", f.Module.Name(), ErrLimitExceeded, limits.MaxInputSize)
+	}
+	out, err := f.Module.Call(ctx, ExportFilter, content)
+	if err != nil {
+		return nil, fmt.Errorf("filter module %s: %w", f.Module.Name(), err)
+	}
+	return &transform.Result{Body: io.NopCloser(strings.NewReader(string(out))), ContentType: req.ContentType}, nil
+}
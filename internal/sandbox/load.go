@@ -0,0 +1,92 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
+)
+
+// Hook is a module attached to a hook, and the objects it applies to.
+type Hook struct {
+	Module Module
+	// Bucket and Prefix restrict the hook; empty Bucket matches every
+	// bucket.
+	Bucket string
+	Prefix string
+}
+
+// Matches reports whether the hook applies to bucket/key.
+func (h *Hook) Matches(bucket, key string) bool {
+	return (h.Bucket == "" || h.Bucket == bucket) && strings.HasPrefix(key, h.Prefix)
+}
+
+// Extensions holds the configured modules by hook.
+type Extensions struct {
+	runtime Runtime
+	// Policy and Metadata run in configuration order.
+	Policy   []*Hook
+	Metadata []*Hook
+	// Filters maps module names to filters for transforms rules.
+	Filters map[string]*Filter
+}
+
+// Load reads and compiles the configured modules. A module attached to a
+// hook must provide that hook's export; any module providing s3eg_filter
+// can back a transforms rule.
+func Load(ctx context.Context, cfg config.ExtensionsConfig, logger *logrus.Logger, m *metrics.Metrics) (*Extensions, error) {
+	rt, err := NewRuntime(logger, m)
+	if err != nil {
+		return nil, err
+	}
+	ext := &Extensions{runtime: rt, Filters: make(map[string]*Filter)}
+	for _, mc := range cfg.Modules {
+		wasm, err := os.ReadFile(mc.Path)
+		if err != nil {
+			_ = ext.Close(ctx)
+			return nil, fmt.Errorf("extension %s: %w", mc.Name, err)
+		}
+		limits := Limits{MemoryPages: mc.MemoryPages, Timeout: mc.Timeout, MaxInputSize: mc.MaxInputSize}
+		mod, err := rt.Compile(ctx, mc.Name, wasm, limits)
+		if err != nil {
+			_ = ext.Close(ctx)
+			return nil, err
+		}
+		hook := &Hook{Module: mod, Bucket: mc.Bucket, Prefix: mc.Prefix}
+		for _, h := range []struct {
+			name   string
+			export string
+			hooks  *[]*Hook
+		}{
+			{config.ExtensionHookPolicy, ExportPolicy, &ext.Policy},
+			{config.ExtensionHookMetadata, ExportMetadata, &ext.Metadata},
+		} {
+			if !slices.Contains(mc.Hooks, h.name) {
+				continue
+			}
+			if !mod.Exports(h.export) {
+				_ = ext.Close(ctx)
+				return nil, fmt.Errorf("extension %s: %w: %s hook needs %s", mc.Name, ErrMissingExport, h.name, h.export)
+			}
+			*h.hooks = append(*h.hooks, hook)
+		}
+		if mod.Exports(ExportFilter) {
+			ext.Filters[mc.Name] = &Filter{Module: mod, Limits: limits}
+		}
+	}
+	return ext, nil
+}
+
+// Close releases every module.
+func (e *Extensions) Close(ctx context.Context) error {
+	if e == nil {
+		return nil
+	}
+	return e.runtime.Close(ctx)
+}
@@ -0,0 +1,247 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
+)
+
+// Module ABI names (see docs/adr/0013-wasm-extension-sandbox.md).
+const (
+	exportMemory = "memory"
+	exportAlloc  = "s3eg_alloc"
+	hostModule   = "s3eg"
+	hostLog      = "log"
+)
+
+// maxLogLine bounds a line a module writes through s3eg.log.
+const maxLogLine = 1024
+
+// wazeroRuntime compiles modules with wazero. Every module gets its own
+// wazero runtime, so its memory limit does not apply to any other module;
+// compiled code is shared through one cache.
+type wazeroRuntime struct {
+	logger  *logrus.Logger
+	metrics *metrics.Metrics
+	cache   wazero.CompilationCache
+
+	mu      sync.Mutex
+	modules []*wazeroModule
+}
+
+// NewRuntime returns the embedded WASM runtime. Module log lines go to
+// logger at debug level and calls are counted in m; both may be nil.
+func NewRuntime(logger *logrus.Logger, m *metrics.Metrics) (Runtime, error) {
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	return &wazeroRuntime{logger: logger, metrics: m, cache: wazero.NewCompilationCache()}, nil
+}
+
+// Compile implements Runtime.
+func (rt *wazeroRuntime) Compile(ctx context.Context, name string, wasm []byte, limits Limits) (Module, error) {
+	limits = limits.withDefaults()
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithCompilationCache(rt.cache).
+		WithMemoryLimitPages(limits.MemoryPages).
+		WithCloseOnContextDone(true))
+	m := &wazeroModule{name: name, limits: limits, runtime: r, metrics: rt.metrics}
+	if err := m.compile(ctx, wasm, rt.logger.WithField("module", name)); err != nil {
+		_ = r.Close(ctx)
+		return nil, fmt.Errorf("sandbox: module %s: %w", name, err)
+	}
+	rt.mu.Lock()
+	rt.modules = append(rt.modules, m)
+	rt.mu.Unlock()
+	return m, nil
+}
+
+// Close implements Runtime. It closes every module compiled by rt.
+func (rt *wazeroRuntime) Close(ctx context.Context) error {
+	rt.mu.Lock()
+	modules := rt.modules
+	rt.modules = nil
+	rt.mu.Unlock()
+	var errs []error
+	for _, m := range modules {
+		errs = append(errs, m.Close(ctx))
+	}
+	errs = append(errs, rt.cache.Close(ctx))
+	return errors.Join(errs...)
+}
+
+type wazeroModule struct {
+	name     string
+	limits   Limits
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	exports  map[string]bool
+	metrics  *metrics.Metrics
+}
+
+// compile checks the module against the ABI: no imports but s3eg.log, an
+// exported memory, s3eg_alloc and at least one extension
+// export with the right signatures. It instantiates the module once so
+// unresolvable imports of any kind fail here rather than on first call.
+func (m *wazeroModule) compile(ctx context.Context, wasm []byte, log *logrus.Entry) error {
+	_, err := m.runtime.NewHostModuleBuilder(hostModule).
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(func(_ context.Context, mod api.Module, stack []uint64) {
+			ptr, n := api.DecodeU32(stack[0]), api.DecodeU32(stack[1])
+			if n > maxLogLine {
+				n = maxLogLine
+			}
+			if line, ok := mod.Memory().Read(ptr, n); ok {
+				log.Debug(string(line))
+			}
+		}), []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, nil).
+		Export(hostLog).
+		Instantiate(ctx)
+	if err != nil {
+		return err
+	}
+
+	compiled, err := m.runtime.CompileModule(ctx, wasm)
+	if err != nil {
+		return err
+	}
+	m.compiled = compiled
+	for _, fn := range compiled.ImportedFunctions() {
+		mod, name, _ := fn.Import()
+		if mod != hostModule || name != hostLog {
+			return fmt.Errorf("imports %s.%s; only %s.%s is available", mod, name, hostModule, hostLog)
+		}
+	}
+	if len(compiled.ImportedMemories()) > 0 {
+		return errors.New("imports memory; it must export its own")
+	}
+	// wazero refuses a declared minimum above the memory limit and lowers
+	// a declared maximum to it, so memory.grow past the limit fails.
+	if _, ok := compiled.ExportedMemories()[exportMemory]; !ok {
+		return fmt.Errorf("does not export %q", exportMemory)
+	}
+
+	i32, i64 := api.ValueTypeI32, api.ValueTypeI64
+	fns := compiled.ExportedFunctions()
+	if !hasSignature(fns[exportAlloc], []api.ValueType{i32}, []api.ValueType{i32}) {
+		return fmt.Errorf("must export %s(i32) -> i32", exportAlloc)
+	}
+	m.exports = make(map[string]bool)
+	for _, name := range []string{ExportPolicy, ExportMetadata, ExportFilter} {
+		def, ok := fns[name]
+		if !ok {
+			continue
+		}
+		if !hasSignature(def, []api.ValueType{i32, i32}, []api.ValueType{i64}) {
+			return fmt.Errorf("%s must have signature (i32, i32) -> i64", name)
+		}
+		m.exports[name] = true
+	}
+	if len(m.exports) == 0 {
+		return fmt.Errorf("exports none of %s, %s, %s", ExportPolicy, ExportMetadata, ExportFilter)
+	}
+
+	inst, err := m.instantiate(ctx)
+	if err != nil {
+		return err
+	}
+	return inst.Close(ctx)
+}
+
+func hasSignature(def api.FunctionDefinition, params, results []api.ValueType) bool {
+	return def != nil && string(def.ParamTypes()) == string(params) && string(def.ResultTypes()) == string(results)
+}
+
+func (m *wazeroModule) instantiate(ctx context.Context) (api.Module, error) {
+	// An empty name lets instances of the module run concurrently. Without
+	// WithFS, WithSysWalltime and friends the instance sees no files, real
+	// clock or randomness, and with only s3eg.log importable, no network.
+	return m.runtime.InstantiateModule(ctx, m.compiled, wazero.NewModuleConfig().WithName("").WithStartFunctions())
+}
+
+// Name implements Module.
+func (m *wazeroModule) Name() string { return m.name }
+
+// Exports implements Module.
+func (m *wazeroModule) Exports(fn string) bool { return m.exports[fn] }
+
+// Call implements Module.
+func (m *wazeroModule) Call(ctx context.Context, fn string, input []byte) ([]byte, error) {
+	out, err := m.call(ctx, fn, input)
+	result := "ok"
+	switch {
+	case errors.Is(err, ErrLimitExceeded):
+		result = "limit_exceeded"
+	case err != nil:
+		result = "failed"
+	}
+	m.metrics.RecordExtensionCall(m.name, fn, result)
+	return out, err
+}
+
+func (m *wazeroModule) call(ctx context.Context, fn string, input []byte) ([]byte, error) {
+	if !m.exports[fn] {
+		return nil, fmt.Errorf("%w: %s", ErrMissingExport, fn)
+	}
+	if int64(len(input)) > m.limits.MaxInputSize {
+		return nil, fmt.Errorf("%w: input of %d bytes, limit is %d", ErrLimitExceeded, len(input), m.limits.MaxInputSize)
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, m.limits.Timeout)
+	defer cancel()
+	inst, err := m.instantiate(callCtx)
+	if err != nil {
+		return nil, m.callError(ctx, callCtx, err)
+	}
+	defer inst.Close(context.Background())
+
+	res, err := inst.ExportedFunction(exportAlloc).Call(callCtx, uint64(len(input)))
+	if err != nil {
+		return nil, m.callError(ctx, callCtx, err)
+	}
+	ptr := api.DecodeU32(res[0])
+	mem := inst.ExportedMemory(exportMemory)
+	if !mem.Write(ptr, input) {
+		return nil, fmt.Errorf("%s returned %d, outside memory", exportAlloc, ptr)
+	}
+
+	res, err = inst.ExportedFunction(fn).Call(callCtx, uint64(ptr), uint64(len(input)))
+	if err != nil {
+		return nil, m.callError(ctx, callCtx, err)
+	}
+	outPtr, outLen := uint32(res[0]>>32), uint32(res[0])
+	if int64(outLen) > m.limits.MaxInputSize {
+		return nil, fmt.Errorf("%w: output of %d bytes, limit is %d", ErrLimitExceeded, outLen, m.limits.MaxInputSize)
+	}
+	out, ok := mem.Read(outPtr, outLen)
+	if !ok {
+		return nil, fmt.Errorf("%s returned %d bytes at %d, outside memory", fn, outLen, outPtr)
+	}
+	// out aliases the instance's memory, which is released on return.
+	return append([]byte(nil), out...), nil
+}
+
+// callError reports a call stopped by its deadline as ErrLimitExceeded. A
+// call stopped because ctx itself ended returns ctx's error.
+func (m *wazeroModule) callError(ctx, callCtx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: call ran longer than %s", ErrLimitExceeded, m.limits.Timeout.Round(time.Millisecond))
+	}
+	return err
+}
+
+// Close implements Module.
+func (m *wazeroModule) Close(ctx context.Context) error {
+	return m.runtime.Close(ctx)
+}
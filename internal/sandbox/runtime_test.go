@@ -0,0 +1,330 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/transform"
+)
+
+// The tests build their modules by hand rather than shipping binaries; see
+// testModule.encode for the subset of the binary format used.
+
+const (
+	i32 = 0x7f
+	i64 = 0x7e
+)
+
+type testFunc struct {
+	export  string
+	params  []byte
+	results []byte
+	body    []byte // instructions, without the final end
+}
+
+type testImport struct {
+	module, name string
+	params       []byte
+}
+
+type testModule struct {
+	imports []testImport
+	memMin  uint32
+	memMax  uint32 // 0: unbounded
+	noMem   bool
+	funcs   []testFunc
+	data    string // stored at address 0
+}
+
+func uleb(v uint64) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v != 0 {
+			out = append(out, b|0x80)
+			continue
+		}
+		return append(out, b)
+	}
+}
+
+func sleb(v int64) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0) {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+func vec(items ...[]byte) []byte {
+	out := uleb(uint64(len(items)))
+	for _, it := range items {
+		out = append(out, it...)
+	}
+	return out
+}
+
+func name(s string) []byte { return append(uleb(uint64(len(s))), s...) }
+
+func section(id byte, content []byte) []byte {
+	return append(append([]byte{id}, uleb(uint64(len(content)))...), content...)
+}
+
+func (m testModule) encode() []byte {
+	var types, imports, funcs, exports, code [][]byte
+	for i, im := range m.imports {
+		types = append(types, append(append([]byte{0x60}, vec(bytesOf(im.params)...)...), 0x00))
+		imports = append(imports, append(append(name(im.module), name(im.name)...), append([]byte{0x00}, uleb(uint64(i))...)...))
+	}
+	if !m.noMem {
+		exports = append(exports, append(name("memory"), 0x02, 0x00))
+	}
+	for i, f := range m.funcs {
+		idx := uint64(len(m.imports) + i)
+		types = append(types, append(append([]byte{0x60}, vec(bytesOf(f.params)...)...), vec(bytesOf(f.results)...)...))
+		funcs = append(funcs, uleb(idx))
+		if f.export != "" {
+			exports = append(exports, append(append(name(f.export), 0x00), uleb(idx)...))
+		}
+		body := append([]byte{0x00}, append(f.body, 0x0b)...) // no locals
+		code = append(code, append(uleb(uint64(len(body))), body...))
+	}
+
+	out := []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+	out = append(out, section(1, vec(types...))...)
+	if len(imports) > 0 {
+		out = append(out, section(2, vec(imports...))...)
+	}
+	out = append(out, section(3, vec(funcs...))...)
+	if !m.noMem {
+		limits := append([]byte{0x00}, uleb(uint64(m.memMin))...)
+		if m.memMax > 0 {
+			limits = append(append([]byte{0x01}, uleb(uint64(m.memMin))...), uleb(uint64(m.memMax))...)
+		}
+		out = append(out, section(5, vec(limits))...)
+	}
+	out = append(out, section(7, vec(exports...))...)
+	out = append(out, section(10, vec(code...))...)
+	if m.data != "" {
+		seg := append([]byte{0x00, 0x41, 0x00, 0x0b}, name(m.data)...)
+		out = append(out, section(11, vec(seg))...)
+	}
+	return out
+}
+
+func bytesOf(b []byte) [][]byte {
+	out := make([][]byte, len(b))
+	for i := range b {
+		out[i] = b[i : i+1]
+	}
+	return out
+}
+
+// Function bodies.
+var (
+	// s3eg_alloc: every input goes to address 1024.
+	allocFunc = testFunc{export: exportAlloc, params: []byte{i32}, results: []byte{i32}, body: append([]byte{0x41}, sleb(1024)...)}
+	// (ptr, len) -> ptr<<32 | len: returns its input.
+	echoBody = []byte{0x20, 0x00, 0xad, 0x42, 0x20, 0x86, 0x20, 0x01, 0xad, 0x84}
+	// Spins forever.
+	loopBody = []byte{0x03, 0x40, 0x0c, 0x00, 0x0b, 0x42, 0x00}
+	// Grows memory by 32 pages and returns memory.grow's result plus one
+	// bytes from address 0: nothing when the grow was refused.
+	growBody = []byte{0x41, 0x20, 0x40, 0x00, 0x41, 0x01, 0x6a, 0xad}
+)
+
+// constBody returns the n bytes at address 0.
+func constBody(n int) []byte { return append([]byte{0x42}, sleb(int64(n))...) }
+
+func extFunc(export string, body []byte) testFunc {
+	return testFunc{export: export, params: []byte{i32, i32}, results: []byte{i64}, body: body}
+}
+
+func compile(t *testing.T, m testModule, limits Limits) (Module, error) {
+	t.Helper()
+	rt, err := NewRuntime(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = rt.Close(context.Background()) })
+	return rt.Compile(context.Background(), "test", m.encode(), limits)
+}
+
+func TestRuntime_Call(t *testing.T) {
+	const decision = `{"allow":false,"reason":"read only"}`
+	mod, err := compile(t, testModule{
+		imports: []testImport{{module: hostModule, name: hostLog, params: []byte{i32, i32}}},
+		memMin:  1,
+		data:    decision,
+		funcs: []testFunc{
+			allocFunc,
+			// Logs the decision, then returns it.
+			extFunc(ExportPolicy, append([]byte{0x41, 0x00, 0x41, byte(len(decision)), 0x10, 0x00}, constBody(len(decision))...)),
+			extFunc(ExportFilter, echoBody),
+		},
+	}, Limits{})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if !mod.Exports(ExportPolicy) || !mod.Exports(ExportFilter) || mod.Exports(ExportMetadata) {
+		t.Errorf("Exports wrong for policy/filter module")
+	}
+
+	d, err := Decide(context.Background(), mod, PolicyRequest{Operation: "PutObject", Bucket: "b", Key: "k"})
+	if err != nil {
+		t.Fatalf("Decide: %v", err)
+	}
+	if d.Allow || d.Reason != "read only" {
+		t.Errorf("decision = %+v", d)
+	}
+
+	f := &Filter{Module: mod}
+	res, err := f.Transform(context.Background(), transform.Request{}, strings.NewReader("passed through the module"))
+	if err != nil {
+		t.Fatalf("Transform: %v", err)
+	}
+	got, _ := io.ReadAll(res.Body)
+	if string(got) != "passed through the module" {
+		t.Errorf("filter output = %q", got)
+	}
+
+	if _, err := mod.Call(context.Background(), ExportMetadata, nil); !errors.Is(err, ErrMissingExport) {
+		t.Errorf("missing export: error = %v, want ErrMissingExport", err)
+	}
+}
+
+func TestRuntime_CompileRejects(t *testing.T) {
+	filter := extFunc(ExportFilter, echoBody)
+	tests := []struct {
+		name   string
+		module testModule
+		is     error
+	}{
+		{"WASI import", testModule{
+			imports: []testImport{{module: "wasi_snapshot_preview1", name: "fd_write", params: []byte{i32, i32}}},
+			memMin:  1,
+			funcs:   []testFunc{allocFunc, filter},
+		}, nil},
+		{"initial memory above limit", testModule{memMin: 32, funcs: []testFunc{allocFunc, filter}}, nil},
+		{"no memory", testModule{noMem: true, funcs: []testFunc{allocFunc, filter}}, nil},
+		{"no alloc", testModule{memMin: 1, funcs: []testFunc{filter}}, nil},
+		{"no extension export", testModule{memMin: 1, funcs: []testFunc{allocFunc}}, nil},
+		{"wrong signature", testModule{memMin: 1, funcs: []testFunc{allocFunc, {export: ExportFilter, params: []byte{i32}, results: []byte{i32}, body: []byte{0x20, 0x00}}}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compile(t, tt.module, Limits{MemoryPages: 16})
+			if err == nil {
+				t.Fatal("Compile succeeded")
+			}
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Errorf("error = %v, want %v", err, tt.is)
+			}
+		})
+	}
+}
+
+func TestRuntime_Limits(t *testing.T) {
+	mod, err := compile(t, testModule{
+		memMin: 1,
+		funcs: []testFunc{
+			allocFunc,
+			extFunc(ExportPolicy, loopBody),
+			extFunc(ExportFilter, echoBody),
+			extFunc(ExportMetadata, constBody(4096)),
+		},
+	}, Limits{Timeout: 50 * time.Millisecond, MaxInputSize: 1024})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	ctx := context.Background()
+
+	start := time.Now()
+	if _, err := mod.Call(ctx, ExportPolicy, []byte("{}")); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("spinning call: error = %v, want ErrLimitExceeded", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("spinning call took %s", d)
+	}
+	if _, err := mod.Call(ctx, ExportFilter, make([]byte, 1025)); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("oversized input: error = %v, want ErrLimitExceeded", err)
+	}
+	if _, err := mod.Call(ctx, ExportMetadata, nil); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("oversized output: error = %v, want ErrLimitExceeded", err)
+	}
+	// The instance stopped by its deadline does not affect the next call.
+	if out, err := mod.Call(ctx, ExportFilter, []byte("ok")); err != nil || string(out) != "ok" {
+		t.Errorf("call after timeout = %q, %v", out, err)
+	}
+}
+
+func TestRuntime_MemoryLimit(t *testing.T) {
+	// The declared maximum of 64 pages is lowered to the limit of 16.
+	mod, err := compile(t, testModule{memMin: 1, memMax: 64, funcs: []testFunc{allocFunc, extFunc(ExportFilter, growBody)}}, Limits{MemoryPages: 16})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	out, err := mod.Call(context.Background(), ExportFilter, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 0 {
+		t.Errorf("memory grew past the limit (grow returned %d)", len(out)-1)
+	}
+
+	mod, err = compile(t, testModule{memMin: 1, memMax: 64, funcs: []testFunc{allocFunc, extFunc(ExportFilter, growBody)}}, Limits{MemoryPages: 64})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if out, err := mod.Call(context.Background(), ExportFilter, nil); err != nil || len(out) != 2 {
+		t.Errorf("grow within the limit: %d bytes, %v", len(out), err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(file string, m testModule) string {
+		path := filepath.Join(dir, file)
+		if err := os.WriteFile(path, m.encode(), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	allow := `{"allow":true}`
+	policy := write("policy.wasm", testModule{memMin: 1, data: allow, funcs: []testFunc{allocFunc, extFunc(ExportPolicy, constBody(len(allow)))}})
+	filter := write("filter.wasm", testModule{memMin: 1, funcs: []testFunc{allocFunc, extFunc(ExportFilter, echoBody)}})
+
+	ext, err := Load(context.Background(), config.ExtensionsConfig{Enabled: true, Modules: []config.ExtensionModule{
+		{Name: "hours", Path: policy, Hooks: []string{config.ExtensionHookPolicy}, Bucket: "reports"},
+		{Name: "redact", Path: filter},
+	}}, nil, nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	defer ext.Close(context.Background())
+	if len(ext.Policy) != 1 || len(ext.Metadata) != 0 || ext.Filters["redact"] == nil || ext.Filters["hours"] != nil {
+		t.Fatalf("Load = %+v", ext)
+	}
+	if !ext.Policy[0].Matches("reports", "2026/q1.csv") || ext.Policy[0].Matches("logs", "a") {
+		t.Error("policy hook scope not applied")
+	}
+
+	_, err = Load(context.Background(), config.ExtensionsConfig{Enabled: true, Modules: []config.ExtensionModule{
+		{Name: "redact", Path: filter, Hooks: []string{config.ExtensionHookMetadata}},
+	}}, nil, nil)
+	if !errors.Is(err, ErrMissingExport) {
+		t.Errorf("metadata hook without s3eg_metadata: error = %v, want ErrMissingExport", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Package sandbox runs operator-supplied WebAssembly modules for policy
+// decisions, metadata transformations and content filters, so custom logic
+// does not require a fork of the gateway.
+//
+// Modules are untrusted. Each call runs in a fresh instance with no
+// filesystem, network, clock or environment access, under a memory cap and
+// a per-call deadline; a module only ever sees the JSON envelope or content
+// it is handed. The module ABI and the limits are specified in
+// docs/adr/0013-wasm-extension-sandbox.md.
+//
+// The runtime is wazero, behind [Runtime]; [Load] compiles the modules
+// configured under extensions and attaches them to their hooks.
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Exports a module may provide, one per extension kind. Each takes
+// (ptr, len) of its input in module memory and returns the output as
+// ptr<<32 | len.
+const (
+	ExportPolicy   = "s3eg_policy"
+	ExportMetadata = "s3eg_metadata"
+	ExportFilter   = "s3eg_filter"
+)
+
+// Default limits, applied to zero fields of [Limits].
+const (
+	// DefaultMemoryPages caps linear memory at 16 MiB (64 KiB pages).
+	DefaultMemoryPages = 256
+	DefaultTimeout     = time.Second
+	// DefaultMaxInputSize bounds the content handed to a filter.
+	DefaultMaxInputSize = 8 << 20
+)
+
+var (
+	// ErrLimitExceeded is returned when a call runs out of time or memory,
+	// or its input or output is over the configured size.
+	ErrLimitExceeded = errors.New("sandbox: module limit exceeded")
+
+	// ErrMissingExport is returned when a module lacks the export a call
+	// needs.
+	ErrMissingExport = errors.New("sandbox: module does not export function")
+)
+
+// Limits bounds every call into a module.
+type Limits struct {
+	// MemoryPages is the maximum linear memory in 64 KiB pages.
+	MemoryPages uint32
+	// Timeout is the wall-clock deadline of one call; the runtime aborts
+	// the instance when it passes.
+	Timeout time.Duration
+	// MaxInputSize bounds the input, and the output, of one call.
+	MaxInputSize int64
+}
+
+// withDefaults returns l with zero fields set to the defaults.
+func (l Limits) withDefaults() Limits {
+	if l.MemoryPages == 0 {
+		l.MemoryPages = DefaultMemoryPages
+	}
+	if l.Timeout <= 0 {
+		l.Timeout = DefaultTimeout
+	}
+	if l.MaxInputSize <= 0 {
+		l.MaxInputSize = DefaultMaxInputSize
+	}
+	return l
+}
+
+// Runtime compiles modules. Implementations MUST be safe for concurrent
+// use.
+type Runtime interface {
+	// Compile validates and compiles a module. It fails if the module
+	// imports anything beyond the host functions of the ABI or declares
+	// more memory than limits allow.
+	Compile(ctx context.Context, name string, wasm []byte, limits Limits) (Module, error)
+	Close(ctx context.Context) error
+}
+
+// Module is a compiled module. Call instantiates it afresh, so no state
+// survives between calls. Implementations MUST be safe for concurrent use.
+type Module interface {
+	Name() string
+	// Exports reports whether the module provides fn.
+	Exports(fn string) bool
+	// Call runs fn on input and returns its output, failing with
+	// ErrLimitExceeded when a limit is hit and ErrMissingExport when fn is
+	// not provided.
+	Call(ctx context.Context, fn string, input []byte) ([]byte, error)
+	Close(ctx context.Context) error
+}
@@ -0,0 +1,91 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/transform"
+)
+
+// fakeModule answers calls from a table of canned outputs.
+type fakeModule struct {
+	out  map[string]string
+	last []byte
+}
+
+func (f *fakeModule) Name() string                { return "fake" }
+func (f *fakeModule) Exports(fn string) bool      { _, ok := f.out[fn]; return ok }
+func (f *fakeModule) Close(context.Context) error { return nil }
+
+func (f *fakeModule) Call(_ context.Context, fn string, input []byte) ([]byte, error) {
+	f.last = input
+	out, ok := f.out[fn]
+	if !ok {
+		return nil, ErrMissingExport
+	}
+	return []byte(out), nil
+}
+
+func TestDecide(t *testing.T) {
+	m := &fakeModule{out: map[string]string{ExportPolicy: `{"allow":false,"reason":"outside business hours"}`}}
+	d, err := Decide(context.Background(), m, PolicyRequest{Operation: "GetObject", Bucket: "b", Key: "k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Allow || d.Reason != "outside business hours" {
+		t.Errorf("decision = %+v", d)
+	}
+	if !strings.Contains(string(m.last), `"operation":"GetObject"`) {
+		t.Errorf("module received %s", m.last)
+	}
+
+	for name, mod := range map[string]*fakeModule{
+		"garbage":        {out: map[string]string{ExportPolicy: "yes"}},
+		"missing export": {out: map[string]string{}},
+	} {
+		if _, err := Decide(context.Background(), mod, PolicyRequest{}); err == nil {
+			t.Errorf("%s: Decide() succeeded", name)
+		}
+	}
+}
+
+func TestTransformMetadata(t *testing.T) {
+	m := &fakeModule{out: map[string]string{ExportMetadata: `{"x-amz-meta-owner":"finance"}`}}
+	md, err := TransformMetadata(context.Background(), m, MetadataRequest{Bucket: "b", Key: "k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md["x-amz-meta-owner"] != "finance" {
+		t.Errorf("metadata = %v", md)
+	}
+
+	for _, out := range []string{
+		`{"x-amz-meta-encrypted":"false"}`,
+		`{"Content-Type":"text/html"}`,
+	} {
+		m := &fakeModule{out: map[string]string{ExportMetadata: out}}
+		if _, err := TransformMetadata(context.Background(), m, MetadataRequest{}); err == nil {
+			t.Errorf("module output %s accepted", out)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	var _ transform.Service = (*Filter)(nil)
+	f := &Filter{Module: &fakeModule{out: map[string]string{ExportFilter: "id,name\n1,[redacted]\n"}}, Limits: Limits{MaxInputSize: 32}}
+	res, err := f.Transform(context.Background(), transform.Request{ContentType: "text/csv"}, strings.NewReader("id,name\n1,alice\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(res.Body)
+	if string(got) != "id,name\n1,[redacted]\n" || res.ContentType != "text/csv" {
+		t.Errorf("filtered %q (%s)", got, res.ContentType)
+	}
+
+	if _, err := f.Transform(context.Background(), transform.Request{}, strings.NewReader(strings.Repeat("x", 33))); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("oversized input: error = %v, want ErrLimitExceeded", err)
+	}
+}
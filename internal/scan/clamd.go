@@ -0,0 +1,84 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// clamdChunkSize is the INSTREAM chunk size. clamd rejects chunks above its
+// StreamMaxLength, which is far larger.
+const clamdChunkSize = 64 << 10
+
+// ClamdScanner talks to clamd using the INSTREAM command.
+type ClamdScanner struct {
+	network string
+	address string
+}
+
+// NewClamdScanner returns a scanner for clamd at address: host:port, or
+// unix:/path for a local socket.
+func NewClamdScanner(address string) *ClamdScanner {
+	network, addr := splitAddress(address)
+	return &ClamdScanner{network: network, address: addr}
+}
+
+// Scan implements Scanner.
+func (c *ClamdScanner) Scan(ctx context.Context, r io.Reader, _ int64) (Verdict, error) {
+	conn, err := dial(ctx, c.network, c.address)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("clamd: %w", err)
+	}
+	defer conn.Close()
+
+	w := bufio.NewWriterSize(conn, clamdChunkSize+4)
+	if _, err := w.WriteString("zINSTREAM\x00"); err != nil {
+		return Verdict{}, fmt.Errorf("clamd: %w", err)
+	}
+	buf := make([]byte, clamdChunkSize)
+	var hdr [4]byte
+	for {
+		n, rerr := io.ReadFull(r, buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(hdr[:], uint32(n))
+			w.Write(hdr[:])
+			if _, err := w.Write(buf[:n]); err != nil {
+				return Verdict{}, fmt.Errorf("clamd: send: %w", err)
+			}
+		}
+		if rerr == io.EOF || rerr == io.ErrUnexpectedEOF {
+			break
+		}
+		if rerr != nil {
+			return Verdict{}, fmt.Errorf("clamd: read upload: %w", rerr)
+		}
+	}
+	binary.BigEndian.PutUint32(hdr[:], 0)
+	w.Write(hdr[:])
+	if err := w.Flush(); err != nil {
+		return Verdict{}, fmt.Errorf("clamd: send: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return Verdict{}, fmt.Errorf("clamd: read reply: %w", err)
+	}
+	return parseClamdReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseClamdReply interprets "stream: OK", "stream: <name> FOUND" and
+// "<message> ERROR".
+func parseClamdReply(reply string) (Verdict, error) {
+	_, result, _ := strings.Cut(reply, ": ")
+	switch {
+	case result == "OK":
+		return Verdict{}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return Verdict{Infected: true, Signature: strings.TrimSuffix(result, " FOUND")}, nil
+	default:
+		return Verdict{}, fmt.Errorf("clamd: %s", reply)
+	}
+}
@@ -0,0 +1,136 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ICAPScanner submits uploads to an ICAP server with RESPMOD, as if the
+// object were an HTTP response being delivered.
+type ICAPScanner struct {
+	url  *url.URL
+	host string
+}
+
+// NewICAPScanner returns a scanner for an icap://host[:port]/service URL.
+// The port defaults to 1344.
+func NewICAPScanner(rawURL string) (*ICAPScanner, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "icap" || u.Host == "" {
+		return nil, fmt.Errorf("icap: invalid service URL %q", rawURL)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "1344")
+	}
+	return &ICAPScanner{url: u, host: host}, nil
+}
+
+// Scan implements Scanner. A 204 reply means the object is clean. A 200
+// reply means the server replaced the response, which scanners do to block
+// it; the threat name is taken from the common X-Infection-Found,
+// X-Violations-Found and X-Virus-ID headers when present.
+func (s *ICAPScanner) Scan(ctx context.Context, r io.Reader, size int64) (Verdict, error) {
+	conn, err := dial(ctx, "tcp", s.host)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("icap: %w", err)
+	}
+	defer conn.Close()
+
+	resHdr := "HTTP/1.1 200 OK\r\nContent-Type: application/octet-stream\r\n"
+	if size > 0 {
+		resHdr += "Content-Length: " + strconv.FormatInt(size, 10) + "\r\n"
+	}
+	resHdr += "\r\n"
+
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "RESPMOD %s ICAP/1.0\r\n", s.url.String())
+	fmt.Fprintf(w, "Host: %s\r\n", s.url.Host)
+	fmt.Fprintf(w, "Allow: 204\r\n")
+	fmt.Fprintf(w, "Encapsulated: res-hdr=0, res-body=%d\r\n\r\n", len(resHdr))
+	w.WriteString(resHdr)
+
+	buf := make([]byte, 64<<10)
+	for {
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			fmt.Fprintf(w, "%x\r\n", n)
+			w.Write(buf[:n])
+			if _, err := w.WriteString("\r\n"); err != nil {
+				return Verdict{}, fmt.Errorf("icap: send: %w", err)
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return Verdict{}, fmt.Errorf("icap: read upload: %w", rerr)
+		}
+	}
+	w.WriteString("0\r\n\r\n")
+	if err := w.Flush(); err != nil {
+		return Verdict{}, fmt.Errorf("icap: send: %w", err)
+	}
+
+	tp := textproto.NewReader(bufio.NewReader(conn))
+	status, err := tp.ReadLine()
+	if err != nil {
+		return Verdict{}, fmt.Errorf("icap: read reply: %w", err)
+	}
+	hdr, err := tp.ReadMIMEHeader()
+	if err != nil && len(hdr) == 0 {
+		return Verdict{}, fmt.Errorf("icap: read reply headers: %w", err)
+	}
+	code, err := parseICAPStatus(status)
+	if err != nil {
+		return Verdict{}, err
+	}
+	switch code {
+	case 204:
+		return Verdict{}, nil
+	case 200:
+		return Verdict{Infected: true, Signature: icapThreat(hdr)}, nil
+	default:
+		return Verdict{}, fmt.Errorf("icap: server replied %q", status)
+	}
+}
+
+func parseICAPStatus(line string) (int, error) {
+	proto, rest, ok := strings.Cut(line, " ")
+	if !ok || !strings.HasPrefix(proto, "ICAP/") {
+		return 0, fmt.Errorf("icap: malformed status line %q", line)
+	}
+	codeStr, _, _ := strings.Cut(rest, " ")
+	code, err := strconv.Atoi(codeStr)
+	if err != nil {
+		return 0, fmt.Errorf("icap: malformed status line %q", line)
+	}
+	return code, nil
+}
+
+// icapThreat extracts a threat name from vendor headers, e.g.
+// "X-Infection-Found: Type=0; Resolution=2; Threat=Eicar-Test-Signature;".
+func icapThreat(hdr textproto.MIMEHeader) string {
+	if v := hdr.Get("X-Infection-Found"); v != "" {
+		for _, part := range strings.Split(v, ";") {
+			if name, ok := strings.CutPrefix(strings.TrimSpace(part), "Threat="); ok {
+				return name
+			}
+		}
+		return v
+	}
+	if v := hdr.Get("X-Virus-ID"); v != "" {
+		return v
+	}
+	if v := hdr.Get("X-Violations-Found"); v != "" {
+		return v
+	}
+	return "unknown"
+}
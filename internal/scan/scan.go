@@ -0,0 +1,77 @@
+// Package scan streams plaintext uploads to an external malware scanner.
+//
+// Two protocols are supported: clamd's INSTREAM command and ICAP RESPMOD
+// (RFC 3507), which covers most commercial scanning appliances. A scanner
+// only reports a verdict; what happens to an infected upload is decided by
+// the caller.
+package scan
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/airgap"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+// Verdict is the outcome of a scan.
+type Verdict struct {
+	Infected bool
+	// Signature names what was found, when the scanner reports it.
+	Signature string
+}
+
+// Scanner checks a stream of size bytes for malware.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader, size int64) (Verdict, error)
+}
+
+// New returns the scanner configured by cfg.
+func New(cfg config.ScanningConfig) (Scanner, error) {
+	switch cfg.Protocol {
+	case "clamd":
+		return NewClamdScanner(cfg.Address), nil
+	case "icap":
+		return NewICAPScanner(cfg.Address)
+	default:
+		return nil, fmt.Errorf("scan: unsupported protocol %q", cfg.Protocol)
+	}
+}
+
+// dial connects to address ("unix:/path" for a Unix socket) and applies
+// ctx's deadline to the connection.
+func dial(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := airgap.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+	// Unblock reads and writes if ctx is cancelled before the deadline.
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Unix(1, 0)) })
+	return &ctxConn{Conn: conn, stop: stop}, nil
+}
+
+type ctxConn struct {
+	net.Conn
+	stop func() bool
+}
+
+func (c *ctxConn) Close() error {
+	c.stop()
+	return c.Conn.Close()
+}
+
+// splitAddress maps "unix:/path" and "unix:///path" to a Unix socket and
+// anything else to TCP.
+func splitAddress(address string) (network, addr string) {
+	if rest, ok := strings.CutPrefix(address, "unix:"); ok {
+		return "unix", "/" + strings.TrimLeft(rest, "/")
+	}
+	return "tcp", strings.TrimPrefix(address, "tcp://")
+}
@@ -0,0 +1,201 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+// serve accepts one connection at a time on ln and hands it to handle.
+func serve(t *testing.T, ln net.Listener, handle func(net.Conn)) {
+	t.Helper()
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			handle(conn)
+			conn.Close()
+		}
+	}()
+}
+
+// fakeClamd implements INSTREAM and reports any stream containing "EICAR".
+func fakeClamd(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	cmd, err := r.ReadString(0)
+	if err != nil || cmd != "zINSTREAM\x00" {
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+		return
+	}
+	var data bytes.Buffer
+	var hdr [4]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return
+		}
+		n := binary.BigEndian.Uint32(hdr[:])
+		if n == 0 {
+			break
+		}
+		if _, err := io.CopyN(&data, r, int64(n)); err != nil {
+			return
+		}
+	}
+	if bytes.Contains(data.Bytes(), []byte("EICAR")) {
+		conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+		return
+	}
+	conn.Write([]byte("stream: OK\x00"))
+}
+
+func TestClamdScanner(t *testing.T) {
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve(t, tcp, fakeClamd)
+	sock := filepath.Join(t.TempDir(), "clamd.sock")
+	unix, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve(t, unix, fakeClamd)
+
+	for _, addr := range []string{tcp.Addr().String(), "unix:" + sock} {
+		s := NewClamdScanner(addr)
+		big := strings.Repeat("a", 3*clamdChunkSize+7)
+		v, err := s.Scan(context.Background(), strings.NewReader(big), int64(len(big)))
+		if err != nil || v.Infected {
+			t.Errorf("%s: clean stream = %+v, %v", addr, v, err)
+		}
+		v, err = s.Scan(context.Background(), strings.NewReader(big+"EICAR"), 0)
+		if err != nil || !v.Infected || v.Signature != "Eicar-Test-Signature" {
+			t.Errorf("%s: infected stream = %+v, %v", addr, v, err)
+		}
+	}
+}
+
+func TestParseClamdReply(t *testing.T) {
+	if _, err := parseClamdReply("INSTREAM size limit exceeded. ERROR"); err == nil {
+		t.Error("expected an error for an ERROR reply")
+	}
+}
+
+func TestClamdScanner_Unreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	if _, err := NewClamdScanner(addr).Scan(context.Background(), strings.NewReader("x"), 1); err == nil {
+		t.Error("expected a dial error")
+	}
+}
+
+func TestClamdScanner_Timeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve(t, ln, func(conn net.Conn) { time.Sleep(500 * time.Millisecond) })
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := NewClamdScanner(ln.Addr().String()).Scan(ctx, strings.NewReader("x"), 1); err == nil {
+		t.Error("expected a timeout error")
+	}
+}
+
+// fakeICAP answers RESPMOD: 204 for clean bodies, 200 with an
+// X-Infection-Found header when the body contains "EICAR".
+func fakeICAP(t *testing.T) func(net.Conn) {
+	return func(conn net.Conn) {
+		br := bufio.NewReader(conn)
+		tp := textproto.NewReader(br)
+		line, err := tp.ReadLine()
+		if err != nil || !strings.HasPrefix(line, "RESPMOD icap://") {
+			conn.Write([]byte("ICAP/1.0 400 Bad Request\r\n\r\n"))
+			return
+		}
+		hdr, err := tp.ReadMIMEHeader()
+		if err != nil {
+			return
+		}
+		if !strings.HasPrefix(hdr.Get("Encapsulated"), "res-hdr=0, res-body=") {
+			t.Errorf("Encapsulated = %q", hdr.Get("Encapsulated"))
+		}
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Errorf("encapsulated response: %v", err)
+			return
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("encapsulated status = %d", resp.StatusCode)
+		}
+		body, _ := io.ReadAll(httputil.NewChunkedReader(br))
+		if bytes.Contains(body, []byte("EICAR")) {
+			conn.Write([]byte("ICAP/1.0 200 OK\r\nX-Infection-Found: Type=0; Resolution=2; Threat=Eicar-Test-Signature;\r\nEncapsulated: null-body=0\r\n\r\n"))
+			return
+		}
+		conn.Write([]byte("ICAP/1.0 204 No Content\r\n\r\n"))
+	}
+}
+
+func TestICAPScanner(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve(t, ln, fakeICAP(t))
+	s, err := NewICAPScanner("icap://" + ln.Addr().String() + "/avscan")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := strings.Repeat("b", 100<<10)
+	v, err := s.Scan(context.Background(), strings.NewReader(body), int64(len(body)))
+	if err != nil || v.Infected {
+		t.Errorf("clean body = %+v, %v", v, err)
+	}
+	v, err = s.Scan(context.Background(), strings.NewReader(body+"EICAR"), int64(len(body)+5))
+	if err != nil || !v.Infected || v.Signature != "Eicar-Test-Signature" {
+		t.Errorf("infected body = %+v, %v", v, err)
+	}
+}
+
+func TestNewICAPScanner_DefaultPort(t *testing.T) {
+	s, err := NewICAPScanner("icap://scanner.internal/avscan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.host != "scanner.internal:1344" {
+		t.Errorf("host = %q", s.host)
+	}
+	if _, err := NewICAPScanner("http://scanner.internal/avscan"); err == nil {
+		t.Error("expected an error for a non-icap URL")
+	}
+}
+
+func TestNew(t *testing.T) {
+	if s, err := New(config.ScanningConfig{Protocol: "clamd", Address: "localhost:3310"}); err != nil || s == nil {
+		t.Errorf("clamd: %v", err)
+	}
+	if _, err := New(config.ScanningConfig{Protocol: "sophos"}); err == nil {
+		t.Error("expected an error for an unknown protocol")
+	}
+}
@@ -0,0 +1,197 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule computes when a job is next due.
+type Schedule interface {
+	// Next returns the first activation strictly after t, or the zero time
+	// when there is none.
+	Next(t time.Time) time.Time
+}
+
+// Parse reads a schedule: a five-field cron expression (minute, hour, day
+// of month, month, day of week), one of the descriptors @yearly,
+// @annually, @monthly, @weekly, @daily, @midnight and @hourly, or
+// "@every <duration>" for a fixed interval.
+//
+// Fields accept *, single values, ranges a-b, lists separated by commas and
+// steps (*/n, a-b/n, a/n). Months and weekdays may be given by their
+// three-letter English names, and 7 is also Sunday. As in cron, when both
+// the day of month and the day of week are restricted a day matching
+// either is due.
+func Parse(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if rest, ok := strings.CutPrefix(expr, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", expr, err)
+		}
+		if d < time.Second {
+			return nil, fmt.Errorf("schedule %q: interval must be at least 1s", expr)
+		}
+		return every(d), nil
+	}
+	switch expr {
+	case "@yearly", "@annually":
+		expr = "0 0 1 1 *"
+	case "@monthly":
+		expr = "0 0 1 * *"
+	case "@weekly":
+		expr = "0 0 * * 0"
+	case "@daily", "@midnight":
+		expr = "0 0 * * *"
+	case "@hourly":
+		expr = "0 * * * *"
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q: want 5 fields, got %d", expr, len(fields))
+	}
+	var c cron
+	var err error
+	for i, f := range []struct {
+		dst      *uint64
+		min, max int
+		names    []string
+	}{
+		{&c.minute, 0, 59, nil},
+		{&c.hour, 0, 23, nil},
+		{&c.dom, 1, 31, nil},
+		{&c.month, 1, 12, monthNames},
+		{&c.dow, 0, 7, dayNames},
+	} {
+		if *f.dst, err = parseField(fields[i], f.min, f.max, f.names); err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", expr, err)
+		}
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow = c.dow&^(1<<7) | 1
+	}
+	c.domAny = fields[2] == "*"
+	c.dowAny = fields[4] == "*"
+	if c.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("schedule %q is never due", expr)
+	}
+	return c, nil
+}
+
+var (
+	monthNames = []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	dayNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// parseField returns the values field selects as a bit set.
+func parseField(field string, min, max int, names []string) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = parseValue(a, min, max, names); err != nil {
+				return 0, err
+			}
+			hi = lo
+			switch {
+			case isRange:
+				if hi, err = parseValue(b, min, max, names); err != nil {
+					return 0, err
+				}
+				if hi < lo {
+					return 0, fmt.Errorf("range %q is reversed", rng)
+				}
+			case hasStep:
+				hi = max
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func parseValue(s string, min, max int, names []string) (int, error) {
+	for i, name := range names {
+		if name != "" && strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < min || v > max {
+		return 0, fmt.Errorf("value %q out of range %d-%d", s, min, max)
+	}
+	return v, nil
+}
+
+// every is a fixed interval from the previous activation.
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// cron is a parsed five-field expression, each field a bit set of the
+// values it matches.
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// Next walks forward from t a field at a time, skipping whole months,
+// days and hours that cannot match. Five years is enough to reach any
+// satisfiable expression, including 29 February.
+func (c cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
@@ -0,0 +1,60 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse_Next(t *testing.T) {
+	// Wednesday.
+	from := time.Date(2026, 1, 14, 10, 7, 30, 0, time.UTC)
+	for _, tc := range []struct {
+		expr, want string
+	}{
+		{"*/15 * * * *", "2026-01-14T10:15:00Z"},
+		{"5 10 * * *", "2026-01-15T10:05:00Z"},
+		{"0 3 * * sun", "2026-01-18T03:00:00Z"},
+		{"0 3 * * 7", "2026-01-18T03:00:00Z"},
+		{"30 2 1 * *", "2026-02-01T02:30:00Z"},
+		{"0 0 1,15 * mon", "2026-01-15T00:00:00Z"},
+		{"0 9-17/4 * * mon-fri", "2026-01-14T13:00:00Z"},
+		{"0 0 29 feb *", "2028-02-29T00:00:00Z"},
+		{"@hourly", "2026-01-14T11:00:00Z"},
+		{"@daily", "2026-01-15T00:00:00Z"},
+		{"@weekly", "2026-01-18T00:00:00Z"},
+		{"@monthly", "2026-02-01T00:00:00Z"},
+		{"@yearly", "2027-01-01T00:00:00Z"},
+		{"@every 90s", "2026-01-14T10:09:00Z"},
+	} {
+		sched, err := Parse(tc.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.expr, err)
+			continue
+		}
+		if got := sched.Next(from).Format(time.RFC3339); got != tc.want {
+			t.Errorf("Parse(%q).Next = %s, want %s", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"0 0 30 feb *",
+		"@every 100ms",
+		"@every soon",
+		"@fortnightly",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) accepted", expr)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+// Package scheduler runs the gateway's periodic background jobs on cron
+// schedules.
+//
+// Each job is a function registered under a name with a schedule. The
+// scheduler starts a job when it falls due, never running two passes of the
+// same job at once: an activation that arrives while the previous pass is
+// still going is skipped. Jobs can be paused, resumed and triggered by
+// hand, and the outcome of every pass is kept for the status API and
+// reported to a Recorder.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Errors returned by the Scheduler.
+var (
+	ErrUnknownJob = errors.New("scheduler: no such job")
+	ErrRunning    = errors.New("scheduler: job is already running")
+	ErrStopped    = errors.New("scheduler: stopped")
+)
+
+// Recorder receives the outcome of every pass.
+type Recorder interface {
+	RecordScheduledJobRun(job string, started time.Time, duration time.Duration, err error)
+}
+
+// Status is a snapshot of one job.
+type Status struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	Paused   bool   `json:"paused"`
+	Running  bool   `json:"running"`
+	// Next is when the job is next due; unset while paused.
+	Next         *time.Time `json:"next,omitempty"`
+	LastRun      *time.Time `json:"last_run,omitempty"`
+	LastDuration string     `json:"last_duration,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	Runs         int        `json:"runs"`
+	Failures     int        `json:"failures"`
+}
+
+type job struct {
+	Status
+	sched Schedule
+	run   func(context.Context) error
+	next  time.Time
+}
+
+// Scheduler runs registered jobs. Every method but Add is safe on a nil
+// *Scheduler, which has no jobs.
+type Scheduler struct {
+	recorder Recorder
+	logger   *logrus.Logger
+	now      func() time.Time
+
+	mu      sync.Mutex
+	jobs    map[string]*job
+	started bool
+	stopped bool
+	wake    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// New returns a scheduler with no jobs. recorder and logger may be nil.
+func New(recorder Recorder, logger *logrus.Logger) *Scheduler {
+	if logger == nil {
+		logger = logrus.New()
+		logger.SetOutput(io.Discard)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		recorder: recorder,
+		logger:   logger,
+		now:      time.Now,
+		jobs:     make(map[string]*job),
+		wake:     make(chan struct{}, 1),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Add registers run under name on the schedule expr (see Parse).
+func (s *Scheduler) Add(name, expr string, run func(context.Context) error) error {
+	sched, err := Parse(expr)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("scheduler: job %q registered twice", name)
+	}
+	j := &job{Status: Status{Name: name, Schedule: expr}, sched: sched, run: run}
+	j.next = sched.Next(s.now())
+	s.jobs[name] = j
+	s.poke()
+	return nil
+}
+
+// Start runs jobs as they fall due until Stop is called.
+func (s *Scheduler) Start() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started || s.stopped {
+		return
+	}
+	s.started = true
+	s.wg.Add(1)
+	go s.loop()
+}
+
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		s.mu.Lock()
+		now := s.now()
+		var wait time.Duration = -1
+		for _, j := range s.jobs {
+			if j.Paused || j.next.IsZero() {
+				continue
+			}
+			if !j.next.After(now) {
+				if j.Running {
+					s.logger.WithField("job", j.Name).Warn("Scheduled job still running; skipping this activation")
+				} else {
+					s.launch(j)
+				}
+				j.next = j.sched.Next(now)
+				if j.next.IsZero() {
+					continue
+				}
+			}
+			if d := j.next.Sub(now); wait < 0 || d < wait {
+				wait = d
+			}
+		}
+		s.mu.Unlock()
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		if wait < 0 {
+			wait = time.Hour
+		}
+		timer.Reset(wait)
+		select {
+		case <-timer.C:
+		case <-s.wake:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// launch starts a pass of j. s.mu is held.
+func (s *Scheduler) launch(j *job) {
+	j.Running = true
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		started := s.now()
+		err := j.run(s.ctx)
+		duration := s.now().Sub(started)
+
+		s.mu.Lock()
+		j.Running = false
+		j.Runs++
+		j.LastRun = &started
+		j.LastDuration = duration.String()
+		j.LastError = ""
+		if err != nil {
+			j.Failures++
+			j.LastError = err.Error()
+		}
+		s.mu.Unlock()
+
+		if s.recorder != nil {
+			s.recorder.RecordScheduledJobRun(j.Name, started, duration, err)
+		}
+		entry := s.logger.WithFields(logrus.Fields{
+			"job":      j.Name,
+			"duration": duration,
+		})
+		if err != nil && s.ctx.Err() == nil {
+			entry.WithError(err).Warn("Scheduled job failed")
+		} else {
+			entry.Debug("Scheduled job finished")
+		}
+	}()
+}
+
+// poke wakes the loop to recompute the next activation.
+func (s *Scheduler) poke() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Trigger starts a pass of the job now, whether or not it is paused. Its
+// schedule is unchanged.
+func (s *Scheduler) Trigger(name string) (Status, error) {
+	if s == nil {
+		return Status{}, ErrUnknownJob
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	switch {
+	case !ok:
+		return Status{}, ErrUnknownJob
+	case s.stopped:
+		return j.snapshot(), ErrStopped
+	case j.Running:
+		return j.snapshot(), ErrRunning
+	}
+	s.launch(j)
+	return j.snapshot(), nil
+}
+
+// Pause stops scheduled passes of the job; a pass in progress finishes.
+func (s *Scheduler) Pause(name string) (Status, error) {
+	return s.setPaused(name, true)
+}
+
+// Resume schedules the job again from now.
+func (s *Scheduler) Resume(name string) (Status, error) {
+	return s.setPaused(name, false)
+}
+
+func (s *Scheduler) setPaused(name string, paused bool) (Status, error) {
+	if s == nil {
+		return Status{}, ErrUnknownJob
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return Status{}, ErrUnknownJob
+	}
+	if j.Paused != paused {
+		j.Paused = paused
+		if !paused {
+			j.next = j.sched.Next(s.now())
+		}
+		s.poke()
+	}
+	return j.snapshot(), nil
+}
+
+// Get returns the status of the job.
+func (s *Scheduler) Get(name string) (Status, bool) {
+	if s == nil {
+		return Status{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return Status{}, false
+	}
+	return j.snapshot(), true
+}
+
+// List returns the status of every job, by name.
+func (s *Scheduler) List() []Status {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Status, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		out = append(out, j.snapshot())
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Name < out[b].Name })
+	return out
+}
+
+// snapshot copies the job's status. s.mu is held.
+func (j *job) snapshot() Status {
+	st := j.Status
+	if !j.Paused && !j.next.IsZero() {
+		next := j.next
+		st.Next = &next
+	}
+	return st
+}
+
+// Stop ends scheduling, cancels passes in progress and waits for them to
+// return or ctx to expire.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	s.cancel()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,162 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeRecorder struct {
+	mu   sync.Mutex
+	runs map[string]int
+	errs map[string]int
+}
+
+func (r *fakeRecorder) RecordScheduledJobRun(job string, started time.Time, duration time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs[job]++
+	if err != nil {
+		r.errs[job]++
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestScheduler_RunsDueJobs(t *testing.T) {
+	rec := &fakeRecorder{runs: map[string]int{}, errs: map[string]int{}}
+	s := New(rec, nil)
+	defer s.Stop(context.Background())
+
+	if err := s.Add("ok", "@every 1s", func(context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("broken", "@every 1s", func(context.Context) error { return errors.New("backend down") }); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("ok", "@hourly", nil); err == nil {
+		t.Error("duplicate job accepted")
+	}
+	if err := s.Add("bad", "every minute", nil); err == nil {
+		t.Error("invalid schedule accepted")
+	}
+	s.Start()
+
+	waitFor(t, "two passes of each job", func() bool {
+		ok, _ := s.Get("ok")
+		broken, _ := s.Get("broken")
+		return ok.Runs >= 2 && broken.Runs >= 2
+	})
+	broken, _ := s.Get("broken")
+	if broken.Failures != broken.Runs || broken.LastError != "backend down" || broken.LastRun == nil || broken.Next == nil {
+		t.Errorf("broken = %+v", broken)
+	}
+	rec.mu.Lock()
+	if rec.runs["ok"] < 2 || rec.errs["ok"] != 0 || rec.errs["broken"] < 2 {
+		t.Errorf("recorded runs %v, failures %v", rec.runs, rec.errs)
+	}
+	rec.mu.Unlock()
+	if list := s.List(); len(list) != 2 || list[0].Name != "broken" {
+		t.Errorf("List = %+v", list)
+	}
+}
+
+func TestScheduler_TriggerPauseResume(t *testing.T) {
+	s := New(nil, nil)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	runs := 0
+	if err := s.Add("gc", "@yearly", func(ctx context.Context) error {
+		mu.Lock()
+		runs++
+		mu.Unlock()
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+
+	st, err := s.Pause("gc")
+	if err != nil || !st.Paused || st.Next != nil {
+		t.Fatalf("Pause = %+v, %v", st, err)
+	}
+	// A paused job can still be triggered by hand.
+	if st, err := s.Trigger("gc"); err != nil || !st.Running {
+		t.Fatalf("Trigger = %+v, %v", st, err)
+	}
+	if _, err := s.Trigger("gc"); !errors.Is(err, ErrRunning) {
+		t.Errorf("second Trigger = %v, want ErrRunning", err)
+	}
+	close(release)
+	waitFor(t, "the triggered pass", func() bool {
+		st, _ := s.Get("gc")
+		return st.Runs == 1 && !st.Running
+	})
+	if st, err := s.Resume("gc"); err != nil || st.Paused || st.Next == nil {
+		t.Errorf("Resume = %+v, %v", st, err)
+	}
+	for _, err := range []error{
+		func() error { _, err := s.Trigger("nope"); return err }(),
+		func() error { _, err := s.Pause("nope"); return err }(),
+	} {
+		if !errors.Is(err, ErrUnknownJob) {
+			t.Errorf("unknown job: %v", err)
+		}
+	}
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Trigger("gc"); !errors.Is(err, ErrStopped) {
+		t.Errorf("Trigger after Stop = %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if runs != 1 {
+		t.Errorf("job ran %d times", runs)
+	}
+}
+
+func TestScheduler_StopCancelsPasses(t *testing.T) {
+	s := New(nil, nil)
+	started := make(chan struct{})
+	if err := s.Add("slow", "@yearly", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	if _, err := s.Trigger("slow"); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatalf("Stop = %v", err)
+	}
+
+	var none *Scheduler
+	none.Start()
+	if none.List() != nil || none.Stop(context.Background()) != nil {
+		t.Error("nil scheduler is not empty")
+	}
+}
@@ -0,0 +1,82 @@
+package selftest
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// Recorder is the subset of metrics.Metrics used by the canary probe.
+type Recorder interface {
+	RecordCanaryFailure(step string)
+	SetCanaryLastSuccess(t time.Time)
+}
+
+// Probe repeats the self-test round trip on a schedule so credentials that
+// expire or a KMS key that is disabled after startup show up in monitoring
+// rather than in failed client requests. It never changes readiness.
+type Probe struct {
+	checker  *Checker
+	interval time.Duration
+	recorder Recorder
+	logger   *logrus.Logger
+}
+
+// NewProbe returns a Probe. recorder may be nil.
+func NewProbe(cfg config.CanaryConfig, engine crypto.EncryptionEngine, keyManager crypto.KeyManager, client s3.Client, recorder Recorder, logger *logrus.Logger) *Probe {
+	checker := New(config.SelfTestConfig{
+		Bucket:    cfg.Bucket,
+		KeyPrefix: cfg.KeyPrefix,
+		Timeout:   cfg.Timeout,
+	}, engine, keyManager, client, logger)
+	return &Probe{
+		checker:  checker,
+		interval: cfg.Interval,
+		recorder: recorder,
+		logger:   checker.logger,
+	}
+}
+
+// Probe runs the round trip once and records the outcome.
+func (p *Probe) Probe(ctx context.Context) error {
+	err := p.checker.Run(ctx)
+	if err == nil {
+		if p.recorder != nil {
+			p.recorder.SetCanaryLastSuccess(time.Now())
+		}
+		p.logger.Debug("Canary probe passed")
+		return nil
+	}
+	step := StepBackend
+	var se *StepError
+	if errors.As(err, &se) {
+		step = se.Step
+	}
+	if p.recorder != nil {
+		p.recorder.RecordCanaryFailure(step)
+	}
+	p.logger.WithError(err).WithField("step", step).Error("Canary probe failed")
+	return err
+}
+
+// Start probes immediately and then every interval until ctx is done.
+func (p *Probe) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			p.Probe(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,89 @@
+package selftest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+type fakeRecorder struct {
+	mu          sync.Mutex
+	failures    map[string]int
+	lastSuccess time.Time
+}
+
+func (r *fakeRecorder) RecordCanaryFailure(step string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures == nil {
+		r.failures = map[string]int{}
+	}
+	r.failures[step]++
+}
+
+func (r *fakeRecorder) SetCanaryLastSuccess(t time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastSuccess = t
+}
+
+func canaryConfig() config.CanaryConfig {
+	return config.CanaryConfig{
+		Enabled:   true,
+		Interval:  10 * time.Millisecond,
+		Bucket:    "canary",
+		KeyPrefix: config.DefaultCanaryKeyPrefix,
+		Timeout:   5 * time.Second,
+	}
+}
+
+func TestProbe_RecordsOutcome(t *testing.T) {
+	client := testsupport.NewMemoryClient()
+	expired := errors.New("ExpiredToken")
+	var fail bool
+	client.OnCall = func(op, bucket, key string) error {
+		if fail && op == "PutObject" {
+			return expired
+		}
+		return nil
+	}
+	rec := &fakeRecorder{}
+	p := NewProbe(canaryConfig(), testEngine(t), testKeyManager(t), client, rec, nil)
+
+	if err := p.Probe(context.Background()); err != nil {
+		t.Fatalf("Probe: %v", err)
+	}
+	if rec.lastSuccess.IsZero() || len(rec.failures) != 0 {
+		t.Errorf("after success: last %v, failures %v", rec.lastSuccess, rec.failures)
+	}
+	passed := rec.lastSuccess
+
+	fail = true
+	if err := p.Probe(context.Background()); !errors.Is(err, expired) {
+		t.Fatalf("Probe = %v, want backend error", err)
+	}
+	if rec.failures[StepBackend] != 1 || !rec.lastSuccess.Equal(passed) {
+		t.Errorf("after failure: last %v, failures %v", rec.lastSuccess, rec.failures)
+	}
+}
+
+func TestProbe_StartRepeats(t *testing.T) {
+	client := testsupport.NewMemoryClient()
+	p := NewProbe(canaryConfig(), testEngine(t), nil, client, nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx)
+
+	deadline := time.Now().Add(30 * time.Second)
+	for client.Calls("DeleteObject") < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("probe ran %d times", client.Calls("DeleteObject"))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,247 @@
+// Package selftest checks at startup that the gateway can actually do its
+// job before it reports ready: the encryption engine must decrypt what it
+// encrypts, the key manager must unwrap what it wraps, and the backend must
+// accept, return and delete an object. A wrong password file, a KMS key the
+// gateway may not use or backend credentials without write access then show
+// up as a failed readiness check instead of as errors on real traffic.
+package selftest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// canarySize spans more than one encryption chunk so chunk boundaries are
+// exercised as well.
+const canarySize = 2*crypto.DefaultChunkSize + 17
+
+// ErrPending is reported by Ready until the first run has finished.
+var ErrPending = errors.New("self-test has not completed")
+
+// Steps of a run, used as the StepError.Step value.
+const (
+	StepEncryption = "encryption"
+	StepKeyManager = "key_manager"
+	StepBackend    = "backend"
+)
+
+// StepError is returned by a failed run and names the step that failed.
+type StepError struct {
+	Step string
+	Err  error
+}
+
+func (e *StepError) Error() string { return e.Err.Error() }
+
+func (e *StepError) Unwrap() error { return e.Err }
+
+// Checker runs the self-test and remembers the outcome of the latest run.
+type Checker struct {
+	cfg        config.SelfTestConfig
+	engine     crypto.EncryptionEngine
+	keyManager crypto.KeyManager
+	client     s3.Client
+	logger     *logrus.Logger
+
+	mu  sync.Mutex
+	err error
+}
+
+// New returns a Checker. keyManager and client may be nil, in which case the
+// key manager and backend steps are skipped, as is the backend step when
+// cfg.Bucket is empty.
+func New(cfg config.SelfTestConfig, engine crypto.EncryptionEngine, keyManager crypto.KeyManager, client s3.Client, logger *logrus.Logger) *Checker {
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	return &Checker{
+		cfg:        cfg,
+		engine:     engine,
+		keyManager: keyManager,
+		client:     client,
+		logger:     logger,
+		err:        ErrPending,
+	}
+}
+
+// Ready returns nil once a run has passed and the error of the latest run
+// otherwise. Its signature matches metrics.ReadyCheck.Check.
+func (c *Checker) Ready(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Run performs every step once and records the result.
+func (c *Checker) Run(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	err := c.run(ctx)
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+	return err
+}
+
+// Start runs the self-test in the background until it passes. Failed runs
+// are retried every RetryInterval, or not at all when it is zero, so a
+// backend that is still coming up does not need a gateway restart.
+func (c *Checker) Start(ctx context.Context) {
+	go func() {
+		for {
+			err := c.Run(ctx)
+			if err == nil {
+				c.logger.Info("Startup self-test passed")
+				return
+			}
+			entry := c.logger.WithError(err)
+			if c.cfg.RetryInterval <= 0 {
+				entry.Error("Startup self-test failed; gateway will not become ready")
+				return
+			}
+			entry.WithField("retry_in", c.cfg.RetryInterval).Error("Startup self-test failed; gateway is not ready")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.cfg.RetryInterval):
+			}
+		}
+	}()
+}
+
+func (c *Checker) run(ctx context.Context) error {
+	canary := make([]byte, canarySize)
+	if _, err := rand.Read(canary); err != nil {
+		return &StepError{StepEncryption, fmt.Errorf("generate canary: %w", err)}
+	}
+
+	ciphertext, meta, err := c.encrypt(ctx, canary)
+	if err == nil {
+		err = c.decrypt(ctx, ciphertext, meta, canary)
+	}
+	if err != nil {
+		return &StepError{StepEncryption, fmt.Errorf("encryption: %w", err)}
+	}
+
+	if c.keyManager != nil {
+		if err := c.checkKeyManager(ctx); err != nil {
+			return &StepError{StepKeyManager, fmt.Errorf("key manager: %w", err)}
+		}
+	}
+
+	if c.client != nil && c.cfg.Bucket != "" {
+		if err := c.checkBackend(ctx, ciphertext, meta, canary); err != nil {
+			return &StepError{StepBackend, fmt.Errorf("backend bucket %q: %w", c.cfg.Bucket, err)}
+		}
+	}
+	return nil
+}
+
+func (c *Checker) encrypt(ctx context.Context, plaintext []byte) ([]byte, map[string]string, error) {
+	r, meta, err := c.engine.Encrypt(ctx, bytes.NewReader(plaintext), map[string]string{})
+	if err != nil {
+		return nil, nil, err
+	}
+	ciphertext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bytes.Equal(ciphertext, plaintext) {
+		return nil, nil, errors.New("ciphertext equals plaintext")
+	}
+	return ciphertext, meta, nil
+}
+
+func (c *Checker) decrypt(ctx context.Context, ciphertext []byte, meta map[string]string, want []byte) error {
+	r, _, err := c.engine.Decrypt(ctx, bytes.NewReader(ciphertext), meta)
+	if err != nil {
+		return fmt.Errorf("decrypt: %w", err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("decrypt: %w", err)
+	}
+	if !bytes.Equal(got, want) {
+		return errors.New("decrypted canary does not match the original")
+	}
+	return nil
+}
+
+func (c *Checker) checkKeyManager(ctx context.Context) error {
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return err
+	}
+	defer clear(dek)
+
+	env, err := c.keyManager.WrapKey(ctx, dek, map[string]string{"purpose": "self-test"})
+	if err != nil {
+		return fmt.Errorf("wrap: %w", err)
+	}
+	if bytes.Equal(env.Ciphertext, dek) {
+		return errors.New("wrapped key equals the plaintext key")
+	}
+	got, err := c.keyManager.UnwrapKey(ctx, env, map[string]string{"purpose": "self-test"})
+	if err != nil {
+		return fmt.Errorf("unwrap: %w", err)
+	}
+	defer clear(got)
+	if !bytes.Equal(got, dek) {
+		return errors.New("unwrapped key does not match the original")
+	}
+	return nil
+}
+
+func (c *Checker) checkBackend(ctx context.Context, ciphertext []byte, meta map[string]string, want []byte) error {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	key := c.cfg.KeyPrefix + hex.EncodeToString(suffix)
+
+	size := int64(len(ciphertext))
+	if err := c.client.PutObject(ctx, c.cfg.Bucket, key, bytes.NewReader(ciphertext), meta, &size, "", nil); err != nil {
+		return fmt.Errorf("put %s: %w", key, err)
+	}
+	// Remove the canary even when reading it back fails; the delete result
+	// is only reported when everything before it passed.
+	readErr := c.readBack(ctx, key, want)
+	delErr := c.client.DeleteObject(ctx, c.cfg.Bucket, key, nil)
+	if readErr != nil {
+		return readErr
+	}
+	if delErr != nil {
+		return fmt.Errorf("delete %s: %w", key, delErr)
+	}
+	return nil
+}
+
+func (c *Checker) readBack(ctx context.Context, key string, want []byte) error {
+	r, meta, err := c.client.GetObject(ctx, c.cfg.Bucket, key, nil, nil)
+	if err != nil {
+		return fmt.Errorf("get %s: %w", key, err)
+	}
+	defer r.Close()
+	stored, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("get %s: %w", key, err)
+	}
+	if err := c.decrypt(ctx, stored, meta, want); err != nil {
+		return fmt.Errorf("get %s: %w", key, err)
+	}
+	return nil
+}
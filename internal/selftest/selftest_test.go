@@ -0,0 +1,153 @@
+package selftest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func testConfig() config.SelfTestConfig {
+	return config.SelfTestConfig{
+		Enabled:   true,
+		Bucket:    "canary",
+		KeyPrefix: config.DefaultSelfTestKeyPrefix,
+		Timeout:   5 * time.Second,
+	}
+}
+
+func testEngine(t *testing.T) crypto.EncryptionEngine {
+	t.Helper()
+	engine, err := crypto.NewEngineWithOpts([]byte("self-test-password-123456"), nil, crypto.WithPBKDF2Iterations(crypto.MinPBKDF2Iterations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func testKeyManager(t *testing.T) crypto.KeyManager {
+	t.Helper()
+	km, err := crypto.NewInMemoryKeyManager(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return km
+}
+
+func TestChecker_Passes(t *testing.T) {
+	client := testsupport.NewMemoryClient()
+	c := New(testConfig(), testEngine(t), testKeyManager(t), client, nil)
+	if err := c.Ready(context.Background()); !errors.Is(err, ErrPending) {
+		t.Errorf("Ready before run = %v, want ErrPending", err)
+	}
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if err := c.Ready(context.Background()); err != nil {
+		t.Errorf("Ready after passing run = %v", err)
+	}
+	for _, op := range []string{"PutObject", "GetObject", "DeleteObject"} {
+		if client.Calls(op) != 1 {
+			t.Errorf("%s called %d times", op, client.Calls(op))
+		}
+	}
+	if res, _ := client.ListObjects(context.Background(), "canary", "", s3.ListOptions{}); len(res.Objects) != 0 {
+		t.Errorf("canary object left behind: %+v", res.Objects)
+	}
+}
+
+func TestChecker_BackendFailure(t *testing.T) {
+	client := testsupport.NewMemoryClient()
+	denied := errors.New("AccessDenied")
+	client.OnCall = func(op, bucket, key string) error {
+		if op == "GetObject" {
+			return denied
+		}
+		return nil
+	}
+	c := New(testConfig(), testEngine(t), nil, client, nil)
+	err := c.Run(context.Background())
+	if !errors.Is(err, denied) || !strings.Contains(err.Error(), `backend bucket "canary"`) {
+		t.Fatalf("Run = %v, want wrapped backend error", err)
+	}
+	if !errors.Is(c.Ready(context.Background()), denied) {
+		t.Error("Ready should report the failed run")
+	}
+	if client.Calls("DeleteObject") != 1 {
+		t.Error("canary should be deleted even when the read fails")
+	}
+}
+
+func TestChecker_SkipsBackendWithoutBucket(t *testing.T) {
+	client := testsupport.NewMemoryClient()
+	cfg := testConfig()
+	cfg.Bucket = ""
+	if err := New(cfg, testEngine(t), nil, client, nil).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if client.Calls("PutObject") != 0 {
+		t.Error("backend should not be touched without a bucket")
+	}
+}
+
+// wrongKeyManager wraps with one key and unwraps with another, like a KMS
+// key the gateway may encrypt with but not decrypt with.
+type wrongKeyManager struct {
+	crypto.KeyManager
+	other crypto.KeyManager
+}
+
+func (w wrongKeyManager) UnwrapKey(ctx context.Context, env *crypto.KeyEnvelope, meta map[string]string) ([]byte, error) {
+	return w.other.UnwrapKey(ctx, env, meta)
+}
+
+func TestChecker_KeyManagerFailure(t *testing.T) {
+	other, err := crypto.NewInMemoryKeyManager([]byte(strings.Repeat("k", 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	km := wrongKeyManager{KeyManager: testKeyManager(t), other: other}
+	err = New(testConfig(), testEngine(t), km, nil, nil).Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "key manager: unwrap") {
+		t.Fatalf("Run = %v, want unwrap failure", err)
+	}
+	var se *StepError
+	if !errors.As(err, &se) || se.Step != StepKeyManager {
+		t.Errorf("failed step = %v, want %s", se, StepKeyManager)
+	}
+}
+
+func TestChecker_StartRetries(t *testing.T) {
+	client := testsupport.NewMemoryClient()
+	var failures int
+	client.OnCall = func(op, bucket, key string) error {
+		if op == "PutObject" && failures < 2 {
+			failures++
+			return errors.New("bucket not yet created")
+		}
+		return nil
+	}
+	cfg := testConfig()
+	cfg.RetryInterval = 10 * time.Millisecond
+	c := New(cfg, testEngine(t), nil, client, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.Start(ctx)
+
+	deadline := time.Now().Add(30 * time.Second)
+	for c.Ready(ctx) != nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("self-test never passed: %v", c.Ready(ctx))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if client.Calls("PutObject") != 3 {
+		t.Errorf("PutObject called %d times, want 3", client.Calls("PutObject"))
+	}
+}
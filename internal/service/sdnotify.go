@@ -0,0 +1,63 @@
+//go:build !windows
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sdNotifier sends sd_notify(3) datagrams to systemd.
+type sdNotifier struct {
+	mu   sync.Mutex
+	conn *net.UnixConn
+}
+
+// newSDNotifier connects to $NOTIFY_SOCKET and reads the watchdog timeout
+// from $WATCHDOG_USEC, when it is meant for this process. It returns a nil
+// notifier when the gateway was not started with Type=notify. The
+// variables are cleared so processes the gateway starts do not inherit
+// them.
+func newSDNotifier() (*sdNotifier, time.Duration, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	usec := os.Getenv("WATCHDOG_USEC")
+	pid := os.Getenv("WATCHDOG_PID")
+	os.Unsetenv("NOTIFY_SOCKET")
+	os.Unsetenv("WATCHDOG_USEC")
+	os.Unsetenv("WATCHDOG_PID")
+	if socket == "" {
+		return nil, 0, nil
+	}
+
+	addr := &net.UnixAddr{Name: socket, Net: "unixgram"}
+	if socket[0] == '@' {
+		// Linux abstract namespace socket.
+		addr.Name = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		return nil, 0, fmt.Errorf("connect to NOTIFY_SOCKET %q: %w", socket, err)
+	}
+
+	var watchdog time.Duration
+	if usec != "" && (pid == "" || pid == strconv.Itoa(os.Getpid())) {
+		n, err := strconv.ParseInt(usec, 10, 64)
+		if err != nil || n <= 0 {
+			conn.Close()
+			return nil, 0, fmt.Errorf("invalid WATCHDOG_USEC %q", usec)
+		}
+		watchdog = time.Duration(n) * time.Microsecond
+	}
+	return &sdNotifier{conn: conn}, watchdog, nil
+}
+
+func (n *sdNotifier) send(state string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, err := n.conn.Write([]byte(state))
+	return err
+}
@@ -0,0 +1,105 @@
+//go:build !windows
+
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func listenNotify(t *testing.T) *net.UnixConn {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	return conn
+}
+
+// readUntil collects datagrams until one contains want.
+func readUntil(t *testing.T, conn *net.UnixConn, want string) []string {
+	t.Helper()
+	var got []string
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("waiting for %q, got %q: %v", want, got, err)
+		}
+		got = append(got, string(buf[:n]))
+		if strings.Contains(string(buf[:n]), want) {
+			return got
+		}
+	}
+}
+
+func TestManager_SDNotify(t *testing.T) {
+	conn := listenNotify(t)
+	t.Setenv("WATCHDOG_USEC", "20000")
+	t.Setenv("WATCHDOG_PID", "")
+
+	m, err := New("gateway", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Stopped()
+	if !m.Supervised() || m.watchdog != 20*time.Millisecond {
+		t.Fatalf("supervised %v, watchdog %v", m.Supervised(), m.watchdog)
+	}
+	if os.Getenv("NOTIFY_SOCKET") != "" || os.Getenv("WATCHDOG_USEC") != "" {
+		t.Error("notify variables left for child processes")
+	}
+
+	calls := 0
+	m.WaitReady(context.Background(), func(context.Context) error {
+		if calls++; calls == 1 {
+			return errors.New("kms: unreachable")
+		}
+		return nil
+	}, time.Millisecond)
+	got := readUntil(t, conn, "READY=1")
+	if !strings.Contains(strings.Join(got, "\n"), "STATUS=Waiting for readiness: kms: unreachable") {
+		t.Errorf("no waiting status before READY=1: %q", got)
+	}
+	readUntil(t, conn, "WATCHDOG=1")
+
+	m.Stopping()
+	readUntil(t, conn, "STOPPING=1")
+}
+
+func TestManager_Unsupervised(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	m, err := New("gateway", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Supervised() {
+		t.Error("supervised without a notify socket")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.WaitReady(ctx, func(context.Context) error { return errors.New("down") }, time.Hour)
+	m.Ready()
+	m.Stopping()
+	m.Stopped()
+	select {
+	case <-m.StopRequested():
+		t.Error("stop requested without a service manager")
+	default:
+	}
+
+	t.Setenv("NOTIFY_SOCKET", filepath.Join(t.TempDir(), "missing.sock"))
+	if _, err := New("gateway", nil); err == nil {
+		t.Error("unreachable notify socket accepted")
+	}
+}
@@ -0,0 +1,14 @@
+package service
+
+import "time"
+
+// sdNotifier is never created on Windows, which has no systemd.
+type sdNotifier struct{}
+
+func newSDNotifier() (*sdNotifier, time.Duration, error) {
+	return nil, 0, nil
+}
+
+func (n *sdNotifier) send(string) error {
+	return nil
+}
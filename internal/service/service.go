@@ -0,0 +1,155 @@
+// Package service reports the gateway's lifecycle to the init system that
+// started it, so the supervisor sees when the gateway can actually serve
+// rather than only that the process exists.
+//
+// Under systemd with Type=notify it sends READY=1 once the gateway is ready,
+// WATCHDOG=1 pings when WatchdogSec= is set, and STOPPING=1 on shutdown
+// (see sd_notify(3)). Run as a Windows service it reports StartPending until
+// ready, then Running, and turns the service control manager's Stop and
+// Shutdown requests into a graceful shutdown. Elsewhere every method is a
+// no-op.
+package service
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Manager reports lifecycle transitions to the supervisor.
+type Manager struct {
+	logger   *logrus.Logger
+	sd       *sdNotifier // nil when not started by systemd with Type=notify
+	win      *winService // nil unless running as a Windows service
+	watchdog time.Duration
+
+	stop      chan struct{}
+	stopOnce  sync.Once
+	readyOnce sync.Once
+	done      chan struct{}
+	doneOnce  sync.Once
+}
+
+// New detects how the gateway was started and, when run as a Windows
+// service, connects to the service control manager under name. logger may
+// be nil.
+func New(name string, logger *logrus.Logger) (*Manager, error) {
+	if logger == nil {
+		logger = logrus.New()
+		logger.SetOutput(io.Discard)
+	}
+	m := &Manager{
+		logger: logger,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	sd, watchdog, err := newSDNotifier()
+	if err != nil {
+		return nil, err
+	}
+	m.sd, m.watchdog = sd, watchdog
+	if err := m.startWindowsService(name); err != nil {
+		return nil, err
+	}
+	if m.watchdog > 0 {
+		go m.pingWatchdog()
+	}
+	return m, nil
+}
+
+// Supervised reports whether the gateway runs under a supervisor that
+// receives notifications.
+func (m *Manager) Supervised() bool {
+	return m.sd != nil || m.win != nil
+}
+
+// StopRequested is closed when the supervisor asks the gateway to stop.
+// Under systemd this arrives as SIGTERM instead.
+func (m *Manager) StopRequested() <-chan struct{} {
+	return m.stop
+}
+
+func (m *Manager) requestStop() {
+	m.stopOnce.Do(func() { close(m.stop) })
+}
+
+// WaitReady calls check every interval until it succeeds, then reports the
+// gateway ready. It returns early, without reporting, when ctx ends.
+func (m *Manager) WaitReady(ctx context.Context, check func(context.Context) error, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := check(ctx)
+		if err == nil {
+			m.Ready()
+			return
+		}
+		m.status("Waiting for readiness: " + err.Error())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Ready reports that the gateway is serving. Only the first call counts.
+func (m *Manager) Ready() {
+	m.readyOnce.Do(func() {
+		m.notify("READY=1\nSTATUS=Serving")
+		if m.win != nil {
+			m.win.running()
+		}
+		if m.Supervised() {
+			m.logger.Info("Reported readiness to the service manager")
+		}
+	})
+}
+
+// Stopping reports that shutdown has begun.
+func (m *Manager) Stopping() {
+	m.notify("STOPPING=1\nSTATUS=Shutting down")
+	if m.win != nil {
+		m.win.stopping()
+	}
+}
+
+// Stopped ends watchdog pings and, for a Windows service, reports the
+// service stopped. Call it last, once shutdown is complete.
+func (m *Manager) Stopped() {
+	m.doneOnce.Do(func() { close(m.done) })
+	if m.win != nil {
+		m.win.stopped()
+	}
+}
+
+func (m *Manager) status(s string) {
+	m.notify("STATUS=" + s)
+}
+
+func (m *Manager) notify(state string) {
+	if m.sd == nil {
+		return
+	}
+	if err := m.sd.send(state); err != nil {
+		m.logger.WithError(err).Debug("sd_notify failed")
+	}
+}
+
+// pingWatchdog sends WATCHDOG=1 at half the configured timeout, as
+// sd_watchdog_enabled(3) recommends, until Stopped.
+func (m *Manager) pingWatchdog() {
+	ticker := time.NewTicker(m.watchdog / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.notify("WATCHDOG=1")
+		}
+	}
+}
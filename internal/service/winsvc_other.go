@@ -0,0 +1,14 @@
+//go:build !windows
+
+package service
+
+// winService is only created on Windows.
+type winService struct{}
+
+func (m *Manager) startWindowsService(string) error {
+	return nil
+}
+
+func (s *winService) running()  {}
+func (s *winService) stopping() {}
+func (s *winService) stopped()  {}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// startWaitHint tells the service control manager how long to allow
+// between StartPending reports before it considers the start hung.
+const startWaitHint = 30 * time.Second
+
+// winService is the svc.Handler run while the gateway is a Windows
+// service. State changes from the gateway arrive on events and are
+// reported from Execute, which owns the changes channel.
+type winService struct {
+	m        *Manager
+	events   chan svc.State
+	exit     chan struct{}
+	finished chan struct{}
+}
+
+func (m *Manager) startWindowsService(name string) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return err
+	}
+	s := &winService{
+		m:        m,
+		events:   make(chan svc.State, 2),
+		exit:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	m.win = s
+	go func() {
+		defer close(s.finished)
+		if err := svc.Run(name, s); err != nil {
+			m.logger.WithError(err).Error("Windows service dispatcher failed")
+			m.requestStop()
+		}
+	}()
+	return nil
+}
+
+// Execute implements svc.Handler.
+func (s *winService) Execute(_ []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending, WaitHint: uint32(startWaitHint / time.Millisecond)}
+	for {
+		select {
+		case state := <-s.events:
+			st := svc.Status{State: state}
+			if state == svc.Running {
+				st.Accepts = svc.AcceptStop | svc.AcceptShutdown
+			}
+			changes <- st
+		case c := <-r:
+			switch c.Cmd {
+			case svc.Interrogate:
+				changes <- c.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				s.m.requestStop()
+			}
+		case <-s.exit:
+			return false, 0
+		}
+	}
+}
+
+func (s *winService) running() {
+	s.report(svc.Running)
+}
+
+func (s *winService) stopping() {
+	s.report(svc.StopPending)
+}
+
+func (s *winService) report(state svc.State) {
+	select {
+	case s.events <- state:
+	case <-s.finished:
+	}
+}
+
+// stopped returns from Execute, which reports the service stopped, and
+// waits briefly for the dispatcher to finish.
+func (s *winService) stopped() {
+	select {
+	case <-s.exit:
+	default:
+		close(s.exit)
+	}
+	select {
+	case <-s.finished:
+	case <-time.After(5 * time.Second):
+	}
+}
@@ -0,0 +1,418 @@
+// Package sizeindex maintains a sidecar index of plaintext object sizes.
+//
+// Backends report ciphertext sizes and ETags for encrypted objects, so
+// reporting plaintext values in a listing normally costs one HEAD per key.
+// The index stores those values next to the data instead: one JSON object
+// per key prefix ("directory"), kept under a reserved backend prefix, mapping
+// each key to its plaintext size, ETag and key version.
+//
+// Writes are recorded in memory and flushed asynchronously. A flush re-reads
+// the index object, merges the pending changes and writes it back
+// conditionally, so concurrent gateway instances do not drop each other's
+// updates on backends that honour If-Match. The index is advisory: readers
+// validate each entry against the listing (backend ETag, or Last-Modified
+// for entries recorded on the write path) and fall back to HEAD otherwise.
+package sizeindex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/sirupsen/logrus"
+)
+
+// indexObjectName is the object name of each shard under its prefix.
+const indexObjectName = "index.json"
+
+// shardRefresh is how long a loaded shard is trusted before it is re-read,
+// which bounds how stale another instance's updates can appear.
+const shardRefresh = 30 * time.Second
+
+// maxShards caps the number of shards held in memory.
+const maxShards = 256
+
+// writeSkew tolerates clock drift between the gateway and the backend when
+// an entry is validated by Last-Modified.
+const writeSkew = 2 * time.Second
+
+// Entry is the indexed state of one object.
+type Entry struct {
+	Size       int64  `json:"size"`
+	ETag       string `json:"etag,omitempty"`
+	KeyVersion int    `json:"key_version,omitempty"`
+	// BackendETag is the ciphertext ETag the entry was observed with. Entries
+	// recorded on the write path do not know it and carry Written instead.
+	BackendETag string    `json:"backend_etag,omitempty"`
+	Written     time.Time `json:"written"`
+}
+
+// EntryFromMetadata builds an entry from the encryption metadata of an
+// object. ok is false when the metadata does not carry a plaintext size.
+func EntryFromMetadata(meta map[string]string) (e Entry, ok bool) {
+	size, err := strconv.ParseInt(meta[crypto.MetaOriginalSize], 10, 64)
+	if err != nil || size < 0 {
+		return Entry{}, false
+	}
+	e = Entry{Size: size, ETag: meta[crypto.MetaOriginalETag], Written: time.Now().UTC()}
+	if v, err := strconv.Atoi(meta[crypto.MetaKeyVersion]); err == nil {
+		e.KeyVersion = v
+	}
+	return e, true
+}
+
+// Matches reports whether the entry describes the object currently listed
+// with backendETag and lastModified.
+func (e Entry) Matches(backendETag, lastModified string) bool {
+	if e.BackendETag != "" {
+		return e.BackendETag == backendETag
+	}
+	t, err := parseListTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !t.After(e.Written.Add(writeSkew))
+}
+
+func parseListTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02T15:04:05.000Z", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// Backend is the subset of s3.Client used to read and write index objects.
+type Backend interface {
+	GetObject(ctx context.Context, bucket, key string, versionID *string, rangeHeader *string) (io.ReadCloser, map[string]string, error)
+	PutObject(ctx context.Context, bucket, key string, reader io.Reader, metadata map[string]string, contentLength *int64, tags string, lock *s3.ObjectLockInput) error
+}
+
+type shardID struct {
+	bucket string
+	prefix string
+}
+
+type shard struct {
+	entries  map[string]Entry
+	loadedAt time.Time
+	// pending holds changes not yet flushed; a nil value is a removal.
+	pending map[string]*Entry
+}
+
+// Index is the in-memory view of the sidecar index. All methods are safe on
+// a nil *Index, which behaves as a disabled index.
+type Index struct {
+	backend  Backend
+	prefix   string
+	interval time.Duration
+	logger   *logrus.Logger
+	now      func() time.Time
+
+	mu     sync.Mutex
+	shards map[shardID]*shard
+
+	flushMu  sync.Mutex
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// New returns an index stored through backend, or nil when cfg disables it.
+func New(backend Backend, cfg config.SizeIndexConfig, logger *logrus.Logger) *Index {
+	if !cfg.Enabled || backend == nil {
+		return nil
+	}
+	return &Index{
+		backend:  backend,
+		prefix:   cfg.Prefix,
+		interval: cfg.FlushInterval,
+		logger:   logger,
+		now:      time.Now,
+		shards:   make(map[shardID]*shard),
+		stop:     make(chan struct{}),
+	}
+}
+
+// IsIndexKey reports whether key belongs to the index itself and must be
+// hidden from clients.
+func (ix *Index) IsIndexKey(key string) bool {
+	return ix != nil && strings.HasPrefix(key, ix.prefix)
+}
+
+// Prefix returns the reserved backend prefix, or "" for a nil index.
+func (ix *Index) Prefix() string {
+	if ix == nil {
+		return ""
+	}
+	return ix.prefix
+}
+
+func splitKey(bucket, key string) shardID {
+	return shardID{bucket: bucket, prefix: key[:strings.LastIndex(key, "/")+1]}
+}
+
+func (ix *Index) objectKey(id shardID) string {
+	return ix.prefix + id.prefix + indexObjectName
+}
+
+// Record queues e as the current state of bucket/key.
+func (ix *Index) Record(bucket, key string, e Entry) {
+	if ix == nil || ix.IsIndexKey(key) {
+		return
+	}
+	ix.update(bucket, key, &e)
+}
+
+// Remove queues the removal of bucket/key.
+func (ix *Index) Remove(bucket, key string) {
+	if ix == nil || ix.IsIndexKey(key) {
+		return
+	}
+	ix.update(bucket, key, nil)
+}
+
+func (ix *Index) update(bucket, key string, e *Entry) {
+	id := splitKey(bucket, key)
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	sh, ok := ix.shards[id]
+	if !ok {
+		sh = &shard{}
+		ix.shards[id] = sh
+	}
+	if sh.pending == nil {
+		sh.pending = make(map[string]*Entry)
+	}
+	sh.pending[key] = e
+}
+
+// Lookup returns the indexed entry for bucket/key, loading the shard from
+// the backend when it is not in memory or has gone stale.
+func (ix *Index) Lookup(ctx context.Context, bucket, key string) (Entry, bool) {
+	if ix == nil {
+		return Entry{}, false
+	}
+	id := splitKey(bucket, key)
+
+	ix.mu.Lock()
+	sh := ix.shards[id]
+	if sh != nil {
+		if e, ok := sh.pending[key]; ok {
+			ix.mu.Unlock()
+			if e == nil {
+				return Entry{}, false
+			}
+			return *e, true
+		}
+		if sh.entries != nil && ix.now().Sub(sh.loadedAt) < shardRefresh {
+			e, ok := sh.entries[key]
+			ix.mu.Unlock()
+			return e, ok
+		}
+	}
+	ix.mu.Unlock()
+
+	entries, _, err := ix.load(ctx, id)
+	if err != nil {
+		if ix.logger != nil {
+			ix.logger.WithError(err).WithFields(logrus.Fields{
+				"bucket": bucket,
+				"prefix": id.prefix,
+			}).Debug("Size index shard unavailable")
+		}
+		return Entry{}, false
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	sh = ix.shards[id]
+	if sh == nil {
+		ix.evictLocked()
+		sh = &shard{}
+		ix.shards[id] = sh
+	}
+	sh.entries = entries
+	sh.loadedAt = ix.now()
+	if e, ok := sh.pending[key]; ok {
+		if e == nil {
+			return Entry{}, false
+		}
+		return *e, true
+	}
+	e, ok := entries[key]
+	return e, ok
+}
+
+// evictLocked drops the least recently loaded shard without pending changes
+// once the shard limit is reached. Caller holds ix.mu.
+func (ix *Index) evictLocked() {
+	if len(ix.shards) < maxShards {
+		return
+	}
+	var (
+		oldest   shardID
+		oldestAt time.Time
+		found    bool
+	)
+	for id, sh := range ix.shards {
+		if len(sh.pending) > 0 {
+			continue
+		}
+		if !found || sh.loadedAt.Before(oldestAt) {
+			oldest, oldestAt, found = id, sh.loadedAt, true
+		}
+	}
+	if found {
+		delete(ix.shards, oldest)
+	}
+}
+
+// load reads a shard from the backend. A missing index object is an empty
+// shard; etag is "" in that case.
+func (ix *Index) load(ctx context.Context, id shardID) (entries map[string]Entry, etag string, err error) {
+	body, meta, err := ix.backend.GetObject(ctx, id.bucket, ix.objectKey(id), nil, nil)
+	if err != nil {
+		if errors.Is(err, s3.ErrNotFound) {
+			return map[string]Entry{}, "", nil
+		}
+		return nil, "", err
+	}
+	defer body.Close()
+
+	entries = map[string]Entry{}
+	if err := json.NewDecoder(body).Decode(&entries); err != nil {
+		return nil, "", fmt.Errorf("sizeindex: decode %s: %w", ix.objectKey(id), err)
+	}
+	return entries, meta["ETag"], nil
+}
+
+// Flush writes every shard with pending changes. Shards that fail to flush
+// keep their changes for the next attempt; the first error is returned.
+func (ix *Index) Flush(ctx context.Context) error {
+	if ix == nil {
+		return nil
+	}
+	ix.flushMu.Lock()
+	defer ix.flushMu.Unlock()
+
+	ix.mu.Lock()
+	work := make(map[shardID]map[string]*Entry)
+	for id, sh := range ix.shards {
+		if len(sh.pending) > 0 {
+			work[id] = sh.pending
+			sh.pending = nil
+		}
+	}
+	ix.mu.Unlock()
+
+	ids := make([]shardID, 0, len(work))
+	for id := range work {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ids[i].bucket != ids[j].bucket {
+			return ids[i].bucket < ids[j].bucket
+		}
+		return ids[i].prefix < ids[j].prefix
+	})
+
+	var firstErr error
+	for _, id := range ids {
+		merged, err := ix.flushShard(ctx, id, work[id])
+		ix.mu.Lock()
+		sh := ix.shards[id]
+		if sh == nil {
+			sh = &shard{}
+			ix.shards[id] = sh
+		}
+		if err != nil {
+			// Requeue, letting changes made since the flush started win.
+			if sh.pending == nil {
+				sh.pending = make(map[string]*Entry)
+			}
+			for k, e := range work[id] {
+				if _, newer := sh.pending[k]; !newer {
+					sh.pending[k] = e
+				}
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else {
+			sh.entries = merged
+			sh.loadedAt = ix.now()
+		}
+		ix.mu.Unlock()
+	}
+	return firstErr
+}
+
+func (ix *Index) flushShard(ctx context.Context, id shardID, changes map[string]*Entry) (map[string]Entry, error) {
+	entries, etag, err := ix.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for k, e := range changes {
+		if e == nil {
+			delete(entries, k)
+			continue
+		}
+		entries[k] = *e
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return nil, fmt.Errorf("sizeindex: encode: %w", err)
+	}
+	conds := s3.WriteConditions{IfMatch: etag}
+	if etag == "" {
+		conds.IfNoneMatch = "*"
+	}
+	size := int64(len(data))
+	meta := map[string]string{"Content-Type": "application/json"}
+	if err := ix.backend.PutObject(s3.WithWriteConditions(ctx, conds), id.bucket, ix.objectKey(id), bytes.NewReader(data), meta, &size, "", nil); err != nil {
+		return nil, fmt.Errorf("sizeindex: write %s/%s: %w", id.bucket, ix.objectKey(id), err)
+	}
+	return entries, nil
+}
+
+// Start flushes pending changes every flush interval until Stop is called.
+func (ix *Index) Start() {
+	if ix == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(ix.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := ix.Flush(context.Background()); err != nil && ix.logger != nil {
+					ix.logger.WithError(err).Warn("Size index flush failed; will retry")
+				}
+			case <-ix.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the flush loop and performs a final flush. A flush already in
+// progress completes first.
+func (ix *Index) Stop(ctx context.Context) error {
+	if ix == nil {
+		return nil
+	}
+	ix.stopOnce.Do(func() { close(ix.stop) })
+	return ix.Flush(ctx)
+}
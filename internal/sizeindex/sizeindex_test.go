@@ -0,0 +1,197 @@
+package sizeindex
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// memBackend stores objects in memory and honours write conditions.
+type memBackend struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	etags   map[string]string
+	seq     int
+	puts    int
+	failPut error
+}
+
+func newMemBackend() *memBackend {
+	return &memBackend{objects: map[string][]byte{}, etags: map[string]string{}}
+}
+
+func (b *memBackend) GetObject(ctx context.Context, bucket, key string, versionID, rangeHeader *string) (io.ReadCloser, map[string]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.objects[bucket+"/"+key]
+	if !ok {
+		return nil, nil, s3.NewAPIError("NoSuchKey", "not found")
+	}
+	return io.NopCloser(bytes.NewReader(data)), map[string]string{"ETag": b.etags[bucket+"/"+key]}, nil
+}
+
+func (b *memBackend) PutObject(ctx context.Context, bucket, key string, reader io.Reader, metadata map[string]string, contentLength *int64, tags string, lock *s3.ObjectLockInput) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failPut != nil {
+		return b.failPut
+	}
+	k := bucket + "/" + key
+	if c, ok := s3.WriteConditionsFromContext(ctx); ok {
+		_, exists := b.objects[k]
+		if (c.IfNoneMatch == "*" && exists) || (c.IfMatch != "" && c.IfMatch != b.etags[k]) {
+			return s3.NewAPIError("PreconditionFailed", "at least one of the preconditions did not hold")
+		}
+	}
+	data, _ := io.ReadAll(reader)
+	b.seq++
+	b.puts++
+	b.objects[k] = data
+	b.etags[k] = fmt.Sprintf(`"v%d"`, b.seq)
+	return nil
+}
+
+func testConfig() config.SizeIndexConfig {
+	return config.SizeIndexConfig{Enabled: true, Prefix: ".s3eg-index/", FlushInterval: time.Second}
+}
+
+func TestIndex_RecordFlushLookup(t *testing.T) {
+	ctx := context.Background()
+	backend := newMemBackend()
+	ix := New(backend, testConfig(), nil)
+
+	ix.Record("b", "dir/a", Entry{Size: 10, ETag: `"a"`})
+	ix.Record("b", "top", Entry{Size: 20})
+	if e, ok := ix.Lookup(ctx, "b", "dir/a"); !ok || e.Size != 10 {
+		t.Fatalf("pending entry not visible: %+v %v", e, ok)
+	}
+	if err := ix.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	for _, k := range []string{"b/.s3eg-index/dir/index.json", "b/.s3eg-index/index.json"} {
+		if _, ok := backend.objects[k]; !ok {
+			t.Errorf("expected index object %s", k)
+		}
+	}
+
+	fresh := New(backend, testConfig(), nil)
+	if e, ok := fresh.Lookup(ctx, "b", "dir/a"); !ok || e.Size != 10 || e.ETag != `"a"` {
+		t.Errorf("persisted entry = %+v (ok=%v)", e, ok)
+	}
+	if _, ok := fresh.Lookup(ctx, "b", "dir/missing"); ok {
+		t.Error("unexpected entry for unknown key")
+	}
+
+	ix.Remove("b", "dir/a")
+	if err := ix.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if _, ok := New(backend, testConfig(), nil).Lookup(ctx, "b", "dir/a"); ok {
+		t.Error("removed entry still indexed")
+	}
+}
+
+func TestIndex_FlushMergesConcurrentInstances(t *testing.T) {
+	ctx := context.Background()
+	backend := newMemBackend()
+	one := New(backend, testConfig(), nil)
+	two := New(backend, testConfig(), nil)
+
+	one.Record("b", "k1", Entry{Size: 1})
+	two.Record("b", "k2", Entry{Size: 2})
+	if err := one.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := two.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	check := New(backend, testConfig(), nil)
+	for _, k := range []string{"k1", "k2"} {
+		if _, ok := check.Lookup(ctx, "b", k); !ok {
+			t.Errorf("entry %s lost in merge", k)
+		}
+	}
+}
+
+func TestIndex_FailedFlushIsRetried(t *testing.T) {
+	ctx := context.Background()
+	backend := newMemBackend()
+	backend.failPut = errors.New("backend unavailable")
+	ix := New(backend, testConfig(), nil)
+
+	ix.Record("b", "k", Entry{Size: 5})
+	if err := ix.Flush(ctx); err == nil {
+		t.Fatal("expected flush error")
+	}
+	backend.failPut = nil
+	if err := ix.Flush(ctx); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if backend.puts != 1 {
+		t.Errorf("expected one successful write, got %d", backend.puts)
+	}
+	if _, ok := New(backend, testConfig(), nil).Lookup(ctx, "b", "k"); !ok {
+		t.Error("entry lost after failed flush")
+	}
+}
+
+func TestEntry_Matches(t *testing.T) {
+	written := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	byWrite := Entry{Size: 1, Written: written}
+	if !byWrite.Matches(`"x"`, "2026-01-02T03:04:05.000Z") {
+		t.Error("object listed at write time should match")
+	}
+	if byWrite.Matches(`"x"`, "2026-01-02T03:05:00.000Z") {
+		t.Error("object modified after the recorded write must not match")
+	}
+	if byWrite.Matches(`"x"`, "garbage") {
+		t.Error("unparseable Last-Modified must not match")
+	}
+
+	byETag := Entry{Size: 1, BackendETag: `"x"`}
+	if !byETag.Matches(`"x"`, "") || byETag.Matches(`"y"`, "") {
+		t.Error("backend ETag comparison is wrong")
+	}
+}
+
+func TestEntryFromMetadata(t *testing.T) {
+	e, ok := EntryFromMetadata(map[string]string{
+		crypto.MetaOriginalSize: "42",
+		crypto.MetaOriginalETag: `"p"`,
+		crypto.MetaKeyVersion:   "3",
+	})
+	if !ok || e.Size != 42 || e.ETag != `"p"` || e.KeyVersion != 3 {
+		t.Errorf("got %+v (ok=%v)", e, ok)
+	}
+	if _, ok := EntryFromMetadata(map[string]string{}); ok {
+		t.Error("metadata without a size must not produce an entry")
+	}
+}
+
+func TestIndex_NilIsDisabled(t *testing.T) {
+	var ix *Index
+	ix.Record("b", "k", Entry{})
+	ix.Remove("b", "k")
+	if _, ok := ix.Lookup(context.Background(), "b", "k"); ok {
+		t.Error("nil index returned an entry")
+	}
+	if ix.IsIndexKey(".s3eg-index/index.json") {
+		t.Error("nil index claims keys")
+	}
+	if err := ix.Stop(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if New(newMemBackend(), config.SizeIndexConfig{}, nil) != nil {
+		t.Error("disabled config must return a nil index")
+	}
+}
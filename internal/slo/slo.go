@@ -0,0 +1,258 @@
+// Package slo tracks per-operation error budgets for the S3 data plane.
+//
+// Each request is classified as good or bad against the objective configured
+// for its S3 operation. Good/bad counts are kept in fixed-width time buckets
+// so burn rates can be computed over several look-back windows at once, which
+// is what multi-window, multi-burn-rate alerting needs (a fast window to
+// detect the spike, a slow window to confirm it is not noise).
+//
+// burn rate = observed error rate / (1 - target)
+//
+// A burn rate of 1 consumes the budget exactly over the SLO period; 14.4 over
+// 1h is the conventional page threshold for a 30-day 99.9% objective.
+package slo
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+// bucketWidth is the resolution of the sliding counters. Windows shorter than
+// a few buckets would be noisy, which is why config validation requires
+// windows of at least one minute.
+const bucketWidth = 10 * time.Second
+
+// Outcome labels recorded for every classified request.
+const (
+	OutcomeGood  = "good"
+	OutcomeError = "error" // 5xx response
+	OutcomeSlow  = "slow"  // over the objective's latency threshold
+)
+
+// Recorder is the subset of metrics.Metrics used by the tracker.
+type Recorder interface {
+	RecordSLOEvent(operation, outcome string)
+	SetSLOBurnRate(operation, window string, rate float64)
+	SetSLOErrorBudgetRemaining(operation string, remaining float64)
+}
+
+// WindowSummary describes a single look-back window for one operation.
+type WindowSummary struct {
+	Window    string  `json:"window"`
+	Total     uint64  `json:"total"`
+	Bad       uint64  `json:"bad"`
+	ErrorRate float64 `json:"error_rate"`
+	BurnRate  float64 `json:"burn_rate"`
+}
+
+// OperationSummary is the /admin/slo view of one operation.
+type OperationSummary struct {
+	Operation        string          `json:"operation"`
+	Target           float64         `json:"target"`
+	LatencyThreshold string          `json:"latency_threshold,omitempty"`
+	Windows          []WindowSummary `json:"windows"`
+	// ErrorBudgetRemaining is the fraction of the budget left over the
+	// longest window; negative once the budget is overspent.
+	ErrorBudgetRemaining float64 `json:"error_budget_remaining"`
+}
+
+type bucket struct {
+	slot  int64 // absolute bucket index (unix time / bucketWidth)
+	total uint64
+	bad   uint64
+}
+
+type series struct {
+	buckets []bucket
+}
+
+// Tracker accumulates SLO events and derives burn rates.
+type Tracker struct {
+	mu         sync.Mutex
+	def        config.SLOObjective
+	objectives map[string]config.SLOObjective
+	windows    []time.Duration
+	series     map[string]*series
+	nbuckets   int
+	metrics    Recorder
+	now        func() time.Time
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewTracker builds a tracker from cfg. metrics may be nil.
+func NewTracker(cfg config.SLOConfig, metrics Recorder) *Tracker {
+	windows := append([]time.Duration(nil), cfg.Windows...)
+	if len(windows) == 0 {
+		windows = config.DefaultSLOWindows()
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i] < windows[j] })
+
+	objectives := make(map[string]config.SLOObjective, len(cfg.Objectives))
+	for _, o := range cfg.Objectives {
+		objectives[o.Operation] = o
+	}
+
+	longest := windows[len(windows)-1]
+	return &Tracker{
+		def:        cfg.Default,
+		objectives: objectives,
+		windows:    windows,
+		series:     make(map[string]*series),
+		nbuckets:   int(longest/bucketWidth) + 1,
+		metrics:    metrics,
+		now:        time.Now,
+		stop:       make(chan struct{}),
+	}
+}
+
+// objective returns the objective that applies to operation.
+func (t *Tracker) objective(operation string) config.SLOObjective {
+	if o, ok := t.objectives[operation]; ok {
+		return o
+	}
+	return t.def
+}
+
+// Classify returns the outcome of a request against operation's objective.
+func (t *Tracker) Classify(operation string, status int, duration time.Duration) string {
+	if status >= http.StatusInternalServerError {
+		return OutcomeError
+	}
+	if o := t.objective(operation); o.LatencyThreshold > 0 && duration > o.LatencyThreshold {
+		return OutcomeSlow
+	}
+	return OutcomeGood
+}
+
+// Record classifies and records a completed request.
+func (t *Tracker) Record(operation string, status int, duration time.Duration) {
+	outcome := t.Classify(operation, status, duration)
+	if t.metrics != nil {
+		t.metrics.RecordSLOEvent(operation, outcome)
+	}
+
+	slot := t.now().UnixNano() / int64(bucketWidth)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.series[operation]
+	if !ok {
+		s = &series{buckets: make([]bucket, t.nbuckets)}
+		t.series[operation] = s
+	}
+	b := &s.buckets[slot%int64(t.nbuckets)]
+	if b.slot != slot {
+		*b = bucket{slot: slot}
+	}
+	b.total++
+	if outcome != OutcomeGood {
+		b.bad++
+	}
+}
+
+// counts sums the buckets of s that fall inside window. Caller holds t.mu.
+func (t *Tracker) counts(s *series, window time.Duration, nowSlot int64) (total, bad uint64) {
+	span := int64(window / bucketWidth)
+	for _, b := range s.buckets {
+		if b.total == 0 {
+			continue
+		}
+		if age := nowSlot - b.slot; age >= 0 && age < span {
+			total += b.total
+			bad += b.bad
+		}
+	}
+	return total, bad
+}
+
+// Summary returns the current state of every operation seen so far, sorted
+// by operation name.
+func (t *Tracker) Summary() []OperationSummary {
+	nowSlot := t.now().UnixNano() / int64(bucketWidth)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]OperationSummary, 0, len(t.series))
+	for op, s := range t.series {
+		o := t.objective(op)
+		budget := 1 - o.Target
+		sum := OperationSummary{
+			Operation: op,
+			Target:    o.Target,
+			Windows:   make([]WindowSummary, 0, len(t.windows)),
+		}
+		if o.LatencyThreshold > 0 {
+			sum.LatencyThreshold = o.LatencyThreshold.String()
+		}
+		for _, w := range t.windows {
+			total, bad := t.counts(s, w, nowSlot)
+			ws := WindowSummary{Window: formatWindow(w), Total: total, Bad: bad}
+			if total > 0 {
+				ws.ErrorRate = float64(bad) / float64(total)
+			}
+			if budget > 0 {
+				ws.BurnRate = ws.ErrorRate / budget
+			}
+			sum.Windows = append(sum.Windows, ws)
+		}
+		sum.ErrorBudgetRemaining = 1 - sum.Windows[len(sum.Windows)-1].BurnRate
+		out = append(out, sum)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Operation < out[j].Operation })
+	return out
+}
+
+// Refresh recomputes the burn-rate and budget gauges.
+func (t *Tracker) Refresh() {
+	if t.metrics == nil {
+		return
+	}
+	for _, op := range t.Summary() {
+		for _, w := range op.Windows {
+			t.metrics.SetSLOBurnRate(op.Operation, w.Window, w.BurnRate)
+		}
+		t.metrics.SetSLOErrorBudgetRemaining(op.Operation, op.ErrorBudgetRemaining)
+	}
+}
+
+// Start refreshes the gauges every interval until Stop is called.
+func (t *Tracker) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.Refresh()
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the refresh loop started by Start.
+func (t *Tracker) Stop() {
+	t.stopOnce.Do(func() { close(t.stop) })
+}
+
+// formatWindow renders a window as a compact Prometheus-style label
+// ("5m", "1h", "6h30m").
+func formatWindow(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,157 @@
+package slo
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+type fakeRecorder struct {
+	mu     sync.Mutex
+	events map[string]int
+	burn   map[string]float64
+	budget map[string]float64
+}
+
+func newFakeRecorder() *fakeRecorder {
+	return &fakeRecorder{
+		events: make(map[string]int),
+		burn:   make(map[string]float64),
+		budget: make(map[string]float64),
+	}
+}
+
+func (f *fakeRecorder) RecordSLOEvent(operation, outcome string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events[operation+"/"+outcome]++
+}
+
+func (f *fakeRecorder) SetSLOBurnRate(operation, window string, rate float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.burn[operation+"/"+window] = rate
+}
+
+func (f *fakeRecorder) SetSLOErrorBudgetRemaining(operation string, remaining float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.budget[operation] = remaining
+}
+
+func testConfig() config.SLOConfig {
+	return config.SLOConfig{
+		Enabled: true,
+		Windows: []time.Duration{5 * time.Minute, time.Hour},
+		Default: config.SLOObjective{Target: 0.99},
+		Objectives: []config.SLOObjective{
+			{Operation: "GetObject", Target: 0.9, LatencyThreshold: time.Second},
+		},
+	}
+}
+
+func almostEqual(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestTracker_Classify(t *testing.T) {
+	tr := NewTracker(testConfig(), nil)
+
+	tests := []struct {
+		op       string
+		status   int
+		duration time.Duration
+		want     string
+	}{
+		{"GetObject", http.StatusOK, 10 * time.Millisecond, OutcomeGood},
+		{"GetObject", http.StatusNotFound, 10 * time.Millisecond, OutcomeGood},
+		{"GetObject", http.StatusBadGateway, 10 * time.Millisecond, OutcomeError},
+		{"GetObject", http.StatusOK, 2 * time.Second, OutcomeSlow},
+		{"PutObject", http.StatusOK, time.Hour, OutcomeGood}, // default has no latency threshold
+	}
+	for _, tt := range tests {
+		if got := tr.Classify(tt.op, tt.status, tt.duration); got != tt.want {
+			t.Errorf("Classify(%s, %d, %s) = %s, want %s", tt.op, tt.status, tt.duration, got, tt.want)
+		}
+	}
+}
+
+func TestTracker_BurnRateAcrossWindows(t *testing.T) {
+	rec := newFakeRecorder()
+	tr := NewTracker(testConfig(), rec)
+	now := time.Unix(1_700_000_000, 0)
+	tr.now = func() time.Time { return now }
+
+	// 30 minutes ago: 10 requests, all bad. Only visible in the 1h window.
+	now = now.Add(-30 * time.Minute)
+	for i := 0; i < 10; i++ {
+		tr.Record("PutObject", http.StatusInternalServerError, time.Millisecond)
+	}
+	// Now: 90 good requests.
+	now = now.Add(30 * time.Minute)
+	for i := 0; i < 90; i++ {
+		tr.Record("PutObject", http.StatusOK, time.Millisecond)
+	}
+
+	summary := tr.Summary()
+	if len(summary) != 1 {
+		t.Fatalf("expected 1 operation, got %d", len(summary))
+	}
+	op := summary[0]
+	if op.Operation != "PutObject" || op.Target != 0.99 {
+		t.Fatalf("unexpected operation summary: %+v", op)
+	}
+
+	short, long := op.Windows[0], op.Windows[1]
+	if short.Window != "5m" || short.Total != 90 || short.Bad != 0 || short.BurnRate != 0 {
+		t.Errorf("unexpected 5m window: %+v", short)
+	}
+	// 10% errors against a 1% budget burns at 10x.
+	if long.Window != "1h" || long.Total != 100 || long.Bad != 10 || !almostEqual(long.BurnRate, 10) {
+		t.Errorf("unexpected 1h window: %+v", long)
+	}
+	if !almostEqual(op.ErrorBudgetRemaining, -9) {
+		t.Errorf("expected overspent budget -9, got %v", op.ErrorBudgetRemaining)
+	}
+
+	tr.Refresh()
+	if !almostEqual(rec.burn["PutObject/1h"], 10) {
+		t.Errorf("expected burn-rate gauge 10, got %v", rec.burn["PutObject/1h"])
+	}
+	if rec.events["PutObject/error"] != 10 || rec.events["PutObject/good"] != 90 {
+		t.Errorf("unexpected event counts: %v", rec.events)
+	}
+}
+
+func TestTracker_OldBucketsExpire(t *testing.T) {
+	tr := NewTracker(testConfig(), nil)
+	now := time.Unix(1_700_000_000, 0)
+	tr.now = func() time.Time { return now }
+
+	tr.Record("HeadObject", http.StatusServiceUnavailable, time.Millisecond)
+	now = now.Add(2 * time.Hour)
+	tr.Record("HeadObject", http.StatusOK, time.Millisecond)
+
+	for _, w := range tr.Summary()[0].Windows {
+		if w.Total != 1 || w.Bad != 0 {
+			t.Errorf("window %s should only see the recent request, got %+v", w.Window, w)
+		}
+	}
+}
+
+func TestFormatWindow(t *testing.T) {
+	tests := map[time.Duration]string{
+		5 * time.Minute:              "5m",
+		time.Hour:                    "1h",
+		6*time.Hour + 30*time.Minute: "6h30m",
+		90 * time.Second:             "1m30s",
+		72 * time.Hour:               "72h",
+	}
+	for d, want := range tests {
+		if got := formatWindow(d); got != want {
+			t.Errorf("formatWindow(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,812 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/smithy-go"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+const (
+	// azureAPIVersion is the Blob service version requests are written
+	// against (x-ms-version).
+	azureAPIVersion = "2021-08-06"
+	azureMetaPrefix = "x-ms-meta-"
+	// azureBlockSize is the block size used to stream objects of unknown
+	// length.
+	azureBlockSize = 8 << 20
+)
+
+// Azure stores objects as block blobs in Azure Blob Storage using the REST
+// API with Shared Key authorization. Buckets are containers, which must
+// exist.
+//
+// Multipart uploads are staged as uncommitted blocks and committed with Put
+// Block List. The metadata given when an upload is created is held in
+// memory until it completes, so uploads do not survive a restart of the
+// gateway: their parts fail with NoSuchUpload and the client starts over.
+// Versioning and Object Lock are not available.
+type Azure struct {
+	account  string
+	key      []byte
+	endpoint *url.URL
+	client   *http.Client
+
+	mu      sync.Mutex
+	uploads map[string]*azureUpload
+}
+
+var _ Backend = (*Azure)(nil)
+
+type azureUpload struct {
+	container, blob string
+	metadata        map[string]string
+	parts           map[int32]s3.PartInfo
+}
+
+// NewAzure returns a backend for the storage account in cfg. A nil client
+// uses http.DefaultClient.
+func NewAzure(cfg config.BackendAzureConfig, client *http.Client) (*Azure, error) {
+	key, err := base64.StdEncoding.DecodeString(cfg.AccountKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid azure account key: %w", err)
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://" + cfg.AccountName + ".blob.core.windows.net"
+	}
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid azure endpoint %q", endpoint)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Azure{
+		account:  cfg.AccountName,
+		key:      key,
+		endpoint: u,
+		client:   client,
+		uploads:  make(map[string]*azureUpload),
+	}, nil
+}
+
+// blobURL returns the URL of container/blob; an empty blob addresses the
+// container.
+func (a *Azure) blobURL(container, blob string, query url.Values) *url.URL {
+	u := *a.endpoint
+	path, raw := u.Path+"/"+container, u.EscapedPath()+"/"+url.PathEscape(container)
+	if blob != "" {
+		segs := strings.Split(blob, "/")
+		for i, s := range segs {
+			segs[i] = url.PathEscape(s)
+		}
+		path += "/" + blob
+		raw += "/" + strings.Join(segs, "/")
+	}
+	u.Path, u.RawPath = path, raw
+	u.RawQuery = query.Encode()
+	return &u
+}
+
+// do sends a signed request and turns error responses into S3 errors.
+func (a *Azure) do(ctx context.Context, method string, u *url.URL, header http.Header, body io.Reader, length int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.ContentLength = length
+	if length == 0 {
+		req.Body = http.NoBody
+	}
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	a.sign(req)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("azure %s %s: %w", method, u.Path, err)
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	_ = xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&e)
+	if e.Code == "" {
+		e.Code = resp.Header.Get("x-ms-error-code")
+	}
+	msg := strings.TrimSpace(e.Message)
+	if msg == "" {
+		msg = resp.Status
+	}
+	return nil, s3.NewAPIError(azureErrorCode(e.Code, resp.StatusCode), fmt.Sprintf("azure %s %s: %s: %s", method, u.Path, e.Code, msg))
+}
+
+// azureErrorCode maps a Blob service error onto the S3 code with the same
+// meaning.
+func azureErrorCode(code string, status int) string {
+	switch code {
+	case "BlobNotFound":
+		return "NoSuchKey"
+	case "ContainerNotFound":
+		return "NoSuchBucket"
+	case "ConditionNotMet", "TargetConditionNotMet", "BlobAlreadyExists":
+		return "PreconditionFailed"
+	case "InvalidRange":
+		return "InvalidRange"
+	case "InvalidBlockList", "InvalidBlockId":
+		return "InvalidPart"
+	case "ServerBusy", "OperationTimedOut":
+		return "SlowDown"
+	case "AuthenticationFailed", "AuthorizationFailure", "AuthorizationPermissionMismatch":
+		return "AccessDenied"
+	}
+	switch {
+	case status == http.StatusNotFound:
+		return "NoSuchKey"
+	case status == http.StatusPreconditionFailed:
+		return "PreconditionFailed"
+	case status == http.StatusForbidden:
+		return "AccessDenied"
+	case status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable:
+		return "SlowDown"
+	case code != "":
+		return code
+	default:
+		return "InternalError"
+	}
+}
+
+// sign adds a Shared Key Authorization header to req.
+func (a *Azure) sign(req *http.Request) {
+	h := req.Header
+	length := ""
+	if req.ContentLength > 0 {
+		length = strconv.FormatInt(req.ContentLength, 10)
+	}
+	var b strings.Builder
+	for _, s := range []string{
+		req.Method,
+		h.Get("Content-Encoding"),
+		h.Get("Content-Language"),
+		length,
+		h.Get("Content-MD5"),
+		h.Get("Content-Type"),
+		"", // Date: x-ms-date is used instead
+		h.Get("If-Modified-Since"),
+		h.Get("If-Match"),
+		h.Get("If-None-Match"),
+		h.Get("If-Unmodified-Since"),
+		h.Get("Range"),
+	} {
+		b.WriteString(s)
+		b.WriteByte('\n')
+	}
+
+	// Metadata headers are set under their raw names, so look them up
+	// through the map rather than with Get.
+	msHeaders := make(map[string]string)
+	var names []string
+	for k, v := range h {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-ms-") && len(v) > 0 {
+			msHeaders[lk] = strings.TrimSpace(v[0])
+			names = append(names, lk)
+		}
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		b.WriteString(k + ":" + msHeaders[k] + "\n")
+	}
+
+	b.WriteString("/" + a.account + req.URL.EscapedPath())
+	query := req.URL.Query()
+	params := make([]string, 0, len(query))
+	for k := range query {
+		params = append(params, k)
+	}
+	sort.Strings(params)
+	for _, k := range params {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		b.WriteString("\n" + strings.ToLower(k) + ":" + strings.Join(values, ","))
+	}
+
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(b.String()))
+	h.Set("Authorization", "SharedKey "+a.account+":"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+// azureMetaName encodes an S3 metadata name as a C# identifier, which is
+// all Azure accepts: characters other than [a-z0-9] become "_" and two hex
+// digits, as does a leading digit.
+func azureMetaName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' && i > 0 {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "_%02x", c)
+	}
+	return b.String()
+}
+
+func azureMetaNameDecode(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '_' && i+2 < len(name) {
+			if v, err := hex.DecodeString(name[i+1 : i+3]); err == nil {
+				b.WriteByte(v[0])
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
+
+// metadataHeaders returns S3 user metadata as x-ms-meta- headers.
+func metadataHeaders(metadata map[string]string) http.Header {
+	h := http.Header{}
+	for k, v := range normalizeMetadata(metadata) {
+		h[azureMetaPrefix+azureMetaName(strings.TrimPrefix(k, metaPrefix))] = []string{v}
+	}
+	return h
+}
+
+// responseMetadata converts blob response headers into the map s3.Client
+// returns.
+func responseMetadata(h http.Header) map[string]string {
+	meta := make(map[string]string)
+	for k, v := range h {
+		lk := strings.ToLower(k)
+		if name, ok := strings.CutPrefix(lk, azureMetaPrefix); ok && len(v) > 0 {
+			meta[metaPrefix+azureMetaNameDecode(name)] = v[0]
+		}
+	}
+	for _, k := range []string{"ETag", "Content-Length", "Content-Type", "Content-Range", "Last-Modified"} {
+		if v := h.Get(k); v != "" {
+			meta[k] = v
+		}
+	}
+	return meta
+}
+
+func conditionHeaders(ctx context.Context, h http.Header) {
+	if conds, ok := s3.WriteConditionsFromContext(ctx); ok {
+		if conds.IfMatch != "" {
+			h.Set("If-Match", conds.IfMatch)
+		}
+		if conds.IfNoneMatch != "" {
+			h.Set("If-None-Match", conds.IfNoneMatch)
+		}
+	}
+}
+
+func hasLock(lock *s3.ObjectLockInput) bool {
+	return lock != nil && (lock.Mode != "" || lock.LegalHoldStatus != "")
+}
+
+// PutObject uploads a block blob. Bodies of unknown length are streamed as
+// a list of blocks.
+func (a *Azure) PutObject(ctx context.Context, bucket, key string, reader io.Reader, metadata map[string]string, contentLength *int64, tags string, lock *s3.ObjectLockInput) error {
+	if hasLock(lock) {
+		return notSupported("azure", "object lock")
+	}
+	h := metadataHeaders(metadata)
+	if tags != "" {
+		h.Set("x-ms-tags", tags)
+	}
+	conditionHeaders(ctx, h)
+	if contentLength == nil {
+		_, err := a.putBlocks(ctx, bucket, key, reader, h)
+		return err
+	}
+	h.Set("x-ms-blob-type", "BlockBlob")
+	resp, err := a.do(ctx, http.MethodPut, a.blobURL(bucket, key, nil), h, reader, *contentLength)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// putBlocks uploads reader in azureBlockSize blocks and commits them with
+// header.
+func (a *Azure) putBlocks(ctx context.Context, bucket, key string, reader io.Reader, header http.Header) (string, error) {
+	prefix, err := newUploadID()
+	if err != nil {
+		return "", err
+	}
+	var ids []string
+	buf := make([]byte, azureBlockSize)
+	for n := 1; ; n++ {
+		read, err := io.ReadFull(reader, buf)
+		if read > 0 || n == 1 {
+			id := blockID(prefix, int32(n))
+			if err := a.putBlock(ctx, bucket, key, id, bytes.NewReader(buf[:read]), int64(read)); err != nil {
+				return "", err
+			}
+			ids = append(ids, id)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read object body: %w", err)
+		}
+	}
+	return a.putBlockList(ctx, bucket, key, ids, header)
+}
+
+// blockID derives the block ID of a part. Azure requires IDs of one blob to
+// have the same length, which the fixed-width part number guarantees.
+func blockID(uploadID string, partNumber int32) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s-%05d", uploadID, partNumber)))
+}
+
+func (a *Azure) putBlock(ctx context.Context, bucket, key, id string, body io.Reader, length int64) error {
+	q := url.Values{"comp": {"block"}, "blockid": {id}}
+	resp, err := a.do(ctx, http.MethodPut, a.blobURL(bucket, key, q), nil, body, length)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (a *Azure) putBlockList(ctx context.Context, bucket, key string, ids []string, header http.Header) (string, error) {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?><BlockList>`)
+	for _, id := range ids {
+		b.WriteString("<Latest>" + id + "</Latest>")
+	}
+	b.WriteString("</BlockList>")
+	q := url.Values{"comp": {"blocklist"}}
+	resp, err := a.do(ctx, http.MethodPut, a.blobURL(bucket, key, q), header, &b, int64(b.Len()))
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+// GetObject downloads a blob, or the byte range rangeHeader of it.
+func (a *Azure) GetObject(ctx context.Context, bucket, key string, versionID *string, rangeHeader *string) (io.ReadCloser, map[string]string, error) {
+	if err := checkVersion(versionID); err != nil {
+		return nil, nil, err
+	}
+	h := http.Header{}
+	if rangeHeader != nil && *rangeHeader != "" {
+		r := *rangeHeader
+		// x-ms-range has no suffix form; resolve it against the size.
+		if strings.HasPrefix(r, "bytes=-") {
+			props, err := a.HeadObject(ctx, bucket, key, versionID)
+			if err != nil {
+				return nil, nil, err
+			}
+			size, _ := strconv.ParseInt(props["Content-Length"], 10, 64)
+			start, end, err := parseRange(r, size)
+			if err != nil {
+				return nil, nil, err
+			}
+			r = fmt.Sprintf("bytes=%d-%d", start, end)
+		}
+		h.Set("x-ms-range", r)
+	}
+	resp, err := a.do(ctx, http.MethodGet, a.blobURL(bucket, key, nil), h, nil, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Body, responseMetadata(resp.Header), nil
+}
+
+// HeadObject returns a blob's properties and metadata.
+func (a *Azure) HeadObject(ctx context.Context, bucket, key string, versionID *string) (map[string]string, error) {
+	if err := checkVersion(versionID); err != nil {
+		return nil, err
+	}
+	resp, err := a.do(ctx, http.MethodHead, a.blobURL(bucket, key, nil), nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return responseMetadata(resp.Header), nil
+}
+
+// DeleteObject deletes a blob. Deleting a missing blob succeeds, as in S3.
+func (a *Azure) DeleteObject(ctx context.Context, bucket, key string, versionID *string) error {
+	if err := checkVersion(versionID); err != nil {
+		return err
+	}
+	resp, err := a.do(ctx, http.MethodDelete, a.blobURL(bucket, key, nil), nil, nil, 0)
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchKey" {
+			return nil
+		}
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// azureList is the List Blobs response.
+type azureList struct {
+	Blobs struct {
+		Blob []struct {
+			Name       string `xml:"Name"`
+			Properties struct {
+				LastModified  string `xml:"Last-Modified"`
+				ETag          string `xml:"Etag"`
+				ContentLength int64  `xml:"Content-Length"`
+			} `xml:"Properties"`
+		} `xml:"Blob"`
+		BlobPrefix []struct {
+			Name string `xml:"Name"`
+		} `xml:"BlobPrefix"`
+	} `xml:"Blobs"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+// ListObjects lists blobs with List Blobs. The continuation token is the
+// service's marker.
+func (a *Azure) ListObjects(ctx context.Context, bucket, prefix string, opts s3.ListOptions) (s3.ListResult, error) {
+	q := url.Values{"restype": {"container"}, "comp": {"list"}}
+	if prefix != "" {
+		q.Set("prefix", prefix)
+	}
+	if opts.Delimiter != "" {
+		q.Set("delimiter", opts.Delimiter)
+	}
+	if opts.ContinuationToken != "" {
+		q.Set("marker", opts.ContinuationToken)
+	}
+	if opts.MaxKeys > 0 {
+		q.Set("maxresults", strconv.Itoa(int(opts.MaxKeys)))
+	}
+	resp, err := a.do(ctx, http.MethodGet, a.blobURL(bucket, "", q), nil, nil, 0)
+	if err != nil {
+		return s3.ListResult{}, err
+	}
+	defer resp.Body.Close()
+	var list azureList
+	if err := xml.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return s3.ListResult{}, fmt.Errorf("failed to parse azure blob list: %w", err)
+	}
+
+	result := s3.ListResult{
+		Objects:               make([]s3.ObjectInfo, 0, len(list.Blobs.Blob)),
+		CommonPrefixes:        make([]string, 0, len(list.Blobs.BlobPrefix)),
+		NextContinuationToken: list.NextMarker,
+		IsTruncated:           list.NextMarker != "",
+	}
+	for _, b := range list.Blobs.Blob {
+		modified := b.Properties.LastModified
+		if t, err := time.Parse(http.TimeFormat, modified); err == nil {
+			modified = t.UTC().Format(listTimeFormat)
+		}
+		etag := b.Properties.ETag
+		if !strings.HasPrefix(etag, `"`) {
+			etag = `"` + etag + `"`
+		}
+		result.Objects = append(result.Objects, s3.ObjectInfo{
+			Key:          b.Name,
+			Size:         b.Properties.ContentLength,
+			LastModified: modified,
+			ETag:         etag,
+		})
+	}
+	for _, p := range list.Blobs.BlobPrefix {
+		result.CommonPrefixes = append(result.CommonPrefixes, p.Name)
+	}
+	return result, nil
+}
+
+func newUploadID() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id[:]), nil
+}
+
+func (a *Azure) upload(bucket, key, uploadID string) (*azureUpload, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	up, ok := a.uploads[uploadID]
+	if !ok || up.container != bucket || up.blob != key {
+		return nil, s3.NewAPIError("NoSuchUpload", "upload not found: "+uploadID)
+	}
+	return up, nil
+}
+
+// CreateMultipartUpload registers an upload. Nothing is sent to Azure
+// until the first part.
+func (a *Azure) CreateMultipartUpload(ctx context.Context, bucket, key string, metadata map[string]string) (string, error) {
+	uploadID, err := newUploadID()
+	if err != nil {
+		return "", err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.uploads[uploadID] = &azureUpload{
+		container: bucket,
+		blob:      key,
+		metadata:  normalizeMetadata(metadata),
+		parts:     make(map[int32]s3.PartInfo),
+	}
+	return uploadID, nil
+}
+
+// UploadPart stores a part as an uncommitted block.
+func (a *Azure) UploadPart(ctx context.Context, bucket, key, uploadID string, partNumber int32, reader io.Reader, contentLength *int64) (string, error) {
+	if partNumber < 1 || partNumber > 10000 {
+		return "", s3.NewAPIError("InvalidArgument", "part number must be between 1 and 10000")
+	}
+	up, err := a.upload(bucket, key, uploadID)
+	if err != nil {
+		return "", err
+	}
+	var length int64
+	if contentLength != nil {
+		length = *contentLength
+	} else {
+		// Put Block needs the length up front.
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return "", fmt.Errorf("failed to read part %d: %w", partNumber, err)
+		}
+		reader, length = bytes.NewReader(data), int64(len(data))
+	}
+	hash := md5.New()
+	if err := a.putBlock(ctx, bucket, key, blockID(uploadID, partNumber), io.TeeReader(reader, hash), length); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)) + `"`
+	a.mu.Lock()
+	up.parts[partNumber] = s3.PartInfo{
+		PartNumber:   partNumber,
+		ETag:         etag,
+		Size:         length,
+		LastModified: time.Now().UTC().Format(listTimeFormat),
+	}
+	a.mu.Unlock()
+	return etag, nil
+}
+
+// CompleteMultipartUpload commits the listed parts as the blob.
+func (a *Azure) CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []s3.CompletedPart, lock *s3.ObjectLockInput) (string, error) {
+	if hasLock(lock) {
+		return "", notSupported("azure", "object lock")
+	}
+	up, err := a.upload(bucket, key, uploadID)
+	if err != nil {
+		return "", err
+	}
+	if len(parts) == 0 {
+		return "", s3.NewAPIError("MalformedXML", "no parts given")
+	}
+	ids := make([]string, len(parts))
+	a.mu.Lock()
+	for i, p := range parts {
+		if i > 0 && p.PartNumber <= parts[i-1].PartNumber {
+			a.mu.Unlock()
+			return "", s3.NewAPIError("InvalidPartOrder", "parts must be in ascending order")
+		}
+		info, ok := up.parts[p.PartNumber]
+		if !ok || strings.Trim(info.ETag, `"`) != strings.Trim(p.ETag, `"`) {
+			a.mu.Unlock()
+			return "", s3.NewAPIError("InvalidPart", fmt.Sprintf("part %d not found or ETag mismatch", p.PartNumber))
+		}
+		ids[i] = blockID(uploadID, p.PartNumber)
+	}
+	a.mu.Unlock()
+
+	h := metadataHeaders(up.metadata)
+	conditionHeaders(ctx, h)
+	etag, err := a.putBlockList(ctx, bucket, key, ids, h)
+	if err != nil {
+		return "", err
+	}
+	a.mu.Lock()
+	delete(a.uploads, uploadID)
+	a.mu.Unlock()
+	return etag, nil
+}
+
+// AbortMultipartUpload forgets an upload. Azure discards uncommitted blocks
+// on its own after a week.
+func (a *Azure) AbortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error {
+	if _, err := a.upload(bucket, key, uploadID); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	delete(a.uploads, uploadID)
+	a.mu.Unlock()
+	return nil
+}
+
+// ListParts lists the parts uploaded so far.
+func (a *Azure) ListParts(ctx context.Context, bucket, key, uploadID string) ([]s3.PartInfo, error) {
+	up, err := a.upload(bucket, key, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	parts := make([]s3.PartInfo, 0, len(up.parts))
+	for _, p := range up.parts {
+		parts = append(parts, p)
+	}
+	a.mu.Unlock()
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	return parts, nil
+}
+
+// CopyObject copies a blob by reading it through the gateway. With metadata
+// the copy carries it instead of the source's.
+func (a *Azure) CopyObject(ctx context.Context, dstBucket, dstKey string, srcBucket, srcKey string, srcVersionID *string, metadata map[string]string, lock *s3.ObjectLockInput) (string, map[string]string, error) {
+	if hasLock(lock) {
+		return "", nil, notSupported("azure", "object lock")
+	}
+	body, srcMeta, err := a.GetObject(ctx, srcBucket, srcKey, srcVersionID, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	defer body.Close()
+	if metadata == nil {
+		metadata = map[string]string{}
+		for k, v := range srcMeta {
+			if strings.HasPrefix(k, metaPrefix) {
+				metadata[k] = v
+			}
+		}
+	}
+	length, err := strconv.ParseInt(srcMeta["Content-Length"], 10, 64)
+	if err != nil {
+		return "", nil, fmt.Errorf("azure copy: source %s/%s has no length", srcBucket, srcKey)
+	}
+	h := metadataHeaders(metadata)
+	h.Set("x-ms-blob-type", "BlockBlob")
+	resp, err := a.do(ctx, http.MethodPut, a.blobURL(dstBucket, dstKey, nil), h, body, length)
+	if err != nil {
+		return "", nil, err
+	}
+	resp.Body.Close()
+	etag := strings.Trim(resp.Header.Get("ETag"), `"`)
+	return etag, map[string]string{"ETag": etag, "Last-Modified": resp.Header.Get("Last-Modified")}, nil
+}
+
+// UploadPartCopy stores a byte range of an existing blob as a part.
+func (a *Azure) UploadPartCopy(ctx context.Context, dstBucket, dstKey, uploadID string, partNumber int32, srcBucket, srcKey string, srcVersionID *string, srcRange *s3.CopyPartRange) (*s3.CopyPartResult, error) {
+	var rangeHeader *string
+	if srcRange != nil {
+		r := fmt.Sprintf("bytes=%d-%d", srcRange.First, srcRange.Last)
+		rangeHeader = &r
+	}
+	body, meta, err := a.GetObject(ctx, srcBucket, srcKey, srcVersionID, rangeHeader)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	var length *int64
+	if n, err := strconv.ParseInt(meta["Content-Length"], 10, 64); err == nil {
+		length = &n
+	}
+	etag, err := a.UploadPart(ctx, dstBucket, dstKey, uploadID, partNumber, body, length)
+	if err != nil {
+		return nil, err
+	}
+	return &s3.CopyPartResult{ETag: etag, LastModified: time.Now().UTC()}, nil
+}
+
+// DeleteObjects deletes each blob in turn.
+func (a *Azure) DeleteObjects(ctx context.Context, bucket string, keys []s3.ObjectIdentifier) ([]s3.DeletedObject, []s3.ErrorObject, error) {
+	return deleteEach(ctx, a, bucket, keys)
+}
+
+// PutObjectRetention is not supported.
+func (a *Azure) PutObjectRetention(ctx context.Context, bucket, key string, versionID *string, retention *s3.RetentionConfig) error {
+	return notSupported("azure", "object lock")
+}
+
+// GetObjectRetention is not supported.
+func (a *Azure) GetObjectRetention(ctx context.Context, bucket, key string, versionID *string) (*s3.RetentionConfig, error) {
+	return nil, notSupported("azure", "object lock")
+}
+
+// PutObjectLegalHold is not supported.
+func (a *Azure) PutObjectLegalHold(ctx context.Context, bucket, key string, versionID *string, status string) error {
+	return notSupported("azure", "object lock")
+}
+
+// GetObjectLegalHold is not supported.
+func (a *Azure) GetObjectLegalHold(ctx context.Context, bucket, key string, versionID *string) (string, error) {
+	return "", notSupported("azure", "object lock")
+}
+
+// PutObjectLockConfiguration is not supported.
+func (a *Azure) PutObjectLockConfiguration(ctx context.Context, bucket string, config *s3.ObjectLockConfiguration) error {
+	return notSupported("azure", "object lock")
+}
+
+// GetObjectLockConfiguration reports that no container has Object Lock.
+func (a *Azure) GetObjectLockConfiguration(ctx context.Context, bucket string) (*s3.ObjectLockConfiguration, error) {
+	return nil, nil
+}
+
+// azureTags is the body of the Set Blob Tags and Get Blob Tags calls.
+type azureTags struct {
+	XMLName xml.Name `xml:"Tags"`
+	TagSet  []s3.Tag `xml:"TagSet>Tag"`
+}
+
+// PutObjectTagging replaces the blob's index tags.
+func (a *Azure) PutObjectTagging(ctx context.Context, bucket, key string, versionID *string, tags []s3.Tag) error {
+	if err := checkVersion(versionID); err != nil {
+		return err
+	}
+	body, err := xml.Marshal(azureTags{TagSet: tags})
+	if err != nil {
+		return err
+	}
+	h := http.Header{"Content-Type": {"application/xml"}}
+	resp, err := a.do(ctx, http.MethodPut, a.blobURL(bucket, key, url.Values{"comp": {"tags"}}), h, bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GetObjectTagging returns the blob's index tags.
+func (a *Azure) GetObjectTagging(ctx context.Context, bucket, key string, versionID *string) ([]s3.Tag, error) {
+	if err := checkVersion(versionID); err != nil {
+		return nil, err
+	}
+	resp, err := a.do(ctx, http.MethodGet, a.blobURL(bucket, key, url.Values{"comp": {"tags"}}), nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var tags azureTags
+	if err := xml.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("azure get tags %s/%s: %w", bucket, key, err)
+	}
+	return tags.TagSet, nil
+}
+
+// DeleteObjectTagging clears the blob's index tags; the Blob service has no
+// separate call for it.
+func (a *Azure) DeleteObjectTagging(ctx context.Context, bucket, key string, versionID *string) error {
+	return a.PutObjectTagging(ctx, bucket, key, versionID, nil)
+}
@@ -0,0 +1,356 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+var testAzureKey = base64.StdEncoding.EncodeToString([]byte("azure-test-account-key"))
+
+func TestAzure_SharedKeySignature(t *testing.T) {
+	a, err := NewAzure(config.BackendAzureConfig{AccountName: "acct", AccountKey: testAzureKey}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := a.blobURL("cont", "dir/a b", url.Values{"restype": {"container"}, "comp": {"list"}})
+	req, _ := http.NewRequest(http.MethodGet, u.String(), nil)
+	req.Header.Set("x-ms-date", "Mon, 02 Jan 2006 15:04:05 GMT")
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	req.Header["x-ms-meta-foo"] = []string{" bar "}
+	a.sign(req)
+
+	stringToSign := "GET\n\n\n\n\n\n\n\n\n\n\n\n" +
+		"x-ms-date:Mon, 02 Jan 2006 15:04:05 GMT\nx-ms-meta-foo:bar\nx-ms-version:2021-08-06\n" +
+		"/acct/cont/dir/a%20b\ncomp:list\nrestype:container"
+	mac := hmac.New(sha256.New, []byte("azure-test-account-key"))
+	mac.Write([]byte(stringToSign))
+	want := "SharedKey acct:" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %s, want %s", got, want)
+	}
+}
+
+func TestAzureMetaName(t *testing.T) {
+	for _, name := range []string{"encryption-key", "x_y", "1st", "MiXed", "a.b c"} {
+		enc := azureMetaName(strings.ToLower(name))
+		for _, c := range enc {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_') {
+				t.Errorf("azureMetaName(%q) = %q is not an identifier", name, enc)
+			}
+		}
+		if enc[0] >= '0' && enc[0] <= '9' {
+			t.Errorf("azureMetaName(%q) = %q starts with a digit", name, enc)
+		}
+		if dec := azureMetaNameDecode(enc); dec != strings.ToLower(name) {
+			t.Errorf("round trip of %q = %q", name, dec)
+		}
+	}
+}
+
+// fakeAzure serves the subset of the Blob service the backend uses and
+// rejects requests whose Shared Key signature does not verify.
+type fakeAzure struct {
+	signer *Azure
+	mu     sync.Mutex
+	blobs  map[string]fakeBlob
+	blocks map[string][]byte
+}
+
+type fakeBlob struct {
+	data []byte
+	meta http.Header
+	etag string
+	tags []byte
+}
+
+func (f *fakeAzure) fail(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("x-ms-error-code", code)
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func (f *fakeAzure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	check := r.Clone(context.Background())
+	check.Header.Del("Authorization")
+	check.ContentLength = r.ContentLength
+	f.signer.sign(check)
+	if check.Header.Get("Authorization") != r.Header.Get("Authorization") {
+		f.fail(w, http.StatusForbidden, "AuthenticationFailed")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q := r.URL.Query()
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	container, _, _ := strings.Cut(name, "/")
+	if container != "bucket" {
+		f.fail(w, http.StatusNotFound, "ContainerNotFound")
+		return
+	}
+	switch {
+	case q.Get("comp") == "list":
+		var b strings.Builder
+		b.WriteString("<EnumerationResults><Blobs>")
+		for n, blob := range f.blobs {
+			if strings.HasPrefix(n, "bucket/"+q.Get("prefix")) {
+				fmt.Fprintf(&b, "<Blob><Name>%s</Name><Properties><Last-Modified>Mon, 02 Jan 2006 15:04:05 GMT</Last-Modified><Etag>%s</Etag><Content-Length>%d</Content-Length></Properties></Blob>",
+					strings.TrimPrefix(n, "bucket/"), strings.Trim(blob.etag, `"`), len(blob.data))
+			}
+		}
+		b.WriteString("</Blobs><NextMarker/></EnumerationResults>")
+		io.WriteString(w, b.String())
+	case q.Get("comp") == "tags":
+		b, ok := f.blobs[name]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		if r.Method == http.MethodPut {
+			b.tags, _ = io.ReadAll(r.Body)
+			f.blobs[name] = b
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if b.tags == nil {
+			io.WriteString(w, "<Tags><TagSet/></Tags>")
+			return
+		}
+		w.Write(b.tags)
+	case r.Method == http.MethodPut && q.Get("comp") == "block":
+		data, _ := io.ReadAll(r.Body)
+		f.blocks[name+"/"+q.Get("blockid")] = data
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && q.Get("comp") == "blocklist":
+		var list struct {
+			Latest []string `xml:"Latest"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&list); err != nil {
+			f.fail(w, http.StatusBadRequest, "InvalidXmlDocument")
+			return
+		}
+		var data []byte
+		for _, id := range list.Latest {
+			block, ok := f.blocks[name+"/"+id]
+			if !ok {
+				f.fail(w, http.StatusBadRequest, "InvalidBlockList")
+				return
+			}
+			data = append(data, block...)
+		}
+		f.store(w, r, name, data)
+	case r.Method == http.MethodPut:
+		if r.Header.Get("x-ms-blob-type") != "BlockBlob" {
+			f.fail(w, http.StatusBadRequest, "MissingRequiredHeader")
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		f.store(w, r, name, data)
+	case r.Method == http.MethodDelete:
+		if _, ok := f.blobs[name]; !ok {
+			f.fail(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		delete(f.blobs, name)
+		w.WriteHeader(http.StatusAccepted)
+	default: // GET, HEAD
+		b, ok := f.blobs[name]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		for k, v := range b.meta {
+			w.Header()[k] = v
+		}
+		w.Header().Set("ETag", b.etag)
+		data := b.data
+		if rng := r.Header.Get("x-ms-range"); rng != "" {
+			var start, end int
+			fmt.Sscanf(rng, "bytes=%d-%d", &start, &end)
+			data = data[start : end+1]
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(b.data)))
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	}
+}
+
+func (f *fakeAzure) store(w http.ResponseWriter, r *http.Request, name string, data []byte) {
+	existing, exists := f.blobs[name]
+	if (r.Header.Get("If-None-Match") == "*" && exists) ||
+		(r.Header.Get("If-Match") != "" && (!exists || r.Header.Get("If-Match") != existing.etag)) {
+		f.fail(w, http.StatusPreconditionFailed, "ConditionNotMet")
+		return
+	}
+	meta := http.Header{}
+	for k, v := range r.Header {
+		if strings.HasPrefix(strings.ToLower(k), azureMetaPrefix) {
+			meta[k] = v
+		}
+	}
+	etag := fmt.Sprintf(`"0x%X"`, len(f.blobs)+len(data)*7919)
+	f.blobs[name] = fakeBlob{data: data, meta: meta, etag: etag}
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func newTestAzure(t *testing.T) *Azure {
+	t.Helper()
+	cfg := config.BackendAzureConfig{AccountName: "acct", AccountKey: testAzureKey}
+	signer, err := NewAzure(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(&fakeAzure{signer: signer, blobs: map[string]fakeBlob{}, blocks: map[string][]byte{}})
+	t.Cleanup(srv.Close)
+	cfg.Endpoint = srv.URL
+	a, err := NewAzure(cfg, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestAzure_Objects(t *testing.T) {
+	a := newTestAzure(t)
+	ctx := context.Background()
+	n := int64(len("hello azure"))
+	if err := a.PutObject(ctx, "bucket", "dir/obj", strings.NewReader("hello azure"), map[string]string{"x-amz-meta-encryption-key": "k1"}, &n, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	body, meta := get(t, a, "dir/obj", nil)
+	if body != "hello azure" || meta["x-amz-meta-encryption-key"] != "k1" {
+		t.Errorf("GetObject = %q, %v", body, meta)
+	}
+	rng := "bytes=-5"
+	if body, meta := get(t, a, "dir/obj", &rng); body != "azure" || meta["Content-Range"] != "bytes 6-10/11" {
+		t.Errorf("suffix range = %q, %v", body, meta)
+	}
+
+	// Unknown length goes through Put Block / Put Block List.
+	if err := a.PutObject(ctx, "bucket", "streamed", strings.NewReader("abc"), nil, nil, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := get(t, a, "streamed", nil); body != "abc" {
+		t.Errorf("streamed body = %q", body)
+	}
+
+	cond := s3.WithWriteConditions(ctx, s3.WriteConditions{IfNoneMatch: "*"})
+	if err := a.PutObject(cond, "bucket", "streamed", strings.NewReader("x"), nil, nil, "", nil); !strings.Contains(fmt.Sprint(err), "ConditionNotMet") {
+		t.Errorf("conditional overwrite = %v, want PreconditionFailed", err)
+	}
+
+	res, err := a.ListObjects(ctx, "bucket", "dir/", s3.ListOptions{})
+	if err != nil || len(res.Objects) != 1 || res.Objects[0].Key != "dir/obj" || res.Objects[0].Size != 11 {
+		t.Errorf("ListObjects = %+v, %v", res, err)
+	}
+	if !strings.HasPrefix(res.Objects[0].ETag, `"`) {
+		t.Errorf("listed ETag %s should be quoted like HEAD's", res.Objects[0].ETag)
+	}
+
+	if err := a.DeleteObject(ctx, "bucket", "dir/obj", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.DeleteObject(ctx, "bucket", "dir/obj", nil); err != nil {
+		t.Errorf("deleting a missing blob: %v", err)
+	}
+	if _, err := a.HeadObject(ctx, "bucket", "dir/obj", nil); !errors.Is(err, s3.ErrNotFound) {
+		t.Errorf("HeadObject after delete = %v, want ErrNotFound", err)
+	}
+	if _, err := a.HeadObject(ctx, "other", "k", nil); !errors.Is(err, s3.ErrNotFound) {
+		t.Errorf("missing container = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAzure_Tagging(t *testing.T) {
+	a := newTestAzure(t)
+	ctx := context.Background()
+	n := int64(1)
+	if err := a.PutObject(ctx, "bucket", "obj", strings.NewReader("x"), nil, &n, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []s3.Tag{{Key: "project", Value: "apollo"}, {Key: "stage", Value: "prod"}}
+	if err := a.PutObjectTagging(ctx, "bucket", "obj", nil, want); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := a.GetObjectTagging(ctx, "bucket", "obj", nil); err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("GetObjectTagging = %v, %v, want %v", got, err, want)
+	}
+	if err := a.DeleteObjectTagging(ctx, "bucket", "obj", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := a.GetObjectTagging(ctx, "bucket", "obj", nil); err != nil || len(got) != 0 {
+		t.Errorf("tags after delete = %v, %v", got, err)
+	}
+	if err := a.PutObjectTagging(ctx, "bucket", "missing", nil, want); !errors.Is(err, s3.ErrNotFound) {
+		t.Errorf("tagging a missing blob = %v, want ErrNotFound", err)
+	}
+	vid := "v1"
+	if _, err := a.GetObjectTagging(ctx, "bucket", "obj", &vid); err == nil {
+		t.Error("GetObjectTagging with a version ID should fail")
+	}
+}
+
+func TestAzure_BadKeyIsAuthFailure(t *testing.T) {
+	a := newTestAzure(t)
+	a.key = []byte("wrong")
+	_, err := a.HeadObject(context.Background(), "bucket", "k", nil)
+	if err == nil || !strings.Contains(err.Error(), "AuthenticationFailed") {
+		t.Errorf("HeadObject with a wrong key = %v", err)
+	}
+}
+
+func TestAzure_Multipart(t *testing.T) {
+	a := newTestAzure(t)
+	ctx := context.Background()
+	id, err := a.CreateMultipartUpload(ctx, "bucket", "mpu", map[string]string{"x-amz-meta-iv": "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e1, err := a.UploadPart(ctx, "bucket", "mpu", id, 1, strings.NewReader("first-"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e2, err := a.UploadPart(ctx, "bucket", "mpu", id, 2, strings.NewReader("second"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts, err := a.ListParts(ctx, "bucket", "mpu", id)
+	if err != nil || len(parts) != 2 || parts[1].ETag != e2 {
+		t.Errorf("ListParts = %+v, %v", parts, err)
+	}
+	if _, err := a.CompleteMultipartUpload(ctx, "bucket", "mpu", id, []s3.CompletedPart{{PartNumber: 1, ETag: e1}, {PartNumber: 2, ETag: e2}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	body, meta := get(t, a, "mpu", nil)
+	if body != "first-second" || meta["x-amz-meta-iv"] != "abc" {
+		t.Errorf("completed blob = %q, %v", body, meta)
+	}
+	if _, err := a.UploadPart(ctx, "bucket", "mpu", id, 3, strings.NewReader("late"), nil); !errors.Is(err, s3.ErrNotFound) {
+		t.Errorf("UploadPart after complete = %v, want NoSuchUpload", err)
+	}
+
+	if _, _, err := a.CopyObject(ctx, "bucket", "copy", "bucket", "mpu", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if body, meta := get(t, a, "copy", nil); body != "first-second" || meta["x-amz-meta-iv"] != "abc" {
+		t.Errorf("copy = %q, %v", body, meta)
+	}
+}
@@ -0,0 +1,812 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// Filesystem stores objects under a local directory. Each bucket is a
+// directory below the root, which must exist; object keys are split on "/"
+// into escaped path segments, so listing a prefix only walks the matching
+// subtree. An object is one file holding a JSON header line (ETag, size,
+// metadata) followed by the body, and is replaced with a rename so readers
+// never see a half-written object. Multipart uploads are staged under
+// <root>/.uploads.
+//
+// Versioning and Object Lock are not available.
+type Filesystem struct {
+	root string
+	// mu serialises conditional writes: the precondition check and the
+	// rename must not interleave with another writer of the same key.
+	mu sync.Mutex
+}
+
+var _ Backend = (*Filesystem)(nil)
+
+const (
+	fsObjectSuffix = "%o"
+	fsTempSuffix   = "%t"
+	fsUploadsDir   = ".uploads"
+	fsMaxListKeys  = 1000
+)
+
+// fsBucketName accepts S3 bucket names. A leading letter or digit keeps
+// buckets apart from the .uploads directory.
+var fsBucketName = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// fsHeader is the first line of an object or part file.
+type fsHeader struct {
+	ETag     string            `json:"etag"`
+	Size     int64             `json:"size"`
+	Modified time.Time         `json:"modified"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// fsUpload describes a pending multipart upload.
+type fsUpload struct {
+	Bucket   string            `json:"bucket"`
+	Key      string            `json:"key"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// NewFilesystem returns a backend rooted at root, creating the directory if
+// needed.
+func NewFilesystem(root string) (*Filesystem, error) {
+	if root == "" {
+		return nil, fmt.Errorf("filesystem backend requires a root directory")
+	}
+	if err := os.MkdirAll(filepath.Join(root, fsUploadsDir), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create filesystem backend root: %w", err)
+	}
+	return &Filesystem{root: root}, nil
+}
+
+// escapeSegment makes one key segment safe as a file name: only
+// [A-Za-z0-9_.-] pass through, a leading dot is escaped so "." and ".."
+// cannot occur, and an empty segment becomes "%".
+func escapeSegment(s string) string {
+	if s == "" {
+		return "%"
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.' && i == 0, !isSafeByte(c):
+			fmt.Fprintf(&b, "%%%02X", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isSafeByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.'
+}
+
+func unescapeSegment(s string) (string, bool) {
+	if s == "%" {
+		return "", true
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return "", false
+		}
+		v, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return "", false
+		}
+		b.WriteByte(v[0])
+		i += 2
+	}
+	return b.String(), true
+}
+
+func (f *Filesystem) bucketDir(bucket string) (string, error) {
+	if !fsBucketName.MatchString(bucket) || strings.Contains(bucket, "..") {
+		return "", s3.NewAPIError("InvalidBucketName", "invalid bucket name "+bucket)
+	}
+	dir := filepath.Join(f.root, bucket)
+	if st, err := os.Stat(dir); err != nil || !st.IsDir() {
+		return "", s3.NewAPIError("NoSuchBucket", "bucket does not exist: "+bucket)
+	}
+	return dir, nil
+}
+
+// objectPath returns the file holding bucket/key.
+func (f *Filesystem) objectPath(bucket, key string) (string, error) {
+	dir, err := f.bucketDir(bucket)
+	if err != nil {
+		return "", err
+	}
+	if key == "" {
+		return "", s3.NewAPIError("InvalidArgument", "empty object key")
+	}
+	segs := strings.Split(key, "/")
+	parts := make([]string, len(segs))
+	for i, s := range segs {
+		parts[i] = escapeSegment(s)
+		if len(parts[i])+len(fsObjectSuffix)+len(fsTempSuffix)+16 > 255 {
+			return "", s3.NewAPIError("InvalidArgument", "key segment too long for the filesystem backend")
+		}
+	}
+	parts[len(parts)-1] += fsObjectSuffix
+	return filepath.Join(append([]string{dir}, parts...)...), nil
+}
+
+func checkVersion(versionID *string) error {
+	if versionID != nil && *versionID != "" && *versionID != "null" {
+		return s3.NewAPIError("NoSuchVersion", "versioning is not supported by this backend")
+	}
+	return nil
+}
+
+func notFound(bucket, key string) error {
+	return s3.NewAPIError("NoSuchKey", fmt.Sprintf("object not found: %s/%s", bucket, key))
+}
+
+// readHeader opens path and returns its header and the file positioned at
+// the body.
+func readHeader(path string) (*fsHeader, *os.File, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	br := bufio.NewReader(file)
+	line, err := br.ReadBytes('\n')
+	if err != nil {
+		file.Close()
+		return nil, nil, 0, fmt.Errorf("corrupt object file %s: %w", path, err)
+	}
+	var h fsHeader
+	if err := json.Unmarshal(line, &h); err != nil {
+		file.Close()
+		return nil, nil, 0, fmt.Errorf("corrupt object file %s: %w", path, err)
+	}
+	offset := int64(len(line))
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, nil, 0, err
+	}
+	return &h, file, offset, nil
+}
+
+func (f *Filesystem) head(bucket, key string) (*fsHeader, error) {
+	path, err := f.objectPath(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	h, file, _, err := readHeader(path)
+	if err != nil {
+		if isNotExist(err) {
+			return nil, notFound(bucket, key)
+		}
+		return nil, err
+	}
+	file.Close()
+	return h, nil
+}
+
+// isNotExist also covers a key whose parent segment is an object rather than
+// a directory; for the caller that object simply does not exist.
+func isNotExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR)
+}
+
+func headerMetadata(h *fsHeader) map[string]string {
+	meta := make(map[string]string, len(h.Metadata)+3)
+	for k, v := range h.Metadata {
+		meta[k] = v
+	}
+	meta["ETag"] = h.ETag
+	meta["Content-Length"] = fmt.Sprintf("%d", h.Size)
+	meta["Last-Modified"] = h.Modified.UTC().Format(httpTimeFormat)
+	return meta
+}
+
+// writeFile writes header and body to a temporary file next to path and
+// returns its name; the caller renames it into place.
+func writeFile(path string, h fsHeader, body io.Reader) (string, *fsHeader, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "*"+fsTempSuffix)
+	if err != nil {
+		return "", nil, err
+	}
+	defer tmp.Close()
+
+	// The header goes first but the ETag and size are only known after the
+	// body, so the body is staged in a second file.
+	stage, err := os.CreateTemp(filepath.Dir(path), "*"+fsTempSuffix)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", nil, err
+	}
+	defer func() {
+		stage.Close()
+		os.Remove(stage.Name())
+	}()
+	hash := md5.New()
+	n, err := io.Copy(io.MultiWriter(stage, hash), body)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", nil, err
+	}
+	if h.ETag == "" {
+		h.ETag = `"` + hex.EncodeToString(hash.Sum(nil)) + `"`
+	}
+	h.Size = n
+	h.Modified = time.Now().UTC()
+	line, err := json.Marshal(h)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", nil, err
+	}
+	if _, err := stage.Seek(0, io.SeekStart); err == nil {
+		_, err = tmp.Write(append(line, '\n'))
+		if err == nil {
+			_, err = io.Copy(tmp, stage)
+		}
+		if err == nil {
+			err = tmp.Sync()
+		}
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", nil, err
+	}
+	return tmp.Name(), &h, nil
+}
+
+// commit renames tmp to path for bucket/key, honouring any write
+// conditions in ctx.
+func (f *Filesystem) commit(ctx context.Context, bucket, key, tmp, path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if conds, ok := s3.WriteConditionsFromContext(ctx); ok {
+		current, err := f.head(bucket, key)
+		exists := err == nil
+		if err != nil && !errors.Is(err, s3.ErrNotFound) {
+			os.Remove(tmp)
+			return err
+		}
+		failed := (conds.IfNoneMatch == "*" && exists) ||
+			(conds.IfMatch != "" && (!exists || strings.Trim(conds.IfMatch, `"`) != strings.Trim(current.ETag, `"`)))
+		if failed {
+			os.Remove(tmp)
+			return s3.NewAPIError("PreconditionFailed", "at least one of the preconditions did not hold")
+		}
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// PutObject stores an object.
+func (f *Filesystem) PutObject(ctx context.Context, bucket, key string, reader io.Reader, metadata map[string]string, contentLength *int64, tags string, lock *s3.ObjectLockInput) error {
+	if lock != nil && (lock.Mode != "" || lock.LegalHoldStatus != "") {
+		return notSupported("filesystem", "object lock")
+	}
+	path, err := f.objectPath(bucket, key)
+	if err != nil {
+		return err
+	}
+	tmp, _, err := writeFile(path, fsHeader{Metadata: normalizeMetadata(metadata)}, reader)
+	if err != nil {
+		return fmt.Errorf("failed to put object %s/%s: %w", bucket, key, err)
+	}
+	return f.commit(ctx, bucket, key, tmp, path)
+}
+
+// GetObject opens an object, or the byte range rangeHeader of it.
+func (f *Filesystem) GetObject(ctx context.Context, bucket, key string, versionID *string, rangeHeader *string) (io.ReadCloser, map[string]string, error) {
+	if err := checkVersion(versionID); err != nil {
+		return nil, nil, err
+	}
+	path, err := f.objectPath(bucket, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	h, file, offset, err := readHeader(path)
+	if err != nil {
+		if isNotExist(err) {
+			return nil, nil, notFound(bucket, key)
+		}
+		return nil, nil, err
+	}
+	meta := headerMetadata(h)
+	if rangeHeader == nil || *rangeHeader == "" {
+		return file, meta, nil
+	}
+	start, end, err := parseRange(*rangeHeader, h.Size)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if _, err := file.Seek(offset+start, io.SeekStart); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	meta["Content-Length"] = fmt.Sprintf("%d", end-start+1)
+	meta["Content-Range"] = fmt.Sprintf("bytes %d-%d/%d", start, end, h.Size)
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(file, end-start+1), file}, meta, nil
+}
+
+// parseRange resolves rangeHeader against an object of size bytes with
+// crypto.ParseHTTPRangeHeader. Any range it cannot serve is InvalidRange.
+func parseRange(rangeHeader string, size int64) (int64, int64, error) {
+	start, end, err := crypto.ParseHTTPRangeHeader(rangeHeader, size)
+	if err != nil || size == 0 {
+		return 0, 0, s3.NewAPIError("InvalidRange", "the requested range is not satisfiable")
+	}
+	return start, end, nil
+}
+
+// HeadObject returns an object's metadata.
+func (f *Filesystem) HeadObject(ctx context.Context, bucket, key string, versionID *string) (map[string]string, error) {
+	if err := checkVersion(versionID); err != nil {
+		return nil, err
+	}
+	h, err := f.head(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	return headerMetadata(h), nil
+}
+
+// DeleteObject removes an object. Deleting a missing object succeeds, as in
+// S3.
+func (f *Filesystem) DeleteObject(ctx context.Context, bucket, key string, versionID *string) error {
+	if err := checkVersion(versionID); err != nil {
+		return err
+	}
+	path, err := f.objectPath(bucket, key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !isNotExist(err) {
+		return fmt.Errorf("failed to delete object %s/%s: %w", bucket, key, err)
+	}
+	// Drop directories the delete left empty; Remove fails on the first
+	// non-empty one.
+	bucketDir, _ := f.bucketDir(bucket)
+	for dir := filepath.Dir(path); dir != bucketDir && strings.HasPrefix(dir, bucketDir); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// ListObjects lists keys under prefix in lexical order.
+func (f *Filesystem) ListObjects(ctx context.Context, bucket, prefix string, opts s3.ListOptions) (s3.ListResult, error) {
+	dir, err := f.bucketDir(bucket)
+	if err != nil {
+		return s3.ListResult{}, err
+	}
+	// Only the subtree of the prefix's directory part can match.
+	start, keyBase := dir, ""
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		for _, s := range strings.Split(prefix[:i], "/") {
+			start = filepath.Join(start, escapeSegment(s))
+		}
+		keyBase = prefix[:i+1]
+	}
+	type entry struct {
+		key  string
+		path string
+	}
+	var entries []entry
+	var walk func(dir, keyPrefix string) error
+	walk = func(dir, keyPrefix string) error {
+		items, err := os.ReadDir(dir)
+		if err != nil {
+			if isNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		for _, it := range items {
+			name := it.Name()
+			if it.IsDir() {
+				seg, ok := unescapeSegment(name)
+				if !ok {
+					continue
+				}
+				sub := keyPrefix + seg + "/"
+				if strings.HasPrefix(sub, prefix) || strings.HasPrefix(prefix, sub) {
+					if err := walk(filepath.Join(dir, name), sub); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			enc, ok := strings.CutSuffix(name, fsObjectSuffix)
+			if !ok {
+				continue
+			}
+			seg, ok := unescapeSegment(enc)
+			if !ok {
+				continue
+			}
+			if key := keyPrefix + seg; strings.HasPrefix(key, prefix) {
+				entries = append(entries, entry{key, filepath.Join(dir, name)})
+			}
+		}
+		return nil
+	}
+	if err := walk(start, keyBase); err != nil {
+		return s3.ListResult{}, fmt.Errorf("failed to list objects in bucket %s: %w", bucket, err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	maxKeys := int(opts.MaxKeys)
+	if maxKeys <= 0 || maxKeys > fsMaxListKeys {
+		maxKeys = fsMaxListKeys
+	}
+	result := s3.ListResult{Objects: []s3.ObjectInfo{}, CommonPrefixes: []string{}}
+	seenPrefix := map[string]bool{}
+	last := ""
+	for _, e := range entries {
+		if opts.ContinuationToken != "" && e.key <= opts.ContinuationToken {
+			continue
+		}
+		if opts.Delimiter != "" {
+			if i := strings.Index(e.key[len(prefix):], opts.Delimiter); i >= 0 {
+				cp := e.key[:len(prefix)+i+len(opts.Delimiter)]
+				if seenPrefix[cp] {
+					continue
+				}
+				if len(result.Objects)+len(result.CommonPrefixes) == maxKeys {
+					result.IsTruncated = true
+					break
+				}
+				seenPrefix[cp] = true
+				result.CommonPrefixes = append(result.CommonPrefixes, cp)
+				// Resume after every key sharing this prefix.
+				last = cp + "\xff"
+				continue
+			}
+		}
+		if len(result.Objects)+len(result.CommonPrefixes) == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+		h, file, _, err := readHeader(e.path)
+		if err != nil {
+			continue // removed while listing
+		}
+		file.Close()
+		result.Objects = append(result.Objects, s3.ObjectInfo{
+			Key:          e.key,
+			Size:         h.Size,
+			LastModified: h.Modified.UTC().Format(listTimeFormat),
+			ETag:         h.ETag,
+		})
+		last = e.key
+	}
+	if result.IsTruncated {
+		result.NextContinuationToken = last
+	}
+	return result, nil
+}
+
+func (f *Filesystem) uploadDir(uploadID string) (string, error) {
+	if uploadID == "" || strings.Trim(uploadID, "0123456789abcdef") != "" {
+		return "", s3.NewAPIError("NoSuchUpload", "upload not found")
+	}
+	return filepath.Join(f.root, fsUploadsDir, uploadID), nil
+}
+
+func (f *Filesystem) loadUpload(bucket, key, uploadID string) (string, *fsUpload, error) {
+	dir, err := f.uploadDir(uploadID)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "upload.json"))
+	if err != nil {
+		return "", nil, s3.NewAPIError("NoSuchUpload", "upload not found: "+uploadID)
+	}
+	var up fsUpload
+	if err := json.Unmarshal(data, &up); err != nil {
+		return "", nil, fmt.Errorf("corrupt upload %s: %w", uploadID, err)
+	}
+	if up.Bucket != bucket || up.Key != key {
+		return "", nil, s3.NewAPIError("NoSuchUpload", "upload not found: "+uploadID)
+	}
+	return dir, &up, nil
+}
+
+func partPath(dir string, partNumber int32) string {
+	return filepath.Join(dir, fmt.Sprintf("part-%05d", partNumber))
+}
+
+// CreateMultipartUpload starts an upload staged under the root.
+func (f *Filesystem) CreateMultipartUpload(ctx context.Context, bucket, key string, metadata map[string]string) (string, error) {
+	if _, err := f.objectPath(bucket, key); err != nil {
+		return "", err
+	}
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	uploadID := hex.EncodeToString(id[:])
+	dir := filepath.Join(f.root, fsUploadsDir, uploadID)
+	data, err := json.Marshal(fsUpload{Bucket: bucket, Key: key, Metadata: normalizeMetadata(metadata)})
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create multipart upload %s/%s: %w", bucket, key, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "upload.json"), data, 0o600); err != nil {
+		return "", fmt.Errorf("failed to create multipart upload %s/%s: %w", bucket, key, err)
+	}
+	return uploadID, nil
+}
+
+// UploadPart stores one part, replacing an earlier upload of the same
+// number.
+func (f *Filesystem) UploadPart(ctx context.Context, bucket, key, uploadID string, partNumber int32, reader io.Reader, contentLength *int64) (string, error) {
+	if partNumber < 1 || partNumber > 10000 {
+		return "", s3.NewAPIError("InvalidArgument", "part number must be between 1 and 10000")
+	}
+	dir, _, err := f.loadUpload(bucket, key, uploadID)
+	if err != nil {
+		return "", err
+	}
+	path := partPath(dir, partNumber)
+	tmp, h, err := writeFile(path, fsHeader{}, reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload part %d for %s/%s: %w", partNumber, bucket, key, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return h.ETag, nil
+}
+
+// CompleteMultipartUpload joins the listed parts into the object.
+func (f *Filesystem) CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []s3.CompletedPart, lock *s3.ObjectLockInput) (string, error) {
+	if lock != nil && (lock.Mode != "" || lock.LegalHoldStatus != "") {
+		return "", notSupported("filesystem", "object lock")
+	}
+	dir, up, err := f.loadUpload(bucket, key, uploadID)
+	if err != nil {
+		return "", err
+	}
+	if len(parts) == 0 {
+		return "", s3.NewAPIError("MalformedXML", "no parts given")
+	}
+	files := make([]io.Reader, 0, len(parts))
+	digests := md5.New()
+	for i, p := range parts {
+		if i > 0 && p.PartNumber <= parts[i-1].PartNumber {
+			return "", s3.NewAPIError("InvalidPartOrder", "parts must be in ascending order")
+		}
+		h, file, _, err := readHeader(partPath(dir, p.PartNumber))
+		if err != nil || strings.Trim(h.ETag, `"`) != strings.Trim(p.ETag, `"`) {
+			if file != nil {
+				file.Close()
+			}
+			return "", s3.NewAPIError("InvalidPart", fmt.Sprintf("part %d not found or ETag mismatch", p.PartNumber))
+		}
+		defer file.Close()
+		sum, _ := hex.DecodeString(strings.Trim(h.ETag, `"`))
+		digests.Write(sum)
+		files = append(files, file)
+	}
+	etag := fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(digests.Sum(nil)), len(parts))
+
+	path, err := f.objectPath(bucket, key)
+	if err != nil {
+		return "", err
+	}
+	tmp, _, err := writeFile(path, fsHeader{ETag: etag, Metadata: up.Metadata}, io.MultiReader(files...))
+	if err != nil {
+		return "", fmt.Errorf("failed to complete multipart upload %s/%s: %w", bucket, key, err)
+	}
+	if err := f.commit(ctx, bucket, key, tmp, path); err != nil {
+		return "", err
+	}
+	os.RemoveAll(dir)
+	return etag, nil
+}
+
+// AbortMultipartUpload discards an upload and its parts.
+func (f *Filesystem) AbortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error {
+	dir, _, err := f.loadUpload(bucket, key, uploadID)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// ListParts lists the parts uploaded so far.
+func (f *Filesystem) ListParts(ctx context.Context, bucket, key, uploadID string) ([]s3.PartInfo, error) {
+	dir, _, err := f.loadUpload(bucket, key, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	parts := []s3.PartInfo{}
+	for _, it := range items {
+		var n int32
+		if _, err := fmt.Sscanf(it.Name(), "part-%05d", &n); err != nil || strings.HasSuffix(it.Name(), fsTempSuffix) {
+			continue
+		}
+		h, file, _, err := readHeader(filepath.Join(dir, it.Name()))
+		if err != nil {
+			continue
+		}
+		file.Close()
+		parts = append(parts, s3.PartInfo{
+			PartNumber:   n,
+			ETag:         h.ETag,
+			Size:         h.Size,
+			LastModified: h.Modified.UTC().Format(listTimeFormat),
+		})
+	}
+	return parts, nil
+}
+
+// CopyObject copies an object. With metadata the copy carries it instead of
+// the source's.
+func (f *Filesystem) CopyObject(ctx context.Context, dstBucket, dstKey string, srcBucket, srcKey string, srcVersionID *string, metadata map[string]string, lock *s3.ObjectLockInput) (string, map[string]string, error) {
+	if lock != nil && (lock.Mode != "" || lock.LegalHoldStatus != "") {
+		return "", nil, notSupported("filesystem", "object lock")
+	}
+	body, srcMeta, err := f.GetObject(ctx, srcBucket, srcKey, srcVersionID, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	defer body.Close()
+	newMeta := map[string]string{}
+	if metadata != nil {
+		newMeta = normalizeMetadata(metadata)
+	} else {
+		for k, v := range srcMeta {
+			if strings.HasPrefix(k, metaPrefix) {
+				newMeta[k] = v
+			}
+		}
+	}
+	path, err := f.objectPath(dstBucket, dstKey)
+	if err != nil {
+		return "", nil, err
+	}
+	tmp, h, err := writeFile(path, fsHeader{Metadata: newMeta}, body)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to copy object from %s/%s to %s/%s: %w", srcBucket, srcKey, dstBucket, dstKey, err)
+	}
+	if err := f.commit(ctx, dstBucket, dstKey, tmp, path); err != nil {
+		return "", nil, err
+	}
+	etag := strings.Trim(h.ETag, `"`)
+	return etag, map[string]string{"ETag": etag, "Last-Modified": h.Modified.Format(httpTimeFormat)}, nil
+}
+
+// UploadPartCopy stores a byte range of an existing object as a part.
+func (f *Filesystem) UploadPartCopy(ctx context.Context, dstBucket, dstKey, uploadID string, partNumber int32, srcBucket, srcKey string, srcVersionID *string, srcRange *s3.CopyPartRange) (*s3.CopyPartResult, error) {
+	var rangeHeader *string
+	if srcRange != nil {
+		r := fmt.Sprintf("bytes=%d-%d", srcRange.First, srcRange.Last)
+		rangeHeader = &r
+	}
+	body, _, err := f.GetObject(ctx, srcBucket, srcKey, srcVersionID, rangeHeader)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	etag, err := f.UploadPart(ctx, dstBucket, dstKey, uploadID, partNumber, body, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &s3.CopyPartResult{ETag: etag, LastModified: time.Now().UTC()}, nil
+}
+
+// DeleteObjects deletes each key in turn.
+func (f *Filesystem) DeleteObjects(ctx context.Context, bucket string, keys []s3.ObjectIdentifier) ([]s3.DeletedObject, []s3.ErrorObject, error) {
+	return deleteEach(ctx, f, bucket, keys)
+}
+
+// deleteEach implements DeleteObjects for stores without a batch call.
+func deleteEach(ctx context.Context, b Backend, bucket string, keys []s3.ObjectIdentifier) ([]s3.DeletedObject, []s3.ErrorObject, error) {
+	deleted := make([]s3.DeletedObject, 0, len(keys))
+	var failed []s3.ErrorObject
+	for _, k := range keys {
+		var versionID *string
+		if k.VersionID != "" {
+			versionID = &k.VersionID
+		}
+		if err := b.DeleteObject(ctx, bucket, k.Key, versionID); err != nil {
+			failed = append(failed, s3.ErrorObject{Key: k.Key, Code: "InternalError", Message: err.Error()})
+			continue
+		}
+		deleted = append(deleted, s3.DeletedObject{Key: k.Key, VersionID: k.VersionID})
+	}
+	return deleted, failed, nil
+}
+
+// PutObjectRetention is not supported.
+func (f *Filesystem) PutObjectRetention(ctx context.Context, bucket, key string, versionID *string, retention *s3.RetentionConfig) error {
+	return notSupported("filesystem", "object lock")
+}
+
+// GetObjectRetention is not supported.
+func (f *Filesystem) GetObjectRetention(ctx context.Context, bucket, key string, versionID *string) (*s3.RetentionConfig, error) {
+	return nil, notSupported("filesystem", "object lock")
+}
+
+// PutObjectLegalHold is not supported.
+func (f *Filesystem) PutObjectLegalHold(ctx context.Context, bucket, key string, versionID *string, status string) error {
+	return notSupported("filesystem", "object lock")
+}
+
+// GetObjectLegalHold is not supported.
+func (f *Filesystem) GetObjectLegalHold(ctx context.Context, bucket, key string, versionID *string) (string, error) {
+	return "", notSupported("filesystem", "object lock")
+}
+
+// PutObjectLockConfiguration is not supported.
+func (f *Filesystem) PutObjectLockConfiguration(ctx context.Context, bucket string, config *s3.ObjectLockConfiguration) error {
+	return notSupported("filesystem", "object lock")
+}
+
+// GetObjectLockConfiguration reports that no bucket has Object Lock.
+func (f *Filesystem) GetObjectLockConfiguration(ctx context.Context, bucket string) (*s3.ObjectLockConfiguration, error) {
+	if _, err := f.bucketDir(bucket); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// PutObjectTagging is not supported.
+func (f *Filesystem) PutObjectTagging(ctx context.Context, bucket, key string, versionID *string, tags []s3.Tag) error {
+	return notSupported("filesystem", "object tagging")
+}
+
+// GetObjectTagging is not supported.
+func (f *Filesystem) GetObjectTagging(ctx context.Context, bucket, key string, versionID *string) ([]s3.Tag, error) {
+	return nil, notSupported("filesystem", "object tagging")
+}
+
+// DeleteObjectTagging is not supported.
+func (f *Filesystem) DeleteObjectTagging(ctx context.Context, bucket, key string, versionID *string) error {
+	return notSupported("filesystem", "object tagging")
+}
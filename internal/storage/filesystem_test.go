@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+func newTestFilesystem(t *testing.T) *Filesystem {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "bucket"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	fs, err := NewFilesystem(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+func put(t *testing.T, b Backend, key, body string, meta map[string]string) {
+	t.Helper()
+	if err := b.PutObject(context.Background(), "bucket", key, strings.NewReader(body), meta, nil, "", nil); err != nil {
+		t.Fatalf("PutObject(%s): %v", key, err)
+	}
+}
+
+func get(t *testing.T, b Backend, key string, rangeHeader *string) (string, map[string]string) {
+	t.Helper()
+	r, meta, err := b.GetObject(context.Background(), "bucket", key, nil, rangeHeader)
+	if err != nil {
+		t.Fatalf("GetObject(%s): %v", key, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), meta
+}
+
+func TestFilesystem_PutGetHeadDelete(t *testing.T) {
+	fs := newTestFilesystem(t)
+	ctx := context.Background()
+
+	put(t, fs, "dir/obj.txt", "hello world", map[string]string{"X-Amz-Meta-Enc": "v1", "plain": "p"})
+	body, meta := get(t, fs, "dir/obj.txt", nil)
+	if body != "hello world" {
+		t.Errorf("body = %q", body)
+	}
+	if meta["x-amz-meta-enc"] != "v1" || meta["x-amz-meta-plain"] != "p" {
+		t.Errorf("metadata = %v", meta)
+	}
+	if meta["ETag"] != `"5eb63bbbe01eeed093cb22bb8f5acdc3"` {
+		t.Errorf("ETag = %s, want the quoted MD5", meta["ETag"])
+	}
+
+	rng := "bytes=6-"
+	if body, meta := get(t, fs, "dir/obj.txt", &rng); body != "world" || meta["Content-Range"] != "bytes 6-10/11" {
+		t.Errorf("range body = %q, Content-Range = %q", body, meta["Content-Range"])
+	}
+	rng = "bytes=-3"
+	if body, _ := get(t, fs, "dir/obj.txt", &rng); body != "rld" {
+		t.Errorf("suffix range body = %q", body)
+	}
+	rng = "bytes=6-100"
+	if body, meta := get(t, fs, "dir/obj.txt", &rng); body != "world" || meta["Content-Range"] != "bytes 6-10/11" {
+		t.Errorf("past-end range body = %q, Content-Range = %q", body, meta["Content-Range"])
+	}
+	for _, rng := range []string{"bytes=20-", "bytes=0-1,3-4"} {
+		_, _, err := fs.GetObject(ctx, "bucket", "dir/obj.txt", nil, &rng)
+		var apiErr interface{ ErrorCode() string }
+		if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "InvalidRange" {
+			t.Errorf("range %s: err = %v, want InvalidRange", rng, err)
+		}
+	}
+
+	head, err := fs.HeadObject(ctx, "bucket", "dir/obj.txt", nil)
+	if err != nil || head["Content-Length"] != "11" {
+		t.Errorf("HeadObject = %v, %v", head, err)
+	}
+	v := "3"
+	if _, err := fs.HeadObject(ctx, "bucket", "dir/obj.txt", &v); err == nil {
+		t.Error("versioned HEAD should fail")
+	}
+
+	if err := fs.DeleteObject(ctx, "bucket", "dir/obj.txt", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.HeadObject(ctx, "bucket", "dir/obj.txt", nil); !errors.Is(err, s3.ErrNotFound) {
+		t.Errorf("HeadObject after delete = %v, want ErrNotFound", err)
+	}
+	if err := fs.DeleteObject(ctx, "bucket", "dir/obj.txt", nil); err != nil {
+		t.Errorf("deleting a missing object: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(fs.root, "bucket", "dir")); !os.IsNotExist(err) {
+		t.Error("empty key directory should be removed with its last object")
+	}
+	if _, err := fs.HeadObject(ctx, "missing", "k", nil); !errors.Is(err, s3.ErrNotFound) {
+		t.Errorf("missing bucket error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFilesystem_KeysStayInBucket(t *testing.T) {
+	fs := newTestFilesystem(t)
+	keys := []string{"../escape", "a/../../b", ".", "..", "a//b", "/lead", "trail/", "spaces and ü", "%o", "x%t"}
+	for _, k := range keys {
+		put(t, fs, k, k, nil)
+	}
+	for _, k := range keys {
+		if body, _ := get(t, fs, k, nil); body != k {
+			t.Errorf("GetObject(%q) = %q", k, body)
+		}
+	}
+	entries, err := os.ReadDir(fs.root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() != "bucket" && e.Name() != fsUploadsDir {
+			t.Errorf("object written outside its bucket: %s", e.Name())
+		}
+	}
+	res, err := fs.ListObjects(context.Background(), "bucket", "", s3.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Objects) != len(keys) {
+		t.Errorf("listed %d objects, want %d", len(res.Objects), len(keys))
+	}
+	if _, err := fs.HeadObject(context.Background(), "../bucket", "k", nil); err == nil {
+		t.Error("bucket names with path elements must be rejected")
+	}
+}
+
+func TestFilesystem_ListObjects(t *testing.T) {
+	fs := newTestFilesystem(t)
+	ctx := context.Background()
+	for _, k := range []string{"a/1", "a/2", "a/b/3", "b", "c/4", "ab"} {
+		put(t, fs, k, "x", nil)
+	}
+
+	res, err := fs.ListObjects(ctx, "bucket", "", s3.ListOptions{Delimiter: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, o := range res.Objects {
+		keys = append(keys, o.Key)
+	}
+	if strings.Join(keys, ",") != "ab,b" || strings.Join(res.CommonPrefixes, ",") != "a/,c/" {
+		t.Errorf("objects = %v, prefixes = %v", keys, res.CommonPrefixes)
+	}
+
+	res, err = fs.ListObjects(ctx, "bucket", "a/", s3.ListOptions{Delimiter: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Objects) != 2 || len(res.CommonPrefixes) != 1 || res.CommonPrefixes[0] != "a/b/" {
+		t.Errorf("a/ listing = %+v", res)
+	}
+
+	// Page through everything two keys at a time.
+	var all []string
+	opts := s3.ListOptions{MaxKeys: 2}
+	for {
+		res, err := fs.ListObjects(ctx, "bucket", "", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, o := range res.Objects {
+			all = append(all, o.Key)
+		}
+		if !res.IsTruncated {
+			break
+		}
+		opts.ContinuationToken = res.NextContinuationToken
+	}
+	if got := strings.Join(all, ","); got != "a/1,a/2,a/b/3,ab,b,c/4" {
+		t.Errorf("paged listing = %s", got)
+	}
+}
+
+func TestFilesystem_ConditionalWrites(t *testing.T) {
+	fs := newTestFilesystem(t)
+	ctx := context.Background()
+	create := s3.WithWriteConditions(ctx, s3.WriteConditions{IfNoneMatch: "*"})
+	if err := fs.PutObject(create, "bucket", "k", strings.NewReader("1"), nil, nil, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.PutObject(create, "bucket", "k", strings.NewReader("2"), nil, nil, "", nil); err == nil {
+		t.Error("If-None-Match: * should fail when the object exists")
+	}
+	head, _ := fs.HeadObject(ctx, "bucket", "k", nil)
+	update := s3.WithWriteConditions(ctx, s3.WriteConditions{IfMatch: head["ETag"]})
+	if err := fs.PutObject(update, "bucket", "k", strings.NewReader("3"), nil, nil, "", nil); err != nil {
+		t.Errorf("If-Match with the current ETag: %v", err)
+	}
+	if err := fs.PutObject(update, "bucket", "k", strings.NewReader("4"), nil, nil, "", nil); err == nil {
+		t.Error("If-Match with a stale ETag should fail")
+	}
+	if body, _ := get(t, fs, "k", nil); body != "3" {
+		t.Errorf("body = %q after failed writes", body)
+	}
+}
+
+func TestFilesystem_MultipartAndCopy(t *testing.T) {
+	fs := newTestFilesystem(t)
+	ctx := context.Background()
+	id, err := fs.CreateMultipartUpload(ctx, "bucket", "mpu", map[string]string{"x-amz-meta-k": "v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	part1 := bytes.Repeat([]byte("a"), 100)
+	e1, err := fs.UploadPart(ctx, "bucket", "mpu", id, 1, bytes.NewReader(part1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e2, err := fs.UploadPart(ctx, "bucket", "mpu", id, 2, strings.NewReader("tail"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts, err := fs.ListParts(ctx, "bucket", "mpu", id); err != nil || len(parts) != 2 || parts[0].Size != 100 {
+		t.Errorf("ListParts = %+v, %v", parts, err)
+	}
+	if _, err := fs.CompleteMultipartUpload(ctx, "bucket", "mpu", id, []s3.CompletedPart{{PartNumber: 1, ETag: e2}}, nil); err == nil {
+		t.Error("complete with a wrong ETag should fail")
+	}
+	etag, err := fs.CompleteMultipartUpload(ctx, "bucket", "mpu", id, []s3.CompletedPart{{PartNumber: 1, ETag: e1}, {PartNumber: 2, ETag: e2}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(etag, `-2"`) {
+		t.Errorf("multipart ETag = %s", etag)
+	}
+	body, meta := get(t, fs, "mpu", nil)
+	if body != string(part1)+"tail" || meta["x-amz-meta-k"] != "v" {
+		t.Errorf("completed object = %d bytes, metadata %v", len(body), meta)
+	}
+	if _, err := fs.ListParts(ctx, "bucket", "mpu", id); !errors.Is(err, s3.ErrNotFound) {
+		t.Errorf("ListParts after complete = %v, want NoSuchUpload", err)
+	}
+
+	if _, _, err := fs.CopyObject(ctx, "bucket", "copy", "bucket", "mpu", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, meta := get(t, fs, "copy", nil); meta["x-amz-meta-k"] != "v" {
+		t.Errorf("copy without metadata should keep the source's, got %v", meta)
+	}
+	if _, _, err := fs.CopyObject(ctx, "bucket", "copy", "bucket", "copy", nil, map[string]string{"x-amz-meta-k": "new"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if body, meta := get(t, fs, "copy", nil); meta["x-amz-meta-k"] != "new" || len(body) != 104 {
+		t.Errorf("in-place copy = %d bytes, metadata %v", len(body), meta)
+	}
+
+	id2, _ := fs.CreateMultipartUpload(ctx, "bucket", "pc", nil)
+	if _, err := fs.UploadPartCopy(ctx, "bucket", "pc", id2, 1, "bucket", "mpu", nil, &s3.CopyPartRange{First: 98, Last: 101}); err != nil {
+		t.Fatal(err)
+	}
+	parts, _ := fs.ListParts(ctx, "bucket", "pc", id2)
+	if len(parts) != 1 || parts[0].Size != 4 {
+		t.Errorf("UploadPartCopy parts = %+v", parts)
+	}
+	if err := fs.AbortMultipartUpload(ctx, "bucket", "pc", id2); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.AbortMultipartUpload(ctx, "bucket", "pc", "../../bucket"); !errors.Is(err, s3.ErrNotFound) {
+		t.Errorf("abort with a path as upload ID = %v, want NoSuchUpload", err)
+	}
+}
+
+func TestFilesystem_ObjectLockNotSupported(t *testing.T) {
+	fs := newTestFilesystem(t)
+	ctx := context.Background()
+	lock := &s3.ObjectLockInput{LegalHoldStatus: "ON"}
+	if err := fs.PutObject(ctx, "bucket", "k", strings.NewReader("x"), nil, nil, "", lock); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("PutObject with lock = %v, want ErrNotSupported", err)
+	}
+	if cfg, err := fs.GetObjectLockConfiguration(ctx, "bucket"); cfg != nil || err != nil {
+		t.Errorf("GetObjectLockConfiguration = %v, %v; want none", cfg, err)
+	}
+}
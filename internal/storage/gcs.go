@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// gcsEndpoint is the Cloud Storage XML API, which accepts S3 requests
+// signed with HMAC keys.
+const gcsEndpoint = "https://storage.googleapis.com"
+
+// gcsBackendConfig fills in what Cloud Storage needs from an S3 client:
+// its endpoint, path-style addressing and a region for the signer.
+// access_key and secret_key are the HMAC key of a service account.
+func gcsBackendConfig(cfg *config.BackendConfig) *config.BackendConfig {
+	c := *cfg
+	if c.Endpoint == "" {
+		c.Endpoint = gcsEndpoint
+	}
+	if c.Region == "" {
+		c.Region = "auto"
+	}
+	c.UsePathStyle = true
+	return &c
+}
+
+// newGCSBackend returns an S3 client talking to Cloud Storage.
+func newGCSBackend(cfg *config.BackendConfig, m *metrics.Metrics) (Backend, error) {
+	return s3.NewClientFactory(gcsBackendConfig(cfg), s3.WithMetrics(m)).GetClient()
+}
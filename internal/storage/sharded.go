@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// Sharded routes every call to the backend that holds its bucket. Buckets
+// are pinned by the override table or placed by a consistent-hash ring over
+// the shard names, so the placement of a bucket depends only on its name and
+// the shard list, and adding a shard moves only the buckets that land on
+// it.
+//
+// Copies between buckets on different shards are emulated with a read from
+// the source and a write to the destination.
+type Sharded struct {
+	shards    map[string]Backend
+	overrides map[string]string
+	ring      []ringPoint
+}
+
+var _ Backend = (*Sharded)(nil)
+
+type ringPoint struct {
+	hash  uint64
+	shard string
+}
+
+// NewSharded returns a backend that spreads buckets over primary, named
+// config.PrimaryShardName, and the shards in cfg. m may be nil.
+func NewSharded(primary Backend, cfg config.ShardingConfig, m *metrics.Metrics) (*Sharded, error) {
+	s := &Sharded{
+		shards:    map[string]Backend{config.PrimaryShardName: primary},
+		overrides: cfg.Overrides,
+	}
+	for i := range cfg.Shards {
+		shard := &cfg.Shards[i]
+		b, err := New(&shard.Backend, m)
+		if err != nil {
+			return nil, fmt.Errorf("shard %q: %w", shard.Name, err)
+		}
+		s.shards[shard.Name] = b
+	}
+	s.ring = buildRing(s.shards, cfg.VirtualNodes)
+	return s, nil
+}
+
+// buildRing places vnodes points per shard on the ring, sorted by hash.
+func buildRing(shards map[string]Backend, vnodes int) []ringPoint {
+	if vnodes <= 0 {
+		vnodes = config.DefaultShardVirtualNodes
+	}
+	ring := make([]ringPoint, 0, len(shards)*vnodes)
+	for name := range shards {
+		for i := 0; i < vnodes; i++ {
+			ring = append(ring, ringPoint{hash: ringHash(name + "#" + strconv.Itoa(i)), shard: name})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool {
+		if ring[i].hash != ring[j].hash {
+			return ring[i].hash < ring[j].hash
+		}
+		// Break ties by name so the ring does not depend on map order.
+		return ring[i].shard < ring[j].shard
+	})
+	return ring
+}
+
+// ringHash maps names onto the ring. FNV and similar hashes cluster names
+// that differ only in a trailing counter, as bucket names often do.
+func ringHash(s string) uint64 {
+	sum := sha256.Sum256([]byte(s))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// ShardFor returns the name of the shard that holds bucket.
+func (s *Sharded) ShardFor(bucket string) string {
+	if name, ok := s.overrides[bucket]; ok {
+		return name
+	}
+	h := ringHash(bucket)
+	i := sort.Search(len(s.ring), func(i int) bool { return s.ring[i].hash >= h })
+	if i == len(s.ring) {
+		i = 0
+	}
+	return s.ring[i].shard
+}
+
+func (s *Sharded) backend(bucket string) Backend {
+	return s.shards[s.ShardFor(bucket)]
+}
+
+func (s *Sharded) PutObject(ctx context.Context, bucket, key string, reader io.Reader, metadata map[string]string, contentLength *int64, tags string, lock *s3.ObjectLockInput) error {
+	return s.backend(bucket).PutObject(ctx, bucket, key, reader, metadata, contentLength, tags, lock)
+}
+
+func (s *Sharded) GetObject(ctx context.Context, bucket, key string, versionID *string, rangeHeader *string) (io.ReadCloser, map[string]string, error) {
+	return s.backend(bucket).GetObject(ctx, bucket, key, versionID, rangeHeader)
+}
+
+func (s *Sharded) DeleteObject(ctx context.Context, bucket, key string, versionID *string) error {
+	return s.backend(bucket).DeleteObject(ctx, bucket, key, versionID)
+}
+
+func (s *Sharded) HeadObject(ctx context.Context, bucket, key string, versionID *string) (map[string]string, error) {
+	return s.backend(bucket).HeadObject(ctx, bucket, key, versionID)
+}
+
+func (s *Sharded) ListObjects(ctx context.Context, bucket, prefix string, opts s3.ListOptions) (s3.ListResult, error) {
+	return s.backend(bucket).ListObjects(ctx, bucket, prefix, opts)
+}
+
+func (s *Sharded) CreateMultipartUpload(ctx context.Context, bucket, key string, metadata map[string]string) (string, error) {
+	return s.backend(bucket).CreateMultipartUpload(ctx, bucket, key, metadata)
+}
+
+func (s *Sharded) UploadPart(ctx context.Context, bucket, key, uploadID string, partNumber int32, reader io.Reader, contentLength *int64) (string, error) {
+	return s.backend(bucket).UploadPart(ctx, bucket, key, uploadID, partNumber, reader, contentLength)
+}
+
+func (s *Sharded) CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []s3.CompletedPart, lock *s3.ObjectLockInput) (string, error) {
+	return s.backend(bucket).CompleteMultipartUpload(ctx, bucket, key, uploadID, parts, lock)
+}
+
+func (s *Sharded) AbortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error {
+	return s.backend(bucket).AbortMultipartUpload(ctx, bucket, key, uploadID)
+}
+
+func (s *Sharded) ListParts(ctx context.Context, bucket, key, uploadID string) ([]s3.PartInfo, error) {
+	return s.backend(bucket).ListParts(ctx, bucket, key, uploadID)
+}
+
+// CopyObject copies within a shard natively. Across shards the source is
+// streamed into a PutObject on the destination; nil metadata keeps the
+// source's user metadata, as it does for a native copy.
+func (s *Sharded) CopyObject(ctx context.Context, dstBucket, dstKey string, srcBucket, srcKey string, srcVersionID *string, metadata map[string]string, lock *s3.ObjectLockInput) (string, map[string]string, error) {
+	dstShard, srcShard := s.ShardFor(dstBucket), s.ShardFor(srcBucket)
+	dst, src := s.shards[dstShard], s.shards[srcShard]
+	if dstShard == srcShard {
+		return dst.CopyObject(ctx, dstBucket, dstKey, srcBucket, srcKey, srcVersionID, metadata, lock)
+	}
+	body, srcMeta, err := src.GetObject(ctx, srcBucket, srcKey, srcVersionID, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	defer body.Close()
+	if metadata == nil {
+		metadata = make(map[string]string)
+		for k, v := range srcMeta {
+			if strings.HasPrefix(strings.ToLower(k), metaPrefix) {
+				metadata[k] = v
+			}
+		}
+	}
+	if err := dst.PutObject(ctx, dstBucket, dstKey, body, metadata, contentLength(srcMeta), "", lock); err != nil {
+		return "", nil, err
+	}
+	head, err := dst.HeadObject(ctx, dstBucket, dstKey, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	etag := strings.Trim(head["ETag"], "\"")
+	result := map[string]string{"ETag": etag}
+	if lm := head["Last-Modified"]; lm != "" {
+		result["Last-Modified"] = lm
+	}
+	return etag, result, nil
+}
+
+// UploadPartCopy copies within a shard natively and otherwise uploads the
+// (ranged) source as the part.
+func (s *Sharded) UploadPartCopy(ctx context.Context, dstBucket, dstKey, uploadID string, partNumber int32, srcBucket, srcKey string, srcVersionID *string, srcRange *s3.CopyPartRange) (*s3.CopyPartResult, error) {
+	dstShard, srcShard := s.ShardFor(dstBucket), s.ShardFor(srcBucket)
+	dst, src := s.shards[dstShard], s.shards[srcShard]
+	if dstShard == srcShard {
+		return dst.UploadPartCopy(ctx, dstBucket, dstKey, uploadID, partNumber, srcBucket, srcKey, srcVersionID, srcRange)
+	}
+	var rangeHeader *string
+	var length *int64
+	if srcRange != nil {
+		r := fmt.Sprintf("bytes=%d-%d", srcRange.First, srcRange.Last)
+		rangeHeader = &r
+		n := srcRange.Last - srcRange.First + 1
+		length = &n
+	}
+	body, srcMeta, err := src.GetObject(ctx, srcBucket, srcKey, srcVersionID, rangeHeader)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	if length == nil {
+		length = contentLength(srcMeta)
+	}
+	etag, err := dst.UploadPart(ctx, dstBucket, dstKey, uploadID, partNumber, body, length)
+	if err != nil {
+		return nil, err
+	}
+	return &s3.CopyPartResult{ETag: etag, LastModified: time.Now().UTC()}, nil
+}
+
+func (s *Sharded) DeleteObjects(ctx context.Context, bucket string, keys []s3.ObjectIdentifier) ([]s3.DeletedObject, []s3.ErrorObject, error) {
+	return s.backend(bucket).DeleteObjects(ctx, bucket, keys)
+}
+
+func (s *Sharded) PutObjectRetention(ctx context.Context, bucket, key string, versionID *string, retention *s3.RetentionConfig) error {
+	return s.backend(bucket).PutObjectRetention(ctx, bucket, key, versionID, retention)
+}
+
+func (s *Sharded) GetObjectRetention(ctx context.Context, bucket, key string, versionID *string) (*s3.RetentionConfig, error) {
+	return s.backend(bucket).GetObjectRetention(ctx, bucket, key, versionID)
+}
+
+func (s *Sharded) PutObjectLegalHold(ctx context.Context, bucket, key string, versionID *string, status string) error {
+	return s.backend(bucket).PutObjectLegalHold(ctx, bucket, key, versionID, status)
+}
+
+func (s *Sharded) GetObjectLegalHold(ctx context.Context, bucket, key string, versionID *string) (string, error) {
+	return s.backend(bucket).GetObjectLegalHold(ctx, bucket, key, versionID)
+}
+
+func (s *Sharded) PutObjectLockConfiguration(ctx context.Context, bucket string, cfg *s3.ObjectLockConfiguration) error {
+	return s.backend(bucket).PutObjectLockConfiguration(ctx, bucket, cfg)
+}
+
+func (s *Sharded) GetObjectLockConfiguration(ctx context.Context, bucket string) (*s3.ObjectLockConfiguration, error) {
+	return s.backend(bucket).GetObjectLockConfiguration(ctx, bucket)
+}
+
+func (s *Sharded) PutObjectTagging(ctx context.Context, bucket, key string, versionID *string, tags []s3.Tag) error {
+	return s.backend(bucket).PutObjectTagging(ctx, bucket, key, versionID, tags)
+}
+
+func (s *Sharded) GetObjectTagging(ctx context.Context, bucket, key string, versionID *string) ([]s3.Tag, error) {
+	return s.backend(bucket).GetObjectTagging(ctx, bucket, key, versionID)
+}
+
+func (s *Sharded) DeleteObjectTagging(ctx context.Context, bucket, key string, versionID *string) error {
+	return s.backend(bucket).DeleteObjectTagging(ctx, bucket, key, versionID)
+}
+
+// contentLength returns the Content-Length in meta, or nil when it is
+// missing or invalid.
+func contentLength(meta map[string]string) *int64 {
+	n, err := strconv.ParseInt(meta["Content-Length"], 10, 64)
+	if err != nil || n < 0 {
+		return nil
+	}
+	return &n
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+func shardConfig(t *testing.T, names ...string) config.ShardingConfig {
+	t.Helper()
+	cfg := config.ShardingConfig{Enabled: true}
+	for _, name := range names {
+		cfg.Shards = append(cfg.Shards, config.BackendShard{
+			Name:    name,
+			Backend: config.BackendConfig{Type: "filesystem", Filesystem: config.BackendFilesystemConfig{Root: t.TempDir()}},
+		})
+	}
+	return cfg
+}
+
+// newTestSharded returns a two-shard backend with the buckets created on
+// the shards that hold them.
+func newTestSharded(t *testing.T, overrides map[string]string, buckets ...string) *Sharded {
+	t.Helper()
+	primaryRoot := t.TempDir()
+	primary, err := NewFilesystem(primaryRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := shardConfig(t, "second")
+	cfg.Overrides = overrides
+	s, err := NewSharded(primary, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := map[string]string{config.PrimaryShardName: primaryRoot, "second": cfg.Shards[0].Backend.Filesystem.Root}
+	for _, bucket := range buckets {
+		if err := os.Mkdir(filepath.Join(roots[s.ShardFor(bucket)], bucket), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestSharded_Placement(t *testing.T) {
+	primary, err := NewFilesystem(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	three, err := NewSharded(primary, shardConfig(t, "b", "c"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int{}
+	for i := 0; i < 3000; i++ {
+		counts[three.ShardFor(fmt.Sprintf("bucket-%d", i))]++
+	}
+	for _, name := range []string{config.PrimaryShardName, "b", "c"} {
+		if counts[name] < 600 {
+			t.Errorf("shard %s holds %d of 3000 buckets", name, counts[name])
+		}
+	}
+
+	// Placement does not depend on construction, and adding a shard only
+	// moves buckets onto the new shard.
+	again, _ := NewSharded(primary, shardConfig(t, "b", "c"), nil)
+	four, _ := NewSharded(primary, shardConfig(t, "b", "c", "d"), nil)
+	moved := 0
+	for i := 0; i < 3000; i++ {
+		bucket := fmt.Sprintf("bucket-%d", i)
+		before := three.ShardFor(bucket)
+		if got := again.ShardFor(bucket); got != before {
+			t.Fatalf("%s placed on %s, then on %s", bucket, before, got)
+		}
+		if after := four.ShardFor(bucket); after != before {
+			if after != "d" {
+				t.Fatalf("%s moved from %s to %s", bucket, before, after)
+			}
+			moved++
+		}
+	}
+	if moved < 450 || moved > 1050 {
+		t.Errorf("adding a fourth shard moved %d of 3000 buckets", moved)
+	}
+
+	cfg := shardConfig(t, "b", "c")
+	cfg.Overrides = map[string]string{"bucket-0": "c", "bucket-1": config.PrimaryShardName}
+	pinned, _ := NewSharded(primary, cfg, nil)
+	if got := pinned.ShardFor("bucket-0"); got != "c" {
+		t.Errorf("override bucket-0 = %s", got)
+	}
+	if got := pinned.ShardFor("bucket-1"); got != config.PrimaryShardName {
+		t.Errorf("override bucket-1 = %s", got)
+	}
+}
+
+func TestSharded_RoutesAndCopiesAcrossShards(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSharded(t, map[string]string{"left": config.PrimaryShardName, "right": "second"}, "left", "right")
+
+	if err := s.PutObject(ctx, "left", "obj", strings.NewReader("hello shards"), map[string]string{"x-amz-meta-team": "blue"}, nil, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.shards["second"].HeadObject(ctx, "left", "obj", nil); err == nil {
+		t.Error("object written to the wrong shard")
+	}
+
+	etag, _, err := s.CopyObject(ctx, "right", "copy", "left", "obj", nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, meta, err := s.GetObject(ctx, "right", "copy", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "hello shards" || meta["x-amz-meta-team"] != "blue" {
+		t.Errorf("copy = %q, metadata %v", data, meta)
+	}
+	if etag == "" || strings.Trim(meta["ETag"], "\"") != etag {
+		t.Errorf("copy ETag = %q, object ETag %q", etag, meta["ETag"])
+	}
+
+	if _, _, err := s.CopyObject(ctx, "right", "replaced", "left", "obj", nil, map[string]string{"x-amz-meta-owner": "alice"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if meta, err := s.HeadObject(ctx, "right", "replaced", nil); err != nil || meta["x-amz-meta-team"] != "" || meta["x-amz-meta-owner"] != "alice" {
+		t.Errorf("replaced metadata = %v, %v", meta, err)
+	}
+
+	uploadID, err := s.CreateMultipartUpload(ctx, "right", "mpu", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part, err := s.UploadPartCopy(ctx, "right", "mpu", uploadID, 1, "left", "obj", nil, &s3.CopyPartRange{First: 6, Last: 11})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CompleteMultipartUpload(ctx, "right", "mpu", uploadID, []s3.CompletedPart{{PartNumber: 1, ETag: part.ETag}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	r, _, err = s.GetObject(ctx, "right", "mpu", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ = io.ReadAll(r)
+	r.Close()
+	if string(data) != "shards" {
+		t.Errorf("part copy = %q", data)
+	}
+}
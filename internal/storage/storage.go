@@ -0,0 +1,75 @@
+// Package storage selects the object store the gateway encrypts in front of.
+// Every store is driven through the same Backend interface, so the API
+// handlers do not know whether objects end up in S3, a local directory,
+// Azure Blob Storage or Google Cloud Storage.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// Backend is the object store API. It is the method set of s3.Client; stores
+// other than S3 translate each call to their own model and report failures
+// with S3 error codes (see s3.NewAPIError) so responses and retries behave
+// the same whatever the store.
+type Backend interface {
+	s3.Client
+}
+
+// ErrNotSupported is returned for operations the configured store cannot
+// provide, such as Object Lock on a filesystem.
+var ErrNotSupported = errors.New("storage: operation not supported by backend")
+
+// New returns the Backend selected by cfg.Type. m may be nil.
+func New(cfg *config.BackendConfig, m *metrics.Metrics) (Backend, error) {
+	switch cfg.StorageType() {
+	case config.BackendTypeS3:
+		return s3.NewClientFactory(cfg, s3.WithMetrics(m)).GetClient()
+	case config.BackendTypeGCS:
+		return newGCSBackend(cfg, m)
+	case config.BackendTypeFilesystem:
+		return NewFilesystem(cfg.Filesystem.Root)
+	case config.BackendTypeAzure:
+		// The proxy and CA settings apply to Azure as to any other backend.
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		if err := s3.NewClientFactory(cfg).ConfigureTransport(tr); err != nil {
+			return nil, err
+		}
+		return NewAzure(cfg.Azure, &http.Client{Transport: tr})
+	default:
+		return nil, fmt.Errorf("unknown backend type %q", cfg.Type)
+	}
+}
+
+// notSupported reports op as unavailable on the named store.
+func notSupported(store, op string) error {
+	return fmt.Errorf("%s: %s: %w", store, op, ErrNotSupported)
+}
+
+const metaPrefix = "x-amz-meta-"
+
+// normalizeMetadata returns metadata keyed the way s3.Client returns it
+// after a round trip: user metadata as lower-case "x-amz-meta-<name>". Keys
+// without the prefix are treated as user metadata too, as S3 does.
+func normalizeMetadata(metadata map[string]string) map[string]string {
+	out := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		name := strings.ToLower(k)
+		name = strings.TrimPrefix(name, metaPrefix)
+		out[metaPrefix+name] = v
+	}
+	return out
+}
+
+// httpTimeFormat is the layout of the Last-Modified values s3.Client returns.
+const httpTimeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// listTimeFormat formats ObjectInfo.LastModified like s3.Client.
+const listTimeFormat = "2006-01-02T15:04:05.000Z"
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	if _, err := New(&config.BackendConfig{Type: "ftp"}, nil); err == nil {
+		t.Error("unknown backend type should fail")
+	}
+	b, err := New(&config.BackendConfig{Type: "filesystem", Filesystem: config.BackendFilesystemConfig{Root: t.TempDir()}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.(*Filesystem); !ok {
+		t.Errorf("filesystem type returned %T", b)
+	}
+	b, err = New(&config.BackendConfig{Type: "azure", Azure: config.BackendAzureConfig{AccountName: "acct", AccountKey: testAzureKey}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, ok := b.(*Azure); !ok || a.endpoint.String() != "https://acct.blob.core.windows.net" {
+		t.Errorf("azure type returned %T", b)
+	}
+	if _, err := New(&config.BackendConfig{Type: "gcs", AccessKey: "GOOG1E", SecretKey: "secret"}, nil); err != nil {
+		t.Errorf("gcs backend: %v", err)
+	}
+}
+
+func TestGCSBackendConfig(t *testing.T) {
+	in := &config.BackendConfig{Type: "gcs", AccessKey: "GOOG1E", SecretKey: "secret"}
+	got := gcsBackendConfig(in)
+	if got.Endpoint != gcsEndpoint || got.Region != "auto" || !got.UsePathStyle {
+		t.Errorf("gcsBackendConfig = %+v", got)
+	}
+	if in.Endpoint != "" {
+		t.Error("gcsBackendConfig must not modify its argument")
+	}
+	custom := gcsBackendConfig(&config.BackendConfig{Endpoint: "https://storage.example.com", Region: "europe-west1"})
+	if custom.Endpoint != "https://storage.example.com" || custom.Region != "europe-west1" {
+		t.Errorf("explicit endpoint and region should be kept: %+v", custom)
+	}
+}
@@ -0,0 +1,97 @@
+// Package tiering moves cold objects to cheaper storage.
+//
+// A Worker applies the configured rules on an interval. An object that
+// matches is re-uploaded exactly as stored, ciphertext and encryption
+// metadata alike, so nothing is decrypted on the way: either in place with
+// another storage class, or to a secondary backend. In the second case the
+// primary backend keeps a zero-byte stub carrying the same metadata plus
+// MetaLocation, which names the secondary bucket. Listings therefore still
+// show the object, and the client returned by NewClient follows stubs on
+// GET and HEAD so every read path of the gateway sees the object as if it
+// had never moved.
+//
+// Only whole-object encrypted uploads are tiered. Multipart-encrypted
+// objects, objects under Object Lock and plaintext objects stay where they
+// are. Tags are not carried over by a transition.
+package tiering
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/aws/smithy-go"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+const (
+	// MetaLocation marks a stub on the primary backend. Its value is the
+	// bucket on the secondary backend holding the object under the same key.
+	MetaLocation = "x-amz-meta-s3eg-tier"
+	// MetaStorageClass records the storage class an object was re-uploaded
+	// with in place, so later passes leave it alone.
+	MetaStorageClass = "x-amz-meta-s3eg-storage-class"
+)
+
+// IsTieringMetadata reports whether key is metadata the tiering worker adds,
+// which clients must not see.
+func IsTieringMetadata(key string) bool {
+	return key == MetaLocation || key == MetaStorageClass
+}
+
+// client resolves stubs on the primary backend to the secondary backend.
+type client struct {
+	s3.Client
+	secondary s3.Client
+}
+
+// NewClient wraps primary so that reads of a stub are answered by
+// secondary. Writes and deletes go to primary only; the worker removes
+// secondary copies whose stub has gone. A nil secondary returns primary
+// unchanged.
+func NewClient(primary, secondary s3.Client) s3.Client {
+	if primary == nil || secondary == nil {
+		return primary
+	}
+	return &client{Client: primary, secondary: secondary}
+}
+
+func (c *client) GetObject(ctx context.Context, bucket, key string, versionID *string, rangeHeader *string) (io.ReadCloser, map[string]string, error) {
+	body, meta, err := c.Client.GetObject(ctx, bucket, key, versionID, rangeHeader)
+	if err != nil {
+		// A range cannot be satisfied by an empty stub; check whether the
+		// object is one before reporting the error.
+		if rangeHeader == nil || !isInvalidRange(err) {
+			return nil, nil, err
+		}
+		stub, headErr := c.Client.HeadObject(ctx, bucket, key, versionID)
+		if headErr != nil || stub[MetaLocation] == "" {
+			return nil, nil, err
+		}
+		return c.secondary.GetObject(ctx, stub[MetaLocation], key, nil, rangeHeader)
+	}
+	if meta[MetaLocation] == "" {
+		return body, meta, nil
+	}
+	body.Close()
+	return c.secondary.GetObject(ctx, meta[MetaLocation], key, nil, rangeHeader)
+}
+
+func (c *client) HeadObject(ctx context.Context, bucket, key string, versionID *string) (map[string]string, error) {
+	meta, err := c.Client.HeadObject(ctx, bucket, key, versionID)
+	if err != nil || meta[MetaLocation] == "" {
+		return meta, err
+	}
+	return c.secondary.HeadObject(ctx, meta[MetaLocation], key, nil)
+}
+
+// isInvalidRange reports whether err is an S3 InvalidRange error.
+func isInvalidRange(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode() == "InvalidRange"
+	}
+	return strings.Contains(err.Error(), "InvalidRange")
+}
@@ -0,0 +1,360 @@
+package tiering
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/accessstats"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/sirupsen/logrus"
+)
+
+// orphanGrace is how old a secondary copy without a stub must be before it
+// is deleted. It covers a transition by another gateway instance that has
+// written the copy but not yet its stub.
+const orphanGrace = time.Hour
+
+// AccessStats is the subset of accessstats.Tracker the worker reads last
+// access times from.
+type AccessStats interface {
+	Query(ctx context.Context, q accessstats.Query) ([]accessstats.Entry, error)
+}
+
+// Result counts what one pass did.
+type Result struct {
+	Transitioned int   `json:"transitioned"`
+	Bytes        int64 `json:"bytes"`
+	Failed       int   `json:"failed"`
+	// Orphans is the number of secondary copies deleted because their stub
+	// was overwritten or deleted.
+	Orphans int `json:"orphans"`
+}
+
+// Worker applies tiering rules. Primary must be the unwrapped backend
+// client so stubs are visible to it.
+type Worker struct {
+	primary   s3.Client
+	secondary s3.Client
+	rules     []config.TieringRule
+	interval  time.Duration
+	stats     AccessStats
+	skip      func(key string) bool
+	logger    *logrus.Logger
+	now       func() time.Time
+
+	runMu   sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started atomic.Bool
+	done    chan struct{}
+}
+
+// NewWorker returns a worker for cfg, or nil when cfg disables tiering.
+// secondary may be nil when no rule moves objects to the secondary
+// backend, and stats may be nil when no rule uses cold_after. skip reports
+// keys the gateway reserves for itself, which are never tiered.
+func NewWorker(primary, secondary s3.Client, cfg config.TieringConfig, stats AccessStats, skip func(key string) bool, logger *logrus.Logger) *Worker {
+	if !cfg.Enabled || primary == nil {
+		return nil
+	}
+	if skip == nil {
+		skip = func(string) bool { return false }
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Worker{
+		primary:   primary,
+		secondary: secondary,
+		rules:     cfg.Rules,
+		interval:  cfg.Interval,
+		stats:     stats,
+		skip:      skip,
+		logger:    logger,
+		now:       time.Now,
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+}
+
+// Run applies every rule once. A failure to list a bucket ends the pass;
+// failures on single objects are logged, counted and skipped.
+func (w *Worker) Run(ctx context.Context) (Result, error) {
+	var res Result
+	if w == nil {
+		return res, nil
+	}
+	w.runMu.Lock()
+	defer w.runMu.Unlock()
+	for i := range w.rules {
+		if err := w.applyRule(ctx, &w.rules[i], &res); err != nil {
+			return res, err
+		}
+	}
+	for i := range w.rules {
+		if w.rules[i].Secondary && w.secondary != nil {
+			if err := w.collectOrphans(ctx, &w.rules[i], &res); err != nil {
+				return res, err
+			}
+		}
+	}
+	return res, nil
+}
+
+func secondaryBucket(rule *config.TieringRule) string {
+	if rule.SecondaryBucket != "" {
+		return rule.SecondaryBucket
+	}
+	return rule.Bucket
+}
+
+// lastAccess returns the last read of every object under the rule's prefix.
+func (w *Worker) lastAccess(ctx context.Context, rule *config.TieringRule) (map[string]time.Time, error) {
+	if rule.ColdAfter <= 0 || w.stats == nil {
+		return nil, nil
+	}
+	entries, err := w.stats.Query(ctx, accessstats.Query{Bucket: rule.Bucket, Prefix: rule.Prefix})
+	if err != nil {
+		return nil, err
+	}
+	last := make(map[string]time.Time, len(entries))
+	for _, e := range entries {
+		last[e.Key] = e.LastAccess
+	}
+	return last, nil
+}
+
+func (w *Worker) applyRule(ctx context.Context, rule *config.TieringRule, res *Result) error {
+	last, err := w.lastAccess(ctx, rule)
+	if err != nil {
+		return fmt.Errorf("tiering: access stats for %s/%s: %w", rule.Bucket, rule.Prefix, err)
+	}
+	now := w.now()
+	opts := s3.ListOptions{MaxKeys: 1000}
+	for {
+		page, err := w.primary.ListObjects(ctx, rule.Bucket, rule.Prefix, opts)
+		if err != nil {
+			return fmt.Errorf("tiering: list %s/%s: %w", rule.Bucket, rule.Prefix, err)
+		}
+		for _, obj := range page.Objects {
+			if strings.HasSuffix(obj.Key, ".mpu-manifest") || w.skip(obj.Key) {
+				continue
+			}
+			written, err := parseListTime(obj.LastModified)
+			if err != nil || now.Sub(written) < rule.MinAge {
+				continue
+			}
+			if rule.ColdAfter > 0 {
+				read := last[obj.Key]
+				if read.IsZero() {
+					read = written
+				}
+				if now.Sub(read) < rule.ColdAfter {
+					continue
+				}
+			}
+			n, err := w.transition(ctx, rule, obj.Key)
+			switch {
+			case err != nil:
+				res.Failed++
+				if w.logger != nil {
+					w.logger.WithError(err).WithFields(logrus.Fields{
+						"bucket": rule.Bucket,
+						"key":    obj.Key,
+					}).Warn("Tiering transition failed")
+				}
+			case n >= 0:
+				res.Transitioned++
+				res.Bytes += n
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return nil
+		}
+		opts.ContinuationToken = page.NextContinuationToken
+	}
+}
+
+// transition moves one object as the rule says. It returns the number of
+// bytes moved, or -1 when the object is not eligible or already in place.
+func (w *Worker) transition(ctx context.Context, rule *config.TieringRule, key string) (int64, error) {
+	meta, err := w.primary.HeadObject(ctx, rule.Bucket, key, nil)
+	if err != nil {
+		if errors.Is(err, s3.ErrNotFound) {
+			return -1, nil
+		}
+		return 0, err
+	}
+	switch {
+	case meta[MetaLocation] != "":
+		return -1, nil
+	case !rule.Secondary && meta[MetaStorageClass] == rule.StorageClass:
+		return -1, nil
+	case meta[crypto.MetaEncrypted] != "true" && meta["x-amz-meta-e"] != "true":
+		return -1, nil
+	case meta[crypto.MetaMPUEncrypted] == "true", meta["x-amz-object-lock-mode"] != "":
+		return -1, nil
+	}
+
+	body, meta, err := w.primary.GetObject(ctx, rule.Bucket, key, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	size, err := strconv.ParseInt(meta["Content-Length"], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("tiering: %s/%s has no content length", rule.Bucket, key)
+	}
+	stored := userMetadata(meta)
+	// Only replace the object the checks above looked at.
+	unchanged := s3.WithWriteConditions(ctx, s3.WriteConditions{IfMatch: meta["ETag"]})
+
+	if !rule.Secondary {
+		stored[MetaStorageClass] = rule.StorageClass
+		if err := w.primary.PutObject(s3.WithStorageClass(unchanged, rule.StorageClass), rule.Bucket, key, body, stored, &size, "", nil); err != nil {
+			return 0, fmt.Errorf("tiering: rewrite %s/%s: %w", rule.Bucket, key, err)
+		}
+		return size, nil
+	}
+
+	target := secondaryBucket(rule)
+	if err := w.secondary.PutObject(s3.WithStorageClass(ctx, rule.StorageClass), target, key, body, stored, &size, "", nil); err != nil {
+		return 0, fmt.Errorf("tiering: copy %s/%s to secondary: %w", rule.Bucket, key, err)
+	}
+	stub := userMetadata(meta)
+	stub[MetaLocation] = target
+	var zero int64
+	if err := w.primary.PutObject(unchanged, rule.Bucket, key, bytes.NewReader(nil), stub, &zero, "", nil); err != nil {
+		// The copy is left for collectOrphans: another instance may have
+		// stubbed the object meanwhile.
+		return 0, fmt.Errorf("tiering: write stub %s/%s: %w", rule.Bucket, key, err)
+	}
+	return size, nil
+}
+
+// collectOrphans deletes secondary copies whose primary object is gone or
+// no longer a stub pointing at them.
+func (w *Worker) collectOrphans(ctx context.Context, rule *config.TieringRule, res *Result) error {
+	target := secondaryBucket(rule)
+	now := w.now()
+	opts := s3.ListOptions{MaxKeys: 1000}
+	for {
+		page, err := w.secondary.ListObjects(ctx, target, rule.Prefix, opts)
+		if err != nil {
+			return fmt.Errorf("tiering: list secondary %s/%s: %w", target, rule.Prefix, err)
+		}
+		for _, obj := range page.Objects {
+			written, err := parseListTime(obj.LastModified)
+			if err != nil || now.Sub(written) < orphanGrace {
+				continue
+			}
+			meta, err := w.primary.HeadObject(ctx, rule.Bucket, obj.Key, nil)
+			if err != nil && !errors.Is(err, s3.ErrNotFound) {
+				continue
+			}
+			if err == nil && meta[MetaLocation] == target {
+				continue
+			}
+			if err := w.secondary.DeleteObject(ctx, target, obj.Key, nil); err != nil {
+				if w.logger != nil {
+					w.logger.WithError(err).WithFields(logrus.Fields{
+						"bucket": target,
+						"key":    obj.Key,
+					}).Warn("Failed to delete orphaned secondary copy")
+				}
+				continue
+			}
+			res.Orphans++
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return nil
+		}
+		opts.ContinuationToken = page.NextContinuationToken
+	}
+}
+
+// Pass runs one pass and logs its outcome. It is what Start runs every
+// interval, for callers that schedule passes themselves.
+func (w *Worker) Pass(ctx context.Context) error {
+	res, err := w.Run(ctx)
+	if w.logger == nil {
+		return err
+	}
+	entry := w.logger.WithFields(logrus.Fields{
+		"transitioned": res.Transitioned,
+		"bytes":        res.Bytes,
+		"failed":       res.Failed,
+		"orphans":      res.Orphans,
+	})
+	if err != nil && ctx.Err() == nil {
+		entry.WithError(err).Warn("Tiering pass failed")
+	} else if res.Transitioned > 0 || res.Failed > 0 || res.Orphans > 0 {
+		entry.Info("Tiering pass complete")
+	}
+	return err
+}
+
+// Start runs a pass every interval until Stop is called.
+func (w *Worker) Start() {
+	if w == nil || !w.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_ = w.Pass(w.ctx)
+			case <-w.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the loop, cancelling a pass in progress, and waits for it to
+// return or ctx to expire. An object interrupted mid-transition stays
+// where it was; a secondary copy it leaves behind is collected later.
+func (w *Worker) Stop(ctx context.Context) error {
+	if w == nil {
+		return nil
+	}
+	w.cancel()
+	if !w.started.Load() {
+		return nil
+	}
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// userMetadata returns the x-amz-meta-* entries of meta.
+func userMetadata(meta map[string]string) map[string]string {
+	out := make(map[string]string)
+	for k, v := range meta {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-meta-") {
+			out[lk] = v
+		}
+	}
+	return out
+}
+
+func parseListTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02T15:04:05.000Z", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,216 @@
+package tiering
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/accessstats"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+var encryptedMeta = map[string]string{
+	crypto.MetaEncrypted:    "true",
+	crypto.MetaOriginalSize: "10",
+	"x-amz-meta-color":      "blue",
+}
+
+func put(t *testing.T, c *testsupport.MemoryClient, bucket, key string, body []byte, meta map[string]string) {
+	t.Helper()
+	size := int64(len(body))
+	if err := c.PutObject(context.Background(), bucket, key, bytes.NewReader(body), meta, &size, "", nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func read(t *testing.T, c interface {
+	GetObject(ctx context.Context, bucket, key string, versionID *string, rangeHeader *string) (io.ReadCloser, map[string]string, error)
+}, bucket, key string, rng *string) ([]byte, map[string]string) {
+	t.Helper()
+	body, meta, err := c.GetObject(context.Background(), bucket, key, nil, rng)
+	if err != nil {
+		t.Fatalf("GetObject %s/%s: %v", bucket, key, err)
+	}
+	defer body.Close()
+	data, _ := io.ReadAll(body)
+	return data, meta
+}
+
+func newTestWorker(primary, secondary *testsupport.MemoryClient, stats AccessStats, rules ...config.TieringRule) *Worker {
+	cfg := config.TieringConfig{Enabled: true, Interval: time.Hour, Rules: rules}
+	var w *Worker
+	if secondary == nil {
+		w = NewWorker(primary, nil, cfg, stats, nil, nil)
+	} else {
+		w = NewWorker(primary, secondary, cfg, stats, nil, nil)
+	}
+	w.now = func() time.Time { return time.Now().Add(48 * time.Hour) }
+	return w
+}
+
+func TestWorker_MovesToSecondaryAndReadsFollowStub(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := testsupport.NewMemoryClient(), testsupport.NewMemoryClient()
+	ciphertext := []byte("0123456789ciphertext")
+	put(t, primary, "b", "logs/old", ciphertext, encryptedMeta)
+	put(t, primary, "b", "logs/plain", []byte("plaintext"), nil)
+	put(t, primary, "b", "other/old", ciphertext, encryptedMeta)
+
+	w := newTestWorker(primary, secondary, nil, config.TieringRule{
+		Bucket: "b", Prefix: "logs/", MinAge: 24 * time.Hour,
+		Secondary: true, SecondaryBucket: "cold", StorageClass: "GLACIER_IR",
+	})
+	res, err := w.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Transitioned != 1 || res.Bytes != int64(len(ciphertext)) || res.Failed != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	stub, stubMeta, _ := primary.Object("b", "logs/old")
+	if len(stub) != 0 || stubMeta[MetaLocation] != "cold" || stubMeta["x-amz-meta-color"] != "blue" {
+		t.Errorf("stub = %q %v", stub, stubMeta)
+	}
+	moved, movedMeta, ok := secondary.Object("cold", "logs/old")
+	if !ok || !bytes.Equal(moved, ciphertext) || movedMeta[crypto.MetaEncrypted] != "true" {
+		t.Errorf("secondary copy = %q %v (ok=%v)", moved, movedMeta, ok)
+	}
+	if got := secondary.StorageClass("cold", "logs/old"); got != "GLACIER_IR" {
+		t.Errorf("storage class = %q", got)
+	}
+	if _, meta, _ := primary.Object("b", "other/old"); meta[MetaLocation] != "" {
+		t.Error("object outside the rule's prefix was moved")
+	}
+
+	client := NewClient(primary, secondary)
+	data, meta := read(t, client, "b", "logs/old", nil)
+	if !bytes.Equal(data, ciphertext) || meta[MetaLocation] != "" || meta["Content-Length"] != "20" {
+		t.Errorf("GET through stub = %q %v", data, meta)
+	}
+	rng := "bytes=2-5"
+	if data, _ := read(t, client, "b", "logs/old", &rng); string(data) != "2345" {
+		t.Errorf("ranged GET through stub = %q", data)
+	}
+	head, err := client.HeadObject(ctx, "b", "logs/old", nil)
+	if err != nil || head["Content-Length"] != "20" {
+		t.Errorf("HEAD through stub = %v, %v", head, err)
+	}
+
+	res, err = w.Run(ctx)
+	if err != nil || res.Transitioned != 0 {
+		t.Errorf("second pass moved objects again: %+v, %v", res, err)
+	}
+}
+
+func TestWorker_StorageClassInPlace(t *testing.T) {
+	primary := testsupport.NewMemoryClient()
+	put(t, primary, "b", "k", []byte("ciphertext"), encryptedMeta)
+
+	w := newTestWorker(primary, nil, nil, config.TieringRule{Bucket: "b", MinAge: time.Hour, StorageClass: "STANDARD_IA"})
+	res, err := w.Run(context.Background())
+	if err != nil || res.Transitioned != 1 {
+		t.Fatalf("Run = %+v, %v", res, err)
+	}
+	data, meta, _ := primary.Object("b", "k")
+	if string(data) != "ciphertext" || meta[MetaStorageClass] != "STANDARD_IA" || meta["x-amz-meta-color"] != "blue" {
+		t.Errorf("object = %q %v", data, meta)
+	}
+	if got := primary.StorageClass("b", "k"); got != "STANDARD_IA" {
+		t.Errorf("storage class = %q", got)
+	}
+	if res, _ := w.Run(context.Background()); res.Transitioned != 0 {
+		t.Error("object rewritten although already in the target class")
+	}
+}
+
+type fakeStats map[string]time.Time
+
+func (f fakeStats) Query(ctx context.Context, q accessstats.Query) ([]accessstats.Entry, error) {
+	var out []accessstats.Entry
+	for k, at := range f {
+		out = append(out, accessstats.Entry{Key: k, Counter: accessstats.Counter{Reads: 1, LastAccess: at}})
+	}
+	return out, nil
+}
+
+func TestWorker_ColdAfterUsesLastAccess(t *testing.T) {
+	primary := testsupport.NewMemoryClient()
+	for _, k := range []string{"hot", "cold", "unread"} {
+		put(t, primary, "b", k, []byte("ciphertext"), encryptedMeta)
+	}
+	now := time.Now().Add(48 * time.Hour)
+	stats := fakeStats{"hot": now.Add(-time.Hour), "cold": now.Add(-30 * time.Hour)}
+
+	w := newTestWorker(primary, nil, stats, config.TieringRule{Bucket: "b", ColdAfter: 24 * time.Hour, StorageClass: "STANDARD_IA"})
+	w.now = func() time.Time { return now }
+	if _, err := w.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{"hot": "", "cold": "STANDARD_IA", "unread": "STANDARD_IA"} {
+		if got := primary.StorageClass("b", key); got != want {
+			t.Errorf("%s: storage class = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestWorker_CollectsOrphans(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := testsupport.NewMemoryClient(), testsupport.NewMemoryClient()
+	put(t, primary, "b", "deleted", []byte("ciphertext"), encryptedMeta)
+	put(t, primary, "b", "kept", []byte("ciphertext"), encryptedMeta)
+	rule := config.TieringRule{Bucket: "b", MinAge: time.Hour, Secondary: true}
+	w := newTestWorker(primary, secondary, nil, rule)
+	if res, err := w.Run(ctx); err != nil || res.Transitioned != 2 {
+		t.Fatalf("Run = %+v, %v", res, err)
+	}
+
+	if err := primary.DeleteObject(ctx, "b", "deleted", nil); err != nil {
+		t.Fatal(err)
+	}
+	res, err := w.Run(ctx)
+	if err != nil || res.Orphans != 1 {
+		t.Fatalf("Run = %+v, %v", res, err)
+	}
+	if _, _, ok := secondary.Object("b", "deleted"); ok {
+		t.Error("orphaned copy not deleted")
+	}
+	if _, _, ok := secondary.Object("b", "kept"); !ok {
+		t.Error("copy behind a live stub was deleted")
+	}
+}
+
+func TestWorker_SkipsIneligibleObjects(t *testing.T) {
+	primary, secondary := testsupport.NewMemoryClient(), testsupport.NewMemoryClient()
+	put(t, primary, "b", "plain", []byte("x"), nil)
+	put(t, primary, "b", "mpu", []byte("x"), map[string]string{crypto.MetaEncrypted: "true", crypto.MetaMPUEncrypted: "true"})
+	put(t, primary, "b", ".s3eg-access/stats.json", []byte("{}"), encryptedMeta)
+	put(t, primary, "b", "fresh", []byte("x"), encryptedMeta)
+
+	cfg := config.TieringConfig{Enabled: true, Interval: time.Hour, Rules: []config.TieringRule{{Bucket: "b", MinAge: time.Hour, Secondary: true}}}
+	w := NewWorker(primary, secondary, cfg, nil, func(key string) bool { return key == ".s3eg-access/stats.json" }, nil)
+	res, err := w.Run(context.Background())
+	if err != nil || res.Transitioned != 0 || res.Failed != 0 {
+		t.Errorf("Run = %+v, %v", res, err)
+	}
+	if NewWorker(primary, nil, config.TieringConfig{}, nil, nil, nil) != nil {
+		t.Error("disabled config must return a nil worker")
+	}
+	var nilWorker *Worker
+	if err := nilWorker.Stop(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWorker_StopWithoutStart(t *testing.T) {
+	w := newTestWorker(testsupport.NewMemoryClient(), nil, nil, config.TieringRule{Bucket: "b", MinAge: time.Hour, StorageClass: "X"})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := w.Stop(ctx); err != nil {
+		t.Errorf("Stop = %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBody bounds how much of a failing service's response is reported.
+const maxErrorBody = 4096
+
+// HTTPService POSTs the plaintext to a URL. A 200 response body is the
+// transformed object; any other status is an error.
+//
+// The request carries the object's Content-Type and the X-S3EG-Bucket and
+// X-S3EG-Key headers. The response Content-Type, when set, replaces the
+// object's.
+type HTTPService struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewHTTPService returns a service posting to url.
+func NewHTTPService(url string) *HTTPService {
+	return &HTTPService{URL: url, Client: &http.Client{}}
+}
+
+// Transform implements Service.
+func (s *HTTPService) Transform(ctx context.Context, req Request, in io.Reader) (*Result, error) {
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, in)
+	if err != nil {
+		return nil, err
+	}
+	if req.ContentType != "" {
+		hreq.Header.Set("Content-Type", req.ContentType)
+	}
+	hreq.Header.Set("X-S3EG-Bucket", req.Bucket)
+	hreq.Header.Set("X-S3EG-Key", req.Key)
+
+	resp, err := s.Client.Do(hreq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		resp.Body.Close()
+		return nil, fmt.Errorf("service returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return &Result{Body: resp.Body, ContentType: resp.Header.Get("Content-Type")}, nil
+}
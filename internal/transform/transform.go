@@ -0,0 +1,169 @@
+// Package transform rewrites decrypted GetObject responses.
+//
+// A GET on a bucket and key prefix covered by a rule is decrypted as usual,
+// then the plaintext is streamed to the rule's service and the service's
+// output is returned to the client instead of the object, in the style of
+// S3 Object Lambda. Typical uses are redacting columns of a CSV export or
+// serving resized images. Services see plaintext only; they never receive
+// ciphertext, encryption metadata or key material.
+//
+// Transformed responses have a different length and content than the stored
+// object, so ranged GETs on a covered key return the whole transformed
+// object and are not served from the object cache.
+package transform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+// DefaultTimeout bounds a transformation, including streaming its output,
+// when the rule sets no timeout.
+const DefaultTimeout = 30 * time.Second
+
+// ErrOutputTooLarge is returned while reading a transformed body that
+// exceeds the configured maximum.
+var ErrOutputTooLarge = errors.New("transform: output exceeds max_output_size")
+
+// Request describes the object being transformed.
+type Request struct {
+	Bucket      string
+	Key         string
+	ContentType string
+}
+
+// Result is a transformed response body. The caller must close Body.
+type Result struct {
+	Body        io.ReadCloser
+	ContentType string
+}
+
+// Service transforms a plaintext stream. Implementations return once the
+// output can be streamed, and report a failure of the transformation itself
+// as an error rather than as output.
+type Service interface {
+	Transform(ctx context.Context, req Request, in io.Reader) (*Result, error)
+}
+
+// Rule is a compiled transformation rule.
+type Rule struct {
+	Name    string
+	Bucket  string
+	Prefix  string
+	Timeout time.Duration
+	Service Service
+}
+
+// Matches reports whether a GET of bucket/key is transformed by r.
+func (r *Rule) Matches(bucket, key string) bool {
+	return bucket == r.Bucket && strings.HasPrefix(key, r.Prefix)
+}
+
+// Transformer selects rules and runs their services. All methods are safe on
+// a nil *Transformer, which transforms nothing.
+type Transformer struct {
+	rules     []*Rule
+	maxOutput int64
+}
+
+// New compiles the configured rules. Rules naming a module are served by
+// modules[name], the module's filter.
+func New(cfg config.TransformsConfig, modules map[string]Service) (*Transformer, error) {
+	t := &Transformer{maxOutput: cfg.MaxOutputSize}
+	for _, rc := range cfg.Rules {
+		var svc Service
+		switch {
+		case rc.Module != "":
+			svc = modules[rc.Module]
+			if svc == nil {
+				return nil, fmt.Errorf("transform: rule %q: module %q has no filter", rc.Name, rc.Module)
+			}
+		case rc.URL != "":
+			svc = NewHTTPService(rc.URL)
+		default:
+			return nil, fmt.Errorf("transform: rule %q has no url", rc.Name)
+		}
+		timeout := rc.Timeout
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+		t.rules = append(t.rules, &Rule{
+			Name:    rc.Name,
+			Bucket:  rc.Bucket,
+			Prefix:  rc.Prefix,
+			Timeout: timeout,
+			Service: svc,
+		})
+	}
+	return t, nil
+}
+
+// NewWithRules returns a transformer running the given rules.
+func NewWithRules(rules []*Rule, maxOutput int64) *Transformer {
+	return &Transformer{rules: rules, maxOutput: maxOutput}
+}
+
+// Match returns the first rule covering bucket/key, or nil.
+func (t *Transformer) Match(bucket, key string) *Rule {
+	if t == nil {
+		return nil
+	}
+	for _, r := range t.rules {
+		if r.Matches(bucket, key) {
+			return r
+		}
+	}
+	return nil
+}
+
+// Apply streams in through rule's service. The rule's timeout covers the
+// whole exchange, including reading the returned body, which fails with
+// ErrOutputTooLarge past the configured maximum.
+func (t *Transformer) Apply(ctx context.Context, rule *Rule, req Request, in io.Reader) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, rule.Timeout)
+	res, err := rule.Service.Transform(ctx, req, in)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("transform %s: %w", rule.Name, err)
+	}
+	res.Body = &limitedBody{rc: res.Body, n: t.maxOutput, cancel: cancel}
+	return res, nil
+}
+
+// limitedBody caps a transformed body and releases the rule's context when
+// closed.
+type limitedBody struct {
+	rc     io.ReadCloser
+	n      int64
+	cancel context.CancelFunc
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.n <= 0 {
+		// Only fail if the service actually has more to send.
+		var one [1]byte
+		n, err := b.rc.Read(one[:])
+		if n > 0 {
+			return 0, ErrOutputTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.n {
+		p = p[:b.n]
+	}
+	n, err := b.rc.Read(p)
+	b.n -= int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	err := b.rc.Close()
+	b.cancel()
+	return err
+}
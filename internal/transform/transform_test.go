@@ -0,0 +1,77 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeService struct {
+	out string
+	ctx context.Context
+}
+
+func (f *fakeService) Transform(ctx context.Context, _ Request, in io.Reader) (*Result, error) {
+	f.ctx = ctx
+	if _, err := io.Copy(io.Discard, in); err != nil {
+		return nil, err
+	}
+	return &Result{Body: io.NopCloser(strings.NewReader(f.out))}, nil
+}
+
+func TestMatch(t *testing.T) {
+	var none *Transformer
+	if none.Match("b", "k") != nil {
+		t.Error("nil transformer matched")
+	}
+
+	broad := &Rule{Name: "broad", Bucket: "b"}
+	narrow := &Rule{Name: "narrow", Bucket: "b", Prefix: "img/"}
+	tr := NewWithRules([]*Rule{narrow, broad}, 1)
+	for _, tc := range []struct {
+		bucket, key string
+		want        *Rule
+	}{
+		{"b", "img/cat.png", narrow},
+		{"b", "docs/a.txt", broad},
+		{"other", "img/cat.png", nil},
+	} {
+		if got := tr.Match(tc.bucket, tc.key); got != tc.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tc.bucket, tc.key, got, tc.want)
+		}
+	}
+}
+
+func TestApply_OutputLimit(t *testing.T) {
+	for _, tc := range []struct {
+		out     string
+		wantErr error
+	}{
+		{"12345678", nil},
+		{"123456789", ErrOutputTooLarge},
+	} {
+		svc := &fakeService{out: tc.out}
+		rule := &Rule{Name: "r", Bucket: "b", Timeout: time.Minute, Service: svc}
+		res, err := NewWithRules([]*Rule{rule}, 8).Apply(context.Background(), rule, Request{}, strings.NewReader("in"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(res.Body)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%d-byte output: error = %v, want %v", len(tc.out), err, tc.wantErr)
+		}
+		if len(got) > 8 {
+			t.Errorf("%d-byte output: read %d bytes past the limit", len(tc.out), len(got))
+		}
+		if svc.ctx.Err() != nil {
+			t.Error("rule context cancelled before the body was closed")
+		}
+		res.Body.Close()
+		if svc.ctx.Err() == nil {
+			t.Error("closing the body does not release the rule context")
+		}
+	}
+}
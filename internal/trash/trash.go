@@ -0,0 +1,240 @@
+// Package trash implements deferred deletes. Deleted objects are kept under
+// a trash prefix, in a key recording when and under which key they were
+// deleted, and purged once their retention has passed.
+package trash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/sirupsen/logrus"
+)
+
+// Metadata recorded on trashed objects.
+const (
+	// MetaOriginalKey is the key the object was deleted from.
+	MetaOriginalKey = "x-amz-meta-s3eg-trash-key"
+	// MetaDeletedAt is the time of deletion, in RFC 3339.
+	MetaDeletedAt = "x-amz-meta-s3eg-trash-deleted-at"
+)
+
+// stampLayout names the deletion time in trash keys. It sorts by time and
+// is unique enough that deleting a key twice keeps both copies.
+const stampLayout = "20060102T150405.000000000Z"
+
+const manifestSuffix = ".mpu-manifest"
+
+// Result counts what one purge pass did.
+type Result struct {
+	Purged int `json:"purged"`
+	Failed int `json:"failed"`
+}
+
+// Bin decides which deletes are deferred and purges expired trash. A nil
+// Bin defers nothing.
+type Bin struct {
+	client    s3.Client
+	buckets   map[string]bool
+	prefix    string
+	retention time.Duration
+	interval  time.Duration
+	logger    *logrus.Logger
+	now       func() time.Time
+
+	runMu   sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started atomic.Bool
+	done    chan struct{}
+}
+
+// New returns the trash for cfg, or nil when cfg disables it. client is
+// the backend client the purge worker lists and deletes with.
+func New(client s3.Client, cfg config.TrashConfig, logger *logrus.Logger) *Bin {
+	if !cfg.Enabled || client == nil {
+		return nil
+	}
+	buckets := make(map[string]bool, len(cfg.Buckets))
+	for _, b := range cfg.Buckets {
+		buckets[b] = true
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Bin{
+		client:    client,
+		buckets:   buckets,
+		prefix:    cfg.Prefix,
+		retention: cfg.Retention,
+		interval:  cfg.Interval,
+		logger:    logger,
+		now:       time.Now,
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+}
+
+// Applies reports whether deleting bucket/key should move it to the trash.
+// Keys already in the trash are deleted for good.
+func (b *Bin) Applies(bucket, key string) bool {
+	return b != nil && b.buckets[bucket] && !b.IsTrashKey(key)
+}
+
+// IsTrashKey reports whether key lies under the trash prefix.
+func (b *Bin) IsTrashKey(key string) bool {
+	return b != nil && strings.HasPrefix(key, b.prefix)
+}
+
+// Key returns the trash key for key deleted at.
+func (b *Bin) Key(key string, at time.Time) string {
+	return b.prefix + at.UTC().Format(stampLayout) + "/" + key
+}
+
+// Now returns the bin's clock.
+func (b *Bin) Now() time.Time {
+	return b.now()
+}
+
+// Parse returns the original key and deletion time recorded in trashKey.
+func (b *Bin) Parse(trashKey string) (key string, at time.Time, ok bool) {
+	rest, found := strings.CutPrefix(trashKey, b.prefix)
+	if !found {
+		return "", time.Time{}, false
+	}
+	stamp, key, found := strings.Cut(rest, "/")
+	if !found || key == "" {
+		return "", time.Time{}, false
+	}
+	at, err := time.Parse(stampLayout, stamp)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return key, at, true
+}
+
+// Run purges every configured bucket once. A failure to list a bucket ends
+// the pass; failures on single objects are logged, counted and skipped.
+func (b *Bin) Run(ctx context.Context) (Result, error) {
+	var res Result
+	if b == nil {
+		return res, nil
+	}
+	b.runMu.Lock()
+	defer b.runMu.Unlock()
+	for bucket := range b.buckets {
+		if err := b.purge(ctx, bucket, &res); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+func (b *Bin) purge(ctx context.Context, bucket string, res *Result) error {
+	now := b.now()
+	opts := s3.ListOptions{MaxKeys: 1000}
+	for {
+		page, err := b.client.ListObjects(ctx, bucket, b.prefix, opts)
+		if err != nil {
+			return fmt.Errorf("trash: list %s/%s: %w", bucket, b.prefix, err)
+		}
+		for _, obj := range page.Objects {
+			if strings.HasSuffix(obj.Key, manifestSuffix) {
+				continue
+			}
+			_, at, ok := b.Parse(obj.Key)
+			if !ok || now.Sub(at) < b.retention {
+				continue
+			}
+			if err := b.delete(ctx, bucket, obj.Key); err != nil {
+				res.Failed++
+				if b.logger != nil {
+					b.logger.WithError(err).WithFields(logrus.Fields{
+						"bucket": bucket,
+						"key":    obj.Key,
+					}).Warn("Failed to purge trashed object")
+				}
+				continue
+			}
+			res.Purged++
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return nil
+		}
+		opts.ContinuationToken = page.NextContinuationToken
+	}
+}
+
+// delete removes a trashed object and its MPU manifest companion, the
+// companion last so the object never points at a missing manifest.
+func (b *Bin) delete(ctx context.Context, bucket, key string) error {
+	if err := b.client.DeleteObject(ctx, bucket, key, nil); err != nil && !errors.Is(err, s3.ErrNotFound) {
+		return err
+	}
+	if err := b.client.DeleteObject(ctx, bucket, key+manifestSuffix, nil); err != nil && !errors.Is(err, s3.ErrNotFound) {
+		return fmt.Errorf("delete manifest: %w", err)
+	}
+	return nil
+}
+
+// Purge runs one purge pass and logs its outcome, for callers that
+// schedule passes instead of calling Start.
+func (b *Bin) Purge(ctx context.Context) error {
+	res, err := b.Run(ctx)
+	if b.logger == nil {
+		return err
+	}
+	entry := b.logger.WithFields(logrus.Fields{
+		"purged": res.Purged,
+		"failed": res.Failed,
+	})
+	if err != nil && ctx.Err() == nil {
+		entry.WithError(err).Warn("Trash purge failed")
+	} else if res.Purged > 0 || res.Failed > 0 {
+		entry.Info("Trash purge complete")
+	}
+	return err
+}
+
+// Start purges expired trash every interval until Stop is called.
+func (b *Bin) Start() {
+	if b == nil || !b.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer close(b.done)
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_ = b.Purge(b.ctx)
+			case <-b.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the purge loop, cancelling a pass in progress, and waits for it
+// to return or ctx to expire.
+func (b *Bin) Stop(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	b.cancel()
+	if !b.started.Load() {
+		return nil
+	}
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
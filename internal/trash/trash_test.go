@@ -0,0 +1,91 @@
+package trash
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+func newBin(c *testsupport.MemoryClient, now time.Time) *Bin {
+	b := New(c, config.TrashConfig{
+		Enabled:   true,
+		Buckets:   []string{"bucket"},
+		Prefix:    ".trash/",
+		Retention: 24 * time.Hour,
+		Interval:  time.Hour,
+	}, nil)
+	b.now = func() time.Time { return now }
+	return b
+}
+
+func TestBin_KeyRoundTrip(t *testing.T) {
+	b := newBin(testsupport.NewMemoryClient(), time.Now())
+	at := time.Date(2026, 3, 1, 12, 30, 0, 123, time.UTC)
+	trashKey := b.Key("dir/report.pdf", at)
+	if trashKey != ".trash/20260301T123000.000000123Z/dir/report.pdf" {
+		t.Fatalf("Key = %q", trashKey)
+	}
+	key, got, ok := b.Parse(trashKey)
+	if !ok || key != "dir/report.pdf" || !got.Equal(at) {
+		t.Errorf("Parse = %q, %v, %v", key, got, ok)
+	}
+	for _, k := range []string{"dir/report.pdf", ".trash/report.pdf", ".trash/not-a-time/report.pdf", ".trash/20260301T123000.000000123Z/"} {
+		if _, _, ok := b.Parse(k); ok {
+			t.Errorf("Parse(%q) accepted", k)
+		}
+	}
+
+	if !b.Applies("bucket", "dir/report.pdf") || b.Applies("bucket", trashKey) || b.Applies("other", "dir/report.pdf") {
+		t.Error("Applies is wrong")
+	}
+	var none *Bin
+	if none.Applies("bucket", "dir/report.pdf") {
+		t.Error("nil bin applies")
+	}
+	if New(testsupport.NewMemoryClient(), config.TrashConfig{}, nil) != nil {
+		t.Error("disabled config built a bin")
+	}
+}
+
+func TestBin_RunPurgesExpiredTrash(t *testing.T) {
+	c := testsupport.NewMemoryClient()
+	now := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	b := newBin(c, now)
+	put := func(key string) {
+		t.Helper()
+		size := int64(1)
+		if err := c.PutObject(context.Background(), "bucket", key, bytes.NewReader([]byte("x")), nil, &size, "", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expired := b.Key("old", now.Add(-48*time.Hour))
+	fresh := b.Key("new", now.Add(-time.Hour))
+	put(expired)
+	put(expired + ".mpu-manifest")
+	put(fresh)
+	put(".trash/unrelated")
+	put("live")
+
+	res, err := b.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Purged != 1 || res.Failed != 0 {
+		t.Errorf("Run = %+v", res)
+	}
+	for key, want := range map[string]bool{
+		expired:                   false,
+		expired + ".mpu-manifest": false,
+		fresh:                     true,
+		".trash/unrelated":        true,
+		"live":                    true,
+	} {
+		if _, _, ok := c.Object("bucket", key); ok != want {
+			t.Errorf("%s present = %v, want %v", key, ok, want)
+		}
+	}
+}
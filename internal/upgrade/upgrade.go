@@ -0,0 +1,208 @@
+// Package upgrade rewrites legacy objects in chunked format after they have
+// been read through the gateway.
+//
+// Objects written before chunked mode use a single IV over the whole body,
+// so every range request has to fetch and decrypt the entire object. When a
+// GET has just served such an object in full, the gateway hands it to the
+// Upgrader, which re-encrypts it in the background at a bounded rate. Hot
+// legacy objects are upgraded first, without a dedicated migration window.
+//
+// Upgrades are best effort: the queue is bounded, candidates that do not fit
+// are dropped (the next full read offers them again), and queued work is
+// abandoned at shutdown.
+package upgrade
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Candidate is a legacy object that has just been read in full.
+type Candidate struct {
+	Bucket string
+	Key    string
+	// ETag is the backend ETag of the version that was read. Objects that
+	// changed since are not rewritten.
+	ETag string
+	// Size is the stored size, or 0 when unknown.
+	Size int64
+}
+
+// Rewriter re-encrypts a single object in chunked format.
+type Rewriter interface {
+	// UpgradeObject rewrites bucket/key if it still has the given ETag and
+	// is still in the legacy format. It reports whether it rewrote it.
+	UpgradeObject(ctx context.Context, bucket, key, etag string) (bool, error)
+}
+
+// Upgrader queues candidates and rewrites them one at a time, at most Rate
+// per second. All methods are safe on a nil *Upgrader, which behaves as a
+// disabled upgrader.
+type Upgrader struct {
+	rewriter Rewriter
+	logger   *logrus.Logger
+	interval time.Duration
+	maxSize  int64
+
+	mu      sync.Mutex // guards pending and stopped against the worker
+	queue   chan Candidate
+	pending map[string]struct{}
+	stopped bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	upgraded atomic.Int64
+	failed   atomic.Int64
+	dropped  atomic.Int64
+}
+
+// New returns an upgrader rewriting through rewriter, or nil when lazy
+// upgrades are disabled. Zero settings fall back to the defaults.
+func New(cfg config.LazyUpgradeConfig, rewriter Rewriter, logger *logrus.Logger) *Upgrader {
+	if !cfg.Enabled {
+		return nil
+	}
+	rate := cfg.Rate
+	if rate <= 0 {
+		rate = config.DefaultLazyUpgradeRate
+	}
+	maxSize := cfg.MaxObjectSize
+	if maxSize <= 0 {
+		maxSize = config.DefaultLazyUpgradeMaxObjectSize
+	}
+	queueSize := cfg.QueueSize
+	if queueSize < 1 {
+		queueSize = config.DefaultLazyUpgradeQueueSize
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Upgrader{
+		rewriter: rewriter,
+		logger:   logger,
+		interval: time.Duration(float64(time.Second) / rate),
+		maxSize:  maxSize,
+		queue:    make(chan Candidate, queueSize),
+		pending:  make(map[string]struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start launches the worker.
+func (u *Upgrader) Start() {
+	if u == nil {
+		return
+	}
+	go u.run()
+}
+
+// Notify queues c without blocking. Candidates that are too large, already
+// queued, or do not fit in the queue are ignored.
+func (u *Upgrader) Notify(c Candidate) {
+	if u == nil || c.Size > u.maxSize || c.ETag == "" {
+		return
+	}
+	id := c.Bucket + "/" + c.Key
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.stopped {
+		return
+	}
+	if _, ok := u.pending[id]; ok {
+		return
+	}
+	select {
+	case u.queue <- c:
+		u.pending[id] = struct{}{}
+	default:
+		// A full queue is expected while a large legacy working set is
+		// read; the object is offered again on its next read.
+		n := u.dropped.Add(1)
+		u.logger.WithFields(logrus.Fields{
+			"bucket":  c.Bucket,
+			"key":     c.Key,
+			"dropped": n,
+		}).Debug("Lazy upgrade queue full; candidate dropped")
+	}
+}
+
+// Stats returns the number of objects upgraded, the number of failed
+// upgrades and the number of candidates dropped because the queue was full.
+func (u *Upgrader) Stats() (upgraded, failed, dropped int64) {
+	if u == nil {
+		return 0, 0, 0
+	}
+	return u.upgraded.Load(), u.failed.Load(), u.dropped.Load()
+}
+
+// Stop stops accepting candidates, abandons queued ones and waits for the
+// rewrite in progress to end, or for ctx to end.
+func (u *Upgrader) Stop(ctx context.Context) error {
+	if u == nil {
+		return nil
+	}
+	u.mu.Lock()
+	u.stopped = true
+	u.mu.Unlock()
+	u.cancel()
+	select {
+	case <-u.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (u *Upgrader) run() {
+	defer close(u.done)
+	var next time.Time
+	for {
+		var c Candidate
+		select {
+		case <-u.ctx.Done():
+			return
+		case c = <-u.queue:
+		}
+		if wait := time.Until(next); wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-u.ctx.Done():
+				t.Stop()
+				return
+			case <-t.C:
+			}
+		}
+		next = time.Now().Add(u.interval)
+		u.upgrade(c)
+		u.mu.Lock()
+		delete(u.pending, c.Bucket+"/"+c.Key)
+		u.mu.Unlock()
+	}
+}
+
+func (u *Upgrader) upgrade(c Candidate) {
+	start := time.Now()
+	entry := u.logger.WithFields(logrus.Fields{
+		"bucket": c.Bucket,
+		"key":    c.Key,
+	})
+	done, err := u.rewriter.UpgradeObject(u.ctx, c.Bucket, c.Key, c.ETag)
+	entry = entry.WithField("duration", time.Since(start))
+	switch {
+	case err != nil:
+		u.failed.Add(1)
+		entry.WithError(err).Warn("Lazy upgrade failed")
+	case !done:
+		entry.Debug("Lazy upgrade skipped; object changed or no longer legacy")
+	default:
+		u.upgraded.Add(1)
+		entry.Info("Upgraded legacy object to chunked format")
+	}
+}
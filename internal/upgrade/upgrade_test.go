@@ -0,0 +1,148 @@
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+	times []time.Time
+	block chan struct{}
+	err   error
+}
+
+func (r *recorder) UpgradeObject(ctx context.Context, bucket, key, etag string) (bool, error) {
+	if r.block != nil {
+		select {
+		case <-r.block:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, bucket+"/"+key+"@"+etag)
+	r.times = append(r.times, time.Now())
+	return r.err == nil, r.err
+}
+
+func (r *recorder) snapshot() ([]string, []time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...), append([]time.Time(nil), r.times...)
+}
+
+func testLogger() *logrus.Logger {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	return l
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestNew_Disabled(t *testing.T) {
+	u := New(config.LazyUpgradeConfig{}, &recorder{}, testLogger())
+	if u != nil {
+		t.Fatal("disabled config returned an upgrader")
+	}
+	// A nil upgrader is inert.
+	u.Start()
+	u.Notify(Candidate{Bucket: "b", Key: "k", ETag: `"e"`})
+	if err := u.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpgrader_RateAndFilters(t *testing.T) {
+	rec := &recorder{}
+	u := New(config.LazyUpgradeConfig{Enabled: true, Rate: 20, MaxObjectSize: 100, QueueSize: 10}, rec, testLogger())
+	u.Start()
+	defer u.Stop(context.Background())
+
+	u.Notify(Candidate{Bucket: "b", Key: "big", ETag: `"1"`, Size: 101})
+	u.Notify(Candidate{Bucket: "b", Key: "no-etag"})
+	for _, k := range []string{"a", "b", "c"} {
+		u.Notify(Candidate{Bucket: "b", Key: k, ETag: `"` + k + `"`, Size: 10})
+	}
+	waitFor(t, func() bool { calls, _ := rec.snapshot(); return len(calls) == 3 })
+
+	calls, times := rec.snapshot()
+	want := []string{`b/a@"a"`, `b/b@"b"`, `b/c@"c"`}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("calls = %v, want %v", calls, want)
+		}
+	}
+	// 20/s leaves at least 50ms between rewrites.
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < 45*time.Millisecond {
+			t.Errorf("rewrite %d started %s after the previous one", i, gap)
+		}
+	}
+	if upgraded, failed, dropped := u.Stats(); upgraded != 3 || failed != 0 || dropped != 0 {
+		t.Errorf("Stats() = %d, %d, %d", upgraded, failed, dropped)
+	}
+}
+
+func TestUpgrader_DedupAndDrop(t *testing.T) {
+	rec := &recorder{block: make(chan struct{})}
+	u := New(config.LazyUpgradeConfig{Enabled: true, Rate: 1000, MaxObjectSize: 100, QueueSize: 1}, rec, testLogger())
+
+	// Not started: the first candidate fills the queue.
+	u.Notify(Candidate{Bucket: "b", Key: "k", ETag: `"1"`})
+	u.Notify(Candidate{Bucket: "b", Key: "k", ETag: `"1"`})
+	u.Notify(Candidate{Bucket: "b", Key: "other", ETag: `"2"`})
+	if _, _, dropped := u.Stats(); dropped != 1 {
+		t.Errorf("dropped = %d, want 1 (the duplicate is not queued)", dropped)
+	}
+
+	u.Start()
+	close(rec.block)
+	waitFor(t, func() bool { calls, _ := rec.snapshot(); return len(calls) == 1 })
+	// Once processed, the key may be queued again.
+	u.Notify(Candidate{Bucket: "b", Key: "k", ETag: `"3"`})
+	waitFor(t, func() bool { calls, _ := rec.snapshot(); return len(calls) == 2 })
+	if err := u.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpgrader_StopAbandonsQueue(t *testing.T) {
+	rec := &recorder{block: make(chan struct{}), err: errors.New("unused")}
+	u := New(config.LazyUpgradeConfig{Enabled: true, Rate: 1, MaxObjectSize: 100, QueueSize: 10}, rec, testLogger())
+	u.Start()
+	for _, k := range []string{"a", "b", "c"} {
+		u.Notify(Candidate{Bucket: "b", Key: k, ETag: `"x"`})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := u.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if calls, _ := rec.snapshot(); len(calls) != 0 {
+		t.Errorf("calls after stop = %v", calls)
+	}
+	u.Notify(Candidate{Bucket: "b", Key: "late", ETag: `"x"`})
+	if _, _, dropped := u.Stats(); dropped != 0 {
+		t.Errorf("a candidate after Stop was counted as dropped")
+	}
+}
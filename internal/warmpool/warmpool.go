@@ -0,0 +1,140 @@
+// Package warmpool keeps the gateway's connections to its dependencies open
+// between requests.
+//
+// Go's HTTP transports close idle connections after a while, and a burst
+// of requests after a quiet period then pays for TCP and TLS handshakes to
+// the backend one request at a time. A Pool runs a cheap health check
+// against each target on a schedule, several at once, so that many
+// connections are (re)established and left in the idle pool. The checks
+// double as background health monitoring: failures are logged and counted
+// but never change readiness.
+package warmpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+// Recorder is the subset of metrics.Metrics used by the pool.
+type Recorder interface {
+	RecordWarmPoolCheck(target, outcome string)
+}
+
+// Target is a dependency kept warm.
+type Target struct {
+	Name string
+	// Check is a cheap request against the dependency, such as listing
+	// one key.
+	Check func(ctx context.Context) error
+	// Connections is the number of checks run at once. Zero uses the
+	// pool's setting; dependencies reached over a single multiplexed
+	// connection need only one.
+	Connections int
+}
+
+// Pool runs the health checks of its targets.
+type Pool struct {
+	targets     []Target
+	interval    time.Duration
+	connections int
+	timeout     time.Duration
+	recorder    Recorder
+	logger      *logrus.Logger
+}
+
+// New returns a Pool for targets. recorder and logger may be nil.
+func New(cfg config.WarmPoolConfig, recorder Recorder, logger *logrus.Logger, targets ...Target) *Pool {
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	return &Pool{
+		targets:     targets,
+		interval:    cfg.Interval,
+		connections: cfg.Connections,
+		timeout:     cfg.Timeout,
+		recorder:    recorder,
+		logger:      logger,
+	}
+}
+
+// Warm runs every target's checks once, concurrently, and returns the
+// failures joined.
+func (p *Pool) Warm(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	errs := make([]error, len(p.targets))
+	var wg sync.WaitGroup
+	for i, t := range p.targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = p.warm(ctx, t)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// warm runs target's checks and records the outcome. The target is
+// healthy if any check passed; the connections of failed checks are
+// simply not kept.
+func (p *Pool) warm(ctx context.Context, t Target) error {
+	n := t.Connections
+	if n <= 0 {
+		n = p.connections
+	}
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = t.Check(ctx)
+		}()
+	}
+	wg.Wait()
+
+	var err error
+	for _, e := range errs {
+		if e == nil {
+			err = nil
+			break
+		}
+		err = e
+	}
+	outcome := "ok"
+	if err != nil {
+		outcome = "failed"
+		err = fmt.Errorf("%s: %w", t.Name, err)
+		p.logger.WithError(err).WithField("target", t.Name).Warn("Warm pool health check failed")
+	}
+	if p.recorder != nil {
+		p.recorder.RecordWarmPoolCheck(t.Name, outcome)
+	}
+	return err
+}
+
+// Start warms the targets immediately and then every interval until ctx
+// is done.
+func (p *Pool) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			p.Warm(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
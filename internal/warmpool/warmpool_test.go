@@ -0,0 +1,123 @@
+package warmpool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+)
+
+type fakeRecorder struct {
+	mu       sync.Mutex
+	outcomes map[string]int
+}
+
+func (r *fakeRecorder) RecordWarmPoolCheck(target, outcome string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.outcomes[target+"/"+outcome]++
+}
+
+func testConfig() config.WarmPoolConfig {
+	return config.WarmPoolConfig{Enabled: true, Interval: time.Minute, Connections: 3, Timeout: 5 * time.Second}
+}
+
+func quietLogger() *logrus.Logger {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	return l
+}
+
+func TestWarm_KeepsConnectionsOpen(t *testing.T) {
+	var dials atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// Hold each check so the pool's checks overlap.
+		time.Sleep(50 * time.Millisecond)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			dials.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: 10}}
+	defer client.CloseIdleConnections()
+	check := func(ctx context.Context) error {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodHead, srv.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	rec := &fakeRecorder{outcomes: map[string]int{}}
+	p := New(testConfig(), rec, quietLogger(), Target{Name: "backend", Check: check})
+	for i := 0; i < 2; i++ {
+		if err := p.Warm(context.Background()); err != nil {
+			t.Fatalf("Warm: %v", err)
+		}
+	}
+	if got := dials.Load(); got != 3 {
+		t.Errorf("connections opened = %d, want 3 opened once and then reused", got)
+	}
+	if rec.outcomes["backend/ok"] != 2 {
+		t.Errorf("outcomes = %v", rec.outcomes)
+	}
+}
+
+func TestWarm_ReportsFailures(t *testing.T) {
+	var kmsCalls atomic.Int32
+	down := errors.New("connection refused")
+	rec := &fakeRecorder{outcomes: map[string]int{}}
+	p := New(testConfig(), rec, quietLogger(),
+		Target{Name: "backend", Check: func(context.Context) error { return down }},
+		Target{Name: "kms", Connections: 1, Check: func(context.Context) error {
+			kmsCalls.Add(1)
+			return nil
+		}},
+	)
+
+	err := p.Warm(context.Background())
+	if !errors.Is(err, down) || !strings.Contains(err.Error(), "backend") {
+		t.Fatalf("Warm() = %v, want the backend failure", err)
+	}
+	if kmsCalls.Load() != 1 {
+		t.Errorf("kms checks = %d, want 1", kmsCalls.Load())
+	}
+	if rec.outcomes["backend/failed"] != 1 || rec.outcomes["kms/ok"] != 1 {
+		t.Errorf("outcomes = %v", rec.outcomes)
+	}
+}
+
+func TestStart_RunsImmediately(t *testing.T) {
+	ran := make(chan struct{}, 1)
+	p := New(testConfig(), nil, quietLogger(), Target{Name: "kms", Connections: 1, Check: func(context.Context) error {
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+		return nil
+	}})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx)
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not warm the targets")
+	}
+}
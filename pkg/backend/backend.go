@@ -0,0 +1,67 @@
+// Package backend exposes the interface the gateway uses to reach its
+// object store, so code outside this module can implement it, wrap it or
+// hand the gateway a test double such as testsupport.MemoryClient.
+//
+// Every name here is an alias of the gateway's internal definition: a
+// Client written against this package is accepted wherever the gateway
+// takes one, and errors it returns are classified the same way.
+package backend
+
+import (
+	"context"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+// Client is the object store interface the gateway encrypts in front of.
+type Client = s3.Client
+
+// Types used in Client method signatures.
+type (
+	ObjectLockInput         = s3.ObjectLockInput
+	RetentionConfig         = s3.RetentionConfig
+	ObjectLockConfiguration = s3.ObjectLockConfiguration
+	LockRule                = s3.LockRule
+	DefaultRetention        = s3.DefaultRetention
+	ListOptions             = s3.ListOptions
+	ListResult              = s3.ListResult
+	ObjectInfo              = s3.ObjectInfo
+	CompletedPart           = s3.CompletedPart
+	PartInfo                = s3.PartInfo
+	ObjectIdentifier        = s3.ObjectIdentifier
+	DeletedObject           = s3.DeletedObject
+	ErrorObject             = s3.ErrorObject
+	CopyPartRange           = s3.CopyPartRange
+	CopyPartResult          = s3.CopyPartResult
+	Tag                     = s3.Tag
+)
+
+// WriteConditions are the If-Match / If-None-Match preconditions the
+// gateway attaches to a write with WithWriteConditions.
+type WriteConditions = s3.WriteConditions
+
+// Error classes. Client implementations return errors that match them with
+// errors.Is, for example by building them with NewAPIError.
+var (
+	ErrNotFound  = s3.ErrNotFound
+	ErrThrottled = s3.ErrThrottled
+)
+
+// NewAPIError returns an error carrying an S3 error code (e.g. "NoSuchKey"),
+// classified like one returned by an S3 backend.
+func NewAPIError(code, message string) error {
+	return s3.NewAPIError(code, message)
+}
+
+// WithWriteConditions returns a context that makes the next PutObject or
+// CompleteMultipartUpload issued with it conditional.
+func WithWriteConditions(ctx context.Context, c WriteConditions) context.Context {
+	return s3.WithWriteConditions(ctx, c)
+}
+
+// WriteConditionsFromContext returns the conditions attached by
+// WithWriteConditions, if any. Client implementations that support
+// conditional writes read them here.
+func WriteConditionsFromContext(ctx context.Context) (WriteConditions, bool) {
+	return s3.WriteConditionsFromContext(ctx)
+}
@@ -0,0 +1,55 @@
+package backend_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/pkg/backend"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+// The test lives outside the package and uses only public import paths, as
+// a downstream module would.
+func TestMemoryClient_ThroughPublicInterface(t *testing.T) {
+	var c backend.Client = testsupport.NewMemoryClient()
+	ctx := context.Background()
+
+	if err := c.PutObject(ctx, "b", "k", strings.NewReader("data"), nil, nil, "", nil); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	body, _, err := c.GetObject(ctx, "b", "k", nil, nil)
+	if err != nil {
+		t.Fatalf("GetObject: %v", err)
+	}
+	got, _ := io.ReadAll(body)
+	body.Close()
+	if string(got) != "data" {
+		t.Errorf("GetObject = %q", got)
+	}
+
+	res, err := c.ListObjects(ctx, "b", "", backend.ListOptions{})
+	if err != nil || len(res.Objects) != 1 || res.Objects[0].Key != "k" {
+		t.Errorf("ListObjects = %+v, %v", res, err)
+	}
+
+	if _, err := c.HeadObject(ctx, "b", "missing", nil); !errors.Is(err, backend.ErrNotFound) {
+		t.Errorf("HeadObject(missing) = %v, want backend.ErrNotFound", err)
+	}
+
+	create := backend.WithWriteConditions(ctx, backend.WriteConditions{IfNoneMatch: "*"})
+	if err := c.PutObject(create, "b", "k", strings.NewReader("again"), nil, nil, "", nil); err == nil {
+		t.Error("If-None-Match on an existing object should fail")
+	}
+}
+
+func TestNewAPIError_Classified(t *testing.T) {
+	if err := backend.NewAPIError("NoSuchKey", "gone"); !errors.Is(err, backend.ErrNotFound) {
+		t.Errorf("NoSuchKey = %v, want backend.ErrNotFound", err)
+	}
+	if err := backend.NewAPIError("SlowDown", "busy"); !errors.Is(err, backend.ErrThrottled) {
+		t.Errorf("SlowDown = %v, want backend.ErrThrottled", err)
+	}
+}
@@ -0,0 +1,1453 @@
+// Package gateway runs the S3 Encryption Gateway inside another Go program.
+//
+// New builds the same request pipeline and background subsystems as the
+// s3-encryption-gateway binary and returns them as an http.Handler, so an
+// application can serve the gateway on its own listeners, next to its own
+// routes, and with its own logger, tracer provider and metrics registry:
+//
+//	cfg, err := gateway.LoadConfig("gateway.yaml")
+//	if err != nil {
+//		return err
+//	}
+//	gw, err := gateway.New(cfg, gateway.WithLogger(logger))
+//	if err != nil {
+//		return err
+//	}
+//	defer gw.Close(context.Background())
+//	mux.Handle("/", gw)
+//
+// Only the listeners are left to the caller: listen_addr, tls, metrics.addr
+// and the admin listener are not opened by New. Mount RegisterAdminRoutes
+// and MetricsHandler where they should be served. Requests are traced with
+// the global OpenTelemetry tracer provider when tracing.enabled is set.
+//
+// Client IP extraction, developer mode, air-gapped mode and the reported
+// build are process-wide, so a process should run one gateway at a time.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/accessstats"
+	"github.com/kenneth/s3-encryption-gateway/internal/admin"
+	"github.com/kenneth/s3-encryption-gateway/internal/admission"
+	"github.com/kenneth/s3-encryption-gateway/internal/airgap"
+	"github.com/kenneth/s3-encryption-gateway/internal/api"
+	"github.com/kenneth/s3-encryption-gateway/internal/audit"
+	"github.com/kenneth/s3-encryption-gateway/internal/authz"
+	"github.com/kenneth/s3-encryption-gateway/internal/batch"
+	"github.com/kenneth/s3-encryption-gateway/internal/billing"
+	"github.com/kenneth/s3-encryption-gateway/internal/cache"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/debug"
+	"github.com/kenneth/s3-encryption-gateway/internal/devtrace"
+	"github.com/kenneth/s3-encryption-gateway/internal/dlp"
+	"github.com/kenneth/s3-encryption-gateway/internal/featureflag"
+	"github.com/kenneth/s3-encryption-gateway/internal/headerrules"
+	"github.com/kenneth/s3-encryption-gateway/internal/hooks"
+	"github.com/kenneth/s3-encryption-gateway/internal/journal"
+	"github.com/kenneth/s3-encryption-gateway/internal/keyhold"
+	"github.com/kenneth/s3-encryption-gateway/internal/keywatch"
+	"github.com/kenneth/s3-encryption-gateway/internal/logsample"
+	"github.com/kenneth/s3-encryption-gateway/internal/membudget"
+	"github.com/kenneth/s3-encryption-gateway/internal/metrics"
+	"github.com/kenneth/s3-encryption-gateway/internal/middleware"
+	mpupkg "github.com/kenneth/s3-encryption-gateway/internal/mpu"
+	"github.com/kenneth/s3-encryption-gateway/internal/quota"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+	"github.com/kenneth/s3-encryption-gateway/internal/sandbox"
+	"github.com/kenneth/s3-encryption-gateway/internal/scan"
+	"github.com/kenneth/s3-encryption-gateway/internal/scheduler"
+	"github.com/kenneth/s3-encryption-gateway/internal/selftest"
+	"github.com/kenneth/s3-encryption-gateway/internal/sizeindex"
+	"github.com/kenneth/s3-encryption-gateway/internal/slo"
+	"github.com/kenneth/s3-encryption-gateway/internal/storage"
+	"github.com/kenneth/s3-encryption-gateway/internal/tiering"
+	"github.com/kenneth/s3-encryption-gateway/internal/transform"
+	"github.com/kenneth/s3-encryption-gateway/internal/trash"
+	"github.com/kenneth/s3-encryption-gateway/internal/upgrade"
+	"github.com/kenneth/s3-encryption-gateway/internal/util"
+	"github.com/kenneth/s3-encryption-gateway/internal/warmpool"
+)
+
+// Config is the gateway configuration, as documented in
+// config.yaml.example.
+type Config = config.Config
+
+// DefaultConfig returns the configuration LoadConfig starts from, for
+// programs that build their configuration in code. It does not pass
+// validation until at least the backend, encryption password and auth
+// credentials are set.
+func DefaultConfig() *Config {
+	return config.Default()
+}
+
+// LoadConfig reads the configuration from a YAML file and the environment,
+// as the binary does, and validates it. A missing file leaves the defaults
+// and environment.
+func LoadConfig(path string) (*Config, error) {
+	return config.LoadConfig(path)
+}
+
+// Gateway is a running gateway. It serves S3 requests as an http.Handler
+// until Close.
+type Gateway struct {
+	cfg     *Config
+	logger  *logrus.Logger
+	metrics *metrics.Metrics
+	http    http.Handler
+
+	handler         *api.Handler
+	engine          crypto.EncryptionEngine
+	keyManager      crypto.KeyManager
+	s3Client        s3.Client
+	auditLogger     audit.Logger
+	mpuStore        *mpupkg.ValkeyStateStore
+	writeJournal    *journal.Journal
+	sizeIndex       *sizeindex.Index
+	accessStats     *accessstats.Tracker
+	featureFlags    *featureflag.Set
+	keyHolds        *keyhold.Store
+	jobScheduler    *scheduler.Scheduler
+	tieringWorker   *tiering.Worker
+	trashBin        *trash.Bin
+	quotas          *quota.Manager
+	billingExporter *billing.Exporter
+	batchJobs       *batch.Manager
+	hookPipeline    *hooks.Pipeline
+	upgrader        *upgrade.Upgrader
+	sloTracker      *slo.Tracker
+	rateLimiter     *middleware.RateLimiter
+	logLevels       *logsample.LevelControl
+	logSampler      *logsample.Sampler
+	configReloader  *config.ConfigReloader
+	configApplier   *configApplier
+	airGapped       bool
+	extensions      *sandbox.Extensions
+
+	// stop cancels the self-test, canary, key version watch and warm pool.
+	stop      context.CancelFunc
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// New validates cfg and starts a gateway serving it. Background subsystems
+// (workers, scheduler, self-test, hot reload) start before New returns and
+// run until Close. If New fails, everything it started has been stopped.
+func New(cfg *Config, opts ...Option) (gw *Gateway, err error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if cfg == nil {
+		return nil, errors.New("gateway: nil config")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	logger := o.logger
+	if logger == nil {
+		logger = logrus.New()
+		logger.SetFormatter(&logrus.JSONFormatter{})
+		level, err := logrus.ParseLevel(cfg.LogLevel)
+		if err != nil {
+			logger.WithError(err).Warn("Invalid log level, using info")
+			level = logrus.InfoLevel
+		}
+		logger.SetLevel(level)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	g := &Gateway{cfg: cfg, logger: logger, stop: stop}
+	defer func() {
+		if err != nil {
+			_ = g.Close(context.Background())
+		}
+	}()
+
+	// Sample noisy loggers and let the admin API change the level at runtime.
+	g.logSampler = logsample.New(cfg.Logging.Sampling)
+	logger.SetFormatter(&logsample.Formatter{Next: logger.Formatter, Sampler: g.logSampler})
+	g.logLevels = logsample.NewLevelControl(logger, func(l logrus.Level) {
+		debug.InitFromLogLevel(l.String())
+	})
+
+	// Developer mode traces every request's crypto work at info level. It
+	// exposes IVs, wrapped keys and object metadata, so release builds only
+	// enable it when explicitly told to.
+	if cfg.DevMode.Enabled {
+		if err := cfg.DevMode.CheckBuild(o.version); err != nil {
+			return nil, fmt.Errorf("refusing to start in developer mode: %w", err)
+		}
+		devtrace.Enable(logger)
+		logger.WithField("version", o.version).Warn("DEVELOPER MODE: logging chunk boundaries, IVs, manifests and object metadata for every request; do not use in production")
+	}
+
+	// Initialize metrics
+	m := metrics.NewMetricsWithConfigAndRegistry(metrics.Config{
+		EnableBucketLabel: cfg.Metrics.EnableBucketLabel,
+	}, o.registry)
+	g.metrics = m
+	metrics.SetVersion(o.version)
+	formatVersions := make(map[string]metrics.FormatVersion)
+	for kind, v := range crypto.FormatVersions() {
+		formatVersions[kind] = metrics.FormatVersion{Read: v.Read, Write: v.Write}
+	}
+	metrics.SetFormatVersions(formatVersions)
+	m.SetFIPSMode(crypto.FIPSEnabled())
+	logger.WithFields(logrus.Fields{
+		"fips": crypto.FIPSEnabled(),
+	}).Info("crypto profile")
+
+	// Start system metrics collector
+	m.StartSystemMetricsCollector()
+
+	// Air-gapped mode: Validate has checked that the configuration names no
+	// destination beyond the backend, the KMS and air_gapped.allow. From
+	// here on every other outbound connection is refused.
+	if cfg.AirGapped.Enabled {
+		guard := airgap.NewGuard(cfg.AirGappedHosts())
+		guard.OnBlock = func(network, addr string) {
+			logger.WithFields(logrus.Fields{
+				"network": network,
+				"address": addr,
+			}).Error("Air-gapped mode: refused outbound connection")
+			m.RecordAirGapBlocked(airgap.Host(addr))
+		}
+		airgap.Install(guard)
+		g.airGapped = true
+		logger.WithField("allowed_hosts", guard.Hosts()).Info("Air-gapped mode: outbound connections limited to the backend, the KMS and air_gapped.allow")
+	}
+
+	// Log any advisory warnings from the retry configuration.
+	for _, w := range s3.ValidationWarnings(cfg.Backend.Retry) {
+		logger.Warn("backend retry config: " + w)
+	}
+	if cfg.Backend.TLS.InsecureSkipVerify {
+		logger.Warn("SECURITY WARNING: backend.tls.insecure_skip_verify is enabled; " +
+			"backend certificates are not verified")
+	}
+
+	// Initialize the storage backend client.
+	// V0.6-PERF-2: S3 backends always use ClientFactory so the retry policy is applied.
+	// It does not depend on the key manager, so both are set up concurrently
+	// and startup waits on the slower of the two only.
+	var s3Client s3.Client
+	var s3Err error
+	var backendInit sync.WaitGroup
+	if o.backend != nil {
+		s3Client = o.backend
+	} else {
+		backendInit.Add(1)
+		go func() {
+			defer backendInit.Done()
+			s3Client, s3Err = storage.New(&cfg.Backend, m)
+		}()
+	}
+	// Every error return below this point waits for the backend client, so
+	// New never leaves it being created in the background.
+	defer backendInit.Wait()
+
+	// Load encryption password (required for both single password and KMS modes)
+	var encryptionPassword []byte
+	if cfg.Encryption.Password != "" {
+		encryptionPassword = []byte(cfg.Encryption.Password)
+	} else if cfg.Encryption.KeyFile != "" {
+		keyData, err := os.ReadFile(cfg.Encryption.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption key file: %w", err)
+		}
+		encryptionPassword = make([]byte, len(keyData))
+		copy(encryptionPassword, keyData)
+		zeroBytes(keyData)
+	}
+
+	if len(encryptionPassword) == 0 {
+		return nil, errors.New("encryption password is required (set ENCRYPTION_PASSWORD or encryption.password)")
+	}
+
+	activePassword := make([]byte, len(encryptionPassword))
+	copy(activePassword, encryptionPassword)
+	zeroBytes(encryptionPassword)
+	// Zero the upstream password copy once everything derived from it holds
+	// its own key material, or on the way out of a failed New.
+	defer zeroBytes(activePassword)
+
+	var keyManager crypto.KeyManager
+	if cfg.Encryption.KeyManager.Enabled {
+		keyManager, err = api.BuildKeyManager(&cfg.Encryption.KeyManager, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize key manager: %w", err)
+		}
+		g.keyManager = keyManager
+		logger.WithFields(logrus.Fields{
+			"provider": strings.ToLower(cfg.Encryption.KeyManager.Provider),
+		}).Info("External key manager initialized")
+	} else {
+		// Password-only mode: construct a PasswordKeyManager so that encrypted
+		// multipart uploads (EncryptMultipartUploads=true) can wrap per-upload
+		// DEKs with PBKDF2+AES-256-GCM instead of storing them in plaintext.
+		// This provides equivalent confidentiality to single-PUT chunked encryption.
+		pkm, pkmErr := crypto.NewPasswordKeyManager(activePassword, cfg.Encryption.KDF.PBKDF2.Iterations)
+		if pkmErr != nil {
+			return nil, fmt.Errorf("failed to initialize password key manager: %w", pkmErr)
+		}
+		keyManager = pkm
+		logger.Info("Using single password mode (no key rotation); PasswordKeyManager active for MPU DEK wrapping")
+	}
+
+	backendInit.Wait()
+	if s3Err != nil {
+		return nil, fmt.Errorf("failed to create storage backend client: %w", s3Err)
+	}
+	if o.backend != nil {
+		logger.Info("Using the backend client supplied by the embedding application")
+	} else {
+		logger.WithFields(logrus.Fields{
+			"backend_type":    cfg.Backend.StorageType(),
+			"retry_mode":      cfg.Backend.Retry.Mode,
+			"max_attempts":    cfg.Backend.Retry.MaxAttempts,
+			"initial_backoff": cfg.Backend.Retry.InitialBackoff,
+		}).Info("S3 backend client initialized with configured credentials and retry policy")
+	}
+	if cfg.Sharding.Enabled {
+		sharded, err := storage.NewSharded(s3Client, cfg.Sharding, m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create backend shards: %w", err)
+		}
+		s3Client = sharded
+		logger.WithFields(logrus.Fields{
+			"shards":    len(cfg.Sharding.Shards) + 1,
+			"overrides": len(cfg.Sharding.Overrides),
+		}).Info("Bucket sharding enabled")
+	}
+
+	// Initialize compression engine if enabled
+	var compressionEngine crypto.CompressionEngine
+	if cfg.Compression.Enabled {
+		compressionEngine = crypto.NewCompressionEngine(
+			cfg.Compression.Enabled,
+			cfg.Compression.MinSize,
+			cfg.Compression.ContentTypes,
+			cfg.Compression.Algorithm,
+			cfg.Compression.Level,
+			crypto.WithMaxRatio(cfg.Compression.MaxRatio),
+		)
+		logger.WithFields(logrus.Fields{
+			"enabled":   cfg.Compression.Enabled,
+			"algorithm": cfg.Compression.Algorithm,
+			"min_size":  cfg.Compression.MinSize,
+		}).Info("Compression enabled")
+	}
+
+	// Log hardware acceleration info
+	hwInfo := crypto.GetHardwareAccelerationInfo(&cfg.Encryption.Hardware)
+	logger.WithFields(logrus.Fields{
+		"aes_hardware_support": hwInfo["aes_hardware_support"],
+		"architecture":         hwInfo["architecture"],
+		"active":               hwInfo["hardware_acceleration_active"],
+	}).Info("Hardware acceleration status")
+
+	// Set hardware acceleration metric
+	hwAccel := ""
+	if active, ok := hwInfo["hardware_acceleration_active"].(bool); ok {
+		accelType := "unknown"
+		if strings.Contains(hwInfo["architecture"].(string), "amd64") || strings.Contains(hwInfo["architecture"].(string), "386") {
+			accelType = "aes-ni"
+		} else if strings.Contains(hwInfo["architecture"].(string), "arm") {
+			accelType = "armv8-aes"
+		}
+		m.SetHardwareAccelerationStatus(accelType, active)
+		if active {
+			hwAccel = accelType
+		}
+	}
+
+	// Default to chunked mode enabled unless explicitly disabled
+	chunkedMode := cfg.Encryption.ChunkedMode
+	if !cfg.Encryption.ChunkedMode && cfg.Encryption.ChunkSize == 0 {
+		// If neither is set, default to enabled for new installations
+		chunkedMode = true
+	}
+
+	// NEW-01: Emit a prominent startup warning when legacy (non-chunked) mode is
+	// active. In this mode Encrypt and Decrypt each call io.ReadAll on the
+	// entire object body, so a single large upload allocates roughly the object
+	// size as heap. This is a remote memory-exhaustion risk for large objects.
+	// Chunked mode streams data in fixed-size chunks and is the recommended
+	// default for all production deployments.
+	if !chunkedMode {
+		logger.Warn("SECURITY WARNING: chunked_mode is disabled. " +
+			"Encrypt and Decrypt buffer entire objects in heap memory (io.ReadAll). " +
+			"A large upload or download will allocate the full object size on the heap, " +
+			"enabling a trivial remote memory-exhaustion DoS. " +
+			"Enable encryption.chunked_mode: true in your configuration for production workloads.")
+	}
+
+	chunkSize := cfg.Encryption.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = crypto.DefaultChunkSize
+	}
+
+	var hybridWrapKey *crypto.HybridPublicKey
+	if cfg.Encryption.HybridWrap.Enabled {
+		pemData, err := os.ReadFile(cfg.Encryption.HybridWrap.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read hybrid wrap public key: %w", err)
+		}
+		hybridWrapKey, err = crypto.ParseHybridPublicKeyPEM(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse hybrid wrap public key: %w", err)
+		}
+		logger.WithField("key_id", hybridWrapKey.KeyID()).Info("Data keys are also wrapped to the hybrid post-quantum key")
+	}
+
+	// Initialize encryption engine with compression, algorithm support, chunked mode, and key resolver (if KMS mode)
+	encryptionEngine, err := crypto.NewEngineWithOpts(
+		activePassword,
+		compressionEngine,
+		crypto.WithPreferredAlgorithm(cfg.Encryption.PreferredAlgorithm),
+		crypto.WithSupportedAlgorithms(cfg.Encryption.SupportedAlgorithms),
+		crypto.WithChunking(chunkedMode),
+		crypto.WithChunkSize(chunkSize),
+		crypto.WithBodyHeader(cfg.Encryption.BodyHeader),
+		crypto.WithHybridWrapKey(hybridWrapKey),
+		crypto.WithProvider("default"),
+		crypto.WithPBKDF2Iterations(cfg.Encryption.KDF.PBKDF2.Iterations),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create encryption engine: %w", err)
+	}
+	g.engine = encryptionEngine
+	// The metadata sealer is built even when sealing is off so that objects
+	// written while it was on keep their metadata readable.
+	metaSealer, err := crypto.NewMetadataSealer(activePassword, cfg.Encryption.KDF.PBKDF2.Iterations, cfg.Encryption.MetadataEncryption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metadata sealer: %w", err)
+	}
+	var keyObfuscator *crypto.KeyObfuscator
+	if cfg.Encryption.KeyObfuscation {
+		keyObfuscator, err = crypto.NewKeyObfuscator(activePassword, cfg.Encryption.KDF.PBKDF2.Iterations, cfg.Encryption.KeyObfuscationScheme)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create key obfuscator: %w", err)
+		}
+	}
+	var objectMarker *crypto.ObjectMarker
+	if cfg.Encryption.IdentityMarker.Enabled {
+		gatewayID := cfg.Encryption.IdentityMarker.GatewayID
+		if gatewayID == "" {
+			gatewayID, _ = os.Hostname()
+		}
+		objectMarker, err = crypto.NewObjectMarker(activePassword, cfg.Encryption.KDF.PBKDF2.Iterations, gatewayID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create object identity marker: %w", err)
+		}
+	}
+	var metaPrefixer *crypto.MetadataPrefixer
+	if p := cfg.Encryption.MetadataKeyPrefix; p != "" && p != crypto.DefaultMetadataKeyPrefix {
+		if metaPrefixer, err = crypto.NewMetadataPrefixer(p); err != nil {
+			return nil, fmt.Errorf("invalid metadata key prefix: %w", err)
+		}
+		s3Client = s3.NewMetadataMappingClient(s3Client, metaPrefixer)
+		logger.WithField("prefix", p).Info("Gateway metadata stored under a custom key prefix")
+	}
+	if keyObfuscator != nil {
+		// Wrap before anything else sees the client so the journal and
+		// archive paths address the same backend names as requests.
+		s3Client = s3.NewKeyMappingClient(s3Client, keyObfuscator)
+		logger.WithField("scheme", keyObfuscator.Scheme()).Info("Object key obfuscation enabled")
+	}
+	// Tiering: reads of objects moved to the secondary backend follow the
+	// stub left in their place. The worker needs the unwrapped client to
+	// see the stubs.
+	tieringPrimary := s3Client
+	var tieringSecondary s3.Client
+	if cfg.Tiering.Enabled && s3Client != nil {
+		for _, rule := range cfg.Tiering.Rules {
+			if rule.Secondary {
+				if tieringSecondary, err = storage.New(&cfg.Tiering.Backend, m); err != nil {
+					return nil, fmt.Errorf("failed to create tiering backend client: %w", err)
+				}
+				if metaPrefixer != nil {
+					tieringSecondary = s3.NewMetadataMappingClient(tieringSecondary, metaPrefixer)
+				}
+				s3Client = tiering.NewClient(s3Client, tieringSecondary)
+				break
+			}
+		}
+	}
+	g.s3Client = s3Client
+	if keyManager != nil {
+		crypto.SetKeyManager(encryptionEngine, keyManager)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"preferred_algorithm":   cfg.Encryption.PreferredAlgorithm,
+		"supported_algorithms":  cfg.Encryption.SupportedAlgorithms,
+		"chunked_mode":          chunkedMode,
+		"chunk_size":            chunkSize,
+		"kdf_pbkdf2_iterations": cfg.Encryption.KDF.PBKDF2.Iterations,
+	}).Info("Encryption configuration")
+
+	// Publish build info on /version and as gateway_build_info so fleet
+	// tooling can spot instances running a different build or feature set.
+	buildInfo := metrics.BuildInfo{
+		Version:   o.version,
+		Commit:    o.commit,
+		BuildDate: o.date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		FIPS:      crypto.FIPSEnabled(),
+		Features: metrics.BuildFeatures{
+			Chunked:              chunkedMode,
+			HardwareAcceleration: hwAccel,
+		},
+	}
+	if chunkedMode {
+		buildInfo.Features.ChunkSize = chunkSize
+	}
+	if cfg.Compression.Enabled {
+		buildInfo.Features.Compression = cfg.Compression.Algorithm
+	}
+	if cfg.Encryption.KeyManager.Enabled {
+		buildInfo.Features.KMSProvider = strings.ToLower(cfg.Encryption.KeyManager.Provider)
+	}
+	m.SetBuildInfo(buildInfo)
+	logger.WithFields(logrus.Fields{
+		"version":      buildInfo.Version,
+		"commit":       buildInfo.Commit,
+		"build_date":   buildInfo.BuildDate,
+		"go_version":   buildInfo.GoVersion,
+		"platform":     buildInfo.Platform,
+		"fips":         buildInfo.FIPS,
+		"chunked":      buildInfo.Features.Chunked,
+		"compression":  buildInfo.Features.Compression,
+		"kms_provider": buildInfo.Features.KMSProvider,
+		"hw_accel":     buildInfo.Features.HardwareAcceleration,
+	}).Info("Build information")
+
+	// Global memory budget shared by the object cache, part buffers and
+	// in-flight request pipelines. nil when disabled.
+	memBudget := membudget.New(cfg.MemoryBudget, m)
+	if memBudget != nil {
+		logger.WithFields(logrus.Fields{
+			"limit":           cfg.MemoryBudget.Limit,
+			"max_wait":        cfg.MemoryBudget.MaxWait,
+			"request_reserve": cfg.MemoryBudget.RequestReserve,
+		}).Info("Memory budget enabled")
+	}
+
+	// Initialize cache if enabled (Phase 5 feature)
+	var objectCache cache.Cache
+	if cfg.Cache.Enabled {
+		objectCache = cache.NewMemoryCacheWithBudget(
+			cfg.Cache.MaxSize,
+			cfg.Cache.MaxItems,
+			cfg.Cache.DefaultTTL,
+			memBudget,
+			cache.WithRevalidation(cfg.Cache.RevalidateFor),
+		)
+		logger.WithFields(logrus.Fields{
+			"max_size":       cfg.Cache.MaxSize,
+			"max_items":      cfg.Cache.MaxItems,
+			"default_ttl":    cfg.Cache.DefaultTTL,
+			"revalidate_for": cfg.Cache.RevalidateFor,
+		}).Info("Cache enabled")
+	}
+
+	// Initialize audit logger if enabled (Phase 5 feature)
+	if cfg.Audit.Enabled {
+		g.auditLogger, err = audit.NewLoggerFromConfig(cfg.Audit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize audit logger: %w", err)
+		}
+		logger.WithFields(logrus.Fields{
+			"max_events": cfg.Audit.MaxEvents,
+			"sink_type":  cfg.Audit.Sink.Type,
+		}).Info("Audit logging enabled")
+	}
+	auditLogger := g.auditLogger
+
+	// Initialize policy manager if policy files are configured
+	var policyManager *config.PolicyManager
+	if len(cfg.PolicyFiles) > 0 {
+		policyManager = config.NewPolicyManager()
+		if err := policyManager.LoadPolicies(cfg.PolicyFiles); err != nil {
+			return nil, fmt.Errorf("failed to load policy files: %w", err)
+		}
+		logger.WithField("count", len(cfg.PolicyFiles)).Info("Policy files loaded")
+	}
+
+	// Fail-closed startup check: if any bucket policy enables EncryptMultipartUploads
+	// but the Valkey addr is not configured, refuse to start rather than silently
+	// falling back to plaintext multipart uploads (Phase C DoD requirement).
+	if policyManager.AnyPolicyRequiresMPUEncryption() && cfg.MultipartState.Valkey.Addr == "" {
+		return nil, errors.New("one or more bucket policies require EncryptMultipartUploads=true " +
+			"but multipart_state.valkey.addr is not configured; " +
+			"configure Valkey or remove EncryptMultipartUploads from all policies")
+	}
+
+	// Create gateway credential store from resolved credentials.
+	// V1.0-AUTH-1: every request must present valid gateway-managed credentials.
+	resolvedCreds := cfg.ResolvedCredentials()
+	credStore, err := api.NewStaticCredentialStore(resolvedCreds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create credential store: %w", err)
+	}
+	// Zero plaintext secrets from the transient slice; the store holds its own copy.
+	for i := range resolvedCreds {
+		resolvedCreds[i].SecretKey = strings.Repeat("\x00", len(resolvedCreds[i].SecretKey))
+	}
+	resolvedCreds = nil
+	logger.WithField("count", len(cfg.Auth.Credentials)).Info("Gateway credential store initialized")
+
+	// Initialize API handler with Phase 5 features
+	handler := api.NewHandlerWithFeatures(s3Client, encryptionEngine, logger, m, keyManager, objectCache, auditLogger, cfg, policyManager)
+	g.handler = handler
+	handler.WithMetadataSealer(metaSealer)
+	handler.WithMemoryBudget(memBudget)
+	if keyObfuscator != nil {
+		handler.WithKeyCodec(keyObfuscator)
+	}
+	if metaPrefixer != nil {
+		handler.WithMetadataCodec(metaPrefixer)
+	}
+	if metaSealer.Mode() != crypto.MetadataSealOff {
+		logger.WithField("mode", metaSealer.Mode()).Info("User metadata encryption enabled")
+	}
+	if objectMarker != nil {
+		handler.WithObjectMarker(objectMarker)
+		logger.WithField("reject", cfg.Encryption.IdentityMarker.Reject).Info("Object identity markers enabled")
+	}
+
+	// Initialise Valkey state store for encrypted multipart uploads when any
+	// bucket policy enables EncryptMultipartUploads. Fail-closed: if Valkey is
+	// unreachable at startup and encrypted MPU is required, refuse to start.
+	if cfg.MultipartState.Valkey.Addr != "" {
+		g.mpuStore, err = mpupkg.NewValkeyStateStore(context.Background(), cfg.MultipartState.Valkey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialise MPU Valkey state store: %w", err)
+		}
+		handler.WithMPUStateStore(g.mpuStore)
+		m.SetMPUValkeyInsecure(!cfg.MultipartState.Valkey.TLS.Enabled)
+		logger.WithField("addr", cfg.MultipartState.Valkey.Addr).Info("MPU Valkey state store initialised")
+	}
+
+	// Open the write-ahead journal and resolve writes interrupted by a
+	// previous crash before serving traffic.
+	if cfg.Journal.Enabled {
+		writeJournal, err := journal.Open(cfg.Journal.Path, cfg.Journal.Sync)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open write-ahead journal: %w", err)
+		}
+		g.writeJournal = writeJournal
+
+		if pending := writeJournal.Pending(); len(pending) > 0 && s3Client != nil {
+			isEncrypted := func(meta map[string]string) bool {
+				return encryptionEngine.IsEncrypted(meta) || meta[crypto.MetaMPUEncrypted] == "true"
+			}
+			results, err := writeJournal.Recover(context.Background(), s3Client, isEncrypted, cfg.Journal.CleanupInconsistent)
+			if err != nil {
+				logger.WithError(err).Error("Write-ahead journal recovery did not complete")
+			}
+			for _, res := range results {
+				entry := logger.WithFields(logrus.Fields{
+					"operation":  res.Operation,
+					"bucket":     res.Bucket,
+					"key":        res.Key,
+					"started_at": res.Time,
+					"state":      res.State,
+				})
+				switch res.State {
+				case journal.StateApplied, journal.StateNotApplied, journal.StateCleaned:
+					entry.Info("Resolved interrupted write")
+				default:
+					entry.WithField("error", res.Error).Warn("Interrupted write needs operator attention")
+				}
+			}
+		}
+		handler.WithJournal(writeJournal)
+		logger.WithFields(logrus.Fields{
+			"path": cfg.Journal.Path,
+			"sync": cfg.Journal.Sync,
+		}).Info("Write-ahead journal enabled")
+	}
+
+	// Sidecar size index of plaintext object sizes, flushed in the background.
+	if cfg.SizeIndex.Enabled {
+		if s3Client == nil {
+			logger.Warn("Size index requires backend credentials; disabled")
+		} else {
+			g.sizeIndex = sizeindex.New(s3Client, cfg.SizeIndex, logger)
+			g.sizeIndex.Start()
+			handler.WithSizeIndex(g.sizeIndex)
+			logger.WithFields(logrus.Fields{
+				"prefix":         cfg.SizeIndex.Prefix,
+				"flush_interval": cfg.SizeIndex.FlushInterval,
+			}).Info("Size index enabled")
+		}
+	}
+	sizeIndex := g.sizeIndex
+
+	// Per-object read counters for tiering decisions, flushed in the background.
+	if cfg.AccessStats.Enabled {
+		if s3Client == nil {
+			logger.Warn("Access stats require backend credentials; disabled")
+		} else {
+			g.accessStats = accessstats.New(s3Client, cfg.AccessStats, logger)
+			g.accessStats.Start()
+			handler.WithAccessStats(g.accessStats)
+			logger.WithFields(logrus.Fields{
+				"prefix":         cfg.AccessStats.Prefix,
+				"flush_interval": cfg.AccessStats.FlushInterval,
+			}).Info("Access stats enabled")
+		}
+	}
+	accessStats := g.accessStats
+
+	// Feature flags gating behaviours that are being rolled out.
+	g.featureFlags, err = featureflag.New(cfg.FeatureFlags)
+	if err != nil {
+		return nil, fmt.Errorf("invalid feature flags: %w", err)
+	}
+	handler.WithFeatureFlags(g.featureFlags)
+	if len(cfg.FeatureFlags) > 0 {
+		logger.WithField("flags", cfg.FeatureFlags).Info("Feature flags configured")
+	}
+
+	// Key holds protecting data keys from shredding and key version retirement.
+	if cfg.KeyHold.Enabled {
+		if s3Client == nil {
+			logger.Warn("Key holds require backend credentials; disabled")
+		} else {
+			g.keyHolds = keyhold.New(s3Client, cfg.KeyHold)
+			handler.WithKeyHolds(g.keyHolds)
+			logger.WithFields(logrus.Fields{
+				"bucket": cfg.KeyHold.Bucket,
+				"key":    cfg.KeyHold.Key,
+			}).Info("Key holds enabled")
+		}
+	}
+	keyHolds := g.keyHolds
+
+	// Background job scheduler. When enabled it runs tiering passes, trash
+	// purges and canary probes in place of their own interval loops.
+	if cfg.Scheduler.Enabled {
+		g.jobScheduler = scheduler.New(m, logger)
+	}
+	// scheduleJob hands run to the scheduler, on its configured schedule or
+	// else every interval, and reports whether it did.
+	scheduleJob := func(name string, interval time.Duration, run func(context.Context) error) (bool, error) {
+		if g.jobScheduler == nil {
+			return false, nil
+		}
+		expr := cfg.Scheduler.Jobs[name]
+		if expr == "" {
+			expr = "@every " + interval.String()
+		}
+		if err := g.jobScheduler.Add(name, expr, run); err != nil {
+			return false, fmt.Errorf("invalid scheduler configuration for job %q: %w", name, err)
+		}
+		return true, nil
+	}
+
+	// Tiering worker, moving objects that match the configured rules.
+	if cfg.Tiering.Enabled {
+		if tieringPrimary == nil {
+			logger.Warn("Tiering requires backend credentials; disabled")
+		} else {
+			reserved := func(key string) bool {
+				return sizeIndex.IsIndexKey(key) || accessStats.IsStatsKey(key) || keyHolds.IsHoldKey(cfg.KeyHold.Bucket, key)
+			}
+			g.tieringWorker = tiering.NewWorker(tieringPrimary, tieringSecondary, cfg.Tiering, accessStats, reserved, logger)
+			scheduled, err := scheduleJob("tiering", cfg.Tiering.Interval, g.tieringWorker.Pass)
+			if err != nil {
+				return nil, err
+			}
+			if !scheduled {
+				g.tieringWorker.Start()
+			}
+			logger.WithFields(logrus.Fields{
+				"interval": cfg.Tiering.Interval,
+				"rules":    len(cfg.Tiering.Rules),
+			}).Info("Tiering enabled")
+		}
+	}
+
+	// Deferred deletes, keeping deleted objects in the trash until purged.
+	if cfg.Trash.Enabled {
+		if s3Client == nil {
+			logger.Warn("Trash requires backend credentials; disabled")
+		} else {
+			g.trashBin = trash.New(s3Client, cfg.Trash, logger)
+			handler.WithTrash(g.trashBin)
+			scheduled, err := scheduleJob("trash", cfg.Trash.Interval, g.trashBin.Purge)
+			if err != nil {
+				return nil, err
+			}
+			if !scheduled {
+				g.trashBin.Start()
+			}
+			logger.WithFields(logrus.Fields{
+				"buckets":   cfg.Trash.Buckets,
+				"prefix":    cfg.Trash.Prefix,
+				"retention": cfg.Trash.Retention,
+			}).Info("Trash enabled")
+		}
+	}
+
+	// Quotas, measured by scanning the limited buckets.
+	if cfg.Quota.Enabled {
+		if s3Client == nil {
+			logger.Warn("Quotas require backend credentials; disabled")
+		} else {
+			reserved := func(bucket, key string) bool {
+				return sizeIndex.IsIndexKey(key) || accessStats.IsStatsKey(key) || keyHolds.IsHoldKey(bucket, key)
+			}
+			quotas := quota.New(s3Client, cfg.Quota, reserved, m, logger)
+			g.quotas = quotas
+			handler.WithQuotas(quotas)
+			scheduled, err := scheduleJob("quota", cfg.Quota.ScanInterval, quotas.Scan)
+			if err != nil {
+				return nil, err
+			}
+			if scheduled {
+				// Usage is unknown, and nothing enforced, until the first
+				// scan; do not wait for the schedule to run it.
+				go func() { _ = quotas.Scan(ctx) }()
+			} else {
+				quotas.Start()
+			}
+			logger.WithFields(logrus.Fields{
+				"buckets":       len(cfg.Quota.Buckets),
+				"tenants":       len(cfg.Quota.Tenants),
+				"scan_interval": cfg.Quota.ScanInterval,
+			}).Info("Quotas enabled")
+		}
+	}
+
+	// Billing export of per-tenant traffic and the storage the quota scans
+	// measure.
+	if cfg.Billing.Enabled {
+		if s3Client == nil {
+			logger.Warn("Billing export requires backend credentials; disabled")
+		} else {
+			g.billingExporter = billing.New(s3Client, g.quotas, cfg.Billing, cfg.Quota.Tenants, m, logger)
+			scheduled, err := scheduleJob("billing", cfg.Billing.Interval, g.billingExporter.Export)
+			if err != nil {
+				return nil, err
+			}
+			if !scheduled {
+				g.billingExporter.Start()
+			}
+			if g.quotas == nil {
+				logger.Warn("Billing export without quotas enabled has no storage figures")
+			}
+			logger.WithFields(logrus.Fields{
+				"bucket":   cfg.Billing.Bucket,
+				"prefix":   cfg.Billing.Prefix,
+				"format":   cfg.Billing.Format,
+				"interval": cfg.Billing.Interval,
+			}).Info("Billing export enabled")
+		}
+	}
+
+	// Batch jobs applying one operation to a manifest of keys.
+	if cfg.Batch.Enabled {
+		if s3Client == nil {
+			logger.Warn("Batch jobs require backend credentials; disabled")
+		} else {
+			g.batchJobs = batch.New(handler, s3Client, cfg.Batch, logger)
+			logger.WithFields(logrus.Fields{
+				"workers":      cfg.Batch.Workers,
+				"max_attempts": cfg.Batch.MaxAttempts,
+			}).Info("Batch jobs enabled")
+		}
+	}
+
+	// Post-PUT hooks: processors receive decrypted objects and their output is
+	// written back through the encryption pipeline.
+	if cfg.Hooks.Enabled {
+		if s3Client == nil {
+			logger.Warn("Hooks require backend credentials; disabled")
+		} else {
+			g.hookPipeline, err = hooks.New(cfg.Hooks, handler, logger)
+			if err != nil {
+				return nil, fmt.Errorf("failed to initialize hooks: %w", err)
+			}
+			g.hookPipeline.Start()
+			handler.WithHooks(g.hookPipeline)
+			logger.WithFields(logrus.Fields{
+				"rules":   len(cfg.Hooks.Rules),
+				"workers": cfg.Hooks.Workers,
+			}).Info("Post-PUT hooks enabled")
+		}
+	}
+
+	// Lazy upgrade: legacy objects read in full are rewritten in chunked
+	// format in the background.
+	if cfg.Encryption.LazyUpgrade.Enabled {
+		switch {
+		case s3Client == nil:
+			logger.Warn("Lazy upgrade requires backend credentials; disabled")
+		case !chunkedMode:
+			logger.Warn("Lazy upgrade requires chunked mode; disabled")
+		default:
+			g.upgrader = upgrade.New(cfg.Encryption.LazyUpgrade, handler, logger)
+			g.upgrader.Start()
+			handler.WithUpgrader(g.upgrader)
+			logger.WithFields(logrus.Fields{
+				"rate":            cfg.Encryption.LazyUpgrade.Rate,
+				"max_object_size": cfg.Encryption.LazyUpgrade.MaxObjectSize,
+			}).Info("Lazy upgrade of legacy objects enabled")
+		}
+	}
+
+	// Malware scanning of uploads before they are encrypted and stored.
+	if cfg.Scanning.Enabled {
+		scanner, err := scan.New(cfg.Scanning)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize malware scanner: %w", err)
+		}
+		handler.WithScanner(scanner, cfg.Scanning)
+		logger.WithFields(logrus.Fields{
+			"protocol":  cfg.Scanning.Protocol,
+			"address":   cfg.Scanning.Address,
+			"action":    cfg.Scanning.Action,
+			"fail_open": cfg.Scanning.FailOpen,
+		}).Info("Malware scanning enabled")
+	}
+
+	// Content inspection (DLP) of text uploads before encryption.
+	if cfg.Inspection.Enabled {
+		inspector, err := dlp.New(cfg.Inspection)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize content inspection: %w", err)
+		}
+		handler.WithInspector(inspector, cfg.Inspection.TagKey)
+		logger.WithFields(logrus.Fields{
+			"rules":    len(cfg.Inspection.Rules),
+			"max_size": cfg.Inspection.MaxSize,
+		}).Info("Content inspection enabled")
+	}
+
+	// Extension modules: operator WebAssembly run in-process in a sandbox.
+	filters := make(map[string]transform.Service)
+	if cfg.Extensions.Enabled {
+		g.extensions, err = sandbox.Load(ctx, cfg.Extensions, logger, m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load extension modules: %w", err)
+		}
+		handler.WithMetadataHooks(g.extensions.Metadata)
+		for name, f := range g.extensions.Filters {
+			filters[name] = f
+		}
+		logger.WithFields(logrus.Fields{
+			"modules":  len(cfg.Extensions.Modules),
+			"policy":   len(g.extensions.Policy),
+			"metadata": len(g.extensions.Metadata),
+			"filters":  len(g.extensions.Filters),
+		}).Info("Extension modules loaded")
+	}
+
+	// Response transformation: matching GETs are streamed, decrypted,
+	// through an external service or a filter module before reaching the
+	// client.
+	if cfg.Transforms.Enabled {
+		transformer, err := transform.New(cfg.Transforms, filters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize response transformation: %w", err)
+		}
+		handler.WithTransformer(transformer)
+		logger.WithFields(logrus.Fields{
+			"rules":           len(cfg.Transforms.Rules),
+			"max_output_size": cfg.Transforms.MaxOutputSize,
+		}).Info("Response transformation enabled")
+	}
+
+	// Startup self-test: readiness stays failed until the crypto, KMS and
+	// backend round trips have passed at least once.
+	if cfg.SelfTest.Enabled {
+		stCfg := cfg.SelfTest
+		if stCfg.Bucket == "" {
+			stCfg.Bucket = cfg.ProxiedBucket
+		}
+		checker := selftest.New(stCfg, encryptionEngine, keyManager, s3Client, logger)
+		handler.WithReadyCheck(metrics.ReadyCheck{Name: "self_test", Check: checker.Ready})
+		checker.Start(ctx)
+		logger.WithFields(logrus.Fields{
+			"bucket":         stCfg.Bucket,
+			"timeout":        stCfg.Timeout,
+			"retry_interval": stCfg.RetryInterval,
+		}).Info("Startup self-test enabled")
+	}
+
+	// Periodic canary probe; failures only show up in the gateway_canary_*
+	// metrics so a backend blip does not take every replica out of service.
+	if cfg.Canary.Enabled {
+		canaryCfg := cfg.Canary
+		if canaryCfg.Bucket == "" {
+			canaryCfg.Bucket = cfg.ProxiedBucket
+		}
+		probe := selftest.NewProbe(canaryCfg, encryptionEngine, keyManager, s3Client, m, logger)
+		scheduled, err := scheduleJob("canary", canaryCfg.Interval, probe.Probe)
+		if err != nil {
+			return nil, err
+		}
+		if !scheduled {
+			probe.Start(ctx)
+		}
+		logger.WithFields(logrus.Fields{
+			"bucket":   canaryCfg.Bucket,
+			"interval": canaryCfg.Interval,
+		}).Info("Canary probe enabled")
+	}
+
+	// Key version watch: follow rotations made in the KMS.
+	if cfg.Encryption.KeyManager.Enabled && cfg.Encryption.KeyManager.VersionWatch.Enabled {
+		watchCfg := cfg.Encryption.KeyManager.VersionWatch
+		watcher := keywatch.New(watchCfg, keyManager, crypto.GetRotationState(encryptionEngine), m, auditLogger, logger)
+		scheduled, err := scheduleJob("key_version_watch", watchCfg.Interval, watcher.Check)
+		if err != nil {
+			return nil, err
+		}
+		if !scheduled {
+			watcher.Start(ctx)
+		}
+		logger.WithField("interval", watchCfg.Interval).Info("Key version watch enabled")
+	}
+
+	// Warm pool: keep backend and KMS connections open between requests.
+	if cfg.WarmPool.Enabled {
+		var targets []warmpool.Target
+		bucket := cfg.Readiness.BackendBucket
+		if bucket == "" {
+			bucket = cfg.ProxiedBucket
+		}
+		if bucket != "" && s3Client != nil {
+			targets = append(targets, warmpool.Target{
+				Name: "backend",
+				Check: func(ctx context.Context) error {
+					_, err := s3Client.ListObjects(ctx, bucket, "", s3.ListOptions{MaxKeys: 1})
+					return err
+				},
+			})
+		}
+		// The password key manager has no connection to keep open.
+		if cfg.Encryption.KeyManager.Enabled {
+			targets = append(targets, warmpool.Target{Name: "kms", Check: keyManager.HealthCheck, Connections: 1})
+		}
+		if len(targets) == 0 {
+			logger.Warn("Warm pool enabled but neither a backend bucket nor an external key manager is configured; nothing to keep warm")
+		} else {
+			warmpool.New(cfg.WarmPool, m, logger, targets...).Start(ctx)
+			logger.WithFields(logrus.Fields{
+				"targets":     len(targets),
+				"connections": cfg.WarmPool.Connections,
+				"interval":    cfg.WarmPool.Interval,
+			}).Info("Warm pool enabled")
+		}
+	}
+
+	g.jobScheduler.Start()
+	for _, st := range g.jobScheduler.List() {
+		logger.WithFields(logrus.Fields{
+			"job":      st.Name,
+			"schedule": st.Schedule,
+		}).Info("Scheduled background job")
+	}
+
+	if cfg.RateLimit.Enabled {
+		g.rateLimiter = middleware.NewRateLimiter(
+			cfg.RateLimit.Limit,
+			cfg.RateLimit.Window,
+			logger,
+		)
+	}
+
+	// Initialize configuration hot-reload (only if config file is specified)
+	if o.configFile != "" {
+		applier := newConfigApplier(logger, g.rateLimiter, objectCache, auditLogger, cfg, policyManager)
+		g.configApplier = applier
+		applier.featureFlags = g.featureFlags
+		applier.logLevels = g.logLevels
+		applier.logSampler = g.logSampler
+
+		// Create and start config reloader
+		g.configReloader, err = config.NewConfigReloader(o.configFile, cfg, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize config reloader: %w", err)
+		}
+
+		// Set the reload callback
+		g.configReloader.SetOnReloadCallback(applier.ApplyConfigChanges)
+		configReloader := g.configReloader
+		handler.WithConfigCheck(func(context.Context) error { return configReloader.LastError() })
+
+		// Start config reloader in background
+		go configReloader.Start()
+
+		logger.WithField("config_file", o.configFile).Info("Configuration hot-reload enabled")
+	}
+
+	// Setup router
+	router := mux.NewRouter()
+
+	// Register health check endpoint
+	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET", "HEAD")
+
+	// With neither a dedicated metrics listener nor the admin server to
+	// serve them, metrics are served on the S3 port (legacy behaviour, no
+	// TLS / auth on metrics).
+	if cfg.Metrics.Addr == "" && !cfg.Admin.Enabled {
+		router.Handle("/metrics", m.Handler()).Methods("GET")
+	}
+
+	// Register API routes
+	handler.RegisterRoutes(router)
+
+	// Initialize IP extractor with trusted proxies (V1.0-SEC-6, V1.0-SEC-16).
+	// This ensures client IP extraction honors trusted proxy configuration
+	// and prevents X-Forwarded-For header spoofing in tracing, rate limiting,
+	// and audit logging.
+	ipExtractor, err := util.NewIPExtractor(cfg.Server.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies configuration: %w", err)
+	}
+	api.SetIPExtractor(ipExtractor)
+	middleware.SetIPExtractor(ipExtractor)
+
+	// Apply middleware
+	// RecoveryMiddleware is intentionally applied LAST so it wraps all other
+	// layers. If it were innermost, panics in outer middleware (logging,
+	// security headers, tracing, bucket validation, rate limiting) would
+	// bypass recovery and crash the server goroutine.
+	httpHandler := middleware.SampledLoggingMiddleware(logger, &cfg.Logging, g.logSampler)(router)
+	httpHandler = middleware.SecurityHeadersMiddleware(cfg.Server.ForceHTTPS)(httpHandler)
+
+	// Apply tracing middleware if tracing is enabled
+	if cfg.Tracing.Enabled {
+		httpHandler = middleware.TracingMiddleware(cfg.Tracing.RedactSensitive, ipExtractor)(httpHandler)
+	}
+
+	// Apply bucket validation middleware if proxied bucket is configured
+	if cfg.ProxiedBucket != "" {
+		httpHandler = middleware.BucketValidationMiddleware(cfg.ProxiedBucket, logger)(httpHandler)
+		logger.WithField("proxied_bucket", cfg.ProxiedBucket).Info("Single bucket proxy mode enabled")
+	}
+
+	// Add rate limiting if enabled
+	if g.rateLimiter != nil {
+		httpHandler = middleware.RateLimitMiddleware(g.rateLimiter)(httpHandler)
+		logger.WithFields(logrus.Fields{
+			"limit":  cfg.RateLimit.Limit,
+			"window": cfg.RateLimit.Window,
+		}).Info("Rate limiting enabled")
+	}
+
+	// The memory budget sits inside auth so unauthenticated requests never
+	// hold a reservation.
+	if memBudget != nil {
+		httpHandler = middleware.MemoryBudgetMiddleware(memBudget, cfg.MemoryBudget.RequestReserve, logger)(httpHandler)
+	}
+
+	// Admission control wraps the memory budget so queued requests hold
+	// neither a slot nor a reservation.
+	if admissionCtrl := admission.New(cfg.Admission, m); admissionCtrl != nil {
+		// Admission runs inside auth, so the signing access key is trusted.
+		isPriority := middleware.PriorityMatcher(cfg.Admission.PriorityBuckets, cfg.Admission.PriorityAccessKeys, func(r *http.Request) string {
+			creds, err := api.ExtractCredentials(r)
+			if err != nil {
+				return ""
+			}
+			return creds.AccessKey
+		})
+		httpHandler = middleware.AdmissionMiddleware(admissionCtrl, cfg.Admission.RetryAfter, cfg.Admission.LargeRequestSize, isPriority, logger)(httpHandler)
+		logger.WithFields(logrus.Fields{
+			"max_in_flight":        admissionCtrl.Stats().MaxInFlight,
+			"queue_size":           cfg.Admission.QueueSize,
+			"max_wait":             cfg.Admission.MaxWait,
+			"priority_buckets":     len(cfg.Admission.PriorityBuckets),
+			"priority_access_keys": len(cfg.Admission.PriorityAccessKeys),
+		}).Info("Admission control enabled")
+	}
+
+	// V1.0-AUTH-1: AuthMiddleware gatekeeps every request before it reaches
+	// business logic. It runs inside RecoveryMiddleware so panics during auth
+	// validation are caught, but it must be outermost among functional
+	// middleware so unauthenticated requests are rejected early.
+	// Billing meters only the requests that pass authentication.
+	if g.billingExporter != nil {
+		httpHandler = middleware.BillingMiddleware(g.billingExporter)(httpHandler)
+	}
+
+	// The authorization policies need the identity AuthMiddleware
+	// attaches, so they sit directly inside it; refused requests are not
+	// billed. Policy modules run once the static policy has allowed the
+	// request.
+	if g.extensions != nil {
+		httpHandler = middleware.ExtensionPolicyMiddleware(g.extensions.Policy, logger)(httpHandler)
+	}
+	if authzPolicy := authz.New(cfg.Auth.Policy); authzPolicy != nil {
+		handler.WithAuthorization(authzPolicy)
+		httpHandler = middleware.AuthorizationMiddleware(authzPolicy, logger)(httpHandler)
+		logger.WithFields(logrus.Fields{
+			"rules":        len(cfg.Auth.Policy.Rules),
+			"default_deny": cfg.Auth.Policy.DefaultDeny,
+		}).Info("Authorization policy enabled")
+	}
+
+	authOpts := api.AuthOptions{ClockSkew: cfg.Auth.ClockSkewTolerance, Metrics: m}
+	if rp := cfg.Auth.ReplayProtection; rp.Enabled {
+		size := rp.CacheSize
+		if size == 0 {
+			size = config.DefaultReplayCacheSize
+		}
+		authOpts.Replay = api.NewReplayCache(size, api.ReplayWindow(cfg.Auth.ClockSkewTolerance))
+		logger.WithFields(logrus.Fields{
+			"cache_size": size,
+			"window":     api.ReplayWindow(cfg.Auth.ClockSkewTolerance),
+		}).Info("Auth replay protection enabled")
+	}
+	httpHandler = api.AuthMiddlewareWithOptions(credStore, authOpts, logger)(httpHandler)
+
+	// Error-budget tracking sits just inside recovery so it observes the final
+	// status and full latency of every request, including auth rejections.
+	if cfg.SLO.Enabled {
+		g.sloTracker = slo.NewTracker(cfg.SLO, m)
+		g.sloTracker.Start(15 * time.Second)
+		httpHandler = middleware.SLOMiddleware(g.sloTracker)(httpHandler)
+		logger.WithFields(logrus.Fields{
+			"windows":        cfg.SLO.Windows,
+			"default_target": cfg.SLO.Default.Target,
+			"objectives":     len(cfg.SLO.Objectives),
+		}).Info("SLO error-budget tracking enabled")
+	}
+
+	// Response header rules sit outside every layer that can answer a request
+	// so auth and rate-limit rejections are rewritten too.
+	if rules := cfg.Server.ResponseHeaderRules; len(rules) > 0 {
+		httpHandler = middleware.HeaderRulesMiddleware(headerrules.New(rules, config.ProtectedResponseHeaders...))(httpHandler)
+		logger.WithField("rules", len(rules)).Info("Response header rules enabled")
+	}
+	if n := len(cfg.Backend.HeaderRules); n > 0 {
+		logger.WithField("rules", n).Info("Backend request header rules enabled")
+	}
+
+	// The developer-mode trace is tagged with the request ID, so it sits
+	// just inside the layer that assigns one.
+	if devtrace.Enabled() {
+		httpHandler = devtrace.Middleware(middleware.RequestIDHeader)(httpHandler)
+	}
+
+	// Request IDs are assigned before any other layer can answer, so every
+	// response and error document carries one.
+	httpHandler = middleware.RequestIDMiddleware()(httpHandler)
+
+	// RecoveryMiddleware wraps the ENTIRE chain so panics in any layer are caught.
+	g.http = middleware.RecoveryMiddleware(logger)(httpHandler)
+
+	return g, nil
+}
+
+// ServeHTTP serves an S3 request, or /health and, when no other listener
+// serves them, /metrics.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.http.ServeHTTP(w, r)
+}
+
+// Ready runs the checks behind /ready and returns the first failure.
+func (g *Gateway) Ready(ctx context.Context) error {
+	return g.handler.Ready(ctx)
+}
+
+// MetricsHandler serves the gateway's Prometheus metrics.
+func (g *Gateway) MetricsHandler() http.Handler {
+	return g.metrics.Handler()
+}
+
+// RegisterAdminRoutes mounts the admin API (key rotation, multipart upload,
+// journal, quota and other operator endpoints) on mux. The routes trust
+// their caller: mux must only be reachable through the admin server's
+// authentication or an equivalent.
+func (g *Gateway) RegisterAdminRoutes(mux *http.ServeMux) error {
+	cfg, logger, m, handler := g.cfg, g.logger, g.metrics, g.handler
+
+	// Register rotation handler on admin mux
+	rotationHandler := api.NewAdminRotationHandler(g.engine, logger, m, g.auditLogger)
+	if err := rotationHandler.WithDryRun(cfg.Admin.Rotation); err != nil {
+		return fmt.Errorf("failed to configure key rotation dry runs: %w", err)
+	}
+	if g.keyHolds != nil {
+		rotationHandler.WithKeyHolds(handler)
+	}
+	rotationHandler.RegisterRoutes(mux)
+
+	// Register MPU admin endpoints
+	var abortFn admin.MPUAbortFunc
+	if g.s3Client != nil {
+		abortFn = g.s3Client.AbortMultipartUpload
+	}
+	var mpuStore mpupkg.StateStore
+	if g.mpuStore != nil {
+		mpuStore = g.mpuStore
+	}
+	admin.RegisterMPUAdminRoutes(mux, mpuStore, abortFn, logger)
+
+	if g.sloTracker != nil {
+		admin.RegisterSLOAdminRoutes(mux, g.sloTracker)
+	}
+	if g.writeJournal != nil {
+		admin.RegisterJournalAdminRoutes(mux, g.writeJournal)
+	}
+	if g.accessStats != nil {
+		admin.RegisterAccessStatsAdminRoutes(mux, g.accessStats)
+	}
+	if g.quotas != nil {
+		admin.RegisterQuotaAdminRoutes(mux, g.quotas)
+	}
+	if g.keyHolds != nil {
+		admin.RegisterKeyHoldAdminRoutes(mux, handler, logger)
+	}
+	admin.RegisterFeatureFlagAdminRoutes(mux, g.featureFlags, logger)
+	admin.RegisterLoggingAdminRoutes(mux, g.logLevels, g.logSampler, logger)
+	// Export/import, shredding and moves run with the gateway's backend
+	// credentials.
+	if g.s3Client != nil {
+		admin.RegisterArchiveAdminRoutes(mux, handler, logger)
+		admin.RegisterShredAdminRoutes(mux, handler, logger)
+		admin.RegisterMoveAdminRoutes(mux, handler, logger)
+	}
+	if g.batchJobs != nil {
+		admin.RegisterBatchAdminRoutes(mux, g.batchJobs, logger)
+	}
+	if g.jobScheduler != nil {
+		admin.RegisterSchedulerAdminRoutes(mux, g.jobScheduler, logger)
+	}
+
+	// V0.6-OBS-1 — register pprof routes when profiling is enabled.
+	if cfg.Admin.Profiling.Enabled {
+		admin.ApplyRuntimeProfilingRates(cfg.Admin.Profiling, logger)
+		admin.RegisterPprofRoutes(
+			mux,
+			cfg.Admin.Profiling,
+			m,
+			g.auditLogger,
+			logger,
+		)
+		m.SetAdminProfilingEnabled(true)
+		logger.WithFields(logrus.Fields{
+			"block_rate":     cfg.Admin.Profiling.BlockRate,
+			"mutex_fraction": cfg.Admin.Profiling.MutexFraction,
+			"max_seconds":    cfg.Admin.Profiling.MaxProfileSeconds,
+			"max_concurrent": cfg.Admin.Profiling.MaxConcurrentProfiles,
+		}).Warn("admin_profiling_enabled") // WARN — this surface widens blast radius
+	}
+
+	// Register metrics on admin mux only when no dedicated metrics addr is
+	// configured (fallback: authenticated admin port).
+	if cfg.Metrics.Addr == "" {
+		mux.Handle("/metrics", m.Handler())
+	}
+
+	// Set admin API enabled metric
+	m.SetAdminAPIEnabled(true)
+	return nil
+}
+
+// Close stops the background subsystems, flushing what they buffer, and
+// releases the gateway's connections and key material. Stop serving
+// requests before calling it; ctx bounds the flushes. Close is safe to call
+// more than once and returns the problems met on the way, if any.
+func (g *Gateway) Close(ctx context.Context) error {
+	g.closeOnce.Do(func() {
+		g.closeErr = g.close(ctx)
+	})
+	return g.closeErr
+}
+
+func (g *Gateway) close(ctx context.Context) error {
+	var errs []error
+	note := func(what string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", what, err))
+		}
+	}
+
+	if g.configReloader != nil {
+		g.configReloader.Stop()
+	}
+
+	// Queued upgrades are abandoned; the objects are offered again when next read.
+	note("lazy upgrade did not stop", g.upgrader.Stop(ctx))
+	// Let queued hooks finish before their output can no longer be stored.
+	note("hooks did not finish", g.hookPipeline.Stop(ctx))
+	// Flush size index updates from the requests that just drained.
+	note("failed to flush size index", g.sizeIndex.Stop(ctx))
+	note("tiering pass did not stop", g.tieringWorker.Stop(ctx))
+	// Scheduled passes in progress are cancelled like those of the workers'
+	// own loops.
+	note("scheduled jobs did not stop", g.jobScheduler.Stop(ctx))
+	// A running batch job is cancelled; its report still records its progress.
+	note("batch job did not stop", g.batchJobs.Stop(ctx))
+	note("quota scan did not stop", g.quotas.Stop(ctx))
+	// The final export covers the traffic of the requests that just drained.
+	note("failed to write the final billing export", g.billingExporter.Stop(ctx))
+	note("trash purge did not stop", g.trashBin.Stop(ctx))
+	note("failed to flush access stats", g.accessStats.Stop(ctx))
+
+	g.stop()
+	if g.sloTracker != nil {
+		g.sloTracker.Stop()
+	}
+	if g.rateLimiter != nil {
+		g.rateLimiter.Stop()
+	}
+	// A reload may have replaced the limiter.
+	if g.configApplier != nil && g.configApplier.rateLimiter != nil {
+		g.configApplier.rateLimiter.Stop()
+	}
+	// Zeroise any cached per-policy engine passwords.
+	if g.handler != nil {
+		g.handler.Close()
+	}
+	if g.auditLogger != nil {
+		note("failed to close audit logger", g.auditLogger.Close())
+	}
+	if g.mpuStore != nil {
+		note("failed to close MPU state store", g.mpuStore.Close())
+	}
+	if g.writeJournal != nil {
+		note("failed to close write-ahead journal", g.writeJournal.Close())
+	}
+	if g.keyManager != nil {
+		note("failed to close key manager", g.keyManager.Close(ctx))
+	}
+	note("failed to close extension modules", g.extensions.Close(ctx))
+	if g.airGapped {
+		airgap.Install(nil)
+	}
+	return errors.Join(errs...)
+}
+
+// zeroBytes overwrites a byte slice with zeros for secure memory cleanup.
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,336 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/airgap"
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/pkg/gatewayclient"
+	"github.com/kenneth/s3-encryption-gateway/test/testsupport"
+)
+
+const (
+	testAccessKey = "AKIAEMBEDDED"
+	testSecretKey = "embedded-secret-key-0123456789"
+)
+
+// testConfig returns a valid configuration storing objects under a
+// temporary directory, with the bucket "data" already created.
+func testConfig(t *testing.T) *Config {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "data"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	cfg.Backend.Type = config.BackendTypeFilesystem
+	cfg.Backend.Filesystem.Root = root
+	cfg.Encryption.Password = "embedded-test-password-0123456789"
+	cfg.Encryption.KDF.PBKDF2.Iterations = 100000
+	cfg.Auth.Credentials = []config.GatewayCredential{{AccessKey: testAccessKey, SecretKey: testSecretKey}}
+	return cfg
+}
+
+func quietLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func startGateway(t *testing.T, cfg *Config, opts ...Option) (*Gateway, *httptest.Server) {
+	t.Helper()
+	opts = append([]Option{WithLogger(quietLogger()), WithMetricsRegistry(prometheus.NewRegistry())}, opts...)
+	gw, err := New(cfg, opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	srv := httptest.NewServer(gw)
+	t.Cleanup(func() {
+		srv.Close()
+		if err := gw.Close(context.Background()); err != nil {
+			t.Errorf("Close: %v", err)
+		}
+	})
+	return gw, srv
+}
+
+func TestNew_ServesEncryptedObjects(t *testing.T) {
+	cfg := testConfig(t)
+	_, srv := startGateway(t, cfg)
+
+	ctx := context.Background()
+	client, err := gatewayclient.NewS3Client(ctx, gatewayclient.Options{
+		Endpoint:        srv.URL,
+		AccessKeyID:     testAccessKey,
+		SecretAccessKey: testSecretKey,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Client: %v", err)
+	}
+
+	plaintext := []byte("stored through the embedded gateway")
+	if _, err := client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String("data"),
+		Key:    aws.String("a.txt"),
+		Body:   bytes.NewReader(plaintext),
+	}); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String("data"), Key: aws.String("a.txt")})
+	if err != nil {
+		t.Fatalf("GetObject: %v", err)
+	}
+	got, err := io.ReadAll(out.Body)
+	_ = out.Body.Close()
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("GetObject = %q, want %q", got, plaintext)
+	}
+
+	// The backend only ever sees ciphertext.
+	err = filepath.WalkDir(cfg.Backend.Filesystem.Root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if bytes.Contains(data, plaintext) {
+			t.Errorf("%s holds the plaintext", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNew_WithBackend(t *testing.T) {
+	cfg := testConfig(t)
+	mem := testsupport.NewMemoryClient()
+	_, srv := startGateway(t, cfg, WithBackend(mem))
+
+	ctx := context.Background()
+	client, err := gatewayclient.NewS3Client(ctx, gatewayclient.Options{
+		Endpoint:        srv.URL,
+		AccessKeyID:     testAccessKey,
+		SecretAccessKey: testSecretKey,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Client: %v", err)
+	}
+	plaintext := []byte("stored in the supplied backend")
+	if _, err := client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String("data"),
+		Key:    aws.String("a.txt"),
+		Body:   bytes.NewReader(plaintext),
+	}); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+
+	body, _, err := mem.GetObject(ctx, "data", "a.txt", nil, nil)
+	if err != nil {
+		t.Fatalf("object not in the supplied backend: %v", err)
+	}
+	stored, _ := io.ReadAll(body)
+	body.Close()
+	if len(stored) == 0 || bytes.Contains(stored, plaintext) {
+		t.Errorf("supplied backend holds %q, want ciphertext", stored)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(cfg.Backend.Filesystem.Root, "data")); len(entries) != 0 {
+		t.Errorf("configured backend was written to: %v", entries)
+	}
+}
+
+func TestNew_RequiresAuthentication(t *testing.T) {
+	_, srv := startGateway(t, testConfig(t))
+
+	resp, err := http.Get(srv.URL + "/data/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("unsigned GET status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+func TestNew_MetricsOnS3Port(t *testing.T) {
+	_, srv := startGateway(t, testConfig(t))
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "gateway_build_info") {
+		t.Errorf("GET /metrics = %d, want the gateway's metrics; body %.200s", resp.StatusCode, body)
+	}
+}
+
+func TestNew_MetricsElsewhere(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Metrics.Addr = "127.0.0.1:0"
+	gw, srv := startGateway(t, cfg)
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if strings.Contains(string(body), "gateway_build_info") {
+		t.Error("metrics served on the S3 port although metrics.addr is set")
+	}
+
+	rec := httptest.NewRecorder()
+	gw.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "gateway_build_info") {
+		t.Error("MetricsHandler does not serve the gateway's metrics")
+	}
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Auth.Credentials = nil
+	if gw, err := New(cfg, WithLogger(quietLogger()), WithMetricsRegistry(prometheus.NewRegistry())); err == nil {
+		_ = gw.Close(context.Background())
+		t.Fatal("New accepted a configuration without credentials")
+	}
+	if _, err := New(nil); err == nil {
+		t.Fatal("New accepted a nil configuration")
+	}
+}
+
+func TestGateway_RegisterAdminRoutes(t *testing.T) {
+	gw, _ := startGateway(t, testConfig(t))
+
+	mux := http.NewServeMux()
+	if err := gw.RegisterAdminRoutes(mux); err != nil {
+		t.Fatalf("RegisterAdminRoutes: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/logging", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /admin/logging = %d, want %d", rec.Code, http.StatusOK)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "gateway_build_info") {
+		t.Error("admin mux does not serve metrics without metrics.addr")
+	}
+}
+
+func TestGateway_CloseTwice(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.RateLimit.Enabled = true
+	cfg.RateLimit.Limit = 10
+	cfg.RateLimit.Window = time.Second
+	gw, err := New(cfg, WithLogger(quietLogger()), WithMetricsRegistry(prometheus.NewRegistry()))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := gw.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := gw.Close(context.Background()); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+}
+
+func TestNew_AirGapped(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AirGapped.Enabled = true
+	gw, err := New(cfg, WithLogger(quietLogger()), WithMetricsRegistry(prometheus.NewRegistry()))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := http.Get("http://telemetry.example.com/"); !errors.Is(err, airgap.ErrBlocked) {
+		t.Errorf("outbound GET while running: err = %v, want airgap.ErrBlocked", err)
+	}
+	rec := httptest.NewRecorder()
+	gw.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), `gateway_airgap_blocked_connections_total{host="telemetry.example.com"} 1`) {
+		t.Error("refused connection not counted")
+	}
+	if err := gw.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if airgap.Active() != nil {
+		t.Error("air-gap guard still installed after Close")
+	}
+
+	cfg = testConfig(t)
+	cfg.AirGapped.Enabled = true
+	cfg.Tracing = config.TracingConfig{Enabled: true, ServiceName: "gw", Exporter: "otlp", OtlpEndpoint: "otel.example.com:4317", SamplingRatio: 1}
+	if gw, err := New(cfg, WithLogger(quietLogger()), WithMetricsRegistry(prometheus.NewRegistry())); err == nil {
+		_ = gw.Close(context.Background())
+		t.Fatal("New accepted an unlisted tracing collector in air-gapped mode")
+	}
+}
+
+func TestNew_StreamedGetFlushesThroughMiddleware(t *testing.T) {
+	cfg := testConfig(t)
+	// Every optional layer that wraps the response writer.
+	cfg.Tracing = config.TracingConfig{Enabled: true, ServiceName: "gw", Exporter: "none", SamplingRatio: 1}
+	cfg.SLO.Enabled = true
+	gw, srv := startGateway(t, cfg)
+
+	ctx := context.Background()
+	client, err := gatewayclient.NewS3Client(ctx, gatewayclient.Options{
+		Endpoint:        srv.URL,
+		AccessKeyID:     testAccessKey,
+		SecretAccessKey: testSecretKey,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Client: %v", err)
+	}
+	plaintext := bytes.Repeat([]byte("streamed "), 1<<16)
+	if _, err := client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String("data"),
+		Key:    aws.String("big.bin"),
+		Body:   bytes.NewReader(plaintext),
+	}); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/data/big.bin", nil)
+	req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+	creds := aws.Credentials{AccessKeyID: testAccessKey, SecretAccessKey: testSecretKey}
+	if err := v4.NewSigner().SignHTTP(ctx, creds, req, "UNSIGNED-PAYLOAD", "s3", "us-east-1", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	// The recorder records Flush calls, so it only sees one if
+	// http.ResponseController gets through every wrapper in the chain.
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d; body = %s", rec.Code, rec.Body.String())
+	}
+	if !bytes.Equal(rec.Body.Bytes(), plaintext) {
+		t.Fatalf("GET returned %d bytes, want %d", rec.Body.Len(), len(plaintext))
+	}
+	if !rec.Flushed {
+		t.Error("response was never flushed through the middleware chain")
+	}
+}
@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kenneth/s3-encryption-gateway/pkg/backend"
+)
+
+// Option configures a Gateway built by New.
+type Option func(*options)
+
+type options struct {
+	logger     *logrus.Logger
+	registry   prometheus.Registerer
+	configFile string
+	version    string
+	commit     string
+	date       string
+	backend    backend.Client
+}
+
+func defaultOptions() options {
+	return options{
+		registry: prometheus.DefaultRegisterer,
+		version:  "dev",
+		commit:   "unknown",
+		date:     "unknown",
+	}
+}
+
+// WithLogger sends the gateway's logs, including its access log, to logger.
+// The gateway wraps logger's formatter to apply logging.sampling and may
+// change its level on a config reload or an admin request. By default a
+// JSON logger at the configured log_level is created.
+func WithLogger(logger *logrus.Logger) Option {
+	return func(o *options) { o.logger = logger }
+}
+
+// WithMetricsRegistry registers the gateway's Prometheus metrics with reg
+// instead of the default registry, so they can be served alongside the
+// embedding application's own. If reg is also a prometheus.Gatherer, the
+// gateway's metrics handler serves from it.
+func WithMetricsRegistry(reg prometheus.Registerer) Option {
+	return func(o *options) { o.registry = reg }
+}
+
+// WithConfigFile enables hot reload: path is watched and the settings that
+// can change at runtime are applied when it changes. cfg passed to New
+// should have been loaded from path.
+func WithConfigFile(path string) Option {
+	return func(o *options) { o.configFile = path }
+}
+
+// WithVersion sets the build reported on /version and in the
+// gateway_build_info metric.
+func WithVersion(version, commit, date string) Option {
+	return func(o *options) {
+		o.version = version
+		o.commit = commit
+		o.date = date
+	}
+}
+
+// WithBackend stores objects through client instead of connecting to the
+// backend described by cfg.Backend, for example a backend.MemoryClient in
+// tests or a custom store. cfg.Backend must still pass validation. Sharding,
+// tiering and key and metadata mapping wrap client as they would the
+// configured backend.
+func WithBackend(client backend.Client) Option {
+	return func(o *options) { o.backend = client }
+}
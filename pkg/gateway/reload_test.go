@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStringSlicesEqual(t *testing.T) {
+	assert.True(t, stringSlicesEqual(nil, nil))
+	assert.True(t, stringSlicesEqual([]string{}, []string{}))
+	assert.True(t, stringSlicesEqual([]string{"a", "b"}, []string{"a", "b"}))
+	assert.False(t, stringSlicesEqual([]string{"a"}, []string{"a", "b"}))
+	assert.False(t, stringSlicesEqual([]string{"a", "b"}, []string{"a", "c"}))
+	assert.False(t, stringSlicesEqual([]string{"a"}, nil))
+}
+
+func TestApplyConfigChanges_PolicyFiles(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	// Create temporary policy files
+	tmpDir := t.TempDir()
+	policyFile1 := tmpDir + "/policy1.yaml"
+	policyFile2 := tmpDir + "/policy2.yaml"
+
+	require.NoError(t, os.WriteFile(policyFile1, []byte(`
+id: test-policy-1
+buckets:
+  - "test-*"
+`), 0600))
+	require.NoError(t, os.WriteFile(policyFile2, []byte(`
+id: test-policy-2
+buckets:
+  - "other-*"
+`), 0600))
+
+	oldCfg := &config.Config{
+		ListenAddr:  ":8080",
+		PolicyFiles: []string{policyFile1},
+	}
+	newCfg := &config.Config{
+		ListenAddr:  ":8080",
+		PolicyFiles: []string{policyFile1, policyFile2},
+	}
+
+	pm := config.NewPolicyManager()
+	applier := newConfigApplier(logger, nil, nil, nil, oldCfg, pm)
+
+	err := applier.ApplyConfigChanges(oldCfg, newCfg)
+	require.NoError(t, err)
+	assert.NotNil(t, applier.policyManager)
+}
@@ -0,0 +1,93 @@
+package gatewayclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/api"
+)
+
+// signedServer accepts requests whose SigV4 signature the gateway would
+// accept and records their paths.
+func signedServer(t *testing.T, secret string) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := api.ValidateSignatureV4(r, secret, 5*time.Minute); err != nil {
+			t.Errorf("%s %s: signature rejected: %v", r.Method, r.URL.Path, err)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = io.Copy(io.Discard, r.Body)
+		mu.Lock()
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), paths...)
+	}
+}
+
+func TestNewS3Client(t *testing.T) {
+	srv, paths := signedServer(t, "secret")
+	client, err := NewS3Client(context.Background(), Options{
+		Endpoint:        srv.URL,
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("dir/key.txt"),
+		Body:   strings.NewReader("hello"),
+	})
+	if err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	if got := paths(); len(got) != 1 || got[0] != "PUT /bucket/dir/key.txt" {
+		t.Errorf("requests = %v, want a path-style PUT", got)
+	}
+}
+
+func TestLoadConfig_InvalidEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"", "localhost:8080", "ftp://gateway"} {
+		if _, err := LoadConfig(context.Background(), Options{Endpoint: endpoint}); err == nil {
+			t.Errorf("endpoint %q accepted", endpoint)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv, paths := signedServer(t, "secret")
+	client := &http.Client{Transport: NewTransport("AKID", "secret")}
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/bucket/raw", strings.NewReader("body"))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	if req.Header.Get("Authorization") != "" {
+		t.Error("RoundTrip modified the caller's request")
+	}
+	if got := paths(); len(got) != 1 || got[0] != "PUT /bucket/raw" {
+		t.Errorf("requests = %v", got)
+	}
+}
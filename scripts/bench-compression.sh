@@ -0,0 +1,114 @@
+#!/usr/bin/env bash
+# bench-compression — compression × encryption recommendation table.
+#
+# Runs BenchmarkCompressionEncrypt (internal/crypto/compression_bench_test.go)
+# and prints a Markdown table of throughput and storage ratio for every
+# corpus / codec / encryption-mode combination, followed by a recommendation
+# per corpus.
+#
+# Usage:
+#   scripts/bench-compression.sh [RAW_OUTPUT]
+#
+# The benchmark skips itself unless BENCH_COMPRESSION=1 (which this script
+# sets), keeping it out of the nightly micro baseline.
+#
+# RAW_OUTPUT, when given, keeps the raw `go test` output (benchstat-ready).
+# Set BENCH_INPUT to an existing raw file to re-render it without running
+# the benchmarks again.
+#
+# Environment overrides:
+#   BENCH_TIME          default 2s
+#   BENCH_COUNT         default 3 (results are averaged)
+#   BENCH_CPU           default 4
+#   MIN_SPEED_PCT       default 50 — a candidate must keep at least this
+#                       share of the uncompressed throughput
+#   MIN_SAVING_PCT      default 10 — below this saving compression is not
+#                       worth its CPU and "off" is recommended
+#
+# "deployable" marks the combinations the gateway runs today: compression
+# off in either mode, and gzip with chunked_mode disabled. The others
+# (zstd, gzip with chunked encryption) are measured as compress-then-encrypt
+# pipelines and are listed for comparison only.
+
+set -euo pipefail
+
+cd "$(dirname "$0")/.."
+
+raw="${BENCH_INPUT:-}"
+if [[ -z "$raw" ]]; then
+  raw="${1:-$(mktemp)}"
+  printf 'bench-compression: running benchmarks → %s\n' "$raw" >&2
+  BENCH_COMPRESSION=1 go test \
+    -run='^$' \
+    -bench='^BenchmarkCompressionEncrypt$' \
+    -benchmem \
+    -benchtime="${BENCH_TIME:-2s}" \
+    -count="${BENCH_COUNT:-3}" \
+    -cpu="${BENCH_CPU:-4}" \
+    -timeout=30m \
+    ./internal/crypto/ >"$raw"
+fi
+
+awk -v min_speed="${MIN_SPEED_PCT:-50}" -v min_saving="${MIN_SAVING_PCT:-10}" '
+/^BenchmarkCompressionEncrypt\// {
+  name = $1
+  sub(/-[0-9]+$/, "", name)
+  split(name, parts, "/")
+  corpus = parts[2]; codec = parts[3]; mode = parts[4]
+  sub(/^corpus=/, "", corpus); sub(/^codec=/, "", codec); sub(/^mode=/, "", mode)
+  key = corpus SUBSEP codec SUBSEP mode
+  if (!(key in n)) { order[++rows] = key }
+  n[key]++
+  for (i = 3; i < NF; i++) {
+    if ($(i+1) == "MB/s")         mbps[key] += $i
+    if ($(i+1) == "stored/plain") ratio[key] += $i
+    if ($(i+1) == "B/op")         bop[key] += $i
+    if ($(i+1) == "allocs/op")    allocs[key] += $i
+  }
+}
+END {
+  if (rows == 0) { print "bench-compression: no BenchmarkCompressionEncrypt results found" > "/dev/stderr"; exit 1 }
+
+  print "| corpus | codec | mode | MB/s | stored/plain | B/op | allocs/op | deployable |"
+  print "|---|---|---|---:|---:|---:|---:|---|"
+  for (r = 1; r <= rows; r++) {
+    key = order[r]
+    split(key, k, SUBSEP)
+    c = n[key]
+    mbps[key] /= c; ratio[key] /= c; bop[key] /= c; allocs[key] /= c
+    deploy[key] = (k[2] == "none" || (k[2] ~ /^gzip-/ && k[3] == "buffered")) ? "yes" : "no"
+    if (!(k[1] in seen)) { corpora[++ncorpora] = k[1]; seen[k[1]] = 1 }
+    if (k[2] == "none" && mbps[key] > base[k[1]]) base[k[1]] = mbps[key]
+    printf "| %s | %s | %s | %.1f | %.3f | %d | %d | %s |\n", k[1], k[2], k[3], mbps[key], ratio[key], bop[key], allocs[key], deploy[key]
+  }
+
+  print ""
+  print "Recommendation (lowest stored/plain keeping >= " min_speed "% of uncompressed MB/s; off below " min_saving "% saving):"
+  print ""
+  for (ci = 1; ci <= ncorpora; ci++) {
+    corpus = corpora[ci]
+    best = ""; alt = ""
+    for (r = 1; r <= rows; r++) {
+      key = order[r]
+      split(key, k, SUBSEP)
+      if (k[1] != corpus || k[2] == "none") continue
+      if (mbps[key] * 100 < base[corpus] * min_speed) continue
+      if (deploy[key] == "yes") {
+        if (best == "" || ratio[key] < ratio[best]) best = key
+      } else if (alt == "" || ratio[key] < ratio[alt]) {
+        alt = key
+      }
+    }
+    rec = "compression off"
+    if (best != "" && (1 - ratio[best]) * 100 >= min_saving) {
+      split(best, k, SUBSEP)
+      rec = sprintf("%s (chunked_mode off): %.1f MB/s, stores %.0f%% of the plaintext", k[2], mbps[best], ratio[best] * 100)
+    }
+    printf "- %s: %s\n", corpus, rec
+    if (alt != "" && (1 - ratio[alt]) * 100 >= min_saving && (best == "" || ratio[alt] < ratio[best])) {
+      split(alt, k, SUBSEP)
+      printf "  - not yet deployable: %s/%s would store %.0f%% at %.1f MB/s\n", k[2], k[3], ratio[alt] * 100, mbps[alt]
+    }
+  }
+}
+' "$raw"
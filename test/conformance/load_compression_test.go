@@ -0,0 +1,176 @@
+//go:build conformance
+
+package conformance
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"runtime/metrics"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/config"
+	"github.com/kenneth/s3-encryption-gateway/test/harness"
+	"github.com/kenneth/s3-encryption-gateway/test/provider"
+)
+
+// ── Compression load test ───────────────────────────────────────────────────
+
+// testCompressionLoad runs PUT+GET round trips through a fresh gateway for
+// every combination of corpus (compressible JSON text, incompressible random
+// bytes) and compression setting (off, gzip levels 1/6/9), and logs a table
+// of throughput, p95 latency and CPU cost per GiB so the settings can be
+// compared on a real backend. Both corpora are sent as text/plain so the
+// gateway compresses the random one too — the cost of misjudged content.
+//
+// Each combination runs for the full SOAK_DURATION and emits its own
+// SummaryRecord (Load_Compression/<corpus>/<setting>) under SOAK_JSON_OUT.
+// The micro-benchmark counterpart, including zstd and the stored size, is
+// BenchmarkCompressionEncrypt (scripts/bench-compression.sh).
+func testCompressionLoad(t *testing.T, inst provider.Instance) {
+	t.Helper()
+	p := ciLoadParams()
+	logParams(t, "CompressionLoad", p)
+
+	corpora := []struct {
+		name string
+		data []byte
+	}{
+		{"text", compressibleCorpus(p.objectSize)},
+		{"random", incompressibleCorpus(p.objectSize)},
+	}
+	levels := []int{0, 1, 6, 9} // 0 = compression off
+
+	var table strings.Builder
+	table.WriteString("| corpus | compression | MB/s | p95 | CPU s/GiB | errors |\n")
+	table.WriteString("|---|---|---:|---:|---:|---:|\n")
+
+	for _, corpus := range corpora {
+		for _, level := range levels {
+			setting := "off"
+			opts := []harness.Option{}
+			if level > 0 {
+				setting = fmt.Sprintf("gzip-%d", level)
+				lvl := level
+				opts = append(opts,
+					harness.WithCompression("gzip"),
+					harness.WithConfigMutator(func(cfg *config.Config) {
+						cfg.Compression.MinSize = 0
+						cfg.Compression.Level = lvl
+					}),
+				)
+			}
+			label := fmt.Sprintf("Load_Compression/%s/%s", corpus.name, setting)
+			gw := harness.StartGateway(t, inst, opts...)
+
+			var res loadResults
+			sampler := NewHeapSampler(500 * time.Millisecond)
+			sampler.Start()
+			cpuBefore := userCPUSeconds()
+
+			data := corpus.data
+			runWorkers(t, p, func(workerID int, idx int64, client *http.Client) {
+				key := fmt.Sprintf("compression-load/%s/%s/w%d/%d", corpus.name, setting, workerID, idx)
+				atomic.AddInt64(&res.total, 1)
+				t0 := time.Now()
+				if err := putGetRoundTrip(client, objectURL(gw, inst.Bucket, key), data); err != nil {
+					t.Logf("%s worker %d: %v", label, workerID, err)
+					atomic.AddInt64(&res.failed, 1)
+					res.recordLatency(time.Since(t0), 0)
+					return
+				}
+				atomic.AddInt64(&res.success, 1)
+				res.recordLatency(time.Since(t0), 2*int64(len(data)))
+			})
+
+			cpu := userCPUSeconds() - cpuBefore
+			sampler.Stop()
+			reportResults(t, label, p, &res, sampler.Max())
+
+			res.mu.Lock()
+			pct := Percentiles(res.latencies)
+			moved := res.totalBytes
+			res.mu.Unlock()
+			mbps := float64(moved) / (1024 * 1024) / p.duration.Seconds()
+			var cpuPerGiB float64
+			if moved > 0 {
+				cpuPerGiB = cpu / (float64(moved) / (1 << 30))
+			}
+			fmt.Fprintf(&table, "| %s | %s | %.1f | %s | %.2f | %d |\n",
+				corpus.name, setting, mbps, time.Duration(pct.P95).Round(time.Millisecond), cpuPerGiB, res.failed)
+		}
+	}
+
+	t.Logf("CompressionLoad results (%s objects, buffered mode):\n%s", humanBytes(p.objectSize), table.String())
+}
+
+// putGetRoundTrip uploads data as text/plain, reads it back and checks the
+// body matches.
+func putGetRoundTrip(client *http.Client, url string, data []byte) error {
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("PUT: %w", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("PUT: status %d", resp.StatusCode)
+	}
+
+	resp, err = client.Get(url)
+	if err != nil {
+		return fmt.Errorf("GET: %w", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("GET: read body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET: status %d", resp.StatusCode)
+	}
+	if !bytes.Equal(body, data) {
+		return fmt.Errorf("GET: body mismatch: got %d bytes, want %d", len(body), len(data))
+	}
+	return nil
+}
+
+// compressibleCorpus returns size bytes of JSON log lines, which gzip
+// shrinks to roughly a quarter.
+func compressibleCorpus(size int64) []byte {
+	rng := rand.New(rand.NewSource(1))
+	var buf bytes.Buffer
+	for int64(buf.Len()) < size {
+		fmt.Fprintf(&buf, `{"ts":%d,"level":"INFO","path":"/api/v1/objects/%d","status":%d,"request_id":"%016x"}`+"\n",
+			rng.Int63n(1<<40), rng.Intn(1000), 200+rng.Intn(4)*100, rng.Uint64())
+	}
+	return buf.Bytes()[:size]
+}
+
+// incompressibleCorpus returns size random bytes.
+func incompressibleCorpus(size int64) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(2)).Read(data)
+	return data
+}
+
+// userCPUSeconds returns the process's cumulative CPU time spent running Go
+// code, gateway and load workers alike (they share the process).
+func userCPUSeconds() float64 {
+	s := []metrics.Sample{{Name: "/cpu/classes/user:cpu-seconds"}}
+	metrics.Read(s)
+	if s[0].Value.Kind() != metrics.KindFloat64 {
+		return 0
+	}
+	return s[0].Value.Float64()
+}
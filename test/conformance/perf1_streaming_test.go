@@ -10,7 +10,7 @@ package conformance
 //   - testCopyObject_LargeChunked    — CopyObject on a multi-chunk chunked object
 //     round-trips correctly after the Phase C streaming pipeline (no intermediate
 //     decryptedData []byte allocation).
-//   - testChunkedRangedRead_Large    — large range read via io.CopyBuffer (Phase B)
+//   - testChunkedRangedRead_Large    — large range read streamed to the writer (Phase B)
 //     spanning two chunk boundaries on a 7-chunk object.
 //   - testCompression_RoundTrip      — compression streaming (Phase E) produces a
 //     correctly decompressable ciphertext when gzip is enabled.
@@ -75,8 +75,8 @@ func testCopyObject_LargeChunked(t *testing.T, inst provider.Instance) {
 
 // testChunkedRangedRead_Large verifies that a range read on a large
 // chunked-encrypted object returns the correct bytes via the Phase B
-// io.CopyBuffer streaming path.  7 chunks × 64 KiB; the range spans two
-// chunk boundaries so the optimised-range path (io.CopyBuffer to the writer)
+// streaming path.  7 chunks × 64 KiB; the range spans two
+// chunk boundaries so the optimised-range path (streamed to the writer)
 // is exercised end-to-end against a real backend.
 //
 // V0.6-PERF-1 Phase B regression guard.
@@ -125,11 +125,13 @@ func TestConformance(t *testing.T) {
 			// in-process gateway can reach the KMS container.
 			{"KMS_EnvelopeEncryption", provider.CapKMSIntegration, testKMSIntegration},
 
-			// In-process load tests (range concurrency + multipart throughput).
+			// In-process load tests (range concurrency, multipart throughput,
+			// compression settings compared).
 			// Only run against local providers (MinIO, Garage) where per-request
 			// latency is low enough for meaningful QPS assertions.
 			{"Load_RangeRead", provider.CapLoadTest, testRangeLoad},
 			{"Load_Multipart", provider.CapLoadTest | provider.CapMultipartUpload, testMultipartLoad},
+			{"Load_Compression", provider.CapLoadTest, testCompressionLoad},
 
 			// Chaos tests — in-process ToxicServer, no real S3 backend used.
 			// Gated on CapLoadTest so they only run on local providers (once
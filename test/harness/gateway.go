@@ -179,6 +179,7 @@ func StartGateway(t *testing.T, inst provider.Instance, opts ...Option) *Gateway
 			cfg.Compression.ContentTypes,
 			cfg.Compression.Algorithm,
 			cfg.Compression.Level,
+			crypto.WithMaxRatio(cfg.Compression.MaxRatio),
 		)
 	}
 
@@ -277,7 +278,7 @@ func StartGateway(t *testing.T, inst provider.Instance, opts ...Option) *Gateway
 			listener.Close()
 			t.Fatalf("harness.StartGateway: create credential store: %v", credErr)
 		}
-		httpHandler = api.AuthMiddleware(credStore, cfg.Auth.ClockSkewTolerance, logger)(httpHandler)
+		httpHandler = api.AuthMiddlewareWithClock(credStore, cfg.Auth.ClockSkewTolerance, o.clock, logger)(httpHandler)
 	}
 
 	// Wire bucket validation middleware if proxied_bucket is configured.
@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	"github.com/kenneth/s3-encryption-gateway/internal/audit"
+	"github.com/kenneth/s3-encryption-gateway/internal/clock"
 	"github.com/kenneth/s3-encryption-gateway/internal/config"
 	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
 	"github.com/kenneth/s3-encryption-gateway/internal/mpu"
@@ -52,6 +53,9 @@ type options struct {
 	adminToken   string
 	// adminProfiling enables /admin/debug/pprof/* when adminEnabled is also true.
 	adminProfiling bool
+	// clock, when non-nil, replaces the wall clock for request signature
+	// timestamps and presigned URL expiry. See WithClock.
+	clock clock.Clock
 }
 
 // Option is a functional option for StartGateway.
@@ -172,6 +176,13 @@ func WithAuth(creds ...config.GatewayCredential) Option {
 	}
 }
 
+// WithClock makes the gateway check request timestamps and presigned URL
+// expiry against clk, typically a *clock.Fake, so tests can move past an
+// expiry without waiting for it. Only takes effect together with WithAuth.
+func WithClock(clk clock.Clock) Option {
+	return func(o *options) { o.clock = clk }
+}
+
 // WithAdminServer starts an admin listener alongside the gateway. The listener
 // binds to a random loopback port; its URL is exposed via Gateway.AdminURL.
 // token is the bearer token used for authentication (inline — test-only).
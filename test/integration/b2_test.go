@@ -0,0 +1,19 @@
+//go:build b2
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+)
+
+// TestB2 runs the provider suite against Backblaze B2. Requires
+// B2_ACCESS_KEY_ID, B2_SECRET_ACCESS_KEY and B2_BUCKET_NAME (B2_ENDPOINT and
+// B2_REGION default to eu-central-003).
+func TestB2(t *testing.T) {
+	runProviderSuite(t, providerSuite{
+		provider:          "backblaze-b2",
+		userMetadataLimit: crypto.ProviderBackblaze.UserMetadataLimit,
+	})
+}
@@ -0,0 +1,22 @@
+//go:build r2
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+)
+
+// TestR2 runs the provider suite against Cloudflare R2. Requires
+// R2_ACCESS_KEY_ID, R2_SECRET_ACCESS_KEY, R2_BUCKET_NAME and either
+// R2_ACCOUNT_ID or R2_ENDPOINT.
+//
+// The gateway has no R2 profile, so the suite holds R2 to the default
+// metadata budget the gateway assumes for unknown providers.
+func TestR2(t *testing.T) {
+	runProviderSuite(t, providerSuite{
+		provider:          "cloudflare-r2",
+		userMetadataLimit: crypto.ProviderDefault.UserMetadataLimit,
+	})
+}
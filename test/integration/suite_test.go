@@ -0,0 +1,406 @@
+//go:build b2 || r2 || wasabi
+
+// Package integration holds per-provider integration suites for public S3
+// vendors whose behaviour has diverged from AWS in ways that hurt gateway
+// users: metadata limits, range reads and multipart uploads.
+//
+// Build tags: one per vendor (b2, r2, wasabi), so each suite is compiled
+// and run only on request:
+//
+//	make test-integration-b2       # Backblaze B2
+//	make test-integration-r2       # Cloudflare R2
+//	make test-integration-wasabi   # Wasabi
+//
+// Credentials come from the environment variables read by the vendor's
+// registration in test/provider (b2.go, r2.go, wasabi.go); without them the
+// suite skips. Unlike the provider-agnostic conformance suite, these tests
+// may encode what one vendor is known to do, and each vendor file states
+// its expectations in a providerSuite.
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/test/harness"
+	"github.com/kenneth/s3-encryption-gateway/test/provider"
+)
+
+// providerSuite describes one vendor's suite.
+type providerSuite struct {
+	// provider is the test/provider registry name.
+	provider string
+	// userMetadataLimit is the user metadata budget, in bytes, the vendor
+	// documents for x-amz-meta-* headers.
+	userMetadataLimit int
+}
+
+// minPartSize is the S3 minimum size of every multipart part but the last.
+const minPartSize = 5 * 1024 * 1024
+
+// runProviderSuite runs every check in s against a gateway in front of the
+// vendor's bucket.
+func runProviderSuite(t *testing.T, s providerSuite) {
+	p := provider.ByName(s.provider)
+	if p == nil {
+		t.Skipf("%s is not registered; set its credentials (see test/provider)", s.provider)
+	}
+	inst := p.Start(context.Background(), t)
+	env := &suiteEnv{inst: inst, cleanup: p.CleanupPolicy()}
+
+	t.Run("Metadata", func(t *testing.T) {
+		gw := harness.StartGateway(t, inst)
+		t.Run("RoundTrip", func(t *testing.T) { testMetadataRoundTrip(t, env, gw) })
+		t.Run("WithinLimit", func(t *testing.T) { testMetadataWithinLimit(t, env, gw, s.userMetadataLimit) })
+		t.Run("OverLimit", func(t *testing.T) { testMetadataOverLimit(t, env, gw, s.userMetadataLimit) })
+	})
+	for _, mode := range []struct {
+		name    string
+		chunked bool
+	}{{"Chunked", true}, {"Buffered", false}} {
+		t.Run("Range/"+mode.name, func(t *testing.T) {
+			testRangeReads(t, env, harness.StartGateway(t, inst, harness.WithChunking(mode.chunked)))
+		})
+	}
+	t.Run("Multipart", func(t *testing.T) {
+		gw := harness.StartGateway(t, inst)
+		t.Run("RoundTrip", func(t *testing.T) { testMultipartRoundTrip(t, env, gw) })
+		t.Run("PartTooSmall", func(t *testing.T) { testMultipartPartTooSmall(t, env, gw) })
+		t.Run("Abort", func(t *testing.T) { testMultipartAbort(t, env, gw) })
+	})
+}
+
+// suiteEnv is the vendor bucket the suite writes to.
+type suiteEnv struct {
+	inst    provider.Instance
+	cleanup provider.CleanupPolicy
+}
+
+// newKey returns a key unique to the running test. The object is deleted
+// when the test ends unless the vendor bills early deletes.
+func (e *suiteEnv) newKey(t *testing.T, gw *harness.Gateway) string {
+	t.Helper()
+	key := fmt.Sprintf("integration/%s/%d", strings.ReplaceAll(t.Name(), " ", "_"), time.Now().UnixNano())
+	if e.cleanup == provider.CleanupPolicyDelete {
+		t.Cleanup(func() {
+			resp, _ := e.do(t, gw, http.MethodDelete, key, "", nil, nil)
+			if resp != nil && resp.StatusCode >= 300 {
+				t.Logf("cleanup: DELETE %s: status %d", key, resp.StatusCode)
+			}
+		})
+	}
+	return key
+}
+
+// do sends a request for key (with the raw query, if any) through gw and
+// returns the response with its body read.
+func (e *suiteEnv) do(t *testing.T, gw *harness.Gateway, method, key, query string, body []byte, header map[string]string) (*http.Response, []byte) {
+	t.Helper()
+	u := fmt.Sprintf("%s/%s/%s", gw.URL, e.inst.Bucket, key)
+	if query != "" {
+		u += "?" + query
+	}
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, key, err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := gw.HTTPClient().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, key, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: read body: %v", method, key, err)
+	}
+	return resp, data
+}
+
+// mustStatus fails the test unless resp has the wanted status.
+func mustStatus(t *testing.T, op string, resp *http.Response, body []byte, want int) {
+	t.Helper()
+	if resp.StatusCode != want {
+		t.Fatalf("%s: status %d, want %d: %s", op, resp.StatusCode, want, body)
+	}
+}
+
+// pattern returns n bytes that differ at every offset within a 251-byte
+// cycle, so a misplaced range shows up as a mismatch.
+func pattern(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+// userMetadata returns the x-amz-meta-* headers of h, lower-cased.
+func userMetadata(h http.Header) map[string]string {
+	meta := map[string]string{}
+	for k := range h {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-meta-") {
+			meta[lk] = h.Get(k)
+		}
+	}
+	return meta
+}
+
+// checkMetadata fails the test unless h carries exactly the user metadata
+// in want: the values sent, and none of the gateway's own encryption keys.
+func checkMetadata(t *testing.T, op string, h http.Header, want map[string]string) {
+	t.Helper()
+	got := userMetadata(h)
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: %s = %q (%d bytes), want %d bytes", op, k, got[k], len(got[k]), len(v))
+		}
+		delete(got, k)
+	}
+	for k := range got {
+		t.Errorf("%s: unexpected metadata header %s", op, k)
+	}
+}
+
+// testMetadataRoundTrip checks that user metadata, mixed-case keys included,
+// comes back unchanged on HEAD and GET, and that Content-Type and
+// Content-Length describe the plaintext.
+func testMetadataRoundTrip(t *testing.T, env *suiteEnv, gw *harness.Gateway) {
+	key := env.newKey(t, gw)
+	data := []byte(`{"hello":"world"}`)
+	resp, body := env.do(t, gw, http.MethodPut, key, "", data, map[string]string{
+		"Content-Type":         "application/json",
+		"X-Amz-Meta-Owner":     "team-a",
+		"X-Amz-Meta-MixedCase": "Value With Spaces",
+	})
+	mustStatus(t, "PUT", resp, body, http.StatusOK)
+	want := map[string]string{
+		"x-amz-meta-owner":     "team-a",
+		"x-amz-meta-mixedcase": "Value With Spaces",
+	}
+
+	resp, body = env.do(t, gw, http.MethodHead, key, "", nil, nil)
+	mustStatus(t, "HEAD", resp, body, http.StatusOK)
+	checkMetadata(t, "HEAD", resp.Header, want)
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("HEAD: Content-Type = %q", got)
+	}
+	if got := resp.Header.Get("Content-Length"); got != fmt.Sprint(len(data)) {
+		t.Errorf("HEAD: Content-Length = %s, want %d", got, len(data))
+	}
+
+	resp, body = env.do(t, gw, http.MethodGet, key, "", nil, nil)
+	mustStatus(t, "GET", resp, body, http.StatusOK)
+	checkMetadata(t, "GET", resp.Header, want)
+	if !bytes.Equal(body, data) {
+		t.Errorf("GET: body = %q", body)
+	}
+}
+
+// testMetadataWithinLimit stores half the vendor's user metadata budget,
+// leaving the other half to the gateway's encryption metadata, and expects
+// it back intact.
+func testMetadataWithinLimit(t *testing.T, env *suiteEnv, gw *harness.Gateway, limit int) {
+	key := env.newKey(t, gw)
+	value := strings.Repeat("m", limit/2-len("x-amz-meta-note: \r\n"))
+	resp, body := env.do(t, gw, http.MethodPut, key, "", []byte("payload"), map[string]string{"X-Amz-Meta-Note": value})
+	mustStatus(t, "PUT", resp, body, http.StatusOK)
+
+	resp, body = env.do(t, gw, http.MethodHead, key, "", nil, nil)
+	mustStatus(t, "HEAD", resp, body, http.StatusOK)
+	checkMetadata(t, "HEAD", resp.Header, map[string]string{"x-amz-meta-note": value})
+}
+
+// testMetadataOverLimit sends twice the vendor's user metadata budget. The
+// gateway may store it (in the object body if the headers cannot hold it)
+// or refuse it with a client error, but must neither fail with a server
+// error nor drop or truncate it.
+func testMetadataOverLimit(t *testing.T, env *suiteEnv, gw *harness.Gateway, limit int) {
+	key := env.newKey(t, gw)
+	value := strings.Repeat("o", 2*limit)
+	data := pattern(1024)
+	resp, body := env.do(t, gw, http.MethodPut, key, "", data, map[string]string{"X-Amz-Meta-Note": value})
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		t.Logf("PUT refused with %d: %s", resp.StatusCode, body)
+		return
+	default:
+		t.Fatalf("PUT: status %d, want 200 or a 4xx: %s", resp.StatusCode, body)
+	}
+
+	resp, body = env.do(t, gw, http.MethodGet, key, "", nil, nil)
+	mustStatus(t, "GET", resp, body, http.StatusOK)
+	checkMetadata(t, "GET", resp.Header, map[string]string{"x-amz-meta-note": value})
+	if !bytes.Equal(body, data) {
+		t.Errorf("GET: body of %d bytes does not match the %d written", len(body), len(data))
+	}
+}
+
+// testRangeReads reads ranges of every shape from an object of three and a
+// bit 64 KiB chunks: single bytes at both ends, chunk-crossing spans,
+// suffix and open-ended ranges, and one past the end.
+func testRangeReads(t *testing.T, env *suiteEnv, gw *harness.Gateway) {
+	const chunk = 64 * 1024
+	data := pattern(3*chunk + 123)
+	size := int64(len(data))
+	key := env.newKey(t, gw)
+	resp, body := env.do(t, gw, http.MethodPut, key, "", data, nil)
+	mustStatus(t, "PUT", resp, body, http.StatusOK)
+
+	cases := []struct {
+		header      string
+		first, last int64
+	}{
+		{"bytes=0-0", 0, 0},
+		{fmt.Sprintf("bytes=%d-%d", size-1, size-1), size - 1, size - 1},
+		{fmt.Sprintf("bytes=%d-%d", chunk-10, chunk+9), chunk - 10, chunk + 9},
+		{fmt.Sprintf("bytes=%d-%d", 100, 2*chunk+100), 100, 2*chunk + 100},
+		{"bytes=-500", size - 500, size - 1},
+		{fmt.Sprintf("bytes=%d-", 3*chunk), 3 * chunk, size - 1},
+		{fmt.Sprintf("bytes=0-%d", size+1000), 0, size - 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.header, func(t *testing.T) {
+			resp, body := env.do(t, gw, http.MethodGet, key, "", nil, map[string]string{"Range": tc.header})
+			mustStatus(t, "GET "+tc.header, resp, body, http.StatusPartialContent)
+			wantCR := fmt.Sprintf("bytes %d-%d/%d", tc.first, tc.last, size)
+			if got := resp.Header.Get("Content-Range"); got != wantCR {
+				t.Errorf("Content-Range = %q, want %q", got, wantCR)
+			}
+			if !bytes.Equal(body, data[tc.first:tc.last+1]) {
+				t.Errorf("body of %d bytes does not match [%d, %d]", len(body), tc.first, tc.last)
+			}
+		})
+	}
+
+	t.Run("unsatisfiable", func(t *testing.T) {
+		rng := fmt.Sprintf("bytes=%d-", size+10)
+		resp, body := env.do(t, gw, http.MethodGet, key, "", nil, map[string]string{"Range": rng})
+		mustStatus(t, "GET "+rng, resp, body, http.StatusRequestedRangeNotSatisfiable)
+	})
+}
+
+// initiate starts a multipart upload for key and returns its upload ID.
+func (e *suiteEnv) initiate(t *testing.T, gw *harness.Gateway, key string) string {
+	t.Helper()
+	resp, body := e.do(t, gw, http.MethodPost, key, "uploads", nil, nil)
+	mustStatus(t, "CreateMultipartUpload", resp, body, http.StatusOK)
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.Unmarshal(body, &result); err != nil || result.UploadID == "" {
+		t.Fatalf("CreateMultipartUpload: no upload ID in %s (%v)", body, err)
+	}
+	return result.UploadID
+}
+
+// uploadParts uploads parts in order and returns the body of a
+// CompleteMultipartUpload request listing them.
+func (e *suiteEnv) uploadParts(t *testing.T, gw *harness.Gateway, key, uploadID string, parts ...[]byte) []byte {
+	t.Helper()
+	var complete strings.Builder
+	complete.WriteString("<CompleteMultipartUpload>")
+	for i, part := range parts {
+		n := i + 1
+		resp, body := e.do(t, gw, http.MethodPut, key, fmt.Sprintf("partNumber=%d&uploadId=%s", n, uploadID), part, nil)
+		mustStatus(t, fmt.Sprintf("UploadPart %d", n), resp, body, http.StatusOK)
+		fmt.Fprintf(&complete, "<Part><PartNumber>%d</PartNumber><ETag>%s</ETag></Part>", n, resp.Header.Get("ETag"))
+	}
+	complete.WriteString("</CompleteMultipartUpload>")
+	return []byte(complete.String())
+}
+
+// testMultipartRoundTrip uploads two minimum-size parts and a short last
+// part, lists them before completing, and reads the object back whole and
+// across a part boundary.
+func testMultipartRoundTrip(t *testing.T, env *suiteEnv, gw *harness.Gateway) {
+	data := pattern(2*minPartSize + 1024*1024 + 7)
+	parts := [][]byte{data[:minPartSize], data[minPartSize : 2*minPartSize], data[2*minPartSize:]}
+	key := env.newKey(t, gw)
+	uploadID := env.initiate(t, gw, key)
+	complete := env.uploadParts(t, gw, key, uploadID, parts...)
+
+	resp, body := env.do(t, gw, http.MethodGet, key, "uploadId="+uploadID, nil, nil)
+	mustStatus(t, "ListParts", resp, body, http.StatusOK)
+	var listed struct {
+		Parts []struct {
+			PartNumber int `xml:"PartNumber"`
+		} `xml:"Part"`
+	}
+	if err := xml.Unmarshal(body, &listed); err != nil {
+		t.Fatalf("ListParts: %v", err)
+	}
+	if len(listed.Parts) != len(parts) {
+		t.Errorf("ListParts: %d parts, want %d", len(listed.Parts), len(parts))
+	}
+
+	resp, body = env.do(t, gw, http.MethodPost, key, "uploadId="+uploadID, complete, map[string]string{"Content-Type": "application/xml"})
+	mustStatus(t, "CompleteMultipartUpload", resp, body, http.StatusOK)
+
+	resp, body = env.do(t, gw, http.MethodHead, key, "", nil, nil)
+	mustStatus(t, "HEAD", resp, body, http.StatusOK)
+	if got := resp.Header.Get("Content-Length"); got != fmt.Sprint(len(data)) {
+		t.Errorf("HEAD: Content-Length = %s, want %d", got, len(data))
+	}
+	resp, body = env.do(t, gw, http.MethodGet, key, "", nil, nil)
+	mustStatus(t, "GET", resp, body, http.StatusOK)
+	if !bytes.Equal(body, data) {
+		t.Errorf("GET: body of %d bytes does not match the %d uploaded", len(body), len(data))
+	}
+
+	first, last := int64(minPartSize-100), int64(2*minPartSize+100)
+	rng := fmt.Sprintf("bytes=%d-%d", first, last)
+	resp, body = env.do(t, gw, http.MethodGet, key, "", nil, map[string]string{"Range": rng})
+	mustStatus(t, "GET "+rng, resp, body, http.StatusPartialContent)
+	if !bytes.Equal(body, data[first:last+1]) {
+		t.Errorf("GET %s: body does not match", rng)
+	}
+}
+
+// testMultipartPartTooSmall completes an upload whose first part is below
+// the minimum part size; the vendor's rejection must reach the client as a
+// client error, not a server error or a stored object.
+func testMultipartPartTooSmall(t *testing.T, env *suiteEnv, gw *harness.Gateway) {
+	key := env.newKey(t, gw)
+	uploadID := env.initiate(t, gw, key)
+	t.Cleanup(func() { env.do(t, gw, http.MethodDelete, key, "uploadId="+uploadID, nil, nil) })
+	complete := env.uploadParts(t, gw, key, uploadID, pattern(1024*1024), pattern(1024))
+
+	resp, body := env.do(t, gw, http.MethodPost, key, "uploadId="+uploadID, complete, map[string]string{"Content-Type": "application/xml"})
+	if resp.StatusCode < 400 || resp.StatusCode >= 500 {
+		t.Fatalf("CompleteMultipartUpload with a 1 MiB first part: status %d, want a 4xx: %s", resp.StatusCode, body)
+	}
+	if !strings.Contains(string(body), "EntityTooSmall") {
+		t.Logf("rejected with %d but not EntityTooSmall: %s", resp.StatusCode, body)
+	}
+}
+
+// testMultipartAbort aborts an upload with a part stored and checks that no
+// object and no upload remain.
+func testMultipartAbort(t *testing.T, env *suiteEnv, gw *harness.Gateway) {
+	key := env.newKey(t, gw)
+	uploadID := env.initiate(t, gw, key)
+	env.uploadParts(t, gw, key, uploadID, pattern(minPartSize))
+
+	resp, body := env.do(t, gw, http.MethodDelete, key, "uploadId="+uploadID, nil, nil)
+	mustStatus(t, "AbortMultipartUpload", resp, body, http.StatusNoContent)
+
+	resp, body = env.do(t, gw, http.MethodGet, key, "", nil, nil)
+	mustStatus(t, "GET after abort", resp, body, http.StatusNotFound)
+	resp, body = env.do(t, gw, http.MethodGet, key, "uploadId="+uploadID, nil, nil)
+	mustStatus(t, "ListParts after abort", resp, body, http.StatusNotFound)
+}
@@ -0,0 +1,20 @@
+//go:build wasabi
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+)
+
+// TestWasabi runs the provider suite against Wasabi. Requires
+// WASABI_ACCESS_KEY_ID, WASABI_SECRET_ACCESS_KEY and WASABI_BUCKET_NAME.
+// Objects are left in place: Wasabi bills 90 days of storage whether or not
+// they are deleted, so point it at a bucket with a lifecycle rule.
+func TestWasabi(t *testing.T) {
+	runProviderSuite(t, providerSuite{
+		provider:          "wasabi",
+		userMetadataLimit: crypto.ProviderWasabi.UserMetadataLimit,
+	})
+}
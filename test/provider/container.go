@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	tc "github.com/testcontainers/testcontainers-go"
+)
+
+// startContainer starts req for the provider called name and registers its
+// termination with t.Cleanup. A container that cannot be started (typically
+// because Docker is unavailable) skips the test rather than failing it.
+//
+// Readiness belongs in req.WaitingFor: a health endpoint or listening port,
+// never a fixed sleep.
+func startContainer(ctx context.Context, t *testing.T, name string, req tc.ContainerRequest) tc.Container {
+	t.Helper()
+
+	c, err := tc.GenericContainer(ctx, tc.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	if err != nil {
+		t.Skipf("%s provider: failed to start container (Docker unavailable?): %v", name, err)
+		return nil
+	}
+	t.Cleanup(func() { _ = c.Terminate(context.Background()) })
+	return c
+}
+
+// containerEndpoint returns the http:// URL at which port of c is reachable
+// from the test process.
+func containerEndpoint(ctx context.Context, t *testing.T, name string, c tc.Container, port string) string {
+	t.Helper()
+
+	host, err := c.Host(ctx)
+	if err != nil {
+		t.Fatalf("%s provider: host: %v", name, err)
+	}
+	mapped, err := c.MappedPort(ctx, port)
+	if err != nil {
+		t.Fatalf("%s provider: port %s: %v", name, port, err)
+	}
+	return fmt.Sprintf("http://%s:%s", host, mapped.Port())
+}
+
+// testBucketName returns a bucket name unique to this run of provider p.
+func testBucketName(p Provider) string {
+	return fmt.Sprintf("conf-%s-%d", p.Name(), time.Now().UnixNano())
+}
+
+// pollUntil calls ready every interval until it reports true, ctx is done or
+// timeout elapses. It is for readiness conditions a container wait strategy
+// cannot express, such as a cluster finishing its own bootstrap. On timeout
+// the last error ready returned, if any, is included.
+func pollUntil(ctx context.Context, timeout, interval time.Duration, ready func(context.Context) (bool, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		ok, err := ready(ctx)
+		if ok {
+			return nil
+		}
+		if err != nil {
+			lastErr = err
+		}
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("not ready after %s: %w", timeout, lastErr)
+			}
+			return fmt.Errorf("not ready after %s", timeout)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPollUntil(t *testing.T) {
+	t.Run("ReadyAfterRetries", func(t *testing.T) {
+		calls := 0
+		err := pollUntil(context.Background(), time.Second, time.Millisecond, func(context.Context) (bool, error) {
+			calls++
+			if calls < 3 {
+				return false, errors.New("connection refused")
+			}
+			return true, nil
+		})
+		if err != nil {
+			t.Fatalf("pollUntil: %v", err)
+		}
+		if calls != 3 {
+			t.Errorf("ready called %d times, want 3", calls)
+		}
+	})
+
+	t.Run("TimeoutKeepsLastError", func(t *testing.T) {
+		err := pollUntil(context.Background(), 20*time.Millisecond, time.Millisecond, func(context.Context) (bool, error) {
+			return false, errors.New("cluster unhealthy")
+		})
+		if err == nil || !strings.Contains(err.Error(), "cluster unhealthy") {
+			t.Fatalf("pollUntil = %v, want timeout carrying the last error", err)
+		}
+	})
+
+	t.Run("ParentCancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		start := time.Now()
+		err := pollUntil(ctx, time.Minute, time.Second, func(context.Context) (bool, error) {
+			return false, nil
+		})
+		if err == nil {
+			t.Fatal("pollUntil on a cancelled context returned nil")
+		}
+		if time.Since(start) > time.Second {
+			t.Errorf("pollUntil took %s after cancellation", time.Since(start))
+		}
+	})
+}
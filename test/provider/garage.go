@@ -86,37 +86,19 @@ func (p *garageProvider) Start(ctx context.Context, t *testing.T) Instance {
 				FileMode:          0644,
 			},
 		},
-		WaitingFor: wait.ForListeningPort(s3Port).WithStartupTimeout(60 * time.Second),
+		// Bootstrap talks to the admin API as soon as Start returns, so
+		// both listeners must be up, not just the S3 one.
+		WaitingFor: wait.ForAll(
+			wait.ForListeningPort(s3Port),
+			wait.ForListeningPort(adminPort),
+		).WithDeadline(60 * time.Second),
 	}
 
-	c, err := tc.GenericContainer(ctx, tc.GenericContainerRequest{
-		ContainerRequest: req,
-		Started:          true,
-	})
-	if err != nil {
-		t.Skipf("garage provider: failed to start container (Docker unavailable?): %v", err)
-		return Instance{}
-	}
-	t.Cleanup(func() { _ = c.Terminate(context.Background()) })
-
-	host, err := c.Host(ctx)
-	if err != nil {
-		t.Fatalf("garage provider: host: %v", err)
-	}
-
-	s3Mapped, err := c.MappedPort(ctx, s3Port)
-	if err != nil {
-		t.Fatalf("garage provider: s3 port: %v", err)
-	}
-	adminMapped, err := c.MappedPort(ctx, adminPort)
-	if err != nil {
-		t.Fatalf("garage provider: admin port: %v", err)
-	}
-
-	s3Endpoint := fmt.Sprintf("http://%s:%s", host, s3Mapped.Port())
-	adminEndpoint := fmt.Sprintf("http://%s:%s", host, adminMapped.Port())
+	c := startContainer(ctx, t, p.Name(), req)
+	s3Endpoint := containerEndpoint(ctx, t, p.Name(), c, s3Port)
+	adminEndpoint := containerEndpoint(ctx, t, p.Name(), c, adminPort)
 
-	bucket := fmt.Sprintf("conf-%s-%d", p.Name(), time.Now().UnixNano())
+	bucket := testBucketName(p)
 	ak, sk, err := bootstrapGarage(ctx, t, adminEndpoint, bucket)
 	if err != nil {
 		t.Fatalf("garage provider: bootstrap: %v", err)
@@ -132,6 +114,13 @@ func (p *garageProvider) Start(ctx context.Context, t *testing.T) Instance {
 	}
 }
 
+// Garage bootstrap readiness: how long to wait for the node to register and
+// the layout to apply, and how often to ask.
+const (
+	garageReadyTimeout = 30 * time.Second
+	garagePollInterval = 200 * time.Millisecond
+)
+
 // garageAdminToken is the bearer token baked into garage.toml. It only
 // protects the admin REST API within an ephemeral test container and is not
 // a security-sensitive value.
@@ -199,31 +188,28 @@ func bootstrapGarage(ctx context.Context, t *testing.T, adminEndpoint, bucket st
 
 	tok := garageAdminToken
 
-	// 1. GET /v2/GetClusterStatus → node ID (poll briefly; admin listener is
-	// up before the cluster is fully initialised).
+	// 1. GET /v2/GetClusterStatus → node ID. The admin listener is up
+	// before the node has registered itself, so poll until it reports one.
 	var statusResp struct {
 		Nodes []struct {
 			ID string `json:"id"`
 		} `json:"nodes"`
 	}
-	for attempt := 0; attempt < 30; attempt++ {
+	err = pollUntil(ctx, garageReadyTimeout, garagePollInterval, func(ctx context.Context) (bool, error) {
 		data, status, err := garageAdminReq(ctx, "GET", adminEndpoint+"/v2/GetClusterStatus", nil, tok)
 		if err != nil {
-			time.Sleep(200 * time.Millisecond)
-			continue
+			return false, err
 		}
-		if status == http.StatusOK {
-			if err := json.Unmarshal(data, &statusResp); err != nil {
-				return "", "", fmt.Errorf("parse /v2/GetClusterStatus: %w (body=%s)", err, string(data))
-			}
-			if len(statusResp.Nodes) > 0 {
-				break
-			}
+		if status != http.StatusOK {
+			return false, fmt.Errorf("GetClusterStatus returned %d: %s", status, string(data))
 		}
-		time.Sleep(200 * time.Millisecond)
-	}
-	if len(statusResp.Nodes) == 0 {
-		return "", "", fmt.Errorf("bootstrap: no node ID reported")
+		if err := json.Unmarshal(data, &statusResp); err != nil {
+			return false, fmt.Errorf("parse /v2/GetClusterStatus: %w (body=%s)", err, string(data))
+		}
+		return len(statusResp.Nodes) > 0, nil
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("bootstrap: no node ID reported: %w", err)
 	}
 	nodeID := statusResp.Nodes[0].ID
 
@@ -253,18 +239,26 @@ func bootstrapGarage(ctx context.Context, t *testing.T, adminEndpoint, bucket st
 		return "", "", fmt.Errorf("ApplyClusterLayout returned %d: %s", status, string(data))
 	}
 
-	// Wait for the cluster to become healthy before issuing writes.
-	for attempt := 0; attempt < 50; attempt++ {
-		data, status, err := garageAdminReq(ctx, "GET",
-			adminEndpoint+"/v2/GetClusterHealth", nil, tok)
-		if err == nil && status == http.StatusOK {
-			// Best-effort: as soon as the storage partitions are up, proceed.
-			if strings.Contains(string(data), `"status": "healthy"`) ||
-				strings.Contains(string(data), `"status":"healthy"`) {
-				break
-			}
+	// Wait for the cluster to become healthy before issuing writes; keys
+	// and buckets created earlier are rejected or lost.
+	err = pollUntil(ctx, garageReadyTimeout, garagePollInterval, func(ctx context.Context) (bool, error) {
+		data, status, err := garageAdminReq(ctx, "GET", adminEndpoint+"/v2/GetClusterHealth", nil, tok)
+		if err != nil {
+			return false, err
+		}
+		if status != http.StatusOK {
+			return false, fmt.Errorf("GetClusterHealth returned %d: %s", status, string(data))
+		}
+		var health struct {
+			Status string `json:"status"`
 		}
-		time.Sleep(200 * time.Millisecond)
+		if err := json.Unmarshal(data, &health); err != nil {
+			return false, fmt.Errorf("parse /v2/GetClusterHealth: %w (body=%s)", err, string(data))
+		}
+		return health.Status == "healthy", nil
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("bootstrap: cluster not healthy: %w", err)
 	}
 
 	// 4. CreateKey.
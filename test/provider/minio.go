@@ -2,10 +2,8 @@ package provider
 
 import (
 	"context"
-	"fmt"
 	"os"
 	"testing"
-	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
@@ -79,7 +77,7 @@ func (p *minioProvider) Start(ctx context.Context, t *testing.T) Instance {
 	}
 	endpoint = "http://" + endpoint
 
-	bucket := fmt.Sprintf("conf-%s-%d", p.Name(), time.Now().UnixNano())
+	bucket := testBucketName(p)
 	inst := Instance{
 		Endpoint:     endpoint,
 		Region:       "us-east-1",
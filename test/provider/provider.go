@@ -86,3 +86,14 @@ func Register(p Provider) {
 func All() []Provider {
 	return append([]Provider(nil), registry...)
 }
+
+// ByName returns the registered provider called name, or nil when it is not
+// registered (for external providers: when its credentials are not set).
+func ByName(name string) Provider {
+	for _, p := range registry {
+		if p.Name() == name {
+			return p
+		}
+	}
+	return nil
+}
@@ -29,6 +29,19 @@ func TestRegistry_NamesNotEmpty(t *testing.T) {
 	}
 }
 
+// TestByName asserts that ByName finds every registered provider and returns
+// nil for unknown names.
+func TestByName(t *testing.T) {
+	for _, p := range provider.All() {
+		if got := provider.ByName(p.Name()); got != p {
+			t.Errorf("ByName(%q) = %v, want %v", p.Name(), got, p)
+		}
+	}
+	if got := provider.ByName("no-such-provider"); got != nil {
+		t.Errorf("ByName(unknown) = %v, want nil", got)
+	}
+}
+
 // TestCapabilities_Stringer exercises the Capabilities.String() method to
 // ensure it does not panic and produces a non-empty string for known bits.
 func TestCapabilities_Stringer(t *testing.T) {
@@ -0,0 +1,42 @@
+// Cloudflare R2 external provider registration.
+//
+// Activated when R2_ACCESS_KEY_ID, R2_SECRET_ACCESS_KEY, and R2_BUCKET_NAME
+// are all set, together with R2_ACCOUNT_ID (the endpoint is derived from it)
+// or an explicit R2_ENDPOINT.
+//
+// R2 implements neither object tagging nor Object Lock, so those bits are
+// absent.
+package provider
+
+import "os"
+
+func init() {
+	if os.Getenv("GATEWAY_TEST_SKIP_EXTERNAL") != "" {
+		return
+	}
+	ak := os.Getenv("R2_ACCESS_KEY_ID")
+	sk := os.Getenv("R2_SECRET_ACCESS_KEY")
+	bk := os.Getenv("R2_BUCKET_NAME")
+	if ak == "" || sk == "" || bk == "" {
+		return
+	}
+	endpoint := os.Getenv("R2_ENDPOINT")
+	if endpoint == "" {
+		account := os.Getenv("R2_ACCOUNT_ID")
+		if account == "" {
+			return
+		}
+		endpoint = "https://" + account + ".r2.cloudflarestorage.com"
+	}
+	Register(&externalProvider{
+		name:      "cloudflare-r2",
+		endpoint:  endpoint,
+		region:    envOr("R2_REGION", "auto"),
+		keyEnv:    "R2_ACCESS_KEY_ID",
+		secretEnv: "R2_SECRET_ACCESS_KEY",
+		bucketEnv: "R2_BUCKET_NAME",
+		caps: CapMultipartUpload | CapMultipartCopy |
+			CapPresignedURL | CapBatchDelete | CapEncryptedMPU,
+		cleanup: CleanupPolicyDelete, // R2 has no minimum storage duration
+	})
+}
@@ -20,7 +20,6 @@ package provider
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 	"os"
 	"testing"
@@ -118,28 +117,10 @@ func (p *rustfsProvider) Start(ctx context.Context, t *testing.T) Instance {
 			WithStartupTimeout(60 * time.Second),
 	}
 
-	c, err := tc.GenericContainer(ctx, tc.GenericContainerRequest{
-		ContainerRequest: req,
-		Started:          true,
-	})
-	if err != nil {
-		t.Skipf("rustfs provider: failed to start container (Docker unavailable?): %v", err)
-		return Instance{}
-	}
-	t.Cleanup(func() { _ = c.Terminate(context.Background()) })
-
-	host, err := c.Host(ctx)
-	if err != nil {
-		t.Fatalf("rustfs provider: host: %v", err)
-	}
-	s3Mapped, err := c.MappedPort(ctx, s3Port)
-	if err != nil {
-		t.Fatalf("rustfs provider: s3 port: %v", err)
-	}
-
-	bucket := fmt.Sprintf("conf-%s-%d", p.Name(), time.Now().UnixNano())
+	c := startContainer(ctx, t, p.Name(), req)
+	bucket := testBucketName(p)
 	inst := Instance{
-		Endpoint:     fmt.Sprintf("http://%s:%s", host, s3Mapped.Port()),
+		Endpoint:     containerEndpoint(ctx, t, p.Name(), c, s3Port),
 		Region:       "us-east-1",
 		AccessKey:    accessKey,
 		SecretKey:    secretKey,
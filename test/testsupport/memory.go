@@ -0,0 +1,802 @@
+// Package testsupport provides test doubles for code built on the gateway's
+// backend interface (backend.Client in pkg/backend, an alias of s3.Client).
+//
+// MemoryClient is a complete in-memory s3.Client: it keeps object bodies
+// and metadata, serves byte ranges, runs real multipart uploads, honours the
+// write conditions attached with s3.WithWriteConditions, and records Object
+// Lock settings, tag sets and the storage class attached with s3.WithStorageClass. Errors carry S3 error codes and are classified like the
+// SDK-backed client's, so errors.Is(err, s3.ErrNotFound) and
+// api.TranslateError behave as they do against a real backend.
+package testsupport
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/crypto"
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+const (
+	httpTimeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+	listTimeFormat = "2006-01-02T15:04:05.000Z"
+	metaPrefix     = "x-amz-meta-"
+)
+
+// MemoryClient is an in-memory s3.Client for tests. The zero value is not
+// usable; create one with NewMemoryClient. Buckets spring into existence on
+// first write. All methods are safe for concurrent use.
+type MemoryClient struct {
+	// OnCall, when set, runs before every operation with the method name
+	// (e.g. "GetObject"), bucket and key. A non-nil error is returned from
+	// the operation without touching the store, which lets tests inject
+	// backend failures or block a call.
+	OnCall func(op, bucket, key string) error
+
+	mu          sync.Mutex
+	objects     map[string][]*memVersion // bucket/key → versions, oldest first
+	versioned   map[string]bool
+	uploads     map[string]*memUpload
+	lockConfigs map[string]*s3.ObjectLockConfiguration
+	calls       map[string]int
+	seq         int
+}
+
+var _ s3.Client = (*MemoryClient)(nil)
+
+type memVersion struct {
+	id           string
+	data         []byte
+	metadata     map[string]string
+	etag         string
+	tags         string
+	storageClass string
+	modified     time.Time
+	deleteMarker bool
+	retention    *s3.RetentionConfig
+	legalHold    string
+}
+
+type memUpload struct {
+	bucket, key string
+	metadata    map[string]string
+	parts       map[int32]*memPart
+}
+
+type memPart struct {
+	data     []byte
+	etag     string
+	modified time.Time
+}
+
+// NewMemoryClient returns an empty store.
+func NewMemoryClient() *MemoryClient {
+	return &MemoryClient{
+		objects:     make(map[string][]*memVersion),
+		versioned:   make(map[string]bool),
+		uploads:     make(map[string]*memUpload),
+		lockConfigs: make(map[string]*s3.ObjectLockConfiguration),
+		calls:       make(map[string]int),
+	}
+}
+
+// EnableVersioning turns on versioning for bucket: writes keep earlier
+// versions, reads and deletes accept version IDs, and deletes without one
+// leave a delete marker.
+func (m *MemoryClient) EnableVersioning(bucket string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.versioned[bucket] = true
+}
+
+// Calls returns how many times op (a method name such as "HeadObject") has
+// been called, including calls failed by OnCall.
+func (m *MemoryClient) Calls(op string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls[op]
+}
+
+// Object returns a copy of the stored body and user metadata of the latest
+// version of bucket/key, for asserting on what reached the backend (for
+// example that it is ciphertext).
+func (m *MemoryClient) Object(bucket, key string) ([]byte, map[string]string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v := m.latest(bucket, key)
+	if v == nil {
+		return nil, nil, false
+	}
+	return bytes.Clone(v.data), cloneMap(v.metadata), true
+}
+
+// StorageClass returns the storage class the latest version of bucket/key
+// was written with, "" when none was requested.
+func (m *MemoryClient) StorageClass(bucket, key string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if v := m.latest(bucket, key); v != nil {
+		return v.storageClass
+	}
+	return ""
+}
+
+// Tags returns the tag set stored with the latest version of bucket/key, in
+// the URL-encoded form PutObject received it.
+func (m *MemoryClient) Tags(bucket, key string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if v := m.latest(bucket, key); v != nil {
+		return v.tags
+	}
+	return ""
+}
+
+// begin counts the call and runs OnCall. The caller must not hold m.mu.
+func (m *MemoryClient) begin(op, bucket, key string) error {
+	m.mu.Lock()
+	m.calls[op]++
+	hook := m.OnCall
+	m.mu.Unlock()
+	if hook != nil {
+		return hook(op, bucket, key)
+	}
+	return nil
+}
+
+func objectKey(bucket, key string) string { return bucket + "/" + key }
+
+func cloneMap(in map[string]string) map[string]string {
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
+
+// userMetadata keys metadata the way s3.Client returns it: lower-case with
+// the x-amz-meta- prefix.
+func userMetadata(in map[string]string) map[string]string {
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[metaPrefix+strings.TrimPrefix(strings.ToLower(k), metaPrefix)] = v
+	}
+	return out
+}
+
+func quotedMD5(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func noSuchKey(bucket, key string) error {
+	return s3.NewAPIError("NoSuchKey", fmt.Sprintf("object not found: %s/%s", bucket, key))
+}
+
+// latest returns the current version of bucket/key, or nil when there is
+// none or it is a delete marker. The caller must hold m.mu.
+func (m *MemoryClient) latest(bucket, key string) *memVersion {
+	versions := m.objects[objectKey(bucket, key)]
+	if len(versions) == 0 || versions[len(versions)-1].deleteMarker {
+		return nil
+	}
+	return versions[len(versions)-1]
+}
+
+// find returns the requested version of bucket/key. The caller must hold
+// m.mu.
+func (m *MemoryClient) find(bucket, key string, versionID *string) (*memVersion, error) {
+	if versionID == nil || *versionID == "" {
+		if v := m.latest(bucket, key); v != nil {
+			return v, nil
+		}
+		return nil, noSuchKey(bucket, key)
+	}
+	for _, v := range m.objects[objectKey(bucket, key)] {
+		if v.id == *versionID {
+			if v.deleteMarker {
+				return nil, s3.NewAPIError("MethodNotAllowed", "the specified version is a delete marker")
+			}
+			return v, nil
+		}
+	}
+	return nil, s3.NewAPIError("NoSuchVersion", fmt.Sprintf("version %s of %s/%s not found", *versionID, bucket, key))
+}
+
+// store adds a new version of bucket/key after checking the write
+// conditions in ctx. The caller must hold m.mu.
+func (m *MemoryClient) store(ctx context.Context, bucket, key string, v *memVersion, lock *s3.ObjectLockInput) error {
+	current := m.latest(bucket, key)
+	if conds, ok := s3.WriteConditionsFromContext(ctx); ok {
+		if conds.IfNoneMatch == "*" && current != nil {
+			return s3.NewAPIError("PreconditionFailed", "at least one of the preconditions did not hold")
+		}
+		if conds.IfMatch != "" {
+			if current == nil {
+				return noSuchKey(bucket, key)
+			}
+			if strings.Trim(conds.IfMatch, `"`) != strings.Trim(current.etag, `"`) {
+				return s3.NewAPIError("PreconditionFailed", "at least one of the preconditions did not hold")
+			}
+		}
+	}
+	if lock != nil {
+		if lock.Mode != "" && lock.RetainUntilDate != nil {
+			v.retention = &s3.RetentionConfig{Mode: lock.Mode, RetainUntilDate: *lock.RetainUntilDate}
+		}
+		v.legalHold = lock.LegalHoldStatus
+	}
+	v.storageClass, _ = s3.StorageClassFromContext(ctx)
+	v.modified = time.Now().UTC()
+	m.seq++
+	ok := objectKey(bucket, key)
+	if m.versioned[bucket] {
+		v.id = fmt.Sprintf("v%06d", m.seq)
+		m.objects[ok] = append(m.objects[ok], v)
+	} else {
+		v.id = ""
+		m.objects[ok] = []*memVersion{v}
+	}
+	return nil
+}
+
+// responseMetadata is what GetObject and HeadObject report for v.
+func (v *memVersion) responseMetadata() map[string]string {
+	meta := cloneMap(v.metadata)
+	meta["ETag"] = v.etag
+	meta["Content-Length"] = strconv.Itoa(len(v.data))
+	meta["Last-Modified"] = v.modified.Format(httpTimeFormat)
+	meta["Accept-Ranges"] = "bytes"
+	if v.id != "" {
+		meta["x-amz-version-id"] = v.id
+	}
+	if v.retention != nil {
+		meta["x-amz-object-lock-mode"] = v.retention.Mode
+		meta["x-amz-object-lock-retain-until-date"] = v.retention.RetainUntilDate.UTC().Format(time.RFC3339)
+	}
+	if v.legalHold != "" {
+		meta["x-amz-object-lock-legal-hold"] = v.legalHold
+	}
+	return meta
+}
+
+// PutObject stores an object.
+func (m *MemoryClient) PutObject(ctx context.Context, bucket, key string, reader io.Reader, metadata map[string]string, contentLength *int64, tags string, lock *s3.ObjectLockInput) error {
+	if err := m.begin("PutObject", bucket, key); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read body for %s/%s: %w", bucket, key, err)
+	}
+	if contentLength != nil && *contentLength != int64(len(data)) {
+		return s3.NewAPIError("IncompleteBody", fmt.Sprintf("content length %d does not match body of %d bytes", *contentLength, len(data)))
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.store(ctx, bucket, key, &memVersion{
+		data:     data,
+		metadata: userMetadata(metadata),
+		etag:     quotedMD5(data),
+		tags:     tags,
+	}, lock)
+}
+
+// GetObject returns an object, or the single byte range in rangeHeader.
+func (m *MemoryClient) GetObject(ctx context.Context, bucket, key string, versionID *string, rangeHeader *string) (io.ReadCloser, map[string]string, error) {
+	if err := m.begin("GetObject", bucket, key); err != nil {
+		return nil, nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.find(bucket, key, versionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	meta := v.responseMetadata()
+	data := v.data
+	if rangeHeader != nil && *rangeHeader != "" {
+		start, end, err := crypto.ParseHTTPRangeHeader(*rangeHeader, int64(len(data)))
+		if err != nil || len(data) == 0 {
+			return nil, nil, s3.NewAPIError("InvalidRange", "the requested range is not satisfiable")
+		}
+		data = data[start : end+1]
+		meta["Content-Length"] = strconv.Itoa(len(data))
+		meta["Content-Range"] = fmt.Sprintf("bytes %d-%d/%d", start, end, len(v.data))
+	}
+	return io.NopCloser(bytes.NewReader(data)), meta, nil
+}
+
+// HeadObject returns an object's metadata.
+func (m *MemoryClient) HeadObject(ctx context.Context, bucket, key string, versionID *string) (map[string]string, error) {
+	if err := m.begin("HeadObject", bucket, key); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.find(bucket, key, versionID)
+	if err != nil {
+		return nil, err
+	}
+	return v.responseMetadata(), nil
+}
+
+// DeleteObject deletes an object or one version of it. Deleting a missing
+// object succeeds; deleting a version under retention or legal hold fails
+// with AccessDenied.
+func (m *MemoryClient) DeleteObject(ctx context.Context, bucket, key string, versionID *string) error {
+	if err := m.begin("DeleteObject", bucket, key); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.delete(bucket, key, versionID)
+}
+
+// delete removes bucket/key. The caller must hold m.mu.
+func (m *MemoryClient) delete(bucket, key string, versionID *string) error {
+	ok := objectKey(bucket, key)
+	versions := m.objects[ok]
+	if versionID != nil && *versionID != "" {
+		for i, v := range versions {
+			if v.id != *versionID {
+				continue
+			}
+			if locked(v) {
+				return s3.NewAPIError("AccessDenied", "object version is protected by object lock")
+			}
+			m.objects[ok] = append(versions[:i:i], versions[i+1:]...)
+			if len(m.objects[ok]) == 0 {
+				delete(m.objects, ok)
+			}
+			return nil
+		}
+		return nil
+	}
+	if m.versioned[bucket] {
+		m.seq++
+		m.objects[ok] = append(versions, &memVersion{id: fmt.Sprintf("v%06d", m.seq), deleteMarker: true, modified: time.Now().UTC()})
+		return nil
+	}
+	if len(versions) > 0 && locked(versions[0]) {
+		return s3.NewAPIError("AccessDenied", "object is protected by object lock")
+	}
+	delete(m.objects, ok)
+	return nil
+}
+
+func locked(v *memVersion) bool {
+	return v.legalHold == "ON" || (v.retention != nil && v.retention.RetainUntilDate.After(time.Now()))
+}
+
+// ListObjects lists the latest versions under prefix in key order,
+// honouring the delimiter, MaxKeys (default 1000) and the continuation
+// token, which is the last key returned.
+func (m *MemoryClient) ListObjects(ctx context.Context, bucket, prefix string, opts s3.ListOptions) (s3.ListResult, error) {
+	if err := m.begin("ListObjects", bucket, ""); err != nil {
+		return s3.ListResult{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var keys []string
+	for ok := range m.objects {
+		if key, found := strings.CutPrefix(ok, bucket+"/"); found && strings.HasPrefix(key, prefix) && m.latest(bucket, key) != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	maxKeys := int(opts.MaxKeys)
+	if maxKeys <= 0 || maxKeys > 1000 {
+		maxKeys = 1000
+	}
+	result := s3.ListResult{Objects: []s3.ObjectInfo{}, CommonPrefixes: []string{}}
+	for _, key := range keys {
+		if opts.ContinuationToken != "" && key <= opts.ContinuationToken {
+			continue
+		}
+		name := key
+		if opts.Delimiter != "" {
+			if i := strings.Index(key[len(prefix):], opts.Delimiter); i >= 0 {
+				name = key[:len(prefix)+i+len(opts.Delimiter)]
+				if n := len(result.CommonPrefixes); n > 0 && result.CommonPrefixes[n-1] == name {
+					continue
+				}
+			}
+		}
+		if len(result.Objects)+len(result.CommonPrefixes) == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+		if name != key {
+			result.CommonPrefixes = append(result.CommonPrefixes, name)
+			// Resume after every key under this prefix.
+			result.NextContinuationToken = name + "\xff"
+			continue
+		}
+		v := m.latest(bucket, key)
+		result.Objects = append(result.Objects, s3.ObjectInfo{
+			Key:          key,
+			Size:         int64(len(v.data)),
+			LastModified: v.modified.Format(listTimeFormat),
+			ETag:         v.etag,
+			VersionID:    v.id,
+		})
+		result.NextContinuationToken = key
+	}
+	if !result.IsTruncated {
+		result.NextContinuationToken = ""
+	}
+	return result, nil
+}
+
+func (m *MemoryClient) upload(bucket, key, uploadID string) (*memUpload, error) {
+	up, ok := m.uploads[uploadID]
+	if !ok || up.bucket != bucket || up.key != key {
+		return nil, s3.NewAPIError("NoSuchUpload", "upload not found: "+uploadID)
+	}
+	return up, nil
+}
+
+// CreateMultipartUpload starts a multipart upload.
+func (m *MemoryClient) CreateMultipartUpload(ctx context.Context, bucket, key string, metadata map[string]string) (string, error) {
+	if err := m.begin("CreateMultipartUpload", bucket, key); err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seq++
+	uploadID := fmt.Sprintf("upload-%06d", m.seq)
+	m.uploads[uploadID] = &memUpload{
+		bucket:   bucket,
+		key:      key,
+		metadata: userMetadata(metadata),
+		parts:    make(map[int32]*memPart),
+	}
+	return uploadID, nil
+}
+
+// UploadPart stores one part of an upload and returns its quoted ETag.
+func (m *MemoryClient) UploadPart(ctx context.Context, bucket, key, uploadID string, partNumber int32, reader io.Reader, contentLength *int64) (string, error) {
+	if err := m.begin("UploadPart", bucket, key); err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to read part %d: %w", partNumber, err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.putPart(bucket, key, uploadID, partNumber, data)
+}
+
+// putPart stores a part. The caller must hold m.mu.
+func (m *MemoryClient) putPart(bucket, key, uploadID string, partNumber int32, data []byte) (string, error) {
+	if partNumber < 1 || partNumber > 10000 {
+		return "", s3.NewAPIError("InvalidArgument", "part number must be between 1 and 10000")
+	}
+	up, err := m.upload(bucket, key, uploadID)
+	if err != nil {
+		return "", err
+	}
+	p := &memPart{data: data, etag: quotedMD5(data), modified: time.Now().UTC()}
+	up.parts[partNumber] = p
+	return p.etag, nil
+}
+
+// CompleteMultipartUpload assembles the listed parts. Parts must be in
+// ascending order with matching ETags; the object's ETag is the MD5 of the
+// part MD5s followed by the part count, as in S3.
+func (m *MemoryClient) CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []s3.CompletedPart, lock *s3.ObjectLockInput) (string, error) {
+	if err := m.begin("CompleteMultipartUpload", bucket, key); err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	up, err := m.upload(bucket, key, uploadID)
+	if err != nil {
+		return "", err
+	}
+	if len(parts) == 0 {
+		return "", s3.NewAPIError("MalformedXML", "no parts given")
+	}
+	var data []byte
+	digests := md5.New()
+	for i, cp := range parts {
+		if i > 0 && cp.PartNumber <= parts[i-1].PartNumber {
+			return "", s3.NewAPIError("InvalidPartOrder", "parts must be in ascending order")
+		}
+		p, ok := up.parts[cp.PartNumber]
+		if !ok || strings.Trim(p.etag, `"`) != strings.Trim(cp.ETag, `"`) {
+			return "", s3.NewAPIError("InvalidPart", fmt.Sprintf("part %d not found or ETag mismatch", cp.PartNumber))
+		}
+		sum, _ := hex.DecodeString(strings.Trim(p.etag, `"`))
+		digests.Write(sum)
+		data = append(data, p.data...)
+	}
+	etag := fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(digests.Sum(nil)), len(parts))
+	if err := m.store(ctx, bucket, key, &memVersion{data: data, metadata: up.metadata, etag: etag}, lock); err != nil {
+		return "", err
+	}
+	delete(m.uploads, uploadID)
+	return etag, nil
+}
+
+// AbortMultipartUpload discards an upload.
+func (m *MemoryClient) AbortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error {
+	if err := m.begin("AbortMultipartUpload", bucket, key); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.upload(bucket, key, uploadID); err != nil {
+		return err
+	}
+	delete(m.uploads, uploadID)
+	return nil
+}
+
+// ListParts lists the parts of an upload in part-number order.
+func (m *MemoryClient) ListParts(ctx context.Context, bucket, key, uploadID string) ([]s3.PartInfo, error) {
+	if err := m.begin("ListParts", bucket, key); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	up, err := m.upload(bucket, key, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]s3.PartInfo, 0, len(up.parts))
+	for n, p := range up.parts {
+		parts = append(parts, s3.PartInfo{
+			PartNumber:   n,
+			ETag:         p.etag,
+			Size:         int64(len(p.data)),
+			LastModified: p.modified.Format(listTimeFormat),
+		})
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	return parts, nil
+}
+
+// CopyObject copies an object. A nil metadata map keeps the source's user
+// metadata; otherwise the copy carries metadata instead. Like the SDK
+// client it returns the ETag without quotes.
+func (m *MemoryClient) CopyObject(ctx context.Context, dstBucket, dstKey string, srcBucket, srcKey string, srcVersionID *string, metadata map[string]string, lock *s3.ObjectLockInput) (string, map[string]string, error) {
+	if err := m.begin("CopyObject", dstBucket, dstKey); err != nil {
+		return "", nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	src, err := m.find(srcBucket, srcKey, srcVersionID)
+	if err != nil {
+		return "", nil, err
+	}
+	newMeta := src.metadata
+	if metadata != nil {
+		newMeta = userMetadata(metadata)
+	}
+	v := &memVersion{data: src.data, metadata: cloneMap(newMeta), etag: quotedMD5(src.data), tags: src.tags}
+	if err := m.store(ctx, dstBucket, dstKey, v, lock); err != nil {
+		return "", nil, err
+	}
+	etag := strings.Trim(v.etag, `"`)
+	return etag, map[string]string{"ETag": etag, "Last-Modified": v.modified.Format(httpTimeFormat)}, nil
+}
+
+// UploadPartCopy stores a byte range of an existing object as a part.
+func (m *MemoryClient) UploadPartCopy(ctx context.Context, dstBucket, dstKey, uploadID string, partNumber int32, srcBucket, srcKey string, srcVersionID *string, srcRange *s3.CopyPartRange) (*s3.CopyPartResult, error) {
+	if err := m.begin("UploadPartCopy", dstBucket, dstKey); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	src, err := m.find(srcBucket, srcKey, srcVersionID)
+	if err != nil {
+		return nil, err
+	}
+	data := src.data
+	if srcRange != nil {
+		if srcRange.First < 0 || srcRange.Last < srcRange.First || srcRange.Last >= int64(len(data)) {
+			return nil, s3.NewAPIError("InvalidRange", "the copy source range is not satisfiable")
+		}
+		data = data[srcRange.First : srcRange.Last+1]
+	}
+	etag, err := m.putPart(dstBucket, dstKey, uploadID, partNumber, bytes.Clone(data))
+	if err != nil {
+		return nil, err
+	}
+	return &s3.CopyPartResult{ETag: etag, LastModified: time.Now().UTC()}, nil
+}
+
+// DeleteObjects deletes each key, reporting per-key failures.
+func (m *MemoryClient) DeleteObjects(ctx context.Context, bucket string, keys []s3.ObjectIdentifier) ([]s3.DeletedObject, []s3.ErrorObject, error) {
+	if err := m.begin("DeleteObjects", bucket, ""); err != nil {
+		return nil, nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	deleted := []s3.DeletedObject{}
+	var failed []s3.ErrorObject
+	for _, k := range keys {
+		var versionID *string
+		if k.VersionID != "" {
+			versionID = &k.VersionID
+		}
+		if err := m.delete(bucket, k.Key, versionID); err != nil {
+			failed = append(failed, s3.ErrorObject{Key: k.Key, Code: "AccessDenied", Message: err.Error()})
+			continue
+		}
+		deleted = append(deleted, s3.DeletedObject{Key: k.Key, VersionID: k.VersionID})
+	}
+	return deleted, failed, nil
+}
+
+// PutObjectRetention sets the retention of an object version.
+func (m *MemoryClient) PutObjectRetention(ctx context.Context, bucket, key string, versionID *string, retention *s3.RetentionConfig) error {
+	if err := m.begin("PutObjectRetention", bucket, key); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.find(bucket, key, versionID)
+	if err != nil {
+		return err
+	}
+	if retention == nil || retention.Mode == "" {
+		v.retention = nil
+		return nil
+	}
+	r := *retention
+	v.retention = &r
+	return nil
+}
+
+// GetObjectRetention returns the retention of an object version.
+func (m *MemoryClient) GetObjectRetention(ctx context.Context, bucket, key string, versionID *string) (*s3.RetentionConfig, error) {
+	if err := m.begin("GetObjectRetention", bucket, key); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.find(bucket, key, versionID)
+	if err != nil {
+		return nil, err
+	}
+	if v.retention == nil {
+		return nil, s3.NewAPIError("NoSuchObjectLockConfiguration", "the object has no retention")
+	}
+	r := *v.retention
+	return &r, nil
+}
+
+// PutObjectLegalHold sets the legal hold ("ON" or "OFF") of an object
+// version.
+func (m *MemoryClient) PutObjectLegalHold(ctx context.Context, bucket, key string, versionID *string, status string) error {
+	if err := m.begin("PutObjectLegalHold", bucket, key); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.find(bucket, key, versionID)
+	if err != nil {
+		return err
+	}
+	v.legalHold = status
+	return nil
+}
+
+// GetObjectLegalHold returns the legal hold of an object version.
+func (m *MemoryClient) GetObjectLegalHold(ctx context.Context, bucket, key string, versionID *string) (string, error) {
+	if err := m.begin("GetObjectLegalHold", bucket, key); err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.find(bucket, key, versionID)
+	if err != nil {
+		return "", err
+	}
+	if v.legalHold == "" {
+		return "", s3.NewAPIError("NoSuchObjectLockConfiguration", "the object has no legal hold")
+	}
+	return v.legalHold, nil
+}
+
+// PutObjectLockConfiguration sets the bucket's Object Lock configuration.
+func (m *MemoryClient) PutObjectLockConfiguration(ctx context.Context, bucket string, config *s3.ObjectLockConfiguration) error {
+	if err := m.begin("PutObjectLockConfiguration", bucket, ""); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if config == nil {
+		delete(m.lockConfigs, bucket)
+		return nil
+	}
+	c := *config
+	m.lockConfigs[bucket] = &c
+	return nil
+}
+
+// GetObjectLockConfiguration returns the bucket's Object Lock
+// configuration, or nil when none was set.
+func (m *MemoryClient) GetObjectLockConfiguration(ctx context.Context, bucket string) (*s3.ObjectLockConfiguration, error) {
+	if err := m.begin("GetObjectLockConfiguration", bucket, ""); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.lockConfigs[bucket]
+	if !ok {
+		return nil, nil
+	}
+	cp := *c
+	return &cp, nil
+}
+
+// PutObjectTagging replaces the tag set of an object version.
+func (m *MemoryClient) PutObjectTagging(ctx context.Context, bucket, key string, versionID *string, tags []s3.Tag) error {
+	if err := m.begin("PutObjectTagging", bucket, key); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.find(bucket, key, versionID)
+	if err != nil {
+		return err
+	}
+	q := url.Values{}
+	for _, t := range tags {
+		q.Set(t.Key, t.Value)
+	}
+	v.tags = q.Encode()
+	return nil
+}
+
+// GetObjectTagging returns the tag set of an object version, sorted by
+// key.
+func (m *MemoryClient) GetObjectTagging(ctx context.Context, bucket, key string, versionID *string) ([]s3.Tag, error) {
+	if err := m.begin("GetObjectTagging", bucket, key); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.find(bucket, key, versionID)
+	if err != nil {
+		return nil, err
+	}
+	q, _ := url.ParseQuery(v.tags)
+	tags := make([]s3.Tag, 0, len(q))
+	for k := range q {
+		tags = append(tags, s3.Tag{Key: k, Value: q.Get(k)})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+	return tags, nil
+}
+
+// DeleteObjectTagging removes every tag from an object version.
+func (m *MemoryClient) DeleteObjectTagging(ctx context.Context, bucket, key string, versionID *string) error {
+	if err := m.begin("DeleteObjectTagging", bucket, key); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, err := m.find(bucket, key, versionID)
+	if err != nil {
+		return err
+	}
+	v.tags = ""
+	return nil
+}
@@ -0,0 +1,215 @@
+package testsupport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kenneth/s3-encryption-gateway/internal/s3"
+)
+
+func read(t *testing.T, m *MemoryClient, key string, versionID, rangeHeader *string) (string, map[string]string) {
+	t.Helper()
+	r, meta, err := m.GetObject(context.Background(), "b", key, versionID, rangeHeader)
+	if err != nil {
+		t.Fatalf("GetObject(%s): %v", key, err)
+	}
+	defer r.Close()
+	data, _ := io.ReadAll(r)
+	return string(data), meta
+}
+
+func TestMemoryClient_ObjectsAndRanges(t *testing.T) {
+	m := NewMemoryClient()
+	ctx := context.Background()
+	if err := m.PutObject(ctx, "b", "k", strings.NewReader("hello world"), map[string]string{"X-Amz-Meta-Iv": "abc"}, nil, "a=1", nil); err != nil {
+		t.Fatal(err)
+	}
+	body, meta := read(t, m, "k", nil, nil)
+	if body != "hello world" || meta["x-amz-meta-iv"] != "abc" || meta["Content-Length"] != "11" {
+		t.Errorf("GetObject = %q, %v", body, meta)
+	}
+	if meta["ETag"] != `"5eb63bbbe01eeed093cb22bb8f5acdc3"` {
+		t.Errorf("ETag = %s", meta["ETag"])
+	}
+	if m.Tags("b", "k") != "a=1" {
+		t.Errorf("tags = %q", m.Tags("b", "k"))
+	}
+
+	for rng, want := range map[string]string{"bytes=0-4": "hello", "bytes=6-": "world", "bytes=-3": "rld", "bytes=6-100": "world"} {
+		r := rng
+		if body, meta := read(t, m, "k", nil, &r); body != want || meta["Content-Length"] != strconv.Itoa(len(want)) {
+			t.Errorf("range %s = %q (%v)", rng, body, meta)
+		}
+	}
+	bad := "bytes=20-"
+	if _, _, err := m.GetObject(ctx, "b", "k", nil, &bad); err == nil {
+		t.Error("unsatisfiable range should fail")
+	}
+
+	if _, err := m.HeadObject(ctx, "b", "missing", nil); !errors.Is(err, s3.ErrNotFound) {
+		t.Errorf("missing object = %v, want ErrNotFound", err)
+	}
+	if m.Calls("HeadObject") != 1 || m.Calls("GetObject") != 6 {
+		t.Errorf("calls: head %d, get %d", m.Calls("HeadObject"), m.Calls("GetObject"))
+	}
+}
+
+func TestMemoryClient_ConditionalWrites(t *testing.T) {
+	m := NewMemoryClient()
+	ctx := context.Background()
+	create := s3.WithWriteConditions(ctx, s3.WriteConditions{IfNoneMatch: "*"})
+	if err := m.PutObject(create, "b", "k", strings.NewReader("1"), nil, nil, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	err := m.PutObject(create, "b", "k", strings.NewReader("2"), nil, nil, "", nil)
+	var apiErr interface{ ErrorCode() string }
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "PreconditionFailed" {
+		t.Errorf("If-None-Match on existing object = %v", err)
+	}
+	_, meta := read(t, m, "k", nil, nil)
+	match := s3.WithWriteConditions(ctx, s3.WriteConditions{IfMatch: meta["ETag"]})
+	if err := m.PutObject(match, "b", "k", strings.NewReader("3"), nil, nil, "", nil); err != nil {
+		t.Errorf("If-Match with current ETag: %v", err)
+	}
+	if err := m.PutObject(match, "b", "k", strings.NewReader("4"), nil, nil, "", nil); err == nil {
+		t.Error("If-Match with stale ETag should fail")
+	}
+}
+
+func TestMemoryClient_Multipart(t *testing.T) {
+	m := NewMemoryClient()
+	ctx := context.Background()
+	id, _ := m.CreateMultipartUpload(ctx, "b", "mpu", map[string]string{"x-amz-meta-k": "v"})
+	e1, _ := m.UploadPart(ctx, "b", "mpu", id, 1, strings.NewReader("abc"), nil)
+	if err := m.PutObject(ctx, "b", "src", strings.NewReader("0123456789"), nil, nil, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	pc, err := m.UploadPartCopy(ctx, "b", "mpu", id, 2, "b", "src", nil, &s3.CopyPartRange{First: 2, Last: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts, _ := m.ListParts(ctx, "b", "mpu", id)
+	if len(parts) != 2 || parts[1].Size != 3 {
+		t.Errorf("ListParts = %+v", parts)
+	}
+	if _, err := m.CompleteMultipartUpload(ctx, "b", "mpu", id, []s3.CompletedPart{{PartNumber: 2, ETag: pc.ETag}, {PartNumber: 1, ETag: e1}}, nil); err == nil {
+		t.Error("out-of-order parts should be rejected")
+	}
+	etag, err := m.CompleteMultipartUpload(ctx, "b", "mpu", id, []s3.CompletedPart{{PartNumber: 1, ETag: e1}, {PartNumber: 2, ETag: pc.ETag}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(etag, `-2"`) {
+		t.Errorf("multipart ETag = %s", etag)
+	}
+	if body, meta := read(t, m, "mpu", nil, nil); body != "abc234" || meta["x-amz-meta-k"] != "v" {
+		t.Errorf("assembled object = %q, %v", body, meta)
+	}
+	if _, err := m.ListParts(ctx, "b", "mpu", id); !errors.Is(err, s3.ErrNotFound) {
+		t.Errorf("ListParts after complete = %v, want NoSuchUpload", err)
+	}
+}
+
+func TestMemoryClient_ListObjects(t *testing.T) {
+	m := NewMemoryClient()
+	ctx := context.Background()
+	for _, k := range []string{"a/1", "a/2", "a/b/3", "ab", "b", "c/4"} {
+		m.PutObject(ctx, "b", k, strings.NewReader("x"), nil, nil, "", nil)
+	}
+	m.PutObject(ctx, "other", "a/9", strings.NewReader("x"), nil, nil, "", nil)
+
+	res, err := m.ListObjects(ctx, "b", "", s3.ListOptions{Delimiter: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Objects) != 2 || strings.Join(res.CommonPrefixes, ",") != "a/,c/" {
+		t.Errorf("delimited listing = %+v", res)
+	}
+
+	var keys []string
+	opts := s3.ListOptions{Delimiter: "/", MaxKeys: 1}
+	for {
+		res, err := m.ListObjects(ctx, "b", "", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, o := range res.Objects {
+			keys = append(keys, o.Key)
+		}
+		keys = append(keys, res.CommonPrefixes...)
+		if !res.IsTruncated {
+			break
+		}
+		opts.ContinuationToken = res.NextContinuationToken
+	}
+	if got := strings.Join(keys, ","); got != "a/,ab,b,c/" {
+		t.Errorf("paged listing = %s", got)
+	}
+}
+
+func TestMemoryClient_VersioningAndLock(t *testing.T) {
+	m := NewMemoryClient()
+	m.EnableVersioning("b")
+	ctx := context.Background()
+	m.PutObject(ctx, "b", "k", strings.NewReader("v1"), nil, nil, "", nil)
+	_, meta := read(t, m, "k", nil, nil)
+	v1 := meta["x-amz-version-id"]
+	until := time.Now().Add(time.Hour)
+	m.PutObject(ctx, "b", "k", strings.NewReader("v2"), nil, nil, "", &s3.ObjectLockInput{Mode: "GOVERNANCE", RetainUntilDate: &until})
+	_, meta = read(t, m, "k", nil, nil)
+	v2 := meta["x-amz-version-id"]
+	if meta["x-amz-object-lock-mode"] != "GOVERNANCE" {
+		t.Errorf("lock metadata missing: %v", meta)
+	}
+
+	if body, _ := read(t, m, "k", &v1, nil); body != "v1" {
+		t.Errorf("version %s = %q", v1, body)
+	}
+	if err := m.DeleteObject(ctx, "b", "k", &v2); err == nil {
+		t.Error("deleting a retained version should fail")
+	}
+	if err := m.DeleteObject(ctx, "b", "k", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.HeadObject(ctx, "b", "k", nil); !errors.Is(err, s3.ErrNotFound) {
+		t.Errorf("HEAD after delete marker = %v", err)
+	}
+	if body, _ := read(t, m, "k", &v2, nil); body != "v2" {
+		t.Errorf("old version after delete marker = %q", body)
+	}
+
+	if err := m.PutObjectLegalHold(ctx, "b", "k", &v1, "ON"); err != nil {
+		t.Fatal(err)
+	}
+	if hold, _ := m.GetObjectLegalHold(ctx, "b", "k", &v1); hold != "ON" {
+		t.Errorf("legal hold = %q", hold)
+	}
+	if cfg, err := m.GetObjectLockConfiguration(ctx, "b"); cfg != nil || err != nil {
+		t.Errorf("lock configuration before put = %v, %v", cfg, err)
+	}
+}
+
+func TestMemoryClient_OnCall(t *testing.T) {
+	m := NewMemoryClient()
+	boom := errors.New("backend down")
+	m.OnCall = func(op, bucket, key string) error {
+		if op == "PutObject" && key == "fail" {
+			return boom
+		}
+		return nil
+	}
+	if err := m.PutObject(context.Background(), "b", "fail", strings.NewReader("x"), nil, nil, "", nil); !errors.Is(err, boom) {
+		t.Errorf("PutObject = %v, want injected error", err)
+	}
+	if _, _, ok := m.Object("b", "fail"); ok {
+		t.Error("failed write must not be stored")
+	}
+	if m.Calls("PutObject") != 1 {
+		t.Errorf("Calls(PutObject) = %d", m.Calls("PutObject"))
+	}
+}